@@ -2,10 +2,161 @@
 package main
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"syscall"
+
 	app "github.com/pocj8ur4in/boilerplate-go/internal/app/boilerplate"
+	"github.com/pocj8ur4in/boilerplate-go/internal/app/boilerplate/config"
+)
+
+const (
+	// exitCodeFailure is exit code when the command fails.
+	exitCodeFailure = 1
+
+	// exitCodeUsage is exit code when the command is used incorrectly.
+	exitCodeUsage = 2
 )
 
 // main is an entry point for the service.
 func main() {
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "selftest":
+			os.Exit(runSelfTest())
+		case "encrypt-config":
+			os.Exit(runEncryptConfig())
+		case "serverless":
+			os.Exit(runServerless())
+		case "migrate":
+			os.Exit(runMigrate())
+		case "graph":
+			os.Exit(runGraph())
+		case "--mock":
+			os.Exit(runMock())
+		default:
+			fmt.Fprintf(os.Stderr, "unknown command: %s\n", os.Args[1])
+			os.Exit(exitCodeUsage)
+		}
+	}
+
 	app.New().Run()
 }
+
+// runSelfTest runs the self-test and returns the exit code.
+func runSelfTest() int {
+	report, err := app.SelfTest(context.Background())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "selftest failed: %v\n", err)
+
+		return exitCodeFailure
+	}
+
+	if err := report.Write(os.Stdout); err != nil {
+		fmt.Fprintf(os.Stderr, "selftest failed: %v\n", err)
+
+		return exitCodeFailure
+	}
+
+	if !report.Passed() {
+		return exitCodeFailure
+	}
+
+	return 0
+}
+
+// runEncryptConfig encrypts the config read from stdin to stdout and returns the exit code.
+func runEncryptConfig() int {
+	key, err := config.LoadEncryptionKey()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "encrypt-config failed: %v\n", err)
+
+		return exitCodeFailure
+	}
+
+	plaintext, err := io.ReadAll(os.Stdin)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "encrypt-config failed: %v\n", err)
+
+		return exitCodeFailure
+	}
+
+	encrypted, err := config.Encrypt(plaintext, key)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "encrypt-config failed: %v\n", err)
+
+		return exitCodeFailure
+	}
+
+	if _, err := os.Stdout.Write(encrypted); err != nil {
+		fmt.Fprintf(os.Stderr, "encrypt-config failed: %v\n", err)
+
+		return exitCodeFailure
+	}
+
+	return 0
+}
+
+// runServerless starts the lambda runtime loop and returns the exit code if it fails to start.
+func runServerless() int {
+	adapter, err := app.NewServerless()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "serverless failed: %v\n", err)
+
+		return exitCodeFailure
+	}
+
+	adapter.Start()
+
+	return 0
+}
+
+// runMigrate runs the migrate command given as argument (up, down or status) and returns the exit code.
+func runMigrate() int {
+	if len(os.Args) != 3 {
+		fmt.Fprintln(os.Stderr, "usage: boilerplate migrate up|down|status")
+
+		return exitCodeUsage
+	}
+
+	if err := app.Migrate(context.Background(), os.Args[2], os.Stdout); err != nil {
+		fmt.Fprintf(os.Stderr, "migrate failed: %v\n", err)
+
+		if errors.Is(err, app.ErrUnknownMigrateCommand) {
+			return exitCodeUsage
+		}
+
+		return exitCodeFailure
+	}
+
+	return 0
+}
+
+// runGraph writes the dependency graph of the application in the DOT language to stdout and returns the exit code.
+func runGraph() int {
+	if err := app.Graph(os.Stdout); err != nil {
+		fmt.Fprintf(os.Stderr, "graph failed: %v\n", err)
+
+		return exitCodeFailure
+	}
+
+	return 0
+}
+
+// runMock serves mock responses of the OpenAPI spec until interrupted and returns the exit code.
+func runMock() int {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	if err := app.RunMock(ctx); err != nil {
+		fmt.Fprintf(os.Stderr, "mock failed: %v\n", err)
+
+		return exitCodeFailure
+	}
+
+	return 0
+}
@@ -1,12 +1,19 @@
 %:
 	@:
 
+VERSION_PKG := github.com/pocj8ur4in/boilerplate-go/internal/pkg/version
+VERSION ?= $(shell git describe --tags --always --dirty 2>/dev/null || echo dev)
+COMMIT ?= $(shell git rev-parse --short HEAD 2>/dev/null || echo unknown)
+BUILD_TIME ?= $(shell date -u +%Y-%m-%dT%H:%M:%SZ)
+LDFLAGS := -X $(VERSION_PKG).Version=$(VERSION) -X $(VERSION_PKG).Commit=$(COMMIT) -X $(VERSION_PKG).BuildTime=$(BUILD_TIME)
+
 .PHONY: help-go
 help-go:
 	@echo "$(WHITE)$(CYAN)[go target]$(RESET)"
 	@echo "- $(WHITE)make go build$(RESET)                            $(CYAN)Build go project$(RESET)"
 	@echo "- $(WHITE)make go run$(RESET)                              $(CYAN)Run go project$(RESET)"
 	@echo "- $(WHITE)make go dev$(RESET)                              $(CYAN)Run go project in development mode$(RESET)"
+	@echo "- $(WHITE)make go selftest$(RESET)                         $(CYAN)Run self-test against configured dependencies$(RESET)"
 	@echo "- $(WHITE)make go test$(RESET)                             $(CYAN)Test go project$(RESET)"
 	@echo "- $(WHITE)make go benchmark$(RESET)                        $(CYAN)Benchmark go project$(RESET)"
 	@echo "- $(WHITE)make go coverage$(RESET)                         $(CYAN)Test coverage for go project$(RESET)"
@@ -23,7 +30,7 @@ go:
 	fi
 	@if [ "$(TARGET)" = "build" ]; then \
 		echo "$(BLUE)Building go project...$(RESET)"; \
-		CONFIG_PATH=$(CONFIG_PATH) CGO_ENABLED=1 go build -o build/$(BINARY_NAME) $(CMD_DIR)/$(BINARY_NAME); \
+		CONFIG_PATH=$(CONFIG_PATH) CGO_ENABLED=1 go build -ldflags "$(LDFLAGS)" -o build/$(BINARY_NAME) $(CMD_DIR)/$(BINARY_NAME); \
 	elif [ "$(TARGET)" = "run" ]; then \
 		echo "$(BLUE)Running go project...$(RESET)"; \
 		CONFIG_PATH=$(CONFIG_PATH) CGO_ENABLED=1 go run $(CMD_DIR)/$(BINARY_NAME)/main.go; \
@@ -32,6 +39,9 @@ go:
 		if command -v air > /dev/null; then \
 			CONFIG_PATH=$(CONFIG_PATH) air; \
 		fi; \
+	elif [ "$(TARGET)" = "selftest" ]; then \
+		echo "$(BLUE)Running self-test...$(RESET)"; \
+		CONFIG_PATH=$(CONFIG_PATH) CGO_ENABLED=1 go run $(CMD_DIR)/$(BINARY_NAME)/main.go selftest; \
 	elif [ "$(TARGET)" = "test" ]; then \
 		echo "$(BLUE)Testing go project...$(RESET)"; \
 		CONFIG_PATH=$(CONFIG_EXAMPLE_PATH) CGO_ENABLED=1 go test $$(go list $(CURDIR)/... | grep -v /internal/gen/) -v -p=1 -parallel=4 -timeout=15m; \
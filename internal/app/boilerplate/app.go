@@ -3,48 +3,287 @@ package app
 
 import (
 	"context"
+	"errors"
 	"fmt"
 
+	"github.com/prometheus/client_golang/prometheus"
 	"go.uber.org/fx"
 
 	configPkg "github.com/pocj8ur4in/boilerplate-go/internal/app/boilerplate/config"
+	grpcserverPkg "github.com/pocj8ur4in/boilerplate-go/internal/app/boilerplate/grpcserver"
 	serverPkg "github.com/pocj8ur4in/boilerplate-go/internal/app/boilerplate/server"
 	handlerPkg "github.com/pocj8ur4in/boilerplate-go/internal/app/boilerplate/server/handler"
+	apikeyPkg "github.com/pocj8ur4in/boilerplate-go/internal/pkg/apikey"
+	auditPkg "github.com/pocj8ur4in/boilerplate-go/internal/pkg/audit"
+	authzPkg "github.com/pocj8ur4in/boilerplate-go/internal/pkg/authz"
+	cachePkg "github.com/pocj8ur4in/boilerplate-go/internal/pkg/cache"
 	databasePkg "github.com/pocj8ur4in/boilerplate-go/internal/pkg/database"
+	healthPkg "github.com/pocj8ur4in/boilerplate-go/internal/pkg/health"
+	httpclientPkg "github.com/pocj8ur4in/boilerplate-go/internal/pkg/httpclient"
+	imagesPkg "github.com/pocj8ur4in/boilerplate-go/internal/pkg/images"
+	jobsPkg "github.com/pocj8ur4in/boilerplate-go/internal/pkg/jobs"
 	jwtPkg "github.com/pocj8ur4in/boilerplate-go/internal/pkg/jwt"
+	ldapPkg "github.com/pocj8ur4in/boilerplate-go/internal/pkg/ldap"
 	loggerPkg "github.com/pocj8ur4in/boilerplate-go/internal/pkg/logger"
+	meteringPkg "github.com/pocj8ur4in/boilerplate-go/internal/pkg/metering"
+	migrationsPkg "github.com/pocj8ur4in/boilerplate-go/internal/pkg/migrations"
+	paymentsPkg "github.com/pocj8ur4in/boilerplate-go/internal/pkg/payments"
+	querycachePkg "github.com/pocj8ur4in/boilerplate-go/internal/pkg/querycache"
+	readonlyPkg "github.com/pocj8ur4in/boilerplate-go/internal/pkg/readonly"
 	redisPkg "github.com/pocj8ur4in/boilerplate-go/internal/pkg/redis"
+	renderPkg "github.com/pocj8ur4in/boilerplate-go/internal/pkg/render"
+	retentionPkg "github.com/pocj8ur4in/boilerplate-go/internal/pkg/retention"
+	samlPkg "github.com/pocj8ur4in/boilerplate-go/internal/pkg/saml"
+	schedulerPkg "github.com/pocj8ur4in/boilerplate-go/internal/pkg/scheduler"
+	settingsPkg "github.com/pocj8ur4in/boilerplate-go/internal/pkg/settings"
+	signedurlPkg "github.com/pocj8ur4in/boilerplate-go/internal/pkg/signedurl"
+	ssePkg "github.com/pocj8ur4in/boilerplate-go/internal/pkg/sse"
+	tracingPkg "github.com/pocj8ur4in/boilerplate-go/internal/pkg/tracing"
+	usagePkg "github.com/pocj8ur4in/boilerplate-go/internal/pkg/usage"
+	userPkg "github.com/pocj8ur4in/boilerplate-go/internal/pkg/user"
+	websocketPkg "github.com/pocj8ur4in/boilerplate-go/internal/pkg/websocket"
 )
 
-// New creates a new application.
+// New creates a new application with the optional subsystems included by the modules config.
 func New() *fx.App {
-	return fx.New(
+	config, err := loadConfig()
+	if err != nil {
+		return fx.New(fx.Error(err))
+	}
+
+	return fx.New(options(config))
+}
+
+// loadConfig loads the config file before the graph is built, so that the modules config decides which optional
+// subsystems are included and the graph is provided the same config.
+func loadConfig() (*configPkg.Config, error) {
+	config, err := configPkg.LoadFromFile()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load config: %w", err)
+	}
+
+	return config, nil
+}
+
+// options returns modules and invocations of the application.
+func options(config *configPkg.Config) fx.Option {
+	return fx.Options(
 		// modules
-		configPkg.NewModule(),
+		modules(config),
+
+		// config reloaders
+		fx.Provide(
+			fx.Annotate(loggerReloader, fx.ResultTags(`group:"config_reloaders"`)),
+			fx.Annotate(serverReloader, fx.ResultTags(`group:"config_reloaders"`)),
+			fx.Annotate(readOnlyReloader, fx.ResultTags(`group:"config_reloaders"`)),
+		),
+
+		// metrics of shared services
+		fx.Invoke(registerCollectors),
+
+		// refresh anomalies in the audit log
+		fx.Invoke(registerAuditHooks),
+
+		// dependency graph on the debug endpoint
+		fx.Invoke(registerGraph),
+
+		// lifecycle hooks
+		fx.Invoke(registerHooks),
+	)
+}
+
+// modules returns modules of the application for the loaded config, excluding optional subsystems disabled by its
+// modules config.
+func modules(config *configPkg.Config) fx.Option {
+	enabled := config.Modules
+	if enabled == nil {
+		enabled = &configPkg.ModulesConfig{}
+		enabled.SetDefault()
+	}
+
+	return fx.Options(
+		configPkg.NewModuleWith(config),
 		loggerPkg.NewModule(),
+		tracingPkg.NewModule(),
+		healthPkg.NewModule(),
 		databasePkg.NewModule(),
+		migrationsPkg.NewModule(),
 		redisPkg.NewModule(),
+		cachePkg.NewModule(),
+		querycachePkg.NewModule(),
 		jwtPkg.NewModule(),
+		renderPkg.NewModule(),
+		optionalModule[settingsPkg.Settings](enabled.Settings, settingsPkg.NewModule()),
+		apikeyPkg.NewModule(),
+		httpclientPkg.NewModule(),
+		optionalModule[paymentsPkg.Payments](enabled.Payments, paymentsPkg.NewModule()),
+		signedurlPkg.NewModule(),
+		optionalModule[imagesPkg.Images](enabled.Images, imagesPkg.NewModule()),
+		userPkg.NewModule(),
+		authzPkg.NewModule(),
+		readonlyPkg.NewModule(),
+		optionalModule[usagePkg.Recorder](enabled.Usage, usagePkg.NewModule()),
+		optionalModule[meteringPkg.Meter](enabled.Metering, meteringPkg.NewModule()),
+		optionalModule[auditPkg.Auditor](enabled.Audit, auditPkg.NewModule(), fx.Provide(auditPkg.ProvideLogger)),
+		optionalModule[samlPkg.ServiceProvider](enabled.SAML, samlPkg.NewModule()),
+		optionalModule[ldapPkg.Directory](enabled.LDAP, ldapPkg.NewModule()),
+		optionalModule[retentionPkg.Retention](enabled.Retention, retentionPkg.NewModule()),
+		optionalModule[jobsPkg.Jobs](enabled.Jobs, jobsPkg.NewModule()),
+		optionalModule[schedulerPkg.Scheduler](enabled.Scheduler, schedulerPkg.NewModule()),
+		optionalModule[websocketPkg.Hub](enabled.WebSocket, websocketPkg.NewModule()),
+		optionalModule[ssePkg.Broker](enabled.SSE, ssePkg.NewModule()),
 		handlerPkg.NewModule(),
 		serverPkg.NewModule(),
-
-		// lifecycle hooks
-		fx.Invoke(registerHooks),
+		optionalModule[grpcserverPkg.Server](enabled.GRPC, grpcserverPkg.NewModule()),
 	)
 }
 
-// registerHooks registers lifecycle hooks for the application.
+// optionalModule returns the module if it is enabled or not configured, or provides a nil service in its place
+// otherwise, so that dependents skip the excluded subsystem as they do a disabled one. Other services the module
+// derives from the nil service are provided by the excluded options.
+func optionalModule[T any](enabled *bool, module fx.Option, excluded ...fx.Option) fx.Option {
+	if enabled == nil || *enabled {
+		return module
+	}
+
+	return fx.Options(append([]fx.Option{fx.Provide(func() *T { return nil })}, excluded...)...)
+}
+
+// loggerReloader reloads the logger level when the config file changes.
+func loggerReloader(log *loggerPkg.Logger) configPkg.Reloader {
+	return configPkg.NewReloader("logger", func(config *configPkg.Config) *loggerPkg.Config {
+		return config.Logger
+	}, log)
+}
+
+// serverReloader reloads rate limits and CORS of the server when the config file changes.
+func serverReloader(server *serverPkg.Server) configPkg.Reloader {
+	return configPkg.NewReloader("server", func(config *configPkg.Config) *serverPkg.Config {
+		return config.Server
+	}, server)
+}
+
+// readOnlyReloader reloads read-only mode when the config file changes.
+func readOnlyReloader(readOnly *readonlyPkg.ReadOnly) configPkg.Reloader {
+	return configPkg.NewReloader("read_only", func(config *configPkg.Config) *readonlyPkg.Config {
+		return config.ReadOnly
+	}, readOnly)
+}
+
+// registerCollectors exposes metrics of shared services on the server metrics endpoint, skipping excluded
+// subsystems.
+func registerCollectors(
+	server *serverPkg.Server,
+	httpClient *httpclientPkg.Client,
+	queryCache *querycachePkg.QueryCache,
+	jobs *jobsPkg.Jobs,
+	retention *retentionPkg.Retention,
+	hub *websocketPkg.Hub,
+	broker *ssePkg.Broker,
+	grpcServer *grpcserverPkg.Server,
+) error {
+	// excluded subsystems are nil
+	collectors := []struct {
+		name      string
+		collector prometheus.Collector
+		included  bool
+	}{
+		{name: "http client", collector: httpClient, included: true},
+		{name: "query cache", collector: queryCache, included: true},
+		{name: "retention", collector: retention, included: retention != nil},
+		{name: "jobs", collector: jobs, included: jobs != nil},
+		{name: "websocket", collector: hub, included: hub != nil},
+		{name: "sse", collector: broker, included: broker != nil},
+		{name: "grpc", collector: grpcServer, included: grpcServer != nil},
+	}
+
+	for _, collector := range collectors {
+		if !collector.included {
+			continue
+		}
+
+		if err := server.RegisterCollector(collector.collector); err != nil {
+			return fmt.Errorf("register %s metrics: %w", collector.name, err)
+		}
+	}
+
+	return nil
+}
+
+// registerAuditHooks logs refresh anomalies detected by the JWT service as audit events.
+func registerAuditHooks(jwtService *jwtPkg.JWT, auditor *auditPkg.Auditor) {
+	if auditor.Enabled() {
+		jwtService.OnRefreshAnomaly(auditor.RefreshAnomaly)
+	}
+}
+
+// registerHooks registers lifecycle hooks for the application, optional subsystems are nil when excluded.
 func registerHooks(
 	lifecycle fx.Lifecycle,
+	auditor *auditPkg.Auditor,
+	broker *ssePkg.Broker,
 	dbConn *databasePkg.DB,
+	directory *ldapPkg.Directory,
+	grpcServer *grpcserverPkg.Server,
+	jobs *jobsPkg.Jobs,
 	log *loggerPkg.Logger,
+	meter *meteringPkg.Meter,
+	queryCache *querycachePkg.QueryCache,
 	redisConn *redisPkg.Redis,
+	retention *retentionPkg.Retention,
+	scheduler *schedulerPkg.Scheduler,
 	server *serverPkg.Server,
+	settings *settingsPkg.Settings,
+	tracing *tracingPkg.Tracing,
+	usage *usagePkg.Recorder,
+	watcher *configPkg.Watcher,
 ) {
 	lifecycle.Append(fx.Hook{
 		OnStart: func(_ context.Context) error {
 			log.Info().Msg("starting application...")
 
+			// watch config file, the application runs with the startup config if watching fails
+			if err := watcher.Start(); err != nil {
+				log.Error().Err(err).Msg("failed to watch config file")
+			}
+
+			// flush usage of tenants periodically
+			if usage != nil {
+				usage.Start()
+			}
+
+			// write billable events in batches
+			if meter != nil {
+				meter.Start()
+			}
+
+			// write audit events in batches
+			if auditor != nil {
+				auditor.Start()
+			}
+
+			// delete expired rows periodically
+			if retention != nil {
+				retention.Start()
+			}
+
+			// process background jobs
+			if jobs != nil {
+				jobs.Start()
+			}
+
+			// run recurring tasks on their schedules
+			if scheduler != nil {
+				scheduler.Start()
+			}
+
+			// start grpc server, it serves in the background
+			if grpcServer != nil {
+				if err := grpcServer.Start(); err != nil {
+					return fmt.Errorf("start grpc server: %w", err)
+				}
+			}
+
 			// start server in a goroutine
 			go func() {
 				if err := server.Run(); err != nil {
@@ -57,30 +296,133 @@ func registerHooks(
 		OnStop: func(ctx context.Context) error {
 			log.Info().Msg("shutting down application...")
 
-			// shutdown server
-			if err := server.Shutdown(ctx); err != nil {
+			// stop watching config file
+			if err := watcher.Stop(); err != nil {
+				log.Error().Err(err).Msg("failed to stop config watcher")
+			}
+
+			// drain in-flight requests within the shutdown timeout
+			shutdownCtx, cancel := context.WithTimeout(ctx, server.ShutdownTimeout())
+			defer cancel()
+
+			// shutdown grpc server together with server, calls canceled by the deadline fail on clients
+			grpcShutdown := make(chan error, 1)
+
+			go func() {
+				if grpcServer == nil {
+					grpcShutdown <- nil
+
+					return
+				}
+
+				grpcShutdown <- grpcServer.Shutdown(shutdownCtx)
+			}()
+
+			// errors are collected, so that a failed step does not skip flushing and closing the rest
+			var errs []error
+
+			// shutdown server, requests not drained by the deadline are canceled
+			if err := server.Shutdown(shutdownCtx); err != nil {
 				log.Error().Err(err).Msg("failed to shutdown server")
 
-				return fmt.Errorf("shutdown server: %w", err)
+				errs = append(errs, fmt.Errorf("shutdown server: %w", err))
+			}
+
+			if err := <-grpcShutdown; err != nil {
+				log.Error().Err(err).Msg("failed to shutdown grpc server")
+			}
+
+			// finish running recurring tasks, tasks canceled by the deadline run again on their next schedule
+			if scheduler != nil {
+				scheduler.Stop(ctx)
+			}
+
+			// finish jobs being processed, jobs canceled by the deadline are re-enqueued with their checkpoints
+			if jobs != nil {
+				jobs.Stop(ctx)
+			}
+
+			// stop deleting expired rows before closing database
+			if retention != nil {
+				retention.Stop()
+			}
+
+			// flush usage recorded by drained requests before closing database
+			if usage != nil {
+				if err := usage.Stop(ctx); err != nil {
+					log.Error().Err(err).Msg("failed to flush usage")
+				}
+			}
+
+			// write billable events recorded by drained requests, events failing to be written are lost
+			if meter != nil {
+				if err := meter.Stop(ctx); err != nil {
+					log.Error().Err(err).Int("pending", meter.Pending()).Msg("failed to flush metering events")
+				}
+			}
+
+			// write audit events logged by drained requests, events failing to be written are lost
+			if auditor != nil {
+				if err := auditor.Stop(ctx); err != nil {
+					log.Error().Err(err).Int("pending", auditor.Pending()).Msg("failed to flush audit events")
+				}
+			}
+
+			// close the event broker before redis, it holds a pub/sub connection
+			if broker != nil {
+				if err := broker.Close(); err != nil {
+					log.Error().Err(err).Msg("failed to close sse broker")
+				}
+			}
+
+			// close idle directory connections, logins of drained requests released theirs
+			if directory != nil {
+				directory.Close()
+			}
+
+			// close the query cache before redis, it holds a pub/sub connection
+			if err := queryCache.Close(); err != nil {
+				log.Error().Err(err).Msg("failed to close query cache")
+			}
+
+			// close settings before redis, it holds a pub/sub connection
+			if settings != nil {
+				if err := settings.Close(); err != nil {
+					log.Error().Err(err).Msg("failed to close settings")
+
+					errs = append(errs, fmt.Errorf("close settings: %w", err))
+				}
 			}
 
 			// close database
 			if err := dbConn.Close(); err != nil {
 				log.Error().Err(err).Msg("failed to close database")
 
-				return fmt.Errorf("close database: %w", err)
+				errs = append(errs, fmt.Errorf("close database: %w", err))
 			}
 
 			// close redis
 			if err := redisConn.Close(); err != nil {
 				log.Error().Err(err).Msg("failed to close redis")
 
-				return fmt.Errorf("close redis: %w", err)
+				errs = append(errs, fmt.Errorf("close redis: %w", err))
+			}
+
+			// export spans of the shutdown last
+			if err := tracing.Shutdown(ctx); err != nil {
+				log.Error().Err(err).Msg("failed to shutdown tracing")
+
+				errs = append(errs, fmt.Errorf("shutdown tracing: %w", err))
 			}
 
 			log.Info().Msg("application stopped")
 
-			return nil
+			// close log file after the last log
+			if err := log.Close(); err != nil {
+				errs = append(errs, fmt.Errorf("close logger: %w", err))
+			}
+
+			return errors.Join(errs...)
 		},
 	})
 }
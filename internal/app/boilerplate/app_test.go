@@ -3,8 +3,10 @@ package app
 import (
 	"context"
 	"database/sql"
+	"net"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
@@ -13,11 +15,27 @@ import (
 	"go.uber.org/fx"
 
 	configPkg "github.com/pocj8ur4in/boilerplate-go/internal/app/boilerplate/config"
+	grpcserverPkg "github.com/pocj8ur4in/boilerplate-go/internal/app/boilerplate/grpcserver"
 	serverPkg "github.com/pocj8ur4in/boilerplate-go/internal/app/boilerplate/server"
+	apikeyPkg "github.com/pocj8ur4in/boilerplate-go/internal/pkg/apikey"
+	auditPkg "github.com/pocj8ur4in/boilerplate-go/internal/pkg/audit"
 	databasePkg "github.com/pocj8ur4in/boilerplate-go/internal/pkg/database"
+	healthPkg "github.com/pocj8ur4in/boilerplate-go/internal/pkg/health"
+	httpclientPkg "github.com/pocj8ur4in/boilerplate-go/internal/pkg/httpclient"
+	jobsPkg "github.com/pocj8ur4in/boilerplate-go/internal/pkg/jobs"
 	jwtPkg "github.com/pocj8ur4in/boilerplate-go/internal/pkg/jwt"
+	ldapPkg "github.com/pocj8ur4in/boilerplate-go/internal/pkg/ldap"
 	loggerPkg "github.com/pocj8ur4in/boilerplate-go/internal/pkg/logger"
+	meteringPkg "github.com/pocj8ur4in/boilerplate-go/internal/pkg/metering"
+	querycachePkg "github.com/pocj8ur4in/boilerplate-go/internal/pkg/querycache"
 	redisPkg "github.com/pocj8ur4in/boilerplate-go/internal/pkg/redis"
+	retentionPkg "github.com/pocj8ur4in/boilerplate-go/internal/pkg/retention"
+	schedulerPkg "github.com/pocj8ur4in/boilerplate-go/internal/pkg/scheduler"
+	settingsPkg "github.com/pocj8ur4in/boilerplate-go/internal/pkg/settings"
+	ssePkg "github.com/pocj8ur4in/boilerplate-go/internal/pkg/sse"
+	tracingPkg "github.com/pocj8ur4in/boilerplate-go/internal/pkg/tracing"
+	usagePkg "github.com/pocj8ur4in/boilerplate-go/internal/pkg/usage"
+	userPkg "github.com/pocj8ur4in/boilerplate-go/internal/pkg/user"
 )
 
 const (
@@ -117,6 +135,49 @@ func TestStartAndStop(t *testing.T) {
 
 		startAndStopApp(t, app)
 	})
+
+	t.Run("close subsystems when server shutdown times out", func(t *testing.T) {
+		listener, err := net.Listen("tcp", "127.0.0.1:0")
+		require.NoError(t, err)
+
+		addr := listener.Addr().String()
+		require.NoError(t, listener.Close())
+
+		content := strings.Replace(defaultConfigContent, `"port": 38080`,
+			`"port": 38080, "shutdown_timeout": 0, "listeners": [{"addr": "`+addr+`"}]`, 1)
+		beforeTest(t, &content)
+
+		config, err := loadConfig()
+		require.NoError(t, err)
+
+		var dbConn *databasePkg.DB
+
+		app := fx.New(options(config), fx.Populate(&dbConn), fx.NopLogger)
+		require.NoError(t, app.Err())
+
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		require.NoError(t, app.Start(ctx))
+
+		// a connection without a complete request is not idle, so it is not drained by the deadline
+		var conn net.Conn
+
+		require.Eventually(t, func() bool {
+			conn, err = net.Dial("tcp", addr)
+
+			return err == nil
+		}, time.Second, 10*time.Millisecond)
+
+		defer func() { _ = conn.Close() }()
+
+		err = app.Stop(ctx)
+		require.ErrorIs(t, err, context.DeadlineExceeded)
+		assert.ErrorContains(t, err, "shutdown server")
+
+		// the database is closed after the failed shutdown
+		require.Error(t, dbConn.PingContext(ctx))
+	})
 }
 
 func TestRegisterHooks(t *testing.T) {
@@ -149,11 +210,211 @@ func TestRegisterHooks(t *testing.T) {
 		// create minimal server
 		server := &serverPkg.Server{}
 
-		registerHooks(lifecycle, dbConn, log, redisConn, server)
+		// create minimal watcher (watches the working directory)
+		watcher := &configPkg.Watcher{}
+
+		// create minimal settings
+		settings := &settingsPkg.Settings{}
+
+		// create disabled tracing
+		tracing := &tracingPkg.Tracing{}
+
+		// create disabled usage recorder
+		usage := usagePkg.NewWithQuerier(nil, nil, nil, log)
+
+		// create disabled meter
+		meter := meteringPkg.NewWithSink(nil, nil, nil, log)
+
+		// create disabled auditor
+		auditor := auditPkg.NewWithQuerier(nil, nil, nil, log)
+
+		// create disabled query cache
+		queryCache, err := querycachePkg.New(nil, nil, nil, log)
+		require.NoError(t, err)
+
+		// create disabled retention
+		retention, err := retentionPkg.NewWithDB(nil, nil, nil, log)
+		require.NoError(t, err)
+
+		// create disabled jobs
+		jobs, err := jobsPkg.New(nil, nil, nil, log)
+		require.NoError(t, err)
+
+		// create disabled scheduler
+		scheduler, err := schedulerPkg.New(&schedulerPkg.Config{Enabled: &[]bool{false}[0]}, nil, log)
+		require.NoError(t, err)
+
+		// create disabled sse broker
+		broker, err := ssePkg.New(&ssePkg.Config{Enabled: &[]bool{false}[0]}, nil, log)
+		require.NoError(t, err)
+
+		// create disabled grpc server
+		grpcServer := grpcserverPkg.New(nil, log, nil)
+
+		// create disabled directory
+		directory, err := ldapPkg.New(nil, nil, log)
+		require.NoError(t, err)
+
+		registerHooks(
+			lifecycle, auditor, broker, dbConn, directory, grpcServer, jobs, log, meter, queryCache, redisConn, retention,
+			scheduler, server, settings, tracing, usage, watcher,
+		)
 
 		require.True(t, hookRegistered, "lifecycle hook should be registered")
 		require.True(t, onStartCalled, "OnStart should be called successfully")
 	})
+
+	t.Run("skip excluded subsystems", func(t *testing.T) {
+		t.Parallel()
+
+		var hooks []fx.Hook
+
+		lifecycle := &mockLifecycle{
+			appendFunc: func(hook fx.Hook) {
+				hooks = append(hooks, hook)
+			},
+		}
+
+		log, err := loggerPkg.New(&loggerPkg.Config{Level: &[]string{"info"}[0]})
+		require.NoError(t, err)
+
+		usage := usagePkg.NewWithQuerier(nil, nil, nil, log)
+		meter := meteringPkg.NewWithSink(nil, nil, nil, log)
+		auditor := auditPkg.NewWithQuerier(nil, nil, nil, log)
+
+		registerHooks(
+			lifecycle, auditor, nil, &databasePkg.DB{DB: &sql.DB{}}, &ldapPkg.Directory{}, nil, nil, log, meter,
+			&querycachePkg.QueryCache{}, &redisPkg.Redis{}, nil, nil, &serverPkg.Server{}, &settingsPkg.Settings{},
+			&tracingPkg.Tracing{}, usage, &configPkg.Watcher{},
+		)
+
+		require.Len(t, hooks, 1)
+		require.NoError(t, hooks[0].OnStart(context.Background()))
+	})
+}
+
+func TestRegisterAuditHooks(t *testing.T) {
+	t.Parallel()
+
+	log, err := loggerPkg.New(&loggerPkg.Config{Level: &[]string{"info"}[0]})
+	require.NoError(t, err)
+
+	jwtService, err := jwtPkg.New(&jwtPkg.Config{RefreshAlertThreshold: &[]int{1}[0]})
+	require.NoError(t, err)
+
+	auditor := auditPkg.NewWithQuerier(&auditPkg.Config{Enabled: &[]bool{true}[0]}, nil, nil, log)
+
+	registerAuditHooks(jwtService, auditor)
+
+	refreshToken, err := jwtService.GenerateRefreshToken("user-1", "user@example.com", "user")
+	require.NoError(t, err)
+
+	for range 2 {
+		_, err := jwtService.RefreshAccessToken(*refreshToken)
+		require.NoError(t, err)
+	}
+
+	assert.Equal(t, 1, auditor.Pending())
+}
+
+//nolint:paralleltest // Cannot run in parallel due to t.Setenv usage
+func TestNewWithExcludedModules(t *testing.T) {
+	configContent := defaultConfigContent[:len(defaultConfigContent)-1] + `,
+			"modules": {
+				"grpc": false,
+				"jobs": false,
+				"scheduler": false,
+				"retention": false,
+				"websocket": false,
+				"sse": false,
+				"payments": false,
+				"saml": false,
+				"ldap": false,
+				"audit": false,
+				"metering": false,
+				"images": false,
+				"settings": false,
+				"usage": false
+			}
+		}`
+
+	t.Run("start and stop application without optional subsystems", func(t *testing.T) {
+		beforeTest(t, &configContent)
+
+		app := New()
+		require.NoError(t, app.Err())
+
+		startAndStopApp(t, app)
+	})
+
+	t.Run("provide nil services of excluded subsystems", func(t *testing.T) {
+		beforeTest(t, &configContent)
+
+		config, err := loadConfig()
+		require.NoError(t, err)
+
+		var (
+			jobs       *jobsPkg.Jobs
+			grpcServer *grpcserverPkg.Server
+			auditor    *auditPkg.Auditor
+			auditLog   auditPkg.Logger
+			directory  *ldapPkg.Directory
+			settings   *settingsPkg.Settings
+			server     *serverPkg.Server
+		)
+
+		fxApp := fx.New(
+			fx.NopLogger,
+			modules(config),
+			fx.Populate(&jobs, &grpcServer, &auditor, &auditLog, &directory, &settings, &server),
+		)
+		require.NoError(t, fxApp.Err())
+
+		assert.Nil(t, jobs)
+		assert.Nil(t, grpcServer)
+		assert.Nil(t, auditor)
+		assert.Nil(t, directory)
+		assert.Nil(t, settings)
+		assert.Nil(t, auditLog)
+		assert.NotNil(t, server)
+	})
+
+	t.Run("return error by using invalid config path", func(t *testing.T) {
+		t.Setenv("CONFIG_PATH", "/non/existent/path/config.json")
+
+		err := New().Err()
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "failed to load config")
+	})
+}
+
+func TestOptionalModule(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		enabled  *bool
+		included bool
+	}{
+		{name: "include enabled module", enabled: &[]bool{true}[0], included: true},
+		{name: "include module without flag", enabled: nil, included: true},
+		{name: "provide nil service of excluded module", enabled: &[]bool{false}[0], included: false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			var service *string
+
+			module := fx.Provide(func() *string { return &[]string{"service"}[0] })
+
+			fxApp := fx.New(fx.NopLogger, optionalModule[string](test.enabled, module), fx.Populate(&service))
+			require.NoError(t, fxApp.Err())
+
+			assert.Equal(t, test.included, service != nil)
+		})
+	}
 }
 
 // mockLifecycle is a mock implementation of fx.Lifecycle.
@@ -177,9 +438,15 @@ func TestNewReturnErrors(t *testing.T) {
 			fx.NopLogger,
 			configPkg.NewModule(),
 			loggerPkg.NewModule(),
+			tracingPkg.NewModule(),
+			healthPkg.NewModule(),
 			databasePkg.NewModule(),
 			jwtPkg.NewModule(),
 			redisPkg.NewModule(),
+			settingsPkg.NewModule(),
+			apikeyPkg.NewModule(),
+			httpclientPkg.NewModule(),
+			userPkg.NewModule(),
 			serverPkg.NewModule(),
 			fx.Invoke(registerHooks),
 		)
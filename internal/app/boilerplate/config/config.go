@@ -9,15 +9,43 @@ import (
 
 	"go.uber.org/fx"
 
+	"github.com/pocj8ur4in/boilerplate-go/internal/app/boilerplate/grpcserver"
 	"github.com/pocj8ur4in/boilerplate-go/internal/app/boilerplate/server"
+	"github.com/pocj8ur4in/boilerplate-go/internal/app/boilerplate/server/handler"
+	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/apikey"
+	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/audit"
+	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/authz"
+	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/cache"
 	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/database"
+	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/httpclient"
+	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/images"
+	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/jobs"
 	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/jwt"
+	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/ldap"
 	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/logger"
+	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/metering"
+	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/payments"
+	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/querycache"
+	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/readonly"
 	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/redis"
+	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/render"
+	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/retention"
+	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/saml"
+	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/scheduler"
+	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/settings"
+	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/signedurl"
+	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/sse"
+	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/tracing"
+	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/usage"
+	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/user"
+	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/websocket"
 )
 
 // Config represents the configuration for the app.
 type Config struct {
+	// DevMode is whether the configuration is relaxed for local development, see applyDevMode.
+	DevMode *bool `json:"dev_mode"`
+
 	// Logger provides logger configuration.
 	Logger *logger.Config `json:"logger"`
 
@@ -30,12 +58,95 @@ type Config struct {
 	// Redis provides redis configuration.
 	Redis *redis.Config `json:"redis"`
 
+	// Render provides render configuration.
+	Render *render.Config `json:"render"`
+
+	// Handler provides handler configuration.
+	Handler *handler.Config `json:"handler"`
+
 	// Server provides server configuration.
 	Server *server.Config `json:"server"`
+
+	// Settings provides settings configuration.
+	Settings *settings.Config `json:"settings"`
+
+	// APIKey provides API key configuration.
+	APIKey *apikey.Config `json:"api_key"`
+
+	// HTTPClient provides HTTP client configuration.
+	HTTPClient *httpclient.Config `json:"http_client"`
+
+	// User provides user configuration.
+	User *user.Config `json:"user"`
+
+	// Authz provides authorization configuration.
+	Authz *authz.Config `json:"authz"`
+
+	// ReadOnly provides read-only mode configuration.
+	ReadOnly *readonly.Config `json:"read_only"`
+
+	// Tracing provides tracing configuration.
+	Tracing *tracing.Config `json:"tracing"`
+
+	// Usage provides usage reporting configuration.
+	Usage *usage.Config `json:"usage"`
+
+	// Metering provides metering configuration.
+	Metering *metering.Config `json:"metering"`
+
+	// Audit provides audit logging configuration.
+	Audit *audit.Config `json:"audit"`
+
+	// SAML provides SAML login configuration.
+	SAML *saml.Config `json:"saml"`
+
+	// LDAP provides LDAP login configuration.
+	LDAP *ldap.Config `json:"ldap"`
+
+	// Payments provides payments configuration.
+	Payments *payments.Config `json:"payments"`
+
+	// SignedURL provides signed URL configuration.
+	SignedURL *signedurl.Config `json:"signed_url"`
+
+	// Images provides images configuration.
+	Images *images.Config `json:"images"`
+
+	// Retention provides data retention configuration.
+	Retention *retention.Config `json:"retention"`
+
+	// Cache provides cache configuration.
+	Cache *cache.Config `json:"cache"`
+
+	// QueryCache provides query result cache configuration.
+	QueryCache *querycache.Config `json:"query_cache"`
+
+	// Jobs provides background jobs configuration.
+	Jobs *jobs.Config `json:"jobs"`
+
+	// Scheduler provides scheduler configuration.
+	Scheduler *scheduler.Config `json:"scheduler"`
+
+	// WebSocket provides websocket configuration.
+	WebSocket *websocket.Config `json:"websocket"`
+
+	// SSE provides server-sent events configuration.
+	SSE *sse.Config `json:"sse"`
+
+	// GRPC provides gRPC server configuration.
+	GRPC *grpcserver.Config `json:"grpc"`
+
+	// Modules provides which optional subsystems are included in the application.
+	Modules *ModulesConfig `json:"modules"`
 }
 
 // SetDefault sets the default values.
 func (c *Config) SetDefault() {
+	// set dev mode
+	if c.DevMode == nil {
+		c.DevMode = &[]bool{false}[0]
+	}
+
 	// set logger
 	if c.Logger == nil {
 		c.Logger = &logger.Config{}
@@ -64,24 +175,246 @@ func (c *Config) SetDefault() {
 
 	c.Redis.SetDefault()
 
+	// set render
+	if c.Render == nil {
+		c.Render = &render.Config{}
+	}
+
+	c.Render.SetDefault()
+
+	// set handler
+	if c.Handler == nil {
+		c.Handler = &handler.Config{}
+	}
+
+	c.Handler.SetDefault()
+
 	// set server
 	if c.Server == nil {
 		c.Server = &server.Config{}
 	}
 
 	c.Server.SetDefault()
+
+	// set settings
+	if c.Settings == nil {
+		c.Settings = &settings.Config{}
+	}
+
+	c.Settings.SetDefault()
+
+	// set api key
+	if c.APIKey == nil {
+		c.APIKey = &apikey.Config{}
+	}
+
+	c.APIKey.SetDefault()
+
+	// set http client
+	if c.HTTPClient == nil {
+		c.HTTPClient = &httpclient.Config{}
+	}
+
+	c.HTTPClient.SetDefault()
+
+	if c.User == nil {
+		c.User = &user.Config{}
+	}
+
+	c.User.SetDefault()
+
+	// set authorization
+	if c.Authz == nil {
+		c.Authz = &authz.Config{}
+	}
+
+	c.Authz.SetDefault()
+
+	// set read-only mode
+	if c.ReadOnly == nil {
+		c.ReadOnly = &readonly.Config{}
+	}
+
+	c.ReadOnly.SetDefault()
+
+	// set tracing
+	if c.Tracing == nil {
+		c.Tracing = &tracing.Config{}
+	}
+
+	c.Tracing.SetDefault()
+
+	// set usage reporting
+	if c.Usage == nil {
+		c.Usage = &usage.Config{}
+	}
+
+	c.Usage.SetDefault()
+
+	// set metering
+	if c.Metering == nil {
+		c.Metering = &metering.Config{}
+	}
+
+	c.Metering.SetDefault()
+
+	// set audit
+	if c.Audit == nil {
+		c.Audit = &audit.Config{}
+	}
+
+	c.Audit.SetDefault()
+
+	// set saml
+	if c.SAML == nil {
+		c.SAML = &saml.Config{}
+	}
+
+	c.SAML.SetDefault()
+
+	// set ldap
+	if c.LDAP == nil {
+		c.LDAP = &ldap.Config{}
+	}
+
+	c.LDAP.SetDefault()
+
+	// set payments
+	if c.Payments == nil {
+		c.Payments = &payments.Config{}
+	}
+
+	c.Payments.SetDefault()
+
+	// set signed urls
+	if c.SignedURL == nil {
+		c.SignedURL = &signedurl.Config{}
+	}
+
+	c.SignedURL.SetDefault()
+
+	// set images
+	if c.Images == nil {
+		c.Images = &images.Config{}
+	}
+
+	c.Images.SetDefault()
+
+	// set retention
+	if c.Retention == nil {
+		c.Retention = &retention.Config{}
+	}
+
+	c.Retention.SetDefault()
+
+	// set cache
+	if c.Cache == nil {
+		c.Cache = &cache.Config{}
+	}
+
+	c.Cache.SetDefault()
+
+	// set query cache
+	if c.QueryCache == nil {
+		c.QueryCache = &querycache.Config{}
+	}
+
+	c.QueryCache.SetDefault()
+
+	// set jobs
+	if c.Jobs == nil {
+		c.Jobs = &jobs.Config{}
+	}
+
+	c.Jobs.SetDefault()
+
+	// set scheduler
+	if c.Scheduler == nil {
+		c.Scheduler = &scheduler.Config{}
+	}
+
+	c.Scheduler.SetDefault()
+
+	// set websocket
+	if c.WebSocket == nil {
+		c.WebSocket = &websocket.Config{}
+	}
+
+	c.WebSocket.SetDefault()
+
+	// set sse
+	if c.SSE == nil {
+		c.SSE = &sse.Config{}
+	}
+
+	c.SSE.SetDefault()
+
+	// set grpc
+	if c.GRPC == nil {
+		c.GRPC = &grpcserver.Config{}
+	}
+
+	c.GRPC.SetDefault()
+
+	// set modules
+	if c.Modules == nil {
+		c.Modules = &ModulesConfig{}
+	}
+
+	c.Modules.SetDefault()
+
+	// relax sections for local development
+	if *c.DevMode {
+		c.applyDevMode()
+	}
 }
 
-// NewModule provides module for config.
+// NewModule provides module for config loaded from file.
 func NewModule() fx.Option {
+	return newModule(fx.Provide(LoadFromFile))
+}
+
+// NewModuleWith provides module for the config already loaded, so that the file is not read again.
+func NewModuleWith(config *Config) fx.Option {
+	return newModule(fx.Supply(config))
+}
+
+// newModule provides module for config with the option providing the config itself.
+func newModule(provideConfig fx.Option) fx.Option {
 	return fx.Module("config",
+		provideConfig,
 		fx.Provide(
-			LoadFromFile,
+			NewWatcher,
 			ProvideLoggerConfig,
 			ProvideDatabaseConfig,
 			ProvideJWTConfig,
 			ProvideRedisConfig,
+			ProvideRenderConfig,
+			ProvideHandlerConfig,
 			ProvideServerConfig,
+			ProvideSettingsConfig,
+			ProvideAPIKeyConfig,
+			ProvideHTTPClientConfig,
+			ProvideUserConfig,
+			ProvideAuthzConfig,
+			ProvideReadOnlyConfig,
+			ProvideTracingConfig,
+			ProvideUsageConfig,
+			ProvideMeteringConfig,
+			ProvideAuditConfig,
+			ProvideSAMLConfig,
+			ProvideLDAPConfig,
+			ProvidePaymentsConfig,
+			ProvideSignedURLConfig,
+			ProvideImagesConfig,
+			ProvideRetentionConfig,
+			ProvideCacheConfig,
+			ProvideQueryCacheConfig,
+			ProvideJobsConfig,
+			ProvideSchedulerConfig,
+			ProvideWebSocketConfig,
+			ProvideSSEConfig,
+			ProvideGRPCConfig,
 		),
 	)
 }
@@ -92,9 +425,24 @@ func New() *Config {
 }
 
 // LoadFromFile loads the configuration from file.
+// Values are applied in order of precedence: defaults, then the file, then environment variables (see ApplyEnv).
 func LoadFromFile() (*Config, error) {
-	cfg := New()
+	configPath, err := resolveConfigPath()
+	if err != nil {
+		return nil, err
+	}
 
+	// read file
+	content, err := os.ReadFile(configPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read file: %w", err)
+	}
+
+	return parse(content)
+}
+
+// resolveConfigPath resolves the config file path to an absolute path.
+func resolveConfigPath() (string, error) {
 	configPath := getConfigPath()
 
 	// clean and validate config path
@@ -103,23 +451,41 @@ func LoadFromFile() (*Config, error) {
 	if !filepath.IsAbs(configPath) {
 		wd, err := os.Getwd()
 		if err != nil {
-			return nil, fmt.Errorf("failed to get working directory: %w", err)
+			return "", fmt.Errorf("failed to get working directory: %w", err)
 		}
 
 		configPath = filepath.Join(wd, configPath)
 	}
 
-	// read file
-	content, err := os.ReadFile(configPath)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read file: %w", err)
+	return configPath, nil
+}
+
+// parse parses the content of a config file, decrypting it when encrypted.
+func parse(content []byte) (*Config, error) {
+	cfg := New()
+
+	// decrypt encrypted config
+	if IsEncrypted(content) {
+		key, err := LoadEncryptionKey()
+		if err != nil {
+			return nil, err
+		}
+
+		if content, err = Decrypt(content, key); err != nil {
+			return nil, err
+		}
 	}
 
 	// unmarshal json to config
-	if err = json.Unmarshal(content, cfg); err != nil {
+	if err := json.Unmarshal(content, cfg); err != nil {
 		return nil, fmt.Errorf("failed to unmarshal json: %w", err)
 	}
 
+	// override with environment variables
+	if err := ApplyEnv(cfg); err != nil {
+		return nil, err
+	}
+
 	// set default values
 	cfg.SetDefault()
 
@@ -156,7 +522,132 @@ func ProvideRedisConfig(config *Config) *redis.Config {
 	return config.Redis
 }
 
+// ProvideRenderConfig provides render configuration.
+func ProvideRenderConfig(config *Config) *render.Config {
+	return config.Render
+}
+
+// ProvideHandlerConfig provides handler configuration.
+func ProvideHandlerConfig(config *Config) *handler.Config {
+	return config.Handler
+}
+
 // ProvideServerConfig provides server configuration.
 func ProvideServerConfig(config *Config) *server.Config {
 	return config.Server
 }
+
+// ProvideSettingsConfig provides settings configuration.
+func ProvideSettingsConfig(config *Config) *settings.Config {
+	return config.Settings
+}
+
+// ProvideAPIKeyConfig provides API key configuration.
+func ProvideAPIKeyConfig(config *Config) *apikey.Config {
+	return config.APIKey
+}
+
+// ProvideHTTPClientConfig provides HTTP client configuration.
+func ProvideHTTPClientConfig(config *Config) *httpclient.Config {
+	return config.HTTPClient
+}
+
+// ProvideUserConfig provides user configuration.
+func ProvideUserConfig(config *Config) *user.Config {
+	return config.User
+}
+
+// ProvideAuthzConfig provides authorization configuration.
+func ProvideAuthzConfig(config *Config) *authz.Config {
+	return config.Authz
+}
+
+// ProvideReadOnlyConfig provides read-only mode configuration.
+func ProvideReadOnlyConfig(config *Config) *readonly.Config {
+	return config.ReadOnly
+}
+
+// ProvideTracingConfig provides tracing configuration.
+func ProvideTracingConfig(config *Config) *tracing.Config {
+	return config.Tracing
+}
+
+// ProvideUsageConfig provides usage reporting configuration.
+func ProvideUsageConfig(config *Config) *usage.Config {
+	return config.Usage
+}
+
+// ProvideMeteringConfig provides metering configuration.
+func ProvideMeteringConfig(config *Config) *metering.Config {
+	return config.Metering
+}
+
+// ProvideAuditConfig provides audit logging configuration.
+func ProvideAuditConfig(config *Config) *audit.Config {
+	return config.Audit
+}
+
+// ProvideSAMLConfig provides SAML login configuration.
+func ProvideSAMLConfig(config *Config) *saml.Config {
+	return config.SAML
+}
+
+// ProvideLDAPConfig provides LDAP login configuration.
+func ProvideLDAPConfig(config *Config) *ldap.Config {
+	return config.LDAP
+}
+
+// ProvidePaymentsConfig provides payments configuration.
+func ProvidePaymentsConfig(config *Config) *payments.Config {
+	return config.Payments
+}
+
+// ProvideSignedURLConfig provides signed URL configuration.
+func ProvideSignedURLConfig(config *Config) *signedurl.Config {
+	return config.SignedURL
+}
+
+// ProvideImagesConfig provides images configuration.
+func ProvideImagesConfig(config *Config) *images.Config {
+	return config.Images
+}
+
+// ProvideRetentionConfig provides data retention configuration.
+func ProvideRetentionConfig(config *Config) *retention.Config {
+	return config.Retention
+}
+
+// ProvideCacheConfig provides cache configuration.
+func ProvideCacheConfig(config *Config) *cache.Config {
+	return config.Cache
+}
+
+// ProvideQueryCacheConfig provides query cache configuration.
+func ProvideQueryCacheConfig(config *Config) *querycache.Config {
+	return config.QueryCache
+}
+
+// ProvideJobsConfig provides background jobs configuration.
+func ProvideJobsConfig(config *Config) *jobs.Config {
+	return config.Jobs
+}
+
+// ProvideSchedulerConfig provides scheduler configuration.
+func ProvideSchedulerConfig(config *Config) *scheduler.Config {
+	return config.Scheduler
+}
+
+// ProvideWebSocketConfig provides websocket configuration.
+func ProvideWebSocketConfig(config *Config) *websocket.Config {
+	return config.WebSocket
+}
+
+// ProvideSSEConfig provides server-sent events configuration.
+func ProvideSSEConfig(config *Config) *sse.Config {
+	return config.SSE
+}
+
+// ProvideGRPCConfig provides gRPC server configuration.
+func ProvideGRPCConfig(config *Config) *grpcserver.Config {
+	return config.GRPC
+}
@@ -4,15 +4,41 @@ import (
 	"os"
 	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"go.uber.org/fx"
 
+	"github.com/pocj8ur4in/boilerplate-go/internal/app/boilerplate/grpcserver"
 	"github.com/pocj8ur4in/boilerplate-go/internal/app/boilerplate/server"
+	"github.com/pocj8ur4in/boilerplate-go/internal/app/boilerplate/server/handler"
+	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/apikey"
+	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/audit"
+	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/authz"
+	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/cache"
 	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/database"
+	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/httpclient"
+	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/images"
+	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/jobs"
 	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/jwt"
+	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/ldap"
 	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/logger"
+	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/metering"
+	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/payments"
+	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/querycache"
+	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/readonly"
 	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/redis"
+	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/render"
+	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/retention"
+	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/saml"
+	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/scheduler"
+	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/settings"
+	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/signedurl"
+	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/sse"
+	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/usage"
+	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/user"
+	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/websocket"
 )
 
 func TestConfigSetDefault(t *testing.T) {
@@ -269,6 +295,24 @@ func TestNewModule(t *testing.T) {
 
 		require.NotNil(t, module)
 	})
+
+	t.Run("provide loaded config without reading file", func(t *testing.T) {
+		t.Parallel()
+
+		loaded := &Config{}
+		loaded.SetDefault()
+
+		var (
+			provided *Config
+			dbConfig *database.Config
+		)
+
+		app := fx.New(fx.NopLogger, NewModuleWith(loaded), fx.Populate(&provided, &dbConfig))
+		require.NoError(t, app.Err())
+
+		assert.Same(t, loaded, provided)
+		assert.Same(t, loaded.Database, dbConfig)
+	})
 }
 
 func TestProvideDatabaseConfig(t *testing.T) {
@@ -460,6 +504,67 @@ func TestConfigSetDefaultJWT(t *testing.T) {
 	})
 }
 
+func TestProvideRenderConfig(t *testing.T) {
+	t.Parallel()
+
+	t.Run("return render config from config", func(t *testing.T) {
+		t.Parallel()
+
+		config := &Config{
+			Render: &render.Config{
+				Layout: &[]string{"custom"}[0],
+			},
+		}
+
+		renderConfig := ProvideRenderConfig(config)
+
+		require.NotNil(t, renderConfig)
+		require.NotNil(t, renderConfig.Layout)
+		assert.Equal(t, "custom", *renderConfig.Layout)
+	})
+
+	t.Run("return nil when config.Render is nil", func(t *testing.T) {
+		t.Parallel()
+
+		config := &Config{}
+
+		renderConfig := ProvideRenderConfig(config)
+
+		assert.Nil(t, renderConfig)
+	})
+}
+
+func TestProvideHandlerConfig(t *testing.T) {
+	t.Parallel()
+
+	t.Run("return handler config from config", func(t *testing.T) {
+		t.Parallel()
+
+		config := &Config{
+			Handler: &handler.Config{
+				HealthCacheTTL: &[]int{500}[0],
+			},
+		}
+
+		handlerConfig := ProvideHandlerConfig(config)
+
+		require.NotNil(t, handlerConfig)
+		require.NotNil(t, handlerConfig.HealthCacheTTL)
+		assert.Equal(t, 500, *handlerConfig.HealthCacheTTL)
+	})
+
+	t.Run("set default handler when config.Handler is nil", func(t *testing.T) {
+		t.Parallel()
+
+		config := &Config{}
+		config.SetDefault()
+
+		require.NotNil(t, config.Handler)
+		assert.Equal(t, 1000, *config.Handler.HealthCacheTTL)
+		assert.Equal(t, 5000, *config.Handler.HealthMaxStale)
+	})
+}
+
 func TestProvideServerConfig(t *testing.T) {
 	t.Parallel()
 
@@ -493,6 +598,625 @@ func TestProvideServerConfig(t *testing.T) {
 	})
 }
 
+func TestProvideSettingsConfig(t *testing.T) {
+	t.Parallel()
+
+	t.Run("return settings config from config", func(t *testing.T) {
+		t.Parallel()
+
+		config := &Config{
+			Settings: &settings.Config{
+				Channel: &[]string{"custom:invalidate"}[0],
+			},
+		}
+
+		settingsConfig := ProvideSettingsConfig(config)
+
+		require.NotNil(t, settingsConfig)
+		assert.Equal(t, "custom:invalidate", *settingsConfig.Channel)
+	})
+
+	t.Run("set default settings when config.Settings is nil", func(t *testing.T) {
+		t.Parallel()
+
+		config := &Config{}
+		config.SetDefault()
+
+		require.NotNil(t, config.Settings)
+		assert.Equal(t, 5*time.Minute, *config.Settings.CacheTTL)
+		assert.Equal(t, "settings:invalidate", *config.Settings.Channel)
+	})
+}
+
+func TestProvideAPIKeyConfig(t *testing.T) {
+	t.Parallel()
+
+	t.Run("return api key config from config", func(t *testing.T) {
+		t.Parallel()
+
+		cacheTTL := 10 * time.Second
+		config := &Config{
+			APIKey: &apikey.Config{CacheTTL: &cacheTTL},
+		}
+
+		apiKeyConfig := ProvideAPIKeyConfig(config)
+
+		require.NotNil(t, apiKeyConfig)
+		assert.Equal(t, 10*time.Second, *apiKeyConfig.CacheTTL)
+	})
+
+	t.Run("set default api key config when config.APIKey is nil", func(t *testing.T) {
+		t.Parallel()
+
+		config := &Config{}
+		config.SetDefault()
+
+		require.NotNil(t, config.APIKey)
+		assert.Equal(t, time.Minute, *config.APIKey.CacheTTL)
+	})
+}
+
+func TestProvideHTTPClientConfig(t *testing.T) {
+	t.Parallel()
+
+	t.Run("return http client config from config", func(t *testing.T) {
+		t.Parallel()
+
+		config := &Config{
+			HTTPClient: &httpclient.Config{Timeout: &[]int{5}[0]},
+		}
+
+		httpClientConfig := ProvideHTTPClientConfig(config)
+
+		require.NotNil(t, httpClientConfig)
+		assert.Equal(t, 5, *httpClientConfig.Timeout)
+	})
+
+	t.Run("set default http client config when config.HTTPClient is nil", func(t *testing.T) {
+		t.Parallel()
+
+		config := &Config{}
+		config.SetDefault()
+
+		require.NotNil(t, config.HTTPClient)
+		assert.Equal(t, 30, *config.HTTPClient.Timeout)
+		assert.Empty(t, *config.HTTPClient.Proxy.URL)
+	})
+}
+
+func TestProvideUserConfig(t *testing.T) {
+	t.Parallel()
+
+	t.Run("return user config from config", func(t *testing.T) {
+		t.Parallel()
+
+		config := &Config{
+			User: &user.Config{MinPasswordLength: &[]int{12}[0]},
+		}
+
+		userConfig := ProvideUserConfig(config)
+
+		require.NotNil(t, userConfig)
+		assert.Equal(t, 12, *userConfig.MinPasswordLength)
+	})
+
+	t.Run("set default user config when config.User is nil", func(t *testing.T) {
+		t.Parallel()
+
+		config := &Config{}
+		config.SetDefault()
+
+		require.NotNil(t, config.User)
+		assert.Equal(t, 8, *config.User.MinPasswordLength)
+		assert.Equal(t, "user", *config.User.DefaultRole)
+	})
+}
+
+func TestProvideAuthzConfig(t *testing.T) {
+	t.Parallel()
+
+	t.Run("return authorization config from config", func(t *testing.T) {
+		t.Parallel()
+
+		config := &Config{
+			Authz: &authz.Config{CacheTTL: &[]time.Duration{time.Minute}[0]},
+		}
+
+		authzConfig := ProvideAuthzConfig(config)
+
+		require.NotNil(t, authzConfig)
+		assert.Equal(t, time.Minute, *authzConfig.CacheTTL)
+	})
+
+	t.Run("set default authorization config when config.Authz is nil", func(t *testing.T) {
+		t.Parallel()
+
+		config := &Config{}
+		config.SetDefault()
+
+		require.NotNil(t, config.Authz)
+		assert.Equal(t, 30*time.Second, *config.Authz.CacheTTL)
+	})
+}
+
+func TestProvideReadOnlyConfig(t *testing.T) {
+	t.Parallel()
+
+	t.Run("return read-only config from config", func(t *testing.T) {
+		t.Parallel()
+
+		config := &Config{
+			ReadOnly: &readonly.Config{Enabled: &[]bool{true}[0]},
+		}
+
+		readOnlyConfig := ProvideReadOnlyConfig(config)
+
+		require.NotNil(t, readOnlyConfig)
+		assert.True(t, *readOnlyConfig.Enabled)
+	})
+
+	t.Run("set default read-only config when config.ReadOnly is nil", func(t *testing.T) {
+		t.Parallel()
+
+		config := &Config{}
+		config.SetDefault()
+
+		require.NotNil(t, config.ReadOnly)
+		assert.False(t, *config.ReadOnly.Enabled)
+		assert.NotEmpty(t, *config.ReadOnly.Message)
+	})
+}
+
+func TestProvideUsageConfig(t *testing.T) {
+	t.Parallel()
+
+	t.Run("return usage config from config", func(t *testing.T) {
+		t.Parallel()
+
+		config := &Config{
+			Usage: &usage.Config{Enabled: &[]bool{true}[0]},
+		}
+
+		usageConfig := ProvideUsageConfig(config)
+
+		require.NotNil(t, usageConfig)
+		assert.True(t, *usageConfig.Enabled)
+	})
+
+	t.Run("set default usage config when config.Usage is nil", func(t *testing.T) {
+		t.Parallel()
+
+		config := &Config{}
+		config.SetDefault()
+
+		require.NotNil(t, config.Usage)
+		assert.False(t, *config.Usage.Enabled)
+		assert.Equal(t, time.Minute, *config.Usage.FlushInterval)
+	})
+}
+
+func TestProvideMeteringConfig(t *testing.T) {
+	t.Parallel()
+
+	t.Run("return metering config from config", func(t *testing.T) {
+		t.Parallel()
+
+		config := &Config{
+			Metering: &metering.Config{Sink: &[]string{metering.SinkRedis}[0]},
+		}
+
+		meteringConfig := ProvideMeteringConfig(config)
+
+		require.NotNil(t, meteringConfig)
+		assert.Equal(t, metering.SinkRedis, *meteringConfig.Sink)
+	})
+
+	t.Run("set default metering config when config.Metering is nil", func(t *testing.T) {
+		t.Parallel()
+
+		config := &Config{}
+		config.SetDefault()
+
+		require.NotNil(t, config.Metering)
+		assert.False(t, *config.Metering.Enabled)
+		assert.Equal(t, metering.SinkDatabase, *config.Metering.Sink)
+	})
+}
+
+func TestProvideAuditConfig(t *testing.T) {
+	t.Parallel()
+
+	t.Run("return audit config from config", func(t *testing.T) {
+		t.Parallel()
+
+		config := &Config{
+			Audit: &audit.Config{Enabled: &[]bool{true}[0]},
+		}
+
+		auditConfig := ProvideAuditConfig(config)
+
+		require.NotNil(t, auditConfig)
+		assert.True(t, *auditConfig.Enabled)
+	})
+
+	t.Run("set default audit config when config.Audit is nil", func(t *testing.T) {
+		t.Parallel()
+
+		config := &Config{}
+		config.SetDefault()
+
+		require.NotNil(t, config.Audit)
+		assert.False(t, *config.Audit.Enabled)
+		assert.Equal(t, 100, *config.Audit.BatchSize)
+	})
+}
+
+func TestProvideSAMLConfig(t *testing.T) {
+	t.Parallel()
+
+	t.Run("return saml config from config", func(t *testing.T) {
+		t.Parallel()
+
+		config := &Config{
+			SAML: &saml.Config{Enabled: &[]bool{true}[0]},
+		}
+
+		samlConfig := ProvideSAMLConfig(config)
+
+		require.NotNil(t, samlConfig)
+		assert.True(t, *samlConfig.Enabled)
+	})
+
+	t.Run("set default saml config when config.SAML is nil", func(t *testing.T) {
+		t.Parallel()
+
+		config := &Config{}
+		config.SetDefault()
+
+		require.NotNil(t, config.SAML)
+		assert.False(t, *config.SAML.Enabled)
+		assert.Equal(t, "/saml", *config.SAML.Path)
+	})
+}
+
+func TestProvideLDAPConfig(t *testing.T) {
+	t.Parallel()
+
+	t.Run("return ldap config from config", func(t *testing.T) {
+		t.Parallel()
+
+		config := &Config{
+			LDAP: &ldap.Config{Enabled: &[]bool{true}[0]},
+		}
+
+		ldapConfig := ProvideLDAPConfig(config)
+
+		require.NotNil(t, ldapConfig)
+		assert.True(t, *ldapConfig.Enabled)
+	})
+
+	t.Run("set default ldap config when config.LDAP is nil", func(t *testing.T) {
+		t.Parallel()
+
+		config := &Config{}
+		config.SetDefault()
+
+		require.NotNil(t, config.LDAP)
+		assert.False(t, *config.LDAP.Enabled)
+		assert.False(t, *config.LDAP.LocalPasswords)
+	})
+}
+
+func TestProvidePaymentsConfig(t *testing.T) {
+	t.Parallel()
+
+	t.Run("return payments config from config", func(t *testing.T) {
+		t.Parallel()
+
+		config := &Config{
+			Payments: &payments.Config{WebhookPath: &[]string{"/billing/webhook"}[0]},
+		}
+
+		paymentsConfig := ProvidePaymentsConfig(config)
+
+		require.NotNil(t, paymentsConfig)
+		assert.Equal(t, "/billing/webhook", *paymentsConfig.WebhookPath)
+	})
+
+	t.Run("set default payments config when config.Payments is nil", func(t *testing.T) {
+		t.Parallel()
+
+		config := &Config{}
+		config.SetDefault()
+
+		require.NotNil(t, config.Payments)
+		assert.False(t, *config.Payments.Enabled)
+		assert.Equal(t, "/webhooks/stripe", *config.Payments.WebhookPath)
+	})
+}
+
+func TestProvideSignedURLConfig(t *testing.T) {
+	t.Parallel()
+
+	t.Run("return signed url config from config", func(t *testing.T) {
+		t.Parallel()
+
+		config := &Config{
+			SignedURL: &signedurl.Config{Paths: []string{"/downloads/"}},
+		}
+
+		signedURLConfig := ProvideSignedURLConfig(config)
+
+		require.NotNil(t, signedURLConfig)
+		assert.Equal(t, []string{"/downloads/"}, signedURLConfig.Paths)
+	})
+
+	t.Run("set default signed url config when config.SignedURL is nil", func(t *testing.T) {
+		t.Parallel()
+
+		config := &Config{}
+		config.SetDefault()
+
+		require.NotNil(t, config.SignedURL)
+		assert.False(t, *config.SignedURL.Enabled)
+		assert.Equal(t, "/signed-urls", *config.SignedURL.Path)
+	})
+}
+
+func TestProvideImagesConfig(t *testing.T) {
+	t.Parallel()
+
+	t.Run("return images config from config", func(t *testing.T) {
+		t.Parallel()
+
+		config := &Config{
+			Images: &images.Config{MaxWidth: &[]int{512}[0]},
+		}
+
+		imagesConfig := ProvideImagesConfig(config)
+
+		require.NotNil(t, imagesConfig)
+		assert.Equal(t, 512, *imagesConfig.MaxWidth)
+	})
+
+	t.Run("set default images config when config.Images is nil", func(t *testing.T) {
+		t.Parallel()
+
+		config := &Config{}
+		config.SetDefault()
+
+		require.NotNil(t, config.Images)
+		assert.False(t, *config.Images.Enabled)
+		assert.Equal(t, "/images", *config.Images.Path)
+	})
+}
+
+func TestProvideRetentionConfig(t *testing.T) {
+	t.Parallel()
+
+	t.Run("return retention config from config", func(t *testing.T) {
+		t.Parallel()
+
+		config := &Config{
+			Retention: &retention.Config{
+				Rules: []retention.Rule{{Table: "sessions", AgeColumn: "expires_at", TTL: time.Hour}},
+			},
+		}
+
+		retentionConfig := ProvideRetentionConfig(config)
+
+		require.NotNil(t, retentionConfig)
+		assert.Len(t, retentionConfig.Rules, 1)
+	})
+
+	t.Run("set default retention config when config.Retention is nil", func(t *testing.T) {
+		t.Parallel()
+
+		config := &Config{}
+		config.SetDefault()
+
+		require.NotNil(t, config.Retention)
+		assert.False(t, *config.Retention.Enabled)
+		assert.False(t, *config.Retention.DryRun)
+	})
+}
+
+func TestProvideCacheConfig(t *testing.T) {
+	t.Parallel()
+
+	t.Run("return cache config from config", func(t *testing.T) {
+		t.Parallel()
+
+		config := &Config{
+			Cache: &cache.Config{Prefix: &[]string{"app:"}[0]},
+		}
+
+		cacheConfig := ProvideCacheConfig(config)
+
+		require.NotNil(t, cacheConfig)
+		assert.Equal(t, "app:", *cacheConfig.Prefix)
+	})
+
+	t.Run("set default cache config when config.Cache is nil", func(t *testing.T) {
+		t.Parallel()
+
+		config := &Config{}
+		config.SetDefault()
+
+		require.NotNil(t, config.Cache)
+		assert.Equal(t, "cache:", *config.Cache.Prefix)
+		assert.Equal(t, 5*time.Minute, *config.Cache.DefaultTTL)
+	})
+}
+
+func TestProvideQueryCacheConfig(t *testing.T) {
+	t.Parallel()
+
+	t.Run("return query cache config from config", func(t *testing.T) {
+		t.Parallel()
+
+		config := &Config{
+			QueryCache: &querycache.Config{Enabled: &[]bool{true}[0]},
+		}
+
+		queryCacheConfig := ProvideQueryCacheConfig(config)
+
+		require.NotNil(t, queryCacheConfig)
+		assert.True(t, *queryCacheConfig.Enabled)
+	})
+
+	t.Run("set default query cache config when config.QueryCache is nil", func(t *testing.T) {
+		t.Parallel()
+
+		config := &Config{}
+		config.SetDefault()
+
+		require.NotNil(t, config.QueryCache)
+		assert.False(t, *config.QueryCache.Enabled)
+		assert.Equal(t, 30*time.Second, *config.QueryCache.LocalTTL)
+	})
+}
+
+func TestProvideJobsConfig(t *testing.T) {
+	t.Parallel()
+
+	t.Run("return jobs config from config", func(t *testing.T) {
+		t.Parallel()
+
+		config := &Config{
+			Jobs: &jobs.Config{Concurrency: &[]int{4}[0]},
+		}
+
+		jobsConfig := ProvideJobsConfig(config)
+
+		require.NotNil(t, jobsConfig)
+		assert.Equal(t, 4, *jobsConfig.Concurrency)
+	})
+
+	t.Run("set default jobs config when config.Jobs is nil", func(t *testing.T) {
+		t.Parallel()
+
+		config := &Config{}
+		config.SetDefault()
+
+		require.NotNil(t, config.Jobs)
+		assert.False(t, *config.Jobs.Enabled)
+		assert.Equal(t, "{jobs}:", *config.Jobs.Prefix)
+	})
+}
+
+func TestProvideSchedulerConfig(t *testing.T) {
+	t.Parallel()
+
+	t.Run("return scheduler config from config", func(t *testing.T) {
+		t.Parallel()
+
+		config := &Config{
+			Scheduler: &scheduler.Config{Timezone: &[]string{"Asia/Seoul"}[0]},
+		}
+
+		schedulerConfig := ProvideSchedulerConfig(config)
+
+		require.NotNil(t, schedulerConfig)
+		assert.Equal(t, "Asia/Seoul", *schedulerConfig.Timezone)
+	})
+
+	t.Run("set default scheduler config when config.Scheduler is nil", func(t *testing.T) {
+		t.Parallel()
+
+		config := &Config{}
+		config.SetDefault()
+
+		require.NotNil(t, config.Scheduler)
+		assert.True(t, *config.Scheduler.Enabled)
+		assert.Equal(t, "UTC", *config.Scheduler.Timezone)
+	})
+}
+
+func TestProvideWebSocketConfig(t *testing.T) {
+	t.Parallel()
+
+	t.Run("return websocket config from config", func(t *testing.T) {
+		t.Parallel()
+
+		config := &Config{
+			WebSocket: &websocket.Config{Path: &[]string{"/events"}[0]},
+		}
+
+		webSocketConfig := ProvideWebSocketConfig(config)
+
+		require.NotNil(t, webSocketConfig)
+		assert.Equal(t, "/events", *webSocketConfig.Path)
+	})
+
+	t.Run("set default websocket config when config.WebSocket is nil", func(t *testing.T) {
+		t.Parallel()
+
+		config := &Config{}
+		config.SetDefault()
+
+		require.NotNil(t, config.WebSocket)
+		assert.True(t, *config.WebSocket.Enabled)
+		assert.Equal(t, "/ws", *config.WebSocket.Path)
+	})
+}
+
+func TestProvideSSEConfig(t *testing.T) {
+	t.Parallel()
+
+	t.Run("return sse config from config", func(t *testing.T) {
+		t.Parallel()
+
+		config := &Config{
+			SSE: &sse.Config{Path: &[]string{"/stream"}[0]},
+		}
+
+		sseConfig := ProvideSSEConfig(config)
+
+		require.NotNil(t, sseConfig)
+		assert.Equal(t, "/stream", *sseConfig.Path)
+	})
+
+	t.Run("set default sse config when config.SSE is nil", func(t *testing.T) {
+		t.Parallel()
+
+		config := &Config{}
+		config.SetDefault()
+
+		require.NotNil(t, config.SSE)
+		assert.True(t, *config.SSE.Enabled)
+		assert.Equal(t, "/events", *config.SSE.Path)
+	})
+}
+
+func TestProvideGRPCConfig(t *testing.T) {
+	t.Parallel()
+
+	t.Run("return grpc config from config", func(t *testing.T) {
+		t.Parallel()
+
+		config := &Config{
+			GRPC: &grpcserver.Config{Port: &[]int{50051}[0]},
+		}
+
+		grpcConfig := ProvideGRPCConfig(config)
+
+		require.NotNil(t, grpcConfig)
+		assert.Equal(t, 50051, *grpcConfig.Port)
+	})
+
+	t.Run("set default grpc config when config.GRPC is nil", func(t *testing.T) {
+		t.Parallel()
+
+		config := &Config{}
+		config.SetDefault()
+
+		require.NotNil(t, config.GRPC)
+		assert.False(t, *config.GRPC.Enabled)
+		assert.Equal(t, 9090, *config.GRPC.Port)
+	})
+}
+
 func TestConfigSetDefaultServer(t *testing.T) {
 	t.Parallel()
 
@@ -0,0 +1,41 @@
+package config
+
+import (
+	"github.com/pocj8ur4in/boilerplate-go/internal/app/boilerplate/server/middleware"
+	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/logger"
+)
+
+// devCORSOrigins is origins allowed by CORS in dev mode, any port of the local host.
+var devCORSOrigins = []string{"http://localhost:*", "http://127.0.0.1:*"}
+
+// applyDevMode relaxes the configuration for local development, overriding values of the file and environment:
+// pretty console logging, no HSTS and rate limiting, localhost CORS with credentials and verbose error responses.
+func (c *Config) applyDevMode() {
+	// logger
+	c.Logger.Format = &[]string{logger.FormatConsole}[0]
+
+	// security headers and errors
+	c.Server.HSTS = &[]bool{false}[0]
+	c.Server.VerboseErrors = &[]bool{true}[0]
+
+	// rate limits
+	for _, limit := range []*middleware.RateLimitTypeConfig{
+		c.Server.RateLimit.Global,
+		c.Server.RateLimit.IP,
+		c.Server.RateLimit.Endpoint,
+		c.Server.RateLimit.Tenant,
+		c.Server.APIKeys.RateLimit,
+	} {
+		limit.Enabled = &[]bool{false}[0]
+	}
+
+	// cors, origins are replaced rather than updated since groups may share them with the default policy
+	origins := append([]string{}, devCORSOrigins...)
+
+	c.Server.CORS.AllowedOrigins = &origins
+	c.Server.CORS.AllowCredentials = &[]bool{true}[0]
+
+	for _, group := range c.Server.CORS.Groups {
+		group.AllowedOrigins = &origins
+	}
+}
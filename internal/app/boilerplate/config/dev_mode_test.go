@@ -0,0 +1,85 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pocj8ur4in/boilerplate-go/internal/app/boilerplate/server"
+	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/logger"
+)
+
+func TestDevMode(t *testing.T) {
+	t.Parallel()
+
+	t.Run("disable dev mode by default", func(t *testing.T) {
+		t.Parallel()
+
+		config := &Config{}
+		config.SetDefault()
+
+		require.NotNil(t, config.DevMode)
+		assert.False(t, *config.DevMode)
+		assert.True(t, *config.Server.HSTS)
+		assert.False(t, *config.Server.VerboseErrors)
+		assert.True(t, *config.Server.RateLimit.IP.Enabled)
+		assert.False(t, *config.Server.CORS.AllowCredentials)
+	})
+
+	t.Run("relax configuration in dev mode", func(t *testing.T) {
+		t.Parallel()
+
+		config := &Config{DevMode: &[]bool{true}[0]}
+		config.SetDefault()
+
+		assert.Equal(t, logger.FormatConsole, *config.Logger.Format)
+		assert.False(t, *config.Server.HSTS)
+		assert.True(t, *config.Server.VerboseErrors)
+		assert.False(t, *config.Server.RateLimit.Global.Enabled)
+		assert.False(t, *config.Server.RateLimit.IP.Enabled)
+		assert.False(t, *config.Server.RateLimit.Endpoint.Enabled)
+		assert.False(t, *config.Server.RateLimit.Tenant.Enabled)
+		assert.False(t, *config.Server.APIKeys.RateLimit.Enabled)
+		assert.Equal(t, devCORSOrigins, *config.Server.CORS.AllowedOrigins)
+		assert.True(t, *config.Server.CORS.AllowCredentials)
+	})
+
+	t.Run("override values of the file in dev mode", func(t *testing.T) {
+		t.Parallel()
+
+		config, err := parse([]byte(`{
+			"dev_mode": true,
+			"logger": {"format": "json", "level": "warn"},
+			"server": {
+				"hsts": true,
+				"cors": {
+					"allowed_origins": ["https://example.com"],
+					"groups": [{"path_prefix": "/admin", "allowed_origins": ["https://admin.example.com"]}]
+				},
+				"rate_limit": {"ip": {"enabled": true}}
+			}
+		}`))
+		require.NoError(t, err)
+
+		assert.Equal(t, logger.FormatConsole, *config.Logger.Format)
+		assert.Equal(t, "warn", *config.Logger.Level)
+		assert.False(t, *config.Server.HSTS)
+		assert.False(t, *config.Server.RateLimit.IP.Enabled)
+		assert.Equal(t, devCORSOrigins, *config.Server.CORS.AllowedOrigins)
+		require.Len(t, config.Server.CORS.Groups, 1)
+		assert.Equal(t, devCORSOrigins, *config.Server.CORS.Groups[0].AllowedOrigins)
+	})
+
+	t.Run("keep sections unrelated to dev mode", func(t *testing.T) {
+		t.Parallel()
+
+		config := &Config{
+			DevMode: &[]bool{true}[0],
+			Server:  &server.Config{Port: &[]int{9090}[0]},
+		}
+		config.SetDefault()
+
+		assert.Equal(t, 9090, *config.Server.Port)
+	})
+}
@@ -0,0 +1,133 @@
+package config
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+var (
+	// ErrEncryptionKeyMissing returned when an encrypted config is loaded without encryption key.
+	ErrEncryptionKeyMissing = errors.New("config encryption key is missing")
+
+	// ErrInvalidEncryptionKey returned when the encryption key is not a base64 encoded 32 bytes key.
+	ErrInvalidEncryptionKey = errors.New("config encryption key must be base64 encoded 32 bytes")
+
+	// ErrInvalidEncryptedConfig returned when the encrypted config is malformed.
+	ErrInvalidEncryptedConfig = errors.New("invalid encrypted config")
+)
+
+const (
+	// encryptedConfigHeader is the first line of an encrypted config file.
+	encryptedConfigHeader = "boilerplate-config-aesgcm-v1\n"
+
+	// encryptionKeySize is the size of the AES-256 encryption key in bytes.
+	encryptionKeySize = 32
+)
+
+// IsEncrypted returns whether the content is an encrypted config.
+func IsEncrypted(content []byte) bool {
+	return bytes.HasPrefix(content, []byte(encryptedConfigHeader))
+}
+
+// Encrypt encrypts the config content with AES-256-GCM.
+func Encrypt(plaintext, key []byte) ([]byte, error) {
+	aead, err := newAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("failed to generate nonce: %w", err)
+	}
+
+	sealed := aead.Seal(nonce, nonce, plaintext, []byte(encryptedConfigHeader))
+
+	return []byte(encryptedConfigHeader + base64.StdEncoding.EncodeToString(sealed) + "\n"), nil
+}
+
+// Decrypt decrypts the config content encrypted by Encrypt.
+func Decrypt(content, key []byte) ([]byte, error) {
+	if !IsEncrypted(content) {
+		return nil, fmt.Errorf("%w: missing header", ErrInvalidEncryptedConfig)
+	}
+
+	aead, err := newAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+
+	encoded := strings.TrimSpace(string(content[len(encryptedConfigHeader):]))
+
+	sealed, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidEncryptedConfig, err)
+	}
+
+	if len(sealed) < aead.NonceSize() {
+		return nil, fmt.Errorf("%w: too short", ErrInvalidEncryptedConfig)
+	}
+
+	nonce, ciphertext := sealed[:aead.NonceSize()], sealed[aead.NonceSize():]
+
+	plaintext, err := aead.Open(nil, nonce, ciphertext, []byte(encryptedConfigHeader))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt config: %w", err)
+	}
+
+	return plaintext, nil
+}
+
+// LoadEncryptionKey loads the encryption key from CONFIG_ENCRYPTION_KEY,
+// or from the file at CONFIG_ENCRYPTION_KEY_FILE (e.g. a KMS-decrypted secret mount).
+func LoadEncryptionKey() ([]byte, error) {
+	encoded := os.Getenv("CONFIG_ENCRYPTION_KEY")
+
+	if encoded == "" {
+		path := os.Getenv("CONFIG_ENCRYPTION_KEY_FILE")
+		if path == "" {
+			return nil, ErrEncryptionKeyMissing
+		}
+
+		content, err := os.ReadFile(filepath.Clean(path))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read encryption key file: %w", err)
+		}
+
+		encoded = string(content)
+	}
+
+	key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(encoded))
+	if err != nil || len(key) != encryptionKeySize {
+		return nil, ErrInvalidEncryptionKey
+	}
+
+	return key, nil
+}
+
+// newAEAD creates AES-GCM cipher from the key.
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	if len(key) != encryptionKeySize {
+		return nil, ErrInvalidEncryptionKey
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cipher: %w", err)
+	}
+
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create gcm: %w", err)
+	}
+
+	return aead, nil
+}
@@ -0,0 +1,117 @@
+package config
+
+import (
+	"bytes"
+	"encoding/base64"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testEncryptionKey returns a fixed 32 bytes encryption key.
+func testEncryptionKey() []byte {
+	return bytes.Repeat([]byte{0x42}, encryptionKeySize)
+}
+
+func TestEncryptDecrypt(t *testing.T) {
+	t.Parallel()
+
+	t.Run("decrypt encrypted content", func(t *testing.T) {
+		t.Parallel()
+
+		plaintext := []byte(`{"jwt":{"secret_key":"secret"}}`)
+
+		encrypted, err := Encrypt(plaintext, testEncryptionKey())
+		require.NoError(t, err)
+		assert.True(t, IsEncrypted(encrypted))
+		assert.NotContains(t, string(encrypted), "secret")
+
+		decrypted, err := Decrypt(encrypted, testEncryptionKey())
+		require.NoError(t, err)
+		assert.Equal(t, plaintext, decrypted)
+	})
+
+	t.Run("reject wrong key", func(t *testing.T) {
+		t.Parallel()
+
+		encrypted, err := Encrypt([]byte(`{}`), testEncryptionKey())
+		require.NoError(t, err)
+
+		_, err = Decrypt(encrypted, bytes.Repeat([]byte{0x01}, encryptionKeySize))
+		require.Error(t, err)
+	})
+
+	t.Run("reject invalid key size", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := Encrypt([]byte(`{}`), []byte("short"))
+		require.ErrorIs(t, err, ErrInvalidEncryptionKey)
+	})
+
+	t.Run("reject content without header", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := Decrypt([]byte(`{}`), testEncryptionKey())
+		require.ErrorIs(t, err, ErrInvalidEncryptedConfig)
+	})
+}
+
+func TestLoadFromFileWithEncryptedConfig(t *testing.T) {
+	t.Run("load encrypted config with key from env", func(t *testing.T) {
+		encrypted, err := Encrypt([]byte(`{"logger":{"level":"debug"}}`), testEncryptionKey())
+		require.NoError(t, err)
+
+		configPath := filepath.Join(t.TempDir(), "config.json.enc")
+		require.NoError(t, os.WriteFile(configPath, encrypted, 0600))
+
+		t.Setenv("CONFIG_PATH", configPath)
+		t.Setenv("CONFIG_ENCRYPTION_KEY", base64.StdEncoding.EncodeToString(testEncryptionKey()))
+
+		config, err := LoadFromFile()
+
+		require.NoError(t, err)
+		assert.Equal(t, "debug", *config.Logger.Level)
+	})
+
+	t.Run("load encrypted config with key from file", func(t *testing.T) {
+		tmpDir := t.TempDir()
+
+		encrypted, err := Encrypt([]byte(`{"logger":{"level":"warn"}}`), testEncryptionKey())
+		require.NoError(t, err)
+
+		configPath := filepath.Join(tmpDir, "config.json.enc")
+		require.NoError(t, os.WriteFile(configPath, encrypted, 0600))
+
+		keyPath := filepath.Join(tmpDir, "config.key")
+		require.NoError(t, os.WriteFile(keyPath, []byte(base64.StdEncoding.EncodeToString(testEncryptionKey())+"\n"), 0600))
+
+		t.Setenv("CONFIG_PATH", configPath)
+		t.Setenv("CONFIG_ENCRYPTION_KEY", "")
+		t.Setenv("CONFIG_ENCRYPTION_KEY_FILE", keyPath)
+
+		config, err := LoadFromFile()
+
+		require.NoError(t, err)
+		assert.Equal(t, "warn", *config.Logger.Level)
+	})
+
+	t.Run("return error when key is missing", func(t *testing.T) {
+		encrypted, err := Encrypt([]byte(`{}`), testEncryptionKey())
+		require.NoError(t, err)
+
+		configPath := filepath.Join(t.TempDir(), "config.json.enc")
+		require.NoError(t, os.WriteFile(configPath, encrypted, 0600))
+
+		t.Setenv("CONFIG_PATH", configPath)
+		t.Setenv("CONFIG_ENCRYPTION_KEY", "")
+		t.Setenv("CONFIG_ENCRYPTION_KEY_FILE", "")
+
+		config, err := LoadFromFile()
+
+		require.ErrorIs(t, err, ErrEncryptionKeyMissing)
+		assert.Nil(t, config)
+	})
+}
@@ -0,0 +1,139 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// EnvPrefix is prefix of environment variables overriding the configuration.
+const EnvPrefix = "BOILERPLATE"
+
+// ErrInvalidEnv is returned when an environment variable cannot be parsed into its field.
+var ErrInvalidEnv = errors.New("invalid environment variable")
+
+// durationType is type of time.Duration fields, parsed from values like 15m.
+var durationType = reflect.TypeFor[time.Duration]()
+
+// ApplyEnv overrides the configuration with environment variables.
+//
+// Each field is mapped to EnvPrefix and its JSON path in upper case joined by underscores
+// (e.g. server.port -> BOILERPLATE_SERVER_PORT, server.rate_limit.ip.requests -> BOILERPLATE_SERVER_RATE_LIMIT_IP_REQUESTS).
+// Strings are used as is, string lists are comma separated, durations accept values like 15m or
+// durations in nanoseconds as in JSON, other values including lists of objects are parsed as JSON.
+func ApplyEnv(config *Config) error {
+	_, err := applyEnvFields(reflect.ValueOf(config).Elem(), EnvPrefix, os.LookupEnv)
+
+	return err
+}
+
+// applyEnv overrides the value and its fields with environment variables, it returns whether any was set.
+func applyEnv(value reflect.Value, name string, lookup func(string) (string, bool)) (bool, error) {
+	if raw, ok := lookup(name); ok {
+		if err := setEnv(value, raw); err != nil {
+			return false, fmt.Errorf("%w %s: %w", ErrInvalidEnv, name, err)
+		}
+
+		return true, nil
+	}
+
+	switch {
+	case value.Kind() == reflect.Struct:
+		return applyEnvFields(value, name, lookup)
+	case value.Kind() == reflect.Pointer && value.Type().Elem().Kind() == reflect.Struct:
+		// allocate nested configs only when one of their fields is set, so defaults still apply
+		target := reflect.New(value.Type().Elem())
+		if !value.IsNil() {
+			target = value
+		}
+
+		set, err := applyEnvFields(target.Elem(), name, lookup)
+		if err != nil || !set {
+			return false, err
+		}
+
+		value.Set(target)
+
+		return true, nil
+	default:
+		return false, nil
+	}
+}
+
+// applyEnvFields overrides the exported fields of a struct with environment variables.
+func applyEnvFields(value reflect.Value, name string, lookup func(string) (string, bool)) (bool, error) {
+	var set bool
+
+	for i := range value.NumField() {
+		field := value.Type().Field(i)
+		if !field.IsExported() {
+			continue
+		}
+
+		tag, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if tag == "-" || tag == "" {
+			continue
+		}
+
+		fieldSet, err := applyEnv(value.Field(i), name+"_"+strings.ToUpper(tag), lookup)
+		if err != nil {
+			return false, err
+		}
+
+		set = set || fieldSet
+	}
+
+	return set, nil
+}
+
+// setEnv parses the raw environment value into the field.
+func setEnv(value reflect.Value, raw string) error {
+	target := value
+	if value.Kind() == reflect.Pointer {
+		target = reflect.New(value.Type().Elem()).Elem()
+	}
+
+	switch {
+	case target.Type() == durationType && !isInteger(raw):
+		duration, err := time.ParseDuration(raw)
+		if err != nil {
+			return fmt.Errorf("failed to parse duration: %w", err)
+		}
+
+		target.SetInt(int64(duration))
+	case target.Kind() == reflect.String:
+		target.SetString(raw)
+	case target.Kind() == reflect.Slice && target.Type().Elem().Kind() == reflect.String && !strings.HasPrefix(raw, "["):
+		items := reflect.MakeSlice(target.Type(), 0, 0)
+
+		for item := range strings.SplitSeq(raw, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = reflect.Append(items, reflect.ValueOf(item).Convert(target.Type().Elem()))
+			}
+		}
+
+		target.Set(items)
+	default:
+		if err := json.Unmarshal([]byte(raw), target.Addr().Interface()); err != nil {
+			return fmt.Errorf("failed to unmarshal json: %w", err)
+		}
+	}
+
+	if value.Kind() == reflect.Pointer {
+		value.Set(target.Addr())
+	}
+
+	return nil
+}
+
+// isInteger checks if the value is a decimal integer.
+func isInteger(value string) bool {
+	_, err := strconv.ParseInt(value, 10, 64)
+
+	return err == nil
+}
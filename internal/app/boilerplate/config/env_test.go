@@ -0,0 +1,148 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pocj8ur4in/boilerplate-go/internal/app/boilerplate/server"
+	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/database"
+)
+
+// applyTestEnv overrides the configuration with the environment variables.
+func applyTestEnv(config *Config, env map[string]string) error {
+	_, err := applyEnvFields(reflect.ValueOf(config).Elem(), EnvPrefix, func(name string) (string, bool) {
+		value, ok := env[name]
+
+		return value, ok
+	})
+
+	return err
+}
+
+func TestApplyEnv(t *testing.T) {
+	t.Parallel()
+
+	t.Run("override fields of every config", func(t *testing.T) {
+		t.Parallel()
+
+		config := &Config{}
+
+		err := applyTestEnv(config, map[string]string{
+			"BOILERPLATE_LOGGER_LEVEL":                  "debug",
+			"BOILERPLATE_DATABASE_HOST":                 "db.internal",
+			"BOILERPLATE_DATABASE_PORT":                 "6432",
+			"BOILERPLATE_REDIS_ADDRS":                   "redis-1:6379, redis-2:6379",
+			"BOILERPLATE_JWT_ACCESS_TOKEN_TTL":          "15m",
+			"BOILERPLATE_JWT_REFRESH_TOKEN_TTL":         "3600000000000",
+			"BOILERPLATE_SERVER_PORT":                   "9090",
+			"BOILERPLATE_SERVER_LISTEN":                 "false",
+			"BOILERPLATE_SERVER_RATE_LIMIT_IP_REQUESTS": "120",
+			"BOILERPLATE_SERVER_CORS_ALLOWED_ORIGINS":   `["https://example.com"]`,
+			"BOILERPLATE_SERVER_LISTENERS":              `[{"addr":":8443"}]`,
+		})
+		require.NoError(t, err)
+
+		config.SetDefault()
+
+		assert.Equal(t, "debug", *config.Logger.Level)
+		assert.Equal(t, "db.internal", *config.Database.Host)
+		assert.Equal(t, 6432, *config.Database.Port)
+		assert.Equal(t, []string{"redis-1:6379", "redis-2:6379"}, config.Redis.Addrs)
+		assert.Equal(t, 15*time.Minute, *config.JWT.AccessTokenTTL)
+		assert.Equal(t, time.Hour, *config.JWT.RefreshTokenTTL)
+		assert.Equal(t, 9090, *config.Server.Port)
+		assert.False(t, *config.Server.Listen)
+		assert.Equal(t, 120, *config.Server.RateLimit.IP.Requests)
+		assert.Equal(t, []string{"https://example.com"}, *config.Server.CORS.AllowedOrigins)
+		require.Len(t, config.Server.Listeners, 1)
+		assert.Equal(t, ":8443", *config.Server.Listeners[0].Addr)
+	})
+
+	t.Run("override file values and keep other fields", func(t *testing.T) {
+		t.Parallel()
+
+		config := &Config{
+			Database: &database.Config{
+				Host: &[]string{"file-host"}[0],
+				Port: &[]int{5432}[0],
+			},
+		}
+
+		err := applyTestEnv(config, map[string]string{"BOILERPLATE_DATABASE_HOST": "env-host"})
+		require.NoError(t, err)
+
+		assert.Equal(t, "env-host", *config.Database.Host)
+		assert.Equal(t, 5432, *config.Database.Port)
+	})
+
+	t.Run("keep nested configs nil without variables", func(t *testing.T) {
+		t.Parallel()
+
+		config := &Config{}
+
+		require.NoError(t, applyTestEnv(config, map[string]string{}))
+
+		assert.Nil(t, config.Server)
+		assert.Nil(t, config.Database)
+	})
+
+	t.Run("return error for invalid values", func(t *testing.T) {
+		t.Parallel()
+
+		tests := map[string]string{
+			"BOILERPLATE_SERVER_PORT":          "http",
+			"BOILERPLATE_SERVER_LISTEN":        "maybe",
+			"BOILERPLATE_JWT_ACCESS_TOKEN_TTL": "15 minutes",
+		}
+
+		for name, value := range tests {
+			config := &Config{Server: &server.Config{}}
+
+			err := applyTestEnv(config, map[string]string{name: value})
+			require.ErrorIs(t, err, ErrInvalidEnv, name)
+			assert.Contains(t, err.Error(), name)
+		}
+	})
+}
+
+func TestLoadFromFileWithEnv(t *testing.T) {
+	t.Run("override file with environment variables", func(t *testing.T) {
+		tmpDir := t.TempDir()
+		configPath := filepath.Join(tmpDir, "config.json")
+
+		content := `{"logger":{"level":"debug"},"server":{"port":8080}}`
+		err := os.WriteFile(configPath, []byte(content), 0600)
+		require.NoError(t, err)
+
+		t.Setenv("CONFIG_PATH", configPath)
+		t.Setenv("BOILERPLATE_SERVER_PORT", "9090")
+
+		config, err := LoadFromFile()
+
+		require.NoError(t, err)
+		assert.Equal(t, "debug", *config.Logger.Level)
+		assert.Equal(t, 9090, *config.Server.Port)
+	})
+
+	t.Run("return error for invalid environment variable", func(t *testing.T) {
+		tmpDir := t.TempDir()
+		configPath := filepath.Join(tmpDir, "config.json")
+
+		err := os.WriteFile(configPath, []byte(`{}`), 0600)
+		require.NoError(t, err)
+
+		t.Setenv("CONFIG_PATH", configPath)
+		t.Setenv("BOILERPLATE_SERVER_PORT", "http")
+
+		config, err := LoadFromFile()
+
+		require.ErrorIs(t, err, ErrInvalidEnv)
+		assert.Nil(t, config)
+	})
+}
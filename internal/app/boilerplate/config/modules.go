@@ -0,0 +1,71 @@
+package config
+
+// ModulesConfig represents which optional subsystems are included in the application. An excluded subsystem is
+// not constructed at all, while an included one still follows the enabled flag of its own section.
+type ModulesConfig struct {
+	// GRPC is whether the gRPC server is included.
+	GRPC *bool `json:"grpc"`
+
+	// Jobs is whether the background job queue is included.
+	Jobs *bool `json:"jobs"`
+
+	// Scheduler is whether the recurring task scheduler is included.
+	Scheduler *bool `json:"scheduler"`
+
+	// Retention is whether the deletion of expired rows is included.
+	Retention *bool `json:"retention"`
+
+	// WebSocket is whether the websocket hub is included.
+	WebSocket *bool `json:"websocket"`
+
+	// SSE is whether the server-sent events broker is included.
+	SSE *bool `json:"sse"`
+
+	// Payments is whether Stripe subscriptions and webhooks are included.
+	Payments *bool `json:"payments"`
+
+	// SAML is whether SAML single sign-on is included.
+	SAML *bool `json:"saml"`
+
+	// LDAP is whether login verification against the directory is included.
+	LDAP *bool `json:"ldap"`
+
+	// Audit is whether the batched audit log is included.
+	Audit *bool `json:"audit"`
+
+	// Metering is whether the batched billable event writer is included.
+	Metering *bool `json:"metering"`
+
+	// Images is whether the image upload and resize pipeline is included.
+	Images *bool `json:"images"`
+
+	// Settings is whether the runtime settings store and its invalidation subscription are included.
+	Settings *bool `json:"settings"`
+
+	// Usage is whether the periodic flush of tenant usage is included.
+	Usage *bool `json:"usage"`
+}
+
+// SetDefault sets the default values, including every subsystem.
+func (c *ModulesConfig) SetDefault() {
+	for _, included := range []**bool{
+		&c.GRPC,
+		&c.Jobs,
+		&c.Scheduler,
+		&c.Retention,
+		&c.WebSocket,
+		&c.SSE,
+		&c.Payments,
+		&c.SAML,
+		&c.LDAP,
+		&c.Audit,
+		&c.Metering,
+		&c.Images,
+		&c.Settings,
+		&c.Usage,
+	} {
+		if *included == nil {
+			*included = &[]bool{true}[0]
+		}
+	}
+}
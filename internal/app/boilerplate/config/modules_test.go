@@ -0,0 +1,75 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestModulesConfigSetDefault(t *testing.T) {
+	t.Parallel()
+
+	t.Run("include every subsystem by default", func(t *testing.T) {
+		t.Parallel()
+
+		config := &Config{}
+		config.SetDefault()
+
+		require.NotNil(t, config.Modules)
+
+		for _, included := range []*bool{
+			config.Modules.GRPC,
+			config.Modules.Jobs,
+			config.Modules.Scheduler,
+			config.Modules.Retention,
+			config.Modules.WebSocket,
+			config.Modules.SSE,
+			config.Modules.Payments,
+			config.Modules.SAML,
+			config.Modules.LDAP,
+			config.Modules.Audit,
+			config.Modules.Metering,
+			config.Modules.Images,
+			config.Modules.Settings,
+			config.Modules.Usage,
+		} {
+			assert.True(t, *included)
+		}
+	})
+
+	t.Run("keep excluded subsystems", func(t *testing.T) {
+		t.Parallel()
+
+		config := &ModulesConfig{GRPC: &[]bool{false}[0], WebSocket: &[]bool{false}[0], Audit: &[]bool{false}[0]}
+		config.SetDefault()
+
+		assert.False(t, *config.GRPC)
+		assert.False(t, *config.WebSocket)
+		assert.False(t, *config.Audit)
+		assert.True(t, *config.Jobs)
+		assert.True(t, *config.Payments)
+	})
+}
+
+//nolint:paralleltest // Cannot run in parallel due to t.Setenv usage
+func TestLoadModulesConfig(t *testing.T) {
+	t.Run("load modules from config file", func(t *testing.T) {
+		configPath := filepath.Join(t.TempDir(), "config.json")
+
+		err := os.WriteFile(configPath, []byte(`{"modules": {"grpc": false, "jobs": true, "websocket": false}}`), 0600)
+		require.NoError(t, err)
+
+		t.Setenv("CONFIG_PATH", configPath)
+
+		config, err := LoadFromFile()
+		require.NoError(t, err)
+
+		assert.False(t, *config.Modules.GRPC)
+		assert.True(t, *config.Modules.Jobs)
+		assert.False(t, *config.Modules.WebSocket)
+		assert.True(t, *config.Modules.SSE)
+	})
+}
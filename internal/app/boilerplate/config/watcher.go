@@ -0,0 +1,242 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"go.uber.org/fx"
+
+	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/logger"
+)
+
+// defaultWatchDebounce is delay after the last file event before the configuration is reloaded,
+// so editors writing a file in several steps trigger a single reload.
+const defaultWatchDebounce = 100 * time.Millisecond
+
+// Reloadable is implemented by modules applying their section of the configuration at runtime.
+type Reloadable[T any] interface {
+	// Reload applies the configuration, the previous configuration stays in effect when it returns an error.
+	Reload(config T) error
+}
+
+// Reloader applies a reloaded configuration to a module.
+type Reloader struct {
+	// name is name of the module in logs.
+	name string
+
+	// reload applies the configuration to the module.
+	reload func(config *Config) error
+}
+
+// NewReloader creates a reloader applying a section of the configuration to the module.
+func NewReloader[T any](name string, section func(config *Config) T, reloadable Reloadable[T]) Reloader {
+	return Reloader{
+		name: name,
+		reload: func(config *Config) error {
+			return reloadable.Reload(section(config))
+		},
+	}
+}
+
+// WatcherParams represents dependencies of the watcher, modules provide reloaders in the config_reloaders group.
+type WatcherParams struct {
+	fx.In
+
+	Logger    *logger.Logger
+	Reloaders []Reloader `group:"config_reloaders"`
+}
+
+// Watcher watches the config file and reloads modules when it changes.
+type Watcher struct {
+	// path is absolute path of the config file.
+	path string
+
+	// logger provides logger.
+	logger *logger.Logger
+
+	// debounce is delay after the last file event before reloading.
+	debounce time.Duration
+
+	// mu guards reloaders and content.
+	mu sync.Mutex
+
+	// reloaders apply reloaded configuration to modules.
+	reloaders []Reloader
+
+	// content is content of the config file last applied.
+	content []byte
+
+	// fsWatcher provides file system notifications, nil until started.
+	fsWatcher *fsnotify.Watcher
+
+	// done is closed to stop watching.
+	done chan struct{}
+
+	// wg waits for the watch loop to exit.
+	wg sync.WaitGroup
+}
+
+// NewWatcher creates a watcher of the config file.
+func NewWatcher(params WatcherParams) (*Watcher, error) {
+	configPath, err := resolveConfigPath()
+	if err != nil {
+		return nil, err
+	}
+
+	return newWatcher(configPath, params.Logger, params.Reloaders), nil
+}
+
+// newWatcher creates a watcher of the file at the path.
+func newWatcher(path string, logger *logger.Logger, reloaders []Reloader) *Watcher {
+	// changes are detected against the content at startup
+	content, _ := os.ReadFile(path) //nolint:gosec // path is the configured config file
+
+	return &Watcher{
+		path:      path,
+		logger:    logger.Named("config"),
+		debounce:  defaultWatchDebounce,
+		reloaders: reloaders,
+		content:   content,
+	}
+}
+
+// Register adds a reloader applied on the next reload.
+func (w *Watcher) Register(reloader Reloader) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.reloaders = append(w.reloaders, reloader)
+}
+
+// Start starts watching the config file.
+func (w *Watcher) Start() error {
+	fsWatcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return fmt.Errorf("failed to create file watcher: %w", err)
+	}
+
+	// watch the directory, editors and kubernetes config maps replace the file instead of writing it
+	if err := fsWatcher.Add(filepath.Dir(w.path)); err != nil {
+		_ = fsWatcher.Close()
+
+		return fmt.Errorf("failed to watch config directory: %w", err)
+	}
+
+	w.fsWatcher = fsWatcher
+	w.done = make(chan struct{})
+
+	w.wg.Add(1)
+
+	go w.watch()
+
+	return nil
+}
+
+// Stop stops watching the config file.
+func (w *Watcher) Stop() error {
+	if w.fsWatcher == nil {
+		return nil
+	}
+
+	close(w.done)
+	err := w.fsWatcher.Close()
+	w.wg.Wait()
+
+	w.fsWatcher = nil
+
+	if err != nil {
+		return fmt.Errorf("failed to close file watcher: %w", err)
+	}
+
+	return nil
+}
+
+// watch reloads the configuration after file events settle until stopped.
+func (w *Watcher) watch() {
+	defer w.wg.Done()
+
+	timer := time.NewTimer(w.debounce)
+	timer.Stop()
+
+	defer timer.Stop()
+
+	for {
+		select {
+		case event, ok := <-w.fsWatcher.Events:
+			if !ok {
+				return
+			}
+
+			// events of other files are kept since the config file may resolve through them (e.g. symlinks),
+			// reloads without content changes are skipped
+			if event.Has(fsnotify.Chmod) {
+				continue
+			}
+
+			timer.Reset(w.debounce)
+		case err, ok := <-w.fsWatcher.Errors:
+			if !ok {
+				return
+			}
+
+			w.logger.Error().Err(err).Msg("config watcher failed")
+		case <-timer.C:
+			// errors are logged by Reload
+			_ = w.Reload()
+		case <-w.done:
+			return
+		}
+	}
+}
+
+// Reload reloads the config file and applies it to modules if its content changed.
+func (w *Watcher) Reload() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	content, err := os.ReadFile(w.path)
+	if err != nil {
+		// the file may be missing while it is replaced
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+
+		w.logger.Error().Err(err).Str("path", w.path).Msg("failed to read config file")
+
+		return fmt.Errorf("failed to read file: %w", err)
+	}
+
+	if bytes.Equal(content, w.content) {
+		return nil
+	}
+
+	config, err := parse(content)
+	if err != nil {
+		w.logger.Error().Err(err).Str("path", w.path).Msg("failed to reload config, keeping current config")
+
+		return err
+	}
+
+	// the content is applied even if a module rejects it, so it is not retried until the file changes
+	w.content = content
+
+	var errs []error
+
+	for _, reloader := range w.reloaders {
+		if err := reloader.reload(config); err != nil {
+			w.logger.Error().Err(err).Str("module", reloader.name).Msg("failed to reload module config")
+
+			errs = append(errs, fmt.Errorf("%s: %w", reloader.name, err))
+		}
+	}
+
+	w.logger.Info().Str("path", w.path).Int("modules", len(w.reloaders)).Msg("config reloaded")
+
+	return errors.Join(errs...)
+}
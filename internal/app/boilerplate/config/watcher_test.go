@@ -0,0 +1,190 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/logger"
+)
+
+// errReloadRejected is returned by the test reloadable when rejecting a configuration.
+var errReloadRejected = errors.New("reload rejected")
+
+// testReloadable records reloaded logger levels.
+type testReloadable struct {
+	// mu guards levels.
+	mu sync.Mutex
+
+	// levels are reloaded logger levels.
+	levels []string
+
+	// err is returned by Reload.
+	err error
+}
+
+// Reload records the logger level.
+func (r *testReloadable) Reload(config *logger.Config) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.levels = append(r.levels, *config.Level)
+
+	return r.err
+}
+
+// reloadedLevels returns the reloaded logger levels.
+func (r *testReloadable) reloadedLevels() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return append([]string(nil), r.levels...)
+}
+
+// newTestWatcher creates a watcher of a config file with the content and a reloadable of the logger section.
+func newTestWatcher(t *testing.T, content string) (*Watcher, *testReloadable, string) {
+	t.Helper()
+
+	configPath := filepath.Join(t.TempDir(), "config.json")
+	require.NoError(t, os.WriteFile(configPath, []byte(content), 0600))
+
+	log, err := logger.New(&logger.Config{Level: &[]string{"info"}[0]})
+	require.NoError(t, err)
+
+	reloadable := &testReloadable{}
+	reloader := NewReloader("logger", func(config *Config) *logger.Config {
+		return config.Logger
+	}, reloadable)
+
+	return newWatcher(configPath, log, []Reloader{reloader}), reloadable, configPath
+}
+
+func TestWatcherReload(t *testing.T) {
+	t.Parallel()
+
+	t.Run("apply changed config to reloaders", func(t *testing.T) {
+		t.Parallel()
+
+		watcher, reloadable, configPath := newTestWatcher(t, `{"logger":{"level":"info"}}`)
+
+		// unchanged content is not applied
+		require.NoError(t, watcher.Reload())
+		assert.Empty(t, reloadable.reloadedLevels())
+
+		require.NoError(t, os.WriteFile(configPath, []byte(`{"logger":{"level":"debug"}}`), 0600))
+		require.NoError(t, watcher.Reload())
+		assert.Equal(t, []string{"debug"}, reloadable.reloadedLevels())
+	})
+
+	t.Run("apply defaults to reloaded config", func(t *testing.T) {
+		t.Parallel()
+
+		watcher, reloadable, configPath := newTestWatcher(t, `{"logger":{"level":"debug"}}`)
+
+		require.NoError(t, os.WriteFile(configPath, []byte(`{}`), 0600))
+		require.NoError(t, watcher.Reload())
+		assert.Equal(t, []string{"info"}, reloadable.reloadedLevels())
+	})
+
+	t.Run("keep current config for invalid file", func(t *testing.T) {
+		t.Parallel()
+
+		watcher, reloadable, configPath := newTestWatcher(t, `{"logger":{"level":"info"}}`)
+
+		require.NoError(t, os.WriteFile(configPath, []byte(`{invalid json}`), 0600))
+		require.Error(t, watcher.Reload())
+		assert.Empty(t, reloadable.reloadedLevels())
+
+		// missing file while it is replaced is ignored
+		require.NoError(t, os.Remove(configPath))
+		require.NoError(t, watcher.Reload())
+	})
+
+	t.Run("return error of rejecting reloaders", func(t *testing.T) {
+		t.Parallel()
+
+		watcher, reloadable, configPath := newTestWatcher(t, `{"logger":{"level":"info"}}`)
+		reloadable.err = errReloadRejected
+
+		require.NoError(t, os.WriteFile(configPath, []byte(`{"logger":{"level":"debug"}}`), 0600))
+
+		err := watcher.Reload()
+		require.ErrorIs(t, err, errReloadRejected)
+		assert.Contains(t, err.Error(), "logger")
+
+		// the rejected content is not applied again
+		require.NoError(t, watcher.Reload())
+		assert.Equal(t, []string{"debug"}, reloadable.reloadedLevels())
+	})
+
+	t.Run("apply registered reloaders", func(t *testing.T) {
+		t.Parallel()
+
+		watcher, _, configPath := newTestWatcher(t, `{"logger":{"level":"info"}}`)
+
+		registered := &testReloadable{}
+		watcher.Register(NewReloader("registered", func(config *Config) *logger.Config {
+			return config.Logger
+		}, registered))
+
+		require.NoError(t, os.WriteFile(configPath, []byte(`{"logger":{"level":"warn"}}`), 0600))
+		require.NoError(t, watcher.Reload())
+		assert.Equal(t, []string{"warn"}, registered.reloadedLevels())
+	})
+}
+
+func TestWatcherWatch(t *testing.T) {
+	t.Parallel()
+
+	t.Run("reload when the file is written", func(t *testing.T) {
+		t.Parallel()
+
+		watcher, reloadable, configPath := newTestWatcher(t, `{"logger":{"level":"info"}}`)
+		watcher.debounce = 10 * time.Millisecond
+
+		require.NoError(t, watcher.Start())
+
+		defer func() { require.NoError(t, watcher.Stop()) }()
+
+		require.NoError(t, os.WriteFile(configPath, []byte(`{"logger":{"level":"debug"}}`), 0600))
+
+		require.Eventually(t, func() bool {
+			return len(reloadable.reloadedLevels()) == 1
+		}, 2*time.Second, 10*time.Millisecond)
+	})
+
+	t.Run("reload when the file is replaced", func(t *testing.T) {
+		t.Parallel()
+
+		watcher, reloadable, configPath := newTestWatcher(t, `{"logger":{"level":"info"}}`)
+		watcher.debounce = 10 * time.Millisecond
+
+		require.NoError(t, watcher.Start())
+
+		defer func() { require.NoError(t, watcher.Stop()) }()
+
+		replacement := filepath.Join(filepath.Dir(configPath), "config.json.tmp")
+		require.NoError(t, os.WriteFile(replacement, []byte(`{"logger":{"level":"error"}}`), 0600))
+		require.NoError(t, os.Rename(replacement, configPath))
+
+		require.Eventually(t, func() bool {
+			levels := reloadable.reloadedLevels()
+
+			return len(levels) == 1 && levels[0] == "error"
+		}, 2*time.Second, 10*time.Millisecond)
+	})
+
+	t.Run("stop without start", func(t *testing.T) {
+		t.Parallel()
+
+		watcher, _, _ := newTestWatcher(t, `{}`)
+
+		require.NoError(t, watcher.Stop())
+	})
+}
@@ -0,0 +1,57 @@
+package app
+
+import (
+	"fmt"
+	"io"
+
+	"go.uber.org/fx"
+
+	serverPkg "github.com/pocj8ur4in/boilerplate-go/internal/app/boilerplate/server"
+	databasePkg "github.com/pocj8ur4in/boilerplate-go/internal/pkg/database"
+	redisPkg "github.com/pocj8ur4in/boilerplate-go/internal/pkg/redis"
+)
+
+// graphDeps represents dependencies resolved from the application graph for writing it.
+type graphDeps struct {
+	fx.In
+
+	Graph fx.DotGraph
+	DB    *databasePkg.DB
+	Redis *redisPkg.Redis
+}
+
+// registerGraph serves the dependency graph of the application on the server.
+func registerGraph(server *serverPkg.Server, graph fx.DotGraph) {
+	server.SetGraph(string(graph))
+}
+
+// Graph boots the application graph without starting the server and writes it in the DOT language, render it
+// with `dot -Tsvg`. Constructors run as on startup, so the database and redis of the config must be reachable.
+func Graph(writer io.Writer) error {
+	var deps graphDeps
+
+	config, err := loadConfig()
+	if err != nil {
+		return fmt.Errorf("failed to build application: %w", err)
+	}
+
+	fxApp := fx.New(
+		fx.NopLogger,
+		options(config),
+		fx.Populate(&deps),
+	)
+	if err := fxApp.Err(); err != nil {
+		return fmt.Errorf("failed to build application: %w", err)
+	}
+
+	defer func() {
+		_ = deps.DB.Close()
+		_ = deps.Redis.Close()
+	}()
+
+	if _, err := io.WriteString(writer, string(deps.Graph)); err != nil {
+		return fmt.Errorf("failed to write graph: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,35 @@
+package app
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+//nolint:paralleltest // Cannot run in parallel due to t.Setenv usage
+func TestGraph(t *testing.T) {
+	t.Run("write graph of the application", func(t *testing.T) {
+		beforeTest(t, nil)
+
+		var buf bytes.Buffer
+		require.NoError(t, Graph(&buf))
+
+		graph := buf.String()
+		assert.Contains(t, graph, "digraph {")
+		assert.Contains(t, graph, "*server.Server")
+		assert.Contains(t, graph, "*jwt.JWT")
+	})
+
+	t.Run("return error by using invalid config path", func(t *testing.T) {
+		t.Setenv("CONFIG_PATH", "/non/existent/path/config.json")
+
+		var buf bytes.Buffer
+
+		err := Graph(&buf)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "failed to build application")
+		assert.Empty(t, buf.String())
+	})
+}
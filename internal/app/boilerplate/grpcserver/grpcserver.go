@@ -0,0 +1,327 @@
+// Package grpcserver provides a gRPC server running alongside the HTTP server on its own port, with interceptors
+// mirroring the HTTP middlewares (metrics, logging, recovery and JWT authentication) and health and reflection
+// services.
+package grpcserver
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"sync"
+
+	"go.uber.org/fx"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/reflection"
+
+	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/jwt"
+	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/logger"
+)
+
+var (
+	// ErrServerStarted is returned when registering a service after the server started.
+	ErrServerStarted = errors.New("grpc server already started")
+
+	// ErrInvalidService is returned when registering a service without a description or implementation.
+	ErrInvalidService = errors.New("grpc service requires a description and an implementation")
+)
+
+// Config represents configuration for the gRPC server.
+type Config struct {
+	// Enabled is whether the gRPC server listens.
+	Enabled *bool `json:"enabled"`
+
+	// Host is host the gRPC server listens on.
+	Host *string `json:"host"`
+
+	// Port is port the gRPC server listens on, separate from the HTTP server.
+	Port *int `json:"port"`
+
+	// Reflection is whether the reflection service is registered, so that tools like grpcurl can list and call
+	// services without their proto files.
+	Reflection *bool `json:"reflection"`
+
+	// MaxRecvMsgSize is maximum size in bytes of messages received from clients.
+	MaxRecvMsgSize *int `json:"max_recv_msg_size"`
+}
+
+// SetDefault sets default values.
+func (c *Config) SetDefault() {
+	if c.Enabled == nil {
+		c.Enabled = &[]bool{false}[0]
+	}
+
+	if c.Host == nil {
+		c.Host = &[]string{"0.0.0.0"}[0]
+	}
+
+	if c.Port == nil {
+		c.Port = &[]int{9090}[0]
+	}
+
+	if c.Reflection == nil {
+		c.Reflection = &[]bool{false}[0]
+	}
+
+	if c.MaxRecvMsgSize == nil {
+		c.MaxRecvMsgSize = &[]int{4 << 20}[0] // 4MB
+	}
+}
+
+// Service represents a gRPC service, modules provide services in the grpc_services group.
+type Service struct {
+	// Desc is description of the service generated by protoc-gen-go-grpc (e.g. pb.Users_ServiceDesc).
+	Desc *grpc.ServiceDesc
+
+	// Impl is implementation of the service.
+	Impl any
+
+	// Public is names of methods callable without a token (e.g. Login), other methods require a valid
+	// access token in the authorization metadata.
+	Public []string
+}
+
+// ServicesParams represents services registered by modules.
+type ServicesParams struct {
+	fx.In
+
+	Server   *Server
+	Services []Service `group:"grpc_services"`
+}
+
+// Server represents the gRPC server.
+type Server struct {
+	// config provides gRPC server configuration.
+	config *Config
+
+	// logger provides logger.
+	logger *logger.Logger
+
+	// jwt validates access tokens of calls.
+	jwt *jwt.JWT
+
+	// server provides gRPC server.
+	server *grpc.Server
+
+	// health provides the health service.
+	health *health.Server
+
+	// metrics provides prometheus collectors of calls.
+	metrics *metrics
+
+	// mu guards public, services and listener.
+	mu sync.RWMutex
+
+	// public is full names of methods callable without a token.
+	public map[string]struct{}
+
+	// services is names of registered services, reported by the health service.
+	services []string
+
+	// listener is listener of the server, nil until started.
+	listener net.Listener
+
+	// done is closed when the server stops serving.
+	done chan struct{}
+}
+
+// NewModule provides module for the gRPC server.
+func NewModule() fx.Option {
+	return fx.Module("grpcserver",
+		fx.Provide(New),
+		fx.Invoke(registerServices),
+	)
+}
+
+// registerServices registers services provided by modules, e.g. with
+// fx.Annotate(newUsersService, fx.ResultTags(`group:"grpc_services"`)).
+func registerServices(params ServicesParams) error {
+	for _, service := range params.Services {
+		if err := params.Server.Register(service); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// New creates a gRPC server.
+func New(config *Config, logger *logger.Logger, jwtService *jwt.JWT) *Server {
+	if config == nil {
+		config = &Config{}
+	}
+
+	config.SetDefault()
+
+	server := &Server{
+		config:  config,
+		logger:  logger.Named("grpc"),
+		jwt:     jwtService,
+		health:  health.NewServer(),
+		metrics: newMetrics(),
+		public:  make(map[string]struct{}),
+	}
+
+	// the outermost interceptor observes the status of calls recovered by the inner ones
+	server.server = grpc.NewServer(
+		grpc.MaxRecvMsgSize(*config.MaxRecvMsgSize),
+		grpc.ChainUnaryInterceptor(
+			server.metricsUnary, server.logUnary, server.recoverUnary, server.authUnary,
+		),
+		grpc.ChainStreamInterceptor(
+			server.metricsStream, server.logStream, server.recoverStream, server.authStream,
+		),
+	)
+
+	healthpb.RegisterHealthServer(server.server, server.health)
+
+	if *config.Reflection {
+		reflection.Register(server.server)
+	}
+
+	return server
+}
+
+// Enabled returns whether the gRPC server listens.
+func (s *Server) Enabled() bool {
+	return *s.config.Enabled
+}
+
+// Register registers the service, services must be registered before the server starts.
+func (s *Server) Register(service Service) error {
+	if service.Desc == nil || service.Impl == nil {
+		return ErrInvalidService
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.listener != nil {
+		return fmt.Errorf("%w: %s", ErrServerStarted, service.Desc.ServiceName)
+	}
+
+	s.server.RegisterService(service.Desc, service.Impl)
+	s.services = append(s.services, service.Desc.ServiceName)
+
+	for _, method := range service.Public {
+		s.public["/"+service.Desc.ServiceName+"/"+method] = struct{}{}
+	}
+
+	return nil
+}
+
+// Start listens on the configured address and serves calls in the background, it does nothing if the server
+// is disabled.
+func (s *Server) Start() error {
+	if !s.Enabled() {
+		s.logger.Info().Msg("grpc server is disabled, skipping start")
+
+		return nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.listener != nil {
+		return nil
+	}
+
+	addr := net.JoinHostPort(*s.config.Host, strconv.Itoa(*s.config.Port))
+
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return fmt.Errorf("failed to listen grpc server: %w", err)
+	}
+
+	s.listener = listener
+	s.done = make(chan struct{})
+
+	// the overall status is reported under the empty service name
+	s.health.SetServingStatus("", healthpb.HealthCheckResponse_SERVING)
+
+	for _, service := range s.services {
+		s.health.SetServingStatus(service, healthpb.HealthCheckResponse_SERVING)
+	}
+
+	s.logger.Info().Str("addr", listener.Addr().String()).Msg("starting grpc server")
+
+	go func() {
+		defer close(s.done)
+
+		if err := s.server.Serve(listener); err != nil {
+			s.logger.Error().Err(err).Msg("grpc server failed to serve")
+		}
+	}()
+
+	return nil
+}
+
+// Addr returns the address the server listens on, empty if it is not started.
+func (s *Server) Addr() string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if s.listener == nil {
+		return ""
+	}
+
+	return s.listener.Addr().String()
+}
+
+// Shutdown reports the services as not serving and stops the server once in-flight calls complete, or
+// cancels them when the context is done.
+func (s *Server) Shutdown(ctx context.Context) error {
+	s.mu.RLock()
+	done := s.done
+	s.mu.RUnlock()
+
+	if done == nil {
+		return nil
+	}
+
+	// health checks of balancers fail first, so that they stop sending calls
+	s.health.Shutdown()
+
+	stopped := make(chan struct{})
+
+	go func() {
+		s.server.GracefulStop()
+		close(stopped)
+	}()
+
+	select {
+	case <-stopped:
+		<-done
+
+		return nil
+	case <-ctx.Done():
+	}
+
+	s.server.Stop()
+	<-done
+
+	return fmt.Errorf("failed to stop grpc server gracefully: %w", ctx.Err())
+}
+
+// isPublic returns whether the method is callable without a token, the health and reflection services
+// are always public.
+func (s *Server) isPublic(fullMethod string) bool {
+	if service, _, ok := splitMethod(fullMethod); ok {
+		switch service {
+		case healthpb.Health_ServiceDesc.ServiceName,
+			"grpc.reflection.v1.ServerReflection",
+			"grpc.reflection.v1alpha.ServerReflection":
+			return true
+		}
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	_, ok := s.public[fullMethod]
+
+	return ok
+}
@@ -0,0 +1,265 @@
+package grpcserver
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	reflectionpb "google.golang.org/grpc/reflection/grpc_reflection_v1"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+
+	"github.com/pocj8ur4in/boilerplate-go/internal/app/boilerplate/server/middleware"
+	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/jwt"
+	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/logger"
+)
+
+// echoService is a test service replying with the user of the call.
+type echoService interface {
+	Ping(ctx context.Context) (string, error)
+}
+
+// echoServer implements echoService.
+type echoServer struct{}
+
+// Ping returns the user of the call, panicking for the panic user.
+func (echoServer) Ping(ctx context.Context) (string, error) {
+	userID, _ := ctx.Value(middleware.UserIDKey).(string)
+	if userID == "panic" {
+		panic("ping panicked")
+	}
+
+	return userID, nil
+}
+
+// echoServiceDesc describes echoService with methods taking and returning empty messages.
+var echoServiceDesc = grpc.ServiceDesc{
+	ServiceName: "test.Echo",
+	HandlerType: (*echoService)(nil),
+	Methods: []grpc.MethodDesc{
+		{MethodName: "Ping", Handler: echoHandler("Ping")},
+		{MethodName: "PublicPing", Handler: echoHandler("PublicPing")},
+	},
+}
+
+// echoHandler returns the handler of the method of echoService.
+func echoHandler(method string) grpc.MethodHandler {
+	return func(srv any, ctx context.Context, dec func(any) error, interceptor grpc.UnaryServerInterceptor) (
+		any, error,
+	) {
+		if err := dec(&emptypb.Empty{}); err != nil {
+			return nil, err
+		}
+
+		handler := func(ctx context.Context, _ any) (any, error) {
+			userID, err := srv.(echoService).Ping(ctx)
+			if err != nil {
+				return nil, err
+			}
+
+			// the user is reported in the header, so that replies stay empty
+			if err := grpc.SetHeader(ctx, metadata.Pairs("user-id", userID)); err != nil {
+				return nil, err
+			}
+
+			return &emptypb.Empty{}, nil
+		}
+
+		info := &grpc.UnaryServerInfo{Server: srv, FullMethod: "/test.Echo/" + method}
+
+		return interceptor(ctx, &emptypb.Empty{}, info, handler)
+	}
+}
+
+// setupTestJWT creates a test JWT.
+func setupTestJWT(t *testing.T) *jwt.JWT {
+	t.Helper()
+
+	jwtService, err := jwt.New(&jwt.Config{SecretKey: &[]string{"test-secret-key"}[0]})
+	require.NoError(t, err)
+
+	return jwtService
+}
+
+// setupTestServer creates and starts a test gRPC server on a random port with the echo service.
+func setupTestServer(t *testing.T, config *Config) (*Server, *grpc.ClientConn) {
+	t.Helper()
+
+	log, err := logger.New(&logger.Config{})
+	require.NoError(t, err)
+
+	config.Enabled = &[]bool{true}[0]
+	config.Host = &[]string{"127.0.0.1"}[0]
+	config.Port = &[]int{0}[0]
+
+	server := New(config, log, setupTestJWT(t))
+	echo := Service{Desc: &echoServiceDesc, Impl: echoServer{}, Public: []string{"PublicPing"}}
+	require.NoError(t, server.Register(echo))
+	require.NoError(t, server.Start())
+
+	t.Cleanup(func() {
+		_ = server.Shutdown(context.Background())
+	})
+
+	conn, err := grpc.NewClient(server.Addr(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	require.NoError(t, err)
+
+	t.Cleanup(func() {
+		_ = conn.Close()
+	})
+
+	return server, conn
+}
+
+func TestConfigSetDefault(t *testing.T) {
+	t.Parallel()
+
+	config := &Config{}
+	config.SetDefault()
+
+	assert.False(t, *config.Enabled)
+	assert.Equal(t, "0.0.0.0", *config.Host)
+	assert.Equal(t, 9090, *config.Port)
+	assert.False(t, *config.Reflection)
+	assert.Equal(t, 4<<20, *config.MaxRecvMsgSize)
+}
+
+func TestServerDisabled(t *testing.T) {
+	t.Parallel()
+
+	log, err := logger.New(&logger.Config{})
+	require.NoError(t, err)
+
+	server := New(nil, log, nil)
+
+	require.NoError(t, server.Start())
+	assert.Empty(t, server.Addr())
+	require.NoError(t, server.Shutdown(t.Context()))
+}
+
+func TestServerRegister(t *testing.T) {
+	t.Parallel()
+
+	server, _ := setupTestServer(t, &Config{})
+
+	require.ErrorIs(t, server.Register(Service{}), ErrInvalidService)
+	require.ErrorIs(t, server.Register(Service{Desc: &echoServiceDesc, Impl: echoServer{}}), ErrServerStarted)
+}
+
+func TestServerHealth(t *testing.T) {
+	t.Parallel()
+
+	server, conn := setupTestServer(t, &Config{})
+	client := healthpb.NewHealthClient(conn)
+
+	for _, service := range []string{"", "test.Echo"} {
+		response, err := client.Check(t.Context(), &healthpb.HealthCheckRequest{Service: service})
+		require.NoError(t, err)
+		assert.Equal(t, healthpb.HealthCheckResponse_SERVING, response.GetStatus())
+	}
+
+	_, err := client.Check(t.Context(), &healthpb.HealthCheckRequest{Service: "unknown"})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+
+	require.NoError(t, server.Shutdown(t.Context()))
+}
+
+func TestServerReflection(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name       string
+		reflection bool
+		expected   codes.Code
+	}{
+		{name: "reflection enabled", reflection: true, expected: codes.OK},
+		{name: "reflection disabled", reflection: false, expected: codes.Unimplemented},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			_, conn := setupTestServer(t, &Config{Reflection: &test.reflection})
+
+			stream, err := reflectionpb.NewServerReflectionClient(conn).ServerReflectionInfo(t.Context())
+			require.NoError(t, err)
+
+			request := &reflectionpb.ServerReflectionRequest{
+				MessageRequest: &reflectionpb.ServerReflectionRequest_ListServices{},
+			}
+			require.NoError(t, stream.Send(request))
+
+			_, err = stream.Recv()
+			assert.Equal(t, test.expected, status.Code(err))
+		})
+	}
+}
+
+func TestServerAuth(t *testing.T) {
+	t.Parallel()
+
+	_, conn := setupTestServer(t, &Config{})
+
+	token, err := setupTestJWT(t).GenerateAccessToken("user-1", "user@example.com", "user")
+	require.NoError(t, err)
+
+	panicToken, err := setupTestJWT(t).GenerateAccessToken("panic", "panic@example.com", "user")
+	require.NoError(t, err)
+
+	tests := []struct {
+		name          string
+		method        string
+		authorization string
+		expectedCode  codes.Code
+		expectedUser  string
+	}{
+		{name: "missing token", method: "Ping", expectedCode: codes.Unauthenticated},
+		{name: "invalid format", method: "Ping", authorization: *token, expectedCode: codes.Unauthenticated},
+		{name: "invalid token", method: "Ping", authorization: "Bearer invalid", expectedCode: codes.Unauthenticated},
+		{name: "valid token", method: "Ping", authorization: "Bearer " + *token, expectedUser: "user-1"},
+		{name: "public method", method: "PublicPing", expectedCode: codes.OK},
+		{name: "panic recovered", method: "Ping", authorization: "Bearer " + *panicToken, expectedCode: codes.Internal},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctx := t.Context()
+			if test.authorization != "" {
+				ctx = metadata.AppendToOutgoingContext(ctx, "authorization", test.authorization)
+			}
+
+			var header metadata.MD
+
+			err := conn.Invoke(ctx, "/test.Echo/"+test.method, &emptypb.Empty{}, &emptypb.Empty{}, grpc.Header(&header))
+			require.Equal(t, test.expectedCode, status.Code(err))
+
+			if test.expectedUser != "" {
+				assert.Equal(t, []string{test.expectedUser}, header.Get("user-id"))
+			}
+		})
+	}
+}
+
+func TestServerShutdown(t *testing.T) {
+	t.Parallel()
+
+	server, conn := setupTestServer(t, &Config{})
+
+	require.NoError(t, server.Shutdown(t.Context()))
+
+	ctx, cancel := context.WithTimeout(t.Context(), time.Second)
+	defer cancel()
+
+	_, err := healthpb.NewHealthClient(conn).Check(ctx, &healthpb.HealthCheckRequest{})
+	require.Error(t, err)
+}
@@ -0,0 +1,195 @@
+package grpcserver
+
+import (
+	"context"
+	"fmt"
+	"runtime/debug"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+
+	"github.com/pocj8ur4in/boilerplate-go/internal/app/boilerplate/server/middleware"
+)
+
+// contextStream is a server stream with a context replaced by an interceptor.
+type contextStream struct {
+	grpc.ServerStream
+
+	// ctx is context of the stream.
+	ctx context.Context //nolint:containedctx // streams carry their context
+}
+
+// Context returns the context of the stream.
+func (s *contextStream) Context() context.Context {
+	return s.ctx
+}
+
+// metricsUnary is an interceptor that counts unary calls by method and status code and observes their duration.
+func (s *Server) metricsUnary(
+	ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler,
+) (any, error) {
+	start := time.Now()
+
+	resp, err := handler(ctx, req)
+	s.metrics.observe(info.FullMethod, status.Code(err), time.Since(start))
+
+	return resp, err
+}
+
+// metricsStream is an interceptor that counts streams by method and status code and observes their duration.
+func (s *Server) metricsStream(
+	srv any, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler,
+) error {
+	start := time.Now()
+
+	err := handler(srv, stream)
+	s.metrics.observe(info.FullMethod, status.Code(err), time.Since(start))
+
+	return err
+}
+
+// logUnary is an interceptor that logs unary calls.
+func (s *Server) logUnary(
+	ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler,
+) (any, error) {
+	start := time.Now()
+
+	resp, err := handler(ctx, req)
+	s.logCall(ctx, info.FullMethod, err, start)
+
+	return resp, err
+}
+
+// logStream is an interceptor that logs streams.
+func (s *Server) logStream(
+	srv any, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler,
+) error {
+	start := time.Now()
+
+	err := handler(srv, stream)
+	s.logCall(stream.Context(), info.FullMethod, err, start)
+
+	return err
+}
+
+// logCall logs the call of the method.
+func (s *Server) logCall(ctx context.Context, fullMethod string, err error, start time.Time) {
+	log := s.logger.Ctx(ctx).Debug().
+		Str("method", fullMethod).
+		Str("code", status.Code(err).String()).
+		Dur("duration", time.Since(start))
+
+	if remote, ok := peer.FromContext(ctx); ok {
+		log = log.Str("remote_addr", remote.Addr.String())
+	}
+
+	if err != nil {
+		log = log.Err(err)
+	}
+
+	log.Msg("grpc request")
+}
+
+// recoverUnary is an interceptor that recovers panics of unary calls, failing them with the internal code.
+func (s *Server) recoverUnary(
+	ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler,
+) (resp any, err error) {
+	defer s.recoverCall(ctx, info.FullMethod, &err)
+
+	return handler(ctx, req)
+}
+
+// recoverStream is an interceptor that recovers panics of streams, failing them with the internal code.
+func (s *Server) recoverStream(
+	srv any, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler,
+) (err error) {
+	defer s.recoverCall(stream.Context(), info.FullMethod, &err)
+
+	return handler(srv, stream)
+}
+
+// recoverCall logs the recovered panic of the call and replaces its error, it must be deferred.
+func (s *Server) recoverCall(ctx context.Context, fullMethod string, err *error) {
+	recovered := recover()
+	if recovered == nil {
+		return
+	}
+
+	s.logger.Ctx(ctx).Error().
+		Str("panic", fmt.Sprint(recovered)).
+		Str("method", fullMethod).
+		Bytes("stack", debug.Stack()).
+		Msg("panic recovered")
+
+	*err = status.Error(codes.Internal, "internal error")
+}
+
+// authUnary is an interceptor that authenticates unary calls of non-public methods by their access token.
+func (s *Server) authUnary(
+	ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler,
+) (any, error) {
+	ctx, err := s.authenticate(ctx, info.FullMethod)
+	if err != nil {
+		return nil, err
+	}
+
+	return handler(ctx, req)
+}
+
+// authStream is an interceptor that authenticates streams of non-public methods by their access token.
+func (s *Server) authStream(
+	srv any, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler,
+) error {
+	ctx, err := s.authenticate(stream.Context(), info.FullMethod)
+	if err != nil {
+		return err
+	}
+
+	return handler(srv, &contextStream{ServerStream: stream, ctx: ctx})
+}
+
+// authenticate validates the bearer token of the authorization metadata unless the method is public, and
+// returns the context with the user of the token under the keys of the HTTP JWT middleware.
+func (s *Server) authenticate(ctx context.Context, fullMethod string) (context.Context, error) {
+	if s.isPublic(fullMethod) {
+		return ctx, nil
+	}
+
+	values := metadata.ValueFromIncomingContext(ctx, "authorization")
+	if len(values) == 0 {
+		return nil, status.Error(codes.Unauthenticated, "missing authorization metadata")
+	}
+
+	token, ok := strings.CutPrefix(values[0], "Bearer ")
+	if !ok || token == "" {
+		return nil, status.Error(codes.Unauthenticated, "invalid authorization metadata format")
+	}
+
+	if s.jwt == nil {
+		return nil, status.Error(codes.Unauthenticated, "authentication is not available")
+	}
+
+	claims, err := s.jwt.ValidateToken(token)
+	if err != nil {
+		s.logger.Ctx(ctx).Debug().Err(err).Str("method", fullMethod).Msg("token validation failed")
+
+		return nil, status.Error(codes.Unauthenticated, "invalid token")
+	}
+
+	ctx = context.WithValue(ctx, middleware.UserIDKey, claims.UserID)
+	ctx = context.WithValue(ctx, middleware.UserEmailKey, claims.Email)
+	ctx = context.WithValue(ctx, middleware.UserRoleKey, claims.Role)
+	ctx = context.WithValue(ctx, middleware.ClaimsKey, claims)
+
+	return ctx, nil
+}
+
+// splitMethod splits the full method name (/package.Service/Method) into its service and method.
+func splitMethod(fullMethod string) (string, string, bool) {
+	return strings.Cut(strings.TrimPrefix(fullMethod, "/"), "/")
+}
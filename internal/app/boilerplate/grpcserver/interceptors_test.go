@@ -0,0 +1,140 @@
+package grpcserver
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/pocj8ur4in/boilerplate-go/internal/app/boilerplate/server/middleware"
+	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/jwt"
+	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/logger"
+)
+
+// testStream is a server stream with a context.
+type testStream struct {
+	grpc.ServerStream
+
+	// ctx is context of the stream.
+	ctx context.Context //nolint:containedctx // streams carry their context
+}
+
+// Context returns the context of the stream.
+func (s *testStream) Context() context.Context {
+	return s.ctx
+}
+
+// setupTestInterceptors creates a server, not started, for calling its interceptors.
+func setupTestInterceptors(t *testing.T) *Server {
+	t.Helper()
+
+	log, err := logger.New(&logger.Config{})
+	require.NoError(t, err)
+
+	return New(nil, log, setupTestJWT(t))
+}
+
+func TestAuthenticate(t *testing.T) {
+	t.Parallel()
+
+	server := setupTestInterceptors(t)
+	echo := Service{Desc: &echoServiceDesc, Impl: echoServer{}, Public: []string{"PublicPing"}}
+	require.NoError(t, server.Register(echo))
+
+	token, err := setupTestJWT(t).GenerateAccessToken("user-1", "user@example.com", "admin")
+	require.NoError(t, err)
+
+	incoming := metadata.NewIncomingContext(t.Context(), metadata.Pairs("authorization", "Bearer "+*token))
+
+	ctx, err := server.authenticate(incoming, "/test.Echo/Ping")
+	require.NoError(t, err)
+	assert.Equal(t, "user-1", ctx.Value(middleware.UserIDKey))
+	assert.Equal(t, "user@example.com", ctx.Value(middleware.UserEmailKey))
+	assert.Equal(t, "admin", ctx.Value(middleware.UserRoleKey))
+	assert.IsType(t, &jwt.Claims{}, ctx.Value(middleware.ClaimsKey))
+
+	_, err = server.authenticate(t.Context(), "/test.Echo/Ping")
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+
+	// public methods and the health service skip authentication
+	_, err = server.authenticate(t.Context(), "/test.Echo/PublicPing")
+	require.NoError(t, err)
+
+	_, err = server.authenticate(t.Context(), "/grpc.health.v1.Health/Check")
+	require.NoError(t, err)
+}
+
+func TestRecoverUnary(t *testing.T) {
+	t.Parallel()
+
+	server := setupTestInterceptors(t)
+	info := &grpc.UnaryServerInfo{FullMethod: "/test.Echo/Ping"}
+
+	_, err := server.recoverUnary(t.Context(), nil, info, func(context.Context, any) (any, error) {
+		panic("unary panicked")
+	})
+	assert.Equal(t, codes.Internal, status.Code(err))
+
+	resp, err := server.recoverUnary(t.Context(), nil, info, func(context.Context, any) (any, error) {
+		return "ok", nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "ok", resp)
+}
+
+func TestRecoverStream(t *testing.T) {
+	t.Parallel()
+
+	server := setupTestInterceptors(t)
+	stream := &testStream{ctx: t.Context()}
+	info := &grpc.StreamServerInfo{FullMethod: "/test.Echo/Watch"}
+
+	err := server.recoverStream(nil, stream, info, func(any, grpc.ServerStream) error {
+		panic("stream panicked")
+	})
+	assert.Equal(t, codes.Internal, status.Code(err))
+}
+
+func TestAuthStream(t *testing.T) {
+	t.Parallel()
+
+	server := setupTestInterceptors(t)
+	info := &grpc.StreamServerInfo{FullMethod: "/test.Echo/Watch"}
+
+	token, err := setupTestJWT(t).GenerateAccessToken("user-1", "user@example.com", "user")
+	require.NoError(t, err)
+
+	ctx := metadata.NewIncomingContext(t.Context(), metadata.Pairs("authorization", "Bearer "+*token))
+
+	var userID any
+
+	err = server.authStream(nil, &testStream{ctx: ctx}, info, func(_ any, stream grpc.ServerStream) error {
+		userID = stream.Context().Value(middleware.UserIDKey)
+
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "user-1", userID)
+
+	err = server.authStream(nil, &testStream{ctx: t.Context()}, info, func(any, grpc.ServerStream) error {
+		return nil
+	})
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+}
+
+func TestSplitMethod(t *testing.T) {
+	t.Parallel()
+
+	service, method, ok := splitMethod("/grpc.health.v1.Health/Check")
+	assert.True(t, ok)
+	assert.Equal(t, "grpc.health.v1.Health", service)
+	assert.Equal(t, "Check", method)
+
+	_, _, ok = splitMethod("malformed")
+	assert.False(t, ok)
+}
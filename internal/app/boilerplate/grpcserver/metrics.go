@@ -0,0 +1,55 @@
+package grpcserver
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"google.golang.org/grpc/codes"
+)
+
+// metrics represents prometheus collectors of gRPC calls.
+type metrics struct {
+	// handled is counter of completed calls by service, method and status code.
+	handled *prometheus.CounterVec
+
+	// duration is histogram of durations of calls by service and method.
+	duration *prometheus.HistogramVec
+}
+
+// newMetrics creates collectors of gRPC calls.
+func newMetrics() *metrics {
+	return &metrics{
+		handled: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "grpc_server_handled_total",
+			Help: "Total number of gRPC calls completed on the server",
+		}, []string{"service", "method", "code"}),
+		duration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "grpc_server_handling_seconds",
+			Help:    "Duration of gRPC calls in seconds",
+			Buckets: prometheus.DefBuckets,
+		}, []string{"service", "method"}),
+	}
+}
+
+// observe records the completed call of the method.
+func (m *metrics) observe(fullMethod string, code codes.Code, duration time.Duration) {
+	service, method, ok := splitMethod(fullMethod)
+	if !ok {
+		service, method = "unknown", fullMethod
+	}
+
+	m.handled.WithLabelValues(service, method, code.String()).Inc()
+	m.duration.WithLabelValues(service, method).Observe(duration.Seconds())
+}
+
+// Describe sends descriptors of the gRPC metrics.
+func (s *Server) Describe(descs chan<- *prometheus.Desc) {
+	s.metrics.handled.Describe(descs)
+	s.metrics.duration.Describe(descs)
+}
+
+// Collect sends the gRPC metrics.
+func (s *Server) Collect(collected chan<- prometheus.Metric) {
+	s.metrics.handled.Collect(collected)
+	s.metrics.duration.Collect(collected)
+}
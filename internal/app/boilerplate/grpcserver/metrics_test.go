@@ -0,0 +1,24 @@
+package grpcserver
+
+import (
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+)
+
+func TestMetricsObserve(t *testing.T) {
+	t.Parallel()
+
+	metrics := newMetrics()
+	metrics.observe("/test.Echo/Ping", codes.OK, time.Millisecond)
+	metrics.observe("/test.Echo/Ping", codes.Unauthenticated, time.Millisecond)
+	metrics.observe("malformed", codes.Unimplemented, time.Millisecond)
+
+	assert.InDelta(t, 1, testutil.ToFloat64(metrics.handled.WithLabelValues("test.Echo", "Ping", "OK")), 0)
+	assert.InDelta(t, 1, testutil.ToFloat64(metrics.handled.WithLabelValues("test.Echo", "Ping", "Unauthenticated")), 0)
+	assert.InDelta(t, 1, testutil.ToFloat64(metrics.handled.WithLabelValues("unknown", "malformed", "Unimplemented")), 0)
+	assert.Equal(t, 2, testutil.CollectAndCount(metrics.duration))
+}
@@ -0,0 +1,106 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	configPkg "github.com/pocj8ur4in/boilerplate-go/internal/app/boilerplate/config"
+	loggerPkg "github.com/pocj8ur4in/boilerplate-go/internal/pkg/logger"
+	migrationsPkg "github.com/pocj8ur4in/boilerplate-go/internal/pkg/migrations"
+	"github.com/pocj8ur4in/boilerplate-go/sql/schema"
+)
+
+// ErrUnknownMigrateCommand returned when the migrate command is not up, down or status.
+var ErrUnknownMigrateCommand = errors.New("unknown migrate command")
+
+const (
+	// MigrateUp applies pending migrations.
+	MigrateUp = "up"
+
+	// MigrateDown rolls the latest applied migration back.
+	MigrateDown = "down"
+
+	// MigrateStatus reports status of the migrations.
+	MigrateStatus = "status"
+)
+
+// Migrate runs the migrate command on the database of the config file and writes its result.
+func Migrate(ctx context.Context, command string, writer io.Writer) error {
+	if command != MigrateUp && command != MigrateDown && command != MigrateStatus {
+		return fmt.Errorf("%w: %s", ErrUnknownMigrateCommand, command)
+	}
+
+	config, err := configPkg.LoadFromFile()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+
+	logger, err := loggerPkg.New(config.Logger)
+	if err != nil {
+		return fmt.Errorf("failed to create logger: %w", err)
+	}
+
+	db, err := migrationsPkg.Open(config.Database)
+	if err != nil {
+		return err
+	}
+
+	defer func() { _ = db.Close() }()
+
+	migrator, err := migrationsPkg.New(db, schema.FS, *config.Database.Migrations.LockTimeout, logger)
+	if err != nil {
+		return err
+	}
+
+	switch command {
+	case MigrateUp:
+		applied, err := migrator.Up(ctx)
+
+		// migrations applied before a failure are reported too
+		for _, migration := range applied {
+			if _, err := fmt.Fprintf(writer, "applied %05d %s\n", migration.Version, migration.Name); err != nil {
+				return fmt.Errorf("failed to write migrations: %w", err)
+			}
+		}
+
+		return err
+	case MigrateDown:
+		migration, err := migrator.Down(ctx)
+		if err != nil {
+			return err
+		}
+
+		if _, err := fmt.Fprintf(writer, "rolled back %05d %s\n", migration.Version, migration.Name); err != nil {
+			return fmt.Errorf("failed to write migrations: %w", err)
+		}
+
+		return nil
+	default:
+		statuses, err := migrator.Status(ctx)
+		if err != nil {
+			return err
+		}
+
+		return writeMigrationStatus(writer, statuses)
+	}
+}
+
+// writeMigrationStatus writes status of the migrations in a human readable format.
+func writeMigrationStatus(writer io.Writer, statuses []*migrationsPkg.Status) error {
+	for _, status := range statuses {
+		state := "pending"
+		if status.AppliedAt != nil {
+			state = "applied " + status.AppliedAt.UTC().Format(time.RFC3339)
+		}
+
+		line := fmt.Sprintf("%05d %-40s %s", status.Migration.Version, status.Migration.Name, state)
+		if _, err := fmt.Fprintln(writer, line); err != nil {
+			return fmt.Errorf("failed to write migrations: %w", err)
+		}
+	}
+
+	return nil
+}
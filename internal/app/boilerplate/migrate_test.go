@@ -0,0 +1,47 @@
+package app
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	migrationsPkg "github.com/pocj8ur4in/boilerplate-go/internal/pkg/migrations"
+)
+
+//nolint:paralleltest // Cannot run in parallel due to t.Setenv usage
+func TestMigrate(t *testing.T) {
+	t.Run("return error for unknown command", func(t *testing.T) {
+		err := Migrate(context.Background(), "sideways", &bytes.Buffer{})
+		require.ErrorIs(t, err, ErrUnknownMigrateCommand)
+	})
+
+	t.Run("return error by using invalid config path", func(t *testing.T) {
+		t.Setenv("CONFIG_PATH", "/non/existent/path/config.json")
+
+		err := Migrate(context.Background(), MigrateStatus, &bytes.Buffer{})
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "failed to load config")
+	})
+}
+
+func TestWriteMigrationStatus(t *testing.T) {
+	t.Parallel()
+
+	appliedAt := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	var buf bytes.Buffer
+
+	require.NoError(t, writeMigrationStatus(&buf, []*migrationsPkg.Status{
+		{Migration: &migrationsPkg.Migration{Version: 1, Name: "create_users"}, AppliedAt: &appliedAt},
+		{Migration: &migrationsPkg.Migration{Version: 2, Name: "create_settings"}},
+	}))
+
+	lines := bytes.Split(bytes.TrimSpace(buf.Bytes()), []byte("\n"))
+	require.Len(t, lines, 2)
+	assert.Regexp(t, `^00001 create_users\s+applied 2024-01-01T00:00:00Z$`, string(lines[0]))
+	assert.Regexp(t, `^00002 create_settings\s+pending$`, string(lines[1]))
+}
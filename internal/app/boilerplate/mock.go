@@ -0,0 +1,81 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	configPkg "github.com/pocj8ur4in/boilerplate-go/internal/app/boilerplate/config"
+	serverPkg "github.com/pocj8ur4in/boilerplate-go/internal/app/boilerplate/server"
+	loggerPkg "github.com/pocj8ur4in/boilerplate-go/internal/pkg/logger"
+)
+
+// mockShutdownTimeout is time in-flight requests of the mock server have to finish on shutdown.
+const mockShutdownTimeout = 5 * time.Second
+
+// RunMock serves responses generated from the OpenAPI spec on the server address until the context is done,
+// without connecting to the database or redis. Defaults and environment variables are used if the config file
+// does not exist.
+func RunMock(ctx context.Context) error {
+	config, err := configPkg.LoadFromFile()
+	if errors.Is(err, fs.ErrNotExist) {
+		config = configPkg.New()
+
+		if err := configPkg.ApplyEnv(config); err != nil {
+			return fmt.Errorf("failed to load config: %w", err)
+		}
+
+		config.SetDefault()
+	} else if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+
+	logger, err := loggerPkg.New(config.Logger)
+	if err != nil {
+		return fmt.Errorf("failed to create logger: %w", err)
+	}
+
+	handler, err := serverPkg.NewMockHandler(config.Server, logger)
+	if err != nil {
+		return err
+	}
+
+	addr := net.JoinHostPort(*config.Server.Host, strconv.Itoa(*config.Server.Port))
+	server := &http.Server{
+		Addr:              addr,
+		Handler:           handler,
+		ReadTimeout:       time.Duration(*config.Server.ReadTimeout) * time.Second,
+		ReadHeaderTimeout: time.Duration(*config.Server.ReadHeaderTimeout) * time.Second,
+		WriteTimeout:      time.Duration(*config.Server.WriteTimeout) * time.Second,
+		IdleTimeout:       time.Duration(*config.Server.IdleTimeout) * time.Second,
+		MaxHeaderBytes:    *config.Server.MaxHeaderBytes,
+	}
+
+	errs := make(chan error, 1)
+
+	go func() {
+		logger.Info().Str("addr", addr).Msg("serving mock responses of the openapi spec")
+
+		errs <- server.ListenAndServe()
+	}()
+
+	select {
+	case err := <-errs:
+		return fmt.Errorf("failed to run mock server: %w", err)
+	case <-ctx.Done():
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), mockShutdownTimeout)
+	defer cancel()
+
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		return fmt.Errorf("failed to shutdown mock server: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,46 @@
+package app
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+//nolint:paralleltest // Cannot run in parallel due to t.Setenv usage
+func TestRunMock(t *testing.T) {
+	t.Run("return error by using malformed config", func(t *testing.T) {
+		configPath := filepath.Join(t.TempDir(), "config.json")
+		require.NoError(t, os.WriteFile(configPath, []byte("{"), 0o600))
+		t.Setenv("CONFIG_PATH", configPath)
+
+		err := RunMock(context.Background())
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "failed to load config")
+	})
+
+	t.Run("shutdown when context is done", func(t *testing.T) {
+		configPath := filepath.Join(t.TempDir(), "config.json")
+		require.NoError(t, os.WriteFile(configPath, []byte(`{"server":{"host":"127.0.0.1","port":0}}`), 0o600))
+		t.Setenv("CONFIG_PATH", configPath)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		require.NoError(t, RunMock(ctx))
+	})
+
+	t.Run("use defaults without config file", func(t *testing.T) {
+		t.Setenv("CONFIG_PATH", filepath.Join(t.TempDir(), "config.json"))
+		t.Setenv("BOILERPLATE_SERVER_HOST", "127.0.0.1")
+		t.Setenv("BOILERPLATE_SERVER_PORT", "0")
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		require.NoError(t, RunMock(ctx))
+	})
+}
@@ -0,0 +1,264 @@
+package app
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	"go.uber.org/fx"
+
+	"github.com/pocj8ur4in/boilerplate-go/internal/gen/api"
+	databasePkg "github.com/pocj8ur4in/boilerplate-go/internal/pkg/database"
+	jwtPkg "github.com/pocj8ur4in/boilerplate-go/internal/pkg/jwt"
+	redisPkg "github.com/pocj8ur4in/boilerplate-go/internal/pkg/redis"
+)
+
+var (
+	// ErrHealthCheckFailed returned when the health check reports an unhealthy service.
+	ErrHealthCheckFailed = errors.New("health check failed")
+
+	// ErrCanaryQueryMismatch returned when the canary query returns an unexpected value.
+	ErrCanaryQueryMismatch = errors.New("canary query returned unexpected value")
+
+	// ErrRedisRoundtripMismatch returned when the redis roundtrip returns an unexpected value.
+	ErrRedisRoundtripMismatch = errors.New("redis roundtrip returned unexpected value")
+
+	// ErrJWTClaimsMismatch returned when the verified JWT claims differ from the signed ones.
+	ErrJWTClaimsMismatch = errors.New("jwt claims mismatch")
+)
+
+const (
+	// selfTestTimeout is the timeout for running all self-test checks.
+	selfTestTimeout = 30 * time.Second
+
+	// selfTestKeyPrefix is the redis key prefix used by the self-test roundtrip.
+	selfTestKeyPrefix = "selftest:"
+
+	// selfTestKeyTTL is the TTL of the redis key used by the self-test roundtrip.
+	selfTestKeyTTL = 10 * time.Second
+
+	// selfTestUserID is the user ID used to sign the self-test JWT.
+	selfTestUserID = "selftest"
+
+	// selfTestValueBytes is the number of random bytes used for the redis roundtrip value.
+	selfTestValueBytes = 16
+)
+
+// SelfTestResult represents result of a single self-test check.
+type SelfTestResult struct {
+	// Name is name of the check.
+	Name string
+
+	// Duration is duration of the check.
+	Duration time.Duration
+
+	// Err is error of the check, nil if the check passed.
+	Err error
+}
+
+// SelfTestReport represents report of all self-test checks.
+type SelfTestReport struct {
+	// Results is results of the checks in execution order.
+	Results []SelfTestResult
+}
+
+// Passed returns whether all checks passed.
+func (r *SelfTestReport) Passed() bool {
+	for _, result := range r.Results {
+		if result.Err != nil {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Write writes the report in a human readable format.
+func (r *SelfTestReport) Write(writer io.Writer) error {
+	for _, result := range r.Results {
+		status := "PASS"
+		if result.Err != nil {
+			status = "FAIL"
+		}
+
+		line := fmt.Sprintf("[%s] %s (%s)", status, result.Name, result.Duration.Round(time.Microsecond))
+		if result.Err != nil {
+			line += ": " + result.Err.Error()
+		}
+
+		if _, err := fmt.Fprintln(writer, line); err != nil {
+			return fmt.Errorf("failed to write report: %w", err)
+		}
+	}
+
+	summary := "selftest passed"
+	if !r.Passed() {
+		summary = "selftest failed"
+	}
+
+	if _, err := fmt.Fprintln(writer, summary); err != nil {
+		return fmt.Errorf("failed to write report: %w", err)
+	}
+
+	return nil
+}
+
+// selfTestDeps represents dependencies resolved from the application graph for the self-test.
+type selfTestDeps struct {
+	fx.In
+
+	DB      *databasePkg.DB
+	Redis   *redisPkg.Redis
+	JWT     *jwtPkg.JWT
+	Handler api.ServerInterface
+}
+
+// SelfTest boots the application graph without starting the server and runs the smoke checks.
+func SelfTest(ctx context.Context) (*SelfTestReport, error) {
+	var deps selfTestDeps
+
+	config, err := loadConfig()
+	if err != nil {
+		return nil, fmt.Errorf("failed to build application: %w", err)
+	}
+
+	fxApp := fx.New(
+		fx.NopLogger,
+		modules(config),
+		fx.Populate(&deps),
+	)
+	if err := fxApp.Err(); err != nil {
+		return nil, fmt.Errorf("failed to build application: %w", err)
+	}
+
+	defer func() {
+		_ = deps.DB.Close()
+		_ = deps.Redis.Close()
+	}()
+
+	ctx, cancel := context.WithTimeout(ctx, selfTestTimeout)
+	defer cancel()
+
+	checks := []struct {
+		name string
+		run  func(ctx context.Context) error
+	}{
+		{name: "health check", run: func(ctx context.Context) error { return checkHealth(ctx, deps.Handler) }},
+		{name: "database canary query", run: func(ctx context.Context) error { return checkCanaryQuery(ctx, deps.DB) }},
+		{name: "redis roundtrip", run: func(ctx context.Context) error { return checkRedisRoundtrip(ctx, deps.Redis) }},
+		{name: "jwt sign and verify", run: func(_ context.Context) error { return checkJWT(deps.JWT) }},
+	}
+
+	report := &SelfTestReport{}
+
+	for _, check := range checks {
+		start := time.Now()
+		err := check.run(ctx)
+
+		report.Results = append(report.Results, SelfTestResult{
+			Name:     check.name,
+			Duration: time.Since(start),
+			Err:      err,
+		})
+	}
+
+	return report, nil
+}
+
+// checkHealth runs the health check handler and verifies all services are healthy.
+func checkHealth(ctx context.Context, handler api.ServerInterface) error {
+	request := httptest.NewRequestWithContext(ctx, http.MethodGet, "/health", nil)
+	recorder := httptest.NewRecorder()
+
+	handler.HealthCheck(recorder, request)
+
+	if recorder.Code != http.StatusOK {
+		return fmt.Errorf("%w: status %d", ErrHealthCheckFailed, recorder.Code)
+	}
+
+	var resp api.SystemHealthCheckResponse
+	if err := json.NewDecoder(recorder.Body).Decode(&resp); err != nil {
+		return fmt.Errorf("failed to decode health check response: %w", err)
+	}
+
+	if !resp.Services.Database {
+		return fmt.Errorf("%w: database", ErrHealthCheckFailed)
+	}
+
+	if !resp.Services.Redis {
+		return fmt.Errorf("%w: redis", ErrHealthCheckFailed)
+	}
+
+	return nil
+}
+
+// checkCanaryQuery executes a canary query against the database.
+func checkCanaryQuery(ctx context.Context, dbConn *databasePkg.DB) error {
+	var result int
+
+	if err := dbConn.QueryRowContext(ctx, "SELECT 1").Scan(&result); err != nil {
+		return fmt.Errorf("failed to execute canary query: %w", err)
+	}
+
+	if result != 1 {
+		return fmt.Errorf("%w: %d", ErrCanaryQueryMismatch, result)
+	}
+
+	return nil
+}
+
+// checkRedisRoundtrip writes, reads, and deletes a random value on redis.
+func checkRedisRoundtrip(ctx context.Context, redisConn *redisPkg.Redis) error {
+	buf := make([]byte, selfTestValueBytes)
+	if _, err := rand.Read(buf); err != nil {
+		return fmt.Errorf("failed to generate value: %w", err)
+	}
+
+	value := hex.EncodeToString(buf)
+	key := selfTestKeyPrefix + value
+
+	if err := redisConn.Set(ctx, key, value, selfTestKeyTTL).Err(); err != nil {
+		return fmt.Errorf("failed to set key: %w", err)
+	}
+
+	got, err := redisConn.Get(ctx, key).Result()
+	if err != nil {
+		return fmt.Errorf("failed to get key: %w", err)
+	}
+
+	if err := redisConn.Del(ctx, key).Err(); err != nil {
+		return fmt.Errorf("failed to delete key: %w", err)
+	}
+
+	if got != value {
+		return fmt.Errorf("%w: %s", ErrRedisRoundtripMismatch, got)
+	}
+
+	return nil
+}
+
+// checkJWT signs a token and verifies it again.
+func checkJWT(jwtService *jwtPkg.JWT) error {
+	token, err := jwtService.GenerateAccessToken(selfTestUserID, "", "")
+	if err != nil {
+		return fmt.Errorf("failed to sign token: %w", err)
+	}
+
+	claims, err := jwtService.ValidateToken(*token)
+	if err != nil {
+		return fmt.Errorf("failed to verify token: %w", err)
+	}
+
+	if claims.UserID != selfTestUserID {
+		return fmt.Errorf("%w: %s", ErrJWTClaimsMismatch, claims.UserID)
+	}
+
+	return nil
+}
@@ -0,0 +1,80 @@
+package app
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+//nolint:paralleltest // Cannot run in parallel due to t.Setenv usage
+func TestSelfTest(t *testing.T) {
+	t.Run("run all checks successfully", func(t *testing.T) {
+		beforeTest(t, nil)
+
+		report, err := SelfTest(context.Background())
+		require.NoError(t, err)
+		require.NotNil(t, report)
+
+		require.Len(t, report.Results, 4)
+
+		for _, result := range report.Results {
+			require.NoError(t, result.Err, result.Name)
+		}
+
+		assert.True(t, report.Passed())
+	})
+
+	t.Run("return error by using invalid config path", func(t *testing.T) {
+		t.Setenv("CONFIG_PATH", "/non/existent/path/config.json")
+
+		report, err := SelfTest(context.Background())
+		require.Error(t, err)
+		assert.Nil(t, report)
+		assert.Contains(t, err.Error(), "failed to build application")
+	})
+}
+
+func TestSelfTestReport(t *testing.T) {
+	t.Parallel()
+
+	t.Run("write passed report", func(t *testing.T) {
+		t.Parallel()
+
+		report := &SelfTestReport{
+			Results: []SelfTestResult{
+				{Name: "health check", Duration: time.Millisecond},
+			},
+		}
+
+		var buf bytes.Buffer
+
+		require.NoError(t, report.Write(&buf))
+		assert.True(t, report.Passed())
+		assert.Contains(t, buf.String(), "[PASS] health check")
+		assert.Contains(t, buf.String(), "selftest passed")
+	})
+
+	t.Run("write failed report", func(t *testing.T) {
+		t.Parallel()
+
+		report := &SelfTestReport{
+			Results: []SelfTestResult{
+				{Name: "health check", Duration: time.Millisecond},
+				{Name: "redis roundtrip", Duration: time.Millisecond, Err: errors.New("connection refused")},
+			},
+		}
+
+		var buf bytes.Buffer
+
+		require.NoError(t, report.Write(&buf))
+		assert.False(t, report.Passed())
+		assert.Contains(t, buf.String(), "[FAIL] redis roundtrip")
+		assert.Contains(t, buf.String(), "connection refused")
+		assert.Contains(t, buf.String(), "selftest failed")
+	})
+}
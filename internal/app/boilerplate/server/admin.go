@@ -0,0 +1,166 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/pocj8ur4in/boilerplate-go/internal/app/boilerplate/server/middleware"
+	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/apierror"
+	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/audit"
+	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/jwt"
+)
+
+const (
+	// defaultReplayListLimit is the default number of replay entries returned by the list endpoint.
+	defaultReplayListLimit = 50
+
+	// maxReplayListLimit is the maximum number of replay entries returned by the list endpoint.
+	maxReplayListLimit = 500
+)
+
+// AdminConfig represents configuration for admin endpoints.
+type AdminConfig struct {
+	// Enabled is whether admin endpoints are enabled.
+	Enabled *bool `json:"enabled"`
+
+	// Path is the path prefix of admin endpoints.
+	Path *string `json:"path"`
+
+	// Role is the JWT role required to access admin endpoints.
+	Role *string `json:"role"`
+
+	// Addr is address of the admin listener serving drain statistics without authentication,
+	// kept open until in-flight requests are drained on shutdown, empty to disable it.
+	Addr *string `json:"addr"`
+}
+
+// setAdminDefault sets default values for admin endpoints on server.
+func (c *Config) setAdminDefault() {
+	if c.Admin == nil {
+		c.Admin = &AdminConfig{}
+	}
+
+	if c.Admin.Enabled == nil {
+		c.Admin.Enabled = &[]bool{true}[0]
+	}
+
+	if c.Admin.Path == nil {
+		c.Admin.Path = &[]string{"/admin"}[0]
+	}
+
+	if c.Admin.Role == nil {
+		c.Admin.Role = &[]string{"admin"}[0]
+	}
+
+	if c.Admin.Addr == nil {
+		c.Admin.Addr = &[]string{""}[0]
+	}
+}
+
+// setupAdminRoutes sets up admin endpoints protected by JWT authentication and the admin role.
+func (s *Server) setupAdminRoutes(router *chi.Mux, config *Config, jwtService *jwt.JWT) {
+	if !*config.Admin.Enabled {
+		return
+	}
+
+	adminAuth := s.adminAuth(config, jwtService)
+
+	router.With(adminAuth...).Get(graphPath, s.handleGraph)
+
+	router.Route(*config.Admin.Path, func(router chi.Router) {
+		router.Use(adminAuth...)
+		router.Use(middleware.AuditActions(audit.ActionAdmin))
+
+		router.Get("/drain", s.handleDrain)
+
+		if s.replayStore != nil {
+			router.Get("/replays", s.handleListReplays)
+			router.Get("/replays/{id}", s.handleGetReplay)
+		}
+
+		s.setupAdminSettingsRoutes(router)
+		s.setupAdminAPIKeyRoutes(router)
+		s.setupAdminReadOnlyRoutes(router)
+		s.setupAdminCacheRoutes(router)
+		s.setupAdminSchedulerRoutes(router)
+		s.setupAdminDeadLetterRoutes(router)
+	})
+}
+
+// adminAuth returns middlewares authenticating admins by JWT and the admin role.
+func (s *Server) adminAuth(config *Config, jwtService *jwt.JWT) []func(http.Handler) http.Handler {
+	// roles on database take effect before tokens issued with the previous role expire
+	requireRole := middleware.RequireRole(*config.Admin.Role)
+	if s.authz != nil {
+		requireRole = middleware.Authorize(s.authz, s.logger, "admin", *config.Admin.Role)
+	}
+
+	return []func(http.Handler) http.Handler{
+		middleware.RequireBearerAuth,
+		middleware.JWTAuth(jwtService, s.logger),
+		requireRole,
+	}
+}
+
+// handleListReplays handles GET /admin/replays endpoint.
+func (s *Server) handleListReplays(writer http.ResponseWriter, request *http.Request) {
+	limit := defaultReplayListLimit
+
+	if value := request.URL.Query().Get("limit"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed <= 0 {
+			writeError(writer, http.StatusBadRequest, "invalid limit")
+
+			return
+		}
+
+		limit = min(parsed, maxReplayListLimit)
+	}
+
+	entries, err := s.replayStore.List(request.Context(), limit)
+	if err != nil {
+		s.logger.Ctx(request.Context()).Error().Err(err).Msg("failed to list replay entries")
+		writeError(writer, http.StatusInternalServerError, "failed to list replay entries")
+
+		return
+	}
+
+	writeJSON(writer, http.StatusOK, map[string]interface{}{"entries": entries})
+}
+
+// handleGetReplay handles GET /admin/replays/{id} endpoint.
+func (s *Server) handleGetReplay(writer http.ResponseWriter, request *http.Request) {
+	entry, err := s.replayStore.Get(request.Context(), chi.URLParam(request, "id"))
+	if err != nil {
+		if errors.Is(err, middleware.ErrReplayNotFound) {
+			writeError(writer, http.StatusNotFound, "replay entry not found")
+
+			return
+		}
+
+		s.logger.Ctx(request.Context()).Error().Err(err).Msg("failed to get replay entry")
+		writeError(writer, http.StatusInternalServerError, "failed to get replay entry")
+
+		return
+	}
+
+	writeJSON(writer, http.StatusOK, entry)
+}
+
+// writeJSON writes a JSON response.
+func writeJSON(writer http.ResponseWriter, code int, data interface{}) {
+	writer.Header().Set("Content-Type", "application/json")
+	writer.WriteHeader(code)
+
+	_ = json.NewEncoder(writer).Encode(data)
+}
+
+// writeError writes the error envelope with the code of the status.
+func writeError(writer http.ResponseWriter, code int, message string) {
+	// error is ignored since nothing else can be written to the client
+	_ = apierror.Write(writer, code, &apierror.Response{Error: message})
+}
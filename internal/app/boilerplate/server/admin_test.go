@@ -0,0 +1,111 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pocj8ur4in/boilerplate-go/internal/app/boilerplate/server/middleware"
+	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/jwt"
+	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/logger"
+)
+
+// newTestAdminServer creates a test server with replay capture enabled.
+func newTestAdminServer(t *testing.T, jwtService *jwt.JWT) *Server {
+	t.Helper()
+
+	log, err := logger.New(&logger.Config{Level: &[]string{"info"}[0]})
+	require.NoError(t, err)
+
+	cfg := &Config{
+		Replay: &middleware.ReplayConfig{
+			Enabled: &[]bool{true}[0],
+		},
+	}
+
+	server, err := New(ServerParams{
+		Config:     cfg,
+		Logger:     log,
+		APIHandler: &mockAPIHandler{},
+		JWT:        jwtService,
+		Redis:      setupTestRedis(t),
+	})
+	require.NoError(t, err)
+
+	return server
+}
+
+// adminRequest performs a request against the admin endpoints with the given role.
+func adminRequest(t *testing.T, server *Server, jwtService *jwt.JWT, path string, role *string) *httptest.ResponseRecorder {
+	t.Helper()
+
+	req := httptest.NewRequest(http.MethodGet, path, nil)
+
+	if role != nil {
+		token, err := jwtService.GenerateAccessToken("user-1", "user@example.com", *role)
+		require.NoError(t, err)
+
+		req.Header.Set("Authorization", "Bearer "+*token)
+	}
+
+	recorder := httptest.NewRecorder()
+	server.httpServer.Handler.ServeHTTP(recorder, req)
+
+	return recorder
+}
+
+//nolint:paralleltest // sequential execution required to avoid redis key conflicts
+func TestAdminRoutes(t *testing.T) {
+	t.Run("reject unauthenticated request", func(t *testing.T) {
+		jwtService := setupTestJWT(t)
+		server := newTestAdminServer(t, jwtService)
+
+		recorder := adminRequest(t, server, jwtService, "/admin/replays", nil)
+
+		assert.Equal(t, http.StatusUnauthorized, recorder.Code)
+	})
+
+	t.Run("reject request without admin role", func(t *testing.T) {
+		jwtService := setupTestJWT(t)
+		server := newTestAdminServer(t, jwtService)
+
+		recorder := adminRequest(t, server, jwtService, "/admin/replays", &[]string{"user"}[0])
+
+		assert.Equal(t, http.StatusForbidden, recorder.Code)
+	})
+
+	t.Run("list replays with admin role", func(t *testing.T) {
+		jwtService := setupTestJWT(t)
+		server := newTestAdminServer(t, jwtService)
+
+		recorder := adminRequest(t, server, jwtService, "/admin/replays", &[]string{"admin"}[0])
+
+		require.Equal(t, http.StatusOK, recorder.Code)
+
+		var body map[string][]middleware.ReplayEntry
+		require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &body))
+		assert.Contains(t, body, "entries")
+	})
+
+	t.Run("reject invalid limit", func(t *testing.T) {
+		jwtService := setupTestJWT(t)
+		server := newTestAdminServer(t, jwtService)
+
+		recorder := adminRequest(t, server, jwtService, "/admin/replays?limit=abc", &[]string{"admin"}[0])
+
+		assert.Equal(t, http.StatusBadRequest, recorder.Code)
+	})
+
+	t.Run("return not found for unknown replay", func(t *testing.T) {
+		jwtService := setupTestJWT(t)
+		server := newTestAdminServer(t, jwtService)
+
+		recorder := adminRequest(t, server, jwtService, "/admin/replays/unknown", &[]string{"admin"}[0])
+
+		assert.Equal(t, http.StatusNotFound, recorder.Code)
+	})
+}
@@ -0,0 +1,258 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/pocj8ur4in/boilerplate-go/internal/app/boilerplate/server/middleware"
+	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/apikey"
+	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/jwt"
+)
+
+// APIKeysConfig represents configuration for API key authentication and endpoints.
+type APIKeysConfig struct {
+	// Enabled is whether API key authentication and endpoints are enabled.
+	Enabled *bool `json:"enabled"`
+
+	// Path is the path prefix of API key endpoints of the authenticated user.
+	Path *string `json:"path"`
+
+	// Header is the request header carrying the API key.
+	Header *string `json:"header"`
+
+	// RateLimit is the default rate limit per API key, overridden by the limit stored on the key.
+	RateLimit *middleware.RateLimitTypeConfig `json:"rate_limit"`
+}
+
+// apiKeyRequest represents the body of API key creation.
+type apiKeyRequest struct {
+	// Name is name of the API key.
+	Name string `json:"name"`
+
+	// Scopes is scopes granted to the API key, a subset of scopes of the caller.
+	Scopes []string `json:"scopes"`
+
+	// ExpiresIn is lifetime of the API key in seconds, zero if it never expires.
+	ExpiresIn int `json:"expires_in"`
+}
+
+// apiKeyResponse represents an API key returned on creation, the only response including the key.
+type apiKeyResponse struct {
+	*apikey.Key
+
+	// Value is the API key to send in the header.
+	Value string `json:"key"`
+}
+
+// apiKeyRateLimitRequest represents the body of API key rate limit overrides.
+type apiKeyRateLimitRequest struct {
+	// Requests is the maximum number of requests allowed.
+	Requests int `json:"requests"`
+
+	// Window is the time window for rate limiting in seconds.
+	Window int `json:"window"`
+}
+
+// setAPIKeysDefault sets default values for API keys on server.
+func (c *Config) setAPIKeysDefault() {
+	if c.APIKeys == nil {
+		c.APIKeys = &APIKeysConfig{}
+	}
+
+	if c.APIKeys.Enabled == nil {
+		c.APIKeys.Enabled = &[]bool{false}[0]
+	}
+
+	if c.APIKeys.Path == nil {
+		c.APIKeys.Path = &[]string{"/api-keys"}[0]
+	}
+
+	if c.APIKeys.Header == nil {
+		c.APIKeys.Header = &[]string{"X-API-Key"}[0]
+	}
+
+	if c.APIKeys.RateLimit == nil {
+		c.APIKeys.RateLimit = &middleware.RateLimitTypeConfig{}
+	}
+
+	if c.APIKeys.RateLimit.Enabled == nil {
+		c.APIKeys.RateLimit.Enabled = &[]bool{true}[0]
+	}
+
+	if c.APIKeys.RateLimit.Requests == nil {
+		c.APIKeys.RateLimit.Requests = &[]int{600}[0]
+	}
+
+	if c.APIKeys.RateLimit.Window == nil {
+		c.APIKeys.RateLimit.Window = &[]int{60}[0]
+	}
+
+	if c.APIKeys.RateLimit.Algorithm == nil {
+		c.APIKeys.RateLimit.Algorithm = &[]middleware.RateLimitAlgorithm{middleware.RateLimitAlgorithmFixedWindow}[0]
+	}
+}
+
+// apiKeyAuthMiddleware returns the API key authentication middleware of the API.
+func (s *Server) apiKeyAuthMiddleware(config *Config) func(next http.Handler) http.Handler {
+	authConfig := &middleware.APIKeyAuthConfig{
+		Header:    *config.APIKeys.Header,
+		Algorithm: *config.APIKeys.RateLimit.Algorithm,
+		Headers:   *config.RateLimit.Headers,
+	}
+
+	// keys with a stored override are limited even if the default limit is disabled
+	if *config.APIKeys.RateLimit.Enabled {
+		authConfig.Requests = *config.APIKeys.RateLimit.Requests
+		authConfig.Window = time.Duration(*config.APIKeys.RateLimit.Window) * time.Second
+	}
+
+	return middleware.APIKeyAuth(authConfig, s.apiKeyStore, s.redis, s.rateLimitFallback, s.logger)
+}
+
+// setupAPIKeyRoutes sets up API key endpoints of the authenticated user,
+// only JWT is accepted so that API keys cannot create other keys.
+func (s *Server) setupAPIKeyRoutes(router *chi.Mux, config *Config, jwtService *jwt.JWT) {
+	if s.apiKeyStore == nil {
+		return
+	}
+
+	router.Route(*config.APIKeys.Path, func(router chi.Router) {
+		router.Use(middleware.RequireBearerAuth)
+		router.Use(middleware.JWTAuth(jwtService, s.logger))
+
+		router.Get("/", s.handleListAPIKeys)
+		router.Post("/", s.handleCreateAPIKey)
+		router.Delete("/{id}", s.handleRevokeAPIKey)
+	})
+}
+
+// setupAdminAPIKeyRoutes sets up API key rate limit endpoints on the admin router.
+func (s *Server) setupAdminAPIKeyRoutes(router chi.Router) {
+	if s.apiKeyStore == nil {
+		return
+	}
+
+	router.Put("/api-keys/{id}/rate-limit", s.handlePutAPIKeyRateLimit)
+	router.Delete("/api-keys/{id}/rate-limit", s.handleDeleteAPIKeyRateLimit)
+}
+
+// handleListAPIKeys handles GET /api-keys endpoint.
+func (s *Server) handleListAPIKeys(writer http.ResponseWriter, request *http.Request) {
+	userID, _ := request.Context().Value(middleware.UserIDKey).(string)
+
+	keys, err := s.apiKeyStore.List(request.Context(), userID)
+	if err != nil {
+		s.writeAPIKeyError(writer, request, err, "failed to list api keys")
+
+		return
+	}
+
+	writeJSON(writer, http.StatusOK, map[string]interface{}{"api_keys": keys})
+}
+
+// handleCreateAPIKey handles POST /api-keys endpoint, granting the role and a subset of scopes of the caller.
+func (s *Server) handleCreateAPIKey(writer http.ResponseWriter, request *http.Request) {
+	claims, _ := request.Context().Value(middleware.ClaimsKey).(*jwt.Claims)
+
+	var body apiKeyRequest
+	if err := json.NewDecoder(request.Body).Decode(&body); err != nil || body.ExpiresIn < 0 {
+		writeError(writer, http.StatusBadRequest, "invalid request body")
+
+		return
+	}
+
+	for _, scope := range body.Scopes {
+		if !slices.Contains(claims.Scopes, scope) {
+			writeError(writer, http.StatusForbidden, "scope not granted: "+scope)
+
+			return
+		}
+	}
+
+	// keys act in the tenant of their creator, so they cannot be used to switch tenants
+	params := &apikey.CreateParams{
+		UserID:   claims.UserID,
+		TenantID: claims.TenantID,
+		Name:     body.Name,
+		Role:     claims.Role,
+		Scopes:   body.Scopes,
+	}
+
+	if body.ExpiresIn > 0 {
+		params.ExpiresAt = &[]time.Time{time.Now().Add(time.Duration(body.ExpiresIn) * time.Second)}[0]
+	}
+
+	key, value, err := s.apiKeyStore.Create(request.Context(), params)
+	if err != nil {
+		s.writeAPIKeyError(writer, request, err, "failed to create api key")
+
+		return
+	}
+
+	writeJSON(writer, http.StatusCreated, apiKeyResponse{Key: key, Value: value})
+}
+
+// handleRevokeAPIKey handles DELETE /api-keys/{id} endpoint.
+func (s *Server) handleRevokeAPIKey(writer http.ResponseWriter, request *http.Request) {
+	userID, _ := request.Context().Value(middleware.UserIDKey).(string)
+
+	if err := s.apiKeyStore.Revoke(request.Context(), userID, chi.URLParam(request, "id")); err != nil {
+		s.writeAPIKeyError(writer, request, err, "failed to revoke api key")
+
+		return
+	}
+
+	writer.WriteHeader(http.StatusNoContent)
+}
+
+// handlePutAPIKeyRateLimit handles PUT /admin/api-keys/{id}/rate-limit endpoint.
+func (s *Server) handlePutAPIKeyRateLimit(writer http.ResponseWriter, request *http.Request) {
+	var body apiKeyRateLimitRequest
+	if err := json.NewDecoder(request.Body).Decode(&body); err != nil {
+		writeError(writer, http.StatusBadRequest, "invalid request body")
+
+		return
+	}
+
+	s.setAPIKeyRateLimit(writer, request, &apikey.RateLimit{
+		Requests: body.Requests,
+		Window:   time.Duration(body.Window) * time.Second,
+	})
+}
+
+// handleDeleteAPIKeyRateLimit handles DELETE /admin/api-keys/{id}/rate-limit endpoint.
+func (s *Server) handleDeleteAPIKeyRateLimit(writer http.ResponseWriter, request *http.Request) {
+	s.setAPIKeyRateLimit(writer, request, nil)
+}
+
+// setAPIKeyRateLimit sets rate limit override of the API key, nil to use the default limit.
+func (s *Server) setAPIKeyRateLimit(writer http.ResponseWriter, request *http.Request, limit *apikey.RateLimit) {
+	key, err := s.apiKeyStore.SetRateLimit(request.Context(), chi.URLParam(request, "id"), limit)
+	if err != nil {
+		s.writeAPIKeyError(writer, request, err, "failed to set api key rate limit")
+
+		return
+	}
+
+	writeJSON(writer, http.StatusOK, key)
+}
+
+// writeAPIKeyError writes the error response of an API key operation.
+func (s *Server) writeAPIKeyError(writer http.ResponseWriter, request *http.Request, err error, message string) {
+	switch {
+	case errors.Is(err, apikey.ErrNotFound):
+		writeError(writer, http.StatusNotFound, "api key not found")
+	case errors.Is(err, apikey.ErrInvalidName):
+		writeError(writer, http.StatusBadRequest, "invalid api key name")
+	case errors.Is(err, apikey.ErrInvalidRateLimit):
+		writeError(writer, http.StatusBadRequest, "invalid api key rate limit")
+	default:
+		s.logger.Ctx(request.Context()).Error().Err(err).Msg(message)
+		writeError(writer, http.StatusInternalServerError, message)
+	}
+}
@@ -0,0 +1,341 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pocj8ur4in/boilerplate-go/internal/gen/db"
+	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/apikey"
+	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/jwt"
+	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/logger"
+)
+
+// mockAPIKeyQuerier is a mock querier storing API keys in memory.
+type mockAPIKeyQuerier struct {
+	db.Querier
+
+	mu   sync.Mutex
+	keys []*db.ApiKey
+}
+
+func (m *mockAPIKeyQuerier) CreateAPIKey(_ context.Context, arg *db.CreateAPIKeyParams) (*db.ApiKey, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	key := &db.ApiKey{
+		ID:        arg.ID,
+		UserID:    arg.UserID,
+		Name:      arg.Name,
+		KeyHash:   arg.KeyHash,
+		Role:      arg.Role,
+		Scopes:    arg.Scopes,
+		ExpiresAt: arg.ExpiresAt,
+		CreatedAt: pgtype.Timestamptz{Time: time.Now(), Valid: true},
+	}
+	m.keys = append(m.keys, key)
+
+	return key, nil
+}
+
+func (m *mockAPIKeyQuerier) GetAPIKeyByHash(_ context.Context, keyHash string) (*db.ApiKey, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, key := range m.keys {
+		if key.KeyHash == keyHash {
+			return key, nil
+		}
+	}
+
+	return nil, pgx.ErrNoRows
+}
+
+func (m *mockAPIKeyQuerier) ListAPIKeys(_ context.Context, userID string) ([]*db.ApiKey, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	keys := []*db.ApiKey{}
+
+	for _, key := range m.keys {
+		if key.UserID == userID {
+			keys = append(keys, key)
+		}
+	}
+
+	return keys, nil
+}
+
+func (m *mockAPIKeyQuerier) RevokeAPIKey(_ context.Context, arg *db.RevokeAPIKeyParams) (*db.ApiKey, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, key := range m.keys {
+		if key.ID == arg.ID && key.UserID == arg.UserID && !key.RevokedAt.Valid {
+			key.RevokedAt = pgtype.Timestamptz{Time: time.Now(), Valid: true}
+
+			return key, nil
+		}
+	}
+
+	return nil, pgx.ErrNoRows
+}
+
+func (m *mockAPIKeyQuerier) SetAPIKeyRateLimit(_ context.Context, arg *db.SetAPIKeyRateLimitParams) (*db.ApiKey, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, key := range m.keys {
+		if key.ID == arg.ID {
+			key.RateLimitRequests = arg.RateLimitRequests
+			key.RateLimitWindowSeconds = arg.RateLimitWindowSeconds
+
+			return key, nil
+		}
+	}
+
+	return nil, pgx.ErrNoRows
+}
+
+// newTestAPIKeysServer creates a test server with API key authentication enabled.
+func newTestAPIKeysServer(t *testing.T, jwtService *jwt.JWT) *Server {
+	t.Helper()
+
+	log, err := logger.New(&logger.Config{Level: &[]string{"info"}[0]})
+	require.NoError(t, err)
+
+	redisClient := setupTestRedis(t)
+
+	cfg := &Config{APIKeys: &APIKeysConfig{Enabled: &[]bool{true}[0]}}
+	store := apikey.NewWithQuerier(nil, &mockAPIKeyQuerier{}, redisClient)
+
+	server, err := New(ServerParams{
+		Config:     cfg,
+		Logger:     log,
+		APIHandler: &mockAPIHandler{},
+		JWT:        jwtService,
+		Redis:      redisClient,
+		APIKeys:    store,
+	})
+	require.NoError(t, err)
+
+	return server
+}
+
+// apiKeysRequest performs a request against the server as the user with the given role and scopes.
+func apiKeysRequest(
+	t *testing.T,
+	server *Server,
+	jwtService *jwt.JWT,
+	method, path, body, role string,
+	scopes ...string,
+) *httptest.ResponseRecorder {
+	t.Helper()
+
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+
+	if role != "" {
+		token, err := jwtService.GenerateAccessToken("user-1", "user@example.com", role, scopes...)
+		require.NoError(t, err)
+
+		req.Header.Set("Authorization", "Bearer "+*token)
+	}
+
+	recorder := httptest.NewRecorder()
+	server.Handler().ServeHTTP(recorder, req)
+
+	return recorder
+}
+
+// createTestAPIKey creates an API key through the endpoint and returns the response.
+func createTestAPIKey(t *testing.T, server *Server, jwtService *jwt.JWT, body string) map[string]interface{} {
+	t.Helper()
+
+	recorder := apiKeysRequest(t, server, jwtService, http.MethodPost, "/api-keys", body, "user", "settings:read")
+	require.Equal(t, http.StatusCreated, recorder.Code, recorder.Body.String())
+
+	var created map[string]interface{}
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &created))
+
+	return created
+}
+
+func TestConfigSetDefaultAPIKeys(t *testing.T) {
+	t.Parallel()
+
+	t.Run("set default values", func(t *testing.T) {
+		t.Parallel()
+
+		config := &Config{}
+		config.SetDefault()
+
+		require.NotNil(t, config.APIKeys)
+		assert.False(t, *config.APIKeys.Enabled)
+		assert.Equal(t, "/api-keys", *config.APIKeys.Path)
+		assert.Equal(t, "X-API-Key", *config.APIKeys.Header)
+		assert.True(t, *config.APIKeys.RateLimit.Enabled)
+		assert.Equal(t, 600, *config.APIKeys.RateLimit.Requests)
+		assert.Equal(t, 60, *config.APIKeys.RateLimit.Window)
+	})
+
+	t.Run("redact configured API key header in replay captures", func(t *testing.T) {
+		t.Parallel()
+
+		config := &Config{APIKeys: &APIKeysConfig{Header: &[]string{"X-Service-Key"}[0]}}
+		config.SetDefault()
+
+		assert.Contains(t, config.Replay.RedactHeaders, "X-Service-Key")
+		assert.NotContains(t, config.Replay.RedactHeaders, "X-API-Key")
+		assert.Contains(t, config.Replay.RedactHeaders, *config.CSRF.HeaderName)
+	})
+}
+
+//nolint:paralleltest // sequential execution required to avoid redis key conflicts
+func TestAPIKeyRoutes(t *testing.T) {
+	t.Run("not register routes when disabled", func(t *testing.T) {
+		jwtService := setupTestJWT(t)
+
+		log, err := logger.New(&logger.Config{Level: &[]string{"info"}[0]})
+		require.NoError(t, err)
+
+		redisClient := setupTestRedis(t)
+		store := apikey.NewWithQuerier(nil, &mockAPIKeyQuerier{}, redisClient)
+
+		server, err := New(ServerParams{
+			Logger:     log,
+			APIHandler: &mockAPIHandler{},
+			JWT:        jwtService,
+			Redis:      redisClient,
+			APIKeys:    store,
+		})
+		require.NoError(t, err)
+
+		recorder := apiKeysRequest(t, server, jwtService, http.MethodGet, "/api-keys", "", "user")
+
+		assert.Equal(t, http.StatusNotFound, recorder.Code)
+	})
+
+	t.Run("reject unauthenticated request", func(t *testing.T) {
+		jwtService := setupTestJWT(t)
+		server := newTestAPIKeysServer(t, jwtService)
+
+		recorder := apiKeysRequest(t, server, jwtService, http.MethodGet, "/api-keys", "", "")
+
+		assert.Equal(t, http.StatusUnauthorized, recorder.Code)
+	})
+
+	t.Run("create, list and revoke api key", func(t *testing.T) {
+		jwtService := setupTestJWT(t)
+		server := newTestAPIKeysServer(t, jwtService)
+
+		created := createTestAPIKey(t, server, jwtService, `{"name":"ci","scopes":["settings:read"],"expires_in":3600}`)
+		assert.Equal(t, "ci", created["name"])
+		assert.Equal(t, "user", created["role"])
+		assert.Equal(t, []interface{}{"settings:read"}, created["scopes"])
+		assert.NotEmpty(t, created["expires_at"])
+		assert.True(t, strings.HasPrefix(created["key"].(string), apikey.Prefix))
+
+		recorder := apiKeysRequest(t, server, jwtService, http.MethodGet, "/api-keys", "", "user")
+		require.Equal(t, http.StatusOK, recorder.Code)
+
+		var listed map[string][]map[string]interface{}
+		require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &listed))
+		require.Len(t, listed["api_keys"], 1)
+		assert.Equal(t, created["id"], listed["api_keys"][0]["id"])
+		assert.NotContains(t, listed["api_keys"][0], "key")
+
+		path := "/api-keys/" + created["id"].(string)
+
+		recorder = apiKeysRequest(t, server, jwtService, http.MethodDelete, path, "", "user")
+		assert.Equal(t, http.StatusNoContent, recorder.Code)
+
+		recorder = apiKeysRequest(t, server, jwtService, http.MethodDelete, path, "", "user")
+		assert.Equal(t, http.StatusNotFound, recorder.Code)
+	})
+
+	t.Run("reject scopes not granted to the caller", func(t *testing.T) {
+		jwtService := setupTestJWT(t)
+		server := newTestAPIKeysServer(t, jwtService)
+
+		recorder := apiKeysRequest(t, server, jwtService, http.MethodPost, "/api-keys",
+			`{"name":"ci","scopes":["settings:write"]}`, "user", "settings:read")
+
+		assert.Equal(t, http.StatusForbidden, recorder.Code)
+	})
+
+	t.Run("reject invalid request body", func(t *testing.T) {
+		jwtService := setupTestJWT(t)
+		server := newTestAPIKeysServer(t, jwtService)
+
+		for _, body := range []string{`{`, `{"name":"ci","expires_in":-1}`, `{"name":""}`} {
+			recorder := apiKeysRequest(t, server, jwtService, http.MethodPost, "/api-keys", body, "user")
+			assert.Equal(t, http.StatusBadRequest, recorder.Code, body)
+		}
+	})
+
+	t.Run("authenticate api request with api key", func(t *testing.T) {
+		jwtService := setupTestJWT(t)
+		server := newTestAPIKeysServer(t, jwtService)
+
+		created := createTestAPIKey(t, server, jwtService, `{"name":"ci"}`)
+
+		for _, test := range []struct {
+			key    string
+			status int
+		}{
+			{key: created["key"].(string), status: http.StatusOK},
+			{key: apikey.Prefix + "unknown", status: http.StatusUnauthorized},
+		} {
+			req := httptest.NewRequest(http.MethodGet, "/status", nil)
+			req.Header.Set("X-API-Key", test.key)
+
+			recorder := httptest.NewRecorder()
+			server.Handler().ServeHTTP(recorder, req)
+
+			assert.Equal(t, test.status, recorder.Code)
+		}
+	})
+
+	t.Run("set and clear rate limit override as admin", func(t *testing.T) {
+		jwtService := setupTestJWT(t)
+		server := newTestAPIKeysServer(t, jwtService)
+
+		created := createTestAPIKey(t, server, jwtService, `{"name":"ci"}`)
+		path := "/admin/api-keys/" + created["id"].(string) + "/rate-limit"
+
+		recorder := apiKeysRequest(t, server, jwtService, http.MethodPut, path, `{"requests":10,"window":60}`, "user")
+		assert.Equal(t, http.StatusForbidden, recorder.Code)
+
+		recorder = apiKeysRequest(t, server, jwtService, http.MethodPut, path, `{"requests":0,"window":60}`, "admin")
+		assert.Equal(t, http.StatusBadRequest, recorder.Code)
+
+		recorder = apiKeysRequest(t, server, jwtService, http.MethodPut, path, `{"requests":10,"window":60}`, "admin")
+		require.Equal(t, http.StatusOK, recorder.Code)
+
+		var key apikey.Key
+		require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &key))
+		assert.Equal(t, &apikey.RateLimit{Requests: 10, Window: time.Minute}, key.RateLimit)
+
+		recorder = apiKeysRequest(t, server, jwtService, http.MethodDelete, path, "", "admin")
+		require.Equal(t, http.StatusOK, recorder.Code)
+
+		key = apikey.Key{}
+		require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &key))
+		assert.Nil(t, key.RateLimit)
+
+		recorder = apiKeysRequest(t, server, jwtService, http.MethodDelete,
+			"/admin/api-keys/unknown/rate-limit", "", "admin")
+		assert.Equal(t, http.StatusNotFound, recorder.Code)
+	})
+}
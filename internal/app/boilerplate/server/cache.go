@@ -0,0 +1,145 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/querycache"
+)
+
+const (
+	// cachePath is the path prefix of cache endpoints on the admin router.
+	cachePath = "/cache"
+
+	// maxCacheInvalidations is maximum number of keys and tags of an invalidation request.
+	maxCacheInvalidations = 1000
+)
+
+// cacheInvalidateRequest represents the body of invalidations.
+type cacheInvalidateRequest struct {
+	// Keys are keys of the cache to delete, such as keys of cache.GetOrLoad.
+	Keys []string `json:"keys"`
+
+	// Tags are tags of query results to invalidate on all instances.
+	Tags []string `json:"tags"`
+}
+
+// cacheInvalidateResponse represents the number of invalidated keys and tags.
+type cacheInvalidateResponse struct {
+	// Keys is number of deleted keys.
+	Keys int `json:"keys"`
+
+	// Tags is number of invalidated tags.
+	Tags int `json:"tags"`
+}
+
+// cacheWarmRequest represents the body of warm requests.
+type cacheWarmRequest struct {
+	// Warmers are names of warmers to run, all warmers if empty.
+	Warmers []string `json:"warmers"`
+}
+
+// cacheWarmResponse represents results of warmers.
+type cacheWarmResponse struct {
+	// Results is result of each warmer in order.
+	Results []querycache.WarmResult `json:"results"`
+}
+
+// cacheWarmersResponse represents registered warmers.
+type cacheWarmersResponse struct {
+	// Warmers is names of registered warmers.
+	Warmers []string `json:"warmers"`
+}
+
+// setupAdminCacheRoutes sets up cache endpoints on the admin router, to invalidate or warm caches after data is
+// changed bypassing the application.
+func (s *Server) setupAdminCacheRoutes(router chi.Router) {
+	if s.queryCache == nil {
+		return
+	}
+
+	router.Post(cachePath+"/invalidate", s.handleCacheInvalidate)
+	router.Get(cachePath+"/warmers", s.handleListCacheWarmers)
+	router.Post(cachePath+"/warm", s.handleCacheWarm)
+}
+
+// handleCacheInvalidate handles POST /admin/cache/invalidate endpoint.
+func (s *Server) handleCacheInvalidate(writer http.ResponseWriter, request *http.Request) {
+	var body cacheInvalidateRequest
+	if err := json.NewDecoder(request.Body).Decode(&body); err != nil {
+		writeError(writer, http.StatusBadRequest, "invalid request body")
+
+		return
+	}
+
+	count := len(body.Keys) + len(body.Tags)
+	if count == 0 || count > maxCacheInvalidations ||
+		slices.ContainsFunc(body.Keys, isBlank) || slices.ContainsFunc(body.Tags, isBlank) {
+		writeError(writer, http.StatusBadRequest, "invalid keys or tags")
+
+		return
+	}
+
+	if err := s.queryCache.Delete(request.Context(), body.Keys...); err != nil {
+		s.logger.Ctx(request.Context()).Error().Err(err).Msg("failed to delete cache keys")
+		writeError(writer, http.StatusInternalServerError, "failed to delete cache keys")
+
+		return
+	}
+
+	if err := s.queryCache.Invalidate(request.Context(), body.Tags...); err != nil {
+		s.logger.Ctx(request.Context()).Error().Err(err).Msg("failed to invalidate cache tags")
+		writeError(writer, http.StatusInternalServerError, "failed to invalidate cache tags")
+
+		return
+	}
+
+	s.logger.Ctx(request.Context()).Info().Strs("keys", body.Keys).Strs("tags", body.Tags).Msg("cache invalidated")
+
+	writeJSON(writer, http.StatusOK, cacheInvalidateResponse{Keys: len(body.Keys), Tags: len(body.Tags)})
+}
+
+// handleListCacheWarmers handles GET /admin/cache/warmers endpoint.
+func (s *Server) handleListCacheWarmers(writer http.ResponseWriter, _ *http.Request) {
+	writeJSON(writer, http.StatusOK, cacheWarmersResponse{Warmers: s.queryCache.Warmers()})
+}
+
+// handleCacheWarm handles POST /admin/cache/warm endpoint, running the warmers before responding.
+func (s *Server) handleCacheWarm(writer http.ResponseWriter, request *http.Request) {
+	// an empty body runs all warmers
+	var body cacheWarmRequest
+	if err := json.NewDecoder(request.Body).Decode(&body); err != nil && !errors.Is(err, io.EOF) {
+		writeError(writer, http.StatusBadRequest, "invalid request body")
+
+		return
+	}
+
+	results, err := s.queryCache.Warm(request.Context(), body.Warmers...)
+	if errors.Is(err, querycache.ErrUnknownWarmer) {
+		writeError(writer, http.StatusBadRequest, err.Error())
+
+		return
+	}
+
+	if err != nil {
+		s.logger.Ctx(request.Context()).Error().Err(err).Msg("failed to warm cache")
+		writeError(writer, http.StatusInternalServerError, "failed to warm cache")
+
+		return
+	}
+
+	s.logger.Ctx(request.Context()).Info().Int("warmers", len(results)).Msg("cache warmed")
+
+	writeJSON(writer, http.StatusOK, cacheWarmResponse{Results: results})
+}
+
+// isBlank returns whether the value is empty or whitespace.
+func isBlank(value string) bool {
+	return strings.TrimSpace(value) == ""
+}
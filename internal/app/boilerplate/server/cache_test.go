@@ -0,0 +1,142 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/cache"
+	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/logger"
+	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/querycache"
+)
+
+//nolint:paralleltest // sequential execution required to avoid redis key conflicts
+func TestCacheRoutes(t *testing.T) {
+	log, err := logger.New(&logger.Config{Level: &[]string{"info"}[0]})
+	require.NoError(t, err)
+
+	jwtService := setupTestJWT(t)
+	redisClient := setupTestRedis(t)
+	namespace := fmt.Sprintf("server:%d", time.Now().UnixNano())
+
+	queryCache, err := querycache.New(&querycache.Config{
+		Enabled: &[]bool{true}[0],
+		Prefix:  &[]string{"query:" + namespace + ":"}[0],
+		Channel: &[]string{"query_cache:" + namespace}[0],
+	}, cache.New(nil, redisClient, log), redisClient, log)
+	require.NoError(t, err)
+
+	t.Cleanup(func() {
+		_ = queryCache.Close()
+	})
+
+	query := querycache.Query{Key: "subscriptions:user-1", Tags: []string{"billing:user:user-1"}}
+	loads := 0
+
+	// load counts loads of the query.
+	load := func(context.Context) (string, error) {
+		loads++
+
+		return "active", nil
+	}
+
+	require.NoError(t, queryCache.RegisterWarmer(querycache.Warmer{
+		Name: "subscriptions",
+		Warm: func(ctx context.Context) (int, error) {
+			_, err := querycache.Run(ctx, queryCache, query, load)
+
+			return 1, err
+		},
+	}))
+
+	server, err := New(ServerParams{
+		Logger:     log,
+		APIHandler: &mockAPIHandler{},
+		JWT:        jwtService,
+		Redis:      redisClient,
+		QueryCache: queryCache,
+	})
+	require.NoError(t, err)
+
+	token, err := jwtService.GenerateAccessToken("admin-1", "admin@example.com", "admin")
+	require.NoError(t, err)
+
+	// serve sends the request as an admin, returns the response.
+	serve := func(method string, path string, body string) *httptest.ResponseRecorder {
+		request := httptest.NewRequest(method, path, strings.NewReader(body))
+		request.Header.Set("Authorization", "Bearer "+*token)
+
+		recorder := httptest.NewRecorder()
+		server.Handler().ServeHTTP(recorder, request)
+
+		return recorder
+	}
+
+	t.Run("list warmers", func(t *testing.T) {
+		recorder := serve(http.MethodGet, "/admin/cache/warmers", "")
+		require.Equal(t, http.StatusOK, recorder.Code)
+		assert.JSONEq(t, `{"warmers":["subscriptions"]}`, recorder.Body.String())
+	})
+
+	t.Run("warm all warmers", func(t *testing.T) {
+		recorder := serve(http.MethodPost, "/admin/cache/warm", "")
+		require.Equal(t, http.StatusOK, recorder.Code)
+
+		var response cacheWarmResponse
+		require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &response))
+		require.Len(t, response.Results, 1)
+		assert.Equal(t, "subscriptions", response.Results[0].Name)
+		assert.Empty(t, response.Results[0].Error)
+		assert.Equal(t, 1, loads)
+
+		// warmed results are kept until invalidated
+		assert.Equal(t, http.StatusOK, serve(http.MethodPost, "/admin/cache/warm", `{"warmers":["subscriptions"]}`).Code)
+		assert.Equal(t, 1, loads)
+	})
+
+	t.Run("reject unknown warmers", func(t *testing.T) {
+		recorder := serve(http.MethodPost, "/admin/cache/warm", `{"warmers":["missing"]}`)
+		assert.Equal(t, http.StatusBadRequest, recorder.Code)
+	})
+
+	t.Run("invalidate tags and keys", func(t *testing.T) {
+		recorder := serve(http.MethodPost, "/admin/cache/invalidate",
+			`{"keys":["user:user-1"],"tags":["billing:user:user-1"]}`)
+		require.Equal(t, http.StatusOK, recorder.Code)
+		assert.JSONEq(t, `{"keys":1,"tags":1}`, recorder.Body.String())
+
+		_, err := querycache.Run(context.Background(), queryCache, query, load)
+		require.NoError(t, err)
+		assert.Equal(t, 2, loads)
+	})
+
+	t.Run("reject invalid invalidations", func(t *testing.T) {
+		tooMany := `{"tags":["` + strings.Repeat(`tag","`, maxCacheInvalidations) + `tag"]}`
+
+		for _, body := range []string{`invalid`, `{}`, `{"keys":[" "]}`, `{"tags":[""]}`, tooMany} {
+			recorder := serve(http.MethodPost, "/admin/cache/invalidate", body)
+			assert.Equal(t, http.StatusBadRequest, recorder.Code, body)
+		}
+	})
+
+	t.Run("require admin role", func(t *testing.T) {
+		userToken, err := jwtService.GenerateAccessToken("user-1", "user@example.com", "user")
+		require.NoError(t, err)
+
+		request := httptest.NewRequest(http.MethodGet, "/admin/cache/warmers", nil)
+		request.Header.Set("Authorization", "Bearer "+*userToken)
+
+		recorder := httptest.NewRecorder()
+		server.Handler().ServeHTTP(recorder, request)
+
+		assert.Equal(t, http.StatusForbidden, recorder.Code)
+	})
+}
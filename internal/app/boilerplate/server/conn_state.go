@@ -0,0 +1,135 @@
+package server
+
+import (
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// connState is state of a connection tracked by the connection state tracker.
+type connState struct {
+	// listener is address of the listener of the connection.
+	listener string
+
+	// state is the current state of the connection.
+	state http.ConnState
+
+	// opened is time the connection was accepted.
+	opened time.Time
+
+	// requests is number of requests served on the connection.
+	requests int
+}
+
+// connStateTracker tracks connections of HTTP servers by their ConnState callbacks, exposing connections by state
+// and their lifetimes to diagnose keep-alive and load balancer tuning (e.g. balancers closing idle connections
+// before the idle timeout, or clients not reusing connections).
+type connStateTracker struct {
+	// mu guards conns.
+	mu sync.Mutex
+
+	// conns is state of open connections.
+	conns map[net.Conn]*connState
+
+	// states is gauge of open connections by listener and state (new, active, idle).
+	states *prometheus.GaugeVec
+
+	// lifetime is histogram of durations of closed connections by listener.
+	lifetime *prometheus.HistogramVec
+
+	// requests is histogram of requests served per closed connection by listener.
+	requests *prometheus.HistogramVec
+
+	// now returns the current time.
+	now func() time.Time
+}
+
+// newConnStateTracker creates a connection state tracker.
+func newConnStateTracker() *connStateTracker {
+	const (
+		lifetimeStart, lifetimeFactor, lifetimeCount = 0.01, 4, 8
+		requestsStart, requestsFactor, requestsCount = 1, 2, 11
+	)
+
+	return &connStateTracker{
+		conns: make(map[net.Conn]*connState),
+		states: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "http_connections",
+			Help: "Number of open connections to the server by state",
+		}, []string{"listener", "state"}),
+		lifetime: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "http_connection_duration_seconds",
+			Help:    "Duration of connections to the server from accept to close in seconds",
+			Buckets: prometheus.ExponentialBuckets(lifetimeStart, lifetimeFactor, lifetimeCount),
+		}, []string{"listener"}),
+		requests: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "http_connection_requests",
+			Help:    "Number of requests served per connection to the server",
+			Buckets: append([]float64{0}, prometheus.ExponentialBuckets(requestsStart, requestsFactor, requestsCount)...),
+		}, []string{"listener"}),
+		now: time.Now,
+	}
+}
+
+// Describe sends descriptors of the connection state metrics.
+func (t *connStateTracker) Describe(descs chan<- *prometheus.Desc) {
+	t.states.Describe(descs)
+	t.lifetime.Describe(descs)
+	t.requests.Describe(descs)
+}
+
+// Collect sends the connection state metrics.
+func (t *connStateTracker) Collect(metrics chan<- prometheus.Metric) {
+	t.states.Collect(metrics)
+	t.lifetime.Collect(metrics)
+	t.requests.Collect(metrics)
+}
+
+// callback returns the ConnState callback of HTTP servers of the listener.
+func (t *connStateTracker) callback(listener string) func(net.Conn, http.ConnState) {
+	return func(conn net.Conn, state http.ConnState) {
+		t.track(listener, conn, state)
+	}
+}
+
+// track records the transition of the connection to the state. Hijacked connections (e.g. websockets) leave the
+// HTTP server, so they are untracked without observing their lifetime.
+func (t *connStateTracker) track(listener string, conn net.Conn, state http.ConnState) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	tracked, ok := t.conns[conn]
+	if ok {
+		t.states.WithLabelValues(tracked.listener, tracked.state.String()).Dec()
+	} else {
+		if state == http.StateClosed || state == http.StateHijacked {
+			return
+		}
+
+		tracked = &connState{listener: listener, opened: t.now()}
+		t.conns[conn] = tracked
+	}
+
+	switch state {
+	case http.StateClosed:
+		delete(t.conns, conn)
+
+		t.lifetime.WithLabelValues(tracked.listener).Observe(t.now().Sub(tracked.opened).Seconds())
+		t.requests.WithLabelValues(tracked.listener).Observe(float64(tracked.requests))
+
+		return
+	case http.StateHijacked:
+		delete(t.conns, conn)
+
+		return
+	case http.StateActive:
+		tracked.requests++
+	case http.StateNew, http.StateIdle:
+	}
+
+	tracked.state = state
+	t.states.WithLabelValues(tracked.listener, state.String()).Inc()
+}
@@ -0,0 +1,146 @@
+package server
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConnStateTracker(t *testing.T) {
+	t.Parallel()
+
+	t.Run("track connections by state and observe their lifetime", func(t *testing.T) {
+		t.Parallel()
+
+		tracker := newConnStateTracker()
+
+		now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+		tracker.now = func() time.Time { return now }
+
+		conn, peer := net.Pipe()
+		t.Cleanup(func() {
+			_ = conn.Close()
+			_ = peer.Close()
+		})
+
+		track := tracker.callback("127.0.0.1:8080")
+
+		track(conn, http.StateNew)
+		assert.InDelta(t, 1, testutil.ToFloat64(tracker.states.WithLabelValues("127.0.0.1:8080", "new")), 0)
+
+		for range 3 {
+			track(conn, http.StateActive)
+			assert.InDelta(t, 1, testutil.ToFloat64(tracker.states.WithLabelValues("127.0.0.1:8080", "active")), 0)
+
+			track(conn, http.StateIdle)
+			assert.InDelta(t, 1, testutil.ToFloat64(tracker.states.WithLabelValues("127.0.0.1:8080", "idle")), 0)
+		}
+
+		assert.Zero(t, testutil.ToFloat64(tracker.states.WithLabelValues("127.0.0.1:8080", "new")))
+		assert.Zero(t, testutil.ToFloat64(tracker.states.WithLabelValues("127.0.0.1:8080", "active")))
+
+		now = now.Add(90 * time.Second)
+		track(conn, http.StateClosed)
+
+		assert.Zero(t, testutil.ToFloat64(tracker.states.WithLabelValues("127.0.0.1:8080", "idle")))
+		assert.Empty(t, tracker.conns)
+
+		expected := `
+			# HELP http_connection_requests Number of requests served per connection to the server
+			# TYPE http_connection_requests histogram
+			http_connection_requests_bucket{listener="127.0.0.1:8080",le="0"} 0
+			http_connection_requests_bucket{listener="127.0.0.1:8080",le="1"} 0
+			http_connection_requests_bucket{listener="127.0.0.1:8080",le="2"} 0
+			http_connection_requests_bucket{listener="127.0.0.1:8080",le="4"} 1
+			http_connection_requests_bucket{listener="127.0.0.1:8080",le="8"} 1
+			http_connection_requests_bucket{listener="127.0.0.1:8080",le="16"} 1
+			http_connection_requests_bucket{listener="127.0.0.1:8080",le="32"} 1
+			http_connection_requests_bucket{listener="127.0.0.1:8080",le="64"} 1
+			http_connection_requests_bucket{listener="127.0.0.1:8080",le="128"} 1
+			http_connection_requests_bucket{listener="127.0.0.1:8080",le="256"} 1
+			http_connection_requests_bucket{listener="127.0.0.1:8080",le="512"} 1
+			http_connection_requests_bucket{listener="127.0.0.1:8080",le="1024"} 1
+			http_connection_requests_bucket{listener="127.0.0.1:8080",le="+Inf"} 1
+			http_connection_requests_sum{listener="127.0.0.1:8080"} 3
+			http_connection_requests_count{listener="127.0.0.1:8080"} 1
+		`
+		require.NoError(t, testutil.CollectAndCompare(
+			tracker, strings.NewReader(expected), "http_connection_requests",
+		))
+
+		assert.Equal(t, 1, testutil.CollectAndCount(tracker, "http_connection_duration_seconds"))
+	})
+
+	t.Run("untrack hijacked connections without observing them", func(t *testing.T) {
+		t.Parallel()
+
+		tracker := newConnStateTracker()
+
+		conn, peer := net.Pipe()
+		t.Cleanup(func() {
+			_ = conn.Close()
+			_ = peer.Close()
+		})
+
+		track := tracker.callback("127.0.0.1:8080")
+
+		track(conn, http.StateNew)
+		track(conn, http.StateActive)
+		track(conn, http.StateHijacked)
+
+		// the closed state of hijacked connections is not reported, but ignored if it is
+		track(conn, http.StateClosed)
+
+		assert.Empty(t, tracker.conns)
+		assert.Zero(t, testutil.ToFloat64(tracker.states.WithLabelValues("127.0.0.1:8080", "active")))
+		assert.Zero(t, testutil.CollectAndCount(tracker, "http_connection_duration_seconds"))
+	})
+}
+
+func TestCreateHTTPServerConnState(t *testing.T) {
+	t.Parallel()
+
+	config := &Config{}
+	config.SetDefault()
+
+	server := &Server{connStates: newConnStateTracker()}
+
+	handler := http.HandlerFunc(func(http.ResponseWriter, *http.Request) {})
+
+	httpServer := server.createHTTPServer(config, "127.0.0.1:0", handler)
+	require.NotNil(t, httpServer.ConnState)
+
+	testServer := httptest.NewUnstartedServer(httpServer.Handler)
+	testServer.Config.ConnState = httpServer.ConnState
+	testServer.Start()
+	t.Cleanup(testServer.Close)
+
+	client := &http.Client{Transport: &http.Transport{}}
+
+	for range 2 {
+		response, err := client.Get(testServer.URL) //nolint:noctx // test request
+		require.NoError(t, err)
+		require.NoError(t, response.Body.Close())
+	}
+
+	// both requests reused the connection, which stays open until the client closes it
+	require.Eventually(t, func() bool {
+		return testutil.ToFloat64(server.connStates.states.WithLabelValues("127.0.0.1:0", "idle")) == 1
+	}, time.Second, 5*time.Millisecond)
+
+	client.CloseIdleConnections()
+
+	require.Eventually(t, func() bool {
+		return testutil.CollectAndCount(server.connStates, "http_connection_duration_seconds") == 1
+	}, time.Second, 5*time.Millisecond)
+
+	assert.Zero(t, testutil.ToFloat64(server.connStates.states.WithLabelValues("127.0.0.1:0", "idle")))
+	assert.Nil(t, (&Server{}).createHTTPServer(config, "127.0.0.1:0", nil).ConnState)
+}
@@ -0,0 +1,252 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// ErrInvalidConnectionLimit is returned when a connection limit is negative.
+var ErrInvalidConnectionLimit = errors.New("connection limit must not be negative")
+
+// ConnectionsConfig represents configuration for limits of connections to listeners of server,
+// except the admin listener so that it stays reachable while the limits are reached.
+type ConnectionsConfig struct {
+	// Max is maximum number of open connections, further connections wait in the backlog until one closes,
+	// 0 for unlimited.
+	Max *int `json:"max"`
+
+	// MaxPerIP is maximum number of open connections of a client IP, further connections are closed,
+	// 0 for unlimited. Keep it 0 behind proxies, since all their clients connect from the proxy IPs.
+	MaxPerIP *int `json:"max_per_ip"`
+}
+
+// setConnectionsDefault sets default values for connection limits on server.
+func (c *Config) setConnectionsDefault() {
+	if c.Connections == nil {
+		c.Connections = &ConnectionsConfig{}
+	}
+
+	if c.Connections.Max == nil {
+		c.Connections.Max = &[]int{10000}[0]
+	}
+
+	if c.Connections.MaxPerIP == nil {
+		c.Connections.MaxPerIP = &[]int{0}[0]
+	}
+}
+
+// validateConnections validates connection limits configuration.
+func validateConnections(config *ConnectionsConfig) error {
+	if *config.Max < 0 || *config.MaxPerIP < 0 {
+		return fmt.Errorf("%w: max %d, max_per_ip %d", ErrInvalidConnectionLimit, *config.Max, *config.MaxPerIP)
+	}
+
+	return nil
+}
+
+// connectionLimiter limits open connections in total and by client IP across listeners.
+type connectionLimiter struct {
+	// slots holds a token per open connection, nil for unlimited.
+	slots chan struct{}
+
+	// maxPerIP is maximum number of open connections of a client IP, 0 for unlimited.
+	maxPerIP int
+
+	// mu guards perIP.
+	mu sync.Mutex
+
+	// perIP is number of open connections by client IP.
+	perIP map[string]int
+
+	// open is gauge of open connections.
+	open prometheus.Gauge
+
+	// rejected is counter of connections closed for exceeding the limit of their client IP.
+	rejected prometheus.Counter
+}
+
+// newConnectionLimiter creates a connection limiter of the configuration.
+func newConnectionLimiter(config *ConnectionsConfig) *connectionLimiter {
+	limiter := &connectionLimiter{
+		maxPerIP: *config.MaxPerIP,
+		perIP:    make(map[string]int),
+		open: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "http_connections_open",
+			Help: "Number of open connections to the server",
+		}),
+		rejected: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "http_connections_rejected_total",
+			Help: "Total number of connections closed for exceeding the open connections of their client IP",
+		}),
+	}
+
+	if *config.Max > 0 {
+		limiter.slots = make(chan struct{}, *config.Max)
+	}
+
+	return limiter
+}
+
+// Describe sends descriptors of the connection metrics.
+func (l *connectionLimiter) Describe(descs chan<- *prometheus.Desc) {
+	l.open.Describe(descs)
+	l.rejected.Describe(descs)
+}
+
+// Collect sends the connection metrics.
+func (l *connectionLimiter) Collect(metrics chan<- prometheus.Metric) {
+	l.open.Collect(metrics)
+	l.rejected.Collect(metrics)
+}
+
+// wrap returns the listener accepting connections within the limits.
+func (l *connectionLimiter) wrap(listener net.Listener) net.Listener {
+	return &limitedListener{Listener: listener, limiter: l, done: make(chan struct{})}
+}
+
+// acquire waits for a slot of a connection, false if done is closed first.
+func (l *connectionLimiter) acquire(done <-chan struct{}) bool {
+	if l.slots == nil {
+		return true
+	}
+
+	select {
+	case l.slots <- struct{}{}:
+		return true
+	case <-done:
+		return false
+	}
+}
+
+// release frees a slot of a connection.
+func (l *connectionLimiter) release() {
+	if l.slots != nil {
+		<-l.slots
+	}
+}
+
+// acquireIP counts a connection of the client IP, false if it exceeds the limit of the IP.
+func (l *connectionLimiter) acquireIP(ip string) bool {
+	if l.maxPerIP == 0 {
+		return true
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.perIP[ip] >= l.maxPerIP {
+		return false
+	}
+
+	l.perIP[ip]++
+
+	return true
+}
+
+// releaseIP uncounts a connection of the client IP.
+func (l *connectionLimiter) releaseIP(ip string) {
+	if l.maxPerIP == 0 {
+		return
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.perIP[ip]--
+	if l.perIP[ip] <= 0 {
+		delete(l.perIP, ip)
+	}
+}
+
+// limitedListener accepts connections within the limits of the limiter.
+type limitedListener struct {
+	net.Listener
+
+	// limiter limits the accepted connections.
+	limiter *connectionLimiter
+
+	// done is closed when the listener is closed, to stop waiting for a slot.
+	done chan struct{}
+
+	// closeOnce closes done once.
+	closeOnce sync.Once
+}
+
+// Accept waits for a slot and accepts the next connection, closing connections over the limit of their client IP.
+func (l *limitedListener) Accept() (net.Conn, error) {
+	for {
+		if !l.limiter.acquire(l.done) {
+			return nil, net.ErrClosed
+		}
+
+		conn, err := l.Listener.Accept()
+		if err != nil {
+			l.limiter.release()
+
+			// http.Server retries temporary errors by asserting their type, so they are returned as is
+			return nil, err //nolint:wrapcheck // errors of the listener are returned as is
+		}
+
+		ip := remoteIP(conn.RemoteAddr())
+		if !l.limiter.acquireIP(ip) {
+			l.limiter.release()
+			l.limiter.rejected.Inc()
+
+			_ = conn.Close()
+
+			continue
+		}
+
+		l.limiter.open.Inc()
+
+		return &limitedConn{Conn: conn, limiter: l.limiter, ip: ip}, nil
+	}
+}
+
+// Close closes the listener, stopping Accept waiting for a slot.
+func (l *limitedListener) Close() error {
+	l.closeOnce.Do(func() {
+		close(l.done)
+	})
+
+	return l.Listener.Close() //nolint:wrapcheck // errors of the listener are returned as is
+}
+
+// limitedConn releases its slot and the count of its client IP once closed.
+type limitedConn struct {
+	net.Conn
+
+	// limiter limits the connection.
+	limiter *connectionLimiter
+
+	// ip is client IP of the connection.
+	ip string
+
+	// closeOnce releases the connection once.
+	closeOnce sync.Once
+}
+
+// Close closes the connection.
+func (c *limitedConn) Close() error {
+	c.closeOnce.Do(func() {
+		c.limiter.releaseIP(c.ip)
+		c.limiter.release()
+		c.limiter.open.Dec()
+	})
+
+	return c.Conn.Close() //nolint:wrapcheck // errors of the connection are returned as is
+}
+
+// remoteIP returns the IP of the remote address, or the address itself if it has no port.
+func remoteIP(addr net.Addr) string {
+	host, _, err := net.SplitHostPort(addr.String())
+	if err != nil {
+		return addr.String()
+	}
+
+	return host
+}
@@ -0,0 +1,173 @@
+package server
+
+import (
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// listenLimited listens on a local address with the connection limits and returns the limiter and listener.
+func listenLimited(t *testing.T, maxConnections, maxPerIP int) (*connectionLimiter, net.Listener) {
+	t.Helper()
+
+	netListener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	limiter := newConnectionLimiter(&ConnectionsConfig{Max: &maxConnections, MaxPerIP: &maxPerIP})
+	listener := limiter.wrap(netListener)
+
+	t.Cleanup(func() {
+		_ = listener.Close()
+	})
+
+	return limiter, listener
+}
+
+// acceptAsync accepts connections of the listener in the background.
+func acceptAsync(listener net.Listener) <-chan net.Conn {
+	accepted := make(chan net.Conn, 8)
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				close(accepted)
+
+				return
+			}
+
+			accepted <- conn
+		}
+	}()
+
+	return accepted
+}
+
+// dial connects to the listener.
+func dial(t *testing.T, listener net.Listener) net.Conn {
+	t.Helper()
+
+	conn, err := net.Dial("tcp", listener.Addr().String())
+	require.NoError(t, err)
+
+	t.Cleanup(func() {
+		_ = conn.Close()
+	})
+
+	return conn
+}
+
+func TestConnectionsDefault(t *testing.T) {
+	t.Parallel()
+
+	config := &Config{}
+	config.SetDefault()
+
+	require.NotNil(t, config.Connections)
+	assert.Equal(t, 10000, *config.Connections.Max)
+	assert.Zero(t, *config.Connections.MaxPerIP)
+	require.NoError(t, validateConnections(config.Connections))
+
+	config.Connections.MaxPerIP = &[]int{-1}[0]
+	require.ErrorIs(t, validateConnections(config.Connections), ErrInvalidConnectionLimit)
+}
+
+func TestCreateHTTPServerLimits(t *testing.T) {
+	t.Parallel()
+
+	config := &Config{ReadHeaderTimeout: &[]int{3}[0], MaxHeaderBytes: &[]int{4096}[0]}
+	config.SetDefault()
+
+	httpServer := (&Server{}).createHTTPServer(config, "127.0.0.1:0", nil)
+	assert.Equal(t, 3*time.Second, httpServer.ReadHeaderTimeout)
+	assert.Equal(t, 4096, httpServer.MaxHeaderBytes)
+}
+
+func TestConnectionLimiter(t *testing.T) {
+	t.Parallel()
+
+	t.Run("wait for open connections to close over maximum", func(t *testing.T) {
+		t.Parallel()
+
+		limiter, listener := listenLimited(t, 1, 0)
+		accepted := acceptAsync(listener)
+
+		dial(t, listener)
+
+		first := <-accepted
+		assert.InDelta(t, 1, testutil.ToFloat64(limiter.open), 0)
+
+		dial(t, listener)
+
+		select {
+		case <-accepted:
+			t.Fatal("connection over maximum was accepted")
+		case <-time.After(50 * time.Millisecond):
+		}
+
+		// closing twice releases the slot once
+		require.NoError(t, first.Close())
+		require.Error(t, first.Close())
+
+		select {
+		case second := <-accepted:
+			require.NoError(t, second.Close())
+		case <-time.After(time.Second):
+			t.Fatal("connection was not accepted after another closed")
+		}
+
+		assert.Zero(t, testutil.ToFloat64(limiter.open))
+	})
+
+	t.Run("close connections over maximum of client ip", func(t *testing.T) {
+		t.Parallel()
+
+		limiter, listener := listenLimited(t, 0, 1)
+		accepted := acceptAsync(listener)
+
+		dial(t, listener)
+		first := <-accepted
+
+		rejected := dial(t, listener)
+		require.NoError(t, rejected.SetReadDeadline(time.Now().Add(time.Second)))
+
+		_, err := rejected.Read(make([]byte, 1))
+		require.ErrorIs(t, err, io.EOF)
+		assert.InDelta(t, 1, testutil.ToFloat64(limiter.rejected), 0)
+
+		require.NoError(t, first.Close())
+
+		dial(t, listener)
+
+		select {
+		case second := <-accepted:
+			require.NoError(t, second.Close())
+		case <-time.After(time.Second):
+			t.Fatal("connection was not accepted after another of the ip closed")
+		}
+	})
+
+	t.Run("stop waiting for a slot once closed", func(t *testing.T) {
+		t.Parallel()
+
+		_, listener := listenLimited(t, 1, 0)
+		accepted := acceptAsync(listener)
+
+		dial(t, listener)
+		<-accepted
+
+		require.NoError(t, listener.Close())
+
+		select {
+		case _, ok := <-accepted:
+			assert.False(t, ok)
+		case <-time.After(time.Second):
+			t.Fatal("accept did not return after close")
+		}
+	})
+}
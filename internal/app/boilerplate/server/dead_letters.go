@@ -0,0 +1,113 @@
+package server
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/jobs"
+)
+
+const (
+	// deadLettersPath is the path prefix of dead-lettered job endpoints on the admin router.
+	deadLettersPath = "/jobs/dead"
+
+	// defaultDeadLetterListLimit is the default number of dead-lettered jobs returned by the list endpoint.
+	defaultDeadLetterListLimit = 50
+
+	// maxDeadLetterListLimit is the maximum number of dead-lettered jobs returned by the list endpoint.
+	maxDeadLetterListLimit = 500
+)
+
+// deadLettersResponse represents a page of dead-lettered jobs.
+type deadLettersResponse struct {
+	// Jobs is dead-lettered jobs, the latest first.
+	Jobs []*jobs.DeadLetter `json:"jobs"`
+
+	// Next is cursor of the next page passed as before, empty if there are no more jobs.
+	Next string `json:"next,omitempty"`
+}
+
+// setupAdminDeadLetterRoutes sets up endpoints listing, inspecting and requeueing dead-lettered jobs on the admin
+// router.
+func (s *Server) setupAdminDeadLetterRoutes(router chi.Router) {
+	if s.jobs == nil {
+		return
+	}
+
+	router.Get(deadLettersPath, s.handleListDeadLetters)
+	router.Get(deadLettersPath+"/{id}", s.handleGetDeadLetter)
+	router.Post(deadLettersPath+"/{id}/requeue", s.handleRequeueDeadLetter)
+}
+
+// handleListDeadLetters handles GET /admin/jobs/dead endpoint, filtering jobs by the type and error query
+// parameters and paginating them by the before cursor.
+func (s *Server) handleListDeadLetters(writer http.ResponseWriter, request *http.Request) {
+	query := request.URL.Query()
+	limit := defaultDeadLetterListLimit
+
+	if value := query.Get("limit"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed <= 0 {
+			writeError(writer, http.StatusBadRequest, "invalid limit")
+
+			return
+		}
+
+		limit = min(parsed, maxDeadLetterListLimit)
+	}
+
+	letters, next, err := s.jobs.DeadLetters(request.Context(), &jobs.DeadLetterFilter{
+		Type:   query.Get("type"),
+		Error:  query.Get("error"),
+		Before: query.Get("before"),
+		Limit:  limit,
+	})
+	if err != nil {
+		s.writeDeadLetterError(writer, request, err, "failed to list dead-lettered jobs")
+
+		return
+	}
+
+	writeJSON(writer, http.StatusOK, deadLettersResponse{Jobs: letters, Next: next})
+}
+
+// handleGetDeadLetter handles GET /admin/jobs/dead/{id} endpoint.
+func (s *Server) handleGetDeadLetter(writer http.ResponseWriter, request *http.Request) {
+	letter, err := s.jobs.DeadLetter(request.Context(), chi.URLParam(request, "id"))
+	if err != nil {
+		s.writeDeadLetterError(writer, request, err, "failed to get dead-lettered job")
+
+		return
+	}
+
+	writeJSON(writer, http.StatusOK, letter)
+}
+
+// handleRequeueDeadLetter handles POST /admin/jobs/dead/{id}/requeue endpoint, responding with 202 once the job
+// is queued again with its attempts reset.
+func (s *Server) handleRequeueDeadLetter(writer http.ResponseWriter, request *http.Request) {
+	job, err := s.jobs.Requeue(request.Context(), chi.URLParam(request, "id"))
+	if err != nil {
+		s.writeDeadLetterError(writer, request, err, "failed to requeue dead-lettered job")
+
+		return
+	}
+
+	writeJSON(writer, http.StatusAccepted, job)
+}
+
+// writeDeadLetterError writes the error response of a dead-lettered job operation.
+func (s *Server) writeDeadLetterError(writer http.ResponseWriter, request *http.Request, err error, message string) {
+	switch {
+	case errors.Is(err, jobs.ErrNotFound):
+		writeError(writer, http.StatusNotFound, "dead-lettered job not found")
+	case errors.Is(err, jobs.ErrInvalidCursor):
+		writeError(writer, http.StatusBadRequest, "invalid cursor")
+	default:
+		s.logger.Ctx(request.Context()).Error().Err(err).Msg(message)
+		writeError(writer, http.StatusInternalServerError, message)
+	}
+}
@@ -0,0 +1,122 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/jobs"
+	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/logger"
+)
+
+//nolint:paralleltest // sequential execution required to avoid redis key conflicts
+func TestDeadLetterRoutes(t *testing.T) {
+	log, err := logger.New(&logger.Config{Level: &[]string{"info"}[0]})
+	require.NoError(t, err)
+
+	jwtService := setupTestJWT(t)
+	redisClient := setupTestRedis(t)
+	prefix := fmt.Sprintf("{jobs:dead:%d}:", time.Now().UnixNano())
+
+	backgroundJobs, err := jobs.New(&jobs.Config{
+		Enabled:     &[]bool{true}[0],
+		Prefix:      &prefix,
+		MaxAttempts: &[]int{1}[0],
+	}, redisClient, nil, log)
+	require.NoError(t, err)
+
+	// jobs without handlers are dead-lettered at once
+	var ids []string
+
+	for _, jobType := range []string{"send_email", "deliver_webhook", "send_email"} {
+		job, err := backgroundJobs.Enqueue(context.Background(), jobType, nil, nil)
+		require.NoError(t, err)
+
+		ids = append(ids, job.ID)
+	}
+
+	backgroundJobs.Start()
+
+	require.Eventually(t, func() bool {
+		depths, err := backgroundJobs.Depths(context.Background())
+
+		return err == nil && depths["dead"] == 3
+	}, 5*time.Second, 10*time.Millisecond)
+
+	backgroundJobs.Stop(context.Background())
+
+	server, err := New(ServerParams{
+		Logger:     log,
+		APIHandler: &mockAPIHandler{},
+		JWT:        jwtService,
+		Redis:      redisClient,
+		Jobs:       backgroundJobs,
+	})
+	require.NoError(t, err)
+
+	token, err := jwtService.GenerateAccessToken("admin-1", "admin@example.com", "admin")
+	require.NoError(t, err)
+
+	// serve sends the request as an admin, returns the response.
+	serve := func(method string, path string) *httptest.ResponseRecorder {
+		request := httptest.NewRequest(method, path, nil)
+		request.Header.Set("Authorization", "Bearer "+*token)
+
+		recorder := httptest.NewRecorder()
+		server.Handler().ServeHTTP(recorder, request)
+
+		return recorder
+	}
+
+	// list returns the page of dead-lettered jobs at the path.
+	list := func(path string) deadLettersResponse {
+		recorder := serve(http.MethodGet, path)
+		require.Equal(t, http.StatusOK, recorder.Code)
+
+		var response deadLettersResponse
+		require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &response))
+
+		return response
+	}
+
+	t.Run("list jobs filtered by type and error by page", func(t *testing.T) {
+		page := list("/admin/jobs/dead?type=send_email&error=no+handler&limit=1")
+		require.Len(t, page.Jobs, 1)
+		assert.Equal(t, "send_email", page.Jobs[0].Type)
+		require.NotEmpty(t, page.Next)
+
+		page = list("/admin/jobs/dead?type=send_email&limit=1&before=" + page.Next)
+		require.Len(t, page.Jobs, 1)
+		assert.Equal(t, "send_email", page.Jobs[0].Type)
+
+		assert.Len(t, list("/admin/jobs/dead").Jobs, 3)
+		assert.Empty(t, list("/admin/jobs/dead?error=timeout").Jobs)
+	})
+
+	t.Run("reject invalid limit and cursor", func(t *testing.T) {
+		assert.Equal(t, http.StatusBadRequest, serve(http.MethodGet, "/admin/jobs/dead?limit=0").Code)
+		assert.Equal(t, http.StatusBadRequest, serve(http.MethodGet, "/admin/jobs/dead?before=invalid").Code)
+	})
+
+	t.Run("inspect and requeue jobs", func(t *testing.T) {
+		recorder := serve(http.MethodGet, "/admin/jobs/dead/"+ids[1])
+		require.Equal(t, http.StatusOK, recorder.Code)
+
+		var letter jobs.DeadLetter
+		require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &letter))
+		assert.Equal(t, "deliver_webhook", letter.Type)
+		assert.Contains(t, letter.Error, jobs.ErrNoHandler.Error())
+
+		assert.Equal(t, http.StatusAccepted, serve(http.MethodPost, "/admin/jobs/dead/"+ids[1]+"/requeue").Code)
+		assert.Len(t, list("/admin/jobs/dead").Jobs, 2)
+		assert.Equal(t, http.StatusNotFound, serve(http.MethodGet, "/admin/jobs/dead/"+ids[1]).Code)
+		assert.Equal(t, http.StatusNotFound, serve(http.MethodPost, "/admin/jobs/dead/"+ids[1]+"/requeue").Code)
+	})
+}
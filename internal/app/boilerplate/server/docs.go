@@ -0,0 +1,113 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/pocj8ur4in/boilerplate-go/internal/gen/api"
+	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/apierror"
+)
+
+// ErrInvalidErrorCatalog is returned when the error catalog file has no valid definitions.
+var ErrInvalidErrorCatalog = errors.New("invalid error catalog")
+
+// DocsConfig represents configuration for documentation endpoints.
+type DocsConfig struct {
+	// Enabled is whether documentation endpoints are enabled.
+	Enabled *bool `json:"enabled"`
+
+	// Path is the path prefix of documentation endpoints.
+	Path *string `json:"path"`
+
+	// ErrorsPath is path of the error catalog file, the embedded catalog of the apierror package is used if empty.
+	ErrorsPath *string `json:"errors_path"`
+}
+
+// errorCatalog represents the error catalog served at /docs/errors and read from the error catalog file.
+type errorCatalog struct {
+	// Errors is definitions of error codes.
+	Errors []apierror.Definition `json:"errors"`
+}
+
+// setDocsDefault sets default values for documentation endpoints on server.
+func (c *Config) setDocsDefault() {
+	if c.Docs == nil {
+		c.Docs = &DocsConfig{}
+	}
+
+	if c.Docs.Enabled == nil {
+		c.Docs.Enabled = &[]bool{true}[0]
+	}
+
+	if c.Docs.Path == nil {
+		c.Docs.Path = &[]string{"/docs"}[0]
+	}
+
+	if c.Docs.ErrorsPath == nil {
+		c.Docs.ErrorsPath = &[]string{""}[0]
+	}
+}
+
+// setupDocsRoutes sets up documentation endpoints.
+func (s *Server) setupDocsRoutes(router *chi.Mux, config *Config) error {
+	if !*config.Docs.Enabled {
+		return nil
+	}
+
+	catalog := errorCatalog{Errors: apierror.Catalog()}
+
+	if path := *config.Docs.ErrorsPath; path != "" {
+		loaded, err := loadErrorCatalog(path)
+		if err != nil {
+			return err
+		}
+
+		catalog = *loaded
+	}
+
+	// encode once, the catalog does not change at runtime
+	content, err := json.Marshal(catalog)
+	if err != nil {
+		return fmt.Errorf("failed to encode error catalog: %w", err)
+	}
+
+	router.Get(*config.Docs.Path+"/errors", serveAsset("application/json", content))
+
+	spec, err := api.GetSwagger()
+	if err != nil {
+		return fmt.Errorf("failed to load openapi spec: %w", err)
+	}
+
+	return setupExamplesRoutes(router, *config.Docs.Path, spec, catalog.Errors)
+}
+
+// loadErrorCatalog reads the error catalog file.
+func loadErrorCatalog(path string) (*errorCatalog, error) {
+	content, err := os.ReadFile(filepath.Clean(path))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read error catalog: %w", err)
+	}
+
+	var catalog errorCatalog
+	if err := json.Unmarshal(content, &catalog); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidErrorCatalog, err)
+	}
+
+	if len(catalog.Errors) == 0 {
+		return nil, fmt.Errorf("%w: no definitions", ErrInvalidErrorCatalog)
+	}
+
+	for _, definition := range catalog.Errors {
+		if definition.Code == "" || http.StatusText(definition.Status) == "" {
+			return nil, fmt.Errorf("%w: definition %q has no code or an unknown status", ErrInvalidErrorCatalog, definition.Code)
+		}
+	}
+
+	return &catalog, nil
+}
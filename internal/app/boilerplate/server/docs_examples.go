@@ -0,0 +1,441 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/go-chi/chi/v5"
+
+	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/apierror"
+	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/apimock"
+)
+
+// operationExamples represents request examples of all operations served at /docs/examples.
+type operationExamples struct {
+	// Operations is examples of operations, ordered by path and method.
+	Operations []operationExample `json:"operations"`
+}
+
+// operationExample represents request examples of an operation.
+type operationExample struct {
+	// OperationID is ID of the operation in the spec.
+	OperationID string `json:"operation_id"`
+
+	// Method is HTTP method of the operation.
+	Method string `json:"method"`
+
+	// Path is path of the operation, with path parameters.
+	Path string `json:"path"`
+
+	// Summary is summary of the operation.
+	Summary string `json:"summary,omitempty"`
+
+	// Authenticated is whether the operation requires an access token.
+	Authenticated bool `json:"authenticated"`
+
+	// ContentType is content type of the request body, empty without a body.
+	ContentType string `json:"content_type,omitempty"`
+
+	// Request is example request body.
+	Request any `json:"request,omitempty"`
+
+	// Status is status code of the successful response.
+	Status int `json:"status,omitempty"`
+
+	// Response is example body of the successful response.
+	Response any `json:"response,omitempty"`
+
+	// Errors is definitions of error codes of the error responses of the operation.
+	Errors []apierror.Definition `json:"errors"`
+
+	// Snippets is snippets sending the example request, reading the base URL and token from variables.
+	Snippets snippets `json:"snippets"`
+}
+
+// snippets represents snippets sending a request.
+type snippets struct {
+	// Curl is the request with curl, reading $BASE_URL and $TOKEN.
+	Curl string `json:"curl"`
+
+	// Go is the request with net/http, reading baseURL and token.
+	Go string `json:"go"`
+
+	// TypeScript is the request with fetch, reading baseUrl and token.
+	TypeScript string `json:"typescript"`
+}
+
+// exampleRequest represents the example request of an operation, rendered as snippets.
+type exampleRequest struct {
+	// method is HTTP method of the request.
+	method string
+
+	// target is path and query of the request, with example parameters.
+	target string
+
+	// authenticated is whether the request sends the access token.
+	authenticated bool
+
+	// contentType is content type of the body, empty without a body.
+	contentType string
+
+	// body is encoded body of the request.
+	body string
+}
+
+// setupExamplesRoutes sets up request examples of operations of the spec, with the errors of the catalog.
+func setupExamplesRoutes(router *chi.Mux, path string, spec *openapi3.T, catalog []apierror.Definition) error {
+	examples, err := buildExamples(spec, catalog)
+	if err != nil {
+		return err
+	}
+
+	// encode once, the spec does not change at runtime
+	content, err := json.Marshal(examples)
+	if err != nil {
+		return fmt.Errorf("failed to encode examples: %w", err)
+	}
+
+	operations := make(map[string][]byte, len(examples.Operations))
+
+	for _, example := range examples.Operations {
+		encoded, err := json.Marshal(example)
+		if err != nil {
+			return fmt.Errorf("failed to encode examples of %s: %w", example.OperationID, err)
+		}
+
+		operations[example.OperationID] = encoded
+	}
+
+	router.Get(path+"/examples", serveAsset("application/json", content))
+	router.Get(path+"/examples/{operationID}", func(writer http.ResponseWriter, request *http.Request) {
+		encoded, ok := operations[chi.URLParam(request, "operationID")]
+		if !ok {
+			writeError(writer, http.StatusNotFound, "operation not found")
+
+			return
+		}
+
+		serveAsset("application/json", encoded)(writer, request)
+	})
+
+	return nil
+}
+
+// buildExamples builds examples of all operations of the spec.
+func buildExamples(spec *openapi3.T, catalog []apierror.Definition) (*operationExamples, error) {
+	examples := &operationExamples{Operations: []operationExample{}}
+
+	paths := spec.Paths.InMatchingOrder()
+	slices.Sort(paths)
+
+	for _, path := range paths {
+		item := spec.Paths.Value(path)
+
+		methods := make([]string, 0, len(item.Operations()))
+		for method := range item.Operations() {
+			methods = append(methods, method)
+		}
+
+		slices.Sort(methods)
+
+		for _, method := range methods {
+			example, err := buildExample(spec, path, method, item, catalog)
+			if err != nil {
+				return nil, err
+			}
+
+			examples.Operations = append(examples.Operations, *example)
+		}
+	}
+
+	return examples, nil
+}
+
+// buildExample builds examples of the operation of the method on the path.
+func buildExample(
+	spec *openapi3.T, path, method string, item *openapi3.PathItem, catalog []apierror.Definition,
+) (*operationExample, error) {
+	operation := item.GetOperation(method)
+
+	security := spec.Security
+	if operation.Security != nil {
+		security = *operation.Security
+	}
+
+	example := &operationExample{
+		OperationID:   operation.OperationID,
+		Method:        method,
+		Path:          path,
+		Summary:       operation.Summary,
+		Authenticated: requiresAuth(security),
+		Errors:        []apierror.Definition{},
+	}
+
+	request := exampleRequest{
+		method:        method,
+		target:        exampleTarget(path, slices.Concat(item.Parameters, operation.Parameters)),
+		authenticated: example.Authenticated,
+	}
+
+	if operation.RequestBody != nil && operation.RequestBody.Value != nil {
+		contentType, media := jsonContent(operation.RequestBody.Value.Content)
+		if media != nil {
+			example.ContentType = contentType
+			example.Request = apimock.MediaExample(media, "")
+
+			body, err := encodeExample(contentType, example.Request)
+			if err != nil {
+				return nil, fmt.Errorf("failed to encode request example of %s: %w", operation.OperationID, err)
+			}
+
+			request.contentType = contentType
+			request.body = body
+		}
+	}
+
+	codes := make([]string, 0, operation.Responses.Len())
+	for code := range operation.Responses.Map() {
+		codes = append(codes, code)
+	}
+
+	slices.Sort(codes)
+
+	for _, code := range codes {
+		status, err := strconv.Atoi(code)
+		if err != nil {
+			continue
+		}
+
+		// the first successful response is the example, errors are described by the catalog
+		if status < http.StatusBadRequest {
+			if example.Status == 0 {
+				example.Status = status
+				example.Response = responseExample(operation.Responses.Value(code))
+			}
+
+			continue
+		}
+
+		for _, definition := range catalog {
+			if definition.Status == status {
+				example.Errors = append(example.Errors, definition)
+			}
+		}
+	}
+
+	example.Snippets = snippets{
+		Curl:       curlSnippet(request),
+		Go:         goSnippet(request),
+		TypeScript: typeScriptSnippet(request),
+	}
+
+	return example, nil
+}
+
+// requiresAuth returns whether the security requirements require credentials, an empty requirement makes
+// them optional.
+func requiresAuth(security openapi3.SecurityRequirements) bool {
+	if len(security) == 0 {
+		return false
+	}
+
+	for _, requirement := range security {
+		if len(requirement) == 0 {
+			return false
+		}
+	}
+
+	return true
+}
+
+// exampleTarget returns the path with path parameters and required query parameters replaced by their examples.
+func exampleTarget(path string, parameters openapi3.Parameters) string {
+	query := url.Values{}
+
+	for _, parameterRef := range parameters {
+		parameter := parameterRef.Value
+		if parameter == nil {
+			continue
+		}
+
+		value := parameterExample(parameter)
+
+		switch parameter.In {
+		case openapi3.ParameterInPath:
+			path = strings.ReplaceAll(path, "{"+parameter.Name+"}", url.PathEscape(value))
+		case openapi3.ParameterInQuery:
+			if parameter.Required {
+				query.Set(parameter.Name, value)
+			}
+		}
+	}
+
+	if len(query) == 0 {
+		return path
+	}
+
+	return path + "?" + query.Encode()
+}
+
+// parameterExample returns the example of the parameter as text.
+func parameterExample(parameter *openapi3.Parameter) string {
+	value := parameter.Example
+	if value == nil && parameter.Schema != nil {
+		value = apimock.Example(parameter.Schema.Value)
+	}
+
+	if text, ok := value.(string); ok {
+		return text
+	}
+
+	return fmt.Sprint(value)
+}
+
+// jsonContent returns the JSON media type of the content, or the first media type by name.
+func jsonContent(content openapi3.Content) (string, *openapi3.MediaType) {
+	if media := content.Get("application/json"); media != nil {
+		return "application/json", media
+	}
+
+	contentTypes := make([]string, 0, len(content))
+	for contentType := range content {
+		contentTypes = append(contentTypes, contentType)
+	}
+
+	slices.Sort(contentTypes)
+
+	if len(contentTypes) == 0 {
+		return "", nil
+	}
+
+	return contentTypes[0], content[contentTypes[0]]
+}
+
+// responseExample returns the example body of the response, nil without content.
+func responseExample(response *openapi3.ResponseRef) any {
+	if response == nil || response.Value == nil {
+		return nil
+	}
+
+	_, media := jsonContent(response.Value.Content)
+	if media == nil {
+		return nil
+	}
+
+	return apimock.MediaExample(media, "")
+}
+
+// encodeExample encodes the example body of the content type, text examples of other types are sent as is.
+func encodeExample(contentType string, value any) (string, error) {
+	if text, ok := value.(string); ok && !strings.Contains(contentType, "json") {
+		return text, nil
+	}
+
+	body, err := json.Marshal(value)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode example: %w", err)
+	}
+
+	return string(body), nil
+}
+
+// curlSnippet returns the request with curl.
+func curlSnippet(request exampleRequest) string {
+	lines := []string{fmt.Sprintf(`curl -X %s "$BASE_URL%s"`, request.method, request.target)}
+
+	if request.authenticated {
+		lines = append(lines, `  -H "Authorization: Bearer $TOKEN"`)
+	}
+
+	if request.contentType != "" {
+		lines = append(lines,
+			fmt.Sprintf(`  -H "Content-Type: %s"`, request.contentType),
+			"  -d '"+strings.ReplaceAll(request.body, "'", `'\''`)+"'",
+		)
+	}
+
+	return strings.Join(lines, " \\\n")
+}
+
+// goSnippet returns the request with net/http.
+func goSnippet(request exampleRequest) string {
+	var snippet strings.Builder
+
+	body := "nil"
+
+	if request.contentType != "" {
+		literal := "`" + request.body + "`"
+		if strings.Contains(request.body, "`") {
+			literal = strconv.Quote(request.body)
+		}
+
+		snippet.WriteString("body := strings.NewReader(" + literal + ")\n\n")
+
+		body = "body"
+	}
+
+	// constants of net/http are named after methods (http.MethodPost)
+	method := "http.Method" + request.method[:1] + strings.ToLower(request.method[1:])
+
+	fmt.Fprintf(&snippet, "request, err := http.NewRequestWithContext(ctx, %s, baseURL+%q, %s)\n",
+		method, request.target, body)
+	snippet.WriteString("if err != nil {\n\treturn err\n}\n\n")
+
+	if request.authenticated {
+		snippet.WriteString("request.Header.Set(\"Authorization\", \"Bearer \"+token)\n")
+	}
+
+	if request.contentType != "" {
+		fmt.Fprintf(&snippet, "request.Header.Set(\"Content-Type\", %q)\n", request.contentType)
+	}
+
+	if request.authenticated || request.contentType != "" {
+		snippet.WriteString("\n")
+	}
+
+	snippet.WriteString("response, err := http.DefaultClient.Do(request)\n")
+	snippet.WriteString("if err != nil {\n\treturn err\n}\n")
+	snippet.WriteString("defer response.Body.Close()")
+
+	return snippet.String()
+}
+
+// typeScriptSnippet returns the request with fetch.
+func typeScriptSnippet(request exampleRequest) string {
+	var snippet strings.Builder
+
+	fmt.Fprintf(&snippet, "const response = await fetch(`${baseUrl}%s`, {\n", request.target)
+	fmt.Fprintf(&snippet, "  method: %q,\n", request.method)
+
+	if request.authenticated || request.contentType != "" {
+		snippet.WriteString("  headers: {\n")
+
+		if request.authenticated {
+			snippet.WriteString("    Authorization: `Bearer ${token}`,\n")
+		}
+
+		if request.contentType != "" {
+			fmt.Fprintf(&snippet, "    \"Content-Type\": %q,\n", request.contentType)
+		}
+
+		snippet.WriteString("  },\n")
+	}
+
+	if request.contentType != "" {
+		if strings.Contains(request.contentType, "json") {
+			fmt.Fprintf(&snippet, "  body: JSON.stringify(%s),\n", request.body)
+		} else {
+			fmt.Fprintf(&snippet, "  body: %s,\n", strconv.Quote(request.body))
+		}
+	}
+
+	snippet.WriteString("});")
+
+	return snippet.String()
+}
@@ -0,0 +1,206 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/apierror"
+)
+
+// testExamplesSpec is a spec with an authenticated operation taking path and query parameters and a body.
+const testExamplesSpec = `
+openapi: 3.0.0
+info:
+  title: test
+  version: 0.0.0
+security:
+  - BearerAuth: []
+paths:
+  /notes/{id}:
+    parameters:
+      - name: id
+        in: path
+        required: true
+        schema:
+          type: string
+        example: note-1
+    put:
+      operationId: UpdateNote
+      summary: update note
+      parameters:
+        - name: notify
+          in: query
+          required: true
+          schema:
+            type: boolean
+        - name: trace
+          in: query
+          schema:
+            type: string
+      requestBody:
+        content:
+          application/json:
+            schema:
+              type: object
+            example:
+              text: "it's done"
+      responses:
+        "200":
+          description: OK
+          content:
+            application/json:
+              example:
+                id: note-1
+        "404":
+          description: Not Found
+  /ping:
+    get:
+      operationId: Ping
+      security: []
+      responses:
+        "204":
+          description: No Content
+`
+
+// loadTestExamplesSpec loads the test examples spec.
+func loadTestExamplesSpec(t *testing.T) *openapi3.T {
+	t.Helper()
+
+	spec, err := openapi3.NewLoader().LoadFromData([]byte(testExamplesSpec))
+	require.NoError(t, err)
+
+	return spec
+}
+
+func TestBuildExamples(t *testing.T) {
+	t.Parallel()
+
+	examples, err := buildExamples(loadTestExamplesSpec(t), apierror.Catalog())
+	require.NoError(t, err)
+	require.Len(t, examples.Operations, 2)
+
+	update := examples.Operations[0]
+	assert.Equal(t, "UpdateNote", update.OperationID)
+	assert.Equal(t, http.MethodPut, update.Method)
+	assert.Equal(t, "/notes/{id}", update.Path)
+	assert.True(t, update.Authenticated)
+	assert.Equal(t, "application/json", update.ContentType)
+	assert.Equal(t, map[string]any{"text": "it's done"}, update.Request)
+	assert.Equal(t, http.StatusOK, update.Status)
+	assert.Equal(t, map[string]any{"id": "note-1"}, update.Response)
+	require.Len(t, update.Errors, 1)
+	assert.Equal(t, apierror.CodeNotFound, update.Errors[0].Code)
+
+	assert.Equal(t, `curl -X PUT "$BASE_URL/notes/note-1?notify=true" \
+  -H "Authorization: Bearer $TOKEN" \
+  -H "Content-Type: application/json" \
+  -d '{"text":"it'\''s done"}'`, update.Snippets.Curl)
+
+	assert.Equal(t, "body := strings.NewReader(`{\"text\":\"it's done\"}`)\n\n"+
+		"request, err := http.NewRequestWithContext(ctx, http.MethodPut, baseURL+\"/notes/note-1?notify=true\", body)\n"+
+		"if err != nil {\n\treturn err\n}\n\n"+
+		"request.Header.Set(\"Authorization\", \"Bearer \"+token)\n"+
+		"request.Header.Set(\"Content-Type\", \"application/json\")\n\n"+
+		"response, err := http.DefaultClient.Do(request)\n"+
+		"if err != nil {\n\treturn err\n}\n"+
+		"defer response.Body.Close()", update.Snippets.Go)
+
+	assert.Equal(t, "const response = await fetch(`${baseUrl}/notes/note-1?notify=true`, {\n"+
+		"  method: \"PUT\",\n"+
+		"  headers: {\n"+
+		"    Authorization: `Bearer ${token}`,\n"+
+		"    \"Content-Type\": \"application/json\",\n"+
+		"  },\n"+
+		"  body: JSON.stringify({\"text\":\"it's done\"}),\n"+
+		"});", update.Snippets.TypeScript)
+
+	// operations without security and body send neither
+	ping := examples.Operations[1]
+	assert.Equal(t, "Ping", ping.OperationID)
+	assert.False(t, ping.Authenticated)
+	assert.Equal(t, http.StatusNoContent, ping.Status)
+	assert.Empty(t, ping.Errors)
+	assert.Equal(t, `curl -X GET "$BASE_URL/ping"`, ping.Snippets.Curl)
+	assert.Equal(t, "const response = await fetch(`${baseUrl}/ping`, {\n  method: \"GET\",\n});",
+		ping.Snippets.TypeScript)
+}
+
+func TestRequiresAuth(t *testing.T) {
+	t.Parallel()
+
+	bearer := openapi3.SecurityRequirement{"BearerAuth": []string{}}
+
+	assert.False(t, requiresAuth(nil))
+	assert.True(t, requiresAuth(openapi3.SecurityRequirements{bearer}))
+	assert.False(t, requiresAuth(openapi3.SecurityRequirements{bearer, {}}), "empty requirement makes it optional")
+}
+
+func TestEncodeExample(t *testing.T) {
+	t.Parallel()
+
+	body, err := encodeExample("application/json", map[string]any{"a": 1})
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"a": 1}`, body)
+
+	body, err = encodeExample("text/plain", "hello")
+	require.NoError(t, err)
+	assert.Equal(t, "hello", body)
+}
+
+//nolint:paralleltest // sequential execution required to avoid redis key conflicts
+func TestExamplesRoutes(t *testing.T) {
+	t.Run("serve examples of all operations", func(t *testing.T) {
+		server, err := newTestDocsServer(t, nil)
+		require.NoError(t, err)
+
+		recorder := httptest.NewRecorder()
+		server.Handler().ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/docs/examples", nil))
+
+		require.Equal(t, http.StatusOK, recorder.Code)
+		assert.Equal(t, "application/json", recorder.Header().Get("Content-Type"))
+
+		var examples operationExamples
+		require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &examples))
+
+		operationIDs := make([]string, 0, len(examples.Operations))
+		for _, example := range examples.Operations {
+			operationIDs = append(operationIDs, example.OperationID)
+		}
+
+		assert.Contains(t, operationIDs, "Login")
+		assert.Contains(t, operationIDs, "StatusCheck")
+	})
+
+	t.Run("serve examples of an operation", func(t *testing.T) {
+		server, err := newTestDocsServer(t, nil)
+		require.NoError(t, err)
+
+		recorder := httptest.NewRecorder()
+		server.Handler().ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/docs/examples/Login", nil))
+
+		require.Equal(t, http.StatusOK, recorder.Code)
+
+		var example operationExample
+		require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &example))
+
+		assert.Equal(t, "/auth/login", example.Path)
+		assert.Contains(t, example.Snippets.Curl, `"$BASE_URL/auth/login"`)
+		assert.NotEmpty(t, example.Errors)
+	})
+
+	t.Run("return 404 for unknown operation", func(t *testing.T) {
+		server, err := newTestDocsServer(t, nil)
+		require.NoError(t, err)
+
+		recorder := httptest.NewRecorder()
+		server.Handler().ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/docs/examples/Unknown", nil))
+
+		assert.Equal(t, http.StatusNotFound, recorder.Code)
+	})
+}
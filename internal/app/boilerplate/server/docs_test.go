@@ -0,0 +1,117 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/apierror"
+	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/logger"
+)
+
+// newTestDocsServer creates a test server with the given documentation configuration.
+func newTestDocsServer(t *testing.T, docs *DocsConfig) (*Server, error) {
+	t.Helper()
+
+	log, err := logger.New(&logger.Config{Level: &[]string{"info"}[0]})
+	require.NoError(t, err)
+
+	return New(ServerParams{
+		Config:     &Config{Docs: docs},
+		Logger:     log,
+		APIHandler: &mockAPIHandler{},
+		JWT:        setupTestJWT(t),
+		Redis:      setupTestRedis(t),
+	})
+}
+
+// writeErrorCatalog writes the error catalog file and returns its path.
+func writeErrorCatalog(t *testing.T, content string) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "errors.json")
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+
+	return path
+}
+
+func TestDocsDefault(t *testing.T) {
+	t.Parallel()
+
+	config := &Config{}
+	config.SetDefault()
+
+	require.NotNil(t, config.Docs)
+	assert.True(t, *config.Docs.Enabled)
+	assert.Equal(t, "/docs", *config.Docs.Path)
+	assert.Empty(t, *config.Docs.ErrorsPath)
+}
+
+//nolint:paralleltest // sequential execution required to avoid redis key conflicts
+func TestDocsRoutes(t *testing.T) {
+	t.Run("serve embedded error catalog", func(t *testing.T) {
+		server, err := newTestDocsServer(t, nil)
+		require.NoError(t, err)
+
+		recorder := httptest.NewRecorder()
+		server.Handler().ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/docs/errors", nil))
+
+		require.Equal(t, http.StatusOK, recorder.Code)
+		assert.Equal(t, "application/json", recorder.Header().Get("Content-Type"))
+
+		expected, err := json.Marshal(errorCatalog{Errors: apierror.Catalog()})
+		require.NoError(t, err)
+		assert.JSONEq(t, string(expected), recorder.Body.String())
+	})
+
+	t.Run("serve error catalog file", func(t *testing.T) {
+		path := writeErrorCatalog(t, `{"errors": [
+			{"code": "quota_exceeded", "status": 402, "description": "The plan quota is exhausted.",
+			 "example": {"error": "Quota exceeded", "code": "quota_exceeded"}}
+		]}`)
+
+		server, err := newTestDocsServer(t, &DocsConfig{ErrorsPath: &path})
+		require.NoError(t, err)
+
+		recorder := httptest.NewRecorder()
+		server.Handler().ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/docs/errors", nil))
+
+		require.Equal(t, http.StatusOK, recorder.Code)
+		assert.JSONEq(t, `{"errors": [
+			{"code": "quota_exceeded", "status": 402, "description": "The plan quota is exhausted.",
+			 "example": {"error": "Quota exceeded", "code": "quota_exceeded"}}
+		]}`, recorder.Body.String())
+	})
+
+	t.Run("return error for invalid error catalog file", func(t *testing.T) {
+		for _, content := range []string{`not json`, `{"errors": []}`, `{"errors": [{"code": "x", "status": 999}]}`} {
+			path := writeErrorCatalog(t, content)
+
+			_, err := newTestDocsServer(t, &DocsConfig{ErrorsPath: &path})
+			require.ErrorIs(t, err, ErrInvalidErrorCatalog, content)
+		}
+	})
+
+	t.Run("return error for missing error catalog file", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "missing.json")
+
+		_, err := newTestDocsServer(t, &DocsConfig{ErrorsPath: &path})
+		require.Error(t, err)
+	})
+
+	t.Run("disable documentation endpoints", func(t *testing.T) {
+		server, err := newTestDocsServer(t, &DocsConfig{Enabled: &[]bool{false}[0]})
+		require.NoError(t, err)
+
+		recorder := httptest.NewRecorder()
+		server.Handler().ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/docs/errors", nil))
+
+		assert.Equal(t, http.StatusNotFound, recorder.Code)
+	})
+}
@@ -0,0 +1,155 @@
+package server
+
+import (
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+)
+
+const (
+	// DrainStateServing is state of the server before shutdown.
+	DrainStateServing = "serving"
+
+	// DrainStateDraining is state of the server while in-flight requests are drained.
+	DrainStateDraining = "draining"
+
+	// DrainStateDrained is state of the server once all in-flight requests completed.
+	DrainStateDrained = "drained"
+
+	// DrainStateTimedOut is state of the server once shutdown timed out with requests still in flight.
+	DrainStateTimedOut = "timed_out"
+)
+
+// DrainStats represents progress of draining in-flight requests on shutdown.
+type DrainStats struct {
+	// State is state of the drain (serving, draining, drained, timed_out).
+	State string `json:"state"`
+
+	// InFlight is number of requests being processed.
+	InFlight int64 `json:"in_flight"`
+
+	// OldestRequestAgeMs is time in milliseconds the oldest request being processed has run for.
+	OldestRequestAgeMs int64 `json:"oldest_request_age_ms"`
+
+	// InFlightAtStart is number of requests being processed when the drain started.
+	InFlightAtStart int64 `json:"in_flight_at_start"`
+
+	// Drained is number of requests completed since the drain started.
+	Drained int64 `json:"drained"`
+
+	// Progress is ratio of requests in flight at start that were drained (0-1).
+	Progress float64 `json:"progress"`
+
+	// ElapsedMs is time in milliseconds since the drain started.
+	ElapsedMs int64 `json:"elapsed_ms"`
+
+	// RemainingMs is time in milliseconds until shutdown times out, 0 without deadline.
+	RemainingMs int64 `json:"remaining_ms"`
+}
+
+// drainState tracks the drain of in-flight requests on shutdown.
+type drainState struct {
+	// mu guards the fields.
+	mu sync.Mutex
+
+	// startedAt is time the drain started, zero before shutdown.
+	startedAt time.Time
+
+	// finishedAt is time the drain finished, zero while draining.
+	finishedAt time.Time
+
+	// deadline is time shutdown times out, zero without deadline.
+	deadline time.Time
+
+	// timedOut is whether shutdown timed out with requests still in flight.
+	timedOut bool
+
+	// activeAtStart is number of requests being processed when the drain started.
+	activeAtStart int64
+
+	// completedAtStart is number of processed requests when the drain started.
+	completedAtStart int64
+}
+
+// start marks the drain as started.
+func (d *drainState) start(active, completed int64, deadline time.Time) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.startedAt = time.Now()
+	d.deadline = deadline
+	d.activeAtStart = active
+	d.completedAtStart = completed
+}
+
+// finish marks the drain as finished.
+func (d *drainState) finish(timedOut bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.finishedAt = time.Now()
+	d.timedOut = timedOut
+}
+
+// drainStats returns progress of the drain of in-flight requests.
+func (s *Server) drainStats() DrainStats {
+	s.drain.mu.Lock()
+	defer s.drain.mu.Unlock()
+
+	stats := DrainStats{
+		State:              DrainStateServing,
+		InFlight:           s.inFlight.Active(),
+		OldestRequestAgeMs: s.inFlight.OldestAge().Milliseconds(),
+	}
+
+	if s.drain.startedAt.IsZero() {
+		return stats
+	}
+
+	stats.State = DrainStateDraining
+	stats.InFlightAtStart = s.drain.activeAtStart
+	stats.Drained = s.inFlight.Completed() - s.drain.completedAtStart
+	stats.Progress = 1
+
+	if s.drain.activeAtStart > 0 {
+		stats.Progress = min(float64(stats.Drained)/float64(s.drain.activeAtStart), 1)
+	}
+
+	end := time.Now()
+
+	if !s.drain.finishedAt.IsZero() {
+		end = s.drain.finishedAt
+		stats.State = DrainStateDrained
+
+		if s.drain.timedOut {
+			stats.State = DrainStateTimedOut
+		}
+	}
+
+	stats.ElapsedMs = end.Sub(s.drain.startedAt).Milliseconds()
+
+	if !s.drain.deadline.IsZero() {
+		stats.RemainingMs = max(s.drain.deadline.Sub(end).Milliseconds(), 0)
+	}
+
+	return stats
+}
+
+// handleDrain handles GET /drain endpoint.
+func (s *Server) handleDrain(writer http.ResponseWriter, _ *http.Request) {
+	writeJSON(writer, http.StatusOK, s.drainStats())
+}
+
+// adminHandler creates the handler of the admin listener, which keeps serving while requests are drained.
+func (s *Server) adminHandler() http.Handler {
+	router := chi.NewRouter()
+
+	router.NotFound(func(writer http.ResponseWriter, _ *http.Request) {
+		writeError(writer, http.StatusNotFound, http.StatusText(http.StatusNotFound))
+	})
+	router.Get("/drain", s.handleDrain)
+
+	return router
+}
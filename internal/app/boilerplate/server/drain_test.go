@@ -0,0 +1,189 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pocj8ur4in/boilerplate-go/internal/app/boilerplate/server/middleware"
+	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/logger"
+)
+
+func TestDrainStats(t *testing.T) {
+	t.Parallel()
+
+	t.Run("report serving before shutdown", func(t *testing.T) {
+		t.Parallel()
+
+		server := &Server{inFlight: middleware.NewInFlight()}
+
+		stats := server.drainStats()
+		assert.Equal(t, DrainStateServing, stats.State)
+		assert.Zero(t, stats.InFlight)
+		assert.Zero(t, stats.ElapsedMs)
+	})
+
+	t.Run("report progress while draining", func(t *testing.T) {
+		t.Parallel()
+
+		server := &Server{inFlight: middleware.NewInFlight()}
+		server.drain.start(4, 0, time.Now().Add(time.Minute))
+
+		stats := server.drainStats()
+		assert.Equal(t, DrainStateDraining, stats.State)
+		assert.Equal(t, int64(4), stats.InFlightAtStart)
+		assert.Zero(t, stats.Drained)
+		assert.Zero(t, stats.Progress)
+		assert.Positive(t, stats.RemainingMs)
+	})
+
+	t.Run("report drained or timed out once finished", func(t *testing.T) {
+		t.Parallel()
+
+		drained := &Server{inFlight: middleware.NewInFlight()}
+		drained.drain.start(0, 0, time.Time{})
+		drained.drain.finish(false)
+
+		stats := drained.drainStats()
+		assert.Equal(t, DrainStateDrained, stats.State)
+		assert.InDelta(t, 1, stats.Progress, 0)
+		assert.Zero(t, stats.RemainingMs)
+
+		timedOut := &Server{inFlight: middleware.NewInFlight()}
+		timedOut.drain.start(2, 0, time.Now())
+		timedOut.drain.finish(true)
+
+		assert.Equal(t, DrainStateTimedOut, timedOut.drainStats().State)
+	})
+}
+
+func TestAdminListener(t *testing.T) {
+	t.Parallel()
+
+	t.Run("serve drain statistics while draining", func(t *testing.T) {
+		t.Parallel()
+
+		log, err := logger.New(&logger.Config{Level: &[]string{"info"}[0]})
+		require.NoError(t, err)
+
+		handler := newSlowAPIHandler()
+		addr, adminAddr := freeAddr(t), freeAddr(t)
+
+		config := &Config{
+			Listeners: []*ListenerConfig{{Addr: &addr}},
+			Admin:     &AdminConfig{Addr: &adminAddr},
+		}
+
+		server, err := New(ServerParams{
+			Config:     config,
+			Logger:     log,
+			APIHandler: handler,
+			JWT:        setupTestJWT(t),
+			Redis:      setupTestRedis(t),
+		})
+		require.NoError(t, err)
+
+		done := make(chan error, 1)
+
+		go func() {
+			done <- server.Run()
+		}()
+
+		waitForStatus(t, http.DefaultClient, "http://"+adminAddr+"/drain")
+
+		go func() {
+			resp, err := http.Get("http://" + addr + "/status") //nolint:noctx // test request
+			if err == nil {
+				_ = resp.Body.Close()
+			}
+		}()
+
+		<-handler.started
+
+		shutdown := make(chan error, 1)
+
+		go func() {
+			ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+			defer cancel()
+
+			shutdown <- server.Shutdown(ctx)
+		}()
+
+		// drain statistics are served while the request is in flight
+		var stats DrainStats
+
+		require.Eventually(t, func() bool {
+			stats = getDrainStats(t, "http://"+adminAddr+"/drain")
+
+			return stats.State == DrainStateDraining
+		}, time.Second, 10*time.Millisecond)
+
+		assert.Equal(t, int64(1), stats.InFlight)
+		assert.Equal(t, int64(1), stats.InFlightAtStart)
+		assert.Zero(t, stats.Drained)
+		assert.Positive(t, stats.RemainingMs)
+
+		close(handler.release)
+
+		require.NoError(t, <-shutdown)
+		require.NoError(t, <-done)
+
+		stats = server.drainStats()
+		assert.Equal(t, DrainStateDrained, stats.State)
+		assert.Equal(t, int64(1), stats.Drained)
+		assert.InDelta(t, 1, stats.Progress, 0)
+	})
+
+	t.Run("return error for admin address outside address family", func(t *testing.T) {
+		t.Parallel()
+
+		log, err := logger.New(&logger.Config{Level: &[]string{"info"}[0]})
+		require.NoError(t, err)
+
+		config := &Config{
+			AddressFamily: &[]string{AddressFamilyTCP4}[0],
+			Admin:         &AdminConfig{Addr: &[]string{"[::1]:9999"}[0]},
+		}
+
+		_, err = New(ServerParams{
+			Config:     config,
+			Logger:     log,
+			APIHandler: &mockAPIHandler{},
+			JWT:        setupTestJWT(t),
+			Redis:      setupTestRedis(t),
+		})
+		require.ErrorIs(t, err, ErrAddressFamilyMismatch)
+	})
+
+	t.Run("respond with not found outside drain statistics", func(t *testing.T) {
+		t.Parallel()
+
+		server := &Server{inFlight: middleware.NewInFlight()}
+
+		recorder := httptest.NewRecorder()
+		server.adminHandler().ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/users", nil))
+
+		assert.Equal(t, http.StatusNotFound, recorder.Code)
+	})
+}
+
+// getDrainStats gets drain statistics of the admin listener.
+func getDrainStats(t *testing.T, url string) DrainStats {
+	t.Helper()
+
+	resp, err := http.Get(url) //nolint:noctx // test request
+	require.NoError(t, err)
+
+	defer func() { _ = resp.Body.Close() }()
+
+	var stats DrainStats
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&stats))
+
+	return stats
+}
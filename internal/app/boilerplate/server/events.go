@@ -0,0 +1,32 @@
+package server
+
+import (
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/pocj8ur4in/boilerplate-go/internal/app/boilerplate/server/middleware"
+	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/jwt"
+)
+
+// setupEventRoutes sets up the server-sent events endpoint, streaming events to users authenticated with JWT.
+func (s *Server) setupEventRoutes(router *chi.Mux, jwtService *jwt.JWT) {
+	if s.broker == nil {
+		return
+	}
+
+	router.Group(func(router chi.Router) {
+		router.Use(queryAccessToken)
+		router.Use(middleware.RequireBearerAuth)
+		router.Use(middleware.JWTAuth(jwtService, s.logger))
+
+		router.Method(http.MethodGet, *s.broker.Config().Path, s.broker.Handler(eventsUserID))
+	})
+}
+
+// eventsUserID returns ID of the user authenticated on the request.
+func eventsUserID(request *http.Request) string {
+	userID, _ := request.Context().Value(middleware.UserIDKey).(string)
+
+	return userID
+}
@@ -0,0 +1,155 @@
+package server
+
+import (
+	"bufio"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/logger"
+	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/sse"
+)
+
+// newTestEventsServer creates a test server with an sse broker on the channel of the test and serves its handler.
+func newTestEventsServer(t *testing.T) (*Server, *sse.Broker, string) {
+	t.Helper()
+
+	log, err := logger.New(&logger.Config{Level: &[]string{"info"}[0]})
+	require.NoError(t, err)
+
+	redisClient := setupTestRedis(t)
+
+	broker, err := sse.New(&sse.Config{
+		Channel:    &[]string{"sse:events:" + t.Name()}[0],
+		HistoryKey: &[]string{"sse:history:" + t.Name()}[0],
+	}, redisClient, log)
+	require.NoError(t, err)
+
+	t.Cleanup(func() {
+		_ = broker.Close()
+	})
+
+	server, err := New(ServerParams{
+		Logger:     log,
+		APIHandler: &mockAPIHandler{},
+		JWT:        setupTestJWT(t),
+		Redis:      redisClient,
+		Broker:     broker,
+	})
+	require.NoError(t, err)
+
+	httpServer := httptest.NewServer(server.Handler())
+	t.Cleanup(httpServer.Close)
+
+	return server, broker, httpServer.URL
+}
+
+// openEventStream requests the events endpoint with the headers and returns the response.
+func openEventStream(t *testing.T, target string, header http.Header) *http.Response {
+	t.Helper()
+
+	request, err := http.NewRequestWithContext(t.Context(), http.MethodGet, target, nil)
+	require.NoError(t, err)
+
+	request.Header = header
+	request.Header.Set("Accept", "text/event-stream")
+
+	response, err := http.DefaultClient.Do(request)
+	require.NoError(t, err)
+
+	t.Cleanup(func() {
+		_ = response.Body.Close()
+	})
+
+	return response
+}
+
+// readEventBlock reads the lines of the stream up to the next blank line.
+func readEventBlock(t *testing.T, reader *bufio.Reader) string {
+	t.Helper()
+
+	block := ""
+
+	for {
+		line, err := reader.ReadString('\n')
+		require.NoError(t, err)
+
+		if line == "\n" {
+			return block
+		}
+
+		block += line
+	}
+}
+
+func TestEventRoutes(t *testing.T) {
+	t.Parallel()
+
+	t.Run("reject streams without token", func(t *testing.T) {
+		t.Parallel()
+
+		_, _, serverURL := newTestEventsServer(t)
+
+		response := openEventStream(t, serverURL+"/events", http.Header{})
+		assert.Equal(t, http.StatusUnauthorized, response.StatusCode)
+
+		response = openEventStream(t, serverURL+"/events?access_token=invalid", http.Header{})
+		assert.Equal(t, http.StatusUnauthorized, response.StatusCode)
+	})
+
+	t.Run("stream events of the authenticated user", func(t *testing.T) {
+		t.Parallel()
+
+		_, broker, serverURL := newTestEventsServer(t)
+
+		token, err := setupTestJWT(t).GenerateAccessToken("user-1", "user@example.com", "user")
+		require.NoError(t, err)
+
+		response := openEventStream(t, serverURL+"/events", http.Header{"Authorization": {"Bearer " + *token}})
+		require.Equal(t, http.StatusOK, response.StatusCode)
+		assert.Equal(t, "text/event-stream", response.Header.Get("Content-Type"))
+
+		response = openEventStream(t, serverURL+"/events?access_token="+*token, http.Header{})
+		require.Equal(t, http.StatusOK, response.StatusCode)
+
+		require.Eventually(t, func() bool { return broker.Streams("user-1") == 2 }, time.Second, 5*time.Millisecond)
+
+		id, err := broker.Send(t.Context(), "user-1", sse.Event{Type: "greeting", Data: "hello"})
+		require.NoError(t, err)
+
+		reader := bufio.NewReader(response.Body)
+		assert.Equal(t, "retry: 3000\n", readEventBlock(t, reader))
+		assert.Equal(t, "id: "+id+"\nevent: greeting\ndata: hello\n", readEventBlock(t, reader))
+	})
+
+	t.Run("end streams on shutdown", func(t *testing.T) {
+		t.Parallel()
+
+		server, broker, serverURL := newTestEventsServer(t)
+
+		token, err := setupTestJWT(t).GenerateAccessToken("user-1", "user@example.com", "user")
+		require.NoError(t, err)
+
+		response := openEventStream(t, serverURL+"/events?access_token="+*token, http.Header{})
+		require.Equal(t, http.StatusOK, response.StatusCode)
+
+		require.Eventually(t, func() bool { return broker.Streams("") == 1 }, time.Second, 5*time.Millisecond)
+
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+
+		require.NoError(t, server.Shutdown(ctx))
+
+		reader := bufio.NewReader(response.Body)
+		assert.Equal(t, "retry: 3000\n", readEventBlock(t, reader))
+
+		_, err = reader.ReadString('\n')
+		require.Error(t, err)
+		assert.Zero(t, broker.Streams(""))
+	})
+}
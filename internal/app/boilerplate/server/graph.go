@@ -0,0 +1,28 @@
+package server
+
+import (
+	"net/http"
+)
+
+// graphPath is path of the dependency graph endpoint, protected like admin endpoints.
+const graphPath = "/debug/graph"
+
+// SetGraph sets the dependency graph of the application in the DOT language, served at /debug/graph.
+func (s *Server) SetGraph(graph string) {
+	s.graph.Store(&graph)
+}
+
+// handleGraph handles GET /debug/graph endpoint, render it with `dot -Tsvg`.
+func (s *Server) handleGraph(writer http.ResponseWriter, _ *http.Request) {
+	graph := s.graph.Load()
+	if graph == nil {
+		writeError(writer, http.StatusNotFound, "dependency graph is not available")
+
+		return
+	}
+
+	writer.Header().Set("Content-Type", "text/vnd.graphviz; charset=utf-8")
+	writer.WriteHeader(http.StatusOK)
+
+	_, _ = writer.Write([]byte(*graph))
+}
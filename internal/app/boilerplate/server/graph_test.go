@@ -0,0 +1,52 @@
+package server
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+//nolint:paralleltest // sequential execution required to avoid redis key conflicts
+func TestGraphRoute(t *testing.T) {
+	t.Run("reject unauthenticated request", func(t *testing.T) {
+		jwtService := setupTestJWT(t)
+		server := newTestAdminServer(t, jwtService)
+		server.SetGraph("digraph {}")
+
+		recorder := adminRequest(t, server, jwtService, graphPath, nil)
+
+		assert.Equal(t, http.StatusUnauthorized, recorder.Code)
+	})
+
+	t.Run("reject non-admin role", func(t *testing.T) {
+		jwtService := setupTestJWT(t)
+		server := newTestAdminServer(t, jwtService)
+		server.SetGraph("digraph {}")
+
+		recorder := adminRequest(t, server, jwtService, graphPath, &[]string{"user"}[0])
+
+		assert.Equal(t, http.StatusForbidden, recorder.Code)
+	})
+
+	t.Run("serve graph to admin", func(t *testing.T) {
+		jwtService := setupTestJWT(t)
+		server := newTestAdminServer(t, jwtService)
+		server.SetGraph("digraph {}")
+
+		recorder := adminRequest(t, server, jwtService, graphPath, &[]string{"admin"}[0])
+
+		assert.Equal(t, http.StatusOK, recorder.Code)
+		assert.Equal(t, "text/vnd.graphviz; charset=utf-8", recorder.Header().Get("Content-Type"))
+		assert.Equal(t, "digraph {}", recorder.Body.String())
+	})
+
+	t.Run("return 404 before the graph is set", func(t *testing.T) {
+		jwtService := setupTestJWT(t)
+		server := newTestAdminServer(t, jwtService)
+
+		recorder := adminRequest(t, server, jwtService, graphPath, &[]string{"admin"}[0])
+
+		assert.Equal(t, http.StatusNotFound, recorder.Code)
+	})
+}
@@ -0,0 +1,185 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/pocj8ur4in/boilerplate-go/internal/gen/api"
+	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/audit"
+	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/ldap"
+	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/user"
+)
+
+// tokenTypeBearer is type of issued access tokens.
+const tokenTypeBearer = "Bearer"
+
+// Signup handles POST /auth/signup endpoint.
+func (h *Handler) Signup(writer http.ResponseWriter, request *http.Request) {
+	var body api.SignupJSONRequestBody
+	if err := json.NewDecoder(request.Body).Decode(&body); err != nil {
+		h.sendError(writer, request, http.StatusBadRequest, "invalid request body", nil)
+
+		return
+	}
+
+	// users of the directory log in by it only, a local account of their email would be theirs on first login
+	if h.directory != nil && h.directory.Manages(body.Email) {
+		h.sendError(writer, request, http.StatusForbidden, "email managed by directory", nil)
+
+		return
+	}
+
+	created, err := h.users.Signup(request.Context(), body.Email, body.Password)
+
+	switch {
+	case errors.Is(err, user.ErrInvalidEmail):
+		h.sendError(writer, request, http.StatusBadRequest, "invalid email", nil)
+
+		return
+	case errors.Is(err, user.ErrInvalidPassword):
+		h.sendError(writer, request, http.StatusBadRequest, "invalid password", nil)
+
+		return
+	case errors.Is(err, user.ErrEmailTaken):
+		h.sendError(writer, request, http.StatusConflict, "email already taken", nil)
+
+		return
+	case err != nil:
+		h.sendError(writer, request, http.StatusInternalServerError, "failed to sign up", err)
+
+		return
+	}
+
+	h.logAudit(request, audit.ActionSignup, created.ID, nil)
+
+	h.sendTokens(writer, request, http.StatusCreated, created)
+}
+
+// Login handles POST /auth/login endpoint.
+func (h *Handler) Login(writer http.ResponseWriter, request *http.Request) {
+	var body api.LoginJSONRequestBody
+	if err := json.NewDecoder(request.Body).Decode(&body); err != nil {
+		h.sendError(writer, request, http.StatusBadRequest, "invalid request body", nil)
+
+		return
+	}
+
+	found, err := h.login(request.Context(), body.Email, body.Password)
+
+	switch {
+	case errors.Is(err, user.ErrInvalidCredentials):
+		h.logAudit(request, audit.ActionLoginFailed, "", map[string]string{"email": body.Email})
+		h.sendError(writer, request, http.StatusUnauthorized, "invalid email or password", nil)
+
+		return
+	case err != nil:
+		h.sendError(writer, request, http.StatusInternalServerError, "failed to log in", err)
+
+		return
+	}
+
+	h.logAudit(request, audit.ActionLogin, found.ID, nil)
+
+	h.sendTokens(writer, request, http.StatusOK, found)
+}
+
+// login verifies the credentials against the directory if it is enabled, falling back to local passwords for
+// users of other domains than those of the directory if they are enabled, or against local passwords otherwise.
+func (h *Handler) login(ctx context.Context, email, password string) (*user.User, error) {
+	if h.directory == nil || !h.directory.Enabled() {
+		return h.users.Login(ctx, email, password)
+	}
+
+	found, err := h.directory.Login(ctx, email, password)
+	local := h.directory.LocalPasswords() && !h.directory.Manages(email)
+
+	switch {
+	case err == nil:
+		return found, nil
+	case !local && errors.Is(err, ldap.ErrInvalidCredentials):
+		return nil, user.ErrInvalidCredentials
+	case !local:
+		return nil, err
+	case !errors.Is(err, ldap.ErrInvalidCredentials):
+		// local users can still log in while the directory is unavailable
+		h.logger.Ctx(ctx).Error().Err(err).Msg("failed to verify login against directory")
+	}
+
+	return h.users.Login(ctx, email, password)
+}
+
+// RefreshToken handles POST /auth/refresh endpoint.
+func (h *Handler) RefreshToken(writer http.ResponseWriter, request *http.Request) {
+	var body api.RefreshTokenJSONRequestBody
+	if err := json.NewDecoder(request.Body).Decode(&body); err != nil || body.RefreshToken == "" {
+		h.sendError(writer, request, http.StatusBadRequest, "invalid request body", nil)
+
+		return
+	}
+
+	accessToken, err := h.jwt.RefreshAccessToken(body.RefreshToken)
+	if err != nil {
+		// the actor is unknown since claims of an invalid token can be forged
+		h.logAudit(request, audit.ActionTokenRefreshFailed, "", nil)
+		h.sendError(writer, request, http.StatusUnauthorized, "invalid refresh token", nil)
+
+		return
+	}
+
+	// the refresh token is validated by the refresh
+	if claims, err := h.jwt.ExtractClaims(body.RefreshToken); err == nil {
+		h.logAudit(request, audit.ActionTokenRefresh, claims.UserID, nil)
+	}
+
+	h.sendResponse(writer, request, http.StatusOK, api.AuthTokenResponse{
+		AccessToken:  *accessToken,
+		RefreshToken: body.RefreshToken,
+		TokenType:    tokenTypeBearer,
+		ExpiresIn:    int64(h.jwt.AccessTokenTTL().Seconds()),
+	})
+}
+
+// sendTokens sends access and refresh tokens issued for the user.
+func (h *Handler) sendTokens(writer http.ResponseWriter, request *http.Request, code int, issued *user.User) {
+	accessToken, err := h.jwt.GenerateAccessToken(issued.ID, issued.Email, issued.Role)
+	if err != nil {
+		h.sendError(writer, request, http.StatusInternalServerError, "failed to issue access token", err)
+
+		return
+	}
+
+	refreshToken, err := h.jwt.GenerateRefreshToken(issued.ID, issued.Email, issued.Role)
+	if err != nil {
+		h.sendError(writer, request, http.StatusInternalServerError, "failed to issue refresh token", err)
+
+		return
+	}
+
+	h.sendResponse(writer, request, code, api.AuthTokenResponse{
+		AccessToken:  *accessToken,
+		RefreshToken: *refreshToken,
+		TokenType:    tokenTypeBearer,
+		ExpiresIn:    int64(h.jwt.AccessTokenTTL().Seconds()),
+		User: &api.AuthUser{
+			Id:        issued.ID,
+			Email:     issued.Email,
+			Role:      issued.Role,
+			CreatedAt: issued.CreatedAt,
+		},
+	})
+}
+
+// logAudit logs the authentication event of the request by the actor, it does nothing without an audit logger.
+func (h *Handler) logAudit(request *http.Request, action, actorID string, attributes map[string]string) {
+	if h.auditor == nil {
+		return
+	}
+
+	event := audit.NewEvent(request, action)
+	event.ActorID = actorID
+	event.Attributes = attributes
+
+	h.auditor.Log(request.Context(), event)
+}
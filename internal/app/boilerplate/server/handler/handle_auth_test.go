@@ -0,0 +1,397 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+
+	"github.com/pocj8ur4in/boilerplate-go/internal/gen/api"
+	"github.com/pocj8ur4in/boilerplate-go/internal/gen/db"
+	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/audit"
+	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/ldap"
+	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/logger"
+	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/user"
+)
+
+// mockUserQuerier is a mock querier storing users in memory.
+type mockUserQuerier struct {
+	db.Querier
+
+	mu    sync.Mutex
+	users []*db.User
+	err   error
+}
+
+func (m *mockUserQuerier) CreateUser(_ context.Context, arg *db.CreateUserParams) (*db.User, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.err != nil {
+		return nil, m.err
+	}
+
+	for _, row := range m.users {
+		if row.Email == arg.Email {
+			return nil, &pgconn.PgError{Code: "23505"}
+		}
+	}
+
+	row := &db.User{
+		ID:           arg.ID,
+		Email:        arg.Email,
+		PasswordHash: arg.PasswordHash,
+		Role:         arg.Role,
+		CreatedAt:    pgtype.Timestamptz{Time: time.Now(), Valid: true},
+		UpdatedAt:    pgtype.Timestamptz{Time: time.Now(), Valid: true},
+	}
+	m.users = append(m.users, row)
+
+	return row, nil
+}
+
+func (m *mockUserQuerier) GetUserByEmail(_ context.Context, email string) (*db.User, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.err != nil {
+		return nil, m.err
+	}
+
+	for _, row := range m.users {
+		if row.Email == email {
+			return row, nil
+		}
+	}
+
+	return nil, pgx.ErrNoRows
+}
+
+// mockAuditLogger is a mock audit logger keeping logged events in memory.
+type mockAuditLogger struct {
+	mu     sync.Mutex
+	events []*audit.Event
+}
+
+func (m *mockAuditLogger) Log(_ context.Context, event *audit.Event) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.events = append(m.events, event)
+}
+
+// actions returns actions of the logged events in order.
+func (m *mockAuditLogger) actions() []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	actions := make([]string, 0, len(m.events))
+	for _, event := range m.events {
+		actions = append(actions, event.Action)
+	}
+
+	return actions
+}
+
+// setupTestAuthHandler creates a handler with users stored on the querier.
+func setupTestAuthHandler(t *testing.T, querier db.Querier) *Handler {
+	t.Helper()
+
+	handler := setupTestHandler(t)
+
+	users, err := user.NewWithQuerier(&user.Config{PasswordCost: &[]int{bcrypt.MinCost}[0]}, querier)
+	require.NoError(t, err)
+
+	handler.users = users
+
+	return handler
+}
+
+// authRequest sends the request body to the auth handler and returns the recorder.
+func authRequest(handlerFunc http.HandlerFunc, path, body string) *httptest.ResponseRecorder {
+	request := httptest.NewRequest(http.MethodPost, path, strings.NewReader(body))
+	recorder := httptest.NewRecorder()
+
+	handlerFunc(recorder, request)
+
+	return recorder
+}
+
+// decodeTokens decodes the token response of the recorder.
+func decodeTokens(t *testing.T, recorder *httptest.ResponseRecorder) *api.AuthTokenResponse {
+	t.Helper()
+
+	var response api.AuthTokenResponse
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &response))
+
+	return &response
+}
+
+func TestSignup(t *testing.T) {
+	t.Parallel()
+
+	t.Run("create user and issue tokens", func(t *testing.T) {
+		t.Parallel()
+
+		handler := setupTestAuthHandler(t, &mockUserQuerier{})
+
+		recorder := authRequest(handler.Signup, "/auth/signup", `{"email":"alice@example.com","password":"correct horse"}`)
+		require.Equal(t, http.StatusCreated, recorder.Code)
+
+		response := decodeTokens(t, recorder)
+		assert.Equal(t, "Bearer", response.TokenType)
+		assert.Equal(t, int64(handler.jwt.AccessTokenTTL().Seconds()), response.ExpiresIn)
+		require.NotNil(t, response.User)
+		assert.Equal(t, "alice@example.com", response.User.Email)
+		assert.Equal(t, "user", response.User.Role)
+
+		claims, err := handler.jwt.ValidateToken(response.AccessToken)
+		require.NoError(t, err)
+		assert.Equal(t, response.User.Id, claims.UserID)
+	})
+
+	t.Run("reject email of directory domains", func(t *testing.T) {
+		t.Parallel()
+
+		querier := &mockUserQuerier{}
+		handler := setupTestAuthHandler(t, querier)
+		handler.directory = setupUnavailableDirectory(t, true)
+
+		recorder := authRequest(handler.Signup, "/auth/signup", `{"email":" Alice@Example.com","password":"correct horse"}`)
+		assert.Equal(t, http.StatusForbidden, recorder.Code)
+		assert.Empty(t, querier.users)
+
+		assert.Equal(t, http.StatusCreated, authRequest(handler.Signup, "/auth/signup",
+			`{"email":"alice@example.org","password":"correct horse"}`).Code)
+	})
+
+	t.Run("reject taken email", func(t *testing.T) {
+		t.Parallel()
+
+		handler := setupTestAuthHandler(t, &mockUserQuerier{})
+
+		body := `{"email":"alice@example.com","password":"correct horse"}`
+		require.Equal(t, http.StatusCreated, authRequest(handler.Signup, "/auth/signup", body).Code)
+		assert.Equal(t, http.StatusConflict, authRequest(handler.Signup, "/auth/signup", body).Code)
+	})
+
+	t.Run("reject invalid requests", func(t *testing.T) {
+		t.Parallel()
+
+		handler := setupTestAuthHandler(t, &mockUserQuerier{})
+
+		for _, body := range []string{
+			`{invalid`,
+			`{"email":"alice","password":"correct horse"}`,
+			`{"email":"alice@example.com","password":"short"}`,
+		} {
+			assert.Equal(t, http.StatusBadRequest, authRequest(handler.Signup, "/auth/signup", body).Code, body)
+		}
+	})
+
+	t.Run("return internal server error on database errors", func(t *testing.T) {
+		t.Parallel()
+
+		handler := setupTestAuthHandler(t, &mockUserQuerier{err: errConnectionRefused})
+
+		recorder := authRequest(handler.Signup, "/auth/signup", `{"email":"alice@example.com","password":"correct horse"}`)
+		assert.Equal(t, http.StatusInternalServerError, recorder.Code)
+	})
+}
+
+func TestLogin(t *testing.T) {
+	t.Parallel()
+
+	t.Run("issue tokens of matching credentials", func(t *testing.T) {
+		t.Parallel()
+
+		handler := setupTestAuthHandler(t, &mockUserQuerier{})
+
+		body := `{"email":"alice@example.com","password":"correct horse"}`
+		require.Equal(t, http.StatusCreated, authRequest(handler.Signup, "/auth/signup", body).Code)
+
+		recorder := authRequest(handler.Login, "/auth/login", body)
+		require.Equal(t, http.StatusOK, recorder.Code)
+
+		response := decodeTokens(t, recorder)
+		require.NotNil(t, response.User)
+		assert.Equal(t, "alice@example.com", response.User.Email)
+
+		_, err := handler.jwt.ValidateToken(response.RefreshToken)
+		require.NoError(t, err)
+	})
+
+	t.Run("reject wrong credentials", func(t *testing.T) {
+		t.Parallel()
+
+		handler := setupTestAuthHandler(t, &mockUserQuerier{})
+
+		require.Equal(t, http.StatusCreated, authRequest(handler.Signup, "/auth/signup",
+			`{"email":"alice@example.com","password":"correct horse"}`).Code)
+
+		assert.Equal(t, http.StatusUnauthorized, authRequest(handler.Login, "/auth/login",
+			`{"email":"alice@example.com","password":"wrong horse"}`).Code)
+		assert.Equal(t, http.StatusUnauthorized, authRequest(handler.Login, "/auth/login",
+			`{"email":"bob@example.com","password":"correct horse"}`).Code)
+	})
+
+	t.Run("reject invalid request body", func(t *testing.T) {
+		t.Parallel()
+
+		handler := setupTestAuthHandler(t, &mockUserQuerier{})
+
+		assert.Equal(t, http.StatusBadRequest, authRequest(handler.Login, "/auth/login", `{invalid`).Code)
+	})
+
+	t.Run("fall back to local passwords while directory is unavailable", func(t *testing.T) {
+		t.Parallel()
+
+		handler := setupTestAuthHandler(t, &mockUserQuerier{})
+		handler.directory = setupUnavailableDirectory(t, true)
+
+		body := `{"email":"alice@example.org","password":"correct horse"}`
+		require.Equal(t, http.StatusCreated, authRequest(handler.Signup, "/auth/signup", body).Code)
+
+		assert.Equal(t, http.StatusOK, authRequest(handler.Login, "/auth/login", body).Code)
+	})
+
+	t.Run("not verify local passwords when disabled", func(t *testing.T) {
+		t.Parallel()
+
+		handler := setupTestAuthHandler(t, &mockUserQuerier{})
+		handler.directory = setupUnavailableDirectory(t, false)
+
+		body := `{"email":"alice@example.org","password":"correct horse"}`
+		require.Equal(t, http.StatusCreated, authRequest(handler.Signup, "/auth/signup", body).Code)
+
+		assert.Equal(t, http.StatusInternalServerError, authRequest(handler.Login, "/auth/login", body).Code)
+		assert.Equal(t, http.StatusUnauthorized, authRequest(handler.Login, "/auth/login",
+			`{"email":"alice@example.org","password":""}`).Code)
+	})
+
+	t.Run("not verify local passwords of directory domains", func(t *testing.T) {
+		t.Parallel()
+
+		handler := setupTestAuthHandler(t, &mockUserQuerier{})
+
+		// signed up before the directory was enabled
+		body := `{"email":"alice@example.com","password":"correct horse"}`
+		require.Equal(t, http.StatusCreated, authRequest(handler.Signup, "/auth/signup", body).Code)
+
+		handler.directory = setupUnavailableDirectory(t, true)
+
+		assert.Equal(t, http.StatusInternalServerError, authRequest(handler.Login, "/auth/login", body).Code)
+		assert.Equal(t, http.StatusUnauthorized, authRequest(handler.Login, "/auth/login",
+			`{"email":"Alice@Example.com","password":""}`).Code)
+	})
+}
+
+// setupUnavailableDirectory creates an enabled directory of a server refusing connections, with local passwords
+// enabled or disabled.
+func setupUnavailableDirectory(t *testing.T, localPasswords bool) *ldap.Directory {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	require.NoError(t, listener.Close())
+
+	log, err := logger.New(&logger.Config{})
+	require.NoError(t, err)
+
+	directory, err := ldap.New(&ldap.Config{
+		Enabled:        &[]bool{true}[0],
+		URL:            &[]string{"ldap://" + listener.Addr().String()}[0],
+		BaseDN:         &[]string{"dc=example,dc=com"}[0],
+		Domains:        []string{"example.com"},
+		LocalPasswords: &localPasswords,
+		Timeout:        &[]time.Duration{time.Second}[0],
+	}, nil, log)
+	require.NoError(t, err)
+
+	return directory
+}
+
+func TestRefreshToken(t *testing.T) {
+	t.Parallel()
+
+	t.Run("issue access token of refresh token", func(t *testing.T) {
+		t.Parallel()
+
+		handler := setupTestAuthHandler(t, &mockUserQuerier{})
+
+		signup := decodeTokens(t, authRequest(handler.Signup, "/auth/signup",
+			`{"email":"alice@example.com","password":"correct horse"}`))
+
+		recorder := authRequest(handler.RefreshToken, "/auth/refresh", `{"refresh_token":"`+signup.RefreshToken+`"}`)
+		require.Equal(t, http.StatusOK, recorder.Code)
+
+		response := decodeTokens(t, recorder)
+		assert.Equal(t, signup.RefreshToken, response.RefreshToken)
+		assert.Nil(t, response.User)
+
+		claims, err := handler.jwt.ValidateToken(response.AccessToken)
+		require.NoError(t, err)
+		assert.Equal(t, signup.User.Id, claims.UserID)
+	})
+
+	t.Run("reject invalid refresh tokens", func(t *testing.T) {
+		t.Parallel()
+
+		handler := setupTestAuthHandler(t, &mockUserQuerier{})
+
+		assert.Equal(t, http.StatusUnauthorized, authRequest(handler.RefreshToken, "/auth/refresh",
+			`{"refresh_token":"invalid"}`).Code)
+		assert.Equal(t, http.StatusBadRequest, authRequest(handler.RefreshToken, "/auth/refresh", `{}`).Code)
+	})
+}
+
+func TestAuthAudit(t *testing.T) {
+	t.Parallel()
+
+	t.Run("log authentication events with actors", func(t *testing.T) {
+		t.Parallel()
+
+		auditor := &mockAuditLogger{}
+		handler := setupTestAuthHandler(t, &mockUserQuerier{})
+		handler.auditor = auditor
+
+		body := `{"email":"alice@example.com","password":"correct horse"}`
+		signup := decodeTokens(t, authRequest(handler.Signup, "/auth/signup", body))
+
+		require.Equal(t, http.StatusOK, authRequest(handler.Login, "/auth/login", body).Code)
+		require.Equal(t, http.StatusUnauthorized, authRequest(handler.Login, "/auth/login",
+			`{"email":"alice@example.com","password":"wrong horse"}`).Code)
+		require.Equal(t, http.StatusOK, authRequest(handler.RefreshToken, "/auth/refresh",
+			`{"refresh_token":"`+signup.RefreshToken+`"}`).Code)
+		require.Equal(t, http.StatusUnauthorized, authRequest(handler.RefreshToken, "/auth/refresh",
+			`{"refresh_token":"invalid"}`).Code)
+
+		assert.Equal(t, []string{
+			audit.ActionSignup,
+			audit.ActionLogin,
+			audit.ActionLoginFailed,
+			audit.ActionTokenRefresh,
+			audit.ActionTokenRefreshFailed,
+		}, auditor.actions())
+
+		assert.Equal(t, signup.User.Id, auditor.events[0].ActorID)
+		assert.Equal(t, signup.User.Id, auditor.events[1].ActorID)
+		assert.Empty(t, auditor.events[2].ActorID)
+		assert.Equal(t, "alice@example.com", auditor.events[2].Attributes["email"])
+		assert.Equal(t, signup.User.Id, auditor.events[3].ActorID)
+		assert.Empty(t, auditor.events[4].ActorID)
+		assert.Equal(t, "/auth/refresh", auditor.events[4].Resource)
+	})
+}
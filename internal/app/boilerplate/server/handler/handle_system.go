@@ -6,52 +6,146 @@ import (
 	"time"
 
 	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 
 	"github.com/pocj8ur4in/boilerplate-go/internal/gen/api"
+	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/health"
+	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/version"
 )
 
 const (
 	// healthCheckTimeout is the timeout for health check operations.
 	healthCheckTimeout = 5 * time.Second
+
+	// tracerName is the name of the tracer used for handler spans.
+	tracerName = "github.com/pocj8ur4in/boilerplate-go/internal/app/boilerplate/server/handler"
 )
 
-// StatusCheck handles GET /status endpoint.
-func (h *Handler) StatusCheck(writer http.ResponseWriter, _ *http.Request) {
-	h.sendResponse(writer, http.StatusOK, map[string]interface{}{})
+// StatusCheck handles GET /status endpoint, it responds with build and runtime information of the service.
+func (h *Handler) StatusCheck(writer http.ResponseWriter, request *http.Request) {
+	info, process := version.Get(), version.ReadRuntime()
+
+	// set response
+	resp := api.SystemStatusResponse{
+		Name:          info.Name,
+		Version:       info.Version,
+		Commit:        info.Commit,
+		BuildTime:     info.BuildTime,
+		GoVersion:     info.GoVersion,
+		UptimeSeconds: int64(process.Uptime.Seconds()),
+		Goroutines:    process.Goroutines,
+		Memory: api.SystemStatusResponseMemory{
+			AllocBytes:      int64(process.Memory.Alloc),       //nolint:gosec // memory size fits in int64
+			TotalAllocBytes: int64(process.Memory.TotalAlloc),  //nolint:gosec // memory size fits in int64
+			SysBytes:        int64(process.Memory.Sys),         //nolint:gosec // memory size fits in int64
+			HeapObjects:     int64(process.Memory.HeapObjects), //nolint:gosec // object count fits in int64
+			NumGc:           int64(process.Memory.NumGC),
+		},
+	}
+
+	h.sendResponse(writer, request, http.StatusOK, resp)
 }
 
 // HealthCheck handles GET /health endpoint.
-func (h *Handler) HealthCheck(writer http.ResponseWriter, r *http.Request) {
-	ctx, cancel := context.WithTimeout(r.Context(), healthCheckTimeout)
-	defer cancel()
+func (h *Handler) HealthCheck(writer http.ResponseWriter, request *http.Request) {
+	result := h.health.get(request.Context())
 
 	// set response
 	resp := api.SystemHealthCheckResponse{
-		Timestamp: time.Now(),
-		Services: api.SystemHealthCheckResponseServices{
-			Database: true,
-			Redis:    true,
-		},
+		Timestamp: result.checkedAt,
+		Services:  result.services,
+		Cached:    result.cached,
+		AgeMs:     time.Since(result.checkedAt).Milliseconds(),
+	}
+
+	h.sendResponse(writer, request, http.StatusOK, resp)
+}
+
+// LivenessCheck handles GET /live endpoint, it passes as long as the process serves requests.
+func (h *Handler) LivenessCheck(writer http.ResponseWriter, request *http.Request) {
+	h.sendResponse(writer, request, http.StatusOK, api.SystemLivenessCheckResponse{Status: health.StatusOK})
+}
+
+// ReadinessCheck handles GET /ready endpoint, it responds with 503 unless all registered checks pass.
+func (h *Handler) ReadinessCheck(writer http.ResponseWriter, request *http.Request) {
+	report := &health.Report{Status: health.StatusOK}
+	if h.checks != nil {
+		report = h.checks.Check(request.Context())
+	}
+
+	resp := api.SystemReadinessCheckResponse{
+		Status: report.Status,
+		Checks: make(map[string]api.SystemReadinessCheckResult, len(report.Checks)),
+	}
+
+	for name, result := range report.Checks {
+		check := api.SystemReadinessCheckResult{
+			Status:     result.Status,
+			DurationMs: result.Duration.Milliseconds(),
+		}
+
+		if result.Error != "" {
+			h.logger.Ctx(request.Context()).Warn().Str("check", name).Str("error", result.Error).Msg("readiness check failed")
+
+			check.Error = &result.Error
+		}
+
+		resp.Checks[name] = check
+	}
+
+	code := http.StatusOK
+	if !report.Ready() {
+		code = http.StatusServiceUnavailable
+	}
+
+	h.sendResponse(writer, request, code, resp)
+}
+
+// checkServices checks health of database and redis.
+func (h *Handler) checkServices(ctx context.Context) api.SystemHealthCheckResponseServices {
+	services := api.SystemHealthCheckResponseServices{
+		Database: true,
+		Redis:    true,
 	}
 
 	// check database health
-	if err := h.db.PingContext(ctx); err != nil {
-		h.logger.Error().Err(err).Msg("database health check failed")
+	if err := pingService(ctx, "database", h.db.PingContext); err != nil {
+		h.logger.Ctx(ctx).Error().Err(err).Msg("database health check failed")
 
-		resp.Services.Database = false
+		services.Database = false
 	}
 
 	// check redis health
-	if err := h.redis.Ping(ctx).Err(); err != nil {
-		h.logger.Error().Err(err).Msg("redis health check failed")
+	if err := pingService(ctx, "redis", func(ctx context.Context) error { return h.redis.Ping(ctx).Err() }); err != nil {
+		h.logger.Ctx(ctx).Error().Err(err).Msg("redis health check failed")
 
-		resp.Services.Redis = false
+		services.Redis = false
 	}
 
-	h.sendResponse(writer, http.StatusOK, resp)
+	return services
 }
 
 // HandleMetrics handles GET /metrics endpoint.
 func (h *Handler) HandleMetrics(writer http.ResponseWriter, request *http.Request) {
 	promhttp.Handler().ServeHTTP(writer, request)
 }
+
+// pingService pings a service in a child span of the context.
+func pingService(ctx context.Context, service string, ping func(ctx context.Context) error) error {
+	ctx, span := otel.Tracer(tracerName).Start(ctx, "health.ping", trace.WithAttributes(
+		attribute.String("health.service", service),
+	))
+	defer span.End()
+
+	if err := ping(ctx); err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, service+" ping failed")
+
+		return err
+	}
+
+	return nil
+}
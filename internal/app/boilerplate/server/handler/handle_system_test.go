@@ -1,14 +1,20 @@
 package handler
 
 import (
+	"context"
+	"encoding/json"
 	"net/http"
 	"net/http/httptest"
+	"runtime"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
+	"github.com/pocj8ur4in/boilerplate-go/internal/gen/api"
+	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/health"
 	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/logger"
+	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/version"
 )
 
 func TestStatusCheck(t *testing.T) {
@@ -34,6 +40,17 @@ func TestStatusCheck(t *testing.T) {
 		// verify response
 		assert.Equal(t, http.StatusOK, recorder.Code)
 		assert.Equal(t, "application/json", recorder.Header().Get("Content-Type"))
+
+		var resp api.SystemStatusResponse
+		require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &resp))
+		assert.Equal(t, version.Name, resp.Name)
+		assert.Equal(t, version.Version, resp.Version)
+		assert.NotEmpty(t, resp.Commit)
+		assert.NotEmpty(t, resp.BuildTime)
+		assert.Equal(t, runtime.Version(), resp.GoVersion)
+		assert.GreaterOrEqual(t, resp.UptimeSeconds, int64(0))
+		assert.Positive(t, resp.Goroutines)
+		assert.Positive(t, resp.Memory.SysBytes)
 	})
 
 	t.Run("status check with different request methods", func(t *testing.T) {
@@ -98,6 +115,98 @@ func TestHealthCheck(t *testing.T) {
 	})
 }
 
+func TestLivenessCheck(t *testing.T) {
+	t.Parallel()
+
+	t.Run("liveness check returns ok without dependencies", func(t *testing.T) {
+		t.Parallel()
+
+		log, err := logger.New(&logger.Config{Level: &[]string{"info"}[0]})
+		require.NoError(t, err)
+
+		handler := &Handler{
+			logger: log,
+		}
+
+		recorder := httptest.NewRecorder()
+		handler.LivenessCheck(recorder, httptest.NewRequest(http.MethodGet, "/live", nil))
+
+		assert.Equal(t, http.StatusOK, recorder.Code)
+		assert.JSONEq(t, `{"status":"ok"}`, recorder.Body.String())
+	})
+}
+
+func TestReadinessCheck(t *testing.T) {
+	t.Parallel()
+
+	// readiness returns the readiness response of the handler with the checks.
+	readiness := func(t *testing.T, checks *health.Registry) (int, api.SystemReadinessCheckResponse) {
+		t.Helper()
+
+		log, err := logger.New(&logger.Config{Level: &[]string{"fatal"}[0]})
+		require.NoError(t, err)
+
+		handler := &Handler{
+			logger: log,
+			checks: checks,
+		}
+
+		recorder := httptest.NewRecorder()
+		handler.ReadinessCheck(recorder, httptest.NewRequest(http.MethodGet, "/ready", nil))
+
+		var resp api.SystemReadinessCheckResponse
+		require.NoError(t, json.NewDecoder(recorder.Body).Decode(&resp))
+
+		return recorder.Code, resp
+	}
+
+	t.Run("readiness check returns ok if all checks pass", func(t *testing.T) {
+		t.Parallel()
+
+		checks := health.NewRegistry()
+		require.NoError(t, checks.Register("database", 0, health.CheckerFunc(func(context.Context) error {
+			return nil
+		})))
+
+		code, resp := readiness(t, checks)
+
+		assert.Equal(t, http.StatusOK, code)
+		assert.Equal(t, health.StatusOK, resp.Status)
+		assert.Equal(t, health.StatusOK, resp.Checks["database"].Status)
+		assert.Nil(t, resp.Checks["database"].Error)
+	})
+
+	t.Run("readiness check returns service unavailable with failed check", func(t *testing.T) {
+		t.Parallel()
+
+		checks := health.NewRegistry()
+		require.NoError(t, checks.Register("database", 0, health.CheckerFunc(func(context.Context) error {
+			return nil
+		})))
+		require.NoError(t, checks.Register("redis", 0, health.CheckerFunc(func(context.Context) error {
+			return errConnectionRefused
+		})))
+
+		code, resp := readiness(t, checks)
+
+		assert.Equal(t, http.StatusServiceUnavailable, code)
+		assert.Equal(t, health.StatusFail, resp.Status)
+		assert.Equal(t, health.StatusOK, resp.Checks["database"].Status)
+		assert.Equal(t, health.StatusFail, resp.Checks["redis"].Status)
+		require.NotNil(t, resp.Checks["redis"].Error)
+		assert.Equal(t, "connection refused", *resp.Checks["redis"].Error)
+	})
+
+	t.Run("readiness check returns ok without registry", func(t *testing.T) {
+		t.Parallel()
+
+		code, resp := readiness(t, nil)
+
+		assert.Equal(t, http.StatusOK, code)
+		assert.Empty(t, resp.Checks)
+	})
+}
+
 func TestHandleMetrics(t *testing.T) {
 	t.Parallel()
 
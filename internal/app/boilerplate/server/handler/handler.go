@@ -4,14 +4,29 @@ package handler
 import (
 	"encoding/json"
 	"net/http"
+	"os"
+	"time"
 
 	"go.uber.org/fx"
 
 	"github.com/pocj8ur4in/boilerplate-go/internal/gen/api"
+	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/apierror"
+	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/audit"
 	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/database"
+	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/health"
 	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/jwt"
+	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/ldap"
 	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/logger"
 	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/redis"
+	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/user"
+)
+
+const (
+	// EnvKey is environment variable of the deployment environment.
+	EnvKey = "APP_ENV"
+
+	// EnvProduction is the deployment environment of production.
+	EnvProduction = "production"
 )
 
 // NewModule provides module for handler.
@@ -27,43 +42,119 @@ type Handler struct {
 	db     *database.DB
 	redis  *redis.Redis
 	jwt    *jwt.JWT
+	users  *user.Service
+	health *healthCache
+
+	// checks provides readiness checks, nil if none are registered.
+	checks *health.Registry
+
+	// auditor provides audit logging of authentication events, nil if not provided.
+	auditor audit.Logger
+
+	// directory verifies logins against LDAP, nil if not provided.
+	directory *ldap.Directory
+
+	// verbose is whether server errors include debugging information.
+	verbose bool
+}
+
+// Config represents configuration for handler.
+type Config struct {
+	// HealthCacheTTL is duration in milliseconds a health check result is served as fresh.
+	HealthCacheTTL *int `json:"health_cache_ttl"`
+
+	// HealthMaxStale is duration in milliseconds a health check result is served after the TTL,
+	// while it is refreshed in background.
+	HealthMaxStale *int `json:"health_max_stale"`
+}
+
+// SetDefault sets default values.
+func (c *Config) SetDefault() {
+	if c.HealthCacheTTL == nil {
+		c.HealthCacheTTL = &[]int{1000}[0]
+	}
+
+	if c.HealthMaxStale == nil {
+		c.HealthMaxStale = &[]int{5000}[0]
+	}
 }
 
 // New creates a new handler instance.
 func New(
+	config *Config,
 	log *logger.Logger,
 	dbConn *database.DB,
 	redisConn *redis.Redis,
 	jwt *jwt.JWT,
+	users *user.Service,
+	checks *health.Registry,
+	auditor audit.Logger,
+	directory *ldap.Directory,
 ) api.ServerInterface {
-	return &Handler{
+	if config == nil {
+		config = &Config{}
+	}
+
+	config.SetDefault()
+
+	handler := &Handler{
 		logger: log,
 		db:     dbConn,
 		redis:  redisConn,
 		jwt:    jwt,
+		users:  users,
+		checks: checks,
+
+		auditor:   auditor,
+		directory: directory,
+
+		verbose: !isProduction(),
 	}
+
+	handler.health = newHealthCache(
+		time.Duration(*config.HealthCacheTTL)*time.Millisecond,
+		time.Duration(*config.HealthMaxStale)*time.Millisecond,
+		handler.checkServices,
+	)
+
+	return handler
 }
 
 // sendResponse sends response.
-func (h *Handler) sendResponse(writer http.ResponseWriter, code int, data interface{}) {
+func (h *Handler) sendResponse(writer http.ResponseWriter, request *http.Request, code int, data interface{}) {
 	// set response header
 	writer.Header().Set("Content-Type", "application/json")
 	writer.WriteHeader(code)
 
 	// encode response
 	if err := json.NewEncoder(writer).Encode(data); err != nil {
-		h.logger.Error().Err(err).Msg("failed to encode response")
+		h.logger.Ctx(request.Context()).Error().Err(err).Msg("failed to encode response")
 	}
 }
 
-// sendError sends error response.
-func (h *Handler) sendError(writer http.ResponseWriter, code int, message string) {
-	// set response header
-	writer.Header().Set("Content-Type", "application/json")
-	writer.WriteHeader(code)
+// sendError sends error response. For server errors outside production, the response
+// includes cause chain, failed query and stack trace of the error in the debug field.
+func (h *Handler) sendError(writer http.ResponseWriter, request *http.Request, code int, message string, cause error) {
+	response := &apierror.Response{Error: message}
+
+	if code >= http.StatusInternalServerError && cause != nil {
+		h.logger.Ctx(request.Context()).Error().Err(cause).Int("status", code).Msg(message)
+
+		if h.verbose {
+			response.Debug = apierror.NewDebug(cause)
+		}
+	}
 
 	// encode error response
-	if err := json.NewEncoder(writer).Encode(map[string]string{"error": message}); err != nil {
-		h.logger.Error().Err(err).Msg("failed to encode error response")
+	if err := apierror.Write(writer, code, response); err != nil {
+		h.logger.Ctx(request.Context()).Error().Err(err).Msg("failed to encode error response")
 	}
 }
+
+// isProduction returns whether the server runs in production,
+// treating unset environment as production to not expose debugging information by mistake.
+func isProduction() bool {
+	env, ok := os.LookupEnv(EnvKey)
+
+	return !ok || env == EnvProduction
+}
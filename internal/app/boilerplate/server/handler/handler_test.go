@@ -1,19 +1,27 @@
 package handler
 
 import (
+	"encoding/json"
+	"errors"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"os"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
+	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/apierror"
 	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/database"
 	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/jwt"
 	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/logger"
 	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/redis"
 )
 
+// errConnectionRefused is the test error of a failed connection.
+var errConnectionRefused = errors.New("connection refused")
+
 // setupTestHandler creates a handler for testings.
 func setupTestHandler(t *testing.T) *Handler {
 	t.Helper()
@@ -44,6 +52,8 @@ func setupTestHandler(t *testing.T) *Handler {
 		jwt:    jwtService,
 	}
 
+	handler.health = newHealthCache(0, 0, handler.checkServices)
+
 	return handler
 }
 
@@ -66,7 +76,7 @@ func TestNew(t *testing.T) {
 		// try to connect to test redis
 		redisConn, _ := redis.New(&redis.Config{Addrs: []string{"localhost:36379"}})
 
-		handler := New(log, dbConn, redisConn, jwtService)
+		handler := New(nil, log, dbConn, redisConn, jwtService, nil, nil, nil, nil)
 
 		require.NotNil(t, handler)
 		assert.IsType(t, &Handler{}, handler)
@@ -91,7 +101,8 @@ func TestSendResponse(t *testing.T) {
 		}
 
 		// send response
-		handler.sendResponse(recorder, http.StatusOK, testData)
+		request := httptest.NewRequest(http.MethodGet, "/", nil)
+		handler.sendResponse(recorder, request, http.StatusOK, testData)
 
 		// verify status code
 		assert.Equal(t, http.StatusOK, recorder.Code)
@@ -115,7 +126,8 @@ func TestSendResponse(t *testing.T) {
 			"error": "not found",
 		}
 
-		handler.sendResponse(recorder, http.StatusNotFound, testData)
+		request := httptest.NewRequest(http.MethodGet, "/", nil)
+		handler.sendResponse(recorder, request, http.StatusNotFound, testData)
 
 		assert.Equal(t, http.StatusNotFound, recorder.Code)
 		assert.Equal(t, "application/json", recorder.Header().Get("Content-Type"))
@@ -129,7 +141,8 @@ func TestSendResponse(t *testing.T) {
 
 		recorder := httptest.NewRecorder()
 
-		handler.sendResponse(recorder, http.StatusNoContent, map[string]interface{}{})
+		request := httptest.NewRequest(http.MethodGet, "/", nil)
+		handler.sendResponse(recorder, request, http.StatusNoContent, map[string]interface{}{})
 
 		assert.Equal(t, http.StatusNoContent, recorder.Code)
 		assert.Equal(t, "application/json", recorder.Header().Get("Content-Type"))
@@ -146,7 +159,8 @@ func TestSendError(t *testing.T) {
 
 		recorder := httptest.NewRecorder()
 
-		handler.sendError(recorder, http.StatusBadRequest, "invalid request")
+		request := httptest.NewRequest(http.MethodGet, "/", nil)
+		handler.sendError(recorder, request, http.StatusBadRequest, "invalid request", nil)
 
 		assert.Equal(t, http.StatusBadRequest, recorder.Code)
 		assert.Equal(t, "application/json", recorder.Header().Get("Content-Type"))
@@ -176,7 +190,8 @@ func TestSendError(t *testing.T) {
 				handler := setupTestHandler(t)
 				recorder := httptest.NewRecorder()
 
-				handler.sendError(recorder, testCase.statusCode, testCase.message)
+				request := httptest.NewRequest(http.MethodGet, "/", nil)
+				handler.sendError(recorder, request, testCase.statusCode, testCase.message, nil)
 
 				assert.Equal(t, testCase.statusCode, recorder.Code)
 				assert.Contains(t, recorder.Body.String(), testCase.message)
@@ -185,6 +200,89 @@ func TestSendError(t *testing.T) {
 	})
 }
 
+func TestSendErrorVerbose(t *testing.T) {
+	t.Parallel()
+
+	cause := fmt.Errorf("failed to get setting: %w", &database.QueryError{
+		Query: "-- name: GetSetting :one\nSELECT value FROM settings WHERE key = 'theme' LIMIT 1",
+		Err:   errConnectionRefused,
+	})
+
+	t.Run("include debugging information for server error", func(t *testing.T) {
+		t.Parallel()
+
+		handler := setupTestHandler(t)
+		handler.verbose = true
+
+		recorder := httptest.NewRecorder()
+		request := httptest.NewRequest(http.MethodGet, "/", nil)
+		handler.sendError(recorder, request, http.StatusInternalServerError, "internal error", cause)
+
+		var response apierror.Response
+		require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &response))
+
+		assert.Equal(t, "internal error", response.Error)
+		require.NotNil(t, response.Debug)
+		assert.Equal(t, "failed to get setting: connection refused", response.Debug.Cause)
+		assert.Equal(t, []string{"connection refused"}, response.Debug.Causes)
+		assert.Equal(t, "SELECT value FROM settings WHERE key = '?' LIMIT ?", response.Debug.Query)
+		assert.NotEmpty(t, response.Debug.Stack)
+	})
+
+	t.Run("omit debugging information for client error", func(t *testing.T) {
+		t.Parallel()
+
+		handler := setupTestHandler(t)
+		handler.verbose = true
+
+		recorder := httptest.NewRecorder()
+		request := httptest.NewRequest(http.MethodGet, "/", nil)
+		handler.sendError(recorder, request, http.StatusBadRequest, "invalid request", cause)
+
+		assert.JSONEq(t, `{"error":"invalid request","code":"invalid_request"}`, recorder.Body.String())
+	})
+
+	t.Run("omit debugging information in production", func(t *testing.T) {
+		t.Parallel()
+
+		handler := setupTestHandler(t)
+
+		recorder := httptest.NewRecorder()
+		request := httptest.NewRequest(http.MethodGet, "/", nil)
+		handler.sendError(recorder, request, http.StatusInternalServerError, "internal error", cause)
+
+		assert.JSONEq(t, `{"error":"internal error","code":"internal_error"}`, recorder.Body.String())
+	})
+}
+
+//nolint:paralleltest // sequential execution required to modify environment variables
+func TestIsProduction(t *testing.T) {
+	testCases := []struct {
+		name       string
+		env        string
+		production bool
+	}{
+		{"production environment", EnvProduction, true},
+		{"staging environment", "staging", false},
+		{"development environment", "development", false},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			t.Setenv(EnvKey, testCase.env)
+
+			assert.Equal(t, testCase.production, isProduction())
+		})
+	}
+
+	t.Run("unset environment", func(t *testing.T) {
+		t.Setenv(EnvKey, "")
+		require.NoError(t, os.Unsetenv(EnvKey))
+
+		assert.True(t, isProduction())
+	})
+}
+
 func TestNewModule(t *testing.T) {
 	t.Parallel()
 
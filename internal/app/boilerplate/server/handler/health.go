@@ -0,0 +1,127 @@
+package handler
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/pocj8ur4in/boilerplate-go/internal/gen/api"
+)
+
+// healthResult represents result of health checks.
+type healthResult struct {
+	// services is health of services.
+	services api.SystemHealthCheckResponseServices
+
+	// checkedAt is the time the checks ran.
+	checkedAt time.Time
+
+	// cached is whether the result was served from cache.
+	cached bool
+}
+
+// healthCache caches health check results and coalesces concurrent checks.
+type healthCache struct {
+	// mu guards the fields below.
+	mu sync.Mutex
+
+	// ttl is duration a result is served as fresh.
+	ttl time.Duration
+
+	// maxStale is duration a result is served after ttl while refreshing in background.
+	maxStale time.Duration
+
+	// check runs the health checks.
+	check func(ctx context.Context) api.SystemHealthCheckResponseServices
+
+	// last is the last completed result, nil before the first check.
+	last *healthResult
+
+	// inflight is closed when the running check completes, nil if no check is running.
+	inflight chan struct{}
+}
+
+// newHealthCache creates a new health cache.
+func newHealthCache(
+	ttl time.Duration,
+	maxStale time.Duration,
+	check func(ctx context.Context) api.SystemHealthCheckResponseServices,
+) *healthCache {
+	return &healthCache{
+		ttl:      ttl,
+		maxStale: maxStale,
+		check:    check,
+	}
+}
+
+// get returns a fresh or stale cached result, or waits for a check if there is none.
+func (c *healthCache) get(ctx context.Context) healthResult {
+	c.mu.Lock()
+
+	if c.last != nil {
+		age := time.Since(c.last.checkedAt)
+
+		if age < c.ttl {
+			result := *c.last
+			c.mu.Unlock()
+
+			result.cached = true
+
+			return result
+		}
+
+		// serve stale result while refreshing in background
+		if age < c.ttl+c.maxStale {
+			c.startLocked(ctx)
+
+			result := *c.last
+			c.mu.Unlock()
+
+			result.cached = true
+
+			return result
+		}
+	}
+
+	done := c.startLocked(ctx)
+	c.mu.Unlock()
+
+	<-done
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return *c.last
+}
+
+// startLocked starts a check unless one is running and returns a channel closed on completion,
+// the check is detached from cancellation of ctx but keeps its trace as parent.
+func (c *healthCache) startLocked(ctx context.Context) chan struct{} {
+	if c.inflight != nil {
+		return c.inflight
+	}
+
+	done := make(chan struct{})
+	c.inflight = done
+
+	go func() {
+		ctx, cancel := context.WithTimeout(
+			trace.ContextWithSpanContext(context.Background(), trace.SpanContextFromContext(ctx)),
+			healthCheckTimeout,
+		)
+		defer cancel()
+
+		services := c.check(ctx)
+
+		c.mu.Lock()
+		c.last = &healthResult{services: services, checkedAt: time.Now()}
+		c.inflight = nil
+		c.mu.Unlock()
+
+		close(done)
+	}()
+
+	return done
+}
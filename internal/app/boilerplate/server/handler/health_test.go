@@ -0,0 +1,198 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace/noop"
+
+	"github.com/pocj8ur4in/boilerplate-go/internal/gen/api"
+	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/logger"
+)
+
+// errPingFailed is returned by failing test pings.
+var errPingFailed = errors.New("ping failed")
+
+// countingCheck returns a health check counting its calls.
+func countingCheck(calls *atomic.Int32, delay time.Duration) func(ctx context.Context) api.SystemHealthCheckResponseServices {
+	return func(_ context.Context) api.SystemHealthCheckResponseServices {
+		calls.Add(1)
+		time.Sleep(delay)
+
+		return api.SystemHealthCheckResponseServices{Database: true, Redis: true}
+	}
+}
+
+func TestHealthCache(t *testing.T) {
+	t.Parallel()
+
+	t.Run("serve fresh result from cache", func(t *testing.T) {
+		t.Parallel()
+
+		var calls atomic.Int32
+
+		cache := newHealthCache(time.Minute, 0, countingCheck(&calls, 0))
+
+		first := cache.get(context.Background())
+		second := cache.get(context.Background())
+
+		assert.False(t, first.cached)
+		assert.True(t, second.cached)
+		assert.Equal(t, first.checkedAt, second.checkedAt)
+		assert.Equal(t, int32(1), calls.Load())
+	})
+
+	t.Run("serve stale result while refreshing in background", func(t *testing.T) {
+		t.Parallel()
+
+		var calls atomic.Int32
+
+		cache := newHealthCache(10*time.Millisecond, time.Minute, countingCheck(&calls, 0))
+
+		first := cache.get(context.Background())
+
+		time.Sleep(20 * time.Millisecond)
+
+		stale := cache.get(context.Background())
+		assert.True(t, stale.cached)
+		assert.Equal(t, first.checkedAt, stale.checkedAt)
+
+		assert.Eventually(t, func() bool {
+			return cache.get(context.Background()).checkedAt.After(first.checkedAt)
+		}, time.Second, 5*time.Millisecond)
+		assert.GreaterOrEqual(t, calls.Load(), int32(2))
+	})
+
+	t.Run("check again when result is too stale", func(t *testing.T) {
+		t.Parallel()
+
+		var calls atomic.Int32
+
+		cache := newHealthCache(0, 0, countingCheck(&calls, 0))
+
+		cache.get(context.Background())
+		result := cache.get(context.Background())
+
+		assert.False(t, result.cached)
+		assert.Equal(t, int32(2), calls.Load())
+	})
+
+	t.Run("coalesce concurrent checks", func(t *testing.T) {
+		t.Parallel()
+
+		var calls atomic.Int32
+
+		cache := newHealthCache(time.Minute, 0, countingCheck(&calls, 50*time.Millisecond))
+
+		var wg sync.WaitGroup
+
+		for range 10 {
+			wg.Add(1)
+
+			go func() {
+				defer wg.Done()
+
+				cache.get(context.Background())
+			}()
+		}
+
+		wg.Wait()
+
+		assert.Equal(t, int32(1), calls.Load())
+	})
+}
+
+func TestHealthCheckFreshness(t *testing.T) {
+	t.Parallel()
+
+	t.Run("expose freshness in response", func(t *testing.T) {
+		t.Parallel()
+
+		log, err := logger.New(&logger.Config{Level: &[]string{"info"}[0]})
+		require.NoError(t, err)
+
+		var calls atomic.Int32
+
+		handler := &Handler{
+			logger: log,
+			health: newHealthCache(time.Minute, 0, countingCheck(&calls, 0)),
+		}
+
+		for _, expectedCached := range []bool{false, true} {
+			recorder := httptest.NewRecorder()
+			handler.HealthCheck(recorder, httptest.NewRequest(http.MethodGet, "/health", nil))
+
+			require.Equal(t, http.StatusOK, recorder.Code)
+
+			var resp api.SystemHealthCheckResponse
+			require.NoError(t, json.NewDecoder(recorder.Body).Decode(&resp))
+
+			assert.Equal(t, expectedCached, resp.Cached)
+			assert.GreaterOrEqual(t, resp.AgeMs, int64(0))
+			assert.True(t, resp.Services.Database)
+		}
+	})
+}
+
+//nolint:paralleltest // sequential execution required to replace the global tracer provider
+func TestPingServiceTracing(t *testing.T) {
+	t.Run("record ping as child span with service attribute", func(t *testing.T) {
+		spanRecorder := tracetest.NewSpanRecorder()
+		otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(spanRecorder)))
+		t.Cleanup(func() { otel.SetTracerProvider(noop.NewTracerProvider()) })
+
+		ctx, parent := otel.Tracer("test").Start(context.Background(), "request")
+
+		require.NoError(t, pingService(ctx, "redis", func(context.Context) error { return nil }))
+		require.ErrorIs(t, pingService(ctx, "database", func(context.Context) error { return errPingFailed }), errPingFailed)
+
+		parent.End()
+
+		spans := spanRecorder.Ended()
+		require.Len(t, spans, 3)
+
+		redisSpan, databaseSpan := spans[0], spans[1]
+
+		assert.Equal(t, "health.ping", redisSpan.Name())
+		assert.Equal(t, parent.SpanContext().SpanID(), redisSpan.Parent().SpanID())
+		assert.Contains(t, redisSpan.Attributes(), attribute.String("health.service", "redis"))
+		assert.Equal(t, codes.Unset, redisSpan.Status().Code)
+
+		assert.Contains(t, databaseSpan.Attributes(), attribute.String("health.service", "database"))
+		assert.Equal(t, codes.Error, databaseSpan.Status().Code)
+	})
+
+	t.Run("keep trace of triggering request in background check", func(t *testing.T) {
+		spanRecorder := tracetest.NewSpanRecorder()
+		otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(spanRecorder)))
+		t.Cleanup(func() { otel.SetTracerProvider(noop.NewTracerProvider()) })
+
+		cache := newHealthCache(time.Minute, time.Minute, func(ctx context.Context) api.SystemHealthCheckResponseServices {
+			_ = pingService(ctx, "redis", func(context.Context) error { return nil })
+
+			return api.SystemHealthCheckResponseServices{Database: true, Redis: true}
+		})
+
+		ctx, parent := otel.Tracer("test").Start(context.Background(), "request")
+		cache.get(ctx)
+		parent.End()
+
+		spans := spanRecorder.Ended()
+		require.Len(t, spans, 2)
+		assert.Equal(t, parent.SpanContext().TraceID(), spans[0].SpanContext().TraceID())
+	})
+}
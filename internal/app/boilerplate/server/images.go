@@ -0,0 +1,130 @@
+package server
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/pocj8ur4in/boilerplate-go/internal/app/boilerplate/server/middleware"
+	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/images"
+	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/jwt"
+)
+
+// setupImageRoutes sets up image endpoints, originals are uploaded and deleted by their owner with JWT,
+// and variants are served to signed URLs only, so that sizes cannot be requested without a signature.
+func (s *Server) setupImageRoutes(router *chi.Mux, jwtService *jwt.JWT) {
+	if s.images == nil {
+		return
+	}
+
+	router.Route(*s.images.Config().Path, func(router chi.Router) {
+		router.Get("/{id}", s.handleGetImage)
+
+		router.Group(func(router chi.Router) {
+			router.Use(middleware.RequireBearerAuth)
+			router.Use(middleware.JWTAuth(jwtService, s.logger))
+
+			router.Post("/", s.handleUploadImage)
+			router.Delete("/{id}", s.handleDeleteImage)
+		})
+	})
+}
+
+// handleUploadImage handles POST /images endpoint, the body is the original image.
+func (s *Server) handleUploadImage(writer http.ResponseWriter, request *http.Request) {
+	userID, _ := request.Context().Value(middleware.UserIDKey).(string)
+
+	metadata, err := s.images.Upload(request.Context(), userID, request.Body)
+
+	var maxBytesErr *http.MaxBytesError
+
+	switch {
+	case errors.Is(err, images.ErrTooLarge), errors.As(err, &maxBytesErr):
+		writeError(writer, http.StatusRequestEntityTooLarge, err.Error())
+	case errors.Is(err, images.ErrUnsupportedFormat):
+		writeError(writer, http.StatusUnsupportedMediaType, "image must be jpeg, png or gif")
+	case err != nil:
+		s.logger.Ctx(request.Context()).Error().Err(err).Msg("failed to upload image")
+		writeError(writer, http.StatusInternalServerError, "failed to upload image")
+	default:
+		writeJSON(writer, http.StatusCreated, metadata)
+	}
+}
+
+// handleGetImage handles GET /images/{id} endpoint of signed URLs, resized by the w, h, fit, format and q
+// parameters, which are covered by the signature.
+func (s *Server) handleGetImage(writer http.ResponseWriter, request *http.Request) {
+	grant, err := s.signer.Verify(request.URL)
+	if err != nil {
+		writeError(writer, http.StatusUnauthorized, "invalid or expired signed url")
+
+		return
+	}
+
+	metadata, err := s.images.Metadata(request.Context(), chi.URLParam(request, "id"))
+	if err != nil {
+		s.writeImageError(writer, request, err, "failed to get image")
+
+		return
+	}
+
+	options, err := images.ParseOptions(request.URL.Query(), s.images.Config(), metadata)
+	if err != nil {
+		writeError(writer, http.StatusBadRequest, err.Error())
+
+		return
+	}
+
+	variant, err := s.images.Variant(request.Context(), metadata.ID, options)
+	if err != nil {
+		s.writeImageError(writer, request, err, "failed to process image")
+
+		return
+	}
+
+	// variants are cached until the signed URL expires
+	maxAge := max(0, int(time.Until(grant.ExpiresAt).Seconds()))
+
+	writer.Header().Set("Content-Type", variant.ContentType)
+	writer.Header().Set("Content-Length", strconv.Itoa(len(variant.Data)))
+	writer.Header().Set("Cache-Control", "private, max-age="+strconv.Itoa(maxAge))
+	writer.WriteHeader(http.StatusOK)
+
+	_, _ = writer.Write(variant.Data)
+}
+
+// handleDeleteImage handles DELETE /images/{id} endpoint, images of other users are not found.
+func (s *Server) handleDeleteImage(writer http.ResponseWriter, request *http.Request) {
+	userID, _ := request.Context().Value(middleware.UserIDKey).(string)
+
+	metadata, err := s.images.Metadata(request.Context(), chi.URLParam(request, "id"))
+	if err == nil && metadata.UserID != userID {
+		err = images.ErrNotFound
+	}
+
+	if err == nil {
+		err = s.images.Delete(request.Context(), metadata.ID)
+	}
+
+	if err != nil {
+		s.writeImageError(writer, request, err, "failed to delete image")
+
+		return
+	}
+
+	writer.WriteHeader(http.StatusNoContent)
+}
+
+// writeImageError writes the error of the image pipeline, logging unexpected errors with the message.
+func (s *Server) writeImageError(writer http.ResponseWriter, request *http.Request, err error, message string) {
+	switch {
+	case errors.Is(err, images.ErrNotFound):
+		writeError(writer, http.StatusNotFound, "image not found")
+	default:
+		s.logger.Ctx(request.Context()).Error().Err(err).Msg(message)
+		writeError(writer, http.StatusInternalServerError, message)
+	}
+}
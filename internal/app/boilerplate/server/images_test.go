@@ -0,0 +1,197 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"image"
+	"image/png"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/images"
+	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/jwt"
+	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/logger"
+	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/signedurl"
+)
+
+// newTestImageServer creates a test server with images enabled and signed URLs of /images.
+func newTestImageServer(t *testing.T, jwtService *jwt.JWT) (*Server, *signedurl.Signer) {
+	t.Helper()
+
+	log, err := logger.New(&logger.Config{Level: &[]string{"info"}[0]})
+	require.NoError(t, err)
+
+	signer, err := signedurl.New(&signedurl.Config{
+		Enabled: &[]bool{true}[0],
+		Secret:  &[]string{"test_secret"}[0],
+		Paths:   []string{"/images/"},
+	})
+	require.NoError(t, err)
+
+	redisClient := setupTestRedis(t)
+
+	imagesService, err := images.New(&images.Config{
+		Enabled: &[]bool{true}[0],
+		Storage: &images.StorageConfig{Dir: &[]string{t.TempDir()}[0]},
+	}, redisClient, log)
+	require.NoError(t, err)
+
+	server, err := New(ServerParams{
+		Logger:     log,
+		APIHandler: &mockAPIHandler{},
+		JWT:        jwtService,
+		Redis:      redisClient,
+		Signer:     signer,
+		Images:     imagesService,
+	})
+	require.NoError(t, err)
+
+	return server, signer
+}
+
+// imageRequest sends the request to the server, authenticated as the user if not empty.
+func imageRequest(
+	t *testing.T,
+	server *Server,
+	jwtService *jwt.JWT,
+	req *http.Request,
+	userID string,
+) *httptest.ResponseRecorder {
+	t.Helper()
+
+	if userID != "" {
+		token, err := jwtService.GenerateAccessToken(userID, userID+"@example.com", "user")
+		require.NoError(t, err)
+
+		req.Header.Set("Authorization", "Bearer "+*token)
+	}
+
+	recorder := httptest.NewRecorder()
+	server.Handler().ServeHTTP(recorder, req)
+
+	return recorder
+}
+
+// uploadTestImage uploads a png image of the size as the user.
+func uploadTestImage(t *testing.T, server *Server, jwtService *jwt.JWT, width, height int) *images.Metadata {
+	t.Helper()
+
+	var buf bytes.Buffer
+	require.NoError(t, png.Encode(&buf, image.NewNRGBA(image.Rect(0, 0, width, height))))
+
+	req := httptest.NewRequest(http.MethodPost, "/images", &buf)
+	req.Header.Set("Content-Type", "image/png")
+
+	recorder := imageRequest(t, server, jwtService, req, "user-1")
+	require.Equal(t, http.StatusCreated, recorder.Code, recorder.Body.String())
+
+	var metadata images.Metadata
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &metadata))
+
+	return &metadata
+}
+
+//nolint:paralleltest // sequential execution required to avoid redis key conflicts
+func TestImageRoutes(t *testing.T) {
+	t.Run("require signed urls", func(t *testing.T) {
+		log, err := logger.New(&logger.Config{Level: &[]string{"info"}[0]})
+		require.NoError(t, err)
+
+		imagesService := images.NewWithStorage(&images.Config{Enabled: &[]bool{true}[0]}, nil, nil, log)
+
+		_, err = New(ServerParams{
+			Logger:     log,
+			APIHandler: &mockAPIHandler{},
+			JWT:        setupTestJWT(t),
+			Images:     imagesService,
+		})
+		require.ErrorIs(t, err, ErrImagesRequireSignedURLs)
+	})
+
+	t.Run("require token to upload", func(t *testing.T) {
+		jwtService := setupTestJWT(t)
+		server, _ := newTestImageServer(t, jwtService)
+
+		req := httptest.NewRequest(http.MethodPost, "/images", strings.NewReader("image"))
+		recorder := imageRequest(t, server, jwtService, req, "")
+
+		assert.Equal(t, http.StatusUnauthorized, recorder.Code)
+	})
+
+	t.Run("reject unsupported format", func(t *testing.T) {
+		jwtService := setupTestJWT(t)
+		server, _ := newTestImageServer(t, jwtService)
+
+		req := httptest.NewRequest(http.MethodPost, "/images", strings.NewReader("<svg></svg>"))
+		recorder := imageRequest(t, server, jwtService, req, "user-1")
+
+		assert.Equal(t, http.StatusUnsupportedMediaType, recorder.Code)
+	})
+
+	t.Run("serve variant to signed url", func(t *testing.T) {
+		jwtService := setupTestJWT(t)
+		server, signer := newTestImageServer(t, jwtService)
+		metadata := uploadTestImage(t, server, jwtService, 64, 32)
+
+		target := "/images/" + metadata.ID
+
+		recorder := imageRequest(t, server, jwtService, httptest.NewRequest(http.MethodGet, target+"?w=16", nil), "")
+		assert.Equal(t, http.StatusUnauthorized, recorder.Code)
+
+		signed, err := signer.Sign(target+"?w=16", "user-1", time.Minute)
+		require.NoError(t, err)
+
+		recorder = imageRequest(t, server, jwtService, httptest.NewRequest(http.MethodGet, signed.URL, nil), "")
+		require.Equal(t, http.StatusOK, recorder.Code, recorder.Body.String())
+		assert.Equal(t, "image/png", recorder.Header().Get("Content-Type"))
+		assert.True(t, strings.HasPrefix(recorder.Header().Get("Cache-Control"), "private, max-age="))
+
+		config, err := png.DecodeConfig(recorder.Body)
+		require.NoError(t, err)
+		assert.Equal(t, 16, config.Width)
+		assert.Equal(t, 8, config.Height)
+
+		// options are covered by the signature
+		tampered := strings.Replace(signed.URL, "w=16", "w=1024", 1)
+		recorder = imageRequest(t, server, jwtService, httptest.NewRequest(http.MethodGet, tampered, nil), "")
+		assert.Equal(t, http.StatusUnauthorized, recorder.Code)
+	})
+
+	t.Run("reject invalid options", func(t *testing.T) {
+		jwtService := setupTestJWT(t)
+		server, signer := newTestImageServer(t, jwtService)
+		metadata := uploadTestImage(t, server, jwtService, 8, 8)
+
+		signed, err := signer.Sign("/images/"+metadata.ID+"?w=100000", "user-1", time.Minute)
+		require.NoError(t, err)
+
+		recorder := imageRequest(t, server, jwtService, httptest.NewRequest(http.MethodGet, signed.URL, nil), "")
+		assert.Equal(t, http.StatusBadRequest, recorder.Code)
+	})
+
+	t.Run("delete image of owner only", func(t *testing.T) {
+		jwtService := setupTestJWT(t)
+		server, signer := newTestImageServer(t, jwtService)
+		metadata := uploadTestImage(t, server, jwtService, 8, 8)
+
+		target := "/images/" + metadata.ID
+
+		recorder := imageRequest(t, server, jwtService, httptest.NewRequest(http.MethodDelete, target, nil), "user-2")
+		assert.Equal(t, http.StatusNotFound, recorder.Code)
+
+		recorder = imageRequest(t, server, jwtService, httptest.NewRequest(http.MethodDelete, target, nil), "user-1")
+		assert.Equal(t, http.StatusNoContent, recorder.Code)
+
+		signed, err := signer.Sign(target, "user-1", time.Minute)
+		require.NoError(t, err)
+
+		recorder = imageRequest(t, server, jwtService, httptest.NewRequest(http.MethodGet, signed.URL, nil), "")
+		assert.Equal(t, http.StatusNotFound, recorder.Code)
+	})
+}
@@ -0,0 +1,88 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"time"
+)
+
+// ErrInvalidTCPKeepAlive is returned when enabled TCP keep-alive probes have a non-positive idle time, interval
+// or count.
+var ErrInvalidTCPKeepAlive = errors.New("tcp keep-alive idle, interval and count must be positive")
+
+// TCPKeepAliveConfig represents configuration for TCP keep-alive probes of accepted connections, detecting
+// clients that disappeared without closing their connections and keeping idle connections open through
+// balancers dropping silent flows.
+type TCPKeepAliveConfig struct {
+	// Enabled is whether keep-alive probes are sent.
+	Enabled *bool `json:"enabled"`
+
+	// Idle is time in seconds a connection is idle before the first probe, keep it below the idle timeout of
+	// balancers in front of server.
+	Idle *int `json:"idle"`
+
+	// Interval is time in seconds between unanswered probes.
+	Interval *int `json:"interval"`
+
+	// Count is number of unanswered probes before the connection is closed.
+	Count *int `json:"count"`
+}
+
+// setKeepAliveDefault sets default values for keep-alive of connections on server.
+func (c *Config) setKeepAliveDefault() {
+	if c.DisableKeepAlives == nil {
+		c.DisableKeepAlives = &[]bool{false}[0]
+	}
+
+	if c.TCPKeepAlive == nil {
+		c.TCPKeepAlive = &TCPKeepAliveConfig{}
+	}
+
+	if c.TCPKeepAlive.Enabled == nil {
+		c.TCPKeepAlive.Enabled = &[]bool{true}[0]
+	}
+
+	// defaults of net.ListenConfig
+	if c.TCPKeepAlive.Idle == nil {
+		c.TCPKeepAlive.Idle = &[]int{15}[0]
+	}
+
+	if c.TCPKeepAlive.Interval == nil {
+		c.TCPKeepAlive.Interval = &[]int{15}[0]
+	}
+
+	if c.TCPKeepAlive.Count == nil {
+		c.TCPKeepAlive.Count = &[]int{9}[0]
+	}
+}
+
+// validateTCPKeepAlive validates TCP keep-alive configuration.
+func validateTCPKeepAlive(config *TCPKeepAliveConfig) error {
+	if !*config.Enabled {
+		return nil
+	}
+
+	if *config.Idle <= 0 || *config.Interval <= 0 || *config.Count <= 0 {
+		return fmt.Errorf("%w: idle %d, interval %d, count %d",
+			ErrInvalidTCPKeepAlive, *config.Idle, *config.Interval, *config.Count)
+	}
+
+	return nil
+}
+
+// listenConfig returns the listen configuration applying TCP keep-alive to accepted connections.
+func listenConfig(config *TCPKeepAliveConfig) *net.ListenConfig {
+	if !*config.Enabled {
+		return &net.ListenConfig{KeepAlive: -1}
+	}
+
+	return &net.ListenConfig{
+		KeepAliveConfig: net.KeepAliveConfig{
+			Enable:   true,
+			Idle:     time.Duration(*config.Idle) * time.Second,
+			Interval: time.Duration(*config.Interval) * time.Second,
+			Count:    *config.Count,
+		},
+	}
+}
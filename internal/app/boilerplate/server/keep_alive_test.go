@@ -0,0 +1,101 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSetKeepAliveDefault(t *testing.T) {
+	t.Parallel()
+
+	config := &Config{}
+	config.SetDefault()
+
+	assert.False(t, *config.DisableKeepAlives)
+	assert.True(t, *config.TCPKeepAlive.Enabled)
+	assert.Equal(t, 15, *config.TCPKeepAlive.Idle)
+	assert.Equal(t, 15, *config.TCPKeepAlive.Interval)
+	assert.Equal(t, 9, *config.TCPKeepAlive.Count)
+}
+
+func TestValidateTCPKeepAlive(t *testing.T) {
+	t.Parallel()
+
+	config := &Config{TCPKeepAlive: &TCPKeepAliveConfig{Interval: &[]int{0}[0]}}
+	config.SetDefault()
+
+	require.ErrorIs(t, validateTCPKeepAlive(config.TCPKeepAlive), ErrInvalidTCPKeepAlive)
+
+	// disabled probes are not validated
+	config.TCPKeepAlive.Enabled = &[]bool{false}[0]
+	require.NoError(t, validateTCPKeepAlive(config.TCPKeepAlive))
+}
+
+func TestListenConfig(t *testing.T) {
+	t.Parallel()
+
+	config := &TCPKeepAliveConfig{
+		Enabled:  &[]bool{true}[0],
+		Idle:     &[]int{30}[0],
+		Interval: &[]int{10}[0],
+		Count:    &[]int{3}[0],
+	}
+
+	listen := listenConfig(config)
+	assert.True(t, listen.KeepAliveConfig.Enable)
+	assert.Equal(t, 30*time.Second, listen.KeepAliveConfig.Idle)
+	assert.Equal(t, 10*time.Second, listen.KeepAliveConfig.Interval)
+	assert.Equal(t, 3, listen.KeepAliveConfig.Count)
+
+	listener, err := listen.Listen(t.Context(), "tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	require.NoError(t, listener.Close())
+
+	config.Enabled = &[]bool{false}[0]
+
+	listen = listenConfig(config)
+	assert.False(t, listen.KeepAliveConfig.Enable)
+	assert.Negative(t, listen.KeepAlive)
+}
+
+func TestCreateHTTPServerKeepAlives(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name              string
+		disableKeepAlives bool
+		expectedClose     bool
+	}{
+		{name: "reuse connections by default", disableKeepAlives: false, expectedClose: false},
+		{name: "close connections after each response", disableKeepAlives: true, expectedClose: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			config := &Config{DisableKeepAlives: &test.disableKeepAlives}
+			config.SetDefault()
+
+			handler := http.HandlerFunc(func(writer http.ResponseWriter, _ *http.Request) {
+				writer.WriteHeader(http.StatusNoContent)
+			})
+
+			testServer := httptest.NewUnstartedServer(handler)
+			testServer.Config = (&Server{}).createHTTPServer(config, "127.0.0.1:0", handler)
+			testServer.Start()
+			t.Cleanup(testServer.Close)
+
+			response, err := testServer.Client().Get(testServer.URL) //nolint:noctx // test request
+			require.NoError(t, err)
+			require.NoError(t, response.Body.Close())
+
+			assert.Equal(t, test.expectedClose, response.Close)
+		})
+	}
+}
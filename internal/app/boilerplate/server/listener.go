@@ -0,0 +1,236 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+)
+
+var (
+	// ErrListenerAddrRequired is returned when a listener is configured without an address.
+	ErrListenerAddrRequired = errors.New("listener address is required")
+
+	// ErrListenerTLSIncomplete is returned when a listener is configured with only one of cert and key files.
+	ErrListenerTLSIncomplete = errors.New("listener tls requires both cert_file and key_file")
+
+	// ErrInvalidAddressFamily is returned when the address family is not one of tcp4, tcp6 or dual.
+	ErrInvalidAddressFamily = errors.New("invalid address family")
+
+	// ErrAddressFamilyMismatch is returned when the listener host is not an address of the address family.
+	ErrAddressFamilyMismatch = errors.New("listener address does not match address family")
+)
+
+const (
+	// AddressFamilyDual binds both IPv4 and IPv6 when the host allows it.
+	AddressFamilyDual = "dual"
+
+	// AddressFamilyTCP4 binds IPv4 only.
+	AddressFamilyTCP4 = "tcp4"
+
+	// AddressFamilyTCP6 binds IPv6 only.
+	AddressFamilyTCP6 = "tcp6"
+)
+
+// ListenerConfig represents configuration for an address server listens on.
+type ListenerConfig struct {
+	// Addr is address of the listener (e.g. 0.0.0.0:8080).
+	Addr *string `json:"addr"`
+
+	// CertFile is path to the TLS certificate file, TLS is enabled when set with KeyFile.
+	CertFile *string `json:"cert_file"`
+
+	// KeyFile is path to the TLS private key file, TLS is enabled when set with CertFile.
+	KeyFile *string `json:"key_file"`
+
+	// AddressFamily is address family of the listener (tcp4, tcp6, dual), defaults to the server address family.
+	AddressFamily *string `json:"address_family"`
+}
+
+// listener represents an HTTP server bound to a configured address.
+type listener struct {
+	// server provides HTTP server of the listener.
+	server *http.Server
+
+	// network is network passed to net.Listen (tcp, tcp4, tcp6).
+	network string
+
+	// reloader provides the TLS certificate, nil for plaintext.
+	reloader *certReloader
+}
+
+// setListenersDefault sets default values for listeners on server.
+func (c *Config) setListenersDefault() {
+	if c.AddressFamily == nil {
+		c.AddressFamily = &[]string{AddressFamilyDual}[0]
+	}
+
+	for _, listener := range c.Listeners {
+		if listener.CertFile == nil {
+			listener.CertFile = &[]string{""}[0]
+		}
+
+		if listener.KeyFile == nil {
+			listener.KeyFile = &[]string{""}[0]
+		}
+
+		if listener.AddressFamily == nil {
+			listener.AddressFamily = c.AddressFamily
+		}
+	}
+}
+
+// validateListeners validates listeners configuration.
+func validateListeners(config *Config) error {
+	if *config.Admin.Addr != "" {
+		if err := validateAddressFamily(*config.Admin.Addr, *config.AddressFamily); err != nil {
+			return err
+		}
+	}
+
+	if len(config.Listeners) == 0 {
+		addr := net.JoinHostPort(*config.Host, strconv.Itoa(*config.Port))
+
+		return validateAddressFamily(addr, *config.AddressFamily)
+	}
+
+	for i, listener := range config.Listeners {
+		if listener.Addr == nil || *listener.Addr == "" {
+			return fmt.Errorf("listener %d: %w", i, ErrListenerAddrRequired)
+		}
+
+		if (*listener.CertFile == "") != (*listener.KeyFile == "") {
+			return fmt.Errorf("listener %s: %w", *listener.Addr, ErrListenerTLSIncomplete)
+		}
+
+		if err := validateAddressFamily(*listener.Addr, *listener.AddressFamily); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// validateAddressFamily validates the address family and that a literal IP host belongs to it.
+func validateAddressFamily(addr, family string) error {
+	if _, err := networkOf(family); err != nil {
+		return err
+	}
+
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return fmt.Errorf("listener %s: %w", addr, err)
+	}
+
+	// hostnames are resolved by net.Listen
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return nil
+	}
+
+	isIPv4 := ip.To4() != nil
+	if (family == AddressFamilyTCP4 && !isIPv4) || (family == AddressFamilyTCP6 && isIPv4) {
+		return fmt.Errorf("listener %s (%s): %w", addr, family, ErrAddressFamilyMismatch)
+	}
+
+	return nil
+}
+
+// networkOf returns the network passed to net.Listen for the address family.
+func networkOf(family string) (string, error) {
+	switch family {
+	case AddressFamilyDual:
+		return "tcp", nil
+	case AddressFamilyTCP4, AddressFamilyTCP6:
+		return family, nil
+	default:
+		return "", fmt.Errorf("%w: %s", ErrInvalidAddressFamily, family)
+	}
+}
+
+// createListeners creates HTTP servers for the configured listeners, or a single one on host and port.
+func (s *Server) createListeners(config *Config, handler http.Handler) ([]*listener, error) {
+	if len(config.Listeners) == 0 {
+		addr := net.JoinHostPort(*config.Host, strconv.Itoa(*config.Port))
+		network, _ := networkOf(*config.AddressFamily)
+
+		certFile, keyFile := "", ""
+		if *config.TLS.Enabled {
+			certFile, keyFile = *config.TLS.CertFile, *config.TLS.KeyFile
+		}
+
+		created, err := s.createListener(config, addr, network, certFile, keyFile, handler)
+		if err != nil {
+			return nil, err
+		}
+
+		return []*listener{created}, nil
+	}
+
+	listeners := make([]*listener, 0, len(config.Listeners))
+
+	for _, listenerConfig := range config.Listeners {
+		network, _ := networkOf(*listenerConfig.AddressFamily)
+
+		created, err := s.createListener(
+			config, *listenerConfig.Addr, network, *listenerConfig.CertFile, *listenerConfig.KeyFile, handler,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		listeners = append(listeners, created)
+	}
+
+	return listeners, nil
+}
+
+// createListener creates an HTTP server on the address, serving TLS when cert and key files are set.
+func (s *Server) createListener(
+	config *Config,
+	addr, network, certFile, keyFile string,
+	handler http.Handler,
+) (*listener, error) {
+	created := &listener{
+		server:  s.createHTTPServer(config, addr, handler),
+		network: network,
+	}
+
+	if certFile == "" {
+		return created, nil
+	}
+
+	reloader, err := newCertReloader(certFile, keyFile)
+	if err != nil {
+		return nil, err
+	}
+
+	tlsConfig, err := newTLSConfig(config.TLS, reloader)
+	if err != nil {
+		return nil, err
+	}
+
+	created.server.TLSConfig = tlsConfig
+	created.reloader = reloader
+
+	return created, nil
+}
+
+// serve serves HTTP requests on the bound network listener until the server is closed.
+func (l *listener) serve(netListener net.Listener) error {
+	var err error
+
+	if l.reloader != nil {
+		// certificates are served by the tls config
+		err = l.server.ServeTLS(netListener, "", "")
+	} else {
+		err = l.server.Serve(netListener)
+	}
+
+	if err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return fmt.Errorf("failed to serve on %s: %w", l.server.Addr, err)
+	}
+
+	return nil
+}
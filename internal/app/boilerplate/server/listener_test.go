@@ -0,0 +1,349 @@
+package server
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/logger"
+)
+
+// freeAddr returns a free local address to listen on.
+func freeAddr(t *testing.T) string {
+	t.Helper()
+
+	netListener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	addr := netListener.Addr().String()
+	require.NoError(t, netListener.Close())
+
+	return addr
+}
+
+// writeTestCert writes a self-signed certificate and key for localhost and returns their paths.
+func writeTestCert(t *testing.T) (string, string) {
+	t.Helper()
+
+	dir := t.TempDir()
+	certFile := filepath.Join(dir, "cert.pem")
+	keyFile := filepath.Join(dir, "key.pem")
+
+	writeTestCertFiles(t, certFile, keyFile, 1)
+
+	return certFile, keyFile
+}
+
+// writeTestCertFiles writes a self-signed certificate with the serial number and its key to the paths.
+func writeTestCertFiles(t *testing.T, certFile, keyFile string, serial int64) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(serial),
+		Subject:               pkix.Name{CommonName: "localhost"},
+		IPAddresses:           []net.IP{net.ParseIP("127.0.0.1")},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+
+	certDER, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+
+	require.NoError(t, os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: certDER}), 0o600))
+	require.NoError(t, os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0o600))
+}
+
+// waitForStatus polls the status endpoint until it responds or the timeout elapses.
+func waitForStatus(t *testing.T, client *http.Client, url string) {
+	t.Helper()
+
+	require.Eventually(t, func() bool {
+		resp, err := client.Get(url) //nolint:noctx // test polling
+		if err != nil {
+			return false
+		}
+
+		_ = resp.Body.Close()
+
+		return resp.StatusCode == http.StatusOK
+	}, 2*time.Second, 10*time.Millisecond)
+}
+
+func TestValidateListeners(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name      string
+		listeners []*ListenerConfig
+		expected  error
+	}{
+		{
+			name:      "accept plaintext listener",
+			listeners: []*ListenerConfig{{Addr: &[]string{":8080"}[0]}},
+		},
+		{
+			name: "accept tls listener",
+			listeners: []*ListenerConfig{{
+				Addr:     &[]string{":8443"}[0],
+				CertFile: &[]string{"cert.pem"}[0],
+				KeyFile:  &[]string{"key.pem"}[0],
+			}},
+		},
+		{
+			name:      "reject listener without address",
+			listeners: []*ListenerConfig{{}},
+			expected:  ErrListenerAddrRequired,
+		},
+		{
+			name: "reject listener with cert file only",
+			listeners: []*ListenerConfig{{
+				Addr:     &[]string{":8443"}[0],
+				CertFile: &[]string{"cert.pem"}[0],
+			}},
+			expected: ErrListenerTLSIncomplete,
+		},
+		{
+			name: "accept ipv6 listener with tcp6 address family",
+			listeners: []*ListenerConfig{{
+				Addr:          &[]string{"[::1]:8080"}[0],
+				AddressFamily: &[]string{AddressFamilyTCP6}[0],
+			}},
+		},
+		{
+			name: "accept hostname listener with tcp4 address family",
+			listeners: []*ListenerConfig{{
+				Addr:          &[]string{"localhost:8080"}[0],
+				AddressFamily: &[]string{AddressFamilyTCP4}[0],
+			}},
+		},
+		{
+			name: "reject ipv6 listener with tcp4 address family",
+			listeners: []*ListenerConfig{{
+				Addr:          &[]string{"[::]:8080"}[0],
+				AddressFamily: &[]string{AddressFamilyTCP4}[0],
+			}},
+			expected: ErrAddressFamilyMismatch,
+		},
+		{
+			name: "reject ipv4 listener with tcp6 address family",
+			listeners: []*ListenerConfig{{
+				Addr:          &[]string{"0.0.0.0:8080"}[0],
+				AddressFamily: &[]string{AddressFamilyTCP6}[0],
+			}},
+			expected: ErrAddressFamilyMismatch,
+		},
+		{
+			name: "reject unknown address family",
+			listeners: []*ListenerConfig{{
+				Addr:          &[]string{":8080"}[0],
+				AddressFamily: &[]string{"udp"}[0],
+			}},
+			expected: ErrInvalidAddressFamily,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			config := &Config{Listeners: test.listeners}
+			config.SetDefault()
+
+			err := validateListeners(config)
+			if test.expected == nil {
+				require.NoError(t, err)
+			} else {
+				require.ErrorIs(t, err, test.expected)
+			}
+		})
+	}
+}
+
+func TestListeners(t *testing.T) {
+	t.Parallel()
+
+	t.Run("serve same handler on plaintext and tls listeners", func(t *testing.T) {
+		t.Parallel()
+
+		log, err := logger.New(&logger.Config{Level: &[]string{"info"}[0]})
+		require.NoError(t, err)
+
+		certFile, keyFile := writeTestCert(t)
+		plainAddr := freeAddr(t)
+		tlsAddr := freeAddr(t)
+
+		config := &Config{
+			Listeners: []*ListenerConfig{
+				{Addr: &plainAddr},
+				{Addr: &tlsAddr, CertFile: &certFile, KeyFile: &keyFile},
+			},
+		}
+
+		server, err := New(ServerParams{
+			Config:     config,
+			Logger:     log,
+			APIHandler: &mockAPIHandler{},
+			JWT:        setupTestJWT(t),
+			Redis:      setupTestRedis(t),
+		})
+		require.NoError(t, err)
+		assert.Equal(t, plainAddr, server.Addr())
+
+		done := make(chan error, 1)
+
+		go func() {
+			done <- server.Run()
+		}()
+
+		waitForStatus(t, http.DefaultClient, "http://"+plainAddr+"/status")
+
+		tlsClient := &http.Client{Transport: &http.Transport{
+			TLSClientConfig: &tls.Config{InsecureSkipVerify: true}, //nolint:gosec // self-signed test certificate
+		}}
+		waitForStatus(t, tlsClient, "https://"+tlsAddr+"/status")
+
+		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+		defer cancel()
+
+		// shutdown stops all listeners
+		require.NoError(t, server.Shutdown(ctx))
+		require.NoError(t, <-done)
+	})
+
+	t.Run("fail before serving when an address is in use", func(t *testing.T) {
+		t.Parallel()
+
+		log, err := logger.New(&logger.Config{Level: &[]string{"info"}[0]})
+		require.NoError(t, err)
+
+		occupied, err := net.Listen("tcp", "127.0.0.1:0")
+		require.NoError(t, err)
+
+		defer func() { _ = occupied.Close() }()
+
+		freeAddress := freeAddr(t)
+		occupiedAddress := occupied.Addr().String()
+
+		config := &Config{
+			Listeners: []*ListenerConfig{{Addr: &freeAddress}, {Addr: &occupiedAddress}},
+		}
+
+		server, err := New(ServerParams{
+			Config:     config,
+			Logger:     log,
+			APIHandler: &mockAPIHandler{},
+			JWT:        setupTestJWT(t),
+			Redis:      setupTestRedis(t),
+		})
+		require.NoError(t, err)
+
+		require.Error(t, server.Run())
+
+		// the free address is released again
+		netListener, err := net.Listen("tcp", freeAddress)
+		require.NoError(t, err)
+		require.NoError(t, netListener.Close())
+	})
+
+	t.Run("bind with tcp4 address family", func(t *testing.T) {
+		t.Parallel()
+
+		log, err := logger.New(&logger.Config{Level: &[]string{"info"}[0]})
+		require.NoError(t, err)
+
+		addr := freeAddr(t)
+
+		config := &Config{
+			AddressFamily: &[]string{AddressFamilyTCP4}[0],
+			Listeners:     []*ListenerConfig{{Addr: &addr}},
+		}
+
+		server, err := New(ServerParams{
+			Config:     config,
+			Logger:     log,
+			APIHandler: &mockAPIHandler{},
+			JWT:        setupTestJWT(t),
+			Redis:      setupTestRedis(t),
+		})
+		require.NoError(t, err)
+		assert.Equal(t, "tcp4", server.listeners[0].network)
+
+		done := make(chan error, 1)
+
+		go func() {
+			done <- server.Run()
+		}()
+
+		waitForStatus(t, http.DefaultClient, "http://"+addr+"/status")
+
+		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+		defer cancel()
+
+		require.NoError(t, server.Shutdown(ctx))
+		require.NoError(t, <-done)
+	})
+
+	t.Run("return error for host outside address family", func(t *testing.T) {
+		t.Parallel()
+
+		log, err := logger.New(&logger.Config{Level: &[]string{"info"}[0]})
+		require.NoError(t, err)
+
+		config := &Config{
+			Host:          &[]string{"127.0.0.1"}[0],
+			AddressFamily: &[]string{AddressFamilyTCP6}[0],
+		}
+
+		_, err = New(ServerParams{
+			Config:     config,
+			Logger:     log,
+			APIHandler: &mockAPIHandler{},
+			JWT:        setupTestJWT(t),
+			Redis:      setupTestRedis(t),
+		})
+		require.ErrorIs(t, err, ErrAddressFamilyMismatch)
+	})
+
+	t.Run("return error for invalid listener", func(t *testing.T) {
+		t.Parallel()
+
+		log, err := logger.New(&logger.Config{Level: &[]string{"info"}[0]})
+		require.NoError(t, err)
+
+		config := &Config{Listeners: []*ListenerConfig{{}}}
+
+		_, err = New(ServerParams{
+			Config:     config,
+			Logger:     log,
+			APIHandler: &mockAPIHandler{},
+			JWT:        setupTestJWT(t),
+			Redis:      setupTestRedis(t),
+		})
+		require.ErrorIs(t, err, ErrListenerAddrRequired)
+	})
+}
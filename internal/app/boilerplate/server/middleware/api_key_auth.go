@@ -0,0 +1,130 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	gojwt "github.com/golang-jwt/jwt/v5"
+
+	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/apierror"
+	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/apikey"
+	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/jwt"
+	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/logger"
+	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/redis"
+)
+
+// ErrMissingAPIKey returned when the request is not authenticated by an API key.
+var ErrMissingAPIKey = errors.New("missing api key")
+
+// APIKeyIDKey is the key for ID of the authenticating API key in context.
+const APIKeyIDKey ContextKey = "api_key_id"
+
+// APIKeyAuthConfig represents configuration of API key authentication.
+type APIKeyAuthConfig struct {
+	// Header is the request header carrying the API key.
+	Header string
+
+	// Requests is the default maximum number of requests per API key, zero to not limit keys without override.
+	Requests int
+
+	// Window is the default time window for rate limiting per API key.
+	Window time.Duration
+
+	// Algorithm is the algorithm counting requests per API key.
+	Algorithm RateLimitAlgorithm
+
+	// Headers is which rate limit headers are set on responses.
+	Headers RateLimitHeaders
+}
+
+// APIKeyAuth is a middleware that authenticates requests carrying an API key as an alternative to JWT,
+// storing the same user information in context so JWTAuth is skipped and authorization applies.
+// Each key is rate limited by its stored override or the default limit.
+func APIKeyAuth(
+	config *APIKeyAuthConfig,
+	store *apikey.Store,
+	redis *redis.Redis,
+	fallback *RateLimitFallback,
+	logger *logger.Logger,
+) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+			value := request.Header.Get(config.Header)
+
+			// if request doesn't carry api key, leave it to JWT
+			if value == "" {
+				next.ServeHTTP(writer, request)
+
+				return
+			}
+
+			key, err := store.Authenticate(request.Context(), value)
+
+			switch {
+			case errors.Is(err, apikey.ErrInvalidKey):
+				logger.Ctx(request.Context()).Debug().Str("path", request.URL.Path).Msg("invalid api key")
+				writeUnauthorized(writer)
+
+				return
+			case err != nil:
+				logger.Ctx(request.Context()).Error().Err(err).Msg("api key authentication failed")
+
+				_ = apierror.Write(writer, http.StatusServiceUnavailable, &apierror.Response{
+					Error: "Service Unavailable",
+					Code:  apierror.CodeUnavailable,
+				})
+
+				return
+			}
+
+			// add user information to context as JWTAuth does
+			claims := &jwt.Claims{
+				UserID:   key.UserID,
+				Role:     key.Role,
+				TenantID: key.TenantID,
+				Scopes:   key.Scopes,
+				RegisteredClaims: gojwt.RegisteredClaims{
+					Subject: key.UserID,
+					ID:      key.ID,
+				},
+			}
+
+			ctx := context.WithValue(request.Context(), UserIDKey, claims.UserID)
+			ctx = context.WithValue(ctx, UserRoleKey, claims.Role)
+			ctx = context.WithValue(ctx, ClaimsKey, claims)
+			ctx = context.WithValue(ctx, APIKeyIDKey, key.ID)
+			logUserID(ctx, claims.UserID)
+			setTenantID(ctx, claims.TenantID)
+			request = request.WithContext(ctx)
+
+			requests, window := config.Requests, config.Window
+			if key.RateLimit != nil {
+				requests, window = key.RateLimit.Requests, key.RateLimit.Window
+			}
+
+			if requests > 0 {
+				rateLimitKey, err := generateRateLimitKey(RateLimitTypeAPIKey, request)
+				if err != nil {
+					logger.Ctx(request.Context()).Error().Err(err).Msg("rate limit key generation failed")
+				} else if !enforceRateLimit(
+					writer, request, redis, fallback, logger, RateLimitTypeAPIKey, config.Headers, *rateLimitKey, requests, window, config.Algorithm,
+				) {
+					return
+				}
+			}
+
+			next.ServeHTTP(writer, request)
+		})
+	}
+}
+
+// writeUnauthorized writes the unauthorized error response.
+func writeUnauthorized(writer http.ResponseWriter) {
+	// error is ignored since nothing else can be written to the client
+	_ = apierror.Write(writer, http.StatusUnauthorized, &apierror.Response{
+		Error: "Unauthorized",
+		Code:  apierror.CodeUnauthorized,
+	})
+}
@@ -0,0 +1,239 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pocj8ur4in/boilerplate-go/internal/gen/api"
+	"github.com/pocj8ur4in/boilerplate-go/internal/gen/db"
+	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/apikey"
+	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/jwt"
+)
+
+const testAPIKeyHeader = "X-API-Key"
+
+// errAPIKeyQueryFailed is the test error of a failed API key query.
+var errAPIKeyQueryFailed = errors.New("query failed")
+
+// mockAPIKeyQuerier is a mock querier storing API keys in memory.
+type mockAPIKeyQuerier struct {
+	db.Querier
+
+	keys []*db.ApiKey
+	err  error
+}
+
+func (m *mockAPIKeyQuerier) CreateAPIKey(_ context.Context, arg *db.CreateAPIKeyParams) (*db.ApiKey, error) {
+	key := &db.ApiKey{
+		ID:        arg.ID,
+		UserID:    arg.UserID,
+		TenantID:  arg.TenantID,
+		Name:      arg.Name,
+		KeyHash:   arg.KeyHash,
+		Role:      arg.Role,
+		Scopes:    arg.Scopes,
+		ExpiresAt: arg.ExpiresAt,
+	}
+	m.keys = append(m.keys, key)
+
+	return key, nil
+}
+
+func (m *mockAPIKeyQuerier) GetAPIKeyByHash(_ context.Context, keyHash string) (*db.ApiKey, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+
+	for _, key := range m.keys {
+		if key.KeyHash == keyHash {
+			return key, nil
+		}
+	}
+
+	return nil, pgx.ErrNoRows
+}
+
+func (m *mockAPIKeyQuerier) SetAPIKeyRateLimit(_ context.Context, arg *db.SetAPIKeyRateLimitParams) (*db.ApiKey, error) {
+	for _, key := range m.keys {
+		if key.ID == arg.ID {
+			key.RateLimitRequests = arg.RateLimitRequests
+			key.RateLimitWindowSeconds = arg.RateLimitWindowSeconds
+
+			return key, nil
+		}
+	}
+
+	return nil, pgx.ErrNoRows
+}
+
+// setupTestAPIKey creates an API key store with a key of the scopes and returns the store, key and its ID.
+func setupTestAPIKey(t *testing.T, querier *mockAPIKeyQuerier, scopes ...string) (*apikey.Store, string, string) {
+	t.Helper()
+
+	store := apikey.NewWithQuerier(nil, querier, setupTestRedis(t))
+
+	created, key, err := store.Create(context.Background(), &apikey.CreateParams{
+		UserID:   "user123",
+		TenantID: "acme",
+		Name:     "ci",
+		Role:     "user",
+		Scopes:   scopes,
+	})
+	require.NoError(t, err)
+
+	return store, key, created.ID
+}
+
+//nolint:paralleltest // sequential execution required to avoid redis key conflicts
+func TestAPIKeyAuth(t *testing.T) {
+	config := &APIKeyAuthConfig{Header: testAPIKeyHeader, Headers: RateLimitHeadersBoth}
+
+	t.Run("pass through request without api key", func(t *testing.T) {
+		store, _, _ := setupTestAPIKey(t, &mockAPIKeyQuerier{})
+
+		recorder := httptest.NewRecorder()
+		APIKeyAuth(config, store, setupTestRedis(t), nil, setupTestLogger(t))(testHandler(http.StatusOK, "success")).ServeHTTP(
+			recorder, httptest.NewRequest(http.MethodGet, "/test", nil),
+		)
+
+		assert.Equal(t, http.StatusOK, recorder.Code)
+	})
+
+	t.Run("reject invalid api key", func(t *testing.T) {
+		store, _, _ := setupTestAPIKey(t, &mockAPIKeyQuerier{})
+
+		request := httptest.NewRequest(http.MethodGet, "/test", nil)
+		request.Header.Set(testAPIKeyHeader, apikey.Prefix+"unknown")
+
+		recorder := httptest.NewRecorder()
+		APIKeyAuth(config, store, setupTestRedis(t), nil, setupTestLogger(t))(testHandler(http.StatusOK, "success")).ServeHTTP(
+			recorder, request,
+		)
+
+		assert.Equal(t, http.StatusUnauthorized, recorder.Code)
+		assert.JSONEq(t, `{"error":"Unauthorized","code":"unauthorized"}`, recorder.Body.String())
+	})
+
+	t.Run("reject request when store fails", func(t *testing.T) {
+		querier := &mockAPIKeyQuerier{}
+		store, key, _ := setupTestAPIKey(t, querier)
+		querier.err = errAPIKeyQueryFailed
+
+		request := httptest.NewRequest(http.MethodGet, "/test", nil)
+		request.Header.Set(testAPIKeyHeader, key)
+
+		recorder := httptest.NewRecorder()
+		APIKeyAuth(config, store, setupTestRedis(t), nil, setupTestLogger(t))(testHandler(http.StatusOK, "success")).ServeHTTP(
+			recorder, request,
+		)
+
+		assert.Equal(t, http.StatusServiceUnavailable, recorder.Code)
+	})
+
+	t.Run("authenticate api key before JWT and authorize its scopes", func(t *testing.T) {
+		store, key, keyID := setupTestAPIKey(t, &mockAPIKeyQuerier{}, "settings:read")
+		log := setupTestLogger(t)
+
+		var (
+			claims   *jwt.Claims
+			apiKey   string
+			tenantID string
+		)
+
+		handler := Tenant("X-Tenant-ID", newTestResolver(t))(APIKeyAuth(config, store, setupTestRedis(t), nil, log)(
+			JWTAuth(setupTestJWT(t), log)(
+				RequireScopes()(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+					claims, _ = request.Context().Value(ClaimsKey).(*jwt.Claims)
+					apiKey, _ = request.Context().Value(APIKeyIDKey).(string)
+					tenantID = TenantIDFromContext(request.Context())
+
+					writer.WriteHeader(http.StatusOK)
+				})),
+			),
+		))
+
+		for _, test := range []struct {
+			scopes []string
+			status int
+		}{
+			{scopes: []string{"settings:read"}, status: http.StatusOK},
+			{scopes: []string{"settings:write"}, status: http.StatusForbidden},
+		} {
+			request := httptest.NewRequest(http.MethodGet, "/test", nil)
+			request.Header.Set(testAPIKeyHeader, key)
+			request = request.WithContext(context.WithValue(request.Context(), api.BearerAuthScopes, test.scopes))
+
+			recorder := httptest.NewRecorder()
+			handler.ServeHTTP(recorder, request)
+
+			assert.Equal(t, test.status, recorder.Code)
+		}
+
+		require.NotNil(t, claims)
+		assert.Equal(t, "user123", claims.UserID)
+		assert.Equal(t, "user", claims.Role)
+		assert.Equal(t, keyID, claims.ID)
+		assert.Equal(t, keyID, apiKey)
+		assert.Equal(t, "acme", claims.TenantID)
+		assert.Equal(t, "acme", tenantID)
+	})
+
+	t.Run("limit requests per api key with override", func(t *testing.T) {
+		store, key, keyID := setupTestAPIKey(t, &mockAPIKeyQuerier{})
+
+		_, err := store.SetRateLimit(context.Background(), keyID, &apikey.RateLimit{Requests: 2, Window: time.Minute})
+		require.NoError(t, err)
+
+		limited := &APIKeyAuthConfig{
+			Header:   testAPIKeyHeader,
+			Requests: 100,
+			Window:   time.Minute,
+			Headers:  RateLimitHeadersBoth,
+		}
+		handler := APIKeyAuth(limited, store, setupTestRedis(t), nil, setupTestLogger(t))(testHandler(http.StatusOK, "success"))
+
+		codes := make([]int, 0, 3)
+
+		for range 3 {
+			request := httptest.NewRequest(http.MethodGet, "/test", nil)
+			request.Header.Set(testAPIKeyHeader, key)
+
+			recorder := httptest.NewRecorder()
+			handler.ServeHTTP(recorder, request)
+
+			codes = append(codes, recorder.Code)
+		}
+
+		assert.Equal(t, []int{http.StatusOK, http.StatusOK, http.StatusTooManyRequests}, codes)
+	})
+}
+
+func TestGenerateAPIKeyRateLimitKey(t *testing.T) {
+	t.Parallel()
+
+	t.Run("generate api key rate limit key", func(t *testing.T) {
+		t.Parallel()
+
+		request := httptest.NewRequest(http.MethodGet, "/test", nil)
+		request = request.WithContext(context.WithValue(request.Context(), APIKeyIDKey, "key123"))
+
+		key, err := generateRateLimitKey(RateLimitTypeAPIKey, request)
+		require.NoError(t, err)
+		assert.Equal(t, "rate_limit:api_key:key123", *key)
+	})
+
+	t.Run("return error without api key", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := generateRateLimitKey(RateLimitTypeAPIKey, httptest.NewRequest(http.MethodGet, "/test", nil))
+		require.ErrorIs(t, err, ErrMissingAPIKey)
+	})
+}
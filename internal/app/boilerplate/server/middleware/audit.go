@@ -0,0 +1,83 @@
+package middleware
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/go-chi/chi/v5/middleware"
+
+	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/audit"
+)
+
+// Audit is a middleware that carries the audit logger in context, so that authorization middlewares log denials
+// and AuditActions logs actions, it must run after RealIP that resolves the client IP.
+func Audit(auditor audit.Logger) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+			next.ServeHTTP(writer, request.WithContext(audit.NewContext(request.Context(), auditor)))
+		})
+	}
+}
+
+// AuditActions is a middleware that logs requests changing state as the action once they are served, with
+// the method and status, it must run after JWTAuth that stores the user ID in context. Safe methods are not
+// logged.
+func AuditActions(action string) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+			auditor := audit.FromContext(request.Context(), nil)
+			if auditor == nil || isSafeMethod(request.Method) {
+				next.ServeHTTP(writer, request)
+
+				return
+			}
+
+			wrappedWriter := middleware.NewWrapResponseWriter(writer, request.ProtoMajor)
+
+			next.ServeHTTP(wrappedWriter, request)
+
+			// handlers writing no response respond with 200
+			status := wrappedWriter.Status()
+			if status == 0 {
+				status = http.StatusOK
+			}
+
+			event := audit.NewEvent(request, action)
+			event.ActorID, _ = request.Context().Value(UserIDKey).(string)
+			event.Attributes = map[string]string{
+				"method": request.Method,
+				"status": strconv.Itoa(status),
+			}
+
+			auditor.Log(request.Context(), event)
+		})
+	}
+}
+
+// auditDenial logs the request denied for the details, it does nothing if the context carries no audit logger.
+func auditDenial(request *http.Request, details *AuthorizationDetails) {
+	auditor := audit.FromContext(request.Context(), nil)
+	if auditor == nil {
+		return
+	}
+
+	event := audit.NewEvent(request, audit.ActionPermissionDenied)
+	event.ActorID, _ = request.Context().Value(UserIDKey).(string)
+	event.Attributes = map[string]string{"method": request.Method}
+
+	if len(details.Roles) > 0 {
+		event.Attributes["roles"] = strings.Join(details.Roles, ",")
+	}
+
+	if len(details.Scopes) > 0 {
+		event.Attributes["scopes"] = strings.Join(details.Scopes, ",")
+	}
+
+	if details.Action != "" {
+		event.Attributes["action"] = details.Action
+		event.Resource = details.Resource
+	}
+
+	auditor.Log(request.Context(), event)
+}
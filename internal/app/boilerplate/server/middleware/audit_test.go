@@ -0,0 +1,136 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/audit"
+)
+
+// mockAuditLogger is a mock audit logger keeping logged events in memory.
+type mockAuditLogger struct {
+	mu     sync.Mutex
+	events []*audit.Event
+}
+
+func (m *mockAuditLogger) Log(_ context.Context, event *audit.Event) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.events = append(m.events, event)
+}
+
+func (m *mockAuditLogger) logged() []*audit.Event {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.events
+}
+
+func TestAudit(t *testing.T) {
+	t.Parallel()
+
+	t.Run("log denial of caller", func(t *testing.T) {
+		t.Parallel()
+
+		auditor := &mockAuditLogger{}
+		handler := Audit(auditor)(RequireRole("admin")(testHandler(http.StatusOK, "success")))
+
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, authorizedRequest(t, "user"))
+
+		assert.Equal(t, http.StatusForbidden, recorder.Code)
+
+		events := auditor.logged()
+		require.Len(t, events, 1)
+		assert.Equal(t, audit.ActionPermissionDenied, events[0].Action)
+		assert.Equal(t, "user123", events[0].ActorID)
+		assert.Equal(t, "/test", events[0].Resource)
+		assert.Equal(t, map[string]string{"method": http.MethodGet, "roles": "admin"}, events[0].Attributes)
+	})
+
+	t.Run("log missing scopes of caller", func(t *testing.T) {
+		t.Parallel()
+
+		auditor := &mockAuditLogger{}
+		handler := Audit(auditor)(RequireScopes("users:write")(testHandler(http.StatusOK, "success")))
+
+		handler.ServeHTTP(httptest.NewRecorder(), authorizedRequest(t, "user", "users:read"))
+
+		events := auditor.logged()
+		require.Len(t, events, 1)
+		assert.Equal(t, "users:write", events[0].Attributes["scopes"])
+	})
+
+	t.Run("log nothing for allowed caller", func(t *testing.T) {
+		t.Parallel()
+
+		auditor := &mockAuditLogger{}
+		handler := Audit(auditor)(RequireRole("admin")(testHandler(http.StatusOK, "success")))
+
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, authorizedRequest(t, "admin"))
+
+		assert.Equal(t, http.StatusOK, recorder.Code)
+		assert.Empty(t, auditor.logged())
+	})
+
+	t.Run("deny without audit logger", func(t *testing.T) {
+		t.Parallel()
+
+		recorder := httptest.NewRecorder()
+		RequireRole("admin")(testHandler(http.StatusOK, "success")).ServeHTTP(recorder, authorizedRequest(t, "user"))
+
+		assert.Equal(t, http.StatusForbidden, recorder.Code)
+	})
+}
+
+func TestAuditActions(t *testing.T) {
+	t.Parallel()
+
+	t.Run("log requests changing state with status", func(t *testing.T) {
+		t.Parallel()
+
+		auditor := &mockAuditLogger{}
+		handler := Audit(auditor)(AuditActions(audit.ActionAdmin)(testHandler(http.StatusAccepted, "accepted")))
+
+		request := authorizedRequest(t, "admin")
+		request.Method = http.MethodPost
+
+		handler.ServeHTTP(httptest.NewRecorder(), request)
+
+		events := auditor.logged()
+		require.Len(t, events, 1)
+		assert.Equal(t, audit.ActionAdmin, events[0].Action)
+		assert.Equal(t, "user123", events[0].ActorID)
+		assert.Equal(t, map[string]string{"method": http.MethodPost, "status": "202"}, events[0].Attributes)
+	})
+
+	t.Run("skip safe methods", func(t *testing.T) {
+		t.Parallel()
+
+		auditor := &mockAuditLogger{}
+		handler := Audit(auditor)(AuditActions(audit.ActionAdmin)(testHandler(http.StatusOK, "success")))
+
+		handler.ServeHTTP(httptest.NewRecorder(), authorizedRequest(t, "admin"))
+
+		assert.Empty(t, auditor.logged())
+	})
+
+	t.Run("serve request without audit logger", func(t *testing.T) {
+		t.Parallel()
+
+		request := httptest.NewRequest(http.MethodDelete, "/test", nil)
+		recorder := httptest.NewRecorder()
+
+		AuditActions(audit.ActionAdmin)(testHandler(http.StatusOK, "success")).ServeHTTP(recorder, request)
+
+		assert.Equal(t, http.StatusOK, recorder.Code)
+	})
+}
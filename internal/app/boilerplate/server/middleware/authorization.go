@@ -0,0 +1,210 @@
+package middleware
+
+import (
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/pocj8ur4in/boilerplate-go/internal/gen/api"
+	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/apierror"
+	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/authz"
+	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/jwt"
+	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/logger"
+	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/netutil"
+)
+
+// AuthorizationDetails represents details of the forbidden error.
+type AuthorizationDetails struct {
+	// Roles is roles of which the caller needs one.
+	Roles []string `json:"roles,omitempty"`
+
+	// Scopes is scopes the caller lacks.
+	Scopes []string `json:"scopes,omitempty"`
+
+	// Action is action denied by authorization policies.
+	Action string `json:"action,omitempty"`
+
+	// Resource is resource the action is denied on by authorization policies.
+	Resource string `json:"resource,omitempty"`
+}
+
+// RequireRole is a middleware that rejects callers without any of the roles,
+// it must run after JWTAuth that stores the role in context.
+func RequireRole(roles ...string) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+			if role, _ := request.Context().Value(UserRoleKey).(string); !slices.Contains(roles, role) {
+				writeForbidden(writer, request, &AuthorizationDetails{Roles: roles})
+
+				return
+			}
+
+			next.ServeHTTP(writer, request)
+		})
+	}
+}
+
+// Authorize is a middleware that rejects callers whose role on database is none of the roles on the resource,
+// unlike RequireRole a role changed after the token was issued applies on the next request,
+// it must run after JWTAuth that stores the user ID in context.
+func Authorize(
+	authorizer *authz.Authz,
+	logger *logger.Logger,
+	resource string,
+	roles ...string,
+) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+			// decisions are cached for the request so nested checks do not reach redis
+			ctx := authz.WithRequestCache(request.Context())
+
+			var allowed bool
+
+			if userID, _ := ctx.Value(UserIDKey).(string); userID != "" {
+				var err error
+
+				allowed, err = authorizer.Authorize(ctx, userID, resource, roles...)
+				if err != nil {
+					logger.Ctx(ctx).Error().Err(err).Str("resource", resource).Msg("failed to authorize request")
+
+					// error is ignored since nothing else can be written to the client
+					_ = apierror.Write(writer, http.StatusInternalServerError, &apierror.Response{
+						Error: http.StatusText(http.StatusInternalServerError),
+						Code:  apierror.CodeInternal,
+					})
+
+					return
+				}
+			}
+
+			if !allowed {
+				writeForbidden(writer, request, &AuthorizationDetails{Roles: roles})
+
+				return
+			}
+
+			next.ServeHTTP(writer, request.WithContext(ctx))
+		})
+	}
+}
+
+// Policy is a middleware that rejects requests the authorization policies deny the action on the resource for,
+// placeholders of the resource such as users/{id} are replaced with path parameters, so it must be added
+// on the route with router.With, and it must run after JWTAuth that stores the user ID in context.
+func Policy(
+	authorizer *authz.Authz,
+	logger *logger.Logger,
+	action, resource string,
+) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+			input := PolicyInput(request, action, resource)
+
+			decision, err := authorizer.Evaluate(request.Context(), input)
+			if err != nil {
+				logger.Ctx(request.Context()).Error().Err(err).Str("resource", input.Resource).
+					Msg("failed to evaluate authorization policies")
+
+				// error is ignored since nothing else can be written to the client
+				_ = apierror.Write(writer, http.StatusInternalServerError, &apierror.Response{
+					Error: http.StatusText(http.StatusInternalServerError),
+					Code:  apierror.CodeInternal,
+				})
+
+				return
+			}
+
+			if !decision.Allowed {
+				writeForbidden(writer, request, &AuthorizationDetails{Action: input.Action, Resource: input.Resource})
+
+				return
+			}
+
+			next.ServeHTTP(writer, request)
+		})
+	}
+}
+
+// PolicyInput returns the input of authorization policies for the action on the resource of the request,
+// handlers evaluate it with authz.Evaluate for rules the middleware can not express.
+func PolicyInput(request *http.Request, action, resource string) *authz.Input {
+	ctx := request.Context()
+	params := make(map[string]string)
+
+	if routeContext := chi.RouteContext(ctx); routeContext != nil {
+		for i, key := range routeContext.URLParams.Keys {
+			params[key] = routeContext.URLParams.Values[i]
+			resource = strings.ReplaceAll(resource, "{"+key+"}", routeContext.URLParams.Values[i])
+		}
+	}
+
+	input := &authz.Input{
+		Action:   action,
+		Resource: resource,
+		Context: map[string]any{
+			"method":    request.Method,
+			"path":      request.URL.Path,
+			"client_ip": netutil.ClientIP(request),
+			"params":    params,
+		},
+	}
+
+	input.Principal.ID, _ = ctx.Value(UserIDKey).(string)
+	input.Principal.Role, _ = ctx.Value(UserRoleKey).(string)
+
+	if claims, ok := ctx.Value(ClaimsKey).(*jwt.Claims); ok {
+		input.Principal.Scopes = claims.Scopes
+	}
+
+	return input
+}
+
+// RequireScopes is a middleware that rejects callers without all of the scopes and the scopes
+// required by OpenAPI spec security requirements, it must run after JWTAuth that stores claims in context.
+func RequireScopes(scopes ...string) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+			// scopes of the operation are set by the generated router
+			specScopes, _ := request.Context().Value(api.BearerAuthScopes).([]string)
+			required := append(slices.Clone(scopes), specScopes...)
+
+			if len(required) == 0 {
+				next.ServeHTTP(writer, request)
+
+				return
+			}
+
+			claims, _ := request.Context().Value(ClaimsKey).(*jwt.Claims)
+
+			var missing []string
+
+			for _, scope := range required {
+				if (claims == nil || !claims.HasScopes(scope)) && !slices.Contains(missing, scope) {
+					missing = append(missing, scope)
+				}
+			}
+
+			if len(missing) > 0 {
+				writeForbidden(writer, request, &AuthorizationDetails{Scopes: missing})
+
+				return
+			}
+
+			next.ServeHTTP(writer, request)
+		})
+	}
+}
+
+// writeForbidden writes the forbidden error response and logs the denial.
+func writeForbidden(writer http.ResponseWriter, request *http.Request, details *AuthorizationDetails) {
+	auditDenial(request, details)
+
+	// error is ignored since nothing else can be written to the client
+	_ = apierror.Write(writer, http.StatusForbidden, &apierror.Response{
+		Error:   "Forbidden",
+		Code:    apierror.CodeForbidden,
+		Details: details,
+	})
+}
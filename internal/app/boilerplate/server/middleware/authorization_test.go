@@ -0,0 +1,322 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/jackc/pgx/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pocj8ur4in/boilerplate-go/internal/gen/api"
+	"github.com/pocj8ur4in/boilerplate-go/internal/gen/db"
+	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/authz"
+	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/logger"
+)
+
+var errRoleQueryFailed = errors.New("query failed")
+
+// mockRoleQuerier is a mock querier serving roles of users from memory.
+type mockRoleQuerier struct {
+	db.Querier
+
+	roles map[string]string
+	err   error
+}
+
+func (m *mockRoleQuerier) GetUserByID(_ context.Context, id string) (*db.User, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+
+	role, ok := m.roles[id]
+	if !ok {
+		return nil, pgx.ErrNoRows
+	}
+
+	return &db.User{ID: id, Role: role}, nil
+}
+
+// authorizedRequest creates a request authenticated by JWTAuth with the role and scopes.
+func authorizedRequest(t *testing.T, role string, scopes ...string) *http.Request {
+	t.Helper()
+
+	jwtService := setupTestJWT(t)
+
+	token, err := jwtService.GenerateAccessToken("user123", "test@example.com", role, scopes...)
+	require.NoError(t, err)
+
+	log, err := logger.New(&logger.Config{Level: &[]string{"fatal"}[0]})
+	require.NoError(t, err)
+
+	request := httptest.NewRequest(http.MethodGet, "/test", nil)
+	request.Header.Set("Authorization", "Bearer "+*token)
+	request = request.WithContext(context.WithValue(request.Context(), api.BearerAuthScopes, []string{}))
+
+	// capture the request with claims stored by JWTAuth
+	var authorized *http.Request
+
+	JWTAuth(jwtService, log)(http.HandlerFunc(func(_ http.ResponseWriter, request *http.Request) {
+		authorized = request
+	})).ServeHTTP(httptest.NewRecorder(), request)
+
+	require.NotNil(t, authorized)
+
+	return authorized
+}
+
+func TestRequireRole(t *testing.T) {
+	t.Parallel()
+
+	t.Run("allow caller with one of the roles", func(t *testing.T) {
+		t.Parallel()
+
+		recorder := httptest.NewRecorder()
+		RequireRole("admin", "operator")(testHandler(http.StatusOK, "success")).ServeHTTP(
+			recorder, authorizedRequest(t, "operator"),
+		)
+
+		assert.Equal(t, http.StatusOK, recorder.Code)
+	})
+
+	t.Run("reject caller without the roles", func(t *testing.T) {
+		t.Parallel()
+
+		recorder := httptest.NewRecorder()
+		RequireRole("admin")(testHandler(http.StatusOK, "success")).ServeHTTP(recorder, authorizedRequest(t, "user"))
+
+		assert.Equal(t, http.StatusForbidden, recorder.Code)
+		assert.Equal(t, "application/json", recorder.Header().Get("Content-Type"))
+		assert.JSONEq(t, `{"error":"Forbidden","code":"forbidden","details":{"roles":["admin"]}}`, recorder.Body.String())
+	})
+
+	t.Run("reject unauthenticated caller", func(t *testing.T) {
+		t.Parallel()
+
+		recorder := httptest.NewRecorder()
+		RequireRole("admin")(testHandler(http.StatusOK, "success")).ServeHTTP(
+			recorder, httptest.NewRequest(http.MethodGet, "/test", nil),
+		)
+
+		assert.Equal(t, http.StatusForbidden, recorder.Code)
+	})
+}
+
+func TestRequireScopes(t *testing.T) {
+	t.Parallel()
+
+	t.Run("allow caller with all of the scopes", func(t *testing.T) {
+		t.Parallel()
+
+		recorder := httptest.NewRecorder()
+		RequireScopes("settings:read", "settings:write")(testHandler(http.StatusOK, "success")).ServeHTTP(
+			recorder, authorizedRequest(t, "user", "settings:read", "settings:write", "profile:read"),
+		)
+
+		assert.Equal(t, http.StatusOK, recorder.Code)
+	})
+
+	t.Run("reject caller lacking scopes with missing scopes", func(t *testing.T) {
+		t.Parallel()
+
+		recorder := httptest.NewRecorder()
+		RequireScopes("settings:read", "settings:write")(testHandler(http.StatusOK, "success")).ServeHTTP(
+			recorder, authorizedRequest(t, "user", "settings:read"),
+		)
+
+		assert.Equal(t, http.StatusForbidden, recorder.Code)
+		assert.JSONEq(t,
+			`{"error":"Forbidden","code":"forbidden","details":{"scopes":["settings:write"]}}`,
+			recorder.Body.String(),
+		)
+	})
+
+	t.Run("honor scopes of openapi security requirement", func(t *testing.T) {
+		t.Parallel()
+
+		request := authorizedRequest(t, "user", "settings:read")
+		request = request.WithContext(context.WithValue(request.Context(), api.BearerAuthScopes, []string{"settings:write"}))
+
+		recorder := httptest.NewRecorder()
+		RequireScopes()(testHandler(http.StatusOK, "success")).ServeHTTP(recorder, request)
+
+		assert.Equal(t, http.StatusForbidden, recorder.Code)
+		assert.Contains(t, recorder.Body.String(), "settings:write")
+	})
+
+	t.Run("allow request without required scopes", func(t *testing.T) {
+		t.Parallel()
+
+		recorder := httptest.NewRecorder()
+		RequireScopes()(testHandler(http.StatusOK, "success")).ServeHTTP(
+			recorder, httptest.NewRequest(http.MethodGet, "/test", nil),
+		)
+
+		assert.Equal(t, http.StatusOK, recorder.Code)
+	})
+
+	t.Run("reject unauthenticated caller when scopes are required", func(t *testing.T) {
+		t.Parallel()
+
+		recorder := httptest.NewRecorder()
+		RequireScopes("settings:read")(testHandler(http.StatusOK, "success")).ServeHTTP(
+			recorder, httptest.NewRequest(http.MethodGet, "/test", nil),
+		)
+
+		assert.Equal(t, http.StatusForbidden, recorder.Code)
+	})
+}
+
+//nolint:paralleltest // sequential execution required to avoid redis key conflicts
+func TestAuthorize(t *testing.T) {
+	// newAuthorize creates the middleware deciding by roles of the querier.
+	newAuthorize := func(t *testing.T, querier *mockRoleQuerier) func(next http.Handler) http.Handler {
+		t.Helper()
+
+		log := setupTestLogger(t)
+
+		authorizer, err := authz.NewWithQuerier(&authz.Config{}, querier, setupTestRedis(t), log)
+		require.NoError(t, err)
+
+		return Authorize(authorizer, log, "admin", "admin")
+	}
+
+	t.Run("allow caller with role on database", func(t *testing.T) {
+		// the token still carries the role the user had when it was issued
+		recorder := httptest.NewRecorder()
+		newAuthorize(t, &mockRoleQuerier{roles: map[string]string{"user123": "admin"}})(
+			testHandler(http.StatusOK, "success"),
+		).ServeHTTP(recorder, authorizedRequest(t, "user"))
+
+		assert.Equal(t, http.StatusOK, recorder.Code)
+	})
+
+	t.Run("reject caller whose role on database changed", func(t *testing.T) {
+		recorder := httptest.NewRecorder()
+		newAuthorize(t, &mockRoleQuerier{roles: map[string]string{"user123": "user"}})(
+			testHandler(http.StatusOK, "success"),
+		).ServeHTTP(recorder, authorizedRequest(t, "admin"))
+
+		assert.Equal(t, http.StatusForbidden, recorder.Code)
+		assert.JSONEq(t, `{"error":"Forbidden","code":"forbidden","details":{"roles":["admin"]}}`, recorder.Body.String())
+	})
+
+	t.Run("reject unauthenticated caller", func(t *testing.T) {
+		recorder := httptest.NewRecorder()
+		newAuthorize(t, &mockRoleQuerier{})(testHandler(http.StatusOK, "success")).ServeHTTP(
+			recorder, httptest.NewRequest(http.MethodGet, "/test", nil),
+		)
+
+		assert.Equal(t, http.StatusForbidden, recorder.Code)
+	})
+
+	t.Run("respond with internal error if role lookup fails", func(t *testing.T) {
+		recorder := httptest.NewRecorder()
+		newAuthorize(t, &mockRoleQuerier{err: errRoleQueryFailed})(testHandler(http.StatusOK, "success")).ServeHTTP(
+			recorder, authorizedRequest(t, "admin"),
+		)
+
+		assert.Equal(t, http.StatusInternalServerError, recorder.Code)
+	})
+}
+
+func TestPolicy(t *testing.T) {
+	t.Parallel()
+
+	// newRouter creates the router reading users by the policies, deciding by roles of the querier.
+	newRouter := func(t *testing.T, querier *mockRoleQuerier) http.Handler {
+		t.Helper()
+
+		log := setupTestLogger(t)
+
+		policies := `@id("admin") permit (principal, action, resource) when { principal.role == "admin" };
+@id("owner") permit (principal, action == Action::"read", resource)
+when { resource.path like "users/*" && principal.id == context.params.id };`
+
+		authorizer, err := authz.NewWithQuerier(&authz.Config{Policies: &policies}, querier, nil, log)
+		require.NoError(t, err)
+
+		router := chi.NewRouter()
+		router.With(Policy(authorizer, log, "read", "users/{id}")).Get("/users/{id}", testHandler(http.StatusOK, "success"))
+
+		return router
+	}
+
+	// request creates the request of the caller with the role reading the user.
+	request := func(t *testing.T, role, id string) *http.Request {
+		t.Helper()
+
+		request := authorizedRequest(t, role)
+		request.URL.Path = "/users/" + id
+
+		return request
+	}
+
+	t.Run("allow owner of resource", func(t *testing.T) {
+		t.Parallel()
+
+		recorder := httptest.NewRecorder()
+		newRouter(t, &mockRoleQuerier{roles: map[string]string{"user123": "user"}}).ServeHTTP(
+			recorder, request(t, "user", "user123"),
+		)
+
+		assert.Equal(t, http.StatusOK, recorder.Code)
+	})
+
+	t.Run("reject caller reading other user", func(t *testing.T) {
+		t.Parallel()
+
+		recorder := httptest.NewRecorder()
+		newRouter(t, &mockRoleQuerier{roles: map[string]string{"user123": "user"}}).ServeHTTP(
+			recorder, request(t, "user", "user456"),
+		)
+
+		assert.Equal(t, http.StatusForbidden, recorder.Code)
+		assert.JSONEq(t, `{"error":"Forbidden","code":"forbidden","details":{"action":"read","resource":"users/user456"}}`,
+			recorder.Body.String())
+	})
+
+	t.Run("allow caller by role on database", func(t *testing.T) {
+		t.Parallel()
+
+		// the token still carries the role the user had when it was issued
+		recorder := httptest.NewRecorder()
+		newRouter(t, &mockRoleQuerier{roles: map[string]string{"user123": "admin"}}).ServeHTTP(
+			recorder, request(t, "user", "user456"),
+		)
+
+		assert.Equal(t, http.StatusOK, recorder.Code)
+	})
+
+	t.Run("respond with internal error if role lookup fails", func(t *testing.T) {
+		t.Parallel()
+
+		recorder := httptest.NewRecorder()
+		newRouter(t, &mockRoleQuerier{err: errRoleQueryFailed}).ServeHTTP(recorder, request(t, "admin", "user123"))
+
+		assert.Equal(t, http.StatusInternalServerError, recorder.Code)
+	})
+}
+
+func TestPolicyInput(t *testing.T) {
+	t.Parallel()
+
+	request := authorizedRequest(t, "user", "users:read")
+	request.RemoteAddr = "192.0.2.1:1234"
+
+	routeContext := chi.NewRouteContext()
+	routeContext.URLParams.Add("id", "user456")
+	request = request.WithContext(context.WithValue(request.Context(), chi.RouteCtxKey, routeContext))
+
+	input := PolicyInput(request, "read", "users/{id}")
+	assert.Equal(t, authz.Principal{ID: "user123", Role: "user", Scopes: []string{"users:read"}}, input.Principal)
+	assert.Equal(t, "read", input.Action)
+	assert.Equal(t, "users/user456", input.Resource)
+	assert.Equal(t, http.MethodGet, input.Context["method"])
+	assert.Equal(t, "192.0.2.1", input.Context["client_ip"])
+	assert.Equal(t, map[string]string{"id": "user456"}, input.Context["params"])
+}
@@ -1,12 +1,355 @@
 package middleware
 
 import (
+	"compress/flate"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
 	"net/http"
+	"slices"
+	"strconv"
+	"strings"
 
-	"github.com/go-chi/chi/v5/middleware"
+	"github.com/andybalholm/brotli"
+	"github.com/klauspost/compress/zstd"
 )
 
-// Compress is a middleware that compresses the response.
-func Compress(level int, format string) func(next http.Handler) http.Handler {
-	return middleware.Compress(level, format)
+var (
+	// ErrUnsupportedCompressionFormat returned when the compression format is not supported.
+	ErrUnsupportedCompressionFormat = errors.New("unsupported compression format")
+
+	// ErrInvalidCompressionLevel returned when the compression level is out of range.
+	ErrInvalidCompressionLevel = errors.New("invalid compression level")
+)
+
+const (
+	// CompressionFormatGzip compresses responses with gzip.
+	CompressionFormatGzip = "gzip"
+
+	// CompressionFormatDeflate compresses responses with deflate.
+	CompressionFormatDeflate = "deflate"
+
+	// CompressionFormatBrotli compresses responses with Brotli.
+	CompressionFormatBrotli = "br"
+
+	// CompressionFormatZstd compresses responses with Zstandard.
+	CompressionFormatZstd = "zstd"
+
+	// zstdWindowSize is window size of Zstandard responses, the largest window browsers decode (RFC 9659).
+	zstdWindowSize = 8 << 20 // 8MB
+
+	// brotliWindowBits is log2 of window size of Brotli responses, bounding memory of encoders at high qualities.
+	brotliWindowBits = 16 // 64KB
+)
+
+// compressionLevels is range of compression levels of each format.
+var compressionLevels = map[string]struct{ min, max int }{
+	CompressionFormatGzip:    {gzip.BestSpeed, gzip.BestCompression},
+	CompressionFormatDeflate: {flate.BestSpeed, flate.BestCompression},
+	CompressionFormatBrotli:  {brotli.BestSpeed, brotli.BestCompression},
+	CompressionFormatZstd:    {1, 22},
+}
+
+// CompressConfig represents configuration of response compression.
+type CompressConfig struct {
+	// Level is compression level of the format, 1-9 for gzip and deflate, 0-11 for br and 1-22 for zstd.
+	Level int
+
+	// Format is compression format (gzip, deflate, br or zstd).
+	Format string
+
+	// MinSize is minimum size of responses in bytes to compress, smaller responses are written as is.
+	MinSize int
+
+	// ContentTypes is content types to compress, all content types if empty. A type ending with /* matches all
+	// its subtypes.
+	ContentTypes []string
+
+	// ExcludeContentTypes is content types not to compress, a type ending with /* matches all its subtypes.
+	ExcludeContentTypes []string
+
+	// ExcludePaths is path prefixes whose responses are not compressed.
+	ExcludePaths []string
+}
+
+// Validate validates the compression config.
+func (c *CompressConfig) Validate() error {
+	levels, ok := compressionLevels[c.Format]
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrUnsupportedCompressionFormat, c.Format)
+	}
+
+	if c.Level < levels.min || c.Level > levels.max {
+		return fmt.Errorf("%w: %d for %s", ErrInvalidCompressionLevel, c.Level, c.Format)
+	}
+
+	return nil
+}
+
+// Compress is a middleware that compresses responses of at least the minimum size whose content type and path
+// are not excluded, when the client accepts the format. Responses flushed before reaching the minimum size,
+// such as streams, are written as is.
+func Compress(config *CompressConfig) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+			// upgraded connections such as websockets are not responses to compress
+			if isExcludedPath(request.URL.Path, config.ExcludePaths) || request.Header.Get("Upgrade") != "" {
+				next.ServeHTTP(writer, request)
+
+				return
+			}
+
+			writer.Header().Add("Vary", "Accept-Encoding")
+
+			if !acceptsEncoding(request, config.Format) || request.Method == http.MethodHead {
+				next.ServeHTTP(writer, request)
+
+				return
+			}
+
+			compressWriter := &compressResponseWriter{ResponseWriter: writer, config: config, status: http.StatusOK}
+			defer compressWriter.close()
+
+			next.ServeHTTP(compressWriter, request)
+		})
+	}
+}
+
+// compressResponseWriter buffers the response until the minimum size to decide whether to compress it.
+type compressResponseWriter struct {
+	http.ResponseWriter
+
+	// config is configuration of response compression.
+	config *CompressConfig
+
+	// status is status code of the response.
+	status int
+
+	// wroteHeader is whether the handler wrote the status code.
+	wroteHeader bool
+
+	// decided is whether the response is being written, compressed or not.
+	decided bool
+
+	// buffer is the response body written before the decision.
+	buffer []byte
+
+	// encoder compresses the response body, nil if the response is not compressed.
+	encoder io.WriteCloser
+}
+
+// WriteHeader records the status code, written with the body once compression is decided.
+func (w *compressResponseWriter) WriteHeader(status int) {
+	if w.wroteHeader || w.decided {
+		return
+	}
+
+	// informational responses are written as is
+	if status >= http.StatusContinue && status < http.StatusOK {
+		w.ResponseWriter.WriteHeader(status)
+
+		return
+	}
+
+	w.status = status
+	w.wroteHeader = true
+
+	if !bodyAllowed(status) {
+		_ = w.decide(false)
+	}
+}
+
+// Write buffers the body until the minimum size, then writes it compressed if allowed.
+func (w *compressResponseWriter) Write(data []byte) (int, error) {
+	if !w.decided {
+		w.buffer = append(w.buffer, data...)
+		if len(w.buffer) < w.config.MinSize {
+			return len(data), nil
+		}
+
+		if err := w.decide(true); err != nil {
+			return 0, err
+		}
+
+		return len(data), nil
+	}
+
+	if w.encoder != nil {
+		return w.encoder.Write(data) //nolint:wrapcheck // errors of the response writer are returned as is
+	}
+
+	return w.ResponseWriter.Write(data) //nolint:wrapcheck // errors of the response writer are returned as is
+}
+
+// Flush writes the buffered body and flushes the response, uncompressed if the minimum size is not reached.
+func (w *compressResponseWriter) Flush() {
+	if !w.decided {
+		_ = w.decide(false)
+	}
+
+	if flusher, ok := w.encoder.(interface{ Flush() error }); ok {
+		_ = flusher.Flush()
+	}
+
+	_ = http.NewResponseController(w.ResponseWriter).Flush()
+}
+
+// Unwrap returns the underlying response writer.
+func (w *compressResponseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// close writes the rest of the response once the handler returns.
+func (w *compressResponseWriter) close() {
+	// the body is smaller than the minimum size if nothing was decided yet
+	if !w.decided {
+		_ = w.decide(false)
+	}
+
+	if w.encoder != nil {
+		_ = w.encoder.Close()
+	}
+}
+
+// decide writes the header and the buffered body, compressed if allowed and the response is compressible.
+func (w *compressResponseWriter) decide(allowed bool) error {
+	w.decided = true
+
+	header := w.Header()
+
+	// detect content type before compressing, since the compressed body can not be sniffed
+	if header.Get("Content-Type") == "" && len(w.buffer) > 0 {
+		header.Set("Content-Type", http.DetectContentType(w.buffer))
+	}
+
+	if allowed && w.compressible() {
+		encoder, err := newEncoder(w.ResponseWriter, w.config)
+		if err != nil {
+			return err
+		}
+
+		w.encoder = encoder
+
+		header.Set("Content-Encoding", w.config.Format)
+		header.Del("Content-Length")
+	}
+
+	w.ResponseWriter.WriteHeader(w.status)
+
+	if len(w.buffer) == 0 {
+		return nil
+	}
+
+	buffer := w.buffer
+	w.buffer = nil
+
+	var err error
+	if w.encoder != nil {
+		_, err = w.encoder.Write(buffer)
+	} else {
+		_, err = w.ResponseWriter.Write(buffer)
+	}
+
+	return err //nolint:wrapcheck // errors of the response writer are returned as is
+}
+
+// compressible returns whether the response is not already encoded and its content type is allowed and not
+// excluded.
+func (w *compressResponseWriter) compressible() bool {
+	header := w.Header()
+	if !bodyAllowed(w.status) || header.Get("Content-Encoding") != "" {
+		return false
+	}
+
+	mediaType, _, err := mime.ParseMediaType(header.Get("Content-Type"))
+	if err != nil {
+		return len(w.config.ContentTypes) == 0
+	}
+
+	if len(w.config.ContentTypes) > 0 && !matchesContentType(mediaType, w.config.ContentTypes) {
+		return false
+	}
+
+	return !matchesContentType(mediaType, w.config.ExcludeContentTypes)
+}
+
+// matchesContentType returns whether the media type is one of the content types, a type ending with /*
+// matching all its subtypes.
+func matchesContentType(mediaType string, contentTypes []string) bool {
+	return slices.ContainsFunc(contentTypes, func(contentType string) bool {
+		if prefix, ok := strings.CutSuffix(contentType, "/*"); ok {
+			return strings.HasPrefix(mediaType, prefix+"/")
+		}
+
+		return strings.EqualFold(mediaType, contentType)
+	})
+}
+
+// newEncoder creates an encoder of the format writing to the writer.
+func newEncoder(writer io.Writer, config *CompressConfig) (io.WriteCloser, error) {
+	var (
+		encoder io.WriteCloser
+		err     error
+	)
+
+	switch config.Format {
+	case CompressionFormatDeflate:
+		encoder, err = flate.NewWriter(writer, config.Level)
+	case CompressionFormatBrotli:
+		encoder = brotli.NewWriterOptions(writer, brotli.WriterOptions{Quality: config.Level, LGWin: brotliWindowBits})
+	case CompressionFormatZstd:
+		// a single goroutine per response, since responses are compressed concurrently
+		encoder, err = zstd.NewWriter(writer,
+			zstd.WithEncoderLevel(zstd.EncoderLevelFromZstd(config.Level)),
+			zstd.WithEncoderConcurrency(1),
+			zstd.WithWindowSize(zstdWindowSize),
+			zstd.WithLowerEncoderMem(true),
+		)
+	default:
+		encoder, err = gzip.NewWriterLevel(writer, config.Level)
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to create %s encoder: %w", config.Format, err)
+	}
+
+	return encoder, nil
+}
+
+// acceptsEncoding returns whether the request accepts the content encoding.
+func acceptsEncoding(request *http.Request, encoding string) bool {
+	for _, header := range request.Header.Values("Accept-Encoding") {
+		for _, value := range strings.Split(header, ",") {
+			name, params, _ := strings.Cut(value, ";")
+			if !strings.EqualFold(strings.TrimSpace(name), encoding) {
+				continue
+			}
+
+			// zero quality means the encoding is not acceptable
+			quality, ok := strings.CutPrefix(strings.TrimSpace(params), "q=")
+			if !ok {
+				return true
+			}
+
+			weight, err := strconv.ParseFloat(quality, 64)
+
+			return err == nil && weight > 0
+		}
+	}
+
+	return false
+}
+
+// isExcludedPath returns whether the path starts with one of the excluded prefixes.
+func isExcludedPath(path string, prefixes []string) bool {
+	return slices.ContainsFunc(prefixes, func(prefix string) bool {
+		return strings.HasPrefix(path, prefix)
+	})
+}
+
+// bodyAllowed returns whether a response of the status can have a body.
+func bodyAllowed(status int) bool {
+	return status != http.StatusNoContent && status != http.StatusNotModified
 }
@@ -0,0 +1,321 @@
+package middleware
+
+import (
+	"compress/flate"
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/andybalholm/brotli"
+	"github.com/klauspost/compress/zstd"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCompressConfigValidate(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		config  *CompressConfig
+		wantErr error
+	}{
+		{name: "accept gzip", config: &CompressConfig{Level: 6, Format: "gzip"}},
+		{name: "accept deflate", config: &CompressConfig{Level: 1, Format: "deflate"}},
+		{name: "accept brotli quality", config: &CompressConfig{Level: 11, Format: "br"}},
+		{name: "accept zstd level", config: &CompressConfig{Level: 19, Format: "zstd"}},
+		{
+			name:    "reject unsupported format",
+			config:  &CompressConfig{Level: 6, Format: "lzma"},
+			wantErr: ErrUnsupportedCompressionFormat,
+		},
+		{
+			name:    "reject out of range level",
+			config:  &CompressConfig{Level: 10, Format: "gzip"},
+			wantErr: ErrInvalidCompressionLevel,
+		},
+		{
+			name:    "reject out of range brotli quality",
+			config:  &CompressConfig{Level: 12, Format: "br"},
+			wantErr: ErrInvalidCompressionLevel,
+		},
+		{
+			name:    "reject out of range zstd level",
+			config:  &CompressConfig{Level: 0, Format: "zstd"},
+			wantErr: ErrInvalidCompressionLevel,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			assert.ErrorIs(t, tt.config.Validate(), tt.wantErr)
+		})
+	}
+}
+
+func TestCompress(t *testing.T) {
+	t.Parallel()
+
+	config := &CompressConfig{
+		Level:               6,
+		Format:              "gzip",
+		MinSize:             64,
+		ExcludeContentTypes: []string{"image/*", "text/event-stream"},
+		ExcludePaths:        []string{"/metrics"},
+	}
+
+	largeBody := strings.Repeat("compressible ", 20)
+
+	// newHandler creates a handler writing the body with the content type behind compression.
+	newHandler := func(contentType string, body string) http.Handler {
+		return Compress(config)(http.HandlerFunc(func(writer http.ResponseWriter, _ *http.Request) {
+			if contentType != "" {
+				writer.Header().Set("Content-Type", contentType)
+			}
+
+			_, _ = io.WriteString(writer, body)
+		}))
+	}
+
+	tests := []struct {
+		name           string
+		path           string
+		acceptEncoding string
+		contentType    string
+		body           string
+		wantCompressed bool
+	}{
+		{
+			name:           "compress large response",
+			path:           "/users",
+			acceptEncoding: "gzip, deflate",
+			contentType:    "application/json",
+			body:           largeBody,
+			wantCompressed: true,
+		},
+		{
+			name:           "skip response smaller than minimum size",
+			path:           "/users",
+			acceptEncoding: "gzip",
+			contentType:    "application/json",
+			body:           "{}",
+		},
+		{
+			name:           "skip excluded content type by wildcard",
+			path:           "/avatar",
+			acceptEncoding: "gzip",
+			contentType:    "image/png",
+			body:           largeBody,
+		},
+		{
+			name:           "skip event stream",
+			path:           "/events",
+			acceptEncoding: "gzip",
+			contentType:    "text/event-stream; charset=utf-8",
+			body:           largeBody,
+		},
+		{
+			name:           "skip excluded path",
+			path:           "/metrics",
+			acceptEncoding: "gzip",
+			contentType:    "text/plain",
+			body:           largeBody,
+		},
+		{
+			name:        "skip client not accepting format",
+			path:        "/users",
+			contentType: "application/json",
+			body:        largeBody,
+		},
+		{
+			name:           "skip format with zero quality",
+			path:           "/users",
+			acceptEncoding: "gzip;q=0",
+			contentType:    "application/json",
+			body:           largeBody,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			request := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			if tt.acceptEncoding != "" {
+				request.Header.Set("Accept-Encoding", tt.acceptEncoding)
+			}
+
+			recorder := httptest.NewRecorder()
+			newHandler(tt.contentType, tt.body).ServeHTTP(recorder, request)
+
+			assert.Equal(t, http.StatusOK, recorder.Code)
+
+			if !tt.wantCompressed {
+				assert.Empty(t, recorder.Header().Get("Content-Encoding"))
+				assert.Equal(t, tt.body, recorder.Body.String())
+
+				return
+			}
+
+			assert.Equal(t, "gzip", recorder.Header().Get("Content-Encoding"))
+			assert.Equal(t, tt.contentType, recorder.Header().Get("Content-Type"))
+
+			reader, err := gzip.NewReader(recorder.Body)
+			require.NoError(t, err)
+
+			body, err := io.ReadAll(reader)
+			require.NoError(t, err)
+			assert.Equal(t, tt.body, string(body))
+		})
+	}
+
+	t.Run("write flushed stream uncompressed", func(t *testing.T) {
+		t.Parallel()
+
+		handler := Compress(config)(http.HandlerFunc(func(writer http.ResponseWriter, _ *http.Request) {
+			writer.Header().Set("Content-Type", "application/x-ndjson")
+
+			for range 10 {
+				_, _ = io.WriteString(writer, "{\"event\":\"tick\"}\n")
+				_ = http.NewResponseController(writer).Flush()
+			}
+		}))
+
+		request := httptest.NewRequest(http.MethodGet, "/stream", nil)
+		request.Header.Set("Accept-Encoding", "gzip")
+
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, request)
+
+		assert.True(t, recorder.Flushed)
+		assert.Empty(t, recorder.Header().Get("Content-Encoding"))
+		assert.Equal(t, strings.Repeat("{\"event\":\"tick\"}\n", 10), recorder.Body.String())
+	})
+
+	t.Run("compress only allowed content types", func(t *testing.T) {
+		t.Parallel()
+
+		allowConfig := *config
+		allowConfig.ContentTypes = []string{"application/json", "text/*"}
+
+		for contentType, wantEncoding := range map[string]string{
+			"application/json; charset=utf-8": "gzip",
+			"text/html":                       "gzip",
+			"application/pdf":                 "",
+		} {
+			handler := Compress(&allowConfig)(http.HandlerFunc(func(writer http.ResponseWriter, _ *http.Request) {
+				writer.Header().Set("Content-Type", contentType)
+				_, _ = io.WriteString(writer, largeBody)
+			}))
+
+			request := httptest.NewRequest(http.MethodGet, "/users", nil)
+			request.Header.Set("Accept-Encoding", "gzip")
+
+			recorder := httptest.NewRecorder()
+			handler.ServeHTTP(recorder, request)
+
+			assert.Equal(t, wantEncoding, recorder.Header().Get("Content-Encoding"), contentType)
+		}
+	})
+
+	t.Run("keep status without body", func(t *testing.T) {
+		t.Parallel()
+
+		handler := Compress(config)(http.HandlerFunc(func(writer http.ResponseWriter, _ *http.Request) {
+			writer.WriteHeader(http.StatusNoContent)
+		}))
+
+		request := httptest.NewRequest(http.MethodDelete, "/users/1", nil)
+		request.Header.Set("Accept-Encoding", "gzip")
+
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, request)
+
+		assert.Equal(t, http.StatusNoContent, recorder.Code)
+		assert.Empty(t, recorder.Header().Get("Content-Encoding"))
+	})
+}
+
+func TestCompressFormats(t *testing.T) {
+	t.Parallel()
+
+	body := strings.Repeat(`{"id":1,"email":"user@example.com"},`, 100)
+
+	tests := []struct {
+		format     string
+		level      int
+		decompress func(t *testing.T, body io.Reader) []byte
+	}{
+		{
+			format: "deflate",
+			level:  6,
+			decompress: func(t *testing.T, body io.Reader) []byte {
+				t.Helper()
+
+				decompressed, err := io.ReadAll(flate.NewReader(body))
+				require.NoError(t, err)
+
+				return decompressed
+			},
+		},
+		{
+			format: "zstd",
+			level:  3,
+			decompress: func(t *testing.T, body io.Reader) []byte {
+				t.Helper()
+
+				decoder, err := zstd.NewReader(body)
+				require.NoError(t, err)
+
+				defer decoder.Close()
+
+				decompressed, err := io.ReadAll(decoder)
+				require.NoError(t, err)
+
+				return decompressed
+			},
+		},
+		{
+			format: "br",
+			level:  11,
+			decompress: func(t *testing.T, body io.Reader) []byte {
+				t.Helper()
+
+				decompressed, err := io.ReadAll(brotli.NewReader(body))
+				require.NoError(t, err)
+
+				return decompressed
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			t.Parallel()
+
+			config := &CompressConfig{Level: tt.level, Format: tt.format, MinSize: 64}
+			require.NoError(t, config.Validate())
+
+			handler := Compress(config)(http.HandlerFunc(func(writer http.ResponseWriter, _ *http.Request) {
+				writer.Header().Set("Content-Type", "application/json")
+				_, _ = io.WriteString(writer, body)
+			}))
+
+			request := httptest.NewRequest(http.MethodGet, "/users", nil)
+			request.Header.Set("Accept-Encoding", "gzip, deflate, br, zstd")
+
+			recorder := httptest.NewRecorder()
+			handler.ServeHTTP(recorder, request)
+
+			assert.Equal(t, tt.format, recorder.Header().Get("Content-Encoding"))
+			assert.Less(t, recorder.Body.Len(), len(body)/10)
+
+			assert.Equal(t, body, string(tt.decompress(t, recorder.Body)))
+		})
+	}
+}
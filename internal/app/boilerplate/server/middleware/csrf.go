@@ -0,0 +1,178 @@
+package middleware
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/apierror"
+	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/logger"
+)
+
+const (
+	// CSRFTokenKey is the key for the CSRF token of the request in context.
+	CSRFTokenKey ContextKey = "csrf_token"
+
+	// csrfTokenSize is size of CSRF tokens in bytes before encoding.
+	csrfTokenSize = 32
+)
+
+// CSRFConfig represents configuration for CSRF protection of cookie-based auth flows.
+type CSRFConfig struct {
+	// Enabled is whether mutating requests must submit the CSRF token of their cookie.
+	Enabled *bool `json:"enabled"`
+
+	// CookieName is name of the cookie carrying the CSRF token.
+	CookieName *string `json:"cookie_name"`
+
+	// HeaderName is the request header submitting the CSRF token.
+	HeaderName *string `json:"header_name"`
+
+	// FormField is the form field submitting the CSRF token, for forms of server-rendered pages.
+	FormField *string `json:"form_field"`
+
+	// MaxAge is lifetime of the CSRF cookie in seconds.
+	MaxAge *int `json:"max_age"`
+
+	// Secure is whether the CSRF cookie is sent over HTTPS only.
+	Secure *bool `json:"secure"`
+
+	// ExemptPaths is path prefixes not checked, such as webhooks authenticated by signatures.
+	ExemptPaths *[]string `json:"exempt_paths"`
+}
+
+// SetDefault sets default values.
+func (c *CSRFConfig) SetDefault() {
+	if c.Enabled == nil {
+		c.Enabled = &[]bool{false}[0]
+	}
+
+	if c.CookieName == nil {
+		c.CookieName = &[]string{"csrf_token"}[0]
+	}
+
+	if c.HeaderName == nil {
+		c.HeaderName = &[]string{"X-CSRF-Token"}[0]
+	}
+
+	if c.FormField == nil {
+		c.FormField = &[]string{"csrf_token"}[0]
+	}
+
+	if c.MaxAge == nil {
+		c.MaxAge = &[]int{43200}[0] // 12 hours
+	}
+
+	if c.Secure == nil {
+		c.Secure = &[]bool{true}[0]
+	}
+
+	if c.ExemptPaths == nil {
+		c.ExemptPaths = &[]string{}
+	}
+}
+
+// CSRF is a middleware that protects cookie-based auth flows with double-submit cookies: requests without the
+// cookie get a new token in it, and mutating requests must submit the token of their cookie in the header or
+// form field. Safe methods, requests to the exempt path prefixes and requests authenticated by a bearer token or
+// one of the exempt headers (such as the API key header) are not checked, since browsers do not attach those
+// to cross-site requests. The token is stored in context for pages to render into forms.
+func CSRF(
+	config *CSRFConfig,
+	exemptPaths []string,
+	exemptHeaders []string,
+	logger *logger.Logger,
+) func(next http.Handler) http.Handler {
+	exemptPaths = slices.Concat(exemptPaths, *config.ExemptPaths)
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+			token, issued := csrfToken(writer, request, config)
+			request = request.WithContext(context.WithValue(request.Context(), CSRFTokenKey, token))
+
+			if isSafeMethod(request.Method) || isCSRFExempt(request, exemptPaths, exemptHeaders) {
+				next.ServeHTTP(writer, request)
+
+				return
+			}
+
+			submitted := request.Header.Get(*config.HeaderName)
+			if submitted == "" {
+				submitted = request.PostFormValue(*config.FormField)
+			}
+
+			// a token issued by this response was never seen by the client, so it can not be submitted
+			if issued || subtle.ConstantTimeCompare([]byte(submitted), []byte(token)) != 1 {
+				logger.Ctx(request.Context()).Debug().Str("path", request.URL.Path).Msg("invalid csrf token")
+
+				// error is ignored since nothing else can be written to the client
+				_ = apierror.Write(writer, http.StatusForbidden, &apierror.Response{
+					Error: "Invalid CSRF token",
+					Code:  apierror.CodeForbidden,
+				})
+
+				return
+			}
+
+			next.ServeHTTP(writer, request)
+		})
+	}
+}
+
+// csrfToken returns the CSRF token of the cookie of the request, or sets a cookie of a new token and returns
+// it as issued.
+func csrfToken(writer http.ResponseWriter, request *http.Request, config *CSRFConfig) (string, bool) {
+	if cookie, err := request.Cookie(*config.CookieName); err == nil && isCSRFToken(cookie.Value) {
+		return cookie.Value, false
+	}
+
+	// error is ignored since crypto/rand never fails
+	buf := make([]byte, csrfTokenSize)
+	_, _ = rand.Read(buf)
+
+	token := base64.RawURLEncoding.EncodeToString(buf)
+
+	// the cookie is readable by scripts, so that they can submit it in the header
+	http.SetCookie(writer, &http.Cookie{
+		Name:     *config.CookieName,
+		Value:    token,
+		Path:     "/",
+		MaxAge:   *config.MaxAge,
+		Secure:   *config.Secure,
+		SameSite: http.SameSiteLaxMode,
+	})
+
+	return token, true
+}
+
+// isCSRFToken returns whether the value has the form of generated CSRF tokens.
+func isCSRFToken(value string) bool {
+	decoded, err := base64.RawURLEncoding.DecodeString(value)
+
+	return err == nil && len(decoded) == csrfTokenSize
+}
+
+// isCSRFExempt returns whether the request is to an exempt path or is authenticated without cookies.
+func isCSRFExempt(request *http.Request, exemptPaths []string, exemptHeaders []string) bool {
+	if strings.HasPrefix(request.Header.Get("Authorization"), "Bearer ") {
+		return true
+	}
+
+	for _, header := range exemptHeaders {
+		if request.Header.Get(header) != "" {
+			return true
+		}
+	}
+
+	for _, path := range exemptPaths {
+		if strings.HasPrefix(request.URL.Path, path) {
+			return true
+		}
+	}
+
+	return false
+}
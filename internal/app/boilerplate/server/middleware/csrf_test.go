@@ -0,0 +1,172 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCSRFConfigSetDefault(t *testing.T) {
+	t.Parallel()
+
+	config := &CSRFConfig{}
+	config.SetDefault()
+
+	assert.False(t, *config.Enabled)
+	assert.Equal(t, "csrf_token", *config.CookieName)
+	assert.Equal(t, "X-CSRF-Token", *config.HeaderName)
+	assert.Equal(t, "csrf_token", *config.FormField)
+	assert.Equal(t, 43200, *config.MaxAge)
+	assert.True(t, *config.Secure)
+	assert.Empty(t, *config.ExemptPaths)
+}
+
+func TestCSRF(t *testing.T) {
+	t.Parallel()
+
+	// newHandler creates a handler behind CSRF protection exempting /webhooks and the X-API-Key header,
+	// responding with the token of the request.
+	newHandler := func(t *testing.T) http.Handler {
+		t.Helper()
+
+		config := &CSRFConfig{Enabled: &[]bool{true}[0]}
+		config.SetDefault()
+
+		return CSRF(config, []string{"/webhooks"}, []string{"X-API-Key"}, setupTestLogger(t))(
+			http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+				token, _ := request.Context().Value(CSRFTokenKey).(string)
+				_, _ = writer.Write([]byte(token))
+			}),
+		)
+	}
+
+	// issue returns the token issued to a new client.
+	issue := func(t *testing.T, handler http.Handler) string {
+		t.Helper()
+
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/users", nil))
+		require.Equal(t, http.StatusOK, recorder.Code)
+
+		cookies := recorder.Result().Cookies()
+		require.Len(t, cookies, 1)
+		assert.Equal(t, "csrf_token", cookies[0].Name)
+		assert.Equal(t, recorder.Body.String(), cookies[0].Value)
+		assert.True(t, cookies[0].Secure)
+		assert.False(t, cookies[0].HttpOnly)
+
+		return cookies[0].Value
+	}
+
+	t.Run("issue token to reads without cookie", func(t *testing.T) {
+		t.Parallel()
+
+		assert.NotEmpty(t, issue(t, newHandler(t)))
+	})
+
+	t.Run("keep token of the cookie", func(t *testing.T) {
+		t.Parallel()
+
+		handler := newHandler(t)
+		token := issue(t, handler)
+
+		request := httptest.NewRequest(http.MethodGet, "/users", nil)
+		request.AddCookie(&http.Cookie{Name: "csrf_token", Value: token})
+
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, request)
+
+		assert.Equal(t, token, recorder.Body.String())
+		assert.Empty(t, recorder.Result().Cookies())
+	})
+
+	t.Run("check token of writes", func(t *testing.T) {
+		t.Parallel()
+
+		handler := newHandler(t)
+		token := issue(t, handler)
+
+		tests := []struct {
+			name       string
+			cookie     string
+			header     string
+			form       string
+			wantStatus int
+		}{
+			{name: "allow token in header", cookie: token, header: token, wantStatus: http.StatusOK},
+			{name: "allow token in form", cookie: token, form: token, wantStatus: http.StatusOK},
+			{name: "reject missing token", cookie: token, wantStatus: http.StatusForbidden},
+			{name: "reject other token", cookie: token, header: issue(t, handler), wantStatus: http.StatusForbidden},
+			{name: "reject token without cookie", header: token, wantStatus: http.StatusForbidden},
+			{name: "reject invalid cookie", cookie: "invalid", header: "invalid", wantStatus: http.StatusForbidden},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				t.Parallel()
+
+				request := httptest.NewRequest(http.MethodPost, "/users", nil)
+				if tt.form != "" {
+					request = httptest.NewRequest(http.MethodPost, "/users",
+						strings.NewReader(url.Values{"csrf_token": {tt.form}}.Encode()))
+					request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+				}
+
+				if tt.cookie != "" {
+					request.AddCookie(&http.Cookie{Name: "csrf_token", Value: tt.cookie})
+				}
+
+				if tt.header != "" {
+					request.Header.Set("X-CSRF-Token", tt.header)
+				}
+
+				recorder := httptest.NewRecorder()
+				handler.ServeHTTP(recorder, request)
+
+				assert.Equal(t, tt.wantStatus, recorder.Code)
+
+				if tt.wantStatus == http.StatusForbidden {
+					assert.JSONEq(t, `{"error":"Invalid CSRF token","code":"forbidden"}`, recorder.Body.String())
+				}
+			})
+		}
+	})
+
+	t.Run("exempt requests not authenticated by cookies", func(t *testing.T) {
+		t.Parallel()
+
+		handler := newHandler(t)
+
+		tests := []struct {
+			name   string
+			path   string
+			header string
+			value  string
+		}{
+			{name: "bearer token", path: "/users", header: "Authorization", value: "Bearer token"},
+			{name: "exempt header", path: "/users", header: "X-API-Key", value: "key"},
+			{name: "exempt path", path: "/webhooks/stripe"},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				t.Parallel()
+
+				request := httptest.NewRequest(http.MethodPost, tt.path, nil)
+				if tt.header != "" {
+					request.Header.Set(tt.header, tt.value)
+				}
+
+				recorder := httptest.NewRecorder()
+				handler.ServeHTTP(recorder, request)
+
+				assert.Equal(t, http.StatusOK, recorder.Code)
+			})
+		}
+	})
+}
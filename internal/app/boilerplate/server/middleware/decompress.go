@@ -0,0 +1,177 @@
+package middleware
+
+import (
+	"compress/gzip"
+	"compress/zlib"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/apierror"
+)
+
+const (
+	// acceptedEncodings is content encodings of request bodies decompressed by Decompress.
+	acceptedEncodings = "gzip, deflate"
+
+	// decompressRatioMinSize is decompressed size in bytes from which the compression ratio is checked,
+	// so that small bodies of repeated bytes are accepted.
+	decompressRatioMinSize = 64 << 10 // 64KB
+)
+
+// ErrInvalidDecompressRatio is returned when the maximum decompression ratio is less than 1.
+var ErrInvalidDecompressRatio = errors.New("max decompression ratio must be at least 1")
+
+// DecompressConfig represents configuration of the decompression middleware.
+type DecompressConfig struct {
+	// MaxRatio is maximum ratio of decompressed to compressed size of bodies.
+	MaxRatio int64
+
+	// MaxSize is maximum size of compressed bodies in bytes, decompressed bodies are limited by RequestSize.
+	MaxSize int64
+}
+
+// Validate validates the decompression config.
+func (c *DecompressConfig) Validate() error {
+	if c.MaxRatio < 1 {
+		return fmt.Errorf("%w: %d", ErrInvalidDecompressRatio, c.MaxRatio)
+	}
+
+	return nil
+}
+
+// DecompressDetails represents details of the unsupported content encoding error.
+type DecompressDetails struct {
+	// Accepted is content encodings accepted by server.
+	Accepted string `json:"accepted"`
+}
+
+// Decompress is a middleware that decompresses gzip and deflate request bodies by their Content-Encoding, so
+// that handlers read them as sent uncompressed. It must run before RequestSize, which then limits the
+// decompressed size. Bodies expanding more than MaxRatio times their compressed size, or longer than MaxSize
+// compressed, are failed as too large while read, rejecting zip bombs before they are inflated up to the limit.
+func Decompress(config *DecompressConfig) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+			encoding := strings.ToLower(strings.TrimSpace(request.Header.Get("Content-Encoding")))
+			if encoding == "" || encoding == "identity" || request.Body == nil || request.Body == http.NoBody {
+				next.ServeHTTP(writer, request)
+
+				return
+			}
+
+			if request.ContentLength > config.MaxSize {
+				writeRequestTooLarge(writer, config.MaxSize)
+
+				return
+			}
+
+			compressed := &countingReader{reader: http.MaxBytesReader(writer, request.Body, config.MaxSize)}
+
+			var (
+				decompressor io.ReadCloser
+				err          error
+			)
+
+			switch encoding {
+			case "gzip", "x-gzip":
+				decompressor, err = gzip.NewReader(compressed)
+			case "deflate":
+				decompressor, err = zlib.NewReader(compressed)
+			default:
+				writeUnsupportedEncoding(writer, encoding)
+
+				return
+			}
+
+			if err != nil {
+				// error is ignored since nothing else can be written to the client
+				_ = apierror.Write(writer, http.StatusBadRequest, &apierror.Response{
+					Error: "Request body is not valid " + encoding,
+					Code:  apierror.CodeInvalidRequest,
+				})
+
+				return
+			}
+
+			request.Body = &decompressBody{
+				decompressor: decompressor,
+				body:         request.Body,
+				compressed:   compressed,
+				maxRatio:     config.MaxRatio,
+			}
+
+			// the length and encoding of the body read by handlers are unknown
+			request.ContentLength = -1
+			request.Header.Del("Content-Encoding")
+			request.Header.Del("Content-Length")
+
+			next.ServeHTTP(writer, request)
+		})
+	}
+}
+
+// writeUnsupportedEncoding writes the unsupported content encoding error response.
+func writeUnsupportedEncoding(writer http.ResponseWriter, encoding string) {
+	writer.Header().Set("Accept-Encoding", acceptedEncodings)
+
+	// error is ignored since nothing else can be written to the client
+	_ = apierror.Write(writer, http.StatusUnsupportedMediaType, &apierror.Response{
+		Error:   "Unsupported content encoding " + encoding,
+		Code:    apierror.CodeInvalidRequest,
+		Details: &DecompressDetails{Accepted: acceptedEncodings},
+	})
+}
+
+// countingReader represents a reader counting bytes read.
+type countingReader struct {
+	reader io.Reader
+
+	n int64
+}
+
+// Read reads from the reader.
+func (r *countingReader) Read(p []byte) (int, error) {
+	n, err := r.reader.Read(p)
+	r.n += int64(n)
+
+	return n, err //nolint:wrapcheck // io.Reader must return io.EOF as is
+}
+
+// decompressBody represents request body decompressed while read.
+type decompressBody struct {
+	decompressor io.ReadCloser
+
+	// body is the compressed body, closed with the decompressor.
+	body io.Closer
+
+	// compressed counts compressed bytes read.
+	compressed *countingReader
+
+	// decompressed is number of decompressed bytes read.
+	decompressed int64
+
+	maxRatio int64
+}
+
+// Read reads decompressed body, failing with the max bytes error once it expands beyond the ratio.
+func (b *decompressBody) Read(p []byte) (int, error) {
+	n, err := b.decompressor.Read(p)
+	b.decompressed += int64(n)
+
+	if b.decompressed > decompressRatioMinSize && b.decompressed > b.maxRatio*b.compressed.n {
+		// RequestSize replaces the response with 413 on the max bytes error
+		return n, &http.MaxBytesError{Limit: b.maxRatio * b.compressed.n}
+	}
+
+	return n, err //nolint:wrapcheck // io.Reader must return io.EOF as is
+}
+
+// Close closes the decompressor and the compressed body.
+func (b *decompressBody) Close() error {
+	_ = b.decompressor.Close()
+
+	return b.body.Close() //nolint:wrapcheck // closing errors are returned as is
+}
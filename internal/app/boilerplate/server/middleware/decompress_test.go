@@ -0,0 +1,195 @@
+package middleware
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/apierror"
+)
+
+// gzipBody returns the content compressed with gzip.
+func gzipBody(t *testing.T, content []byte) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+
+	writer := gzip.NewWriter(&buf)
+	_, err := writer.Write(content)
+	require.NoError(t, err)
+	require.NoError(t, writer.Close())
+
+	return buf.Bytes()
+}
+
+// zlibBody returns the content compressed with deflate (zlib).
+func zlibBody(t *testing.T, content []byte) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+
+	writer := zlib.NewWriter(&buf)
+	_, err := writer.Write(content)
+	require.NoError(t, err)
+	require.NoError(t, writer.Close())
+
+	return buf.Bytes()
+}
+
+// echoBodyHandler responds with the request body, or 400 if it fails to be read.
+func echoBodyHandler() http.Handler {
+	return http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		body, err := io.ReadAll(request.Body)
+		if err != nil {
+			writer.WriteHeader(http.StatusBadRequest)
+
+			return
+		}
+
+		writer.Header().Set("X-Content-Encoding", request.Header.Get("Content-Encoding"))
+		_, _ = writer.Write(body)
+	})
+}
+
+// serveDecompress serves the request through Decompress and RequestSize with the limits.
+func serveDecompress(
+	request *http.Request, maxRatio, maxSize, maxRequestSize int64,
+) *httptest.ResponseRecorder {
+	config := &DecompressConfig{MaxRatio: maxRatio, MaxSize: maxSize}
+	handler := Decompress(config)(RequestSize(maxRequestSize)(echoBodyHandler()))
+
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, request)
+
+	return recorder
+}
+
+func TestDecompressConfigValidate(t *testing.T) {
+	t.Parallel()
+
+	require.NoError(t, (&DecompressConfig{MaxRatio: 100}).Validate())
+	require.ErrorIs(t, (&DecompressConfig{MaxRatio: 0}).Validate(), ErrInvalidDecompressRatio)
+}
+
+func TestDecompress(t *testing.T) {
+	t.Parallel()
+
+	content := []byte(`{"email":"user@example.com","password":"correct horse battery staple"}`)
+
+	tests := []struct {
+		name     string
+		encoding string
+		body     []byte
+	}{
+		{name: "decompress gzip body", encoding: "gzip", body: gzipBody(t, content)},
+		{name: "decompress x-gzip body", encoding: "x-gzip", body: gzipBody(t, content)},
+		{name: "decompress deflate body", encoding: "Deflate", body: zlibBody(t, content)},
+		{name: "pass identity body", encoding: "identity", body: content},
+		{name: "pass body without encoding", encoding: "", body: content},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			request := httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(test.body))
+			if test.encoding != "" {
+				request.Header.Set("Content-Encoding", test.encoding)
+			}
+
+			recorder := serveDecompress(request, 100, 1<<20, 1<<20)
+
+			require.Equal(t, http.StatusOK, recorder.Code)
+			assert.Equal(t, content, recorder.Body.Bytes())
+
+			// handlers read the body as sent uncompressed
+			if test.encoding != "identity" {
+				assert.Empty(t, recorder.Header().Get("X-Content-Encoding"))
+			}
+		})
+	}
+
+	t.Run("reject unsupported encoding", func(t *testing.T) {
+		t.Parallel()
+
+		request := httptest.NewRequest(http.MethodPost, "/", strings.NewReader("data"))
+		request.Header.Set("Content-Encoding", "br")
+
+		recorder := serveDecompress(request, 100, 1<<20, 1<<20)
+
+		require.Equal(t, http.StatusUnsupportedMediaType, recorder.Code)
+		assert.Equal(t, "gzip, deflate", recorder.Header().Get("Accept-Encoding"))
+
+		var response apierror.Response
+		require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &response))
+		assert.Equal(t, apierror.CodeInvalidRequest, response.Code)
+	})
+
+	t.Run("reject invalid gzip body", func(t *testing.T) {
+		t.Parallel()
+
+		request := httptest.NewRequest(http.MethodPost, "/", strings.NewReader("not gzip"))
+		request.Header.Set("Content-Encoding", "gzip")
+
+		recorder := serveDecompress(request, 100, 1<<20, 1<<20)
+
+		assert.Equal(t, http.StatusBadRequest, recorder.Code)
+	})
+
+	t.Run("limit decompressed size", func(t *testing.T) {
+		t.Parallel()
+
+		// random-looking content does not exceed the ratio, only the request size
+		large := make([]byte, 4096)
+		for i := range large {
+			large[i] = byte(i * 7 % 251)
+		}
+
+		request := httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(gzipBody(t, large)))
+		request.Header.Set("Content-Encoding", "gzip")
+
+		recorder := serveDecompress(request, 100, 1<<20, 1024)
+
+		require.Equal(t, http.StatusRequestEntityTooLarge, recorder.Code)
+
+		var response apierror.Response
+		require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &response))
+		assert.Equal(t, apierror.CodeRequestTooLarge, response.Code)
+	})
+
+	t.Run("reject zip bomb", func(t *testing.T) {
+		t.Parallel()
+
+		// 8MB of zeros compress to about 8KB
+		bomb := gzipBody(t, make([]byte, 8<<20))
+
+		request := httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(bomb))
+		request.Header.Set("Content-Encoding", "gzip")
+
+		recorder := serveDecompress(request, 100, 1<<20, 16<<20)
+
+		assert.Equal(t, http.StatusRequestEntityTooLarge, recorder.Code)
+	})
+
+	t.Run("limit compressed size", func(t *testing.T) {
+		t.Parallel()
+
+		body := gzipBody(t, content)
+
+		request := httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(body))
+		request.Header.Set("Content-Encoding", "gzip")
+
+		recorder := serveDecompress(request, 100, int64(len(body)-1), 1<<20)
+
+		assert.Equal(t, http.StatusRequestEntityTooLarge, recorder.Code)
+	})
+}
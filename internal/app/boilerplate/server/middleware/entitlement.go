@@ -0,0 +1,63 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/apierror"
+	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/logger"
+	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/payments"
+)
+
+// EntitlementDetails represents details of the payment required error.
+type EntitlementDetails struct {
+	// Entitlement is entitlement the caller's subscription lacks.
+	Entitlement string `json:"entitlement"`
+}
+
+// Entitlement is a middleware that rejects callers whose subscriptions do not grant the entitlement
+// of the gated path with 402, it must run after JWTAuth that stores the user ID in context.
+func Entitlement(payments *payments.Payments, logger *logger.Logger) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+			entitlement, gated := payments.Gate(request.URL.Path)
+			if !gated {
+				next.ServeHTTP(writer, request)
+
+				return
+			}
+
+			var entitled bool
+
+			if userID, _ := request.Context().Value(UserIDKey).(string); userID != "" {
+				var err error
+
+				entitled, err = payments.HasEntitlement(request.Context(), userID, entitlement)
+				if err != nil {
+					logger.Ctx(request.Context()).Error().Err(err).Str("entitlement", entitlement).
+						Msg("failed to look up entitlements")
+
+					// error is ignored since nothing else can be written to the client
+					_ = apierror.Write(writer, http.StatusInternalServerError, &apierror.Response{
+						Error: http.StatusText(http.StatusInternalServerError),
+						Code:  apierror.CodeInternal,
+					})
+
+					return
+				}
+			}
+
+			if !entitled {
+				// error is ignored since nothing else can be written to the client
+				_ = apierror.Write(writer, http.StatusPaymentRequired, &apierror.Response{
+					Error:   http.StatusText(http.StatusPaymentRequired),
+					Code:    apierror.CodePaymentRequired,
+					Details: &EntitlementDetails{Entitlement: entitlement},
+				})
+
+				return
+			}
+
+			next.ServeHTTP(writer, request)
+		})
+	}
+}
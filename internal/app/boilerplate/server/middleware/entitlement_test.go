@@ -0,0 +1,136 @@
+package middleware
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pocj8ur4in/boilerplate-go/internal/gen/db"
+	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/apierror"
+	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/payments"
+)
+
+var errSubscriptionsUnavailable = errors.New("subscriptions unavailable")
+
+// mockSubscriptionQuerier is a mock querier serving subscriptions of users from memory.
+type mockSubscriptionQuerier struct {
+	db.Querier
+
+	subscriptions map[string][]*db.BillingSubscription
+	err           error
+}
+
+func (m *mockSubscriptionQuerier) ListBillingSubscriptionsByUserID(
+	_ context.Context,
+	userID string,
+) ([]*db.BillingSubscription, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+
+	return m.subscriptions[userID], nil
+}
+
+func TestEntitlement(t *testing.T) {
+	t.Parallel()
+
+	// serve serves the request of the user through Entitlement gating /reports on the reports entitlement.
+	serve := func(t *testing.T, querier db.Querier, userID, path string) *httptest.ResponseRecorder {
+		t.Helper()
+
+		service, err := payments.NewWithQuerier(&payments.Config{
+			Enabled:       &[]bool{true}[0],
+			WebhookSecret: &[]string{"whsec_test"}[0],
+			Plans:         map[string][]string{"price_pro": {"reports"}},
+			Gates:         []*payments.Gate{{PathPrefix: "/reports", Entitlement: "reports"}},
+		}, nil, querier, setupTestRedis(t), setupTestLogger(t))
+		require.NoError(t, err)
+
+		request := httptest.NewRequest(http.MethodGet, path, nil)
+		if userID != "" {
+			request = request.WithContext(context.WithValue(request.Context(), UserIDKey, userID))
+		}
+
+		recorder := httptest.NewRecorder()
+		Entitlement(service, setupTestLogger(t))(testHandler(http.StatusOK, "success")).ServeHTTP(recorder, request)
+
+		return recorder
+	}
+
+	// uniqueUserID returns a user ID not cached by other tests.
+	uniqueUserID := func(t *testing.T) string {
+		t.Helper()
+
+		return fmt.Sprintf("%s-%d", t.Name(), time.Now().UnixNano())
+	}
+
+	t.Run("allow paths which are not gated", func(t *testing.T) {
+		t.Parallel()
+
+		recorder := serve(t, &mockSubscriptionQuerier{err: errSubscriptionsUnavailable}, "", "/users")
+
+		assert.Equal(t, http.StatusOK, recorder.Code)
+	})
+
+	t.Run("allow user with active subscription", func(t *testing.T) {
+		t.Parallel()
+
+		userID := uniqueUserID(t)
+		querier := &mockSubscriptionQuerier{subscriptions: map[string][]*db.BillingSubscription{
+			userID: {{ID: "sub_1", Status: "active", PriceIds: []string{"price_pro"}}},
+		}}
+
+		recorder := serve(t, querier, userID, "/reports/daily")
+
+		assert.Equal(t, http.StatusOK, recorder.Code)
+		assert.Equal(t, "success", recorder.Body.String())
+	})
+
+	t.Run("reject user without entitlement", func(t *testing.T) {
+		t.Parallel()
+
+		userID := uniqueUserID(t)
+		querier := &mockSubscriptionQuerier{subscriptions: map[string][]*db.BillingSubscription{
+			userID: {{ID: "sub_1", Status: "canceled", PriceIds: []string{"price_pro"}}},
+		}}
+
+		recorder := serve(t, querier, userID, "/reports")
+
+		assert.Equal(t, http.StatusPaymentRequired, recorder.Code)
+
+		var response struct {
+			Code    apierror.Code      `json:"code"`
+			Details EntitlementDetails `json:"details"`
+		}
+
+		require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &response))
+		assert.Equal(t, apierror.CodePaymentRequired, response.Code)
+		assert.Equal(t, "reports", response.Details.Entitlement)
+	})
+
+	t.Run("reject anonymous request", func(t *testing.T) {
+		t.Parallel()
+
+		recorder := serve(t, &mockSubscriptionQuerier{}, "", "/reports")
+
+		assert.Equal(t, http.StatusPaymentRequired, recorder.Code)
+	})
+
+	t.Run("return internal error if entitlements are unavailable", func(t *testing.T) {
+		t.Parallel()
+
+		querier := &mockSubscriptionQuerier{err: errSubscriptionsUnavailable}
+
+		recorder := serve(t, querier, uniqueUserID(t), "/reports")
+
+		assert.Equal(t, http.StatusInternalServerError, recorder.Code)
+	})
+}
@@ -0,0 +1,174 @@
+package middleware
+
+import (
+	"mime"
+	"net/http"
+
+	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/apierror"
+)
+
+const (
+	// mediaTypeURLEncoded is media type of urlencoded form bodies.
+	mediaTypeURLEncoded = "application/x-www-form-urlencoded"
+
+	// mediaTypeMultipart is media type of multipart form bodies.
+	mediaTypeMultipart = "multipart/form-data"
+)
+
+// FormLimitConfig represents configuration for form parsing limits,
+// separate from the maximum request size applied to other bodies such as JSON.
+type FormLimitConfig struct {
+	// MaxURLEncodedSize is maximum size of urlencoded form bodies in bytes.
+	MaxURLEncodedSize *int64 `json:"max_urlencoded_size"`
+
+	// MaxMultipartSize is maximum size of multipart form bodies in bytes, including uploaded files.
+	MaxMultipartSize *int64 `json:"max_multipart_size"`
+
+	// MaxMemory is maximum bytes of multipart form bodies kept in memory, the rest of files is stored on disk.
+	MaxMemory *int64 `json:"max_memory"`
+
+	// MaxValues is maximum number of form values.
+	MaxValues *int `json:"max_values"`
+
+	// MaxFiles is maximum number of files of multipart form bodies.
+	MaxFiles *int `json:"max_files"`
+}
+
+// SetDefault sets default values.
+func (c *FormLimitConfig) SetDefault() {
+	if c.MaxURLEncodedSize == nil {
+		c.MaxURLEncodedSize = &[]int64{1048576}[0] // 1MB
+	}
+
+	if c.MaxMultipartSize == nil {
+		c.MaxMultipartSize = &[]int64{33554432}[0] // 32MB
+	}
+
+	if c.MaxMemory == nil {
+		c.MaxMemory = &[]int64{8388608}[0] // 8MB
+	}
+
+	if c.MaxValues == nil {
+		c.MaxValues = &[]int{1000}[0]
+	}
+
+	if c.MaxFiles == nil {
+		c.MaxFiles = &[]int{10}[0]
+	}
+}
+
+// FormLimitDetails represents details of the too many form values or files error.
+type FormLimitDetails struct {
+	// MaxValues is maximum number of form values.
+	MaxValues int `json:"max_values,omitempty"`
+
+	// MaxFiles is maximum number of form files.
+	MaxFiles int `json:"max_files,omitempty"`
+}
+
+// FormLimit is a middleware that limits form bodies by their own size limits and parses them
+// eagerly to enforce value and file counts, while other bodies are limited to maxRequestSize.
+func FormLimit(config *FormLimitConfig, maxRequestSize int64) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		limitRequest := RequestSize(maxRequestSize)(next)
+		limitURLEncoded := RequestSize(*config.MaxURLEncodedSize)(parseURLEncodedForm(config, next))
+		limitMultipart := RequestSize(*config.MaxMultipartSize)(parseMultipartForm(config, next))
+
+		return http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+			mediaType, _, _ := mime.ParseMediaType(request.Header.Get("Content-Type"))
+
+			switch mediaType {
+			case mediaTypeURLEncoded:
+				limitURLEncoded.ServeHTTP(writer, request)
+			case mediaTypeMultipart:
+				limitMultipart.ServeHTTP(writer, request)
+			default:
+				limitRequest.ServeHTTP(writer, request)
+			}
+		})
+	}
+}
+
+// parseURLEncodedForm returns a handler parsing urlencoded form body before the next handler.
+func parseURLEncodedForm(config *FormLimitConfig, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		// response is replaced with the request too large error if body exceeded the limit
+		if err := request.ParseForm(); err != nil {
+			writeInvalidForm(writer)
+
+			return
+		}
+
+		if countValues(request.PostForm) > *config.MaxValues {
+			writeFormTooLarge(writer, "Too many form values", &FormLimitDetails{MaxValues: *config.MaxValues})
+
+			return
+		}
+
+		next.ServeHTTP(writer, request)
+	})
+}
+
+// parseMultipartForm returns a handler parsing multipart form body before the next handler,
+// removing files stored on disk once the next handler returns.
+func parseMultipartForm(config *FormLimitConfig, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		// response is replaced with the request too large error if body exceeded the limit
+		if err := request.ParseMultipartForm(*config.MaxMemory); err != nil {
+			writeInvalidForm(writer)
+
+			return
+		}
+
+		// error is ignored since temporary files are removed by the system eventually
+		defer func() { _ = request.MultipartForm.RemoveAll() }()
+
+		if countValues(request.MultipartForm.Value) > *config.MaxValues {
+			writeFormTooLarge(writer, "Too many form values", &FormLimitDetails{MaxValues: *config.MaxValues})
+
+			return
+		}
+
+		files := 0
+		for _, headers := range request.MultipartForm.File {
+			files += len(headers)
+		}
+
+		if files > *config.MaxFiles {
+			writeFormTooLarge(writer, "Too many form files", &FormLimitDetails{MaxFiles: *config.MaxFiles})
+
+			return
+		}
+
+		next.ServeHTTP(writer, request)
+	})
+}
+
+// countValues returns the number of values of all form fields.
+func countValues(values map[string][]string) int {
+	count := 0
+	for _, value := range values {
+		count += len(value)
+	}
+
+	return count
+}
+
+// writeInvalidForm writes the invalid form error response.
+func writeInvalidForm(writer http.ResponseWriter) {
+	// error is ignored since nothing else can be written to the client
+	_ = apierror.Write(writer, http.StatusBadRequest, &apierror.Response{
+		Error: "invalid form body",
+		Code:  apierror.CodeInvalidRequest,
+	})
+}
+
+// writeFormTooLarge writes the too many form values or files error response.
+func writeFormTooLarge(writer http.ResponseWriter, message string, details *FormLimitDetails) {
+	// error is ignored since nothing else can be written to the client
+	_ = apierror.Write(writer, http.StatusRequestEntityTooLarge, &apierror.Response{
+		Error:   message,
+		Code:    apierror.CodeRequestTooLarge,
+		Details: details,
+	})
+}
@@ -0,0 +1,228 @@
+package middleware
+
+import (
+	"bytes"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestFormLimitConfig creates a form limit config with small limits.
+func newTestFormLimitConfig() *FormLimitConfig {
+	config := &FormLimitConfig{
+		MaxURLEncodedSize: &[]int64{64}[0],
+		MaxMultipartSize:  &[]int64{1024}[0],
+		MaxValues:         &[]int{2}[0],
+		MaxFiles:          &[]int{1}[0],
+	}
+	config.SetDefault()
+
+	return config
+}
+
+// formHandler is a handler that responds with the number of parsed form values and files.
+func formHandler(values, files *int) http.Handler {
+	return http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		*values = len(request.PostForm)
+
+		if request.MultipartForm != nil {
+			*files = len(request.MultipartForm.File)
+		}
+
+		writer.WriteHeader(http.StatusOK)
+	})
+}
+
+// newMultipartRequest creates a multipart form request with the fields and files of the size.
+func newMultipartRequest(t *testing.T, fields, files, fileSize int) *http.Request {
+	t.Helper()
+
+	var body bytes.Buffer
+
+	formWriter := multipart.NewWriter(&body)
+
+	for i := range fields {
+		require.NoError(t, formWriter.WriteField("field"+string(rune('a'+i)), "value"))
+	}
+
+	for i := range files {
+		part, err := formWriter.CreateFormFile("file"+string(rune('a'+i)), "file.txt")
+		require.NoError(t, err)
+
+		_, err = part.Write(bytes.Repeat([]byte("x"), fileSize))
+		require.NoError(t, err)
+	}
+
+	require.NoError(t, formWriter.Close())
+
+	request := httptest.NewRequest(http.MethodPost, "/test", &body)
+	request.Header.Set("Content-Type", formWriter.FormDataContentType())
+
+	return request
+}
+
+// newURLEncodedRequest creates a urlencoded form request of the values.
+func newURLEncodedRequest(values url.Values) *http.Request {
+	request := httptest.NewRequest(http.MethodPost, "/test", strings.NewReader(values.Encode()))
+	request.Header.Set("Content-Type", mediaTypeURLEncoded+"; charset=utf-8")
+
+	return request
+}
+
+func TestFormLimitConfigSetDefault(t *testing.T) {
+	t.Parallel()
+
+	t.Run("set default values", func(t *testing.T) {
+		t.Parallel()
+
+		config := &FormLimitConfig{}
+		config.SetDefault()
+
+		assert.Equal(t, int64(1048576), *config.MaxURLEncodedSize)
+		assert.Equal(t, int64(33554432), *config.MaxMultipartSize)
+		assert.Equal(t, int64(8388608), *config.MaxMemory)
+		assert.Equal(t, 1000, *config.MaxValues)
+		assert.Equal(t, 10, *config.MaxFiles)
+	})
+}
+
+func TestFormLimit(t *testing.T) {
+	t.Parallel()
+
+	t.Run("parse urlencoded form within limits", func(t *testing.T) {
+		t.Parallel()
+
+		var values, files int
+
+		recorder := httptest.NewRecorder()
+		FormLimit(newTestFormLimitConfig(), 10)(formHandler(&values, &files)).ServeHTTP(
+			recorder, newURLEncodedRequest(url.Values{"a": {"1"}, "b": {"2"}}),
+		)
+
+		assert.Equal(t, http.StatusOK, recorder.Code)
+		assert.Equal(t, 2, values)
+	})
+
+	t.Run("reject urlencoded form exceeding its size limit", func(t *testing.T) {
+		t.Parallel()
+
+		var values, files int
+
+		recorder := httptest.NewRecorder()
+		FormLimit(newTestFormLimitConfig(), 1024)(formHandler(&values, &files)).ServeHTTP(
+			recorder, newURLEncodedRequest(url.Values{"a": {strings.Repeat("x", 128)}}),
+		)
+
+		assert.Equal(t, http.StatusRequestEntityTooLarge, recorder.Code)
+		assert.JSONEq(t,
+			`{"error":"Request body too large","code":"request_too_large","details":{"limit":64}}`,
+			recorder.Body.String(),
+		)
+	})
+
+	t.Run("reject urlencoded form with too many values", func(t *testing.T) {
+		t.Parallel()
+
+		var values, files int
+
+		recorder := httptest.NewRecorder()
+		FormLimit(newTestFormLimitConfig(), 10)(formHandler(&values, &files)).ServeHTTP(
+			recorder, newURLEncodedRequest(url.Values{"a": {"1", "2"}, "b": {"3"}}),
+		)
+
+		assert.Equal(t, http.StatusRequestEntityTooLarge, recorder.Code)
+		assert.JSONEq(t,
+			`{"error":"Too many form values","code":"request_too_large","details":{"max_values":2}}`,
+			recorder.Body.String(),
+		)
+	})
+
+	t.Run("reject malformed urlencoded form", func(t *testing.T) {
+		t.Parallel()
+
+		var values, files int
+
+		request := httptest.NewRequest(http.MethodPost, "/test", strings.NewReader("a=%zz"))
+		request.Header.Set("Content-Type", mediaTypeURLEncoded)
+
+		recorder := httptest.NewRecorder()
+		FormLimit(newTestFormLimitConfig(), 10)(formHandler(&values, &files)).ServeHTTP(recorder, request)
+
+		assert.Equal(t, http.StatusBadRequest, recorder.Code)
+		assert.Contains(t, recorder.Body.String(), `"code":"invalid_request"`)
+	})
+
+	t.Run("parse multipart form larger than maximum request size", func(t *testing.T) {
+		t.Parallel()
+
+		var values, files int
+
+		recorder := httptest.NewRecorder()
+		FormLimit(newTestFormLimitConfig(), 10)(formHandler(&values, &files)).ServeHTTP(
+			recorder, newMultipartRequest(t, 2, 1, 256),
+		)
+
+		assert.Equal(t, http.StatusOK, recorder.Code)
+		assert.Equal(t, 2, values)
+		assert.Equal(t, 1, files)
+	})
+
+	t.Run("reject multipart form exceeding its size limit", func(t *testing.T) {
+		t.Parallel()
+
+		var values, files int
+
+		recorder := httptest.NewRecorder()
+		FormLimit(newTestFormLimitConfig(), 1<<20)(formHandler(&values, &files)).ServeHTTP(
+			recorder, newMultipartRequest(t, 0, 1, 2048),
+		)
+
+		assert.Equal(t, http.StatusRequestEntityTooLarge, recorder.Code)
+		assert.Contains(t, recorder.Body.String(), `"limit":1024`)
+	})
+
+	t.Run("reject multipart form with too many values or files", func(t *testing.T) {
+		t.Parallel()
+
+		for _, test := range []struct {
+			fields   int
+			files    int
+			expected string
+		}{
+			{fields: 3, expected: `{"error":"Too many form values","code":"request_too_large","details":{"max_values":2}}`},
+			{files: 2, expected: `{"error":"Too many form files","code":"request_too_large","details":{"max_files":1}}`},
+		} {
+			var values, files int
+
+			recorder := httptest.NewRecorder()
+			FormLimit(newTestFormLimitConfig(), 10)(formHandler(&values, &files)).ServeHTTP(
+				recorder, newMultipartRequest(t, test.fields, test.files, 8),
+			)
+
+			assert.Equal(t, http.StatusRequestEntityTooLarge, recorder.Code)
+			assert.JSONEq(t, test.expected, recorder.Body.String())
+		}
+	})
+
+	t.Run("limit other bodies to maximum request size", func(t *testing.T) {
+		t.Parallel()
+
+		called := false
+
+		request := httptest.NewRequest(http.MethodPost, "/test", strings.NewReader(`{"key":"value"}`))
+		request.Header.Set("Content-Type", "application/json")
+
+		recorder := httptest.NewRecorder()
+		FormLimit(newTestFormLimitConfig(), 10)(readBodyHandler(&called)).ServeHTTP(recorder, request)
+
+		assert.False(t, called)
+		assert.Equal(t, http.StatusRequestEntityTooLarge, recorder.Code)
+		assert.Contains(t, recorder.Body.String(), `"limit":10`)
+	})
+}
@@ -0,0 +1,86 @@
+package middleware
+
+import (
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// InFlight counts requests being processed so that shutdown can report how many were drained.
+type InFlight struct {
+	// active is number of requests being processed.
+	active atomic.Int64
+
+	// completed is number of processed requests.
+	completed atomic.Int64
+
+	// mu guards started and next.
+	mu sync.Mutex
+
+	// started is start time of requests being processed by their sequence number.
+	started map[uint64]time.Time
+
+	// next is sequence number of the next request.
+	next uint64
+}
+
+// NewInFlight creates a new in-flight request counter.
+func NewInFlight() *InFlight {
+	return &InFlight{started: map[uint64]time.Time{}}
+}
+
+// Middleware is a middleware that counts the request while it is processed.
+func (f *InFlight) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		f.active.Add(1)
+
+		f.mu.Lock()
+		sequence := f.next
+		f.next++
+		f.started[sequence] = time.Now()
+		f.mu.Unlock()
+
+		// count the request as completed even if the handler panics
+		defer func() {
+			f.mu.Lock()
+			delete(f.started, sequence)
+			f.mu.Unlock()
+
+			f.active.Add(-1)
+			f.completed.Add(1)
+		}()
+
+		next.ServeHTTP(writer, request)
+	})
+}
+
+// Active returns number of requests being processed.
+func (f *InFlight) Active() int64 {
+	return f.active.Load()
+}
+
+// Completed returns number of processed requests.
+func (f *InFlight) Completed() int64 {
+	return f.completed.Load()
+}
+
+// OldestAge returns time the oldest request being processed has run for, 0 if no request is processed.
+func (f *InFlight) OldestAge() time.Duration {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	var oldest time.Time
+
+	for _, started := range f.started {
+		if oldest.IsZero() || started.Before(oldest) {
+			oldest = started
+		}
+	}
+
+	if oldest.IsZero() {
+		return 0
+	}
+
+	return time.Since(oldest)
+}
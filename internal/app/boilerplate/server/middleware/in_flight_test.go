@@ -0,0 +1,72 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInFlight(t *testing.T) {
+	t.Parallel()
+
+	t.Run("count active and completed requests", func(t *testing.T) {
+		t.Parallel()
+
+		inFlight := NewInFlight()
+
+		var activeDuringRequest int64
+
+		handler := inFlight.Middleware(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			activeDuringRequest = inFlight.Active()
+
+			w.WriteHeader(http.StatusOK)
+		}))
+
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+
+		assert.Equal(t, int64(1), activeDuringRequest)
+		assert.Equal(t, int64(0), inFlight.Active())
+		assert.Equal(t, int64(1), inFlight.Completed())
+	})
+
+	t.Run("count panicking requests as completed", func(t *testing.T) {
+		t.Parallel()
+
+		inFlight := NewInFlight()
+
+		handler := inFlight.Middleware(http.HandlerFunc(func(_ http.ResponseWriter, _ *http.Request) {
+			panic("test panic")
+		}))
+
+		assert.Panics(t, func() {
+			handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+		})
+
+		assert.Equal(t, int64(0), inFlight.Active())
+		assert.Equal(t, int64(1), inFlight.Completed())
+	})
+	t.Run("report age of oldest request", func(t *testing.T) {
+		t.Parallel()
+
+		inFlight := NewInFlight()
+		assert.Zero(t, inFlight.OldestAge())
+
+		var ageDuringRequest time.Duration
+
+		handler := inFlight.Middleware(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			time.Sleep(10 * time.Millisecond)
+
+			ageDuringRequest = inFlight.OldestAge()
+
+			w.WriteHeader(http.StatusOK)
+		}))
+
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+
+		assert.GreaterOrEqual(t, ageDuringRequest, 10*time.Millisecond)
+		assert.Zero(t, inFlight.OldestAge())
+	})
+}
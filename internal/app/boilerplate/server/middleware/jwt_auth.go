@@ -27,15 +27,26 @@ const (
 	ClaimsKey ContextKey = "claims"
 )
 
+// RequireBearerAuth is a middleware that marks routes outside the OpenAPI spec as requiring bearer authentication,
+// so that JWTAuth enforces a valid token on them.
+func RequireBearerAuth(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		ctx := context.WithValue(request.Context(), api.BearerAuthScopes, []string{})
+
+		next.ServeHTTP(writer, request.WithContext(ctx))
+	})
+}
+
 // JWTAuth is a middleware that validates JWT tokens based on OpenAPI spec security requirements.
 func JWTAuth(jwt *jwt.JWT, logger *logger.Logger) func(next http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
 			_, requiresAuth := request.Context().Value(api.BearerAuthScopes).([]string)
+			authenticated := request.Context().Value(ClaimsKey) != nil
 
-			// if endpoint doesn't require auth, skip
-			if !requiresAuth {
-				logger.Debug().Str("path", request.URL.Path).Msg("endpoint does not require authentication")
+			// if endpoint doesn't require auth or another scheme authenticated the request, skip
+			if !requiresAuth || authenticated {
+				logger.Ctx(request.Context()).Debug().Str("path", request.URL.Path).Msg("endpoint does not require authentication")
 				next.ServeHTTP(writer, request)
 
 				return
@@ -44,16 +55,16 @@ func JWTAuth(jwt *jwt.JWT, logger *logger.Logger) func(next http.Handler) http.H
 			// extract token from Authorization header
 			authHeader := request.Header.Get("Authorization")
 			if authHeader == "" {
-				logger.Debug().Msg("missing authorization header")
-				http.Error(writer, "Unauthorized", http.StatusUnauthorized)
+				logger.Ctx(request.Context()).Debug().Msg("missing authorization header")
+				writeUnauthorized(writer)
 
 				return
 			}
 
 			// check if token starts with "Bearer "
 			if !strings.HasPrefix(authHeader, "Bearer ") {
-				logger.Debug().Str("auth_header", authHeader).Msg("invalid authorization header format")
-				http.Error(writer, "Unauthorized", http.StatusUnauthorized)
+				logger.Ctx(request.Context()).Debug().Str("auth_header", authHeader).Msg("invalid authorization header format")
+				writeUnauthorized(writer)
 
 				return
 			}
@@ -61,8 +72,8 @@ func JWTAuth(jwt *jwt.JWT, logger *logger.Logger) func(next http.Handler) http.H
 			// extract token
 			tokenString := strings.TrimPrefix(authHeader, "Bearer ")
 			if tokenString == "" {
-				logger.Debug().Msg("empty token")
-				http.Error(writer, "Unauthorized", http.StatusUnauthorized)
+				logger.Ctx(request.Context()).Debug().Msg("empty token")
+				writeUnauthorized(writer)
 
 				return
 			}
@@ -70,8 +81,8 @@ func JWTAuth(jwt *jwt.JWT, logger *logger.Logger) func(next http.Handler) http.H
 			// validate token
 			claims, err := jwt.ValidateToken(tokenString)
 			if err != nil {
-				logger.Debug().Err(err).Msg("token validation failed")
-				http.Error(writer, "Unauthorized", http.StatusUnauthorized)
+				logger.Ctx(request.Context()).Debug().Err(err).Msg("token validation failed")
+				writeUnauthorized(writer)
 
 				return
 			}
@@ -81,6 +92,8 @@ func JWTAuth(jwt *jwt.JWT, logger *logger.Logger) func(next http.Handler) http.H
 			ctx = context.WithValue(ctx, UserEmailKey, claims.Email)
 			ctx = context.WithValue(ctx, UserRoleKey, claims.Role)
 			ctx = context.WithValue(ctx, ClaimsKey, claims)
+			logUserID(ctx, claims.UserID)
+			setTenantID(ctx, claims.TenantID)
 
 			// create new request with updated context
 			request = request.WithContext(ctx)
@@ -105,6 +105,7 @@ func TestJWTAuth(t *testing.T) {
 		handler.ServeHTTP(recorder, req)
 
 		assert.Equal(t, http.StatusUnauthorized, recorder.Code)
+		assert.JSONEq(t, `{"error":"Unauthorized","code":"unauthorized"}`, recorder.Body.String())
 	})
 
 	t.Run("reject request with invalid authorization header format", func(t *testing.T) {
@@ -392,8 +393,8 @@ func TestJWTAuthMiddlewareChaining(t *testing.T) {
 
 		token := generateTestToken(t, jwtService, "user123", "test@example.com", "user")
 
-		handler := RequestID(
-			SecurityHeaders()(
+		handler := newTestRequestID(t)(
+			SecurityHeaders(true)(
 				JWTAuth(jwtService, log)(
 					testHandler(http.StatusOK, "success"),
 				),
@@ -1,13 +1,22 @@
 package middleware
 
 import (
+	"errors"
+	"fmt"
 	"net/http"
 	"strconv"
+	"sync"
 	"time"
+	"unicode/utf8"
 
+	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
 	"github.com/prometheus/client_golang/prometheus"
-	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/version"
 )
 
 const (
@@ -19,6 +28,16 @@ const (
 
 	// bucketCount is the count for the bucket.
 	bucketCount = 8
+
+	// otherTenant is the tenant label of tenants outside the allowlist.
+	otherTenant = "other"
+
+	// otherPath is the path label of requests matching no route nor allowlisted path, and of paths beyond the
+	// maximum number of paths.
+	otherPath = "other"
+
+	// defaultMaxPaths is default maximum number of path labels.
+	defaultMaxPaths = 1000
 )
 
 // metricsCollector holds all prometheus metrics collectors.
@@ -49,6 +68,34 @@ type MetricsConfig struct {
 
 	// ExcludePaths is a list of paths to exclude from metrics.
 	ExcludePaths []string `json:"exclude_paths"`
+
+	// OpenMetrics is whether the OpenMetrics format, carrying exemplars, is served to scrapers accepting it.
+	OpenMetrics *bool `json:"open_metrics"`
+
+	// CreatedSamples is whether created timestamps of counters, histograms and summaries are served
+	// in the OpenMetrics format.
+	CreatedSamples *bool `json:"created_samples"`
+
+	// Tenants is allowlist of tenants labeled on request metrics, other tenants are labeled as other
+	// so that the number of series is bounded, empty to label no tenant.
+	Tenants []string `json:"tenants"`
+
+	// PathAllowlist is paths labeled as requested on requests matching no route pattern, such as paths served
+	// outside the router, other requests matching no route (e.g. 404s of scanners) are labeled as other.
+	PathAllowlist []string `json:"path_allowlist"`
+
+	// MaxPaths is maximum number of path labels, requests of further paths are labeled as other so that the
+	// number of series is bounded.
+	MaxPaths *int `json:"max_paths"`
+
+	// GoRuntime is whether metrics of the go runtime, such as GC, goroutines and memory statistics, are exposed.
+	GoRuntime *bool `json:"go_runtime"`
+
+	// Process is whether metrics of the process, such as CPU time, memory and open file descriptors, are exposed.
+	Process *bool `json:"process"`
+
+	// BuildInfo is whether the build_info gauge labeled with the version and commit of the build is exposed.
+	BuildInfo *bool `json:"build_info"`
 }
 
 // SetDefault sets default values.
@@ -62,55 +109,176 @@ func (c *MetricsConfig) SetDefault() {
 	}
 
 	if c.ExcludePaths == nil {
-		c.ExcludePaths = []string{"/health", "/status"}
+		c.ExcludePaths = []string{"/health", "/live", "/ready", "/status"}
+	}
+
+	if c.OpenMetrics == nil {
+		c.OpenMetrics = &[]bool{true}[0]
+	}
+
+	if c.CreatedSamples == nil {
+		c.CreatedSamples = &[]bool{true}[0]
+	}
+
+	if c.Tenants == nil {
+		c.Tenants = []string{}
+	}
+
+	if c.PathAllowlist == nil {
+		c.PathAllowlist = []string{}
+	}
+
+	if c.MaxPaths == nil {
+		c.MaxPaths = &[]int{defaultMaxPaths}[0]
+	}
+
+	if c.GoRuntime == nil {
+		c.GoRuntime = &[]bool{true}[0]
+	}
+
+	if c.Process == nil {
+		c.Process = &[]bool{true}[0]
+	}
+
+	if c.BuildInfo == nil {
+		c.BuildInfo = &[]bool{true}[0]
 	}
 }
 
-// newMetricsCollector creates a new metrics collector.
-func newMetricsCollector(registry prometheus.Registerer) *metricsCollector {
-	return &metricsCollector{
-		requestsTotal: promauto.With(registry).NewCounterVec(
-			prometheus.CounterOpts{
-				Name: "http_requests_total",
-				Help: "Total number of HTTP requests",
-			},
-			[]string{"method", "path", "status"},
-		),
-		requestDuration: promauto.With(registry).NewHistogramVec(
-			prometheus.HistogramOpts{
-				Name:    "http_request_duration_seconds",
-				Help:    "Duration of HTTP requests in seconds",
-				Buckets: prometheus.DefBuckets,
-			},
-			[]string{"method", "path", "status"},
-		),
-		requestSize: promauto.With(registry).NewHistogramVec(
-			prometheus.HistogramOpts{
-				Name:    "http_request_size_bytes",
-				Help:    "Size of HTTP requests in bytes",
-				Buckets: prometheus.ExponentialBuckets(bucketStart, bucketFactor, bucketCount),
-			},
-			[]string{"method", "path"},
-		),
-		responseSize: promauto.With(registry).NewHistogramVec(
-			prometheus.HistogramOpts{
-				Name:    "http_response_size_bytes",
-				Help:    "Size of HTTP responses in bytes",
-				Buckets: prometheus.ExponentialBuckets(bucketStart, bucketFactor, bucketCount),
-			},
-			[]string{"method", "path", "status"},
-		),
-		requestsInFlight: promauto.With(registry).NewGauge(
-			prometheus.GaugeOpts{
-				Name: "http_requests_in_flight",
-				Help: "Number of HTTP requests currently being processed",
+// HandlerOpts returns options of the metrics endpoint, negotiating the OpenMetrics format by the Accept header.
+func (c *MetricsConfig) HandlerOpts() promhttp.HandlerOpts {
+	return promhttp.HandlerOpts{
+		EnableOpenMetrics:                   *c.OpenMetrics,
+		EnableOpenMetricsTextCreatedSamples: *c.OpenMetrics && *c.CreatedSamples,
+	}
+}
+
+// RuntimeCollectors returns collectors of the go runtime, the process and the build enabled by the config.
+func (c *MetricsConfig) RuntimeCollectors() []prometheus.Collector {
+	var runtimeCollectors []prometheus.Collector
+
+	if *c.GoRuntime {
+		runtimeCollectors = append(runtimeCollectors, collectors.NewGoCollector())
+	}
+
+	if *c.Process {
+		runtimeCollectors = append(runtimeCollectors, collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}))
+	}
+
+	if *c.BuildInfo {
+		info := version.Get()
+
+		buildInfo := prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "build_info",
+			Help: "Build information of the service, always 1.",
+			ConstLabels: prometheus.Labels{
+				"name":       info.Name,
+				"version":    info.Version,
+				"commit":     info.Commit,
+				"build_time": info.BuildTime,
+				"go_version": info.GoVersion,
 			},
-		),
+		})
+		buildInfo.Set(1)
+
+		runtimeCollectors = append(runtimeCollectors, buildInfo)
+	}
+
+	return runtimeCollectors
+}
+
+// newMetricsCollector creates a new metrics collector, reusing collectors already registered on the registry
+// so that multiple middlewares sharing a registry record into the same metrics.
+func newMetricsCollector(registry prometheus.Registerer) (*metricsCollector, error) {
+	requestsTotal, err := registerCollector(registry, prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "http_requests_total",
+			Help: "Total number of HTTP requests",
+		},
+		[]string{"method", "path", "status", "tenant"},
+	))
+	if err != nil {
+		return nil, err
+	}
+
+	requestDuration, err := registerCollector(registry, prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "http_request_duration_seconds",
+			Help:    "Duration of HTTP requests in seconds",
+			Buckets: prometheus.DefBuckets,
+		},
+		[]string{"method", "path", "status", "tenant"},
+	))
+	if err != nil {
+		return nil, err
+	}
+
+	requestSize, err := registerCollector(registry, prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "http_request_size_bytes",
+			Help:    "Size of HTTP requests in bytes",
+			Buckets: prometheus.ExponentialBuckets(bucketStart, bucketFactor, bucketCount),
+		},
+		[]string{"method", "path", "tenant"},
+	))
+	if err != nil {
+		return nil, err
+	}
+
+	responseSize, err := registerCollector(registry, prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "http_response_size_bytes",
+			Help:    "Size of HTTP responses in bytes",
+			Buckets: prometheus.ExponentialBuckets(bucketStart, bucketFactor, bucketCount),
+		},
+		[]string{"method", "path", "status", "tenant"},
+	))
+	if err != nil {
+		return nil, err
 	}
+
+	requestsInFlight, err := registerCollector(registry, prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "http_requests_in_flight",
+			Help: "Number of HTTP requests currently being processed",
+		},
+	))
+	if err != nil {
+		return nil, err
+	}
+
+	return &metricsCollector{
+		requestsTotal:    requestsTotal,
+		requestDuration:  requestDuration,
+		requestSize:      requestSize,
+		responseSize:     responseSize,
+		requestsInFlight: requestsInFlight,
+	}, nil
+}
+
+// registerCollector registers the collector on the registry and returns the registered collector.
+// If an equal collector is already registered, the existing one is returned. Other conflicts are
+// returned as errors, so that metrics are not recorded into collectors that are never exported.
+func registerCollector[T prometheus.Collector](registry prometheus.Registerer, collector T) (T, error) {
+	err := registry.Register(collector)
+	if err == nil {
+		return collector, nil
+	}
+
+	var alreadyRegistered prometheus.AlreadyRegisteredError
+	if errors.As(err, &alreadyRegistered) {
+		if existing, ok := alreadyRegistered.ExistingCollector.(T); ok {
+			return existing, nil
+		}
+	}
+
+	var unregistered T
+
+	return unregistered, fmt.Errorf("failed to register metrics collector: %w", err)
 }
 
 // Metrics is a middleware that collects Prometheus metrics.
-func Metrics(config *MetricsConfig, registry prometheus.Registerer) func(next http.Handler) http.Handler {
+func Metrics(config *MetricsConfig, registry prometheus.Registerer) (func(next http.Handler) http.Handler, error) {
 	// set default config
 	if config == nil {
 		config = &MetricsConfig{}
@@ -124,7 +292,17 @@ func Metrics(config *MetricsConfig, registry prometheus.Registerer) func(next ht
 	}
 
 	// create collector instance for this middleware
-	collector := newMetricsCollector(registry)
+	collector, err := newMetricsCollector(registry)
+	if err != nil {
+		return nil, err
+	}
+
+	tenants := make(map[string]struct{}, len(config.Tenants))
+	for _, tenant := range config.Tenants {
+		tenants[tenant] = struct{}{}
+	}
+
+	paths := newPathLabels(config.PathAllowlist, *config.MaxPaths)
 
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
@@ -134,9 +312,9 @@ func Metrics(config *MetricsConfig, registry prometheus.Registerer) func(next ht
 				return
 			}
 
-			processWithMetrics(next, writer, request, collector)
+			processWithMetrics(next, writer, request, collector, tenants, paths)
 		})
-	}
+	}, nil
 }
 
 // shouldSkipMetrics checks if metrics should be skipped for this request.
@@ -165,26 +343,115 @@ func processWithMetrics(
 	writer http.ResponseWriter,
 	request *http.Request,
 	collector *metricsCollector,
+	tenants map[string]struct{},
+	paths *pathLabels,
 ) {
 	collector.requestsInFlight.Inc()
 	defer collector.requestsInFlight.Dec()
 
-	recordRequestSize(collector, request)
-
 	start := time.Now()
 	wrappedWriter := middleware.NewWrapResponseWriter(writer, request.ProtoMajor)
 
 	next.ServeHTTP(wrappedWriter, request)
 
-	recordRequestMetrics(collector, request, wrappedWriter, time.Since(start))
+	// the route pattern and the tenant of the authenticated principal are known once the request is served
+	path := paths.label(request)
+	tenant := tenantLabel(tenants, request)
+
+	recordRequestSize(collector, request, path, tenant)
+	recordRequestMetrics(collector, request, wrappedWriter, time.Since(start), path, tenant)
+}
+
+// pathLabels provides path labels of requests, bounded in number.
+type pathLabels struct {
+	// allowlist is paths labeled on requests matching no route pattern.
+	allowlist map[string]struct{}
+
+	// maxPaths is maximum number of path labels.
+	maxPaths int
+
+	// mu guards labels.
+	mu sync.Mutex
+
+	// labels is path labels given so far.
+	labels map[string]struct{}
+}
+
+// newPathLabels creates path labels of the allowlist, bounded to the maximum number of paths.
+func newPathLabels(allowlist []string, maxPaths int) *pathLabels {
+	paths := &pathLabels{
+		allowlist: make(map[string]struct{}, len(allowlist)),
+		maxPaths:  maxPaths,
+		labels:    make(map[string]struct{}),
+	}
+
+	for _, path := range allowlist {
+		paths.allowlist[path] = struct{}{}
+	}
+
+	return paths
+}
+
+// label returns the path label of the served request, its route pattern (e.g. /users/{id}) so that paths with
+// IDs share a series, the path if it matches no route and is on the allowlist, and other if neither or once the
+// maximum number of paths is reached.
+func (p *pathLabels) label(request *http.Request) string {
+	var path string
+
+	if routeContext := chi.RouteContext(request.Context()); routeContext != nil {
+		path = routeContext.RoutePattern()
+	}
+
+	if path == "" {
+		if _, ok := p.allowlist[request.URL.Path]; !ok {
+			return otherPath
+		}
+
+		path = request.URL.Path
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if _, ok := p.labels[path]; ok {
+		return path
+	}
+
+	if len(p.labels) >= p.maxPaths {
+		return otherPath
+	}
+
+	p.labels[path] = struct{}{}
+
+	return path
+}
+
+// tenantLabel returns the tenant label of the request, the verified tenant if it is on the allowlist, other if
+// not, and empty without allowlist or verified tenant.
+func tenantLabel(tenants map[string]struct{}, request *http.Request) string {
+	if len(tenants) == 0 {
+		return ""
+	}
+
+	tenantID := TenantIDFromContext(request.Context())
+	if tenantID == "" {
+		return ""
+	}
+
+	if _, ok := tenants[tenantID]; ok {
+		return tenantID
+	}
+
+	return otherTenant
 }
 
 // recordRequestSize records the size of the request.
-func recordRequestSize(collector *metricsCollector, request *http.Request) {
+func recordRequestSize(collector *metricsCollector, request *http.Request, path, tenant string) {
 	if request.ContentLength > 0 {
 		collector.requestSize.WithLabelValues(
 			request.Method,
-			request.URL.Path,
+			path,
+			tenant,
 		).Observe(float64(request.ContentLength))
 	}
 }
@@ -195,26 +462,64 @@ func recordRequestMetrics(
 	request *http.Request,
 	wrappedWriter middleware.WrapResponseWriter,
 	duration time.Duration,
+	path, tenant string,
 ) {
 	status := strconv.Itoa(wrappedWriter.Status())
+	exemplar := requestExemplar(request)
 
-	collector.requestsTotal.WithLabelValues(
-		request.Method,
-		request.URL.Path,
-		status,
-	).Inc()
+	requestsTotal := collector.requestsTotal.WithLabelValues(request.Method, path, status, tenant)
+	if adder, ok := requestsTotal.(prometheus.ExemplarAdder); ok && exemplar != nil {
+		adder.AddWithExemplar(1, exemplar)
+	} else {
+		requestsTotal.Inc()
+	}
 
-	collector.requestDuration.WithLabelValues(
-		request.Method,
-		request.URL.Path,
-		status,
-	).Observe(duration.Seconds())
+	requestDuration := collector.requestDuration.WithLabelValues(request.Method, path, status, tenant)
+	if observer, ok := requestDuration.(prometheus.ExemplarObserver); ok && exemplar != nil {
+		observer.ObserveWithExemplar(duration.Seconds(), exemplar)
+	} else {
+		requestDuration.Observe(duration.Seconds())
+	}
 
 	if wrappedWriter.BytesWritten() > 0 {
 		collector.responseSize.WithLabelValues(
 			request.Method,
-			request.URL.Path,
+			path,
 			status,
+			tenant,
 		).Observe(float64(wrappedWriter.BytesWritten()))
 	}
 }
+
+// requestExemplar returns labels of the exemplar linking metrics of the request to its trace and logs,
+// nil without trace or request ID.
+func requestExemplar(request *http.Request) prometheus.Labels {
+	labels := prometheus.Labels{}
+
+	if spanContext := trace.SpanContextFromContext(request.Context()); spanContext.IsSampled() {
+		labels["trace_id"] = spanContext.TraceID().String()
+	}
+
+	// request IDs may come from clients, exemplars exceeding the limit or of invalid UTF-8 panic
+	requestID := middleware.GetReqID(request.Context())
+	if requestID != "" && utf8.ValidString(requestID) &&
+		exemplarRunes(labels)+utf8.RuneCountInString("request_id"+requestID) <= prometheus.ExemplarMaxRunes {
+		labels["request_id"] = requestID
+	}
+
+	if len(labels) == 0 {
+		return nil
+	}
+
+	return labels
+}
+
+// exemplarRunes returns the number of runes of names and values of the exemplar labels.
+func exemplarRunes(labels prometheus.Labels) int {
+	runes := 0
+	for name, value := range labels {
+		runes += utf8.RuneCountInString(name) + utf8.RuneCountInString(value)
+	}
+
+	return runes
+}
@@ -1,16 +1,35 @@
 package middleware
 
 import (
+	"context"
 	"net/http"
 	"net/http/httptest"
+	"runtime"
 	"strings"
 	"testing"
 
+	"github.com/go-chi/chi/v5"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+
+	"github.com/pocj8ur4in/boilerplate-go/internal/gen/api"
 )
 
+// newTestMetrics creates a metrics middleware of the config on the registry.
+func newTestMetrics(
+	t *testing.T,
+	config *MetricsConfig,
+	registry prometheus.Registerer,
+) func(next http.Handler) http.Handler {
+	t.Helper()
+
+	metrics, err := Metrics(config, registry)
+	require.NoError(t, err)
+
+	return metrics
+}
+
 func TestMetricsConfigSetDefault(t *testing.T) {
 	t.Parallel()
 
@@ -27,7 +46,27 @@ func TestMetricsConfigSetDefault(t *testing.T) {
 		assert.True(t, *config.Enabled)
 		assert.Equal(t, "/metrics", *config.Path)
 		assert.Contains(t, config.ExcludePaths, "/health")
+		assert.Contains(t, config.ExcludePaths, "/ready")
 		assert.Contains(t, config.ExcludePaths, "/status")
+		assert.True(t, *config.OpenMetrics)
+		assert.True(t, *config.CreatedSamples)
+		assert.Empty(t, config.Tenants)
+		assert.Empty(t, config.PathAllowlist)
+		assert.Equal(t, 1000, *config.MaxPaths)
+		assert.True(t, *config.GoRuntime)
+		assert.True(t, *config.Process)
+		assert.True(t, *config.BuildInfo)
+	})
+
+	t.Run("serve created samples only with openmetrics", func(t *testing.T) {
+		t.Parallel()
+
+		config := &MetricsConfig{OpenMetrics: &[]bool{false}[0]}
+		config.SetDefault()
+
+		opts := config.HandlerOpts()
+		assert.False(t, opts.EnableOpenMetrics)
+		assert.False(t, opts.EnableOpenMetricsTextCreatedSamples)
 	})
 
 	t.Run("not override existing values", func(t *testing.T) {
@@ -61,7 +100,7 @@ func TestMetrics(t *testing.T) {
 		registry := prometheus.NewRegistry()
 		config := &MetricsConfig{}
 
-		handler := Metrics(config, registry)(testHandler(http.StatusOK, "success"))
+		handler := newTestMetrics(t, config, registry)(testHandler(http.StatusOK, "success"))
 
 		req := httptest.NewRequest(http.MethodGet, "/test", nil)
 		recorder := httptest.NewRecorder()
@@ -85,7 +124,7 @@ func TestMetrics(t *testing.T) {
 			ExcludePaths: []string{"/health"},
 		}
 
-		handler := Metrics(config, registry)(testHandler(http.StatusOK, "success"))
+		handler := newTestMetrics(t, config, registry)(testHandler(http.StatusOK, "success"))
 
 		req := httptest.NewRequest(http.MethodGet, "/health", nil)
 		recorder := httptest.NewRecorder()
@@ -101,7 +140,7 @@ func TestMetrics(t *testing.T) {
 		registry := prometheus.NewRegistry()
 		config := &MetricsConfig{}
 
-		handler := Metrics(config, registry)(testHandler(http.StatusOK, "success"))
+		handler := newTestMetrics(t, config, registry)(testHandler(http.StatusOK, "success"))
 
 		req := httptest.NewRequest(http.MethodGet, "/metrics", nil)
 		recorder := httptest.NewRecorder()
@@ -120,7 +159,7 @@ func TestMetrics(t *testing.T) {
 			Enabled: &enabled,
 		}
 
-		handler := Metrics(config, registry)(testHandler(http.StatusOK, "success"))
+		handler := newTestMetrics(t, config, registry)(testHandler(http.StatusOK, "success"))
 
 		req := httptest.NewRequest(http.MethodGet, "/test", nil)
 		recorder := httptest.NewRecorder()
@@ -134,7 +173,7 @@ func TestMetrics(t *testing.T) {
 		t.Parallel()
 
 		registry := prometheus.NewRegistry()
-		handler := Metrics(nil, registry)(testHandler(http.StatusOK, "success"))
+		handler := newTestMetrics(t, nil, registry)(testHandler(http.StatusOK, "success"))
 
 		req := httptest.NewRequest(http.MethodGet, "/test", nil)
 		recorder := httptest.NewRecorder()
@@ -148,7 +187,7 @@ func TestMetrics(t *testing.T) {
 		t.Parallel()
 
 		config := &MetricsConfig{}
-		handler := Metrics(config, nil)(testHandler(http.StatusOK, "success"))
+		handler := newTestMetrics(t, config, nil)(testHandler(http.StatusOK, "success"))
 
 		req := httptest.NewRequest(http.MethodGet, "/test", nil)
 		recorder := httptest.NewRecorder()
@@ -159,6 +198,231 @@ func TestMetrics(t *testing.T) {
 	})
 }
 
+func TestMetricsConfigRuntimeCollectors(t *testing.T) {
+	t.Parallel()
+
+	// names returns names of metric families gathered from the collectors.
+	names := func(t *testing.T, config *MetricsConfig) []string {
+		t.Helper()
+
+		config.SetDefault()
+
+		registry := prometheus.NewRegistry()
+		for _, collector := range config.RuntimeCollectors() {
+			require.NoError(t, registry.Register(collector))
+		}
+
+		families, err := registry.Gather()
+		require.NoError(t, err)
+
+		names := make([]string, 0, len(families))
+		for _, family := range families {
+			names = append(names, family.GetName())
+		}
+
+		return names
+	}
+
+	t.Run("collect runtime, process and build metrics by default", func(t *testing.T) {
+		t.Parallel()
+
+		collected := names(t, &MetricsConfig{})
+		assert.Contains(t, collected, "go_goroutines")
+		assert.Contains(t, collected, "go_gc_duration_seconds")
+		assert.Contains(t, collected, "go_memstats_alloc_bytes")
+		assert.Contains(t, collected, "build_info")
+
+		if runtime.GOOS == "linux" {
+			assert.Contains(t, collected, "process_resident_memory_bytes")
+		}
+	})
+
+	t.Run("collect nothing when disabled", func(t *testing.T) {
+		t.Parallel()
+
+		disabled := &[]bool{false}[0]
+
+		assert.Empty(t, names(t, &MetricsConfig{GoRuntime: disabled, Process: disabled, BuildInfo: disabled}))
+	})
+
+	t.Run("label build information", func(t *testing.T) {
+		t.Parallel()
+
+		config := &MetricsConfig{GoRuntime: &[]bool{false}[0], Process: &[]bool{false}[0]}
+		config.SetDefault()
+
+		registry := prometheus.NewRegistry()
+		for _, collector := range config.RuntimeCollectors() {
+			require.NoError(t, registry.Register(collector))
+		}
+
+		families, err := registry.Gather()
+		require.NoError(t, err)
+		require.Len(t, families, 1)
+
+		labels := make(map[string]string)
+		for _, label := range families[0].GetMetric()[0].GetLabel() {
+			labels[label.GetName()] = label.GetValue()
+		}
+
+		assert.Equal(t, "boilerplate", labels["name"])
+		assert.Equal(t, runtime.Version(), labels["go_version"])
+		assert.InDelta(t, 1, families[0].GetMetric()[0].GetGauge().GetValue(), 0)
+	})
+}
+
+func TestMetricsTenantLabel(t *testing.T) {
+	t.Parallel()
+
+	// requests counts requests by tenant label after serving requests of the tenants prepared by the function.
+	requests := func(
+		t *testing.T, allowlist []string, prepare func(*http.Request, string) *http.Request, tenants ...string,
+	) map[string]float64 {
+		t.Helper()
+
+		registry := prometheus.NewRegistry()
+		handler := Tenant("X-Tenant-ID", newTestResolver(t))(
+			newTestMetrics(t, &MetricsConfig{Tenants: allowlist}, registry)(
+				JWTAuth(setupTestJWT(t), setupTestLogger(t))(testHandler(http.StatusOK, "success")),
+			),
+		)
+
+		for _, tenant := range tenants {
+			handler.ServeHTTP(httptest.NewRecorder(), prepare(httptest.NewRequest(http.MethodGet, "/test", nil), tenant))
+		}
+
+		families, err := registry.Gather()
+		require.NoError(t, err)
+
+		counts := make(map[string]float64)
+
+		for _, family := range families {
+			if family.GetName() != "http_requests_total" {
+				continue
+			}
+
+			for _, metric := range family.GetMetric() {
+				for _, label := range metric.GetLabel() {
+					if label.GetName() == "tenant" {
+						counts[label.GetValue()] += metric.GetCounter().GetValue()
+					}
+				}
+			}
+		}
+
+		return counts
+	}
+
+	// fromPeer returns a function setting the tenant header on requests of the peer.
+	fromPeer := func(remoteAddr string) func(*http.Request, string) *http.Request {
+		return func(request *http.Request, tenant string) *http.Request {
+			request.RemoteAddr = remoteAddr
+			request.Header.Set("X-Tenant-ID", tenant)
+
+			return request
+		}
+	}
+
+	t.Run("label allowlisted tenants and group others", func(t *testing.T) {
+		t.Parallel()
+
+		counts := requests(t, []string{"tenant-a"}, fromPeer(testTrustedProxy), "tenant-a", "tenant-b", "tenant-c", "")
+		assert.Equal(t, map[string]float64{"tenant-a": 1, "other": 2, "": 1}, counts)
+	})
+
+	t.Run("label no tenant without allowlist", func(t *testing.T) {
+		t.Parallel()
+
+		counts := requests(t, nil, fromPeer(testTrustedProxy), "tenant-a", "tenant-b")
+		assert.Equal(t, map[string]float64{"": 2}, counts)
+	})
+
+	t.Run("label no tenant of untrusted peer", func(t *testing.T) {
+		t.Parallel()
+
+		counts := requests(t, []string{"tenant-a"}, fromPeer("192.0.2.1:1234"), "tenant-a", "tenant-b")
+		assert.Equal(t, map[string]float64{"": 2}, counts)
+	})
+
+	t.Run("label tenant of authenticated token", func(t *testing.T) {
+		t.Parallel()
+
+		withToken := func(request *http.Request, tenant string) *http.Request {
+			request.Header.Set("Authorization", "Bearer "+generateTestTenantToken(t, tenant))
+
+			return request.WithContext(context.WithValue(request.Context(), api.BearerAuthScopes, []string{}))
+		}
+
+		counts := requests(t, []string{"tenant-a"}, withToken, "tenant-a", "tenant-b")
+		assert.Equal(t, map[string]float64{"tenant-a": 1, "other": 1}, counts)
+	})
+}
+
+func TestMetricsPathLabel(t *testing.T) {
+	t.Parallel()
+
+	// requests counts requests by path label after serving requests of the paths.
+	requests := func(t *testing.T, config *MetricsConfig, paths ...string) map[string]float64 {
+		t.Helper()
+
+		registry := prometheus.NewRegistry()
+
+		router := chi.NewRouter()
+		router.Use(newTestMetrics(t, config, registry))
+		router.Get("/users/{id}", testHandler(http.StatusOK, "success"))
+		router.Route("/admin", func(router chi.Router) {
+			router.Get("/jobs/{id}", testHandler(http.StatusOK, "success"))
+		})
+
+		for _, path := range paths {
+			router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+		}
+
+		families, err := registry.Gather()
+		require.NoError(t, err)
+
+		counts := make(map[string]float64)
+
+		for _, family := range families {
+			if family.GetName() != "http_requests_total" {
+				continue
+			}
+
+			for _, metric := range family.GetMetric() {
+				for _, label := range metric.GetLabel() {
+					if label.GetName() == "path" {
+						counts[label.GetValue()] += metric.GetCounter().GetValue()
+					}
+				}
+			}
+		}
+
+		return counts
+	}
+
+	t.Run("label route patterns instead of paths", func(t *testing.T) {
+		t.Parallel()
+
+		counts := requests(t, &MetricsConfig{}, "/users/1", "/users/2", "/admin/jobs/3")
+		assert.Equal(t, map[string]float64{"/users/{id}": 2, "/admin/jobs/{id}": 1}, counts)
+	})
+
+	t.Run("label unmatched paths as other unless allowlisted", func(t *testing.T) {
+		t.Parallel()
+
+		counts := requests(t, &MetricsConfig{PathAllowlist: []string{"/favicon.ico"}},
+			"/wp-login.php", "/.env", "/favicon.ico")
+		assert.Equal(t, map[string]float64{"other": 2, "/favicon.ico": 1}, counts)
+	})
+
+	t.Run("label paths beyond maximum as other", func(t *testing.T) {
+		t.Parallel()
+
+		counts := requests(t, &MetricsConfig{MaxPaths: &[]int{1}[0]}, "/users/1", "/admin/jobs/2", "/users/3")
+		assert.Equal(t, map[string]float64{"/users/{id}": 2, "other": 1}, counts)
+	})
+}
+
 func TestMetricsWithDifferentStatusCodes(t *testing.T) {
 	t.Parallel()
 
@@ -179,7 +443,7 @@ func TestMetricsWithDifferentStatusCodes(t *testing.T) {
 			registry := prometheus.NewRegistry()
 			config := &MetricsConfig{}
 
-			handler := Metrics(config, registry)(testHandler(statusCode, "response"))
+			handler := newTestMetrics(t, config, registry)(testHandler(statusCode, "response"))
 
 			req := httptest.NewRequest(http.MethodGet, "/test", nil)
 			recorder := httptest.NewRecorder()
@@ -209,7 +473,7 @@ func TestMetricsWithDifferentMethods(t *testing.T) {
 			registry := prometheus.NewRegistry()
 			config := &MetricsConfig{}
 
-			handler := Metrics(config, registry)(testHandler(http.StatusOK, "success"))
+			handler := newTestMetrics(t, config, registry)(testHandler(http.StatusOK, "success"))
 
 			req := httptest.NewRequest(method, "/test", nil)
 			recorder := httptest.NewRecorder()
@@ -230,7 +494,7 @@ func TestMetricsWithRequestBody(t *testing.T) {
 		registry := prometheus.NewRegistry()
 		config := &MetricsConfig{}
 
-		handler := Metrics(config, registry)(testHandler(http.StatusOK, "success"))
+		handler := newTestMetrics(t, config, registry)(testHandler(http.StatusOK, "success"))
 
 		body := strings.NewReader(`{"key": "value"}`)
 		req := httptest.NewRequest(http.MethodPost, "/test", body)
@@ -253,7 +517,7 @@ func TestMetricsWithRequestBody(t *testing.T) {
 		registry := prometheus.NewRegistry()
 		config := &MetricsConfig{}
 
-		handler := Metrics(config, registry)(testHandler(http.StatusOK, "success"))
+		handler := newTestMetrics(t, config, registry)(testHandler(http.StatusOK, "success"))
 
 		req := httptest.NewRequest(http.MethodGet, "/test", nil)
 		recorder := httptest.NewRecorder()
@@ -271,7 +535,8 @@ func TestMetricsCollectorCreation(t *testing.T) {
 		t.Parallel()
 
 		registry := prometheus.NewRegistry()
-		collector := newMetricsCollector(registry)
+		collector, err := newMetricsCollector(registry)
+		require.NoError(t, err)
 
 		require.NotNil(t, collector)
 		require.NotNil(t, collector.requestsTotal)
@@ -282,6 +547,65 @@ func TestMetricsCollectorCreation(t *testing.T) {
 	})
 }
 
+func TestMetricsCollectorRegistration(t *testing.T) {
+	t.Parallel()
+
+	t.Run("reuse collectors already registered on registry", func(t *testing.T) {
+		t.Parallel()
+
+		registry := prometheus.NewRegistry()
+
+		first, err := newMetricsCollector(registry)
+		require.NoError(t, err)
+
+		second, err := newMetricsCollector(registry)
+		require.NoError(t, err)
+
+		assert.Same(t, first.requestsTotal, second.requestsTotal)
+		assert.Same(t, first.requestDuration, second.requestDuration)
+		assert.Equal(t, first.requestsInFlight, second.requestsInFlight)
+	})
+
+	t.Run("create multiple middlewares on the same registry", func(t *testing.T) {
+		t.Parallel()
+
+		registry := prometheus.NewRegistry()
+
+		for range 2 {
+			_, err := Metrics(nil, registry)
+			require.NoError(t, err)
+		}
+	})
+
+	t.Run("return error on conflicting collector", func(t *testing.T) {
+		t.Parallel()
+
+		registry := prometheus.NewRegistry()
+		registry.MustRegister(prometheus.NewCounterVec(
+			prometheus.CounterOpts{Name: "http_requests_total", Help: "conflicting"},
+			[]string{"other"},
+		))
+
+		_, err := newMetricsCollector(registry)
+		require.Error(t, err)
+
+		_, err = Metrics(nil, registry)
+		require.Error(t, err)
+	})
+
+	t.Run("return error on collector of another type", func(t *testing.T) {
+		t.Parallel()
+
+		registry := prometheus.NewRegistry()
+		registry.MustRegister(prometheus.NewGauge(
+			prometheus.GaugeOpts{Name: "http_requests_total", Help: "Total number of HTTP requests"},
+		))
+
+		_, err := newMetricsCollector(registry)
+		require.Error(t, err)
+	})
+}
+
 func TestShouldSkipMetrics(t *testing.T) {
 	t.Parallel()
 
@@ -345,9 +669,9 @@ func TestMetricsMiddlewareChaining(t *testing.T) {
 		registry := prometheus.NewRegistry()
 		config := &MetricsConfig{}
 
-		handler := RequestID(
-			SecurityHeaders()(
-				Metrics(config, registry)(
+		handler := newTestRequestID(t)(
+			SecurityHeaders(true)(
+				newTestMetrics(t, config, registry)(
 					testHandler(http.StatusOK, "success"),
 				),
 			),
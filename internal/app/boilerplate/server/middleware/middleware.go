@@ -2,22 +2,29 @@
 package middleware
 
 import (
+	"context"
+	"errors"
 	"net/http"
+	"strings"
 	"time"
 
 	"github.com/go-chi/chi/v5/middleware"
 
+	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/apierror"
 	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/logger"
+	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/netutil"
 )
 
-// RequestID is a middleware that adds a request ID to the request.
-func RequestID(next http.Handler) http.Handler {
-	return middleware.RequestID(next)
-}
+// RealIP is a middleware that sets RemoteAddr of the request to the client IP resolved from forwarded headers of
+// trusted proxies, so that rate limits and logs use it.
+func RealIP(resolver *netutil.Resolver) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+			request.RemoteAddr = resolver.ClientIP(request)
 
-// RealIP is a middleware that adds the real IP address to the request.
-func RealIP(next http.Handler) http.Handler {
-	return middleware.RealIP(next)
+			next.ServeHTTP(writer, request)
+		})
+	}
 }
 
 // Recoverer is a middleware that recovers from panics.
@@ -25,8 +32,9 @@ func Recoverer(next http.Handler) http.Handler {
 	return middleware.Recoverer(next)
 }
 
-// SecurityHeaders is a middleware that adds security headers to responses.
-func SecurityHeaders() func(next http.Handler) http.Handler {
+// SecurityHeaders is a middleware that adds security headers to responses,
+// Strict-Transport-Security is only set if hsts is enabled.
+func SecurityHeaders(hsts bool) func(next http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
 			// prevent MIME type sniffing
@@ -39,7 +47,9 @@ func SecurityHeaders() func(next http.Handler) http.Handler {
 			writer.Header().Set("X-XSS-Protection", "1; mode=block")
 
 			// force HTTPS (adjust max-age as needed)
-			writer.Header().Set("Strict-Transport-Security", "max-age=31536000; includeSubDomains; preload")
+			if hsts {
+				writer.Header().Set("Strict-Transport-Security", "max-age=31536000; includeSubDomains; preload")
+			}
 
 			// control referrer information
 			writer.Header().Set("Referrer-Policy", "strict-origin-when-cross-origin")
@@ -55,11 +65,6 @@ func SecurityHeaders() func(next http.Handler) http.Handler {
 	}
 }
 
-// RequestSize is a middleware that sets a maximum request size.
-func RequestSize(maxBytes int64) func(next http.Handler) http.Handler {
-	return middleware.RequestSize(maxBytes)
-}
-
 // LogRequest is a middleware that logs HTTP requests.
 func LogRequest(logger *logger.Logger) func(next http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
@@ -72,29 +77,50 @@ func LogRequest(logger *logger.Logger) func(next http.Handler) http.Handler {
 			// process request
 			next.ServeHTTP(wrappedWriter, request)
 
-			// set log request
-			log := logger.Debug().
+			// set log request, the request-scoped logger carries correlation IDs
+			log := logger.Ctx(request.Context()).Debug().
 				Str("method", request.Method).
 				Str("path", request.URL.Path).
 				Str("remote_addr", request.RemoteAddr).
+				Str("client_ip", netutil.ClientIP(request)).
 				Str("user_agent", request.UserAgent()).
 				Int("status", wrappedWriter.Status()).
 				Int("bytes", wrappedWriter.BytesWritten()).
 				Dur("duration", time.Since(start))
 
-			// set request ID on log
-			if requestID := request.Context().Value(middleware.RequestIDKey); requestID != nil {
-				if id, ok := requestID.(string); ok {
-					log = log.Str("request_id", id)
-				}
-			}
-
 			log.Msg("http request")
 		})
 	}
 }
 
-// Timeout is a middleware that sets a timeout for the request.
+// Timeout is a middleware that sets a timeout for the request,
+// responding with the timeout error envelope if the handler returns after the deadline without a response.
+// Requests for event streams are not timed out, since streams stay open until the client disconnects.
 func Timeout(timeout time.Duration) func(next http.Handler) http.Handler {
-	return middleware.Timeout(timeout)
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+			if strings.Contains(request.Header.Get("Accept"), "text/event-stream") {
+				next.ServeHTTP(writer, request)
+
+				return
+			}
+
+			ctx, cancel := context.WithTimeout(request.Context(), timeout)
+			defer cancel()
+
+			wrappedWriter := middleware.NewWrapResponseWriter(writer, request.ProtoMajor)
+
+			next.ServeHTTP(wrappedWriter, request.WithContext(ctx))
+
+			if !errors.Is(ctx.Err(), context.DeadlineExceeded) || wrappedWriter.Status() != 0 {
+				return
+			}
+
+			// error is ignored since the client may have gone away
+			_ = apierror.Write(writer, http.StatusGatewayTimeout, &apierror.Response{
+				Error: "Request timed out",
+				Code:  apierror.CodeTimeout,
+			})
+		})
+	}
 }
@@ -10,11 +10,11 @@ import (
 	"testing"
 	"time"
 
-	"github.com/go-chi/chi/v5/middleware"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
 	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/logger"
+	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/netutil"
 )
 
 // testHandler is a simple handler that returns 200 OK.
@@ -25,77 +25,42 @@ func testHandler(statusCode int, message string) http.HandlerFunc {
 	}
 }
 
-func TestRequestID(t *testing.T) {
+func TestRealIP(t *testing.T) {
 	t.Parallel()
 
-	t.Run("add request ID to request", func(t *testing.T) {
-		t.Parallel()
-
-		handler := RequestID(testHandler(http.StatusOK, "test"))
+	// serve returns RemoteAddr seen by the handler behind RealIP trusting the proxies.
+	serve := func(t *testing.T, trustedProxies []string, remoteAddr, forwardedFor string) string {
+		t.Helper()
 
-		req := httptest.NewRequest(http.MethodGet, "/test", nil)
-		recorder := httptest.NewRecorder()
-
-		handler.ServeHTTP(recorder, req)
-
-		assert.Equal(t, http.StatusOK, recorder.Code)
-	})
-
-	t.Run("preserve existing request ID", func(t *testing.T) {
-		t.Parallel()
+		resolver, err := netutil.NewResolver(trustedProxies)
+		require.NoError(t, err)
 
-		var capturedID string
+		var seen string
 
-		handler := RequestID(http.HandlerFunc(func(_ http.ResponseWriter, request *http.Request) {
-			if id := request.Context().Value(middleware.RequestIDKey); id != nil {
-				if idStr, ok := id.(string); ok {
-					capturedID = idStr
-				}
-			}
+		handler := RealIP(resolver)(http.HandlerFunc(func(_ http.ResponseWriter, request *http.Request) {
+			seen = request.RemoteAddr
 		}))
 
 		req := httptest.NewRequest(http.MethodGet, "/test", nil)
-		req.Header.Set("X-Request-Id", "test-request-id")
+		req.RemoteAddr = remoteAddr
+		req.Header.Set("X-Forwarded-For", forwardedFor)
 
-		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(httptest.NewRecorder(), req)
 
-		handler.ServeHTTP(recorder, req)
-
-		assert.NotEmpty(t, capturedID)
-	})
-}
-
-func TestRealIP(t *testing.T) {
-	t.Parallel()
+		return seen
+	}
 
-	t.Run("extract real IP from request", func(t *testing.T) {
+	t.Run("use forwarded client of trusted proxies", func(t *testing.T) {
 		t.Parallel()
 
-		handler := RealIP(testHandler(http.StatusOK, "test"))
-
-		req := httptest.NewRequest(http.MethodGet, "/test", nil)
-		req.Header.Set("X-Real-IP", "192.168.1.1")
-
-		recorder := httptest.NewRecorder()
-
-		handler.ServeHTTP(recorder, req)
-
-		assert.Equal(t, http.StatusOK, recorder.Code)
+		assert.Equal(t, "203.0.113.1", serve(t, []string{"10.0.0.0/8"}, "10.0.0.1:1234", "203.0.113.1"))
 	})
 
-	t.Run("handle X-Forwarded-For header", func(t *testing.T) {
+	t.Run("ignore forwarded headers of untrusted peers", func(t *testing.T) {
 		t.Parallel()
 
-		handler := RealIP(testHandler(http.StatusOK, "test"))
-
-		req := httptest.NewRequest(http.MethodGet, "/test", nil)
-		req.Header.Set("X-Forwarded-For", "10.0.0.1, 192.168.1.1")
-
-		recorder := httptest.NewRecorder()
-
-		handler.ServeHTTP(recorder, req)
-
-		assert.Equal(t, http.StatusOK, recorder.Code)
+		assert.Equal(t, "192.0.2.1", serve(t, []string{"10.0.0.0/8"}, "192.0.2.1:1234", "203.0.113.1"))
+		assert.Equal(t, "10.0.0.1", serve(t, nil, "10.0.0.1:1234", "203.0.113.1"))
 	})
 }
 
@@ -141,7 +106,7 @@ func TestSecurityHeaders(t *testing.T) {
 	t.Run("add all security headers", func(t *testing.T) {
 		t.Parallel()
 
-		handler := SecurityHeaders()(testHandler(http.StatusOK, "test"))
+		handler := SecurityHeaders(true)(testHandler(http.StatusOK, "test"))
 
 		req := httptest.NewRequest(http.MethodGet, "/test", nil)
 		recorder := httptest.NewRecorder()
@@ -160,6 +125,18 @@ func TestSecurityHeaders(t *testing.T) {
 			recorder.Header().Get("Permissions-Policy"))
 	})
 
+	t.Run("omit HSTS header when disabled", func(t *testing.T) {
+		t.Parallel()
+
+		handler := SecurityHeaders(false)(testHandler(http.StatusOK, "test"))
+
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/test", nil))
+
+		assert.Empty(t, recorder.Header().Get("Strict-Transport-Security"))
+		assert.Equal(t, "nosniff", recorder.Header().Get("X-Content-Type-Options"))
+	})
+
 	t.Run("headers are present for different status codes", func(t *testing.T) {
 		t.Parallel()
 
@@ -172,7 +149,7 @@ func TestSecurityHeaders(t *testing.T) {
 		}
 
 		for _, code := range statusCodes {
-			handler := SecurityHeaders()(testHandler(code, "test"))
+			handler := SecurityHeaders(true)(testHandler(code, "test"))
 
 			req := httptest.NewRequest(http.MethodGet, "/test", nil)
 			recorder := httptest.NewRecorder()
@@ -256,7 +233,7 @@ func TestLogRequest(t *testing.T) {
 		log, err := logger.New(&logger.Config{})
 		require.NoError(t, err)
 
-		handler := RequestID(LogRequest(log)(testHandler(http.StatusOK, "test")))
+		handler := newTestRequestID(t)(LogRequest(log)(testHandler(http.StatusOK, "test")))
 
 		req := httptest.NewRequest(http.MethodGet, "/test", nil)
 		recorder := httptest.NewRecorder()
@@ -365,6 +342,29 @@ func TestTimeout(t *testing.T) {
 		handler.ServeHTTP(recorder, req)
 
 		assert.Equal(t, http.StatusGatewayTimeout, recorder.Code)
+		assert.JSONEq(t, `{"error":"Request timed out","code":"timeout"}`, recorder.Body.String())
+	})
+
+	t.Run("not timeout event streams", func(t *testing.T) {
+		t.Parallel()
+
+		streamHandler := http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+			_, hasDeadline := request.Context().Deadline()
+			assert.False(t, hasDeadline)
+
+			writer.WriteHeader(http.StatusOK)
+		})
+
+		handler := Timeout(50 * time.Millisecond)(streamHandler)
+
+		req := httptest.NewRequest(http.MethodGet, "/events", nil)
+		req.Header.Set("Accept", "text/event-stream")
+
+		recorder := httptest.NewRecorder()
+
+		handler.ServeHTTP(recorder, req)
+
+		assert.Equal(t, http.StatusOK, recorder.Code)
 	})
 }
 
@@ -377,10 +377,13 @@ func TestMiddlewareChaining(t *testing.T) {
 		log, err := logger.New(&logger.Config{})
 		require.NoError(t, err)
 
-		handler := RequestID(
-			RealIP(
+		resolver, err := netutil.NewResolver(nil)
+		require.NoError(t, err)
+
+		handler := newTestRequestID(t)(
+			RealIP(resolver)(
 				Recoverer(
-					SecurityHeaders()(
+					SecurityHeaders(true)(
 						LogRequest(log)(
 							testHandler(http.StatusOK, "success"),
 						),
@@ -433,7 +436,7 @@ func TestMiddlewareWithContext(t *testing.T) {
 
 		const testKey contextKey = "test"
 
-		handler := RequestID(http.HandlerFunc(func(_ http.ResponseWriter, request *http.Request) {
+		handler := newTestRequestID(t)(http.HandlerFunc(func(_ http.ResponseWriter, request *http.Request) {
 			// verify context value is preserved
 			if val := request.Context().Value(testKey); val != nil {
 				if strVal, ok := val.(string); ok {
@@ -4,11 +4,19 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"math"
 	"net/http"
 	"strconv"
 	"time"
 
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/apierror"
 	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/logger"
+	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/netutil"
 	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/redis"
 )
 
@@ -24,6 +32,14 @@ var (
 
 	// ErrFailedToParseResult returned when the rate limit script result is failed to parse.
 	ErrFailedToParseResult = errors.New("failed to parse rate limit script result")
+
+	// ErrInvalidRateLimitHeaders returned when the rate limit headers mode is unknown.
+	ErrInvalidRateLimitHeaders = errors.New("invalid rate limit headers mode")
+)
+
+const (
+	// tracerName is the name of the tracer used for middleware spans.
+	tracerName = "github.com/pocj8ur4in/boilerplate-go/internal/app/boilerplate/server/middleware"
 )
 
 // RateLimitType represents the type of rate limiting.
@@ -38,8 +54,42 @@ const (
 
 	// RateLimitTypeEndpoint limits requests per endpoint.
 	RateLimitTypeEndpoint RateLimitType = "endpoint"
+
+	// RateLimitTypeTenant limits requests per tenant.
+	RateLimitTypeTenant RateLimitType = "tenant"
+
+	// RateLimitTypeAPIKey limits requests per API key.
+	RateLimitTypeAPIKey RateLimitType = "api_key"
+
+	// RateLimitTypeUser limits requests per authenticated user, per IP address for unauthenticated requests.
+	RateLimitTypeUser RateLimitType = "user"
 )
 
+// RateLimitHeaders represents which rate limit headers are set on responses.
+type RateLimitHeaders string
+
+const (
+	// RateLimitHeadersLegacy sets the X-Ratelimit-Limit, X-Ratelimit-Remaining and X-Ratelimit-Reset headers.
+	RateLimitHeadersLegacy RateLimitHeaders = "legacy"
+
+	// RateLimitHeadersDraft sets the IETF draft RateLimit-Limit, RateLimit-Remaining, RateLimit-Reset
+	// and RateLimit-Policy headers.
+	RateLimitHeadersDraft RateLimitHeaders = "draft"
+
+	// RateLimitHeadersBoth sets both legacy and draft headers.
+	RateLimitHeadersBoth RateLimitHeaders = "both"
+)
+
+// Validate validates the rate limit headers mode.
+func (h RateLimitHeaders) Validate() error {
+	switch h {
+	case RateLimitHeadersLegacy, RateLimitHeadersDraft, RateLimitHeadersBoth:
+		return nil
+	default:
+		return fmt.Errorf("%w: %s", ErrInvalidRateLimitHeaders, h)
+	}
+}
+
 // RateLimitConfig represents configuration for rate limiting.
 type RateLimitConfig struct {
 	// Global is global rate limit configuration.
@@ -50,6 +100,24 @@ type RateLimitConfig struct {
 
 	// Endpoint is endpoint-based rate limit configuration.
 	Endpoint *RateLimitTypeConfig `json:"endpoint"`
+
+	// Tenant is tenant-based rate limit configuration, used for tenants without a stored limit.
+	Tenant *RateLimitTypeConfig `json:"tenant"`
+
+	// User is user-based rate limit configuration, keyed by the authenticated user ID.
+	User *RateLimitTypeConfig `json:"user"`
+
+	// TenantCacheTTL is the TTL in seconds of cached per-tenant limits.
+	TenantCacheTTL *int `json:"tenant_cache_ttl"`
+
+	// Headers is which rate limit headers are set on responses (legacy, draft, both).
+	Headers *RateLimitHeaders `json:"headers"`
+
+	// FailureMode is how requests are limited when redis is unavailable (local, fail_open, fail_closed).
+	FailureMode *RateLimitFailureMode `json:"failure_mode"`
+
+	// Exemptions is requests exempt from rate limits and endpoints with their own limits.
+	Exemptions *RateLimitExemptionsConfig `json:"exemptions"`
 }
 
 // RateLimitTypeConfig represents configuration for a specific rate limit type.
@@ -62,36 +130,105 @@ type RateLimitTypeConfig struct {
 
 	// Window is the time window for rate limiting in seconds.
 	Window *int `json:"window"`
+
+	// Algorithm is the algorithm counting requests (fixed_window, sliding_window, token_bucket).
+	Algorithm *RateLimitAlgorithm `json:"algorithm"`
+}
+
+// Validate validates the rate limit configuration, it expects default values to be set.
+func (c *RateLimitConfig) Validate() error {
+	if err := c.Headers.Validate(); err != nil {
+		return err
+	}
+
+	if err := c.FailureMode.Validate(); err != nil {
+		return err
+	}
+
+	if err := c.Exemptions.Validate(); err != nil {
+		return err
+	}
+
+	for _, limit := range []*RateLimitTypeConfig{c.Global, c.IP, c.Endpoint, c.Tenant, c.User} {
+		if err := limit.Validate(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Validate validates the rate limit type configuration, it expects default values to be set.
+func (c *RateLimitTypeConfig) Validate() error {
+	return c.Algorithm.Validate()
+}
+
+// RateLimitDetails represents details of the error response returned when a rate limit is exceeded.
+type RateLimitDetails struct {
+	// Limit is the maximum number of requests allowed in the window.
+	Limit int `json:"limit"`
+
+	// Remaining is the number of requests remaining in the window.
+	Remaining int `json:"remaining"`
+
+	// Reset is the unix timestamp when the window resets.
+	Reset int64 `json:"reset"`
+
+	// Type is the type of the exceeded rate limit.
+	Type RateLimitType `json:"type"`
 }
 
 // GlobalRateLimit is a middleware that limits the rate of requests globally.
 func GlobalRateLimit(
 	requests int,
 	window time.Duration,
+	algorithm RateLimitAlgorithm,
+	headers RateLimitHeaders,
 	redis *redis.Redis,
+	fallback *RateLimitFallback,
 	logger *logger.Logger,
 ) func(next http.Handler) http.Handler {
-	return rateLimit(RateLimitTypeGlobal, requests, window, redis, logger)
+	return rateLimit(RateLimitTypeGlobal, requests, window, algorithm, headers, redis, fallback, logger)
 }
 
 // IPRateLimit is a middleware that limits the rate of requests per IP address.
 func IPRateLimit(
 	requests int,
 	window time.Duration,
+	algorithm RateLimitAlgorithm,
+	headers RateLimitHeaders,
 	redis *redis.Redis,
+	fallback *RateLimitFallback,
 	logger *logger.Logger,
 ) func(next http.Handler) http.Handler {
-	return rateLimit(RateLimitTypeIP, requests, window, redis, logger)
+	return rateLimit(RateLimitTypeIP, requests, window, algorithm, headers, redis, fallback, logger)
 }
 
 // EndpointRateLimit is a middleware that limits the rate of requests per endpoint.
 func EndpointRateLimit(
 	requests int,
 	window time.Duration,
+	algorithm RateLimitAlgorithm,
+	headers RateLimitHeaders,
+	redis *redis.Redis,
+	fallback *RateLimitFallback,
+	logger *logger.Logger,
+) func(next http.Handler) http.Handler {
+	return rateLimit(RateLimitTypeEndpoint, requests, window, algorithm, headers, redis, fallback, logger)
+}
+
+// UserRateLimit is a middleware that limits the rate of requests per authenticated user,
+// it must run after authentication and limits unauthenticated requests per IP address.
+func UserRateLimit(
+	requests int,
+	window time.Duration,
+	algorithm RateLimitAlgorithm,
+	headers RateLimitHeaders,
 	redis *redis.Redis,
+	fallback *RateLimitFallback,
 	logger *logger.Logger,
 ) func(next http.Handler) http.Handler {
-	return rateLimit(RateLimitTypeEndpoint, requests, window, redis, logger)
+	return rateLimit(RateLimitTypeUser, requests, window, algorithm, headers, redis, fallback, logger)
 }
 
 // rateLimit is a common function for limiting the rate of requests.
@@ -99,7 +236,10 @@ func rateLimit(
 	limitType RateLimitType,
 	requests int,
 	window time.Duration,
+	algorithm RateLimitAlgorithm,
+	headers RateLimitHeaders,
 	redis *redis.Redis,
+	fallback *RateLimitFallback,
 	logger *logger.Logger,
 ) func(next http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
@@ -107,72 +247,230 @@ func rateLimit(
 			// generate key
 			key, err := generateRateLimitKey(limitType, request)
 			if err != nil {
-				logger.Error().Err(err).Msg("rate limit key generation failed")
+				logger.Ctx(request.Context()).Error().Err(err).Msg("rate limit key generation failed")
 				next.ServeHTTP(writer, request)
 
 				return
 			}
 
-			// check rate limit
-			allowed, current, remaining, resetTime, err := checkRateLimit(
-				request.Context(),
-				redis,
-				*key,
-				requests,
-				window,
-			)
-			if err != nil {
-				logger.Error().Err(err).Str("key", *key).Msg("rate limit check failed")
-				next.ServeHTTP(writer, request)
+			limitRequests, limitWindow := requests, window
+			if override := rateLimitOverride(request.Context(), limitType); override != nil {
+				limitRequests, limitWindow = override.overrideLimit()
+			}
 
+			if !enforceRateLimit(
+				writer, request, redis, fallback, logger, limitType, headers, *key, limitRequests, limitWindow, algorithm,
+			) {
 				return
 			}
 
-			// set rate limit headers
-			writer.Header().Set("X-Ratelimit-Limit", strconv.Itoa(requests))
-			writer.Header().Set("X-Ratelimit-Remaining", strconv.Itoa(remaining))
-			writer.Header().Set("X-Ratelimit-Reset", strconv.FormatInt(resetTime.Unix(), 10))
+			next.ServeHTTP(writer, request)
+		})
+	}
+}
 
-			// check if rate limit exceeded
-			if !allowed {
-				logger.Debug().
-					Str("key", *key).
-					Int("current", current).
-					Int("limit", requests).
-					Msg("rate limit exceeded")
+// enforceRateLimit checks the rate limit for the key and sets rate limit headers,
+// limiting the request by the fallback if redis fails, returns false if the request was rejected.
+func enforceRateLimit(
+	writer http.ResponseWriter,
+	request *http.Request,
+	redis *redis.Redis,
+	fallback *RateLimitFallback,
+	logger *logger.Logger,
+	limitType RateLimitType,
+	headers RateLimitHeaders,
+	key string,
+	requests int,
+	window time.Duration,
+	algorithm RateLimitAlgorithm,
+) bool {
+	if isRateLimitExempt(request.Context()) {
+		return true
+	}
 
-				writer.Header().Set("Retry-After", strconv.Itoa(int(window.Seconds())))
-				http.Error(writer, "Rate limit exceeded", http.StatusTooManyRequests)
+	// check rate limit in a child span of the request
+	ctx, span := otel.Tracer(tracerName).Start(request.Context(), "rate_limit.check", trace.WithAttributes(
+		attribute.String("rate_limit.type", string(limitType)),
+		attribute.String("rate_limit.key", key),
+		attribute.Int("rate_limit.limit", requests),
+		attribute.Int("rate_limit.window_seconds", int(window.Seconds())),
+		attribute.String("rate_limit.algorithm", string(algorithm)),
+	))
 
-				return
+	allowed, current, remaining, resetTime, err := checkRateLimit(ctx, redis, algorithm, key, requests, window)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "rate limit check failed")
+		span.SetAttributes(attribute.String("rate_limit.failure_mode", string(fallback.Mode())))
+		span.End()
+
+		logger.Ctx(request.Context()).Error().Err(err).
+			Str("key", key).
+			Str("failure_mode", string(fallback.Mode())).
+			Msg("rate limit check failed")
+
+		fallback.activate(limitType)
+
+		switch fallback.Mode() {
+		case RateLimitFailureModeLocal:
+			allowed, current, remaining, resetTime = fallback.take(key, requests, window)
+		case RateLimitFailureModeClosed:
+			if err := apierror.Write(writer, http.StatusServiceUnavailable, &apierror.Response{
+				Error: "Rate limit unavailable",
+				Code:  apierror.CodeUnavailable,
+			}); err != nil {
+				logger.Ctx(request.Context()).Error().Err(err).Msg("failed to write rate limit response")
 			}
 
-			next.ServeHTTP(writer, request)
-		})
+			return false
+		default:
+			return true
+		}
+	} else {
+		span.SetAttributes(
+			attribute.Bool("rate_limit.allowed", allowed),
+			attribute.Int("rate_limit.remaining", remaining),
+		)
+		span.End()
+	}
+
+	// seconds until the window resets, at least one so clients never retry immediately
+	resetAfter := max(int(math.Ceil(time.Until(resetTime).Seconds())), 1)
+
+	setRateLimitHeaders(writer, headers, requests, remaining, resetTime, resetAfter, window)
+
+	// check if rate limit exceeded
+	if !allowed {
+		logger.Ctx(request.Context()).Debug().
+			Str("key", key).
+			Int("current", current).
+			Int("limit", requests).
+			Msg("rate limit exceeded")
+
+		writer.Header().Set("Retry-After", strconv.Itoa(resetAfter))
+
+		if err := apierror.Write(writer, http.StatusTooManyRequests, &apierror.Response{
+			Error: "Rate limit exceeded",
+			Code:  apierror.CodeRateLimited,
+			Details: &RateLimitDetails{
+				Limit:     requests,
+				Remaining: remaining,
+				Reset:     resetTime.Unix(),
+				Type:      limitType,
+			},
+		}); err != nil {
+			logger.Ctx(request.Context()).Error().Err(err).Msg("failed to write rate limit response")
+		}
+
+		return false
+	}
+
+	return true
+}
+
+// setRateLimitHeaders sets rate limit headers of the mode on response.
+func setRateLimitHeaders(
+	writer http.ResponseWriter,
+	headers RateLimitHeaders,
+	requests int,
+	remaining int,
+	resetTime time.Time,
+	resetAfter int,
+	window time.Duration,
+) {
+	if headers != RateLimitHeadersDraft {
+		writer.Header().Set("X-Ratelimit-Limit", strconv.Itoa(requests))
+		writer.Header().Set("X-Ratelimit-Remaining", strconv.Itoa(remaining))
+		writer.Header().Set("X-Ratelimit-Reset", strconv.FormatInt(resetTime.Unix(), 10))
+	}
+
+	if headers != RateLimitHeadersLegacy {
+		// draft headers use delta seconds for reset instead of a timestamp
+		writer.Header().Set("RateLimit-Limit", strconv.Itoa(requests))
+		writer.Header().Set("RateLimit-Remaining", strconv.Itoa(remaining))
+		writer.Header().Set("RateLimit-Reset", strconv.Itoa(resetAfter))
+		writer.Header().Set("RateLimit-Policy", strconv.Itoa(requests)+";w="+strconv.Itoa(int(window.Seconds())))
 	}
 }
 
-// generateRateLimitKey generates a redis key based on rate limit type.
+// generateRateLimitKey generates a redis key based on rate limit type,
+// counting requests of overridden endpoints separately from the limit they override.
 func generateRateLimitKey(limitType RateLimitType, request *http.Request) (*string, error) {
+	key, err := rateLimitBaseKey(limitType, request)
+	if err != nil {
+		return nil, err
+	}
+
+	if override := rateLimitOverride(request.Context(), limitType); override != nil {
+		key += ":override:" + override.Method + ":" + override.Path
+	}
+
+	return &key, nil
+}
+
+// rateLimitBaseKey returns the redis key of the rate limit type for the request.
+func rateLimitBaseKey(limitType RateLimitType, request *http.Request) (string, error) {
 	switch limitType {
 	case RateLimitTypeGlobal:
-		return &[]string{"rate_limit:global"}[0], nil
+		return "rate_limit:global", nil
 	case RateLimitTypeIP:
-		clientIP := getClientIP(request)
+		clientIP := netutil.ClientIP(request)
 
-		return &[]string{"rate_limit:ip:" + clientIP}[0], nil
+		return "rate_limit:ip:" + clientIP, nil
 	case RateLimitTypeEndpoint:
-		clientIP := getClientIP(request)
+		clientIP := netutil.ClientIP(request)
 		endpoint := request.Method + ":" + request.URL.Path
 
-		return &[]string{"rate_limit:endpoint:" + clientIP + ":" + endpoint}[0], nil
+		return "rate_limit:endpoint:" + clientIP + ":" + endpoint, nil
+	case RateLimitTypeTenant:
+		tenantID := TenantIDFromContext(request.Context())
+		if tenantID == "" {
+			return "", ErrMissingTenant
+		}
+
+		return "rate_limit:tenant:" + tenantID, nil
+	case RateLimitTypeAPIKey:
+		keyID, _ := request.Context().Value(APIKeyIDKey).(string)
+		if keyID == "" {
+			return "", ErrMissingAPIKey
+		}
+
+		return "rate_limit:api_key:" + keyID, nil
+	case RateLimitTypeUser:
+		if userID, _ := request.Context().Value(UserIDKey).(string); userID != "" {
+			return "rate_limit:user:id:" + userID, nil
+		}
+
+		// unauthenticated requests fall back to the client IP
+		clientIP := netutil.ClientIP(request)
+
+		return "rate_limit:user:ip:" + clientIP, nil
 	default:
-		return nil, fmt.Errorf("%w: %s", ErrUnknownRateLimitType, limitType)
+		return "", fmt.Errorf("%w: %s", ErrUnknownRateLimitType, limitType)
 	}
 }
 
-// checkRateLimit checks if the request is allowed based on rate limit.
+// checkRateLimit checks if the request is allowed based on rate limit of the algorithm.
 func checkRateLimit(
+	ctx context.Context,
+	redis *redis.Redis,
+	algorithm RateLimitAlgorithm,
+	key string,
+	limit int,
+	window time.Duration,
+) (bool, int, int, time.Time, error) {
+	switch algorithm {
+	case RateLimitAlgorithmSlidingWindow:
+		return checkSlidingWindow(ctx, redis, key, limit, window)
+	case RateLimitAlgorithmTokenBucket:
+		return checkTokenBucket(ctx, redis, key, limit, window)
+	default:
+		return checkFixedWindow(ctx, redis, key, limit, window)
+	}
+}
+
+// checkFixedWindow checks the rate limit of the key with a fixed window counter.
+func checkFixedWindow(
 	ctx context.Context,
 	redis *redis.Redis,
 	key string,
@@ -232,19 +530,3 @@ func checkRateLimit(
 
 	return allowed, int(current), remaining, resetTime, nil
 }
-
-// getClientIP extracts the client IP address from the request.
-func getClientIP(request *http.Request) string {
-	// check X-Forwarded-For header
-	if xff := request.Header.Get("X-Forwarded-For"); xff != "" {
-		return xff
-	}
-
-	// check X-Real-IP header
-	if xri := request.Header.Get("X-Real-IP"); xri != "" {
-		return xri
-	}
-
-	// use RemoteAddr as fallback
-	return request.RemoteAddr
-}
@@ -0,0 +1,169 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"strconv"
+	"time"
+
+	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/redis"
+)
+
+// ErrInvalidRateLimitAlgorithm returned when the rate limit algorithm is unknown.
+var ErrInvalidRateLimitAlgorithm = errors.New("invalid rate limit algorithm")
+
+// RateLimitAlgorithm represents the algorithm counting requests of a rate limit.
+type RateLimitAlgorithm string
+
+const (
+	// RateLimitAlgorithmFixedWindow counts requests in fixed windows, allowing bursts at window boundaries.
+	RateLimitAlgorithmFixedWindow RateLimitAlgorithm = "fixed_window"
+
+	// RateLimitAlgorithmSlidingWindow counts requests in the window preceding each request.
+	RateLimitAlgorithmSlidingWindow RateLimitAlgorithm = "sliding_window"
+
+	// RateLimitAlgorithmTokenBucket refills a bucket of the limit evenly over the window, one token per request.
+	RateLimitAlgorithmTokenBucket RateLimitAlgorithm = "token_bucket"
+)
+
+// Validate validates the rate limit algorithm.
+func (a RateLimitAlgorithm) Validate() error {
+	switch a {
+	case RateLimitAlgorithmFixedWindow, RateLimitAlgorithmSlidingWindow, RateLimitAlgorithmTokenBucket:
+		return nil
+	default:
+		return fmt.Errorf("%w: %s", ErrInvalidRateLimitAlgorithm, a)
+	}
+}
+
+// slidingWindowScript counts requests in the window preceding now with a sorted set of request timestamps,
+// rejected requests are not recorded (returns: [allowed, current_count, reset_milliseconds]).
+const slidingWindowScript = `
+	local key = KEYS[1]
+	local limit = tonumber(ARGV[1])
+	local window = tonumber(ARGV[2])
+	local now = tonumber(ARGV[3])
+	local member = ARGV[4]
+
+	-- remove requests that left the window
+	redis.call('ZREMRANGEBYSCORE', key, '-inf', now - window)
+
+	local count = redis.call('ZCARD', key) + 1
+	local allowed = 0
+	if count <= limit then
+		redis.call('ZADD', key, now, member)
+		allowed = 1
+	end
+
+	-- the window frees a request when its oldest request leaves
+	local reset = window
+	local oldest = redis.call('ZRANGE', key, 0, 0, 'WITHSCORES')
+	if oldest[2] then
+		reset = tonumber(oldest[2]) + window - now
+	end
+
+	redis.call('PEXPIRE', key, window)
+
+	return {allowed, count, reset}
+`
+
+// tokenBucketScript refills the bucket by elapsed time since the last request and takes a token
+// (returns: [allowed, used_tokens, reset_milliseconds]).
+const tokenBucketScript = `
+	local key = KEYS[1]
+	local capacity = tonumber(ARGV[1])
+	local window = tonumber(ARGV[2])
+	local now = tonumber(ARGV[3])
+
+	local bucket = redis.call('HMGET', key, 'tokens', 'updated_at')
+	local tokens = tonumber(bucket[1]) or capacity
+	local updatedAt = tonumber(bucket[2]) or now
+
+	-- refill the full capacity over the window
+	local rate = capacity / window
+	tokens = math.min(capacity, tokens + math.max(0, now - updatedAt) * rate)
+
+	local allowed = 0
+	if tokens >= 1 then
+		tokens = tokens - 1
+		allowed = 1
+	end
+
+	redis.call('HSET', key, 'tokens', tostring(tokens), 'updated_at', tostring(now))
+	redis.call('PEXPIRE', key, window)
+
+	-- rejected requests wait for the next token, allowed requests for the bucket to be full
+	local reset
+	if allowed == 0 then
+		reset = math.ceil((1 - tokens) / rate)
+	else
+		reset = math.ceil((capacity - tokens) / rate)
+	end
+
+	return {allowed, capacity - math.floor(tokens), reset}
+`
+
+// checkRateLimitScript checks the rate limit with a script returning [allowed, current_count, reset_milliseconds].
+func checkRateLimitScript(
+	ctx context.Context,
+	redis *redis.Redis,
+	script string,
+	key string,
+	limit int,
+	window time.Duration,
+	args ...interface{},
+) (bool, int, int, time.Time, error) {
+	now := time.Now()
+
+	args = append([]interface{}{limit, window.Milliseconds(), now.UnixMilli()}, args...)
+
+	result, err := redis.Eval(ctx, script, []string{key}, args...).Result()
+	if err != nil {
+		return false, 0, 0, time.Time{}, fmt.Errorf("%w: %w", ErrFailedToExecuteScript, err)
+	}
+
+	values, ok := result.([]interface{})
+	if !ok || len(values) != 3 {
+		return false, 0, 0, time.Time{}, fmt.Errorf("%w: %v", ErrInvalidScriptResult, result)
+	}
+
+	allowed, ok1 := values[0].(int64)
+	current, ok2 := values[1].(int64)
+
+	reset, ok3 := values[2].(int64)
+	if !ok1 || !ok2 || !ok3 {
+		return false, 0, 0, time.Time{}, fmt.Errorf("%w: %v", ErrFailedToParseResult, result)
+	}
+
+	remaining := max(limit-int(current), 0)
+	resetTime := now.Add(time.Duration(reset) * time.Millisecond)
+
+	return allowed == 1, int(current), remaining, resetTime, nil
+}
+
+// checkSlidingWindow checks the rate limit of the key with a sliding window log.
+func checkSlidingWindow(
+	ctx context.Context,
+	redis *redis.Redis,
+	key string,
+	limit int,
+	window time.Duration,
+) (bool, int, int, time.Time, error) {
+	// members are unique so that requests in the same millisecond are counted separately
+	member := strconv.FormatInt(time.Now().UnixNano(), 36) + ":" + strconv.FormatUint(rand.Uint64(), 36) //nolint:gosec // uniqueness only
+
+	return checkRateLimitScript(ctx, redis, slidingWindowScript, key+":sliding", limit, window, member)
+}
+
+// checkTokenBucket checks the rate limit of the key with a token bucket.
+func checkTokenBucket(
+	ctx context.Context,
+	redis *redis.Redis,
+	key string,
+	limit int,
+	window time.Duration,
+) (bool, int, int, time.Time, error) {
+	return checkRateLimitScript(ctx, redis, tokenBucketScript, key+":bucket", limit, window)
+}
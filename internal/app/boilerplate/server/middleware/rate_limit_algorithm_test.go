@@ -0,0 +1,209 @@
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRateLimitAlgorithmValidate(t *testing.T) {
+	t.Parallel()
+
+	for _, algorithm := range []RateLimitAlgorithm{
+		RateLimitAlgorithmFixedWindow,
+		RateLimitAlgorithmSlidingWindow,
+		RateLimitAlgorithmTokenBucket,
+	} {
+		require.NoError(t, algorithm.Validate())
+	}
+
+	require.ErrorIs(t, RateLimitAlgorithm("leaky_bucket").Validate(), ErrInvalidRateLimitAlgorithm)
+	require.ErrorIs(t, RateLimitAlgorithm("").Validate(), ErrInvalidRateLimitAlgorithm)
+}
+
+func TestRateLimitConfigValidate(t *testing.T) {
+	t.Parallel()
+
+	// newConfig creates a valid rate limit config with the IP algorithm.
+	newConfig := func(algorithm RateLimitAlgorithm) *RateLimitConfig {
+		fixedWindow := RateLimitAlgorithmFixedWindow
+		headers := RateLimitHeadersBoth
+		failureMode := RateLimitFailureModeLocal
+
+		return &RateLimitConfig{
+			Global:      &RateLimitTypeConfig{Algorithm: &fixedWindow},
+			IP:          &RateLimitTypeConfig{Algorithm: &algorithm},
+			Endpoint:    &RateLimitTypeConfig{Algorithm: &fixedWindow},
+			Tenant:      &RateLimitTypeConfig{Algorithm: &fixedWindow},
+			User:        &RateLimitTypeConfig{Algorithm: &fixedWindow},
+			Headers:     &headers,
+			FailureMode: &failureMode,
+		}
+	}
+
+	t.Run("accept valid config", func(t *testing.T) {
+		t.Parallel()
+
+		require.NoError(t, newConfig(RateLimitAlgorithmSlidingWindow).Validate())
+	})
+
+	t.Run("reject unknown algorithm", func(t *testing.T) {
+		t.Parallel()
+
+		require.ErrorIs(t, newConfig("leaky_bucket").Validate(), ErrInvalidRateLimitAlgorithm)
+	})
+
+	t.Run("reject unknown failure mode", func(t *testing.T) {
+		t.Parallel()
+
+		config := newConfig(RateLimitAlgorithmFixedWindow)
+		config.FailureMode = &[]RateLimitFailureMode{"unknown"}[0]
+
+		require.ErrorIs(t, config.Validate(), ErrInvalidRateLimitFailureMode)
+	})
+
+	t.Run("reject unknown headers mode", func(t *testing.T) {
+		t.Parallel()
+
+		config := newConfig(RateLimitAlgorithmFixedWindow)
+		config.Headers = &[]RateLimitHeaders{"unknown"}[0]
+
+		require.ErrorIs(t, config.Validate(), ErrInvalidRateLimitHeaders)
+	})
+}
+
+//nolint:paralleltest // sequential execution required to avoid redis key conflicts
+func TestCheckSlidingWindow(t *testing.T) {
+	t.Run("limit requests in the window preceding each request", func(t *testing.T) {
+		redisClient := setupTestRedis(t)
+		key := fmt.Sprintf("test:sliding:%d", time.Now().UnixNano())
+		window := 400 * time.Millisecond
+
+		for i := range 2 {
+			allowed, current, remaining, _, err := checkRateLimit(
+				context.Background(), redisClient, RateLimitAlgorithmSlidingWindow, key, 2, window)
+			require.NoError(t, err)
+			assert.True(t, allowed)
+			assert.Equal(t, i+1, current)
+			assert.Equal(t, 1-i, remaining)
+		}
+
+		allowed, current, remaining, resetTime, err := checkRateLimit(
+			context.Background(), redisClient, RateLimitAlgorithmSlidingWindow, key, 2, window)
+		require.NoError(t, err)
+		assert.False(t, allowed)
+		assert.Equal(t, 3, current)
+		assert.Equal(t, 0, remaining)
+		assert.WithinDuration(t, time.Now().Add(window), resetTime, window)
+
+		// rejected requests are not counted, so the window frees once the first requests leave it
+		time.Sleep(window + 50*time.Millisecond)
+
+		allowed, current, _, _, err = checkRateLimit(
+			context.Background(), redisClient, RateLimitAlgorithmSlidingWindow, key, 2, window)
+		require.NoError(t, err)
+		assert.True(t, allowed)
+		assert.Equal(t, 1, current)
+	})
+
+	t.Run("do not allow bursts at window boundaries", func(t *testing.T) {
+		redisClient := setupTestRedis(t)
+		key := fmt.Sprintf("test:sliding_boundary:%d", time.Now().UnixNano())
+		window := 400 * time.Millisecond
+
+		allowed, _, _, _, err := checkRateLimit(
+			context.Background(), redisClient, RateLimitAlgorithmSlidingWindow, key, 2, window)
+		require.NoError(t, err)
+		assert.True(t, allowed)
+
+		time.Sleep(250 * time.Millisecond)
+
+		allowed, _, _, _, err = checkRateLimit(
+			context.Background(), redisClient, RateLimitAlgorithmSlidingWindow, key, 2, window)
+		require.NoError(t, err)
+		assert.True(t, allowed)
+
+		// the first request left the window, the second has not
+		time.Sleep(200 * time.Millisecond)
+
+		allowed, _, _, _, err = checkRateLimit(
+			context.Background(), redisClient, RateLimitAlgorithmSlidingWindow, key, 2, window)
+		require.NoError(t, err)
+		assert.True(t, allowed)
+
+		allowed, _, _, _, err = checkRateLimit(
+			context.Background(), redisClient, RateLimitAlgorithmSlidingWindow, key, 2, window)
+		require.NoError(t, err)
+		assert.False(t, allowed)
+	})
+}
+
+//nolint:paralleltest // sequential execution required to avoid redis key conflicts
+func TestCheckTokenBucket(t *testing.T) {
+	t.Run("take tokens and refill them over the window", func(t *testing.T) {
+		redisClient := setupTestRedis(t)
+		key := fmt.Sprintf("test:bucket:%d", time.Now().UnixNano())
+		window := 400 * time.Millisecond
+
+		for i := range 2 {
+			allowed, current, remaining, _, err := checkRateLimit(
+				context.Background(), redisClient, RateLimitAlgorithmTokenBucket, key, 2, window)
+			require.NoError(t, err)
+			assert.True(t, allowed)
+			assert.Equal(t, i+1, current)
+			assert.Equal(t, 1-i, remaining)
+		}
+
+		allowed, _, remaining, resetTime, err := checkRateLimit(
+			context.Background(), redisClient, RateLimitAlgorithmTokenBucket, key, 2, window)
+		require.NoError(t, err)
+		assert.False(t, allowed)
+		assert.Equal(t, 0, remaining)
+
+		// a token is refilled every half window
+		assert.WithinDuration(t, time.Now().Add(window/2), resetTime, window/2)
+
+		time.Sleep(window/2 + 50*time.Millisecond)
+
+		allowed, _, _, _, err = checkRateLimit(
+			context.Background(), redisClient, RateLimitAlgorithmTokenBucket, key, 2, window)
+		require.NoError(t, err)
+		assert.True(t, allowed)
+
+		allowed, _, _, _, err = checkRateLimit(
+			context.Background(), redisClient, RateLimitAlgorithmTokenBucket, key, 2, window)
+		require.NoError(t, err)
+		assert.False(t, allowed)
+	})
+}
+
+//nolint:paralleltest // sequential execution required to avoid redis key conflicts
+func TestRateLimitAlgorithms(t *testing.T) {
+	for _, algorithm := range []RateLimitAlgorithm{RateLimitAlgorithmSlidingWindow, RateLimitAlgorithmTokenBucket} {
+		t.Run("reject requests over limit with "+string(algorithm), func(t *testing.T) {
+			redisClient := setupTestRedis(t)
+			handler := createTestRateLimitHandler(
+				t, GlobalRateLimit(2, time.Minute, algorithm, RateLimitHeadersBoth, redisClient, nil, setupTestLogger(t)))
+
+			for range 2 {
+				recorder := httptest.NewRecorder()
+				handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/test", nil))
+				assert.Equal(t, http.StatusOK, recorder.Code)
+			}
+
+			recorder := httptest.NewRecorder()
+			handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/test", nil))
+
+			assert.Equal(t, http.StatusTooManyRequests, recorder.Code)
+			assert.Equal(t, "2", recorder.Header().Get("X-Ratelimit-Limit"))
+			assert.Equal(t, "0", recorder.Header().Get("X-Ratelimit-Remaining"))
+			assert.NotEmpty(t, recorder.Header().Get("Retry-After"))
+		})
+	}
+}
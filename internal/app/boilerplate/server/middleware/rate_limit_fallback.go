@@ -0,0 +1,178 @@
+package middleware
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	// fallbackSweepInterval is the interval of removing idle local buckets.
+	fallbackSweepInterval = time.Minute
+)
+
+// ErrInvalidRateLimitFailureMode returned when the rate limit failure mode is unknown.
+var ErrInvalidRateLimitFailureMode = errors.New("invalid rate limit failure mode")
+
+// RateLimitFailureMode represents how requests are limited when redis is unavailable.
+type RateLimitFailureMode string
+
+const (
+	// RateLimitFailureModeLocal limits requests with in-memory token buckets of each instance.
+	RateLimitFailureModeLocal RateLimitFailureMode = "local"
+
+	// RateLimitFailureModeOpen allows all requests.
+	RateLimitFailureModeOpen RateLimitFailureMode = "fail_open"
+
+	// RateLimitFailureModeClosed rejects all requests with 503.
+	RateLimitFailureModeClosed RateLimitFailureMode = "fail_closed"
+)
+
+// Validate validates the rate limit failure mode.
+func (m RateLimitFailureMode) Validate() error {
+	switch m {
+	case RateLimitFailureModeLocal, RateLimitFailureModeOpen, RateLimitFailureModeClosed:
+		return nil
+	default:
+		return fmt.Errorf("%w: %s", ErrInvalidRateLimitFailureMode, m)
+	}
+}
+
+// localBucket is an in-memory token bucket of a rate limit key.
+type localBucket struct {
+	// tokens is number of tokens left.
+	tokens float64
+
+	// capacity is the maximum number of tokens.
+	capacity float64
+
+	// rate is number of tokens refilled per second.
+	rate float64
+
+	// updatedAt is when tokens were last refilled.
+	updatedAt time.Time
+}
+
+// refill refills tokens by elapsed time until now.
+func (b *localBucket) refill(now time.Time) {
+	b.tokens = math.Min(b.capacity, b.tokens+now.Sub(b.updatedAt).Seconds()*b.rate)
+	b.updatedAt = now
+}
+
+// RateLimitFallback limits requests when rate limit checks on redis fail.
+// Limits of the local mode are kept per instance, so they are only approximate with multiple instances.
+type RateLimitFallback struct {
+	// mode is how requests are limited.
+	mode RateLimitFailureMode
+
+	// activations counts requests limited by the fallback.
+	activations *prometheus.CounterVec
+
+	// mu guards buckets and lastSweep.
+	mu sync.Mutex
+
+	// buckets is local token buckets by rate limit key.
+	buckets map[string]*localBucket
+
+	// lastSweep is when idle buckets were last removed.
+	lastSweep time.Time
+
+	// now returns the current time, replaced in tests.
+	now func() time.Time
+}
+
+// NewRateLimitFallback creates a new rate limit fallback of the mode, registering its counter on the registry.
+func NewRateLimitFallback(mode RateLimitFailureMode, registry prometheus.Registerer) (*RateLimitFallback, error) {
+	if registry == nil {
+		registry = prometheus.DefaultRegisterer
+	}
+
+	activations, err := registerCollector(registry, prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "rate_limit_fallback_activations_total",
+			Help: "Total number of requests limited by the fallback because redis was unavailable",
+		},
+		[]string{"type", "mode"},
+	))
+	if err != nil {
+		return nil, err
+	}
+
+	return &RateLimitFallback{
+		mode:        mode,
+		activations: activations,
+		buckets:     make(map[string]*localBucket),
+		now:         time.Now,
+	}, nil
+}
+
+// Mode returns how requests are limited, fail open if the fallback is nil.
+func (f *RateLimitFallback) Mode() RateLimitFailureMode {
+	if f == nil {
+		return RateLimitFailureModeOpen
+	}
+
+	return f.mode
+}
+
+// activate counts a request limited by the fallback.
+func (f *RateLimitFallback) activate(limitType RateLimitType) {
+	if f == nil {
+		return
+	}
+
+	f.activations.WithLabelValues(string(limitType), string(f.mode)).Inc()
+}
+
+// take takes a token of the key from the local bucket refilling the limit over the window,
+// returns whether the request is allowed, used and remaining tokens and when the next token is refilled.
+func (f *RateLimitFallback) take(key string, limit int, window time.Duration) (bool, int, int, time.Time) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	now := f.now()
+	f.sweep(now)
+
+	capacity := float64(limit)
+	rate := capacity / window.Seconds()
+
+	bucket, ok := f.buckets[key]
+	if !ok || bucket.capacity != capacity || bucket.rate != rate {
+		// keys whose limit changed start with a full bucket
+		bucket = &localBucket{tokens: capacity, capacity: capacity, rate: rate, updatedAt: now}
+		f.buckets[key] = bucket
+	}
+
+	bucket.refill(now)
+
+	allowed := bucket.tokens >= 1
+	if allowed {
+		bucket.tokens--
+	}
+
+	// the next whole token is refilled after the missing fraction of a token
+	remaining := int(bucket.tokens)
+	wait := (1 - (bucket.tokens - float64(remaining))) / rate
+	resetTime := now.Add(time.Duration(wait * float64(time.Second)))
+
+	return allowed, limit - remaining, remaining, resetTime
+}
+
+// sweep removes buckets refilled to full, so that keys of idle clients do not grow memory.
+func (f *RateLimitFallback) sweep(now time.Time) {
+	if now.Sub(f.lastSweep) < fallbackSweepInterval {
+		return
+	}
+
+	for key, bucket := range f.buckets {
+		if bucket.tokens+now.Sub(bucket.updatedAt).Seconds()*bucket.rate >= bucket.capacity {
+			delete(f.buckets, key)
+		}
+	}
+
+	f.lastSweep = now
+}
@@ -0,0 +1,178 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	goredis "github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/redis"
+)
+
+// setupUnavailableRedis creates a redis client whose commands fail.
+// newTestRateLimitFallback creates a rate limit fallback of the mode on the registry.
+func newTestRateLimitFallback(
+	t *testing.T,
+	mode RateLimitFailureMode,
+	registry prometheus.Registerer,
+) *RateLimitFallback {
+	t.Helper()
+
+	fallback, err := NewRateLimitFallback(mode, registry)
+	require.NoError(t, err)
+
+	return fallback
+}
+
+func setupUnavailableRedis(t *testing.T) *redis.Redis {
+	t.Helper()
+
+	client := goredis.NewUniversalClient(&goredis.UniversalOptions{
+		Addrs:       []string{"localhost:1"},
+		DialTimeout: 100 * time.Millisecond,
+		MaxRetries:  -1,
+	})
+
+	t.Cleanup(func() {
+		_ = client.Close()
+	})
+
+	return &redis.Redis{UniversalClient: client}
+}
+
+func TestRateLimitFailureModeValidate(t *testing.T) {
+	t.Parallel()
+
+	for _, mode := range []RateLimitFailureMode{
+		RateLimitFailureModeLocal,
+		RateLimitFailureModeOpen,
+		RateLimitFailureModeClosed,
+	} {
+		require.NoError(t, mode.Validate())
+	}
+
+	require.ErrorIs(t, RateLimitFailureMode("fail_slow").Validate(), ErrInvalidRateLimitFailureMode)
+}
+
+func TestRateLimitFallbackTake(t *testing.T) {
+	t.Parallel()
+
+	t.Run("take tokens and refill them over the window", func(t *testing.T) {
+		t.Parallel()
+
+		now := time.Now()
+		fallback := newTestRateLimitFallback(t, RateLimitFailureModeLocal, prometheus.NewRegistry())
+		fallback.now = func() time.Time { return now }
+
+		for i := range 2 {
+			allowed, current, remaining, _ := fallback.take("key", 2, 2*time.Second)
+			assert.True(t, allowed)
+			assert.Equal(t, i+1, current)
+			assert.Equal(t, 1-i, remaining)
+		}
+
+		allowed, _, remaining, resetTime := fallback.take("key", 2, 2*time.Second)
+		assert.False(t, allowed)
+		assert.Equal(t, 0, remaining)
+		assert.Equal(t, now.Add(time.Second), resetTime)
+
+		// a token is refilled every second
+		now = now.Add(time.Second)
+
+		allowed, _, _, _ = fallback.take("key", 2, 2*time.Second)
+		assert.True(t, allowed)
+
+		// other keys have their own buckets
+		allowed, _, _, _ = fallback.take("other", 2, 2*time.Second)
+		assert.True(t, allowed)
+	})
+
+	t.Run("remove idle buckets", func(t *testing.T) {
+		t.Parallel()
+
+		now := time.Now()
+		fallback := newTestRateLimitFallback(t, RateLimitFailureModeLocal, prometheus.NewRegistry())
+		fallback.now = func() time.Time { return now }
+
+		fallback.take("idle", 10, time.Second)
+
+		now = now.Add(fallbackSweepInterval)
+		fallback.take("active", 10, time.Hour)
+
+		assert.NotContains(t, fallback.buckets, "idle")
+		assert.Contains(t, fallback.buckets, "active")
+	})
+}
+
+func TestRateLimitFallbackMode(t *testing.T) {
+	t.Parallel()
+
+	var fallback *RateLimitFallback
+
+	assert.Equal(t, RateLimitFailureModeOpen, fallback.Mode())
+	assert.Equal(t, RateLimitFailureModeClosed,
+		newTestRateLimitFallback(t, RateLimitFailureModeClosed, prometheus.NewRegistry()).Mode())
+}
+
+func TestRateLimitWithUnavailableRedis(t *testing.T) {
+	t.Parallel()
+
+	// serve sends requests to a global rate limit of one request, returns status codes.
+	serve := func(t *testing.T, fallback *RateLimitFallback, count int) []int {
+		t.Helper()
+
+		handler := createTestRateLimitHandler(t, GlobalRateLimit(
+			1, time.Minute, RateLimitAlgorithmFixedWindow, RateLimitHeadersBoth,
+			setupUnavailableRedis(t), fallback, setupTestLogger(t),
+		))
+
+		statuses := make([]int, 0, count)
+
+		for range count {
+			recorder := httptest.NewRecorder()
+			handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/test", nil))
+			statuses = append(statuses, recorder.Code)
+		}
+
+		return statuses
+	}
+
+	t.Run("limit requests locally", func(t *testing.T) {
+		t.Parallel()
+
+		registry := prometheus.NewRegistry()
+		fallback := newTestRateLimitFallback(t, RateLimitFailureModeLocal, registry)
+
+		assert.Equal(t, []int{http.StatusOK, http.StatusTooManyRequests}, serve(t, fallback, 2))
+		assert.InDelta(t, 2, testutil.ToFloat64(
+			fallback.activations.WithLabelValues(string(RateLimitTypeGlobal), string(RateLimitFailureModeLocal))), 0)
+	})
+
+	t.Run("allow requests on fail open", func(t *testing.T) {
+		t.Parallel()
+
+		fallback := newTestRateLimitFallback(t, RateLimitFailureModeOpen, prometheus.NewRegistry())
+
+		assert.Equal(t, []int{http.StatusOK, http.StatusOK}, serve(t, fallback, 2))
+	})
+
+	t.Run("reject requests on fail closed", func(t *testing.T) {
+		t.Parallel()
+
+		fallback := newTestRateLimitFallback(t, RateLimitFailureModeClosed, prometheus.NewRegistry())
+
+		assert.Equal(t, []int{http.StatusServiceUnavailable}, serve(t, fallback, 1))
+	})
+
+	t.Run("allow requests without fallback", func(t *testing.T) {
+		t.Parallel()
+
+		assert.Equal(t, []int{http.StatusOK, http.StatusOK}, serve(t, nil, 2))
+	})
+}
@@ -0,0 +1,211 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/netip"
+	"strings"
+	"time"
+
+	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/netutil"
+)
+
+// rateLimitRuleKey is the key for the rate limit rule matching the request in context.
+const rateLimitRuleKey ContextKey = "rate_limit_rule"
+
+var (
+	// ErrInvalidRateLimitExemption returned when a rate limit exemption is invalid.
+	ErrInvalidRateLimitExemption = errors.New("invalid rate limit exemption")
+
+	// ErrInvalidRateLimitOverride returned when a rate limit override is invalid.
+	ErrInvalidRateLimitOverride = errors.New("invalid rate limit override")
+)
+
+// RateLimitExemptionsConfig represents configuration for requests exempt from or overriding rate limits.
+type RateLimitExemptionsConfig struct {
+	// CIDRs is client networks exempt from all rate limits.
+	CIDRs []string `json:"cidrs"`
+
+	// PathPrefixes is path prefixes exempt from all rate limits.
+	PathPrefixes []string `json:"path_prefixes"`
+
+	// Overrides is limits replacing the IP, endpoint and user rate limits of matching endpoints.
+	Overrides []RateLimitOverrideConfig `json:"overrides"`
+}
+
+// RateLimitOverrideConfig represents configuration for the rate limit of an endpoint.
+type RateLimitOverrideConfig struct {
+	// Method is the method of the endpoint, empty to match any method.
+	Method string `json:"method"`
+
+	// Path is the path of the endpoint.
+	Path string `json:"path"`
+
+	// Requests is the maximum number of requests allowed.
+	Requests int `json:"requests"`
+
+	// Window is the time window for rate limiting in seconds.
+	Window int `json:"window"`
+}
+
+// SetDefault sets default values.
+func (c *RateLimitExemptionsConfig) SetDefault() {
+	if c.CIDRs == nil {
+		c.CIDRs = []string{}
+	}
+
+	if c.PathPrefixes == nil {
+		c.PathPrefixes = []string{}
+	}
+
+	if c.Overrides == nil {
+		c.Overrides = []RateLimitOverrideConfig{}
+	}
+}
+
+// Validate validates the rate limit exemptions configuration.
+func (c *RateLimitExemptionsConfig) Validate() error {
+	_, err := NewRateLimitRules(c)
+
+	return err
+}
+
+// rateLimitRule is the rate limit rule matching a request.
+type rateLimitRule struct {
+	// exempt is whether the request is exempt from all rate limits.
+	exempt bool
+
+	// override is the limit replacing the IP, endpoint and user rate limits, nil if none.
+	override *RateLimitOverrideConfig
+}
+
+// RateLimitRules matches requests against rate limit exemptions and overrides.
+type RateLimitRules struct {
+	// prefixes is exempt client networks.
+	prefixes []netip.Prefix
+
+	// pathPrefixes is exempt path prefixes.
+	pathPrefixes []string
+
+	// overrides is endpoint limits.
+	overrides []RateLimitOverrideConfig
+}
+
+// NewRateLimitRules creates rate limit rules from the exemptions configuration.
+func NewRateLimitRules(config *RateLimitExemptionsConfig) (*RateLimitRules, error) {
+	rules := &RateLimitRules{}
+	if config == nil {
+		return rules, nil
+	}
+
+	for _, cidr := range config.CIDRs {
+		prefix, err := netip.ParsePrefix(cidr)
+		if err != nil {
+			return nil, fmt.Errorf("%w: cidr %q: %w", ErrInvalidRateLimitExemption, cidr, err)
+		}
+
+		rules.prefixes = append(rules.prefixes, prefix.Masked())
+	}
+
+	for _, pathPrefix := range config.PathPrefixes {
+		if !strings.HasPrefix(pathPrefix, "/") {
+			return nil, fmt.Errorf("%w: path prefix %q must start with /", ErrInvalidRateLimitExemption, pathPrefix)
+		}
+
+		rules.pathPrefixes = append(rules.pathPrefixes, pathPrefix)
+	}
+
+	for _, override := range config.Overrides {
+		if !strings.HasPrefix(override.Path, "/") || override.Requests <= 0 || override.Window <= 0 {
+			return nil, fmt.Errorf("%w: %s %s needs a path starting with / and positive requests and window",
+				ErrInvalidRateLimitOverride, override.Method, override.Path)
+		}
+
+		override.Method = strings.ToUpper(override.Method)
+		rules.overrides = append(rules.overrides, override)
+	}
+
+	return rules, nil
+}
+
+// Middleware is a middleware that stores the rule matching the request in context,
+// so that rate limits skip exempt requests and apply overrides without hitting redis.
+func (r *RateLimitRules) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		rule := r.match(request)
+		if rule == nil {
+			next.ServeHTTP(writer, request)
+
+			return
+		}
+
+		ctx := context.WithValue(request.Context(), rateLimitRuleKey, rule)
+
+		next.ServeHTTP(writer, request.WithContext(ctx))
+	})
+}
+
+// match returns the rule matching the request, nil if none.
+func (r *RateLimitRules) match(request *http.Request) *rateLimitRule {
+	for _, pathPrefix := range r.pathPrefixes {
+		if strings.HasPrefix(request.URL.Path, pathPrefix) {
+			return &rateLimitRule{exempt: true}
+		}
+	}
+
+	if len(r.prefixes) > 0 {
+		if addr, err := netip.ParseAddr(netutil.ClientIP(request)); err == nil {
+			addr = addr.Unmap()
+
+			for _, prefix := range r.prefixes {
+				if prefix.Contains(addr) {
+					return &rateLimitRule{exempt: true}
+				}
+			}
+		}
+	}
+
+	for i, override := range r.overrides {
+		if request.URL.Path == override.Path && (override.Method == "" || request.Method == override.Method) {
+			return &rateLimitRule{override: &r.overrides[i]}
+		}
+	}
+
+	return nil
+}
+
+// rateLimitRuleFromContext returns the rate limit rule matching the request, nil if none.
+func rateLimitRuleFromContext(ctx context.Context) *rateLimitRule {
+	rule, _ := ctx.Value(rateLimitRuleKey).(*rateLimitRule)
+
+	return rule
+}
+
+// isRateLimitExempt returns whether the request is exempt from all rate limits.
+func isRateLimitExempt(ctx context.Context) bool {
+	rule := rateLimitRuleFromContext(ctx)
+
+	return rule != nil && rule.exempt
+}
+
+// rateLimitOverride returns the override of the rate limit type for the request, nil if none.
+func rateLimitOverride(ctx context.Context, limitType RateLimitType) *RateLimitOverrideConfig {
+	// overrides only replace limits of each client, not limits shared by all clients, tenants or keys
+	if limitType != RateLimitTypeIP && limitType != RateLimitTypeEndpoint && limitType != RateLimitTypeUser {
+		return nil
+	}
+
+	rule := rateLimitRuleFromContext(ctx)
+	if rule == nil {
+		return nil
+	}
+
+	return rule.override
+}
+
+// overrideLimit returns the requests and window of the override.
+func (o *RateLimitOverrideConfig) overrideLimit() (int, time.Duration) {
+	return o.Requests, time.Duration(o.Window) * time.Second
+}
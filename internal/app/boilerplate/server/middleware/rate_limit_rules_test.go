@@ -0,0 +1,187 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestRateLimitRules creates rate limit rules exempting 10.0.0.0/8 and /internal, limiting POST /auth/login to 1 request.
+func newTestRateLimitRules(t *testing.T) *RateLimitRules {
+	t.Helper()
+
+	rules, err := NewRateLimitRules(&RateLimitExemptionsConfig{
+		CIDRs:        []string{"10.0.0.0/8", "2001:db8::/32"},
+		PathPrefixes: []string{"/internal"},
+		Overrides:    []RateLimitOverrideConfig{{Method: "post", Path: "/auth/login", Requests: 1, Window: 60}},
+	})
+	require.NoError(t, err)
+
+	return rules
+}
+
+func TestNewRateLimitRules(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		config  *RateLimitExemptionsConfig
+		wantErr error
+	}{
+		{name: "nil config", config: nil},
+		{name: "empty config", config: &RateLimitExemptionsConfig{}},
+		{
+			name:    "invalid cidr",
+			config:  &RateLimitExemptionsConfig{CIDRs: []string{"10.0.0.1"}},
+			wantErr: ErrInvalidRateLimitExemption,
+		},
+		{
+			name:    "relative path prefix",
+			config:  &RateLimitExemptionsConfig{PathPrefixes: []string{"internal"}},
+			wantErr: ErrInvalidRateLimitExemption,
+		},
+		{
+			name:    "override without requests",
+			config:  &RateLimitExemptionsConfig{Overrides: []RateLimitOverrideConfig{{Path: "/auth/login", Window: 60}}},
+			wantErr: ErrInvalidRateLimitOverride,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			rules, err := NewRateLimitRules(tt.config)
+			if tt.wantErr != nil {
+				require.ErrorIs(t, err, tt.wantErr)
+
+				return
+			}
+
+			require.NoError(t, err)
+			assert.NotNil(t, rules)
+		})
+	}
+}
+
+func TestRateLimitRulesMatch(t *testing.T) {
+	t.Parallel()
+
+	rules := newTestRateLimitRules(t)
+
+	tests := []struct {
+		name         string
+		method       string
+		path         string
+		remoteAddr   string
+		wantExempt   bool
+		wantOverride bool
+	}{
+		{name: "no rule", method: http.MethodGet, path: "/users", remoteAddr: "203.0.113.1:1234"},
+		{name: "exempt network", method: http.MethodGet, path: "/users", remoteAddr: "10.1.2.3:1234", wantExempt: true},
+		{name: "exempt ipv6 network", method: http.MethodGet, path: "/users", remoteAddr: "[2001:db8::1]:1234", wantExempt: true},
+		{name: "exempt path prefix", method: http.MethodGet, path: "/internal/jobs", remoteAddr: "203.0.113.1:1234", wantExempt: true},
+		{name: "override endpoint", method: http.MethodPost, path: "/auth/login", remoteAddr: "203.0.113.1:1234", wantOverride: true},
+		{name: "other method of override", method: http.MethodGet, path: "/auth/login", remoteAddr: "203.0.113.1:1234"},
+		{name: "exemption before override", method: http.MethodPost, path: "/auth/login", remoteAddr: "10.1.2.3:1234", wantExempt: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			request := httptest.NewRequest(tt.method, tt.path, nil)
+			request.RemoteAddr = tt.remoteAddr
+
+			rule := rules.match(request)
+			if !tt.wantExempt && !tt.wantOverride {
+				assert.Nil(t, rule)
+
+				return
+			}
+
+			require.NotNil(t, rule)
+			assert.Equal(t, tt.wantExempt, rule.exempt)
+			assert.Equal(t, tt.wantOverride, rule.override != nil)
+		})
+	}
+}
+
+func TestGenerateRateLimitKeyWithOverride(t *testing.T) {
+	t.Parallel()
+
+	rules := newTestRateLimitRules(t)
+
+	var request *http.Request
+
+	handler := rules.Middleware(http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) {
+		request = r
+	}))
+
+	login := httptest.NewRequest(http.MethodPost, "/auth/login", nil)
+	login.RemoteAddr = testRemoteAddr
+	handler.ServeHTTP(httptest.NewRecorder(), login)
+
+	key, err := generateRateLimitKey(RateLimitTypeIP, request)
+	require.NoError(t, err)
+	assert.Equal(t, "rate_limit:ip:192.168.1.1:override:POST:/auth/login", *key)
+
+	// limits shared by all clients are not overridden
+	key, err = generateRateLimitKey(RateLimitTypeGlobal, request)
+	require.NoError(t, err)
+	assert.Equal(t, "rate_limit:global", *key)
+}
+
+//nolint:paralleltest // sequential execution required to avoid redis key conflicts
+func TestRateLimitWithRules(t *testing.T) {
+	// newHandler creates an IP rate limit of 5 requests behind the test rules.
+	newHandler := func(t *testing.T) http.Handler {
+		t.Helper()
+
+		return newTestRateLimitRules(t).Middleware(createTestRateLimitHandler(t, IPRateLimit(
+			5, time.Minute, RateLimitAlgorithmFixedWindow, RateLimitHeadersBoth, setupTestRedis(t), nil, setupTestLogger(t),
+		)))
+	}
+
+	// serve sends the request, returns the response.
+	serve := func(handler http.Handler, method string, path string, remoteAddr string) *httptest.ResponseRecorder {
+		request := httptest.NewRequest(method, path, nil)
+		request.RemoteAddr = remoteAddr
+
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, request)
+
+		return recorder
+	}
+
+	t.Run("skip exempt requests", func(t *testing.T) {
+		handler := newHandler(t)
+
+		for range 10 {
+			recorder := serve(handler, http.MethodGet, "/users", "10.1.2.3:1234")
+			assert.Equal(t, http.StatusOK, recorder.Code)
+			assert.Empty(t, recorder.Header().Get("X-Ratelimit-Limit"))
+		}
+	})
+
+	t.Run("limit overridden endpoint separately", func(t *testing.T) {
+		handler := newHandler(t)
+
+		recorder := serve(handler, http.MethodPost, "/auth/login", testRemoteAddr)
+		assert.Equal(t, http.StatusOK, recorder.Code)
+		assert.Equal(t, "1", recorder.Header().Get("X-Ratelimit-Limit"))
+
+		recorder = serve(handler, http.MethodPost, "/auth/login", testRemoteAddr)
+		assert.Equal(t, http.StatusTooManyRequests, recorder.Code)
+
+		// other endpoints keep the IP limit
+		recorder = serve(handler, http.MethodGet, "/users", testRemoteAddr)
+		assert.Equal(t, http.StatusOK, recorder.Code)
+		assert.Equal(t, "5", recorder.Header().Get("X-Ratelimit-Limit"))
+		assert.Equal(t, "4", recorder.Header().Get("X-Ratelimit-Remaining"))
+	})
+}
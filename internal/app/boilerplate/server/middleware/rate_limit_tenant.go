@@ -0,0 +1,150 @@
+package middleware
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	goredis "github.com/redis/go-redis/v9"
+
+	"github.com/pocj8ur4in/boilerplate-go/internal/gen/db"
+	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/logger"
+	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/redis"
+)
+
+// ErrMissingTenant returned when the request has no resolved tenant.
+var ErrMissingTenant = errors.New("missing tenant")
+
+// tenantLimitCacheKeyPrefix is the redis key prefix of cached per-tenant limits.
+const tenantLimitCacheKeyPrefix = "rate_limit:tenant_config:"
+
+// TenantLimit represents a rate limit configured for a tenant.
+type TenantLimit struct {
+	// Requests is the maximum number of requests allowed.
+	Requests int `json:"requests"`
+
+	// Window is the time window for rate limiting.
+	Window time.Duration `json:"window"`
+}
+
+// TenantLimitStore provides per-tenant rate limits stored on database and cached on redis.
+type TenantLimitStore struct {
+	// queries provides database queries.
+	queries db.Querier
+
+	// redis provides redis client.
+	redis *redis.Redis
+
+	// cacheTTL is the TTL of cached limits.
+	cacheTTL time.Duration
+}
+
+// NewTenantLimitStore creates a new tenant limit store.
+func NewTenantLimitStore(queries db.Querier, redis *redis.Redis, cacheTTL time.Duration) *TenantLimitStore {
+	return &TenantLimitStore{
+		queries:  queries,
+		redis:    redis,
+		cacheTTL: cacheTTL,
+	}
+}
+
+// Get returns the limit configured for the tenant, nil if the tenant has no stored limit.
+func (s *TenantLimitStore) Get(ctx context.Context, tenantID string) (*TenantLimit, error) {
+	key := tenantLimitCacheKeyPrefix + tenantID
+
+	// check cache first, a cached null means the tenant has no stored limit
+	cached, err := s.redis.Get(ctx, key).Bytes()
+	if err == nil {
+		var limit *TenantLimit
+		if err := json.Unmarshal(cached, &limit); err == nil {
+			return limit, nil
+		}
+	} else if !errors.Is(err, goredis.Nil) {
+		return nil, fmt.Errorf("failed to get cached tenant limit: %w", err)
+	}
+
+	// load from database
+	var limit *TenantLimit
+
+	row, err := s.queries.GetTenantRateLimit(ctx, tenantID)
+
+	switch {
+	case err == nil:
+		limit = &TenantLimit{
+			Requests: int(row.Requests),
+			Window:   time.Duration(row.WindowSeconds) * time.Second,
+		}
+	case errors.Is(err, pgx.ErrNoRows):
+		limit = nil
+	default:
+		return nil, fmt.Errorf("failed to get tenant limit: %w", err)
+	}
+
+	data, err := json.Marshal(limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal tenant limit: %w", err)
+	}
+
+	if err := s.redis.Set(ctx, key, data, s.cacheTTL).Err(); err != nil {
+		return nil, fmt.Errorf("failed to cache tenant limit: %w", err)
+	}
+
+	return limit, nil
+}
+
+// Invalidate removes the cached limit of the tenant.
+func (s *TenantLimitStore) Invalidate(ctx context.Context, tenantID string) error {
+	if err := s.redis.Del(ctx, tenantLimitCacheKeyPrefix+tenantID).Err(); err != nil {
+		return fmt.Errorf("failed to invalidate tenant limit: %w", err)
+	}
+
+	return nil
+}
+
+// TenantRateLimit is a middleware that limits the rate of requests per tenant,
+// using the stored limit of the tenant or the given default limit.
+func TenantRateLimit(
+	requests int,
+	window time.Duration,
+	algorithm RateLimitAlgorithm,
+	headers RateLimitHeaders,
+	store *TenantLimitStore,
+	redis *redis.Redis,
+	fallback *RateLimitFallback,
+	logger *logger.Logger,
+) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+			// skip requests without tenant
+			key, err := generateRateLimitKey(RateLimitTypeTenant, request)
+			if err != nil {
+				next.ServeHTTP(writer, request)
+
+				return
+			}
+
+			limitRequests, limitWindow := requests, window
+
+			tenantID := TenantIDFromContext(request.Context())
+
+			limit, err := store.Get(request.Context(), tenantID)
+			if err != nil {
+				logger.Ctx(request.Context()).Error().Err(err).
+					Str("tenant_id", tenantID).
+					Msg("tenant limit lookup failed")
+			} else if limit != nil {
+				limitRequests, limitWindow = limit.Requests, limit.Window
+			}
+
+			if !enforceRateLimit(writer, request, redis, fallback, logger, RateLimitTypeTenant, headers, *key, limitRequests, limitWindow, algorithm) {
+				return
+			}
+
+			next.ServeHTTP(writer, request)
+		})
+	}
+}
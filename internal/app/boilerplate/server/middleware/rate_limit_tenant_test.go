@@ -0,0 +1,161 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pocj8ur4in/boilerplate-go/internal/gen/db"
+)
+
+// mockTenantQuerier is a mock querier serving tenant rate limits from memory.
+type mockTenantQuerier struct {
+	db.Querier
+
+	limits map[string]*db.TenantRateLimit
+	calls  int
+}
+
+func (m *mockTenantQuerier) GetTenantRateLimit(_ context.Context, tenantID string) (*db.TenantRateLimit, error) {
+	m.calls++
+
+	limit, ok := m.limits[tenantID]
+	if !ok {
+		return nil, pgx.ErrNoRows
+	}
+
+	return limit, nil
+}
+
+// tenantRequest creates a request resolved to the given tenant.
+func tenantRequest(tenantID string) *http.Request {
+	req := httptest.NewRequest(http.MethodGet, "/test", nil)
+	if tenantID == "" {
+		return req
+	}
+
+	ctx := context.WithValue(req.Context(), TenantIDKey, &tenant{})
+	setTenantID(ctx, tenantID)
+
+	return req.WithContext(ctx)
+}
+
+//nolint:paralleltest // sequential execution required to avoid redis key conflicts
+func TestTenantLimitStore(t *testing.T) {
+	t.Run("load limit from database and cache it", func(t *testing.T) {
+		querier := &mockTenantQuerier{limits: map[string]*db.TenantRateLimit{
+			"acme": {TenantID: "acme", Requests: 5, WindowSeconds: 30},
+		}}
+		store := NewTenantLimitStore(querier, setupTestRedis(t), time.Minute)
+
+		for range 3 {
+			limit, err := store.Get(context.Background(), "acme")
+			require.NoError(t, err)
+			require.NotNil(t, limit)
+			assert.Equal(t, 5, limit.Requests)
+			assert.Equal(t, 30*time.Second, limit.Window)
+		}
+
+		assert.Equal(t, 1, querier.calls)
+	})
+
+	t.Run("cache tenant without stored limit", func(t *testing.T) {
+		querier := &mockTenantQuerier{}
+		store := NewTenantLimitStore(querier, setupTestRedis(t), time.Minute)
+
+		for range 2 {
+			limit, err := store.Get(context.Background(), "unknown")
+			require.NoError(t, err)
+			assert.Nil(t, limit)
+		}
+
+		assert.Equal(t, 1, querier.calls)
+	})
+
+	t.Run("reload limit after invalidation", func(t *testing.T) {
+		querier := &mockTenantQuerier{}
+		store := NewTenantLimitStore(querier, setupTestRedis(t), time.Minute)
+
+		_, err := store.Get(context.Background(), "acme")
+		require.NoError(t, err)
+		require.NoError(t, store.Invalidate(context.Background(), "acme"))
+
+		_, err = store.Get(context.Background(), "acme")
+		require.NoError(t, err)
+
+		assert.Equal(t, 2, querier.calls)
+	})
+}
+
+//nolint:paralleltest // sequential execution required to avoid redis key conflicts
+func TestTenantRateLimit(t *testing.T) {
+	t.Run("apply stored limit per tenant", func(t *testing.T) {
+		redisClient := setupTestRedis(t)
+		querier := &mockTenantQuerier{limits: map[string]*db.TenantRateLimit{
+			"acme": {TenantID: "acme", Requests: 2, WindowSeconds: 60},
+		}}
+		store := NewTenantLimitStore(querier, redisClient, time.Minute)
+
+		handler := createTestRateLimitHandler(t, TenantRateLimit(100, time.Minute, RateLimitAlgorithmFixedWindow, RateLimitHeadersBoth, store, redisClient, nil, setupTestLogger(t)))
+
+		for range 2 {
+			recorder := httptest.NewRecorder()
+			handler.ServeHTTP(recorder, tenantRequest("acme"))
+			assert.Equal(t, http.StatusOK, recorder.Code)
+			assert.Equal(t, "2", recorder.Header().Get("X-Ratelimit-Limit"))
+		}
+
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, tenantRequest("acme"))
+		assert.Equal(t, http.StatusTooManyRequests, recorder.Code)
+		assert.Contains(t, recorder.Body.String(), `"type":"tenant"`)
+
+		// other tenant uses the default limit
+		recorder = httptest.NewRecorder()
+		handler.ServeHTTP(recorder, tenantRequest("other"))
+		assert.Equal(t, http.StatusOK, recorder.Code)
+		assert.Equal(t, "100", recorder.Header().Get("X-Ratelimit-Limit"))
+	})
+
+	t.Run("skip request without tenant", func(t *testing.T) {
+		redisClient := setupTestRedis(t)
+		store := NewTenantLimitStore(&mockTenantQuerier{}, redisClient, time.Minute)
+
+		handler := createTestRateLimitHandler(t, TenantRateLimit(1, time.Minute, RateLimitAlgorithmFixedWindow, RateLimitHeadersBoth, store, redisClient, nil, setupTestLogger(t)))
+
+		for range 3 {
+			recorder := httptest.NewRecorder()
+			handler.ServeHTTP(recorder, tenantRequest(""))
+			assert.Equal(t, http.StatusOK, recorder.Code)
+			assert.Empty(t, recorder.Header().Get("X-Ratelimit-Limit"))
+		}
+	})
+
+	t.Run("skip request with tenant header of untrusted peer", func(t *testing.T) {
+		redisClient := setupTestRedis(t)
+		querier := &mockTenantQuerier{}
+		store := NewTenantLimitStore(querier, redisClient, time.Minute)
+
+		handler := Tenant("X-Tenant-ID", newTestResolver(t))(createTestRateLimitHandler(
+			t, TenantRateLimit(1, time.Minute, RateLimitAlgorithmFixedWindow, RateLimitHeadersBoth, store, redisClient, nil, setupTestLogger(t)),
+		))
+
+		for range 3 {
+			request := httptest.NewRequest(http.MethodGet, "/test", nil)
+			request.Header.Set("X-Tenant-ID", "acme")
+
+			recorder := httptest.NewRecorder()
+			handler.ServeHTTP(recorder, request)
+			assert.Equal(t, http.StatusOK, recorder.Code)
+			assert.Empty(t, recorder.Header().Get("X-Ratelimit-Limit"))
+		}
+
+		assert.Equal(t, 0, querier.calls)
+	})
+}
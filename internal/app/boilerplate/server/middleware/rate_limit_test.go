@@ -2,6 +2,7 @@ package middleware
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
@@ -11,6 +12,11 @@ import (
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace/noop"
 
 	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/logger"
 	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/redis"
@@ -170,6 +176,31 @@ func TestGenerateRateLimitKey(t *testing.T) {
 		assert.Contains(t, *key, "GET:/test")
 	})
 
+	t.Run("generate user rate limit key from authenticated user", func(t *testing.T) {
+		t.Parallel()
+
+		req := httptest.NewRequest(http.MethodGet, "/test", nil)
+		req.RemoteAddr = testRemoteAddr
+		req = req.WithContext(context.WithValue(req.Context(), UserIDKey, "user-1"))
+		key, err := generateRateLimitKey(RateLimitTypeUser, req)
+
+		require.NoError(t, err)
+		require.NotNil(t, key)
+		assert.Equal(t, "rate_limit:user:id:user-1", *key)
+	})
+
+	t.Run("generate user rate limit key from IP for unauthenticated requests", func(t *testing.T) {
+		t.Parallel()
+
+		req := httptest.NewRequest(http.MethodGet, "/test", nil)
+		req.RemoteAddr = testRemoteAddr
+		key, err := generateRateLimitKey(RateLimitTypeUser, req)
+
+		require.NoError(t, err)
+		require.NotNil(t, key)
+		assert.Equal(t, "rate_limit:user:ip:192.168.1.1", *key)
+	})
+
 	t.Run("return error for unknown rate limit type", func(t *testing.T) {
 		t.Parallel()
 
@@ -182,49 +213,110 @@ func TestGenerateRateLimitKey(t *testing.T) {
 	})
 }
 
-func TestGetClientIP(t *testing.T) {
+func TestSetRateLimitHeaders(t *testing.T) {
 	t.Parallel()
 
-	t.Run("extract IP from X-Forwarded-For header", func(t *testing.T) {
-		t.Parallel()
+	resetTime := time.Unix(1700000000, 0)
+
+	tests := []struct {
+		name         string
+		headers      RateLimitHeaders
+		expectLegacy bool
+		expectDraft  bool
+	}{
+		{name: "set legacy headers only", headers: RateLimitHeadersLegacy, expectLegacy: true},
+		{name: "set draft headers only", headers: RateLimitHeadersDraft, expectDraft: true},
+		{name: "set both headers", headers: RateLimitHeadersBoth, expectLegacy: true, expectDraft: true},
+	}
 
-		req := httptest.NewRequest(http.MethodGet, "/test", nil)
-		req.Header.Set("X-Forwarded-For", "203.0.113.1")
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
 
-		ip := getClientIP(req)
-		assert.Equal(t, "203.0.113.1", ip)
-	})
+			recorder := httptest.NewRecorder()
 
-	t.Run("extract IP from X-Real-IP header", func(t *testing.T) {
-		t.Parallel()
+			setRateLimitHeaders(recorder, test.headers, 100, 42, resetTime, 30, time.Minute)
+
+			if test.expectLegacy {
+				assert.Equal(t, "100", recorder.Header().Get("X-Ratelimit-Limit"))
+				assert.Equal(t, "42", recorder.Header().Get("X-Ratelimit-Remaining"))
+				assert.Equal(t, "1700000000", recorder.Header().Get("X-Ratelimit-Reset"))
+			} else {
+				assert.Empty(t, recorder.Header().Get("X-Ratelimit-Limit"))
+			}
+
+			if test.expectDraft {
+				assert.Equal(t, "100", recorder.Header().Get("RateLimit-Limit"))
+				assert.Equal(t, "42", recorder.Header().Get("RateLimit-Remaining"))
+				assert.Equal(t, "30", recorder.Header().Get("RateLimit-Reset"))
+				assert.Equal(t, "100;w=60", recorder.Header().Get("RateLimit-Policy"))
+			} else {
+				assert.Empty(t, recorder.Header().Get("RateLimit-Limit"))
+			}
+		})
+	}
+}
 
-		req := httptest.NewRequest(http.MethodGet, "/test", nil)
-		req.Header.Set("X-Real-IP", "203.0.113.2")
+func TestRateLimitHeadersValidate(t *testing.T) {
+	t.Parallel()
 
-		ip := getClientIP(req)
-		assert.Equal(t, "203.0.113.2", ip)
-	})
+	require.NoError(t, RateLimitHeadersLegacy.Validate())
+	require.NoError(t, RateLimitHeadersDraft.Validate())
+	require.NoError(t, RateLimitHeadersBoth.Validate())
+	require.ErrorIs(t, RateLimitHeaders("unknown").Validate(), ErrInvalidRateLimitHeaders)
+}
 
-	t.Run("use RemoteAddr as fallback", func(t *testing.T) {
-		t.Parallel()
+func TestRateLimitKeyClientIP(t *testing.T) {
+	t.Parallel()
 
-		req := httptest.NewRequest(http.MethodGet, "/test", nil)
-		req.RemoteAddr = testRemoteAddr
+	tests := []struct {
+		name       string
+		headers    map[string]string
+		remoteAddr string
+		expected   string
+	}{
+		{
+			name:       "strip port from RemoteAddr",
+			remoteAddr: testRemoteAddr,
+			expected:   "rate_limit:ip:192.168.1.1",
+		},
+		{
+			name:       "strip brackets and port from IPv6 RemoteAddr",
+			remoteAddr: "[2001:db8::1]:443",
+			expected:   "rate_limit:ip:2001:db8::1",
+		},
+		{
+			name:       "use client IP resolved by RealIP",
+			remoteAddr: "203.0.113.1",
+			expected:   "rate_limit:ip:203.0.113.1",
+		},
+		{
+			name:       "ignore forwarded headers set by clients",
+			headers:    map[string]string{"X-Forwarded-For": "203.0.113.1", "X-Real-IP": "203.0.113.2"},
+			remoteAddr: testRemoteAddr,
+			expected:   "rate_limit:ip:192.168.1.1",
+		},
+	}
 
-		ip := getClientIP(req)
-		assert.Equal(t, testRemoteAddr, ip)
-	})
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
 
-	t.Run("X-Forwarded-For takes precedence over X-Real-IP", func(t *testing.T) {
-		t.Parallel()
+			req := httptest.NewRequest(http.MethodGet, "/test", nil)
+			if test.remoteAddr != "" {
+				req.RemoteAddr = test.remoteAddr
+			}
 
-		req := httptest.NewRequest(http.MethodGet, "/test", nil)
-		req.Header.Set("X-Forwarded-For", "203.0.113.1")
-		req.Header.Set("X-Real-IP", "203.0.113.2")
+			for key, value := range test.headers {
+				req.Header.Set(key, value)
+			}
 
-		ip := getClientIP(req)
-		assert.Equal(t, "203.0.113.1", ip)
-	})
+			key, err := generateRateLimitKey(RateLimitTypeIP, req)
+			require.NoError(t, err)
+			require.NotNil(t, key)
+			assert.Equal(t, test.expected, *key)
+		})
+	}
 }
 
 //nolint:paralleltest // sequential execution required to avoid redis key conflicts
@@ -233,7 +325,7 @@ func TestGlobalRateLimit(t *testing.T) {
 		redisClient := setupTestRedis(t)
 		log := setupTestLogger(t)
 
-		middleware := GlobalRateLimit(10, 1*time.Second, redisClient, log)
+		middleware := GlobalRateLimit(10, 1*time.Second, RateLimitAlgorithmFixedWindow, RateLimitHeadersBoth, redisClient, nil, log)
 		handler := createTestRateLimitHandler(t, middleware)
 
 		// make requests
@@ -258,7 +350,7 @@ func TestGlobalRateLimit(t *testing.T) {
 		log := setupTestLogger(t)
 
 		limit := 3
-		middleware := GlobalRateLimit(limit, 1*time.Second, redisClient, log)
+		middleware := GlobalRateLimit(limit, 1*time.Second, RateLimitAlgorithmFixedWindow, RateLimitHeadersBoth, redisClient, nil, log)
 		handler := createTestRateLimitHandler(t, middleware)
 
 		// make requests up to limit
@@ -284,7 +376,29 @@ func TestGlobalRateLimit(t *testing.T) {
 		assert.Equal(t, http.StatusTooManyRequests, recorder.Code)
 		assert.Equal(t, "3", recorder.Header().Get("X-Ratelimit-Limit"))
 		assert.Equal(t, "0", recorder.Header().Get("X-Ratelimit-Remaining"))
-		assert.NotEmpty(t, recorder.Header().Get("Retry-After"))
+		assert.Equal(t, "3", recorder.Header().Get("RateLimit-Limit"))
+		assert.Equal(t, "0", recorder.Header().Get("RateLimit-Remaining"))
+
+		// retry after is the time until the window resets, not the whole window
+		retryAfter, err := strconv.Atoi(recorder.Header().Get("Retry-After"))
+		require.NoError(t, err)
+		assert.Equal(t, 1, retryAfter)
+		assert.Equal(t, recorder.Header().Get("RateLimit-Reset"), recorder.Header().Get("Retry-After"))
+
+		// body carries the error envelope with limit metadata
+		assert.Equal(t, "application/json", recorder.Header().Get("Content-Type"))
+
+		var body struct {
+			Error   string           `json:"error"`
+			Code    string           `json:"code"`
+			Details RateLimitDetails `json:"details"`
+		}
+		require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &body))
+		assert.Equal(t, "rate_limited", body.Code)
+		assert.Equal(t, limit, body.Details.Limit)
+		assert.Equal(t, 0, body.Details.Remaining)
+		assert.Equal(t, recorder.Header().Get("X-Ratelimit-Reset"), strconv.FormatInt(body.Details.Reset, 10))
+		assert.Equal(t, RateLimitTypeGlobal, body.Details.Type)
 	})
 }
 
@@ -295,11 +409,11 @@ func TestIPRateLimit(t *testing.T) {
 		testRateLimitingBehavior(
 			t,
 			func(redis *redis.Redis, log *logger.Logger) func(http.Handler) http.Handler {
-				return IPRateLimit(limit, 1*time.Second, redis, log)
+				return IPRateLimit(limit, 1*time.Second, RateLimitAlgorithmFixedWindow, RateLimitHeadersBoth, redis, nil, log)
 			},
 			limit,
-			func(req *http.Request) { req.Header.Set("X-Forwarded-For", testIP1) },
-			func(req *http.Request) { req.Header.Set("X-Forwarded-For", testIP2) },
+			func(req *http.Request) { req.RemoteAddr = testIP1 },
+			func(req *http.Request) { req.RemoteAddr = testIP2 },
 			true,
 		)
 	})
@@ -312,13 +426,13 @@ func TestEndpointRateLimit(t *testing.T) {
 		log := setupTestLogger(t)
 
 		limit := 3
-		middleware := EndpointRateLimit(limit, 1*time.Second, redisClient, log)
+		middleware := EndpointRateLimit(limit, 1*time.Second, RateLimitAlgorithmFixedWindow, RateLimitHeadersBoth, redisClient, nil, log)
 		handler := createTestRateLimitHandler(t, middleware)
 
 		// make requests to /test endpoint
 		for range limit {
 			req := httptest.NewRequest(http.MethodGet, "/test", nil)
-			req.Header.Set("X-Forwarded-For", testIP1)
+			req.RemoteAddr = testIP1
 
 			recorder := httptest.NewRecorder()
 
@@ -333,7 +447,7 @@ func TestEndpointRateLimit(t *testing.T) {
 
 		// next request to /test should be rate limited
 		req1 := httptest.NewRequest(http.MethodGet, "/test", nil)
-		req1.Header.Set("X-Forwarded-For", testIP1)
+		req1.RemoteAddr = testIP1
 
 		recorder1 := httptest.NewRecorder()
 
@@ -343,7 +457,7 @@ func TestEndpointRateLimit(t *testing.T) {
 
 		// request to different endpoint should succeed
 		req2 := httptest.NewRequest(http.MethodGet, "/other", nil)
-		req2.Header.Set("X-Forwarded-For", testIP1)
+		req2.RemoteAddr = testIP1
 
 		recorder2 := httptest.NewRecorder()
 
@@ -360,7 +474,7 @@ func TestRateLimitHeaders(t *testing.T) {
 		log := setupTestLogger(t)
 
 		limit := 10
-		middleware := GlobalRateLimit(limit, 1*time.Second, redisClient, log)
+		middleware := GlobalRateLimit(limit, 1*time.Second, RateLimitAlgorithmFixedWindow, RateLimitHeadersBoth, redisClient, nil, log)
 		handler := createTestRateLimitHandler(t, middleware)
 
 		// make request
@@ -390,7 +504,7 @@ func callCheckRateLimit(
 ) (bool, int, int, time.Time, error) {
 	t.Helper()
 
-	return checkRateLimit(context.Background(), redisClient, key, limit, window)
+	return checkRateLimit(context.Background(), redisClient, RateLimitAlgorithmFixedWindow, key, limit, window)
 }
 
 //nolint:paralleltest // sequential execution required to avoid redis key conflicts
@@ -439,3 +553,35 @@ func TestCheckRateLimit(t *testing.T) {
 		assert.Equal(t, 0, remaining)
 	})
 }
+
+//nolint:paralleltest // sequential execution required to replace the global tracer provider
+func TestRateLimitTracing(t *testing.T) {
+	t.Run("record rate limit check as child span of request", func(t *testing.T) {
+		spanRecorder := tracetest.NewSpanRecorder()
+		otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(spanRecorder)))
+		t.Cleanup(func() { otel.SetTracerProvider(noop.NewTracerProvider()) })
+
+		redisClient := setupTestRedis(t)
+		handler := createTestRateLimitHandler(t, GlobalRateLimit(10, time.Second, RateLimitAlgorithmFixedWindow, RateLimitHeadersBoth, redisClient, nil, setupTestLogger(t)))
+
+		ctx, parent := otel.Tracer("test").Start(context.Background(), "request")
+		req := httptest.NewRequestWithContext(ctx, http.MethodGet, "/test", nil)
+
+		handler.ServeHTTP(httptest.NewRecorder(), req)
+		parent.End()
+
+		var span sdktrace.ReadOnlySpan
+
+		for _, ended := range spanRecorder.Ended() {
+			if ended.Name() == "rate_limit.check" {
+				span = ended
+			}
+		}
+
+		require.NotNil(t, span)
+		assert.Equal(t, parent.SpanContext().SpanID(), span.Parent().SpanID())
+		assert.Contains(t, span.Attributes(), attribute.String("rate_limit.type", "global"))
+		assert.Contains(t, span.Attributes(), attribute.String("rate_limit.key", "rate_limit:global"))
+		assert.Contains(t, span.Attributes(), attribute.Bool("rate_limit.allowed", true))
+	})
+}
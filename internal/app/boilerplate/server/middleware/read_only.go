@@ -0,0 +1,56 @@
+package middleware
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/apierror"
+	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/logger"
+	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/readonly"
+)
+
+// ReadOnly is a middleware that rejects mutating requests with 503 while the service is in read-only mode,
+// safe methods and requests to the exempt paths (such as the toggle itself) continue.
+func ReadOnly(mode *readonly.ReadOnly, exemptPaths []string, logger *logger.Logger) func(next http.Handler) http.Handler {
+	exempt := make(map[string]struct{}, len(exemptPaths))
+	for _, path := range exemptPaths {
+		exempt[path] = struct{}{}
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+			if isSafeMethod(request.Method) || !mode.Enabled(request.Context()) {
+				next.ServeHTTP(writer, request)
+
+				return
+			}
+
+			if _, ok := exempt[request.URL.Path]; ok {
+				next.ServeHTTP(writer, request)
+
+				return
+			}
+
+			if retryAfter := mode.RetryAfter(); retryAfter > 0 {
+				writer.Header().Set("Retry-After", strconv.Itoa(retryAfter))
+			}
+
+			if err := apierror.Write(writer, http.StatusServiceUnavailable, &apierror.Response{
+				Error: mode.Message(),
+				Code:  apierror.CodeReadOnly,
+			}); err != nil {
+				logger.Ctx(request.Context()).Error().Err(err).Msg("failed to write read-only response")
+			}
+		})
+	}
+}
+
+// isSafeMethod returns whether the method does not modify state.
+func isSafeMethod(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace:
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,65 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/readonly"
+)
+
+func TestReadOnly(t *testing.T) {
+	t.Parallel()
+
+	// newHandler creates a handler behind read-only mode exempting /admin/read-only.
+	newHandler := func(t *testing.T, enabled bool) http.Handler {
+		t.Helper()
+
+		mode := readonly.New(&readonly.Config{RetryAfter: &[]int{30}[0]}, nil)
+		require.NoError(t, mode.Set(context.Background(), enabled))
+
+		return ReadOnly(mode, []string{"/admin/read-only"}, setupTestLogger(t))(
+			http.HandlerFunc(func(writer http.ResponseWriter, _ *http.Request) {
+				writer.WriteHeader(http.StatusOK)
+			}),
+		)
+	}
+
+	tests := []struct {
+		name       string
+		enabled    bool
+		method     string
+		path       string
+		wantStatus int
+	}{
+		{name: "allow writes when disabled", method: http.MethodPost, path: "/users", wantStatus: http.StatusOK},
+		{name: "allow reads", enabled: true, method: http.MethodGet, path: "/users", wantStatus: http.StatusOK},
+		{name: "allow preflight", enabled: true, method: http.MethodOptions, path: "/users", wantStatus: http.StatusOK},
+		{name: "reject writes", enabled: true, method: http.MethodPost, path: "/users", wantStatus: http.StatusServiceUnavailable},
+		{name: "reject deletes", enabled: true, method: http.MethodDelete, path: "/users/1", wantStatus: http.StatusServiceUnavailable},
+		{name: "allow exempt path", enabled: true, method: http.MethodPut, path: "/admin/read-only", wantStatus: http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			recorder := httptest.NewRecorder()
+			newHandler(t, tt.enabled).ServeHTTP(recorder, httptest.NewRequest(tt.method, tt.path, nil))
+
+			assert.Equal(t, tt.wantStatus, recorder.Code)
+
+			if tt.wantStatus == http.StatusServiceUnavailable {
+				assert.Equal(t, "30", recorder.Header().Get("Retry-After"))
+				assert.JSONEq(t,
+					`{"error":"Service is in read-only mode for maintenance","code":"read_only"}`,
+					recorder.Body.String(),
+				)
+			}
+		})
+	}
+}
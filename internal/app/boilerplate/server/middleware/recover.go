@@ -0,0 +1,64 @@
+package middleware
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"runtime/debug"
+
+	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/apierror"
+	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/logger"
+)
+
+// Recover is a middleware that recovers from panics, logs them with the stack trace and
+// responds with the internal error envelope. If verbose is enabled, the panic value and
+// stack trace are included in the debug field of the response.
+func Recover(logger *logger.Logger, verbose bool) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+			defer func() {
+				recovered := recover()
+				if recovered == nil {
+					return
+				}
+
+				// abort panics stop the response on purpose, let the server handle them
+				if err, ok := recovered.(error); ok && errors.Is(err, http.ErrAbortHandler) {
+					panic(recovered)
+				}
+
+				stack := debug.Stack()
+
+				logger.Ctx(request.Context()).Error().
+					Str("panic", fmt.Sprint(recovered)).
+					Str("method", request.Method).
+					Str("path", request.URL.Path).
+					Bytes("stack", stack).
+					Msg("panic recovered")
+
+				// upgraded connections are no longer HTTP
+				if request.Header.Get("Connection") == "Upgrade" {
+					return
+				}
+
+				response := &apierror.Response{
+					Error: http.StatusText(http.StatusInternalServerError),
+					Code:  apierror.CodeInternal,
+				}
+
+				if verbose {
+					response.Debug = &apierror.Debug{
+						Cause: fmt.Sprint(recovered),
+						Stack: apierror.StackFrames(stack),
+					}
+				}
+
+				if err := apierror.Write(writer, http.StatusInternalServerError, response); err != nil {
+					logger.Ctx(request.Context()).Error().Err(err).Msg("failed to write panic response")
+				}
+			}()
+
+			next.ServeHTTP(writer, request)
+		})
+	}
+}
@@ -0,0 +1,78 @@
+package middleware
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/apierror"
+	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/logger"
+)
+
+// panicHandler is a handler that panics with the value.
+func panicHandler(value interface{}) http.Handler {
+	return http.HandlerFunc(func(_ http.ResponseWriter, _ *http.Request) {
+		panic(value)
+	})
+}
+
+func TestRecover(t *testing.T) {
+	t.Parallel()
+
+	log, err := logger.New(&logger.Config{Level: &[]string{"fatal"}[0]})
+	require.NoError(t, err)
+
+	t.Run("respond with internal error envelope", func(t *testing.T) {
+		t.Parallel()
+
+		recorder := httptest.NewRecorder()
+
+		require.NotPanics(t, func() {
+			Recover(log, false)(panicHandler("test panic")).ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/test", nil))
+		})
+
+		assert.Equal(t, http.StatusInternalServerError, recorder.Code)
+		assert.Equal(t, "application/json", recorder.Header().Get("Content-Type"))
+		assert.JSONEq(t, `{"error":"Internal Server Error","code":"internal_error"}`, recorder.Body.String())
+	})
+
+	t.Run("include panic and stack trace when verbose", func(t *testing.T) {
+		t.Parallel()
+
+		recorder := httptest.NewRecorder()
+		Recover(log, true)(panicHandler("test panic")).ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/test", nil))
+
+		var response apierror.Response
+		require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &response))
+
+		assert.Equal(t, apierror.CodeInternal, response.Code)
+		require.NotNil(t, response.Debug)
+		assert.Equal(t, "test panic", response.Debug.Cause)
+		assert.NotEmpty(t, response.Debug.Stack)
+		assert.Contains(t, response.Debug.Stack[0], "goroutine")
+	})
+
+	t.Run("re-panic on abort handler", func(t *testing.T) {
+		t.Parallel()
+
+		assert.PanicsWithValue(t, http.ErrAbortHandler, func() {
+			Recover(log, false)(panicHandler(http.ErrAbortHandler)).ServeHTTP(
+				httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/test", nil),
+			)
+		})
+	})
+
+	t.Run("pass through normal request", func(t *testing.T) {
+		t.Parallel()
+
+		recorder := httptest.NewRecorder()
+		Recover(log, true)(testHandler(http.StatusOK, "success")).ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/test", nil))
+
+		assert.Equal(t, http.StatusOK, recorder.Code)
+		assert.Equal(t, "success", recorder.Body.String())
+	})
+}
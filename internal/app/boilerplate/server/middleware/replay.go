@@ -0,0 +1,546 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5/middleware"
+	goredis "github.com/redis/go-redis/v9"
+
+	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/logger"
+	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/redis"
+)
+
+var (
+	// ErrReplayNotFound returned when the replay entry is not found.
+	ErrReplayNotFound = errors.New("replay entry not found")
+)
+
+const (
+	// replayEntryKeyPrefix is the redis key prefix for replay entries.
+	replayEntryKeyPrefix = "replay:entry:"
+
+	// replayIndexKey is the redis key of the sorted set indexing replay entries by capture time.
+	replayIndexKey = "replay:index"
+
+	// replayIDBytes is the number of random bytes of a replay entry ID.
+	replayIDBytes = 8
+
+	// redactedValue is the value replacing sanitized headers, query parameters, body fields and unparsable bodies.
+	redactedValue = "[REDACTED]"
+)
+
+// ReplayConfig represents configuration for request replay capture.
+type ReplayConfig struct {
+	// Enabled is whether request replay capture is enabled.
+	Enabled *bool `json:"enabled"`
+
+	// TTL is the time to live of captured entries in seconds.
+	TTL *int `json:"ttl"`
+
+	// MaxBodySize is the maximum captured body size in bytes, longer bodies are redacted.
+	MaxBodySize *int64 `json:"max_body_size"`
+
+	// UserIDs captures only requests from these user IDs, empty matches any user.
+	UserIDs []string `json:"user_ids"`
+
+	// PathPrefixes captures only requests whose path starts with one of these prefixes, empty matches any path.
+	PathPrefixes []string `json:"path_prefixes"`
+
+	// Headers captures only requests carrying all of these header values, empty matches any request.
+	Headers map[string]string `json:"headers"`
+
+	// RedactHeaders is a list of headers whose values are redacted.
+	RedactHeaders []string `json:"redact_headers"`
+
+	// RedactQueryParams is a list of URL query parameters whose values are redacted.
+	RedactQueryParams []string `json:"redact_query_params"`
+
+	// RedactFields is a list of JSON body fields whose values are redacted, bodies that are not JSON are redacted whole.
+	RedactFields []string `json:"redact_fields"`
+}
+
+// SetDefault sets default values.
+func (c *ReplayConfig) SetDefault() {
+	if c.Enabled == nil {
+		c.Enabled = &[]bool{false}[0]
+	}
+
+	if c.TTL == nil {
+		c.TTL = &[]int{3600}[0]
+	}
+
+	if c.MaxBodySize == nil {
+		c.MaxBodySize = &[]int64{65536}[0] // 64KB
+	}
+
+	if c.UserIDs == nil {
+		c.UserIDs = []string{}
+	}
+
+	if c.PathPrefixes == nil {
+		c.PathPrefixes = []string{}
+	}
+
+	if c.Headers == nil {
+		c.Headers = map[string]string{}
+	}
+
+	if c.RedactHeaders == nil {
+		c.RedactHeaders = []string{"Authorization", "Cookie", "Set-Cookie"}
+	}
+
+	if c.RedactQueryParams == nil {
+		c.RedactQueryParams = []string{"signature", "token", "access_token", "api_key"}
+	}
+
+	if c.RedactFields == nil {
+		c.RedactFields = []string{"password", "token", "access_token", "refresh_token", "secret"}
+	}
+}
+
+// ReplayEntry represents a captured request/response pair.
+type ReplayEntry struct {
+	// ID is ID of the entry.
+	ID string `json:"id"`
+
+	// RequestID is ID of the captured request.
+	RequestID string `json:"request_id,omitempty"`
+
+	// UserID is ID of the authenticated user.
+	UserID string `json:"user_id,omitempty"`
+
+	// CapturedAt is time when the request was captured.
+	CapturedAt time.Time `json:"captured_at"`
+
+	// Duration is duration of the request.
+	Duration time.Duration `json:"duration"`
+
+	// Request is captured request.
+	Request ReplayRequest `json:"request"`
+
+	// Response is captured response.
+	Response ReplayResponse `json:"response"`
+}
+
+// ReplayRequest represents a captured request.
+type ReplayRequest struct {
+	// Method is method of the request.
+	Method string `json:"method"`
+
+	// URL is URL of the request.
+	URL string `json:"url"`
+
+	// Headers is headers of the request.
+	Headers http.Header `json:"headers"`
+
+	// Body is body of the request.
+	Body string `json:"body"`
+}
+
+// ReplayResponse represents a captured response.
+type ReplayResponse struct {
+	// Status is status code of the response.
+	Status int `json:"status"`
+
+	// Headers is headers of the response.
+	Headers http.Header `json:"headers"`
+
+	// Body is body of the response.
+	Body string `json:"body"`
+}
+
+// ReplayStore stores captured entries on redis.
+type ReplayStore struct {
+	// redis provides redis client.
+	redis *redis.Redis
+
+	// ttl is the time to live of captured entries.
+	ttl time.Duration
+}
+
+// NewReplayStore creates a new replay store.
+func NewReplayStore(redis *redis.Redis, ttl time.Duration) *ReplayStore {
+	return &ReplayStore{
+		redis: redis,
+		ttl:   ttl,
+	}
+}
+
+// Save saves the entry.
+func (s *ReplayStore) Save(ctx context.Context, entry *ReplayEntry) error {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("failed to marshal replay entry: %w", err)
+	}
+
+	expired := entry.CapturedAt.Add(-s.ttl)
+
+	_, err = s.redis.TxPipelined(ctx, func(pipe goredis.Pipeliner) error {
+		pipe.Set(ctx, replayEntryKeyPrefix+entry.ID, data, s.ttl)
+		pipe.ZAdd(ctx, replayIndexKey, goredis.Z{Score: float64(entry.CapturedAt.UnixNano()), Member: entry.ID})
+		pipe.ZRemRangeByScore(ctx, replayIndexKey, "-inf", fmt.Sprintf("(%d", expired.UnixNano()))
+		pipe.Expire(ctx, replayIndexKey, s.ttl)
+
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to save replay entry: %w", err)
+	}
+
+	return nil
+}
+
+// Get gets the entry by ID.
+func (s *ReplayStore) Get(ctx context.Context, id string) (*ReplayEntry, error) {
+	data, err := s.redis.Get(ctx, replayEntryKeyPrefix+id).Bytes()
+	if err != nil {
+		if errors.Is(err, goredis.Nil) {
+			return nil, fmt.Errorf("%w: %s", ErrReplayNotFound, id)
+		}
+
+		return nil, fmt.Errorf("failed to get replay entry: %w", err)
+	}
+
+	entry := &ReplayEntry{}
+	if err := json.Unmarshal(data, entry); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal replay entry: %w", err)
+	}
+
+	return entry, nil
+}
+
+// List lists the most recent entries, newest first.
+func (s *ReplayStore) List(ctx context.Context, limit int) ([]*ReplayEntry, error) {
+	ids, err := s.redis.ZRevRange(ctx, replayIndexKey, 0, int64(limit-1)).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list replay entries: %w", err)
+	}
+
+	entries := make([]*ReplayEntry, 0, len(ids))
+
+	for _, id := range ids {
+		entry, err := s.Get(ctx, id)
+		if err != nil {
+			// entry expired before the index was pruned
+			if errors.Is(err, ErrReplayNotFound) {
+				continue
+			}
+
+			return nil, err
+		}
+
+		entries = append(entries, entry)
+	}
+
+	return entries, nil
+}
+
+// Replay is a middleware that captures sanitized request/response pairs matching the configured filter.
+// It must run after authentication so that the user ID filter can be applied.
+func Replay(config *ReplayConfig, store *ReplayStore, logger *logger.Logger) func(next http.Handler) http.Handler {
+	// set default config
+	if config == nil {
+		config = &ReplayConfig{}
+	}
+
+	config.SetDefault()
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+			if !*config.Enabled || !matchReplayFilter(config, request) {
+				next.ServeHTTP(writer, request)
+
+				return
+			}
+
+			// read request body and restore it for the next handler
+			requestBody, err := readReplayBody(request, *config.MaxBodySize)
+			if err != nil {
+				logger.Ctx(request.Context()).Error().Err(err).Msg("failed to read request body for replay capture")
+				next.ServeHTTP(writer, request)
+
+				return
+			}
+
+			// wrap response writer to capture status code and body
+			responseBody := &limitedBuffer{limit: *config.MaxBodySize}
+			wrappedWriter := middleware.NewWrapResponseWriter(writer, request.ProtoMajor)
+			wrappedWriter.Tee(responseBody)
+
+			start := time.Now()
+
+			next.ServeHTTP(wrappedWriter, request)
+
+			entry, err := newReplayEntry(config, request, requestBody, wrappedWriter, responseBody)
+			if err != nil {
+				logger.Ctx(request.Context()).Error().Err(err).Msg("failed to create replay entry")
+
+				return
+			}
+
+			entry.Duration = time.Since(start)
+
+			if err := store.Save(context.WithoutCancel(request.Context()), entry); err != nil {
+				logger.Ctx(request.Context()).Error().Err(err).Msg("failed to save replay entry")
+
+				return
+			}
+
+			logger.Ctx(request.Context()).Debug().
+				Str("replay_id", entry.ID).
+				Str("path", request.URL.Path).
+				Msg("request captured for replay")
+		})
+	}
+}
+
+// matchReplayFilter checks if the request matches all configured filters.
+func matchReplayFilter(config *ReplayConfig, request *http.Request) bool {
+	if len(config.UserIDs) > 0 {
+		userID, _ := request.Context().Value(UserIDKey).(string)
+		if !containsString(config.UserIDs, userID) {
+			return false
+		}
+	}
+
+	if len(config.PathPrefixes) > 0 {
+		matched := false
+
+		for _, prefix := range config.PathPrefixes {
+			if strings.HasPrefix(request.URL.Path, prefix) {
+				matched = true
+
+				break
+			}
+		}
+
+		if !matched {
+			return false
+		}
+	}
+
+	for name, value := range config.Headers {
+		if request.Header.Get(name) != value {
+			return false
+		}
+	}
+
+	return true
+}
+
+// readReplayBody reads up to maxBytes of the request body and restores the body for the next handler.
+func readReplayBody(request *http.Request, maxBytes int64) (*limitedBuffer, error) {
+	captured := &limitedBuffer{limit: maxBytes}
+
+	if request.Body == nil || request.Body == http.NoBody {
+		return captured, nil
+	}
+
+	// one more byte is read to tell whether the body was truncated
+	data, err := io.ReadAll(io.LimitReader(request.Body, maxBytes+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read body: %w", err)
+	}
+
+	_, _ = captured.Write(data)
+
+	request.Body = readCloser{
+		Reader: io.MultiReader(bytes.NewReader(data), request.Body),
+		Closer: request.Body,
+	}
+
+	return captured, nil
+}
+
+// newReplayEntry creates a sanitized replay entry.
+func newReplayEntry(
+	config *ReplayConfig,
+	request *http.Request,
+	requestBody *limitedBuffer,
+	wrappedWriter middleware.WrapResponseWriter,
+	responseBody *limitedBuffer,
+) (*ReplayEntry, error) {
+	buf := make([]byte, replayIDBytes)
+	if _, err := rand.Read(buf); err != nil {
+		return nil, fmt.Errorf("failed to generate replay ID: %w", err)
+	}
+
+	entry := &ReplayEntry{
+		ID:         hex.EncodeToString(buf),
+		CapturedAt: time.Now(),
+		Request: ReplayRequest{
+			Method:  request.Method,
+			URL:     sanitizeURL(request.URL, config.RedactQueryParams),
+			Headers: sanitizeHeaders(request.Header, config.RedactHeaders),
+			Body:    sanitizeBody(requestBody, config.RedactFields),
+		},
+		Response: ReplayResponse{
+			Status:  wrappedWriter.Status(),
+			Headers: sanitizeHeaders(wrappedWriter.Header(), config.RedactHeaders),
+			Body:    sanitizeBody(responseBody, config.RedactFields),
+		},
+	}
+
+	if requestID, ok := request.Context().Value(middleware.RequestIDKey).(string); ok {
+		entry.RequestID = requestID
+	}
+
+	if userID, ok := request.Context().Value(UserIDKey).(string); ok {
+		entry.UserID = userID
+	}
+
+	return entry, nil
+}
+
+// sanitizeHeaders returns a copy of headers with the configured headers redacted.
+func sanitizeHeaders(headers http.Header, redact []string) http.Header {
+	sanitized := headers.Clone()
+
+	for _, name := range redact {
+		if sanitized.Get(name) != "" {
+			sanitized.Set(name, redactedValue)
+		}
+	}
+
+	return sanitized
+}
+
+// sanitizeURL returns the URL with the values of the configured query parameters redacted.
+func sanitizeURL(requestURL *url.URL, redact []string) string {
+	query := requestURL.Query()
+	redacted := false
+
+	for name, values := range query {
+		if !containsFold(redact, name) {
+			continue
+		}
+
+		for i := range values {
+			values[i] = redactedValue
+		}
+
+		redacted = true
+	}
+
+	if !redacted {
+		return requestURL.String()
+	}
+
+	sanitized := *requestURL
+	sanitized.RawQuery = query.Encode()
+
+	return sanitized.String()
+}
+
+// sanitizeBody redacts the configured fields of JSON bodies, and redacts truncated or non-JSON bodies whole since
+// their fields can not be told apart.
+func sanitizeBody(body *limitedBuffer, redact []string) string {
+	if body.Len() == 0 {
+		return ""
+	}
+
+	if body.truncated {
+		return redactedValue
+	}
+
+	var value interface{}
+	if err := json.Unmarshal(body.Bytes(), &value); err != nil {
+		return redactedValue
+	}
+
+	data, err := json.Marshal(redactFields(value, redact))
+	if err != nil {
+		return redactedValue
+	}
+
+	return string(data)
+}
+
+// redactFields redacts the configured fields recursively.
+func redactFields(value interface{}, redact []string) interface{} {
+	switch typed := value.(type) {
+	case map[string]interface{}:
+		for key, child := range typed {
+			if containsFold(redact, key) {
+				typed[key] = redactedValue
+
+				continue
+			}
+
+			typed[key] = redactFields(child, redact)
+		}
+	case []interface{}:
+		for i, child := range typed {
+			typed[i] = redactFields(child, redact)
+		}
+	}
+
+	return value
+}
+
+// containsString checks if the slice contains the value.
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+
+	return false
+}
+
+// containsFold checks if the slice contains the value, ignoring case.
+func containsFold(values []string, value string) bool {
+	for _, v := range values {
+		if strings.EqualFold(v, value) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// readCloser combines a reader with the closer of the original body.
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
+
+// limitedBuffer is a buffer that silently drops writes beyond its limit.
+type limitedBuffer struct {
+	bytes.Buffer
+
+	// limit is the maximum number of bytes kept.
+	limit int64
+
+	// truncated is whether writes beyond the limit were dropped.
+	truncated bool
+}
+
+// Write writes data up to the limit and always reports the full length as written.
+func (b *limitedBuffer) Write(data []byte) (int, error) {
+	remaining := b.limit - int64(b.Len())
+
+	if int64(len(data)) > remaining {
+		if remaining > 0 {
+			b.Buffer.Write(data[:remaining])
+		}
+
+		b.truncated = true
+	} else {
+		b.Buffer.Write(data)
+	}
+
+	return len(data), nil
+}
@@ -0,0 +1,268 @@
+package middleware
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReplayConfigSetDefault(t *testing.T) {
+	t.Parallel()
+
+	t.Run("set default values when config is empty", func(t *testing.T) {
+		t.Parallel()
+
+		config := &ReplayConfig{}
+		config.SetDefault()
+
+		require.NotNil(t, config.Enabled)
+		assert.False(t, *config.Enabled)
+		require.NotNil(t, config.TTL)
+		assert.Equal(t, 3600, *config.TTL)
+		require.NotNil(t, config.MaxBodySize)
+		assert.Equal(t, int64(65536), *config.MaxBodySize)
+		assert.Empty(t, config.UserIDs)
+		assert.Empty(t, config.PathPrefixes)
+		assert.Empty(t, config.Headers)
+		assert.Contains(t, config.RedactHeaders, "Authorization")
+		assert.Contains(t, config.RedactFields, "password")
+	})
+}
+
+func TestMatchReplayFilter(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		config   *ReplayConfig
+		setupReq func(*http.Request) *http.Request
+		expected bool
+	}{
+		{
+			name:     "match any request without filters",
+			config:   &ReplayConfig{},
+			expected: true,
+		},
+		{
+			name:   "match user ID from context",
+			config: &ReplayConfig{UserIDs: []string{"user-1"}},
+			setupReq: func(req *http.Request) *http.Request {
+				return req.WithContext(context.WithValue(req.Context(), UserIDKey, "user-1"))
+			},
+			expected: true,
+		},
+		{
+			name:     "reject unauthenticated request when user IDs are configured",
+			config:   &ReplayConfig{UserIDs: []string{"user-1"}},
+			expected: false,
+		},
+		{
+			name:     "match path prefix",
+			config:   &ReplayConfig{PathPrefixes: []string{"/api"}},
+			expected: true,
+		},
+		{
+			name:     "reject other path prefix",
+			config:   &ReplayConfig{PathPrefixes: []string{"/other"}},
+			expected: false,
+		},
+		{
+			name:   "match header",
+			config: &ReplayConfig{Headers: map[string]string{"X-Debug": "1"}},
+			setupReq: func(req *http.Request) *http.Request {
+				req.Header.Set("X-Debug", "1")
+
+				return req
+			},
+			expected: true,
+		},
+		{
+			name:     "reject missing header",
+			config:   &ReplayConfig{Headers: map[string]string{"X-Debug": "1"}},
+			expected: false,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			test.config.SetDefault()
+
+			req := httptest.NewRequest(http.MethodGet, "/api/test", nil)
+			if test.setupReq != nil {
+				req = test.setupReq(req)
+			}
+
+			assert.Equal(t, test.expected, matchReplayFilter(test.config, req))
+		})
+	}
+}
+
+func TestSanitizeReplay(t *testing.T) {
+	t.Parallel()
+
+	t.Run("redact configured headers", func(t *testing.T) {
+		t.Parallel()
+
+		headers := http.Header{}
+		headers.Set("Authorization", "Bearer secret")
+		headers.Set("Accept", "application/json")
+
+		sanitized := sanitizeHeaders(headers, []string{"Authorization"})
+
+		assert.Equal(t, redactedValue, sanitized.Get("Authorization"))
+		assert.Equal(t, "application/json", sanitized.Get("Accept"))
+		assert.Equal(t, "Bearer secret", headers.Get("Authorization"))
+	})
+
+	t.Run("redact nested JSON body fields", func(t *testing.T) {
+		t.Parallel()
+
+		body := []byte(`{"email":"a@b.c","password":"secret","nested":[{"Token":"abc"}]}`)
+
+		sanitized := sanitizeBody(newTestReplayBody(body, 1024), []string{"password", "token"})
+
+		assert.Contains(t, sanitized, `"email":"a@b.c"`)
+		assert.NotContains(t, sanitized, "secret")
+		assert.NotContains(t, sanitized, "abc")
+	})
+
+	t.Run("redact non-JSON body", func(t *testing.T) {
+		t.Parallel()
+
+		body := newTestReplayBody([]byte("email=a%40b.c&password=secret"), 1024)
+
+		assert.Equal(t, redactedValue, sanitizeBody(body, []string{"password"}))
+	})
+
+	t.Run("redact truncated body", func(t *testing.T) {
+		t.Parallel()
+
+		// truncated bodies are redacted even if the kept prefix parses
+		body := newTestReplayBody([]byte(`12345678`), 4)
+
+		assert.Equal(t, redactedValue, sanitizeBody(body, []string{"password"}))
+	})
+
+	t.Run("redact configured query parameters", func(t *testing.T) {
+		t.Parallel()
+
+		requestURL, err := url.Parse("/downloads/a?expires=1&Signature=abc&page=2")
+		require.NoError(t, err)
+
+		sanitized := sanitizeURL(requestURL, []string{"signature"})
+
+		assert.NotContains(t, sanitized, "abc")
+		assert.Contains(t, sanitized, "expires=1")
+		assert.Contains(t, sanitized, "page=2")
+		assert.Contains(t, sanitized, "Signature="+url.QueryEscape(redactedValue))
+		assert.Equal(t, "/downloads/a?expires=1&Signature=abc&page=2", requestURL.String())
+	})
+
+	t.Run("keep URL without configured query parameters", func(t *testing.T) {
+		t.Parallel()
+
+		requestURL, err := url.Parse("/items?b=2&a=1")
+		require.NoError(t, err)
+
+		assert.Equal(t, "/items?b=2&a=1", sanitizeURL(requestURL, []string{"signature"}))
+	})
+}
+
+// newTestReplayBody returns a captured body of data written to a buffer of the limit.
+func newTestReplayBody(data []byte, limit int64) *limitedBuffer {
+	body := &limitedBuffer{limit: limit}
+	_, _ = body.Write(data)
+
+	return body
+}
+
+//nolint:paralleltest // sequential execution required to avoid redis key conflicts
+func TestReplay(t *testing.T) {
+	t.Run("capture matching request and response", func(t *testing.T) {
+		redisClient := setupTestRedis(t)
+		log := setupTestLogger(t)
+		store := NewReplayStore(redisClient, time.Minute)
+
+		config := &ReplayConfig{
+			Enabled:      &[]bool{true}[0],
+			PathPrefixes: []string{"/capture"},
+		}
+
+		handler := Replay(config, store, log)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			body, err := io.ReadAll(r.Body)
+			assert.NoError(t, err)
+
+			w.WriteHeader(http.StatusCreated)
+			_, _ = w.Write(body)
+		}))
+
+		req := httptest.NewRequest(http.MethodPost, "/capture?token=secret", strings.NewReader(`{"password":"secret","name":"x"}`))
+		req.Header.Set("Authorization", "Bearer token")
+
+		recorder := httptest.NewRecorder()
+
+		handler.ServeHTTP(recorder, req)
+
+		// handler still receives the full body
+		assert.Equal(t, http.StatusCreated, recorder.Code)
+		assert.JSONEq(t, `{"password":"secret","name":"x"}`, recorder.Body.String())
+
+		entries, err := store.List(context.Background(), 10)
+		require.NoError(t, err)
+		require.Len(t, entries, 1)
+
+		entry := entries[0]
+		assert.Equal(t, http.MethodPost, entry.Request.Method)
+		assert.NotContains(t, entry.Request.URL, "secret")
+		assert.Equal(t, redactedValue, entry.Request.Headers.Get("Authorization"))
+		assert.NotContains(t, entry.Request.Body, "secret")
+		assert.Equal(t, http.StatusCreated, entry.Response.Status)
+		assert.NotContains(t, entry.Response.Body, "secret")
+
+		stored, err := store.Get(context.Background(), entry.ID)
+		require.NoError(t, err)
+		assert.Equal(t, entry.ID, stored.ID)
+	})
+
+	t.Run("skip request not matching filter", func(t *testing.T) {
+		redisClient := setupTestRedis(t)
+		log := setupTestLogger(t)
+		store := NewReplayStore(redisClient, time.Minute)
+
+		config := &ReplayConfig{
+			Enabled:      &[]bool{true}[0],
+			PathPrefixes: []string{"/capture"},
+		}
+
+		handler := Replay(config, store, log)(testHandler(http.StatusOK, "ok"))
+
+		req := httptest.NewRequest(http.MethodGet, "/other", nil)
+		recorder := httptest.NewRecorder()
+
+		handler.ServeHTTP(recorder, req)
+
+		assert.Equal(t, http.StatusOK, recorder.Code)
+
+		entries, err := store.List(context.Background(), 10)
+		require.NoError(t, err)
+		assert.Empty(t, entries)
+	})
+
+	t.Run("return not found for unknown entry", func(t *testing.T) {
+		redisClient := setupTestRedis(t)
+		store := NewReplayStore(redisClient, time.Minute)
+
+		_, err := store.Get(context.Background(), "unknown")
+		require.ErrorIs(t, err, ErrReplayNotFound)
+	})
+}
@@ -0,0 +1,109 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/netip"
+
+	"github.com/go-chi/chi/v5/middleware"
+
+	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/apierror"
+	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/netutil"
+)
+
+// maxRequestIDLength is maximum length of request IDs accepted from trusted proxies.
+const maxRequestIDLength = 128
+
+// ErrInvalidTrustedProxy returned when a trusted proxy network is invalid.
+var ErrInvalidTrustedProxy = netutil.ErrInvalidTrustedProxy
+
+// RequestIDConfig represents configuration for request IDs.
+type RequestIDConfig struct {
+	// Header is header of the request ID, set on every response and accepted from trusted proxies.
+	Header *string `json:"header"`
+
+	// TrustedProxies is networks of proxies whose request ID header is kept, IDs are generated for other peers.
+	TrustedProxies []string `json:"trusted_proxies"`
+}
+
+// SetDefault sets default values.
+func (c *RequestIDConfig) SetDefault() {
+	if c.Header == nil {
+		c.Header = &[]string{apierror.RequestIDHeader}[0]
+	}
+
+	if c.TrustedProxies == nil {
+		c.TrustedProxies = []string{}
+	}
+}
+
+// RequestID is a middleware that adds a request ID to the request and the response header, so that clients can
+// report it and error responses include it. The ID of the request header is kept only if the peer is a trusted proxy.
+func RequestID(config *RequestIDConfig) (func(next http.Handler) http.Handler, error) {
+	config.SetDefault()
+
+	proxies, err := netutil.ParsePrefixes(config.TrustedProxies)
+	if err != nil {
+		return nil, err
+	}
+
+	header := *config.Header
+
+	return func(next http.Handler) http.Handler {
+		// respond with the ID of the request context
+		respond := http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+			writer.Header().Set(header, middleware.GetReqID(request.Context()))
+
+			next.ServeHTTP(writer, request)
+		})
+
+		generate := middleware.RequestID(respond)
+
+		return http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+			if id := request.Header.Get(header); validRequestID(id) && trustedPeer(request, proxies) {
+				ctx := context.WithValue(request.Context(), middleware.RequestIDKey, id)
+				respond.ServeHTTP(writer, request.WithContext(ctx))
+
+				return
+			}
+
+			// chi keeps the X-Request-Id header, so it is removed to generate the ID
+			request.Header.Del(middleware.RequestIDHeader)
+			generate.ServeHTTP(writer, request)
+		})
+	}, nil
+}
+
+// trustedPeer checks if the peer of the request, not the forwarded client, is in the trusted networks.
+func trustedPeer(request *http.Request, proxies []netip.Prefix) bool {
+	addr, err := netip.ParseAddr(netutil.ParseIP(request.RemoteAddr))
+	if err != nil {
+		return false
+	}
+
+	addr = addr.Unmap()
+
+	for _, prefix := range proxies {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// validRequestID checks if the request ID is not empty, not too long and only printable ASCII, so that it is safe
+// to write to logs and headers.
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+
+	for i := range len(id) {
+		if id[i] < '!' || id[i] > '~' {
+			return false
+		}
+	}
+
+	return true
+}
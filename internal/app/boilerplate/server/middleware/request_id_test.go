@@ -0,0 +1,111 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestRequestID creates the request ID middleware with default configuration.
+func newTestRequestID(t *testing.T) func(next http.Handler) http.Handler {
+	t.Helper()
+
+	requestID, err := RequestID(&RequestIDConfig{})
+	require.NoError(t, err)
+
+	return requestID
+}
+
+func TestRequestIDConfig(t *testing.T) {
+	t.Parallel()
+
+	t.Run("set default values", func(t *testing.T) {
+		t.Parallel()
+
+		config := &RequestIDConfig{}
+		config.SetDefault()
+
+		assert.Equal(t, "X-Request-ID", *config.Header)
+		assert.Empty(t, config.TrustedProxies)
+	})
+
+	t.Run("reject invalid trusted proxy", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := RequestID(&RequestIDConfig{TrustedProxies: []string{"10.0.0.0/33"}})
+		require.ErrorIs(t, err, ErrInvalidTrustedProxy)
+	})
+}
+
+func TestRequestID(t *testing.T) {
+	t.Parallel()
+
+	requestID, err := RequestID(&RequestIDConfig{
+		Header:         &[]string{"X-Correlation-ID"}[0],
+		TrustedProxies: []string{"10.0.0.0/8", "2001:db8::/32"},
+	})
+	require.NoError(t, err)
+
+	// capturedID returns the request ID the handler sees and the response header of the request.
+	capturedID := func(request *http.Request) (string, string) {
+		var id string
+
+		handler := requestID(http.HandlerFunc(func(_ http.ResponseWriter, request *http.Request) {
+			id = middleware.GetReqID(request.Context())
+		}))
+
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, request)
+
+		return id, recorder.Header().Get("X-Correlation-ID")
+	}
+
+	tests := []struct {
+		name       string
+		remoteAddr string
+		header     string
+		value      string
+		wantKept   bool
+	}{
+		{name: "keep id from trusted proxy", remoteAddr: "10.1.2.3:4567", header: "X-Correlation-ID", value: "abc-123",
+			wantKept: true},
+		{name: "keep id from trusted ipv6 proxy", remoteAddr: "[2001:db8::1]:443", header: "X-Correlation-ID",
+			value: "abc-123", wantKept: true},
+		{name: "generate id for untrusted peer", remoteAddr: "203.0.113.1:4567", header: "X-Correlation-ID",
+			value: "abc-123"},
+		{name: "ignore forwarded client of untrusted peer", remoteAddr: "203.0.113.1:4567",
+			header: "X-Forwarded-For", value: "10.1.2.3"},
+		{name: "generate id for default chi header", remoteAddr: "10.1.2.3:4567", header: "X-Request-Id",
+			value: "abc-123"},
+		{name: "generate id for unprintable id", remoteAddr: "10.1.2.3:4567", header: "X-Correlation-ID",
+			value: "abc 123"},
+		{name: "generate id for too long id", remoteAddr: "10.1.2.3:4567", header: "X-Correlation-ID",
+			value: strings.Repeat("a", maxRequestIDLength+1)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			request := httptest.NewRequest(http.MethodGet, "/test", nil)
+			request.RemoteAddr = tt.remoteAddr
+			request.Header.Set(tt.header, tt.value)
+
+			id, header := capturedID(request)
+
+			require.NotEmpty(t, id)
+			assert.Equal(t, id, header)
+
+			if tt.wantKept {
+				assert.Equal(t, tt.value, id)
+			} else {
+				assert.NotEqual(t, tt.value, id)
+			}
+		})
+	}
+}
@@ -0,0 +1,45 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/rs/zerolog"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/logger"
+)
+
+// RequestLogger is a middleware that adds a logger carrying request_id, trace_id and span_id to the request context,
+// so that every log line of the request written with logger.FromContext can be correlated.
+func RequestLogger(log *logger.Logger) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+			ctx := request.Context()
+
+			requestLogger := log.Child(func(fields zerolog.Context) zerolog.Context {
+				if requestID := middleware.GetReqID(ctx); requestID != "" {
+					fields = fields.Str("request_id", requestID)
+				}
+
+				if spanContext := trace.SpanContextFromContext(ctx); spanContext.IsValid() {
+					fields = fields.
+						Str("trace_id", spanContext.TraceID().String()).
+						Str("span_id", spanContext.SpanID().String())
+				}
+
+				return fields
+			})
+
+			next.ServeHTTP(writer, request.WithContext(logger.NewContext(ctx, requestLogger)))
+		})
+	}
+}
+
+// logUserID adds the authenticated user ID to the request-scoped logger.
+func logUserID(ctx context.Context, userID string) {
+	logger.UpdateContext(ctx, func(fields zerolog.Context) zerolog.Context {
+		return fields.Str("user_id", userID)
+	})
+}
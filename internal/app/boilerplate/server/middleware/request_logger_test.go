@@ -0,0 +1,99 @@
+package middleware
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/logger"
+)
+
+func TestRequestLogger(t *testing.T) {
+	t.Parallel()
+
+	t.Run("add correlation IDs to request-scoped logger", func(t *testing.T) {
+		t.Parallel()
+
+		var buffer bytes.Buffer
+
+		log := &logger.Logger{Logger: zerolog.New(&buffer)}
+
+		traceID, err := trace.TraceIDFromHex("4bf92f3577b34da6a3ce929d0e0e4736")
+		require.NoError(t, err)
+
+		spanID, err := trace.SpanIDFromHex("00f067aa0ba902b7")
+		require.NoError(t, err)
+
+		handler := newTestRequestID(t)(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+			ctx := trace.ContextWithSpanContext(request.Context(), trace.NewSpanContext(trace.SpanContextConfig{
+				TraceID:    traceID,
+				SpanID:     spanID,
+				TraceFlags: trace.FlagsSampled,
+			}))
+
+			RequestLogger(log)(http.HandlerFunc(func(_ http.ResponseWriter, request *http.Request) {
+				logger.FromContext(request.Context(), nil).Info().Msg("handled")
+			})).ServeHTTP(writer, request.WithContext(ctx))
+		}))
+
+		request := httptest.NewRequest(http.MethodGet, "/test", nil)
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, request)
+
+		requestID := recorder.Header().Get("X-Request-ID")
+		require.NotEmpty(t, requestID)
+
+		assert.Contains(t, buffer.String(), `"request_id":"`+requestID+`"`)
+		assert.Contains(t, buffer.String(), `"trace_id":"4bf92f3577b34da6a3ce929d0e0e4736"`)
+		assert.Contains(t, buffer.String(), `"span_id":"00f067aa0ba902b7"`)
+	})
+
+	t.Run("omit trace IDs without span", func(t *testing.T) {
+		t.Parallel()
+
+		var buffer bytes.Buffer
+
+		log := &logger.Logger{Logger: zerolog.New(&buffer)}
+
+		handler := RequestLogger(log)(http.HandlerFunc(func(_ http.ResponseWriter, request *http.Request) {
+			logger.FromContext(request.Context(), nil).Info().Msg("handled")
+		}))
+
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/test", nil))
+
+		assert.Contains(t, buffer.String(), "handled")
+		assert.NotContains(t, buffer.String(), "trace_id")
+		assert.NotContains(t, buffer.String(), "request_id")
+	})
+
+	t.Run("log authenticated user ID on request log", func(t *testing.T) {
+		t.Parallel()
+
+		var buffer bytes.Buffer
+
+		log := &logger.Logger{Logger: zerolog.New(&buffer)}
+
+		handler := RequestLogger(log)(LogRequest(log)(http.HandlerFunc(
+			func(writer http.ResponseWriter, request *http.Request) {
+				logUserID(request.Context(), "user-1")
+				writer.WriteHeader(http.StatusOK)
+			},
+		)))
+
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/test", nil))
+
+		assert.Contains(t, buffer.String(), "http request")
+		assert.Contains(t, buffer.String(), `"user_id":"user-1"`)
+
+		buffer.Reset()
+
+		log.Info().Msg("unscoped")
+		assert.NotContains(t, buffer.String(), "user_id")
+	})
+}
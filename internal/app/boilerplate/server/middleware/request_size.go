@@ -0,0 +1,125 @@
+package middleware
+
+import (
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/apierror"
+)
+
+// RequestSizeDetails represents details of the request too large error.
+type RequestSizeDetails struct {
+	// Limit is maximum size of request body in bytes.
+	Limit int64 `json:"limit"`
+}
+
+// RequestSize is a middleware that limits size of request body with 413 error response.
+// Requests declaring larger Content-Length are rejected before the handler runs, and
+// if body of unknown length exceeds the limit while read, the handler response is replaced.
+func RequestSize(maxBytes int64) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+			if request.ContentLength > maxBytes {
+				writeRequestTooLarge(writer, maxBytes)
+
+				return
+			}
+
+			limitedWriter := &requestSizeWriter{ResponseWriter: writer, maxBytes: maxBytes}
+
+			request.Body = &requestSizeBody{
+				ReadCloser: http.MaxBytesReader(writer, request.Body, maxBytes),
+				writer:     limitedWriter,
+			}
+
+			next.ServeHTTP(limitedWriter, request)
+		})
+	}
+}
+
+// writeRequestTooLarge writes the request too large error response.
+func writeRequestTooLarge(writer http.ResponseWriter, maxBytes int64) {
+	// close connection since the rest of body is not read
+	writer.Header().Set("Connection", "close")
+
+	// error is ignored since the client would not read the response anyway
+	_ = apierror.Write(writer, http.StatusRequestEntityTooLarge, &apierror.Response{
+		Error:   "Request body too large",
+		Code:    apierror.CodeRequestTooLarge,
+		Details: &RequestSizeDetails{Limit: maxBytes},
+	})
+}
+
+// requestSizeBody represents request body marking the writer when it exceeds the limit.
+type requestSizeBody struct {
+	io.ReadCloser
+
+	writer *requestSizeWriter
+}
+
+// Read reads request body.
+func (b *requestSizeBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+
+	var maxBytesErr *http.MaxBytesError
+	if errors.As(err, &maxBytesErr) {
+		b.writer.exceeded = true
+	}
+
+	return n, err //nolint:wrapcheck // io.Reader must return io.EOF as is
+}
+
+// requestSizeWriter represents response writer replacing the response once request body exceeded the limit.
+type requestSizeWriter struct {
+	http.ResponseWriter
+
+	maxBytes    int64
+	exceeded    bool
+	wroteHeader bool
+	discard     bool
+}
+
+// WriteHeader writes the status code, or the request too large error if request body exceeded the limit.
+func (w *requestSizeWriter) WriteHeader(code int) {
+	if w.wroteHeader {
+		return
+	}
+
+	w.wroteHeader = true
+
+	if w.exceeded {
+		w.discard = true
+
+		writeRequestTooLarge(w.ResponseWriter, w.maxBytes)
+
+		return
+	}
+
+	w.ResponseWriter.WriteHeader(code)
+}
+
+// Write writes the response body, discarding it if replaced with the request too large error.
+func (w *requestSizeWriter) Write(p []byte) (int, error) {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+
+	if w.discard {
+		return len(p), nil
+	}
+
+	return w.ResponseWriter.Write(p) //nolint:wrapcheck // response writer errors are returned as is
+}
+
+// Flush sends buffered response to the client.
+func (w *requestSizeWriter) Flush() {
+	if flusher, ok := w.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// Unwrap returns the underlying response writer.
+func (w *requestSizeWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
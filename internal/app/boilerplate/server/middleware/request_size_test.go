@@ -0,0 +1,98 @@
+package middleware
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// readBodyHandler is a handler that reads request body and responds with the read error.
+func readBodyHandler(called *bool) http.Handler {
+	return http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		*called = true
+
+		if _, err := io.ReadAll(request.Body); err != nil {
+			http.Error(writer, err.Error(), http.StatusBadRequest)
+
+			return
+		}
+
+		writer.WriteHeader(http.StatusOK)
+		_, _ = writer.Write([]byte("success"))
+	})
+}
+
+func TestRequestSizeTooLarge(t *testing.T) {
+	t.Parallel()
+
+	t.Run("reject declared content length before handler runs", func(t *testing.T) {
+		t.Parallel()
+
+		called := false
+		recorder := httptest.NewRecorder()
+
+		request := httptest.NewRequest(http.MethodPost, "/test", strings.NewReader("this body exceeds the limit"))
+		RequestSize(10)(readBodyHandler(&called)).ServeHTTP(recorder, request)
+
+		assert.False(t, called)
+		assert.Equal(t, http.StatusRequestEntityTooLarge, recorder.Code)
+		assert.Equal(t, "application/json", recorder.Header().Get("Content-Type"))
+		assert.Equal(t, "close", recorder.Header().Get("Connection"))
+		assert.JSONEq(t,
+			`{"error":"Request body too large","code":"request_too_large","details":{"limit":10}}`,
+			recorder.Body.String(),
+		)
+	})
+
+	t.Run("replace handler response when body of unknown length exceeds limit", func(t *testing.T) {
+		t.Parallel()
+
+		called := false
+		recorder := httptest.NewRecorder()
+
+		request := httptest.NewRequest(http.MethodPost, "/test", strings.NewReader("this body exceeds the limit"))
+		request.ContentLength = -1
+
+		RequestSize(10)(readBodyHandler(&called)).ServeHTTP(recorder, request)
+
+		assert.True(t, called)
+		assert.Equal(t, http.StatusRequestEntityTooLarge, recorder.Code)
+		assert.JSONEq(t,
+			`{"error":"Request body too large","code":"request_too_large","details":{"limit":10}}`,
+			recorder.Body.String(),
+		)
+	})
+
+	t.Run("pass body of unknown length within limit", func(t *testing.T) {
+		t.Parallel()
+
+		called := false
+		recorder := httptest.NewRecorder()
+
+		request := httptest.NewRequest(http.MethodPost, "/test", strings.NewReader("small"))
+		request.ContentLength = -1
+
+		RequestSize(10)(readBodyHandler(&called)).ServeHTTP(recorder, request)
+
+		assert.True(t, called)
+		assert.Equal(t, http.StatusOK, recorder.Code)
+		assert.Equal(t, "success", recorder.Body.String())
+	})
+
+	t.Run("expose underlying response writer", func(t *testing.T) {
+		t.Parallel()
+
+		recorder := httptest.NewRecorder()
+
+		RequestSize(10)(http.HandlerFunc(func(writer http.ResponseWriter, _ *http.Request) {
+			require.NoError(t, http.NewResponseController(writer).Flush())
+		})).ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/test", nil))
+
+		assert.True(t, recorder.Flushed)
+	})
+}
@@ -0,0 +1,254 @@
+package middleware
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/apierror"
+	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/logger"
+	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/netutil"
+	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/redis"
+)
+
+const (
+	// retryBudgetKeyPrefix is the redis key prefix of retry budget counters.
+	retryBudgetKeyPrefix = "retry_budget:"
+
+	// retryBudgetGuidance is guidance returned to clients exceeding their retry budget.
+	retryBudgetGuidance = "Too many retries, retry after the Retry-After delay with exponential backoff and jitter"
+)
+
+// RetryBudgetConfig represents configuration for the retry budget of clients.
+type RetryBudgetConfig struct {
+	// Enabled is whether retries of clients are limited by a budget.
+	Enabled *bool `json:"enabled"`
+
+	// Ratio is the fraction of requests of a client in the window allowed to be retries.
+	Ratio *float64 `json:"ratio"`
+
+	// MinRetries is the number of retries allowed in the window regardless of the ratio.
+	MinRetries *int `json:"min_retries"`
+
+	// Window is the time window of the budget in seconds.
+	Window *int `json:"window"`
+
+	// IdempotencyHeader is the header whose value reused within the window marks a retry, empty to ignore.
+	IdempotencyHeader *string `json:"idempotency_header"`
+
+	// RetryHeaders is headers marking a retry when their value is a positive attempt number.
+	RetryHeaders *[]string `json:"retry_headers"`
+}
+
+// SetDefault sets default values.
+func (c *RetryBudgetConfig) SetDefault() {
+	if c.Enabled == nil {
+		c.Enabled = &[]bool{false}[0]
+	}
+
+	if c.Ratio == nil {
+		c.Ratio = &[]float64{0.2}[0]
+	}
+
+	if c.MinRetries == nil {
+		c.MinRetries = &[]int{10}[0]
+	}
+
+	if c.Window == nil {
+		c.Window = &[]int{60}[0]
+	}
+
+	if c.IdempotencyHeader == nil {
+		c.IdempotencyHeader = &[]string{"Idempotency-Key"}[0]
+	}
+
+	if c.RetryHeaders == nil {
+		c.RetryHeaders = &[]string{"Retry-Attempt", "X-Retry-Attempt", "X-Retry-Count"}
+	}
+}
+
+// RetryBudgetDetails represents details of the error response returned when the retry budget is exceeded.
+type RetryBudgetDetails struct {
+	// Retries is the number of retries of the client in the window.
+	Retries int `json:"retries"`
+
+	// Budget is the number of retries allowed in the window.
+	Budget int `json:"budget"`
+
+	// Reset is the unix timestamp when the window resets.
+	Reset int64 `json:"reset"`
+
+	// Type is the type of the exceeded limit.
+	Type string `json:"type"`
+
+	// Guidance is how the client should retry.
+	Guidance string `json:"guidance"`
+}
+
+// retryBudgetScript counts requests of the client and, for retries, spends the retry budget
+// (returns: [is_retry, allowed, retries, budget, ttl_seconds]).
+const retryBudgetScript = `
+	local requestsKey = KEYS[1]
+	local retriesKey = KEYS[2]
+	local idempotencyKey = KEYS[3]
+	local window = tonumber(ARGV[1])
+	local ratio = tonumber(ARGV[2])
+	local minRetries = tonumber(ARGV[3])
+	local isRetry = tonumber(ARGV[4])
+
+	local requests = redis.call('INCR', requestsKey)
+	if requests == 1 then
+		redis.call('EXPIRE', requestsKey, window)
+	end
+
+	-- a reused idempotency key within the window is a retry
+	if idempotencyKey ~= '' then
+		if not redis.call('SET', idempotencyKey, 1, 'NX', 'EX', window) then
+			isRetry = 1
+		end
+	end
+
+	local ttl = redis.call('TTL', requestsKey)
+	local retries = tonumber(redis.call('GET', retriesKey) or '0')
+	local budget = math.max(minRetries, math.floor(requests * ratio))
+
+	if isRetry == 0 then
+		return {0, 1, retries, budget, ttl}
+	end
+
+	if retries >= budget then
+		return {1, 0, retries, budget, ttl}
+	end
+
+	retries = redis.call('INCR', retriesKey)
+	if retries == 1 then
+		redis.call('EXPIRE', retriesKey, window)
+	end
+
+	return {1, 1, retries, budget, ttl}
+`
+
+// RetryBudget is a middleware that limits retries of each client to a fraction of its requests,
+// rejecting retries over the budget with 429 so retry storms do not overload dependencies.
+// Requests are retries if they reuse an idempotency key within the window or carry a retry header.
+func RetryBudget(config *RetryBudgetConfig, redis *redis.Redis, logger *logger.Logger) func(next http.Handler) http.Handler {
+	window := time.Duration(*config.Window) * time.Second
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+			client := netutil.ClientIP(request)
+
+			idempotencyKey := ""
+			if *config.IdempotencyHeader != "" {
+				if value := request.Header.Get(*config.IdempotencyHeader); value != "" {
+					// hash the key so that its length does not bound redis key sizes
+					sum := sha256.Sum256([]byte(value))
+					idempotencyKey = retryBudgetKeyPrefix + "idempotency:" + client + ":" + hex.EncodeToString(sum[:16])
+				}
+			}
+
+			isRetry, allowed, retries, budget, ttl, err := checkRetryBudget(
+				request.Context(), redis, client, idempotencyKey, hasRetryHeader(request, *config.RetryHeaders),
+				window, *config.Ratio, *config.MinRetries,
+			)
+			if err != nil {
+				logger.Ctx(request.Context()).Error().Err(err).Str("client", client).Msg("retry budget check failed")
+				next.ServeHTTP(writer, request)
+
+				return
+			}
+
+			if !isRetry || allowed {
+				next.ServeHTTP(writer, request)
+
+				return
+			}
+
+			logger.Ctx(request.Context()).Warn().
+				Str("client", client).
+				Int("retries", retries).
+				Int("budget", budget).
+				Msg("retry budget exceeded")
+
+			// at least one second so clients never retry immediately
+			retryAfter := max(ttl, 1)
+			writer.Header().Set("Retry-After", strconv.Itoa(retryAfter))
+
+			if err := apierror.Write(writer, http.StatusTooManyRequests, &apierror.Response{
+				Error: "Retry budget exceeded",
+				Code:  apierror.CodeRateLimited,
+				Details: &RetryBudgetDetails{
+					Retries:  retries,
+					Budget:   budget,
+					Reset:    time.Now().Add(time.Duration(retryAfter) * time.Second).Unix(),
+					Type:     "retry_budget",
+					Guidance: retryBudgetGuidance,
+				},
+			}); err != nil {
+				logger.Ctx(request.Context()).Error().Err(err).Msg("failed to write retry budget response")
+			}
+		})
+	}
+}
+
+// hasRetryHeader returns whether the request carries one of the retry headers with a positive attempt number.
+func hasRetryHeader(request *http.Request, headers []string) bool {
+	for _, header := range headers {
+		attempt, err := strconv.Atoi(strings.TrimSpace(request.Header.Get(header)))
+		if err == nil && attempt > 0 {
+			return true
+		}
+	}
+
+	return false
+}
+
+// checkRetryBudget counts the request of the client and spends the retry budget if it is a retry,
+// returns whether the request is a retry, whether it is allowed, retries and budget of the window and its TTL.
+func checkRetryBudget(
+	ctx context.Context,
+	redis *redis.Redis,
+	client string,
+	idempotencyKey string,
+	isRetry bool,
+	window time.Duration,
+	ratio float64,
+	minRetries int,
+) (bool, bool, int, int, int, error) {
+	keys := []string{
+		retryBudgetKeyPrefix + "requests:" + client,
+		retryBudgetKeyPrefix + "retries:" + client,
+		idempotencyKey,
+	}
+
+	retryArg := 0
+	if isRetry {
+		retryArg = 1
+	}
+
+	result, err := redis.Eval(ctx, retryBudgetScript, keys,
+		int(window.Seconds()), strconv.FormatFloat(ratio, 'f', -1, 64), minRetries, retryArg).Result()
+	if err != nil {
+		return false, false, 0, 0, 0, fmt.Errorf("%w: %w", ErrFailedToExecuteScript, err)
+	}
+
+	values, ok := result.([]interface{})
+	if !ok || len(values) != 5 {
+		return false, false, 0, 0, 0, fmt.Errorf("%w: %v", ErrInvalidScriptResult, result)
+	}
+
+	parsed := make([]int64, len(values))
+
+	for i, value := range values {
+		if parsed[i], ok = value.(int64); !ok {
+			return false, false, 0, 0, 0, fmt.Errorf("%w: %v", ErrFailedToParseResult, result)
+		}
+	}
+
+	return parsed[0] == 1, parsed[1] == 1, int(parsed[2]), int(parsed[3]), int(parsed[4]), nil
+}
@@ -0,0 +1,216 @@
+package middleware
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/apierror"
+)
+
+// newTestRetryBudgetConfig creates a retry budget config allowing minRetries retries per window.
+func newTestRetryBudgetConfig(minRetries int) *RetryBudgetConfig {
+	config := &RetryBudgetConfig{
+		Enabled:    &[]bool{true}[0],
+		Ratio:      &[]float64{0}[0],
+		MinRetries: &minRetries,
+	}
+	config.SetDefault()
+
+	return config
+}
+
+// newTestRetryRequest creates a request from a client address unique to the test run.
+func newTestRetryRequest(client string) *http.Request {
+	request := httptest.NewRequest(http.MethodPost, "/test", nil)
+	request.RemoteAddr = client + ":1234"
+
+	return request
+}
+
+// uniqueTestClient returns a client address not used by earlier test runs.
+func uniqueTestClient() string {
+	now := time.Now().UnixNano()
+
+	return fmt.Sprintf("10.%d.%d.%d", now/65536%256, now/256%256, now%256)
+}
+
+func TestRetryBudgetConfigSetDefault(t *testing.T) {
+	t.Parallel()
+
+	config := &RetryBudgetConfig{}
+	config.SetDefault()
+
+	assert.False(t, *config.Enabled)
+	assert.InDelta(t, 0.2, *config.Ratio, 0)
+	assert.Equal(t, 10, *config.MinRetries)
+	assert.Equal(t, 60, *config.Window)
+	assert.Equal(t, "Idempotency-Key", *config.IdempotencyHeader)
+	assert.Equal(t, []string{"Retry-Attempt", "X-Retry-Attempt", "X-Retry-Count"}, *config.RetryHeaders)
+}
+
+func TestHasRetryHeader(t *testing.T) {
+	t.Parallel()
+
+	headers := []string{"Retry-Attempt", "X-Retry-Count"}
+
+	tests := []struct {
+		name   string
+		header string
+		value  string
+		want   bool
+	}{
+		{name: "no header", want: false},
+		{name: "first attempt", header: "Retry-Attempt", value: "0", want: false},
+		{name: "retry attempt", header: "Retry-Attempt", value: "2", want: true},
+		{name: "retry count", header: "X-Retry-Count", value: " 1 ", want: true},
+		{name: "invalid value", header: "X-Retry-Count", value: "yes", want: false},
+		{name: "unknown header", header: "X-Attempt", value: "3", want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			request := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tt.header != "" {
+				request.Header.Set(tt.header, tt.value)
+			}
+
+			assert.Equal(t, tt.want, hasRetryHeader(request, headers))
+		})
+	}
+}
+
+//nolint:paralleltest // sequential execution required to avoid redis key conflicts
+func TestRetryBudget(t *testing.T) {
+	t.Run("allow requests that are not retries", func(t *testing.T) {
+		handler := createTestRateLimitHandler(
+			t, RetryBudget(newTestRetryBudgetConfig(1), setupTestRedis(t), setupTestLogger(t)))
+		client := uniqueTestClient()
+
+		for range 5 {
+			recorder := httptest.NewRecorder()
+			handler.ServeHTTP(recorder, newTestRetryRequest(client))
+			assert.Equal(t, http.StatusOK, recorder.Code)
+		}
+	})
+
+	t.Run("reject retry headers over budget", func(t *testing.T) {
+		handler := createTestRateLimitHandler(
+			t, RetryBudget(newTestRetryBudgetConfig(2), setupTestRedis(t), setupTestLogger(t)))
+		client := uniqueTestClient()
+
+		for attempt := range 2 {
+			request := newTestRetryRequest(client)
+			request.Header.Set("Retry-Attempt", strconv.Itoa(attempt+1))
+
+			recorder := httptest.NewRecorder()
+			handler.ServeHTTP(recorder, request)
+			assert.Equal(t, http.StatusOK, recorder.Code)
+		}
+
+		request := newTestRetryRequest(client)
+		request.Header.Set("X-Retry-Count", "3")
+
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, request)
+
+		assert.Equal(t, http.StatusTooManyRequests, recorder.Code)
+		assert.NotEmpty(t, recorder.Header().Get("Retry-After"))
+
+		var response struct {
+			Error   string             `json:"error"`
+			Code    apierror.Code      `json:"code"`
+			Details RetryBudgetDetails `json:"details"`
+		}
+
+		require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &response))
+		assert.Equal(t, "Retry budget exceeded", response.Error)
+		assert.Equal(t, apierror.CodeRateLimited, response.Code)
+		assert.Equal(t, 2, response.Details.Retries)
+		assert.Equal(t, 2, response.Details.Budget)
+		assert.Equal(t, "retry_budget", response.Details.Type)
+		assert.NotEmpty(t, response.Details.Guidance)
+
+		// requests that are not retries are still served
+		recorder = httptest.NewRecorder()
+		handler.ServeHTTP(recorder, newTestRetryRequest(client))
+		assert.Equal(t, http.StatusOK, recorder.Code)
+	})
+
+	t.Run("detect reused idempotency keys as retries", func(t *testing.T) {
+		handler := createTestRateLimitHandler(
+			t, RetryBudget(newTestRetryBudgetConfig(1), setupTestRedis(t), setupTestLogger(t)))
+		client := uniqueTestClient()
+
+		send := func(key string) int {
+			request := newTestRetryRequest(client)
+			request.Header.Set("Idempotency-Key", key)
+
+			recorder := httptest.NewRecorder()
+			handler.ServeHTTP(recorder, request)
+
+			return recorder.Code
+		}
+
+		assert.Equal(t, http.StatusOK, send("key-1"))
+		assert.Equal(t, http.StatusOK, send("key-2"))
+
+		// the first reuse spends the only retry, the second exceeds the budget
+		assert.Equal(t, http.StatusOK, send("key-1"))
+		assert.Equal(t, http.StatusTooManyRequests, send("key-2"))
+	})
+
+	t.Run("grow budget with requests by ratio", func(t *testing.T) {
+		config := newTestRetryBudgetConfig(0)
+		config.Ratio = &[]float64{0.25}[0]
+
+		handler := createTestRateLimitHandler(t, RetryBudget(config, setupTestRedis(t), setupTestLogger(t)))
+		client := uniqueTestClient()
+
+		for range 7 {
+			recorder := httptest.NewRecorder()
+			handler.ServeHTTP(recorder, newTestRetryRequest(client))
+			assert.Equal(t, http.StatusOK, recorder.Code)
+		}
+
+		// eight to ten requests including the retries allow two retries
+		for _, want := range []int{http.StatusOK, http.StatusOK, http.StatusTooManyRequests} {
+			request := newTestRetryRequest(client)
+			request.Header.Set("Retry-Attempt", "1")
+
+			recorder := httptest.NewRecorder()
+			handler.ServeHTTP(recorder, request)
+			assert.Equal(t, want, recorder.Code)
+		}
+	})
+
+	t.Run("budget clients separately", func(t *testing.T) {
+		handler := createTestRateLimitHandler(
+			t, RetryBudget(newTestRetryBudgetConfig(1), setupTestRedis(t), setupTestLogger(t)))
+		first := uniqueTestClient()
+		second := "172.16.0." + strconv.Itoa(int(time.Now().UnixNano()%256))
+
+		retry := func(client string) int {
+			request := newTestRetryRequest(client)
+			request.Header.Set("Retry-Attempt", "1")
+
+			recorder := httptest.NewRecorder()
+			handler.ServeHTTP(recorder, request)
+
+			return recorder.Code
+		}
+
+		assert.Equal(t, http.StatusOK, retry(first))
+		assert.Equal(t, http.StatusTooManyRequests, retry(first))
+		assert.Equal(t, http.StatusOK, retry(second))
+	})
+}
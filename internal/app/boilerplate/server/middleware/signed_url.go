@@ -0,0 +1,54 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+
+	gojwt "github.com/golang-jwt/jwt/v5"
+
+	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/jwt"
+	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/logger"
+	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/signedurl"
+)
+
+// SignedURLKey is the key for the grant of the verified signed URL in context.
+const SignedURLKey ContextKey = "signed_url"
+
+// SignedURL is a middleware that authenticates GET and HEAD requests of signed URLs as the signing user,
+// storing claims without role and scopes in context so JWTAuth is skipped but scoped endpoints stay forbidden.
+// Requests of other methods are left to JWT even if they carry a signature.
+func SignedURL(signer *signedurl.Signer, logger *logger.Logger) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+			if (request.Method != http.MethodGet && request.Method != http.MethodHead) || !signedurl.Signed(request.URL) {
+				next.ServeHTTP(writer, request)
+
+				return
+			}
+
+			grant, err := signer.Verify(request.URL)
+			if err != nil {
+				logger.Ctx(request.Context()).Debug().Err(err).Str("path", request.URL.Path).Msg("invalid signed url")
+				writeUnauthorized(writer)
+
+				return
+			}
+
+			// add user information to context as JWTAuth does
+			claims := &jwt.Claims{
+				UserID: grant.UserID,
+				RegisteredClaims: gojwt.RegisteredClaims{
+					Subject:   grant.UserID,
+					ExpiresAt: gojwt.NewNumericDate(grant.ExpiresAt),
+				},
+			}
+
+			ctx := context.WithValue(request.Context(), UserIDKey, claims.UserID)
+			ctx = context.WithValue(ctx, ClaimsKey, claims)
+			ctx = context.WithValue(ctx, SignedURLKey, grant)
+			logUserID(ctx, claims.UserID)
+
+			next.ServeHTTP(writer, request.WithContext(ctx))
+		})
+	}
+}
@@ -0,0 +1,91 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/jwt"
+	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/signedurl"
+)
+
+func TestSignedURL(t *testing.T) {
+	t.Parallel()
+
+	signer, err := signedurl.New(&signedurl.Config{
+		Enabled: &[]bool{true}[0],
+		Secret:  &[]string{"test_secret"}[0],
+		Paths:   []string{"/downloads/"},
+	})
+	require.NoError(t, err)
+
+	signed, err := signer.Sign("/downloads/report.pdf", "user-1", time.Minute)
+	require.NoError(t, err)
+
+	// serve serves the request through SignedURL, capturing the claims in context.
+	serve := func(t *testing.T, request *http.Request) (*httptest.ResponseRecorder, *jwt.Claims) {
+		t.Helper()
+
+		var claims *jwt.Claims
+
+		handler := SignedURL(signer, setupTestLogger(t))(http.HandlerFunc(
+			func(writer http.ResponseWriter, request *http.Request) {
+				claims, _ = request.Context().Value(ClaimsKey).(*jwt.Claims)
+
+				writer.WriteHeader(http.StatusOK)
+			},
+		))
+
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, request)
+
+		return recorder, claims
+	}
+
+	t.Run("authenticate signing user", func(t *testing.T) {
+		t.Parallel()
+
+		recorder, claims := serve(t, httptest.NewRequest(http.MethodGet, signed.URL, nil))
+
+		assert.Equal(t, http.StatusOK, recorder.Code)
+		require.NotNil(t, claims)
+		assert.Equal(t, "user-1", claims.UserID)
+		assert.Empty(t, claims.Role)
+		assert.Empty(t, claims.Scopes)
+	})
+
+	t.Run("pass through request without signature", func(t *testing.T) {
+		t.Parallel()
+
+		recorder, claims := serve(t, httptest.NewRequest(http.MethodGet, "/downloads/report.pdf", nil))
+
+		assert.Equal(t, http.StatusOK, recorder.Code)
+		assert.Nil(t, claims)
+	})
+
+	t.Run("leave signed request of other methods to jwt", func(t *testing.T) {
+		t.Parallel()
+
+		recorder, claims := serve(t, httptest.NewRequest(http.MethodDelete, signed.URL, nil))
+
+		assert.Equal(t, http.StatusOK, recorder.Code)
+		assert.Nil(t, claims)
+	})
+
+	t.Run("reject modified url", func(t *testing.T) {
+		t.Parallel()
+
+		modified := strings.Replace(signed.URL, "report.pdf", "secret.pdf", 1)
+
+		recorder, claims := serve(t, httptest.NewRequest(http.MethodGet, modified, nil))
+
+		assert.Equal(t, http.StatusUnauthorized, recorder.Code)
+		assert.JSONEq(t, `{"error":"Unauthorized","code":"unauthorized"}`, recorder.Body.String())
+		assert.Nil(t, claims)
+	})
+}
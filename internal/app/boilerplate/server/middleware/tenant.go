@@ -0,0 +1,89 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"sync/atomic"
+
+	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/netutil"
+)
+
+// TenantIDKey is the key for tenant of the request in context.
+const TenantIDKey ContextKey = "tenant_id"
+
+// maxTenantIDLength is the maximum length of a tenant ID accepted from the request.
+const maxTenantIDLength = 128
+
+// TenancyConfig represents configuration for tenant resolution.
+type TenancyConfig struct {
+	// Enabled is whether tenant resolution is enabled.
+	Enabled *bool `json:"enabled"`
+
+	// Header is the request header carrying the tenant ID, only trusted from trusted proxies.
+	Header *string `json:"header"`
+}
+
+// SetDefault sets default values.
+func (c *TenancyConfig) SetDefault() {
+	if c.Enabled == nil {
+		c.Enabled = &[]bool{false}[0]
+	}
+
+	if c.Header == nil {
+		c.Header = &[]string{"X-Tenant-ID"}[0]
+	}
+}
+
+// tenant holds the verified tenant ID of a request, set in place so that middlewares running before
+// authentication see the tenant of the authenticated principal once the request is served.
+type tenant struct {
+	id atomic.Pointer[string]
+}
+
+// Tenant is a middleware that resolves the tenant of the request. The tenant is verified: it is taken from the
+// tenant of the authenticated principal once JWTAuth or APIKeyAuth authenticate the request, or from the header
+// if the peer is a trusted proxy of the resolver, since clients can set any header.
+func Tenant(header string, resolver *netutil.Resolver) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+			ctx := context.WithValue(request.Context(), TenantIDKey, &tenant{})
+
+			if resolver.TrustedPeer(request) {
+				setTenantID(ctx, request.Header.Get(header))
+			}
+
+			next.ServeHTTP(writer, request.WithContext(ctx))
+		})
+	}
+}
+
+// setTenantID sets the verified tenant of the request, it ignores missing or oversized tenant IDs and does
+// nothing if tenant resolution is disabled.
+func setTenantID(ctx context.Context, tenantID string) {
+	resolved, ok := ctx.Value(TenantIDKey).(*tenant)
+	if !ok {
+		return
+	}
+
+	tenantID = strings.TrimSpace(tenantID)
+	if tenantID == "" || len(tenantID) > maxTenantIDLength {
+		return
+	}
+
+	resolved.id.Store(&tenantID)
+}
+
+// TenantIDFromContext returns the verified tenant ID of the request, empty if none.
+func TenantIDFromContext(ctx context.Context) string {
+	resolved, ok := ctx.Value(TenantIDKey).(*tenant)
+	if !ok {
+		return ""
+	}
+
+	if tenantID := resolved.id.Load(); tenantID != nil {
+		return *tenantID
+	}
+
+	return ""
+}
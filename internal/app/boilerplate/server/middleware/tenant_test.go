@@ -0,0 +1,147 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	gojwt "github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pocj8ur4in/boilerplate-go/internal/gen/api"
+	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/jwt"
+	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/netutil"
+)
+
+// testTrustedProxy is the address of a trusted proxy of the resolver of newTestResolver.
+const testTrustedProxy = "10.0.0.1:1234"
+
+// newTestResolver creates a resolver trusting proxies in 10.0.0.0/8.
+func newTestResolver(t *testing.T) *netutil.Resolver {
+	t.Helper()
+
+	resolver, err := netutil.NewResolver([]string{"10.0.0.0/8"})
+	require.NoError(t, err)
+
+	return resolver
+}
+
+// generateTestTenantToken generates a test access token of the tenant, signed with the secret of setupTestJWT.
+func generateTestTenantToken(t *testing.T, tenantID string) string {
+	t.Helper()
+
+	token, err := gojwt.NewWithClaims(gojwt.SigningMethodHS256, &jwt.Claims{
+		UserID:   "user123",
+		TenantID: tenantID,
+		RegisteredClaims: gojwt.RegisteredClaims{
+			ExpiresAt: gojwt.NewNumericDate(time.Now().Add(time.Hour)),
+		},
+	}).SignedString([]byte("test-secret-key"))
+	require.NoError(t, err)
+
+	return token
+}
+
+func TestTenancyConfigSetDefault(t *testing.T) {
+	t.Parallel()
+
+	t.Run("set default values when config is empty", func(t *testing.T) {
+		t.Parallel()
+
+		config := &TenancyConfig{}
+		config.SetDefault()
+
+		require.NotNil(t, config.Enabled)
+		assert.False(t, *config.Enabled)
+		require.NotNil(t, config.Header)
+		assert.Equal(t, "X-Tenant-ID", *config.Header)
+	})
+}
+
+func TestTenant(t *testing.T) {
+	t.Parallel()
+
+	t.Run("resolve tenant from header of trusted proxy", func(t *testing.T) {
+		t.Parallel()
+
+		tests := []struct {
+			name       string
+			remoteAddr string
+			header     string
+			expected   string
+		}{
+			{name: "resolve tenant from header", remoteAddr: testTrustedProxy, header: "acme", expected: "acme"},
+			{name: "trim whitespace", remoteAddr: testTrustedProxy, header: "  acme  ", expected: "acme"},
+			{name: "ignore missing header", remoteAddr: testTrustedProxy, header: "", expected: ""},
+			{
+				name:       "ignore oversized tenant ID",
+				remoteAddr: testTrustedProxy,
+				header:     strings.Repeat("a", maxTenantIDLength+1),
+				expected:   "",
+			},
+			{name: "ignore header of untrusted peer", remoteAddr: "192.0.2.1:1234", header: "acme", expected: ""},
+		}
+
+		for _, test := range tests {
+			var tenantID string
+
+			handler := Tenant("X-Tenant-ID", newTestResolver(t))(
+				http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) {
+					tenantID = TenantIDFromContext(r.Context())
+				}),
+			)
+
+			req := httptest.NewRequest(http.MethodGet, "/test", nil)
+			req.RemoteAddr = test.remoteAddr
+
+			if test.header != "" {
+				req.Header.Set("X-Tenant-ID", test.header)
+			}
+
+			handler.ServeHTTP(httptest.NewRecorder(), req)
+
+			assert.Equal(t, test.expected, tenantID, test.name)
+		}
+	})
+
+	t.Run("resolve tenant of authenticated token", func(t *testing.T) {
+		t.Parallel()
+
+		var tenantID string
+
+		// middlewares running before authentication see the tenant once the request is served
+		handler := Tenant("X-Tenant-ID", newTestResolver(t))(
+			http.HandlerFunc(func(writer http.ResponseWriter, r *http.Request) {
+				assert.Empty(t, TenantIDFromContext(r.Context()))
+
+				JWTAuth(setupTestJWT(t), setupTestLogger(t))(testHandler(http.StatusOK, "success")).ServeHTTP(writer, r)
+
+				tenantID = TenantIDFromContext(r.Context())
+			}),
+		)
+
+		req := httptest.NewRequest(http.MethodGet, "/test", nil)
+		req.Header.Set("Authorization", "Bearer "+generateTestTenantToken(t, "acme"))
+		req.Header.Set("X-Tenant-ID", "other")
+		req = req.WithContext(context.WithValue(req.Context(), api.BearerAuthScopes, []string{}))
+
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, req)
+
+		assert.Equal(t, http.StatusOK, recorder.Code)
+		assert.Equal(t, "acme", tenantID)
+	})
+
+	t.Run("resolve no tenant without tenancy", func(t *testing.T) {
+		t.Parallel()
+
+		ctx := context.Background()
+		setTenantID(ctx, "acme")
+
+		assert.Empty(t, TenantIDFromContext(ctx))
+	})
+}
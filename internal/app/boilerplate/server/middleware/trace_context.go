@@ -0,0 +1,22 @@
+package middleware
+
+import (
+	"net/http"
+
+	"go.opentelemetry.io/otel/propagation"
+
+	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/tracing"
+)
+
+// TraceContext is a middleware that adds the trace context of the traceparent header and the baggage header
+// to the request context even if tracing is disabled, so that request logs carry the trace ID of upstream services
+// and outbound requests continue their trace.
+func TraceContext(next http.Handler) http.Handler {
+	propagator := tracing.Propagator()
+
+	return http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		ctx := propagator.Extract(request.Context(), propagation.HeaderCarrier(request.Header))
+
+		next.ServeHTTP(writer, request.WithContext(ctx))
+	})
+}
@@ -0,0 +1,81 @@
+package middleware
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel/baggage"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/logger"
+)
+
+func TestTraceContext(t *testing.T) {
+	t.Parallel()
+
+	t.Run("add upstream trace context and baggage to request", func(t *testing.T) {
+		t.Parallel()
+
+		var (
+			spanContext trace.SpanContext
+			member      baggage.Member
+		)
+
+		handler := TraceContext(http.HandlerFunc(func(_ http.ResponseWriter, request *http.Request) {
+			spanContext = trace.SpanContextFromContext(request.Context())
+			member = baggage.FromContext(request.Context()).Member("tenant")
+		}))
+
+		request := httptest.NewRequest(http.MethodGet, "/test", nil)
+		request.Header.Set("Traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+		request.Header.Set("Baggage", "tenant=acme")
+
+		handler.ServeHTTP(httptest.NewRecorder(), request)
+
+		assert.True(t, spanContext.IsRemote())
+		assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", spanContext.TraceID().String())
+		assert.Equal(t, "00f067aa0ba902b7", spanContext.SpanID().String())
+		assert.True(t, spanContext.IsSampled())
+		assert.Equal(t, "acme", member.Value())
+	})
+
+	t.Run("log upstream trace ID without tracing", func(t *testing.T) {
+		t.Parallel()
+
+		var buffer bytes.Buffer
+
+		log := &logger.Logger{Logger: zerolog.New(&buffer)}
+
+		handler := TraceContext(RequestLogger(log)(http.HandlerFunc(func(_ http.ResponseWriter, request *http.Request) {
+			logger.FromContext(request.Context(), nil).Info().Msg("handled")
+		})))
+
+		request := httptest.NewRequest(http.MethodGet, "/test", nil)
+		request.Header.Set("Traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+
+		handler.ServeHTTP(httptest.NewRecorder(), request)
+
+		assert.Contains(t, buffer.String(), `"trace_id":"4bf92f3577b34da6a3ce929d0e0e4736"`)
+	})
+
+	t.Run("ignore malformed traceparent", func(t *testing.T) {
+		t.Parallel()
+
+		var spanContext trace.SpanContext
+
+		handler := TraceContext(http.HandlerFunc(func(_ http.ResponseWriter, request *http.Request) {
+			spanContext = trace.SpanContextFromContext(request.Context())
+		}))
+
+		request := httptest.NewRequest(http.MethodGet, "/test", nil)
+		request.Header.Set("Traceparent", "00-invalid-01")
+
+		handler.ServeHTTP(httptest.NewRecorder(), request)
+
+		assert.False(t, spanContext.IsValid())
+	})
+}
@@ -0,0 +1,80 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+//nolint:paralleltest // sequential execution required to replace the global tracer provider
+func TestTracing(t *testing.T) {
+	spanRecorder := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(spanRecorder)))
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	t.Cleanup(func() {
+		otel.SetTracerProvider(noop.NewTracerProvider())
+		otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator())
+	})
+
+	router := chi.NewRouter()
+	router.Use(Tracing)
+	router.Get("/users/{id}", func(writer http.ResponseWriter, _ *http.Request) {
+		writer.WriteHeader(http.StatusOK)
+	})
+	router.Get("/fail", func(writer http.ResponseWriter, _ *http.Request) {
+		writer.WriteHeader(http.StatusInternalServerError)
+	})
+
+	// serve serves the request, returns the server span of the request.
+	serve := func(t *testing.T, request *http.Request) sdktrace.ReadOnlySpan {
+		t.Helper()
+
+		router.ServeHTTP(httptest.NewRecorder(), request)
+
+		spans := spanRecorder.Ended()
+		require.NotEmpty(t, spans)
+
+		return spans[len(spans)-1]
+	}
+
+	t.Run("name span after route template with status", func(t *testing.T) {
+		span := serve(t, httptest.NewRequest(http.MethodGet, "/users/42", nil))
+
+		assert.Equal(t, "GET /users/{id}", span.Name())
+		assert.Contains(t, span.Attributes(), attribute.String("http.route", "/users/{id}"))
+		assert.Contains(t, span.Attributes(), attribute.String("url.path", "/users/42"))
+		assert.Contains(t, span.Attributes(), attribute.Int("http.response.status_code", http.StatusOK))
+		assert.Equal(t, codes.Unset, span.Status().Code)
+	})
+
+	t.Run("continue trace of traceparent header", func(t *testing.T) {
+		ctx, parent := otel.Tracer("test").Start(context.Background(), "upstream")
+		parent.End()
+
+		request := httptest.NewRequest(http.MethodGet, "/users/42", nil)
+		otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(request.Header))
+
+		span := serve(t, request)
+
+		assert.Equal(t, parent.SpanContext().TraceID(), span.SpanContext().TraceID())
+		assert.Equal(t, parent.SpanContext().SpanID(), span.Parent().SpanID())
+	})
+
+	t.Run("mark server errors", func(t *testing.T) {
+		span := serve(t, httptest.NewRequest(http.MethodGet, "/fail", nil))
+
+		assert.Equal(t, codes.Error, span.Status().Code)
+	})
+}
@@ -0,0 +1,35 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/go-chi/chi/v5/middleware"
+
+	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/usage"
+)
+
+// Usage is a middleware that records request and byte counts of verified tenants for usage reporting,
+// it must run after Tenant, and requests without a verified tenant once served are not recorded.
+func Usage(recorder *usage.Recorder) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+			if !recorder.Enabled() {
+				next.ServeHTTP(writer, request)
+
+				return
+			}
+
+			wrappedWriter := middleware.NewWrapResponseWriter(writer, request.ProtoMajor)
+
+			next.ServeHTTP(wrappedWriter, request)
+
+			// the tenant of the authenticated principal is known once the request is served
+			tenantID := TenantIDFromContext(request.Context())
+			if tenantID == "" {
+				return
+			}
+
+			recorder.Record(tenantID, max(request.ContentLength, 0), int64(wrappedWriter.BytesWritten()))
+		})
+	}
+}
@@ -0,0 +1,107 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pocj8ur4in/boilerplate-go/internal/gen/api"
+	"github.com/pocj8ur4in/boilerplate-go/internal/gen/db"
+	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/usage"
+)
+
+// mockUsageQuerier is a mock querier capturing added usage.
+type mockUsageQuerier struct {
+	db.Querier
+
+	mu    sync.Mutex
+	added []*db.AddTenantUsageParams
+}
+
+func (m *mockUsageQuerier) AddTenantUsage(_ context.Context, arg *db.AddTenantUsageParams) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.added = append(m.added, arg)
+
+	return nil
+}
+
+func TestUsage(t *testing.T) {
+	t.Parallel()
+
+	// serve serves the request through Tenant, Usage and JWTAuth, then flushes the recorded usage.
+	serve := func(t *testing.T, enabled bool, request *http.Request) []*db.AddTenantUsageParams {
+		t.Helper()
+
+		querier := &mockUsageQuerier{}
+		recorder := usage.NewWithQuerier(&usage.Config{Enabled: &enabled}, querier, nil, setupTestLogger(t))
+
+		handler := Tenant("X-Tenant-ID", newTestResolver(t))(Usage(recorder)(
+			JWTAuth(setupTestJWT(t), setupTestLogger(t))(testHandler(http.StatusOK, "success")),
+		))
+		handler.ServeHTTP(httptest.NewRecorder(), request)
+
+		require.NoError(t, recorder.Flush(context.Background()))
+
+		return querier.added
+	}
+
+	t.Run("record requests and bytes of tenant", func(t *testing.T) {
+		t.Parallel()
+
+		request := httptest.NewRequest(http.MethodPost, "/test", strings.NewReader("body"))
+		request.RemoteAddr = testTrustedProxy
+		request.Header.Set("X-Tenant-ID", "tenant-a")
+
+		added := serve(t, true, request)
+		require.Len(t, added, 1)
+		assert.Equal(t, "tenant-a", added[0].TenantID)
+		assert.Equal(t, int64(1), added[0].Requests)
+		assert.Equal(t, int64(len("body")), added[0].RequestBytes)
+		assert.Equal(t, int64(len("success")), added[0].ResponseBytes)
+	})
+
+	t.Run("skip request without tenant", func(t *testing.T) {
+		t.Parallel()
+
+		assert.Empty(t, serve(t, true, httptest.NewRequest(http.MethodGet, "/test", nil)))
+	})
+
+	t.Run("record tenant of authenticated token", func(t *testing.T) {
+		t.Parallel()
+
+		request := httptest.NewRequest(http.MethodGet, "/test", nil)
+		request.Header.Set("Authorization", "Bearer "+generateTestTenantToken(t, "tenant-a"))
+		request = request.WithContext(context.WithValue(request.Context(), api.BearerAuthScopes, []string{}))
+
+		added := serve(t, true, request)
+		require.Len(t, added, 1)
+		assert.Equal(t, "tenant-a", added[0].TenantID)
+	})
+
+	t.Run("skip tenant header of untrusted peer", func(t *testing.T) {
+		t.Parallel()
+
+		request := httptest.NewRequest(http.MethodGet, "/test", nil)
+		request.Header.Set("X-Tenant-ID", "tenant-a")
+
+		assert.Empty(t, serve(t, true, request))
+	})
+
+	t.Run("skip request while disabled", func(t *testing.T) {
+		t.Parallel()
+
+		request := httptest.NewRequest(http.MethodGet, "/test", nil)
+		request.RemoteAddr = testTrustedProxy
+		request.Header.Set("X-Tenant-ID", "tenant-a")
+
+		assert.Empty(t, serve(t, false, request))
+	})
+}
@@ -0,0 +1,232 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/getkin/kin-openapi/openapi3filter"
+	"github.com/getkin/kin-openapi/routers/legacy"
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/apierror"
+	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/logger"
+	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/netutil"
+)
+
+// validationLocationBody is location of fields of the request body.
+const validationLocationBody = "body"
+
+// arrayIndexPattern matches indexes of arrays in paths of body fields.
+var arrayIndexPattern = regexp.MustCompile(`(^|\.)[0-9]+(\.|$)`)
+
+// ValidationConfig represents configuration for request validation against the OpenAPI spec.
+type ValidationConfig struct {
+	// Enabled is whether request validation is enabled.
+	Enabled *bool `json:"enabled"`
+}
+
+// SetDefault sets default values.
+func (c *ValidationConfig) SetDefault() {
+	if c.Enabled == nil {
+		c.Enabled = &[]bool{true}[0]
+	}
+}
+
+// ValidationDetails represents details of the request validation error.
+type ValidationDetails struct {
+	// Fields is validation errors of the request fields.
+	Fields []*FieldError `json:"fields"`
+}
+
+// FieldError represents a validation error of a request field.
+type FieldError struct {
+	// Field is name of the parameter or path of the body field, empty for the whole location.
+	Field string `json:"field,omitempty"`
+
+	// In is location of the field (body, query, header, path or cookie).
+	In string `json:"in"`
+
+	// Message is reason of the validation error.
+	Message string `json:"message"`
+}
+
+// Validation is a middleware that validates request bodies, query parameters and headers against the spec
+// before handlers run, requests to operations not in the spec are passed through. Failures are counted by route
+// and field on the registry, and logged with the client sending them.
+func Validation(
+	spec *openapi3.T,
+	logger *logger.Logger,
+	registry prometheus.Registerer,
+) (func(next http.Handler) http.Handler, error) {
+	// servers are ignored so that operations match regardless of the host the server listens on
+	routeSpec := *spec
+	routeSpec.Servers = nil
+
+	router, err := legacy.NewRouter(&routeSpec)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create openapi router: %w", err)
+	}
+
+	options := &openapi3filter.Options{
+		MultiError: true,
+
+		// authentication is enforced by the auth middlewares
+		AuthenticationFunc: func(context.Context, *openapi3filter.AuthenticationInput) error {
+			return nil
+		},
+	}
+
+	if registry == nil {
+		registry = prometheus.DefaultRegisterer
+	}
+
+	failuresTotal, err := registerCollector(registry, prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "http_request_validation_failures_total",
+			Help: "Total number of request fields failing OpenAPI validation",
+		},
+		[]string{"method", "route", "in", "field"},
+	))
+	if err != nil {
+		return nil, err
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+			route, pathParams, err := router.FindRoute(request)
+			if err != nil {
+				next.ServeHTTP(writer, request)
+
+				return
+			}
+
+			err = openapi3filter.ValidateRequest(request.Context(), &openapi3filter.RequestValidationInput{
+				Request:    request,
+				PathParams: pathParams,
+				Route:      route,
+				Options:    options,
+			})
+			if err == nil {
+				next.ServeHTTP(writer, request)
+
+				return
+			}
+
+			fields := fieldErrors(err)
+			labels := make([]string, 0, len(fields))
+
+			for _, field := range fields {
+				failuresTotal.WithLabelValues(route.Method, route.Path, field.In, metricField(field.Field)).Inc()
+				labels = append(labels, field.In+":"+field.Field)
+			}
+
+			logger.Ctx(request.Context()).Info().
+				Str("route", route.Method+" "+route.Path).
+				Strs("fields", labels).
+				Str("client_ip", netutil.ClientIP(request)).
+				Str("user_agent", request.UserAgent()).
+				Msg("request validation failed")
+
+			if err := apierror.Write(writer, http.StatusBadRequest, &apierror.Response{
+				Error:   "Request validation failed",
+				Code:    apierror.CodeInvalidRequest,
+				Details: &ValidationDetails{Fields: fields},
+			}); err != nil {
+				logger.Ctx(request.Context()).Error().Err(err).Msg("failed to write validation response")
+			}
+		})
+	}, nil
+}
+
+// metricField returns the field as a metric label, indexes of arrays are replaced by * so that
+// the number of label values is bounded by the spec rather than by request bodies.
+func metricField(field string) string {
+	// matches of the pattern overlap on consecutive indexes, so it is replaced until none are left
+	for arrayIndexPattern.MatchString(field) {
+		field = arrayIndexPattern.ReplaceAllString(field, "$1*$2")
+	}
+
+	return field
+}
+
+// fieldErrors converts the request validation error to errors of the request fields.
+func fieldErrors(err error) []*FieldError {
+	// multiple errors are split before matching, since errors.As also matches errors wrapping multiple errors
+	if multiError, ok := err.(openapi3.MultiError); ok { //nolint:errorlint // only the direct type is split
+		fields := []*FieldError{}
+		for _, err := range multiError {
+			fields = append(fields, fieldErrors(err)...)
+		}
+
+		return fields
+	}
+
+	var requestError *openapi3filter.RequestError
+	if !errors.As(err, &requestError) {
+		return []*FieldError{{Message: err.Error()}}
+	}
+
+	switch {
+	case requestError.Parameter != nil:
+		return []*FieldError{{
+			Field:   requestError.Parameter.Name,
+			In:      requestError.Parameter.In,
+			Message: requestErrorReason(requestError),
+		}}
+	case requestError.RequestBody != nil:
+		if fields := schemaFieldErrors(requestError.Err); len(fields) > 0 {
+			return fields
+		}
+
+		return []*FieldError{{In: validationLocationBody, Message: requestErrorReason(requestError)}}
+	default:
+		return []*FieldError{{Message: requestErrorReason(requestError)}}
+	}
+}
+
+// schemaFieldErrors converts schema errors of the request body to errors of the body fields.
+func schemaFieldErrors(err error) []*FieldError {
+	// schema errors of each body field are collected separately
+	if multiError, ok := err.(openapi3.MultiError); ok { //nolint:errorlint // only the direct type is split
+		fields := []*FieldError{}
+		for _, err := range multiError {
+			fields = append(fields, schemaFieldErrors(err)...)
+		}
+
+		return fields
+	}
+
+	var schemaError *openapi3.SchemaError
+	if !errors.As(err, &schemaError) {
+		return nil
+	}
+
+	return []*FieldError{{
+		Field:   strings.Join(schemaError.JSONPointer(), "."),
+		In:      validationLocationBody,
+		Message: schemaError.Reason,
+	}}
+}
+
+// requestErrorReason returns the reason of the request error, falling back to the cause.
+func requestErrorReason(requestError *openapi3filter.RequestError) string {
+	var schemaError *openapi3.SchemaError
+	if errors.As(requestError.Err, &schemaError) {
+		return schemaError.Reason
+	}
+
+	if requestError.Reason != "" {
+		return requestError.Reason
+	}
+
+	if requestError.Err != nil {
+		return requestError.Err.Error()
+	}
+
+	return "invalid request"
+}
@@ -0,0 +1,233 @@
+package middleware
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testValidationSpec is the spec of the endpoints behind the validation middleware.
+const testValidationSpec = `
+openapi: 3.0.3
+info:
+  title: test
+  version: 1.0.0
+servers:
+  - url: https://api.example.com
+paths:
+  /users:
+    get:
+      parameters:
+        - name: limit
+          in: query
+          schema:
+            type: integer
+            maximum: 100
+        - name: X-Tenant-ID
+          in: header
+          required: true
+          schema:
+            type: string
+      responses:
+        "200":
+          description: OK
+    post:
+      requestBody:
+        required: true
+        content:
+          application/json:
+            schema:
+              type: object
+              required:
+                - email
+              properties:
+                email:
+                  type: string
+                age:
+                  type: integer
+      responses:
+        "200":
+          description: OK
+`
+
+func TestValidation(t *testing.T) {
+	t.Parallel()
+
+	spec, err := openapi3.NewLoader().LoadFromData([]byte(testValidationSpec))
+	require.NoError(t, err)
+
+	validation, err := Validation(spec, setupTestLogger(t), prometheus.NewRegistry())
+	require.NoError(t, err)
+
+	handler := validation(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		// the body is still readable by the handler after validation
+		var body map[string]interface{}
+		if request.Body != nil && request.ContentLength != 0 {
+			if err := json.NewDecoder(request.Body).Decode(&body); err != nil {
+				writer.WriteHeader(http.StatusInternalServerError)
+
+				return
+			}
+		}
+
+		writer.WriteHeader(http.StatusOK)
+	}))
+
+	tests := []struct {
+		name       string
+		method     string
+		path       string
+		header     map[string]string
+		body       string
+		wantStatus int
+		wantFields []*FieldError
+	}{
+		{
+			name:       "allow valid query and header",
+			method:     http.MethodGet,
+			path:       "/users?limit=10",
+			header:     map[string]string{"X-Tenant-ID": "acme"},
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "reject invalid query",
+			method:     http.MethodGet,
+			path:       "/users?limit=1000",
+			header:     map[string]string{"X-Tenant-ID": "acme"},
+			wantStatus: http.StatusBadRequest,
+			wantFields: []*FieldError{{Field: "limit", In: "query", Message: "number must be at most 100"}},
+		},
+		{
+			name:       "reject missing header",
+			method:     http.MethodGet,
+			path:       "/users",
+			wantStatus: http.StatusBadRequest,
+			wantFields: []*FieldError{{Field: "X-Tenant-ID", In: "header", Message: "value is required but missing"}},
+		},
+		{
+			name:       "allow valid body",
+			method:     http.MethodPost,
+			path:       "/users",
+			body:       `{"email":"user@example.com","age":20}`,
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "reject invalid body fields",
+			method:     http.MethodPost,
+			path:       "/users",
+			body:       `{"age":"twenty"}`,
+			wantStatus: http.StatusBadRequest,
+			wantFields: []*FieldError{
+				{Field: "email", In: "body", Message: `property "email" is missing`},
+				{Field: "age", In: "body", Message: `value must be an integer`},
+			},
+		},
+		{
+			name:       "pass through unknown operations",
+			method:     http.MethodPost,
+			path:       "/orders",
+			body:       `{}`,
+			wantStatus: http.StatusOK,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			request := httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body))
+			if tt.body != "" {
+				request.Header.Set("Content-Type", "application/json")
+			}
+
+			for key, value := range tt.header {
+				request.Header.Set(key, value)
+			}
+
+			recorder := httptest.NewRecorder()
+			handler.ServeHTTP(recorder, request)
+
+			require.Equal(t, tt.wantStatus, recorder.Code)
+
+			if tt.wantFields == nil {
+				return
+			}
+
+			var response struct {
+				Code    string             `json:"code"`
+				Details *ValidationDetails `json:"details"`
+			}
+
+			require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &response))
+			assert.Equal(t, "invalid_request", response.Code)
+			require.NotNil(t, response.Details)
+			assert.ElementsMatch(t, tt.wantFields, response.Details.Fields)
+		})
+	}
+}
+
+func TestValidationMetrics(t *testing.T) {
+	t.Parallel()
+
+	spec, err := openapi3.NewLoader().LoadFromData([]byte(testValidationSpec))
+	require.NoError(t, err)
+
+	registry := prometheus.NewRegistry()
+
+	validation, err := Validation(spec, setupTestLogger(t), registry)
+	require.NoError(t, err)
+
+	handler := validation(http.HandlerFunc(func(writer http.ResponseWriter, _ *http.Request) {
+		writer.WriteHeader(http.StatusOK)
+	}))
+
+	requests := []*http.Request{
+		httptest.NewRequest(http.MethodPost, "/users", strings.NewReader(`{"age":"twenty"}`)),
+		httptest.NewRequest(http.MethodPost, "/users", strings.NewReader(`{"email":"user@example.com","age":1.5}`)),
+		httptest.NewRequest(http.MethodPost, "/users", strings.NewReader(`{"email":"user@example.com"}`)),
+		httptest.NewRequest(http.MethodGet, "/users?limit=1000", nil),
+	}
+
+	for _, request := range requests {
+		request.Header.Set("Content-Type", "application/json")
+		handler.ServeHTTP(httptest.NewRecorder(), request)
+	}
+
+	assert.Equal(t, 4, testutil.CollectAndCount(registry, "http_request_validation_failures_total"))
+
+	expected := `
+# HELP http_request_validation_failures_total Total number of request fields failing OpenAPI validation
+# TYPE http_request_validation_failures_total counter
+http_request_validation_failures_total{field="age",in="body",method="POST",route="/users"} 2
+http_request_validation_failures_total{field="email",in="body",method="POST",route="/users"} 1
+http_request_validation_failures_total{field="limit",in="query",method="GET",route="/users"} 1
+http_request_validation_failures_total{field="X-Tenant-ID",in="header",method="GET",route="/users"} 1
+`
+	require.NoError(t, testutil.GatherAndCompare(registry, strings.NewReader(expected)))
+}
+
+func TestMetricField(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]string{
+		"":                  "",
+		"email":             "email",
+		"items.0.name":      "items.*.name",
+		"items.12.3":        "items.*.*",
+		"0":                 "*",
+		"address.line2":     "address.line2",
+		"matrix.1.2.3.cell": "matrix.*.*.*.cell",
+	}
+
+	for field, expected := range tests {
+		assert.Equal(t, expected, metricField(field), field)
+	}
+}
@@ -0,0 +1,51 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/pocj8ur4in/boilerplate-go/internal/app/boilerplate/server/middleware"
+	"github.com/pocj8ur4in/boilerplate-go/internal/gen/api"
+	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/apimock"
+	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/logger"
+)
+
+// NewMockHandler creates a handler serving responses of the OpenAPI spec from its examples and schemas instead of
+// the handlers, for developing clients without the database and redis. Requests are validated and CORS is applied
+// as configured on the server.
+func NewMockHandler(config *Config, logger *logger.Logger) (http.Handler, error) {
+	if config == nil {
+		config = &Config{}
+	}
+
+	config.SetDefault()
+
+	spec, err := api.GetSwagger()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load openapi spec: %w", err)
+	}
+
+	mock, err := apimock.New(spec)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create mock: %w", err)
+	}
+
+	router := chi.NewRouter()
+	router.Use(corsMiddleware(config))
+
+	if *config.Validation.Enabled {
+		validation, err := middleware.Validation(spec, logger, prometheus.NewRegistry())
+		if err != nil {
+			return nil, err
+		}
+
+		router.Use(validation)
+	}
+
+	router.Handle("/*", mock)
+
+	return router, nil
+}
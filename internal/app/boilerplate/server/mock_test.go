@@ -0,0 +1,65 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/apimock"
+	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/logger"
+)
+
+func TestNewMockHandler(t *testing.T) {
+	t.Parallel()
+
+	log, err := logger.New(&logger.Config{})
+	require.NoError(t, err)
+
+	handler, err := NewMockHandler(nil, log)
+	require.NoError(t, err)
+
+	t.Run("serve mocked responses", func(t *testing.T) {
+		t.Parallel()
+
+		request := httptest.NewRequest(http.MethodPost, "/auth/login",
+			strings.NewReader(`{"email":"user@example.com","password":"secret"}`))
+		request.Header.Set("Content-Type", "application/json")
+
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, request)
+
+		assert.Equal(t, http.StatusOK, recorder.Code)
+		assert.Equal(t, "true", recorder.Header().Get(apimock.HeaderMock))
+		assert.Contains(t, recorder.Body.String(), "access_token")
+	})
+
+	t.Run("validate requests", func(t *testing.T) {
+		t.Parallel()
+
+		request := httptest.NewRequest(http.MethodPost, "/auth/login", strings.NewReader(`{"email":"user@example.com"}`))
+		request.Header.Set("Content-Type", "application/json")
+
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, request)
+
+		assert.Equal(t, http.StatusBadRequest, recorder.Code)
+		assert.Empty(t, recorder.Header().Get(apimock.HeaderMock))
+	})
+
+	t.Run("apply cors", func(t *testing.T) {
+		t.Parallel()
+
+		request := httptest.NewRequest(http.MethodOptions, "/auth/login", nil)
+		request.Header.Set("Origin", "http://localhost:3000")
+		request.Header.Set("Access-Control-Request-Method", http.MethodPost)
+
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, request)
+
+		assert.NotEmpty(t, recorder.Header().Get("Access-Control-Allow-Origin"))
+	})
+}
@@ -0,0 +1,102 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"go.uber.org/fx"
+
+	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/proxy"
+)
+
+var (
+	// ErrInvalidMount is returned when a mount has an invalid path, target or rewrite rule.
+	ErrInvalidMount = errors.New("invalid mount")
+
+	// ErrMountConflict is returned when a mount path is already routed by server or another mount.
+	ErrMountConflict = errors.New("mount path is already routed")
+)
+
+// mountPathPattern matches mount paths, static paths below the root with an optional trailing slash.
+var mountPathPattern = regexp.MustCompile(`^(/[A-Za-z0-9._~-]+)+/?$`)
+
+// MountConfig represents configuration for a reverse proxy mounted on a path of server.
+type MountConfig struct {
+	// Path is path the proxy is mounted on, requests to the path and below it are proxied (e.g. /legacy/).
+	Path *string `json:"path"`
+
+	// Config is configuration of the proxy, e.g. its target, in the same object as the path.
+	proxy.Config
+}
+
+// Mount represents a handler mounted on a path of server, modules provide mounts in the server_mounts group.
+type Mount struct {
+	// Pattern is path the handler is mounted on, requests to the path and below it are routed to the handler.
+	Pattern string
+
+	// Handler handles requests with their full path.
+	Handler http.Handler
+}
+
+// Mounts is handlers mounted on server by modules.
+type Mounts []Mount
+
+// MountsParams represents handlers mounted by modules.
+type MountsParams struct {
+	fx.In
+
+	Mounts []Mount `group:"server_mounts"`
+}
+
+// NewMounts collects handlers mounted by modules, e.g. provided with
+// fx.Annotate(newLegacyMount, fx.ResultTags(`group:"server_mounts"`)).
+func NewMounts(params MountsParams) Mounts {
+	return params.Mounts
+}
+
+// setMountsDefault sets default values for mounts on server.
+func (c *Config) setMountsDefault() {
+	for _, mount := range c.Mounts {
+		if mount.Path == nil {
+			mount.Path = &[]string{""}[0]
+		}
+
+		mount.Config.SetDefault()
+	}
+}
+
+// setupMounts mounts the configured reverse proxies and the handlers of modules on the router.
+func (s *Server) setupMounts(router *chi.Mux, config *Config, mounts Mounts) error {
+	all := make(Mounts, 0, len(config.Mounts)+len(mounts))
+
+	for _, mountConfig := range config.Mounts {
+		mountProxy, err := proxy.New(&mountConfig.Config, *mountConfig.Path, s.logger)
+		if err != nil {
+			return fmt.Errorf("%w: %w", ErrInvalidMount, err)
+		}
+
+		s.proxies = append(s.proxies, mountProxy)
+		all = append(all, Mount{Pattern: *mountConfig.Path, Handler: mountProxy})
+	}
+
+	for _, mount := range append(all, mounts...) {
+		if !mountPathPattern.MatchString(mount.Pattern) || mount.Handler == nil {
+			return fmt.Errorf("%w: path %q", ErrInvalidMount, mount.Pattern)
+		}
+
+		// chi panics on mounting an existing path, so conflicts are returned as errors
+		pattern := strings.TrimSuffix(mount.Pattern, "/")
+		if router.Match(chi.NewRouteContext(), http.MethodGet, pattern) ||
+			router.Match(chi.NewRouteContext(), http.MethodGet, pattern+"/") {
+			return fmt.Errorf("%w: %s", ErrMountConflict, pattern)
+		}
+
+		router.Mount(pattern, mount.Handler)
+	}
+
+	return nil
+}
@@ -0,0 +1,168 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/logger"
+	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/proxy"
+)
+
+// proxiedRequest represents a request received by the test backend.
+type proxiedRequest struct {
+	Path          string `json:"path"`
+	Query         string `json:"query"`
+	Host          string `json:"host"`
+	ForwardedHost string `json:"forwarded_host"`
+	Header        string `json:"header"`
+}
+
+// newTestBackend creates a backend responding with the request it received.
+func newTestBackend(t *testing.T) *httptest.Server {
+	t.Helper()
+
+	backend := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		writer.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(writer).Encode(proxiedRequest{
+			Path:          request.URL.Path,
+			Query:         request.URL.RawQuery,
+			Host:          request.Host,
+			ForwardedHost: request.Header.Get("X-Forwarded-Host"),
+			Header:        request.Header.Get("X-Legacy-Client"),
+		})
+	}))
+	t.Cleanup(backend.Close)
+
+	return backend
+}
+
+// newTestMountServer creates a test server with the given mounts.
+func newTestMountServer(t *testing.T, configs []*MountConfig, mounts Mounts) (*Server, error) {
+	t.Helper()
+
+	log, err := logger.New(&logger.Config{Level: &[]string{"info"}[0]})
+	require.NoError(t, err)
+
+	return New(ServerParams{
+		Config:     &Config{Mounts: configs},
+		Logger:     log,
+		APIHandler: &mockAPIHandler{},
+		JWT:        setupTestJWT(t),
+		Redis:      setupTestRedis(t),
+		Mounts:     mounts,
+	})
+}
+
+// proxyRequest sends the request to the server and decodes the request received by the backend.
+func proxyRequest(t *testing.T, server *Server, target string) proxiedRequest {
+	t.Helper()
+
+	recorder := httptest.NewRecorder()
+	server.Handler().ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, target, nil))
+	require.Equal(t, http.StatusOK, recorder.Code, recorder.Body.String())
+
+	var received proxiedRequest
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &received))
+
+	return received
+}
+
+func TestMountsDefault(t *testing.T) {
+	t.Parallel()
+
+	config := &Config{Mounts: []*MountConfig{{}}}
+	config.SetDefault()
+
+	mount := config.Mounts[0]
+	assert.Empty(t, *mount.Path)
+	assert.Empty(t, *mount.Target)
+	assert.False(t, *mount.StripPrefix)
+	assert.Equal(t, 30, *mount.Timeout)
+	assert.Empty(t, *mount.HealthCheck.Path)
+}
+
+//nolint:paralleltest // sequential execution required to avoid redis key conflicts
+func TestMountRoutes(t *testing.T) {
+	t.Run("proxy requests to the backend", func(t *testing.T) {
+		backend := newTestBackend(t)
+
+		server, err := newTestMountServer(t, []*MountConfig{{
+			Path: &[]string{"/legacy/"}[0],
+			Config: proxy.Config{
+				Target:  &[]string{backend.URL + "/app"}[0],
+				Headers: map[string]string{"X-Legacy-Client": "boilerplate"},
+			},
+		}}, nil)
+		require.NoError(t, err)
+
+		received := proxyRequest(t, server, "http://example.com/legacy/users?page=2")
+		assert.Equal(t, "/app/legacy/users", received.Path)
+		assert.Equal(t, "page=2", received.Query)
+		assert.Equal(t, backend.Listener.Addr().String(), received.Host)
+		assert.Equal(t, "example.com", received.ForwardedHost)
+		assert.Equal(t, "boilerplate", received.Header)
+
+		// the mount path itself is proxied as well
+		assert.Equal(t, "/app/legacy", proxyRequest(t, server, "/legacy").Path)
+	})
+
+	t.Run("decode mounts with proxy configuration", func(t *testing.T) {
+		backend := newTestBackend(t)
+
+		var configs []*MountConfig
+		require.NoError(t, json.Unmarshal([]byte(`[{
+			"path": "/legacy",
+			"targets": ["`+backend.URL+`"],
+			"strip_prefix": true,
+			"rewrites": [{"pattern": "^/users/(\\d+)$", "replacement": "/user.php/$1"}]
+		}]`), &configs))
+
+		server, err := newTestMountServer(t, configs, nil)
+		require.NoError(t, err)
+
+		assert.Equal(t, "/user.php/42", proxyRequest(t, server, "/legacy/users/42").Path)
+	})
+
+	t.Run("mount handlers of modules", func(t *testing.T) {
+		handler := http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+			writeJSON(writer, http.StatusOK, proxiedRequest{Path: request.URL.Path})
+		})
+
+		server, err := newTestMountServer(t, nil, Mounts{{Pattern: "/webhooks/", Handler: handler}})
+		require.NoError(t, err)
+
+		assert.Equal(t, "/webhooks/github", proxyRequest(t, server, "/webhooks/github").Path)
+	})
+
+	t.Run("return error for invalid mounts", func(t *testing.T) {
+		configs := []*MountConfig{
+			{Path: &[]string{"/"}[0], Config: proxy.Config{Target: &[]string{"http://localhost"}[0]}},
+			{Path: &[]string{"/legacy/{id}"}[0], Config: proxy.Config{Target: &[]string{"http://localhost"}[0]}},
+			{Path: &[]string{"/legacy"}[0], Config: proxy.Config{Target: &[]string{"localhost:8080"}[0]}},
+			{Path: &[]string{"/legacy"}[0]},
+		}
+
+		for _, config := range configs {
+			_, err := newTestMountServer(t, []*MountConfig{config}, nil)
+			require.ErrorIs(t, err, ErrInvalidMount, *config.Path)
+		}
+	})
+
+	t.Run("return error for conflicting mounts", func(t *testing.T) {
+		handler := http.NotFoundHandler()
+
+		_, err := newTestMountServer(t, []*MountConfig{{
+			Path:   &[]string{"/legacy"}[0],
+			Config: proxy.Config{Target: &[]string{"http://localhost"}[0]},
+		}}, Mounts{{Pattern: "/legacy/", Handler: handler}})
+		require.ErrorIs(t, err, ErrMountConflict)
+
+		_, err = newTestMountServer(t, nil, Mounts{{Pattern: "/docs/errors", Handler: handler}})
+		require.ErrorIs(t, err, ErrMountConflict)
+	})
+}
@@ -0,0 +1,97 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/pocj8ur4in/boilerplate-go/internal/app/boilerplate/server/middleware"
+	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/jobs"
+	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/jwt"
+	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/websocket"
+)
+
+const (
+	// operationsPath is the path prefix of operation endpoints.
+	operationsPath = "/operations"
+
+	// jobStatusEvent is type of websocket messages carrying statuses of jobs.
+	jobStatusEvent = "job.status"
+)
+
+// jobStatusMessage represents a websocket message carrying the status of a job.
+type jobStatusMessage struct {
+	// Type is type of the message.
+	Type string `json:"type"`
+
+	// Data is the status of the job.
+	Data *jobs.Status `json:"data"`
+}
+
+// setupOperationRoutes sets up the endpoint reading statuses of background jobs run for users authenticated
+// with JWT.
+func (s *Server) setupOperationRoutes(router *chi.Mux, jwtService *jwt.JWT) {
+	if s.jobs == nil {
+		return
+	}
+
+	router.Route(operationsPath, func(router chi.Router) {
+		router.Use(middleware.RequireBearerAuth)
+		router.Use(middleware.JWTAuth(jwtService, s.logger))
+
+		router.Get("/{id}", s.handleGetOperation)
+	})
+}
+
+// handleGetOperation handles GET /operations/{id} endpoint, responding with the status, progress and result of
+// the job. Jobs of other users are reported as not found.
+func (s *Server) handleGetOperation(writer http.ResponseWriter, request *http.Request) {
+	userID, _ := request.Context().Value(middleware.UserIDKey).(string)
+
+	status, err := s.jobs.Status(request.Context(), chi.URLParam(request, "id"))
+	if errors.Is(err, jobs.ErrNotFound) || (err == nil && status.UserID != userID) {
+		writeError(writer, http.StatusNotFound, "operation not found")
+
+		return
+	}
+
+	if err != nil {
+		s.logger.Ctx(request.Context()).Error().Err(err).Msg("failed to get operation")
+		writeError(writer, http.StatusInternalServerError, "failed to get operation")
+
+		return
+	}
+
+	writeJSON(writer, http.StatusOK, status)
+}
+
+// watchJobs sends statuses of jobs updated on any instance to websocket connections of their users, returns a
+// function stopping it.
+func (s *Server) watchJobs() func() {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	if err := s.jobs.Watch(ctx, s.sendJobStatus); err != nil {
+		s.logger.Warn().Err(err).Msg("failed to watch job statuses, progress is not sent to websocket connections")
+	}
+
+	return cancel
+}
+
+// sendJobStatus sends the status of the job to websocket connections of its user on this instance.
+func (s *Server) sendJobStatus(status *jobs.Status) {
+	if status.UserID == "" {
+		return
+	}
+
+	data, err := json.Marshal(jobStatusMessage{Type: jobStatusEvent, Data: status})
+	if err != nil {
+		s.logger.Warn().Err(err).Str("job_id", status.ID).Msg("failed to encode job status")
+
+		return
+	}
+
+	s.hub.Send(status.UserID, websocket.TextMessage, data)
+}
@@ -0,0 +1,122 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/jobs"
+	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/logger"
+	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/websocket"
+)
+
+// newTestOperationServer creates a test server with jobs and a websocket hub and serves its handler.
+func newTestOperationServer(t *testing.T) (*Server, *jobs.Jobs, *websocket.Hub, string) {
+	t.Helper()
+
+	log, err := logger.New(&logger.Config{Level: &[]string{"info"}[0]})
+	require.NoError(t, err)
+
+	redisClient := setupTestRedis(t)
+	prefix := fmt.Sprintf("{jobs:server:%d}:", time.Now().UnixNano())
+
+	backgroundJobs, err := jobs.New(&jobs.Config{Prefix: &prefix}, redisClient, nil, log)
+	require.NoError(t, err)
+
+	hub, err := websocket.New(nil, log)
+	require.NoError(t, err)
+
+	server, err := New(ServerParams{
+		Logger:     log,
+		APIHandler: &mockAPIHandler{},
+		JWT:        setupTestJWT(t),
+		Redis:      redisClient,
+		Hub:        hub,
+		Jobs:       backgroundJobs,
+	})
+	require.NoError(t, err)
+
+	httpServer := httptest.NewServer(server.Handler())
+	t.Cleanup(httpServer.Close)
+
+	return server, backgroundJobs, hub, httpServer.URL
+}
+
+func TestOperationRoutes(t *testing.T) {
+	t.Parallel()
+
+	jwtService := setupTestJWT(t)
+
+	token, err := jwtService.GenerateAccessToken("user-1", "user@example.com", "user")
+	require.NoError(t, err)
+
+	otherToken, err := jwtService.GenerateAccessToken("user-2", "other@example.com", "user")
+	require.NoError(t, err)
+
+	t.Run("return status of jobs of the user", func(t *testing.T) {
+		t.Parallel()
+
+		server, backgroundJobs, _, _ := newTestOperationServer(t)
+
+		job, err := backgroundJobs.Enqueue(context.Background(), "export", nil, &jobs.EnqueueOptions{UserID: "user-1"})
+		require.NoError(t, err)
+
+		// serve sends the request with the token, returns the response.
+		serve := func(id, token string) *httptest.ResponseRecorder {
+			request := httptest.NewRequest(http.MethodGet, "/operations/"+id, nil)
+			if token != "" {
+				request.Header.Set("Authorization", "Bearer "+token)
+			}
+
+			recorder := httptest.NewRecorder()
+			server.Handler().ServeHTTP(recorder, request)
+
+			return recorder
+		}
+
+		recorder := serve(job.ID, *token)
+		require.Equal(t, http.StatusOK, recorder.Code)
+
+		var status jobs.Status
+		require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &status))
+		assert.Equal(t, job.ID, status.ID)
+		assert.Equal(t, jobs.StateQueued, status.State)
+
+		assert.Equal(t, http.StatusNotFound, serve(job.ID, *otherToken).Code)
+		assert.Equal(t, http.StatusNotFound, serve("missing", *token).Code)
+		assert.Equal(t, http.StatusUnauthorized, serve(job.ID, "").Code)
+	})
+
+	t.Run("send statuses to websocket connections of the user", func(t *testing.T) {
+		t.Parallel()
+
+		server, backgroundJobs, hub, serverURL := newTestOperationServer(t)
+
+		stop := server.watchJobs()
+		defer stop()
+
+		conn, reader, status := dialWebSocket(t, serverURL, "/ws?access_token="+*token, http.Header{})
+		require.Equal(t, http.StatusSwitchingProtocols, status)
+
+		require.Eventually(t, func() bool { return hub.Connections("user-1") == 1 }, time.Second, 5*time.Millisecond)
+
+		job, err := backgroundJobs.Enqueue(context.Background(), "export", nil, &jobs.EnqueueOptions{UserID: "user-1"})
+		require.NoError(t, err)
+
+		_, payload := readWebSocketFrame(t, conn, reader)
+
+		var message jobStatusMessage
+		require.NoError(t, json.Unmarshal(payload, &message))
+		assert.Equal(t, jobStatusEvent, message.Type)
+		require.NotNil(t, message.Data)
+		assert.Equal(t, job.ID, message.Data.ID)
+		assert.Equal(t, jobs.StateQueued, message.Data.State)
+	})
+}
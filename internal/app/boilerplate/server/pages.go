@@ -0,0 +1,110 @@
+package server
+
+import (
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	chiMiddleware "github.com/go-chi/chi/v5/middleware"
+
+	"github.com/pocj8ur4in/boilerplate-go/internal/app/boilerplate/server/middleware"
+	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/render"
+)
+
+// PagesConfig represents configuration for server-rendered pages.
+type PagesConfig struct {
+	// Enabled is whether server-rendered pages are enabled.
+	Enabled *bool `json:"enabled"`
+}
+
+// setPagesDefault sets default values for server-rendered pages on server.
+func (c *Config) setPagesDefault() {
+	if c.Pages == nil {
+		c.Pages = &PagesConfig{}
+	}
+
+	if c.Pages.Enabled == nil {
+		c.Pages.Enabled = &[]bool{false}[0]
+	}
+}
+
+// homePage represents data of the home page.
+type homePage struct {
+	// Heading is heading of the page.
+	Heading string
+
+	// Message is message of the page.
+	Message string
+}
+
+// errorPage represents data of the error page.
+type errorPage struct {
+	// Status is HTTP status code of the page.
+	Status int
+
+	// Message is message of the page.
+	Message string
+}
+
+// setupPageRoutes sets up server-rendered pages.
+func (s *Server) setupPageRoutes(router *chi.Mux, config *Config, renderer *render.Render) {
+	if !*config.Pages.Enabled || renderer == nil {
+		return
+	}
+
+	s.renderer = renderer
+	s.renderer.Use(injectRequestID, injectUser, injectCSRFToken(*config.CSRF.CookieName))
+
+	router.Get("/", s.handleHomePage)
+}
+
+// handleHomePage handles GET / endpoint.
+func (s *Server) handleHomePage(writer http.ResponseWriter, request *http.Request) {
+	s.renderPage(writer, request, http.StatusOK, "home", homePage{
+		Heading: "boilerplate",
+		Message: "server-rendered pages are served alongside the JSON API.",
+	})
+}
+
+// renderPage renders the page, falling back to the error page on failure.
+func (s *Server) renderPage(writer http.ResponseWriter, request *http.Request, code int, name string, data interface{}) {
+	err := s.renderer.HTML(writer, request, code, name, data)
+	if err == nil {
+		return
+	}
+
+	s.logger.Ctx(request.Context()).Error().Err(err).Str("page", name).Msg("failed to render page")
+
+	err = s.renderer.HTML(writer, request, http.StatusInternalServerError, "error", errorPage{
+		Status:  http.StatusInternalServerError,
+		Message: http.StatusText(http.StatusInternalServerError),
+	})
+	if err != nil {
+		http.Error(writer, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+	}
+}
+
+// injectRequestID injects the request ID into page data.
+func injectRequestID(request *http.Request, page *render.PageData) {
+	page.RequestID = chiMiddleware.GetReqID(request.Context())
+}
+
+// injectUser injects the authenticated user into page data.
+func injectUser(request *http.Request, page *render.PageData) {
+	page.User, _ = request.Context().Value(middleware.UserEmailKey).(string)
+}
+
+// injectCSRFToken returns a hook injecting the CSRF token of the CSRF middleware, or else of the double-submit
+// cookie of the name, into page data.
+func injectCSRFToken(cookieName string) render.Injector {
+	return func(request *http.Request, page *render.PageData) {
+		if token, ok := request.Context().Value(middleware.CSRFTokenKey).(string); ok {
+			page.CSRFToken = token
+
+			return
+		}
+
+		if cookie, err := request.Cookie(cookieName); err == nil {
+			page.CSRFToken = cookie.Value
+		}
+	}
+}
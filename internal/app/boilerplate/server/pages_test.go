@@ -0,0 +1,120 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pocj8ur4in/boilerplate-go/internal/app/boilerplate/server/middleware"
+	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/logger"
+	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/render"
+)
+
+//nolint:paralleltest // sequential execution required to avoid redis key conflicts
+func TestPageRoutes(t *testing.T) {
+	t.Run("render home page when pages are enabled", func(t *testing.T) {
+		log, err := logger.New(&logger.Config{Level: &[]string{"info"}[0]})
+		require.NoError(t, err)
+
+		renderer, err := render.New(nil)
+		require.NoError(t, err)
+
+		cfg := &Config{
+			Pages: &PagesConfig{Enabled: &[]bool{true}[0]},
+			CSRF:  &middleware.CSRFConfig{CookieName: &[]string{"xsrf_token"}[0]},
+		}
+
+		server, err := New(ServerParams{
+			Config:     cfg,
+			Logger:     log,
+			APIHandler: &mockAPIHandler{},
+			JWT:        setupTestJWT(t),
+			Redis:      setupTestRedis(t),
+			Renderer:   renderer,
+		})
+		require.NoError(t, err)
+
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.AddCookie(&http.Cookie{Name: "csrf_token", Value: "ignored-token"})
+		req.AddCookie(&http.Cookie{Name: "xsrf_token", Value: "csrf-token"})
+
+		recorder := httptest.NewRecorder()
+		server.httpServer.Handler.ServeHTTP(recorder, req)
+
+		assert.Equal(t, http.StatusOK, recorder.Code)
+		assert.Contains(t, recorder.Header().Get("Content-Type"), "text/html")
+		assert.Contains(t, recorder.Body.String(), "<h1>boilerplate</h1>")
+		assert.Contains(t, recorder.Body.String(), `content="csrf-token"`)
+	})
+
+	t.Run("render issued csrf token and require it on writes", func(t *testing.T) {
+		log, err := logger.New(&logger.Config{Level: &[]string{"info"}[0]})
+		require.NoError(t, err)
+
+		renderer, err := render.New(nil)
+		require.NoError(t, err)
+
+		cfg := &Config{
+			Pages: &PagesConfig{Enabled: &[]bool{true}[0]},
+			CSRF:  &middleware.CSRFConfig{Enabled: &[]bool{true}[0]},
+		}
+
+		server, err := New(ServerParams{
+			Config:     cfg,
+			Logger:     log,
+			APIHandler: &mockAPIHandler{},
+			JWT:        setupTestJWT(t),
+			Redis:      setupTestRedis(t),
+			Renderer:   renderer,
+		})
+		require.NoError(t, err)
+
+		recorder := httptest.NewRecorder()
+		server.httpServer.Handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/", nil))
+		require.Equal(t, http.StatusOK, recorder.Code)
+
+		cookies := recorder.Result().Cookies()
+		require.Len(t, cookies, 1)
+		assert.Contains(t, recorder.Body.String(), `content="`+cookies[0].Value+`"`)
+
+		// post sends a write with the cookie and the submitted token, returns the status.
+		post := func(token string) int {
+			req := httptest.NewRequest(http.MethodPost, "/status", nil)
+			req.AddCookie(cookies[0])
+			req.Header.Set("X-CSRF-Token", token)
+
+			recorder := httptest.NewRecorder()
+			server.httpServer.Handler.ServeHTTP(recorder, req)
+
+			return recorder.Code
+		}
+
+		assert.Equal(t, http.StatusForbidden, post(""))
+		assert.NotEqual(t, http.StatusForbidden, post(cookies[0].Value))
+	})
+
+	t.Run("skip pages when disabled", func(t *testing.T) {
+		log, err := logger.New(&logger.Config{Level: &[]string{"info"}[0]})
+		require.NoError(t, err)
+
+		renderer, err := render.New(nil)
+		require.NoError(t, err)
+
+		server, err := New(ServerParams{
+			Logger:     log,
+			APIHandler: &mockAPIHandler{},
+			JWT:        setupTestJWT(t),
+			Redis:      setupTestRedis(t),
+			Renderer:   renderer,
+		})
+		require.NoError(t, err)
+
+		recorder := httptest.NewRecorder()
+		server.httpServer.Handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/", nil))
+
+		assert.Equal(t, http.StatusNotFound, recorder.Code)
+	})
+}
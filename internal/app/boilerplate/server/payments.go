@@ -0,0 +1,46 @@
+package server
+
+import (
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/payments"
+)
+
+// maxWebhookSize is maximum size of webhook events in bytes.
+const maxWebhookSize = 1 << 20
+
+// setupPaymentRoutes sets up the webhook endpoint of Stripe, authenticated by its signature instead of a token.
+func (s *Server) setupPaymentRoutes(router *chi.Mux) {
+	if s.payments == nil {
+		return
+	}
+
+	router.Post(s.payments.WebhookPath(), s.handleStripeWebhook)
+}
+
+// handleStripeWebhook handles webhook events of Stripe, failures other than invalid signatures get 500
+// so that Stripe delivers the event again.
+func (s *Server) handleStripeWebhook(writer http.ResponseWriter, request *http.Request) {
+	payload, err := io.ReadAll(http.MaxBytesReader(writer, request.Body, maxWebhookSize))
+	if err != nil {
+		writeError(writer, http.StatusBadRequest, "invalid request body")
+
+		return
+	}
+
+	err = s.payments.HandleWebhook(request.Context(), payload, request.Header.Get("Stripe-Signature"))
+
+	switch {
+	case err == nil:
+		writer.WriteHeader(http.StatusNoContent)
+	case errors.Is(err, payments.ErrInvalidSignature):
+		writeError(writer, http.StatusBadRequest, "invalid webhook signature")
+	default:
+		s.logger.Ctx(request.Context()).Error().Err(err).Msg("failed to handle stripe webhook")
+		writeError(writer, http.StatusInternalServerError, "failed to handle webhook")
+	}
+}
@@ -0,0 +1,109 @@
+package server
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/logger"
+	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/payments"
+)
+
+// newTestPaymentsServer creates a test server with payments enabled or disabled.
+func newTestPaymentsServer(t *testing.T, enabled bool) *Server {
+	t.Helper()
+
+	log, err := logger.New(&logger.Config{Level: &[]string{"info"}[0]})
+	require.NoError(t, err)
+
+	redisClient := setupTestRedis(t)
+	paymentsService, err := payments.NewWithQuerier(&payments.Config{
+		Enabled:       &enabled,
+		WebhookSecret: &[]string{"whsec_test"}[0],
+	}, nil, nil, redisClient, log)
+	require.NoError(t, err)
+
+	server, err := New(ServerParams{
+		Logger:     log,
+		APIHandler: &mockAPIHandler{},
+		JWT:        setupTestJWT(t),
+		Redis:      redisClient,
+		Payments:   paymentsService,
+	})
+	require.NoError(t, err)
+
+	return server
+}
+
+// webhookRequest sends the payload to the webhook endpoint, signed with the secret if it is not empty.
+func webhookRequest(t *testing.T, server *Server, payload, secret string) *httptest.ResponseRecorder {
+	t.Helper()
+
+	req := httptest.NewRequest(http.MethodPost, "/webhooks/stripe", strings.NewReader(payload))
+
+	if secret != "" {
+		timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+
+		mac := hmac.New(sha256.New, []byte(secret))
+		mac.Write([]byte(timestamp + "." + payload))
+
+		req.Header.Set("Stripe-Signature", "t="+timestamp+",v1="+hex.EncodeToString(mac.Sum(nil)))
+	}
+
+	recorder := httptest.NewRecorder()
+	server.Handler().ServeHTTP(recorder, req)
+
+	return recorder
+}
+
+//nolint:paralleltest // sequential execution required to avoid redis key conflicts
+func TestStripeWebhook(t *testing.T) {
+	t.Run("not register webhook when disabled", func(t *testing.T) {
+		server := newTestPaymentsServer(t, false)
+
+		recorder := webhookRequest(t, server, `{"id": "evt_1", "type": "invoice.paid"}`, "whsec_test")
+
+		assert.NotEqual(t, http.StatusNoContent, recorder.Code)
+	})
+
+	t.Run("accept signed event", func(t *testing.T) {
+		server := newTestPaymentsServer(t, true)
+
+		recorder := webhookRequest(t, server, `{"id": "evt_1", "type": "invoice.paid"}`, "whsec_test")
+
+		assert.Equal(t, http.StatusNoContent, recorder.Code, recorder.Body.String())
+	})
+
+	t.Run("reject unsigned event", func(t *testing.T) {
+		server := newTestPaymentsServer(t, true)
+
+		recorder := webhookRequest(t, server, `{"id": "evt_1", "type": "invoice.paid"}`, "")
+
+		assert.Equal(t, http.StatusBadRequest, recorder.Code)
+	})
+
+	t.Run("reject event signed with other secret", func(t *testing.T) {
+		server := newTestPaymentsServer(t, true)
+
+		recorder := webhookRequest(t, server, `{"id": "evt_1", "type": "invoice.paid"}`, "whsec_other")
+
+		assert.Equal(t, http.StatusBadRequest, recorder.Code)
+	})
+
+	t.Run("return internal error if event cannot be handled", func(t *testing.T) {
+		server := newTestPaymentsServer(t, true)
+
+		recorder := webhookRequest(t, server, `not json`, "whsec_test")
+
+		assert.Equal(t, http.StatusInternalServerError, recorder.Code)
+	})
+}
@@ -0,0 +1,79 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// readOnlyPath is the path of the read-only toggle on the admin router.
+const readOnlyPath = "/read-only"
+
+// readOnlyResponse represents read-only mode returned by the toggle endpoints.
+type readOnlyResponse struct {
+	// Enabled is whether the service is in read-only mode.
+	Enabled bool `json:"enabled"`
+
+	// Forced is whether read-only mode is forced on by configuration, the toggle can not turn it off.
+	Forced bool `json:"forced"`
+
+	// Message is message of rejected requests.
+	Message string `json:"message"`
+}
+
+// readOnlyRequest represents the body of toggle updates.
+type readOnlyRequest struct {
+	// Enabled is whether to turn read-only mode on.
+	Enabled *bool `json:"enabled"`
+}
+
+// readOnlyTogglePath returns the path of the read-only toggle, exempt from read-only mode.
+func readOnlyTogglePath(config *Config) string {
+	return *config.Admin.Path + readOnlyPath
+}
+
+// setupAdminReadOnlyRoutes sets up read-only toggle endpoints on the admin router.
+func (s *Server) setupAdminReadOnlyRoutes(router chi.Router) {
+	if s.readOnly == nil {
+		return
+	}
+
+	router.Get(readOnlyPath, s.handleGetReadOnly)
+	router.Put(readOnlyPath, s.handlePutReadOnly)
+}
+
+// handleGetReadOnly handles GET /admin/read-only endpoint.
+func (s *Server) handleGetReadOnly(writer http.ResponseWriter, request *http.Request) {
+	s.writeReadOnly(writer, request)
+}
+
+// handlePutReadOnly handles PUT /admin/read-only endpoint, applied by all instances within the refresh interval.
+func (s *Server) handlePutReadOnly(writer http.ResponseWriter, request *http.Request) {
+	var body readOnlyRequest
+	if err := json.NewDecoder(request.Body).Decode(&body); err != nil || body.Enabled == nil {
+		writeError(writer, http.StatusBadRequest, "invalid request body")
+
+		return
+	}
+
+	if err := s.readOnly.Set(request.Context(), *body.Enabled); err != nil {
+		s.logger.Ctx(request.Context()).Error().Err(err).Msg("failed to set read-only mode")
+		writeError(writer, http.StatusInternalServerError, "failed to set read-only mode")
+
+		return
+	}
+
+	s.logger.Ctx(request.Context()).Info().Bool("enabled", *body.Enabled).Msg("read-only mode toggled")
+
+	s.writeReadOnly(writer, request)
+}
+
+// writeReadOnly writes the current read-only mode.
+func (s *Server) writeReadOnly(writer http.ResponseWriter, request *http.Request) {
+	writeJSON(writer, http.StatusOK, readOnlyResponse{
+		Enabled: s.readOnly.Enabled(request.Context()),
+		Forced:  s.readOnly.Forced(),
+		Message: s.readOnly.Message(),
+	})
+}
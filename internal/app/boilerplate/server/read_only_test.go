@@ -0,0 +1,81 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/logger"
+	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/readonly"
+)
+
+//nolint:paralleltest // sequential execution required to avoid redis key conflicts
+func TestReadOnlyRoutes(t *testing.T) {
+	log, err := logger.New(&logger.Config{Level: &[]string{"info"}[0]})
+	require.NoError(t, err)
+
+	jwtService := setupTestJWT(t)
+	redisClient := setupTestRedis(t)
+
+	server, err := New(ServerParams{
+		Logger:     log,
+		APIHandler: &mockAPIHandler{},
+		JWT:        jwtService,
+		Redis:      redisClient,
+		ReadOnly:   readonly.New(nil, redisClient),
+	})
+	require.NoError(t, err)
+
+	token, err := jwtService.GenerateAccessToken("admin-1", "admin@example.com", "admin")
+	require.NoError(t, err)
+
+	// serve sends the request as an admin, returns the response.
+	serve := func(method string, path string, body string) *httptest.ResponseRecorder {
+		request := httptest.NewRequest(method, path, strings.NewReader(body))
+		request.Header.Set("Authorization", "Bearer "+*token)
+
+		recorder := httptest.NewRecorder()
+		server.Handler().ServeHTTP(recorder, request)
+
+		return recorder
+	}
+
+	// toggle turns read-only mode on or off, returns the reported mode.
+	toggle := func(body string) readOnlyResponse {
+		recorder := serve(http.MethodPut, "/admin/read-only", body)
+		require.Equal(t, http.StatusOK, recorder.Code)
+
+		var response readOnlyResponse
+		require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &response))
+
+		return response
+	}
+
+	t.Run("reject writes while reads continue", func(t *testing.T) {
+		assert.True(t, toggle(`{"enabled":true}`).Enabled)
+
+		recorder := serve(http.MethodPost, "/status", "")
+		assert.Equal(t, http.StatusServiceUnavailable, recorder.Code)
+		assert.Contains(t, recorder.Body.String(), `"code":"read_only"`)
+
+		assert.Equal(t, http.StatusOK, serve(http.MethodGet, "/status", "").Code)
+		assert.Equal(t, http.StatusOK, serve(http.MethodGet, "/admin/read-only", "").Code)
+	})
+
+	t.Run("turn read-only mode off", func(t *testing.T) {
+		response := toggle(`{"enabled":false}`)
+		assert.False(t, response.Enabled)
+		assert.False(t, response.Forced)
+
+		assert.NotEqual(t, http.StatusServiceUnavailable, serve(http.MethodPost, "/status", "").Code)
+	})
+
+	t.Run("reject invalid body", func(t *testing.T) {
+		assert.Equal(t, http.StatusBadRequest, serve(http.MethodPut, "/admin/read-only", `{}`).Code)
+	})
+}
@@ -0,0 +1,134 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"sync"
+	"sync/atomic"
+
+	"github.com/pocj8ur4in/boilerplate-go/internal/app/boilerplate/server/middleware"
+	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/apierror"
+)
+
+// swappableMiddleware is a middleware whose handler is rebuilt when configuration is reloaded.
+type swappableMiddleware struct {
+	// mu guards next and middleware.
+	mu sync.Mutex
+
+	// next is handler the middleware wraps, nil until it is used on a router.
+	next http.Handler
+
+	// middleware builds the current handler.
+	middleware func(next http.Handler) http.Handler
+
+	// handler is the current handler serving requests.
+	handler atomic.Pointer[http.Handler]
+}
+
+// newSwappableMiddleware creates a swappable middleware serving the middleware.
+func newSwappableMiddleware(middleware func(next http.Handler) http.Handler) *swappableMiddleware {
+	return &swappableMiddleware{middleware: middleware}
+}
+
+// use wraps the next handler, it is passed to router.Use.
+func (m *swappableMiddleware) use(next http.Handler) http.Handler {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.next = next
+	handler := m.middleware(next)
+	m.handler.Store(&handler)
+
+	return m
+}
+
+// swap replaces the middleware, requests in flight finish on the previous handler.
+func (m *swappableMiddleware) swap(middleware func(next http.Handler) http.Handler) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.middleware = middleware
+
+	if m.next == nil {
+		return
+	}
+
+	handler := middleware(m.next)
+	m.handler.Store(&handler)
+}
+
+// ServeHTTP serves the request with the current handler.
+func (m *swappableMiddleware) ServeHTTP(writer http.ResponseWriter, request *http.Request) {
+	(*m.handler.Load()).ServeHTTP(writer, request)
+}
+
+// routeMiddleware is a middleware applied on each route whose middleware is replaced when configuration is reloaded.
+type routeMiddleware struct {
+	// middleware is the current middleware.
+	middleware atomic.Pointer[func(next http.Handler) http.Handler]
+}
+
+// newRouteMiddleware creates a route middleware serving the middleware.
+func newRouteMiddleware(middleware func(next http.Handler) http.Handler) *routeMiddleware {
+	m := &routeMiddleware{}
+	m.middleware.Store(&middleware)
+
+	return m
+}
+
+// use wraps the next handler of a route, unlike swappableMiddleware it can be used on many routes.
+func (m *routeMiddleware) use(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		(*m.middleware.Load())(next).ServeHTTP(writer, request)
+	})
+}
+
+// swap replaces the middleware, requests in flight finish on the previous middleware.
+func (m *routeMiddleware) swap(middleware func(next http.Handler) http.Handler) {
+	m.middleware.Store(&middleware)
+}
+
+// Reload applies rate limit, CORS and error format configuration at runtime, other fields take effect on restart.
+func (s *Server) Reload(config *Config) error {
+	if config == nil {
+		return nil
+	}
+
+	config.SetDefault()
+
+	if err := config.ErrorFormat.Validate(); err != nil {
+		return err
+	}
+
+	if err := config.RateLimit.Validate(); err != nil {
+		return fmt.Errorf("invalid rate limit config: %w", err)
+	}
+
+	rateLimitRules, err := middleware.NewRateLimitRules(config.RateLimit.Exemptions)
+	if err != nil {
+		return fmt.Errorf("invalid rate limit config: %w", err)
+	}
+
+	rateLimitFallback, err := middleware.NewRateLimitFallback(*config.RateLimit.FailureMode, s.registry)
+	if err != nil {
+		return err
+	}
+
+	s.rateLimitRules = rateLimitRules
+	s.rateLimitFallback = rateLimitFallback
+	s.rateLimits.swap(s.rateLimitMiddleware(config, s.redis, s.logger))
+
+	if s.userRateLimit != nil {
+		s.userRateLimit.swap(userRateLimitMiddleware(config, s.redis, s.rateLimitFallback, s.logger))
+	}
+
+	if s.tenantRateLimit != nil {
+		s.tenantRateLimit.swap(s.tenantRateLimitMiddleware(config))
+	}
+
+	s.cors.swap(corsMiddleware(config))
+
+	apierror.SetFormat(*config.ErrorFormat)
+
+	return nil
+}
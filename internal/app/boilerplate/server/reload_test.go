@@ -0,0 +1,211 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pocj8ur4in/boilerplate-go/internal/app/boilerplate/server/middleware"
+	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/logger"
+)
+
+// newReloadTestConfig creates a config with the IP rate limit and allowed origin.
+func newReloadTestConfig(requests int, origin string) *Config {
+	return &Config{
+		RateLimit: &middleware.RateLimitConfig{
+			Global:   &middleware.RateLimitTypeConfig{Enabled: &[]bool{false}[0]},
+			IP:       &middleware.RateLimitTypeConfig{Requests: &requests},
+			Endpoint: &middleware.RateLimitTypeConfig{Enabled: &[]bool{false}[0]},
+		},
+		CORS: &CORSConfig{AllowedOrigins: &[]string{origin}},
+	}
+}
+
+func TestSwappableMiddleware(t *testing.T) {
+	t.Parallel()
+
+	t.Run("serve with swapped middleware", func(t *testing.T) {
+		t.Parallel()
+
+		headerMiddleware := func(value string) func(next http.Handler) http.Handler {
+			return func(next http.Handler) http.Handler {
+				return http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+					writer.Header().Set("X-Test", value)
+					next.ServeHTTP(writer, request)
+				})
+			}
+		}
+
+		swappable := newSwappableMiddleware(headerMiddleware("before"))
+
+		// swapping before use keeps the latest middleware
+		swappable.swap(headerMiddleware("first"))
+
+		handler := swappable.use(http.HandlerFunc(func(writer http.ResponseWriter, _ *http.Request) {
+			writer.WriteHeader(http.StatusOK)
+		}))
+
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/", nil))
+		assert.Equal(t, "first", recorder.Header().Get("X-Test"))
+
+		swappable.swap(headerMiddleware("second"))
+
+		recorder = httptest.NewRecorder()
+		handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/", nil))
+		assert.Equal(t, "second", recorder.Header().Get("X-Test"))
+	})
+}
+
+func TestRouteMiddleware(t *testing.T) {
+	t.Parallel()
+
+	t.Run("serve every route with swapped middleware", func(t *testing.T) {
+		t.Parallel()
+
+		headerMiddleware := func(value string) func(next http.Handler) http.Handler {
+			return func(next http.Handler) http.Handler {
+				return http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+					writer.Header().Set("X-Test", value)
+					next.ServeHTTP(writer, request)
+				})
+			}
+		}
+
+		routeHandler := func(status int) http.Handler {
+			return http.HandlerFunc(func(writer http.ResponseWriter, _ *http.Request) {
+				writer.WriteHeader(status)
+			})
+		}
+
+		route := newRouteMiddleware(headerMiddleware("first"))
+		created := route.use(routeHandler(http.StatusCreated))
+		accepted := route.use(routeHandler(http.StatusAccepted))
+
+		route.swap(headerMiddleware("second"))
+
+		for _, tt := range []struct {
+			handler http.Handler
+			status  int
+		}{{created, http.StatusCreated}, {accepted, http.StatusAccepted}} {
+			recorder := httptest.NewRecorder()
+			tt.handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/", nil))
+			assert.Equal(t, tt.status, recorder.Code)
+			assert.Equal(t, "second", recorder.Header().Get("X-Test"))
+		}
+	})
+}
+
+func TestReload(t *testing.T) {
+	t.Parallel()
+
+	t.Run("apply rate limits and CORS origins", func(t *testing.T) {
+		t.Parallel()
+
+		log, err := logger.New(&logger.Config{Level: &[]string{"info"}[0]})
+		require.NoError(t, err)
+
+		server, err := New(ServerParams{
+			Config:     newReloadTestConfig(10, "https://before.example.com"),
+			Logger:     log,
+			APIHandler: &mockAPIHandler{},
+			JWT:        setupTestJWT(t),
+			Redis:      setupTestRedis(t),
+		})
+		require.NoError(t, err)
+
+		serve := func() *httptest.ResponseRecorder {
+			request := httptest.NewRequest(http.MethodOptions, "/status", nil)
+			request.Header.Set("Origin", "https://after.example.com")
+			request.Header.Set("Access-Control-Request-Method", http.MethodGet)
+
+			recorder := httptest.NewRecorder()
+			server.Handler().ServeHTTP(recorder, request)
+
+			return recorder
+		}
+
+		recorder := serve()
+		assert.Equal(t, "10", recorder.Header().Get("X-Ratelimit-Limit"))
+		assert.Empty(t, recorder.Header().Get("Access-Control-Allow-Origin"))
+
+		require.NoError(t, server.Reload(newReloadTestConfig(20, "https://after.example.com")))
+
+		recorder = serve()
+		assert.Equal(t, "20", recorder.Header().Get("X-Ratelimit-Limit"))
+		assert.Equal(t, "https://after.example.com", recorder.Header().Get("Access-Control-Allow-Origin"))
+	})
+
+	t.Run("apply user rate limit of API routes", func(t *testing.T) {
+		t.Parallel()
+
+		log, err := logger.New(&logger.Config{Level: &[]string{"info"}[0]})
+		require.NoError(t, err)
+
+		// newConfig creates a config with only the user rate limit enabled.
+		newConfig := func(requests int) *Config {
+			config := newReloadTestConfig(requests, "*")
+			config.RateLimit.IP.Enabled = &[]bool{false}[0]
+			config.RateLimit.User = &middleware.RateLimitTypeConfig{Enabled: &[]bool{true}[0], Requests: &requests}
+
+			return config
+		}
+
+		server, err := New(ServerParams{
+			Config:     newConfig(10),
+			Logger:     log,
+			APIHandler: &mockAPIHandler{},
+			JWT:        setupTestJWT(t),
+			Redis:      setupTestRedis(t),
+		})
+		require.NoError(t, err)
+
+		serve := func() *httptest.ResponseRecorder {
+			recorder := httptest.NewRecorder()
+			server.Handler().ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/status", nil))
+
+			return recorder
+		}
+
+		assert.Equal(t, "10", serve().Header().Get("X-Ratelimit-Limit"))
+
+		require.NoError(t, server.Reload(newConfig(20)))
+		assert.Equal(t, "20", serve().Header().Get("X-Ratelimit-Limit"))
+	})
+
+	t.Run("keep current config for invalid config", func(t *testing.T) {
+		t.Parallel()
+
+		log, err := logger.New(&logger.Config{Level: &[]string{"info"}[0]})
+		require.NoError(t, err)
+
+		server, err := New(ServerParams{
+			Config:     newReloadTestConfig(10, "*"),
+			Logger:     log,
+			APIHandler: &mockAPIHandler{},
+			JWT:        setupTestJWT(t),
+			Redis:      setupTestRedis(t),
+		})
+		require.NoError(t, err)
+
+		config := newReloadTestConfig(20, "*")
+		config.RateLimit.Headers = &[]middleware.RateLimitHeaders{"invalid"}[0]
+
+		require.ErrorIs(t, server.Reload(config), middleware.ErrInvalidRateLimitHeaders)
+
+		recorder := httptest.NewRecorder()
+		server.Handler().ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/status", nil))
+		assert.Equal(t, "10", recorder.Header().Get("X-Ratelimit-Limit"))
+	})
+
+	t.Run("ignore nil config", func(t *testing.T) {
+		t.Parallel()
+
+		server := &Server{}
+
+		require.NoError(t, server.Reload(nil))
+	})
+}
@@ -0,0 +1,168 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+)
+
+// listenFDsStart is the first file descriptor passed by process managers, after stdin, stdout and stderr.
+const listenFDsStart = 3
+
+// ErrReusePortUnsupported is returned when reusing ports is enabled on a platform without SO_REUSEPORT.
+var ErrReusePortUnsupported = errors.New("reuse_port is not supported on this platform")
+
+// RestartConfig represents configuration for restarting server without refusing connections, either by
+// binding the new process next to the old one or by serving sockets held by the process manager.
+type RestartConfig struct {
+	// ReusePort is whether listeners are bound with SO_REUSEPORT, so that the new process binds the same
+	// addresses while the old one drains, and the kernel balances connections between them.
+	ReusePort *bool `json:"reuse_port"`
+
+	// InheritListeners is whether listeners are taken over from file descriptors passed with LISTEN_FDS
+	// (systemd socket activation, or a parent passing Listener files), binding only addresses not passed.
+	InheritListeners *bool `json:"inherit_listeners"`
+}
+
+// setRestartDefault sets default values for restarts of server.
+func (c *Config) setRestartDefault() {
+	if c.Restart == nil {
+		c.Restart = &RestartConfig{}
+	}
+
+	if c.Restart.ReusePort == nil {
+		c.Restart.ReusePort = &[]bool{false}[0]
+	}
+
+	if c.Restart.InheritListeners == nil {
+		c.Restart.InheritListeners = &[]bool{false}[0]
+	}
+}
+
+// validateRestart validates restart configuration.
+func validateRestart(config *RestartConfig) error {
+	if *config.ReusePort && !reusePortSupported {
+		return ErrReusePortUnsupported
+	}
+
+	return nil
+}
+
+// Listener returns the listener of the first address once Run bound it, nil before, e.g. to pass its file to
+// a new process with exec.Cmd.ExtraFiles and LISTEN_FDS.
+func (s *Server) Listener() net.Listener {
+	s.boundMu.Lock()
+	defer s.boundMu.Unlock()
+
+	if len(s.bound) == 0 {
+		return nil
+	}
+
+	return s.bound[0]
+}
+
+// setBound sets listeners bound by Run.
+func (s *Server) setBound(bound []net.Listener) {
+	s.boundMu.Lock()
+	defer s.boundMu.Unlock()
+
+	s.bound = bound
+}
+
+// inheritedListeners returns listeners passed by the process manager if inheriting is enabled, unsetting
+// LISTEN_FDS so that child processes do not inherit them again.
+func (s *Server) inheritedListeners() ([]net.Listener, error) {
+	if !*s.config.Restart.InheritListeners {
+		return nil, nil
+	}
+
+	count := listenFDs(os.Getenv, os.Getpid())
+
+	_ = os.Unsetenv("LISTEN_PID")
+	_ = os.Unsetenv("LISTEN_FDS")
+	_ = os.Unsetenv("LISTEN_FDNAMES")
+
+	files := make([]*os.File, 0, count)
+	for i := range count {
+		files = append(files, os.NewFile(uintptr(listenFDsStart+i), "listen-fd-"+strconv.Itoa(i)))
+	}
+
+	listeners, err := fileListeners(files)
+	if err != nil {
+		return nil, err
+	}
+
+	s.logger.Info().Int("count", len(listeners)).Msg("inherited listeners")
+
+	return listeners, nil
+}
+
+// listenFDs returns the number of file descriptors passed with LISTEN_FDS to the process, 0 if they were
+// passed to another process.
+func listenFDs(getenv func(string) string, pid int) int {
+	if listenPID := getenv("LISTEN_PID"); listenPID != "" && listenPID != strconv.Itoa(pid) {
+		return 0
+	}
+
+	count, err := strconv.Atoi(getenv("LISTEN_FDS"))
+	if err != nil || count < 0 {
+		return 0
+	}
+
+	return count
+}
+
+// fileListeners returns listeners of the files, closing the files since listeners hold duplicates.
+func fileListeners(files []*os.File) ([]net.Listener, error) {
+	listeners := make([]net.Listener, 0, len(files))
+
+	for _, file := range files {
+		listener, err := net.FileListener(file)
+		_ = file.Close()
+
+		if err != nil {
+			for _, inherited := range listeners {
+				_ = inherited.Close()
+			}
+
+			return nil, fmt.Errorf("failed to inherit listener %s: %w", file.Name(), err)
+		}
+
+		listeners = append(listeners, listener)
+	}
+
+	return listeners, nil
+}
+
+// takeListener returns the inherited listener bound to the address and the remaining inherited listeners.
+func takeListener(inherited []net.Listener, addr string) (net.Listener, []net.Listener) {
+	for i, listener := range inherited {
+		if sameAddr(addr, listener.Addr()) {
+			return listener, append(inherited[:i:i], inherited[i+1:]...)
+		}
+	}
+
+	return nil, inherited
+}
+
+// sameAddr returns whether the bound address is the configured address, unspecified hosts match any
+// unspecified address (0.0.0.0:8080 is bound as [::]:8080 on dual stack).
+func sameAddr(configured string, bound net.Addr) bool {
+	boundTCP, ok := bound.(*net.TCPAddr)
+	if !ok {
+		return false
+	}
+
+	configuredTCP, err := net.ResolveTCPAddr("tcp", configured)
+	if err != nil || configuredTCP.Port != boundTCP.Port {
+		return false
+	}
+
+	if configuredTCP.IP == nil || configuredTCP.IP.IsUnspecified() {
+		return boundTCP.IP == nil || boundTCP.IP.IsUnspecified()
+	}
+
+	return configuredTCP.IP.Equal(boundTCP.IP)
+}
@@ -0,0 +1,228 @@
+package server
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/logger"
+)
+
+func TestSetRestartDefault(t *testing.T) {
+	t.Parallel()
+
+	config := &Config{}
+	config.SetDefault()
+
+	assert.False(t, *config.Restart.ReusePort)
+	assert.False(t, *config.Restart.InheritListeners)
+	require.NoError(t, validateRestart(config.Restart))
+}
+
+func TestListenFDs(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		env      map[string]string
+		expected int
+	}{
+		{name: "no descriptors passed", env: map[string]string{}, expected: 0},
+		{name: "descriptors passed to process", env: map[string]string{"LISTEN_PID": "42", "LISTEN_FDS": "2"}, expected: 2},
+		{name: "descriptors passed without pid", env: map[string]string{"LISTEN_FDS": "1"}, expected: 1},
+		{name: "descriptors passed to another process", env: map[string]string{"LISTEN_PID": "7", "LISTEN_FDS": "2"}},
+		{name: "invalid count", env: map[string]string{"LISTEN_FDS": "two"}, expected: 0},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			getenv := func(key string) string { return test.env[key] }
+
+			assert.Equal(t, test.expected, listenFDs(getenv, 42))
+		})
+	}
+}
+
+func TestFileListeners(t *testing.T) {
+	t.Parallel()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	defer func() { _ = listener.Close() }()
+
+	file, err := listener.(*net.TCPListener).File()
+	require.NoError(t, err)
+
+	inherited, err := fileListeners([]*os.File{file})
+	require.NoError(t, err)
+	require.Len(t, inherited, 1)
+
+	defer func() { _ = inherited[0].Close() }()
+
+	assert.Equal(t, listener.Addr().String(), inherited[0].Addr().String())
+
+	// the file is closed once the listener holds a duplicate
+	_, err = file.Stat()
+	require.Error(t, err)
+}
+
+func TestTakeListener(t *testing.T) {
+	t.Parallel()
+
+	first, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	defer func() { _ = first.Close() }()
+
+	second, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	defer func() { _ = second.Close() }()
+
+	inherited := []net.Listener{first, second}
+
+	taken, remaining := takeListener(inherited, second.Addr().String())
+	assert.Equal(t, second, taken)
+	assert.Equal(t, []net.Listener{first}, remaining)
+	assert.Len(t, inherited, 2, "inherited listeners are not modified")
+
+	taken, remaining = takeListener(remaining, "127.0.0.1:1")
+	assert.Nil(t, taken)
+	assert.Equal(t, []net.Listener{first}, remaining)
+}
+
+func TestSameAddr(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name       string
+		configured string
+		bound      net.Addr
+		expected   bool
+	}{
+		{
+			name:       "same address",
+			configured: "127.0.0.1:8080",
+			bound:      &net.TCPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 8080},
+			expected:   true,
+		},
+		{
+			name:       "unspecified host bound on dual stack",
+			configured: "0.0.0.0:8080",
+			bound:      &net.TCPAddr{IP: net.IPv6unspecified, Port: 8080},
+			expected:   true,
+		},
+		{
+			name:       "empty host",
+			configured: ":8080",
+			bound:      &net.TCPAddr{IP: net.IPv4zero, Port: 8080},
+			expected:   true,
+		},
+		{
+			name:       "different port",
+			configured: "127.0.0.1:8080",
+			bound:      &net.TCPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 8081},
+		},
+		{
+			name:       "different host",
+			configured: "127.0.0.1:8080",
+			bound:      &net.TCPAddr{IP: net.IPv4(10, 0, 0, 1), Port: 8080},
+		},
+		{
+			name:       "unix socket",
+			configured: "127.0.0.1:8080",
+			bound:      &net.UnixAddr{Name: "/tmp/server.sock", Net: "unix"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			assert.Equal(t, test.expected, sameAddr(test.configured, test.bound))
+		})
+	}
+}
+
+func TestReusePortControl(t *testing.T) {
+	t.Parallel()
+
+	if !reusePortSupported {
+		t.Skip("SO_REUSEPORT is not supported on this platform")
+	}
+
+	listen := &net.ListenConfig{Control: reusePortControl}
+
+	first, err := listen.Listen(t.Context(), "tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	defer func() { _ = first.Close() }()
+
+	// a new process binds the address while the old one still listens
+	second, err := listen.Listen(t.Context(), "tcp", first.Addr().String())
+	require.NoError(t, err)
+	require.NoError(t, second.Close())
+}
+
+func TestServerRunReusePort(t *testing.T) {
+	t.Parallel()
+
+	if !reusePortSupported {
+		t.Skip("SO_REUSEPORT is not supported on this platform")
+	}
+
+	log, err := logger.New(&logger.Config{Level: &[]string{"info"}[0]})
+	require.NoError(t, err)
+
+	host := "127.0.0.1"
+	port, err := strconv.Atoi(freeAddr(t)[len(host)+1:])
+	require.NoError(t, err)
+
+	config := &Config{
+		Host:    &host,
+		Port:    &port,
+		Restart: &RestartConfig{ReusePort: &[]bool{true}[0]},
+	}
+
+	server, err := New(ServerParams{
+		Config:     config,
+		Logger:     log,
+		APIHandler: &mockAPIHandler{},
+		JWT:        setupTestJWT(t),
+		Redis:      setupTestRedis(t),
+	})
+	require.NoError(t, err)
+	assert.Nil(t, server.Listener())
+
+	done := make(chan error, 1)
+
+	go func() {
+		done <- server.Run()
+	}()
+
+	waitForStatus(t, http.DefaultClient, "http://"+server.Addr()+"/status")
+
+	require.NotNil(t, server.Listener())
+	assert.Equal(t, server.Addr(), server.Listener().Addr().String())
+
+	// the next process binds the same address while server still serves
+	next, err := (&net.ListenConfig{Control: reusePortControl}).Listen(t.Context(), "tcp", server.Addr())
+	require.NoError(t, err)
+	require.NoError(t, next.Close())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	require.NoError(t, server.Shutdown(ctx))
+	require.NoError(t, <-done)
+}
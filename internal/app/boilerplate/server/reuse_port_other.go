@@ -0,0 +1,13 @@
+//go:build !(darwin || dragonfly || freebsd || linux || netbsd || openbsd)
+
+package server
+
+import "syscall"
+
+// reusePortSupported is whether the platform supports SO_REUSEPORT.
+const reusePortSupported = false
+
+// reusePortControl fails since the platform does not support SO_REUSEPORT, validation rejects enabling it.
+func reusePortControl(_, _ string, _ syscall.RawConn) error {
+	return ErrReusePortUnsupported
+}
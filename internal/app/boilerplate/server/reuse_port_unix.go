@@ -0,0 +1,31 @@
+//go:build darwin || dragonfly || freebsd || linux || netbsd || openbsd
+
+package server
+
+import (
+	"fmt"
+	"syscall"
+
+	"golang.org/x/sys/unix"
+)
+
+// reusePortSupported is whether the platform supports SO_REUSEPORT.
+const reusePortSupported = true
+
+// reusePortControl sets SO_REUSEPORT on sockets before they are bound.
+func reusePortControl(_, _ string, conn syscall.RawConn) error {
+	var sockErr error
+
+	err := conn.Control(func(fd uintptr) {
+		sockErr = unix.SetsockoptInt(int(fd), unix.SOL_SOCKET, unix.SO_REUSEPORT, 1)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to control socket: %w", err)
+	}
+
+	if sockErr != nil {
+		return fmt.Errorf("failed to set SO_REUSEPORT: %w", sockErr)
+	}
+
+	return nil
+}
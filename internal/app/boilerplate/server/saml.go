@@ -0,0 +1,179 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/pocj8ur4in/boilerplate-go/internal/gen/api"
+	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/audit"
+	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/jwt"
+	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/saml"
+	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/user"
+)
+
+// auditMethodSAML is the method attribute of audit events of SAML logins.
+const auditMethodSAML = "saml"
+
+// setupSAMLRoutes sets up the SAML endpoints of identity providers by tenant, the assertion consumer service
+// issues JWTs to users asserted by signed responses.
+func (s *Server) setupSAMLRoutes(router *chi.Mux, jwtService *jwt.JWT) {
+	if s.saml == nil {
+		return
+	}
+
+	router.Route(s.saml.Path(), func(router chi.Router) {
+		router.Get("/{tenant}/metadata", s.handleSAMLMetadata)
+		router.Get("/{tenant}/login", s.handleSAMLLogin)
+		router.Post("/{tenant}/acs", s.handleSAMLACS(jwtService))
+	})
+}
+
+// handleSAMLMetadata handles GET /saml/{tenant}/metadata endpoint, responding with the metadata registered at the
+// identity provider of the tenant.
+func (s *Server) handleSAMLMetadata(writer http.ResponseWriter, request *http.Request) {
+	metadata, err := s.saml.Metadata(chi.URLParam(request, "tenant"))
+	if errors.Is(err, saml.ErrUnknownProvider) {
+		writeError(writer, http.StatusNotFound, "identity provider not found")
+
+		return
+	}
+
+	if err != nil {
+		s.logger.Ctx(request.Context()).Error().Err(err).Msg("failed to create saml metadata")
+		writeError(writer, http.StatusInternalServerError, "failed to create metadata")
+
+		return
+	}
+
+	writer.Header().Set("Content-Type", "application/samlmetadata+xml")
+	writer.WriteHeader(http.StatusOK)
+
+	// error is ignored since nothing else can be written to the client
+	_, _ = writer.Write(metadata)
+}
+
+// handleSAMLLogin handles GET /saml/{tenant}/login endpoint, redirecting to the identity provider of the tenant
+// with the relay_state query parameter returned with the tokens.
+func (s *Server) handleSAMLLogin(writer http.ResponseWriter, request *http.Request) {
+	loginURL, err := s.saml.LoginURL(request.Context(), chi.URLParam(request, "tenant"),
+		request.URL.Query().Get("relay_state"))
+
+	switch {
+	case errors.Is(err, saml.ErrUnknownProvider):
+		writeError(writer, http.StatusNotFound, "identity provider not found")
+	case errors.Is(err, saml.ErrInvalidRelayState):
+		writeError(writer, http.StatusBadRequest, "invalid relay state")
+	case err != nil:
+		s.logger.Ctx(request.Context()).Error().Err(err).Msg("failed to create saml login url")
+		writeError(writer, http.StatusInternalServerError, "failed to start login")
+	default:
+		http.Redirect(writer, request, loginURL, http.StatusFound)
+	}
+}
+
+// handleSAMLACS returns the handler of POST /saml/{tenant}/acs endpoint, logging in the user asserted by the
+// response posted by the browser. Tokens are returned as JSON, or in the fragment of the redirect URL of the
+// identity provider if it has one.
+func (s *Server) handleSAMLACS(jwtService *jwt.JWT) http.HandlerFunc {
+	return func(writer http.ResponseWriter, request *http.Request) {
+		tenant := chi.URLParam(request, "tenant")
+
+		loggedIn, err := s.saml.Login(request.Context(), tenant, request.PostFormValue("SAMLResponse"))
+
+		switch {
+		case errors.Is(err, saml.ErrUnknownProvider):
+			writeError(writer, http.StatusNotFound, "identity provider not found")
+
+			return
+		case errors.Is(err, saml.ErrInvalidResponse), errors.Is(err, saml.ErrDomainNotAllowed):
+			s.logSAMLAudit(request, audit.ActionLoginFailed, "", tenant)
+			writeError(writer, http.StatusUnauthorized, "invalid saml response")
+
+			return
+		case errors.Is(err, saml.ErrAccountConflict):
+			s.logSAMLAudit(request, audit.ActionLoginFailed, "", tenant)
+			writeError(writer, http.StatusConflict, "email linked to another account")
+
+			return
+		case err != nil:
+			s.logger.Ctx(request.Context()).Error().Err(err).Msg("failed to log in with saml")
+			writeError(writer, http.StatusInternalServerError, "failed to log in")
+
+			return
+		}
+
+		// tokens carry the tenant, so that tenant limits and usage apply to the session
+		tokens, err := issueTokens(jwtService, loggedIn, tenant)
+		if err != nil {
+			s.logger.Ctx(request.Context()).Error().Err(err).Msg("failed to issue tokens")
+			writeError(writer, http.StatusInternalServerError, "failed to issue tokens")
+
+			return
+		}
+
+		s.logSAMLAudit(request, audit.ActionLogin, loggedIn.ID, tenant)
+
+		// the tenant is known since the login succeeded
+		redirectURL, _ := s.saml.RedirectURL(tenant)
+		if redirectURL == "" {
+			writeJSON(writer, http.StatusOK, tokens)
+
+			return
+		}
+
+		// tokens are passed in the fragment, so that they are not sent to servers or logged
+		fragment := url.Values{
+			"access_token":  {tokens.AccessToken},
+			"refresh_token": {tokens.RefreshToken},
+			"token_type":    {tokens.TokenType},
+			"expires_in":    {strconv.FormatInt(tokens.ExpiresIn, 10)},
+		}
+
+		if relayState := request.PostFormValue("RelayState"); relayState != "" {
+			fragment.Set("state", relayState)
+		}
+
+		http.Redirect(writer, request, redirectURL+"#"+fragment.Encode(), http.StatusSeeOther)
+	}
+}
+
+// issueTokens issues access and refresh tokens for the user logged in to the tenant.
+func issueTokens(jwtService *jwt.JWT, issued *user.User, tenant string) (*api.AuthTokenResponse, error) {
+	accessToken, err := jwtService.GenerateTenantAccessToken(issued.ID, issued.Email, issued.Role, tenant)
+	if err != nil {
+		return nil, fmt.Errorf("failed to issue access token: %w", err)
+	}
+
+	refreshToken, err := jwtService.GenerateTenantRefreshToken(issued.ID, issued.Email, issued.Role, tenant)
+	if err != nil {
+		return nil, fmt.Errorf("failed to issue refresh token: %w", err)
+	}
+
+	return &api.AuthTokenResponse{
+		AccessToken:  *accessToken,
+		RefreshToken: *refreshToken,
+		TokenType:    "Bearer",
+		ExpiresIn:    int64(jwtService.AccessTokenTTL().Seconds()),
+		User: &api.AuthUser{
+			Id:        issued.ID,
+			Email:     issued.Email,
+			Role:      issued.Role,
+			CreatedAt: issued.CreatedAt,
+		},
+	}, nil
+}
+
+// logSAMLAudit logs the SAML login event of the request by the actor, it does nothing if audit logging is
+// disabled.
+func (s *Server) logSAMLAudit(request *http.Request, action, actorID, tenant string) {
+	event := audit.NewEvent(request, action)
+	event.ActorID = actorID
+	event.Attributes = map[string]string{"method": auditMethodSAML, "tenant": tenant}
+
+	s.auditor.Log(request.Context(), event)
+}
@@ -0,0 +1,160 @@
+package server
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pocj8ur4in/boilerplate-go/internal/app/boilerplate/server/middleware"
+	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/logger"
+	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/saml"
+	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/user"
+)
+
+// newTestSAMLServer creates a test server with SAML of the acme tenant enabled or disabled, and CSRF protection.
+func newTestSAMLServer(t *testing.T, enabled bool) *Server {
+	t.Helper()
+
+	log, err := logger.New(&logger.Config{Level: &[]string{"info"}[0]})
+	require.NoError(t, err)
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "idp.example.com"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+
+	certificate, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+
+	redis := setupTestRedis(t)
+
+	serviceProvider, err := saml.New(&saml.Config{
+		Enabled: &enabled,
+		BaseURL: &[]string{"https://api.example.com"}[0],
+		Providers: map[string]*saml.ProviderConfig{
+			"acme": {
+				EntityID:     &[]string{"https://idp.example.com/metadata"}[0],
+				SSOURL:       &[]string{"https://idp.example.com/sso"}[0],
+				Certificates: []string{string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: certificate}))},
+				Domains:      []string{"example.com"},
+			},
+		},
+	}, nil, redis, log)
+	require.NoError(t, err)
+
+	config := &Config{CSRF: &middleware.CSRFConfig{Enabled: &[]bool{true}[0]}}
+
+	server, err := New(ServerParams{
+		Config:     config,
+		Logger:     log,
+		APIHandler: &mockAPIHandler{},
+		JWT:        setupTestJWT(t),
+		Redis:      redis,
+		SAML:       serviceProvider,
+	})
+	require.NoError(t, err)
+
+	return server
+}
+
+func TestIssueTokens(t *testing.T) {
+	t.Parallel()
+
+	t.Run("issue tokens in tenant of login", func(t *testing.T) {
+		t.Parallel()
+
+		jwtService := setupTestJWT(t)
+
+		tokens, err := issueTokens(jwtService, &user.User{ID: "user-1", Email: "a@example.com", Role: "user"}, "acme")
+		require.NoError(t, err)
+
+		for _, token := range []string{tokens.AccessToken, tokens.RefreshToken} {
+			claims, err := jwtService.ValidateToken(token)
+			require.NoError(t, err)
+			assert.Equal(t, "acme", claims.TenantID)
+			assert.Equal(t, "user-1", claims.UserID)
+		}
+	})
+}
+
+//nolint:paralleltest // sequential execution required to avoid redis key conflicts
+func TestSAMLRoutes(t *testing.T) {
+	t.Run("not register routes when disabled", func(t *testing.T) {
+		server := newTestSAMLServer(t, false)
+
+		recorder := httptest.NewRecorder()
+		server.Handler().ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/saml/acme/metadata", nil))
+
+		assert.Equal(t, http.StatusNotFound, recorder.Code)
+	})
+
+	t.Run("serve metadata of tenant", func(t *testing.T) {
+		server := newTestSAMLServer(t, true)
+
+		recorder := httptest.NewRecorder()
+		server.Handler().ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/saml/acme/metadata", nil))
+
+		require.Equal(t, http.StatusOK, recorder.Code)
+		assert.Equal(t, "application/samlmetadata+xml", recorder.Header().Get("Content-Type"))
+		assert.Contains(t, recorder.Body.String(), `Location="https://api.example.com/saml/acme/acs"`)
+
+		recorder = httptest.NewRecorder()
+		server.Handler().ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/saml/other/metadata", nil))
+
+		assert.Equal(t, http.StatusNotFound, recorder.Code)
+	})
+
+	t.Run("redirect login to identity provider", func(t *testing.T) {
+		server := newTestSAMLServer(t, true)
+
+		recorder := httptest.NewRecorder()
+		server.Handler().ServeHTTP(recorder,
+			httptest.NewRequest(http.MethodGet, "/saml/acme/login?relay_state=dashboard", nil))
+
+		require.Equal(t, http.StatusFound, recorder.Code)
+
+		location, err := url.Parse(recorder.Header().Get("Location"))
+		require.NoError(t, err)
+		assert.Equal(t, "idp.example.com", location.Host)
+		assert.NotEmpty(t, location.Query().Get("SAMLRequest"))
+		assert.Equal(t, "dashboard", location.Query().Get("RelayState"))
+
+		recorder = httptest.NewRecorder()
+		server.Handler().ServeHTTP(recorder,
+			httptest.NewRequest(http.MethodGet, "/saml/acme/login?relay_state="+strings.Repeat("a", 81), nil))
+
+		assert.Equal(t, http.StatusBadRequest, recorder.Code)
+	})
+
+	t.Run("reject invalid response posted without csrf token", func(t *testing.T) {
+		server := newTestSAMLServer(t, true)
+
+		form := url.Values{"SAMLResponse": {base64.StdEncoding.EncodeToString([]byte(`<Response/>`))}}
+		request := httptest.NewRequest(http.MethodPost, "/saml/acme/acs", strings.NewReader(form.Encode()))
+		request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+		recorder := httptest.NewRecorder()
+		server.Handler().ServeHTTP(recorder, request)
+
+		// the response is checked instead of a csrf token
+		assert.Equal(t, http.StatusUnauthorized, recorder.Code)
+	})
+}
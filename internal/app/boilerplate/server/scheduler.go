@@ -0,0 +1,141 @@
+package server
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/scheduler"
+)
+
+// schedulerTasksPath is the path prefix of scheduled task endpoints on the admin router.
+const schedulerTasksPath = "/scheduler/tasks"
+
+// schedulerTasksResponse represents registered scheduled tasks.
+type schedulerTasksResponse struct {
+	// Tasks is states of the tasks ordered by name.
+	Tasks []scheduler.TaskState `json:"tasks"`
+}
+
+// schedulerTaskResponse represents a scheduled task with its history.
+type schedulerTaskResponse struct {
+	scheduler.TaskState
+
+	// History is recorded runs of the task, the latest first.
+	History []scheduler.Run `json:"history"`
+}
+
+// setupAdminSchedulerRoutes sets up endpoints listing, pausing, resuming and triggering scheduled tasks on the
+// admin router.
+func (s *Server) setupAdminSchedulerRoutes(router chi.Router) {
+	if s.scheduler == nil {
+		return
+	}
+
+	router.Get(schedulerTasksPath, s.handleListScheduledTasks)
+	router.Get(schedulerTasksPath+"/{name}", s.handleGetScheduledTask)
+	router.Post(schedulerTasksPath+"/{name}/pause", s.handlePauseScheduledTask)
+	router.Post(schedulerTasksPath+"/{name}/resume", s.handleResumeScheduledTask)
+	router.Post(schedulerTasksPath+"/{name}/trigger", s.handleTriggerScheduledTask)
+}
+
+// handleListScheduledTasks handles GET /admin/scheduler/tasks endpoint.
+func (s *Server) handleListScheduledTasks(writer http.ResponseWriter, request *http.Request) {
+	tasks, err := s.scheduler.Tasks(request.Context())
+	if err != nil {
+		s.writeSchedulerError(writer, request, err, "failed to list scheduled tasks")
+
+		return
+	}
+
+	writeJSON(writer, http.StatusOK, schedulerTasksResponse{Tasks: tasks})
+}
+
+// handleGetScheduledTask handles GET /admin/scheduler/tasks/{name} endpoint.
+func (s *Server) handleGetScheduledTask(writer http.ResponseWriter, request *http.Request) {
+	name := chi.URLParam(request, "name")
+
+	task, err := s.scheduler.Task(request.Context(), name)
+	if err != nil {
+		s.writeSchedulerError(writer, request, err, "failed to get scheduled task")
+
+		return
+	}
+
+	history, err := s.scheduler.History(request.Context(), name)
+	if err != nil {
+		s.writeSchedulerError(writer, request, err, "failed to get scheduled task history")
+
+		return
+	}
+
+	writeJSON(writer, http.StatusOK, schedulerTaskResponse{TaskState: task, History: history})
+}
+
+// handlePauseScheduledTask handles POST /admin/scheduler/tasks/{name}/pause endpoint.
+func (s *Server) handlePauseScheduledTask(writer http.ResponseWriter, request *http.Request) {
+	name := chi.URLParam(request, "name")
+
+	if err := s.scheduler.Pause(request.Context(), name); err != nil {
+		s.writeSchedulerError(writer, request, err, "failed to pause scheduled task")
+
+		return
+	}
+
+	s.writeScheduledTask(writer, request, http.StatusOK, name)
+}
+
+// handleResumeScheduledTask handles POST /admin/scheduler/tasks/{name}/resume endpoint.
+func (s *Server) handleResumeScheduledTask(writer http.ResponseWriter, request *http.Request) {
+	name := chi.URLParam(request, "name")
+
+	if err := s.scheduler.Resume(request.Context(), name); err != nil {
+		s.writeSchedulerError(writer, request, err, "failed to resume scheduled task")
+
+		return
+	}
+
+	s.writeScheduledTask(writer, request, http.StatusOK, name)
+}
+
+// handleTriggerScheduledTask handles POST /admin/scheduler/tasks/{name}/trigger endpoint, responding with 202
+// once the run started on this instance, its outcome is recorded in the history of the task.
+func (s *Server) handleTriggerScheduledTask(writer http.ResponseWriter, request *http.Request) {
+	name := chi.URLParam(request, "name")
+
+	if err := s.scheduler.Trigger(request.Context(), name); err != nil {
+		s.writeSchedulerError(writer, request, err, "failed to trigger scheduled task")
+
+		return
+	}
+
+	s.logger.Ctx(request.Context()).Info().Str("task", name).Msg("scheduled task triggered")
+
+	s.writeScheduledTask(writer, request, http.StatusAccepted, name)
+}
+
+// writeScheduledTask writes the state of the task of the name with the code.
+func (s *Server) writeScheduledTask(writer http.ResponseWriter, request *http.Request, code int, name string) {
+	task, err := s.scheduler.Task(request.Context(), name)
+	if err != nil {
+		s.writeSchedulerError(writer, request, err, "failed to get scheduled task")
+
+		return
+	}
+
+	writeJSON(writer, code, task)
+}
+
+// writeSchedulerError writes the error response of a scheduler operation.
+func (s *Server) writeSchedulerError(writer http.ResponseWriter, request *http.Request, err error, message string) {
+	switch {
+	case errors.Is(err, scheduler.ErrUnknownTask):
+		writeError(writer, http.StatusNotFound, "scheduled task not found")
+	case errors.Is(err, scheduler.ErrTaskRunning):
+		writeError(writer, http.StatusConflict, "scheduled task is running")
+	default:
+		s.logger.Ctx(request.Context()).Error().Err(err).Msg(message)
+		writeError(writer, http.StatusInternalServerError, message)
+	}
+}
@@ -0,0 +1,143 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/logger"
+	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/redis"
+	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/scheduler"
+)
+
+//nolint:paralleltest // sequential execution required to avoid redis key conflicts
+func TestSchedulerRoutes(t *testing.T) {
+	log, err := logger.New(&logger.Config{Level: &[]string{"info"}[0]})
+	require.NoError(t, err)
+
+	jwtService := setupTestJWT(t)
+	redisClient := setupTestRedis(t)
+	prefix := fmt.Sprintf("scheduler:server:%d:", time.Now().UnixNano())
+
+	taskScheduler, err := scheduler.New(&scheduler.Config{Prefix: &prefix}, redisClient, log)
+	require.NoError(t, err)
+
+	ran := make(chan struct{}, 1)
+
+	require.NoError(t, taskScheduler.Register(scheduler.Task{
+		Name:     "cleanup",
+		Schedule: "@daily",
+		Run: func(context.Context) error {
+			ran <- struct{}{}
+
+			return nil
+		},
+	}))
+
+	t.Cleanup(func() {
+		taskScheduler.Stop(context.Background())
+	})
+
+	server, err := New(ServerParams{
+		Logger:     log,
+		APIHandler: &mockAPIHandler{},
+		JWT:        jwtService,
+		Redis:      redisClient,
+		Scheduler:  taskScheduler,
+	})
+	require.NoError(t, err)
+
+	token, err := jwtService.GenerateAccessToken("admin-1", "admin@example.com", "admin")
+	require.NoError(t, err)
+
+	// serve sends the request as an admin, returns the response.
+	serve := func(method string, path string) *httptest.ResponseRecorder {
+		request := httptest.NewRequest(method, path, nil)
+		request.Header.Set("Authorization", "Bearer "+*token)
+
+		recorder := httptest.NewRecorder()
+		server.Handler().ServeHTTP(recorder, request)
+
+		return recorder
+	}
+
+	// task returns the reported task with its history.
+	task := func() schedulerTaskResponse {
+		recorder := serve(http.MethodGet, "/admin/scheduler/tasks/cleanup")
+		require.Equal(t, http.StatusOK, recorder.Code)
+
+		var response schedulerTaskResponse
+		require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &response))
+
+		return response
+	}
+
+	t.Run("list tasks with next run", func(t *testing.T) {
+		recorder := serve(http.MethodGet, "/admin/scheduler/tasks")
+		require.Equal(t, http.StatusOK, recorder.Code)
+
+		var response schedulerTasksResponse
+		require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &response))
+		require.Len(t, response.Tasks, 1)
+		assert.Equal(t, "cleanup", response.Tasks[0].Name)
+		assert.Equal(t, "@daily", response.Tasks[0].Schedule)
+		assert.NotNil(t, response.Tasks[0].NextRun)
+		assert.Nil(t, response.Tasks[0].LastRun)
+	})
+
+	t.Run("pause and resume tasks", func(t *testing.T) {
+		recorder := serve(http.MethodPost, "/admin/scheduler/tasks/cleanup/pause")
+		require.Equal(t, http.StatusOK, recorder.Code)
+		assert.True(t, task().Paused)
+
+		recorder = serve(http.MethodPost, "/admin/scheduler/tasks/cleanup/resume")
+		require.Equal(t, http.StatusOK, recorder.Code)
+		assert.False(t, task().Paused)
+	})
+
+	t.Run("trigger tasks and record runs", func(t *testing.T) {
+		recorder := serve(http.MethodPost, "/admin/scheduler/tasks/cleanup/trigger")
+		require.Equal(t, http.StatusAccepted, recorder.Code)
+
+		<-ran
+
+		require.Eventually(t, func() bool {
+			return len(task().History) == 1
+		}, time.Second, 10*time.Millisecond)
+
+		response := task()
+		assert.Equal(t, scheduler.TriggerManual, response.History[0].Trigger)
+		require.NotNil(t, response.LastRun)
+		assert.Empty(t, response.LastRun.Error)
+	})
+
+	t.Run("reject triggers of running tasks", func(t *testing.T) {
+		var lock *redis.Lock
+
+		// the triggered run releases its lock after recording the run
+		require.Eventually(t, func() bool {
+			lock, err = redisClient.TryLock(context.Background(), prefix+"cleanup", nil)
+
+			return err == nil
+		}, time.Second, 10*time.Millisecond)
+
+		defer func() {
+			_ = lock.Release(context.Background())
+		}()
+
+		assert.Equal(t, http.StatusConflict, serve(http.MethodPost, "/admin/scheduler/tasks/cleanup/trigger").Code)
+	})
+
+	t.Run("return 404 for unknown tasks", func(t *testing.T) {
+		assert.Equal(t, http.StatusNotFound, serve(http.MethodGet, "/admin/scheduler/tasks/missing").Code)
+		assert.Equal(t, http.StatusNotFound, serve(http.MethodPost, "/admin/scheduler/tasks/missing/pause").Code)
+		assert.Equal(t, http.StatusNotFound, serve(http.MethodPost, "/admin/scheduler/tasks/missing/trigger").Code)
+	})
+}
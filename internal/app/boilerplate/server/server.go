@@ -5,8 +5,13 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"net"
 	"net/http"
-	"strconv"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/go-chi/chi/v5"
@@ -17,14 +22,40 @@ import (
 
 	"github.com/pocj8ur4in/boilerplate-go/internal/app/boilerplate/server/middleware"
 	"github.com/pocj8ur4in/boilerplate-go/internal/gen/api"
+	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/apierror"
+	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/apikey"
+	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/audit"
+	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/authz"
+	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/database"
+	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/images"
+	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/jobs"
 	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/jwt"
 	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/logger"
+	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/netutil"
+	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/payments"
+	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/proxy"
+	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/querycache"
+	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/readonly"
 	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/redis"
+	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/render"
+	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/saml"
+	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/scheduler"
+	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/settings"
+	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/signedurl"
+	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/sse"
+	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/usage"
+	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/websocket"
 )
 
 var (
 	// ErrServerNotInitialized is returned when the http server is not initialized.
 	ErrServerNotInitialized = errors.New("http server is not initialized")
+
+	// ErrTenantRateLimitRequiresDatabase is returned when tenant rate limit is enabled without database.
+	ErrTenantRateLimitRequiresDatabase = errors.New("tenant rate limit requires database")
+
+	// ErrImagesRequireSignedURLs is returned when images are enabled without signed URLs.
+	ErrImagesRequireSignedURLs = errors.New("images require signed urls")
 )
 
 // Server represents server.
@@ -35,11 +66,132 @@ type Server struct {
 	// logger provides logger.
 	logger *logger.Logger
 
-	// httpServer provides HTTP server.
+	// httpServer provides HTTP server of the first listener.
 	httpServer *http.Server
 
+	// listeners provides HTTP servers of all listeners, sharing the same handler.
+	listeners []*listener
+
+	// adminListener provides HTTP server of drain statistics, nil if the admin listener is disabled.
+	adminListener *listener
+
+	// drain tracks the drain of in-flight requests on shutdown.
+	drain drainState
+
 	// registry provides Prometheus registry for metrics.
 	registry *prometheus.Registry
+
+	// replayStore provides storage for captured requests, nil if replay capture is disabled.
+	replayStore *middleware.ReplayStore
+
+	// requestID provides request IDs of requests and responses.
+	requestID func(next http.Handler) http.Handler
+
+	// realIP resolves client IPs of requests from forwarded headers of trusted proxies.
+	realIP func(next http.Handler) http.Handler
+
+	// tenant resolves verified tenants of requests, nil if tenancy is disabled.
+	tenant func(next http.Handler) http.Handler
+
+	// validation provides request validation against the OpenAPI spec, nil if validation is disabled.
+	validation func(next http.Handler) http.Handler
+
+	// metrics collects request metrics, nil if metrics are disabled.
+	metrics func(next http.Handler) http.Handler
+
+	// tenantLimitStore provides per-tenant rate limits, nil if tenant rate limit is disabled.
+	tenantLimitStore *middleware.TenantLimitStore
+
+	// renderer provides HTML rendering, nil if server-rendered pages are disabled.
+	renderer *render.Render
+
+	// redis provides redis for rate limits rebuilt on reload.
+	redis *redis.Redis
+
+	// rateLimits provides rate limit middlewares, rebuilt on reload.
+	rateLimits *swappableMiddleware
+
+	// rateLimitFallback provides rate limits when redis is unavailable, replaced on reload.
+	rateLimitFallback *middleware.RateLimitFallback
+
+	// rateLimitRules provides rate limit exemptions and overrides, replaced on reload.
+	rateLimitRules *middleware.RateLimitRules
+
+	// userRateLimit provides user rate limit middleware of API routes, rebuilt on reload.
+	userRateLimit *routeMiddleware
+
+	// tenantRateLimit provides tenant rate limit middleware of API routes, rebuilt on reload.
+	tenantRateLimit *routeMiddleware
+
+	// cors provides CORS middleware, rebuilt on reload.
+	cors *swappableMiddleware
+
+	// settings provides application settings, nil if settings endpoints are disabled.
+	settings *settings.Settings
+
+	// apiKeyStore provides API keys, nil if API key authentication is disabled.
+	apiKeyStore *apikey.Store
+
+	// readOnly provides read-only mode, nil if it is not available.
+	readOnly *readonly.ReadOnly
+
+	// authz provides authorization by roles on database, nil to authorize admins by the role of their token.
+	authz *authz.Authz
+
+	// usage records usage of tenants, nil if usage reporting is not available.
+	usage *usage.Recorder
+
+	// payments provides subscriptions gating premium endpoints, nil if payments are not enabled.
+	payments *payments.Payments
+
+	// signer provides signed URLs authenticating requests without a token, nil if signed URLs are not enabled.
+	signer *signedurl.Signer
+
+	// images provides the image pipeline served to signed URLs, nil if images are not enabled.
+	images *images.Images
+
+	// hub provides websocket connections of users, nil if websocket is not enabled.
+	hub *websocket.Hub
+
+	// broker streams server-sent events to users, nil if sse is not enabled.
+	broker *sse.Broker
+
+	// queryCache provides the cache invalidated and warmed by admin endpoints, nil if it is not available.
+	queryCache *querycache.QueryCache
+
+	// scheduler provides scheduled tasks managed by admin endpoints, nil if it is not available.
+	scheduler *scheduler.Scheduler
+
+	// jobs provides statuses of background jobs read by users and sent to their websocket connections, nil if
+	// jobs are not available.
+	jobs *jobs.Jobs
+
+	// auditor provides audit logging of denials and admin actions, nil if audit logging is disabled.
+	auditor *audit.Auditor
+
+	// saml provides SAML login with identity providers of tenants, nil if SAML is not enabled.
+	saml *saml.ServiceProvider
+
+	// proxies is reverse proxies of mounts, health checking their upstreams while server runs.
+	proxies []*proxy.Proxy
+
+	// inFlight counts requests being processed, drained on shutdown.
+	inFlight *middleware.InFlight
+
+	// connections limits open connections to the listeners, except the admin listener.
+	connections *connectionLimiter
+
+	// connStates tracks connections of all listeners by state.
+	connStates *connStateTracker
+
+	// boundMu guards bound.
+	boundMu sync.Mutex
+
+	// bound is listeners bound or inherited by Run, in the order of listeners.
+	bound []net.Listener
+
+	// graph is the dependency graph of the application in the DOT language, nil until set.
+	graph atomic.Pointer[string]
 }
 
 // Config represents configuration for server.
@@ -50,6 +202,15 @@ type Config struct {
 	// Port is port of server.
 	Port *int `json:"port"`
 
+	// Listen is whether Run listens on the address, disable it to mount Handler in another server.
+	Listen *bool `json:"listen"`
+
+	// AddressFamily is address family of server (tcp4, tcp6, dual).
+	AddressFamily *string `json:"address_family"`
+
+	// Listeners is addresses server listens on, defaults to a single plaintext listener on host and port.
+	Listeners []*ListenerConfig `json:"listeners"`
+
 	// ReadTimeout is read timeout of server.
 	ReadTimeout *int `json:"read_timeout"`
 
@@ -59,35 +220,154 @@ type Config struct {
 	// IdleTimeout is idle timeout of server.
 	IdleTimeout *int `json:"idle_timeout"`
 
-	// ShutdownTimeout is shutdown timeout of server.
+	// ReadHeaderTimeout is time in seconds clients have to send request headers, so that slow clients
+	// can not hold connections open by trickling headers (slowloris).
+	ReadHeaderTimeout *int `json:"read_header_timeout"`
+
+	// MaxHeaderBytes is maximum size in bytes of request headers, including the request line.
+	MaxHeaderBytes *int `json:"max_header_bytes"`
+
+	// Connections is limits of open connections to server.
+	Connections *ConnectionsConfig `json:"connections"`
+
+	// DisableKeepAlives is whether connections are closed after each response instead of being reused,
+	// for balancers mishandling reused connections.
+	DisableKeepAlives *bool `json:"disable_keep_alives"`
+
+	// TCPKeepAlive is TCP keep-alive probes of accepted connections.
+	TCPKeepAlive *TCPKeepAliveConfig `json:"tcp_keep_alive"`
+
+	// Restart is restarts of server without refusing connections.
+	Restart *RestartConfig `json:"restart"`
+
+	// ShutdownTimeout is time in seconds in-flight requests are drained for on shutdown.
 	ShutdownTimeout *int `json:"shutdown_timeout"`
 
-	// MaxRequestSize is maximum request size in bytes.
+	// MaxRequestSize is maximum request size in bytes, except form bodies limited by Forms.
 	MaxRequestSize *int64 `json:"max_request_size"`
 
+	// Forms is form parsing limits of server.
+	Forms *middleware.FormLimitConfig `json:"forms"`
+
+	// CSRF is CSRF protection of cookie-based auth flows of server.
+	CSRF *middleware.CSRFConfig `json:"csrf"`
+
+	// HSTS is whether the Strict-Transport-Security header is set on responses.
+	HSTS *bool `json:"hsts"`
+
+	// VerboseErrors is whether error responses include debugging information such as stack traces.
+	VerboseErrors *bool `json:"verbose_errors"`
+
+	// ErrorFormat is format of error responses (json, or problem for RFC 7807 application/problem+json).
+	ErrorFormat *apierror.Format `json:"error_format"`
+
+	// TrustedProxies is networks of proxies whose forwarded headers are honored when resolving client IPs used by
+	// rate limits and logs, forwarded headers of other peers are ignored.
+	TrustedProxies []string `json:"trusted_proxies"`
+
+	// RequestID is request ID configuration of server.
+	RequestID *middleware.RequestIDConfig `json:"request_id"`
+
+	// TLS is TLS configuration of server, applied to the listener on host and port and to listeners with certificates.
+	TLS *TLSConfig `json:"tls"`
+
 	// Compression is compression configuration of server.
 	Compression *CompressionConfig `json:"compression"`
 
 	// CORS is CORS of server.
 	CORS *CORSConfig `json:"cors"`
 
+	// Tenancy is tenant resolution configuration of server.
+	Tenancy *middleware.TenancyConfig `json:"tenancy"`
+
 	// RateLimit is rate limit of server.
 	RateLimit *middleware.RateLimitConfig `json:"rate_limit"`
 
+	// RetryBudget is retry budget of clients of server.
+	RetryBudget *middleware.RetryBudgetConfig `json:"retry_budget"`
+
 	// Metrics is metrics configuration of server.
 	Metrics *middleware.MetricsConfig `json:"metrics"`
+
+	// Admin is admin endpoints configuration of server.
+	Admin *AdminConfig `json:"admin"`
+
+	// Replay is request replay capture configuration of server.
+	Replay *middleware.ReplayConfig `json:"replay"`
+
+	// Validation is request validation configuration of server.
+	Validation *middleware.ValidationConfig `json:"validation"`
+
+	// Settings is settings endpoints configuration of server.
+	Settings *SettingsConfig `json:"settings"`
+
+	// APIKeys is API key authentication and endpoints configuration of server.
+	APIKeys *APIKeysConfig `json:"api_keys"`
+
+	// Pages is server-rendered pages configuration of server.
+	Pages *PagesConfig `json:"pages"`
+
+	// WellKnown is robots.txt, favicon, and well-known endpoints configuration of server.
+	WellKnown *WellKnownConfig `json:"well_known"`
+
+	// Docs is documentation endpoints configuration of server.
+	Docs *DocsConfig `json:"docs"`
+
+	// Mounts is reverse proxies mounted on paths of server, e.g. to route a legacy backend.
+	Mounts []*MountConfig `json:"mounts"`
 }
 
 // CompressionConfig represents configuration for compression.
 type CompressionConfig struct {
-	// Level is compression level (1-9).
+	// Level is compression level of the format, 1-9 for gzip and deflate, 0-11 for br and 1-22 for zstd.
 	Level *int `json:"level"`
 
-	// Format is compression format (gzip, deflate, br).
+	// Format is compression format (gzip, deflate, br, zstd).
 	Format *string `json:"format"`
 
 	// Enabled is whether compression is enabled.
 	Enabled *bool `json:"enabled"`
+
+	// MinSize is minimum size of responses in bytes to compress.
+	MinSize *int `json:"min_size"`
+
+	// ContentTypes is content types to compress, all content types not excluded if empty.
+	ContentTypes *[]string `json:"content_types"`
+
+	// ExcludeContentTypes is content types not to compress, such as already compressed images and streams.
+	ExcludeContentTypes *[]string `json:"exclude_content_types"`
+
+	// ExcludePaths is path prefixes whose responses are not compressed.
+	ExcludePaths *[]string `json:"exclude_paths"`
+
+	// DecompressRequests is whether gzip and deflate request bodies are decompressed by their Content-Encoding,
+	// limited by MaxRequestSize and the form limits once decompressed.
+	DecompressRequests *bool `json:"decompress_requests"`
+
+	// MaxDecompressRatio is maximum ratio of decompressed to compressed size of request bodies, bodies
+	// expanding more (zip bombs) are rejected with 413.
+	MaxDecompressRatio *int64 `json:"max_decompress_ratio"`
+}
+
+// compressConfig returns the configuration of the compression middleware.
+func (c *CompressionConfig) compressConfig() *middleware.CompressConfig {
+	return &middleware.CompressConfig{
+		Level:               *c.Level,
+		Format:              *c.Format,
+		MinSize:             *c.MinSize,
+		ContentTypes:        *c.ContentTypes,
+		ExcludeContentTypes: *c.ExcludeContentTypes,
+		ExcludePaths:        *c.ExcludePaths,
+	}
+}
+
+// decompressConfig returns the configuration of the decompression middleware, compressed bodies are limited
+// to the largest body limit.
+func (c *Config) decompressConfig() *middleware.DecompressConfig {
+	return &middleware.DecompressConfig{
+		MaxRatio: *c.Compression.MaxDecompressRatio,
+		MaxSize:  max(*c.MaxRequestSize, *c.Forms.MaxURLEncodedSize, *c.Forms.MaxMultipartSize),
+	}
 }
 
 // CORSConfig represents configuration for CORS.
@@ -100,15 +380,55 @@ type CORSConfig struct {
 
 	// AllowedHeaders is allowed headers of CORS.
 	AllowedHeaders *[]string `json:"allowed_headers"`
+
+	// AllowCredentials is whether cookies and authorization headers are allowed on cross-origin requests of all policies.
+	AllowCredentials *bool `json:"allow_credentials"`
+
+	// Groups is CORS policies of route groups, matched by the longest path prefix.
+	Groups []*CORSGroupConfig `json:"groups"`
+}
+
+// CORSGroupConfig represents configuration for CORS of a route group.
+type CORSGroupConfig struct {
+	// PathPrefix is path prefix of the route group.
+	PathPrefix *string `json:"path_prefix"`
+
+	// AllowedOrigins is allowed origins of CORS, defaults to the global allowed origins.
+	AllowedOrigins *[]string `json:"allowed_origins"`
+
+	// AllowedMethods is allowed methods of CORS, defaults to the global allowed methods.
+	AllowedMethods *[]string `json:"allowed_methods"`
+
+	// AllowedHeaders is allowed headers of CORS, defaults to the global allowed headers.
+	AllowedHeaders *[]string `json:"allowed_headers"`
 }
 
 // SetDefault sets default values.
 func (c *Config) SetDefault() {
 	c.setServerDefault()
+	c.setListenersDefault()
+	c.setConnectionsDefault()
+	c.setKeepAliveDefault()
+	c.setRestartDefault()
+	c.setTLSDefault()
+	c.setRequestIDDefault()
 	c.setCompressionDefault()
+	c.setFormsDefault()
+	c.setCSRFDefault()
 	c.setCORSDefault()
+	c.setTenancyDefault()
 	c.setRateLimitDefault()
+	c.setRetryBudgetDefault()
 	c.setMetricsDefault()
+	c.setAdminDefault()
+	c.setSettingsDefault()
+	c.setAPIKeysDefault()
+	c.setReplayDefault()
+	c.setValidationDefault()
+	c.setPagesDefault()
+	c.setWellKnownDefault()
+	c.setDocsDefault()
+	c.setMountsDefault()
 }
 
 // setServerDefault sets default values for server.
@@ -121,6 +441,10 @@ func (c *Config) setServerDefault() {
 		c.Port = &[]int{8080}[0]
 	}
 
+	if c.Listen == nil {
+		c.Listen = &[]bool{true}[0]
+	}
+
 	if c.ReadTimeout == nil {
 		c.ReadTimeout = &[]int{10}[0]
 	}
@@ -133,6 +457,14 @@ func (c *Config) setServerDefault() {
 		c.IdleTimeout = &[]int{10}[0]
 	}
 
+	if c.ReadHeaderTimeout == nil {
+		c.ReadHeaderTimeout = &[]int{5}[0]
+	}
+
+	if c.MaxHeaderBytes == nil {
+		c.MaxHeaderBytes = &[]int{65536}[0] // 64KB
+	}
+
 	if c.ShutdownTimeout == nil {
 		c.ShutdownTimeout = &[]int{10}[0]
 	}
@@ -140,6 +472,22 @@ func (c *Config) setServerDefault() {
 	if c.MaxRequestSize == nil {
 		c.MaxRequestSize = &[]int64{10485760}[0] // 10MB
 	}
+
+	if c.HSTS == nil {
+		c.HSTS = &[]bool{true}[0]
+	}
+
+	if c.VerboseErrors == nil {
+		c.VerboseErrors = &[]bool{false}[0]
+	}
+
+	if c.ErrorFormat == nil {
+		c.ErrorFormat = &[]apierror.Format{apierror.FormatJSON}[0]
+	}
+
+	if c.TrustedProxies == nil {
+		c.TrustedProxies = []string{}
+	}
 }
 
 // setCompressionDefault sets default values for compression on server.
@@ -159,6 +507,50 @@ func (c *Config) setCompressionDefault() {
 	if c.Compression.Enabled == nil {
 		c.Compression.Enabled = &[]bool{true}[0]
 	}
+
+	if c.Compression.MinSize == nil {
+		c.Compression.MinSize = &[]int{1024}[0] // 1KB
+	}
+
+	if c.Compression.ContentTypes == nil {
+		c.Compression.ContentTypes = &[]string{}
+	}
+
+	if c.Compression.ExcludeContentTypes == nil {
+		c.Compression.ExcludeContentTypes = &[]string{
+			"image/*", "video/*", "audio/*", "application/gzip", "application/zip", "text/event-stream",
+		}
+	}
+
+	if c.Compression.ExcludePaths == nil {
+		c.Compression.ExcludePaths = &[]string{"/metrics"}
+	}
+
+	if c.Compression.DecompressRequests == nil {
+		c.Compression.DecompressRequests = &[]bool{true}[0]
+	}
+
+	if c.Compression.MaxDecompressRatio == nil {
+		c.Compression.MaxDecompressRatio = &[]int64{100}[0]
+	}
+}
+
+// setFormsDefault sets default values for form parsing limits on server.
+func (c *Config) setFormsDefault() {
+	if c.Forms == nil {
+		c.Forms = &middleware.FormLimitConfig{}
+	}
+
+	c.Forms.SetDefault()
+}
+
+// setCSRFDefault sets default values for CSRF protection on server.
+func (c *Config) setCSRFDefault() {
+	if c.CSRF == nil {
+		c.CSRF = &middleware.CSRFConfig{}
+	}
+
+	c.CSRF.SetDefault()
 }
 
 // setCORSDefault sets default values for CORS on server.
@@ -178,6 +570,37 @@ func (c *Config) setCORSDefault() {
 	if c.CORS.AllowedHeaders == nil {
 		c.CORS.AllowedHeaders = &[]string{"Accept", "Authorization", "Content-Type", "X-CSRF-Token"}
 	}
+
+	if c.CORS.AllowCredentials == nil {
+		c.CORS.AllowCredentials = &[]bool{false}[0]
+	}
+
+	for _, group := range c.CORS.Groups {
+		if group.PathPrefix == nil {
+			group.PathPrefix = &[]string{"/"}[0]
+		}
+
+		if group.AllowedOrigins == nil {
+			group.AllowedOrigins = c.CORS.AllowedOrigins
+		}
+
+		if group.AllowedMethods == nil {
+			group.AllowedMethods = c.CORS.AllowedMethods
+		}
+
+		if group.AllowedHeaders == nil {
+			group.AllowedHeaders = c.CORS.AllowedHeaders
+		}
+	}
+}
+
+// setTenancyDefault sets default values for tenant resolution on server.
+func (c *Config) setTenancyDefault() {
+	if c.Tenancy == nil {
+		c.Tenancy = &middleware.TenancyConfig{}
+	}
+
+	c.Tenancy.SetDefault()
 }
 
 // setRateLimitDefault sets default values for rate limit on server.
@@ -189,6 +612,22 @@ func (c *Config) setRateLimitDefault() {
 	c.setGlobalRateLimitDefault()
 	c.setIPRateLimitDefault()
 	c.setEndpointRateLimitDefault()
+	c.setTenantRateLimitDefault()
+	c.setUserRateLimitDefault()
+
+	if c.RateLimit.Headers == nil {
+		c.RateLimit.Headers = &[]middleware.RateLimitHeaders{middleware.RateLimitHeadersBoth}[0]
+	}
+
+	if c.RateLimit.FailureMode == nil {
+		c.RateLimit.FailureMode = &[]middleware.RateLimitFailureMode{middleware.RateLimitFailureModeLocal}[0]
+	}
+
+	if c.RateLimit.Exemptions == nil {
+		c.RateLimit.Exemptions = &middleware.RateLimitExemptionsConfig{}
+	}
+
+	c.RateLimit.Exemptions.SetDefault()
 }
 
 // setGlobalRateLimitDefault sets default values for global rate limit.
@@ -208,6 +647,10 @@ func (c *Config) setGlobalRateLimitDefault() {
 	if c.RateLimit.Global.Window == nil {
 		c.RateLimit.Global.Window = &[]int{60}[0]
 	}
+
+	if c.RateLimit.Global.Algorithm == nil {
+		c.RateLimit.Global.Algorithm = &[]middleware.RateLimitAlgorithm{middleware.RateLimitAlgorithmFixedWindow}[0]
+	}
 }
 
 // setIPRateLimitDefault sets default values for IP rate limit.
@@ -227,6 +670,10 @@ func (c *Config) setIPRateLimitDefault() {
 	if c.RateLimit.IP.Window == nil {
 		c.RateLimit.IP.Window = &[]int{60}[0]
 	}
+
+	if c.RateLimit.IP.Algorithm == nil {
+		c.RateLimit.IP.Algorithm = &[]middleware.RateLimitAlgorithm{middleware.RateLimitAlgorithmFixedWindow}[0]
+	}
 }
 
 // setEndpointRateLimitDefault sets default values for endpoint rate limit.
@@ -246,6 +693,60 @@ func (c *Config) setEndpointRateLimitDefault() {
 	if c.RateLimit.Endpoint.Window == nil {
 		c.RateLimit.Endpoint.Window = &[]int{60}[0]
 	}
+
+	if c.RateLimit.Endpoint.Algorithm == nil {
+		c.RateLimit.Endpoint.Algorithm = &[]middleware.RateLimitAlgorithm{middleware.RateLimitAlgorithmFixedWindow}[0]
+	}
+}
+
+// setTenantRateLimitDefault sets default values for tenant rate limit.
+func (c *Config) setTenantRateLimitDefault() {
+	if c.RateLimit.Tenant == nil {
+		c.RateLimit.Tenant = &middleware.RateLimitTypeConfig{}
+	}
+
+	if c.RateLimit.Tenant.Enabled == nil {
+		c.RateLimit.Tenant.Enabled = &[]bool{false}[0]
+	}
+
+	if c.RateLimit.Tenant.Requests == nil {
+		c.RateLimit.Tenant.Requests = &[]int{1000}[0]
+	}
+
+	if c.RateLimit.Tenant.Window == nil {
+		c.RateLimit.Tenant.Window = &[]int{60}[0]
+	}
+
+	if c.RateLimit.Tenant.Algorithm == nil {
+		c.RateLimit.Tenant.Algorithm = &[]middleware.RateLimitAlgorithm{middleware.RateLimitAlgorithmFixedWindow}[0]
+	}
+
+	if c.RateLimit.TenantCacheTTL == nil {
+		c.RateLimit.TenantCacheTTL = &[]int{60}[0]
+	}
+}
+
+// setUserRateLimitDefault sets default values for user rate limit.
+func (c *Config) setUserRateLimitDefault() {
+	if c.RateLimit.User == nil {
+		c.RateLimit.User = &middleware.RateLimitTypeConfig{}
+	}
+
+	if c.RateLimit.User.Enabled == nil {
+		c.RateLimit.User.Enabled = &[]bool{false}[0]
+	}
+
+	if c.RateLimit.User.Requests == nil {
+		c.RateLimit.User.Requests = &[]int{100}[0]
+	}
+
+	if c.RateLimit.User.Window == nil {
+		c.RateLimit.User.Window = &[]int{60}[0]
+	}
+
+	if c.RateLimit.User.Algorithm == nil {
+		c.RateLimit.User.Algorithm = &[]middleware.RateLimitAlgorithm{middleware.RateLimitAlgorithmFixedWindow}[0]
+	}
 }
 
 // setMetricsDefault sets default values for metrics.
@@ -263,27 +764,103 @@ func (c *Config) setMetricsDefault() {
 	}
 
 	if c.Metrics.ExcludePaths == nil {
-		c.Metrics.ExcludePaths = []string{"/health", "/status"}
+		c.Metrics.ExcludePaths = []string{
+			"/health",
+			"/live",
+			"/ready",
+			"/status",
+			"/robots.txt",
+			"/favicon.ico",
+			"/.well-known/security.txt",
+			"/.well-known/change-password",
+		}
 	}
 
 	c.Metrics.SetDefault()
 }
 
+// setRetryBudgetDefault sets default values for retry budget of clients.
+func (c *Config) setRetryBudgetDefault() {
+	if c.RetryBudget == nil {
+		c.RetryBudget = &middleware.RetryBudgetConfig{}
+	}
+
+	c.RetryBudget.SetDefault()
+}
+
+// setReplayDefault sets default values for request replay capture.
+func (c *Config) setReplayDefault() {
+	if c.Replay == nil {
+		c.Replay = &middleware.ReplayConfig{}
+	}
+
+	// credentials of the configured API key and CSRF headers are redacted along with the standard headers
+	if c.Replay.RedactHeaders == nil {
+		c.Replay.RedactHeaders = []string{"Authorization", "Cookie", "Set-Cookie", *c.APIKeys.Header, *c.CSRF.HeaderName}
+	}
+
+	c.Replay.SetDefault()
+}
+
+// setRequestIDDefault sets default values for request IDs on server.
+func (c *Config) setRequestIDDefault() {
+	if c.RequestID == nil {
+		c.RequestID = &middleware.RequestIDConfig{}
+	}
+
+	c.RequestID.SetDefault()
+}
+
+// setValidationDefault sets default values for request validation on server.
+func (c *Config) setValidationDefault() {
+	if c.Validation == nil {
+		c.Validation = &middleware.ValidationConfig{}
+	}
+
+	c.Validation.SetDefault()
+}
+
 // NewModule provides module for server.
 func NewModule() fx.Option {
 	return fx.Module("server",
-		fx.Provide(New),
+		fx.Provide(New, NewMounts),
 	)
 }
 
+// ServerParams represents dependencies of the server, subsystems are nil if their modules are excluded, so that
+// tests set only the dependencies they use.
+type ServerParams struct {
+	fx.In
+
+	Config     *Config
+	Logger     *logger.Logger
+	APIHandler api.ServerInterface
+	JWT        *jwt.JWT
+	Redis      *redis.Redis
+	DB         *database.DB
+	Renderer   *render.Render
+	Settings   *settings.Settings
+	APIKeys    *apikey.Store
+	ReadOnly   *readonly.ReadOnly
+	Authz      *authz.Authz
+	Usage      *usage.Recorder
+	Payments   *payments.Payments
+	Signer     *signedurl.Signer
+	Images     *images.Images
+	Mounts     Mounts
+	Hub        *websocket.Hub
+	Broker     *sse.Broker
+	QueryCache *querycache.QueryCache
+	Scheduler  *scheduler.Scheduler
+	Jobs       *jobs.Jobs
+	Auditor    *audit.Auditor
+	SAML       *saml.ServiceProvider
+}
+
 // New create new server instance.
-func New(
-	config *Config,
-	logger *logger.Logger,
-	apiHandler api.ServerInterface,
-	jwtService *jwt.JWT,
-	redis *redis.Redis,
-) (*Server, error) {
+func New(params ServerParams) (*Server, error) {
+	config, logger := params.Config, params.Logger
+
 	// set default
 	if config == nil {
 		config = &Config{}
@@ -291,17 +868,248 @@ func New(
 
 	config.SetDefault()
 
+	logger = logger.Named("server")
+
+	if err := validateListeners(config); err != nil {
+		return nil, err
+	}
+
+	if err := validateConnections(config.Connections); err != nil {
+		return nil, err
+	}
+
+	if err := validateTCPKeepAlive(config.TCPKeepAlive); err != nil {
+		return nil, err
+	}
+
+	if err := validateRestart(config.Restart); err != nil {
+		return nil, err
+	}
+
+	if err := validateTLS(config.TLS); err != nil {
+		return nil, err
+	}
+
+	if err := config.ErrorFormat.Validate(); err != nil {
+		return nil, err
+	}
+
+	if err := config.RateLimit.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid rate limit config: %w", err)
+	}
+
+	if *config.Compression.Enabled {
+		if err := config.Compression.compressConfig().Validate(); err != nil {
+			return nil, fmt.Errorf("invalid compression config: %w", err)
+		}
+	}
+
+	if *config.Compression.DecompressRequests {
+		if err := config.decompressConfig().Validate(); err != nil {
+			return nil, fmt.Errorf("invalid compression config: %w", err)
+		}
+	}
+
+	if err := config.APIKeys.RateLimit.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid api key rate limit config: %w", err)
+	}
+
+	requestID, err := middleware.RequestID(config.RequestID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid request id config: %w", err)
+	}
+
+	resolver, err := netutil.NewResolver(config.TrustedProxies)
+	if err != nil {
+		return nil, fmt.Errorf("invalid trusted proxies config: %w", err)
+	}
+
+	apierror.SetFormat(*config.ErrorFormat)
+	apierror.SetRequestIDHeader(*config.RequestID.Header)
+
 	// create server
 	server := &Server{
-		config:   config,
-		logger:   logger,
-		registry: prometheus.NewRegistry(),
+		config:      config,
+		logger:      logger,
+		registry:    prometheus.NewRegistry(),
+		redis:       params.Redis,
+		inFlight:    middleware.NewInFlight(),
+		readOnly:    params.ReadOnly,
+		queryCache:  params.QueryCache,
+		scheduler:   params.Scheduler,
+		jobs:        params.Jobs,
+		authz:       params.Authz,
+		usage:       params.Usage,
+		requestID:   requestID,
+		realIP:      middleware.RealIP(resolver),
+		connections: newConnectionLimiter(config.Connections),
+		connStates:  newConnStateTracker(),
+	}
+
+	// tenant headers are only trusted from trusted proxies, as client IPs are
+	if *config.Tenancy.Enabled {
+		server.tenant = middleware.Tenant(*config.Tenancy.Header, resolver)
+	}
+
+	if err := server.registry.Register(server.connections); err != nil {
+		return nil, fmt.Errorf("failed to register connection metrics: %w", err)
+	}
+
+	if err := server.registry.Register(server.connStates); err != nil {
+		return nil, fmt.Errorf("failed to register connection state metrics: %w", err)
+	}
+
+	for _, collector := range config.Metrics.RuntimeCollectors() {
+		if err := server.registry.Register(collector); err != nil {
+			return nil, fmt.Errorf("failed to register runtime metrics: %w", err)
+		}
+	}
+
+	// expose token metrics on the server registry
+	if params.JWT != nil {
+		if err := server.registry.Register(params.JWT); err != nil {
+			return nil, fmt.Errorf("failed to register jwt metrics: %w", err)
+		}
+	}
+
+	rateLimitFallback, err := middleware.NewRateLimitFallback(*config.RateLimit.FailureMode, server.registry)
+	if err != nil {
+		return nil, err
+	}
+
+	server.rateLimitFallback = rateLimitFallback
+
+	rateLimitRules, err := middleware.NewRateLimitRules(config.RateLimit.Exemptions)
+	if err != nil {
+		return nil, fmt.Errorf("invalid rate limit config: %w", err)
+	}
+
+	server.rateLimitRules = rateLimitRules
+
+	if *config.Replay.Enabled {
+		server.replayStore = middleware.NewReplayStore(params.Redis, time.Duration(*config.Replay.TTL)*time.Second)
+	}
+
+	if *config.Metrics.Enabled {
+		metrics, err := middleware.Metrics(config.Metrics, server.registry)
+		if err != nil {
+			return nil, err
+		}
+
+		server.metrics = metrics
+	}
+
+	if *config.Validation.Enabled {
+		spec, err := api.GetSwagger()
+		if err != nil {
+			return nil, fmt.Errorf("failed to load openapi spec: %w", err)
+		}
+
+		validation, err := middleware.Validation(spec, logger, server.registry)
+		if err != nil {
+			return nil, err
+		}
+
+		server.validation = validation
+	}
+
+	if *config.Settings.Enabled {
+		server.settings = params.Settings
+	}
+
+	if *config.APIKeys.Enabled {
+		server.apiKeyStore = params.APIKeys
+	}
+
+	if params.Payments.Enabled() {
+		server.payments = params.Payments
+	}
+
+	if params.Signer.Enabled() {
+		server.signer = params.Signer
+	}
+
+	if params.Images.Enabled() {
+		if server.signer == nil {
+			return nil, ErrImagesRequireSignedURLs
+		}
+
+		server.images = params.Images
+	}
+
+	if params.Hub.Enabled() {
+		server.hub = params.Hub
+	}
+
+	if params.Auditor.Enabled() {
+		server.auditor = params.Auditor
+	}
+
+	if params.SAML.Enabled() {
+		server.saml = params.SAML
+	}
+
+	if params.Broker.Enabled() {
+		server.broker = params.Broker
+	}
+
+	if *config.RateLimit.Tenant.Enabled {
+		if params.DB == nil {
+			return nil, ErrTenantRateLimitRequiresDatabase
+		}
+
+		server.tenantLimitStore = middleware.NewTenantLimitStore(
+			params.DB.Queries,
+			params.Redis,
+			time.Duration(*config.RateLimit.TenantCacheTTL)*time.Second,
+		)
 	}
 
 	// setup router and handlers
-	router := server.setupRouter(config, logger, redis)
-	httpHandler := server.setupAPIHandler(apiHandler, router, jwtService, logger)
-	server.httpServer = server.createHTTPServer(config, httpHandler)
+	router := server.setupRouter(config, logger, params.Redis)
+	server.setupAdminRoutes(router, config, params.JWT)
+	server.setupSettingsRoutes(router, config, params.JWT)
+	server.setupAPIKeyRoutes(router, config, params.JWT)
+	server.setupPaymentRoutes(router)
+	server.setupSignedURLRoutes(router, params.JWT)
+	server.setupImageRoutes(router, params.JWT)
+	server.setupWebSocketRoutes(router, params.JWT)
+	server.setupEventRoutes(router, params.JWT)
+	server.setupOperationRoutes(router, params.JWT)
+	server.setupSAMLRoutes(router, params.JWT)
+	server.setupPageRoutes(router, config, params.Renderer)
+
+	if err := server.setupWellKnownRoutes(router, config); err != nil {
+		return nil, err
+	}
+
+	if err := server.setupDocsRoutes(router, config); err != nil {
+		return nil, err
+	}
+
+	if err := server.setupMounts(router, config, params.Mounts); err != nil {
+		return nil, err
+	}
+
+	httpHandler := server.setupAPIHandler(params.APIHandler, router, config, params.JWT, logger)
+	listeners, err := server.createListeners(config, httpHandler)
+	if err != nil {
+		return nil, err
+	}
+
+	server.listeners = listeners
+	server.httpServer = listeners[0].server
+
+	if *config.Admin.Addr != "" {
+		network, _ := networkOf(*config.AddressFamily)
+
+		adminListener, err := server.createListener(config, *config.Admin.Addr, network, "", "", server.adminHandler())
+		if err != nil {
+			return nil, err
+		}
+
+		server.adminListener = adminListener
+	}
 
 	return server, nil
 }
@@ -310,9 +1118,23 @@ func New(
 func (s *Server) setupRouter(config *Config, logger *logger.Logger, redis *redis.Redis) *chi.Mux {
 	router := chi.NewRouter()
 
+	// unmatched routes respond with the error envelope
+	router.NotFound(func(writer http.ResponseWriter, _ *http.Request) {
+		writeError(writer, http.StatusNotFound, http.StatusText(http.StatusNotFound))
+	})
+	router.MethodNotAllowed(func(writer http.ResponseWriter, _ *http.Request) {
+		writeError(writer, http.StatusMethodNotAllowed, http.StatusText(http.StatusMethodNotAllowed))
+	})
+
 	s.setupBasicMiddlewares(router, config)
-	s.setupRateLimitMiddlewares(router, config, redis, logger)
-	s.setupCORS(router, config)
+
+	// rate limits and CORS are swapped when configuration is reloaded
+	s.rateLimits = newSwappableMiddleware(s.rateLimitMiddleware(config, redis, logger))
+	router.Use(s.rateLimits.use)
+
+	s.cors = newSwappableMiddleware(corsMiddleware(config))
+	router.Use(s.cors.use)
+
 	s.setupMetricsEndpoint(router, config)
 
 	return router
@@ -320,66 +1142,257 @@ func (s *Server) setupRouter(config *Config, logger *logger.Logger, redis *redis
 
 // setupBasicMiddlewares sets up basic middlewares.
 func (s *Server) setupBasicMiddlewares(router *chi.Mux, config *Config) {
-	router.Use(middleware.RequestID)
-	router.Use(middleware.RealIP)
-	router.Use(middleware.Recoverer)
-	router.Use(middleware.SecurityHeaders())
-	router.Use(middleware.RequestSize(*config.MaxRequestSize))
+	router.Use(s.inFlight.Middleware)
+	router.Use(s.requestID)
+	router.Use(middleware.TraceContext)
+	router.Use(middleware.Tracing)
+	router.Use(middleware.RequestLogger(s.logger))
+	router.Use(s.realIP)
+
+	// denials and admin actions are logged with the client IP resolved above
+	if s.auditor != nil {
+		router.Use(middleware.Audit(s.auditor))
+	}
+
+	if s.tenant != nil {
+		router.Use(s.tenant)
+
+		// usage is recorded outside compression, so that bytes sent to clients are counted
+		if s.usage.Enabled() {
+			router.Use(middleware.Usage(s.usage))
+		}
+	}
+
+	router.Use(middleware.Recover(s.logger, *config.VerboseErrors))
+	router.Use(middleware.SecurityHeaders(*config.HSTS))
+
+	// bodies are decompressed before they are limited, so that limits apply to the decompressed size
+	if *config.Compression.DecompressRequests {
+		router.Use(middleware.Decompress(config.decompressConfig()))
+	}
+
+	router.Use(middleware.FormLimit(config.Forms, *config.MaxRequestSize))
+
+	if *config.CSRF.Enabled {
+		router.Use(s.csrfMiddleware(config))
+	}
 
 	if *config.Compression.Enabled {
-		router.Use(middleware.Compress(*config.Compression.Level, *config.Compression.Format))
+		router.Use(middleware.Compress(config.Compression.compressConfig()))
 	}
 
-	if *config.Metrics.Enabled {
-		router.Use(middleware.Metrics(config.Metrics, s.registry))
+	if s.metrics != nil {
+		router.Use(s.metrics)
 	}
 
 	router.Use(middleware.LogRequest(s.logger))
 	router.Use(middleware.Timeout(time.Duration(*config.ReadTimeout) * time.Second))
+
+	// the toggle stays writable, so that read-only mode can be turned off
+	if s.readOnly != nil {
+		router.Use(middleware.ReadOnly(s.readOnly, []string{readOnlyTogglePath(config)}, s.logger))
+	}
+}
+
+// csrfMiddleware returns the CSRF middleware, exempting webhooks signed by their senders, SAML responses
+// posted by identity providers and requests authenticated by API keys.
+func (s *Server) csrfMiddleware(config *Config) func(next http.Handler) http.Handler {
+	var exemptPaths, exemptHeaders []string
+
+	if s.payments != nil {
+		exemptPaths = append(exemptPaths, s.payments.WebhookPath())
+	}
+
+	// responses are signed by identity providers, which post them cross-site
+	if s.saml != nil {
+		exemptPaths = append(exemptPaths, s.saml.Path()+"/")
+	}
+
+	if *config.APIKeys.Enabled {
+		exemptHeaders = append(exemptHeaders, *config.APIKeys.Header)
+	}
+
+	return middleware.CSRF(config.CSRF, exemptPaths, exemptHeaders, s.logger)
 }
 
-// setupRateLimitMiddlewares sets up rate limit middlewares.
-func (s *Server) setupRateLimitMiddlewares(router *chi.Mux, config *Config, redis *redis.Redis, logger *logger.Logger) {
+// rateLimitMiddleware returns the enabled rate limit and retry budget middlewares chained in one middleware.
+func (s *Server) rateLimitMiddleware(
+	config *Config,
+	redis *redis.Redis,
+	logger *logger.Logger,
+) func(next http.Handler) http.Handler {
+	// exemptions and overrides are matched before any rate limit
+	middlewares := chi.Middlewares{s.rateLimitRules.Middleware}
+
 	if *config.RateLimit.Global.Enabled {
-		router.Use(middleware.GlobalRateLimit(
+		middlewares = append(middlewares, middleware.GlobalRateLimit(
 			*config.RateLimit.Global.Requests,
 			time.Duration(*config.RateLimit.Global.Window)*time.Second,
+			*config.RateLimit.Global.Algorithm,
+			*config.RateLimit.Headers,
 			redis,
+			s.rateLimitFallback,
 			logger,
 		))
 	}
 
 	if *config.RateLimit.IP.Enabled {
-		router.Use(middleware.IPRateLimit(
+		middlewares = append(middlewares, middleware.IPRateLimit(
 			*config.RateLimit.IP.Requests,
 			time.Duration(*config.RateLimit.IP.Window)*time.Second,
+			*config.RateLimit.IP.Algorithm,
+			*config.RateLimit.Headers,
 			redis,
+			s.rateLimitFallback,
 			logger,
 		))
 	}
 
 	if *config.RateLimit.Endpoint.Enabled {
-		router.Use(middleware.EndpointRateLimit(
+		middlewares = append(middlewares, middleware.EndpointRateLimit(
 			*config.RateLimit.Endpoint.Requests,
 			time.Duration(*config.RateLimit.Endpoint.Window)*time.Second,
+			*config.RateLimit.Endpoint.Algorithm,
+			*config.RateLimit.Headers,
 			redis,
+			s.rateLimitFallback,
 			logger,
 		))
 	}
+
+	if *config.RetryBudget.Enabled {
+		middlewares = append(middlewares, middleware.RetryBudget(config.RetryBudget, redis, logger))
+	}
+
+	return middlewares.Handler
+}
+
+// userRateLimitMiddleware returns the user rate limit middleware, passing requests through if it is disabled.
+func userRateLimitMiddleware(
+	config *Config,
+	redis *redis.Redis,
+	fallback *middleware.RateLimitFallback,
+	logger *logger.Logger,
+) func(next http.Handler) http.Handler {
+	if !*config.RateLimit.User.Enabled {
+		return func(next http.Handler) http.Handler {
+			return next
+		}
+	}
+
+	return middleware.UserRateLimit(
+		*config.RateLimit.User.Requests,
+		time.Duration(*config.RateLimit.User.Window)*time.Second,
+		*config.RateLimit.User.Algorithm,
+		*config.RateLimit.Headers,
+		redis,
+		fallback,
+		logger,
+	)
+}
+
+// tenantRateLimitMiddleware returns the tenant rate limit middleware, passing requests through if it is disabled.
+func (s *Server) tenantRateLimitMiddleware(config *Config) func(next http.Handler) http.Handler {
+	// the tenant store is created on startup, so tenant rate limit can only be disabled on reload
+	if s.tenantLimitStore == nil || !*config.RateLimit.Tenant.Enabled {
+		return func(next http.Handler) http.Handler {
+			return next
+		}
+	}
+
+	return middleware.TenantRateLimit(
+		*config.RateLimit.Tenant.Requests,
+		time.Duration(*config.RateLimit.Tenant.Window)*time.Second,
+		*config.RateLimit.Tenant.Algorithm,
+		*config.RateLimit.Headers,
+		s.tenantLimitStore,
+		s.redis,
+		s.rateLimitFallback,
+		s.logger,
+	)
 }
 
-// setupCORS sets up CORS handler on router.
-func (s *Server) setupCORS(router *chi.Mux, config *Config) {
+// corsMiddleware returns CORS middleware, using the policy of the matching route group.
+func corsMiddleware(config *Config) func(next http.Handler) http.Handler {
+	// clients read the request ID to report failures
+	exposedHeaders := []string{"Link", *config.RequestID.Header}
+
+	defaultCORS := newCORSHandler(
+		*config.CORS.AllowedOrigins,
+		*config.CORS.AllowedMethods,
+		*config.CORS.AllowedHeaders,
+		exposedHeaders,
+		*config.CORS.AllowCredentials,
+	)
+
+	if len(config.CORS.Groups) == 0 {
+		return defaultCORS
+	}
+
+	// match longer prefixes first
+	groups := make([]*CORSGroupConfig, len(config.CORS.Groups))
+	copy(groups, config.CORS.Groups)
+	sort.SliceStable(groups, func(i, j int) bool {
+		return len(*groups[i].PathPrefix) > len(*groups[j].PathPrefix)
+	})
+
+	groupCORS := make([]func(next http.Handler) http.Handler, len(groups))
+	for i, group := range groups {
+		groupCORS[i] = newCORSHandler(
+			*group.AllowedOrigins,
+			*group.AllowedMethods,
+			*group.AllowedHeaders,
+			exposedHeaders,
+			*config.CORS.AllowCredentials,
+		)
+	}
+
+	return func(next http.Handler) http.Handler {
+		defaultHandler := defaultCORS(next)
+
+		groupHandlers := make([]http.Handler, len(groups))
+		for i := range groups {
+			groupHandlers[i] = groupCORS[i](next)
+		}
+
+		return http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+			for i, group := range groups {
+				if matchPathPrefix(request.URL.Path, *group.PathPrefix) {
+					groupHandlers[i].ServeHTTP(writer, request)
+
+					return
+				}
+			}
+
+			defaultHandler.ServeHTTP(writer, request)
+		})
+	}
+}
+
+// newCORSHandler creates a CORS handler with the given policy.
+func newCORSHandler(
+	origins, methods, headers, exposedHeaders []string,
+	credentials bool,
+) func(next http.Handler) http.Handler {
 	const corsMaxAge = 300 // 5 minutes
 
-	router.Use(cors.Handler(cors.Options{
-		AllowedOrigins:   *config.CORS.AllowedOrigins,
-		AllowedMethods:   *config.CORS.AllowedMethods,
-		AllowedHeaders:   *config.CORS.AllowedHeaders,
-		AllowCredentials: false,
-		ExposedHeaders:   []string{"Link"},
+	return cors.Handler(cors.Options{
+		AllowedOrigins:   origins,
+		AllowedMethods:   methods,
+		AllowedHeaders:   headers,
+		AllowCredentials: credentials,
+		ExposedHeaders:   exposedHeaders,
 		MaxAge:           corsMaxAge,
-	}))
+	})
+}
+
+// matchPathPrefix checks if the path is the prefix or below it.
+func matchPathPrefix(path, prefix string) bool {
+	if prefix == "/" || path == prefix {
+		return true
+	}
+
+	return strings.HasPrefix(path, strings.TrimSuffix(prefix, "/")+"/")
 }
 
 // setupMetricsEndpoint sets up the metrics endpoint with isolated registry.
@@ -387,55 +1400,233 @@ func (s *Server) setupMetricsEndpoint(router *chi.Mux, config *Config) {
 	if *config.Metrics.Enabled {
 		router.Handle(*config.Metrics.Path, promhttp.HandlerFor(
 			s.registry,
-			promhttp.HandlerOpts{},
+			config.Metrics.HandlerOpts(),
 		))
 	}
 }
 
-// setupAPIHandler sets up the API handler with JWT authentication.
+// setupAPIHandler sets up the API handler with JWT authentication and scope authorization.
 func (s *Server) setupAPIHandler(
 	apiHandler api.ServerInterface,
 	router *chi.Mux,
+	config *Config,
 	jwtService *jwt.JWT,
 	logger *logger.Logger,
 ) http.Handler {
+	// middlewares are applied in reverse order, so the first middleware runs closest to the handler
+	middlewares := []api.MiddlewareFunc{}
+
+	// requests are validated after authentication, so unauthenticated requests are rejected first
+	if s.validation != nil {
+		middlewares = append(middlewares, s.validation)
+	}
+
+	if s.replayStore != nil {
+		middlewares = append(middlewares, middleware.Replay(config.Replay, s.replayStore, logger))
+	}
+
+	// entitlements are checked after authentication and scopes, so callers are not told about plans they cannot use
+	if s.payments != nil {
+		middlewares = append(middlewares, middleware.Entitlement(s.payments, logger))
+	}
+
+	// user and tenant rate limits run after authentication to key requests by the authenticated user and tenant
+	s.userRateLimit = newRouteMiddleware(userRateLimitMiddleware(config, s.redis, s.rateLimitFallback, logger))
+	s.tenantRateLimit = newRouteMiddleware(s.tenantRateLimitMiddleware(config))
+
+	middlewares = append(middlewares,
+		middleware.RequireScopes(), s.tenantRateLimit.use, s.userRateLimit.use, middleware.JWTAuth(jwtService, logger),
+	)
+
+	// api keys authenticate before JWT, which is skipped for requests authenticated by a key
+	if s.apiKeyStore != nil {
+		middlewares = append(middlewares, s.apiKeyAuthMiddleware(config))
+	}
+
+	// signed URLs authenticate before JWT as well, so links work without a token
+	if s.signer != nil {
+		middlewares = append(middlewares, middleware.SignedURL(s.signer, logger))
+	}
+
 	return api.HandlerWithOptions(apiHandler, api.ChiServerOptions{
-		BaseRouter: router,
-		Middlewares: []api.MiddlewareFunc{
-			middleware.JWTAuth(jwtService, logger),
+		BaseRouter:  router,
+		Middlewares: middlewares,
+		ErrorHandlerFunc: func(writer http.ResponseWriter, _ *http.Request, err error) {
+			writeError(writer, http.StatusBadRequest, err.Error())
 		},
 	})
 }
 
 // createHTTPServer creates the HTTP server.
-func (s *Server) createHTTPServer(config *Config, handler http.Handler) *http.Server {
-	return &http.Server{
-		Addr:         *config.Host + ":" + strconv.Itoa(*config.Port),
-		Handler:      handler,
-		ReadTimeout:  time.Duration(*config.ReadTimeout) * time.Second,
-		WriteTimeout: time.Duration(*config.WriteTimeout) * time.Second,
-		IdleTimeout:  time.Duration(*config.IdleTimeout) * time.Second,
+func (s *Server) createHTTPServer(config *Config, addr string, handler http.Handler) *http.Server {
+	httpServer := &http.Server{
+		Addr:              addr,
+		Handler:           handler,
+		ReadTimeout:       time.Duration(*config.ReadTimeout) * time.Second,
+		ReadHeaderTimeout: time.Duration(*config.ReadHeaderTimeout) * time.Second,
+		WriteTimeout:      time.Duration(*config.WriteTimeout) * time.Second,
+		IdleTimeout:       time.Duration(*config.IdleTimeout) * time.Second,
+		MaxHeaderBytes:    *config.MaxHeaderBytes,
+	}
+
+	httpServer.SetKeepAlivesEnabled(!*config.DisableKeepAlives)
+
+	if s.connStates != nil {
+		httpServer.ConnState = s.connStates.callback(addr)
 	}
+
+	return httpServer
+}
+
+// Handler returns the HTTP handler of server, including all middlewares and routes.
+func (s *Server) Handler() http.Handler {
+	if s.httpServer == nil {
+		return nil
+	}
+
+	return s.httpServer.Handler
+}
+
+// Addr returns the address of the first listener.
+func (s *Server) Addr() string {
+	if s.httpServer == nil {
+		return ""
+	}
+
+	return s.httpServer.Addr
+}
+
+// RegisterCollector exposes metrics of the collector on the metrics endpoint.
+func (s *Server) RegisterCollector(collector prometheus.Collector) error {
+	if err := s.registry.Register(collector); err != nil {
+		return fmt.Errorf("failed to register collector: %w", err)
+	}
+
+	return nil
 }
 
-// Run runs HTTP server.
+// Run runs HTTP servers on all listeners until they are shut down, returns immediately if listening is disabled.
 func (s *Server) Run() error {
 	if s.httpServer == nil {
 		return ErrServerNotInitialized
 	}
 
-	s.logger.Info().
-		Str("addr", s.httpServer.Addr).
-		Msg("starting server")
+	if !*s.config.Listen {
+		s.logger.Info().Msg("listening is disabled, skipping server start")
+
+		return nil
+	}
 
-	if err := s.httpServer.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+	listeners := s.listeners
+	if s.adminListener != nil {
+		listeners = append(slices.Clone(listeners), s.adminListener)
+	}
+
+	inherited, err := s.inheritedListeners()
+	if err != nil {
 		return fmt.Errorf("failed to start server: %w", err)
 	}
 
-	return nil
+	// bind all addresses first so a conflict fails before serving any
+	netListeners := make([]net.Listener, 0, len(listeners))
+	bound := make([]net.Listener, 0, len(listeners))
+	listen := listenConfig(s.config.TCPKeepAlive)
+
+	if *s.config.Restart.ReusePort {
+		listen.Control = reusePortControl
+	}
+
+	for i, listener := range listeners {
+		// addresses passed by the process manager are served without binding them again
+		netListener, remaining := takeListener(inherited, listener.server.Addr)
+		inherited = remaining
+
+		if netListener == nil {
+			netListener, err = listen.Listen(context.Background(), listener.network, listener.server.Addr)
+		}
+
+		if err != nil {
+			for _, opened := range append(netListeners, inherited...) {
+				_ = opened.Close()
+			}
+
+			return fmt.Errorf("failed to start server: %w", err)
+		}
+
+		bound = append(bound, netListener)
+
+		// the admin listener is appended last and stays unlimited
+		if i < len(s.listeners) {
+			netListener = s.connections.wrap(netListener)
+		}
+
+		netListeners = append(netListeners, netListener)
+	}
+
+	// listeners passed for addresses server no longer listens on are released
+	for _, listener := range inherited {
+		s.logger.Warn().Str("addr", listener.Addr().String()).Msg("closing inherited listener not configured")
+
+		_ = listener.Close()
+	}
+
+	s.setBound(bound)
+
+	// upstreams of mounts are health checked while serving
+	for _, mountProxy := range s.proxies {
+		mountProxy.Start()
+		defer mountProxy.Stop()
+	}
+
+	// statuses of jobs are sent to websocket connections of their users while serving
+	if s.jobs != nil && s.hub != nil {
+		stop := s.watchJobs()
+		defer stop()
+	}
+
+	// only handle SIGHUP with certificates to reload, since handling it disables the default termination
+	if *s.config.TLS.ReloadOnSIGHUP && s.hasTLSListener() {
+		stop := s.reloadCertificatesOnSignal()
+		defer stop()
+	}
+
+	errs := make(chan error, len(listeners))
+
+	for i, listener := range listeners {
+		s.logger.Info().
+			Str("addr", listener.server.Addr).
+			Str("network", listener.network).
+			Bool("tls", listener.reloader != nil).
+			Msg("starting server")
+
+		go func() {
+			errs <- listener.serve(netListeners[i])
+		}()
+	}
+
+	var runErr error
+
+	for range listeners {
+		if err := <-errs; err != nil && runErr == nil {
+			runErr = err
+
+			// stop the other listeners so run returns the failure
+			for _, listener := range listeners {
+				_ = listener.server.Close()
+			}
+		}
+	}
+
+	return runErr
 }
 
-// Shutdown gracefully shuts down HTTP server.
+// ShutdownTimeout returns time in-flight requests are drained for on shutdown.
+func (s *Server) ShutdownTimeout() time.Duration {
+	return time.Duration(*s.config.ShutdownTimeout) * time.Second
+}
+
+// Shutdown gracefully shuts down HTTP servers on all listeners, draining in-flight requests
+// until they complete or the context is done.
 func (s *Server) Shutdown(ctx context.Context) error {
 	if s.httpServer == nil {
 		s.logger.Info().Msg("http server is not running, skipping shutdown")
@@ -443,11 +1634,94 @@ func (s *Server) Shutdown(ctx context.Context) error {
 		return nil
 	}
 
-	s.logger.Info().Msg("shutting down server")
+	active, completed := s.inFlight.Active(), s.inFlight.Completed()
+	deadline, _ := ctx.Deadline()
+
+	s.drain.start(active, completed, deadline)
+
+	s.logger.Info().Int64("in_flight", active).Msg("shutting down server, draining in-flight requests")
 
-	if err := s.httpServer.Shutdown(ctx); err != nil {
-		return fmt.Errorf("failed to shutdown server: %w", err)
+	// close connections after their current response instead of keeping them idle
+	for _, listener := range s.listeners {
+		listener.server.SetKeepAlivesEnabled(false)
+	}
+
+	// event streams never complete on their own, so they are ended for their requests to drain
+	if s.broker != nil {
+		s.broker.Shutdown()
+	}
+
+	// shut down listeners together so that none accepts requests while another drains
+	errs := make(chan error, len(s.listeners)+1)
+
+	for _, listener := range s.listeners {
+		go func() {
+			errs <- listener.server.Shutdown(ctx)
+		}()
+	}
+
+	// websocket connections are hijacked from the listeners, so they are closed separately
+	shutdowns := len(s.listeners)
+
+	if s.hub != nil {
+		shutdowns++
+
+		go func() {
+			errs <- s.hub.Shutdown(ctx)
+		}()
+	}
+
+	var shutdownErr error
+
+	for range shutdowns {
+		if err := <-errs; err != nil && shutdownErr == nil {
+			shutdownErr = err
+		}
+	}
+
+	s.drain.finish(shutdownErr != nil)
+	s.logDrainSummary()
+
+	// the admin listener is stopped last so that drain statistics stay available while draining
+	s.shutdownAdminListener(ctx)
+
+	if shutdownErr != nil {
+		return fmt.Errorf("failed to shutdown server: %w", shutdownErr)
 	}
 
 	return nil
 }
+
+// logDrainSummary logs the result of draining in-flight requests.
+func (s *Server) logDrainSummary() {
+	stats := s.drainStats()
+
+	if stats.State == DrainStateTimedOut {
+		s.logger.Warn().
+			Int64("in_flight_at_start", stats.InFlightAtStart).
+			Int64("drained", stats.Drained).
+			Int64("remaining", stats.InFlight).
+			Int64("oldest_request_age_ms", stats.OldestRequestAgeMs).
+			Int64("elapsed_ms", stats.ElapsedMs).
+			Msg("shutdown timed out before in-flight requests were drained")
+
+		return
+	}
+
+	s.logger.Info().
+		Int64("in_flight_at_start", stats.InFlightAtStart).
+		Int64("drained", stats.Drained).
+		Int64("elapsed_ms", stats.ElapsedMs).
+		Msg("drained in-flight requests")
+}
+
+// shutdownAdminListener stops the admin listener, closing it if its requests do not complete within the context.
+func (s *Server) shutdownAdminListener(ctx context.Context) {
+	if s.adminListener == nil {
+		return
+	}
+
+	if err := s.adminListener.server.Shutdown(ctx); err != nil {
+		_ = s.adminListener.server.Close()
+	}
+}
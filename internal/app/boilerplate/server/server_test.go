@@ -2,17 +2,24 @@ package server
 
 import (
 	"context"
+	"encoding/json"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 	"time"
 
+	"github.com/prometheus/client_golang/prometheus"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
 	"github.com/pocj8ur4in/boilerplate-go/internal/app/boilerplate/server/middleware"
+	"github.com/pocj8ur4in/boilerplate-go/internal/gen/api"
+	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/apierror"
 	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/jwt"
 	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/logger"
+	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/netutil"
 	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/redis"
 )
 
@@ -29,11 +36,36 @@ func (m *mockAPIHandler) HealthCheck(w http.ResponseWriter, _ *http.Request) {
 	w.WriteHeader(http.StatusOK)
 }
 
+// LivenessCheck handles GET /live endpoint.
+func (m *mockAPIHandler) LivenessCheck(w http.ResponseWriter, _ *http.Request) {
+	w.WriteHeader(http.StatusOK)
+}
+
+// ReadinessCheck handles GET /ready endpoint.
+func (m *mockAPIHandler) ReadinessCheck(w http.ResponseWriter, _ *http.Request) {
+	w.WriteHeader(http.StatusOK)
+}
+
 // HandleMetrics handles GET /metrics endpoint.
 func (m *mockAPIHandler) HandleMetrics(w http.ResponseWriter, _ *http.Request) {
 	w.WriteHeader(http.StatusOK)
 }
 
+// Signup handles POST /auth/signup endpoint.
+func (m *mockAPIHandler) Signup(w http.ResponseWriter, _ *http.Request) {
+	w.WriteHeader(http.StatusCreated)
+}
+
+// Login handles POST /auth/login endpoint.
+func (m *mockAPIHandler) Login(w http.ResponseWriter, _ *http.Request) {
+	w.WriteHeader(http.StatusOK)
+}
+
+// RefreshToken handles POST /auth/refresh endpoint.
+func (m *mockAPIHandler) RefreshToken(w http.ResponseWriter, _ *http.Request) {
+	w.WriteHeader(http.StatusOK)
+}
+
 // setupTestRedis creates a test redis client.
 func setupTestRedis(t *testing.T) *redis.Redis {
 	t.Helper()
@@ -94,11 +126,20 @@ func TestConfigSetDefault(t *testing.T) {
 
 		assert.Equal(t, "localhost", *config.Host)
 		assert.Equal(t, 8080, *config.Port)
+		assert.Empty(t, config.TrustedProxies)
 		assert.Equal(t, 10, *config.ReadTimeout)
 		assert.Equal(t, 10, *config.WriteTimeout)
 		assert.Equal(t, 10, *config.IdleTimeout)
 		assert.Equal(t, 10, *config.ShutdownTimeout)
 		assert.Equal(t, int64(10485760), *config.MaxRequestSize) // 10MB
+		assert.Equal(t, 5, *config.ReadHeaderTimeout)
+		assert.Equal(t, 65536, *config.MaxHeaderBytes) // 64KB
+		require.NotNil(t, config.Forms)
+		assert.Equal(t, int64(33554432), *config.Forms.MaxMultipartSize)
+		require.NotNil(t, config.CSRF)
+		assert.False(t, *config.CSRF.Enabled)
+		require.NotNil(t, config.Validation)
+		assert.True(t, *config.Validation.Enabled)
 	})
 
 	t.Run("keep existing values when config is already set", func(t *testing.T) {
@@ -152,6 +193,48 @@ func TestConfigSetDefaultCompression(t *testing.T) {
 		assert.Equal(t, 6, *config.Compression.Level)
 		assert.Equal(t, "gzip", *config.Compression.Format)
 		assert.True(t, *config.Compression.Enabled)
+		assert.Equal(t, 1024, *config.Compression.MinSize)
+		assert.Empty(t, *config.Compression.ContentTypes)
+		assert.Contains(t, *config.Compression.ExcludeContentTypes, "text/event-stream")
+		assert.Equal(t, []string{"/metrics"}, *config.Compression.ExcludePaths)
+		assert.True(t, *config.Compression.DecompressRequests)
+		assert.Equal(t, int64(100), *config.Compression.MaxDecompressRatio)
+	})
+
+	t.Run("return error for unsupported compression format", func(t *testing.T) {
+		t.Parallel()
+
+		log, err := logger.New(&logger.Config{Level: &[]string{"info"}[0]})
+		require.NoError(t, err)
+
+		config := &Config{Compression: &CompressionConfig{Format: &[]string{"lzma"}[0]}}
+
+		_, err = New(ServerParams{
+			Config:     config,
+			Logger:     log,
+			APIHandler: &mockAPIHandler{},
+			JWT:        setupTestJWT(t),
+			Redis:      setupTestRedis(t),
+		})
+		require.ErrorIs(t, err, middleware.ErrUnsupportedCompressionFormat)
+	})
+
+	t.Run("return error for invalid decompression ratio", func(t *testing.T) {
+		t.Parallel()
+
+		log, err := logger.New(&logger.Config{Level: &[]string{"info"}[0]})
+		require.NoError(t, err)
+
+		config := &Config{Compression: &CompressionConfig{MaxDecompressRatio: &[]int64{0}[0]}}
+
+		_, err = New(ServerParams{
+			Config:     config,
+			Logger:     log,
+			APIHandler: &mockAPIHandler{},
+			JWT:        setupTestJWT(t),
+			Redis:      setupTestRedis(t),
+		})
+		require.ErrorIs(t, err, middleware.ErrInvalidDecompressRatio)
 	})
 }
 
@@ -193,6 +276,104 @@ func TestConfigSetDefaultRateLimit(t *testing.T) {
 		assert.False(t, *config.RateLimit.Endpoint.Enabled)
 		assert.Equal(t, 50, *config.RateLimit.Endpoint.Requests)
 		assert.Equal(t, 60, *config.RateLimit.Endpoint.Window)
+
+		// verify rate limit algorithm defaults
+		for _, limit := range []*middleware.RateLimitTypeConfig{
+			config.RateLimit.Global, config.RateLimit.IP, config.RateLimit.Endpoint, config.RateLimit.Tenant,
+			config.RateLimit.User,
+		} {
+			require.NotNil(t, limit.Algorithm)
+			assert.Equal(t, middleware.RateLimitAlgorithmFixedWindow, *limit.Algorithm)
+		}
+
+		// verify rate limit headers default
+		require.NotNil(t, config.RateLimit.Headers)
+		assert.Equal(t, middleware.RateLimitHeadersBoth, *config.RateLimit.Headers)
+
+		// verify rate limit failure mode default
+		require.NotNil(t, config.RateLimit.FailureMode)
+		assert.Equal(t, middleware.RateLimitFailureModeLocal, *config.RateLimit.FailureMode)
+	})
+
+	t.Run("return error for invalid rate limit exemption", func(t *testing.T) {
+		t.Parallel()
+
+		log, err := logger.New(&logger.Config{Level: &[]string{"info"}[0]})
+		require.NoError(t, err)
+
+		config := &Config{
+			RateLimit: &middleware.RateLimitConfig{
+				Exemptions: &middleware.RateLimitExemptionsConfig{CIDRs: []string{"not-a-cidr"}},
+			},
+		}
+
+		_, err = New(ServerParams{
+			Config:     config,
+			Logger:     log,
+			APIHandler: &mockAPIHandler{},
+			JWT:        setupTestJWT(t),
+			Redis:      setupTestRedis(t),
+		})
+		require.ErrorIs(t, err, middleware.ErrInvalidRateLimitExemption)
+	})
+
+	t.Run("return error for invalid rate limit headers", func(t *testing.T) {
+		t.Parallel()
+
+		log, err := logger.New(&logger.Config{Level: &[]string{"info"}[0]})
+		require.NoError(t, err)
+
+		config := &Config{
+			RateLimit: &middleware.RateLimitConfig{
+				Headers: &[]middleware.RateLimitHeaders{"unknown"}[0],
+			},
+		}
+
+		_, err = New(ServerParams{
+			Config:     config,
+			Logger:     log,
+			APIHandler: &mockAPIHandler{},
+			JWT:        setupTestJWT(t),
+			Redis:      setupTestRedis(t),
+		})
+		require.ErrorIs(t, err, middleware.ErrInvalidRateLimitHeaders)
+	})
+
+	t.Run("return error for invalid rate limit algorithm", func(t *testing.T) {
+		t.Parallel()
+
+		log, err := logger.New(&logger.Config{Level: &[]string{"info"}[0]})
+		require.NoError(t, err)
+
+		config := &Config{
+			RateLimit: &middleware.RateLimitConfig{
+				Endpoint: &middleware.RateLimitTypeConfig{Algorithm: &[]middleware.RateLimitAlgorithm{"leaky_bucket"}[0]},
+			},
+		}
+
+		_, err = New(ServerParams{
+			Config:     config,
+			Logger:     log,
+			APIHandler: &mockAPIHandler{},
+			JWT:        setupTestJWT(t),
+			Redis:      setupTestRedis(t),
+		})
+		require.ErrorIs(t, err, middleware.ErrInvalidRateLimitAlgorithm)
+
+		config = &Config{
+			APIKeys: &APIKeysConfig{
+				RateLimit: &middleware.RateLimitTypeConfig{Algorithm: &[]middleware.RateLimitAlgorithm{"leaky_bucket"}[0]},
+			},
+		}
+
+		_, err = New(ServerParams{
+			Config:     config,
+			Logger:     log,
+			APIHandler: &mockAPIHandler{},
+			JWT:        setupTestJWT(t),
+			Redis:      setupTestRedis(t),
+		})
+		require.ErrorIs(t, err, middleware.ErrInvalidRateLimitAlgorithm)
 	})
 }
 
@@ -236,7 +417,13 @@ func TestNew(t *testing.T) {
 		}
 
 		mockHandler := &mockAPIHandler{}
-		server, err := New(cfg, log, mockHandler, jwtService, redisClient)
+		server, err := New(ServerParams{
+			Config:     cfg,
+			Logger:     log,
+			APIHandler: mockHandler,
+			JWT:        jwtService,
+			Redis:      redisClient,
+		})
 
 		require.NoError(t, err)
 		require.NotNil(t, server)
@@ -265,7 +452,13 @@ func TestNew(t *testing.T) {
 		jwtService := setupTestJWT(t)
 
 		mockHandler := &mockAPIHandler{}
-		server, err := New(config, log, mockHandler, jwtService, redisClient)
+		server, err := New(ServerParams{
+			Config:     config,
+			Logger:     log,
+			APIHandler: mockHandler,
+			JWT:        jwtService,
+			Redis:      redisClient,
+		})
 
 		require.NoError(t, err)
 		require.NotNil(t, server)
@@ -279,9 +472,12 @@ func TestNew(t *testing.T) {
 	})
 }
 
-//nolint:paralleltest // sequential execution required to avoid prometheus registry conflicts
 func TestSetupRouter(t *testing.T) {
+	t.Parallel()
+
 	t.Run("setup router successfully", func(t *testing.T) {
+		t.Parallel()
+
 		log, err := logger.New(&logger.Config{Level: &[]string{"info"}[0]})
 		require.NoError(t, err)
 
@@ -295,7 +491,13 @@ func TestSetupRouter(t *testing.T) {
 		}
 
 		mockHandler := &mockAPIHandler{}
-		server, err := New(cfg, log, mockHandler, jwtService, redisClient)
+		server, err := New(ServerParams{
+			Config:     cfg,
+			Logger:     log,
+			APIHandler: mockHandler,
+			JWT:        jwtService,
+			Redis:      redisClient,
+		})
 		require.NoError(t, err)
 
 		require.NotNil(t, server.httpServer)
@@ -303,9 +505,12 @@ func TestSetupRouter(t *testing.T) {
 	})
 }
 
-//nolint:paralleltest // sequential execution required to avoid prometheus registry conflicts
 func TestSetupAPIHandler(t *testing.T) {
+	t.Parallel()
+
 	t.Run("setup API handler successfully", func(t *testing.T) {
+		t.Parallel()
+
 		log, err := logger.New(&logger.Config{Level: &[]string{"info"}[0]})
 		require.NoError(t, err)
 
@@ -313,7 +518,12 @@ func TestSetupAPIHandler(t *testing.T) {
 		jwtService := setupTestJWT(t)
 
 		mockHandler := &mockAPIHandler{}
-		server, err := New(nil, log, mockHandler, jwtService, redisClient)
+		server, err := New(ServerParams{
+			Logger:     log,
+			APIHandler: mockHandler,
+			JWT:        jwtService,
+			Redis:      redisClient,
+		})
 		require.NoError(t, err)
 
 		require.NotNil(t, server.httpServer)
@@ -340,9 +550,12 @@ func verifyHTTPServer(
 	assert.NotNil(t, httpServer.Handler)
 }
 
-//nolint:paralleltest // sequential execution required to avoid prometheus registry conflicts
 func TestCreateHTTPServer(t *testing.T) {
+	t.Parallel()
+
 	t.Run("create HTTP server with default config", func(t *testing.T) {
+		t.Parallel()
+
 		config := &Config{}
 		config.SetDefault()
 
@@ -353,7 +566,13 @@ func TestCreateHTTPServer(t *testing.T) {
 		jwtService := setupTestJWT(t)
 
 		mockHandler := &mockAPIHandler{}
-		server, err := New(config, log, mockHandler, jwtService, redisClient)
+		server, err := New(ServerParams{
+			Config:     config,
+			Logger:     log,
+			APIHandler: mockHandler,
+			JWT:        jwtService,
+			Redis:      redisClient,
+		})
 		require.NoError(t, err)
 
 		verifyHTTPServer(t, server.httpServer, "localhost:8080",
@@ -361,6 +580,8 @@ func TestCreateHTTPServer(t *testing.T) {
 	})
 
 	t.Run("create HTTP server with custom config", func(t *testing.T) {
+		t.Parallel()
+
 		config := &Config{
 			Host:         &[]string{"0.0.0.0"}[0],
 			Port:         &[]int{9090}[0],
@@ -377,7 +598,13 @@ func TestCreateHTTPServer(t *testing.T) {
 		jwtService := setupTestJWT(t)
 
 		mockHandler := &mockAPIHandler{}
-		server, err := New(config, log, mockHandler, jwtService, redisClient)
+		server, err := New(ServerParams{
+			Config:     config,
+			Logger:     log,
+			APIHandler: mockHandler,
+			JWT:        jwtService,
+			Redis:      redisClient,
+		})
 		require.NoError(t, err)
 
 		verifyHTTPServer(t, server.httpServer, "0.0.0.0:9090",
@@ -398,7 +625,12 @@ func TestShutdown(t *testing.T) {
 		jwtService := setupTestJWT(t)
 
 		mockHandler := &mockAPIHandler{}
-		server, err := New(nil, log, mockHandler, jwtService, redisClient)
+		server, err := New(ServerParams{
+			Logger:     log,
+			APIHandler: mockHandler,
+			JWT:        jwtService,
+			Redis:      redisClient,
+		})
 		require.NoError(t, err)
 
 		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
@@ -408,6 +640,222 @@ func TestShutdown(t *testing.T) {
 		err = server.Shutdown(ctx)
 		require.NoError(t, err)
 	})
+
+	t.Run("drain in-flight requests before shutting down", func(t *testing.T) {
+		t.Parallel()
+
+		handler := newSlowAPIHandler()
+		server, addr := runTestServer(t, handler)
+
+		response := make(chan int, 1)
+
+		go func() {
+			resp, err := http.Get("http://" + addr + "/status") //nolint:noctx // test request
+			if err != nil {
+				response <- 0
+
+				return
+			}
+
+			_ = resp.Body.Close()
+			response <- resp.StatusCode
+		}()
+
+		<-handler.started
+
+		shutdown := make(chan error, 1)
+
+		go func() {
+			ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+			defer cancel()
+
+			shutdown <- server.Shutdown(ctx)
+		}()
+
+		// new requests are refused while the request is still in flight
+		require.Eventually(t, func() bool {
+			resp, err := http.Get("http://" + addr + "/health") //nolint:noctx // test request
+			if err != nil {
+				return true
+			}
+
+			_ = resp.Body.Close()
+
+			return false
+		}, time.Second, 10*time.Millisecond)
+		assert.Equal(t, int64(1), server.inFlight.Active())
+
+		close(handler.release)
+
+		require.NoError(t, <-shutdown)
+		assert.Equal(t, http.StatusOK, <-response)
+		assert.Equal(t, int64(0), server.inFlight.Active())
+	})
+
+	t.Run("return error when requests are not drained within timeout", func(t *testing.T) {
+		t.Parallel()
+
+		handler := newSlowAPIHandler()
+		defer close(handler.release)
+
+		server, addr := runTestServer(t, handler)
+
+		go func() {
+			resp, err := http.Get("http://" + addr + "/status") //nolint:noctx // test request
+			if err == nil {
+				_ = resp.Body.Close()
+			}
+		}()
+
+		<-handler.started
+
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+
+		require.ErrorIs(t, server.Shutdown(ctx), context.DeadlineExceeded)
+		assert.Equal(t, int64(1), server.inFlight.Active())
+	})
+}
+
+func TestShutdownTimeout(t *testing.T) {
+	t.Parallel()
+
+	t.Run("return configured shutdown timeout", func(t *testing.T) {
+		t.Parallel()
+
+		server := &Server{config: &Config{ShutdownTimeout: &[]int{15}[0]}}
+
+		assert.Equal(t, 15*time.Second, server.ShutdownTimeout())
+	})
+}
+
+// slowAPIHandler is a mock API handler whose status check blocks until released.
+type slowAPIHandler struct {
+	mockAPIHandler
+
+	started chan struct{}
+	release chan struct{}
+}
+
+// newSlowAPIHandler creates a slow API handler.
+func newSlowAPIHandler() *slowAPIHandler {
+	return &slowAPIHandler{started: make(chan struct{}), release: make(chan struct{})}
+}
+
+// StatusCheck handles GET /status endpoint after the handler is released.
+func (m *slowAPIHandler) StatusCheck(w http.ResponseWriter, _ *http.Request) {
+	close(m.started)
+	<-m.release
+
+	w.WriteHeader(http.StatusOK)
+}
+
+// runTestServer runs a server of the handler on a free address until the test ends.
+func runTestServer(t *testing.T, handler api.ServerInterface) (*Server, string) {
+	t.Helper()
+
+	log, err := logger.New(&logger.Config{Level: &[]string{"info"}[0]})
+	require.NoError(t, err)
+
+	addr := freeAddr(t)
+	config := &Config{Listeners: []*ListenerConfig{{Addr: &addr}}}
+
+	server, err := New(ServerParams{
+		Config:     config,
+		Logger:     log,
+		APIHandler: handler,
+		JWT:        setupTestJWT(t),
+		Redis:      setupTestRedis(t),
+	})
+	require.NoError(t, err)
+
+	go func() {
+		_ = server.Run()
+	}()
+
+	t.Cleanup(func() {
+		_ = server.listeners[0].server.Close()
+	})
+
+	waitForStatus(t, http.DefaultClient, "http://"+addr+"/health")
+
+	return server, addr
+}
+
+func TestEmbeddedServer(t *testing.T) {
+	t.Parallel()
+
+	t.Run("expose handler and address", func(t *testing.T) {
+		t.Parallel()
+
+		log, err := logger.New(&logger.Config{Level: &[]string{"info"}[0]})
+		require.NoError(t, err)
+
+		config := &Config{
+			Host: &[]string{"127.0.0.1"}[0],
+			Port: &[]int{9091}[0],
+		}
+
+		server, err := New(ServerParams{
+			Config:     config,
+			Logger:     log,
+			APIHandler: &mockAPIHandler{},
+			JWT:        setupTestJWT(t),
+			Redis:      setupTestRedis(t),
+		})
+		require.NoError(t, err)
+
+		assert.Equal(t, "127.0.0.1:9091", server.Addr())
+
+		// mount handler in another server
+		outer := http.NewServeMux()
+		outer.Handle("/", server.Handler())
+
+		recorder := httptest.NewRecorder()
+		outer.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/status", nil))
+
+		assert.Equal(t, http.StatusOK, recorder.Code)
+	})
+
+	t.Run("skip listening when disabled", func(t *testing.T) {
+		t.Parallel()
+
+		log, err := logger.New(&logger.Config{Level: &[]string{"info"}[0]})
+		require.NoError(t, err)
+
+		config := &Config{Listen: &[]bool{false}[0]}
+
+		server, err := New(ServerParams{
+			Config:     config,
+			Logger:     log,
+			APIHandler: &mockAPIHandler{},
+			JWT:        setupTestJWT(t),
+			Redis:      setupTestRedis(t),
+		})
+		require.NoError(t, err)
+
+		done := make(chan error, 1)
+
+		go func() {
+			done <- server.Run()
+		}()
+
+		select {
+		case err := <-done:
+			require.NoError(t, err)
+		case <-time.After(time.Second):
+			t.Fatal("run did not return when listening is disabled")
+		}
+	})
+
+	t.Run("return empty values when server is not initialized", func(t *testing.T) {
+		t.Parallel()
+
+		server := &Server{}
+
+		assert.Nil(t, server.Handler())
+		assert.Empty(t, server.Addr())
+	})
 }
 
 func TestNewModule(t *testing.T) {
@@ -435,7 +883,12 @@ func TestServerInvalidEndpoint(t *testing.T) {
 		jwtService := setupTestJWT(t)
 
 		mockHandler := &mockAPIHandler{}
-		server, err := New(nil, log, mockHandler, jwtService, redisClient)
+		server, err := New(ServerParams{
+			Logger:     log,
+			APIHandler: mockHandler,
+			JWT:        jwtService,
+			Redis:      redisClient,
+		})
 		require.NoError(t, err)
 
 		// create test request for non-existent endpoint
@@ -447,6 +900,216 @@ func TestServerInvalidEndpoint(t *testing.T) {
 
 		// verify response - should return 404
 		assert.Equal(t, http.StatusNotFound, recorder.Code)
+
+		var body map[string]interface{}
+		require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &body))
+		assert.Equal(t, "not_found", body["code"])
+		assert.Equal(t, recorder.Header().Get("X-Request-ID"), body["request_id"])
+		assert.NotEmpty(t, body["request_id"])
+	})
+}
+
+func TestServerValidation(t *testing.T) {
+	t.Parallel()
+
+	log, err := logger.New(&logger.Config{Level: &[]string{"info"}[0]})
+	require.NoError(t, err)
+
+	// login sends the login request to a server with request validation enabled or disabled.
+	login := func(t *testing.T, enabled bool, body string) *httptest.ResponseRecorder {
+		t.Helper()
+
+		config := &Config{Validation: &middleware.ValidationConfig{Enabled: &enabled}}
+
+		server, err := New(ServerParams{
+			Config:     config,
+			Logger:     log,
+			APIHandler: &mockAPIHandler{},
+			JWT:        setupTestJWT(t),
+			Redis:      setupTestRedis(t),
+		})
+		require.NoError(t, err)
+
+		request := httptest.NewRequest(http.MethodPost, "/auth/login", strings.NewReader(body))
+		request.Header.Set("Content-Type", "application/json")
+
+		recorder := httptest.NewRecorder()
+		server.Handler().ServeHTTP(recorder, request)
+
+		return recorder
+	}
+
+	t.Run("reject request failing the spec", func(t *testing.T) {
+		t.Parallel()
+
+		recorder := login(t, true, `{"email":"user@example.com"}`)
+		assert.Equal(t, http.StatusBadRequest, recorder.Code)
+		assert.JSONEq(t,
+			`{"fields":[{"field":"password","in":"body","message":"property \"password\" is missing"}]}`,
+			string(errorDetails(t, recorder.Body.Bytes())),
+		)
+	})
+
+	t.Run("pass valid request to handler", func(t *testing.T) {
+		t.Parallel()
+
+		recorder := login(t, true, `{"email":"user@example.com","password":"secret"}`)
+		assert.Equal(t, http.StatusOK, recorder.Code)
+	})
+
+	t.Run("skip validation when disabled", func(t *testing.T) {
+		t.Parallel()
+
+		recorder := login(t, false, `{"email":"user@example.com"}`)
+		assert.Equal(t, http.StatusOK, recorder.Code)
+	})
+}
+
+// errorDetails returns the raw details of the error response.
+func errorDetails(t *testing.T, body []byte) json.RawMessage {
+	t.Helper()
+
+	var response struct {
+		Details json.RawMessage `json:"details"`
+	}
+
+	require.NoError(t, json.Unmarshal(body, &response))
+
+	return response.Details
+}
+
+//nolint:paralleltest // sequential execution required to set the error format of all responses
+func TestErrorFormat(t *testing.T) {
+	log, err := logger.New(&logger.Config{Level: &[]string{"info"}[0]})
+	require.NoError(t, err)
+
+	t.Cleanup(func() {
+		apierror.SetFormat(apierror.FormatJSON)
+	})
+
+	t.Run("write problem details", func(t *testing.T) {
+		config := &Config{ErrorFormat: &[]apierror.Format{apierror.FormatProblem}[0]}
+
+		server, err := New(ServerParams{
+			Config:     config,
+			Logger:     log,
+			APIHandler: &mockAPIHandler{},
+			JWT:        setupTestJWT(t),
+			Redis:      setupTestRedis(t),
+		})
+		require.NoError(t, err)
+
+		recorder := httptest.NewRecorder()
+		server.Handler().ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/invalid", nil))
+
+		assert.Equal(t, http.StatusNotFound, recorder.Code)
+		assert.Equal(t, "application/problem+json", recorder.Header().Get("Content-Type"))
+
+		var body map[string]interface{}
+		require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &body))
+		assert.Equal(t, "about:blank", body["type"])
+		assert.InDelta(t, http.StatusNotFound, body["status"], 0)
+		assert.Equal(t, "not_found", body["code"])
+
+		require.NoError(t, server.Reload(&Config{}))
+
+		recorder = httptest.NewRecorder()
+		server.Handler().ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/invalid", nil))
+		assert.Equal(t, "application/json", recorder.Header().Get("Content-Type"))
+	})
+
+	t.Run("return error for unknown format", func(t *testing.T) {
+		config := &Config{ErrorFormat: &[]apierror.Format{"xml"}[0]}
+
+		_, err := New(ServerParams{
+			Config:     config,
+			Logger:     log,
+			APIHandler: &mockAPIHandler{},
+			JWT:        setupTestJWT(t),
+			Redis:      setupTestRedis(t),
+		})
+		require.ErrorIs(t, err, apierror.ErrInvalidFormat)
+	})
+}
+
+//nolint:paralleltest // sequential execution required to set the request ID header of all error responses
+func TestServerRequestID(t *testing.T) {
+	log, err := logger.New(&logger.Config{Level: &[]string{"info"}[0]})
+	require.NoError(t, err)
+
+	t.Cleanup(func() {
+		apierror.SetRequestIDHeader(apierror.RequestIDHeader)
+	})
+
+	config := &Config{RequestID: &middleware.RequestIDConfig{
+		Header:         &[]string{"X-Correlation-ID"}[0],
+		TrustedProxies: []string{"10.0.0.0/8"},
+	}}
+
+	server, err := New(ServerParams{
+		Config:     config,
+		Logger:     log,
+		APIHandler: &mockAPIHandler{},
+		JWT:        setupTestJWT(t),
+		Redis:      setupTestRedis(t),
+	})
+	require.NoError(t, err)
+
+	t.Run("echo request ID of trusted proxy in configured header", func(t *testing.T) {
+		request := httptest.NewRequest(http.MethodGet, "/invalid", nil)
+		request.RemoteAddr = "10.0.0.1:4567"
+		request.Header.Set("Origin", "https://example.com")
+		request.Header.Set("X-Correlation-ID", "support-123")
+
+		recorder := httptest.NewRecorder()
+		server.Handler().ServeHTTP(recorder, request)
+
+		assert.Equal(t, "support-123", recorder.Header().Get("X-Correlation-ID"))
+		assert.Empty(t, recorder.Header().Get("X-Request-ID"))
+		assert.Contains(t, recorder.Header().Get("Access-Control-Expose-Headers"), "X-Correlation-Id")
+
+		var body map[string]interface{}
+		require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &body))
+		assert.Equal(t, "support-123", body["request_id"])
+	})
+
+	t.Run("generate request ID for untrusted client", func(t *testing.T) {
+		request := httptest.NewRequest(http.MethodGet, "/invalid", nil)
+		request.RemoteAddr = "203.0.113.1:4567"
+		request.Header.Set("X-Correlation-ID", "support-123")
+
+		recorder := httptest.NewRecorder()
+		server.Handler().ServeHTTP(recorder, request)
+
+		requestID := recorder.Header().Get("X-Correlation-ID")
+		assert.NotEmpty(t, requestID)
+		assert.NotEqual(t, "support-123", requestID)
+	})
+
+	t.Run("return error for invalid trusted proxy", func(t *testing.T) {
+		config := &Config{RequestID: &middleware.RequestIDConfig{TrustedProxies: []string{"proxy"}}}
+
+		_, err := New(ServerParams{
+			Config:     config,
+			Logger:     log,
+			APIHandler: &mockAPIHandler{},
+			JWT:        setupTestJWT(t),
+			Redis:      setupTestRedis(t),
+		})
+		require.ErrorIs(t, err, middleware.ErrInvalidTrustedProxy)
+	})
+
+	t.Run("return error for invalid trusted proxy of client IPs", func(t *testing.T) {
+		config := &Config{TrustedProxies: []string{"10.0.0.1"}}
+
+		_, err := New(ServerParams{
+			Config:     config,
+			Logger:     log,
+			APIHandler: &mockAPIHandler{},
+			JWT:        setupTestJWT(t),
+			Redis:      setupTestRedis(t),
+		})
+		require.ErrorIs(t, err, netutil.ErrInvalidTrustedProxy)
 	})
 }
 
@@ -463,7 +1126,12 @@ func TestServerHTTPMethods(t *testing.T) {
 		jwtService := setupTestJWT(t)
 
 		mockHandler := &mockAPIHandler{}
-		server, err := New(nil, log, mockHandler, jwtService, redisClient)
+		server, err := New(ServerParams{
+			Logger:     log,
+			APIHandler: mockHandler,
+			JWT:        jwtService,
+			Redis:      redisClient,
+		})
 		require.NoError(t, err)
 
 		methods := []string{
@@ -494,9 +1162,12 @@ func TestServerHTTPMethods(t *testing.T) {
 	})
 }
 
-//nolint:paralleltest // sequential execution required to avoid prometheus registry conflicts
 func TestServerHandlerIntegration(t *testing.T) {
+	t.Parallel()
+
 	t.Run("verify handler is properly integrated", func(t *testing.T) {
+		t.Parallel()
+
 		log, err := logger.New(&logger.Config{Level: &[]string{"info"}[0]})
 		require.NoError(t, err)
 
@@ -504,7 +1175,12 @@ func TestServerHandlerIntegration(t *testing.T) {
 		jwtService := setupTestJWT(t)
 
 		mockHandler := &mockAPIHandler{}
-		server, err := New(nil, log, mockHandler, jwtService, redisClient)
+		server, err := New(ServerParams{
+			Logger:     log,
+			APIHandler: mockHandler,
+			JWT:        jwtService,
+			Redis:      redisClient,
+		})
 		require.NoError(t, err)
 
 		// verify server components
@@ -521,6 +1197,8 @@ func TestServerHandlerIntegration(t *testing.T) {
 	})
 
 	t.Run("verify router is properly set up", func(t *testing.T) {
+		t.Parallel()
+
 		log, err := logger.New(&logger.Config{Level: &[]string{"info"}[0]})
 		require.NoError(t, err)
 
@@ -528,7 +1206,12 @@ func TestServerHandlerIntegration(t *testing.T) {
 		jwtService := setupTestJWT(t)
 
 		mockHandler := &mockAPIHandler{}
-		server, err := New(nil, log, mockHandler, jwtService, redisClient)
+		server, err := New(ServerParams{
+			Logger:     log,
+			APIHandler: mockHandler,
+			JWT:        jwtService,
+			Redis:      redisClient,
+		})
 		require.NoError(t, err)
 
 		// verify server httpServer handler is set
@@ -578,7 +1261,13 @@ func TestServerConfiguration(t *testing.T) {
 		jwtService := setupTestJWT(t)
 
 		mockHandler := &mockAPIHandler{}
-		server, err := New(config, log, mockHandler, jwtService, redisClient)
+		server, err := New(ServerParams{
+			Config:     config,
+			Logger:     log,
+			APIHandler: mockHandler,
+			JWT:        jwtService,
+			Redis:      redisClient,
+		})
 		require.NoError(t, err)
 
 		// verify config is applied to HTTP server
@@ -602,7 +1291,12 @@ func TestServerStatusEndpoint(t *testing.T) {
 		jwtService := setupTestJWT(t)
 
 		mockHandler := &mockAPIHandler{}
-		server, err := New(nil, log, mockHandler, jwtService, redisClient)
+		server, err := New(ServerParams{
+			Logger:     log,
+			APIHandler: mockHandler,
+			JWT:        jwtService,
+			Redis:      redisClient,
+		})
 		require.NoError(t, err)
 
 		// create test request
@@ -630,7 +1324,12 @@ func TestServerHealthEndpoint(t *testing.T) {
 		jwtService := setupTestJWT(t)
 
 		mockHandler := &mockAPIHandler{}
-		server, err := New(nil, log, mockHandler, jwtService, redisClient)
+		server, err := New(ServerParams{
+			Logger:     log,
+			APIHandler: mockHandler,
+			JWT:        jwtService,
+			Redis:      redisClient,
+		})
 		require.NoError(t, err)
 
 		// create test request
@@ -658,7 +1357,12 @@ func TestServerMetricsEndpoint(t *testing.T) {
 		jwtService := setupTestJWT(t)
 
 		mockHandler := &mockAPIHandler{}
-		server, err := New(nil, log, mockHandler, jwtService, redisClient)
+		server, err := New(ServerParams{
+			Logger:     log,
+			APIHandler: mockHandler,
+			JWT:        jwtService,
+			Redis:      redisClient,
+		})
 		require.NoError(t, err)
 
 		// create test request
@@ -671,6 +1375,133 @@ func TestServerMetricsEndpoint(t *testing.T) {
 		// verify response
 		assert.Equal(t, http.StatusOK, recorder.Code)
 	})
+
+	t.Run("expose jwt metrics on metrics endpoint", func(t *testing.T) {
+		t.Parallel()
+
+		log, err := logger.New(&logger.Config{Level: &[]string{"info"}[0]})
+		require.NoError(t, err)
+
+		jwtService := setupTestJWT(t)
+
+		// serve the server registry apart from the API metrics route
+		config := &Config{Metrics: &middleware.MetricsConfig{Path: &[]string{"/server-metrics"}[0]}}
+
+		server, err := New(ServerParams{
+			Config:     config,
+			Logger:     log,
+			APIHandler: &mockAPIHandler{},
+			JWT:        jwtService,
+			Redis:      setupTestRedis(t),
+		})
+		require.NoError(t, err)
+
+		_, err = jwtService.GenerateAccessToken("user123", "test@example.com", "user")
+		require.NoError(t, err)
+
+		recorder := httptest.NewRecorder()
+		server.httpServer.Handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/server-metrics", nil))
+
+		assert.Equal(t, http.StatusOK, recorder.Code)
+		assert.Contains(t, recorder.Body.String(), `jwt_tokens_issued_total{type="access"} 1`)
+	})
+
+	t.Run("expose runtime metrics enabled by config on metrics endpoint", func(t *testing.T) {
+		t.Parallel()
+
+		log, err := logger.New(&logger.Config{Level: &[]string{"info"}[0]})
+		require.NoError(t, err)
+
+		// serve the server registry apart from the API metrics route
+		config := &Config{Metrics: &middleware.MetricsConfig{
+			Path:    &[]string{"/server-metrics"}[0],
+			Process: &[]bool{false}[0],
+		}}
+
+		server, err := New(ServerParams{
+			Config:     config,
+			Logger:     log,
+			APIHandler: &mockAPIHandler{},
+			Redis:      setupTestRedis(t),
+		})
+		require.NoError(t, err)
+
+		recorder := httptest.NewRecorder()
+		server.httpServer.Handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/server-metrics", nil))
+
+		assert.Equal(t, http.StatusOK, recorder.Code)
+		assert.Contains(t, recorder.Body.String(), "go_goroutines ")
+		assert.Contains(t, recorder.Body.String(), "go_memstats_heap_alloc_bytes ")
+		assert.Contains(t, recorder.Body.String(), `build_info{build_time=`)
+		assert.NotContains(t, recorder.Body.String(), "process_")
+	})
+
+	t.Run("expose registered collectors on metrics endpoint", func(t *testing.T) {
+		t.Parallel()
+
+		log, err := logger.New(&logger.Config{Level: &[]string{"info"}[0]})
+		require.NoError(t, err)
+
+		config := &Config{Metrics: &middleware.MetricsConfig{Path: &[]string{"/server-metrics"}[0]}}
+
+		server, err := New(ServerParams{
+			Config:     config,
+			Logger:     log,
+			APIHandler: &mockAPIHandler{},
+			Redis:      setupTestRedis(t),
+		})
+		require.NoError(t, err)
+
+		counter := prometheus.NewCounter(prometheus.CounterOpts{Name: "test_collector_total", Help: "Test collector"})
+		counter.Inc()
+
+		require.NoError(t, server.RegisterCollector(counter))
+		require.Error(t, server.RegisterCollector(counter))
+
+		recorder := httptest.NewRecorder()
+		server.httpServer.Handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/server-metrics", nil))
+
+		assert.Equal(t, http.StatusOK, recorder.Code)
+		assert.Contains(t, recorder.Body.String(), "test_collector_total 1")
+	})
+
+	t.Run("serve openmetrics format with exemplars when accepted", func(t *testing.T) {
+		t.Parallel()
+
+		log, err := logger.New(&logger.Config{Level: &[]string{"info"}[0]})
+		require.NoError(t, err)
+
+		config := &Config{Metrics: &middleware.MetricsConfig{Path: &[]string{"/server-metrics"}[0]}}
+
+		server, err := New(ServerParams{
+			Config:     config,
+			Logger:     log,
+			APIHandler: &mockAPIHandler{},
+			Redis:      setupTestRedis(t),
+		})
+		require.NoError(t, err)
+
+		server.httpServer.Handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/invalid", nil))
+
+		request := httptest.NewRequest(http.MethodGet, "/server-metrics", nil)
+		request.Header.Set("Accept", "application/openmetrics-text; version=1.0.0")
+
+		recorder := httptest.NewRecorder()
+		server.httpServer.Handler.ServeHTTP(recorder, request)
+
+		assert.Equal(t, http.StatusOK, recorder.Code)
+		assert.Contains(t, recorder.Header().Get("Content-Type"), "application/openmetrics-text")
+		assert.Contains(t, recorder.Body.String(), "http_requests_created")
+		assert.Contains(t, recorder.Body.String(), `# {request_id="`)
+		assert.True(t, strings.HasSuffix(recorder.Body.String(), "# EOF\n"))
+
+		// scrapers not accepting openmetrics get the text format
+		recorder = httptest.NewRecorder()
+		server.httpServer.Handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/server-metrics", nil))
+
+		assert.Contains(t, recorder.Header().Get("Content-Type"), "text/plain")
+		assert.NotContains(t, recorder.Body.String(), "# EOF")
+	})
 }
 
 func TestCompressionEnabled(t *testing.T) {
@@ -755,7 +1586,13 @@ func TestCompressionInResponse(t *testing.T) {
 		jwtService := setupTestJWT(t)
 
 		mockHandler := &mockAPIHandler{}
-		server, err := New(config, log, mockHandler, jwtService, redisClient)
+		server, err := New(ServerParams{
+			Config:     config,
+			Logger:     log,
+			APIHandler: mockHandler,
+			JWT:        jwtService,
+			Redis:      redisClient,
+		})
 		require.NoError(t, err)
 
 		// create test request with Accept-Encoding header
@@ -787,7 +1624,13 @@ func TestCompressionInResponse(t *testing.T) {
 		jwtService := setupTestJWT(t)
 
 		mockHandler := &mockAPIHandler{}
-		server, err := New(config, log, mockHandler, jwtService, redisClient)
+		server, err := New(ServerParams{
+			Config:     config,
+			Logger:     log,
+			APIHandler: mockHandler,
+			JWT:        jwtService,
+			Redis:      redisClient,
+		})
 		require.NoError(t, err)
 
 		// create test request with Accept-Encoding header
@@ -841,6 +1684,18 @@ func TestRateLimitGlobalDefault(t *testing.T) {
 	})
 }
 
+func TestRetryBudgetDefault(t *testing.T) {
+	t.Parallel()
+
+	config := &Config{}
+	config.SetDefault()
+
+	require.NotNil(t, config.RetryBudget)
+	assert.False(t, *config.RetryBudget.Enabled)
+	assert.Equal(t, 10, *config.RetryBudget.MinRetries)
+	assert.Equal(t, 60, *config.RetryBudget.Window)
+}
+
 func TestRateLimitIPDefault(t *testing.T) {
 	t.Parallel()
 
@@ -869,6 +1724,61 @@ func TestRateLimitEndpointDefault(t *testing.T) {
 	})
 }
 
+func TestRateLimitTenantDefault(t *testing.T) {
+	t.Parallel()
+
+	t.Run("tenant rate limit has default values", func(t *testing.T) {
+		t.Parallel()
+
+		config := &Config{}
+		config.SetDefault()
+
+		require.NotNil(t, config.RateLimit)
+		verifyRateLimitConfig(t, config.RateLimit.Tenant, false, 1000, 60)
+		require.NotNil(t, config.RateLimit.TenantCacheTTL)
+		assert.Equal(t, 60, *config.RateLimit.TenantCacheTTL)
+
+		require.NotNil(t, config.Tenancy)
+		assert.False(t, *config.Tenancy.Enabled)
+		assert.Equal(t, "X-Tenant-ID", *config.Tenancy.Header)
+	})
+
+	t.Run("require database when tenant rate limit is enabled", func(t *testing.T) {
+		t.Parallel()
+
+		log, err := logger.New(&logger.Config{Level: &[]string{"info"}[0]})
+		require.NoError(t, err)
+
+		config := &Config{
+			RateLimit: &middleware.RateLimitConfig{
+				Tenant: &middleware.RateLimitTypeConfig{Enabled: &[]bool{true}[0]},
+			},
+		}
+
+		_, err = New(ServerParams{
+			Config:     config,
+			Logger:     log,
+			APIHandler: &mockAPIHandler{},
+			JWT:        setupTestJWT(t),
+		})
+		require.ErrorIs(t, err, ErrTenantRateLimitRequiresDatabase)
+	})
+}
+
+func TestRateLimitUserDefault(t *testing.T) {
+	t.Parallel()
+
+	t.Run("user rate limit has default values", func(t *testing.T) {
+		t.Parallel()
+
+		config := &Config{}
+		config.SetDefault()
+
+		require.NotNil(t, config.RateLimit)
+		verifyRateLimitConfig(t, config.RateLimit.User, false, 100, 60)
+	})
+}
+
 func TestRateLimitCustomConfiguration(t *testing.T) {
 	t.Parallel()
 
@@ -995,7 +1905,12 @@ func TestCORSHeaders(t *testing.T) {
 		jwtService := setupTestJWT(t)
 
 		mockHandler := &mockAPIHandler{}
-		server, err := New(nil, log, mockHandler, jwtService, redisClient)
+		server, err := New(ServerParams{
+			Logger:     log,
+			APIHandler: mockHandler,
+			JWT:        jwtService,
+			Redis:      redisClient,
+		})
 		require.NoError(t, err)
 
 		// create test request with Origin header
@@ -1022,7 +1937,12 @@ func TestCORSHeaders(t *testing.T) {
 		jwtService := setupTestJWT(t)
 
 		mockHandler := &mockAPIHandler{}
-		server, err := New(nil, log, mockHandler, jwtService, redisClient)
+		server, err := New(ServerParams{
+			Logger:     log,
+			APIHandler: mockHandler,
+			JWT:        jwtService,
+			Redis:      redisClient,
+		})
 		require.NoError(t, err)
 
 		// create preflight request
@@ -1054,7 +1974,13 @@ func createTestServerWithCORS(
 	jwtService := setupTestJWT(t)
 
 	mockHandler := &mockAPIHandler{}
-	server, err := New(config, log, mockHandler, jwtService, redisClient)
+	server, err := New(ServerParams{
+		Config:     config,
+		Logger:     log,
+		APIHandler: mockHandler,
+		JWT:        jwtService,
+		Redis:      redisClient,
+	})
 	require.NoError(t, err)
 
 	return server
@@ -1118,6 +2044,154 @@ func TestCORSCustomOrigins(t *testing.T) {
 	})
 }
 
+func TestCORSAllowCredentials(t *testing.T) {
+	t.Parallel()
+
+	t.Run("allow credentials for localhost origins", func(t *testing.T) {
+		t.Parallel()
+
+		config := &Config{
+			CORS: &CORSConfig{
+				AllowedOrigins:   &[]string{"http://localhost:*"},
+				AllowCredentials: &[]bool{true}[0],
+			},
+		}
+
+		server := createTestServerWithCORS(t, config)
+
+		req := httptest.NewRequest(http.MethodGet, "/status", nil)
+		req.Header.Set("Origin", "http://localhost:5173")
+
+		recorder := httptest.NewRecorder()
+		server.httpServer.Handler.ServeHTTP(recorder, req)
+
+		assert.Equal(t, "http://localhost:5173", recorder.Header().Get("Access-Control-Allow-Origin"))
+		assert.Equal(t, "true", recorder.Header().Get("Access-Control-Allow-Credentials"))
+	})
+
+	t.Run("disallow credentials by default", func(t *testing.T) {
+		t.Parallel()
+
+		server := createTestServerWithCORS(t, nil)
+
+		req := httptest.NewRequest(http.MethodGet, "/status", nil)
+		req.Header.Set("Origin", "http://localhost:5173")
+
+		recorder := httptest.NewRecorder()
+		server.httpServer.Handler.ServeHTTP(recorder, req)
+
+		assert.Empty(t, recorder.Header().Get("Access-Control-Allow-Credentials"))
+	})
+}
+
+func TestHSTS(t *testing.T) {
+	t.Parallel()
+
+	for _, hsts := range []bool{true, false} {
+		t.Run(fmt.Sprintf("hsts %t", hsts), func(t *testing.T) {
+			t.Parallel()
+
+			server := createTestServerWithCORS(t, &Config{HSTS: &hsts})
+
+			recorder := httptest.NewRecorder()
+			server.httpServer.Handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/status", nil))
+
+			assert.Equal(t, hsts, recorder.Header().Get("Strict-Transport-Security") != "")
+		})
+	}
+}
+
+// preflightAllowOrigin sends a preflight request and returns the allowed origin.
+func preflightAllowOrigin(t *testing.T, server *Server, path, origin string) string {
+	t.Helper()
+
+	req := httptest.NewRequest(http.MethodOptions, path, nil)
+	req.Header.Set("Origin", origin)
+	req.Header.Set("Access-Control-Request-Method", http.MethodGet)
+
+	recorder := httptest.NewRecorder()
+
+	server.httpServer.Handler.ServeHTTP(recorder, req)
+
+	return recorder.Header().Get("Access-Control-Allow-Origin")
+}
+
+func TestCORSRouteGroups(t *testing.T) {
+	t.Parallel()
+
+	t.Run("apply route group policy by path prefix", func(t *testing.T) {
+		t.Parallel()
+
+		config := &Config{
+			CORS: &CORSConfig{
+				AllowedOrigins: &[]string{"*"},
+				Groups: []*CORSGroupConfig{
+					{
+						PathPrefix:     &[]string{"/admin"}[0],
+						AllowedOrigins: &[]string{"https://internal.example.com"},
+					},
+				},
+			},
+		}
+
+		server := createTestServerWithCORS(t, config)
+
+		// public routes stay wide-open
+		assert.Equal(t, "*", preflightAllowOrigin(t, server, "/status", "https://evil.com"))
+
+		// admin routes are locked to internal origins
+		assert.Empty(t, preflightAllowOrigin(t, server, "/admin/replays", "https://evil.com"))
+		assert.Equal(t,
+			"https://internal.example.com",
+			preflightAllowOrigin(t, server, "/admin/replays", "https://internal.example.com"),
+		)
+
+		// prefix matches whole path segments only
+		assert.Equal(t, "*", preflightAllowOrigin(t, server, "/administrator", "https://evil.com"))
+	})
+
+	t.Run("inherit global policy for unset group fields", func(t *testing.T) {
+		t.Parallel()
+
+		config := &Config{
+			CORS: &CORSConfig{
+				AllowedMethods: &[]string{"GET"},
+				Groups:         []*CORSGroupConfig{{PathPrefix: &[]string{"/admin"}[0]}},
+			},
+		}
+		config.SetDefault()
+
+		group := config.CORS.Groups[0]
+		assert.Equal(t, []string{"*"}, *group.AllowedOrigins)
+		assert.Equal(t, []string{"GET"}, *group.AllowedMethods)
+		assert.Equal(t, *config.CORS.AllowedHeaders, *group.AllowedHeaders)
+	})
+}
+
+func TestMatchPathPrefix(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		path     string
+		prefix   string
+		expected bool
+	}{
+		{path: "/admin", prefix: "/admin", expected: true},
+		{path: "/admin/replays", prefix: "/admin", expected: true},
+		{path: "/admin/replays", prefix: "/admin/", expected: true},
+		{path: "/administrator", prefix: "/admin", expected: false},
+		{path: "/status", prefix: "/", expected: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.path+" "+test.prefix, func(t *testing.T) {
+			t.Parallel()
+
+			assert.Equal(t, test.expected, matchPathPrefix(test.path, test.prefix))
+		})
+	}
+}
+
 func TestServerJWTIntegration(t *testing.T) {
 	t.Parallel()
 
@@ -1131,7 +2205,12 @@ func TestServerJWTIntegration(t *testing.T) {
 		jwtService := setupTestJWT(t)
 
 		mockHandler := &mockAPIHandler{}
-		server, err := New(nil, log, mockHandler, jwtService, redisClient)
+		server, err := New(ServerParams{
+			Logger:     log,
+			APIHandler: mockHandler,
+			JWT:        jwtService,
+			Redis:      redisClient,
+		})
 		require.NoError(t, err)
 
 		require.NotNil(t, server)
@@ -1148,7 +2227,12 @@ func TestServerJWTIntegration(t *testing.T) {
 		jwtService := setupTestJWT(t)
 
 		mockHandler := &mockAPIHandler{}
-		server, err := New(nil, log, mockHandler, jwtService, redisClient)
+		server, err := New(ServerParams{
+			Logger:     log,
+			APIHandler: mockHandler,
+			JWT:        jwtService,
+			Redis:      redisClient,
+		})
 		require.NoError(t, err)
 
 		require.NotNil(t, server.httpServer.Handler)
@@ -1174,7 +2258,12 @@ func TestServerWithDifferentJWTConfig(t *testing.T) {
 		require.NoError(t, err)
 
 		mockHandler := &mockAPIHandler{}
-		server, err := New(nil, log, mockHandler, jwtService, redisClient)
+		server, err := New(ServerParams{
+			Logger:     log,
+			APIHandler: mockHandler,
+			JWT:        jwtService,
+			Redis:      redisClient,
+		})
 		require.NoError(t, err)
 
 		require.NotNil(t, server)
@@ -1218,7 +2307,13 @@ func TestServerSetupWithAllComponents(t *testing.T) {
 		jwtService := setupTestJWT(t)
 
 		mockHandler := &mockAPIHandler{}
-		server, err := New(config, log, mockHandler, jwtService, redisClient)
+		server, err := New(ServerParams{
+			Config:     config,
+			Logger:     log,
+			APIHandler: mockHandler,
+			JWT:        jwtService,
+			Redis:      redisClient,
+		})
 		require.NoError(t, err)
 
 		require.NotNil(t, server)
@@ -0,0 +1,197 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/pocj8ur4in/boilerplate-go/internal/app/boilerplate/server/middleware"
+	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/jwt"
+	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/settings"
+)
+
+// SettingsConfig represents configuration for settings endpoints.
+type SettingsConfig struct {
+	// Enabled is whether settings endpoints are enabled.
+	Enabled *bool `json:"enabled"`
+
+	// Path is the path prefix of settings endpoints of the authenticated user.
+	Path *string `json:"path"`
+}
+
+// settingResponse represents a setting returned by settings endpoints.
+type settingResponse struct {
+	// Key is key of the setting.
+	Key string `json:"key"`
+
+	// Value is JSON value of the setting.
+	Value json.RawMessage `json:"value"`
+
+	// Scope is scope the value was resolved from (global or user).
+	Scope string `json:"scope"`
+}
+
+// settingRequest represents the body of setting updates.
+type settingRequest struct {
+	// Value is JSON value of the setting.
+	Value json.RawMessage `json:"value"`
+}
+
+// setSettingsDefault sets default values for settings endpoints on server.
+func (c *Config) setSettingsDefault() {
+	if c.Settings == nil {
+		c.Settings = &SettingsConfig{}
+	}
+
+	if c.Settings.Enabled == nil {
+		c.Settings.Enabled = &[]bool{true}[0]
+	}
+
+	if c.Settings.Path == nil {
+		c.Settings.Path = &[]string{"/settings"}[0]
+	}
+}
+
+// setupSettingsRoutes sets up settings endpoints of the authenticated user.
+func (s *Server) setupSettingsRoutes(router *chi.Mux, config *Config, jwtService *jwt.JWT) {
+	if s.settings == nil {
+		return
+	}
+
+	router.Route(*config.Settings.Path, func(router chi.Router) {
+		router.Use(middleware.RequireBearerAuth)
+		router.Use(middleware.JWTAuth(jwtService, s.logger))
+
+		router.Get("/", s.handleListUserSettings)
+		router.Get("/{key}", s.handleGetUserSetting)
+		router.Put("/{key}", s.handlePutUserSetting)
+		router.Delete("/{key}", s.handleDeleteUserSetting)
+	})
+}
+
+// setupAdminSettingsRoutes sets up global settings endpoints on the admin router.
+func (s *Server) setupAdminSettingsRoutes(router chi.Router) {
+	if s.settings == nil {
+		return
+	}
+
+	router.Get("/settings", s.handleListGlobalSettings)
+	router.Put("/settings/{key}", s.handlePutGlobalSetting)
+	router.Delete("/settings/{key}", s.handleDeleteGlobalSetting)
+}
+
+// handleListUserSettings handles GET /settings endpoint.
+func (s *Server) handleListUserSettings(writer http.ResponseWriter, request *http.Request) {
+	userID, _ := request.Context().Value(middleware.UserIDKey).(string)
+
+	s.listSettings(writer, request, settings.UserScope(userID))
+}
+
+// handleGetUserSetting handles GET /settings/{key} endpoint, falling back to the global setting.
+func (s *Server) handleGetUserSetting(writer http.ResponseWriter, request *http.Request) {
+	userID, _ := request.Context().Value(middleware.UserIDKey).(string)
+	key := chi.URLParam(request, "key")
+
+	value, scope, err := s.settings.Resolve(request.Context(), userID, key)
+	if err != nil {
+		s.writeSettingError(writer, request, err, "failed to get setting")
+
+		return
+	}
+
+	resolved := string(settings.GlobalScope)
+	if scope != settings.GlobalScope {
+		resolved = "user"
+	}
+
+	writeJSON(writer, http.StatusOK, settingResponse{Key: key, Value: value, Scope: resolved})
+}
+
+// handlePutUserSetting handles PUT /settings/{key} endpoint.
+func (s *Server) handlePutUserSetting(writer http.ResponseWriter, request *http.Request) {
+	userID, _ := request.Context().Value(middleware.UserIDKey).(string)
+
+	s.putSetting(writer, request, settings.UserScope(userID), "user")
+}
+
+// handleDeleteUserSetting handles DELETE /settings/{key} endpoint.
+func (s *Server) handleDeleteUserSetting(writer http.ResponseWriter, request *http.Request) {
+	userID, _ := request.Context().Value(middleware.UserIDKey).(string)
+
+	s.deleteSetting(writer, request, settings.UserScope(userID))
+}
+
+// handleListGlobalSettings handles GET /admin/settings endpoint.
+func (s *Server) handleListGlobalSettings(writer http.ResponseWriter, request *http.Request) {
+	s.listSettings(writer, request, settings.GlobalScope)
+}
+
+// handlePutGlobalSetting handles PUT /admin/settings/{key} endpoint.
+func (s *Server) handlePutGlobalSetting(writer http.ResponseWriter, request *http.Request) {
+	s.putSetting(writer, request, settings.GlobalScope, string(settings.GlobalScope))
+}
+
+// handleDeleteGlobalSetting handles DELETE /admin/settings/{key} endpoint.
+func (s *Server) handleDeleteGlobalSetting(writer http.ResponseWriter, request *http.Request) {
+	s.deleteSetting(writer, request, settings.GlobalScope)
+}
+
+// listSettings writes all settings of the scope.
+func (s *Server) listSettings(writer http.ResponseWriter, request *http.Request, scope settings.Scope) {
+	values, err := s.settings.List(request.Context(), scope)
+	if err != nil {
+		s.writeSettingError(writer, request, err, "failed to list settings")
+
+		return
+	}
+
+	writeJSON(writer, http.StatusOK, map[string]interface{}{"settings": values})
+}
+
+// putSetting stores the setting of the scope from the request body.
+func (s *Server) putSetting(writer http.ResponseWriter, request *http.Request, scope settings.Scope, scopeName string) {
+	key := chi.URLParam(request, "key")
+
+	var body settingRequest
+	if err := json.NewDecoder(request.Body).Decode(&body); err != nil || body.Value == nil {
+		writeError(writer, http.StatusBadRequest, "invalid request body")
+
+		return
+	}
+
+	if err := s.settings.SetRaw(request.Context(), scope, key, body.Value); err != nil {
+		s.writeSettingError(writer, request, err, "failed to set setting")
+
+		return
+	}
+
+	writeJSON(writer, http.StatusOK, settingResponse{Key: key, Value: body.Value, Scope: scopeName})
+}
+
+// deleteSetting removes the setting of the scope.
+func (s *Server) deleteSetting(writer http.ResponseWriter, request *http.Request, scope settings.Scope) {
+	if err := s.settings.Delete(request.Context(), scope, chi.URLParam(request, "key")); err != nil {
+		s.writeSettingError(writer, request, err, "failed to delete setting")
+
+		return
+	}
+
+	writer.WriteHeader(http.StatusNoContent)
+}
+
+// writeSettingError writes the error response of a settings operation.
+func (s *Server) writeSettingError(writer http.ResponseWriter, request *http.Request, err error, message string) {
+	switch {
+	case errors.Is(err, settings.ErrNotFound):
+		writeError(writer, http.StatusNotFound, "setting not found")
+	case errors.Is(err, settings.ErrInvalidKey):
+		writeError(writer, http.StatusBadRequest, "invalid setting key")
+	case errors.Is(err, settings.ErrInvalidValue):
+		writeError(writer, http.StatusBadRequest, "invalid setting value")
+	default:
+		s.logger.Ctx(request.Context()).Error().Err(err).Msg(message)
+		writeError(writer, http.StatusInternalServerError, message)
+	}
+}
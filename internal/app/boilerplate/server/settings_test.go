@@ -0,0 +1,234 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pocj8ur4in/boilerplate-go/internal/gen/db"
+	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/jwt"
+	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/logger"
+	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/settings"
+)
+
+// mockSettingsQuerier is a mock querier serving settings from memory.
+type mockSettingsQuerier struct {
+	db.Querier
+
+	mu       sync.Mutex
+	settings map[string]map[string][]byte
+}
+
+func (m *mockSettingsQuerier) GetSetting(_ context.Context, arg *db.GetSettingParams) (*db.Setting, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	value, ok := m.settings[arg.Scope][arg.Key]
+	if !ok {
+		return nil, pgx.ErrNoRows
+	}
+
+	return &db.Setting{Scope: arg.Scope, Key: arg.Key, Value: value}, nil
+}
+
+func (m *mockSettingsQuerier) ListSettings(_ context.Context, scope string) ([]*db.Setting, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	rows := []*db.Setting{}
+	for key, value := range m.settings[scope] {
+		rows = append(rows, &db.Setting{Scope: scope, Key: key, Value: value})
+	}
+
+	return rows, nil
+}
+
+func (m *mockSettingsQuerier) UpsertSetting(_ context.Context, arg *db.UpsertSettingParams) (*db.Setting, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.settings[arg.Scope] == nil {
+		m.settings[arg.Scope] = make(map[string][]byte)
+	}
+
+	m.settings[arg.Scope][arg.Key] = arg.Value
+
+	return &db.Setting{Scope: arg.Scope, Key: arg.Key, Value: arg.Value}, nil
+}
+
+func (m *mockSettingsQuerier) DeleteSetting(_ context.Context, arg *db.DeleteSettingParams) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delete(m.settings[arg.Scope], arg.Key)
+
+	return nil
+}
+
+// newTestSettingsServer creates a test server with settings endpoints.
+func newTestSettingsServer(t *testing.T, jwtService *jwt.JWT) *Server {
+	t.Helper()
+
+	log, err := logger.New(&logger.Config{Level: &[]string{"info"}[0]})
+	require.NoError(t, err)
+
+	redisClient := setupTestRedis(t)
+
+	settingsService, err := settings.NewWithQuerier(
+		nil, &mockSettingsQuerier{settings: make(map[string]map[string][]byte)}, redisClient, log,
+	)
+	require.NoError(t, err)
+
+	t.Cleanup(func() {
+		_ = settingsService.Close()
+	})
+
+	server, err := New(ServerParams{
+		Logger:     log,
+		APIHandler: &mockAPIHandler{},
+		JWT:        jwtService,
+		Redis:      redisClient,
+		Settings:   settingsService,
+	})
+	require.NoError(t, err)
+
+	return server
+}
+
+// settingsRequest performs a request against the settings endpoints as the user with the given role.
+func settingsRequest(
+	t *testing.T,
+	server *Server,
+	jwtService *jwt.JWT,
+	method, path, body, userID, role string,
+) *httptest.ResponseRecorder {
+	t.Helper()
+
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+
+	if userID != "" {
+		token, err := jwtService.GenerateAccessToken(userID, userID+"@example.com", role)
+		require.NoError(t, err)
+
+		req.Header.Set("Authorization", "Bearer "+*token)
+	}
+
+	recorder := httptest.NewRecorder()
+	server.Handler().ServeHTTP(recorder, req)
+
+	return recorder
+}
+
+//nolint:paralleltest // sequential execution required to avoid redis key conflicts
+func TestSettingsRoutes(t *testing.T) {
+	t.Run("reject unauthenticated request", func(t *testing.T) {
+		jwtService := setupTestJWT(t)
+		server := newTestSettingsServer(t, jwtService)
+
+		recorder := settingsRequest(t, server, jwtService, http.MethodGet, "/settings", "", "", "")
+
+		assert.Equal(t, http.StatusUnauthorized, recorder.Code)
+	})
+
+	t.Run("set, get and delete user setting", func(t *testing.T) {
+		jwtService := setupTestJWT(t)
+		server := newTestSettingsServer(t, jwtService)
+
+		recorder := settingsRequest(t, server, jwtService, http.MethodPut, "/settings/theme",
+			`{"value": "dark"}`, "user-1", "user")
+		require.Equal(t, http.StatusOK, recorder.Code)
+
+		recorder = settingsRequest(t, server, jwtService, http.MethodGet, "/settings/theme", "", "user-1", "user")
+		require.Equal(t, http.StatusOK, recorder.Code)
+		assert.JSONEq(t, `{"key": "theme", "value": "dark", "scope": "user"}`, recorder.Body.String())
+
+		recorder = settingsRequest(t, server, jwtService, http.MethodGet, "/settings", "", "user-1", "user")
+		require.Equal(t, http.StatusOK, recorder.Code)
+		assert.JSONEq(t, `{"settings": {"theme": "dark"}}`, recorder.Body.String())
+
+		// other users do not see the setting
+		recorder = settingsRequest(t, server, jwtService, http.MethodGet, "/settings/theme", "", "user-2", "user")
+		assert.Equal(t, http.StatusNotFound, recorder.Code)
+
+		recorder = settingsRequest(t, server, jwtService, http.MethodDelete, "/settings/theme", "", "user-1", "user")
+		require.Equal(t, http.StatusNoContent, recorder.Code)
+
+		recorder = settingsRequest(t, server, jwtService, http.MethodGet, "/settings/theme", "", "user-1", "user")
+		assert.Equal(t, http.StatusNotFound, recorder.Code)
+	})
+
+	t.Run("fall back to global setting", func(t *testing.T) {
+		jwtService := setupTestJWT(t)
+		server := newTestSettingsServer(t, jwtService)
+
+		recorder := settingsRequest(t, server, jwtService, http.MethodPut, "/admin/settings/page_size",
+			`{"value": 50}`, "admin-1", "admin")
+		require.Equal(t, http.StatusOK, recorder.Code)
+
+		recorder = settingsRequest(t, server, jwtService, http.MethodGet, "/settings/page_size", "", "user-1", "user")
+		require.Equal(t, http.StatusOK, recorder.Code)
+		assert.JSONEq(t, `{"key": "page_size", "value": 50, "scope": "global"}`, recorder.Body.String())
+
+		recorder = settingsRequest(t, server, jwtService, http.MethodGet, "/admin/settings", "", "admin-1", "admin")
+		require.Equal(t, http.StatusOK, recorder.Code)
+
+		var body map[string]map[string]json.RawMessage
+		require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &body))
+		assert.JSONEq(t, `50`, string(body["settings"]["page_size"]))
+	})
+
+	t.Run("reject global setting without admin role", func(t *testing.T) {
+		jwtService := setupTestJWT(t)
+		server := newTestSettingsServer(t, jwtService)
+
+		recorder := settingsRequest(t, server, jwtService, http.MethodPut, "/admin/settings/page_size",
+			`{"value": 50}`, "user-1", "user")
+
+		assert.Equal(t, http.StatusForbidden, recorder.Code)
+	})
+
+	t.Run("reject invalid key and body", func(t *testing.T) {
+		jwtService := setupTestJWT(t)
+		server := newTestSettingsServer(t, jwtService)
+
+		recorder := settingsRequest(t, server, jwtService, http.MethodPut, "/settings/.hidden",
+			`{"value": 1}`, "user-1", "user")
+		assert.Equal(t, http.StatusBadRequest, recorder.Code)
+
+		recorder = settingsRequest(t, server, jwtService, http.MethodPut, "/settings/theme",
+			`{"other": 1}`, "user-1", "user")
+		assert.Equal(t, http.StatusBadRequest, recorder.Code)
+
+		recorder = settingsRequest(t, server, jwtService, http.MethodPut, "/settings/theme",
+			`not json`, "user-1", "user")
+		assert.Equal(t, http.StatusBadRequest, recorder.Code)
+	})
+
+	t.Run("disable settings endpoints", func(t *testing.T) {
+		jwtService := setupTestJWT(t)
+
+		log, err := logger.New(&logger.Config{Level: &[]string{"info"}[0]})
+		require.NoError(t, err)
+
+		server, err := New(ServerParams{
+			Logger:     log,
+			APIHandler: &mockAPIHandler{},
+			JWT:        jwtService,
+			Redis:      setupTestRedis(t),
+		})
+		require.NoError(t, err)
+
+		recorder := settingsRequest(t, server, jwtService, http.MethodGet, "/settings", "", "user-1", "user")
+
+		assert.Equal(t, http.StatusNotFound, recorder.Code)
+	})
+}
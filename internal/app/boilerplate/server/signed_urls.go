@@ -0,0 +1,62 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/pocj8ur4in/boilerplate-go/internal/app/boilerplate/server/middleware"
+	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/jwt"
+	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/signedurl"
+)
+
+// signedURLRequest represents the body of signed URL creation.
+type signedURLRequest struct {
+	// Path is path of the resource with an optional query, e.g. /downloads/report.pdf.
+	Path string `json:"path"`
+
+	// ExpiresIn is lifetime of the URL in seconds, the default lifetime if zero.
+	ExpiresIn int `json:"expires_in"`
+}
+
+// setupSignedURLRoutes sets up the endpoint signing URLs for the authenticated user,
+// only JWT is accepted so that signed URLs cannot sign other URLs.
+func (s *Server) setupSignedURLRoutes(router *chi.Mux, jwtService *jwt.JWT) {
+	if s.signer == nil {
+		return
+	}
+
+	router.Route(s.signer.Path(), func(router chi.Router) {
+		router.Use(middleware.RequireBearerAuth)
+		router.Use(middleware.JWTAuth(jwtService, s.logger))
+
+		router.Post("/", s.handleCreateSignedURL)
+	})
+}
+
+// handleCreateSignedURL handles POST /signed-urls endpoint.
+func (s *Server) handleCreateSignedURL(writer http.ResponseWriter, request *http.Request) {
+	userID, _ := request.Context().Value(middleware.UserIDKey).(string)
+
+	var body signedURLRequest
+	if err := json.NewDecoder(request.Body).Decode(&body); err != nil || body.Path == "" {
+		writeError(writer, http.StatusBadRequest, "invalid request body")
+
+		return
+	}
+
+	signed, err := s.signer.Sign(body.Path, userID, time.Duration(body.ExpiresIn)*time.Second)
+
+	switch {
+	case errors.Is(err, signedurl.ErrPathNotSignable), errors.Is(err, signedurl.ErrInvalidTTL):
+		writeError(writer, http.StatusBadRequest, err.Error())
+	case err != nil:
+		s.logger.Ctx(request.Context()).Error().Err(err).Msg("failed to sign url")
+		writeError(writer, http.StatusInternalServerError, "failed to sign url")
+	default:
+		writeJSON(writer, http.StatusCreated, signed)
+	}
+}
@@ -0,0 +1,129 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/jwt"
+	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/logger"
+	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/signedurl"
+)
+
+// newTestSignedURLServer creates a test server with signed URLs of /status enabled or disabled.
+func newTestSignedURLServer(t *testing.T, jwtService *jwt.JWT, enabled bool) *Server {
+	t.Helper()
+
+	log, err := logger.New(&logger.Config{Level: &[]string{"info"}[0]})
+	require.NoError(t, err)
+
+	signer, err := signedurl.New(&signedurl.Config{
+		Enabled: &enabled,
+		Secret:  &[]string{"test_secret"}[0],
+		BaseURL: &[]string{"https://api.example.com"}[0],
+		Paths:   []string{"/status"},
+	})
+	require.NoError(t, err)
+
+	server, err := New(ServerParams{
+		Logger:     log,
+		APIHandler: &mockAPIHandler{},
+		JWT:        jwtService,
+		Redis:      setupTestRedis(t),
+		Signer:     signer,
+	})
+	require.NoError(t, err)
+
+	return server
+}
+
+// requestSignedURL requests a signed URL of the path, authenticated by a token if authenticated.
+func requestSignedURL(
+	t *testing.T,
+	server *Server,
+	jwtService *jwt.JWT,
+	body string,
+	authenticated bool,
+) *httptest.ResponseRecorder {
+	t.Helper()
+
+	req := httptest.NewRequest(http.MethodPost, "/signed-urls", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+
+	if authenticated {
+		token, err := jwtService.GenerateAccessToken("user-1", "user@example.com", "user")
+		require.NoError(t, err)
+
+		req.Header.Set("Authorization", "Bearer "+*token)
+	}
+
+	recorder := httptest.NewRecorder()
+	server.Handler().ServeHTTP(recorder, req)
+
+	return recorder
+}
+
+//nolint:paralleltest // sequential execution required to avoid redis key conflicts
+func TestSignedURLRoutes(t *testing.T) {
+	t.Run("not register routes when disabled", func(t *testing.T) {
+		jwtService := setupTestJWT(t)
+		server := newTestSignedURLServer(t, jwtService, false)
+
+		recorder := requestSignedURL(t, server, jwtService, `{"path": "/status"}`, true)
+
+		assert.NotEqual(t, http.StatusCreated, recorder.Code)
+	})
+
+	t.Run("require token", func(t *testing.T) {
+		jwtService := setupTestJWT(t)
+		server := newTestSignedURLServer(t, jwtService, true)
+
+		recorder := requestSignedURL(t, server, jwtService, `{"path": "/status"}`, false)
+
+		assert.Equal(t, http.StatusUnauthorized, recorder.Code)
+	})
+
+	t.Run("reject path which is not signable", func(t *testing.T) {
+		jwtService := setupTestJWT(t)
+		server := newTestSignedURLServer(t, jwtService, true)
+
+		recorder := requestSignedURL(t, server, jwtService, `{"path": "/health"}`, true)
+
+		assert.Equal(t, http.StatusBadRequest, recorder.Code)
+	})
+
+	t.Run("reject lifetime over maximum", func(t *testing.T) {
+		jwtService := setupTestJWT(t)
+		server := newTestSignedURLServer(t, jwtService, true)
+
+		recorder := requestSignedURL(t, server, jwtService, `{"path": "/status", "expires_in": 31536000}`, true)
+
+		assert.Equal(t, http.StatusBadRequest, recorder.Code)
+	})
+
+	t.Run("sign url verified by api", func(t *testing.T) {
+		jwtService := setupTestJWT(t)
+		server := newTestSignedURLServer(t, jwtService, true)
+
+		recorder := requestSignedURL(t, server, jwtService, `{"path": "/status", "expires_in": 60}`, true)
+		require.Equal(t, http.StatusCreated, recorder.Code, recorder.Body.String())
+
+		var signed signedurl.SignedURL
+		require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &signed))
+		assert.True(t, strings.HasPrefix(signed.URL, "https://api.example.com/status?"))
+
+		recorder = httptest.NewRecorder()
+		server.Handler().ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, signed.URL, nil))
+		assert.Equal(t, http.StatusOK, recorder.Code)
+
+		recorder = httptest.NewRecorder()
+		tampered := strings.Replace(signed.URL, "signed_by=user-1", "signed_by=user-2", 1)
+		server.Handler().ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, tampered, nil))
+		assert.Equal(t, http.StatusUnauthorized, recorder.Code)
+	})
+}
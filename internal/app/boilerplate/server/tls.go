@@ -0,0 +1,245 @@
+package server
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"sync/atomic"
+	"syscall"
+)
+
+var (
+	// ErrTLSCertRequired is returned when TLS is enabled without cert and key files.
+	ErrTLSCertRequired = errors.New("tls requires cert_file and key_file")
+
+	// ErrInvalidTLSMinVersion is returned when the TLS minimum version is unknown.
+	ErrInvalidTLSMinVersion = errors.New("invalid tls min version")
+
+	// ErrInvalidTLSClientAuth is returned when the TLS client auth mode is unknown.
+	ErrInvalidTLSClientAuth = errors.New("invalid tls client auth")
+
+	// ErrTLSClientCARequired is returned when client certificates are verified without a client CA file.
+	ErrTLSClientCARequired = errors.New("tls client auth requires client_ca_file")
+
+	// ErrInvalidTLSClientCA is returned when the client CA file has no valid certificate.
+	ErrInvalidTLSClientCA = errors.New("invalid tls client ca file")
+)
+
+// tlsVersions maps configured TLS minimum versions to their values.
+var tlsVersions = map[string]uint16{
+	"1.0": tls.VersionTLS10,
+	"1.1": tls.VersionTLS11,
+	"1.2": tls.VersionTLS12,
+	"1.3": tls.VersionTLS13,
+}
+
+// tlsClientAuths maps configured TLS client auth modes to their values.
+var tlsClientAuths = map[string]tls.ClientAuthType{
+	"none":               tls.NoClientCert,
+	"request":            tls.RequestClientCert,
+	"require":            tls.RequireAnyClientCert,
+	"verify_if_given":    tls.VerifyClientCertIfGiven,
+	"require_and_verify": tls.RequireAndVerifyClientCert,
+}
+
+// TLSConfig represents configuration for TLS of server.
+type TLSConfig struct {
+	// Enabled is whether the listener on host and port serves TLS.
+	Enabled *bool `json:"enabled"`
+
+	// CertFile is path to the TLS certificate file.
+	CertFile *string `json:"cert_file"`
+
+	// KeyFile is path to the TLS private key file.
+	KeyFile *string `json:"key_file"`
+
+	// MinVersion is minimum TLS version (1.0, 1.1, 1.2, 1.3).
+	MinVersion *string `json:"min_version"`
+
+	// ClientAuth is client certificate mode (none, request, require, verify_if_given, require_and_verify).
+	ClientAuth *string `json:"client_auth"`
+
+	// ClientCAFile is path to the CA file verifying client certificates.
+	ClientCAFile *string `json:"client_ca_file"`
+
+	// ReloadOnSIGHUP is whether certificates are reloaded from files on SIGHUP.
+	ReloadOnSIGHUP *bool `json:"reload_on_sighup"`
+}
+
+// certReloader serves a certificate loaded from files and reloads it on demand.
+type certReloader struct {
+	certFile string
+	keyFile  string
+	cert     atomic.Pointer[tls.Certificate]
+}
+
+// setTLSDefault sets default values for TLS on server.
+func (c *Config) setTLSDefault() {
+	if c.TLS == nil {
+		c.TLS = &TLSConfig{}
+	}
+
+	if c.TLS.Enabled == nil {
+		c.TLS.Enabled = &[]bool{false}[0]
+	}
+
+	if c.TLS.CertFile == nil {
+		c.TLS.CertFile = &[]string{""}[0]
+	}
+
+	if c.TLS.KeyFile == nil {
+		c.TLS.KeyFile = &[]string{""}[0]
+	}
+
+	if c.TLS.MinVersion == nil {
+		c.TLS.MinVersion = &[]string{"1.2"}[0]
+	}
+
+	if c.TLS.ClientAuth == nil {
+		c.TLS.ClientAuth = &[]string{"none"}[0]
+	}
+
+	if c.TLS.ClientCAFile == nil {
+		c.TLS.ClientCAFile = &[]string{""}[0]
+	}
+
+	if c.TLS.ReloadOnSIGHUP == nil {
+		c.TLS.ReloadOnSIGHUP = &[]bool{true}[0]
+	}
+}
+
+// validateTLS validates TLS configuration.
+func validateTLS(config *TLSConfig) error {
+	if *config.Enabled && (*config.CertFile == "" || *config.KeyFile == "") {
+		return ErrTLSCertRequired
+	}
+
+	if _, ok := tlsVersions[*config.MinVersion]; !ok {
+		return fmt.Errorf("%w: %s", ErrInvalidTLSMinVersion, *config.MinVersion)
+	}
+
+	clientAuth, ok := tlsClientAuths[*config.ClientAuth]
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrInvalidTLSClientAuth, *config.ClientAuth)
+	}
+
+	if clientAuth >= tls.VerifyClientCertIfGiven && *config.ClientCAFile == "" {
+		return ErrTLSClientCARequired
+	}
+
+	return nil
+}
+
+// newCertReloader creates a certificate reloader and loads the certificate.
+func newCertReloader(certFile, keyFile string) (*certReloader, error) {
+	reloader := &certReloader{certFile: certFile, keyFile: keyFile}
+
+	if err := reloader.reload(); err != nil {
+		return nil, err
+	}
+
+	return reloader, nil
+}
+
+// reload loads the certificate from files, the previous certificate is kept on failure.
+func (r *certReloader) reload() error {
+	cert, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+	if err != nil {
+		return fmt.Errorf("failed to load tls certificate %s: %w", r.certFile, err)
+	}
+
+	r.cert.Store(&cert)
+
+	return nil
+}
+
+// getCertificate returns the current certificate for TLS handshakes.
+func (r *certReloader) getCertificate(_ *tls.ClientHelloInfo) (*tls.Certificate, error) {
+	return r.cert.Load(), nil
+}
+
+// newTLSConfig creates TLS configuration serving the reloader certificate.
+func newTLSConfig(config *TLSConfig, reloader *certReloader) (*tls.Config, error) {
+	tlsConfig := &tls.Config{
+		MinVersion:     tlsVersions[*config.MinVersion],
+		ClientAuth:     tlsClientAuths[*config.ClientAuth],
+		GetCertificate: reloader.getCertificate,
+	}
+
+	if *config.ClientCAFile != "" {
+		pem, err := os.ReadFile(*config.ClientCAFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read tls client ca file: %w", err)
+		}
+
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, ErrInvalidTLSClientCA
+		}
+
+		tlsConfig.ClientCAs = pool
+	}
+
+	return tlsConfig, nil
+}
+
+// ReloadCertificates reloads TLS certificates of all listeners from files.
+func (s *Server) ReloadCertificates() error {
+	var errs []error
+
+	for _, listener := range s.listeners {
+		if listener.reloader == nil {
+			continue
+		}
+
+		if err := listener.reloader.reload(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// hasTLSListener checks if any listener serves TLS.
+func (s *Server) hasTLSListener() bool {
+	for _, listener := range s.listeners {
+		if listener.reloader != nil {
+			return true
+		}
+	}
+
+	return false
+}
+
+// reloadCertificatesOnSignal reloads certificates whenever SIGHUP is received, returns a function stopping it.
+func (s *Server) reloadCertificatesOnSignal() func() {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGHUP)
+
+	done := make(chan struct{})
+
+	go func() {
+		for {
+			select {
+			case <-signals:
+				if err := s.ReloadCertificates(); err != nil {
+					s.logger.Error().Err(err).Msg("failed to reload tls certificates")
+
+					continue
+				}
+
+				s.logger.Info().Msg("tls certificates reloaded")
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	return func() {
+		signal.Stop(signals)
+		close(done)
+	}
+}
@@ -0,0 +1,279 @@
+package server
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/logger"
+)
+
+// newTestTLSServer creates and runs a server with TLS on the listener on host and port.
+func newTestTLSServer(t *testing.T, tlsConfig *TLSConfig) (*Server, string) {
+	t.Helper()
+
+	log, err := logger.New(&logger.Config{Level: &[]string{"info"}[0]})
+	require.NoError(t, err)
+
+	host, portValue, err := net.SplitHostPort(freeAddr(t))
+	require.NoError(t, err)
+
+	port, err := net.LookupPort("tcp", portValue)
+	require.NoError(t, err)
+
+	config := &Config{
+		Host:          &host,
+		Port:          &port,
+		AddressFamily: &[]string{AddressFamilyTCP4}[0],
+		TLS:           tlsConfig,
+	}
+
+	server, err := New(ServerParams{
+		Config:     config,
+		Logger:     log,
+		APIHandler: &mockAPIHandler{},
+		JWT:        setupTestJWT(t),
+		Redis:      setupTestRedis(t),
+	})
+	require.NoError(t, err)
+
+	done := make(chan error, 1)
+
+	go func() {
+		done <- server.Run()
+	}()
+
+	require.Eventually(t, func() bool {
+		conn, err := net.Dial("tcp", server.Addr())
+		if err != nil {
+			return false
+		}
+
+		_ = conn.Close()
+
+		return true
+	}, 2*time.Second, 10*time.Millisecond)
+
+	t.Cleanup(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+		defer cancel()
+
+		assert.NoError(t, server.Shutdown(ctx))
+		assert.NoError(t, <-done)
+	})
+
+	return server, server.Addr()
+}
+
+// handshake connects to the address and returns the serial number of the served certificate.
+func handshake(t *testing.T, addr string, config *tls.Config) (int64, error) {
+	t.Helper()
+
+	conn, err := tls.Dial("tcp", addr, config)
+	if err != nil {
+		return 0, err
+	}
+
+	defer func() { _ = conn.Close() }()
+
+	return conn.ConnectionState().PeerCertificates[0].SerialNumber.Int64(), nil
+}
+
+func TestConfigSetDefaultTLS(t *testing.T) {
+	t.Parallel()
+
+	t.Run("set default TLS when config is empty", func(t *testing.T) {
+		t.Parallel()
+
+		config := &Config{}
+		config.SetDefault()
+
+		require.NotNil(t, config.TLS)
+		assert.False(t, *config.TLS.Enabled)
+		assert.Empty(t, *config.TLS.CertFile)
+		assert.Empty(t, *config.TLS.KeyFile)
+		assert.Equal(t, "1.2", *config.TLS.MinVersion)
+		assert.Equal(t, "none", *config.TLS.ClientAuth)
+		assert.Empty(t, *config.TLS.ClientCAFile)
+		assert.True(t, *config.TLS.ReloadOnSIGHUP)
+	})
+}
+
+func TestValidateTLS(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		config   *TLSConfig
+		expected error
+	}{
+		{
+			name:   "accept disabled TLS",
+			config: &TLSConfig{},
+		},
+		{
+			name: "accept enabled TLS with cert and key",
+			config: &TLSConfig{
+				Enabled:  &[]bool{true}[0],
+				CertFile: &[]string{"cert.pem"}[0],
+				KeyFile:  &[]string{"key.pem"}[0],
+			},
+		},
+		{
+			name:     "reject enabled TLS without cert",
+			config:   &TLSConfig{Enabled: &[]bool{true}[0]},
+			expected: ErrTLSCertRequired,
+		},
+		{
+			name:     "reject unknown min version",
+			config:   &TLSConfig{MinVersion: &[]string{"1.4"}[0]},
+			expected: ErrInvalidTLSMinVersion,
+		},
+		{
+			name:     "reject unknown client auth",
+			config:   &TLSConfig{ClientAuth: &[]string{"always"}[0]},
+			expected: ErrInvalidTLSClientAuth,
+		},
+		{
+			name:     "reject verified client auth without CA",
+			config:   &TLSConfig{ClientAuth: &[]string{"require_and_verify"}[0]},
+			expected: ErrTLSClientCARequired,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			config := &Config{TLS: test.config}
+			config.SetDefault()
+
+			err := validateTLS(config.TLS)
+			if test.expected == nil {
+				require.NoError(t, err)
+			} else {
+				require.ErrorIs(t, err, test.expected)
+			}
+		})
+	}
+}
+
+func TestTLS(t *testing.T) {
+	t.Parallel()
+
+	t.Run("serve TLS with minimum version", func(t *testing.T) {
+		t.Parallel()
+
+		certFile, keyFile := writeTestCert(t)
+
+		_, addr := newTestTLSServer(t, &TLSConfig{
+			Enabled:    &[]bool{true}[0],
+			CertFile:   &certFile,
+			KeyFile:    &keyFile,
+			MinVersion: &[]string{"1.3"}[0],
+		})
+
+		serial, err := handshake(t, addr, &tls.Config{InsecureSkipVerify: true}) //nolint:gosec // self-signed test certificate
+		require.NoError(t, err)
+		assert.Equal(t, int64(1), serial)
+
+		// clients below the minimum version are rejected
+		_, err = handshake(t, addr, &tls.Config{
+			InsecureSkipVerify: true, //nolint:gosec // self-signed test certificate
+			MaxVersion:         tls.VersionTLS12,
+		})
+		require.Error(t, err)
+	})
+
+	t.Run("reload certificate from files", func(t *testing.T) {
+		t.Parallel()
+
+		certFile, keyFile := writeTestCert(t)
+
+		server, addr := newTestTLSServer(t, &TLSConfig{
+			Enabled:  &[]bool{true}[0],
+			CertFile: &certFile,
+			KeyFile:  &keyFile,
+		})
+
+		serial, err := handshake(t, addr, &tls.Config{InsecureSkipVerify: true}) //nolint:gosec // self-signed test certificate
+		require.NoError(t, err)
+		assert.Equal(t, int64(1), serial)
+
+		writeTestCertFiles(t, certFile, keyFile, 2)
+		require.NoError(t, server.ReloadCertificates())
+
+		serial, err = handshake(t, addr, &tls.Config{InsecureSkipVerify: true}) //nolint:gosec // self-signed test certificate
+		require.NoError(t, err)
+		assert.Equal(t, int64(2), serial)
+	})
+
+	t.Run("require verified client certificate", func(t *testing.T) {
+		t.Parallel()
+
+		certFile, keyFile := writeTestCert(t)
+
+		_, addr := newTestTLSServer(t, &TLSConfig{
+			Enabled:      &[]bool{true}[0],
+			CertFile:     &certFile,
+			KeyFile:      &keyFile,
+			ClientAuth:   &[]string{"require_and_verify"}[0],
+			ClientCAFile: &certFile,
+		})
+
+		clientCert, err := tls.LoadX509KeyPair(certFile, keyFile)
+		require.NoError(t, err)
+
+		_, err = handshake(t, addr, &tls.Config{
+			InsecureSkipVerify: true, //nolint:gosec // self-signed test certificate
+			Certificates:       []tls.Certificate{clientCert},
+		})
+		require.NoError(t, err)
+	})
+
+	t.Run("keep plaintext listener when TLS is disabled", func(t *testing.T) {
+		t.Parallel()
+
+		log, err := logger.New(&logger.Config{Level: &[]string{"info"}[0]})
+		require.NoError(t, err)
+
+		server, err := New(ServerParams{
+			Logger:     log,
+			APIHandler: &mockAPIHandler{},
+			JWT:        setupTestJWT(t),
+			Redis:      setupTestRedis(t),
+		})
+		require.NoError(t, err)
+
+		assert.Nil(t, server.httpServer.TLSConfig)
+		assert.False(t, server.hasTLSListener())
+		require.NoError(t, server.ReloadCertificates())
+	})
+
+	t.Run("return error for missing certificate file", func(t *testing.T) {
+		t.Parallel()
+
+		log, err := logger.New(&logger.Config{Level: &[]string{"info"}[0]})
+		require.NoError(t, err)
+
+		config := &Config{TLS: &TLSConfig{
+			Enabled:  &[]bool{true}[0],
+			CertFile: &[]string{"missing.pem"}[0],
+			KeyFile:  &[]string{"missing.pem"}[0],
+		}}
+
+		_, err = New(ServerParams{
+			Config:     config,
+			Logger:     log,
+			APIHandler: &mockAPIHandler{},
+			JWT:        setupTestJWT(t),
+			Redis:      setupTestRedis(t),
+		})
+		require.Error(t, err)
+	})
+}
@@ -0,0 +1,49 @@
+package server
+
+import (
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/pocj8ur4in/boilerplate-go/internal/app/boilerplate/server/middleware"
+	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/jwt"
+)
+
+// setupWebSocketRoutes sets up the websocket endpoint, upgrading requests authenticated with JWT.
+func (s *Server) setupWebSocketRoutes(router *chi.Mux, jwtService *jwt.JWT) {
+	if s.hub == nil {
+		return
+	}
+
+	router.Group(func(router chi.Router) {
+		router.Use(queryAccessToken)
+		router.Use(middleware.RequireBearerAuth)
+		router.Use(middleware.JWTAuth(jwtService, s.logger))
+
+		router.Get(*s.hub.Config().Path, s.handleWebSocket)
+	})
+}
+
+// queryAccessToken is a middleware that authenticates with the access_token query parameter when the request has
+// no Authorization header, since browsers can not set headers on websocket and EventSource requests.
+func queryAccessToken(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		token := request.URL.Query().Get("access_token")
+		if token != "" && request.Header.Get("Authorization") == "" {
+			request = request.Clone(request.Context())
+			request.Header.Set("Authorization", "Bearer "+token)
+		}
+
+		next.ServeHTTP(writer, request)
+	})
+}
+
+// handleWebSocket handles GET /ws endpoint, upgrading the request to a connection of the authenticated user.
+func (s *Server) handleWebSocket(writer http.ResponseWriter, request *http.Request) {
+	userID, _ := request.Context().Value(middleware.UserIDKey).(string)
+
+	// the upgrader writes the error response of failed handshakes
+	if err := s.hub.Serve(writer, request, userID); err != nil {
+		s.logger.Ctx(request.Context()).Debug().Err(err).Msg("failed to upgrade websocket")
+	}
+}
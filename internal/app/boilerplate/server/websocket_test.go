@@ -0,0 +1,177 @@
+package server
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/logger"
+	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/websocket"
+)
+
+// newTestWebSocketServer creates a test server with a websocket hub and serves its handler.
+func newTestWebSocketServer(t *testing.T) (*Server, *websocket.Hub, string) {
+	t.Helper()
+
+	log, err := logger.New(&logger.Config{Level: &[]string{"info"}[0]})
+	require.NoError(t, err)
+
+	hub, err := websocket.New(nil, log)
+	require.NoError(t, err)
+
+	server, err := New(ServerParams{
+		Logger:     log,
+		APIHandler: &mockAPIHandler{},
+		JWT:        setupTestJWT(t),
+		Redis:      setupTestRedis(t),
+		Hub:        hub,
+	})
+	require.NoError(t, err)
+
+	httpServer := httptest.NewServer(server.Handler())
+	t.Cleanup(httpServer.Close)
+
+	return server, hub, httpServer.URL
+}
+
+// dialWebSocket sends a websocket handshake to the path and returns the connection and the response status.
+func dialWebSocket(t *testing.T, serverURL, path string, header http.Header) (net.Conn, *bufio.Reader, int) {
+	t.Helper()
+
+	parsed, err := url.Parse(serverURL)
+	require.NoError(t, err)
+
+	conn, err := net.Dial("tcp", parsed.Host)
+	require.NoError(t, err)
+
+	t.Cleanup(func() {
+		_ = conn.Close()
+	})
+
+	request, err := http.NewRequest(http.MethodGet, serverURL+path, nil)
+	require.NoError(t, err)
+
+	request.Header = header
+	request.Header.Set("Connection", "Upgrade")
+	request.Header.Set("Upgrade", "websocket")
+	request.Header.Set("Sec-WebSocket-Version", "13")
+	request.Header.Set("Sec-WebSocket-Key", "dGhlIHNhbXBsZSBub25jZQ==")
+	require.NoError(t, request.Write(conn))
+
+	reader := bufio.NewReader(conn)
+
+	response, err := http.ReadResponse(reader, request)
+	require.NoError(t, err)
+
+	if response.StatusCode != http.StatusSwitchingProtocols {
+		require.NoError(t, response.Body.Close())
+	}
+
+	return conn, reader, response.StatusCode
+}
+
+// readWebSocketFrame reads an unmasked frame of up to 65535 bytes and returns its opcode and payload.
+func readWebSocketFrame(t *testing.T, conn net.Conn, reader *bufio.Reader) (byte, []byte) {
+	t.Helper()
+
+	require.NoError(t, conn.SetReadDeadline(time.Now().Add(2*time.Second)))
+
+	header := make([]byte, 2)
+
+	_, err := io.ReadFull(reader, header)
+	require.NoError(t, err)
+
+	length := int(header[1] & 0x7f)
+
+	// lengths over 125 bytes follow the header in 2 bytes
+	if length == 126 {
+		extended := make([]byte, 2)
+
+		_, err = io.ReadFull(reader, extended)
+		require.NoError(t, err)
+
+		length = int(binary.BigEndian.Uint16(extended))
+	}
+
+	payload := make([]byte, length)
+
+	_, err = io.ReadFull(reader, payload)
+	require.NoError(t, err)
+
+	return header[0] & 0x0f, payload
+}
+
+func TestWebSocketRoutes(t *testing.T) {
+	t.Parallel()
+
+	t.Run("reject upgrades without token", func(t *testing.T) {
+		t.Parallel()
+
+		_, _, serverURL := newTestWebSocketServer(t)
+
+		_, _, status := dialWebSocket(t, serverURL, "/ws", http.Header{})
+		assert.Equal(t, http.StatusUnauthorized, status)
+
+		_, _, status = dialWebSocket(t, serverURL, "/ws?access_token=invalid", http.Header{})
+		assert.Equal(t, http.StatusUnauthorized, status)
+	})
+
+	t.Run("upgrade authenticated requests to connections of the user", func(t *testing.T) {
+		t.Parallel()
+
+		_, hub, serverURL := newTestWebSocketServer(t)
+		jwtService := setupTestJWT(t)
+
+		token, err := jwtService.GenerateAccessToken("user-1", "user@example.com", "user")
+		require.NoError(t, err)
+
+		_, _, status := dialWebSocket(t, serverURL, "/ws", http.Header{"Authorization": {"Bearer " + *token}})
+		assert.Equal(t, http.StatusSwitchingProtocols, status)
+
+		conn, reader, status := dialWebSocket(t, serverURL, "/ws?access_token="+*token, http.Header{})
+		require.Equal(t, http.StatusSwitchingProtocols, status)
+
+		require.Eventually(t, func() bool { return hub.Connections("user-1") == 2 }, time.Second, 5*time.Millisecond)
+
+		hub.Send("user-1", websocket.TextMessage, []byte("hello"))
+
+		opcode, payload := readWebSocketFrame(t, conn, reader)
+		assert.Equal(t, byte(0x1), opcode)
+		assert.Equal(t, "hello", string(payload))
+	})
+
+	t.Run("close connections on shutdown", func(t *testing.T) {
+		t.Parallel()
+
+		server, hub, serverURL := newTestWebSocketServer(t)
+
+		token, err := setupTestJWT(t).GenerateAccessToken("user-1", "user@example.com", "user")
+		require.NoError(t, err)
+
+		conn, reader, status := dialWebSocket(t, serverURL, "/ws?access_token="+*token, http.Header{})
+		require.Equal(t, http.StatusSwitchingProtocols, status)
+
+		require.Eventually(t, func() bool { return hub.Connections("") == 1 }, time.Second, 5*time.Millisecond)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+
+		// the client does not answer the close frame, so it is disconnected at the deadline
+		_ = server.Shutdown(ctx)
+
+		opcode, payload := readWebSocketFrame(t, conn, reader)
+		assert.Equal(t, byte(0x8), opcode)
+		assert.Equal(t, []byte{0x03, 0xe9}, payload[:2]) // 1001 going away
+		assert.Zero(t, hub.Connections(""))
+	})
+}
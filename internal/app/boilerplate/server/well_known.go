@@ -0,0 +1,106 @@
+package server
+
+import (
+	_ "embed"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+
+	"github.com/go-chi/chi/v5"
+)
+
+//go:embed assets/favicon.ico
+var defaultFavicon []byte
+
+// assetCacheControl is the Cache-Control header of static assets.
+const assetCacheControl = "public, max-age=86400"
+
+// WellKnownConfig represents configuration for robots.txt, favicon, and well-known endpoints.
+type WellKnownConfig struct {
+	// Enabled is whether robots.txt, favicon, and well-known endpoints are enabled.
+	Enabled *bool `json:"enabled"`
+
+	// RobotsTxt is content of /robots.txt.
+	RobotsTxt *string `json:"robots_txt"`
+
+	// FaviconPath is path of the favicon file, the embedded favicon is used if empty.
+	FaviconPath *string `json:"favicon_path"`
+
+	// SecurityTxt is content of /.well-known/security.txt, not served if empty.
+	SecurityTxt *string `json:"security_txt"`
+
+	// ChangePasswordURL is redirect target of /.well-known/change-password, not served if empty.
+	ChangePasswordURL *string `json:"change_password_url"`
+}
+
+// setWellKnownDefault sets default values for well-known endpoints on server.
+func (c *Config) setWellKnownDefault() {
+	if c.WellKnown == nil {
+		c.WellKnown = &WellKnownConfig{}
+	}
+
+	if c.WellKnown.Enabled == nil {
+		c.WellKnown.Enabled = &[]bool{true}[0]
+	}
+
+	if c.WellKnown.RobotsTxt == nil {
+		c.WellKnown.RobotsTxt = &[]string{"User-agent: *\nDisallow: /\n"}[0]
+	}
+
+	if c.WellKnown.FaviconPath == nil {
+		c.WellKnown.FaviconPath = &[]string{""}[0]
+	}
+
+	if c.WellKnown.SecurityTxt == nil {
+		c.WellKnown.SecurityTxt = &[]string{""}[0]
+	}
+
+	if c.WellKnown.ChangePasswordURL == nil {
+		c.WellKnown.ChangePasswordURL = &[]string{""}[0]
+	}
+}
+
+// setupWellKnownRoutes sets up robots.txt, favicon, and well-known endpoints.
+func (s *Server) setupWellKnownRoutes(router *chi.Mux, config *Config) error {
+	if !*config.WellKnown.Enabled {
+		return nil
+	}
+
+	favicon := defaultFavicon
+
+	if path := *config.WellKnown.FaviconPath; path != "" {
+		content, err := os.ReadFile(filepath.Clean(path))
+		if err != nil {
+			return fmt.Errorf("failed to read favicon: %w", err)
+		}
+
+		favicon = content
+	}
+
+	router.Get("/robots.txt", serveAsset("text/plain; charset=utf-8", []byte(*config.WellKnown.RobotsTxt)))
+	router.Get("/favicon.ico", serveAsset("image/x-icon", favicon))
+
+	if securityTxt := *config.WellKnown.SecurityTxt; securityTxt != "" {
+		router.Get("/.well-known/security.txt", serveAsset("text/plain; charset=utf-8", []byte(securityTxt)))
+	}
+
+	if changePasswordURL := *config.WellKnown.ChangePasswordURL; changePasswordURL != "" {
+		router.Get("/.well-known/change-password", func(writer http.ResponseWriter, request *http.Request) {
+			http.Redirect(writer, request, changePasswordURL, http.StatusFound)
+		})
+	}
+
+	return nil
+}
+
+// serveAsset returns a handler serving static content.
+func serveAsset(contentType string, content []byte) http.HandlerFunc {
+	return func(writer http.ResponseWriter, _ *http.Request) {
+		writer.Header().Set("Content-Type", contentType)
+		writer.Header().Set("Cache-Control", assetCacheControl)
+		writer.WriteHeader(http.StatusOK)
+
+		_, _ = writer.Write(content)
+	}
+}
@@ -0,0 +1,118 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/logger"
+)
+
+// newTestWellKnownServer creates a test server with the given well-known configuration.
+func newTestWellKnownServer(t *testing.T, wellKnown *WellKnownConfig) (*Server, error) {
+	t.Helper()
+
+	log, err := logger.New(&logger.Config{Level: &[]string{"info"}[0]})
+	require.NoError(t, err)
+
+	return New(ServerParams{
+		Config:     &Config{WellKnown: wellKnown},
+		Logger:     log,
+		APIHandler: &mockAPIHandler{},
+		JWT:        setupTestJWT(t),
+		Redis:      setupTestRedis(t),
+	})
+}
+
+func TestWellKnownDefault(t *testing.T) {
+	t.Parallel()
+
+	t.Run("well-known endpoints have default values", func(t *testing.T) {
+		t.Parallel()
+
+		config := &Config{}
+		config.SetDefault()
+
+		require.NotNil(t, config.WellKnown)
+		assert.True(t, *config.WellKnown.Enabled)
+		assert.Contains(t, *config.WellKnown.RobotsTxt, "Disallow: /")
+		assert.Empty(t, *config.WellKnown.FaviconPath)
+		assert.Empty(t, *config.WellKnown.SecurityTxt)
+		assert.Empty(t, *config.WellKnown.ChangePasswordURL)
+		assert.Contains(t, config.Metrics.ExcludePaths, "/favicon.ico")
+	})
+}
+
+//nolint:paralleltest // sequential execution required to avoid redis key conflicts
+func TestWellKnownRoutes(t *testing.T) {
+	t.Run("serve robots.txt and embedded favicon", func(t *testing.T) {
+		server, err := newTestWellKnownServer(t, nil)
+		require.NoError(t, err)
+
+		recorder := httptest.NewRecorder()
+		server.httpServer.Handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/robots.txt", nil))
+
+		assert.Equal(t, http.StatusOK, recorder.Code)
+		assert.Contains(t, recorder.Body.String(), "User-agent: *")
+
+		recorder = httptest.NewRecorder()
+		server.httpServer.Handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/favicon.ico", nil))
+
+		assert.Equal(t, http.StatusOK, recorder.Code)
+		assert.Equal(t, "image/x-icon", recorder.Header().Get("Content-Type"))
+		assert.Equal(t, defaultFavicon, recorder.Body.Bytes())
+	})
+
+	t.Run("serve configured well-known endpoints", func(t *testing.T) {
+		server, err := newTestWellKnownServer(t, &WellKnownConfig{
+			SecurityTxt:       &[]string{"Contact: mailto:security@example.com\n"}[0],
+			ChangePasswordURL: &[]string{"https://example.com/account/password"}[0],
+		})
+		require.NoError(t, err)
+
+		recorder := httptest.NewRecorder()
+		server.httpServer.Handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/.well-known/security.txt", nil))
+
+		assert.Equal(t, http.StatusOK, recorder.Code)
+		assert.Contains(t, recorder.Body.String(), "security@example.com")
+
+		recorder = httptest.NewRecorder()
+		server.httpServer.Handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/.well-known/change-password", nil))
+
+		assert.Equal(t, http.StatusFound, recorder.Code)
+		assert.Equal(t, "https://example.com/account/password", recorder.Header().Get("Location"))
+	})
+
+	t.Run("skip unconfigured well-known endpoints", func(t *testing.T) {
+		server, err := newTestWellKnownServer(t, nil)
+		require.NoError(t, err)
+
+		recorder := httptest.NewRecorder()
+		server.httpServer.Handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/.well-known/security.txt", nil))
+
+		assert.Equal(t, http.StatusNotFound, recorder.Code)
+	})
+
+	t.Run("serve favicon from file", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "favicon.ico")
+		require.NoError(t, os.WriteFile(path, []byte("icon"), 0o600))
+
+		server, err := newTestWellKnownServer(t, &WellKnownConfig{FaviconPath: &path})
+		require.NoError(t, err)
+
+		recorder := httptest.NewRecorder()
+		server.httpServer.Handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/favicon.ico", nil))
+
+		assert.Equal(t, "icon", recorder.Body.String())
+	})
+
+	t.Run("return error for missing favicon file", func(t *testing.T) {
+		_, err := newTestWellKnownServer(t, &WellKnownConfig{FaviconPath: &[]string{"/non/existent/favicon.ico"}[0]})
+		require.Error(t, err)
+	})
+}
@@ -0,0 +1,122 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"go.uber.org/fx"
+
+	configPkg "github.com/pocj8ur4in/boilerplate-go/internal/app/boilerplate/config"
+	serverPkg "github.com/pocj8ur4in/boilerplate-go/internal/app/boilerplate/server"
+	auditPkg "github.com/pocj8ur4in/boilerplate-go/internal/pkg/audit"
+	loggerPkg "github.com/pocj8ur4in/boilerplate-go/internal/pkg/logger"
+	meteringPkg "github.com/pocj8ur4in/boilerplate-go/internal/pkg/metering"
+	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/serverless"
+	usagePkg "github.com/pocj8ur4in/boilerplate-go/internal/pkg/usage"
+)
+
+// ErrServerlessSubsystem is returned when a subsystem working in the background is enabled in serverless mode.
+var ErrServerlessSubsystem = errors.New("subsystem can not run in serverless mode")
+
+// recorder buffers records in memory until they are flushed.
+type recorder interface {
+	// Enabled returns whether records are buffered.
+	Enabled() bool
+
+	// Flush writes the buffered records.
+	Flush(ctx context.Context) error
+}
+
+// NewServerless creates a serverless adapter that builds the application graph on the first invocation.
+func NewServerless() (*serverless.Adapter, error) {
+	adapter, err := serverless.New(buildHandler)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create serverless adapter: %w", err)
+	}
+
+	return adapter, nil
+}
+
+// buildHandler builds the application graph and returns the server handler, connections stay open across invocations.
+// The application is not started, so that no listener is opened, and recorders are flushed after each invocation
+// instead of by their flush loops.
+func buildHandler() (http.Handler, error) {
+	config, err := loadConfig()
+	if err != nil {
+		return nil, fmt.Errorf("failed to build application: %w", err)
+	}
+
+	if err := checkServerless(config); err != nil {
+		return nil, err
+	}
+
+	var (
+		server  *serverPkg.Server
+		log     *loggerPkg.Logger
+		usage   *usagePkg.Recorder
+		meter   *meteringPkg.Meter
+		auditor *auditPkg.Auditor
+	)
+
+	fxApp := fx.New(
+		fx.NopLogger,
+		modules(config),
+		fx.Populate(&server, &log, &usage, &meter, &auditor),
+	)
+	if err := fxApp.Err(); err != nil {
+		return nil, fmt.Errorf("failed to build application: %w", err)
+	}
+
+	return flushAfterInvocation(server.Handler(), log, usage, meter, auditor), nil
+}
+
+// checkServerless returns ErrServerlessSubsystem if a subsystem needing a running process is enabled, since the
+// process is frozen between invocations: jobs, recurring tasks, retention and the gRPC server are never started,
+// and the query cache would miss invalidations of its in-memory results.
+func checkServerless(config *configPkg.Config) error {
+	subsystems := []struct {
+		name    string
+		enabled bool
+	}{
+		{name: "modules.jobs", enabled: *config.Modules.Jobs},
+		{name: "modules.scheduler", enabled: *config.Modules.Scheduler},
+		{name: "modules.retention", enabled: *config.Modules.Retention},
+		{name: "modules.grpc", enabled: *config.Modules.GRPC},
+		{name: "query_cache.enabled", enabled: *config.QueryCache.Enabled},
+	}
+
+	for _, subsystem := range subsystems {
+		if subsystem.enabled {
+			return fmt.Errorf("%w: disable %s", ErrServerlessSubsystem, subsystem.name)
+		}
+	}
+
+	return nil
+}
+
+// flushAfterInvocation returns the handler flushing the enabled recorders after each request, so that records are
+// written before the process is frozen or recycled.
+func flushAfterInvocation(handler http.Handler, log *loggerPkg.Logger, recorders ...recorder) http.Handler {
+	enabled := make([]recorder, 0, len(recorders))
+
+	for _, recorder := range recorders {
+		if recorder.Enabled() {
+			enabled = append(enabled, recorder)
+		}
+	}
+
+	return http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		handler.ServeHTTP(writer, request)
+
+		// records are written even if the client went away
+		ctx := context.WithoutCancel(request.Context())
+
+		for _, recorder := range enabled {
+			if err := recorder.Flush(ctx); err != nil {
+				log.Ctx(ctx).Error().Err(err).Msg("failed to flush records of invocation")
+			}
+		}
+	})
+}
@@ -0,0 +1,101 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	loggerPkg "github.com/pocj8ur4in/boilerplate-go/internal/pkg/logger"
+)
+
+// errFlushFailed is the test error of a failed flush.
+var errFlushFailed = errors.New("flush failed")
+
+// mockRecorder is a mock recorder counting flushes.
+type mockRecorder struct {
+	enabled bool
+	flushes int
+	err     error
+}
+
+func (m *mockRecorder) Enabled() bool {
+	return m.enabled
+}
+
+func (m *mockRecorder) Flush(_ context.Context) error {
+	m.flushes++
+
+	return m.err
+}
+
+//nolint:paralleltest // Cannot run in parallel due to t.Setenv usage
+func TestBuildHandler(t *testing.T) {
+	t.Run("refuse subsystems working in the background", func(t *testing.T) {
+		beforeTest(t, nil)
+
+		_, err := buildHandler()
+		require.ErrorIs(t, err, ErrServerlessSubsystem)
+		assert.Contains(t, err.Error(), "modules.jobs")
+	})
+
+	t.Run("refuse query cache", func(t *testing.T) {
+		configContent := defaultConfigContent[:len(defaultConfigContent)-1] + `,
+			"modules": {"grpc": false, "jobs": false, "scheduler": false, "retention": false},
+			"query_cache": {"enabled": true}
+		}`
+		beforeTest(t, &configContent)
+
+		_, err := buildHandler()
+		require.ErrorIs(t, err, ErrServerlessSubsystem)
+		assert.Contains(t, err.Error(), "query_cache.enabled")
+	})
+
+	t.Run("build handler without background subsystems", func(t *testing.T) {
+		configContent := defaultConfigContent[:len(defaultConfigContent)-1] + `,
+			"modules": {"grpc": false, "jobs": false, "scheduler": false, "retention": false}
+		}`
+		beforeTest(t, &configContent)
+
+		handler, err := buildHandler()
+		require.NoError(t, err)
+		require.NotNil(t, handler)
+
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/health", nil))
+		assert.Equal(t, http.StatusOK, recorder.Code)
+	})
+}
+
+func TestFlushAfterInvocation(t *testing.T) {
+	t.Parallel()
+
+	log, err := loggerPkg.New(&loggerPkg.Config{Level: &[]string{"info"}[0]})
+	require.NoError(t, err)
+
+	enabled := &mockRecorder{enabled: true}
+	failing := &mockRecorder{enabled: true, err: errFlushFailed}
+	disabled := &mockRecorder{}
+
+	served := 0
+	handler := flushAfterInvocation(http.HandlerFunc(func(writer http.ResponseWriter, _ *http.Request) {
+		served++
+
+		writer.WriteHeader(http.StatusNoContent)
+	}), log, enabled, failing, disabled)
+
+	for range 2 {
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/", nil))
+		assert.Equal(t, http.StatusNoContent, recorder.Code)
+	}
+
+	assert.Equal(t, 2, served)
+	assert.Equal(t, 2, enabled.flushes)
+	assert.Equal(t, 2, failing.flushes)
+	assert.Equal(t, 0, disabled.flushes)
+}
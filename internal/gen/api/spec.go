@@ -18,25 +18,59 @@ import (
 // Base64 encoded, gzipped, json marshaled Swagger object
 var swaggerSpec = []string{
 
-	"H4sIAAAAAAAC/6xWbW/bNhD+KwS7Dy3qWLJsx6mAfei6bu22bkbjYdjmQqCps8SOIlny1MYL/N8HUrJl",
-	"x1FiFAOCWDrePffci+54S7mujFag0NH0ljpeQsXC4/XGIVRvgEksX5XA/3kPzmjlwB/CDauMDI8O7GfB",
-	"IdjkDNmKeRW0NQyohVy45mU7oCgqcMgqQ1OaxMnkIh5dxKNFHKfh7y+6HVBjtQGLosE7xP7Gwpqm9EnU",
-	"MY5aulEv1+sdwLH7W5qD41YYFFrRlHJvQ7wCHdC1thVDmvpo4KIV4sYATalDK1RBt1sf26daWMhp+nfH",
-	"89DLh72VXn0Ejp7D40TT2zs56HJ6l7VwZHdIygBJmkAMcw7yjvVKawlM0e2+IvdAhZMzce6Ev6e4wz8N",
-	"fTugDnhtBW6ufdGa2L4DZsG+rLH0b6vw9sMu/T/9saCDpiOD83DakSkRDd16YKHW+jSklRYSrJEMgbyc",
-	"vyXfa15XoJCF8wGVgkPbzYoFD+/eeoe1lS26S6NIG1BO15bDUNsiao1c5HVDT6GEU2d0QD+DdQ2ReBgP",
-	"Y6/ssZgRNKXjIBpQw7AMiYiavPvHArC3Qct9ofU6vPnGA0uYykkOBlQOigtwQxq82RDr25ym9KDlQpWa",
-	"rgu+kzj2P1wrBBV8M2Ok4ME4+ug8gd1k+OoPsanUcVC//dz0RV1VzG580g+azxeaFS58XQGUfvDKUQVo",
-	"BXe9mSoAQ2bmVleAJdSOtCbk6YEsIj9atmaKPbsnV0zlEt61jh7NFsINRkYyoY4mI31C3rz+ZU4KnRU8",
-	"y+vGQeaAa5U78pK0ce9K+YVJGaYNMax2QJ461OYCS7j4oq3Mn5EdBBGKFMyuWAGEaymBBynfcAluuFRP",
-	"yOLP+es+v63Xpbr//PZTzRQKCd8uabykWxIP4zgex+PLZHqWzTCZfpVZZzWZvphOkvOsZnuzZJRMxuPx",
-	"OWajM20yV1eN3tXVrDeMjOtaIRn7xO8Lrq2uUShw5Ne6WoH1RT4QYsmQ8NpaUCg3BG6Ew+PKdboFqwto",
-	"nHfCUXLoroLKIUOXlcBMxqTUPFtt8Mi7PyKNMCgwhDzMDYdCSt9TtYMjDv2oHaV+nfFwNJnOkit4Hl/2",
-	"c3Ub9xBTvUImFORkbXVFmjnwAMcOrY9hpzEbXo4nLyaTI35+6md+s4FDlwmVraUoSjwg92axmJOdxkEJ",
-	"VyBU0YzjvGPYh9fS6zuO94SM1Rycy7ip9/2GGpkki/C/du30b3JDXs1/D9cY4gwo9FVtrTpO/ZChkcEu",
-	"Vb9KPJwmJ+QsOJGDQp9rbTdtgt+3UtJIiRP/gicUTk/p3A/SJuphpWQ4mc4u4Xk8W6rdjSFsqruXtsc3",
-	"ULUf+vcuH99JtTtjSzeKx1v6dNFcB63/ZSl3F/Lt6d3r8bhbvv2bN9zffBRefjduP07k0dUpjaIgLLXD",
-	"dHwVX8V0+2H7XwAAAP//5qpzBm4MAAA=",
+	"H4sIAAAAAAAC/+xa+W8bN/b/Vwj2+0OKTqTRaUXAF9g0vZxNWsN29nQhUDNPEmsOOSU5TtTC//uCx8xw",
+	"LlveNulisUAQWOTjOz7vIPk4v+JEZLngwLXC618xfCBZzsD+/Y2QW5qmwL+WUkgzkoJKJM01FRyv8fUB",
+	"UEIYA4kYSW4V0gdAUjBAQiKViByQhJ8LKiFF26OdBZ7mgnI9whFWRZYRecRrvCsFoWfzePY5jvAdYQUY",
+	"iYlIIaTAEQanTa0evr+P8DnXIDlhD+iqQN6BRDtCGaRIC3QgPGXg1IafC1AtvajnubEi0bNFHPcp1yQL",
+	"NCx1QldOstPNaXtHGE0vndQHdPZ6IapQRthOyAxSJJwRClkmxJC3Fbczm3L1s/mQ5g26QHU/U8nfivRo",
+	"Nf9e6G9EwdPHdQazWolCJoBSAQpxoRF8oG2UudCbnWFp1Jz3qVlRBAoq0JryvWXq5ox2l0TDG5pRDSco",
+	"iOBDApAqRJAkGhAzCyMkQcsjIjsN0obGpfn9/KX9fQCSgmzqb9ZumBOKns2nL/pMCIlwhFPQxoGGwA7i",
+	"9TKOsISMUE75Hq/tLwUarydn8Txenk0NgT7m1m05vq+RuKx09waBx8JZeS3EGyL3cAIexskVKMb0RPAd",
+	"3RcmgRX9xUtpWe/WbrQQG2bkoGfzSW8Odyh7cZjE89XibBmHBobqaSGQW21sfMdJoQ9C0l9OcviBmCh0",
+	"aYO2QKRxsbiFVvoUAVcTlJM+c0KiIC5DjUoV7whlZMuGXPASpZADT4EnRyR2SNe1iipU1OvL2GREt4PQ",
+	"0NMENgG1qVe9juihDQy4crMo0BvfG0NUcoCMWF+9LPThjdhT7l0T7Bz2z4xQZiBSIP/kx0eJyHCEc6LU",
+	"eyFTvMaJkBISjQ5CKkBbojXII1KaGC73Ec6lyEFqCirg2cbODpegGXm4yhKlpUml+1Boe3k58zCH+wiX",
+	"Gxle/9OrErD9sVohtj9Boo1MA9El7CSoQz9I0k1ubPwZS46vD9tvE/oDfX3+7pfzyff0XJ3zy0Xy6nx5",
+	"fpv/7S+vXr8YjUZdZFqM2ib6aRfniCpVmB2EI0X3HBW52UyY2CPKH7W7KWjI6Cu650X+3xgYEdqCfg/A",
+	"27Uxo5xmRYYIT9HZFG2PGtTvF0XXBu5LULngClqAkiQBpZ4cQxGGDzmVoDaU4/VsGcdRy7tP4WVXbLzu",
+	"X9qqiiPrY1t0JBAN6YZovMbTeDp/Hk+ex5PrOF7bf//AkceiNy6oiYjZbpq8IGew2E7SebLakRiW22n6",
+	"IpnvJmQFZzjCUjDwHPB9J0iaOLWd7WZ9hmiBFPAUUefml76a21OW3//7QikEtM1fQSJ4akq5psxybUj0",
+	"S3FkjrmZxYlyvZzXYijXsDeGRU/Ldi1cwjfkqT71Qx+2eZrRMg9CRhEi7D05KlT5vMO2DIL/k7DDa/zZ",
+	"uL5tjP1+MjYIvyvdFmZIw2ltyxsqN+AfyqN3XpdmZITh2TGcZlBlvy2YkKIiDx2VEg3PDV1vTPyG0kR7",
+	"ihJNgWu6oyAfW+2yoRMc9m72hJ2OplVyep5RCFgf0vaMM1Ct/qCzcLsYODXa4GQkOVAOSAJJ7fHJ8kSG",
+	"OEKM2vsM0WicikSN7VxvIgXWtIpMmlLzJ2EoA01Soknpi/Le2AET+g+MhyIjvK1oBkqRPTy+71iefa67",
+	"OioN2XdAmD68OkByO7Tp7GGTKbyeT+MIJyQ5GMZaFhCVR0tnPtFkSxSUcxJSqtwPU3BoBkqTLB/aFbo1",
+	"3IvtOI4yRssSqyhPXM4mxgKFJOGnFdbSkE7SKSRBFUy7M3mKdlJkyFLXfLZCMCDc8AkheKjsDaJ9VTJo",
+	"otTWy9qHfOk5pR614qDSM5RSwRCVcD8pUK4C25vOq4OhB99y0myvTB+c65A5GFlNuhj7UOp1VUrViXxa",
+	"gFQqlvyHTX9D74CDUg9lidJEF8osvu3GcjnZtsCNl3UhlyIBpap9Vtw+7lXHeFj1SyApfUx3lzsdvxXS",
+	"noJsFk6jtoW1Uxp0sTlclvXZRFqKRKF9f2Wq1uYgreGDRimQlJkKXFXuQITpd1ljWyOd2l5pXtfbiwbF",
+	"4znZgahg2pX25knLjFtf+VKzPSJOwsyrsR/yt7hFdIcIYyUPF6yR7e8hoQ8g31MFp7o9Ku1/gv+Ncd10",
+	"DV34YMGtSi3SQtyGpWi41A5sa3a4jH3fpbWs+/bZhxGttfqtgIZIDKN6ZckHsmlbUJZubFEevAElIrNH",
+	"HzzfzZIpmeAI78XmDqRyZu3FZDRdjGI7LkWhjQ/NFhzhDDIhjzbkGRPJxl0/16vZarWMVxE+AMk3TmGz",
+	"IrYJyYtss0/wemLy+KjKRdPFZLlYTecR1kITtmlwXJ5N4tVqOb+PsI1zU0opA5kzogFHuMiNjRsfGeW9",
+	"srbhbjKKR3E3Z0N8eo/gPiQsXV8slOC1F++pRm7uURYh2B02Avm5E9jUvmmz4UW2dSf3gKovPWqHPl6r",
+	"moH31q2sHNRRgdRo+v2/99bWcuTQfbY+bPmdCilNpDvVn1AGBgE/De1WxvrSWzKtoiIK46vh6I6hDf9V",
+	"fjg1699WbmudW8Mcaptqh42hlspcrMzhJUdlvp4EZDPDh+Put8goC8YwdxOjDAz3fYKSY8JObWkE9WcQ",
+	"HucLJLaaUF4ewk1wiBOF9NSztrCkyApGNL0D18IL8NoJ+WTM2t2MQHSfOiEOLZdW6HdD0cAHSSGpPl6Z",
+	"wuDscg0Z0/Qwv9xDxzelvq//eo19N9/W8Fbz5qB17hr+lO9Ej0vqmo9eXpyjr0RSZMC13SVxhBlNwO+B",
+	"fpd4e24EFpJ57mo9HoscuHsbHAm5H/tFamxo7b1HM+gKw+F2Epe7ieFFcmr6hHYowjnRBwvE2DzGjJl5",
+	"qjA/c6F037UhaJGZHm6jiabCjkn5t2vhGNqyfWsaoSbrLQznKV5j+0CCo/Ld60vzgmq7D1wDt2qQPGc0",
+	"sSvGPylXCV15P6Vn1niAuW+Gm79kS1+aLBbTOP5d5Tf70laBJq4//Nl4Z/47im02l3pEfklSVEFiZE8+",
+	"nezOy1/9Olc/sJC9ssXApOaPhshFqI+4R2OUNzvHPhob8doNRP8MdV31UT9OPLZeu/4Xkf/JEdkImQcC",
+	"U9nXvOG4dN1gRB6pjpH99YRC241i9674EeO3+XB5UvhOPm34vnKt9z8+hmefTnb1lVcUhBZ1R2aUCvNI",
+	"UMbNm69eXqCUSki0vwXN4xefTtNXgu8YTXQr0/w7e3+OuUalEb0HPdjjPVSd0ebXISaFqk9HKKhu0gQ9",
+	"WvwRi+/wy8FAEW4gFHZrA5iUZeqBYvQOToCpvIea72byCL2n+mCajZbAfC32MFqNtu7Hx6u/i3wKYsyv",
+	"fBizDLSkiRqEbQ/agnYhRQb6AIVCfgl6FoyN0beS7Agnn/fEl/2C8q0X9Chips87zhmhvNEiw5+h775+",
+	"c4H2YrNPNlXHrWwzvETe8jL83xPG7CMHykmhAD1TWuTP9QGevxeSpZ+jkoV5xN8TuSV7QIlgDBI76i6n",
+	"oxv+Gbr++8XXQ3K91BveP//rzwUxD/rw/zc4vsH3KB7FcTyLZ8vp4qQ1o+ni31pWr5ovXizm09NWnVXL",
+	"ppPpfDabnbJscuKajSoyR7danQ2asUlEwTWa3fDA4VXHBX3f0yBD+kA0SgopgWt29N+PNjxX0+5JsYcb",
+	"3hycTENxGWRKE6029oId3L8D6Waq0wEwtVZpypiJqUJBQ4dhrrVKwzSz0WS+OJuu4It4Oaxr1R3o17TZ",
+	"GXF14AEda25DGtYUZ6PlbP5iPm/oZy7y5XfD5rOLzY7R/UEHyn13fX1RfnmpAhduwVRi95haazjEz6s3",
+	"NB1XCvnSv0nyooo322VB1/Z/e0C1XrTYoFcX7+zrKVI5cG286lfVOg2ztIEM8oYPk8SjxbSjnARlP+LY",
+	"uH6WB/jSj5ZdLvutLeXOs111+pl4oB4mmo7mi7MlfBGf3XAcBbtVu7f6+B6UVUW/d/ORQNLj4NZT79jl",
+	"A2Pkn26Nh+AO5BFJ2FOlQZavQPYyESElwiNQQjjS5BaQlmS3o0nf7Td88Pr4u/rAA+vgbXQRz/4wDfq/",
+	"9W3eEz2vh48a9WvcI+5uPnA7J/bc9SzVJ3JY6+3ulNj3VgxD4r8DAWnG22iYLYU1OqLr8dgOHoTS69kq",
+	"XsX4/sf7fw0AaYW+ypwzAAA=",
 }
 
 // GetSwagger returns the content of the embedded swagger specification file
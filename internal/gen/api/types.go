@@ -11,8 +11,80 @@ const (
 	BearerAuthScopes = "BearerAuth.Scopes"
 )
 
+// AuthLoginRequest defines model for AuthLoginRequest.
+type AuthLoginRequest struct {
+	// Email email of the user
+	Email string `json:"email"`
+
+	// Password password of the user
+	Password string `json:"password"`
+}
+
+// AuthRefreshRequest defines model for AuthRefreshRequest.
+type AuthRefreshRequest struct {
+	// RefreshToken refresh token issued on sign up or log in
+	RefreshToken string `json:"refresh_token"`
+}
+
+// AuthSignupRequest defines model for AuthSignupRequest.
+type AuthSignupRequest struct {
+	// Email email of the user
+	Email string `json:"email"`
+
+	// Password password of the user, between the configured minimum and 72 bytes
+	Password string `json:"password"`
+}
+
+// AuthTokenResponse defines model for AuthTokenResponse.
+type AuthTokenResponse struct {
+	// AccessToken access token to send in the Authorization header
+	AccessToken string `json:"access_token"`
+
+	// ExpiresIn seconds until the access token expires
+	ExpiresIn int64 `json:"expires_in"`
+
+	// RefreshToken refresh token to issue access tokens
+	RefreshToken string `json:"refresh_token"`
+
+	// TokenType type of the access token, always Bearer
+	TokenType string    `json:"token_type"`
+	User      *AuthUser `json:"user,omitempty"`
+}
+
+// AuthUser defines model for AuthUser.
+type AuthUser struct {
+	// CreatedAt time the user signed up
+	CreatedAt time.Time `json:"created_at"`
+
+	// Email email of the user
+	Email string `json:"email"`
+
+	// Id identifier of the user
+	Id string `json:"id"`
+
+	// Role role of the user
+	Role string `json:"role"`
+}
+
+// ErrorResponse defines model for ErrorResponse.
+type ErrorResponse struct {
+	// Code machine readable error code, listed at /docs/errors
+	Code *string `json:"code,omitempty"`
+
+	// Details additional metadata of the error
+	Details *map[string]interface{} `json:"details,omitempty"`
+
+	// Error human readable error message
+	Error string `json:"error"`
+}
+
 // SystemHealthCheckResponse defines model for SystemHealthCheckResponse.
 type SystemHealthCheckResponse struct {
+	// AgeMs milliseconds since the checks ran
+	AgeMs int64 `json:"age_ms"`
+
+	// Cached is result served from cache
+	Cached   bool                              `json:"cached"`
 	Services SystemHealthCheckResponseServices `json:"services"`
 
 	// Timestamp check time
@@ -27,3 +99,82 @@ type SystemHealthCheckResponseServices struct {
 	// Redis is redis health check passed
 	Redis bool `json:"redis"`
 }
+
+// SystemLivenessCheckResponse defines model for SystemLivenessCheckResponse.
+type SystemLivenessCheckResponse struct {
+	// Status status of the process, always ok
+	Status string `json:"status"`
+}
+
+// SystemReadinessCheckResponse defines model for SystemReadinessCheckResponse.
+type SystemReadinessCheckResponse struct {
+	// Checks results of checks by name
+	Checks map[string]SystemReadinessCheckResult `json:"checks"`
+
+	// Status ok if all checks passed, fail otherwise
+	Status string `json:"status"`
+}
+
+// SystemReadinessCheckResult defines model for SystemReadinessCheckResult.
+type SystemReadinessCheckResult struct {
+	// DurationMs milliseconds the check took
+	DurationMs int64 `json:"duration_ms"`
+
+	// Error error of the failed check
+	Error *string `json:"error,omitempty"`
+
+	// Status ok if the check passed, fail otherwise
+	Status string `json:"status"`
+}
+
+// SystemStatusResponse defines model for SystemStatusResponse.
+type SystemStatusResponse struct {
+	// BuildTime time of the build
+	BuildTime string `json:"build_time"`
+
+	// Commit git commit of the build
+	Commit string `json:"commit"`
+
+	// GoVersion go version of the build
+	GoVersion string `json:"go_version"`
+
+	// Goroutines number of goroutines
+	Goroutines int                        `json:"goroutines"`
+	Memory     SystemStatusResponseMemory `json:"memory"`
+
+	// Name name of the service
+	Name string `json:"name"`
+
+	// UptimeSeconds seconds since the process started
+	UptimeSeconds int64 `json:"uptime_seconds"`
+
+	// Version version of the build
+	Version string `json:"version"`
+}
+
+// SystemStatusResponseMemory defines model for SystemStatusResponseMemory.
+type SystemStatusResponseMemory struct {
+	// AllocBytes bytes of allocated heap objects
+	AllocBytes int64 `json:"alloc_bytes"`
+
+	// HeapObjects number of allocated heap objects
+	HeapObjects int64 `json:"heap_objects"`
+
+	// NumGc number of completed gc cycles
+	NumGc int64 `json:"num_gc"`
+
+	// SysBytes bytes of memory obtained from the os
+	SysBytes int64 `json:"sys_bytes"`
+
+	// TotalAllocBytes cumulative bytes allocated for heap objects
+	TotalAllocBytes int64 `json:"total_alloc_bytes"`
+}
+
+// LoginJSONRequestBody defines body for Login for application/json ContentType.
+type LoginJSONRequestBody = AuthLoginRequest
+
+// RefreshTokenJSONRequestBody defines body for RefreshToken for application/json ContentType.
+type RefreshTokenJSONRequestBody = AuthRefreshRequest
+
+// SignupJSONRequestBody defines body for Signup for application/json ContentType.
+type SignupJSONRequestBody = AuthSignupRequest
@@ -3,3 +3,103 @@
 //   sqlc v1.29.0
 
 package db
+
+import (
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+type ApiKey struct {
+	ID                     string             `json:"id"`
+	UserID                 string             `json:"user_id"`
+	Name                   string             `json:"name"`
+	KeyHash                string             `json:"key_hash"`
+	Role                   string             `json:"role"`
+	Scopes                 []string           `json:"scopes"`
+	RateLimitRequests      *int32             `json:"rate_limit_requests"`
+	RateLimitWindowSeconds *int32             `json:"rate_limit_window_seconds"`
+	ExpiresAt              pgtype.Timestamptz `json:"expires_at"`
+	RevokedAt              pgtype.Timestamptz `json:"revoked_at"`
+	CreatedAt              pgtype.Timestamptz `json:"created_at"`
+	TenantID               string             `json:"tenant_id"`
+}
+
+type AuditEvent struct {
+	ID         string             `json:"id"`
+	Action     string             `json:"action"`
+	ActorID    string             `json:"actor_id"`
+	Ip         string             `json:"ip"`
+	UserAgent  string             `json:"user_agent"`
+	RequestID  string             `json:"request_id"`
+	Resource   string             `json:"resource"`
+	Attributes []byte             `json:"attributes"`
+	OccurredAt pgtype.Timestamptz `json:"occurred_at"`
+	CreatedAt  pgtype.Timestamptz `json:"created_at"`
+}
+
+type BillingCustomer struct {
+	UserID     string             `json:"user_id"`
+	CustomerID string             `json:"customer_id"`
+	CreatedAt  pgtype.Timestamptz `json:"created_at"`
+}
+
+type BillingSubscription struct {
+	ID                string             `json:"id"`
+	CustomerID        string             `json:"customer_id"`
+	Status            string             `json:"status"`
+	PriceIds          []string           `json:"price_ids"`
+	CurrentPeriodEnd  pgtype.Timestamptz `json:"current_period_end"`
+	CancelAtPeriodEnd bool               `json:"cancel_at_period_end"`
+	UpdatedAt         pgtype.Timestamptz `json:"updated_at"`
+}
+
+type MeteringEvent struct {
+	ID         string             `json:"id"`
+	TenantID   string             `json:"tenant_id"`
+	Type       string             `json:"type"`
+	Quantity   int64              `json:"quantity"`
+	Attributes []byte             `json:"attributes"`
+	OccurredAt pgtype.Timestamptz `json:"occurred_at"`
+	CreatedAt  pgtype.Timestamptz `json:"created_at"`
+}
+
+type Setting struct {
+	Scope     string             `json:"scope"`
+	Key       string             `json:"key"`
+	Value     []byte             `json:"value"`
+	CreatedAt pgtype.Timestamptz `json:"created_at"`
+	UpdatedAt pgtype.Timestamptz `json:"updated_at"`
+}
+
+type TenantRateLimit struct {
+	TenantID      string             `json:"tenant_id"`
+	Requests      int32              `json:"requests"`
+	WindowSeconds int32              `json:"window_seconds"`
+	CreatedAt     pgtype.Timestamptz `json:"created_at"`
+	UpdatedAt     pgtype.Timestamptz `json:"updated_at"`
+}
+
+type TenantUsage struct {
+	TenantID      string             `json:"tenant_id"`
+	Day           pgtype.Date        `json:"day"`
+	Requests      int64              `json:"requests"`
+	RequestBytes  int64              `json:"request_bytes"`
+	ResponseBytes int64              `json:"response_bytes"`
+	CreatedAt     pgtype.Timestamptz `json:"created_at"`
+	UpdatedAt     pgtype.Timestamptz `json:"updated_at"`
+}
+
+type User struct {
+	ID           string             `json:"id"`
+	Email        string             `json:"email"`
+	PasswordHash string             `json:"password_hash"`
+	Role         string             `json:"role"`
+	CreatedAt    pgtype.Timestamptz `json:"created_at"`
+	UpdatedAt    pgtype.Timestamptz `json:"updated_at"`
+}
+
+type UserIdentity struct {
+	Provider  string             `json:"provider"`
+	Subject   string             `json:"subject"`
+	UserID    string             `json:"user_id"`
+	CreatedAt pgtype.Timestamptz `json:"created_at"`
+}
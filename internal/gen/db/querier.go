@@ -4,6 +4,39 @@
 
 package db
 
-type Querier interface{}
+import (
+	"context"
+)
+
+type Querier interface {
+	AddTenantUsage(ctx context.Context, arg *AddTenantUsageParams) error
+	CreateAPIKey(ctx context.Context, arg *CreateAPIKeyParams) (*ApiKey, error)
+	CreateBillingCustomer(ctx context.Context, arg *CreateBillingCustomerParams) (*BillingCustomer, error)
+	CreateUser(ctx context.Context, arg *CreateUserParams) (*User, error)
+	CreateUserIdentity(ctx context.Context, arg *CreateUserIdentityParams) (*UserIdentity, error)
+	DeleteSetting(ctx context.Context, arg *DeleteSettingParams) error
+	DeleteTenantRateLimit(ctx context.Context, tenantID string) error
+	GetAPIKeyByHash(ctx context.Context, keyHash string) (*ApiKey, error)
+	GetBillingCustomerByCustomerID(ctx context.Context, customerID string) (*BillingCustomer, error)
+	GetBillingCustomerByUserID(ctx context.Context, userID string) (*BillingCustomer, error)
+	GetSetting(ctx context.Context, arg *GetSettingParams) (*Setting, error)
+	GetTenantRateLimit(ctx context.Context, tenantID string) (*TenantRateLimit, error)
+	GetUserByEmail(ctx context.Context, email string) (*User, error)
+	GetUserByID(ctx context.Context, id string) (*User, error)
+	GetUserIdentity(ctx context.Context, arg *GetUserIdentityParams) (*UserIdentity, error)
+	GetUserIdentityByUserID(ctx context.Context, arg *GetUserIdentityByUserIDParams) (*UserIdentity, error)
+	InsertAuditEvent(ctx context.Context, arg *InsertAuditEventParams) (int64, error)
+	InsertMeteringEvent(ctx context.Context, arg *InsertMeteringEventParams) (int64, error)
+	ListAPIKeys(ctx context.Context, userID string) ([]*ApiKey, error)
+	ListBillingSubscriptionsByUserID(ctx context.Context, userID string) ([]*BillingSubscription, error)
+	ListSettings(ctx context.Context, scope string) ([]*Setting, error)
+	ListTenantUsage(ctx context.Context, arg *ListTenantUsageParams) ([]*TenantUsage, error)
+	RevokeAPIKey(ctx context.Context, arg *RevokeAPIKeyParams) (*ApiKey, error)
+	SetAPIKeyRateLimit(ctx context.Context, arg *SetAPIKeyRateLimitParams) (*ApiKey, error)
+	UpdateUserRole(ctx context.Context, arg *UpdateUserRoleParams) (*User, error)
+	UpsertBillingSubscription(ctx context.Context, arg *UpsertBillingSubscriptionParams) (*BillingSubscription, error)
+	UpsertSetting(ctx context.Context, arg *UpsertSettingParams) (*Setting, error)
+	UpsertTenantRateLimit(ctx context.Context, arg *UpsertTenantRateLimitParams) (*TenantRateLimit, error)
+}
 
 var _ Querier = (*Queries)(nil)
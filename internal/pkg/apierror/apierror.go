@@ -0,0 +1,267 @@
+// Package apierror provides the JSON error envelope of API responses.
+package apierror
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"runtime/debug"
+	"strings"
+	"sync/atomic"
+)
+
+// RequestIDHeader is the default response header of the request ID, copied to error responses.
+const RequestIDHeader = "X-Request-ID"
+
+// ErrInvalidFormat returned when the error response format is unknown.
+var ErrInvalidFormat = errors.New("invalid error format")
+
+// Format represents format of error responses.
+type Format string
+
+const (
+	// FormatJSON writes the error envelope as application/json.
+	FormatJSON Format = "json"
+
+	// FormatProblem writes RFC 7807 problem details as application/problem+json, with the envelope fields as extensions.
+	FormatProblem Format = "problem"
+)
+
+// Validate validates the error response format.
+func (f Format) Validate() error {
+	switch f {
+	case FormatJSON, FormatProblem:
+		return nil
+	default:
+		return fmt.Errorf("%w: %s", ErrInvalidFormat, f)
+	}
+}
+
+// format is format of error responses written by Write, set once by the server configuration.
+var format atomic.Value
+
+// SetFormat sets format of error responses written by Write.
+func SetFormat(f Format) {
+	format.Store(f)
+}
+
+// currentFormat returns format of error responses written by Write, JSON if unset.
+func currentFormat() Format {
+	if f, ok := format.Load().(Format); ok {
+		return f
+	}
+
+	return FormatJSON
+}
+
+// requestIDHeader is response header of the request ID copied to error responses, set once by the server configuration.
+var requestIDHeader atomic.Value
+
+// SetRequestIDHeader sets response header of the request ID copied to error responses.
+func SetRequestIDHeader(name string) {
+	requestIDHeader.Store(name)
+}
+
+// RequestIDHeaderName returns response header of the request ID copied to error responses, RequestIDHeader if unset.
+func RequestIDHeaderName() string {
+	if name, ok := requestIDHeader.Load().(string); ok && name != "" {
+		return name
+	}
+
+	return RequestIDHeader
+}
+
+// Code represents a machine readable error code.
+type Code string
+
+const (
+	// CodeInvalidRequest is returned when the request is malformed or fails validation.
+	CodeInvalidRequest Code = "invalid_request"
+
+	// CodeUnauthorized is returned when the request is not authenticated.
+	CodeUnauthorized Code = "unauthorized"
+
+	// CodePaymentRequired is returned when the caller's subscription does not include the endpoint.
+	CodePaymentRequired Code = "payment_required"
+
+	// CodeForbidden is returned when the caller is not allowed to perform the request.
+	CodeForbidden Code = "forbidden"
+
+	// CodeNotFound is returned when the requested resource does not exist.
+	CodeNotFound Code = "not_found"
+
+	// CodeConflict is returned when the request conflicts with an existing resource.
+	CodeConflict Code = "conflict"
+
+	// CodeRequestTooLarge is returned when the request body exceeds the size limit.
+	CodeRequestTooLarge Code = "request_too_large"
+
+	// CodeRateLimited is returned when the request exceeds a rate limit.
+	CodeRateLimited Code = "rate_limited"
+
+	// CodeInternal is returned when the server fails to handle the request.
+	CodeInternal Code = "internal_error"
+
+	// CodeUnavailable is returned when a dependency of the server is unavailable.
+	CodeUnavailable Code = "service_unavailable"
+
+	// CodeReadOnly is returned when a mutating request is made while the service is in read-only mode.
+	CodeReadOnly Code = "read_only"
+
+	// CodeTimeout is returned when the server does not handle the request within the timeout.
+	CodeTimeout Code = "timeout"
+)
+
+// Response represents the JSON error envelope.
+type Response struct {
+	// Error is human readable error message.
+	Error string `json:"error"`
+
+	// Code is machine readable error code.
+	Code Code `json:"code,omitempty"`
+
+	// RequestID is ID of the request, for reporting the error.
+	RequestID string `json:"request_id,omitempty"`
+
+	// Details is additional metadata of the error.
+	Details interface{} `json:"details,omitempty"`
+
+	// Debug is debugging information of the error, only set when verbose errors are enabled.
+	Debug *Debug `json:"debug,omitempty"`
+}
+
+// Problem represents RFC 7807 problem details with the envelope fields as extension members.
+type Problem struct {
+	// Type is URI reference of the problem type, about:blank as the code identifies it.
+	Type string `json:"type"`
+
+	// Title is summary of the problem type, the status text.
+	Title string `json:"title"`
+
+	// Status is HTTP status code.
+	Status int `json:"status"`
+
+	// Detail is human readable error message.
+	Detail string `json:"detail,omitempty"`
+
+	// Code is machine readable error code.
+	Code Code `json:"code,omitempty"`
+
+	// RequestID is ID of the request, for reporting the error.
+	RequestID string `json:"request_id,omitempty"`
+
+	// Details is additional metadata of the error.
+	Details interface{} `json:"details,omitempty"`
+
+	// Debug is debugging information of the error, only set when verbose errors are enabled.
+	Debug *Debug `json:"debug,omitempty"`
+}
+
+// Debug represents debugging information of an error.
+type Debug struct {
+	// Cause is the underlying error or panic value.
+	Cause string `json:"cause,omitempty"`
+
+	// Causes is messages of errors wrapped by the cause, outermost first.
+	Causes []string `json:"causes,omitempty"`
+
+	// Query is sanitized SQL statement of the failed query, if the cause is a query error.
+	Query string `json:"query,omitempty"`
+
+	// Stack is stack trace where the error occurred, one frame per line.
+	Stack []string `json:"stack,omitempty"`
+}
+
+// NewDebug creates debugging information of the error with its cause chain,
+// the failed query and the stack trace of the caller.
+func NewDebug(err error) *Debug {
+	info := &Debug{
+		Stack: StackFrames(debug.Stack()),
+	}
+
+	if err == nil {
+		return info
+	}
+
+	info.Cause = err.Error()
+
+	// skip wrappers not adding context to the message
+	message := info.Cause
+
+	for cause := errors.Unwrap(err); cause != nil; cause = errors.Unwrap(cause) {
+		if cause.Error() != message {
+			message = cause.Error()
+			info.Causes = append(info.Causes, message)
+		}
+	}
+
+	// query errors expose their statement without literal values
+	var queryErr interface{ SanitizedQuery() string }
+	if errors.As(err, &queryErr) {
+		info.Query = queryErr.SanitizedQuery()
+	}
+
+	return info
+}
+
+// StackFrames splits the stack trace into trimmed lines.
+func StackFrames(stack []byte) []string {
+	lines := strings.Split(string(stack), "\n")
+	frames := make([]string, 0, len(lines))
+
+	for _, line := range lines {
+		if line = strings.TrimSpace(line); line != "" {
+			frames = append(frames, line)
+		}
+	}
+
+	return frames
+}
+
+// Write writes the error envelope with the status code in the configured format,
+// filling the code of the status and the request ID of the response if missing.
+func Write(writer http.ResponseWriter, status int, response *Response) error {
+	return WriteFormat(writer, status, response, currentFormat())
+}
+
+// WriteFormat writes the error envelope with the status code in the format.
+func WriteFormat(writer http.ResponseWriter, status int, response *Response, format Format) error {
+	envelope := *response
+
+	if envelope.Code == "" {
+		envelope.Code = CodeForStatus(status)
+	}
+
+	if envelope.RequestID == "" {
+		envelope.RequestID = writer.Header().Get(RequestIDHeaderName())
+	}
+
+	var body interface{} = &envelope
+
+	contentType := "application/json"
+
+	if format == FormatProblem {
+		contentType = "application/problem+json"
+		body = &Problem{
+			Type:      "about:blank",
+			Title:     http.StatusText(status),
+			Status:    status,
+			Detail:    envelope.Error,
+			Code:      envelope.Code,
+			RequestID: envelope.RequestID,
+			Details:   envelope.Details,
+			Debug:     envelope.Debug,
+		}
+	}
+
+	writer.Header().Set("Content-Type", contentType)
+	writer.Header().Set("X-Content-Type-Options", "nosniff")
+	writer.WriteHeader(status)
+
+	if err := json.NewEncoder(writer).Encode(body); err != nil {
+		return fmt.Errorf("failed to encode error response: %w", err)
+	}
+
+	return nil
+}
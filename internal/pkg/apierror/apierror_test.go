@@ -0,0 +1,175 @@
+package apierror
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	// errTimeout is the test error of a timed out operation.
+	errTimeout = errors.New("i/o timeout")
+
+	// errQueryFailed is the test error of a failed query.
+	errQueryFailed = &queryError{query: "SELECT ?", err: errTimeout}
+)
+
+// queryError is the test error exposing sanitized query.
+type queryError struct {
+	query string
+	err   error
+}
+
+func (e *queryError) Error() string          { return e.err.Error() }
+func (e *queryError) Unwrap() error          { return e.err }
+func (e *queryError) SanitizedQuery() string { return e.query }
+
+func TestWrite(t *testing.T) {
+	t.Parallel()
+
+	t.Run("write error envelope with details", func(t *testing.T) {
+		t.Parallel()
+
+		recorder := httptest.NewRecorder()
+
+		err := Write(recorder, http.StatusTooManyRequests, &Response{
+			Error:   "rate limit exceeded",
+			Code:    CodeRateLimited,
+			Details: map[string]int{"limit": 10},
+		})
+		require.NoError(t, err)
+
+		assert.Equal(t, http.StatusTooManyRequests, recorder.Code)
+		assert.Equal(t, "application/json", recorder.Header().Get("Content-Type"))
+		assert.JSONEq(t, `{"error":"rate limit exceeded","code":"rate_limited","details":{"limit":10}}`, recorder.Body.String())
+	})
+
+	t.Run("fill code of the status and request ID", func(t *testing.T) {
+		t.Parallel()
+
+		recorder := httptest.NewRecorder()
+		recorder.Header().Set(RequestIDHeader, "host/abc-000001")
+
+		require.NoError(t, Write(recorder, http.StatusConflict, &Response{Error: "email already taken"}))
+
+		var body map[string]interface{}
+		require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &body))
+		assert.Equal(t, map[string]interface{}{
+			"error":      "email already taken",
+			"code":       "conflict",
+			"request_id": "host/abc-000001",
+		}, body)
+	})
+
+	t.Run("write problem details", func(t *testing.T) {
+		t.Parallel()
+
+		recorder := httptest.NewRecorder()
+
+		err := WriteFormat(recorder, http.StatusTooManyRequests, &Response{
+			Error:   "rate limit exceeded",
+			Code:    CodeRateLimited,
+			Details: map[string]int{"limit": 10},
+		}, FormatProblem)
+		require.NoError(t, err)
+
+		assert.Equal(t, "application/problem+json", recorder.Header().Get("Content-Type"))
+		assert.JSONEq(t, `{
+			"type": "about:blank",
+			"title": "Too Many Requests",
+			"status": 429,
+			"detail": "rate limit exceeded",
+			"code": "rate_limited",
+			"details": {"limit": 10}
+		}`, recorder.Body.String())
+	})
+}
+
+//nolint:paralleltest // sequential execution required to modify the request ID header
+func TestRequestIDHeaderName(t *testing.T) {
+	t.Cleanup(func() {
+		SetRequestIDHeader(RequestIDHeader)
+	})
+
+	assert.Equal(t, RequestIDHeader, RequestIDHeaderName())
+
+	SetRequestIDHeader("X-Correlation-ID")
+	assert.Equal(t, "X-Correlation-ID", RequestIDHeaderName())
+
+	recorder := httptest.NewRecorder()
+	recorder.Header().Set("X-Correlation-ID", "host/abc-000001")
+
+	require.NoError(t, Write(recorder, http.StatusNotFound, &Response{Error: "not found"}))
+	assert.JSONEq(t, `{"error":"not found","code":"not_found","request_id":"host/abc-000001"}`, recorder.Body.String())
+}
+
+func TestFormatValidate(t *testing.T) {
+	t.Parallel()
+
+	require.NoError(t, FormatJSON.Validate())
+	require.NoError(t, FormatProblem.Validate())
+	require.ErrorIs(t, Format("xml").Validate(), ErrInvalidFormat)
+}
+
+func TestCodeForStatus(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, CodeNotFound, CodeForStatus(http.StatusNotFound))
+	assert.Equal(t, CodeUnavailable, CodeForStatus(http.StatusServiceUnavailable))
+	assert.Equal(t, CodeInvalidRequest, CodeForStatus(http.StatusMethodNotAllowed))
+	assert.Equal(t, CodeInternal, CodeForStatus(http.StatusBadGateway))
+}
+
+func TestNewDebug(t *testing.T) {
+	t.Parallel()
+
+	t.Run("collect cause chain and stack trace", func(t *testing.T) {
+		t.Parallel()
+
+		err := fmt.Errorf("failed to load user: %w", fmt.Errorf("failed to connect: %w", errTimeout))
+
+		debug := NewDebug(err)
+
+		assert.Equal(t, "failed to load user: failed to connect: i/o timeout", debug.Cause)
+		assert.Equal(t, []string{"failed to connect: i/o timeout", "i/o timeout"}, debug.Causes)
+		assert.Empty(t, debug.Query)
+		require.NotEmpty(t, debug.Stack)
+		assert.Contains(t, debug.Stack[0], "goroutine")
+	})
+
+	t.Run("include sanitized query of query error", func(t *testing.T) {
+		t.Parallel()
+
+		debug := NewDebug(fmt.Errorf("failed to list settings: %w", errQueryFailed))
+
+		assert.Equal(t, "SELECT ?", debug.Query)
+		assert.Equal(t, []string{"i/o timeout"}, debug.Causes)
+	})
+
+	t.Run("collect stack trace without error", func(t *testing.T) {
+		t.Parallel()
+
+		debug := NewDebug(nil)
+
+		assert.Empty(t, debug.Cause)
+		assert.NotEmpty(t, debug.Stack)
+	})
+}
+
+func TestStackFrames(t *testing.T) {
+	t.Parallel()
+
+	t.Run("split stack trace into trimmed lines", func(t *testing.T) {
+		t.Parallel()
+
+		frames := StackFrames([]byte("goroutine 1 [running]:\nmain.main()\n\t/app/main.go:10 +0x1d\n"))
+
+		assert.Equal(t, []string{"goroutine 1 [running]:", "main.main()", "/app/main.go:10 +0x1d"}, frames)
+	})
+}
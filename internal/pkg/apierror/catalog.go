@@ -0,0 +1,148 @@
+package apierror
+
+import (
+	"net/http"
+)
+
+// Definition describes an error code of the catalog.
+type Definition struct {
+	// Code is machine readable error code.
+	Code Code `json:"code"`
+
+	// Status is HTTP status code the error is returned with.
+	Status int `json:"status"`
+
+	// Description is description of when the error is returned.
+	Description string `json:"description"`
+
+	// Example is example error envelope of the code.
+	Example *Response `json:"example"`
+}
+
+// catalog is definitions of all error codes, ordered by status.
+var catalog = []Definition{
+	{
+		Code:        CodeInvalidRequest,
+		Status:      http.StatusBadRequest,
+		Description: "The request is malformed or fails validation.",
+		Example:     &Response{Error: "invalid request body", Code: CodeInvalidRequest},
+	},
+	{
+		Code:        CodeUnauthorized,
+		Status:      http.StatusUnauthorized,
+		Description: "The request has no valid bearer token.",
+		Example:     &Response{Error: "Unauthorized", Code: CodeUnauthorized},
+	},
+	{
+		Code:        CodePaymentRequired,
+		Status:      http.StatusPaymentRequired,
+		Description: "The caller's subscription does not include the entitlement required by the endpoint.",
+		Example: &Response{
+			Error:   "Payment Required",
+			Code:    CodePaymentRequired,
+			Details: map[string]interface{}{"entitlement": "reports"},
+		},
+	},
+	{
+		Code:        CodeForbidden,
+		Status:      http.StatusForbidden,
+		Description: "The caller lacks the role or scope required by the endpoint.",
+		Example:     &Response{Error: "Forbidden", Code: CodeForbidden},
+	},
+	{
+		Code:        CodeNotFound,
+		Status:      http.StatusNotFound,
+		Description: "The requested resource does not exist.",
+		Example:     &Response{Error: "setting not found", Code: CodeNotFound},
+	},
+	{
+		Code:        CodeConflict,
+		Status:      http.StatusConflict,
+		Description: "The request conflicts with an existing resource.",
+		Example:     &Response{Error: "email already taken", Code: CodeConflict},
+	},
+	{
+		Code:        CodeRequestTooLarge,
+		Status:      http.StatusRequestEntityTooLarge,
+		Description: "The request body exceeds the configured size limit.",
+		Example: &Response{
+			Error:   "Request body too large",
+			Code:    CodeRequestTooLarge,
+			Details: map[string]interface{}{"limit": 10485760},
+		},
+	},
+	{
+		Code:        CodeRateLimited,
+		Status:      http.StatusTooManyRequests,
+		Description: "The request exceeds a rate limit, retry after the Retry-After header.",
+		Example: &Response{
+			Error: "Rate limit exceeded",
+			Code:  CodeRateLimited,
+			Details: map[string]interface{}{
+				"limit":     60,
+				"remaining": 0,
+				"reset":     1704067260,
+				"type":      "ip",
+			},
+		},
+	},
+	{
+		Code:        CodeInternal,
+		Status:      http.StatusInternalServerError,
+		Description: "The server failed to handle the request.",
+		Example:     &Response{Error: "Internal Server Error", Code: CodeInternal},
+	},
+	{
+		Code:        CodeUnavailable,
+		Status:      http.StatusServiceUnavailable,
+		Description: "A dependency of the server is unavailable, retry later.",
+		Example:     &Response{Error: "Service Unavailable", Code: CodeUnavailable},
+	},
+	{
+		Code:        CodeReadOnly,
+		Status:      http.StatusServiceUnavailable,
+		Description: "The service is in read-only mode for maintenance, reads still succeed, retry writes later.",
+		Example:     &Response{Error: "Service is in read-only mode for maintenance", Code: CodeReadOnly},
+	},
+	{
+		Code:        CodeTimeout,
+		Status:      http.StatusGatewayTimeout,
+		Description: "The server did not handle the request within the timeout, retry later.",
+		Example:     &Response{Error: "Request timed out", Code: CodeTimeout},
+	},
+}
+
+// Catalog returns definitions of all error codes, ordered by status.
+func Catalog() []Definition {
+	definitions := make([]Definition, len(catalog))
+	copy(definitions, catalog)
+
+	return definitions
+}
+
+// CodeForStatus returns the code of the first definition with the status,
+// the internal or invalid request code by status class if none.
+func CodeForStatus(status int) Code {
+	for _, definition := range catalog {
+		if definition.Status == status {
+			return definition.Code
+		}
+	}
+
+	if status >= http.StatusInternalServerError {
+		return CodeInternal
+	}
+
+	return CodeInvalidRequest
+}
+
+// Lookup returns the definition of the code.
+func Lookup(code Code) (Definition, bool) {
+	for _, definition := range catalog {
+		if definition.Code == code {
+			return definition, true
+		}
+	}
+
+	return Definition{}, false
+}
@@ -0,0 +1,124 @@
+package apierror
+
+import (
+	"encoding/json"
+	"net/http"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+)
+
+// specDir is directory of the OpenAPI spec.
+const specDir = "../../../api"
+
+func TestCatalog(t *testing.T) {
+	t.Parallel()
+
+	t.Run("define each code once with example", func(t *testing.T) {
+		t.Parallel()
+
+		seen := make(map[Code]bool)
+
+		for _, definition := range Catalog() {
+			assert.False(t, seen[definition.Code], "duplicate code %s", definition.Code)
+			seen[definition.Code] = true
+
+			assert.NotEmpty(t, http.StatusText(definition.Status), definition.Code)
+			assert.NotEmpty(t, definition.Description, definition.Code)
+			require.NotNil(t, definition.Example, definition.Code)
+			assert.Equal(t, definition.Code, definition.Example.Code)
+		}
+
+		assert.True(t, seen[CodeRateLimited])
+	})
+
+	t.Run("order definitions by status", func(t *testing.T) {
+		t.Parallel()
+
+		definitions := Catalog()
+		for i := 1; i < len(definitions); i++ {
+			assert.LessOrEqual(t, definitions[i-1].Status, definitions[i].Status)
+		}
+	})
+
+	t.Run("return a copy", func(t *testing.T) {
+		t.Parallel()
+
+		definitions := Catalog()
+		definitions[0].Status = 0
+
+		assert.NotZero(t, Catalog()[0].Status)
+	})
+}
+
+func TestLookup(t *testing.T) {
+	t.Parallel()
+
+	definition, ok := Lookup(CodeRateLimited)
+	require.True(t, ok)
+	assert.Equal(t, http.StatusTooManyRequests, definition.Status)
+
+	_, ok = Lookup("unknown")
+	assert.False(t, ok)
+}
+
+func TestCatalogMatchesSpec(t *testing.T) {
+	t.Parallel()
+
+	// the spec embeds the examples file as its example components
+	var spec struct {
+		Components struct {
+			Examples struct {
+				Ref string `yaml:"$ref"`
+			} `yaml:"examples"`
+		} `yaml:"components"`
+	}
+
+	readYAML(t, specDir+"/server.yaml", &spec)
+	require.Equal(t, "./errors.yaml", spec.Components.Examples.Ref)
+
+	var examples map[string]struct {
+		Value map[string]interface{} `yaml:"value"`
+	}
+
+	readYAML(t, specDir+"/errors.yaml", &examples)
+
+	// index examples of the spec by code
+	values := make(map[Code]map[string]interface{})
+
+	for name, example := range examples {
+		code, _ := example.Value["code"].(string)
+		require.NotEmpty(t, code, name)
+
+		values[Code(code)] = example.Value
+	}
+
+	assert.Len(t, values, len(Catalog()))
+
+	for _, definition := range Catalog() {
+		value, ok := values[definition.Code]
+		if !assert.True(t, ok, "missing example of %s in %s/errors.yaml", definition.Code, specDir) {
+			continue
+		}
+
+		expected, err := json.Marshal(definition.Example)
+		require.NoError(t, err)
+
+		actual, err := json.Marshal(value)
+		require.NoError(t, err)
+
+		assert.JSONEq(t, string(expected), string(actual), definition.Code)
+	}
+}
+
+// readYAML decodes the YAML file into target.
+func readYAML(t *testing.T, path string, target interface{}) {
+	t.Helper()
+
+	content, err := os.ReadFile(path) //nolint:gosec // path is a spec file of the repository
+	require.NoError(t, err)
+	require.NoError(t, yaml.Unmarshal(content, target))
+}
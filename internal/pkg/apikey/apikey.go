@@ -0,0 +1,408 @@
+// Package apikey provides API keys of machine clients stored on database and cached on redis.
+package apikey
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	goredis "github.com/redis/go-redis/v9"
+	"go.uber.org/fx"
+
+	"github.com/pocj8ur4in/boilerplate-go/internal/gen/db"
+	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/database"
+	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/querycache"
+	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/redis"
+)
+
+var (
+	// ErrInvalidKey returned when the API key does not exist, is revoked or expired.
+	ErrInvalidKey = errors.New("invalid api key")
+
+	// ErrNotFound returned when the API key does not exist.
+	ErrNotFound = errors.New("api key not found")
+
+	// ErrInvalidName returned when the API key name is empty or too long.
+	ErrInvalidName = errors.New("invalid api key name")
+
+	// ErrInvalidRateLimit returned when the rate limit of API key is not positive.
+	ErrInvalidRateLimit = errors.New("invalid api key rate limit")
+)
+
+const (
+	// Prefix is prefix of API keys, making them recognizable by secret scanners.
+	Prefix = "bpk_"
+
+	// idLength is number of random bytes of API key IDs.
+	idLength = 8
+
+	// secretLength is number of random bytes of API key secrets.
+	secretLength = 32
+
+	// maxNameLength is maximum length of API key names.
+	maxNameLength = 128
+
+	// cacheKeyPrefix is the redis key prefix of cached API keys.
+	cacheKeyPrefix = "api_key:"
+
+	// defaultCacheTTL is default TTL of API keys cached on redis.
+	defaultCacheTTL = time.Minute
+)
+
+// Config represents configuration for API keys.
+type Config struct {
+	// CacheTTL is TTL of API keys cached on redis.
+	CacheTTL *time.Duration `json:"cache_ttl"`
+}
+
+// SetDefault sets default values.
+func (c *Config) SetDefault() {
+	if c.CacheTTL == nil {
+		cacheTTL := defaultCacheTTL
+		c.CacheTTL = &cacheTTL
+	}
+}
+
+// RateLimit represents a rate limit overriding the default limit of API keys.
+type RateLimit struct {
+	// Requests is the maximum number of requests allowed.
+	Requests int `json:"requests"`
+
+	// Window is the time window for rate limiting.
+	Window time.Duration `json:"window"`
+}
+
+// Key represents an API key, without its secret.
+type Key struct {
+	// ID is public identifier of the API key.
+	ID string `json:"id"`
+
+	// UserID is ID of the user owning the API key.
+	UserID string `json:"user_id"`
+
+	// TenantID is ID of the tenant of the user when the API key was created, empty if none.
+	TenantID string `json:"tenant_id,omitempty"`
+
+	// Name is name of the API key given by the user.
+	Name string `json:"name"`
+
+	// Role is role granted to the API key.
+	Role string `json:"role"`
+
+	// Scopes is scopes granted to the API key.
+	Scopes []string `json:"scopes"`
+
+	// RateLimit is rate limit of the API key, nil to use the default limit.
+	RateLimit *RateLimit `json:"rate_limit,omitempty"`
+
+	// ExpiresAt is time the API key expires, nil if it never expires.
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+
+	// RevokedAt is time the API key was revoked, nil if it is active.
+	RevokedAt *time.Time `json:"revoked_at,omitempty"`
+
+	// CreatedAt is time the API key was created.
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Valid returns whether the API key is neither revoked nor expired at the time.
+func (k *Key) Valid(now time.Time) bool {
+	return k.RevokedAt == nil && (k.ExpiresAt == nil || now.Before(*k.ExpiresAt))
+}
+
+// CreateParams represents parameters of API key creation.
+type CreateParams struct {
+	// UserID is ID of the user owning the API key.
+	UserID string
+
+	// TenantID is ID of the tenant of the user, empty if none.
+	TenantID string
+
+	// Name is name of the API key.
+	Name string
+
+	// Role is role granted to the API key.
+	Role string
+
+	// Scopes is scopes granted to the API key.
+	Scopes []string
+
+	// ExpiresAt is time the API key expires, nil if it never expires.
+	ExpiresAt *time.Time
+}
+
+// Generate generates an API key with its ID, the key is formatted as "<prefix><id>_<secret>".
+func Generate() (string, string, error) {
+	id := make([]byte, idLength)
+	if _, err := rand.Read(id); err != nil {
+		return "", "", fmt.Errorf("failed to generate api key id: %w", err)
+	}
+
+	secret := make([]byte, secretLength)
+	if _, err := rand.Read(secret); err != nil {
+		return "", "", fmt.Errorf("failed to generate api key secret: %w", err)
+	}
+
+	keyID := hex.EncodeToString(id)
+
+	return keyID, Prefix + keyID + "_" + base64.RawURLEncoding.EncodeToString(secret), nil
+}
+
+// Hash returns hash of the API key stored instead of the key,
+// a fast hash is enough since keys are random with high entropy.
+func Hash(key string) string {
+	sum := sha256.Sum256([]byte(key))
+
+	return hex.EncodeToString(sum[:])
+}
+
+// Store provides API keys stored on database and cached on redis.
+type Store struct {
+	// queries provides database queries.
+	queries db.Querier
+
+	// redis provides redis client.
+	redis *redis.Redis
+
+	// cacheTTL is the TTL of cached API keys.
+	cacheTTL time.Duration
+}
+
+// NewModule provides module for API keys.
+func NewModule() fx.Option {
+	return fx.Module("apikey",
+		fx.Provide(New),
+	)
+}
+
+// New creates a new API key store on database, listing keys through the query cache.
+func New(config *Config, dbConn *database.DB, redis *redis.Redis, queryCache *querycache.QueryCache) *Store {
+	return NewWithQuerier(config, querycache.NewQuerier(dbConn.Queries, queryCache), redis)
+}
+
+// NewWithQuerier creates a new API key store using the querier.
+func NewWithQuerier(config *Config, queries db.Querier, redis *redis.Redis) *Store {
+	if config == nil {
+		config = &Config{}
+	}
+
+	config.SetDefault()
+
+	return &Store{
+		queries:  queries,
+		redis:    redis,
+		cacheTTL: *config.CacheTTL,
+	}
+}
+
+// Create creates an API key and returns it with the key, which is only available on creation.
+func (s *Store) Create(ctx context.Context, params *CreateParams) (*Key, string, error) {
+	if name := strings.TrimSpace(params.Name); name == "" || len(name) > maxNameLength {
+		return nil, "", ErrInvalidName
+	}
+
+	id, key, err := Generate()
+	if err != nil {
+		return nil, "", err
+	}
+
+	var expiresAt pgtype.Timestamptz
+	if params.ExpiresAt != nil {
+		expiresAt = pgtype.Timestamptz{Time: *params.ExpiresAt, Valid: true}
+	}
+
+	scopes := params.Scopes
+	if scopes == nil {
+		scopes = []string{}
+	}
+
+	row, err := s.queries.CreateAPIKey(ctx, &db.CreateAPIKeyParams{
+		ID:        id,
+		UserID:    params.UserID,
+		TenantID:  params.TenantID,
+		Name:      strings.TrimSpace(params.Name),
+		KeyHash:   Hash(key),
+		Role:      params.Role,
+		Scopes:    slices.Clone(scopes),
+		ExpiresAt: expiresAt,
+	})
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to create api key: %w", err)
+	}
+
+	return fromRow(row), key, nil
+}
+
+// Authenticate returns the API key of the key, ErrInvalidKey if it does not exist, is revoked or expired.
+func (s *Store) Authenticate(ctx context.Context, key string) (*Key, error) {
+	if !strings.HasPrefix(key, Prefix) {
+		return nil, ErrInvalidKey
+	}
+
+	found, err := s.get(ctx, Hash(key))
+	if err != nil {
+		return nil, err
+	}
+
+	if found == nil || !found.Valid(time.Now()) {
+		return nil, ErrInvalidKey
+	}
+
+	return found, nil
+}
+
+// get returns the API key of the hash from cache or database, nil if it does not exist.
+func (s *Store) get(ctx context.Context, hash string) (*Key, error) {
+	cacheKey := cacheKeyPrefix + hash
+
+	// check cache first, a cached null means the key does not exist
+	cached, err := s.redis.Get(ctx, cacheKey).Bytes()
+	if err == nil {
+		var key *Key
+		if err := json.Unmarshal(cached, &key); err == nil {
+			return key, nil
+		}
+	} else if !errors.Is(err, goredis.Nil) {
+		return nil, fmt.Errorf("failed to get cached api key: %w", err)
+	}
+
+	// load from database
+	var key *Key
+
+	row, err := s.queries.GetAPIKeyByHash(ctx, hash)
+
+	switch {
+	case err == nil:
+		key = fromRow(row)
+	case errors.Is(err, pgx.ErrNoRows):
+		key = nil
+	default:
+		return nil, fmt.Errorf("failed to get api key: %w", err)
+	}
+
+	data, err := json.Marshal(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal api key: %w", err)
+	}
+
+	if err := s.redis.Set(ctx, cacheKey, data, s.cacheTTL).Err(); err != nil {
+		return nil, fmt.Errorf("failed to cache api key: %w", err)
+	}
+
+	return key, nil
+}
+
+// List returns API keys of the user, including revoked and expired keys.
+func (s *Store) List(ctx context.Context, userID string) ([]*Key, error) {
+	rows, err := s.queries.ListAPIKeys(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list api keys: %w", err)
+	}
+
+	keys := make([]*Key, 0, len(rows))
+	for _, row := range rows {
+		keys = append(keys, fromRow(row))
+	}
+
+	return keys, nil
+}
+
+// Revoke revokes the active API key of the user, ErrNotFound if there is no such key.
+func (s *Store) Revoke(ctx context.Context, userID, id string) error {
+	row, err := s.queries.RevokeAPIKey(ctx, &db.RevokeAPIKeyParams{ID: id, UserID: userID})
+
+	switch {
+	case errors.Is(err, pgx.ErrNoRows):
+		return ErrNotFound
+	case err != nil:
+		return fmt.Errorf("failed to revoke api key: %w", err)
+	}
+
+	return s.invalidate(ctx, row.KeyHash)
+}
+
+// SetRateLimit sets rate limit of the API key, nil to use the default limit.
+func (s *Store) SetRateLimit(ctx context.Context, id string, limit *RateLimit) (*Key, error) {
+	params := &db.SetAPIKeyRateLimitParams{ID: id}
+
+	if limit != nil {
+		requests, window := limit.Requests, int(limit.Window/time.Second)
+		if requests <= 0 || window <= 0 || requests > math.MaxInt32 || window > math.MaxInt32 {
+			return nil, ErrInvalidRateLimit
+		}
+
+		// #nosec G115 -- validated above
+		params.RateLimitRequests = &[]int32{int32(requests)}[0]
+		// #nosec G115 -- validated above
+		params.RateLimitWindowSeconds = &[]int32{int32(window)}[0]
+	}
+
+	row, err := s.queries.SetAPIKeyRateLimit(ctx, params)
+
+	switch {
+	case errors.Is(err, pgx.ErrNoRows):
+		return nil, ErrNotFound
+	case err != nil:
+		return nil, fmt.Errorf("failed to set api key rate limit: %w", err)
+	}
+
+	if err := s.invalidate(ctx, row.KeyHash); err != nil {
+		return nil, err
+	}
+
+	return fromRow(row), nil
+}
+
+// invalidate removes the cached API key of the hash.
+func (s *Store) invalidate(ctx context.Context, hash string) error {
+	if err := s.redis.Del(ctx, cacheKeyPrefix+hash).Err(); err != nil {
+		return fmt.Errorf("failed to invalidate api key: %w", err)
+	}
+
+	return nil
+}
+
+// fromRow converts the database row to an API key.
+func fromRow(row *db.ApiKey) *Key {
+	key := &Key{
+		ID:        row.ID,
+		UserID:    row.UserID,
+		TenantID:  row.TenantID,
+		Name:      row.Name,
+		Role:      row.Role,
+		Scopes:    row.Scopes,
+		CreatedAt: row.CreatedAt.Time,
+	}
+
+	if key.Scopes == nil {
+		key.Scopes = []string{}
+	}
+
+	if row.RateLimitRequests != nil && row.RateLimitWindowSeconds != nil {
+		key.RateLimit = &RateLimit{
+			Requests: int(*row.RateLimitRequests),
+			Window:   time.Duration(*row.RateLimitWindowSeconds) * time.Second,
+		}
+	}
+
+	if row.ExpiresAt.Valid {
+		key.ExpiresAt = &row.ExpiresAt.Time
+	}
+
+	if row.RevokedAt.Valid {
+		key.RevokedAt = &row.RevokedAt.Time
+	}
+
+	return key
+}
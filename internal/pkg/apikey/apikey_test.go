@@ -0,0 +1,327 @@
+package apikey
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pocj8ur4in/boilerplate-go/internal/gen/db"
+	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/redis"
+)
+
+// errQueryFailed is the test error of a failed query.
+var errQueryFailed = errors.New("query failed")
+
+// mockQuerier is a mock querier storing API keys in memory.
+type mockQuerier struct {
+	db.Querier
+
+	keys  []*db.ApiKey
+	calls int
+	err   error
+}
+
+func (m *mockQuerier) CreateAPIKey(_ context.Context, arg *db.CreateAPIKeyParams) (*db.ApiKey, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+
+	key := &db.ApiKey{
+		ID:        arg.ID,
+		UserID:    arg.UserID,
+		TenantID:  arg.TenantID,
+		Name:      arg.Name,
+		KeyHash:   arg.KeyHash,
+		Role:      arg.Role,
+		Scopes:    arg.Scopes,
+		ExpiresAt: arg.ExpiresAt,
+		CreatedAt: pgtype.Timestamptz{Time: time.Now(), Valid: true},
+	}
+	m.keys = append(m.keys, key)
+
+	return key, nil
+}
+
+func (m *mockQuerier) GetAPIKeyByHash(_ context.Context, keyHash string) (*db.ApiKey, error) {
+	m.calls++
+
+	if m.err != nil {
+		return nil, m.err
+	}
+
+	for _, key := range m.keys {
+		if key.KeyHash == keyHash {
+			return key, nil
+		}
+	}
+
+	return nil, pgx.ErrNoRows
+}
+
+func (m *mockQuerier) ListAPIKeys(_ context.Context, userID string) ([]*db.ApiKey, error) {
+	keys := []*db.ApiKey{}
+
+	for _, key := range m.keys {
+		if key.UserID == userID {
+			keys = append(keys, key)
+		}
+	}
+
+	return keys, nil
+}
+
+func (m *mockQuerier) RevokeAPIKey(_ context.Context, arg *db.RevokeAPIKeyParams) (*db.ApiKey, error) {
+	for _, key := range m.keys {
+		if key.ID == arg.ID && key.UserID == arg.UserID && !key.RevokedAt.Valid {
+			key.RevokedAt = pgtype.Timestamptz{Time: time.Now(), Valid: true}
+
+			return key, nil
+		}
+	}
+
+	return nil, pgx.ErrNoRows
+}
+
+func (m *mockQuerier) SetAPIKeyRateLimit(_ context.Context, arg *db.SetAPIKeyRateLimitParams) (*db.ApiKey, error) {
+	for _, key := range m.keys {
+		if key.ID == arg.ID {
+			key.RateLimitRequests = arg.RateLimitRequests
+			key.RateLimitWindowSeconds = arg.RateLimitWindowSeconds
+
+			return key, nil
+		}
+	}
+
+	return nil, pgx.ErrNoRows
+}
+
+// setupTestRedis creates a redis client on a flushed database.
+func setupTestRedis(t *testing.T) *redis.Redis {
+	t.Helper()
+
+	password := ""
+	redisDB := 0
+
+	redisClient, err := redis.New(&redis.Config{
+		Addrs:    []string{"localhost:36379"},
+		Password: &password,
+		DB:       &redisDB,
+	})
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	require.NoError(t, redisClient.FlushDB(ctx).Err())
+
+	t.Cleanup(func() {
+		_ = redisClient.Close()
+	})
+
+	return redisClient
+}
+
+func TestConfig(t *testing.T) {
+	t.Parallel()
+
+	t.Run("set default values", func(t *testing.T) {
+		t.Parallel()
+
+		config := &Config{}
+		config.SetDefault()
+
+		require.NotNil(t, config.CacheTTL)
+		assert.Equal(t, time.Minute, *config.CacheTTL)
+	})
+}
+
+func TestNewModule(t *testing.T) {
+	t.Parallel()
+
+	t.Run("return fx.Option", func(t *testing.T) {
+		t.Parallel()
+
+		require.NotNil(t, NewModule())
+	})
+}
+
+func TestGenerate(t *testing.T) {
+	t.Parallel()
+
+	t.Run("generate unique prefixed keys", func(t *testing.T) {
+		t.Parallel()
+
+		id, key, err := Generate()
+		require.NoError(t, err)
+
+		assert.Len(t, id, 2*idLength)
+		assert.True(t, strings.HasPrefix(key, Prefix+id+"_"))
+
+		_, other, err := Generate()
+		require.NoError(t, err)
+		assert.NotEqual(t, key, other)
+	})
+
+	t.Run("hash keys deterministically", func(t *testing.T) {
+		t.Parallel()
+
+		assert.Equal(t, Hash("bpk_test"), Hash("bpk_test"))
+		assert.NotEqual(t, Hash("bpk_test"), Hash("bpk_other"))
+		assert.Len(t, Hash("bpk_test"), 64)
+	})
+}
+
+//nolint:paralleltest // sequential execution required to avoid redis key conflicts
+func TestStore(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("authenticate created key from cache", func(t *testing.T) {
+		querier := &mockQuerier{}
+		store := NewWithQuerier(nil, querier, setupTestRedis(t))
+
+		created, key, err := store.Create(ctx, &CreateParams{
+			UserID:   "user123",
+			TenantID: "acme",
+			Name:     " ci ",
+			Role:     "user",
+			Scopes:   []string{"settings:read"},
+		})
+		require.NoError(t, err)
+		assert.Equal(t, "ci", created.Name)
+		assert.Equal(t, []string{"settings:read"}, created.Scopes)
+
+		for range 3 {
+			found, err := store.Authenticate(ctx, key)
+			require.NoError(t, err)
+			assert.Equal(t, created.ID, found.ID)
+			assert.Equal(t, "user123", found.UserID)
+			assert.Equal(t, "acme", found.TenantID)
+			assert.Equal(t, "user", found.Role)
+		}
+
+		assert.Equal(t, 1, querier.calls)
+	})
+
+	t.Run("reject unknown and malformed keys", func(t *testing.T) {
+		querier := &mockQuerier{}
+		store := NewWithQuerier(nil, querier, setupTestRedis(t))
+
+		_, err := store.Authenticate(ctx, "not-a-key")
+		require.ErrorIs(t, err, ErrInvalidKey)
+		assert.Equal(t, 0, querier.calls)
+
+		for range 2 {
+			_, err = store.Authenticate(ctx, Prefix+"unknown")
+			require.ErrorIs(t, err, ErrInvalidKey)
+		}
+
+		assert.Equal(t, 1, querier.calls)
+	})
+
+	t.Run("reject revoked key", func(t *testing.T) {
+		store := NewWithQuerier(nil, &mockQuerier{}, setupTestRedis(t))
+
+		created, key, err := store.Create(ctx, &CreateParams{UserID: "user123", Name: "ci"})
+		require.NoError(t, err)
+
+		_, err = store.Authenticate(ctx, key)
+		require.NoError(t, err)
+
+		require.ErrorIs(t, store.Revoke(ctx, "other", created.ID), ErrNotFound)
+		require.NoError(t, store.Revoke(ctx, "user123", created.ID))
+		require.ErrorIs(t, store.Revoke(ctx, "user123", created.ID), ErrNotFound)
+
+		_, err = store.Authenticate(ctx, key)
+		require.ErrorIs(t, err, ErrInvalidKey)
+	})
+
+	t.Run("reject expired key", func(t *testing.T) {
+		store := NewWithQuerier(nil, &mockQuerier{}, setupTestRedis(t))
+
+		expiresAt := time.Now().Add(-time.Minute)
+
+		_, key, err := store.Create(ctx, &CreateParams{UserID: "user123", Name: "ci", ExpiresAt: &expiresAt})
+		require.NoError(t, err)
+
+		_, err = store.Authenticate(ctx, key)
+		require.ErrorIs(t, err, ErrInvalidKey)
+	})
+
+	t.Run("reject invalid name", func(t *testing.T) {
+		store := NewWithQuerier(nil, &mockQuerier{}, setupTestRedis(t))
+
+		for _, name := range []string{"", "  ", strings.Repeat("a", maxNameLength+1)} {
+			_, _, err := store.Create(ctx, &CreateParams{UserID: "user123", Name: name})
+			require.ErrorIs(t, err, ErrInvalidName)
+		}
+	})
+
+	t.Run("list keys of user", func(t *testing.T) {
+		store := NewWithQuerier(nil, &mockQuerier{}, setupTestRedis(t))
+
+		for _, userID := range []string{"user123", "user123", "other"} {
+			_, _, err := store.Create(ctx, &CreateParams{UserID: userID, Name: "ci"})
+			require.NoError(t, err)
+		}
+
+		keys, err := store.List(ctx, "user123")
+		require.NoError(t, err)
+		assert.Len(t, keys, 2)
+	})
+
+	t.Run("set and clear rate limit override", func(t *testing.T) {
+		store := NewWithQuerier(nil, &mockQuerier{}, setupTestRedis(t))
+
+		created, key, err := store.Create(ctx, &CreateParams{UserID: "user123", Name: "ci"})
+		require.NoError(t, err)
+
+		// cache the key before the override
+		_, err = store.Authenticate(ctx, key)
+		require.NoError(t, err)
+
+		updated, err := store.SetRateLimit(ctx, created.ID, &RateLimit{Requests: 10, Window: time.Minute})
+		require.NoError(t, err)
+		assert.Equal(t, &RateLimit{Requests: 10, Window: time.Minute}, updated.RateLimit)
+
+		found, err := store.Authenticate(ctx, key)
+		require.NoError(t, err)
+		assert.Equal(t, &RateLimit{Requests: 10, Window: time.Minute}, found.RateLimit)
+
+		_, err = store.SetRateLimit(ctx, created.ID, nil)
+		require.NoError(t, err)
+
+		found, err = store.Authenticate(ctx, key)
+		require.NoError(t, err)
+		assert.Nil(t, found.RateLimit)
+	})
+
+	t.Run("reject invalid rate limit override", func(t *testing.T) {
+		store := NewWithQuerier(nil, &mockQuerier{}, setupTestRedis(t))
+
+		_, err := store.SetRateLimit(ctx, "id", &RateLimit{Requests: 0, Window: time.Minute})
+		require.ErrorIs(t, err, ErrInvalidRateLimit)
+
+		_, err = store.SetRateLimit(ctx, "id", &RateLimit{Requests: 10, Window: time.Millisecond})
+		require.ErrorIs(t, err, ErrInvalidRateLimit)
+
+		_, err = store.SetRateLimit(ctx, "missing", &RateLimit{Requests: 10, Window: time.Minute})
+		require.ErrorIs(t, err, ErrNotFound)
+	})
+
+	t.Run("return database errors", func(t *testing.T) {
+		store := NewWithQuerier(nil, &mockQuerier{err: errQueryFailed}, setupTestRedis(t))
+
+		_, _, err := store.Create(ctx, &CreateParams{UserID: "user123", Name: "ci"})
+		require.ErrorIs(t, err, errQueryFailed)
+
+		_, err = store.Authenticate(ctx, Prefix+"key")
+		require.ErrorIs(t, err, errQueryFailed)
+	})
+}
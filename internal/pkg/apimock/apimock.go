@@ -0,0 +1,355 @@
+// Package apimock provides a handler serving responses of an OpenAPI spec from its examples and schemas,
+// so that clients can be developed against an API before its handlers exist.
+package apimock
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/getkin/kin-openapi/routers"
+	"github.com/getkin/kin-openapi/routers/legacy"
+
+	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/apierror"
+)
+
+const (
+	// HeaderMock is header set on mocked responses.
+	HeaderMock = "X-Mock"
+
+	// maxDepth bounds nesting of generated values, so that recursive schemas terminate.
+	maxDepth = 8
+
+	// contentTypeJSON is content type of JSON responses.
+	contentTypeJSON = "application/json"
+)
+
+// ErrNoResponse is returned when an operation defines no response of the requested status.
+var ErrNoResponse = errors.New("operation has no response of the status")
+
+// Mock serves responses of the operations of a spec.
+type Mock struct {
+	// router finds operations of requests.
+	router routers.Router
+}
+
+// New creates a mock of the spec, servers are ignored so that operations match on any host.
+func New(spec *openapi3.T) (*Mock, error) {
+	routeSpec := *spec
+	routeSpec.Servers = nil
+
+	router, err := legacy.NewRouter(&routeSpec)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create openapi router: %w", err)
+	}
+
+	return &Mock{router: router}, nil
+}
+
+// ServeHTTP serves the response of the operation of the request. The lowest 2xx status is served unless
+// a status or a named example is requested with the Prefer header, e.g. "code=404" or "example=admin".
+func (m *Mock) ServeHTTP(writer http.ResponseWriter, request *http.Request) {
+	route, _, err := m.router.FindRoute(request)
+	if err != nil {
+		status := http.StatusNotFound
+
+		// route errors carry the reason as a string rather than wrapping the sentinel errors
+		var routeError *routers.RouteError
+		if errors.As(err, &routeError) && routeError.Reason == routers.ErrMethodNotAllowed.Error() {
+			status = http.StatusMethodNotAllowed
+		}
+
+		_ = apierror.Write(writer, status, &apierror.Response{Error: http.StatusText(status)})
+
+		return
+	}
+
+	code, exampleName := parsePrefer(request.Header.Get("Prefer"))
+
+	status, response, err := selectResponse(route.Operation, code)
+	if err != nil {
+		_ = apierror.Write(writer, http.StatusNotFound, &apierror.Response{Error: err.Error()})
+
+		return
+	}
+
+	writer.Header().Set(HeaderMock, "true")
+
+	contentType, media := selectContent(response, request.Header.Get("Accept"))
+	if media == nil || status == http.StatusNoContent {
+		writer.WriteHeader(status)
+
+		return
+	}
+
+	body, err := encode(contentType, MediaExample(media, exampleName))
+	if err != nil {
+		_ = apierror.Write(writer, http.StatusInternalServerError, &apierror.Response{Error: err.Error()})
+
+		return
+	}
+
+	writer.Header().Set("Content-Type", contentType)
+	writer.WriteHeader(status)
+	_, _ = writer.Write(body)
+}
+
+// parsePrefer returns the status and the example name requested by the Prefer header.
+func parsePrefer(prefer string) (string, string) {
+	var code, example string
+
+	for preference := range strings.SplitSeq(prefer, ",") {
+		for part := range strings.SplitSeq(preference, ";") {
+			key, value, _ := strings.Cut(strings.TrimSpace(part), "=")
+
+			switch strings.ToLower(key) {
+			case "code":
+				code = strings.Trim(value, `"`)
+			case "example":
+				example = strings.Trim(value, `"`)
+			}
+		}
+	}
+
+	return code, example
+}
+
+// selectResponse returns the response of the requested status, or the lowest 2xx status if none is requested,
+// falling back to the default response.
+func selectResponse(operation *openapi3.Operation, code string) (int, *openapi3.Response, error) {
+	responses := operation.Responses.Map()
+
+	if code != "" {
+		status, err := strconv.Atoi(code)
+		if err != nil {
+			return 0, nil, fmt.Errorf("%w: %s", ErrNoResponse, code)
+		}
+
+		if response := operation.Responses.Status(status); response != nil && response.Value != nil {
+			return status, response.Value, nil
+		}
+
+		if response := operation.Responses.Default(); response != nil && response.Value != nil {
+			return status, response.Value, nil
+		}
+
+		return 0, nil, fmt.Errorf("%w: %s", ErrNoResponse, code)
+	}
+
+	statuses := []int{}
+
+	for key := range responses {
+		if status, err := strconv.Atoi(key); err == nil && status >= 200 && status < 300 {
+			statuses = append(statuses, status)
+		}
+	}
+
+	if len(statuses) > 0 {
+		status := slices.Min(statuses)
+
+		return status, operation.Responses.Status(status).Value, nil
+	}
+
+	if response := operation.Responses.Default(); response != nil && response.Value != nil {
+		return http.StatusOK, response.Value, nil
+	}
+
+	return 0, nil, fmt.Errorf("%w: 2xx", ErrNoResponse)
+}
+
+// selectContent returns the content type of the response accepted by the request, preferring JSON,
+// nil if the response has no content.
+func selectContent(response *openapi3.Response, accept string) (string, *openapi3.MediaType) {
+	if len(response.Content) == 0 {
+		return "", nil
+	}
+
+	types := make([]string, 0, len(response.Content))
+	for contentType := range response.Content {
+		types = append(types, contentType)
+	}
+
+	slices.Sort(types)
+
+	for _, contentType := range types {
+		if accept != "" && strings.Contains(accept, contentType) {
+			return contentType, response.Content[contentType]
+		}
+	}
+
+	if media := response.Content.Get(contentTypeJSON); media != nil {
+		return contentTypeJSON, media
+	}
+
+	return types[0], response.Content[types[0]]
+}
+
+// encode encodes the value as the content type, strings of other content types than JSON are written as is.
+func encode(contentType string, value any) ([]byte, error) {
+	if text, ok := value.(string); ok && !strings.Contains(contentType, "json") {
+		return []byte(text), nil
+	}
+
+	body, err := json.Marshal(value)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode example: %w", err)
+	}
+
+	return body, nil
+}
+
+// MediaExample returns the example of the media type: the named example if it exists, the example,
+// the first named example by name, or a value generated from the schema.
+func MediaExample(media *openapi3.MediaType, name string) any {
+	if example, ok := media.Examples[name]; ok && example.Value != nil {
+		return example.Value.Value
+	}
+
+	if media.Example != nil {
+		return media.Example
+	}
+
+	names := make([]string, 0, len(media.Examples))
+	for name := range media.Examples {
+		names = append(names, name)
+	}
+
+	slices.Sort(names)
+
+	for _, name := range names {
+		if example := media.Examples[name]; example.Value != nil {
+			return example.Value.Value
+		}
+	}
+
+	if media.Schema == nil {
+		return nil
+	}
+
+	return Example(media.Schema.Value)
+}
+
+// Example returns the example of the schema, or a value generated from its type, format, enum and bounds.
+func Example(schema *openapi3.Schema) any {
+	return example(schema, 0)
+}
+
+// example returns the example of the schema at the depth.
+func example(schema *openapi3.Schema, depth int) any {
+	if schema == nil || depth > maxDepth {
+		return nil
+	}
+
+	switch {
+	case schema.Example != nil:
+		return schema.Example
+	case schema.Default != nil:
+		return schema.Default
+	case len(schema.Enum) > 0:
+		return schema.Enum[0]
+	case len(schema.AllOf) > 0:
+		return allOfExample(schema, depth)
+	case len(schema.OneOf) > 0:
+		return example(schema.OneOf[0].Value, depth+1)
+	case len(schema.AnyOf) > 0:
+		return example(schema.AnyOf[0].Value, depth+1)
+	}
+
+	switch {
+	case schema.Type.Is(openapi3.TypeObject) || (schema.Type == nil && len(schema.Properties) > 0):
+		return objectExample(schema, depth)
+	case schema.Type.Is(openapi3.TypeArray):
+		if schema.Items == nil {
+			return []any{}
+		}
+
+		return []any{example(schema.Items.Value, depth+1)}
+	case schema.Type.Is(openapi3.TypeString):
+		return stringExample(schema)
+	case schema.Type.Is(openapi3.TypeInteger):
+		if schema.Min != nil {
+			return int64(*schema.Min)
+		}
+
+		return 0
+	case schema.Type.Is(openapi3.TypeNumber):
+		if schema.Min != nil {
+			return *schema.Min
+		}
+
+		return 0.0
+	case schema.Type.Is(openapi3.TypeBoolean):
+		return true
+	default:
+		return nil
+	}
+}
+
+// objectExample returns an object of examples of the properties of the schema.
+func objectExample(schema *openapi3.Schema, depth int) map[string]any {
+	object := make(map[string]any, len(schema.Properties))
+
+	for name, property := range schema.Properties {
+		if property.Value != nil && property.Value.WriteOnly {
+			continue
+		}
+
+		object[name] = example(property.Value, depth+1)
+	}
+
+	return object
+}
+
+// allOfExample returns the examples of the schemas of allOf merged, later objects override properties of earlier.
+func allOfExample(schema *openapi3.Schema, depth int) any {
+	merged := map[string]any{}
+
+	for _, ref := range schema.AllOf {
+		value := example(ref.Value, depth+1)
+
+		object, ok := value.(map[string]any)
+		if !ok {
+			return value
+		}
+
+		for key, property := range object {
+			merged[key] = property
+		}
+	}
+
+	return merged
+}
+
+// stringExample returns an example string of the format of the schema, padded to its minimum length.
+func stringExample(schema *openapi3.Schema) string {
+	formats := map[string]string{
+		"date-time": "2026-01-01T00:00:00Z",
+		"date":      "2026-01-01",
+		"time":      "00:00:00",
+		"email":     "user@example.com",
+		"uuid":      "00000000-0000-4000-8000-000000000000",
+		"uri":       "https://example.com",
+		"url":       "https://example.com",
+		"hostname":  "example.com",
+		"ipv4":      "192.0.2.1",
+		"ipv6":      "2001:db8::1",
+		"byte":      "ZXhhbXBsZQ==",
+		"password":  "password",
+	}
+
+	value, ok := formats[schema.Format]
+	if !ok {
+		value = "string"
+	}
+
+	if minLength := int(schema.MinLength); len(value) < minLength {
+		value += strings.Repeat("x", minLength-len(value))
+	}
+
+	return value
+}
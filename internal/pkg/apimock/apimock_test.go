@@ -0,0 +1,209 @@
+package apimock
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testSpec is the spec of the mocked endpoints.
+const testSpec = `
+openapi: 3.0.3
+info:
+  title: test
+  version: 1.0.0
+servers:
+  - url: https://api.example.com
+paths:
+  /users/{id}:
+    parameters:
+      - name: id
+        in: path
+        required: true
+        schema:
+          type: string
+    get:
+      responses:
+        "200":
+          description: OK
+          content:
+            application/json:
+              schema:
+                $ref: "#/components/schemas/User"
+              examples:
+                member:
+                  value: {"id": "1", "role": "member"}
+                admin:
+                  value: {"id": "2", "role": "admin"}
+        "404":
+          description: Not Found
+          content:
+            application/json:
+              example: {"error": "user not found", "code": "not_found"}
+    delete:
+      responses:
+        "204":
+          description: No Content
+  /users:
+    post:
+      responses:
+        "201":
+          description: Created
+          content:
+            application/json:
+              schema:
+                $ref: "#/components/schemas/User"
+        "400":
+          description: Bad Request
+  /metrics:
+    get:
+      responses:
+        default:
+          description: OK
+          content:
+            text/plain:
+              schema:
+                type: string
+              example: "up 1"
+components:
+  schemas:
+    User:
+      type: object
+      properties:
+        id:
+          type: string
+          format: uuid
+        email:
+          type: string
+          format: email
+        role:
+          type: string
+          enum: [member, admin]
+        age:
+          type: integer
+          minimum: 18
+        password:
+          type: string
+          writeOnly: true
+        tags:
+          type: array
+          items:
+            type: string
+            minLength: 8
+        manager:
+          $ref: "#/components/schemas/User"
+`
+
+// setupTestMock creates a mock of the test spec.
+func setupTestMock(t *testing.T) *Mock {
+	t.Helper()
+
+	spec, err := openapi3.NewLoader().LoadFromData([]byte(testSpec))
+	require.NoError(t, err)
+
+	mock, err := New(spec)
+	require.NoError(t, err)
+
+	return mock
+}
+
+// serve sends the request with the Prefer header to the mock.
+func serve(mock *Mock, method, target, prefer string) *httptest.ResponseRecorder {
+	request := httptest.NewRequest(method, target, nil)
+	if prefer != "" {
+		request.Header.Set("Prefer", prefer)
+	}
+
+	recorder := httptest.NewRecorder()
+	mock.ServeHTTP(recorder, request)
+
+	return recorder
+}
+
+func TestServeHTTP(t *testing.T) {
+	t.Parallel()
+
+	mock := setupTestMock(t)
+
+	t.Run("serve first named example of lowest 2xx status", func(t *testing.T) {
+		t.Parallel()
+
+		recorder := serve(mock, http.MethodGet, "/users/1", "")
+		assert.Equal(t, http.StatusOK, recorder.Code)
+		assert.Equal(t, "true", recorder.Header().Get(HeaderMock))
+		assert.Equal(t, "application/json", recorder.Header().Get("Content-Type"))
+		assert.JSONEq(t, `{"id": "2", "role": "admin"}`, recorder.Body.String())
+	})
+
+	t.Run("serve requested status and example", func(t *testing.T) {
+		t.Parallel()
+
+		recorder := serve(mock, http.MethodGet, "/users/1", "example=member")
+		assert.JSONEq(t, `{"id": "1", "role": "member"}`, recorder.Body.String())
+
+		recorder = serve(mock, http.MethodGet, "/users/1", `code="404"`)
+		assert.Equal(t, http.StatusNotFound, recorder.Code)
+		assert.JSONEq(t, `{"error": "user not found", "code": "not_found"}`, recorder.Body.String())
+
+		recorder = serve(mock, http.MethodGet, "/users/1", "code=500")
+		assert.Equal(t, http.StatusNotFound, recorder.Code)
+		assert.Empty(t, recorder.Header().Get(HeaderMock))
+	})
+
+	t.Run("generate response from schema", func(t *testing.T) {
+		t.Parallel()
+
+		recorder := serve(mock, http.MethodPost, "/users", "")
+		require.Equal(t, http.StatusCreated, recorder.Code)
+
+		var user map[string]any
+		require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &user))
+		assert.Equal(t, "00000000-0000-4000-8000-000000000000", user["id"])
+		assert.Equal(t, "user@example.com", user["email"])
+		assert.Equal(t, "member", user["role"])
+		assert.InDelta(t, 18, user["age"], 0)
+		assert.Equal(t, []any{"stringxx"}, user["tags"])
+		assert.NotContains(t, user, "password")
+		assert.Contains(t, user["manager"], "manager")
+	})
+
+	t.Run("serve responses without content and of other content types", func(t *testing.T) {
+		t.Parallel()
+
+		recorder := serve(mock, http.MethodDelete, "/users/1", "")
+		assert.Equal(t, http.StatusNoContent, recorder.Code)
+		assert.Empty(t, recorder.Body.String())
+
+		recorder = serve(mock, http.MethodGet, "/metrics", "")
+		assert.Equal(t, http.StatusOK, recorder.Code)
+		assert.Equal(t, "text/plain", recorder.Header().Get("Content-Type"))
+		assert.Equal(t, "up 1", recorder.Body.String())
+	})
+
+	t.Run("respond with not found for unknown operations", func(t *testing.T) {
+		t.Parallel()
+
+		assert.Equal(t, http.StatusNotFound, serve(mock, http.MethodGet, "/orders", "").Code)
+		assert.Equal(t, http.StatusMethodNotAllowed, serve(mock, http.MethodPatch, "/users", "").Code)
+	})
+}
+
+func TestExample(t *testing.T) {
+	t.Parallel()
+
+	schema := openapi3.NewAllOfSchema(
+		openapi3.NewObjectSchema().WithProperty("id", openapi3.NewInt64Schema()),
+		openapi3.NewObjectSchema().WithProperty("created_at", openapi3.NewDateTimeSchema()),
+	)
+
+	assert.Equal(t, map[string]any{"id": 0, "created_at": "2026-01-01T00:00:00Z"}, Example(schema))
+	assert.Equal(t, true, Example(openapi3.NewBoolSchema()))
+	assert.Equal(t, "a", Example(openapi3.NewOneOfSchema(openapi3.NewStringSchema().WithEnum("a"))))
+	assert.InDelta(t, 0.5, Example(openapi3.NewFloat64Schema().WithMin(0.5)), 0)
+	assert.Nil(t, Example(nil))
+}
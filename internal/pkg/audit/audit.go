@@ -0,0 +1,424 @@
+// Package audit provides audit logging of security-relevant events such as logins, token refreshes, permission
+// denials and admin actions. Events are buffered in memory and written to the audit_events table in batches,
+// so that logging never blocks requests.
+package audit
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	chimiddleware "github.com/go-chi/chi/v5/middleware"
+	"github.com/jackc/pgx/v5/pgtype"
+	"go.uber.org/fx"
+
+	"github.com/pocj8ur4in/boilerplate-go/internal/gen/db"
+	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/database"
+	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/jwt"
+	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/logger"
+	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/netutil"
+	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/readonly"
+)
+
+const (
+	// ActionLogin is action of successful logins.
+	ActionLogin = "auth.login"
+
+	// ActionLoginFailed is action of logins rejected for invalid credentials.
+	ActionLoginFailed = "auth.login_failed"
+
+	// ActionSignup is action of signups.
+	ActionSignup = "auth.signup"
+
+	// ActionTokenRefresh is action of successful access token refreshes.
+	ActionTokenRefresh = "auth.token_refresh"
+
+	// ActionTokenRefreshFailed is action of refreshes rejected for invalid refresh tokens.
+	ActionTokenRefreshFailed = "auth.token_refresh_failed"
+
+	// ActionRefreshAnomaly is action of refreshes of a user exceeding the alert threshold of the JWT service.
+	ActionRefreshAnomaly = "auth.refresh_anomaly"
+
+	// ActionPermissionDenied is action of requests rejected by roles, scopes or authorization policies.
+	ActionPermissionDenied = "authz.denied"
+
+	// ActionAdmin is action of state-changing requests on admin endpoints.
+	ActionAdmin = "admin.action"
+)
+
+const (
+	// defaultBatchSize is default maximum number of events written at once.
+	defaultBatchSize = 100
+
+	// defaultFlushInterval is default interval of writing logged events.
+	defaultFlushInterval = 5 * time.Second
+
+	// defaultMaxPending is default maximum number of events waiting to be written.
+	defaultMaxPending = 10000
+
+	// idLength is length of generated event IDs in bytes.
+	idLength = 16
+)
+
+// ErrDatabaseRequired is returned when audit logging is enabled without a database.
+var ErrDatabaseRequired = errors.New("audit logging requires database")
+
+// Config represents configuration for audit logging.
+type Config struct {
+	// Enabled is whether audit events are written.
+	Enabled *bool `json:"enabled"`
+
+	// BatchSize is maximum number of events written at once, a full batch is written without waiting.
+	BatchSize *int `json:"batch_size"`
+
+	// FlushInterval is interval of writing logged events.
+	FlushInterval *time.Duration `json:"flush_interval"`
+
+	// MaxPending is maximum number of events waiting to be written, events beyond it are dropped.
+	MaxPending *int `json:"max_pending"`
+}
+
+// SetDefault sets default values.
+func (c *Config) SetDefault() {
+	if c.Enabled == nil {
+		c.Enabled = &[]bool{false}[0]
+	}
+
+	if c.BatchSize == nil {
+		c.BatchSize = &[]int{defaultBatchSize}[0]
+	}
+
+	if c.FlushInterval == nil {
+		c.FlushInterval = &[]time.Duration{defaultFlushInterval}[0]
+	}
+
+	if c.MaxPending == nil {
+		c.MaxPending = &[]int{defaultMaxPending}[0]
+	}
+}
+
+// Event represents a security-relevant event.
+type Event struct {
+	// ID is ID of the event, generated if empty, events with the same ID are written once.
+	ID string `json:"id"`
+
+	// Action is action of the event, e.g. ActionLogin.
+	Action string `json:"action"`
+
+	// ActorID is ID of the user who acted, empty if unknown.
+	ActorID string `json:"actor_id,omitempty"`
+
+	// IP is client IP of the request.
+	IP string `json:"ip,omitempty"`
+
+	// UserAgent is user agent of the request.
+	UserAgent string `json:"user_agent,omitempty"`
+
+	// RequestID is ID of the request.
+	RequestID string `json:"request_id,omitempty"`
+
+	// Resource is resource acted on, e.g. the request path.
+	Resource string `json:"resource,omitempty"`
+
+	// Attributes is additional attributes of the event, e.g. the denied roles.
+	Attributes map[string]string `json:"attributes,omitempty"`
+
+	// OccurredAt is time of the event, set to the logging time if zero.
+	OccurredAt time.Time `json:"occurred_at"`
+}
+
+// NewEvent returns an event of the action with the client IP, user agent and ID of the request.
+func NewEvent(request *http.Request, action string) *Event {
+	return &Event{
+		Action:    action,
+		IP:        netutil.ClientIP(request),
+		UserAgent: request.UserAgent(),
+		RequestID: chimiddleware.GetReqID(request.Context()),
+		Resource:  request.URL.Path,
+	}
+}
+
+// Logger logs audit events, handlers call it on security-relevant events.
+type Logger interface {
+	// Log logs the event to be written asynchronously, it never blocks and drops the event if it can not be
+	// buffered.
+	Log(ctx context.Context, event *Event)
+}
+
+// Auditor logs audit events to the audit_events table in batches.
+type Auditor struct {
+	// config provides audit configuration.
+	config *Config
+
+	// queries provides database queries.
+	queries db.Querier
+
+	// readOnly provides read-only mode, events are kept in memory while it is on.
+	readOnly *readonly.ReadOnly
+
+	// logger provides logger.
+	logger *logger.Logger
+
+	// mu guards pending.
+	mu sync.Mutex
+
+	// pending is events waiting to be written, in order of logging.
+	pending []*Event
+
+	// flushMu serializes flushes.
+	flushMu sync.Mutex
+
+	// full signals the flush loop that a batch is full.
+	full chan struct{}
+
+	// stop stops the flush loop.
+	stop chan struct{}
+
+	// done is closed when the flush loop exits.
+	done chan struct{}
+
+	// now returns the current time, replaced in tests.
+	now func() time.Time
+}
+
+// NewModule provides module for audit logging.
+func NewModule() fx.Option {
+	return fx.Module("audit",
+		fx.Provide(New, ProvideLogger),
+	)
+}
+
+// New creates a new auditor writing events to the database.
+func New(
+	config *Config,
+	dbConn *database.DB,
+	readOnly *readonly.ReadOnly,
+	logger *logger.Logger,
+) (*Auditor, error) {
+	if config == nil {
+		config = &Config{}
+	}
+
+	config.SetDefault()
+
+	var queries db.Querier
+
+	if *config.Enabled {
+		if dbConn == nil {
+			return nil, ErrDatabaseRequired
+		}
+
+		queries = dbConn.Queries
+	}
+
+	return NewWithQuerier(config, queries, readOnly, logger), nil
+}
+
+// NewWithQuerier creates a new auditor writing events using the querier.
+func NewWithQuerier(config *Config, queries db.Querier, readOnly *readonly.ReadOnly, logger *logger.Logger) *Auditor {
+	if config == nil {
+		config = &Config{}
+	}
+
+	config.SetDefault()
+
+	return &Auditor{
+		config:   config,
+		queries:  queries,
+		readOnly: readOnly,
+		logger:   logger.Named("audit"),
+		full:     make(chan struct{}, 1),
+		now:      time.Now,
+	}
+}
+
+// ProvideLogger provides the auditor as the logger handlers call.
+func ProvideLogger(auditor *Auditor) Logger {
+	return auditor
+}
+
+// Enabled returns whether audit events are written.
+func (a *Auditor) Enabled() bool {
+	return a != nil && *a.config.Enabled
+}
+
+// Log logs the event to be written with the next batch, it does nothing if the auditor is disabled.
+// The event is copied, an empty ID and a zero OccurredAt are filled in. Events are dropped with a warning
+// while max pending events wait to be written.
+func (a *Auditor) Log(ctx context.Context, event *Event) {
+	if !a.Enabled() {
+		return
+	}
+
+	logged := *event
+
+	if logged.ID == "" {
+		id := make([]byte, idLength)
+		if _, err := rand.Read(id); err != nil {
+			a.logger.Ctx(ctx).Error().Err(err).Str("action", event.Action).Msg("failed to generate audit event id")
+
+			return
+		}
+
+		logged.ID = hex.EncodeToString(id)
+	}
+
+	if logged.OccurredAt.IsZero() {
+		logged.OccurredAt = a.now()
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if len(a.pending) >= *a.config.MaxPending {
+		a.logger.Ctx(ctx).Warn().Str("action", event.Action).Str("actor_id", event.ActorID).
+			Msg("audit buffer full, dropping event")
+
+		return
+	}
+
+	a.pending = append(a.pending, &logged)
+
+	// wake the flush loop without blocking, a signal is already pending otherwise
+	if len(a.pending) >= *a.config.BatchSize {
+		select {
+		case a.full <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// RefreshAnomaly logs the refresh anomaly detected by the JWT service, it is added with jwt.OnRefreshAnomaly.
+func (a *Auditor) RefreshAnomaly(anomaly jwt.RefreshAnomaly) {
+	a.Log(context.Background(), &Event{
+		Action:  ActionRefreshAnomaly,
+		ActorID: anomaly.UserID,
+		Attributes: map[string]string{
+			"count":  strconv.Itoa(anomaly.Count),
+			"window": anomaly.Window.String(),
+		},
+		OccurredAt: anomaly.DetectedAt,
+	})
+}
+
+// Flush writes the pending events in batches, a batch failing to be written is kept for the next flush
+// and written again, events of the batch already written are skipped by their IDs.
+// Events are kept in memory while read-only mode is on.
+func (a *Auditor) Flush(ctx context.Context) error {
+	a.flushMu.Lock()
+	defer a.flushMu.Unlock()
+
+	if a.readOnly != nil && a.readOnly.Enabled(ctx) {
+		return nil
+	}
+
+	for {
+		a.mu.Lock()
+		batch := a.pending[:min(len(a.pending), *a.config.BatchSize)]
+		a.mu.Unlock()
+
+		if len(batch) == 0 {
+			return nil
+		}
+
+		if err := a.write(ctx, batch); err != nil {
+			return fmt.Errorf("failed to write audit events: %w", err)
+		}
+
+		// events are only appended while flushing, so the batch is still at the front
+		a.mu.Lock()
+		a.pending = a.pending[len(batch):]
+		a.mu.Unlock()
+	}
+}
+
+// Pending returns number of events waiting to be written.
+func (a *Auditor) Pending() int {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	return len(a.pending)
+}
+
+// Start starts writing events every flush interval and whenever a batch is full,
+// it does nothing if the auditor is disabled.
+func (a *Auditor) Start() {
+	if !a.Enabled() || a.stop != nil {
+		return
+	}
+
+	a.stop, a.done = make(chan struct{}), make(chan struct{})
+
+	go func() {
+		defer close(a.done)
+
+		ticker := time.NewTicker(*a.config.FlushInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-a.stop:
+				return
+			case <-ticker.C:
+			case <-a.full:
+			}
+
+			if err := a.Flush(context.Background()); err != nil {
+				a.logger.Error().Err(err).Int("pending", a.Pending()).Msg("failed to flush audit events")
+			}
+		}
+	}()
+}
+
+// Stop stops writing events and writes the pending events.
+func (a *Auditor) Stop(ctx context.Context) error {
+	if a.stop == nil {
+		return nil
+	}
+
+	close(a.stop)
+	<-a.done
+
+	a.stop = nil
+
+	return a.Flush(ctx)
+}
+
+// write inserts the events, skipping IDs already written.
+func (a *Auditor) write(ctx context.Context, events []*Event) error {
+	for _, event := range events {
+		attributes := []byte("{}")
+
+		if len(event.Attributes) > 0 {
+			encoded, err := json.Marshal(event.Attributes)
+			if err != nil {
+				return fmt.Errorf("failed to encode attributes of event %s: %w", event.ID, err)
+			}
+
+			attributes = encoded
+		}
+
+		if _, err := a.queries.InsertAuditEvent(ctx, &db.InsertAuditEventParams{
+			ID:         event.ID,
+			Action:     event.Action,
+			ActorID:    event.ActorID,
+			Ip:         event.IP,
+			UserAgent:  event.UserAgent,
+			RequestID:  event.RequestID,
+			Resource:   event.Resource,
+			Attributes: attributes,
+			OccurredAt: pgtype.Timestamptz{Time: event.OccurredAt, Valid: true},
+		}); err != nil {
+			return fmt.Errorf("failed to insert event %s: %w", event.ID, err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,299 @@
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	chimiddleware "github.com/go-chi/chi/v5/middleware"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pocj8ur4in/boilerplate-go/internal/gen/db"
+	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/jwt"
+	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/logger"
+	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/readonly"
+)
+
+var errQueryFailed = errors.New("query failed")
+
+// mockEventQuerier is a mock querier inserting events in memory.
+type mockEventQuerier struct {
+	db.Querier
+
+	mu       sync.Mutex
+	inserted map[string]*db.InsertAuditEventParams
+	err      error
+}
+
+func newMockEventQuerier() *mockEventQuerier {
+	return &mockEventQuerier{inserted: make(map[string]*db.InsertAuditEventParams)}
+}
+
+func (m *mockEventQuerier) InsertAuditEvent(_ context.Context, arg *db.InsertAuditEventParams) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.err != nil {
+		return 0, m.err
+	}
+
+	if _, ok := m.inserted[arg.ID]; ok {
+		return 0, nil
+	}
+
+	m.inserted[arg.ID] = arg
+
+	return 1, nil
+}
+
+func (m *mockEventQuerier) setErr(err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.err = err
+}
+
+func (m *mockEventQuerier) count() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return len(m.inserted)
+}
+
+// setupTestAuditor creates an enabled auditor writing events using the querier.
+func setupTestAuditor(t *testing.T, config *Config, queries db.Querier, readOnly *readonly.ReadOnly) *Auditor {
+	t.Helper()
+
+	log, err := logger.New(&logger.Config{})
+	require.NoError(t, err)
+
+	if config == nil {
+		config = &Config{}
+	}
+
+	if config.Enabled == nil {
+		config.Enabled = &[]bool{true}[0]
+	}
+
+	auditor := NewWithQuerier(config, queries, readOnly, log)
+	auditor.now = func() time.Time { return time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC) }
+
+	return auditor
+}
+
+func TestConfigSetDefault(t *testing.T) {
+	t.Parallel()
+
+	config := &Config{}
+	config.SetDefault()
+
+	assert.False(t, *config.Enabled)
+	assert.Equal(t, defaultBatchSize, *config.BatchSize)
+	assert.Equal(t, defaultFlushInterval, *config.FlushInterval)
+	assert.Equal(t, defaultMaxPending, *config.MaxPending)
+}
+
+func TestNew(t *testing.T) {
+	t.Parallel()
+
+	t.Run("return fx.Option", func(t *testing.T) {
+		t.Parallel()
+
+		require.NotNil(t, NewModule())
+	})
+
+	t.Run("return error when enabled without database", func(t *testing.T) {
+		t.Parallel()
+
+		log, err := logger.New(&logger.Config{})
+		require.NoError(t, err)
+
+		_, err = New(&Config{Enabled: &[]bool{true}[0]}, nil, nil, log)
+		require.ErrorIs(t, err, ErrDatabaseRequired)
+	})
+
+	t.Run("create disabled auditor without database", func(t *testing.T) {
+		t.Parallel()
+
+		log, err := logger.New(&logger.Config{})
+		require.NoError(t, err)
+
+		auditor, err := New(nil, nil, nil, log)
+		require.NoError(t, err)
+		assert.False(t, auditor.Enabled())
+		assert.Same(t, auditor, ProvideLogger(auditor))
+	})
+}
+
+func TestNewEvent(t *testing.T) {
+	t.Parallel()
+
+	request := httptest.NewRequest(http.MethodPost, "/auth/login", nil)
+	request.RemoteAddr = "203.0.113.7:4321"
+	request.Header.Set("User-Agent", "test-agent")
+	request = request.WithContext(context.WithValue(request.Context(), chimiddleware.RequestIDKey, "req-1"))
+
+	event := NewEvent(request, ActionLogin)
+
+	assert.Equal(t, ActionLogin, event.Action)
+	assert.Equal(t, "203.0.113.7", event.IP)
+	assert.Equal(t, "test-agent", event.UserAgent)
+	assert.Equal(t, "req-1", event.RequestID)
+	assert.Equal(t, "/auth/login", event.Resource)
+}
+
+func TestLog(t *testing.T) {
+	t.Parallel()
+
+	t.Run("fill in id and time of logged event", func(t *testing.T) {
+		t.Parallel()
+
+		queries := newMockEventQuerier()
+		auditor := setupTestAuditor(t, nil, queries, nil)
+
+		event := &Event{Action: ActionLogin, ActorID: "user-1", Attributes: map[string]string{"method": "password"}}
+		auditor.Log(context.Background(), event)
+		require.NoError(t, auditor.Flush(context.Background()))
+
+		require.Equal(t, 1, queries.count())
+
+		for id, inserted := range queries.inserted {
+			assert.Len(t, id, idLength*2)
+			assert.Equal(t, ActionLogin, inserted.Action)
+			assert.Equal(t, "user-1", inserted.ActorID)
+			assert.Equal(t, time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC), inserted.OccurredAt.Time)
+
+			var attributes map[string]string
+			require.NoError(t, json.Unmarshal(inserted.Attributes, &attributes))
+			assert.Equal(t, map[string]string{"method": "password"}, attributes)
+		}
+
+		assert.Empty(t, event.ID, "logged event is copied")
+	})
+
+	t.Run("drop events when buffer is full", func(t *testing.T) {
+		t.Parallel()
+
+		auditor := setupTestAuditor(t, &Config{MaxPending: &[]int{1}[0]}, newMockEventQuerier(), nil)
+
+		auditor.Log(context.Background(), &Event{Action: ActionLogin})
+		auditor.Log(context.Background(), &Event{Action: ActionLogin})
+
+		assert.Equal(t, 1, auditor.Pending())
+	})
+
+	t.Run("log nothing while disabled", func(t *testing.T) {
+		t.Parallel()
+
+		auditor := setupTestAuditor(t, &Config{Enabled: &[]bool{false}[0]}, newMockEventQuerier(), nil)
+
+		auditor.Log(context.Background(), &Event{Action: ActionLogin})
+		assert.Zero(t, auditor.Pending())
+
+		var nilAuditor *Auditor
+		nilAuditor.Log(context.Background(), &Event{Action: ActionLogin})
+		assert.False(t, nilAuditor.Enabled())
+	})
+}
+
+func TestRefreshAnomaly(t *testing.T) {
+	t.Parallel()
+
+	queries := newMockEventQuerier()
+	auditor := setupTestAuditor(t, nil, queries, nil)
+
+	detectedAt := time.Date(2024, 5, 6, 7, 8, 9, 0, time.UTC)
+	auditor.RefreshAnomaly(jwt.RefreshAnomaly{UserID: "user-1", Count: 11, Window: time.Minute, DetectedAt: detectedAt})
+	require.NoError(t, auditor.Flush(context.Background()))
+
+	require.Equal(t, 1, queries.count())
+
+	for _, inserted := range queries.inserted {
+		assert.Equal(t, ActionRefreshAnomaly, inserted.Action)
+		assert.Equal(t, "user-1", inserted.ActorID)
+		assert.Equal(t, detectedAt, inserted.OccurredAt.Time)
+		assert.JSONEq(t, `{"count":"11","window":"1m0s"}`, string(inserted.Attributes))
+	}
+}
+
+func TestFlush(t *testing.T) {
+	t.Parallel()
+
+	t.Run("write events in batches", func(t *testing.T) {
+		t.Parallel()
+
+		queries := newMockEventQuerier()
+		auditor := setupTestAuditor(t, &Config{BatchSize: &[]int{2}[0]}, queries, nil)
+
+		for range 5 {
+			auditor.Log(context.Background(), &Event{Action: ActionPermissionDenied})
+		}
+
+		require.NoError(t, auditor.Flush(context.Background()))
+		assert.Equal(t, 5, queries.count())
+		assert.Zero(t, auditor.Pending())
+	})
+
+	t.Run("keep failed batch for next flush", func(t *testing.T) {
+		t.Parallel()
+
+		queries := newMockEventQuerier()
+		queries.setErr(errQueryFailed)
+		auditor := setupTestAuditor(t, nil, queries, nil)
+
+		auditor.Log(context.Background(), &Event{ID: "event-1", Action: ActionAdmin})
+		require.ErrorIs(t, auditor.Flush(context.Background()), errQueryFailed)
+		assert.Equal(t, 1, auditor.Pending())
+
+		queries.setErr(nil)
+
+		require.NoError(t, auditor.Flush(context.Background()))
+		assert.Contains(t, queries.inserted, "event-1")
+		assert.Zero(t, auditor.Pending())
+	})
+
+	t.Run("keep events in memory while read-only", func(t *testing.T) {
+		t.Parallel()
+
+		readOnly := readonly.New(&readonly.Config{}, nil)
+		require.NoError(t, readOnly.Set(context.Background(), true))
+
+		queries := newMockEventQuerier()
+		auditor := setupTestAuditor(t, nil, queries, readOnly)
+
+		auditor.Log(context.Background(), &Event{Action: ActionTokenRefresh})
+		require.NoError(t, auditor.Flush(context.Background()))
+		assert.Zero(t, queries.count())
+
+		require.NoError(t, readOnly.Set(context.Background(), false))
+		require.NoError(t, auditor.Flush(context.Background()))
+		assert.Equal(t, 1, queries.count())
+	})
+
+	t.Run("write full batches and on stop", func(t *testing.T) {
+		t.Parallel()
+
+		queries := newMockEventQuerier()
+		auditor := setupTestAuditor(t, &Config{BatchSize: &[]int{2}[0]}, queries, nil)
+
+		auditor.Start()
+
+		auditor.Log(context.Background(), &Event{Action: ActionLogin})
+		auditor.Log(context.Background(), &Event{Action: ActionLogin})
+
+		// the full batch is written before the flush interval
+		require.Eventually(t, func() bool {
+			return queries.count() == 2
+		}, time.Second, 5*time.Millisecond)
+
+		auditor.Log(context.Background(), &Event{Action: ActionLogin})
+		require.NoError(t, auditor.Stop(context.Background()))
+		assert.Equal(t, 3, queries.count())
+	})
+}
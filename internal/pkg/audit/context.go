@@ -0,0 +1,21 @@
+package audit
+
+import "context"
+
+// contextKey is the context key of the request-scoped audit logger.
+type contextKey struct{}
+
+// NewContext returns a copy of the context carrying the audit logger, so that middlewares without access to the
+// application services log events of the request.
+func NewContext(ctx context.Context, logger Logger) context.Context {
+	return context.WithValue(ctx, contextKey{}, logger)
+}
+
+// FromContext returns the audit logger carried by the context, or the fallback if the context carries none.
+func FromContext(ctx context.Context, fallback Logger) Logger {
+	if logger, ok := ctx.Value(contextKey{}).(Logger); ok && logger != nil {
+		return logger
+	}
+
+	return fallback
+}
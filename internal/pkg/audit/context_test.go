@@ -0,0 +1,30 @@
+package audit
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestContext(t *testing.T) {
+	t.Parallel()
+
+	t.Run("return fallback if context carries no logger", func(t *testing.T) {
+		t.Parallel()
+
+		fallback := setupTestAuditor(t, nil, newMockEventQuerier(), nil)
+
+		assert.Same(t, fallback, FromContext(context.Background(), fallback))
+		assert.Nil(t, FromContext(context.Background(), nil))
+	})
+
+	t.Run("return logger carried by context", func(t *testing.T) {
+		t.Parallel()
+
+		auditor := setupTestAuditor(t, nil, newMockEventQuerier(), nil)
+		ctx := NewContext(context.Background(), auditor)
+
+		assert.Same(t, auditor, FromContext(ctx, nil))
+	})
+}
@@ -0,0 +1,246 @@
+// Package authz provides authorization decisions of users on resources by their role stored on database,
+// cached for the request and on redis so the role is not looked up on every request, and evaluation of
+// cedar authorization policies on attributes of the request.
+package authz
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	goredis "github.com/redis/go-redis/v9"
+	"go.uber.org/fx"
+
+	"github.com/pocj8ur4in/boilerplate-go/internal/gen/db"
+	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/database"
+	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/logger"
+	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/redis"
+	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/user"
+)
+
+const (
+	// cacheKeyPrefix is the redis key prefix of hashes of cached decisions by user.
+	cacheKeyPrefix = "authz:user:"
+
+	// cachedAllowed is the cached value of allowed decisions.
+	cachedAllowed = "1"
+
+	// cachedDenied is the cached value of denied decisions.
+	cachedDenied = "0"
+
+	// defaultCacheTTL is default TTL of decisions cached on redis.
+	defaultCacheTTL = 30 * time.Second
+)
+
+// Config represents configuration for authorization.
+type Config struct {
+	// CacheTTL is TTL of decisions of a user cached on redis, it bounds staleness if an invalidation is missed.
+	CacheTTL *time.Duration `json:"cache_ttl"`
+
+	// Policies is cedar policies evaluated by the policy engine, requests are denied unless a permit policy
+	// matches.
+	Policies *string `json:"policies"`
+}
+
+// SetDefault sets default values.
+func (c *Config) SetDefault() {
+	if c.CacheTTL == nil {
+		c.CacheTTL = &[]time.Duration{defaultCacheTTL}[0]
+	}
+
+	if c.Policies == nil {
+		c.Policies = &[]string{""}[0]
+	}
+}
+
+// Authz provides authorization decisions, reads go through the request cache, then redis, then database.
+// Decisions of a user are invalidated when the role of the user changes.
+type Authz struct {
+	// config provides authorization configuration.
+	config *Config
+
+	// queries provides database queries.
+	queries db.Querier
+
+	// redis provides redis client.
+	redis *redis.Redis
+
+	// logger provides logger.
+	logger *logger.Logger
+
+	// engine evaluates authorization policies.
+	engine Engine
+}
+
+// NewModule provides module for authorization.
+func NewModule() fx.Option {
+	return fx.Module("authz",
+		fx.Provide(New),
+		fx.Invoke(invalidateOnRoleChange),
+	)
+}
+
+// New creates authorization on database.
+func New(config *Config, dbConn *database.DB, redis *redis.Redis, logger *logger.Logger) (*Authz, error) {
+	return NewWithQuerier(config, dbConn.Queries, redis, logger)
+}
+
+// NewWithQuerier creates authorization looking roles up using the querier.
+func NewWithQuerier(config *Config, queries db.Querier, redis *redis.Redis, logger *logger.Logger) (*Authz, error) {
+	if config == nil {
+		config = &Config{}
+	}
+
+	config.SetDefault()
+
+	engine, err := NewPolicyEngine(*config.Policies)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Authz{
+		config:  config,
+		queries: queries,
+		redis:   redis,
+		logger:  logger.Named("authz"),
+		engine:  engine,
+	}, nil
+}
+
+// invalidateOnRoleChange invalidates cached decisions of users whose role changed.
+func invalidateOnRoleChange(users *user.Service, authz *Authz) {
+	users.OnRoleChange(func(ctx context.Context, userID string) {
+		if err := authz.Invalidate(ctx, userID); err != nil {
+			authz.logger.Ctx(ctx).Error().Err(err).Str("user_id", userID).Msg("failed to invalidate authorization")
+		}
+	})
+}
+
+// Authorize returns whether the role of the user is one of the roles on the resource, unknown users are denied.
+// Decisions are cached by user, resource and roles.
+func (a *Authz) Authorize(ctx context.Context, userID, resource string, roles ...string) (bool, error) {
+	field := resource + "=" + strings.Join(roles, ",")
+
+	// check request cache first
+	cache, _ := ctx.Value(contextKey{}).(*requestCache)
+	if allowed, ok := cache.get(userID, field); ok {
+		return allowed, nil
+	}
+
+	allowed, err := a.load(ctx, userID, field, roles)
+	if err != nil {
+		return false, err
+	}
+
+	cache.set(userID, field, allowed)
+
+	return allowed, nil
+}
+
+// load returns the decision from redis, or decides it by the role of the user on database and caches it.
+func (a *Authz) load(ctx context.Context, userID, field string, roles []string) (bool, error) {
+	cacheKey := cacheKeyPrefix + userID
+
+	// check redis cache
+	cached, err := a.redis.HGet(ctx, cacheKey, field).Result()
+	if err == nil {
+		return cached == cachedAllowed, nil
+	}
+
+	if !errors.Is(err, goredis.Nil) {
+		// redis is a cache, fall back to database
+		a.logger.Ctx(ctx).Warn().Err(err).Str("key", cacheKey).Msg("failed to get cached authorization")
+	}
+
+	// look the role up on database
+	var allowed bool
+
+	row, err := a.queries.GetUserByID(ctx, userID)
+
+	switch {
+	case err == nil:
+		allowed = slices.Contains(roles, row.Role)
+	case errors.Is(err, pgx.ErrNoRows):
+		allowed = false
+	default:
+		return false, fmt.Errorf("failed to get user role: %w", err)
+	}
+
+	cached = cachedDenied
+	if allowed {
+		cached = cachedAllowed
+	}
+
+	// the hash expires a TTL after its first decision so decisions are never older than the TTL
+	pipe := a.redis.TxPipeline()
+	pipe.HSet(ctx, cacheKey, field, cached)
+	pipe.ExpireNX(ctx, cacheKey, *a.config.CacheTTL)
+
+	if _, err := pipe.Exec(ctx); err != nil {
+		a.logger.Ctx(ctx).Warn().Err(err).Str("key", cacheKey).Msg("failed to cache authorization")
+	}
+
+	return allowed, nil
+}
+
+// Invalidate removes cached decisions of the user, so its next requests are decided by its current role.
+func (a *Authz) Invalidate(ctx context.Context, userID string) error {
+	if err := a.redis.Del(ctx, cacheKeyPrefix+userID).Err(); err != nil {
+		return fmt.Errorf("failed to invalidate cached authorization: %w", err)
+	}
+
+	return nil
+}
+
+// contextKey is the context key of the request cache.
+type contextKey struct{}
+
+// requestCache is decisions cached for the rest of the request.
+type requestCache struct {
+	// mu guards decisions.
+	mu sync.Mutex
+
+	// decisions is decisions by user and field.
+	decisions map[string]bool
+}
+
+// WithRequestCache returns a copy of the context caching decisions until the request completes,
+// or the context itself if it caches decisions already.
+func WithRequestCache(ctx context.Context) context.Context {
+	if _, ok := ctx.Value(contextKey{}).(*requestCache); ok {
+		return ctx
+	}
+
+	return context.WithValue(ctx, contextKey{}, &requestCache{decisions: make(map[string]bool)})
+}
+
+// get returns the cached decision, false if it is not cached or the context caches no decisions.
+func (c *requestCache) get(userID, field string) (bool, bool) {
+	if c == nil {
+		return false, false
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	allowed, ok := c.decisions[userID+"\x00"+field]
+
+	return allowed, ok
+}
+
+// set caches the decision, it does nothing if the context caches no decisions.
+func (c *requestCache) set(userID, field string, allowed bool) {
+	if c == nil {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.decisions[userID+"\x00"+field] = allowed
+}
@@ -0,0 +1,223 @@
+package authz
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pocj8ur4in/boilerplate-go/internal/gen/db"
+	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/logger"
+	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/redis"
+	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/user"
+)
+
+var errQueryFailed = errors.New("query failed")
+
+// mockUserQuerier is a mock querier serving roles of users from memory.
+type mockUserQuerier struct {
+	db.Querier
+
+	mu    sync.Mutex
+	roles map[string]string
+	gets  int
+	err   error
+}
+
+func (m *mockUserQuerier) GetUserByID(_ context.Context, id string) (*db.User, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.gets++
+
+	if m.err != nil {
+		return nil, m.err
+	}
+
+	role, ok := m.roles[id]
+	if !ok {
+		return nil, pgx.ErrNoRows
+	}
+
+	return &db.User{ID: id, Role: role}, nil
+}
+
+func (m *mockUserQuerier) UpdateUserRole(_ context.Context, arg *db.UpdateUserRoleParams) (*db.User, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.roles[arg.ID] = arg.Role
+
+	return &db.User{ID: arg.ID, Role: arg.Role}, nil
+}
+
+func (m *mockUserQuerier) getCalls() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.gets
+}
+
+func setupTestRedis(t *testing.T) *redis.Redis {
+	t.Helper()
+
+	password := ""
+	redisDB := 0
+
+	redisClient, err := redis.New(&redis.Config{
+		Addrs:    []string{"localhost:36379"},
+		Password: &password,
+		DB:       &redisDB,
+	})
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	require.NoError(t, redisClient.FlushDB(ctx).Err())
+
+	t.Cleanup(func() {
+		_ = redisClient.Close()
+	})
+
+	return redisClient
+}
+
+func setupTestAuthz(t *testing.T, querier db.Querier, redisClient *redis.Redis) *Authz {
+	t.Helper()
+
+	log, err := logger.New(&logger.Config{})
+	require.NoError(t, err)
+
+	authz, err := NewWithQuerier(&Config{}, querier, redisClient, log)
+	require.NoError(t, err)
+
+	return authz
+}
+
+func TestConfigSetDefault(t *testing.T) {
+	t.Parallel()
+
+	config := &Config{}
+	config.SetDefault()
+
+	assert.Equal(t, defaultCacheTTL, *config.CacheTTL)
+	assert.Empty(t, config.Policies)
+}
+
+func TestNewModule(t *testing.T) {
+	t.Parallel()
+
+	t.Run("return fx.Option", func(t *testing.T) {
+		t.Parallel()
+
+		require.NotNil(t, NewModule())
+	})
+}
+
+//nolint:paralleltest // sequential execution required to avoid redis key conflicts
+func TestAuthorize(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("decide by role on database", func(t *testing.T) {
+		authz := setupTestAuthz(t, &mockUserQuerier{roles: map[string]string{"alice": "admin", "bob": "user"}},
+			setupTestRedis(t))
+
+		allowed, err := authz.Authorize(ctx, "alice", "admin", "admin", "operator")
+		require.NoError(t, err)
+		assert.True(t, allowed)
+
+		allowed, err = authz.Authorize(ctx, "bob", "admin", "admin", "operator")
+		require.NoError(t, err)
+		assert.False(t, allowed)
+
+		allowed, err = authz.Authorize(ctx, "unknown", "admin", "admin")
+		require.NoError(t, err)
+		assert.False(t, allowed)
+	})
+
+	t.Run("cache decisions on redis", func(t *testing.T) {
+		querier := &mockUserQuerier{roles: map[string]string{"alice": "admin"}}
+		redisClient := setupTestRedis(t)
+		authz := setupTestAuthz(t, querier, redisClient)
+
+		for range 3 {
+			allowed, err := authz.Authorize(ctx, "alice", "admin", "admin")
+			require.NoError(t, err)
+			assert.True(t, allowed)
+		}
+
+		assert.Equal(t, 1, querier.getCalls())
+
+		// decisions of other roles are cached separately
+		allowed, err := authz.Authorize(ctx, "alice", "admin", "operator")
+		require.NoError(t, err)
+		assert.False(t, allowed)
+		assert.Equal(t, 2, querier.getCalls())
+
+		ttl, err := redisClient.TTL(ctx, cacheKeyPrefix+"alice").Result()
+		require.NoError(t, err)
+		assert.Positive(t, ttl)
+		assert.LessOrEqual(t, ttl, defaultCacheTTL)
+	})
+
+	t.Run("cache decisions for the request", func(t *testing.T) {
+		querier := &mockUserQuerier{roles: map[string]string{"alice": "admin"}}
+		redisClient := setupTestRedis(t)
+		authz := setupTestAuthz(t, querier, redisClient)
+
+		requestCtx := WithRequestCache(ctx)
+		assert.Equal(t, requestCtx, WithRequestCache(requestCtx))
+
+		_, err := authz.Authorize(requestCtx, "alice", "admin", "admin")
+		require.NoError(t, err)
+
+		// the request keeps its decision even once redis no longer has it
+		require.NoError(t, redisClient.FlushDB(ctx).Err())
+
+		allowed, err := authz.Authorize(requestCtx, "alice", "admin", "admin")
+		require.NoError(t, err)
+		assert.True(t, allowed)
+		assert.Equal(t, 1, querier.getCalls())
+	})
+
+	t.Run("decide by new role once role changes", func(t *testing.T) {
+		querier := &mockUserQuerier{roles: map[string]string{"alice": "admin"}}
+		authz := setupTestAuthz(t, querier, setupTestRedis(t))
+
+		users, err := user.NewWithQuerier(&user.Config{}, querier)
+		require.NoError(t, err)
+
+		invalidateOnRoleChange(users, authz)
+
+		allowed, err := authz.Authorize(ctx, "alice", "admin", "admin")
+		require.NoError(t, err)
+		assert.True(t, allowed)
+
+		_, err = users.SetRole(ctx, "alice", "user")
+		require.NoError(t, err)
+
+		allowed, err = authz.Authorize(ctx, "alice", "admin", "admin")
+		require.NoError(t, err)
+		assert.False(t, allowed)
+		assert.Equal(t, 2, querier.getCalls())
+	})
+
+	t.Run("return database errors without caching", func(t *testing.T) {
+		querier := &mockUserQuerier{err: errQueryFailed}
+		redisClient := setupTestRedis(t)
+		authz := setupTestAuthz(t, querier, redisClient)
+
+		_, err := authz.Authorize(ctx, "alice", "admin", "admin")
+		require.ErrorIs(t, err, errQueryFailed)
+
+		exists, err := redisClient.Exists(ctx, cacheKeyPrefix+"alice").Result()
+		require.NoError(t, err)
+		assert.Zero(t, exists)
+	})
+}
@@ -0,0 +1,284 @@
+package authz
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+
+	"github.com/cedar-policy/cedar-go"
+	"github.com/jackc/pgx/v5"
+)
+
+const (
+	// PrincipalType is entity type of principals in policies, such as User::"123".
+	PrincipalType = "User"
+
+	// ActionType is entity type of actions in policies, such as Action::"read".
+	ActionType = "Action"
+
+	// ResourceType is entity type of resources in policies, such as Resource::"users/123".
+	ResourceType = "Resource"
+
+	// idAnnotation is annotation naming policies in decisions, such as @id("owner").
+	idAnnotation = "id"
+)
+
+var (
+	// ErrInvalidPolicy returned when the policies are not valid cedar.
+	ErrInvalidPolicy = errors.New("invalid authorization policy")
+
+	// ErrInvalidInput returned when an attribute of the context has a type policies can not read.
+	ErrInvalidInput = errors.New("invalid authorization input")
+)
+
+// Engine evaluates authorization policies, an engine embedding another policy language such as OPA can replace
+// the cedar engine by implementing it.
+type Engine interface {
+	// Evaluate returns the decision of the policies on the input.
+	Evaluate(ctx context.Context, input *Input) (*Decision, error)
+}
+
+// Principal represents the caller of the request.
+type Principal struct {
+	// ID is ID of the user.
+	ID string `json:"id"`
+
+	// Role is role of the user.
+	Role string `json:"role"`
+
+	// Scopes is scopes granted to the caller.
+	Scopes []string `json:"scopes"`
+}
+
+// Input represents the request policies are evaluated on.
+type Input struct {
+	// Principal is the caller of the request.
+	Principal Principal `json:"principal"`
+
+	// Action is the action requested, such as read or delete.
+	Action string `json:"action"`
+
+	// Resource is the resource the action is requested on, such as users/123.
+	Resource string `json:"resource"`
+
+	// Context is attributes of the request, such as path parameters or client IP.
+	Context map[string]any `json:"context"`
+}
+
+// Decision represents the decision of the policies.
+type Decision struct {
+	// Allowed is whether the request is allowed.
+	Allowed bool `json:"allowed"`
+
+	// Policy is ID of the policy that decided, empty if no policy matched.
+	Policy string `json:"policy,omitempty"`
+}
+
+// PolicyEngine evaluates cedar policies, requests are denied unless a permit policy matches, and a matching
+// forbid policy overrides any permit policy.
+type PolicyEngine struct {
+	// policies is policies evaluated.
+	policies *cedar.PolicySet
+}
+
+// NewPolicyEngine creates a policy engine evaluating the cedar policies.
+func NewPolicyEngine(policies string) (*PolicyEngine, error) {
+	policySet, err := cedar.NewPolicySetFromBytes("authz.policies", []byte(policies))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidPolicy, err)
+	}
+
+	return &PolicyEngine{policies: policySet}, nil
+}
+
+// Evaluate returns the decision of the policies on the input. The principal is a User entity with the id, role
+// and scopes attributes, the resource a Resource entity with the path attribute, and the context a record of
+// the context of the input. Cedar skips policies failing to evaluate, such as on a missing attribute, so a
+// failing forbid policy denies the request rather than letting permit policies allow it.
+func (e *PolicyEngine) Evaluate(_ context.Context, input *Input) (*Decision, error) {
+	request, entities, err := cedarRequest(input)
+	if err != nil {
+		return nil, err
+	}
+
+	decision, diagnostic := e.policies.IsAuthorized(entities, request)
+
+	var failed []string
+
+	for _, diagnosticError := range diagnostic.Errors {
+		if policy := e.policies.Get(diagnosticError.PolicyID); policy != nil && policy.Effect() == cedar.Forbid {
+			failed = append(failed, e.policyName(diagnosticError.PolicyID))
+		}
+	}
+
+	if len(failed) > 0 {
+		return &Decision{Allowed: false, Policy: slices.Min(failed)}, nil
+	}
+
+	if len(diagnostic.Reasons) == 0 {
+		return &Decision{}, nil
+	}
+
+	// policies are iterated in no order, the first policy by name decides so decisions are stable
+	names := make([]string, 0, len(diagnostic.Reasons))
+	for _, reason := range diagnostic.Reasons {
+		names = append(names, e.policyName(reason.PolicyID))
+	}
+
+	return &Decision{Allowed: decision == cedar.Allow, Policy: slices.Min(names)}, nil
+}
+
+// policyName returns the @id annotation of the policy, or its position in the policies without one.
+func (e *PolicyEngine) policyName(id cedar.PolicyID) string {
+	if policy := e.policies.Get(id); policy != nil {
+		if name, ok := policy.Annotations()[idAnnotation]; ok {
+			return string(name)
+		}
+	}
+
+	return string(id)
+}
+
+// cedarRequest returns the cedar request of the input with the entities of its principal and resource.
+func cedarRequest(input *Input) (cedar.Request, cedar.EntityMap, error) {
+	attributes, err := cedarValue(input.Context)
+	if err != nil {
+		return cedar.Request{}, nil, err
+	}
+
+	record, ok := attributes.(cedar.Record)
+	if !ok {
+		record = cedar.NewRecord(nil)
+	}
+
+	scopes := make([]cedar.Value, 0, len(input.Principal.Scopes))
+	for _, scope := range input.Principal.Scopes {
+		scopes = append(scopes, cedar.String(scope))
+	}
+
+	principal := cedar.NewEntityUID(PrincipalType, cedar.String(input.Principal.ID))
+	resource := cedar.NewEntityUID(ResourceType, cedar.String(input.Resource))
+
+	entities := cedar.EntityMap{
+		principal: {
+			UID: principal,
+			Attributes: cedar.NewRecord(cedar.RecordMap{
+				"id":     cedar.String(input.Principal.ID),
+				"role":   cedar.String(input.Principal.Role),
+				"scopes": cedar.NewSet(scopes...),
+			}),
+		},
+		resource: {
+			UID:        resource,
+			Attributes: cedar.NewRecord(cedar.RecordMap{"path": cedar.String(input.Resource)}),
+		},
+	}
+
+	return cedar.Request{
+		Principal: principal,
+		Action:    cedar.NewEntityUID(ActionType, cedar.String(input.Action)),
+		Resource:  resource,
+		Context:   record,
+	}, entities, nil
+}
+
+// cedarValue returns the cedar value of the attribute, maps are records without their nil attributes and slices
+// are sets.
+func cedarValue(value any) (cedar.Value, error) {
+	switch v := value.(type) {
+	case string:
+		return cedar.String(v), nil
+	case bool:
+		return cedar.Boolean(v), nil
+	case int:
+		return cedar.Long(v), nil
+	case int64:
+		return cedar.Long(v), nil
+	case float64:
+		// numbers decoded from JSON are floats, cedar has integers only
+		if v != math.Trunc(v) || math.Abs(v) > math.MaxInt64 {
+			return nil, fmt.Errorf("%w: %v is not an integer", ErrInvalidInput, v)
+		}
+
+		return cedar.Long(int64(v)), nil
+	case []string:
+		values := make([]cedar.Value, 0, len(v))
+		for _, item := range v {
+			values = append(values, cedar.String(item))
+		}
+
+		return cedar.NewSet(values...), nil
+	case []any:
+		values := make([]cedar.Value, 0, len(v))
+
+		for _, item := range v {
+			converted, err := cedarValue(item)
+			if err != nil {
+				return nil, err
+			}
+
+			values = append(values, converted)
+		}
+
+		return cedar.NewSet(values...), nil
+	case map[string]string:
+		record := make(cedar.RecordMap, len(v))
+		for key, item := range v {
+			record[cedar.String(key)] = cedar.String(item)
+		}
+
+		return cedar.NewRecord(record), nil
+	case map[string]any:
+		record := make(cedar.RecordMap, len(v))
+
+		for key, item := range v {
+			if item == nil {
+				continue
+			}
+
+			converted, err := cedarValue(item)
+			if err != nil {
+				return nil, err
+			}
+
+			record[cedar.String(key)] = converted
+		}
+
+		return cedar.NewRecord(record), nil
+	default:
+		return nil, fmt.Errorf("%w: unsupported type %T", ErrInvalidInput, value)
+	}
+}
+
+// Evaluate returns the decision of the policies on the input, the principal role is looked up on database
+// so a role changed after the token was issued applies.
+func (a *Authz) Evaluate(ctx context.Context, input *Input) (*Decision, error) {
+	input = &[]Input{*input}[0]
+
+	if input.Principal.ID != "" {
+		row, err := a.queries.GetUserByID(ctx, input.Principal.ID)
+
+		switch {
+		case err == nil:
+			input.Principal.Role = row.Role
+		case errors.Is(err, pgx.ErrNoRows):
+			return &Decision{}, nil
+		default:
+			return nil, fmt.Errorf("failed to get user role: %w", err)
+		}
+	}
+
+	decision, err := a.engine.Evaluate(ctx, input)
+	if err != nil {
+		return nil, fmt.Errorf("failed to evaluate authorization policies: %w", err)
+	}
+
+	return decision, nil
+}
+
+// SetEngine replaces the engine evaluating policies, it must be called before requests are served.
+func (a *Authz) SetEngine(engine Engine) {
+	a.engine = engine
+}
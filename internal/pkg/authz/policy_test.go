@@ -0,0 +1,300 @@
+package authz
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/logger"
+)
+
+// mockEngine is a mock engine allowing every request.
+type mockEngine struct {
+	input *Input
+}
+
+func (m *mockEngine) Evaluate(_ context.Context, input *Input) (*Decision, error) {
+	m.input = input
+
+	return &Decision{Allowed: true, Policy: "mock"}, nil
+}
+
+// testPolicies is policies of the policy engine tests.
+const testPolicies = `
+@id("admin")
+permit (principal, action, resource) when { principal.role == "admin" };
+
+@id("owner")
+permit (principal, action in [Action::"read", Action::"update"], resource)
+when { resource.path like "users/*" && principal.id == context.params.id };
+
+@id("reports")
+permit (principal, action == Action::"read", resource)
+when { principal.scopes.contains("reports:read") && !(["203.0.113.1"].contains(context.client_ip)) };
+
+@id("protected")
+forbid (principal, action == Action::"delete", resource == Resource::"users/root");
+
+@id("blocked")
+forbid (principal, action, resource) when { context.blocked };
+`
+
+func TestNewPolicyEngine(t *testing.T) {
+	t.Parallel()
+
+	t.Run("reject invalid policies", func(t *testing.T) {
+		t.Parallel()
+
+		for _, policies := range []string{
+			`permit`,
+			`allow (principal, action, resource);`,
+			`permit (principal, action, resource) when { principal.role == };`,
+		} {
+			_, err := NewPolicyEngine(policies)
+			require.ErrorIs(t, err, ErrInvalidPolicy, policies)
+		}
+	})
+
+	t.Run("return error from authorization with invalid policy", func(t *testing.T) {
+		t.Parallel()
+
+		log, err := logger.New(&logger.Config{})
+		require.NoError(t, err)
+
+		_, err = NewWithQuerier(&Config{Policies: &[]string{"permit"}[0]}, &mockUserQuerier{}, nil, log)
+		require.ErrorIs(t, err, ErrInvalidPolicy)
+	})
+}
+
+func TestPolicyEngineEvaluate(t *testing.T) {
+	t.Parallel()
+
+	engine, err := NewPolicyEngine(testPolicies)
+	require.NoError(t, err)
+
+	// params returns the context of the path parameter id, and the other attributes.
+	params := func(id string, attributes ...any) map[string]any {
+		values := map[string]any{"params": map[string]string{"id": id}, "blocked": false}
+		for i := 0; i+1 < len(attributes); i += 2 {
+			values[attributes[i].(string)] = attributes[i+1]
+		}
+
+		return values
+	}
+
+	tests := []struct {
+		name        string
+		input       *Input
+		wantAllowed bool
+		wantPolicy  string
+	}{
+		{
+			name: "permit by role",
+			input: &Input{
+				Principal: Principal{Role: "admin"}, Action: "delete", Resource: "users/1", Context: params("1"),
+			},
+			wantAllowed: true,
+			wantPolicy:  "admin",
+		},
+		{
+			name: "permit owner by condition",
+			input: &Input{
+				Principal: Principal{ID: "1", Role: "user"}, Action: "read", Resource: "users/1", Context: params("1"),
+			},
+			wantAllowed: true,
+			wantPolicy:  "owner",
+		},
+		{
+			name: "deny other user",
+			input: &Input{
+				Principal: Principal{ID: "1", Role: "user"}, Action: "read", Resource: "users/2", Context: params("2"),
+			},
+		},
+		{
+			name: "deny action the policy does not match",
+			input: &Input{
+				Principal: Principal{ID: "1", Role: "user"}, Action: "delete", Resource: "users/1", Context: params("1"),
+			},
+		},
+		{
+			name: "deny missing attribute",
+			input: &Input{
+				Principal: Principal{ID: "1", Role: "user"}, Action: "read", Resource: "users/1",
+				Context: map[string]any{"blocked": false},
+			},
+		},
+		{
+			name: "deny resource outside the pattern",
+			input: &Input{
+				Principal: Principal{ID: "1", Role: "user"}, Action: "read", Resource: "admins/users/1",
+				Context: params("1"),
+			},
+		},
+		{
+			name: "deny role claimed in context",
+			input: &Input{
+				Principal: Principal{ID: "1", Role: "user"}, Action: "read", Resource: "reports/1",
+				Context: params("1", "role", "admin"),
+			},
+		},
+		{
+			name: "deny condition injected into the action",
+			input: &Input{
+				Principal: Principal{ID: "1", Role: "user"}, Action: `read" || true || "`, Resource: "users/1",
+				Context: params("1"),
+			},
+		},
+		{
+			name: "permit by scope",
+			input: &Input{
+				Principal: Principal{Scopes: []string{"reports:read"}}, Action: "read", Resource: "reports/1",
+				Context: params("", "client_ip", "192.0.2.1"),
+			},
+			wantAllowed: true,
+			wantPolicy:  "reports",
+		},
+		{
+			name: "deny blocked client",
+			input: &Input{
+				Principal: Principal{Scopes: []string{"reports:read"}}, Action: "read", Resource: "reports/1",
+				Context: params("", "client_ip", "203.0.113.1"),
+			},
+		},
+		{
+			name: "forbid overrides permit",
+			input: &Input{
+				Principal: Principal{Role: "admin"}, Action: "delete", Resource: "users/root", Context: params("root"),
+			},
+			wantPolicy: "protected",
+		},
+		{
+			name: "forbid failing to evaluate denies",
+			input: &Input{
+				Principal: Principal{Role: "admin"}, Action: "read", Resource: "users/1",
+				Context: map[string]any{"params": map[string]string{"id": "1"}},
+			},
+			wantPolicy: "blocked",
+		},
+		{
+			name: "forbid failing on attribute of another type denies",
+			input: &Input{
+				Principal: Principal{Role: "admin"}, Action: "read", Resource: "users/1",
+				Context: params("1", "blocked", "false"),
+			},
+			wantPolicy: "blocked",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			decision, err := engine.Evaluate(context.Background(), tt.input)
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantAllowed, decision.Allowed)
+			assert.Equal(t, tt.wantPolicy, decision.Policy)
+		})
+	}
+
+	t.Run("deny without policies", func(t *testing.T) {
+		t.Parallel()
+
+		empty, err := NewPolicyEngine("")
+		require.NoError(t, err)
+
+		decision, err := empty.Evaluate(context.Background(), &Input{Principal: Principal{Role: "admin"}})
+		require.NoError(t, err)
+		assert.False(t, decision.Allowed)
+	})
+
+	t.Run("name policies without id by position", func(t *testing.T) {
+		t.Parallel()
+
+		unnamed, err := NewPolicyEngine(`permit (principal, action, resource);`)
+		require.NoError(t, err)
+
+		decision, err := unnamed.Evaluate(context.Background(), &Input{})
+		require.NoError(t, err)
+		assert.True(t, decision.Allowed)
+		assert.Equal(t, "policy0", decision.Policy)
+	})
+
+	t.Run("read numbers and lists of json context", func(t *testing.T) {
+		t.Parallel()
+
+		limits, err := NewPolicyEngine(`permit (principal, action, resource)
+when { context.size <= 10 && context.tags.contains("public") && context.owner.active };`)
+		require.NoError(t, err)
+
+		var attributes map[string]any
+		require.NoError(t, json.Unmarshal(
+			[]byte(`{"size": 10, "tags": ["public"], "owner": {"active": true, "name": null}}`), &attributes,
+		))
+
+		decision, err := limits.Evaluate(context.Background(), &Input{Context: attributes})
+		require.NoError(t, err)
+		assert.True(t, decision.Allowed)
+	})
+
+	t.Run("return error for context of unsupported type", func(t *testing.T) {
+		t.Parallel()
+
+		for _, value := range []any{1.5, struct{}{}, []any{1e300}} {
+			_, err := engine.Evaluate(context.Background(), &Input{Context: map[string]any{"value": value}})
+			require.ErrorIs(t, err, ErrInvalidInput)
+		}
+	})
+}
+
+func TestEvaluate(t *testing.T) {
+	t.Parallel()
+
+	// newAuthz creates authorization evaluating with the engine, deciding by roles of the querier.
+	newAuthz := func(t *testing.T, querier *mockUserQuerier, engine Engine) *Authz {
+		t.Helper()
+
+		authz := setupTestAuthz(t, querier, nil)
+		authz.SetEngine(engine)
+
+		return authz
+	}
+
+	t.Run("evaluate with role on database", func(t *testing.T) {
+		t.Parallel()
+
+		engine := &mockEngine{}
+		authz := newAuthz(t, &mockUserQuerier{roles: map[string]string{"user1": "admin"}}, engine)
+
+		input := &Input{Principal: Principal{ID: "user1", Role: "user"}, Action: "read"}
+
+		decision, err := authz.Evaluate(context.Background(), input)
+		require.NoError(t, err)
+		assert.True(t, decision.Allowed)
+		assert.Equal(t, "admin", engine.input.Principal.Role)
+		assert.Equal(t, "user", input.Principal.Role)
+	})
+
+	t.Run("deny unknown user", func(t *testing.T) {
+		t.Parallel()
+
+		engine := &mockEngine{}
+		authz := newAuthz(t, &mockUserQuerier{roles: map[string]string{}}, engine)
+
+		decision, err := authz.Evaluate(context.Background(), &Input{Principal: Principal{ID: "user1"}})
+		require.NoError(t, err)
+		assert.False(t, decision.Allowed)
+		assert.Nil(t, engine.input)
+	})
+
+	t.Run("return error if role lookup fails", func(t *testing.T) {
+		t.Parallel()
+
+		authz := newAuthz(t, &mockUserQuerier{err: errQueryFailed}, &mockEngine{})
+
+		_, err := authz.Evaluate(context.Background(), &Input{Principal: Principal{ID: "user1"}})
+		require.ErrorIs(t, err, errQueryFailed)
+	})
+}
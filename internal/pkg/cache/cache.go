@@ -0,0 +1,296 @@
+// Package cache provides a typed cache on redis, values are encoded by a codec and stored with jittered TTLs,
+// missing values are cached as not found, and concurrent loads of a key are collapsed into one.
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"time"
+
+	goredis "github.com/redis/go-redis/v9"
+	"go.uber.org/fx"
+	"golang.org/x/sync/singleflight"
+
+	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/logger"
+	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/redis"
+)
+
+const (
+	// defaultPrefix is default prefix of cache keys.
+	defaultPrefix = "cache:"
+
+	// defaultTTL is default TTL of cached values.
+	defaultTTL = 5 * time.Minute
+
+	// defaultJitter is default fraction of TTLs randomly added or removed.
+	defaultJitter = 0.1
+
+	// defaultNegativeTTL is default TTL of values cached as not found.
+	defaultNegativeTTL = 30 * time.Second
+
+	// entryValue marks entries holding an encoded value.
+	entryValue byte = 'v'
+
+	// entryNotFound marks entries of values cached as not found.
+	entryNotFound byte = 'n'
+)
+
+var (
+	// ErrMiss is returned when the key is not cached.
+	ErrMiss = errors.New("cache miss")
+
+	// ErrNotFound is returned by loaders when the value does not exist, and for keys cached as not found.
+	ErrNotFound = errors.New("not found")
+)
+
+// Config represents configuration for cache.
+type Config struct {
+	// Prefix is prefix of cache keys.
+	Prefix *string `json:"prefix"`
+
+	// DefaultTTL is TTL of values set without a TTL.
+	DefaultTTL *time.Duration `json:"default_ttl"`
+
+	// Jitter is fraction of TTLs randomly added or removed, so that keys cached together do not expire together.
+	Jitter *float64 `json:"jitter"`
+
+	// NegativeTTL is TTL of values cached as not found, 0 to not cache them.
+	NegativeTTL *time.Duration `json:"negative_ttl"`
+}
+
+// SetDefault sets default values.
+func (c *Config) SetDefault() {
+	if c.Prefix == nil {
+		c.Prefix = &[]string{defaultPrefix}[0]
+	}
+
+	if c.DefaultTTL == nil {
+		c.DefaultTTL = &[]time.Duration{defaultTTL}[0]
+	}
+
+	if c.Jitter == nil {
+		c.Jitter = &[]float64{defaultJitter}[0]
+	}
+
+	if c.NegativeTTL == nil {
+		c.NegativeTTL = &[]time.Duration{defaultNegativeTTL}[0]
+	}
+}
+
+// Codec encodes and decodes cached values.
+type Codec interface {
+	// Marshal encodes the value.
+	Marshal(value any) ([]byte, error)
+
+	// Unmarshal decodes the data into the value.
+	Unmarshal(data []byte, value any) error
+}
+
+// JSONCodec encodes values as JSON.
+type JSONCodec struct{}
+
+// Marshal encodes the value as JSON.
+func (JSONCodec) Marshal(value any) ([]byte, error) {
+	return json.Marshal(value) //nolint:wrapcheck // errors are wrapped by the cache
+}
+
+// Unmarshal decodes the JSON data into the value.
+func (JSONCodec) Unmarshal(data []byte, value any) error {
+	return json.Unmarshal(data, value) //nolint:wrapcheck // errors are wrapped by the cache
+}
+
+// Cache provides a typed cache on redis, used through Get, Set and GetOrLoad.
+type Cache struct {
+	// config provides cache configuration.
+	config *Config
+
+	// redis provides redis client storing values.
+	redis *redis.Redis
+
+	// codec encodes cached values.
+	codec Codec
+
+	// logger provides logger.
+	logger *logger.Logger
+
+	// group collapses concurrent loads of a key.
+	group singleflight.Group
+
+	// random returns a random number in [0, 1), replaced in tests.
+	random func() float64
+}
+
+// NewModule provides module for cache.
+func NewModule() fx.Option {
+	return fx.Module("cache",
+		fx.Provide(New),
+	)
+}
+
+// New creates a new cache encoding values as JSON.
+func New(config *Config, redisConn *redis.Redis, logger *logger.Logger) *Cache {
+	return NewWithCodec(config, redisConn, JSONCodec{}, logger)
+}
+
+// NewWithCodec creates a new cache encoding values with the codec, e.g. a msgpack codec for compact values.
+func NewWithCodec(config *Config, redisConn *redis.Redis, codec Codec, logger *logger.Logger) *Cache {
+	if config == nil {
+		config = &Config{}
+	}
+
+	config.SetDefault()
+
+	return &Cache{
+		config: config,
+		redis:  redisConn,
+		codec:  codec,
+		logger: logger.Named("cache"),
+		random: rand.Float64,
+	}
+}
+
+// Delete deletes the keys, so that their values are loaded again.
+func (c *Cache) Delete(ctx context.Context, keys ...string) error {
+	prefixed := make([]string, 0, len(keys))
+	for _, key := range keys {
+		prefixed = append(prefixed, c.key(key))
+	}
+
+	if err := c.redis.Del(ctx, prefixed...).Err(); err != nil {
+		return fmt.Errorf("failed to delete cached keys: %w", err)
+	}
+
+	return nil
+}
+
+// key returns the redis key of the cache key.
+func (c *Cache) key(key string) string {
+	return *c.config.Prefix + key
+}
+
+// ttl returns the TTL, the default if 0, randomly extended or shortened by the jitter.
+func (c *Cache) ttl(ttl time.Duration) time.Duration {
+	if ttl <= 0 {
+		ttl = *c.config.DefaultTTL
+	}
+
+	return ttl + time.Duration((c.random()*2-1)*(*c.config.Jitter)*float64(ttl))
+}
+
+// get returns the entry of the key.
+func (c *Cache) get(ctx context.Context, key string) ([]byte, error) {
+	entry, err := c.redis.Get(ctx, c.key(key)).Bytes()
+	if errors.Is(err, goredis.Nil) {
+		return nil, ErrMiss
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to get cached key %s: %w", key, err)
+	}
+
+	if len(entry) == 0 {
+		return nil, ErrMiss
+	}
+
+	if entry[0] == entryNotFound {
+		return nil, ErrNotFound
+	}
+
+	return entry[1:], nil
+}
+
+// set stores the entry of the key with the TTL.
+func (c *Cache) set(ctx context.Context, key string, entry []byte, ttl time.Duration) error {
+	if err := c.redis.Set(ctx, c.key(key), entry, ttl).Err(); err != nil {
+		return fmt.Errorf("failed to cache key %s: %w", key, err)
+	}
+
+	return nil
+}
+
+// setNotFound caches the key as not found for the negative TTL, it does nothing if negative caching is disabled.
+func (c *Cache) setNotFound(ctx context.Context, key string) error {
+	if *c.config.NegativeTTL <= 0 {
+		return nil
+	}
+
+	return c.set(ctx, key, []byte{entryNotFound}, c.ttl(*c.config.NegativeTTL))
+}
+
+// Get returns the cached value of the key, ErrMiss if it is not cached and ErrNotFound if it is cached as not found.
+func Get[T any](ctx context.Context, c *Cache, key string) (T, error) {
+	var value T
+
+	data, err := c.get(ctx, key)
+	if err != nil {
+		return value, err
+	}
+
+	if err := c.codec.Unmarshal(data, &value); err != nil {
+		return value, fmt.Errorf("failed to decode cached key %s: %w", key, err)
+	}
+
+	return value, nil
+}
+
+// Set caches the value of the key for the TTL, the default TTL if 0.
+func Set[T any](ctx context.Context, c *Cache, key string, value T, ttl time.Duration) error {
+	data, err := c.codec.Marshal(value)
+	if err != nil {
+		return fmt.Errorf("failed to encode key %s: %w", key, err)
+	}
+
+	return c.set(ctx, key, append([]byte{entryValue}, data...), c.ttl(ttl))
+}
+
+// GetOrLoad returns the cached value of the key, loading and caching it for the TTL on a miss.
+// Concurrent misses of a key wait for a single load. When the loader returns ErrNotFound, the key is cached
+// as not found for the negative TTL and ErrNotFound is returned. The value is loaded as well if the cache
+// fails, so that an unavailable cache only adds load.
+func GetOrLoad[T any](
+	ctx context.Context,
+	c *Cache,
+	key string,
+	ttl time.Duration,
+	load func(ctx context.Context) (T, error),
+) (T, error) {
+	value, err := Get[T](ctx, c, key)
+	if err == nil || errors.Is(err, ErrNotFound) {
+		return value, err
+	}
+
+	if !errors.Is(err, ErrMiss) {
+		c.logger.Ctx(ctx).Warn().Err(err).Str("key", key).Msg("failed to get cached value, loading it")
+	}
+
+	// the load is shared, so it is not canceled with the request starting it
+	result, err, _ := c.group.Do(key, func() (any, error) {
+		loadCtx := context.WithoutCancel(ctx)
+
+		value, err := load(loadCtx)
+		if errors.Is(err, ErrNotFound) {
+			if err := c.setNotFound(loadCtx, key); err != nil {
+				c.logger.Ctx(ctx).Warn().Err(err).Str("key", key).Msg("failed to cache value as not found")
+			}
+
+			return value, ErrNotFound
+		}
+
+		if err != nil {
+			return value, err
+		}
+
+		if err := Set(loadCtx, c, key, value, ttl); err != nil {
+			c.logger.Ctx(ctx).Warn().Err(err).Str("key", key).Msg("failed to cache loaded value")
+		}
+
+		return value, nil
+	})
+
+	value, _ = result.(T)
+
+	return value, err
+}
@@ -0,0 +1,262 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/logger"
+	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/redis"
+)
+
+var errLoadFailed = errors.New("load failed")
+
+// testUser is a value cached in tests.
+type testUser struct {
+	ID    string `json:"id"`
+	Email string `json:"email"`
+}
+
+// setupTestCache creates a cache on the test redis server with keys prefixed by the test name.
+func setupTestCache(t *testing.T, config *Config) *Cache {
+	t.Helper()
+
+	log, err := logger.New(&logger.Config{})
+	require.NoError(t, err)
+
+	password := ""
+	redisDB := 0
+
+	redisClient, err := redis.New(&redis.Config{
+		Addrs:    []string{"localhost:36379"},
+		Password: &password,
+		DB:       &redisDB,
+	})
+	require.NoError(t, err)
+
+	t.Cleanup(func() {
+		_ = redisClient.Close()
+	})
+
+	if config == nil {
+		config = &Config{}
+	}
+
+	config.Prefix = &[]string{fmt.Sprintf("cache:%s:%d:", t.Name(), time.Now().UnixNano())}[0]
+
+	return New(config, redisClient, log)
+}
+
+func TestConfigSetDefault(t *testing.T) {
+	t.Parallel()
+
+	config := &Config{}
+	config.SetDefault()
+
+	assert.Equal(t, defaultPrefix, *config.Prefix)
+	assert.Equal(t, defaultTTL, *config.DefaultTTL)
+	assert.InDelta(t, defaultJitter, *config.Jitter, 0)
+	assert.Equal(t, defaultNegativeTTL, *config.NegativeTTL)
+}
+
+func TestNewModule(t *testing.T) {
+	t.Parallel()
+
+	t.Run("return fx.Option", func(t *testing.T) {
+		t.Parallel()
+
+		require.NotNil(t, NewModule())
+	})
+}
+
+func TestGetSet(t *testing.T) {
+	t.Parallel()
+
+	t.Run("return miss of key not cached", func(t *testing.T) {
+		t.Parallel()
+
+		cache := setupTestCache(t, nil)
+
+		_, err := Get[testUser](context.Background(), cache, "user:1")
+		require.ErrorIs(t, err, ErrMiss)
+	})
+
+	t.Run("return cached value", func(t *testing.T) {
+		t.Parallel()
+
+		cache := setupTestCache(t, nil)
+		ctx := context.Background()
+
+		require.NoError(t, Set(ctx, cache, "user:1", testUser{ID: "1", Email: "user@example.com"}, time.Minute))
+
+		user, err := Get[testUser](ctx, cache, "user:1")
+		require.NoError(t, err)
+		assert.Equal(t, testUser{ID: "1", Email: "user@example.com"}, user)
+
+		// pointers and slices are cached as well
+		require.NoError(t, Set(ctx, cache, "ids", []string{"a", "b"}, 0))
+
+		ids, err := Get[[]string](ctx, cache, "ids")
+		require.NoError(t, err)
+		assert.Equal(t, []string{"a", "b"}, ids)
+
+		require.NoError(t, cache.Delete(ctx, "user:1", "ids"))
+
+		_, err = Get[testUser](ctx, cache, "user:1")
+		require.ErrorIs(t, err, ErrMiss)
+	})
+
+	t.Run("return error of value of another type", func(t *testing.T) {
+		t.Parallel()
+
+		cache := setupTestCache(t, nil)
+		ctx := context.Background()
+
+		require.NoError(t, Set(ctx, cache, "count", 3, 0))
+
+		_, err := Get[testUser](ctx, cache, "count")
+		require.Error(t, err)
+		require.NotErrorIs(t, err, ErrMiss)
+	})
+
+	t.Run("jitter ttl", func(t *testing.T) {
+		t.Parallel()
+
+		cache := setupTestCache(t, &Config{Jitter: &[]float64{0.5}[0]})
+		ctx := context.Background()
+
+		cache.random = func() float64 { return 0 }
+		require.NoError(t, Set(ctx, cache, "short", 1, time.Hour))
+
+		cache.random = func() float64 { return 0.999 }
+		require.NoError(t, Set(ctx, cache, "long", 1, 0))
+
+		ttl, err := cache.redis.TTL(ctx, cache.key("short")).Result()
+		require.NoError(t, err)
+		assert.Equal(t, 30*time.Minute, ttl)
+
+		ttl, err = cache.redis.TTL(ctx, cache.key("long")).Result()
+		require.NoError(t, err)
+		assert.InDelta(t, float64(7*time.Minute+30*time.Second), float64(ttl), float64(time.Second))
+	})
+}
+
+func TestGetOrLoad(t *testing.T) {
+	t.Parallel()
+
+	t.Run("load value once and serve it from cache", func(t *testing.T) {
+		t.Parallel()
+
+		cache := setupTestCache(t, nil)
+
+		var loads atomic.Int32
+
+		release := make(chan struct{})
+		load := func(context.Context) (*testUser, error) {
+			loads.Add(1)
+			<-release
+
+			return &testUser{ID: "1"}, nil
+		}
+
+		var wg sync.WaitGroup
+
+		for range 5 {
+			wg.Go(func() {
+				user, err := GetOrLoad(context.Background(), cache, "user:1", time.Minute, load)
+				assert.NoError(t, err)
+				assert.Equal(t, "1", user.ID)
+			})
+		}
+
+		// concurrent misses wait for the first load
+		assert.Eventually(t, func() bool { return loads.Load() == 1 }, time.Second, time.Millisecond)
+		close(release)
+		wg.Wait()
+
+		loaded := loads.Load()
+
+		user, err := GetOrLoad(context.Background(), cache, "user:1", time.Minute, load)
+		require.NoError(t, err)
+		assert.Equal(t, "1", user.ID)
+		assert.Equal(t, loaded, loads.Load())
+
+		cached, err := Get[*testUser](context.Background(), cache, "user:1")
+		require.NoError(t, err)
+		assert.Equal(t, "1", cached.ID)
+	})
+
+	t.Run("cache value not found", func(t *testing.T) {
+		t.Parallel()
+
+		cache := setupTestCache(t, nil)
+
+		var loads atomic.Int32
+
+		load := func(context.Context) (*testUser, error) {
+			loads.Add(1)
+
+			return nil, fmt.Errorf("user 1: %w", ErrNotFound)
+		}
+
+		for range 2 {
+			user, err := GetOrLoad(context.Background(), cache, "user:1", time.Minute, load)
+			require.ErrorIs(t, err, ErrNotFound)
+			assert.Nil(t, user)
+		}
+
+		assert.Equal(t, int32(1), loads.Load())
+
+		ttl, err := cache.redis.TTL(context.Background(), cache.key("user:1")).Result()
+		require.NoError(t, err)
+		assert.LessOrEqual(t, ttl, defaultNegativeTTL+defaultNegativeTTL/10)
+	})
+
+	t.Run("not cache value not found without negative ttl", func(t *testing.T) {
+		t.Parallel()
+
+		cache := setupTestCache(t, &Config{NegativeTTL: &[]time.Duration{0}[0]})
+
+		_, err := GetOrLoad(context.Background(), cache, "user:1", 0, func(context.Context) (int, error) {
+			return 0, ErrNotFound
+		})
+		require.ErrorIs(t, err, ErrNotFound)
+
+		_, err = Get[int](context.Background(), cache, "user:1")
+		require.ErrorIs(t, err, ErrMiss)
+	})
+
+	t.Run("return load error without caching", func(t *testing.T) {
+		t.Parallel()
+
+		cache := setupTestCache(t, nil)
+
+		_, err := GetOrLoad(context.Background(), cache, "user:1", 0, func(context.Context) (int, error) {
+			return 0, errLoadFailed
+		})
+		require.ErrorIs(t, err, errLoadFailed)
+
+		_, err = Get[int](context.Background(), cache, "user:1")
+		require.ErrorIs(t, err, ErrMiss)
+	})
+
+	t.Run("load value when cache fails", func(t *testing.T) {
+		t.Parallel()
+
+		cache := setupTestCache(t, nil)
+		require.NoError(t, cache.redis.Close())
+
+		value, err := GetOrLoad(context.Background(), cache, "user:1", 0, func(context.Context) (int, error) {
+			return 42, nil
+		})
+		require.NoError(t, err)
+		assert.Equal(t, 42, value)
+	})
+}
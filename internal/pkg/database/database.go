@@ -7,13 +7,17 @@ import (
 	"errors"
 	"fmt"
 	"math"
+	"net/url"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/jackc/pgx/v5/stdlib"
 	"go.uber.org/fx"
 
 	"github.com/pocj8ur4in/boilerplate-go/internal/gen/db"
+	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/health"
 )
 
 var (
@@ -22,6 +26,9 @@ var (
 
 	// ErrMaxIdleExceedsLimit returned when max_idle exceeds int32 limit.
 	ErrMaxIdleExceedsLimit = errors.New("max_idle exceeds int32 limit")
+
+	// ErrInvalidURL returned when url is not a postgres URL.
+	ErrInvalidURL = errors.New("invalid database url")
 )
 
 // DB represents database.
@@ -31,13 +38,25 @@ type DB struct {
 
 	// Queries provides database queries.
 	Queries *db.Queries
+
+	// pool begins transactions of WithTx.
+	pool txBeginner
+
+	// txConfig provides configuration for retrying transactions.
+	txConfig *TxConfig
 }
 
 // Config represents configuration for database.
 type Config struct {
+	// URL is postgres:// URL of database with query parameters, overriding the connection fields below if set.
+	URL *string `json:"url"`
+
 	// Host is host of database.
 	Host *string `json:"host"`
 
+	// SocketDir is directory of the unix socket of database, used instead of host if set.
+	SocketDir *string `json:"socket_dir"`
+
 	// Port is port of database.
 	Port *int `json:"port"`
 
@@ -58,6 +77,52 @@ type Config struct {
 
 	// MaxIdle is maximum number of idle connections to database.
 	MaxIdle *int `json:"max_idle"`
+
+	// Migrations is credentials of database for running migrations.
+	Migrations *MigrationsConfig `json:"migrations"`
+
+	// AutoMigrate is whether pending migrations are applied on start, instances starting together
+	// wait on an advisory lock for at most Migrations.LockTimeout so they are applied once.
+	AutoMigrate *bool `json:"auto_migrate"`
+
+	// Transaction is configuration for retrying transactions of WithTx.
+	Transaction *TxConfig `json:"transaction"`
+}
+
+// MigrationsConfig represents credentials of database for running migrations,
+// so that schema changes use a DDL-capable role while the runtime pool uses a restricted one.
+// Empty fields fall back to the runtime connection.
+type MigrationsConfig struct {
+	// URL is postgres:// URL of database for migrations, overriding the fields below if set.
+	URL *string `json:"url"`
+
+	// User is user of database for migrations.
+	User *string `json:"user"`
+
+	// Password is password of database for migrations.
+	Password *string `json:"password"`
+
+	// LockTimeout is maximum duration of waiting for another instance applying migrations, 0 to wait indefinitely.
+	LockTimeout *time.Duration `json:"lock_timeout"`
+}
+
+// SetDefault sets default values.
+func (c *MigrationsConfig) SetDefault() {
+	if c.URL == nil {
+		c.URL = &[]string{""}[0]
+	}
+
+	if c.User == nil {
+		c.User = &[]string{""}[0]
+	}
+
+	if c.Password == nil {
+		c.Password = &[]string{""}[0]
+	}
+
+	if c.LockTimeout == nil {
+		c.LockTimeout = &[]time.Duration{defaultMigrationLockTimeout}[0]
+	}
 }
 
 const (
@@ -84,15 +149,26 @@ const (
 
 	// defaultMaxIdle is default maximum number of idle connections to database.
 	defaultMaxIdle = 5
+
+	// defaultMigrationLockTimeout is default maximum duration of waiting for another instance applying migrations.
+	defaultMigrationLockTimeout = 5 * time.Minute
 )
 
 // SetDefault sets default values.
 func (c *Config) SetDefault() {
+	if c.URL == nil {
+		c.URL = &[]string{""}[0]
+	}
+
 	if c.Host == nil {
 		host := defaultHost
 		c.Host = &host
 	}
 
+	if c.SocketDir == nil {
+		c.SocketDir = &[]string{""}[0]
+	}
+
 	if c.Port == nil {
 		port := defaultPort
 		c.Port = &port
@@ -127,15 +203,42 @@ func (c *Config) SetDefault() {
 		maxIdle := defaultMaxIdle
 		c.MaxIdle = &maxIdle
 	}
+
+	if c.Migrations == nil {
+		c.Migrations = &MigrationsConfig{}
+	}
+
+	c.Migrations.SetDefault()
+
+	// set auto migrate
+	if c.AutoMigrate == nil {
+		c.AutoMigrate = &[]bool{false}[0]
+	}
+
+	if c.Transaction == nil {
+		c.Transaction = &TxConfig{}
+	}
+
+	c.Transaction.SetDefault()
 }
 
 // NewModule provides module for database.
 func NewModule() fx.Option {
 	return fx.Module("database",
 		fx.Provide(New),
+		fx.Invoke(registerHealthCheck),
 	)
 }
 
+// registerHealthCheck registers the ping of the database as readiness check.
+func registerHealthCheck(registry *health.Registry, database *DB) error {
+	if err := registry.Register("database", 0, health.CheckerFunc(database.PingContext)); err != nil {
+		return fmt.Errorf("failed to register database health check: %w", err)
+	}
+
+	return nil
+}
+
 // New creates new database instance.
 func New(config *Config) (*DB, error) {
 	ctx := context.Background()
@@ -147,16 +250,11 @@ func New(config *Config) (*DB, error) {
 
 	config.SetDefault()
 
-	// build database connection string
-	sslmodeStr := "disable"
-	if *config.SSLMode {
-		sslmodeStr = "require"
+	connString, err := buildConnString(config)
+	if err != nil {
+		return nil, err
 	}
 
-	connString := "host=" + *config.Host + " port=" + strconv.Itoa(*config.Port) +
-		" user=" + *config.User + " password=" + *config.Password + " dbname=" + *config.DBName +
-		" sslmode=" + sslmodeStr
-
 	// parse database connection pool config
 	poolConfig, err := pgxpool.ParseConfig(connString)
 	if err != nil {
@@ -177,6 +275,9 @@ func New(config *Config) (*DB, error) {
 	// #nosec G115 -- validated above
 	poolConfig.MinConns = int32(*config.MaxIdle)
 
+	// record a span of each query in the trace of the request
+	poolConfig.ConnConfig.Tracer = spanTracer{}
+
 	// create database connection pool
 	connPool, err := pgxpool.NewWithConfig(context.Background(), poolConfig)
 	if err != nil {
@@ -191,11 +292,107 @@ func New(config *Config) (*DB, error) {
 		return nil, fmt.Errorf("failed to ping database: %w", err)
 	}
 
-	// create queries using database connection pool
-	queries := db.New(connPool)
+	// create queries using database connection pool, attaching SQL statement to errors
+	queries := db.New(&queryTracer{db: connPool})
 
 	return &DB{
-		DB:      sqlDB,
-		Queries: queries,
+		DB:       sqlDB,
+		Queries:  queries,
+		pool:     connPool,
+		txConfig: config.Transaction,
 	}, nil
 }
+
+// buildConnString builds the connection string of database, the URL as is if set,
+// otherwise a keyword/value string of the connection fields.
+func buildConnString(config *Config) (string, error) {
+	if *config.URL != "" {
+		parsed, err := url.Parse(*config.URL)
+		if err != nil || (parsed.Scheme != "postgres" && parsed.Scheme != "postgresql") {
+			return "", fmt.Errorf("%w: scheme must be postgres or postgresql", ErrInvalidURL)
+		}
+
+		return *config.URL, nil
+	}
+
+	sslMode := "disable"
+	if *config.SSLMode {
+		sslMode = "require"
+	}
+
+	// a host starting with a slash is the directory of the unix socket
+	host := *config.Host
+	if *config.SocketDir != "" {
+		host = *config.SocketDir
+	}
+
+	params := [][2]string{
+		{"host", host},
+		{"port", strconv.Itoa(*config.Port)},
+		{"user", *config.User},
+		{"password", *config.Password},
+		{"dbname", *config.DBName},
+		{"sslmode", sslMode},
+	}
+
+	pairs := make([]string, 0, len(params))
+	for _, param := range params {
+		pairs = append(pairs, param[0]+"="+quoteConnValue(param[1]))
+	}
+
+	return strings.Join(pairs, " "), nil
+}
+
+// MigrationConnString returns the connection string of database for running migrations,
+// the runtime connection with the migration URL or credentials applied.
+func (c *Config) MigrationConnString() (string, error) {
+	c.SetDefault()
+
+	migrations := c.Migrations
+	if *migrations.URL != "" {
+		return buildConnString(&Config{URL: migrations.URL})
+	}
+
+	config := *c
+	if *migrations.User != "" {
+		config.User = migrations.User
+	}
+
+	if *migrations.Password != "" {
+		config.Password = migrations.Password
+	}
+
+	if *config.URL == "" {
+		return buildConnString(&config)
+	}
+
+	// replace the credentials of the runtime URL
+	if _, err := buildConnString(&config); err != nil {
+		return "", err
+	}
+
+	parsed, err := url.Parse(*config.URL)
+	if err != nil {
+		return "", ErrInvalidURL
+	}
+
+	user := parsed.User.Username()
+	password, _ := parsed.User.Password()
+
+	if *migrations.User != "" {
+		user = *migrations.User
+	}
+
+	if *migrations.Password != "" {
+		password = *migrations.Password
+	}
+
+	parsed.User = url.UserPassword(user, password)
+
+	return parsed.String(), nil
+}
+
+// quoteConnValue quotes the value of a keyword/value connection string, so that values may contain spaces and quotes.
+func quoteConnValue(value string) string {
+	return "'" + strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(value) + "'"
+}
@@ -0,0 +1,124 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+
+	"github.com/pocj8ur4in/boilerplate-go/internal/gen/db"
+)
+
+var (
+	// commentPattern matches line and block comments of SQL statement.
+	commentPattern = regexp.MustCompile(`(?s)--[^\n]*|/\*.*?\*/`)
+
+	// stringPattern matches string literals of SQL statement.
+	stringPattern = regexp.MustCompile(`'(?:[^']|'')*'`)
+
+	// numberPattern matches numeric literals of SQL statement, except positional parameters.
+	numberPattern = regexp.MustCompile(`(^|[^\w$])\d+(?:\.\d+)?`)
+
+	// spacePattern matches consecutive whitespaces of SQL statement.
+	spacePattern = regexp.MustCompile(`\s+`)
+)
+
+// QueryError represents an error of failed query with its SQL statement.
+type QueryError struct {
+	// Query is SQL statement of the failed query.
+	Query string
+
+	// Err is the underlying error.
+	Err error
+}
+
+// Error returns message of the underlying error.
+func (e *QueryError) Error() string {
+	return e.Err.Error()
+}
+
+// Unwrap returns the underlying error.
+func (e *QueryError) Unwrap() error {
+	return e.Err
+}
+
+// SanitizedQuery returns SQL statement of the failed query without comments and literal values.
+func (e *QueryError) SanitizedQuery() string {
+	return SanitizeQuery(e.Query)
+}
+
+// SanitizeQuery removes comments from SQL statement and replaces literal values with placeholders.
+func SanitizeQuery(query string) string {
+	query = commentPattern.ReplaceAllString(query, " ")
+	query = stringPattern.ReplaceAllString(query, "'?'")
+	query = numberPattern.ReplaceAllString(query, "${1}?")
+
+	return strings.TrimSpace(spacePattern.ReplaceAllString(query, " "))
+}
+
+// wrapQueryError wraps the error of query with its SQL statement, except for no rows.
+func wrapQueryError(query string, err error) error {
+	if err == nil || errors.Is(err, pgx.ErrNoRows) {
+		return err
+	}
+
+	return &QueryError{Query: query, Err: err}
+}
+
+// queryTracer wraps connection of database to attach SQL statement to errors of queries.
+type queryTracer struct {
+	db db.DBTX
+}
+
+// Exec executes the SQL statement.
+func (t *queryTracer) Exec(ctx context.Context, sql string, args ...interface{}) (pgconn.CommandTag, error) {
+	tag, err := t.db.Exec(ctx, sql, args...)
+
+	return tag, wrapQueryError(sql, err)
+}
+
+// Query executes the SQL statement returning rows.
+func (t *queryTracer) Query(ctx context.Context, sql string, args ...interface{}) (pgx.Rows, error) {
+	rows, err := t.db.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, wrapQueryError(sql, err)
+	}
+
+	return &tracedRows{Rows: rows, query: sql}, nil
+}
+
+// QueryRow executes the SQL statement returning at most one row.
+func (t *queryTracer) QueryRow(ctx context.Context, sql string, args ...interface{}) pgx.Row {
+	return &tracedRow{row: t.db.QueryRow(ctx, sql, args...), query: sql}
+}
+
+// tracedRows represents rows of query attaching SQL statement to errors.
+type tracedRows struct {
+	pgx.Rows
+
+	query string
+}
+
+// Scan reads values of the current row.
+func (r *tracedRows) Scan(dest ...interface{}) error {
+	return wrapQueryError(r.query, r.Rows.Scan(dest...))
+}
+
+// Err returns error occurred while reading rows.
+func (r *tracedRows) Err() error {
+	return wrapQueryError(r.query, r.Rows.Err())
+}
+
+// tracedRow represents row of query attaching SQL statement to errors.
+type tracedRow struct {
+	row   pgx.Row
+	query string
+}
+
+// Scan reads values of the row.
+func (r *tracedRow) Scan(dest ...interface{}) error {
+	return wrapQueryError(r.query, r.row.Scan(dest...))
+}
@@ -0,0 +1,124 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// errConnectionReset is the test error of a reset connection.
+var errConnectionReset = errors.New("connection reset by peer")
+
+// mockDBTX is a connection of database returning the error for all queries.
+type mockDBTX struct {
+	err error
+}
+
+func (m *mockDBTX) Exec(context.Context, string, ...interface{}) (pgconn.CommandTag, error) {
+	return pgconn.CommandTag{}, m.err
+}
+
+func (m *mockDBTX) Query(context.Context, string, ...interface{}) (pgx.Rows, error) {
+	return nil, m.err
+}
+
+func (m *mockDBTX) QueryRow(context.Context, string, ...interface{}) pgx.Row {
+	return &mockRow{err: m.err}
+}
+
+// mockRow is a row returning the error on scan.
+type mockRow struct {
+	err error
+}
+
+func (m *mockRow) Scan(...interface{}) error {
+	return m.err
+}
+
+func TestSanitizeQuery(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name     string
+		query    string
+		expected string
+	}{
+		{
+			"remove sqlc name comment",
+			"-- name: GetSetting :one\nSELECT value FROM settings\nWHERE scope = $1 AND key = $2",
+			"SELECT value FROM settings WHERE scope = $1 AND key = $2",
+		},
+		{
+			"replace string literals",
+			"SELECT * FROM users WHERE email = 'user@example.com' AND name = 'O''Brien'",
+			"SELECT * FROM users WHERE email = '?' AND name = '?'",
+		},
+		{
+			"replace numeric literals",
+			"SELECT * FROM users WHERE age > 18 AND score < 9.5 LIMIT 10",
+			"SELECT * FROM users WHERE age > ? AND score < ? LIMIT ?",
+		},
+		{
+			"keep identifiers with digits",
+			"SELECT col1 FROM table2 /* block comment */ WHERE id = $1",
+			"SELECT col1 FROM table2 WHERE id = $1",
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			t.Parallel()
+
+			assert.Equal(t, testCase.expected, SanitizeQuery(testCase.query))
+		})
+	}
+}
+
+func TestQueryTracer(t *testing.T) {
+	t.Parallel()
+
+	query := "SELECT value FROM settings WHERE key = 'theme'"
+
+	t.Run("attach query to errors", func(t *testing.T) {
+		t.Parallel()
+
+		tracer := &queryTracer{db: &mockDBTX{err: errConnectionReset}}
+
+		_, execErr := tracer.Exec(context.Background(), query)
+		_, queryErr := tracer.Query(context.Background(), query)
+		scanErr := tracer.QueryRow(context.Background(), query).Scan()
+
+		for _, err := range []error{execErr, queryErr, scanErr} {
+			var target *QueryError
+
+			require.ErrorAs(t, err, &target)
+			require.ErrorIs(t, err, errConnectionReset)
+			assert.Equal(t, query, target.Query)
+			assert.Equal(t, "SELECT value FROM settings WHERE key = '?'", target.SanitizedQuery())
+			assert.Equal(t, errConnectionReset.Error(), err.Error())
+		}
+	})
+
+	t.Run("keep no rows error unwrapped", func(t *testing.T) {
+		t.Parallel()
+
+		tracer := &queryTracer{db: &mockDBTX{err: pgx.ErrNoRows}}
+
+		assert.Equal(t, pgx.ErrNoRows, tracer.QueryRow(context.Background(), query).Scan())
+	})
+
+	t.Run("return no error on success", func(t *testing.T) {
+		t.Parallel()
+
+		tracer := &queryTracer{db: &mockDBTX{}}
+
+		_, err := tracer.Exec(context.Background(), query)
+		require.NoError(t, err)
+		require.NoError(t, tracer.QueryRow(context.Background(), query).Scan())
+	})
+}
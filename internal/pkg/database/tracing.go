@@ -0,0 +1,66 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// tracerName is the name of the tracer used for database spans.
+const tracerName = "github.com/pocj8ur4in/boilerplate-go/internal/pkg/database"
+
+// queryNamePattern matches the name comment of queries generated by sqlc.
+var queryNamePattern = regexp.MustCompile(`^\s*-- name: (\w+)`)
+
+// spanTracer is a pgx query tracer that records a span of each query, named after the sqlc query if generated.
+type spanTracer struct{}
+
+// TraceQueryStart starts a span of the query.
+func (spanTracer) TraceQueryStart(ctx context.Context, _ *pgx.Conn, data pgx.TraceQueryStartData) context.Context {
+	operation := queryOperation(data.SQL)
+
+	name := operation
+	if match := queryNamePattern.FindStringSubmatch(data.SQL); match != nil {
+		name = match[1]
+	}
+
+	ctx, _ = otel.Tracer(tracerName).Start(ctx, name, trace.WithSpanKind(trace.SpanKindClient), trace.WithAttributes(
+		attribute.String("db.system.name", "postgresql"),
+		attribute.String("db.operation.name", operation),
+		attribute.String("db.query.text", SanitizeQuery(data.SQL)),
+	))
+
+	return ctx
+}
+
+// TraceQueryEnd ends the span of the query, recording errors except for no rows.
+func (spanTracer) TraceQueryEnd(ctx context.Context, _ *pgx.Conn, data pgx.TraceQueryEndData) {
+	span := trace.SpanFromContext(ctx)
+	defer span.End()
+
+	if data.Err != nil && !errors.Is(data.Err, pgx.ErrNoRows) {
+		span.RecordError(data.Err)
+		span.SetStatus(codes.Error, "query failed")
+
+		return
+	}
+
+	span.SetAttributes(attribute.Int64("db.response.rows_affected", data.CommandTag.RowsAffected()))
+}
+
+// queryOperation returns the first keyword of the SQL statement, such as SELECT.
+func queryOperation(query string) string {
+	fields := strings.Fields(commentPattern.ReplaceAllString(query, " "))
+	if len(fields) == 0 {
+		return "query"
+	}
+
+	return strings.ToUpper(fields[0])
+}
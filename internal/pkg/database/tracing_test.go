@@ -0,0 +1,95 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+var errRelationNotExist = errors.New("relation does not exist")
+
+func TestQueryOperation(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name  string
+		query string
+		want  string
+	}{
+		{name: "first keyword", query: "select * from users", want: "SELECT"},
+		{name: "skip comments", query: "-- name: GetUser :one\nSELECT * FROM users", want: "SELECT"},
+		{name: "empty query", query: "  ", want: "query"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			assert.Equal(t, tt.want, queryOperation(tt.query))
+		})
+	}
+}
+
+//nolint:paralleltest // sequential execution required to replace the global tracer provider
+func TestSpanTracer(t *testing.T) {
+	spanRecorder := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(spanRecorder)))
+	t.Cleanup(func() { otel.SetTracerProvider(noop.NewTracerProvider()) })
+
+	// trace records the query as a child span of a request, returns the span of the query.
+	trace := func(t *testing.T, query string, err error) sdktrace.ReadOnlySpan {
+		t.Helper()
+
+		ctx, parent := otel.Tracer("test").Start(context.Background(), "request")
+
+		tracer := spanTracer{}
+		ctx = tracer.TraceQueryStart(ctx, nil, pgx.TraceQueryStartData{SQL: query})
+		tracer.TraceQueryEnd(ctx, nil, pgx.TraceQueryEndData{CommandTag: pgconn.NewCommandTag("UPDATE 2"), Err: err})
+
+		parent.End()
+
+		for _, span := range spanRecorder.Ended() {
+			if span.Parent().SpanID() == parent.SpanContext().SpanID() {
+				return span
+			}
+		}
+
+		require.FailNow(t, "query span not recorded")
+
+		return nil
+	}
+
+	t.Run("name span after sqlc query with sanitized statement", func(t *testing.T) {
+		span := trace(t, "-- name: UpdateUserName :execrows\nUPDATE users SET name = 'alice' WHERE id = $1", nil)
+
+		assert.Equal(t, "UpdateUserName", span.Name())
+		assert.Contains(t, span.Attributes(), attribute.String("db.system.name", "postgresql"))
+		assert.Contains(t, span.Attributes(), attribute.String("db.operation.name", "UPDATE"))
+		assert.Contains(t, span.Attributes(), attribute.String("db.query.text", "UPDATE users SET name = '?' WHERE id = $1"))
+		assert.Contains(t, span.Attributes(), attribute.Int64("db.response.rows_affected", 2))
+	})
+
+	t.Run("record failed query", func(t *testing.T) {
+		span := trace(t, "SELECT * FROM missing", errRelationNotExist)
+
+		assert.Equal(t, "SELECT", span.Name())
+		assert.Equal(t, codes.Error, span.Status().Code)
+	})
+
+	t.Run("ignore no rows", func(t *testing.T) {
+		span := trace(t, "SELECT * FROM users", pgx.ErrNoRows)
+
+		assert.Equal(t, codes.Unset, span.Status().Code)
+	})
+}
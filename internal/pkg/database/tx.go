@@ -0,0 +1,158 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+
+	"github.com/pocj8ur4in/boilerplate-go/internal/gen/db"
+)
+
+const (
+	// serializationFailure is postgres error code of serialization failures.
+	serializationFailure = "40001"
+
+	// deadlockDetected is postgres error code of detected deadlocks.
+	deadlockDetected = "40P01"
+
+	// defaultTxMaxAttempts is default maximum number of attempts of a transaction.
+	defaultTxMaxAttempts = 3
+
+	// defaultTxInitialBackoff is default delay before the second attempt of a transaction.
+	defaultTxInitialBackoff = 10 * time.Millisecond
+
+	// defaultTxMaxBackoff is default maximum delay between attempts of a transaction.
+	defaultTxMaxBackoff = 500 * time.Millisecond
+)
+
+// ErrNoPool is returned when a transaction is begun on database without connection pool.
+var ErrNoPool = errors.New("database has no connection pool")
+
+// TxConfig represents configuration for retrying transactions.
+type TxConfig struct {
+	// MaxAttempts is maximum number of attempts of a transaction failing with serialization failures or deadlocks.
+	MaxAttempts *int `json:"max_attempts"`
+
+	// InitialBackoff is delay before the second attempt, doubled on each further attempt.
+	InitialBackoff *time.Duration `json:"initial_backoff"`
+
+	// MaxBackoff is maximum delay between attempts.
+	MaxBackoff *time.Duration `json:"max_backoff"`
+}
+
+// SetDefault sets default values.
+func (c *TxConfig) SetDefault() {
+	if c.MaxAttempts == nil {
+		c.MaxAttempts = &[]int{defaultTxMaxAttempts}[0]
+	}
+
+	if c.InitialBackoff == nil {
+		c.InitialBackoff = &[]time.Duration{defaultTxInitialBackoff}[0]
+	}
+
+	if c.MaxBackoff == nil {
+		c.MaxBackoff = &[]time.Duration{defaultTxMaxBackoff}[0]
+	}
+}
+
+// txBeginner begins transactions, implemented by pgxpool.Pool.
+type txBeginner interface {
+	BeginTx(ctx context.Context, options pgx.TxOptions) (pgx.Tx, error)
+}
+
+// IsRetryable returns whether the error is a serialization failure or a deadlock,
+// after which the transaction succeeds if run again.
+func IsRetryable(err error) bool {
+	var pgErr *pgconn.PgError
+
+	return errors.As(err, &pgErr) && (pgErr.Code == serializationFailure || pgErr.Code == deadlockDetected)
+}
+
+// WithTx runs fn in a transaction with default options, see WithTxOptions.
+func (d *DB) WithTx(ctx context.Context, fn func(queries *db.Queries) error) error {
+	return d.WithTxOptions(ctx, pgx.TxOptions{}, fn)
+}
+
+// WithTxOptions runs fn in a transaction with the options, giving it queries of the transaction.
+// The transaction is committed if fn returns nil and rolled back otherwise, and it is run again
+// with exponential backoff when it fails with a serialization failure or a deadlock, so fn must wrap
+// errors of queries with %w and must not have side effects outside the transaction.
+func (d *DB) WithTxOptions(ctx context.Context, options pgx.TxOptions, fn func(queries *db.Queries) error) error {
+	if d.pool == nil {
+		return ErrNoPool
+	}
+
+	config := d.txConfig
+	if config == nil {
+		config = &TxConfig{}
+		config.SetDefault()
+	}
+
+	backoff := *config.InitialBackoff
+
+	for attempt := 1; ; attempt++ {
+		err := d.runTx(ctx, options, fn)
+		if err == nil || !IsRetryable(err) {
+			return err
+		}
+
+		if attempt >= *config.MaxAttempts {
+			return fmt.Errorf("transaction failed after %d attempts: %w", attempt, err)
+		}
+
+		// wait half of the backoff and a random part of the other half, spreading retries of conflicting transactions
+		// #nosec G404 -- jitter does not need cryptographic randomness
+		delay := backoff/2 + time.Duration(rand.Int64N(int64(backoff/2)+1))
+
+		timer := time.NewTimer(delay)
+
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+
+			return fmt.Errorf("transaction retry canceled: %w", errors.Join(err, ctx.Err()))
+		case <-timer.C:
+		}
+
+		backoff = min(backoff*2, *config.MaxBackoff)
+	}
+}
+
+// runTx runs fn in a transaction once.
+func (d *DB) runTx(ctx context.Context, options pgx.TxOptions, fn func(queries *db.Queries) error) (err error) {
+	tx, err := d.pool.BeginTx(ctx, options)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+
+	committed := false
+
+	// roll back on errors and panics of fn, a transaction failing to commit is already closed
+	defer func() {
+		if committed {
+			return
+		}
+
+		rollbackErr := tx.Rollback(ctx)
+		if rollbackErr != nil && !errors.Is(rollbackErr, pgx.ErrTxClosed) {
+			err = errors.Join(err, fmt.Errorf("failed to roll back transaction: %w", rollbackErr))
+		}
+	}()
+
+	if fnErr := fn(db.New(&queryTracer{db: tx})); fnErr != nil {
+		return fnErr
+	}
+
+	if commitErr := tx.Commit(ctx); commitErr != nil {
+		return fmt.Errorf("failed to commit transaction: %w", commitErr)
+	}
+
+	committed = true
+
+	return nil
+}
@@ -0,0 +1,225 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pocj8ur4in/boilerplate-go/internal/gen/db"
+)
+
+var (
+	errBeginFailed = errors.New("begin failed")
+	errFnFailed    = errors.New("fn failed")
+)
+
+// fakeTx is a transaction recording its statements and outcome.
+type fakeTx struct {
+	pgx.Tx
+
+	execErr    error
+	commitErr  error
+	executed   []string
+	committed  bool
+	rolledBack bool
+}
+
+func (t *fakeTx) Exec(_ context.Context, sql string, _ ...interface{}) (pgconn.CommandTag, error) {
+	t.executed = append(t.executed, sql)
+
+	return pgconn.CommandTag{}, t.execErr
+}
+
+func (t *fakeTx) Commit(context.Context) error {
+	if t.commitErr != nil {
+		return t.commitErr
+	}
+
+	t.committed = true
+
+	return nil
+}
+
+func (t *fakeTx) Rollback(context.Context) error {
+	if t.committed || t.commitErr != nil {
+		return pgx.ErrTxClosed
+	}
+
+	t.rolledBack = true
+
+	return nil
+}
+
+// fakeBeginner begins the transactions in order.
+type fakeBeginner struct {
+	txs     []*fakeTx
+	begun   int
+	options pgx.TxOptions
+	err     error
+}
+
+func (b *fakeBeginner) BeginTx(_ context.Context, options pgx.TxOptions) (pgx.Tx, error) {
+	if b.err != nil {
+		return nil, b.err
+	}
+
+	b.options = options
+	tx := b.txs[b.begun]
+	b.begun++
+
+	return tx, nil
+}
+
+// setupTestTxDB creates database beginning the transactions, retrying without backoff.
+func setupTestTxDB(beginner *fakeBeginner) *DB {
+	return &DB{pool: beginner, txConfig: &TxConfig{
+		MaxAttempts:    &[]int{3}[0],
+		InitialBackoff: &[]time.Duration{0}[0],
+		MaxBackoff:     &[]time.Duration{0}[0],
+	}}
+}
+
+// deleteRateLimit runs a statement of the transaction.
+func deleteRateLimit(queries *db.Queries) error {
+	if err := queries.DeleteTenantRateLimit(context.Background(), "tenant-a"); err != nil {
+		return fmt.Errorf("failed to delete rate limit: %w", err)
+	}
+
+	return nil
+}
+
+func TestTxConfigSetDefault(t *testing.T) {
+	t.Parallel()
+
+	config := &Config{}
+	config.SetDefault()
+
+	require.NotNil(t, config.Transaction)
+	assert.Equal(t, defaultTxMaxAttempts, *config.Transaction.MaxAttempts)
+	assert.Equal(t, defaultTxInitialBackoff, *config.Transaction.InitialBackoff)
+	assert.Equal(t, defaultTxMaxBackoff, *config.Transaction.MaxBackoff)
+}
+
+func TestIsRetryable(t *testing.T) {
+	t.Parallel()
+
+	assert.True(t, IsRetryable(&QueryError{Err: &pgconn.PgError{Code: serializationFailure}}))
+	assert.True(t, IsRetryable(fmt.Errorf("wrapped: %w", &pgconn.PgError{Code: deadlockDetected})))
+	assert.False(t, IsRetryable(&pgconn.PgError{Code: "23505"}))
+	assert.False(t, IsRetryable(errFnFailed))
+}
+
+func TestWithTx(t *testing.T) {
+	t.Parallel()
+
+	t.Run("commit transaction", func(t *testing.T) {
+		t.Parallel()
+
+		tx := &fakeTx{}
+		beginner := &fakeBeginner{txs: []*fakeTx{tx}}
+
+		require.NoError(t, setupTestTxDB(beginner).WithTx(context.Background(), deleteRateLimit))
+		assert.True(t, tx.committed)
+		assert.False(t, tx.rolledBack)
+		assert.Len(t, tx.executed, 1)
+	})
+
+	t.Run("roll back transaction on error", func(t *testing.T) {
+		t.Parallel()
+
+		tx := &fakeTx{}
+		beginner := &fakeBeginner{txs: []*fakeTx{tx}}
+
+		err := setupTestTxDB(beginner).WithTx(context.Background(), func(*db.Queries) error {
+			return errFnFailed
+		})
+		require.ErrorIs(t, err, errFnFailed)
+		assert.True(t, tx.rolledBack)
+		assert.Equal(t, 1, beginner.begun)
+	})
+
+	t.Run("roll back transaction on panic", func(t *testing.T) {
+		t.Parallel()
+
+		tx := &fakeTx{}
+		beginner := &fakeBeginner{txs: []*fakeTx{tx}}
+
+		assert.Panics(t, func() {
+			_ = setupTestTxDB(beginner).WithTx(context.Background(), func(*db.Queries) error {
+				panic("fn panicked")
+			})
+		})
+		assert.True(t, tx.rolledBack)
+	})
+
+	t.Run("retry serialization failures and deadlocks", func(t *testing.T) {
+		t.Parallel()
+
+		failed := &fakeTx{execErr: &pgconn.PgError{Code: serializationFailure}}
+		deadlocked := &fakeTx{commitErr: &pgconn.PgError{Code: deadlockDetected}}
+		succeeded := &fakeTx{}
+		beginner := &fakeBeginner{txs: []*fakeTx{failed, deadlocked, succeeded}}
+
+		options := pgx.TxOptions{IsoLevel: pgx.Serializable}
+
+		require.NoError(t, setupTestTxDB(beginner).WithTxOptions(context.Background(), options, deleteRateLimit))
+		assert.True(t, failed.rolledBack)
+		assert.False(t, deadlocked.committed)
+		assert.True(t, succeeded.committed)
+		assert.Equal(t, pgx.Serializable, beginner.options.IsoLevel)
+	})
+
+	t.Run("return error after max attempts", func(t *testing.T) {
+		t.Parallel()
+
+		txs := make([]*fakeTx, 3)
+		for i := range txs {
+			txs[i] = &fakeTx{execErr: &pgconn.PgError{Code: serializationFailure}}
+		}
+
+		beginner := &fakeBeginner{txs: txs}
+
+		err := setupTestTxDB(beginner).WithTx(context.Background(), deleteRateLimit)
+		require.Error(t, err)
+		assert.True(t, IsRetryable(err))
+		assert.Contains(t, err.Error(), "after 3 attempts")
+		assert.Equal(t, 3, beginner.begun)
+	})
+
+	t.Run("stop retrying when context is canceled", func(t *testing.T) {
+		t.Parallel()
+
+		beginner := &fakeBeginner{txs: []*fakeTx{{execErr: &pgconn.PgError{Code: serializationFailure}}}}
+
+		database := setupTestTxDB(beginner)
+		database.txConfig.InitialBackoff = &[]time.Duration{time.Hour}[0]
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		err := database.WithTx(ctx, deleteRateLimit)
+		require.ErrorIs(t, err, context.Canceled)
+		assert.Equal(t, 1, beginner.begun)
+	})
+
+	t.Run("return error if begin fails", func(t *testing.T) {
+		t.Parallel()
+
+		err := setupTestTxDB(&fakeBeginner{err: errBeginFailed}).WithTx(context.Background(), deleteRateLimit)
+		require.ErrorIs(t, err, errBeginFailed)
+	})
+
+	t.Run("return error without connection pool", func(t *testing.T) {
+		t.Parallel()
+
+		err := (&DB{}).WithTx(context.Background(), deleteRateLimit)
+		require.ErrorIs(t, err, ErrNoPool)
+	})
+}
@@ -0,0 +1,207 @@
+// Package health provides readiness checks of dependencies registered by modules.
+package health
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/fx"
+)
+
+var (
+	// ErrDuplicateCheck returned when a check of the name is already registered.
+	ErrDuplicateCheck = errors.New("duplicate health check")
+
+	// ErrInvalidCheck returned when the check has no name or checker.
+	ErrInvalidCheck = errors.New("invalid health check")
+)
+
+const (
+	// StatusOK is status of passed checks.
+	StatusOK = "ok"
+
+	// StatusFail is status of failed checks.
+	StatusFail = "fail"
+
+	// DefaultTimeout is timeout of checks registered without timeout.
+	DefaultTimeout = 2 * time.Second
+
+	// tracerName is the name of the tracer used for check spans.
+	tracerName = "github.com/pocj8ur4in/boilerplate-go/internal/pkg/health"
+)
+
+// Checker checks health of a dependency.
+type Checker interface {
+	// Check returns an error if the dependency is not healthy.
+	Check(ctx context.Context) error
+}
+
+// CheckerFunc adapts a function to Checker.
+type CheckerFunc func(ctx context.Context) error
+
+// Check calls the function.
+func (f CheckerFunc) Check(ctx context.Context) error {
+	return f(ctx)
+}
+
+// Result represents result of a check.
+type Result struct {
+	// Status is StatusOK or StatusFail.
+	Status string
+
+	// Error is error of the failed check, empty if it passed.
+	Error string
+
+	// Duration is time the check took.
+	Duration time.Duration
+}
+
+// Report represents results of all checks.
+type Report struct {
+	// Status is StatusOK if all checks passed, StatusFail otherwise.
+	Status string
+
+	// Checks is results of checks by name.
+	Checks map[string]Result
+}
+
+// Ready returns whether all checks passed.
+func (r *Report) Ready() bool {
+	return r.Status == StatusOK
+}
+
+// check is a registered check.
+type check struct {
+	// name is name of the check.
+	name string
+
+	// timeout is duration after which the check fails.
+	timeout time.Duration
+
+	// checker checks the dependency.
+	checker Checker
+}
+
+// Registry holds checks that must pass for the service to be ready.
+type Registry struct {
+	// mu guards checks.
+	mu sync.RWMutex
+
+	// checks is registered checks in order of registration.
+	checks []check
+}
+
+// NewModule provides module for health checks.
+func NewModule() fx.Option {
+	return fx.Module("health",
+		fx.Provide(NewRegistry),
+	)
+}
+
+// NewRegistry creates an empty registry.
+func NewRegistry() *Registry {
+	return &Registry{}
+}
+
+// Register adds the check of the name failing if it does not pass within the timeout,
+// DefaultTimeout is used if the timeout is not positive.
+func (r *Registry) Register(name string, timeout time.Duration, checker Checker) error {
+	if name == "" || checker == nil {
+		return fmt.Errorf("%w: %q", ErrInvalidCheck, name)
+	}
+
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, registered := range r.checks {
+		if registered.name == name {
+			return fmt.Errorf("%w: %s", ErrDuplicateCheck, name)
+		}
+	}
+
+	r.checks = append(r.checks, check{name: name, timeout: timeout, checker: checker})
+
+	return nil
+}
+
+// Check runs all checks concurrently and returns their results.
+func (r *Registry) Check(ctx context.Context) *Report {
+	r.mu.RLock()
+	checks := r.checks
+	r.mu.RUnlock()
+
+	results := make([]Result, len(checks))
+
+	var wg sync.WaitGroup
+
+	for i, check := range checks {
+		wg.Go(func() {
+			results[i] = check.run(ctx)
+		})
+	}
+
+	wg.Wait()
+
+	report := &Report{Status: StatusOK, Checks: make(map[string]Result, len(checks))}
+
+	for i, check := range checks {
+		report.Checks[check.name] = results[i]
+
+		if results[i].Status != StatusOK {
+			report.Status = StatusFail
+		}
+	}
+
+	return report
+}
+
+// run runs the check within its timeout in a child span of the context.
+func (c *check) run(ctx context.Context) Result {
+	ctx, span := otel.Tracer(tracerName).Start(ctx, "health.check", trace.WithAttributes(
+		attribute.String("health.check", c.name),
+	))
+	defer span.End()
+
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	start := time.Now()
+
+	// checkers ignoring the context fail on time all the same
+	done := make(chan error, 1)
+
+	go func() {
+		done <- c.checker.Check(ctx)
+	}()
+
+	var err error
+
+	select {
+	case err = <-done:
+	case <-ctx.Done():
+		err = fmt.Errorf("timed out after %s: %w", c.timeout, ctx.Err())
+	}
+
+	result := Result{Status: StatusOK, Duration: time.Since(start)}
+
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, c.name+" check failed")
+
+		result.Status = StatusFail
+		result.Error = err.Error()
+	}
+
+	return result
+}
@@ -0,0 +1,126 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var errUnavailable = errors.New("unavailable")
+
+// passing is a checker that always passes.
+var passing = CheckerFunc(func(context.Context) error { return nil })
+
+func TestNewModule(t *testing.T) {
+	t.Parallel()
+
+	t.Run("return fx.Option", func(t *testing.T) {
+		t.Parallel()
+
+		require.NotNil(t, NewModule())
+	})
+}
+
+func TestRegister(t *testing.T) {
+	t.Parallel()
+
+	t.Run("reject duplicate name", func(t *testing.T) {
+		t.Parallel()
+
+		registry := NewRegistry()
+
+		require.NoError(t, registry.Register("database", 0, passing))
+		require.ErrorIs(t, registry.Register("database", time.Second, passing), ErrDuplicateCheck)
+	})
+
+	t.Run("reject check without name or checker", func(t *testing.T) {
+		t.Parallel()
+
+		registry := NewRegistry()
+
+		require.ErrorIs(t, registry.Register("", 0, passing), ErrInvalidCheck)
+		require.ErrorIs(t, registry.Register("redis", 0, nil), ErrInvalidCheck)
+	})
+
+	t.Run("use default timeout", func(t *testing.T) {
+		t.Parallel()
+
+		registry := NewRegistry()
+
+		require.NoError(t, registry.Register("database", 0, passing))
+		assert.Equal(t, DefaultTimeout, registry.checks[0].timeout)
+	})
+}
+
+func TestCheck(t *testing.T) {
+	t.Parallel()
+
+	t.Run("report ok if all checks pass", func(t *testing.T) {
+		t.Parallel()
+
+		registry := NewRegistry()
+		require.NoError(t, registry.Register("database", 0, passing))
+		require.NoError(t, registry.Register("redis", 0, passing))
+
+		report := registry.Check(context.Background())
+
+		assert.True(t, report.Ready())
+		assert.Equal(t, StatusOK, report.Status)
+		assert.Len(t, report.Checks, 2)
+		assert.Equal(t, StatusOK, report.Checks["redis"].Status)
+		assert.Empty(t, report.Checks["redis"].Error)
+	})
+
+	t.Run("report ok without checks", func(t *testing.T) {
+		t.Parallel()
+
+		report := NewRegistry().Check(context.Background())
+
+		assert.True(t, report.Ready())
+		assert.Empty(t, report.Checks)
+	})
+
+	t.Run("report failed check", func(t *testing.T) {
+		t.Parallel()
+
+		registry := NewRegistry()
+		require.NoError(t, registry.Register("database", 0, passing))
+		require.NoError(t, registry.Register("redis", 0, CheckerFunc(func(context.Context) error {
+			return errUnavailable
+		})))
+
+		report := registry.Check(context.Background())
+
+		assert.False(t, report.Ready())
+		assert.Equal(t, StatusFail, report.Status)
+		assert.Equal(t, StatusOK, report.Checks["database"].Status)
+		assert.Equal(t, Result{Status: StatusFail, Error: "unavailable", Duration: report.Checks["redis"].Duration},
+			report.Checks["redis"])
+	})
+
+	t.Run("fail check exceeding timeout", func(t *testing.T) {
+		t.Parallel()
+
+		release := make(chan struct{})
+		t.Cleanup(func() { close(release) })
+
+		registry := NewRegistry()
+		require.NoError(t, registry.Register("slow", 20*time.Millisecond, CheckerFunc(func(context.Context) error {
+			// ignores the context
+			<-release
+
+			return nil
+		})))
+
+		start := time.Now()
+		report := registry.Check(context.Background())
+
+		assert.Less(t, time.Since(start), time.Second)
+		assert.Equal(t, StatusFail, report.Checks["slow"].Status)
+		assert.Contains(t, report.Checks["slow"].Error, "timed out after 20ms")
+	})
+}
@@ -0,0 +1,333 @@
+package httpclient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/netip"
+	"sync"
+	"time"
+
+	"golang.org/x/sync/singleflight"
+)
+
+// ErrNoAddresses returned when the host resolves to no addresses.
+var ErrNoAddresses = errors.New("no addresses")
+
+const (
+	// familyIPv4 is family label of IPv4 addresses.
+	familyIPv4 = "ipv4"
+
+	// familyIPv6 is family label of IPv6 addresses.
+	familyIPv6 = "ipv6"
+)
+
+// lookupFunc resolves IP addresses of the host.
+type lookupFunc func(ctx context.Context, network, host string) ([]netip.Addr, error)
+
+// dnsEntry is cached addresses of a host.
+type dnsEntry struct {
+	// addrs is resolved addresses of the host.
+	addrs []netip.Addr
+
+	// expiresAt is time the addresses must be resolved again.
+	expiresAt time.Time
+}
+
+// dnsCache caches resolved addresses of hosts.
+type dnsCache struct {
+	// mu guards entries.
+	mu sync.RWMutex
+
+	// entries is cached addresses by host.
+	entries map[string]*dnsEntry
+
+	// group deduplicates concurrent lookups of a host.
+	group singleflight.Group
+
+	// lookup resolves addresses on cache misses.
+	lookup lookupFunc
+
+	// ttl is duration addresses are cached, 0 disables caching.
+	ttl time.Duration
+
+	// staleTTL is duration expired addresses are served while lookups fail.
+	staleTTL time.Duration
+
+	// metrics records lookups.
+	metrics *metrics
+
+	// now returns current time.
+	now func() time.Time
+}
+
+// newDNSCache creates a DNS cache resolving with the lookup.
+func newDNSCache(config *DNSConfig, lookup lookupFunc, metrics *metrics) *dnsCache {
+	return &dnsCache{
+		entries:  make(map[string]*dnsEntry),
+		lookup:   lookup,
+		ttl:      time.Duration(*config.CacheTTL) * time.Second,
+		staleTTL: time.Duration(*config.StaleTTL) * time.Second,
+		metrics:  metrics,
+		now:      time.Now,
+	}
+}
+
+// resolve returns addresses of the host, from cache while they are fresh.
+func (c *dnsCache) resolve(ctx context.Context, host string) ([]netip.Addr, error) {
+	if c.ttl <= 0 {
+		return c.resolveUncached(ctx, host)
+	}
+
+	c.mu.RLock()
+	entry, ok := c.entries[host]
+	c.mu.RUnlock()
+
+	now := c.now()
+	if ok && now.Before(entry.expiresAt) {
+		c.metrics.dnsLookupsTotal.WithLabelValues("hit").Inc()
+
+		return entry.addrs, nil
+	}
+
+	// lookups are shared by concurrent dials and not canceled when one of them is
+	result, err, _ := c.group.Do(host, func() (interface{}, error) {
+		return c.resolveUncached(context.WithoutCancel(ctx), host)
+	})
+	if err != nil {
+		// serve expired addresses so that resolver outages do not fail requests
+		if ok && now.Before(entry.expiresAt.Add(c.staleTTL)) {
+			c.metrics.dnsLookupsTotal.WithLabelValues("stale").Inc()
+
+			return entry.addrs, nil
+		}
+
+		return nil, err
+	}
+
+	addrs, _ := result.([]netip.Addr)
+
+	c.mu.Lock()
+	c.entries[host] = &dnsEntry{addrs: addrs, expiresAt: c.now().Add(c.ttl)}
+	c.mu.Unlock()
+
+	return addrs, nil
+}
+
+// resolveUncached resolves addresses of the host with the lookup.
+func (c *dnsCache) resolveUncached(ctx context.Context, host string) ([]netip.Addr, error) {
+	start := time.Now()
+
+	addrs, err := c.lookup(ctx, "ip", host)
+
+	c.metrics.dnsLookupDuration.Observe(time.Since(start).Seconds())
+
+	if err == nil && len(addrs) == 0 {
+		err = fmt.Errorf("%w: %s", ErrNoAddresses, host)
+	}
+
+	if err != nil {
+		c.metrics.dnsLookupsTotal.WithLabelValues(resultFailure).Inc()
+
+		return nil, fmt.Errorf("failed to resolve %s: %w", host, err)
+	}
+
+	c.metrics.dnsLookupsTotal.WithLabelValues("miss").Inc()
+
+	// unmap IPv4-mapped IPv6 addresses so that they are dialed as IPv4
+	for i, addr := range addrs {
+		addrs[i] = addr.Unmap()
+	}
+
+	return addrs, nil
+}
+
+// dialFunc dials the address on the network.
+type dialFunc func(ctx context.Context, network, address string) (net.Conn, error)
+
+// dialer dials resolved addresses of hosts, racing IPv4 and IPv6 addresses (happy eyeballs).
+type dialer struct {
+	// cache resolves addresses of hosts.
+	cache *dnsCache
+
+	// dial dials a single address.
+	dial dialFunc
+
+	// fallbackDelay is delay before dialing addresses of the other family, negative disables fallback.
+	fallbackDelay time.Duration
+
+	// metrics records dials.
+	metrics *metrics
+}
+
+// newDialer creates a dialer resolving addresses with the cache.
+func newDialer(cache *dnsCache, fallbackDelay time.Duration, metrics *metrics) *dialer {
+	netDialer := &net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second}
+
+	return &dialer{
+		cache:         cache,
+		dial:          netDialer.DialContext,
+		fallbackDelay: fallbackDelay,
+		metrics:       metrics,
+	}
+}
+
+// DialContext dials the address, resolving its host with the DNS cache.
+func (d *dialer) DialContext(ctx context.Context, network, address string) (net.Conn, error) {
+	host, port, err := net.SplitHostPort(address)
+	if err != nil {
+		return nil, fmt.Errorf("failed to split address: %w", err)
+	}
+
+	// dial addresses directly
+	if addr, err := netip.ParseAddr(host); err == nil {
+		return d.dialAddr(ctx, network, addr.Unmap(), port)
+	}
+
+	addrs, err := d.cache.resolve(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+
+	primaries, fallbacks := partitionAddrs(addrs)
+	if d.fallbackDelay < 0 || len(fallbacks) == 0 {
+		return d.dialSerial(ctx, network, append(primaries, fallbacks...), port)
+	}
+
+	return d.dialParallel(ctx, network, primaries, fallbacks, port)
+}
+
+// dialResult is result of a serial dial.
+type dialResult struct {
+	// conn is dialed connection.
+	conn net.Conn
+
+	// err is error of the dial.
+	err error
+
+	// primary is whether the dial is of primary addresses.
+	primary bool
+}
+
+// dialParallel dials primary addresses and, after the fallback delay or their failure,
+// fallback addresses, returning the first established connection.
+func (d *dialer) dialParallel(
+	ctx context.Context, network string, primaries, fallbacks []netip.Addr, port string,
+) (net.Conn, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	results := make(chan dialResult)
+	race := func(primary bool, addrs []netip.Addr) {
+		conn, err := d.dialSerial(ctx, network, addrs, port)
+
+		select {
+		case results <- dialResult{conn: conn, err: err, primary: primary}:
+		case <-ctx.Done():
+			if conn != nil {
+				_ = conn.Close()
+			}
+		}
+	}
+
+	go race(true, primaries)
+
+	fallbackTimer := time.NewTimer(d.fallbackDelay)
+	defer fallbackTimer.Stop()
+
+	// fallback is nil once fallback addresses are being dialed
+	fallback := fallbackTimer.C
+
+	var primaryErr, fallbackErr error
+
+	for {
+		select {
+		case <-fallback:
+			fallback = nil
+
+			go race(false, fallbacks)
+		case result := <-results:
+			if result.err == nil {
+				return result.conn, nil
+			}
+
+			if result.primary {
+				primaryErr = result.err
+			} else {
+				fallbackErr = result.err
+			}
+
+			if primaryErr != nil && fallbackErr != nil {
+				return nil, primaryErr
+			}
+
+			// start fallback immediately when primary addresses fail
+			if result.primary && fallback != nil {
+				fallback = nil
+
+				go race(false, fallbacks)
+			}
+		}
+	}
+}
+
+// dialSerial dials the addresses in order, returning the first established connection.
+func (d *dialer) dialSerial(ctx context.Context, network string, addrs []netip.Addr, port string) (net.Conn, error) {
+	var firstErr error
+
+	for _, addr := range addrs {
+		conn, err := d.dialAddr(ctx, network, addr, port)
+		if err == nil {
+			return conn, nil
+		}
+
+		if firstErr == nil {
+			firstErr = err
+		}
+
+		if ctx.Err() != nil {
+			break
+		}
+	}
+
+	if firstErr == nil {
+		firstErr = ErrNoAddresses
+	}
+
+	return nil, firstErr
+}
+
+// dialAddr dials a single address.
+func (d *dialer) dialAddr(ctx context.Context, network string, addr netip.Addr, port string) (net.Conn, error) {
+	family := familyIPv4
+	if addr.Is6() {
+		family = familyIPv6
+	}
+
+	conn, err := d.dial(ctx, network, net.JoinHostPort(addr.String(), port))
+	if err != nil {
+		d.metrics.dialsTotal.WithLabelValues(family, resultFailure).Inc()
+
+		return nil, err
+	}
+
+	d.metrics.dialsTotal.WithLabelValues(family, resultSuccess).Inc()
+
+	return conn, nil
+}
+
+// partitionAddrs splits the addresses into those of the first address family and the rest.
+func partitionAddrs(addrs []netip.Addr) ([]netip.Addr, []netip.Addr) {
+	var primaries, fallbacks []netip.Addr
+
+	for _, addr := range addrs {
+		if len(primaries) == 0 || addr.Is4() == primaries[0].Is4() {
+			primaries = append(primaries, addr)
+		} else {
+			fallbacks = append(fallbacks, addr)
+		}
+	}
+
+	return primaries, fallbacks
+}
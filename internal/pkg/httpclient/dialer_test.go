@@ -0,0 +1,322 @@
+package httpclient
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/netip"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	// errLookupFailed is the test error of a failed lookup.
+	errLookupFailed = errors.New("lookup failed")
+
+	// errDialFailed is the test error of a failed dial.
+	errDialFailed = errors.New("dial failed")
+)
+
+// mockLookup is a lookup returning fixed addresses and counting calls.
+type mockLookup struct {
+	addrs []netip.Addr
+	err   error
+	delay time.Duration
+	calls atomic.Int32
+}
+
+func (m *mockLookup) lookup(_ context.Context, _, _ string) ([]netip.Addr, error) {
+	m.calls.Add(1)
+	time.Sleep(m.delay)
+
+	if m.err != nil {
+		return nil, m.err
+	}
+
+	return append([]netip.Addr(nil), m.addrs...), nil
+}
+
+// newTestDNSCache creates a DNS cache of the lookup with a controllable clock.
+func newTestDNSCache(lookup lookupFunc, cacheTTL, staleTTL int) (*dnsCache, *time.Time) {
+	now := time.Now()
+	cache := newDNSCache(&DNSConfig{CacheTTL: &cacheTTL, StaleTTL: &staleTTL}, lookup, newMetrics())
+	cache.now = func() time.Time { return now }
+
+	return cache, &now
+}
+
+// mockDial records dialed addresses, failing or delaying addresses of the maps.
+type mockDial struct {
+	mu     sync.Mutex
+	dialed []string
+	fail   map[string]bool
+	delay  map[string]time.Duration
+}
+
+func (m *mockDial) dial(ctx context.Context, _, address string) (net.Conn, error) {
+	m.mu.Lock()
+	m.dialed = append(m.dialed, address)
+	delay, fail := m.delay[address], m.fail[address]
+	m.mu.Unlock()
+
+	select {
+	case <-time.After(delay):
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+
+	if fail {
+		return nil, errDialFailed
+	}
+
+	client, server := net.Pipe()
+	_ = server.Close()
+
+	return &addrConn{Conn: client, address: address}, nil
+}
+
+// addrConn is a connection reporting the dialed address.
+type addrConn struct {
+	net.Conn
+
+	address string
+}
+
+// newTestDialer creates a dialer of the addresses with the mock dial.
+func newTestDialer(addrs []netip.Addr, dial *mockDial, fallbackDelay time.Duration) *dialer {
+	metrics := newMetrics()
+	cache, _ := newTestDNSCache((&mockLookup{addrs: addrs}).lookup, 60, 0)
+
+	return &dialer{cache: cache, dial: dial.dial, fallbackDelay: fallbackDelay, metrics: metrics}
+}
+
+func TestDNSCache(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	addrs := []netip.Addr{netip.MustParseAddr("192.0.2.1")}
+
+	t.Run("cache addresses until ttl expires", func(t *testing.T) {
+		t.Parallel()
+
+		lookup := &mockLookup{addrs: addrs}
+		cache, now := newTestDNSCache(lookup.lookup, 60, 0)
+
+		for range 3 {
+			resolved, err := cache.resolve(ctx, "example.com")
+			require.NoError(t, err)
+			assert.Equal(t, addrs, resolved)
+		}
+
+		assert.Equal(t, int32(1), lookup.calls.Load())
+		assert.InDelta(t, 2, testutil.ToFloat64(cache.metrics.dnsLookupsTotal.WithLabelValues("hit")), 0)
+
+		*now = now.Add(61 * time.Second)
+
+		_, err := cache.resolve(ctx, "example.com")
+		require.NoError(t, err)
+		assert.Equal(t, int32(2), lookup.calls.Load())
+	})
+
+	t.Run("look up every time when caching is disabled", func(t *testing.T) {
+		t.Parallel()
+
+		lookup := &mockLookup{addrs: addrs}
+		cache, _ := newTestDNSCache(lookup.lookup, 0, 0)
+
+		for range 2 {
+			_, err := cache.resolve(ctx, "example.com")
+			require.NoError(t, err)
+		}
+
+		assert.Equal(t, int32(2), lookup.calls.Load())
+	})
+
+	t.Run("share concurrent lookups of a host", func(t *testing.T) {
+		t.Parallel()
+
+		lookup := &mockLookup{addrs: addrs, delay: 50 * time.Millisecond}
+		cache, _ := newTestDNSCache(lookup.lookup, 60, 0)
+
+		var wg sync.WaitGroup
+
+		for range 10 {
+			wg.Add(1)
+
+			go func() {
+				defer wg.Done()
+
+				_, err := cache.resolve(ctx, "example.com")
+				assert.NoError(t, err)
+			}()
+		}
+
+		wg.Wait()
+
+		assert.Equal(t, int32(1), lookup.calls.Load())
+	})
+
+	t.Run("serve expired addresses while lookups fail", func(t *testing.T) {
+		t.Parallel()
+
+		lookup := &mockLookup{addrs: addrs}
+		cache, now := newTestDNSCache(lookup.lookup, 60, 300)
+
+		_, err := cache.resolve(ctx, "example.com")
+		require.NoError(t, err)
+
+		lookup.err = errLookupFailed
+		*now = now.Add(120 * time.Second)
+
+		resolved, err := cache.resolve(ctx, "example.com")
+		require.NoError(t, err)
+		assert.Equal(t, addrs, resolved)
+		assert.InDelta(t, 1, testutil.ToFloat64(cache.metrics.dnsLookupsTotal.WithLabelValues("stale")), 0)
+
+		*now = now.Add(300 * time.Second)
+
+		_, err = cache.resolve(ctx, "example.com")
+		require.ErrorIs(t, err, errLookupFailed)
+	})
+
+	t.Run("return error of hosts without addresses", func(t *testing.T) {
+		t.Parallel()
+
+		cache, _ := newTestDNSCache((&mockLookup{}).lookup, 60, 0)
+
+		_, err := cache.resolve(ctx, "example.com")
+		require.ErrorIs(t, err, ErrNoAddresses)
+	})
+
+	t.Run("unmap IPv4-mapped addresses", func(t *testing.T) {
+		t.Parallel()
+
+		cache, _ := newTestDNSCache((&mockLookup{addrs: []netip.Addr{netip.MustParseAddr("::ffff:192.0.2.1")}}).lookup, 60, 0)
+
+		resolved, err := cache.resolve(ctx, "example.com")
+		require.NoError(t, err)
+		assert.Equal(t, addrs, resolved)
+	})
+}
+
+func TestDialer(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	addrs := []netip.Addr{
+		netip.MustParseAddr("2001:db8::1"),
+		netip.MustParseAddr("2001:db8::2"),
+		netip.MustParseAddr("192.0.2.1"),
+	}
+
+	t.Run("dial first address of primary family", func(t *testing.T) {
+		t.Parallel()
+
+		dial := &mockDial{}
+		dialer := newTestDialer(addrs, dial, 300*time.Millisecond)
+
+		conn, err := dialer.DialContext(ctx, "tcp", "example.com:443")
+		require.NoError(t, err)
+		assert.Equal(t, "[2001:db8::1]:443", conn.(*addrConn).address)
+		assert.Equal(t, []string{"[2001:db8::1]:443"}, dial.dialed)
+		assert.InDelta(t, 1, testutil.ToFloat64(dialer.metrics.dialsTotal.WithLabelValues(familyIPv6, resultSuccess)), 0)
+	})
+
+	t.Run("fall back to other family when primary family is slow", func(t *testing.T) {
+		t.Parallel()
+
+		dial := &mockDial{delay: map[string]time.Duration{
+			"[2001:db8::1]:443": time.Second,
+		}}
+		dialer := newTestDialer(addrs, dial, 20*time.Millisecond)
+
+		start := time.Now()
+
+		conn, err := dialer.DialContext(ctx, "tcp", "example.com:443")
+		require.NoError(t, err)
+		assert.Equal(t, "192.0.2.1:443", conn.(*addrConn).address)
+		assert.Less(t, time.Since(start), time.Second)
+	})
+
+	t.Run("fall back immediately when primary family fails", func(t *testing.T) {
+		t.Parallel()
+
+		dial := &mockDial{fail: map[string]bool{
+			"[2001:db8::1]:443": true,
+			"[2001:db8::2]:443": true,
+		}}
+		dialer := newTestDialer(addrs, dial, time.Hour)
+
+		conn, err := dialer.DialContext(ctx, "tcp", "example.com:443")
+		require.NoError(t, err)
+		assert.Equal(t, "192.0.2.1:443", conn.(*addrConn).address)
+		assert.InDelta(t, 2, testutil.ToFloat64(dialer.metrics.dialsTotal.WithLabelValues(familyIPv6, resultFailure)), 0)
+	})
+
+	t.Run("dial addresses in order when fallback is disabled", func(t *testing.T) {
+		t.Parallel()
+
+		dial := &mockDial{fail: map[string]bool{"[2001:db8::1]:443": true}}
+		dialer := newTestDialer(addrs, dial, -1)
+
+		conn, err := dialer.DialContext(ctx, "tcp", "example.com:443")
+		require.NoError(t, err)
+		assert.Equal(t, "[2001:db8::2]:443", conn.(*addrConn).address)
+		assert.Equal(t, []string{"[2001:db8::1]:443", "[2001:db8::2]:443"}, dial.dialed)
+	})
+
+	t.Run("return error when all addresses fail", func(t *testing.T) {
+		t.Parallel()
+
+		dial := &mockDial{fail: map[string]bool{
+			"[2001:db8::1]:443": true,
+			"[2001:db8::2]:443": true,
+			"192.0.2.1:443":     true,
+		}}
+		dialer := newTestDialer(addrs, dial, 10*time.Millisecond)
+
+		_, err := dialer.DialContext(ctx, "tcp", "example.com:443")
+		require.ErrorIs(t, err, errDialFailed)
+	})
+
+	t.Run("dial ip addresses without lookup", func(t *testing.T) {
+		t.Parallel()
+
+		dial := &mockDial{}
+		dialer := newTestDialer(nil, dial, 300*time.Millisecond)
+
+		conn, err := dialer.DialContext(ctx, "tcp", "198.51.100.1:80")
+		require.NoError(t, err)
+		assert.Equal(t, "198.51.100.1:80", conn.(*addrConn).address)
+	})
+
+	t.Run("reject addresses without port", func(t *testing.T) {
+		t.Parallel()
+
+		dialer := newTestDialer(addrs, &mockDial{}, 0)
+
+		_, err := dialer.DialContext(ctx, "tcp", "example.com")
+		require.Error(t, err)
+	})
+}
+
+func TestPartitionAddrs(t *testing.T) {
+	t.Parallel()
+
+	ipv4 := netip.MustParseAddr("192.0.2.1")
+	ipv6 := netip.MustParseAddr("2001:db8::1")
+
+	primaries, fallbacks := partitionAddrs([]netip.Addr{ipv4, ipv6, ipv4})
+	assert.Equal(t, []netip.Addr{ipv4, ipv4}, primaries)
+	assert.Equal(t, []netip.Addr{ipv6}, fallbacks)
+
+	primaries, fallbacks = partitionAddrs([]netip.Addr{ipv6})
+	assert.Equal(t, []netip.Addr{ipv6}, primaries)
+	assert.Empty(t, fallbacks)
+}
@@ -0,0 +1,272 @@
+// Package httpclient provides the shared HTTP client for outbound requests, routed through egress proxies
+// and dialed with cached DNS results and happy eyeballs.
+package httpclient
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"go.uber.org/fx"
+
+	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/tracing"
+)
+
+// ErrInvalidProxyURL returned when the proxy URL is malformed or its scheme is not supported.
+var ErrInvalidProxyURL = errors.New("invalid proxy url")
+
+// proxySchemes is supported schemes of proxy URLs.
+var proxySchemes = []string{"http", "https", "socks5", "socks5h"}
+
+// Config represents configuration for the HTTP client.
+type Config struct {
+	// Timeout is timeout of requests in seconds.
+	Timeout *int `json:"timeout"`
+
+	// Proxy is egress proxy configuration of the HTTP client.
+	Proxy *ProxyConfig `json:"proxy"`
+
+	// DNS is DNS caching configuration of the HTTP client.
+	DNS *DNSConfig `json:"dns"`
+
+	// FallbackDelay is delay in milliseconds before dialing addresses of the other IP family
+	// when the first family does not connect (happy eyeballs), negative to dial addresses in order.
+	FallbackDelay *int `json:"fallback_delay"`
+}
+
+// DNSConfig represents configuration for caching DNS results.
+type DNSConfig struct {
+	// CacheTTL is duration in seconds resolved addresses are cached, 0 to disable caching.
+	// The system resolver does not expose record TTLs, so it should not exceed the TTLs of the records.
+	CacheTTL *int `json:"cache_ttl"`
+
+	// StaleTTL is duration in seconds expired addresses are still served while lookups fail.
+	StaleTTL *int `json:"stale_ttl"`
+}
+
+// ProxyConfig represents configuration for egress proxies.
+type ProxyConfig struct {
+	// URL is proxy of all destinations (http, https, socks5 or socks5h),
+	// empty to use the HTTP_PROXY, HTTPS_PROXY and NO_PROXY environment variables.
+	URL *string `json:"url"`
+
+	// NoProxy is hosts connected directly instead of through URL, "*.example.com" matches subdomains.
+	NoProxy *[]string `json:"no_proxy"`
+
+	// Rules is per-destination proxies, the first rule matching the host takes precedence over URL.
+	Rules []*ProxyRule `json:"rules"`
+}
+
+// ProxyRule represents a proxy of destination hosts.
+type ProxyRule struct {
+	// Hosts is hosts the rule applies to, "*.example.com" matches subdomains.
+	Hosts []string `json:"hosts"`
+
+	// URL is proxy of the hosts, empty to connect directly.
+	URL *string `json:"url"`
+}
+
+// SetDefault sets default values.
+func (c *Config) SetDefault() {
+	if c.Timeout == nil {
+		c.Timeout = &[]int{30}[0]
+	}
+
+	if c.Proxy == nil {
+		c.Proxy = &ProxyConfig{}
+	}
+
+	if c.Proxy.URL == nil {
+		c.Proxy.URL = &[]string{""}[0]
+	}
+
+	if c.Proxy.NoProxy == nil {
+		c.Proxy.NoProxy = &[]string{}
+	}
+
+	for _, rule := range c.Proxy.Rules {
+		if rule.URL == nil {
+			rule.URL = &[]string{""}[0]
+		}
+	}
+
+	if c.DNS == nil {
+		c.DNS = &DNSConfig{}
+	}
+
+	if c.DNS.CacheTTL == nil {
+		c.DNS.CacheTTL = &[]int{60}[0]
+	}
+
+	if c.DNS.StaleTTL == nil {
+		c.DNS.StaleTTL = &[]int{300}[0]
+	}
+
+	if c.FallbackDelay == nil {
+		c.FallbackDelay = &[]int{300}[0]
+	}
+}
+
+// Client is the shared HTTP client, it collects metrics of DNS lookups, dials and connection reuse.
+type Client struct {
+	*http.Client
+
+	// metrics provides collectors of outbound connections.
+	metrics *metrics
+}
+
+// NewModule provides module for the HTTP client.
+func NewModule() fx.Option {
+	return fx.Module("httpclient",
+		fx.Provide(New),
+	)
+}
+
+// New creates a new HTTP client.
+func New(config *Config) (*Client, error) {
+	if config == nil {
+		config = &Config{}
+	}
+
+	config.SetDefault()
+
+	proxy, err := newProxySelector(config.Proxy)
+	if err != nil {
+		return nil, err
+	}
+
+	metrics := newMetrics()
+	dialer := newDialer(
+		newDNSCache(config.DNS, net.DefaultResolver.LookupNetIP, metrics),
+		time.Duration(*config.FallbackDelay)*time.Millisecond,
+		metrics,
+	)
+
+	transport, _ := http.DefaultTransport.(*http.Transport)
+	transport = transport.Clone()
+	transport.Proxy = proxy.proxy
+	transport.DialContext = dialer.DialContext
+
+	return &Client{
+		Client: &http.Client{
+			Transport: &propagationTransport{
+				next:       &tracingTransport{next: transport, metrics: metrics},
+				propagator: tracing.Propagator(),
+			},
+			Timeout: time.Duration(*config.Timeout) * time.Second,
+		},
+		metrics: metrics,
+	}, nil
+}
+
+// proxyRule represents a parsed proxy rule.
+type proxyRule struct {
+	// hosts is hosts the rule applies to.
+	hosts []string
+
+	// url is proxy of the hosts, nil to connect directly.
+	url *url.URL
+}
+
+// proxySelector selects the proxy of requests.
+type proxySelector struct {
+	// url is proxy of all destinations, nil to use the environment variables.
+	url *url.URL
+
+	// noProxy is hosts connected directly.
+	noProxy []string
+
+	// rules is per-destination proxies.
+	rules []*proxyRule
+}
+
+// newProxySelector creates a new proxy selector of the configuration.
+func newProxySelector(config *ProxyConfig) (*proxySelector, error) {
+	proxyURL, err := parseProxyURL(*config.URL)
+	if err != nil {
+		return nil, err
+	}
+
+	selector := &proxySelector{url: proxyURL, noProxy: *config.NoProxy}
+
+	for _, rule := range config.Rules {
+		ruleURL, err := parseProxyURL(*rule.URL)
+		if err != nil {
+			return nil, err
+		}
+
+		selector.rules = append(selector.rules, &proxyRule{hosts: rule.Hosts, url: ruleURL})
+	}
+
+	return selector, nil
+}
+
+// proxy returns the proxy of the request, nil to connect directly.
+func (s *proxySelector) proxy(request *http.Request) (*url.URL, error) {
+	host := request.URL.Hostname()
+
+	for _, rule := range s.rules {
+		if matchHost(rule.hosts, host) {
+			return rule.url, nil
+		}
+	}
+
+	if s.url == nil {
+		return http.ProxyFromEnvironment(request)
+	}
+
+	if matchHost(s.noProxy, host) {
+		return nil, nil //nolint:nilnil // nil URL means no proxy for http.Transport
+	}
+
+	return s.url, nil
+}
+
+// parseProxyURL parses the proxy URL, nil if it is empty.
+func parseProxyURL(raw string) (*url.URL, error) {
+	if raw == "" {
+		return nil, nil //nolint:nilnil // nil URL means no proxy
+	}
+
+	parsed, err := url.Parse(raw)
+	if err != nil {
+		// the URL is not included since it may contain credentials
+		return nil, ErrInvalidProxyURL
+	}
+
+	if !slices.Contains(proxySchemes, parsed.Scheme) || parsed.Host == "" {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidProxyURL, parsed.Redacted())
+	}
+
+	return parsed, nil
+}
+
+// matchHost returns whether the host matches one of the patterns,
+// "*" matches all hosts and "*.example.com" or ".example.com" matches subdomains.
+func matchHost(patterns []string, host string) bool {
+	host = strings.ToLower(host)
+
+	for _, pattern := range patterns {
+		pattern = strings.ToLower(strings.TrimSpace(pattern))
+
+		switch {
+		case pattern == "*" || pattern == host:
+			return true
+		case strings.HasPrefix(pattern, "*."):
+			if strings.HasSuffix(host, pattern[1:]) {
+				return true
+			}
+		case strings.HasPrefix(pattern, "."):
+			if strings.HasSuffix(host, pattern) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,106 @@
+package httpclient
+
+import (
+	"net/http"
+	"net/http/httptrace"
+	"strconv"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	// resultSuccess is result label of successful operations.
+	resultSuccess = "success"
+
+	// resultFailure is result label of failed operations.
+	resultFailure = "failure"
+)
+
+// metrics holds prometheus collectors of outbound connections.
+type metrics struct {
+	// dnsLookupsTotal is number of DNS lookups by result (hit, miss, stale or failure).
+	dnsLookupsTotal *prometheus.CounterVec
+
+	// dnsLookupDuration is duration of DNS lookups sent to the resolver.
+	dnsLookupDuration prometheus.Histogram
+
+	// dialsTotal is number of dials by address family and result.
+	dialsTotal *prometheus.CounterVec
+
+	// connectionsTotal is number of connections used by requests by whether they were reused.
+	connectionsTotal *prometheus.CounterVec
+}
+
+// newMetrics creates collectors of outbound connections.
+func newMetrics() *metrics {
+	return &metrics{
+		dnsLookupsTotal: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "http_client_dns_lookups_total",
+				Help: "Total number of DNS lookups of the HTTP client",
+			},
+			[]string{"result"},
+		),
+		dnsLookupDuration: prometheus.NewHistogram(
+			prometheus.HistogramOpts{
+				Name:    "http_client_dns_lookup_duration_seconds",
+				Help:    "Duration of DNS lookups of the HTTP client in seconds",
+				Buckets: prometheus.ExponentialBuckets(0.0005, 2, 12),
+			},
+		),
+		dialsTotal: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "http_client_dials_total",
+				Help: "Total number of dials of the HTTP client",
+			},
+			[]string{"family", "result"},
+		),
+		connectionsTotal: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "http_client_connections_total",
+				Help: "Total number of connections used by requests of the HTTP client",
+			},
+			[]string{"reused"},
+		),
+	}
+}
+
+// collectors returns all collectors of the metrics.
+func (m *metrics) collectors() []prometheus.Collector {
+	return []prometheus.Collector{m.dnsLookupsTotal, m.dnsLookupDuration, m.dialsTotal, m.connectionsTotal}
+}
+
+// tracingTransport records whether requests reuse connections.
+type tracingTransport struct {
+	// next is transport sending requests.
+	next http.RoundTripper
+
+	// metrics records connection reuse.
+	metrics *metrics
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *tracingTransport) RoundTrip(request *http.Request) (*http.Response, error) {
+	// hooks of traces already on the request are called as well
+	trace := &httptrace.ClientTrace{
+		GotConn: func(info httptrace.GotConnInfo) {
+			t.metrics.connectionsTotal.WithLabelValues(strconv.FormatBool(info.Reused)).Inc()
+		},
+	}
+
+	return t.next.RoundTrip(request.WithContext(httptrace.WithClientTrace(request.Context(), trace))) //nolint:wrapcheck // errors of the transport are returned as is
+}
+
+// Describe implements prometheus.Collector.
+func (c *Client) Describe(ch chan<- *prometheus.Desc) {
+	for _, collector := range c.metrics.collectors() {
+		collector.Describe(ch)
+	}
+}
+
+// Collect implements prometheus.Collector.
+func (c *Client) Collect(ch chan<- prometheus.Metric) {
+	for _, collector := range c.metrics.collectors() {
+		collector.Collect(ch)
+	}
+}
@@ -0,0 +1,48 @@
+package httpclient
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClientMetrics(t *testing.T) {
+	t.Parallel()
+
+	t.Run("count new and reused connections", func(t *testing.T) {
+		t.Parallel()
+
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			w.WriteHeader(http.StatusOK)
+		}))
+		defer server.Close()
+
+		client, err := New(&Config{})
+		require.NoError(t, err)
+
+		for range 2 {
+			response, err := client.Get(server.URL)
+			require.NoError(t, err)
+			require.NoError(t, response.Body.Close())
+		}
+
+		assert.InDelta(t, 1, testutil.ToFloat64(client.metrics.connectionsTotal.WithLabelValues("false")), 0)
+		assert.InDelta(t, 1, testutil.ToFloat64(client.metrics.connectionsTotal.WithLabelValues("true")), 0)
+		assert.InDelta(t, 1, testutil.ToFloat64(client.metrics.dialsTotal.WithLabelValues(familyIPv4, resultSuccess)), 0)
+	})
+
+	t.Run("register client as collector", func(t *testing.T) {
+		t.Parallel()
+
+		client, err := New(&Config{})
+		require.NoError(t, err)
+
+		registry := prometheus.NewRegistry()
+		require.NoError(t, registry.Register(client))
+	})
+}
@@ -0,0 +1,38 @@
+package httpclient
+
+import (
+	"net/http"
+
+	"go.opentelemetry.io/otel/propagation"
+)
+
+// propagationTransport adds the traceparent and baggage headers of the request context to outbound requests,
+// so that downstream services continue the trace even if tracing is disabled.
+type propagationTransport struct {
+	// next is transport sending requests.
+	next http.RoundTripper
+
+	// propagator injects the trace context headers.
+	propagator propagation.TextMapPropagator
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *propagationTransport) RoundTrip(request *http.Request) (*http.Response, error) {
+	headers := http.Header{}
+	t.propagator.Inject(request.Context(), propagation.HeaderCarrier(headers))
+
+	if len(headers) == 0 {
+		return t.next.RoundTrip(request) //nolint:wrapcheck // errors of the transport are returned as is
+	}
+
+	// round trippers must not modify the request, headers set by the caller are kept
+	request = request.Clone(request.Context())
+
+	for key, values := range headers {
+		if request.Header.Get(key) == "" {
+			request.Header[key] = values
+		}
+	}
+
+	return t.next.RoundTrip(request) //nolint:wrapcheck // errors of the transport are returned as is
+}
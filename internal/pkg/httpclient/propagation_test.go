@@ -0,0 +1,92 @@
+package httpclient
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/baggage"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/tracing"
+)
+
+func TestPropagationTransport(t *testing.T) {
+	t.Parallel()
+
+	traceID, err := trace.TraceIDFromHex("4bf92f3577b34da6a3ce929d0e0e4736")
+	require.NoError(t, err)
+
+	spanID, err := trace.SpanIDFromHex("00f067aa0ba902b7")
+	require.NoError(t, err)
+
+	member, err := baggage.NewMember("tenant", "acme")
+	require.NoError(t, err)
+
+	bag, err := baggage.New(member)
+	require.NoError(t, err)
+
+	ctx := trace.ContextWithRemoteSpanContext(context.Background(), trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    traceID,
+		SpanID:     spanID,
+		TraceFlags: trace.FlagsSampled,
+	}))
+	ctx = baggage.ContextWithBaggage(ctx, bag)
+
+	// send sends the request through the transport, returns headers received by the server.
+	send := func(t *testing.T, request *http.Request) http.Header {
+		t.Helper()
+
+		var received http.Header
+
+		server := httptest.NewServer(http.HandlerFunc(func(_ http.ResponseWriter, request *http.Request) {
+			received = request.Header
+		}))
+		t.Cleanup(server.Close)
+
+		request.URL.Scheme, request.URL.Host, request.RequestURI = "http", server.Listener.Addr().String(), ""
+
+		transport := &propagationTransport{next: http.DefaultTransport, propagator: tracing.Propagator()}
+
+		response, err := transport.RoundTrip(request)
+		require.NoError(t, err)
+		require.NoError(t, response.Body.Close())
+
+		return received
+	}
+
+	t.Run("add trace context headers of request context", func(t *testing.T) {
+		t.Parallel()
+
+		request := httptest.NewRequestWithContext(ctx, http.MethodGet, "/", nil)
+
+		received := send(t, request)
+
+		assert.Equal(t, "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", received.Get("Traceparent"))
+		assert.Equal(t, "tenant=acme", received.Get("Baggage"))
+		assert.Empty(t, request.Header.Get("Traceparent"))
+	})
+
+	t.Run("keep headers set by caller", func(t *testing.T) {
+		t.Parallel()
+
+		request := httptest.NewRequestWithContext(ctx, http.MethodGet, "/", nil)
+		request.Header.Set("Baggage", "tenant=other")
+
+		received := send(t, request)
+
+		assert.Equal(t, "tenant=other", received.Get("Baggage"))
+	})
+
+	t.Run("send request without trace context as is", func(t *testing.T) {
+		t.Parallel()
+
+		received := send(t, httptest.NewRequestWithContext(context.Background(), http.MethodGet, "/", nil))
+
+		assert.Empty(t, received.Get("Traceparent"))
+		assert.Empty(t, received.Get("Baggage"))
+	})
+}
@@ -0,0 +1,382 @@
+// Package images provides an image pipeline, originals are stored in object storage with metadata in redis
+// and processed variants (resized, cropped or converted) are generated on first request and stored beside them.
+package images
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"image"
+	"io"
+	"time"
+
+	goredis "github.com/redis/go-redis/v9"
+	"go.uber.org/fx"
+	"golang.org/x/sync/singleflight"
+
+	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/logger"
+	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/redis"
+)
+
+const (
+	// defaultMaxUploadSize is default maximum size of originals in bytes.
+	defaultMaxUploadSize = 10 << 20
+
+	// defaultMaxSourcePixels is default maximum number of pixels of originals.
+	defaultMaxSourcePixels = 40_000_000
+
+	// defaultMaxSize is default maximum width and height of variants.
+	defaultMaxSize = 2048
+
+	// defaultQuality is default quality of jpeg variants.
+	defaultQuality = 85
+
+	// keyPrefix is prefix of redis keys of images.
+	keyPrefix = "images:"
+)
+
+var (
+	// ErrTooLarge is returned when the original exceeds the maximum size or number of pixels.
+	ErrTooLarge = errors.New("image too large")
+
+	// ErrUnsupportedFormat is returned when the original is not a jpeg, png or gif image.
+	ErrUnsupportedFormat = errors.New("unsupported image format")
+)
+
+// Config represents configuration for images.
+type Config struct {
+	// Enabled is whether image endpoints are enabled.
+	Enabled *bool `json:"enabled"`
+
+	// Path is path prefix of image endpoints.
+	Path *string `json:"path"`
+
+	// Storage provides storage of originals and variants.
+	Storage *StorageConfig `json:"storage"`
+
+	// MaxUploadSize is maximum size of originals in bytes.
+	MaxUploadSize *int64 `json:"max_upload_size"`
+
+	// MaxSourcePixels is maximum number of pixels of originals, checked before decoding them.
+	MaxSourcePixels *int `json:"max_source_pixels"`
+
+	// MaxWidth is maximum width of variants.
+	MaxWidth *int `json:"max_width"`
+
+	// MaxHeight is maximum height of variants.
+	MaxHeight *int `json:"max_height"`
+
+	// DefaultQuality is quality of jpeg variants if not requested.
+	DefaultQuality *int `json:"default_quality"`
+}
+
+// SetDefault sets default values.
+func (c *Config) SetDefault() {
+	if c.Enabled == nil {
+		c.Enabled = &[]bool{false}[0]
+	}
+
+	if c.Path == nil {
+		c.Path = &[]string{"/images"}[0]
+	}
+
+	if c.Storage == nil {
+		c.Storage = &StorageConfig{}
+	}
+
+	c.Storage.SetDefault()
+
+	if c.MaxUploadSize == nil {
+		c.MaxUploadSize = &[]int64{defaultMaxUploadSize}[0]
+	}
+
+	if c.MaxSourcePixels == nil {
+		c.MaxSourcePixels = &[]int{defaultMaxSourcePixels}[0]
+	}
+
+	if c.MaxWidth == nil {
+		c.MaxWidth = &[]int{defaultMaxSize}[0]
+	}
+
+	if c.MaxHeight == nil {
+		c.MaxHeight = &[]int{defaultMaxSize}[0]
+	}
+
+	if c.DefaultQuality == nil {
+		c.DefaultQuality = &[]int{defaultQuality}[0]
+	}
+}
+
+// Metadata represents metadata of an original image.
+type Metadata struct {
+	// ID is ID of the image.
+	ID string `json:"id"`
+
+	// UserID is ID of the user who uploaded the image.
+	UserID string `json:"user_id"`
+
+	// Format is format of the original, jpeg, png or gif.
+	Format string `json:"format"`
+
+	// Width is width of the original.
+	Width int `json:"width"`
+
+	// Height is height of the original.
+	Height int `json:"height"`
+
+	// Size is size of the original in bytes.
+	Size int `json:"size"`
+
+	// CreatedAt is time the image was uploaded at.
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Variant represents a processed variant of an image.
+type Variant struct {
+	// Data is the encoded variant.
+	Data []byte
+
+	// ContentType is media type of the variant.
+	ContentType string
+}
+
+// Images provides the image pipeline.
+type Images struct {
+	// config provides images configuration.
+	config *Config
+
+	// storage provides storage of originals and variants.
+	storage Storage
+
+	// redis provides redis client storing metadata.
+	redis *redis.Redis
+
+	// logger provides logger.
+	logger *logger.Logger
+
+	// group deduplicates concurrent processing of the same variant.
+	group singleflight.Group
+
+	// now returns the current time, replaced in tests.
+	now func() time.Time
+}
+
+// NewModule provides module for images.
+func NewModule() fx.Option {
+	return fx.Module("images",
+		fx.Provide(New),
+	)
+}
+
+// New creates a new image pipeline storing objects in the configured directory.
+func New(config *Config, redisConn *redis.Redis, logger *logger.Logger) (*Images, error) {
+	if config == nil {
+		config = &Config{}
+	}
+
+	config.SetDefault()
+
+	// the directory is created only if images are enabled
+	var storage Storage
+
+	if *config.Enabled {
+		fileStorage, err := NewFileStorage(*config.Storage.Dir)
+		if err != nil {
+			return nil, err
+		}
+
+		storage = fileStorage
+	}
+
+	return NewWithStorage(config, storage, redisConn, logger), nil
+}
+
+// NewWithStorage creates a new image pipeline using the storage.
+func NewWithStorage(config *Config, storage Storage, redisConn *redis.Redis, logger *logger.Logger) *Images {
+	if config == nil {
+		config = &Config{}
+	}
+
+	config.SetDefault()
+
+	return &Images{
+		config:  config,
+		storage: storage,
+		redis:   redisConn,
+		logger:  logger.Named("images"),
+		now:     time.Now,
+	}
+}
+
+// Enabled returns whether images are enabled.
+func (i *Images) Enabled() bool {
+	return i != nil && *i.config.Enabled
+}
+
+// Config returns images configuration.
+func (i *Images) Config() *Config {
+	return i.config
+}
+
+// Upload stores the original read from the reader for the user, rejecting originals over the size limits
+// before decoding them.
+func (i *Images) Upload(ctx context.Context, userID string, reader io.Reader) (*Metadata, error) {
+	data, err := io.ReadAll(io.LimitReader(reader, *i.config.MaxUploadSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read image: %w", err)
+	}
+
+	if int64(len(data)) > *i.config.MaxUploadSize {
+		return nil, fmt.Errorf("%w: exceeds %d bytes", ErrTooLarge, *i.config.MaxUploadSize)
+	}
+
+	config, format, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrUnsupportedFormat, err)
+	}
+
+	if config.Width*config.Height > *i.config.MaxSourcePixels {
+		return nil, fmt.Errorf("%w: exceeds %d pixels", ErrTooLarge, *i.config.MaxSourcePixels)
+	}
+
+	id := make([]byte, 16)
+	_, _ = rand.Read(id)
+
+	metadata := &Metadata{
+		ID:        hex.EncodeToString(id),
+		UserID:    userID,
+		Format:    format,
+		Width:     config.Width,
+		Height:    config.Height,
+		Size:      len(data),
+		CreatedAt: i.now().UTC(),
+	}
+
+	if err := i.storage.Put(ctx, objectKey(metadata.ID, "original"), data); err != nil {
+		return nil, err
+	}
+
+	encoded, err := json.Marshal(metadata)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode metadata: %w", err)
+	}
+
+	if err := i.redis.Set(ctx, metadataKey(metadata.ID), encoded, 0).Err(); err != nil {
+		return nil, fmt.Errorf("failed to store metadata of image %s: %w", metadata.ID, err)
+	}
+
+	return metadata, nil
+}
+
+// Metadata returns metadata of the image.
+func (i *Images) Metadata(ctx context.Context, id string) (*Metadata, error) {
+	encoded, err := i.redis.Get(ctx, metadataKey(id)).Bytes()
+	if errors.Is(err, goredis.Nil) {
+		return nil, ErrNotFound
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to get metadata of image %s: %w", id, err)
+	}
+
+	var metadata Metadata
+	if err := json.Unmarshal(encoded, &metadata); err != nil {
+		return nil, fmt.Errorf("failed to decode metadata of image %s: %w", id, err)
+	}
+
+	return &metadata, nil
+}
+
+// Variant returns the variant of the image, processing and storing it on first request.
+func (i *Images) Variant(ctx context.Context, id string, options *Options) (*Variant, error) {
+	key := objectKey(id, options.key())
+	contentType := "image/" + options.Format
+
+	data, err := i.storage.Get(ctx, key)
+	if err == nil {
+		return &Variant{Data: data, ContentType: contentType}, nil
+	}
+
+	if !errors.Is(err, ErrNotFound) {
+		return nil, err
+	}
+
+	// concurrent requests of a variant not stored yet wait for a single processing
+	result, err, _ := i.group.Do(key, func() (interface{}, error) {
+		// processing is shared, so it is not canceled with the request starting it
+		return i.generate(context.WithoutCancel(ctx), id, key, options)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	data, _ = result.([]byte)
+
+	return &Variant{Data: data, ContentType: contentType}, nil
+}
+
+// generate processes the variant from the original and stores it.
+func (i *Images) generate(ctx context.Context, id, key string, options *Options) ([]byte, error) {
+	original, err := i.storage.Get(ctx, objectKey(id, "original"))
+	if err != nil {
+		return nil, err
+	}
+
+	src, _, err := image.Decode(bytes.NewReader(original))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode image %s: %w", id, err)
+	}
+
+	data, err := process(src, options)
+	if err != nil {
+		return nil, err
+	}
+
+	// variants are recorded before they are stored, so that deleting the image never leaves them behind
+	if err := i.redis.SAdd(ctx, variantsKey(id), key).Err(); err != nil {
+		return nil, fmt.Errorf("failed to record variant of image %s: %w", id, err)
+	}
+
+	if err := i.storage.Put(ctx, key, data); err != nil {
+		return nil, err
+	}
+
+	i.logger.Ctx(ctx).Debug().Str("image_id", id).Str("variant", key).Int("size", len(data)).
+		Msg("image variant generated")
+
+	return data, nil
+}
+
+// Delete deletes the image with its variants and metadata.
+func (i *Images) Delete(ctx context.Context, id string) error {
+	variants, err := i.redis.SMembers(ctx, variantsKey(id)).Result()
+	if err != nil {
+		return fmt.Errorf("failed to list variants of image %s: %w", id, err)
+	}
+
+	for _, key := range append(variants, objectKey(id, "original")) {
+		if err := i.storage.Delete(ctx, key); err != nil {
+			return err
+		}
+	}
+
+	if err := i.redis.Del(ctx, metadataKey(id), variantsKey(id)).Err(); err != nil {
+		return fmt.Errorf("failed to delete metadata of image %s: %w", id, err)
+	}
+
+	return nil
+}
+
+// metadataKey returns the redis key of metadata of the image, keys of an image share a hash slot.
+func metadataKey(id string) string {
+	return keyPrefix + "{" + id + "}:metadata"
+}
+
+// variantsKey returns the redis key of the set of variant keys of the image.
+func variantsKey(id string) string {
+	return keyPrefix + "{" + id + "}:variants"
+}
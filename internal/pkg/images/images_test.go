@@ -0,0 +1,270 @@
+package images
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"image"
+	"image/png"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/logger"
+	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/redis"
+)
+
+var errStorageFailed = errors.New("storage failed")
+
+// countingStorage is a storage counting objects stored, failing if err is set.
+type countingStorage struct {
+	Storage
+
+	puts atomic.Int32
+	err  error
+}
+
+func (s *countingStorage) Put(ctx context.Context, key string, data []byte) error {
+	if s.err != nil {
+		return s.err
+	}
+
+	s.puts.Add(1)
+
+	return s.Storage.Put(ctx, key, data)
+}
+
+// setupTestRedis creates a redis client of the test redis server.
+func setupTestRedis(t *testing.T) *redis.Redis {
+	t.Helper()
+
+	password := ""
+	redisDB := 0
+
+	redisClient, err := redis.New(&redis.Config{
+		Addrs:    []string{"localhost:36379"},
+		Password: &password,
+		DB:       &redisDB,
+	})
+	require.NoError(t, err)
+
+	t.Cleanup(func() {
+		_ = redisClient.Close()
+	})
+
+	return redisClient
+}
+
+// setupTestImages creates an enabled image pipeline storing objects in a temporary directory.
+func setupTestImages(t *testing.T, config *Config) (*Images, *countingStorage) {
+	t.Helper()
+
+	log, err := logger.New(&logger.Config{})
+	require.NoError(t, err)
+
+	fileStorage, err := NewFileStorage(t.TempDir())
+	require.NoError(t, err)
+
+	if config == nil {
+		config = &Config{}
+	}
+
+	config.Enabled = &[]bool{true}[0]
+	storage := &countingStorage{Storage: fileStorage}
+
+	return NewWithStorage(config, storage, setupTestRedis(t), log), storage
+}
+
+func TestConfigSetDefault(t *testing.T) {
+	t.Parallel()
+
+	config := &Config{}
+	config.SetDefault()
+
+	assert.False(t, *config.Enabled)
+	assert.Equal(t, "/images", *config.Path)
+	assert.Equal(t, "data/images", *config.Storage.Dir)
+	assert.Equal(t, int64(defaultMaxUploadSize), *config.MaxUploadSize)
+	assert.Equal(t, defaultMaxSourcePixels, *config.MaxSourcePixels)
+	assert.Equal(t, defaultMaxSize, *config.MaxWidth)
+	assert.Equal(t, defaultMaxSize, *config.MaxHeight)
+	assert.Equal(t, defaultQuality, *config.DefaultQuality)
+}
+
+func TestNewModule(t *testing.T) {
+	t.Parallel()
+
+	t.Run("return fx.Option", func(t *testing.T) {
+		t.Parallel()
+
+		require.NotNil(t, NewModule())
+	})
+}
+
+func TestNew(t *testing.T) {
+	t.Parallel()
+
+	log, err := logger.New(&logger.Config{})
+	require.NoError(t, err)
+
+	t.Run("create disabled pipeline without directory", func(t *testing.T) {
+		t.Parallel()
+
+		dir := t.TempDir() + "/images"
+
+		images, err := New(&Config{Storage: &StorageConfig{Dir: &dir}}, nil, log)
+		require.NoError(t, err)
+		assert.False(t, images.Enabled())
+		assert.NoDirExists(t, dir)
+	})
+
+	t.Run("create directory of enabled pipeline", func(t *testing.T) {
+		t.Parallel()
+
+		dir := t.TempDir() + "/images"
+
+		images, err := New(&Config{Enabled: &[]bool{true}[0], Storage: &StorageConfig{Dir: &dir}}, nil, log)
+		require.NoError(t, err)
+		assert.True(t, images.Enabled())
+		assert.DirExists(t, dir)
+	})
+}
+
+func TestUpload(t *testing.T) {
+	t.Parallel()
+
+	t.Run("store original and metadata", func(t *testing.T) {
+		t.Parallel()
+
+		images, _ := setupTestImages(t, nil)
+		data := encodePNG(t, testImage(40, 20))
+
+		metadata, err := images.Upload(context.Background(), "user-1", bytes.NewReader(data))
+		require.NoError(t, err)
+		assert.Len(t, metadata.ID, 32)
+		assert.Equal(t, "user-1", metadata.UserID)
+		assert.Equal(t, FormatPNG, metadata.Format)
+		assert.Equal(t, 40, metadata.Width)
+		assert.Equal(t, 20, metadata.Height)
+		assert.Equal(t, len(data), metadata.Size)
+
+		stored, err := images.Metadata(context.Background(), metadata.ID)
+		require.NoError(t, err)
+		assert.Equal(t, metadata.ID, stored.ID)
+		assert.Equal(t, metadata.Width, stored.Width)
+	})
+
+	t.Run("reject original over size limits", func(t *testing.T) {
+		t.Parallel()
+
+		images, storage := setupTestImages(t, &Config{
+			MaxUploadSize:   &[]int64{1 << 20}[0],
+			MaxSourcePixels: &[]int{1000}[0],
+		})
+
+		_, err := images.Upload(context.Background(), "user-1", bytes.NewReader(make([]byte, 1<<20+1)))
+		require.ErrorIs(t, err, ErrTooLarge)
+
+		// pixels are checked from the header, before the image is decoded
+		_, err = images.Upload(context.Background(), "user-1", bytes.NewReader(encodePNG(t, testImage(100, 100))))
+		require.ErrorIs(t, err, ErrTooLarge)
+
+		assert.Zero(t, storage.puts.Load())
+	})
+
+	t.Run("reject unsupported format", func(t *testing.T) {
+		t.Parallel()
+
+		images, _ := setupTestImages(t, nil)
+
+		_, err := images.Upload(context.Background(), "user-1", bytes.NewReader([]byte("<svg></svg>")))
+		require.ErrorIs(t, err, ErrUnsupportedFormat)
+	})
+
+	t.Run("return error of storage", func(t *testing.T) {
+		t.Parallel()
+
+		images, storage := setupTestImages(t, nil)
+		storage.err = errStorageFailed
+
+		_, err := images.Upload(context.Background(), "user-1", bytes.NewReader(encodePNG(t, testImage(4, 4))))
+		require.ErrorIs(t, err, errStorageFailed)
+	})
+}
+
+func TestVariant(t *testing.T) {
+	t.Parallel()
+
+	t.Run("process variant once", func(t *testing.T) {
+		t.Parallel()
+
+		images, storage := setupTestImages(t, nil)
+
+		metadata, err := images.Upload(context.Background(), "user-1", bytes.NewReader(encodePNG(t, testImage(400, 200))))
+		require.NoError(t, err)
+
+		options := &Options{Width: 100, Fit: FitContain, Format: FormatPNG, Quality: 100}
+
+		var wg sync.WaitGroup
+
+		for range 5 {
+			wg.Go(func() {
+				variant, err := images.Variant(context.Background(), metadata.ID, options)
+				assert.NoError(t, err)
+				assert.Equal(t, "image/png", variant.ContentType)
+			})
+		}
+
+		wg.Wait()
+
+		variant, err := images.Variant(context.Background(), metadata.ID, options)
+		require.NoError(t, err)
+
+		img, err := png.Decode(bytes.NewReader(variant.Data))
+		require.NoError(t, err)
+		assert.Equal(t, image.Rect(0, 0, 100, 50), img.Bounds())
+
+		// the original and the variant, processed once and served from storage afterwards
+		assert.Equal(t, int32(2), storage.puts.Load())
+	})
+
+	t.Run("return error of missing image", func(t *testing.T) {
+		t.Parallel()
+
+		images, _ := setupTestImages(t, nil)
+
+		_, err := images.Variant(context.Background(), "missing", &Options{Fit: FitContain, Format: FormatPNG})
+		require.ErrorIs(t, err, ErrNotFound)
+
+		_, err = images.Metadata(context.Background(), "missing")
+		require.ErrorIs(t, err, ErrNotFound)
+	})
+}
+
+func TestDelete(t *testing.T) {
+	t.Parallel()
+
+	images, storage := setupTestImages(t, nil)
+	ctx := context.Background()
+
+	metadata, err := images.Upload(ctx, "user-1", bytes.NewReader(encodePNG(t, testImage(40, 20))))
+	require.NoError(t, err)
+
+	options := &Options{Width: 10, Fit: FitContain, Format: FormatJPEG, Quality: 80}
+
+	_, err = images.Variant(ctx, metadata.ID, options)
+	require.NoError(t, err)
+
+	require.NoError(t, images.Delete(ctx, metadata.ID))
+
+	_, err = images.Metadata(ctx, metadata.ID)
+	require.ErrorIs(t, err, ErrNotFound)
+
+	for _, key := range []string{objectKey(metadata.ID, "original"), objectKey(metadata.ID, options.key())} {
+		_, err = storage.Get(ctx, key)
+		require.ErrorIs(t, err, ErrNotFound, key)
+	}
+}
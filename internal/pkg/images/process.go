@@ -0,0 +1,230 @@
+package images
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/jpeg"
+	"image/png"
+	"net/url"
+	"strconv"
+
+	// register decoder of gif originals, variants are encoded as jpeg or png
+	_ "image/gif"
+)
+
+const (
+	// FitContain scales images to fit within the requested size, keeping the whole image.
+	FitContain = "contain"
+
+	// FitCover scales images to cover the requested size, cropping the center.
+	FitCover = "cover"
+
+	// FormatJPEG is the jpeg format.
+	FormatJPEG = "jpeg"
+
+	// FormatPNG is the png format.
+	FormatPNG = "png"
+
+	// FormatGIF is the gif format, accepted for originals only.
+	FormatGIF = "gif"
+)
+
+// ErrInvalidOptions is returned when options of a variant are invalid or exceed the limits.
+var ErrInvalidOptions = errors.New("invalid image options")
+
+// Options represents options of a processed variant of an image.
+type Options struct {
+	// Width is maximum width of the variant, unconstrained if zero.
+	Width int
+
+	// Height is maximum height of the variant, unconstrained if zero.
+	Height int
+
+	// Fit is how the image is fitted into the size, contain or cover.
+	Fit string
+
+	// Format is format of the variant, jpeg or png.
+	Format string
+
+	// Quality is quality of jpeg variants from 1 to 100.
+	Quality int
+}
+
+// key returns the key of the variant, unique per output.
+func (o *Options) key() string {
+	return fmt.Sprintf("%dx%d-%s-q%d.%s", o.Width, o.Height, o.Fit, o.Quality, o.Format)
+}
+
+// ParseOptions parses options of the query, w, h, fit, format and q, limited by the config,
+// the format of the original is kept if not requested.
+func ParseOptions(query url.Values, config *Config, original *Metadata) (*Options, error) {
+	options := &Options{
+		Fit:     query.Get("fit"),
+		Format:  query.Get("format"),
+		Quality: *config.DefaultQuality,
+	}
+
+	for param, target := range map[string]*int{"w": &options.Width, "h": &options.Height, "q": &options.Quality} {
+		value := query.Get(param)
+		if value == "" {
+			continue
+		}
+
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 0 {
+			return nil, fmt.Errorf("%w: %s must be a positive integer", ErrInvalidOptions, param)
+		}
+
+		*target = parsed
+	}
+
+	if options.Width > *config.MaxWidth || options.Height > *config.MaxHeight {
+		return nil, fmt.Errorf("%w: size exceeds %dx%d", ErrInvalidOptions, *config.MaxWidth, *config.MaxHeight)
+	}
+
+	if options.Quality < 1 || options.Quality > 100 {
+		return nil, fmt.Errorf("%w: q must be between 1 and 100", ErrInvalidOptions)
+	}
+
+	switch options.Fit {
+	case "":
+		options.Fit = FitContain
+	case FitContain, FitCover:
+	default:
+		return nil, fmt.Errorf("%w: fit must be %s or %s", ErrInvalidOptions, FitContain, FitCover)
+	}
+
+	switch options.Format {
+	case "":
+		options.Format = original.Format
+		if options.Format == FormatGIF {
+			options.Format = FormatPNG
+		}
+	case FormatJPEG, FormatPNG:
+	default:
+		return nil, fmt.Errorf("%w: format must be %s or %s", ErrInvalidOptions, FormatJPEG, FormatPNG)
+	}
+
+	// quality does not change png variants, so they share a key
+	if options.Format == FormatPNG {
+		options.Quality = 100
+	}
+
+	return options, nil
+}
+
+// process resizes the image by the options and encodes it, images are never enlarged.
+func process(src image.Image, options *Options) ([]byte, error) {
+	crop, width, height := layout(src.Bounds(), options)
+	dst := scale(src, crop, width, height)
+
+	var buf bytes.Buffer
+
+	switch options.Format {
+	case FormatJPEG:
+		// jpeg has no alpha, transparent pixels are flattened onto white
+		flattened := image.NewRGBA(dst.Bounds())
+		draw.Draw(flattened, flattened.Bounds(), image.NewUniform(color.White), image.Point{}, draw.Src)
+		draw.Draw(flattened, flattened.Bounds(), dst, image.Point{}, draw.Over)
+
+		if err := jpeg.Encode(&buf, flattened, &jpeg.Options{Quality: options.Quality}); err != nil {
+			return nil, fmt.Errorf("failed to encode jpeg: %w", err)
+		}
+	default:
+		if err := png.Encode(&buf, dst); err != nil {
+			return nil, fmt.Errorf("failed to encode png: %w", err)
+		}
+	}
+
+	return buf.Bytes(), nil
+}
+
+// layout returns the region of the source to scale and the size of the variant.
+func layout(bounds image.Rectangle, options *Options) (image.Rectangle, int, int) {
+	sourceWidth, sourceHeight := bounds.Dx(), bounds.Dy()
+	width, height := options.Width, options.Height
+
+	if options.Fit == FitCover && width > 0 && height > 0 {
+		// shrink the box to the source keeping its aspect ratio, then crop the center of the source to it
+		factor := min(1, float64(sourceWidth)/float64(width), float64(sourceHeight)/float64(height))
+		width, height = max(1, round(float64(width)*factor)), max(1, round(float64(height)*factor))
+
+		cropWidth, cropHeight := sourceWidth, sourceHeight
+		if sourceWidth*height > sourceHeight*width {
+			cropWidth = max(1, round(float64(sourceHeight)*float64(width)/float64(height)))
+		} else {
+			cropHeight = max(1, round(float64(sourceWidth)*float64(height)/float64(width)))
+		}
+
+		origin := bounds.Min.Add(image.Pt((sourceWidth-cropWidth)/2, (sourceHeight-cropHeight)/2))
+
+		return image.Rectangle{Min: origin, Max: origin.Add(image.Pt(cropWidth, cropHeight))}, width, height
+	}
+
+	factor := 1.0
+	if width > 0 {
+		factor = min(factor, float64(width)/float64(sourceWidth))
+	}
+
+	if height > 0 {
+		factor = min(factor, float64(height)/float64(sourceHeight))
+	}
+
+	return bounds, max(1, round(float64(sourceWidth)*factor)), max(1, round(float64(sourceHeight)*factor))
+}
+
+// scale scales the region of the source to the size by averaging the source pixels covered by each pixel.
+func scale(src image.Image, region image.Rectangle, width, height int) *image.RGBA {
+	// the region is copied to premultiplied RGBA once, reading pixels through the interface is slow
+	source := image.NewRGBA(image.Rect(0, 0, region.Dx(), region.Dy()))
+	draw.Draw(source, source.Bounds(), src, region.Min, draw.Src)
+
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	sourceWidth, sourceHeight := region.Dx(), region.Dy()
+
+	for y := range height {
+		top, bottom := y*sourceHeight/height, max((y+1)*sourceHeight/height, y*sourceHeight/height+1)
+
+		for x := range width {
+			left, right := x*sourceWidth/width, max((x+1)*sourceWidth/width, x*sourceWidth/width+1)
+
+			var red, green, blue, alpha, count int
+
+			for sy := top; sy < bottom; sy++ {
+				offset := source.PixOffset(left, sy)
+
+				for sx := left; sx < right; sx++ {
+					red += int(source.Pix[offset])
+					green += int(source.Pix[offset+1])
+					blue += int(source.Pix[offset+2])
+					alpha += int(source.Pix[offset+3])
+					count++
+					offset += 4
+				}
+			}
+
+			offset := dst.PixOffset(x, y)
+			dst.Pix[offset] = mean(red, count)
+			dst.Pix[offset+1] = mean(green, count)
+			dst.Pix[offset+2] = mean(blue, count)
+			dst.Pix[offset+3] = mean(alpha, count)
+		}
+	}
+
+	return dst
+}
+
+// mean returns the mean of count uint8 values summing to sum.
+func mean(sum, count int) uint8 {
+	// #nosec G115 -- mean of uint8 values is within uint8
+	return uint8(sum / count)
+}
+
+// round rounds the value to the nearest integer.
+func round(value float64) int {
+	return int(value + 0.5)
+}
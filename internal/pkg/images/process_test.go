@@ -0,0 +1,199 @@
+package images
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testImage returns an image of the size, the left half red and the right half blue.
+func testImage(width, height int) *image.NRGBA {
+	img := image.NewNRGBA(image.Rect(0, 0, width, height))
+
+	for y := range height {
+		for x := range width {
+			if x < width/2 {
+				img.Set(x, y, color.NRGBA{R: 255, A: 255})
+			} else {
+				img.Set(x, y, color.NRGBA{B: 255, A: 255})
+			}
+		}
+	}
+
+	return img
+}
+
+// encodePNG encodes the image as png.
+func encodePNG(t *testing.T, img image.Image) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	require.NoError(t, png.Encode(&buf, img))
+
+	return buf.Bytes()
+}
+
+// testConfig returns a config with default values.
+func testConfig() *Config {
+	config := &Config{}
+	config.SetDefault()
+
+	return config
+}
+
+func TestParseOptions(t *testing.T) {
+	t.Parallel()
+
+	original := &Metadata{Format: FormatJPEG}
+
+	t.Run("parse options", func(t *testing.T) {
+		t.Parallel()
+
+		query := url.Values{"w": {"200"}, "h": {"100"}, "fit": {"cover"}, "format": {"jpeg"}, "q": {"70"}}
+
+		options, err := ParseOptions(query, testConfig(), original)
+		require.NoError(t, err)
+		assert.Equal(t, &Options{Width: 200, Height: 100, Fit: FitCover, Format: FormatJPEG, Quality: 70}, options)
+	})
+
+	t.Run("set default options", func(t *testing.T) {
+		t.Parallel()
+
+		options, err := ParseOptions(url.Values{}, testConfig(), original)
+		require.NoError(t, err)
+		assert.Equal(t, &Options{Fit: FitContain, Format: FormatJPEG, Quality: defaultQuality}, options)
+
+		options, err = ParseOptions(url.Values{"q": {"50"}}, testConfig(), &Metadata{Format: FormatGIF})
+		require.NoError(t, err)
+		assert.Equal(t, &Options{Fit: FitContain, Format: FormatPNG, Quality: 100}, options)
+	})
+
+	t.Run("reject invalid options", func(t *testing.T) {
+		t.Parallel()
+
+		tests := map[string]url.Values{
+			"not a number":   {"w": {"wide"}},
+			"negative":       {"h": {"-1"}},
+			"too wide":       {"w": {"4096"}},
+			"too high":       {"h": {"4096"}},
+			"quality zero":   {"q": {"0"}},
+			"quality over":   {"q": {"101"}},
+			"unknown fit":    {"fit": {"stretch"}},
+			"unknown format": {"format": {"gif"}},
+		}
+
+		for name, query := range tests {
+			_, err := ParseOptions(query, testConfig(), original)
+			require.ErrorIs(t, err, ErrInvalidOptions, name)
+		}
+	})
+}
+
+func TestLayout(t *testing.T) {
+	t.Parallel()
+
+	bounds := image.Rect(0, 0, 400, 200)
+
+	tests := []struct {
+		name    string
+		options *Options
+		crop    image.Rectangle
+		width   int
+		height  int
+	}{
+		{
+			name:    "contain within width",
+			options: &Options{Width: 100, Fit: FitContain},
+			crop:    bounds, width: 100, height: 50,
+		},
+		{
+			name:    "contain within box",
+			options: &Options{Width: 100, Height: 100, Fit: FitContain},
+			crop:    bounds, width: 100, height: 50,
+		},
+		{
+			name:    "never enlarge",
+			options: &Options{Width: 800, Fit: FitContain},
+			crop:    bounds, width: 400, height: 200,
+		},
+		{
+			name:    "cover crops center",
+			options: &Options{Width: 100, Height: 100, Fit: FitCover},
+			crop:    image.Rect(100, 0, 300, 200), width: 100, height: 100,
+		},
+		{
+			name:    "cover shrinks box larger than source",
+			options: &Options{Width: 400, Height: 400, Fit: FitCover},
+			crop:    image.Rect(100, 0, 300, 200), width: 200, height: 200,
+		},
+		{
+			name:    "cover without height contains",
+			options: &Options{Width: 100, Fit: FitCover},
+			crop:    bounds, width: 100, height: 50,
+		},
+	}
+
+	for _, test := range tests {
+		crop, width, height := layout(bounds, test.options)
+		assert.Equal(t, test.crop, crop, test.name)
+		assert.Equal(t, test.width, width, test.name)
+		assert.Equal(t, test.height, height, test.name)
+	}
+}
+
+func TestProcess(t *testing.T) {
+	t.Parallel()
+
+	t.Run("resize to png", func(t *testing.T) {
+		t.Parallel()
+
+		data, err := process(testImage(400, 200), &Options{Width: 100, Fit: FitContain, Format: FormatPNG})
+		require.NoError(t, err)
+
+		img, err := png.Decode(bytes.NewReader(data))
+		require.NoError(t, err)
+		assert.Equal(t, image.Rect(0, 0, 100, 50), img.Bounds())
+
+		// halves keep their colors after averaging
+		red, _, blue, _ := img.At(10, 25).RGBA()
+		assert.Equal(t, [2]uint32{0xffff, 0}, [2]uint32{red, blue})
+
+		red, _, blue, _ = img.At(90, 25).RGBA()
+		assert.Equal(t, [2]uint32{0, 0xffff}, [2]uint32{red, blue})
+	})
+
+	t.Run("crop to jpeg", func(t *testing.T) {
+		t.Parallel()
+
+		data, err := process(testImage(400, 200), &Options{
+			Width: 50, Height: 50, Fit: FitCover, Format: FormatJPEG, Quality: 90,
+		})
+		require.NoError(t, err)
+
+		img, err := jpeg.Decode(bytes.NewReader(data))
+		require.NoError(t, err)
+		assert.Equal(t, image.Rect(0, 0, 50, 50), img.Bounds())
+	})
+
+	t.Run("flatten transparency onto white for jpeg", func(t *testing.T) {
+		t.Parallel()
+
+		transparent := image.NewNRGBA(image.Rect(0, 0, 8, 8))
+
+		data, err := process(transparent, &Options{Fit: FitContain, Format: FormatJPEG, Quality: 100})
+		require.NoError(t, err)
+
+		img, err := jpeg.Decode(bytes.NewReader(data))
+		require.NoError(t, err)
+
+		red, green, blue, _ := img.At(4, 4).RGBA()
+		assert.Greater(t, min(red, green, blue), uint32(0xf000))
+	})
+}
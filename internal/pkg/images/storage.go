@@ -0,0 +1,135 @@
+package images
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ErrNotFound is returned when the image or its object does not exist.
+var ErrNotFound = errors.New("image not found")
+
+// Storage stores objects of images, originals and processed variants, by key.
+type Storage interface {
+	// Get returns the object of the key, ErrNotFound if it does not exist.
+	Get(ctx context.Context, key string) ([]byte, error)
+
+	// Put stores the object of the key, replacing the existing object.
+	Put(ctx context.Context, key string, data []byte) error
+
+	// Delete deletes the object of the key, deleting a missing object is not an error.
+	Delete(ctx context.Context, key string) error
+}
+
+// StorageConfig represents configuration for storage of images.
+type StorageConfig struct {
+	// Dir is directory objects are stored in, e.g. a mounted volume or bucket.
+	Dir *string `json:"dir"`
+}
+
+// SetDefault sets default values.
+func (c *StorageConfig) SetDefault() {
+	if c.Dir == nil {
+		c.Dir = &[]string{"data/images"}[0]
+	}
+}
+
+// FileStorage stores objects as files under a directory.
+type FileStorage struct {
+	// dir is directory objects are stored in.
+	dir string
+}
+
+// NewFileStorage creates a new file storage of the directory, creating it if it does not exist.
+func NewFileStorage(dir string) (*FileStorage, error) {
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		return nil, fmt.Errorf("failed to create image directory %s: %w", dir, err)
+	}
+
+	return &FileStorage{dir: dir}, nil
+}
+
+// Get returns the object of the key.
+func (s *FileStorage) Get(_ context.Context, key string) ([]byte, error) {
+	root, err := s.open()
+	if err != nil {
+		return nil, err
+	}
+	defer root.Close()
+
+	data, err := root.ReadFile(key)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, ErrNotFound
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to read object %s: %w", key, err)
+	}
+
+	return data, nil
+}
+
+// Put stores the object of the key, written to a temporary file first so that readers never see partial objects
+// and concurrent writers of the key do not interleave.
+func (s *FileStorage) Put(_ context.Context, key string, data []byte) error {
+	root, err := s.open()
+	if err != nil {
+		return err
+	}
+	defer root.Close()
+
+	if dir := filepath.Dir(key); dir != "." {
+		if err := root.MkdirAll(dir, 0o750); err != nil {
+			return fmt.Errorf("failed to create directory of object %s: %w", key, err)
+		}
+	}
+
+	temp := key + "." + rand.Text() + ".tmp"
+
+	if err := root.WriteFile(temp, data, 0o600); err != nil {
+		return fmt.Errorf("failed to write object %s: %w", key, err)
+	}
+
+	if err := root.Rename(temp, key); err != nil {
+		_ = root.Remove(temp)
+
+		return fmt.Errorf("failed to store object %s: %w", key, err)
+	}
+
+	return nil
+}
+
+// Delete deletes the object of the key.
+func (s *FileStorage) Delete(_ context.Context, key string) error {
+	root, err := s.open()
+	if err != nil {
+		return err
+	}
+	defer root.Close()
+
+	if err := root.Remove(key); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("failed to delete object %s: %w", key, err)
+	}
+
+	return nil
+}
+
+// open opens the directory as a root, so that keys cannot escape it.
+func (s *FileStorage) open() (*os.Root, error) {
+	root, err := os.OpenRoot(s.dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open image directory %s: %w", s.dir, err)
+	}
+
+	return root, nil
+}
+
+// objectKey returns the key of the object of the image, names are joined with slashes.
+func objectKey(names ...string) string {
+	return strings.Join(names, "/")
+}
@@ -0,0 +1,70 @@
+package images
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFileStorage(t *testing.T) {
+	t.Parallel()
+
+	t.Run("create directory", func(t *testing.T) {
+		t.Parallel()
+
+		dir := filepath.Join(t.TempDir(), "nested", "images")
+
+		_, err := NewFileStorage(dir)
+		require.NoError(t, err)
+		assert.DirExists(t, dir)
+	})
+
+	t.Run("put, get and delete object", func(t *testing.T) {
+		t.Parallel()
+
+		dir := t.TempDir()
+		storage, err := NewFileStorage(dir)
+		require.NoError(t, err)
+
+		ctx := context.Background()
+
+		require.NoError(t, storage.Put(ctx, objectKey("abc", "original"), []byte("data")))
+		require.NoError(t, storage.Put(ctx, objectKey("abc", "original"), []byte("replaced")))
+
+		data, err := storage.Get(ctx, objectKey("abc", "original"))
+		require.NoError(t, err)
+		assert.Equal(t, []byte("replaced"), data)
+
+		// temporary files are renamed into place
+		entries, err := os.ReadDir(filepath.Join(dir, "abc"))
+		require.NoError(t, err)
+		assert.Len(t, entries, 1)
+
+		require.NoError(t, storage.Delete(ctx, objectKey("abc", "original")))
+		require.NoError(t, storage.Delete(ctx, objectKey("abc", "original")))
+
+		_, err = storage.Get(ctx, objectKey("abc", "original"))
+		require.ErrorIs(t, err, ErrNotFound)
+	})
+
+	t.Run("reject keys escaping directory", func(t *testing.T) {
+		t.Parallel()
+
+		dir := t.TempDir()
+		storage, err := NewFileStorage(filepath.Join(dir, "images"))
+		require.NoError(t, err)
+
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "secret"), []byte("secret"), 0o600))
+
+		_, err = storage.Get(context.Background(), "../secret")
+		require.Error(t, err)
+		require.NotErrorIs(t, err, ErrNotFound)
+
+		require.Error(t, storage.Put(context.Background(), "../escaped", []byte("data")))
+		assert.NoFileExists(t, filepath.Join(dir, "escaped"))
+	})
+}
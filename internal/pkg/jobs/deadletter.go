@@ -0,0 +1,213 @@
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	goredis "github.com/redis/go-redis/v9"
+)
+
+// deadLetterBatchSize is number of dead-lettered jobs read from the stream at once while scanning it.
+const deadLetterBatchSize = 100
+
+// requeueScript moves the entry ARGV[1] from the dead-letter stream KEYS[1] to the ready stream KEYS[2] as the
+// job ARGV[3] in the field ARGV[2], returning 0 if the entry does not exist. Moving jobs in a script keeps them
+// from being requeued twice when requeued concurrently.
+var requeueScript = goredis.NewScript(`
+if redis.call("XDEL", KEYS[1], ARGV[1]) == 0 then
+	return 0
+end
+redis.call("XADD", KEYS[2], "*", ARGV[2], ARGV[3])
+return 1
+`)
+
+// DeadLetter represents a job dead-lettered after its last attempt.
+type DeadLetter struct {
+	*Job
+
+	// DeadLetteredAt is time the job was dead-lettered.
+	DeadLetteredAt time.Time `json:"dead_lettered_at"`
+}
+
+// DeadLetterFilter represents a filter of dead-lettered jobs.
+type DeadLetterFilter struct {
+	// Type is type of the jobs, empty for all types.
+	Type string
+
+	// Error is text contained in errors of the jobs, empty for all errors.
+	Error string
+
+	// Before is cursor returned by the previous page, jobs dead-lettered before it are returned.
+	Before string
+
+	// Limit is maximum number of jobs returned, at least one.
+	Limit int
+}
+
+// match returns whether the job matches the filter.
+func (f *DeadLetterFilter) match(job *Job) bool {
+	return (f.Type == "" || job.Type == f.Type) && strings.Contains(job.Error, f.Error)
+}
+
+// DeadLetters returns dead-lettered jobs matching the filter, the latest first, and the cursor of the next page,
+// empty if there are no more jobs. ErrInvalidCursor is returned if the cursor is not one returned before.
+func (j *Jobs) DeadLetters(ctx context.Context, filter *DeadLetterFilter) ([]*DeadLetter, string, error) {
+	if filter.Before != "" && !validEntryID(filter.Before) {
+		return nil, "", fmt.Errorf("%w: %s", ErrInvalidCursor, filter.Before)
+	}
+
+	limit := max(filter.Limit, 1)
+	letters := make([]*DeadLetter, 0, limit)
+	next := ""
+
+	err := j.scanDeadLetters(ctx, filter.Before, func(letter *DeadLetter) bool {
+		if !filter.match(letter.Job) {
+			return true
+		}
+
+		letters = append(letters, letter)
+
+		if len(letters) < limit {
+			return true
+		}
+
+		next = letter.entryID
+
+		return false
+	})
+	if err != nil {
+		return nil, "", err
+	}
+
+	return letters, next, nil
+}
+
+// DeadLetter returns the dead-lettered job of the ID, ErrNotFound if it is not dead-lettered.
+func (j *Jobs) DeadLetter(ctx context.Context, id string) (*DeadLetter, error) {
+	var found *DeadLetter
+
+	err := j.scanDeadLetters(ctx, "", func(letter *DeadLetter) bool {
+		if letter.ID == id {
+			found = letter
+		}
+
+		return found == nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if found == nil {
+		return nil, fmt.Errorf("%w: %s", ErrNotFound, id)
+	}
+
+	return found, nil
+}
+
+// Requeue moves the dead-lettered job of the ID back to the ready stream with its attempts reset, ErrNotFound if
+// it is not dead-lettered. Its status is queued again.
+func (j *Jobs) Requeue(ctx context.Context, id string) (*Job, error) {
+	letter, err := j.DeadLetter(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	job := letter.Job
+	job.Attempt = 0
+	job.Error = ""
+
+	encoded, err := json.Marshal(job)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode job %s: %w", job.ID, err)
+	}
+
+	keys := []string{j.key("dead"), j.key("ready")}
+
+	moved, err := requeueScript.Run(ctx, j.redis, keys, job.entryID, jobField, encoded).Int()
+	if err != nil {
+		return nil, fmt.Errorf("failed to requeue job %s: %w", job.ID, err)
+	}
+
+	// the job was requeued concurrently
+	if moved == 0 {
+		return nil, fmt.Errorf("%w: %s", ErrNotFound, id)
+	}
+
+	if err := j.saveStatus(ctx, job, StateQueued); err != nil {
+		j.logger.Warn().Err(err).Str("job_id", job.ID).Msg("failed to save job status")
+	}
+
+	j.logger.Info().Str("job_id", job.ID).Str("job_type", job.Type).Msg("dead-lettered job requeued")
+
+	return job, nil
+}
+
+// scanDeadLetters calls the function with dead-lettered jobs before the entry ID, the latest first, until it
+// returns false or all jobs are scanned. Malformed entries are skipped.
+func (j *Jobs) scanDeadLetters(ctx context.Context, before string, fn func(letter *DeadLetter) bool) error {
+	end := "+"
+	if before != "" {
+		end = "(" + before
+	}
+
+	for {
+		messages, err := j.redis.XRevRangeN(ctx, j.key("dead"), end, "-", deadLetterBatchSize).Result()
+		if err != nil {
+			return fmt.Errorf("failed to read dead-letter stream: %w", err)
+		}
+
+		for _, message := range messages {
+			letter, err := decodeDeadLetter(message)
+			if err != nil {
+				j.logger.Warn().Err(err).Str("entry_id", message.ID).Msg("skipping malformed dead-lettered job")
+
+				continue
+			}
+
+			if !fn(letter) {
+				return nil
+			}
+		}
+
+		if len(messages) < deadLetterBatchSize {
+			return nil
+		}
+
+		end = "(" + messages[len(messages)-1].ID
+	}
+}
+
+// decodeDeadLetter decodes the dead-lettered job of the message.
+func decodeDeadLetter(message goredis.XMessage) (*DeadLetter, error) {
+	encoded, _ := message.Values[jobField].(string)
+
+	job := &Job{}
+	if err := json.Unmarshal([]byte(encoded), job); err != nil {
+		return nil, fmt.Errorf("failed to decode dead-lettered job: %w", err)
+	}
+
+	job.entryID = message.ID
+
+	// stream entry IDs start with the time they were added in milliseconds
+	millis, _, _ := strings.Cut(message.ID, "-")
+	deadLetteredAt, _ := strconv.ParseInt(millis, 10, 64)
+
+	return &DeadLetter{Job: job, DeadLetteredAt: time.UnixMilli(deadLetteredAt).UTC()}, nil
+}
+
+// validEntryID returns whether the ID is a stream entry ID.
+func validEntryID(id string) bool {
+	millis, sequence, ok := strings.Cut(id, "-")
+	if !ok {
+		return false
+	}
+
+	_, millisErr := strconv.ParseUint(millis, 10, 64)
+	_, sequenceErr := strconv.ParseUint(sequence, 10, 64)
+
+	return millisErr == nil && sequenceErr == nil
+}
@@ -0,0 +1,135 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// deadLetterJob adds a job of the type failed with the error to the dead-letter stream.
+func deadLetterJob(t *testing.T, jobs *Jobs, jobType string, err error) *Job {
+	t.Helper()
+
+	job := &Job{ID: jobType + "-" + err.Error(), Type: jobType, Payload: []byte(`{}`), Attempt: 3, MaxAttempts: 3}
+	job.Error = err.Error()
+
+	require.NoError(t, jobs.deadLetter(context.Background(), jobs.redis, job))
+
+	return job
+}
+
+func TestDeadLetters(t *testing.T) {
+	t.Parallel()
+
+	errTimeout := errors.New("smtp timeout")
+	errRejected := errors.New("recipient rejected")
+
+	t.Run("list jobs matching filter latest first by page", func(t *testing.T) {
+		t.Parallel()
+
+		jobs := setupTestJobs(t, nil, nil)
+		first := deadLetterJob(t, jobs, "send_email", errTimeout)
+		deadLetterJob(t, jobs, "deliver_webhook", errTimeout)
+		third := deadLetterJob(t, jobs, "send_email", errRejected)
+
+		letters, next, err := jobs.DeadLetters(context.Background(), &DeadLetterFilter{Type: "send_email", Limit: 1})
+		require.NoError(t, err)
+		require.Len(t, letters, 1)
+		assert.Equal(t, third.ID, letters[0].ID)
+		assert.False(t, letters[0].DeadLetteredAt.IsZero())
+		require.NotEmpty(t, next)
+
+		letters, next, err = jobs.DeadLetters(context.Background(), &DeadLetterFilter{
+			Type:   "send_email",
+			Before: next,
+			Limit:  1,
+		})
+		require.NoError(t, err)
+		require.Len(t, letters, 1)
+		assert.Equal(t, first.ID, letters[0].ID)
+		assert.NotEmpty(t, next)
+
+		letters, next, err = jobs.DeadLetters(context.Background(), &DeadLetterFilter{Error: "timeout", Limit: 10})
+		require.NoError(t, err)
+		assert.Len(t, letters, 2)
+		assert.Empty(t, next)
+	})
+
+	t.Run("return error for invalid cursor", func(t *testing.T) {
+		t.Parallel()
+
+		jobs := setupTestJobs(t, nil, nil)
+
+		_, _, err := jobs.DeadLetters(context.Background(), &DeadLetterFilter{Before: "invalid", Limit: 10})
+		require.ErrorIs(t, err, ErrInvalidCursor)
+	})
+
+	t.Run("get job by id", func(t *testing.T) {
+		t.Parallel()
+
+		jobs := setupTestJobs(t, nil, nil)
+		job := deadLetterJob(t, jobs, "send_email", errTimeout)
+
+		letter, err := jobs.DeadLetter(context.Background(), job.ID)
+		require.NoError(t, err)
+		assert.Equal(t, "send_email", letter.Type)
+		assert.Equal(t, errTimeout.Error(), letter.Error)
+
+		_, err = jobs.DeadLetter(context.Background(), "missing")
+		require.ErrorIs(t, err, ErrNotFound)
+	})
+}
+
+func TestRequeue(t *testing.T) {
+	t.Parallel()
+
+	t.Run("move job to ready stream with attempts reset", func(t *testing.T) {
+		t.Parallel()
+
+		jobs := setupTestJobs(t, nil, nil)
+		job := deadLetterJob(t, jobs, "send_email", errors.New("smtp timeout"))
+
+		requeued, err := jobs.Requeue(context.Background(), job.ID)
+		require.NoError(t, err)
+		assert.Zero(t, requeued.Attempt)
+
+		assert.Empty(t, streamJobs(t, jobs, "dead"))
+
+		ready := streamJobs(t, jobs, "ready")
+		require.Len(t, ready, 1)
+		assert.Equal(t, job.ID, ready[0].ID)
+		assert.Zero(t, ready[0].Attempt)
+		assert.Empty(t, ready[0].Error)
+
+		status, err := jobs.Status(context.Background(), job.ID)
+		require.NoError(t, err)
+		assert.Equal(t, StateQueued, status.State)
+
+		_, err = jobs.Requeue(context.Background(), job.ID)
+		require.ErrorIs(t, err, ErrNotFound)
+	})
+
+	t.Run("not requeue entries requeued concurrently", func(t *testing.T) {
+		t.Parallel()
+
+		jobs := setupTestJobs(t, nil, nil)
+		job := deadLetterJob(t, jobs, "send_email", errors.New("smtp timeout"))
+
+		letter, err := jobs.DeadLetter(context.Background(), job.ID)
+		require.NoError(t, err)
+
+		keys := []string{jobs.key("dead"), jobs.key("ready")}
+
+		moved, err := requeueScript.Run(context.Background(), jobs.redis, keys, letter.entryID, jobField, "{}").Int()
+		require.NoError(t, err)
+		assert.Equal(t, 1, moved)
+
+		moved, err = requeueScript.Run(context.Background(), jobs.redis, keys, letter.entryID, jobField, "{}").Int()
+		require.NoError(t, err)
+		assert.Zero(t, moved)
+		assert.Len(t, streamJobs(t, jobs, "ready"), 1)
+	})
+}
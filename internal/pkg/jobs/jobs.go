@@ -0,0 +1,448 @@
+// Package jobs provides background jobs on redis: jobs are enqueued with a type, a payload, a delay and a retry
+// policy, processed at least once by a pool of workers on any instance, retried with exponential backoff and
+// dead-lettered after their last attempt.
+package jobs
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	goredis "github.com/redis/go-redis/v9"
+	"go.uber.org/fx"
+
+	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/logger"
+	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/readonly"
+	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/redis"
+)
+
+const (
+	// defaultPrefix is default prefix of redis keys, the hash tag keeps all keys on one cluster slot.
+	defaultPrefix = "{jobs}:"
+
+	// defaultConcurrency is default number of jobs processed concurrently per instance.
+	defaultConcurrency = 10
+
+	// defaultTimeout is default time an attempt of a job may take.
+	defaultTimeout = 5 * time.Minute
+
+	// defaultReclaimAfter is default time after which jobs of workers that stopped are processed again.
+	defaultReclaimAfter = 10 * time.Minute
+
+	// defaultPollInterval is default interval of promoting delayed jobs and reclaiming jobs.
+	defaultPollInterval = time.Second
+
+	// defaultMaxAttempts is default number of attempts of jobs.
+	defaultMaxAttempts = 5
+
+	// defaultBackoff is default delay before the first retry of jobs, doubled for every further retry.
+	defaultBackoff = 10 * time.Second
+
+	// defaultDeadLetterMaxLen is default approximate maximum number of dead-lettered jobs kept.
+	defaultDeadLetterMaxLen = 10000
+
+	// defaultStatusTTL is default time statuses of jobs are kept after their last update.
+	defaultStatusTTL = 24 * time.Hour
+
+	// group is consumer group of workers on the ready stream.
+	group = "workers"
+
+	// jobField is field of stream entries holding the encoded job.
+	jobField = "job"
+)
+
+var (
+	// ErrInvalidConfig is returned when jobs are reclaimed before their attempts time out.
+	ErrInvalidConfig = errors.New("jobs reclaim_after must be greater than timeout")
+
+	// ErrNoHandler is recorded on jobs dead-lettered because no handler is registered for their type.
+	ErrNoHandler = errors.New("no handler registered for job type")
+
+	// ErrNotFound is returned when no status of the job is stored, or it expired.
+	ErrNotFound = errors.New("job not found")
+
+	// ErrNotProcessing is returned when progress is reported on a job that is not being processed.
+	ErrNotProcessing = errors.New("job is not being processed")
+
+	// ErrInvalidCursor is returned when listing dead-lettered jobs with a cursor that is not a stream entry ID.
+	ErrInvalidCursor = errors.New("invalid dead-letter cursor")
+)
+
+// Config represents configuration for background jobs.
+type Config struct {
+	// Enabled is whether workers process jobs on this instance, jobs can be enqueued either way.
+	Enabled *bool `json:"enabled"`
+
+	// Prefix is prefix of redis keys, keep a hash tag (e.g. {jobs}:) so that keys share a cluster slot.
+	Prefix *string `json:"prefix"`
+
+	// Concurrency is number of jobs processed concurrently per instance.
+	Concurrency *int `json:"concurrency"`
+
+	// Timeout is time an attempt of a job may take, its context is canceled afterwards.
+	Timeout *time.Duration `json:"timeout"`
+
+	// ReclaimAfter is time after which jobs of workers that stopped without finishing them are processed again,
+	// it must be greater than the timeout.
+	ReclaimAfter *time.Duration `json:"reclaim_after"`
+
+	// PollInterval is interval of promoting delayed jobs, reclaiming jobs and checking read-only mode.
+	PollInterval *time.Duration `json:"poll_interval"`
+
+	// MaxAttempts is number of attempts of jobs enqueued without a retry policy.
+	MaxAttempts *int `json:"max_attempts"`
+
+	// Backoff is delay before the first retry of jobs enqueued without a retry policy, doubled for every retry.
+	Backoff *time.Duration `json:"backoff"`
+
+	// DeadLetterMaxLen is approximate maximum number of dead-lettered jobs kept, older ones are trimmed.
+	DeadLetterMaxLen *int64 `json:"dead_letter_max_len"`
+
+	// StatusTTL is time statuses of jobs, with their progress and result, are kept after their last update.
+	StatusTTL *time.Duration `json:"status_ttl"`
+}
+
+// SetDefault sets default values.
+func (c *Config) SetDefault() {
+	if c.Enabled == nil {
+		c.Enabled = &[]bool{false}[0]
+	}
+
+	if c.Prefix == nil {
+		c.Prefix = &[]string{defaultPrefix}[0]
+	}
+
+	if c.Concurrency == nil {
+		c.Concurrency = &[]int{defaultConcurrency}[0]
+	}
+
+	if c.Timeout == nil {
+		c.Timeout = &[]time.Duration{defaultTimeout}[0]
+	}
+
+	if c.ReclaimAfter == nil {
+		c.ReclaimAfter = &[]time.Duration{defaultReclaimAfter}[0]
+	}
+
+	if c.PollInterval == nil {
+		c.PollInterval = &[]time.Duration{defaultPollInterval}[0]
+	}
+
+	if c.MaxAttempts == nil {
+		c.MaxAttempts = &[]int{defaultMaxAttempts}[0]
+	}
+
+	if c.Backoff == nil {
+		c.Backoff = &[]time.Duration{defaultBackoff}[0]
+	}
+
+	if c.DeadLetterMaxLen == nil {
+		c.DeadLetterMaxLen = &[]int64{defaultDeadLetterMaxLen}[0]
+	}
+
+	if c.StatusTTL == nil {
+		c.StatusTTL = &[]time.Duration{defaultStatusTTL}[0]
+	}
+}
+
+// Job represents a background job.
+type Job struct {
+	// ID is unique ID of the job.
+	ID string `json:"id"`
+
+	// Type is type of the job, selecting its handler.
+	Type string `json:"type"`
+
+	// UserID is ID of the user the job runs for, who may read its status and receives its updates.
+	UserID string `json:"user_id,omitempty"`
+
+	// Payload is JSON encoded payload of the job.
+	Payload json.RawMessage `json:"payload"`
+
+	// Attempt is number of attempts of the job started, including the current attempt.
+	Attempt int `json:"attempt"`
+
+	// MaxAttempts is number of attempts of the job before it is dead-lettered.
+	MaxAttempts int `json:"max_attempts"`
+
+	// Backoff is delay before the first retry of the job, doubled for every retry.
+	Backoff time.Duration `json:"backoff"`
+
+	// EnqueuedAt is time the job was enqueued.
+	EnqueuedAt time.Time `json:"enqueued_at"`
+
+	// Error is error of the last failed attempt.
+	Error string `json:"error,omitempty"`
+
+	// Checkpoint is JSON encoded state saved by the handler, from which the next attempt resumes.
+	Checkpoint json.RawMessage `json:"checkpoint,omitempty"`
+
+	// entryID is ID of the stream entry of the job being processed.
+	entryID string
+
+	// jobs stores the status of the job being processed, nil if it is not being processed.
+	jobs *Jobs
+
+	// percent is progress of the attempt in percent.
+	percent int
+
+	// message is message of the progress of the attempt.
+	message string
+
+	// result is JSON encoded result of the attempt.
+	result json.RawMessage
+}
+
+// Decode decodes the payload of the job into the value.
+func (j *Job) Decode(value any) error {
+	if err := json.Unmarshal(j.Payload, value); err != nil {
+		return fmt.Errorf("failed to decode payload of job %s: %w", j.ID, err)
+	}
+
+	return nil
+}
+
+// EnqueueOptions represents options of an enqueued job, zero values are replaced by defaults of the config.
+type EnqueueOptions struct {
+	// Delay is time before the job is processed.
+	Delay time.Duration
+
+	// MaxAttempts is number of attempts of the job before it is dead-lettered.
+	MaxAttempts int
+
+	// Backoff is delay before the first retry of the job, doubled for every retry.
+	Backoff time.Duration
+
+	// UserID is ID of the user the job runs for, who may read its status and receives its updates.
+	UserID string
+}
+
+// Handler processes jobs of a type, jobs whose handler returns an error are retried.
+type Handler func(ctx context.Context, job *Job) error
+
+// Registration represents a handler of a job type, modules provide registrations in the job_handlers group.
+type Registration struct {
+	// Type is type of jobs processed by the handler.
+	Type string
+
+	// Handler processes the jobs.
+	Handler Handler
+}
+
+// Jobs provides enqueuing background jobs and the workers processing them.
+type Jobs struct {
+	// config provides jobs configuration.
+	config *Config
+
+	// redis provides redis client storing jobs.
+	redis *redis.Redis
+
+	// readOnly pauses workers in read-only mode, nil if it is not available.
+	readOnly *readonly.ReadOnly
+
+	// logger provides logger.
+	logger *logger.Logger
+
+	// metrics provides prometheus collectors of jobs.
+	metrics *metrics
+
+	// consumer is name of this instance in the consumer group.
+	consumer string
+
+	// handlersMu guards handlers.
+	handlersMu sync.RWMutex
+
+	// handlers is handlers by job type.
+	handlers map[string]Handler
+
+	// runMu guards cancel and done.
+	runMu sync.Mutex
+
+	// cancel stops fetching jobs, nil if workers are not running.
+	cancel context.CancelFunc
+
+	// abort cancels jobs being processed, when workers are stopped before they finish.
+	abort context.CancelFunc
+
+	// done is closed when workers return.
+	done chan struct{}
+
+	// now returns the current time, replaced in tests.
+	now func() time.Time
+}
+
+// HandlersParams represents handlers registered by modules.
+type HandlersParams struct {
+	fx.In
+
+	Jobs          *Jobs
+	Registrations []Registration `group:"job_handlers"`
+}
+
+// NewModule provides module for jobs.
+func NewModule() fx.Option {
+	return fx.Module("jobs",
+		fx.Provide(New),
+		fx.Invoke(registerHandlers),
+	)
+}
+
+// registerHandlers registers handlers provided by modules, e.g. with
+// fx.Annotate(newEmailRegistration, fx.ResultTags(`group:"job_handlers"`)).
+func registerHandlers(params HandlersParams) {
+	for _, registration := range params.Registrations {
+		params.Jobs.Handle(registration.Type, registration.Handler)
+	}
+}
+
+// New creates new jobs, readOnly may be nil.
+func New(config *Config, redisConn *redis.Redis, readOnly *readonly.ReadOnly, logger *logger.Logger) (*Jobs, error) {
+	if config == nil {
+		config = &Config{}
+	}
+
+	config.SetDefault()
+
+	if *config.ReclaimAfter <= *config.Timeout {
+		return nil, ErrInvalidConfig
+	}
+
+	hostname, _ := os.Hostname()
+
+	suffix, err := newID()
+	if err != nil {
+		return nil, err
+	}
+
+	return &Jobs{
+		config:   config,
+		redis:    redisConn,
+		readOnly: readOnly,
+		logger:   logger.Named("jobs"),
+		metrics:  newMetrics(),
+		consumer: hostname + "-" + strconv.Itoa(os.Getpid()) + "-" + suffix[:8],
+		handlers: map[string]Handler{},
+		now:      time.Now,
+	}, nil
+}
+
+// Enabled returns whether workers process jobs on this instance.
+func (j *Jobs) Enabled() bool {
+	return *j.config.Enabled
+}
+
+// Handle registers the handler of jobs of the type, replacing a handler registered before.
+func (j *Jobs) Handle(jobType string, handler Handler) {
+	j.handlersMu.Lock()
+	defer j.handlersMu.Unlock()
+
+	j.handlers[jobType] = handler
+}
+
+// Enqueue enqueues a job of the type with the JSON encoded payload, options may be nil to use defaults.
+func (j *Jobs) Enqueue(ctx context.Context, jobType string, payload any, options *EnqueueOptions) (*Job, error) {
+	encoded, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode payload of %s job: %w", jobType, err)
+	}
+
+	id, err := newID()
+	if err != nil {
+		return nil, err
+	}
+
+	opts := EnqueueOptions{}
+	if options != nil {
+		opts = *options
+	}
+
+	job := &Job{
+		ID:          id,
+		Type:        jobType,
+		UserID:      opts.UserID,
+		Payload:     encoded,
+		MaxAttempts: opts.MaxAttempts,
+		Backoff:     opts.Backoff,
+		EnqueuedAt:  j.now().UTC(),
+	}
+
+	if job.MaxAttempts <= 0 {
+		job.MaxAttempts = *j.config.MaxAttempts
+	}
+
+	if job.Backoff <= 0 {
+		job.Backoff = *j.config.Backoff
+	}
+
+	// the job is queued with its status, so that the status is readable as soon as the job is
+	_, err = j.redis.TxPipelined(ctx, func(pipe goredis.Pipeliner) error {
+		if err := j.schedule(ctx, pipe, job, opts.Delay); err != nil {
+			return err
+		}
+
+		return j.setStatus(ctx, pipe, job, StateQueued)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to store job %s: %w", job.ID, err)
+	}
+
+	j.metrics.enqueuedTotal.WithLabelValues(jobType).Inc()
+
+	return job, nil
+}
+
+// schedule adds the job to the ready stream, or to the delayed jobs if it has a delay.
+func (j *Jobs) schedule(ctx context.Context, cmd goredis.Cmdable, job *Job, delay time.Duration) error {
+	encoded, err := json.Marshal(job)
+	if err != nil {
+		return fmt.Errorf("failed to encode job %s: %w", job.ID, err)
+	}
+
+	if delay > 0 {
+		err = cmd.ZAdd(ctx, j.key("delayed"), goredis.Z{
+			Score:  float64(j.now().Add(delay).UnixMilli()),
+			Member: encoded,
+		}).Err()
+	} else {
+		err = cmd.XAdd(ctx, &goredis.XAddArgs{
+			Stream: j.key("ready"),
+			Values: []any{jobField, encoded},
+		}).Err()
+	}
+
+	if err != nil {
+		return fmt.Errorf("failed to enqueue job %s: %w", job.ID, err)
+	}
+
+	return nil
+}
+
+// key returns the redis key of the name.
+func (j *Jobs) key(name string) string {
+	return *j.config.Prefix + name
+}
+
+// handler returns the handler of the job type, nil if none is registered.
+func (j *Jobs) handler(jobType string) Handler {
+	j.handlersMu.RLock()
+	defer j.handlersMu.RUnlock()
+
+	return j.handlers[jobType]
+}
+
+// newID returns a random ID.
+func newID() (string, error) {
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return "", fmt.Errorf("failed to generate job id: %w", err)
+	}
+
+	return hex.EncodeToString(id), nil
+}
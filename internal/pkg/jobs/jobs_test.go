@@ -0,0 +1,207 @@
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/logger"
+	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/readonly"
+	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/redis"
+)
+
+// testPayload is a payload of jobs in tests.
+type testPayload struct {
+	Email string `json:"email"`
+}
+
+// setupTestRedis creates a client of the test redis server.
+func setupTestRedis(t *testing.T) *redis.Redis {
+	t.Helper()
+
+	password := ""
+	redisDB := 0
+
+	redisClient, err := redis.New(&redis.Config{
+		Addrs:    []string{"localhost:36379"},
+		Password: &password,
+		DB:       &redisDB,
+	})
+	require.NoError(t, err)
+
+	t.Cleanup(func() {
+		_ = redisClient.Close()
+	})
+
+	return redisClient
+}
+
+// setupTestJobs creates jobs on the test redis server with keys prefixed by the test name.
+func setupTestJobs(t *testing.T, config *Config, readOnly *readonly.ReadOnly) *Jobs {
+	t.Helper()
+
+	log, err := logger.New(&logger.Config{})
+	require.NoError(t, err)
+
+	if config == nil {
+		config = &Config{}
+	}
+
+	config.Prefix = &[]string{fmt.Sprintf("{jobs:%s:%d}:", t.Name(), time.Now().UnixNano())}[0]
+
+	jobs, err := New(config, setupTestRedis(t), readOnly, log)
+	require.NoError(t, err)
+
+	return jobs
+}
+
+// streamJobs returns jobs in the stream.
+func streamJobs(t *testing.T, jobs *Jobs, stream string) []*Job {
+	t.Helper()
+
+	messages, err := jobs.redis.XRange(context.Background(), jobs.key(stream), "-", "+").Result()
+	require.NoError(t, err)
+
+	result := make([]*Job, 0, len(messages))
+
+	for _, message := range messages {
+		job := &Job{}
+		require.NoError(t, json.Unmarshal([]byte(message.Values[jobField].(string)), job))
+
+		result = append(result, job)
+	}
+
+	return result
+}
+
+func TestConfigSetDefault(t *testing.T) {
+	t.Parallel()
+
+	config := &Config{}
+	config.SetDefault()
+
+	assert.False(t, *config.Enabled)
+	assert.Equal(t, defaultPrefix, *config.Prefix)
+	assert.Equal(t, defaultConcurrency, *config.Concurrency)
+	assert.Equal(t, defaultTimeout, *config.Timeout)
+	assert.Equal(t, defaultReclaimAfter, *config.ReclaimAfter)
+	assert.Equal(t, defaultPollInterval, *config.PollInterval)
+	assert.Equal(t, defaultMaxAttempts, *config.MaxAttempts)
+	assert.Equal(t, defaultBackoff, *config.Backoff)
+	assert.Equal(t, int64(defaultDeadLetterMaxLen), *config.DeadLetterMaxLen)
+	assert.Equal(t, defaultStatusTTL, *config.StatusTTL)
+}
+
+func TestNew(t *testing.T) {
+	t.Parallel()
+
+	log, err := logger.New(&logger.Config{})
+	require.NoError(t, err)
+
+	t.Run("create jobs with default configuration", func(t *testing.T) {
+		t.Parallel()
+
+		jobs, err := New(nil, nil, nil, log)
+		require.NoError(t, err)
+		assert.False(t, jobs.Enabled())
+		assert.NotEmpty(t, jobs.consumer)
+	})
+
+	t.Run("return error if jobs are reclaimed before timing out", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := New(&Config{
+			Timeout:      &[]time.Duration{time.Minute}[0],
+			ReclaimAfter: &[]time.Duration{time.Minute}[0],
+		}, nil, nil, log)
+		require.ErrorIs(t, err, ErrInvalidConfig)
+	})
+}
+
+func TestEnqueue(t *testing.T) {
+	t.Parallel()
+
+	t.Run("add job to ready stream with default retry policy", func(t *testing.T) {
+		t.Parallel()
+
+		jobs := setupTestJobs(t, nil, nil)
+
+		job, err := jobs.Enqueue(context.Background(), "send_email", testPayload{Email: "user@example.com"}, nil)
+		require.NoError(t, err)
+		assert.Len(t, job.ID, 32)
+		assert.Equal(t, defaultMaxAttempts, job.MaxAttempts)
+		assert.Equal(t, defaultBackoff, job.Backoff)
+
+		ready := streamJobs(t, jobs, "ready")
+		require.Len(t, ready, 1)
+		assert.Equal(t, job.ID, ready[0].ID)
+		assert.Equal(t, "send_email", ready[0].Type)
+		assert.Zero(t, ready[0].Attempt)
+
+		var payload testPayload
+		require.NoError(t, ready[0].Decode(&payload))
+		assert.Equal(t, "user@example.com", payload.Email)
+	})
+
+	t.Run("add delayed job to delayed set with retry policy", func(t *testing.T) {
+		t.Parallel()
+
+		jobs := setupTestJobs(t, nil, nil)
+		now := time.Now()
+		jobs.now = func() time.Time { return now }
+
+		job, err := jobs.Enqueue(context.Background(), "send_email", testPayload{}, &EnqueueOptions{
+			Delay:       time.Minute,
+			MaxAttempts: 2,
+			Backoff:     time.Second,
+		})
+		require.NoError(t, err)
+		assert.Equal(t, 2, job.MaxAttempts)
+		assert.Equal(t, time.Second, job.Backoff)
+		assert.Empty(t, streamJobs(t, jobs, "ready"))
+
+		delayed, err := jobs.redis.ZRangeWithScores(context.Background(), jobs.key("delayed"), 0, -1).Result()
+		require.NoError(t, err)
+		require.Len(t, delayed, 1)
+		assert.InDelta(t, float64(now.Add(time.Minute).UnixMilli()), delayed[0].Score, 0)
+	})
+
+	t.Run("return error for payload that cannot be encoded", func(t *testing.T) {
+		t.Parallel()
+
+		jobs := setupTestJobs(t, nil, nil)
+
+		_, err := jobs.Enqueue(context.Background(), "send_email", make(chan int), nil)
+		require.Error(t, err)
+	})
+}
+
+func TestDecode(t *testing.T) {
+	t.Parallel()
+
+	job := &Job{ID: "job", Payload: json.RawMessage(`"not an object"`)}
+
+	var payload testPayload
+	require.Error(t, job.Decode(&payload))
+}
+
+func TestRegisterHandlers(t *testing.T) {
+	t.Parallel()
+
+	jobs := setupTestJobs(t, nil, nil)
+
+	registerHandlers(HandlersParams{
+		Jobs: jobs,
+		Registrations: []Registration{
+			{Type: "send_email", Handler: func(context.Context, *Job) error { return nil }},
+		},
+	})
+
+	assert.NotNil(t, jobs.handler("send_email"))
+	assert.Nil(t, jobs.handler("unknown"))
+}
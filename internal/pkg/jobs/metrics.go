@@ -0,0 +1,117 @@
+package jobs
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	// resultSuccess is result label of jobs processed successfully.
+	resultSuccess = "success"
+
+	// resultRetry is result label of failed jobs scheduled for another attempt.
+	resultRetry = "retry"
+
+	// resultDead is result label of failed jobs dead-lettered.
+	resultDead = "dead"
+
+	// depthTimeout is time reading queue depths may take when metrics are collected.
+	depthTimeout = time.Second
+)
+
+// metrics holds prometheus collectors of jobs.
+type metrics struct {
+	// enqueuedTotal is number of enqueued jobs by type.
+	enqueuedTotal *prometheus.CounterVec
+
+	// processedTotal is number of processed attempts of jobs by type and result.
+	processedTotal *prometheus.CounterVec
+
+	// duration is duration of attempts of jobs by type.
+	duration *prometheus.HistogramVec
+
+	// depth is number of jobs by queue, read from redis when metrics are collected.
+	depth *prometheus.GaugeVec
+}
+
+// newMetrics creates collectors of jobs.
+func newMetrics() *metrics {
+	return &metrics{
+		enqueuedTotal: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "jobs_enqueued_total",
+				Help: "Total number of enqueued jobs",
+			},
+			[]string{"type"},
+		),
+		processedTotal: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "jobs_processed_total",
+				Help: "Total number of processed attempts of jobs",
+			},
+			[]string{"type", "result"},
+		),
+		duration: prometheus.NewHistogramVec(
+			prometheus.HistogramOpts{
+				Name:    "jobs_processing_duration_seconds",
+				Help:    "Duration of attempts of jobs in seconds",
+				Buckets: prometheus.ExponentialBuckets(0.005, 4, 10),
+			},
+			[]string{"type"},
+		),
+		depth: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "jobs_queue_depth",
+				Help: "Number of jobs ready or being processed, delayed and dead-lettered",
+			},
+			[]string{"queue"},
+		),
+	}
+}
+
+// collectors returns all collectors of the metrics.
+func (m *metrics) collectors() []prometheus.Collector {
+	return []prometheus.Collector{m.enqueuedTotal, m.processedTotal, m.duration, m.depth}
+}
+
+// Depths returns number of jobs by queue: ready (including jobs being processed), delayed and dead.
+func (j *Jobs) Depths(ctx context.Context) (map[string]int64, error) {
+	pipe := j.redis.Pipeline()
+	ready := pipe.XLen(ctx, j.key("ready"))
+	delayed := pipe.ZCard(ctx, j.key("delayed"))
+	dead := pipe.XLen(ctx, j.key("dead"))
+
+	if _, err := pipe.Exec(ctx); err != nil {
+		return nil, fmt.Errorf("failed to read queue depths: %w", err)
+	}
+
+	return map[string]int64{"ready": ready.Val(), "delayed": delayed.Val(), "dead": dead.Val()}, nil
+}
+
+// Describe implements prometheus.Collector.
+func (j *Jobs) Describe(ch chan<- *prometheus.Desc) {
+	for _, collector := range j.metrics.collectors() {
+		collector.Describe(ch)
+	}
+}
+
+// Collect implements prometheus.Collector, queue depths are read from redis and kept if reading fails.
+func (j *Jobs) Collect(ch chan<- prometheus.Metric) {
+	ctx, cancel := context.WithTimeout(context.Background(), depthTimeout)
+	defer cancel()
+
+	if depths, err := j.Depths(ctx); err == nil {
+		for queue, depth := range depths {
+			j.metrics.depth.WithLabelValues(queue).Set(float64(depth))
+		}
+	} else {
+		j.logger.Debug().Err(err).Msg("failed to collect queue depths")
+	}
+
+	for _, collector := range j.metrics.collectors() {
+		collector.Collect(ch)
+	}
+}
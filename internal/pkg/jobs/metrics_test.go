@@ -0,0 +1,58 @@
+package jobs
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestJobsMetrics(t *testing.T) {
+	t.Parallel()
+
+	t.Run("count enqueued and processed jobs", func(t *testing.T) {
+		t.Parallel()
+
+		jobs := setupTestJobs(t, nil, nil)
+		jobs.Handle("send_email", func(context.Context, *Job) error { return nil })
+
+		for range 2 {
+			_, err := jobs.Enqueue(context.Background(), "send_email", testPayload{}, nil)
+			require.NoError(t, err)
+		}
+
+		processNext(t, jobs)
+
+		metrics := jobs.metrics
+		assert.InDelta(t, 2, testutil.ToFloat64(metrics.enqueuedTotal.WithLabelValues("send_email")), 0)
+		assert.InDelta(t, 1,
+			testutil.ToFloat64(metrics.processedTotal.WithLabelValues("send_email", resultSuccess)), 0)
+		assert.Equal(t, 1, testutil.CollectAndCount(metrics.duration))
+	})
+
+	t.Run("report queue depths", func(t *testing.T) {
+		t.Parallel()
+
+		jobs := setupTestJobs(t, nil, nil)
+
+		_, err := jobs.Enqueue(context.Background(), "send_email", testPayload{}, nil)
+		require.NoError(t, err)
+
+		_, err = jobs.Enqueue(context.Background(), "send_email", testPayload{}, &EnqueueOptions{Delay: time.Hour})
+		require.NoError(t, err)
+
+		registry := prometheus.NewRegistry()
+		require.NoError(t, registry.Register(jobs))
+
+		_, err = registry.Gather()
+		require.NoError(t, err)
+
+		assert.InDelta(t, 1, testutil.ToFloat64(jobs.metrics.depth.WithLabelValues("ready")), 0)
+		assert.InDelta(t, 1, testutil.ToFloat64(jobs.metrics.depth.WithLabelValues("delayed")), 0)
+		assert.InDelta(t, 0, testutil.ToFloat64(jobs.metrics.depth.WithLabelValues("dead")), 0)
+	})
+}
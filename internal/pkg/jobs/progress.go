@@ -0,0 +1,218 @@
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	goredis "github.com/redis/go-redis/v9"
+)
+
+// State represents a state of a job.
+type State string
+
+const (
+	// StateQueued is state of jobs waiting for a worker.
+	StateQueued State = "queued"
+
+	// StateRunning is state of jobs being processed.
+	StateRunning State = "running"
+
+	// StateRetrying is state of jobs waiting for a retry after a failed attempt.
+	StateRetrying State = "retrying"
+
+	// StateSucceeded is state of jobs whose handler succeeded.
+	StateSucceeded State = "succeeded"
+
+	// StateFailed is state of jobs dead-lettered after their last attempt.
+	StateFailed State = "failed"
+)
+
+// Status represents the status of a job with its progress and result, stored until it expires and published to
+// watchers on every update.
+type Status struct {
+	// ID is ID of the job.
+	ID string `json:"id"`
+
+	// Type is type of the job.
+	Type string `json:"type"`
+
+	// UserID is ID of the user the job runs for.
+	UserID string `json:"user_id,omitempty"`
+
+	// State is state of the job.
+	State State `json:"state"`
+
+	// Attempt is number of attempts started.
+	Attempt int `json:"attempt"`
+
+	// MaxAttempts is maximum number of attempts.
+	MaxAttempts int `json:"max_attempts"`
+
+	// Percent is progress of the current attempt in percent.
+	Percent int `json:"percent"`
+
+	// Message is message of the progress of the current attempt.
+	Message string `json:"message,omitempty"`
+
+	// Result is JSON encoded result of the job once it succeeded.
+	Result json.RawMessage `json:"result,omitempty"`
+
+	// Error is error of the last failed attempt.
+	Error string `json:"error,omitempty"`
+
+	// UpdatedAt is time the status was updated.
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Progress reports progress of the job in percent, clamped to 0 to 100, with a message. It is called by the
+// handler processing the job, the progress is stored in its status and published to watchers.
+func (j *Job) Progress(ctx context.Context, percent int, message string) error {
+	if j.jobs == nil {
+		return fmt.Errorf("%w: %s", ErrNotProcessing, j.ID)
+	}
+
+	j.percent = min(max(percent, 0), 100)
+	j.message = message
+
+	return j.jobs.saveStatus(ctx, j, StateRunning)
+}
+
+// SetResult sets the result of the job, stored JSON encoded in its status once the handler succeeds.
+func (j *Job) SetResult(value any) error {
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Errorf("failed to encode result of job %s: %w", j.ID, err)
+	}
+
+	j.result = encoded
+
+	return nil
+}
+
+// SetCheckpoint saves the state of the job, JSON encoded, kept when the job is retried or re-enqueued by stopping
+// workers, so that handlers checkpoint long jobs and resume them with Resume instead of starting over.
+func (j *Job) SetCheckpoint(value any) error {
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Errorf("failed to encode checkpoint of job %s: %w", j.ID, err)
+	}
+
+	j.Checkpoint = encoded
+
+	return nil
+}
+
+// Resume decodes the checkpoint of the job into the value, and returns false if no checkpoint is saved.
+func (j *Job) Resume(value any) (bool, error) {
+	if len(j.Checkpoint) == 0 {
+		return false, nil
+	}
+
+	if err := json.Unmarshal(j.Checkpoint, value); err != nil {
+		return false, fmt.Errorf("failed to decode checkpoint of job %s: %w", j.ID, err)
+	}
+
+	return true, nil
+}
+
+// Status returns the status of the job of the ID, ErrNotFound if it is not stored.
+func (j *Jobs) Status(ctx context.Context, id string) (*Status, error) {
+	encoded, err := j.redis.Get(ctx, j.key("status:"+id)).Bytes()
+	if errors.Is(err, goredis.Nil) {
+		return nil, fmt.Errorf("%w: %s", ErrNotFound, id)
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to get status of job %s: %w", id, err)
+	}
+
+	var status Status
+	if err := json.Unmarshal(encoded, &status); err != nil {
+		return nil, fmt.Errorf("failed to decode status of job %s: %w", id, err)
+	}
+
+	return &status, nil
+}
+
+// Watch calls the function with statuses of jobs updated on any instance until the context is done. It returns
+// once subscribed, statuses are received in the background.
+func (j *Jobs) Watch(ctx context.Context, watch func(status *Status)) error {
+	pubsub := j.redis.Subscribe(ctx, j.key("status"))
+
+	// wait for the subscription so updates published after Watch are received
+	if _, err := pubsub.Receive(ctx); err != nil {
+		_ = pubsub.Close()
+
+		return fmt.Errorf("failed to subscribe to job statuses: %w", err)
+	}
+
+	go func() {
+		defer func() {
+			_ = pubsub.Close()
+		}()
+
+		messages := pubsub.Channel()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case message, ok := <-messages:
+				if !ok {
+					return
+				}
+
+				var status Status
+				if err := json.Unmarshal([]byte(message.Payload), &status); err != nil {
+					j.logger.Warn().Err(err).Msg("failed to decode job status")
+
+					continue
+				}
+
+				watch(&status)
+			}
+		}
+	}()
+
+	return nil
+}
+
+// saveStatus stores the status of the job in the state and publishes it.
+func (j *Jobs) saveStatus(ctx context.Context, job *Job, state State) error {
+	_, err := j.redis.TxPipelined(ctx, func(pipe goredis.Pipeliner) error {
+		return j.setStatus(ctx, pipe, job, state)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to save status of job %s: %w", job.ID, err)
+	}
+
+	return nil
+}
+
+// setStatus adds storing and publishing the status of the job in the state to the pipeline.
+func (j *Jobs) setStatus(ctx context.Context, pipe goredis.Pipeliner, job *Job, state State) error {
+	encoded, err := json.Marshal(&Status{
+		ID:          job.ID,
+		Type:        job.Type,
+		UserID:      job.UserID,
+		State:       state,
+		Attempt:     job.Attempt,
+		MaxAttempts: job.MaxAttempts,
+		Percent:     job.percent,
+		Message:     job.message,
+		Result:      job.result,
+		Error:       job.Error,
+		UpdatedAt:   j.now().UTC(),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to encode status of job %s: %w", job.ID, err)
+	}
+
+	pipe.Set(ctx, j.key("status:"+job.ID), encoded, *j.config.StatusTTL)
+	pipe.Publish(ctx, j.key("status"), encoded)
+
+	return nil
+}
@@ -0,0 +1,202 @@
+package jobs
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStatus(t *testing.T) {
+	t.Parallel()
+
+	t.Run("store queued status of enqueued jobs", func(t *testing.T) {
+		t.Parallel()
+
+		jobs := setupTestJobs(t, nil, nil)
+
+		job, err := jobs.Enqueue(context.Background(), "export", testPayload{}, &EnqueueOptions{UserID: "user-1"})
+		require.NoError(t, err)
+
+		status, err := jobs.Status(context.Background(), job.ID)
+		require.NoError(t, err)
+		assert.Equal(t, job.ID, status.ID)
+		assert.Equal(t, "export", status.Type)
+		assert.Equal(t, "user-1", status.UserID)
+		assert.Equal(t, StateQueued, status.State)
+		assert.Zero(t, status.Attempt)
+
+		ttl, err := jobs.redis.TTL(context.Background(), jobs.key("status:"+job.ID)).Result()
+		require.NoError(t, err)
+		assert.Positive(t, ttl)
+	})
+
+	t.Run("store progress and result of succeeded jobs", func(t *testing.T) {
+		t.Parallel()
+
+		jobs := setupTestJobs(t, nil, nil)
+
+		jobs.Handle("export", func(ctx context.Context, job *Job) error {
+			require.NoError(t, job.Progress(ctx, 150, "exporting rows"))
+
+			status, err := jobs.Status(ctx, job.ID)
+			require.NoError(t, err)
+			assert.Equal(t, StateRunning, status.State)
+			assert.Equal(t, 100, status.Percent)
+			assert.Equal(t, "exporting rows", status.Message)
+			assert.Equal(t, 1, status.Attempt)
+
+			return job.SetResult(map[string]int{"rows": 42})
+		})
+
+		job, err := jobs.Enqueue(context.Background(), "export", testPayload{}, nil)
+		require.NoError(t, err)
+
+		processNext(t, jobs)
+
+		status, err := jobs.Status(context.Background(), job.ID)
+		require.NoError(t, err)
+		assert.Equal(t, StateSucceeded, status.State)
+		assert.Equal(t, 100, status.Percent)
+		assert.JSONEq(t, `{"rows":42}`, string(status.Result))
+	})
+
+	t.Run("store error of retrying and failed jobs", func(t *testing.T) {
+		t.Parallel()
+
+		jobs := setupTestJobs(t, nil, nil)
+		now := time.Now()
+		jobs.now = func() time.Time { return now }
+
+		jobs.Handle("export", func(ctx context.Context, job *Job) error {
+			require.NoError(t, job.Progress(ctx, -10, "starting"))
+
+			return errHandlerFailed
+		})
+
+		job, err := jobs.Enqueue(context.Background(), "export", testPayload{}, &EnqueueOptions{MaxAttempts: 2, Backoff: time.Minute})
+		require.NoError(t, err)
+
+		processNext(t, jobs)
+
+		status, err := jobs.Status(context.Background(), job.ID)
+		require.NoError(t, err)
+		assert.Equal(t, StateRetrying, status.State)
+		assert.Zero(t, status.Percent)
+		assert.Equal(t, errHandlerFailed.Error(), status.Error)
+
+		now = now.Add(time.Minute)
+		require.NoError(t, jobs.promote(context.Background()))
+
+		processNext(t, jobs)
+
+		status, err = jobs.Status(context.Background(), job.ID)
+		require.NoError(t, err)
+		assert.Equal(t, StateFailed, status.State)
+		assert.Equal(t, 2, status.Attempt)
+	})
+
+	t.Run("return error for unknown jobs", func(t *testing.T) {
+		t.Parallel()
+
+		jobs := setupTestJobs(t, nil, nil)
+
+		_, err := jobs.Status(context.Background(), "missing")
+		require.ErrorIs(t, err, ErrNotFound)
+	})
+
+	t.Run("return error for progress of jobs not being processed", func(t *testing.T) {
+		t.Parallel()
+
+		job := &Job{ID: "job-1"}
+
+		require.ErrorIs(t, job.Progress(context.Background(), 50, ""), ErrNotProcessing)
+	})
+}
+
+func TestCheckpoint(t *testing.T) {
+	t.Parallel()
+
+	t.Run("resume retried jobs from their checkpoint", func(t *testing.T) {
+		t.Parallel()
+
+		jobs := setupTestJobs(t, nil, nil)
+		now := time.Now()
+		jobs.now = func() time.Time { return now }
+
+		var offsets []int
+
+		jobs.Handle("export", func(_ context.Context, job *Job) error {
+			checkpoint := struct{ Offset int }{}
+
+			if _, err := job.Resume(&checkpoint); err != nil {
+				return err
+			}
+
+			offsets = append(offsets, checkpoint.Offset)
+
+			if err := job.SetCheckpoint(struct{ Offset int }{Offset: checkpoint.Offset + 100}); err != nil {
+				return err
+			}
+
+			return errHandlerFailed
+		})
+
+		options := &EnqueueOptions{MaxAttempts: 3, Backoff: time.Minute}
+
+		_, err := jobs.Enqueue(context.Background(), "export", testPayload{}, options)
+		require.NoError(t, err)
+
+		for range 2 {
+			processNext(t, jobs)
+
+			now = now.Add(time.Hour)
+			require.NoError(t, jobs.promote(context.Background()))
+		}
+
+		assert.Equal(t, []int{0, 100}, offsets)
+	})
+
+	t.Run("not resume jobs without checkpoint", func(t *testing.T) {
+		t.Parallel()
+
+		var checkpoint map[string]int
+
+		ok, err := (&Job{}).Resume(&checkpoint)
+		require.NoError(t, err)
+		assert.False(t, ok)
+
+		ok, err = (&Job{ID: "job-1", Checkpoint: []byte(`[`)}).Resume(&checkpoint)
+		require.Error(t, err)
+		assert.False(t, ok)
+	})
+}
+
+func TestWatch(t *testing.T) {
+	t.Parallel()
+
+	jobs := setupTestJobs(t, nil, nil)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	statuses := make(chan *Status, 1)
+
+	require.NoError(t, jobs.Watch(ctx, func(status *Status) {
+		statuses <- status
+	}))
+
+	job, err := jobs.Enqueue(context.Background(), "export", testPayload{}, &EnqueueOptions{UserID: "user-1"})
+	require.NoError(t, err)
+
+	select {
+	case status := <-statuses:
+		assert.Equal(t, job.ID, status.ID)
+		assert.Equal(t, "user-1", status.UserID)
+		assert.Equal(t, StateQueued, status.State)
+	case <-time.After(time.Second):
+		t.Fatal("status was not received")
+	}
+}
@@ -0,0 +1,456 @@
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	goredis "github.com/redis/go-redis/v9"
+)
+
+const (
+	// promoteBatchSize is number of due delayed jobs moved to the ready stream per script call.
+	promoteBatchSize = 100
+
+	// maxRetryDelay bounds delays of retries, so that doubling the backoff does not overflow.
+	maxRetryDelay = 24 * time.Hour
+
+	// settleTimeout is time acknowledging, retrying or dead-lettering a job may take.
+	settleTimeout = 5 * time.Second
+)
+
+// errPanic is recorded on jobs whose handler panicked.
+var errPanic = errors.New("job handler panicked")
+
+// promoteScript moves delayed jobs due by ARGV[1] from the delayed set KEYS[1] to the ready stream KEYS[2],
+// returning number of moved jobs. Moving jobs in a script keeps them from being lost or duplicated
+// when instances promote concurrently.
+var promoteScript = goredis.NewScript(`
+local jobs = redis.call("ZRANGEBYSCORE", KEYS[1], "-inf", ARGV[1], "LIMIT", 0, ARGV[2])
+for _, job in ipairs(jobs) do
+	redis.call("XADD", KEYS[2], "*", ARGV[3], job)
+	redis.call("ZREM", KEYS[1], job)
+end
+return #jobs
+`)
+
+// Start starts workers processing jobs, it does nothing if workers are disabled.
+func (j *Jobs) Start() {
+	j.runMu.Lock()
+	defer j.runMu.Unlock()
+
+	if !j.Enabled() || j.cancel != nil {
+		return
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	jobCtx, abort := context.WithCancel(context.Background())
+	j.cancel, j.abort, j.done = cancel, abort, make(chan struct{})
+
+	slots := make(chan struct{}, *j.config.Concurrency)
+	inFlight := &sync.WaitGroup{}
+
+	var loops sync.WaitGroup
+
+	loops.Go(func() { j.fetchLoop(ctx, jobCtx, slots, inFlight) })
+	loops.Go(func() { j.maintenanceLoop(ctx, jobCtx, slots, inFlight) })
+
+	done := j.done
+
+	go func() {
+		loops.Wait()
+		inFlight.Wait()
+		close(done)
+	}()
+}
+
+// Stop stops fetching jobs and waits for jobs being processed until the context is done, then cancels them.
+// Handlers of canceled jobs may save a checkpoint before returning, the jobs are re-enqueued with it without
+// counting the attempt, so that other instances resume them at once instead of after the reclaim time.
+func (j *Jobs) Stop(ctx context.Context) {
+	j.runMu.Lock()
+	defer j.runMu.Unlock()
+
+	if j.cancel == nil {
+		return
+	}
+
+	j.cancel()
+
+	select {
+	case <-j.done:
+	case <-ctx.Done():
+		j.logger.Warn().Msg("canceling jobs not finished before shutdown")
+		j.abort()
+		<-j.done
+	}
+
+	j.abort()
+	j.cancel = nil
+}
+
+// fetchLoop reads jobs from the ready stream and processes them while slots are free.
+func (j *Jobs) fetchLoop(ctx, jobCtx context.Context, slots chan struct{}, inFlight *sync.WaitGroup) {
+	for {
+		if !j.acquire(ctx, slots) {
+			return
+		}
+
+		if j.paused(ctx) {
+			<-slots
+
+			if !j.sleep(ctx) {
+				return
+			}
+
+			continue
+		}
+
+		messages, err := j.read(ctx)
+		if err != nil {
+			<-slots
+
+			if ctx.Err() != nil {
+				return
+			}
+
+			j.logger.Error().Err(err).Msg("failed to read jobs")
+
+			if !j.sleep(ctx) {
+				return
+			}
+
+			continue
+		}
+
+		if len(messages) == 0 {
+			<-slots
+
+			continue
+		}
+
+		j.dispatch(jobCtx, messages[0], slots, inFlight)
+	}
+}
+
+// maintenanceLoop promotes due delayed jobs and reclaims jobs of stopped workers every poll interval.
+func (j *Jobs) maintenanceLoop(ctx, jobCtx context.Context, slots chan struct{}, inFlight *sync.WaitGroup) {
+	ticker := time.NewTicker(*j.config.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		if j.paused(ctx) {
+			continue
+		}
+
+		if err := j.promote(ctx); err != nil && ctx.Err() == nil {
+			j.logger.Error().Err(err).Msg("failed to promote delayed jobs")
+		}
+
+		messages, err := j.reclaim(ctx)
+		if err != nil && ctx.Err() == nil {
+			j.logger.Error().Err(err).Msg("failed to reclaim jobs")
+		}
+
+		for i, message := range messages {
+			if !j.acquire(ctx, slots) {
+				// claimed jobs not processed are handed back instead of waiting to be reclaimed again
+				for _, message := range messages[i:] {
+					j.settle(ctx, message.ID, func(pipe goredis.Pipeliner) error {
+						return pipe.XAdd(ctx, &goredis.XAddArgs{Stream: j.key("ready"), Values: message.Values}).Err()
+					})
+				}
+
+				return
+			}
+
+			j.dispatch(jobCtx, message, slots, inFlight)
+		}
+	}
+}
+
+// acquire waits for a free slot, returns false if the context is done first.
+func (j *Jobs) acquire(ctx context.Context, slots chan struct{}) bool {
+	select {
+	case slots <- struct{}{}:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// dispatch processes the message in a goroutine, releasing its slot when done.
+func (j *Jobs) dispatch(ctx context.Context, message goredis.XMessage, slots chan struct{}, inFlight *sync.WaitGroup) {
+	inFlight.Go(func() {
+		defer func() { <-slots }()
+
+		j.process(ctx, message)
+	})
+}
+
+// paused returns whether workers are paused by read-only mode.
+func (j *Jobs) paused(ctx context.Context) bool {
+	return j.readOnly != nil && j.readOnly.Enabled(ctx)
+}
+
+// sleep waits for the poll interval, returns false if the context is done first.
+func (j *Jobs) sleep(ctx context.Context) bool {
+	timer := time.NewTimer(*j.config.PollInterval)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// read reads a job from the ready stream, blocking up to the poll interval. The consumer group is created if
+// it does not exist yet.
+func (j *Jobs) read(ctx context.Context) ([]goredis.XMessage, error) {
+	streams, err := j.redis.XReadGroup(ctx, &goredis.XReadGroupArgs{
+		Group:    group,
+		Consumer: j.consumer,
+		Streams:  []string{j.key("ready"), ">"},
+		Count:    1,
+		Block:    *j.config.PollInterval,
+	}).Result()
+
+	switch {
+	case errors.Is(err, goredis.Nil):
+		return nil, nil
+	case err != nil && strings.HasPrefix(err.Error(), "NOGROUP"):
+		return nil, j.createGroup(ctx)
+	case err != nil:
+		return nil, fmt.Errorf("failed to read ready stream: %w", err)
+	}
+
+	if len(streams) == 0 {
+		return nil, nil
+	}
+
+	return streams[0].Messages, nil
+}
+
+// createGroup creates the consumer group of workers, reading the ready stream from its start.
+func (j *Jobs) createGroup(ctx context.Context) error {
+	err := j.redis.XGroupCreateMkStream(ctx, j.key("ready"), group, "0").Err()
+	if err != nil && !strings.HasPrefix(err.Error(), "BUSYGROUP") {
+		return fmt.Errorf("failed to create consumer group: %w", err)
+	}
+
+	return nil
+}
+
+// promote moves due delayed jobs to the ready stream.
+func (j *Jobs) promote(ctx context.Context) error {
+	keys := []string{j.key("delayed"), j.key("ready")}
+
+	for {
+		moved, err := promoteScript.Run(ctx, j.redis, keys, j.now().UnixMilli(), promoteBatchSize, jobField).Int()
+		if err != nil {
+			return fmt.Errorf("failed to run promote script: %w", err)
+		}
+
+		if moved < promoteBatchSize {
+			return nil
+		}
+	}
+}
+
+// reclaim claims jobs pending for longer than the reclaim time from workers that stopped without finishing them.
+func (j *Jobs) reclaim(ctx context.Context) ([]goredis.XMessage, error) {
+	messages, _, err := j.redis.XAutoClaim(ctx, &goredis.XAutoClaimArgs{
+		Stream:   j.key("ready"),
+		Group:    group,
+		Consumer: j.consumer,
+		MinIdle:  *j.config.ReclaimAfter,
+		Start:    "0-0",
+		Count:    int64(*j.config.Concurrency),
+	}).Result()
+
+	switch {
+	case err != nil && strings.HasPrefix(err.Error(), "NOGROUP"):
+		return nil, j.createGroup(ctx)
+	case err != nil:
+		return nil, fmt.Errorf("failed to claim pending jobs: %w", err)
+	}
+
+	return messages, nil
+}
+
+// process runs the handler of the job in the message and acknowledges, retries or dead-letters it.
+func (j *Jobs) process(ctx context.Context, message goredis.XMessage) {
+	encoded, _ := message.Values[jobField].(string)
+
+	job := &Job{}
+	if err := json.Unmarshal([]byte(encoded), job); err != nil {
+		j.logger.Error().Err(err).Str("entry_id", message.ID).Msg("dropping malformed job")
+		j.settle(ctx, message.ID, nil)
+
+		return
+	}
+
+	job.entryID = message.ID
+	job.Attempt++
+	job.jobs = j
+
+	if err := j.saveStatus(ctx, job, StateRunning); err != nil {
+		j.logger.Warn().Err(err).Str("job_id", job.ID).Msg("failed to save job status")
+	}
+
+	start := time.Now()
+	err := j.run(ctx, job)
+
+	// progress reported after the handler returned is not stored
+	job.jobs = nil
+
+	// jobs canceled by stopping workers are re-enqueued with their checkpoint
+	if err != nil && ctx.Err() != nil {
+		j.requeue(ctx, job)
+
+		return
+	}
+
+	j.metrics.duration.WithLabelValues(job.Type).Observe(time.Since(start).Seconds())
+
+	log := j.logger.With().Str("job_id", job.ID).Str("job_type", job.Type).Int("attempt", job.Attempt).Logger()
+
+	switch {
+	case err == nil:
+		job.percent = 100
+
+		j.metrics.processedTotal.WithLabelValues(job.Type, resultSuccess).Inc()
+		j.settle(ctx, job.entryID, func(pipe goredis.Pipeliner) error {
+			return j.setStatus(ctx, pipe, job, StateSucceeded)
+		})
+	case job.Attempt < job.MaxAttempts && !errors.Is(err, ErrNoHandler):
+		job.Error = err.Error()
+
+		j.metrics.processedTotal.WithLabelValues(job.Type, resultRetry).Inc()
+		log.Warn().Err(err).Msg("job failed, retrying")
+		j.settle(ctx, job.entryID, func(pipe goredis.Pipeliner) error {
+			if err := j.schedule(ctx, pipe, job, retryDelay(job)); err != nil {
+				return err
+			}
+
+			return j.setStatus(ctx, pipe, job, StateRetrying)
+		})
+	default:
+		job.Error = err.Error()
+
+		j.metrics.processedTotal.WithLabelValues(job.Type, resultDead).Inc()
+		log.Error().Err(err).Msg("job failed, dead-lettering")
+		j.settle(ctx, job.entryID, func(pipe goredis.Pipeliner) error {
+			if err := j.deadLetter(ctx, pipe, job); err != nil {
+				return err
+			}
+
+			return j.setStatus(ctx, pipe, job, StateFailed)
+		})
+	}
+}
+
+// requeue adds the job canceled by stopping workers back to the ready stream, not counting its attempt.
+func (j *Jobs) requeue(ctx context.Context, job *Job) {
+	job.Attempt--
+
+	j.logger.Info().Str("job_id", job.ID).Str("job_type", job.Type).Bool("checkpoint", len(job.Checkpoint) > 0).
+		Msg("re-enqueuing job canceled by shutdown")
+	j.settle(ctx, job.entryID, func(pipe goredis.Pipeliner) error {
+		if err := j.schedule(ctx, pipe, job, 0); err != nil {
+			return err
+		}
+
+		return j.setStatus(ctx, pipe, job, StateQueued)
+	})
+}
+
+// run runs the handler of the job with the attempt timeout, recovering panics.
+func (j *Jobs) run(ctx context.Context, job *Job) (err error) {
+	handler := j.handler(job.Type)
+	if handler == nil {
+		return fmt.Errorf("%w: %s", ErrNoHandler, job.Type)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, *j.config.Timeout)
+	defer cancel()
+
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			err = fmt.Errorf("%w: %v", errPanic, recovered)
+		}
+	}()
+
+	return handler(ctx, job)
+}
+
+// settle acknowledges and deletes the stream entry, queuing commands of the function in the same transaction.
+// It is not canceled by stopping workers, so that finished jobs are not processed again.
+func (j *Jobs) settle(ctx context.Context, entryID string, fn func(pipe goredis.Pipeliner) error) {
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), settleTimeout)
+	defer cancel()
+
+	_, err := j.redis.TxPipelined(ctx, func(pipe goredis.Pipeliner) error {
+		if fn != nil {
+			if err := fn(pipe); err != nil {
+				return err
+			}
+		}
+
+		pipe.XAck(ctx, j.key("ready"), group, entryID)
+		pipe.XDel(ctx, j.key("ready"), entryID)
+
+		return nil
+	})
+	if err != nil {
+		// unacknowledged jobs are processed again once reclaimed
+		j.logger.Error().Err(err).Str("entry_id", entryID).Msg("failed to acknowledge job")
+	}
+}
+
+// deadLetter adds the job to the dead-letter stream, trimming old jobs.
+func (j *Jobs) deadLetter(ctx context.Context, cmd goredis.Cmdable, job *Job) error {
+	encoded, err := json.Marshal(job)
+	if err != nil {
+		return fmt.Errorf("failed to encode job %s: %w", job.ID, err)
+	}
+
+	err = cmd.XAdd(ctx, &goredis.XAddArgs{
+		Stream: j.key("dead"),
+		MaxLen: *j.config.DeadLetterMaxLen,
+		Approx: true,
+		Values: []any{jobField, encoded},
+	}).Err()
+	if err != nil {
+		return fmt.Errorf("failed to dead-letter job %s: %w", job.ID, err)
+	}
+
+	return nil
+}
+
+// retryDelay returns delay before the next attempt of the job, the backoff doubled for every retry.
+func retryDelay(job *Job) time.Duration {
+	delay := job.Backoff
+
+	for range job.Attempt - 1 {
+		delay *= 2
+
+		if delay >= maxRetryDelay {
+			return maxRetryDelay
+		}
+	}
+
+	return delay
+}
@@ -0,0 +1,390 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	goredis "github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/readonly"
+)
+
+var errHandlerFailed = errors.New("handler failed")
+
+// testWorkerConfig returns configuration of enabled workers polling every 20ms.
+func testWorkerConfig() *Config {
+	return &Config{
+		Enabled:      &[]bool{true}[0],
+		PollInterval: &[]time.Duration{20 * time.Millisecond}[0],
+		Backoff:      &[]time.Duration{time.Millisecond}[0],
+	}
+}
+
+// processNext reads the next ready job and processes it.
+func processNext(t *testing.T, jobs *Jobs) {
+	t.Helper()
+
+	ctx := context.Background()
+	require.NoError(t, jobs.createGroup(ctx))
+
+	messages, err := jobs.read(ctx)
+	require.NoError(t, err)
+	require.Len(t, messages, 1)
+
+	jobs.process(ctx, messages[0])
+}
+
+// pending returns number of jobs read but not acknowledged.
+func pending(t *testing.T, jobs *Jobs) int64 {
+	t.Helper()
+
+	summary, err := jobs.redis.XPending(context.Background(), jobs.key("ready"), group).Result()
+	require.NoError(t, err)
+
+	return summary.Count
+}
+
+func TestProcess(t *testing.T) {
+	t.Parallel()
+
+	t.Run("acknowledge and delete successful jobs", func(t *testing.T) {
+		t.Parallel()
+
+		jobs := setupTestJobs(t, nil, nil)
+
+		var received testPayload
+
+		jobs.Handle("send_email", func(_ context.Context, job *Job) error {
+			assert.Equal(t, 1, job.Attempt)
+
+			return job.Decode(&received)
+		})
+
+		_, err := jobs.Enqueue(context.Background(), "send_email", testPayload{Email: "user@example.com"}, nil)
+		require.NoError(t, err)
+
+		processNext(t, jobs)
+
+		assert.Equal(t, "user@example.com", received.Email)
+		assert.Empty(t, streamJobs(t, jobs, "ready"))
+		assert.Zero(t, pending(t, jobs))
+	})
+
+	t.Run("retry failed jobs with backoff", func(t *testing.T) {
+		t.Parallel()
+
+		jobs := setupTestJobs(t, nil, nil)
+		now := time.Now()
+		jobs.now = func() time.Time { return now }
+
+		jobs.Handle("send_email", func(context.Context, *Job) error { return errHandlerFailed })
+
+		_, err := jobs.Enqueue(context.Background(), "send_email", testPayload{}, &EnqueueOptions{Backoff: time.Minute})
+		require.NoError(t, err)
+
+		processNext(t, jobs)
+
+		assert.Empty(t, streamJobs(t, jobs, "ready"))
+		assert.Zero(t, pending(t, jobs))
+
+		delayed, err := jobs.redis.ZRangeWithScores(context.Background(), jobs.key("delayed"), 0, -1).Result()
+		require.NoError(t, err)
+		require.Len(t, delayed, 1)
+		assert.InDelta(t, float64(now.Add(time.Minute).UnixMilli()), delayed[0].Score, 0)
+
+		// the retry is promoted once due and processed with the next attempt
+		now = now.Add(time.Minute)
+		require.NoError(t, jobs.promote(context.Background()))
+
+		ready := streamJobs(t, jobs, "ready")
+		require.Len(t, ready, 1)
+		assert.Equal(t, 1, ready[0].Attempt)
+		assert.Equal(t, errHandlerFailed.Error(), ready[0].Error)
+	})
+
+	t.Run("dead-letter jobs after last attempt", func(t *testing.T) {
+		t.Parallel()
+
+		jobs := setupTestJobs(t, nil, nil)
+
+		var attempts atomic.Int32
+
+		jobs.Handle("send_email", func(context.Context, *Job) error {
+			attempts.Add(1)
+
+			return errHandlerFailed
+		})
+
+		_, err := jobs.Enqueue(context.Background(), "send_email", testPayload{}, &EnqueueOptions{MaxAttempts: 2})
+		require.NoError(t, err)
+
+		processNext(t, jobs)
+
+		jobs.now = func() time.Time { return time.Now().Add(time.Hour) }
+		require.NoError(t, jobs.promote(context.Background()))
+		processNext(t, jobs)
+
+		assert.Equal(t, int32(2), attempts.Load())
+		assert.Empty(t, streamJobs(t, jobs, "ready"))
+
+		dead := streamJobs(t, jobs, "dead")
+		require.Len(t, dead, 1)
+		assert.Equal(t, 2, dead[0].Attempt)
+		assert.Equal(t, errHandlerFailed.Error(), dead[0].Error)
+	})
+
+	t.Run("dead-letter jobs without handler at once", func(t *testing.T) {
+		t.Parallel()
+
+		jobs := setupTestJobs(t, nil, nil)
+
+		_, err := jobs.Enqueue(context.Background(), "unknown", testPayload{}, nil)
+		require.NoError(t, err)
+
+		processNext(t, jobs)
+
+		dead := streamJobs(t, jobs, "dead")
+		require.Len(t, dead, 1)
+		assert.Contains(t, dead[0].Error, ErrNoHandler.Error())
+	})
+
+	t.Run("recover panicking handlers", func(t *testing.T) {
+		t.Parallel()
+
+		jobs := setupTestJobs(t, nil, nil)
+		jobs.Handle("send_email", func(context.Context, *Job) error { panic("boom") })
+
+		_, err := jobs.Enqueue(context.Background(), "send_email", testPayload{}, &EnqueueOptions{MaxAttempts: 1})
+		require.NoError(t, err)
+
+		processNext(t, jobs)
+
+		dead := streamJobs(t, jobs, "dead")
+		require.Len(t, dead, 1)
+		assert.Contains(t, dead[0].Error, "boom")
+	})
+
+	t.Run("cancel attempts after timeout", func(t *testing.T) {
+		t.Parallel()
+
+		jobs := setupTestJobs(t, &Config{Timeout: &[]time.Duration{10 * time.Millisecond}[0]}, nil)
+		jobs.Handle("send_email", func(ctx context.Context, _ *Job) error {
+			<-ctx.Done()
+
+			return ctx.Err()
+		})
+
+		_, err := jobs.Enqueue(context.Background(), "send_email", testPayload{}, &EnqueueOptions{MaxAttempts: 1})
+		require.NoError(t, err)
+
+		processNext(t, jobs)
+
+		dead := streamJobs(t, jobs, "dead")
+		require.Len(t, dead, 1)
+		assert.Equal(t, context.DeadlineExceeded.Error(), dead[0].Error)
+	})
+
+	t.Run("drop malformed jobs", func(t *testing.T) {
+		t.Parallel()
+
+		jobs := setupTestJobs(t, nil, nil)
+
+		require.NoError(t, jobs.redis.XAdd(context.Background(), &goredis.XAddArgs{
+			Stream: jobs.key("ready"),
+			Values: []any{jobField, "{"},
+		}).Err())
+
+		processNext(t, jobs)
+
+		assert.Empty(t, streamJobs(t, jobs, "ready"))
+		assert.Empty(t, streamJobs(t, jobs, "dead"))
+	})
+}
+
+func TestReclaim(t *testing.T) {
+	t.Parallel()
+
+	jobs := setupTestJobs(t, &Config{
+		Timeout:      &[]time.Duration{10 * time.Millisecond}[0],
+		ReclaimAfter: &[]time.Duration{50 * time.Millisecond}[0],
+	}, nil)
+
+	_, err := jobs.Enqueue(context.Background(), "send_email", testPayload{}, nil)
+	require.NoError(t, err)
+
+	// a job read by a worker that stopped without acknowledging it
+	require.NoError(t, jobs.createGroup(context.Background()))
+
+	messages, err := jobs.read(context.Background())
+	require.NoError(t, err)
+	require.Len(t, messages, 1)
+
+	claimed, err := jobs.reclaim(context.Background())
+	require.NoError(t, err)
+	assert.Empty(t, claimed)
+
+	time.Sleep(100 * time.Millisecond)
+
+	claimed, err = jobs.reclaim(context.Background())
+	require.NoError(t, err)
+	require.Len(t, claimed, 1)
+	assert.Equal(t, messages[0].ID, claimed[0].ID)
+}
+
+func TestStartStop(t *testing.T) {
+	t.Parallel()
+
+	t.Run("process ready and delayed jobs until stopped", func(t *testing.T) {
+		t.Parallel()
+
+		jobs := setupTestJobs(t, testWorkerConfig(), nil)
+
+		var processed atomic.Int32
+
+		jobs.Handle("send_email", func(context.Context, *Job) error {
+			processed.Add(1)
+
+			return nil
+		})
+
+		ctx := context.Background()
+
+		_, err := jobs.Enqueue(ctx, "send_email", testPayload{}, nil)
+		require.NoError(t, err)
+
+		_, err = jobs.Enqueue(ctx, "send_email", testPayload{}, &EnqueueOptions{Delay: 50 * time.Millisecond})
+		require.NoError(t, err)
+
+		jobs.Start()
+		jobs.Start()
+
+		require.Eventually(t, func() bool { return processed.Load() == 2 }, 2*time.Second, 10*time.Millisecond)
+
+		jobs.Stop(ctx)
+		jobs.Stop(ctx)
+
+		_, err = jobs.Enqueue(ctx, "send_email", testPayload{}, nil)
+		require.NoError(t, err)
+
+		time.Sleep(100 * time.Millisecond)
+		assert.Equal(t, int32(2), processed.Load())
+	})
+
+	t.Run("re-enqueue jobs not finished before stop deadline with their checkpoint", func(t *testing.T) {
+		t.Parallel()
+
+		jobs := setupTestJobs(t, testWorkerConfig(), nil)
+		started := make(chan struct{})
+
+		jobs.Handle("send_email", func(ctx context.Context, job *Job) error {
+			close(started)
+			<-ctx.Done()
+
+			if err := job.SetCheckpoint(map[string]int{"sent": 3}); err != nil {
+				return err
+			}
+
+			return ctx.Err()
+		})
+
+		job, err := jobs.Enqueue(context.Background(), "send_email", testPayload{}, nil)
+		require.NoError(t, err)
+
+		jobs.Start()
+		<-started
+
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+		defer cancel()
+
+		jobs.Stop(ctx)
+
+		assert.Zero(t, pending(t, jobs))
+		assert.Empty(t, streamJobs(t, jobs, "dead"))
+
+		ready := streamJobs(t, jobs, "ready")
+		require.Len(t, ready, 1)
+		assert.Equal(t, job.ID, ready[0].ID)
+		assert.Zero(t, ready[0].Attempt)
+		assert.JSONEq(t, `{"sent":3}`, string(ready[0].Checkpoint))
+
+		status, err := jobs.Status(context.Background(), job.ID)
+		require.NoError(t, err)
+		assert.Equal(t, StateQueued, status.State)
+
+		// the next worker resumes the job from its checkpoint
+		var resumed atomic.Int32
+
+		jobs.Handle("send_email", func(_ context.Context, job *Job) error {
+			var checkpoint map[string]int
+
+			ok, err := job.Resume(&checkpoint)
+			if err != nil || !ok {
+				return errHandlerFailed
+			}
+
+			resumed.Store(int32(checkpoint["sent"]))
+
+			return nil
+		})
+
+		jobs.Start()
+		defer jobs.Stop(context.Background())
+
+		require.Eventually(t, func() bool { return resumed.Load() == 3 }, 2*time.Second, 10*time.Millisecond)
+	})
+
+	t.Run("not process jobs in read-only mode", func(t *testing.T) {
+		t.Parallel()
+
+		readOnly := readonly.New(&readonly.Config{Enabled: &[]bool{true}[0]}, nil)
+		jobs := setupTestJobs(t, testWorkerConfig(), readOnly)
+
+		var processed atomic.Int32
+
+		jobs.Handle("send_email", func(context.Context, *Job) error {
+			processed.Add(1)
+
+			return nil
+		})
+
+		_, err := jobs.Enqueue(context.Background(), "send_email", testPayload{}, nil)
+		require.NoError(t, err)
+
+		jobs.Start()
+		time.Sleep(100 * time.Millisecond)
+		jobs.Stop(context.Background())
+
+		assert.Zero(t, processed.Load())
+		assert.Len(t, streamJobs(t, jobs, "ready"), 1)
+	})
+
+	t.Run("not start disabled workers", func(t *testing.T) {
+		t.Parallel()
+
+		jobs := setupTestJobs(t, nil, nil)
+
+		jobs.Start()
+		assert.Nil(t, jobs.cancel)
+
+		jobs.Stop(context.Background())
+	})
+}
+
+func TestRetryDelay(t *testing.T) {
+	t.Parallel()
+
+	job := &Job{Backoff: time.Second}
+
+	for attempt, expected := range []time.Duration{time.Second, 2 * time.Second, 4 * time.Second} {
+		job.Attempt = attempt + 1
+		assert.Equal(t, expected, retryDelay(job))
+	}
+
+	job.Attempt = 100
+	assert.Equal(t, maxRetryDelay, retryDelay(job))
+}
@@ -4,6 +4,7 @@ package jwt
 import (
 	"errors"
 	"fmt"
+	"slices"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
@@ -28,6 +29,12 @@ var (
 type JWT struct {
 	// config provides JWT configuration.
 	config *Config
+
+	// metrics provides collectors of token operations.
+	metrics *metrics
+
+	// refreshTracker counts refreshes per user to detect anomalies.
+	refreshTracker *refreshTracker
 }
 
 // Config represents configuration for JWT.
@@ -46,6 +53,13 @@ type Config struct {
 
 	// RefreshTokenTTL is refresh token TTL of JWT.
 	RefreshTokenTTL *time.Duration `json:"refresh_token_ttl"`
+
+	// RefreshAlertThreshold is number of refreshes of a user within RefreshAlertWindow above which
+	// refresh anomaly hooks are called, 0 disables the alert.
+	RefreshAlertThreshold *int `json:"refresh_alert_threshold"`
+
+	// RefreshAlertWindow is duration refreshes of a user are counted in.
+	RefreshAlertWindow *time.Duration `json:"refresh_alert_window"`
 }
 
 const (
@@ -63,6 +77,9 @@ const (
 
 	// defaultRefreshTokenTTL is default refresh token TTL of JWT.
 	defaultRefreshTokenTTL = 24 * time.Hour
+
+	// defaultRefreshAlertWindow is default refresh alert window of JWT.
+	defaultRefreshAlertWindow = 1 * time.Hour
 )
 
 // SetDefault sets default values.
@@ -91,6 +108,15 @@ func (c *Config) SetDefault() {
 		refreshTokenTTL := defaultRefreshTokenTTL
 		c.RefreshTokenTTL = &refreshTokenTTL
 	}
+
+	if c.RefreshAlertThreshold == nil {
+		c.RefreshAlertThreshold = &[]int{0}[0]
+	}
+
+	if c.RefreshAlertWindow == nil {
+		refreshAlertWindow := defaultRefreshAlertWindow
+		c.RefreshAlertWindow = &refreshAlertWindow
+	}
 }
 
 // Claims represents JWT claims.
@@ -104,6 +130,12 @@ type Claims struct {
 	// Role is role of JWT.
 	Role string `json:"role"`
 
+	// TenantID is tenant of JWT, empty if the user belongs to no tenant.
+	TenantID string `json:"tenant_id,omitempty"`
+
+	// Scopes is scopes granted to JWT.
+	Scopes []string `json:"scopes,omitempty"`
+
 	// RegisteredClaims provides registered claims of JWT.
 	jwt.RegisteredClaims
 }
@@ -124,29 +156,59 @@ func New(config *Config) (*JWT, error) {
 	config.SetDefault()
 
 	return &JWT{
-		config: config,
+		config:         config,
+		metrics:        newMetrics(),
+		refreshTracker: newRefreshTracker(*config.RefreshAlertThreshold, *config.RefreshAlertWindow),
 	}, nil
 }
 
-// GenerateAccessToken generates an access token.
-func (j *JWT) GenerateAccessToken(userID, email, role string) (*string, error) {
-	return j.generateToken(userID, email, role, *j.config.AccessTokenTTL)
+// GenerateAccessToken generates an access token with the granted scopes.
+func (j *JWT) GenerateAccessToken(userID, email, role string, scopes ...string) (*string, error) {
+	return j.generateToken(userID, email, role, "", scopes, *j.config.AccessTokenTTL, tokenTypeAccess)
+}
+
+// GenerateRefreshToken generates a refresh token with the granted scopes.
+func (j *JWT) GenerateRefreshToken(userID, email, role string, scopes ...string) (*string, error) {
+	return j.generateToken(userID, email, role, "", scopes, *j.config.RefreshTokenTTL, tokenTypeRefresh)
+}
+
+// GenerateTenantAccessToken generates an access token in the tenant the user was authenticated for, e.g. by the
+// identity provider of the tenant.
+func (j *JWT) GenerateTenantAccessToken(userID, email, role, tenantID string, scopes ...string) (*string, error) {
+	return j.generateToken(userID, email, role, tenantID, scopes, *j.config.AccessTokenTTL, tokenTypeAccess)
+}
+
+// GenerateTenantRefreshToken generates a refresh token in the tenant the user was authenticated for, access tokens
+// refreshed by it stay in the tenant.
+func (j *JWT) GenerateTenantRefreshToken(userID, email, role, tenantID string, scopes ...string) (*string, error) {
+	return j.generateToken(userID, email, role, tenantID, scopes, *j.config.RefreshTokenTTL, tokenTypeRefresh)
 }
 
-// GenerateRefreshToken generates a refresh token.
-func (j *JWT) GenerateRefreshToken(userID, email, role string) (*string, error) {
-	return j.generateToken(userID, email, role, *j.config.RefreshTokenTTL)
+// AccessTokenTTL returns TTL of access tokens.
+func (j *JWT) AccessTokenTTL() time.Duration {
+	return *j.config.AccessTokenTTL
 }
 
 // generateToken generates a JWT token.
-func (j *JWT) generateToken(userID, email, role string, ttl time.Duration) (*string, error) {
+func (j *JWT) generateToken(
+	userID, email, role, tenantID string,
+	scopes []string,
+	ttl time.Duration,
+	tokenType string,
+) (*string, error) {
 	now := time.Now()
 
+	defer func() {
+		j.metrics.duration.WithLabelValues("issue_" + tokenType).Observe(time.Since(now).Seconds())
+	}()
+
 	// set claims
 	claims := &Claims{
-		UserID: userID,
-		Email:  email,
-		Role:   role,
+		UserID:   userID,
+		Email:    email,
+		Role:     role,
+		TenantID: tenantID,
+		Scopes:   scopes,
 		RegisteredClaims: jwt.RegisteredClaims{
 			Issuer:    *j.config.Issuer,
 			Subject:   userID,
@@ -166,6 +228,8 @@ func (j *JWT) generateToken(userID, email, role string, ttl time.Duration) (*str
 		return nil, fmt.Errorf("failed to sign token: %w", err)
 	}
 
+	j.metrics.issuedTotal.WithLabelValues(tokenType).Inc()
+
 	return &signedTokenStr, nil
 }
 
@@ -203,13 +267,45 @@ func (j *JWT) ValidateToken(tokenStr string) (*Claims, error) {
 
 // RefreshAccessToken refreshes an access token using a refresh token.
 func (j *JWT) RefreshAccessToken(refreshToken string) (*string, error) {
+	start := time.Now()
+
+	defer func() {
+		j.metrics.duration.WithLabelValues("refresh").Observe(time.Since(start).Seconds())
+	}()
+
 	// validate refresh token
 	claims, err := j.ValidateToken(refreshToken)
 	if err != nil {
+		j.metrics.refreshesTotal.WithLabelValues(resultFailure).Inc()
+
 		return nil, fmt.Errorf("invalid refresh token: %w", err)
 	}
 
-	return j.GenerateAccessToken(claims.UserID, claims.Email, claims.Role)
+	// the tenant is kept, so refreshed tokens stay in the tenant of the refresh token
+	accessToken, err := j.generateToken(
+		claims.UserID, claims.Email, claims.Role, claims.TenantID, claims.Scopes, *j.config.AccessTokenTTL, tokenTypeAccess,
+	)
+	if err != nil {
+		j.metrics.refreshesTotal.WithLabelValues(resultFailure).Inc()
+
+		return nil, err
+	}
+
+	j.metrics.refreshesTotal.WithLabelValues(resultSuccess).Inc()
+	j.observeRefresh(claims.UserID)
+
+	return accessToken, nil
+}
+
+// HasScopes returns whether the claims are granted all of the scopes.
+func (c *Claims) HasScopes(scopes ...string) bool {
+	for _, scope := range scopes {
+		if !slices.Contains(c.Scopes, scope) {
+			return false
+		}
+	}
+
+	return true
 }
 
 // ExtractClaims extracts claims from a token without validation.
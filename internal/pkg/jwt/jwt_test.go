@@ -68,6 +68,10 @@ func TestConfig(t *testing.T) {
 		require.Equal(t, defaultAccessTokenTTL, *config.AccessTokenTTL)
 		require.NotNil(t, config.RefreshTokenTTL)
 		require.Equal(t, defaultRefreshTokenTTL, *config.RefreshTokenTTL)
+		require.NotNil(t, config.RefreshAlertThreshold)
+		require.Equal(t, 0, *config.RefreshAlertThreshold)
+		require.NotNil(t, config.RefreshAlertWindow)
+		require.Equal(t, defaultRefreshAlertWindow, *config.RefreshAlertWindow)
 	})
 
 	t.Run("preserve existing values on jwt config", func(t *testing.T) {
@@ -155,6 +159,39 @@ func TestGenerateAccessToken(t *testing.T) {
 		require.NotNil(t, token)
 		require.NotEmpty(t, *token)
 	})
+
+	t.Run("generate access token in tenant", func(t *testing.T) {
+		t.Parallel()
+
+		jwt := createTestJWT(t)
+
+		token, err := jwt.GenerateTenantAccessToken("user123", "test@example.com", "user", "acme", "settings:read")
+		require.NoError(t, err)
+
+		claims, err := jwt.ValidateToken(*token)
+		require.NoError(t, err)
+		assert.Equal(t, "acme", claims.TenantID)
+		assert.Equal(t, []string{"settings:read"}, claims.Scopes)
+	})
+
+	t.Run("generate access token without tenant", func(t *testing.T) {
+		t.Parallel()
+
+		jwt := createTestJWT(t)
+
+		token, err := jwt.GenerateAccessToken("user123", "test@example.com", "user")
+		require.NoError(t, err)
+
+		claims, err := jwt.ValidateToken(*token)
+		require.NoError(t, err)
+		assert.Empty(t, claims.TenantID)
+	})
+
+	t.Run("return access token TTL", func(t *testing.T) {
+		t.Parallel()
+
+		assert.Equal(t, testAccessTokenTTL, createTestJWT(t).AccessTokenTTL())
+	})
 }
 
 func TestGenerateRefreshToken(t *testing.T) {
@@ -304,6 +341,38 @@ func TestRefreshAccessToken(t *testing.T) {
 		require.Equal(t, "admin", claims.Role)
 	})
 
+	t.Run("keep scopes of refresh token", func(t *testing.T) {
+		t.Parallel()
+
+		jwt := createTestJWT(t)
+
+		refreshToken, err := jwt.GenerateRefreshToken("user123", "test@example.com", "user", "settings:read")
+		require.NoError(t, err)
+
+		newAccessToken, err := jwt.RefreshAccessToken(*refreshToken)
+		require.NoError(t, err)
+
+		claims, err := jwt.ValidateToken(*newAccessToken)
+		require.NoError(t, err)
+		require.Equal(t, []string{"settings:read"}, claims.Scopes)
+	})
+
+	t.Run("keep tenant of refresh token", func(t *testing.T) {
+		t.Parallel()
+
+		jwt := createTestJWT(t)
+
+		refreshToken, err := jwt.GenerateTenantRefreshToken("user123", "test@example.com", "user", "acme")
+		require.NoError(t, err)
+
+		newAccessToken, err := jwt.RefreshAccessToken(*refreshToken)
+		require.NoError(t, err)
+
+		claims, err := jwt.ValidateToken(*newAccessToken)
+		require.NoError(t, err)
+		require.Equal(t, "acme", claims.TenantID)
+	})
+
 	t.Run("reject invalid refresh token", func(t *testing.T) {
 		t.Parallel()
 
@@ -410,6 +479,43 @@ func TestClaimsCustomFields(t *testing.T) {
 	})
 }
 
+func TestClaimsHasScopes(t *testing.T) {
+	t.Parallel()
+
+	t.Run("check granted scopes of token", func(t *testing.T) {
+		t.Parallel()
+
+		jwt := createTestJWT(t)
+
+		token, err := jwt.GenerateAccessToken("user123", "test@example.com", "user", "settings:read", "settings:write")
+		require.NoError(t, err)
+
+		claims, err := jwt.ValidateToken(*token)
+		require.NoError(t, err)
+
+		assert.Equal(t, []string{"settings:read", "settings:write"}, claims.Scopes)
+		assert.True(t, claims.HasScopes())
+		assert.True(t, claims.HasScopes("settings:read"))
+		assert.True(t, claims.HasScopes("settings:read", "settings:write"))
+		assert.False(t, claims.HasScopes("settings:read", "admin"))
+	})
+
+	t.Run("omit scopes of token without scopes", func(t *testing.T) {
+		t.Parallel()
+
+		jwt := createTestJWT(t)
+
+		token, err := jwt.GenerateAccessToken("user123", "test@example.com", "user")
+		require.NoError(t, err)
+
+		claims, err := jwt.ValidateToken(*token)
+		require.NoError(t, err)
+
+		assert.Empty(t, claims.Scopes)
+		assert.False(t, claims.HasScopes("settings:read"))
+	})
+}
+
 func TestNewModule(t *testing.T) {
 	t.Parallel()
 
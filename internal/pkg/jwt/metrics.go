@@ -0,0 +1,215 @@
+package jwt
+
+import (
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	// tokenTypeAccess is token type label of access tokens.
+	tokenTypeAccess = "access"
+
+	// tokenTypeRefresh is token type label of refresh tokens.
+	tokenTypeRefresh = "refresh"
+
+	// resultSuccess is result label of successful operations.
+	resultSuccess = "success"
+
+	// resultFailure is result label of failed operations.
+	resultFailure = "failure"
+)
+
+// RefreshAnomaly represents refresh volume of a user exceeding the alert threshold within the alert window.
+type RefreshAnomaly struct {
+	// UserID is user ID of the refreshed tokens.
+	UserID string
+
+	// Count is number of refreshes within the window.
+	Count int
+
+	// Window is duration refreshes are counted in.
+	Window time.Duration
+
+	// DetectedAt is time the threshold was exceeded.
+	DetectedAt time.Time
+}
+
+// AlertHook is called when a refresh anomaly is detected, it is called synchronously and should not block.
+type AlertHook func(anomaly RefreshAnomaly)
+
+// metrics holds prometheus collectors of token operations.
+type metrics struct {
+	// issuedTotal is number of issued tokens by type.
+	issuedTotal *prometheus.CounterVec
+
+	// refreshesTotal is number of refreshes by result.
+	refreshesTotal *prometheus.CounterVec
+
+	// duration is duration of token operations.
+	duration *prometheus.HistogramVec
+
+	// anomaliesTotal is number of detected refresh anomalies.
+	anomaliesTotal prometheus.Counter
+}
+
+// refreshWindow is refresh count of a user within a window.
+type refreshWindow struct {
+	// start is start time of the window.
+	start time.Time
+
+	// count is number of refreshes within the window.
+	count int
+}
+
+// refreshTracker counts refreshes per user to detect anomalies.
+type refreshTracker struct {
+	// mu guards the fields below.
+	mu sync.Mutex
+
+	// threshold is refresh count per window above which an anomaly is reported, 0 disables tracking.
+	threshold int
+
+	// window is duration refreshes are counted in.
+	window time.Duration
+
+	// windows is refresh windows by user ID.
+	windows map[string]*refreshWindow
+
+	// lastSweep is time expired windows were last removed.
+	lastSweep time.Time
+
+	// hooks are called when an anomaly is detected.
+	hooks []AlertHook
+}
+
+// newMetrics creates collectors of token operations.
+func newMetrics() *metrics {
+	return &metrics{
+		issuedTotal: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "jwt_tokens_issued_total",
+				Help: "Total number of issued JWT tokens",
+			},
+			[]string{"type"},
+		),
+		refreshesTotal: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "jwt_token_refreshes_total",
+				Help: "Total number of JWT access token refreshes",
+			},
+			[]string{"result"},
+		),
+		duration: prometheus.NewHistogramVec(
+			prometheus.HistogramOpts{
+				Name:    "jwt_token_operation_duration_seconds",
+				Help:    "Duration of JWT token operations in seconds",
+				Buckets: prometheus.ExponentialBuckets(0.00001, 4, 8),
+			},
+			[]string{"operation"},
+		),
+		anomaliesTotal: prometheus.NewCounter(
+			prometheus.CounterOpts{
+				Name: "jwt_refresh_anomalies_total",
+				Help: "Total number of users exceeding the refresh alert threshold",
+			},
+		),
+	}
+}
+
+// collectors returns all collectors of the metrics.
+func (m *metrics) collectors() []prometheus.Collector {
+	return []prometheus.Collector{m.issuedTotal, m.refreshesTotal, m.duration, m.anomaliesTotal}
+}
+
+// newRefreshTracker creates a refresh tracker.
+func newRefreshTracker(threshold int, window time.Duration) *refreshTracker {
+	return &refreshTracker{
+		threshold: threshold,
+		window:    window,
+		windows:   make(map[string]*refreshWindow),
+	}
+}
+
+// addHook adds a hook called when an anomaly is detected.
+func (r *refreshTracker) addHook(hook AlertHook) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.hooks = append(r.hooks, hook)
+}
+
+// track counts a refresh of the user, it returns the anomaly and hooks to call when the threshold is exceeded.
+// An anomaly is reported once per window when the count first exceeds the threshold.
+func (r *refreshTracker) track(userID string, now time.Time) (*RefreshAnomaly, []AlertHook) {
+	if r.threshold <= 0 {
+		return nil, nil
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	// remove expired windows so inactive users do not accumulate
+	if now.Sub(r.lastSweep) >= r.window {
+		for id, window := range r.windows {
+			if now.Sub(window.start) >= r.window {
+				delete(r.windows, id)
+			}
+		}
+
+		r.lastSweep = now
+	}
+
+	window, ok := r.windows[userID]
+	if !ok || now.Sub(window.start) >= r.window {
+		window = &refreshWindow{start: now}
+		r.windows[userID] = window
+	}
+
+	window.count++
+
+	if window.count != r.threshold+1 {
+		return nil, nil
+	}
+
+	return &RefreshAnomaly{
+		UserID:     userID,
+		Count:      window.count,
+		Window:     r.window,
+		DetectedAt: now,
+	}, r.hooks
+}
+
+// Describe implements prometheus.Collector.
+func (j *JWT) Describe(ch chan<- *prometheus.Desc) {
+	for _, collector := range j.metrics.collectors() {
+		collector.Describe(ch)
+	}
+}
+
+// Collect implements prometheus.Collector.
+func (j *JWT) Collect(ch chan<- prometheus.Metric) {
+	for _, collector := range j.metrics.collectors() {
+		collector.Collect(ch)
+	}
+}
+
+// OnRefreshAnomaly adds a hook called when refreshes of a user exceed the alert threshold (possible token theft).
+func (j *JWT) OnRefreshAnomaly(hook AlertHook) {
+	j.refreshTracker.addHook(hook)
+}
+
+// observeRefresh records a successful refresh of the user and calls hooks when it is an anomaly.
+func (j *JWT) observeRefresh(userID string) {
+	anomaly, hooks := j.refreshTracker.track(userID, time.Now())
+	if anomaly == nil {
+		return
+	}
+
+	j.metrics.anomaliesTotal.Inc()
+
+	for _, hook := range hooks {
+		hook(*anomaly)
+	}
+}
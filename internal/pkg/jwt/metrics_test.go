@@ -0,0 +1,157 @@
+package jwt
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// createAlertTestJWT creates a JWT instance alerting above the refresh threshold.
+func createAlertTestJWT(t *testing.T, threshold int) *JWT {
+	t.Helper()
+
+	jwt, err := New(&Config{
+		SecretKey:             &[]string{testSecretKey}[0],
+		RefreshAlertThreshold: &threshold,
+		RefreshAlertWindow:    &[]time.Duration{time.Hour}[0],
+	})
+	require.NoError(t, err)
+
+	return jwt
+}
+
+func TestMetrics(t *testing.T) {
+	t.Parallel()
+
+	t.Run("count issued tokens and refreshes", func(t *testing.T) {
+		t.Parallel()
+
+		jwt := createTestJWT(t)
+
+		refreshToken, err := jwt.GenerateRefreshToken("user123", "test@example.com", "user")
+		require.NoError(t, err)
+
+		_, err = jwt.RefreshAccessToken(*refreshToken)
+		require.NoError(t, err)
+
+		_, err = jwt.RefreshAccessToken("invalid_refresh_token")
+		require.Error(t, err)
+
+		assert.InDelta(t, 1, testutil.ToFloat64(jwt.metrics.issuedTotal.WithLabelValues(tokenTypeRefresh)), 0)
+		assert.InDelta(t, 1, testutil.ToFloat64(jwt.metrics.issuedTotal.WithLabelValues(tokenTypeAccess)), 0)
+		assert.InDelta(t, 1, testutil.ToFloat64(jwt.metrics.refreshesTotal.WithLabelValues(resultSuccess)), 0)
+		assert.InDelta(t, 1, testutil.ToFloat64(jwt.metrics.refreshesTotal.WithLabelValues(resultFailure)), 0)
+		assert.Equal(t, 3, testutil.CollectAndCount(jwt.metrics.duration))
+	})
+
+	t.Run("register on a prometheus registry", func(t *testing.T) {
+		t.Parallel()
+
+		jwt := createTestJWT(t)
+
+		_, err := jwt.GenerateAccessToken("user123", "test@example.com", "user")
+		require.NoError(t, err)
+
+		registry := prometheus.NewRegistry()
+		require.NoError(t, registry.Register(jwt))
+
+		families, err := registry.Gather()
+		require.NoError(t, err)
+
+		names := make([]string, 0, len(families))
+		for _, family := range families {
+			names = append(names, family.GetName())
+		}
+
+		assert.Contains(t, names, "jwt_tokens_issued_total")
+		assert.Contains(t, names, "jwt_token_operation_duration_seconds")
+	})
+}
+
+func TestRefreshAnomaly(t *testing.T) {
+	t.Parallel()
+
+	t.Run("alert once when refreshes exceed threshold", func(t *testing.T) {
+		t.Parallel()
+
+		jwt := createAlertTestJWT(t, 2)
+
+		var (
+			mu        sync.Mutex
+			anomalies []RefreshAnomaly
+		)
+
+		jwt.OnRefreshAnomaly(func(anomaly RefreshAnomaly) {
+			mu.Lock()
+			defer mu.Unlock()
+
+			anomalies = append(anomalies, anomaly)
+		})
+
+		refreshToken, err := jwt.GenerateRefreshToken("user123", "test@example.com", "user")
+		require.NoError(t, err)
+
+		for range 5 {
+			_, err = jwt.RefreshAccessToken(*refreshToken)
+			require.NoError(t, err)
+		}
+
+		require.Len(t, anomalies, 1)
+		assert.Equal(t, "user123", anomalies[0].UserID)
+		assert.Equal(t, 3, anomalies[0].Count)
+		assert.Equal(t, time.Hour, anomalies[0].Window)
+		assert.InDelta(t, 1, testutil.ToFloat64(jwt.metrics.anomaliesTotal), 0)
+	})
+
+	t.Run("count refreshes per user", func(t *testing.T) {
+		t.Parallel()
+
+		tracker := newRefreshTracker(1, time.Hour)
+		now := time.Now()
+
+		anomaly, _ := tracker.track("user1", now)
+		assert.Nil(t, anomaly)
+
+		anomaly, _ = tracker.track("user2", now)
+		assert.Nil(t, anomaly)
+
+		anomaly, _ = tracker.track("user1", now)
+		require.NotNil(t, anomaly)
+		assert.Equal(t, "user1", anomaly.UserID)
+	})
+
+	t.Run("reset count after window", func(t *testing.T) {
+		t.Parallel()
+
+		tracker := newRefreshTracker(1, time.Minute)
+		now := time.Now()
+
+		anomaly, _ := tracker.track("user1", now)
+		assert.Nil(t, anomaly)
+
+		anomaly, _ = tracker.track("user1", now.Add(2*time.Minute))
+		assert.Nil(t, anomaly)
+
+		// expired windows are removed
+		tracker.track("user2", now.Add(4*time.Minute))
+		assert.NotContains(t, tracker.windows, "user1")
+	})
+
+	t.Run("ignore refreshes when threshold is disabled", func(t *testing.T) {
+		t.Parallel()
+
+		tracker := newRefreshTracker(0, time.Minute)
+
+		for range 10 {
+			anomaly, _ := tracker.track("user1", time.Now())
+			assert.Nil(t, anomaly)
+		}
+
+		assert.Empty(t, tracker.windows)
+	})
+}
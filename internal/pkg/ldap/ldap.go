@@ -0,0 +1,601 @@
+// Package ldap provides an LDAP and Active Directory authentication backend, verifying passwords of users by
+// binding as their entries found with a service account, over pooled connections.
+package ldap
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	goldap "github.com/go-ldap/ldap/v3"
+	"go.uber.org/fx"
+
+	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/logger"
+	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/user"
+)
+
+const (
+	// usernamePlaceholder is the placeholder of the user filter replaced with the escaped username.
+	usernamePlaceholder = "{username}"
+
+	// defaultMaxConnections is default maximum number of connections to the server.
+	defaultMaxConnections = 4
+
+	// defaultTimeout is default timeout of connecting and operations.
+	defaultTimeout = 5 * time.Second
+
+	// identityProvider is provider of identities users are linked to by the subject of their entries.
+	identityProvider = "ldap"
+)
+
+var (
+	// ErrInvalidURL is returned when the URL of the server is not an ldap or ldaps URL.
+	ErrInvalidURL = errors.New("invalid ldap url")
+
+	// ErrMissingBaseDN is returned when no base DN is configured.
+	ErrMissingBaseDN = errors.New("missing ldap base dn")
+
+	// ErrMissingDomains is returned when no email domains are configured.
+	ErrMissingDomains = errors.New("missing ldap email domains")
+
+	// ErrInvalidFilter is returned when the user filter is not a valid RFC 4515 filter with the username placeholder.
+	ErrInvalidFilter = errors.New("invalid ldap filter")
+
+	// ErrInvalidCertificate is returned when a CA certificate is not a valid PEM encoded certificate.
+	ErrInvalidCertificate = errors.New("invalid ldap ca certificate")
+
+	// ErrInvalidCredentials is returned when the user is not found in the directory or its password is wrong.
+	ErrInvalidCredentials = errors.New("invalid ldap credentials")
+)
+
+// NewModule provides module for LDAP.
+func NewModule() fx.Option {
+	return fx.Module("ldap",
+		fx.Provide(New),
+	)
+}
+
+// Config represents configuration for LDAP.
+type Config struct {
+	// Enabled is whether logins are verified against the directory.
+	Enabled *bool `json:"enabled"`
+
+	// URL is URL of the server, ldaps://host:636 for TLS or ldap://host:389.
+	URL *string `json:"url"`
+
+	// StartTLS is whether connections of ldap URLs are upgraded to TLS with StartTLS, passwords are sent in
+	// the clear over ldap URLs without it.
+	StartTLS *bool `json:"start_tls"`
+
+	// CACertificates is PEM encoded certificates of CAs of the server certificate, system roots if empty.
+	CACertificates []string `json:"ca_certificates"`
+
+	// BindDN is DN of the service account searching users, searches are anonymous if empty.
+	BindDN *string `json:"bind_dn"`
+
+	// BindPassword is password of the service account.
+	BindPassword *string `json:"bind_password"`
+
+	// BaseDN is DN of the subtree users are searched in.
+	BaseDN *string `json:"base_dn"`
+
+	// UserFilter is filter of the entry of a user, with {username} replaced with the escaped username, e.g.
+	// (&(objectClass=user)(userPrincipalName={username})) for Active Directory.
+	UserFilter *string `json:"user_filter"`
+
+	// EmailAttribute is attribute of the email of users, their local accounts are provisioned by it on first login.
+	EmailAttribute *string `json:"email_attribute"`
+
+	// Domains is email domains of users the directory may log in, so that its entries can not log in local
+	// accounts of other domains.
+	Domains []string `json:"domains"`
+
+	// IDAttribute is attribute of immutable IDs of entries, e.g. entryUUID or objectGUID for Active Directory,
+	// users are linked to on first login and keep on later logins whatever their email, their DN if empty.
+	IDAttribute *string `json:"id_attribute"`
+
+	// GroupAttribute is attribute of DNs of groups users are members of, mapped to roles by Roles.
+	GroupAttribute *string `json:"group_attribute"`
+
+	// Roles is roles of users by DN of their groups, the first group mapped wins and users of no mapped group get
+	// the default role, roles are not synced if empty.
+	Roles map[string]string `json:"roles"`
+
+	// LocalPasswords is whether users of other email domains than Domains may log in with local passwords, users of
+	// Domains can neither sign up nor log in with one.
+	LocalPasswords *bool `json:"local_passwords"`
+
+	// MaxConnections is maximum number of connections to the server, idle ones are reused.
+	MaxConnections *int `json:"max_connections"`
+
+	// Timeout is timeout of connecting and of each operation.
+	Timeout *time.Duration `json:"timeout"`
+}
+
+// SetDefault sets default values.
+func (c *Config) SetDefault() {
+	if c.Enabled == nil {
+		c.Enabled = &[]bool{false}[0]
+	}
+
+	if c.URL == nil {
+		c.URL = &[]string{""}[0]
+	}
+
+	if c.StartTLS == nil {
+		c.StartTLS = &[]bool{false}[0]
+	}
+
+	if c.CACertificates == nil {
+		c.CACertificates = []string{}
+	}
+
+	if c.BindDN == nil {
+		c.BindDN = &[]string{""}[0]
+	}
+
+	if c.BindPassword == nil {
+		c.BindPassword = &[]string{""}[0]
+	}
+
+	if c.BaseDN == nil {
+		c.BaseDN = &[]string{""}[0]
+	}
+
+	if c.UserFilter == nil {
+		c.UserFilter = &[]string{"(mail={username})"}[0]
+	}
+
+	if c.EmailAttribute == nil {
+		c.EmailAttribute = &[]string{"mail"}[0]
+	}
+
+	if c.Domains == nil {
+		c.Domains = []string{}
+	}
+
+	if c.IDAttribute == nil {
+		c.IDAttribute = &[]string{""}[0]
+	}
+
+	if c.GroupAttribute == nil {
+		c.GroupAttribute = &[]string{"memberOf"}[0]
+	}
+
+	if c.Roles == nil {
+		c.Roles = map[string]string{}
+	}
+
+	if c.LocalPasswords == nil {
+		c.LocalPasswords = &[]bool{false}[0]
+	}
+
+	if c.MaxConnections == nil {
+		c.MaxConnections = &[]int{defaultMaxConnections}[0]
+	}
+
+	if c.Timeout == nil {
+		c.Timeout = &[]time.Duration{defaultTimeout}[0]
+	}
+}
+
+// Directory verifies logins against an LDAP server.
+type Directory struct {
+	// config provides LDAP configuration.
+	config *Config
+
+	// url is URL of the server, with the default port of its scheme.
+	url string
+
+	// tlsConfig is TLS configuration of connections, nil for plain connections.
+	tlsConfig *tls.Config
+
+	// roles is roles by lowercased group DN.
+	roles map[string]string
+
+	// slots limits the number of connections, holding one value per connection in use.
+	slots chan struct{}
+
+	// idle is connections bound as the service account waiting to be reused.
+	idle chan *goldap.Conn
+
+	// users provides users logged in.
+	users *user.Service
+
+	// logger provides logging.
+	logger *logger.Logger
+}
+
+// New creates a new directory, validating the config if it is enabled.
+func New(config *Config, users *user.Service, logger *logger.Logger) (*Directory, error) {
+	if config == nil {
+		config = &Config{}
+	}
+
+	config.SetDefault()
+
+	directory := &Directory{
+		config: config,
+		roles:  make(map[string]string, len(config.Roles)),
+		slots:  make(chan struct{}, max(*config.MaxConnections, 1)),
+		idle:   make(chan *goldap.Conn, max(*config.MaxConnections, 1)),
+		users:  users,
+		logger: logger.Named("ldap"),
+	}
+
+	for group, role := range config.Roles {
+		directory.roles[strings.ToLower(group)] = role
+	}
+
+	if !*config.Enabled {
+		return directory, nil
+	}
+
+	serverURL, err := url.Parse(*config.URL)
+	if err != nil || serverURL.Hostname() == "" || serverURL.Scheme != "ldap" && serverURL.Scheme != "ldaps" {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidURL, *config.URL)
+	}
+
+	if serverURL.Scheme == "ldaps" && *config.StartTLS {
+		return nil, fmt.Errorf("%w: start tls is used with ldap urls", ErrInvalidURL)
+	}
+
+	port := serverURL.Port()
+	if port == "" {
+		port = map[string]string{"ldap": "389", "ldaps": "636"}[serverURL.Scheme]
+	}
+
+	directory.url = serverURL.Scheme + "://" + net.JoinHostPort(serverURL.Hostname(), port)
+
+	if *config.BaseDN == "" {
+		return nil, ErrMissingBaseDN
+	}
+
+	if len(config.Domains) == 0 {
+		return nil, ErrMissingDomains
+	}
+
+	if _, err := goldap.CompileFilter(strings.ReplaceAll(*config.UserFilter, usernamePlaceholder, "user")); err != nil ||
+		!strings.Contains(*config.UserFilter, usernamePlaceholder) {
+		return nil, fmt.Errorf("%w: user filter must contain %s", ErrInvalidFilter, usernamePlaceholder)
+	}
+
+	if serverURL.Scheme == "ldaps" || *config.StartTLS {
+		directory.tlsConfig, err = newTLSConfig(serverURL.Hostname(), config.CACertificates)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		directory.logger.Warn().Str("url", *config.URL).Msg("passwords are sent to the directory in the clear")
+	}
+
+	return directory, nil
+}
+
+// newTLSConfig creates TLS configuration verifying the server name with the CA certificates, system roots if
+// none are given.
+func newTLSConfig(serverName string, caCertificates []string) (*tls.Config, error) {
+	tlsConfig := &tls.Config{ServerName: serverName, MinVersion: tls.VersionTLS12}
+
+	if len(caCertificates) == 0 {
+		return tlsConfig, nil
+	}
+
+	tlsConfig.RootCAs = x509.NewCertPool()
+
+	for _, certificate := range caCertificates {
+		if !tlsConfig.RootCAs.AppendCertsFromPEM([]byte(certificate)) {
+			return nil, ErrInvalidCertificate
+		}
+	}
+
+	return tlsConfig, nil
+}
+
+// Enabled returns whether logins are verified against the directory.
+func (d *Directory) Enabled() bool {
+	return *d.config.Enabled
+}
+
+// LocalPasswords returns whether users of other email domains than those of the directory may log in with local
+// passwords.
+func (d *Directory) LocalPasswords() bool {
+	return *d.config.LocalPasswords
+}
+
+// Manages returns whether the directory is enabled and the email is of one of its domains, whose users are verified
+// by the directory only, so that nobody can sign up with the email of an entry before its first login.
+func (d *Directory) Manages(email string) bool {
+	return *d.config.Enabled && d.inDomains(email)
+}
+
+// Login verifies the password of the user found by the username in the directory, and returns the local user
+// linked to its entry, provisioned and linked by its email on first login, with the role of its groups synced.
+func (d *Directory) Login(ctx context.Context, username, password string) (*user.User, error) {
+	found, err := d.authenticate(ctx, username, password)
+	if err != nil {
+		return nil, err
+	}
+
+	email := found.GetEqualFoldAttributeValue(*d.config.EmailAttribute)
+	if !d.inDomains(email) {
+		d.logger.Ctx(ctx).Warn().Str("dn", found.DN).Msg("rejected ldap user of email domain not allowed")
+
+		return nil, ErrInvalidCredentials
+	}
+
+	subject := d.subject(found)
+	if subject == "" {
+		d.logger.Ctx(ctx).Warn().Str("dn", found.DN).Msg("rejected ldap user without id")
+
+		return nil, ErrInvalidCredentials
+	}
+
+	loggedIn, err := d.users.ProvisionIdentity(ctx, identityProvider, subject, email)
+
+	switch {
+	case errors.Is(err, user.ErrInvalidEmail):
+		d.logger.Ctx(ctx).Warn().Str("dn", found.DN).Msg("rejected ldap user without valid email")
+
+		return nil, ErrInvalidCredentials
+	case errors.Is(err, user.ErrIdentityConflict):
+		d.logger.Ctx(ctx).Warn().Str("dn", found.DN).Msg("rejected ldap user of email linked to another entry")
+
+		return nil, ErrInvalidCredentials
+	case errors.Is(err, user.ErrLocalPassword):
+		d.logger.Ctx(ctx).Warn().Str("dn", found.DN).Msg("rejected ldap user of email signed up with a password")
+
+		return nil, ErrInvalidCredentials
+	case err != nil:
+		return nil, fmt.Errorf("failed to provision user: %w", err)
+	}
+
+	role := d.mapRole(found)
+	if role == "" || role == loggedIn.Role {
+		return loggedIn, nil
+	}
+
+	loggedIn, err = d.users.SetRole(ctx, loggedIn.ID, role)
+	if err != nil {
+		return nil, fmt.Errorf("failed to sync user role: %w", err)
+	}
+
+	return loggedIn, nil
+}
+
+// Close closes idle connections, connections in use are closed when released.
+func (d *Directory) Close() {
+	for {
+		select {
+		case c := <-d.idle:
+			_ = c.Unbind()
+		default:
+			return
+		}
+	}
+}
+
+// authenticate returns the entry of the user found by the username, if the password binds as it.
+func (d *Directory) authenticate(ctx context.Context, username, password string) (*goldap.Entry, error) {
+	// binds without password are unauthenticated binds, which succeed without verifying anything
+	if username == "" || password == "" {
+		return nil, ErrInvalidCredentials
+	}
+
+	filter := strings.ReplaceAll(*d.config.UserFilter, usernamePlaceholder, goldap.EscapeFilter(username))
+
+	for attempt := 0; ; attempt++ {
+		c, reused, err := d.acquire(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		found, err := d.verify(ctx, c, filter, password)
+
+		healthy := err == nil || errors.Is(err, ErrInvalidCredentials)
+		d.release(c, healthy)
+
+		// idle connections may have been closed by the server, the request is retried on a new one
+		if !healthy && reused && attempt == 0 && ctx.Err() == nil {
+			continue
+		}
+
+		return found, err
+	}
+}
+
+// verify finds the entry of the user matching the filter and binds as it with the password, rebinding as the
+// service account afterwards.
+func (d *Directory) verify(ctx context.Context, c *goldap.Conn, filter, password string) (*goldap.Entry, error) {
+	attributes := []string{*d.config.EmailAttribute, *d.config.GroupAttribute}
+	if *d.config.IDAttribute != "" {
+		attributes = append(attributes, *d.config.IDAttribute)
+	}
+
+	// two entries at most are enough to tell the user is ambiguous, entries found are returned with the size
+	// limit exceeded result
+	result, err := c.Search(goldap.NewSearchRequest(
+		*d.config.BaseDN, goldap.ScopeWholeSubtree, goldap.NeverDerefAliases, 2,
+		int(*d.config.Timeout/time.Second), false, filter, attributes, nil,
+	))
+	if err != nil && !goldap.IsErrorWithCode(err, goldap.LDAPResultSizeLimitExceeded) {
+		return nil, fmt.Errorf("failed to search user: %w", err)
+	}
+
+	if len(result.Entries) != 1 {
+		if len(result.Entries) > 1 {
+			d.logger.Ctx(ctx).Warn().Msg("rejected ldap login of username matching several entries")
+		}
+
+		return nil, ErrInvalidCredentials
+	}
+
+	bindErr := c.Bind(result.Entries[0].DN, password)
+
+	if err := d.bindService(c); err != nil {
+		return nil, err
+	}
+
+	if goldap.IsErrorWithCode(bindErr, goldap.LDAPResultInvalidCredentials) {
+		return nil, ErrInvalidCredentials
+	}
+
+	if bindErr != nil {
+		return nil, fmt.Errorf("failed to bind user: %w", bindErr)
+	}
+
+	return result.Entries[0], nil
+}
+
+// acquire returns a connection bound as the service account, an idle one if any, waiting while all connections
+// are in use, and whether it was reused.
+func (d *Directory) acquire(ctx context.Context) (*goldap.Conn, bool, error) {
+	select {
+	case d.slots <- struct{}{}:
+	case <-ctx.Done():
+		return nil, false, fmt.Errorf("failed to acquire ldap connection: %w", ctx.Err())
+	}
+
+	if c := d.takeIdle(); c != nil {
+		return c, true, nil
+	}
+
+	c, err := d.dial(ctx)
+	if err != nil {
+		<-d.slots
+
+		return nil, false, err
+	}
+
+	if err := d.bindService(c); err != nil {
+		_ = c.Close()
+		<-d.slots
+
+		return nil, false, err
+	}
+
+	return c, false, nil
+}
+
+// takeIdle returns an idle connection, dropping those closed by the server, nil if there is none.
+func (d *Directory) takeIdle() *goldap.Conn {
+	for {
+		select {
+		case c := <-d.idle:
+			if !c.IsClosing() {
+				return c
+			}
+
+			_ = c.Close()
+		default:
+			return nil
+		}
+	}
+}
+
+// dial connects to the server, over TLS with ldaps URLs or upgraded with StartTLS if configured, within the
+// timeout or the deadline of the context if earlier.
+func (d *Directory) dial(ctx context.Context) (*goldap.Conn, error) {
+	dialer := &net.Dialer{Timeout: *d.config.Timeout}
+
+	if deadline, ok := ctx.Deadline(); ok {
+		dialer.Deadline = deadline
+	}
+
+	c, err := goldap.DialURL(d.url, goldap.DialWithDialer(dialer), goldap.DialWithTLSConfig(d.tlsConfig))
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to directory: %w", err)
+	}
+
+	c.SetTimeout(*d.config.Timeout)
+
+	if *d.config.StartTLS {
+		if err := c.StartTLS(d.tlsConfig); err != nil {
+			_ = c.Close()
+
+			return nil, fmt.Errorf("failed to start tls: %w", err)
+		}
+	}
+
+	return c, nil
+}
+
+// bindService binds the connection as the service account, anonymously if no bind DN is configured.
+func (d *Directory) bindService(c *goldap.Conn) error {
+	var err error
+
+	if *d.config.BindDN == "" {
+		err = c.UnauthenticatedBind("")
+	} else {
+		err = c.Bind(*d.config.BindDN, *d.config.BindPassword)
+	}
+
+	if err != nil {
+		return fmt.Errorf("failed to bind service account: %w", err)
+	}
+
+	return nil
+}
+
+// release returns the connection to the idle connections if it is healthy, or closes it otherwise.
+func (d *Directory) release(c *goldap.Conn, healthy bool) {
+	if !healthy {
+		_ = c.Close()
+	} else {
+		select {
+		case d.idle <- c:
+		default:
+			_ = c.Unbind()
+		}
+	}
+
+	<-d.slots
+}
+
+// subject returns the subject the user of the entry is linked to, the hex encoded ID attribute as IDs such as
+// objectGUID are binary, or else the lowercased DN, empty if the entry has no ID.
+func (d *Directory) subject(found *goldap.Entry) string {
+	if *d.config.IDAttribute == "" {
+		return strings.ToLower(found.DN)
+	}
+
+	return hex.EncodeToString(found.GetEqualFoldRawAttributeValue(*d.config.IDAttribute))
+}
+
+// mapRole returns the role of the first group of the entry mapped by the config, the default role if none is, so
+// that users leaving groups lose their roles, or empty if roles are not synced.
+func (d *Directory) mapRole(found *goldap.Entry) string {
+	if len(d.roles) == 0 {
+		return ""
+	}
+
+	for _, group := range found.GetEqualFoldAttributeValues(*d.config.GroupAttribute) {
+		if role, ok := d.roles[strings.ToLower(group)]; ok {
+			return role
+		}
+	}
+
+	return d.users.DefaultRole()
+}
+
+// inDomains returns whether the email is of one of the configured domains.
+func (d *Directory) inDomains(email string) bool {
+	at := strings.LastIndexByte(email, '@')
+	if at < 0 {
+		return false
+	}
+
+	domain := strings.TrimSpace(email[at+1:])
+
+	return slices.ContainsFunc(d.config.Domains, func(allowed string) bool {
+		return strings.EqualFold(allowed, domain)
+	})
+}
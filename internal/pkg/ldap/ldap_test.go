@@ -0,0 +1,602 @@
+package ldap
+
+import (
+	"context"
+	"encoding/hex"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+
+	"github.com/pocj8ur4in/boilerplate-go/internal/gen/db"
+	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/logger"
+	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/user"
+)
+
+// mockUserQuerier is a mock querier storing users in memory.
+type mockUserQuerier struct {
+	db.Querier
+
+	mu         sync.Mutex
+	users      []*db.User
+	identities []*db.UserIdentity
+}
+
+func (m *mockUserQuerier) CreateUser(_ context.Context, arg *db.CreateUserParams) (*db.User, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	row := &db.User{
+		ID:           arg.ID,
+		Email:        arg.Email,
+		PasswordHash: arg.PasswordHash,
+		Role:         arg.Role,
+		CreatedAt:    pgtype.Timestamptz{Time: time.Now(), Valid: true},
+	}
+	m.users = append(m.users, row)
+
+	return row, nil
+}
+
+func (m *mockUserQuerier) GetUserByEmail(_ context.Context, email string) (*db.User, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, row := range m.users {
+		if row.Email == email {
+			return row, nil
+		}
+	}
+
+	return nil, pgx.ErrNoRows
+}
+
+func (m *mockUserQuerier) GetUserByID(_ context.Context, id string) (*db.User, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, row := range m.users {
+		if row.ID == id {
+			return row, nil
+		}
+	}
+
+	return nil, pgx.ErrNoRows
+}
+
+func (m *mockUserQuerier) CreateUserIdentity(
+	_ context.Context,
+	arg *db.CreateUserIdentityParams,
+) (*db.UserIdentity, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, row := range m.identities {
+		if row.Provider == arg.Provider && (row.Subject == arg.Subject || row.UserID == arg.UserID) {
+			return nil, &pgconn.PgError{Code: "23505"}
+		}
+	}
+
+	row := &db.UserIdentity{Provider: arg.Provider, Subject: arg.Subject, UserID: arg.UserID}
+	m.identities = append(m.identities, row)
+
+	return row, nil
+}
+
+func (m *mockUserQuerier) GetUserIdentity(_ context.Context, arg *db.GetUserIdentityParams) (*db.UserIdentity, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, row := range m.identities {
+		if row.Provider == arg.Provider && row.Subject == arg.Subject {
+			return row, nil
+		}
+	}
+
+	return nil, pgx.ErrNoRows
+}
+
+func (m *mockUserQuerier) GetUserIdentityByUserID(
+	_ context.Context,
+	arg *db.GetUserIdentityByUserIDParams,
+) (*db.UserIdentity, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, row := range m.identities {
+		if row.Provider == arg.Provider && row.UserID == arg.UserID {
+			return row, nil
+		}
+	}
+
+	return nil, pgx.ErrNoRows
+}
+
+func (m *mockUserQuerier) UpdateUserRole(_ context.Context, arg *db.UpdateUserRoleParams) (*db.User, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, row := range m.users {
+		if row.ID == arg.ID {
+			row.Role = arg.Role
+
+			return row, nil
+		}
+	}
+
+	return nil, pgx.ErrNoRows
+}
+
+// testEntries is entries of users of test servers.
+func testEntries() []*testEntry {
+	return []*testEntry{
+		{
+			dn:       "uid=alice,ou=people,dc=example,dc=com",
+			password: "alice-secret",
+			attributes: map[string][]string{
+				"mail":     {"Alice@Example.com"},
+				"memberOf": {"cn=staff,ou=groups,dc=example,dc=com", "CN=Admins,OU=Groups,DC=Example,DC=Com"},
+			},
+		},
+		{
+			dn:       "uid=bob,ou=people,dc=example,dc=com",
+			password: "bob-secret",
+			attributes: map[string][]string{
+				"mail":      {"bob@example.com"},
+				"alias":     {"bob@example.com"},
+				"uid":       {"bob"},
+				"entryUUID": {"b0b"},
+			},
+		},
+		{
+			dn:         "uid=bobby,ou=people,dc=example,dc=com",
+			password:   "bobby-secret",
+			attributes: map[string][]string{"alias": {"bob@example.com"}, "uid": {"bobby"}},
+		},
+		{
+			dn:         "uid=mallory,ou=people,dc=example,dc=com",
+			password:   "mallory-secret",
+			attributes: map[string][]string{"mail": {"mallory@example.org"}},
+		},
+		{
+			dn:         "uid=nomail,ou=people,dc=example,dc=com",
+			password:   "nomail-secret",
+			attributes: map[string][]string{"uid": {"nomail"}},
+		},
+		{
+			dn:         "uid=twin1,ou=people,dc=example,dc=com",
+			password:   "twin-secret",
+			attributes: map[string][]string{"mail": {"twin@example.com"}},
+		},
+		{
+			dn:         "uid=twin2,ou=people,dc=example,dc=com",
+			password:   "twin-secret",
+			attributes: map[string][]string{"mail": {"twin@example.com"}},
+		},
+	}
+}
+
+// setupTestDirectory creates an enabled directory of the test server.
+func setupTestDirectory(
+	t *testing.T,
+	server *testServer,
+	configure func(config *Config),
+) (*Directory, *mockUserQuerier) {
+	t.Helper()
+
+	log, err := logger.New(&logger.Config{})
+	require.NoError(t, err)
+
+	querier := &mockUserQuerier{}
+
+	users, err := user.NewWithQuerier(&user.Config{PasswordCost: &[]int{bcrypt.MinCost}[0]}, querier)
+	require.NoError(t, err)
+
+	config := &Config{
+		Enabled:      &[]bool{true}[0],
+		URL:          &[]string{"ldap://" + server.address()}[0],
+		BindDN:       &[]string{testServiceDN}[0],
+		BindPassword: &[]string{testServicePassword}[0],
+		BaseDN:       &[]string{testBaseDN}[0],
+		Domains:      []string{"Example.com"},
+		Roles:        map[string]string{"cn=admins,ou=groups,dc=example,dc=com": "admin"},
+		Timeout:      &[]time.Duration{time.Second}[0],
+	}
+
+	if configure != nil {
+		configure(config)
+	}
+
+	directory, err := New(config, users, log)
+	require.NoError(t, err)
+
+	t.Cleanup(directory.Close)
+
+	return directory, querier
+}
+
+func TestConfigSetDefault(t *testing.T) {
+	t.Parallel()
+
+	config := &Config{}
+	config.SetDefault()
+
+	assert.False(t, *config.Enabled)
+	assert.False(t, *config.StartTLS)
+	assert.Equal(t, "(mail={username})", *config.UserFilter)
+	assert.Equal(t, "mail", *config.EmailAttribute)
+	assert.Empty(t, config.Domains)
+	assert.Empty(t, *config.IDAttribute)
+	assert.Equal(t, "memberOf", *config.GroupAttribute)
+	assert.Empty(t, config.Roles)
+	assert.False(t, *config.LocalPasswords)
+	assert.Equal(t, 4, *config.MaxConnections)
+	assert.Equal(t, 5*time.Second, *config.Timeout)
+}
+
+func TestNew(t *testing.T) {
+	t.Parallel()
+
+	log, err := logger.New(&logger.Config{})
+	require.NoError(t, err)
+
+	t.Run("not validate disabled config", func(t *testing.T) {
+		t.Parallel()
+
+		directory, err := New(nil, nil, log)
+		require.NoError(t, err)
+		assert.False(t, directory.Enabled())
+	})
+
+	t.Run("reject invalid configs", func(t *testing.T) {
+		t.Parallel()
+
+		valid := func() *Config {
+			return &Config{
+				Enabled: &[]bool{true}[0],
+				URL:     &[]string{"ldaps://ldap.example.com"}[0],
+				BaseDN:  &[]string{testBaseDN}[0],
+				Domains: []string{"example.com"},
+			}
+		}
+
+		for name, test := range map[string]struct {
+			configure func(config *Config)
+			err       error
+		}{
+			"missing url":         {func(c *Config) { c.URL = nil }, ErrInvalidURL},
+			"unsupported scheme":  {func(c *Config) { c.URL = &[]string{"https://ldap.example.com"}[0] }, ErrInvalidURL},
+			"starttls with ldaps": {func(c *Config) { c.StartTLS = &[]bool{true}[0] }, ErrInvalidURL},
+			"missing base dn":     {func(c *Config) { c.BaseDN = nil }, ErrMissingBaseDN},
+			"missing domains":     {func(c *Config) { c.Domains = nil }, ErrMissingDomains},
+			"invalid filter":      {func(c *Config) { c.UserFilter = &[]string{"(mail={username}"}[0] }, ErrInvalidFilter},
+			"missing placeholder": {func(c *Config) { c.UserFilter = &[]string{"(mail=a)"}[0] }, ErrInvalidFilter},
+			"invalid certificate": {func(c *Config) { c.CACertificates = []string{"invalid"} }, ErrInvalidCertificate},
+		} {
+			config := valid()
+			test.configure(config)
+
+			_, err := New(config, nil, log)
+			require.ErrorIs(t, err, test.err, name)
+		}
+	})
+
+	t.Run("default port of scheme", func(t *testing.T) {
+		t.Parallel()
+
+		directory, err := New(&Config{
+			Enabled: &[]bool{true}[0],
+			URL:     &[]string{"ldap://ldap.example.com"}[0],
+			BaseDN:  &[]string{testBaseDN}[0],
+			Domains: []string{"example.com"},
+		}, nil, log)
+		require.NoError(t, err)
+		assert.Equal(t, "ldap://ldap.example.com:389", directory.url)
+		assert.Nil(t, directory.tlsConfig)
+	})
+}
+
+func TestLogin(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	server := newTestServer(t, nil, false, testEntries()...)
+	directory, querier := setupTestDirectory(t, server, nil)
+
+	t.Run("provision user with role of groups", func(t *testing.T) {
+		t.Parallel()
+
+		loggedIn, err := directory.Login(ctx, "alice@example.com", "alice-secret")
+		require.NoError(t, err)
+		assert.Equal(t, "alice@example.com", loggedIn.Email)
+		assert.Equal(t, "admin", loggedIn.Role)
+
+		again, err := directory.Login(ctx, "alice@example.com", "alice-secret")
+		require.NoError(t, err)
+		assert.Equal(t, loggedIn.ID, again.ID)
+	})
+
+	t.Run("demote users of unmapped groups", func(t *testing.T) {
+		t.Parallel()
+
+		demoteDirectory, demoteQuerier := setupTestDirectory(t, server, nil)
+
+		loggedIn, err := demoteDirectory.Login(ctx, "bob@example.com", "bob-secret")
+		require.NoError(t, err)
+		assert.Equal(t, "user", loggedIn.Role)
+
+		_, err = demoteQuerier.UpdateUserRole(ctx, &db.UpdateUserRoleParams{ID: loggedIn.ID, Role: "admin"})
+		require.NoError(t, err)
+
+		loggedIn, err = demoteDirectory.Login(ctx, "bob@example.com", "bob-secret")
+		require.NoError(t, err)
+		assert.Equal(t, "user", loggedIn.Role)
+	})
+
+	t.Run("keep role if roles are not synced", func(t *testing.T) {
+		t.Parallel()
+
+		unsyncedDirectory, unsyncedQuerier := setupTestDirectory(t, server, func(config *Config) {
+			config.Roles = map[string]string{}
+		})
+
+		loggedIn, err := unsyncedDirectory.Login(ctx, "alice@example.com", "alice-secret")
+		require.NoError(t, err)
+		assert.Equal(t, "user", loggedIn.Role)
+
+		_, err = unsyncedQuerier.UpdateUserRole(ctx, &db.UpdateUserRoleParams{ID: loggedIn.ID, Role: "admin"})
+		require.NoError(t, err)
+
+		loggedIn, err = unsyncedDirectory.Login(ctx, "alice@example.com", "alice-secret")
+		require.NoError(t, err)
+		assert.Equal(t, "admin", loggedIn.Role)
+	})
+
+	t.Run("not link users with local passwords", func(t *testing.T) {
+		t.Parallel()
+
+		linkDirectory, linkQuerier := setupTestDirectory(t, server, nil)
+		linkQuerier.users = append(linkQuerier.users, &db.User{
+			ID:           "signed-up",
+			Email:        "bob@example.com",
+			PasswordHash: "hash",
+			Role:         "user",
+		})
+
+		_, err := linkDirectory.Login(ctx, "bob@example.com", "bob-secret")
+		require.ErrorIs(t, err, ErrInvalidCredentials)
+		assert.Empty(t, linkQuerier.identities)
+	})
+
+	t.Run("reject invalid credentials", func(t *testing.T) {
+		t.Parallel()
+
+		for name, credentials := range map[string][2]string{
+			"wrong password":   {"bob@example.com", "wrong"},
+			"empty password":   {"bob@example.com", ""},
+			"unknown user":     {"eve@example.com", "eve-secret"},
+			"ambiguous user":   {"twin@example.com", "twin-secret"},
+			"filter injection": {"*", "bob-secret"},
+		} {
+			_, err := directory.Login(ctx, credentials[0], credentials[1])
+			require.ErrorIs(t, err, ErrInvalidCredentials, name)
+		}
+	})
+
+	t.Run("reject user without email", func(t *testing.T) {
+		t.Parallel()
+
+		uidDirectory, _ := setupTestDirectory(t, server, func(config *Config) {
+			config.UserFilter = &[]string{"(uid={username})"}[0]
+		})
+
+		_, err := uidDirectory.Login(ctx, "nomail", "nomail-secret")
+		require.ErrorIs(t, err, ErrInvalidCredentials)
+	})
+
+	t.Run("reject users of other domains", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := directory.Login(ctx, "mallory@example.org", "mallory-secret")
+		require.ErrorIs(t, err, ErrInvalidCredentials)
+
+		_, err = querier.GetUserByEmail(ctx, "mallory@example.org")
+		require.ErrorIs(t, err, pgx.ErrNoRows)
+	})
+
+	t.Run("reject entry of email linked to another entry", func(t *testing.T) {
+		t.Parallel()
+
+		uidDirectory, _ := setupTestDirectory(t, server, func(config *Config) {
+			config.UserFilter = &[]string{"(uid={username})"}[0]
+			config.EmailAttribute = &[]string{"alias"}[0]
+		})
+
+		bob, err := uidDirectory.Login(ctx, "bob", "bob-secret")
+		require.NoError(t, err)
+
+		_, err = uidDirectory.Login(ctx, "bobby", "bobby-secret")
+		require.ErrorIs(t, err, ErrInvalidCredentials)
+
+		again, err := uidDirectory.Login(ctx, "bob", "bob-secret")
+		require.NoError(t, err)
+		assert.Equal(t, bob.ID, again.ID)
+	})
+
+	t.Run("link users by id attribute", func(t *testing.T) {
+		t.Parallel()
+
+		idDirectory, idQuerier := setupTestDirectory(t, server, func(config *Config) {
+			config.IDAttribute = &[]string{"entryUUID"}[0]
+		})
+
+		_, err := idDirectory.Login(ctx, "bob@example.com", "bob-secret")
+		require.NoError(t, err)
+		require.Len(t, idQuerier.identities, 1)
+		assert.Equal(t, hex.EncodeToString([]byte("b0b")), idQuerier.identities[0].Subject)
+
+		// entries without id are not linked
+		_, err = idDirectory.Login(ctx, "alice@example.com", "alice-secret")
+		require.ErrorIs(t, err, ErrInvalidCredentials)
+	})
+
+	t.Run("not provision rejected users", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := directory.Login(ctx, "eve@example.com", "eve-secret")
+		require.ErrorIs(t, err, ErrInvalidCredentials)
+
+		_, err = querier.GetUserByEmail(ctx, "eve@example.com")
+		require.ErrorIs(t, err, pgx.ErrNoRows)
+	})
+}
+
+func TestManages(t *testing.T) {
+	t.Parallel()
+
+	server := newTestServer(t, nil, false)
+	directory, _ := setupTestDirectory(t, server, nil)
+
+	assert.True(t, directory.Manages("alice@example.com"))
+	assert.True(t, directory.Manages(" Alice@EXAMPLE.com "))
+	assert.False(t, directory.Manages("alice@example.org"))
+	assert.False(t, directory.Manages("alice@sub.example.com"))
+	assert.False(t, directory.Manages("example.com"))
+
+	disabled, err := New(nil, nil, directory.logger)
+	require.NoError(t, err)
+	assert.False(t, disabled.Manages("alice@example.com"))
+}
+
+func TestLoginTLS(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	serverTLSConfig, caCertificate := newTestTLSConfig(t)
+
+	t.Run("log in over ldaps", func(t *testing.T) {
+		t.Parallel()
+
+		server := newTestServer(t, serverTLSConfig, true, testEntries()...)
+		directory, _ := setupTestDirectory(t, server, func(config *Config) {
+			config.URL = &[]string{"ldaps://" + server.address()}[0]
+			config.CACertificates = []string{caCertificate}
+		})
+
+		_, err := directory.Login(ctx, "bob@example.com", "bob-secret")
+		require.NoError(t, err)
+	})
+
+	t.Run("log in with starttls", func(t *testing.T) {
+		t.Parallel()
+
+		server := newTestServer(t, serverTLSConfig, false, testEntries()...)
+		directory, _ := setupTestDirectory(t, server, func(config *Config) {
+			config.StartTLS = &[]bool{true}[0]
+			config.CACertificates = []string{caCertificate}
+		})
+
+		_, err := directory.Login(ctx, "bob@example.com", "bob-secret")
+		require.NoError(t, err)
+	})
+
+	t.Run("fail with untrusted certificate", func(t *testing.T) {
+		t.Parallel()
+
+		server := newTestServer(t, serverTLSConfig, true, testEntries()...)
+		directory, _ := setupTestDirectory(t, server, func(config *Config) {
+			config.URL = &[]string{"ldaps://" + server.address()}[0]
+		})
+
+		_, err := directory.Login(ctx, "bob@example.com", "bob-secret")
+		require.Error(t, err)
+		assert.NotErrorIs(t, err, ErrInvalidCredentials)
+	})
+}
+
+func TestConnectionPool(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	t.Run("reuse connections rebound as service account", func(t *testing.T) {
+		t.Parallel()
+
+		server := newTestServer(t, nil, false, testEntries()...)
+		directory, _ := setupTestDirectory(t, server, nil)
+
+		for _, password := range []string{"bob-secret", "wrong", "bob-secret"} {
+			_, _ = directory.Login(ctx, "bob@example.com", password)
+		}
+
+		_, err := directory.Login(ctx, "bob@example.com", "bob-secret")
+		require.NoError(t, err)
+		assert.Equal(t, int32(1), server.dials.Load())
+	})
+
+	t.Run("limit connections", func(t *testing.T) {
+		t.Parallel()
+
+		server := newTestServer(t, nil, false, testEntries()...)
+		directory, _ := setupTestDirectory(t, server, func(config *Config) {
+			config.MaxConnections = &[]int{2}[0]
+		})
+
+		var group sync.WaitGroup
+
+		for range 10 {
+			group.Go(func() {
+				_, err := directory.Login(ctx, "bob@example.com", "bob-secret")
+				assert.NoError(t, err)
+			})
+		}
+
+		group.Wait()
+
+		assert.LessOrEqual(t, server.dials.Load(), int32(2))
+	})
+
+	t.Run("retry on new connection when idle one was closed", func(t *testing.T) {
+		t.Parallel()
+
+		server := newTestServer(t, nil, false, testEntries()...)
+		directory, _ := setupTestDirectory(t, server, nil)
+
+		_, err := directory.Login(ctx, "bob@example.com", "bob-secret")
+		require.NoError(t, err)
+
+		server.closeConnections()
+
+		_, err = directory.Login(ctx, "bob@example.com", "bob-secret")
+		require.NoError(t, err)
+		assert.Equal(t, int32(2), server.dials.Load())
+	})
+
+	t.Run("wait for connection until context is done", func(t *testing.T) {
+		t.Parallel()
+
+		server := newTestServer(t, nil, false, testEntries()...)
+		directory, _ := setupTestDirectory(t, server, func(config *Config) {
+			config.MaxConnections = &[]int{1}[0]
+		})
+
+		held, _, err := directory.acquire(ctx)
+		require.NoError(t, err)
+
+		timeoutCtx, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+		defer cancel()
+
+		_, err = directory.Login(timeoutCtx, "bob@example.com", "bob-secret")
+		require.ErrorIs(t, err, context.DeadlineExceeded)
+
+		directory.release(held, true)
+	})
+}
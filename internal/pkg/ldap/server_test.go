@@ -0,0 +1,412 @@
+package ldap
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	ber "github.com/go-asn1-ber/asn1-ber"
+	goldap "github.com/go-ldap/ldap/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	// testServiceDN is DN of the service account of the test server.
+	testServiceDN = "cn=service,dc=example,dc=com"
+
+	// testServicePassword is password of the service account of the test server.
+	testServicePassword = "service-secret"
+
+	// testBaseDN is base DN of entries of the test server.
+	testBaseDN = "dc=example,dc=com"
+
+	// testOIDStartTLS is the request name of StartTLS extended requests.
+	testOIDStartTLS = "1.3.6.1.4.1.1466.20037"
+)
+
+// testEntry represents an entry of the test server.
+type testEntry struct {
+	// dn is DN of the entry.
+	dn string
+
+	// password is password binding as the entry.
+	password string
+
+	// attributes is attribute values of the entry.
+	attributes map[string][]string
+}
+
+// testServer is an LDAP server serving binds and searches of equality filters on entries in memory, searches
+// require binding as the service account.
+type testServer struct {
+	// listener accepts connections.
+	listener net.Listener
+
+	// tlsConfig is TLS configuration of StartTLS, nil if not supported.
+	tlsConfig *tls.Config
+
+	// entries is entries of the server, including the service account.
+	entries []*testEntry
+
+	// dials is number of connections accepted.
+	dials atomic.Int32
+
+	mu    sync.Mutex
+	conns []net.Conn
+}
+
+// newTestServer starts a test server with the entries, serving TLS if ldaps or StartTLS with the TLS config.
+func newTestServer(t *testing.T, tlsConfig *tls.Config, ldaps bool, entries ...*testEntry) *testServer {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	server := &testServer{
+		listener: listener,
+		entries:  append([]*testEntry{{dn: testServiceDN, password: testServicePassword}}, entries...),
+	}
+
+	if ldaps {
+		server.listener = tls.NewListener(listener, tlsConfig)
+	} else {
+		server.tlsConfig = tlsConfig
+	}
+
+	t.Cleanup(func() {
+		_ = server.listener.Close()
+		server.closeConnections()
+	})
+
+	go func() {
+		for {
+			netConn, err := server.listener.Accept()
+			if err != nil {
+				return
+			}
+
+			server.dials.Add(1)
+
+			server.mu.Lock()
+			server.conns = append(server.conns, netConn)
+			server.mu.Unlock()
+
+			go server.serve(netConn)
+		}
+	}()
+
+	return server
+}
+
+// address returns host and port of the server.
+func (s *testServer) address() string {
+	return s.listener.Addr().String()
+}
+
+// closeConnections closes accepted connections, as servers do with idle connections.
+func (s *testServer) closeConnections() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, netConn := range s.conns {
+		_ = netConn.Close()
+	}
+
+	s.conns = nil
+}
+
+// serve serves requests of the connection until it is unbound or closed.
+func (s *testServer) serve(netConn net.Conn) {
+	connection := netConn
+	bound := ""
+
+	for {
+		message, err := ber.ReadPacket(connection)
+		if err != nil || len(message.Children) < 2 {
+			return
+		}
+
+		request := message.Children[1]
+
+		reply := func(response *ber.Packet) {
+			envelope := ber.NewSequence("")
+			envelope.AppendChild(message.Children[0])
+			envelope.AppendChild(response)
+
+			_, _ = connection.Write(envelope.Bytes())
+		}
+
+		switch request.Tag {
+		case goldap.ApplicationBindRequest:
+			bound = ""
+			dn, password := testString(request.Children[1]), testString(request.Children[2])
+
+			if dn != "" && !s.authenticates(dn, password) {
+				reply(testResult(goldap.ApplicationBindResponse, goldap.LDAPResultInvalidCredentials))
+
+				continue
+			}
+
+			bound = dn
+			reply(testResult(goldap.ApplicationBindResponse, goldap.LDAPResultSuccess))
+		case goldap.ApplicationSearchRequest:
+			if bound != testServiceDN {
+				reply(testResult(goldap.ApplicationSearchResultDone, goldap.LDAPResultInsufficientAccessRights))
+
+				continue
+			}
+
+			s.search(request, reply)
+		case goldap.ApplicationExtendedRequest:
+			if s.tlsConfig == nil || testString(request.Children[0]) != testOIDStartTLS {
+				reply(testResult(goldap.ApplicationExtendedResponse, goldap.LDAPResultProtocolError))
+
+				continue
+			}
+
+			reply(testResult(goldap.ApplicationExtendedResponse, goldap.LDAPResultSuccess))
+
+			tlsConn := tls.Server(connection, s.tlsConfig)
+			if err := tlsConn.Handshake(); err != nil {
+				return
+			}
+
+			connection = tlsConn
+		default:
+			_ = connection.Close()
+
+			return
+		}
+	}
+}
+
+// authenticates returns whether the password binds as the entry of the DN.
+func (s *testServer) authenticates(dn, password string) bool {
+	for _, entry := range s.entries {
+		if strings.EqualFold(entry.dn, dn) {
+			return password != "" && entry.password == password
+		}
+	}
+
+	return false
+}
+
+// search replies entries matching the filter of the request, at most its size limit.
+func (s *testServer) search(request *ber.Packet, reply func(*ber.Packet)) {
+	sizeLimit, _ := request.Children[3].Value.(int64)
+	filter := request.Children[6]
+
+	var returned int64
+
+	for _, entry := range s.entries {
+		if !testMatches(entry, filter) {
+			continue
+		}
+
+		if sizeLimit > 0 && returned == sizeLimit {
+			reply(testResult(goldap.ApplicationSearchResultDone, goldap.LDAPResultSizeLimitExceeded))
+
+			return
+		}
+
+		attributes := ber.NewSequence("")
+
+		for _, description := range request.Children[7].Children {
+			values := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSet, nil, "")
+			for _, value := range entry.attributes[testString(description)] {
+				values.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, value, ""))
+			}
+
+			if len(values.Children) > 0 {
+				attribute := ber.NewSequence("")
+				attribute.AppendChild(description)
+				attribute.AppendChild(values)
+				attributes.AppendChild(attribute)
+			}
+		}
+
+		response := ber.Encode(ber.ClassApplication, ber.TypeConstructed, goldap.ApplicationSearchResultEntry, nil, "")
+		response.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, entry.dn, ""))
+		response.AppendChild(attributes)
+		reply(response)
+
+		returned++
+	}
+
+	reply(testResult(goldap.ApplicationSearchResultDone, goldap.LDAPResultSuccess))
+}
+
+// testMatches returns whether the entry matches the filter of equality matches, possibly and-ed.
+func testMatches(entry *testEntry, filter *ber.Packet) bool {
+	switch filter.Tag {
+	case goldap.FilterAnd:
+		for _, child := range filter.Children {
+			if !testMatches(entry, child) {
+				return false
+			}
+		}
+
+		return true
+	case goldap.FilterEqualityMatch:
+		for _, value := range entry.attributes[testString(filter.Children[0])] {
+			if strings.EqualFold(value, testString(filter.Children[1])) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// testString returns contents of the primitive value.
+func testString(value *ber.Packet) string {
+	return value.Data.String()
+}
+
+// testResult creates a response of the tag with the result code.
+func testResult(tag ber.Tag, code uint16) *ber.Packet {
+	response := ber.Encode(ber.ClassApplication, ber.TypeConstructed, tag, nil, "")
+	response.AppendChild(ber.NewInteger(ber.ClassUniversal, ber.TypePrimitive, ber.TagEnumerated, int64(code), ""))
+	response.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, "", ""))
+	response.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, "", ""))
+
+	return response
+}
+
+// newTestTLSConfig creates TLS configuration of a self-signed certificate of 127.0.0.1, and returns it with the
+// PEM encoded certificate.
+func newTestTLSConfig(t *testing.T) (*tls.Config, string) {
+	t.Helper()
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "ldap.example.com"},
+		IPAddresses:           []net.IP{net.ParseIP("127.0.0.1")},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+
+	certificate, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+
+	return &tls.Config{
+		Certificates: []tls.Certificate{{Certificate: [][]byte{certificate}, PrivateKey: key}},
+		MinVersion:   tls.VersionTLS12,
+	}, string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: certificate}))
+}
+
+func TestDial(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	serverTLSConfig, caCertificate := newTestTLSConfig(t)
+
+	t.Run("connect bound as service account", func(t *testing.T) {
+		t.Parallel()
+
+		server := newTestServer(t, nil, false)
+		directory, _ := setupTestDirectory(t, server, nil)
+
+		c, reused, err := directory.acquire(ctx)
+		require.NoError(t, err)
+
+		defer directory.release(c, true)
+
+		assert.False(t, reused)
+		assert.False(t, c.IsClosing())
+	})
+
+	t.Run("fail to bind unknown service account", func(t *testing.T) {
+		t.Parallel()
+
+		server := newTestServer(t, nil, false)
+		directory, _ := setupTestDirectory(t, server, func(config *Config) {
+			config.BindPassword = &[]string{"wrong"}[0]
+		})
+
+		_, _, err := directory.acquire(ctx)
+		require.Error(t, err)
+		assert.True(t, goldap.IsErrorWithCode(err, goldap.LDAPResultInvalidCredentials))
+	})
+
+	t.Run("connect over tls", func(t *testing.T) {
+		t.Parallel()
+
+		server := newTestServer(t, serverTLSConfig, true)
+		directory, _ := setupTestDirectory(t, server, func(config *Config) {
+			config.URL = &[]string{"ldaps://" + server.address()}[0]
+			config.CACertificates = []string{caCertificate}
+		})
+
+		c, err := directory.dial(ctx)
+		require.NoError(t, err)
+
+		defer func() { _ = c.Close() }()
+
+		require.NoError(t, directory.bindService(c))
+	})
+
+	t.Run("upgrade with starttls", func(t *testing.T) {
+		t.Parallel()
+
+		server := newTestServer(t, serverTLSConfig, false)
+		directory, _ := setupTestDirectory(t, server, func(config *Config) {
+			config.StartTLS = &[]bool{true}[0]
+			config.CACertificates = []string{caCertificate}
+		})
+
+		c, err := directory.dial(ctx)
+		require.NoError(t, err)
+
+		defer func() { _ = c.Close() }()
+
+		_, ok := c.TLSConnectionState()
+		assert.True(t, ok)
+		require.NoError(t, directory.bindService(c))
+	})
+
+	t.Run("fail without starttls support", func(t *testing.T) {
+		t.Parallel()
+
+		server := newTestServer(t, nil, false)
+		directory, _ := setupTestDirectory(t, server, func(config *Config) {
+			config.StartTLS = &[]bool{true}[0]
+			config.CACertificates = []string{caCertificate}
+		})
+
+		_, err := directory.dial(ctx)
+		require.Error(t, err)
+	})
+
+	t.Run("fail with untrusted certificate", func(t *testing.T) {
+		t.Parallel()
+
+		server := newTestServer(t, serverTLSConfig, false)
+		directory, _ := setupTestDirectory(t, server, func(config *Config) {
+			config.StartTLS = &[]bool{true}[0]
+		})
+
+		_, err := directory.dial(ctx)
+		require.Error(t, err)
+	})
+}
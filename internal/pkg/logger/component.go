@@ -0,0 +1,102 @@
+package logger
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/rs/zerolog"
+)
+
+// components holds minimum levels of components and writers of their loggers, so that levels of components
+// can be changed at runtime.
+type components struct {
+	// mu guards levels and writers.
+	mu sync.Mutex
+
+	// root is writer of the logger created by New, followed by components without level.
+	root *levelWriter
+
+	// levels is minimum levels of components.
+	levels map[string]zerolog.Level
+
+	// writers is writers of component loggers by component.
+	writers map[string]*levelWriter
+}
+
+// newComponents creates components writing to the root writer.
+func newComponents(root *levelWriter, levels map[string]zerolog.Level) *components {
+	return &components{
+		root:    root,
+		levels:  levels,
+		writers: map[string]*levelWriter{},
+	}
+}
+
+// writer returns writer of the component, filtered by the level of the component or else of the root writer.
+func (c *components) writer(component string) *levelWriter {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if writer, ok := c.writers[component]; ok {
+		return writer
+	}
+
+	writer := &levelWriter{Writer: c.root.Writer, parent: c.root}
+	if level, ok := c.levels[component]; ok {
+		writer.setLevel(level)
+	}
+
+	c.writers[component] = writer
+
+	return writer
+}
+
+// setLevels replaces minimum levels of components, components without level follow the root writer again.
+func (c *components) setLevels(levels map[string]zerolog.Level) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.levels = levels
+
+	for component, writer := range c.writers {
+		if level, ok := levels[component]; ok {
+			writer.setLevel(level)
+		} else {
+			writer.resetLevel()
+		}
+	}
+}
+
+// Named returns logger of the component carrying its name in the component field, filtered by the level of the
+// component in Config.Levels, or by the level of the logger if the component has none.
+func (l *Logger) Named(component string) *Logger {
+	if l.components == nil {
+		return l.Child(func(fields zerolog.Context) zerolog.Context {
+			return fields.Str("component", component)
+		})
+	}
+
+	writer := l.components.writer(component)
+
+	return &Logger{
+		Logger:     l.Output(writer).With().Str("component", component).Logger(),
+		level:      writer,
+		components: l.components,
+	}
+}
+
+// parseLevels parses minimum levels of components.
+func parseLevels(levels map[string]string) (map[string]zerolog.Level, error) {
+	parsed := make(map[string]zerolog.Level, len(levels))
+
+	for component, level := range levels {
+		componentLevel, err := zerolog.ParseLevel(level)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse log level of %s: %w", component, err)
+		}
+
+		parsed[component] = componentLevel
+	}
+
+	return parsed, nil
+}
@@ -0,0 +1,166 @@
+package logger
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/rs/zerolog"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newFileLogger creates a logger writing JSON events to a file and returns the path of the file.
+func newFileLogger(t *testing.T, config *Config) (*Logger, string) {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "app.log")
+
+	config.Format = &[]string{FormatJSON}[0]
+	config.Output = &path
+
+	logger, err := New(config)
+	require.NoError(t, err)
+
+	t.Cleanup(func() { _ = logger.Close() })
+
+	return logger, path
+}
+
+// readLog returns the content of the log file.
+func readLog(t *testing.T, path string) string {
+	t.Helper()
+
+	content, err := os.ReadFile(path) //nolint:gosec // path is in the test directory
+	require.NoError(t, err)
+
+	return string(content)
+}
+
+func TestNamed(t *testing.T) {
+	t.Parallel()
+
+	t.Run("filter component by its own level", func(t *testing.T) {
+		t.Parallel()
+
+		logger, path := newFileLogger(t, &Config{
+			Level:  &[]string{"info"}[0],
+			Levels: map[string]string{"server": "debug", "database": "warn"},
+		})
+
+		logger.Named("server").Debug().Msg("server debug")
+		logger.Named("database").Info().Msg("database info")
+		logger.Named("database").Warn().Msg("database warn")
+		logger.Named("settings").Debug().Msg("settings debug")
+		logger.Named("settings").Info().Msg("settings info")
+
+		content := readLog(t, path)
+		assert.Contains(t, content, `"level":"debug","component":"server"`)
+		assert.NotContains(t, content, "database info")
+		assert.Contains(t, content, "database warn")
+		assert.NotContains(t, content, "settings debug")
+		assert.Contains(t, content, `"level":"info","component":"settings"`)
+	})
+
+	t.Run("follow level of logger without component level", func(t *testing.T) {
+		t.Parallel()
+
+		logger, path := newFileLogger(t, &Config{
+			Level:  &[]string{"info"}[0],
+			Levels: map[string]string{"server": "error"},
+		})
+
+		settings := logger.Named("settings")
+		server := logger.Named("server")
+
+		require.NoError(t, logger.SetLevel("debug"))
+
+		assert.Equal(t, zerolog.DebugLevel, settings.GetLevel())
+		assert.Equal(t, zerolog.ErrorLevel, server.GetLevel())
+
+		settings.Debug().Msg("settings debug")
+		server.Debug().Msg("server debug")
+
+		content := readLog(t, path)
+		assert.Contains(t, content, "settings debug")
+		assert.NotContains(t, content, "server debug")
+	})
+
+	t.Run("apply component levels on reload", func(t *testing.T) {
+		t.Parallel()
+
+		logger, _ := newFileLogger(t, &Config{
+			Level:  &[]string{"info"}[0],
+			Levels: map[string]string{"server": "error"},
+		})
+
+		server := logger.Named("server")
+		settings := logger.Named("settings")
+
+		require.NoError(t, logger.Reload(&Config{Levels: map[string]string{"settings": "trace"}}))
+
+		assert.Equal(t, zerolog.InfoLevel, server.GetLevel())
+		assert.Equal(t, zerolog.TraceLevel, settings.GetLevel())
+		assert.Same(t, settings.level, logger.Named("settings").level)
+
+		err := logger.Reload(&Config{Levels: map[string]string{"settings": "invalid"}})
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "failed to parse log level of settings")
+		assert.Equal(t, zerolog.TraceLevel, settings.GetLevel())
+	})
+
+	t.Run("return error by using invalid component level", func(t *testing.T) {
+		t.Parallel()
+
+		logger, err := New(&Config{Levels: map[string]string{"server": "invalid"}})
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "failed to parse log level of server")
+		assert.Nil(t, logger)
+	})
+
+	t.Run("add component to logger not created by new", func(t *testing.T) {
+		t.Parallel()
+
+		var buffer bytes.Buffer
+
+		newBufferLogger(&buffer, zerolog.InfoLevel).Named("server").Info().Msg("handled")
+		assert.Contains(t, buffer.String(), `"component":"server"`)
+	})
+}
+
+func TestSampling(t *testing.T) {
+	t.Parallel()
+
+	t.Run("write 1 of n events of sampled level", func(t *testing.T) {
+		t.Parallel()
+
+		logger, path := newFileLogger(t, &Config{
+			Level:    &[]string{"debug"}[0],
+			Sampling: map[string]uint32{"debug": 3},
+		})
+
+		server := logger.Named("server")
+
+		for range 6 {
+			server.Debug().Msg("sampled")
+			server.Info().Msg("not sampled")
+		}
+
+		content := readLog(t, path)
+		assert.Equal(t, 2, strings.Count(content, `"message":"sampled"`))
+		assert.Equal(t, 6, strings.Count(content, `"message":"not sampled"`))
+	})
+
+	t.Run("return error by using invalid sampling", func(t *testing.T) {
+		t.Parallel()
+
+		for _, sampling := range []map[string]uint32{{"debug": 0}, {"invalid": 2}, {"fatal": 2}} {
+			logger, err := New(&Config{Sampling: sampling})
+			require.ErrorIs(t, err, ErrInvalidSampling)
+			assert.Nil(t, logger)
+		}
+	})
+}
@@ -0,0 +1,46 @@
+package logger
+
+import (
+	"context"
+
+	"github.com/rs/zerolog"
+)
+
+// contextKey is the context key of the request-scoped logger.
+type contextKey struct{}
+
+// NewContext returns a copy of the context carrying the logger.
+func NewContext(ctx context.Context, logger *Logger) context.Context {
+	return context.WithValue(ctx, contextKey{}, logger)
+}
+
+// FromContext returns the logger carried by the context, or the fallback if the context carries none.
+func FromContext(ctx context.Context, fallback *Logger) *Logger {
+	if logger, ok := ctx.Value(contextKey{}).(*Logger); ok && logger != nil {
+		return logger
+	}
+
+	return fallback
+}
+
+// UpdateContext adds fields to the logger carried by the context in place, so that loggers retrieved earlier
+// from the same context log them too, it does nothing if the context carries no logger.
+func UpdateContext(ctx context.Context, update func(zerolog.Context) zerolog.Context) {
+	if logger := FromContext(ctx, nil); logger != nil {
+		logger.UpdateContext(update)
+	}
+}
+
+// Ctx returns the logger carried by the context, or the logger itself if the context carries none.
+func (l *Logger) Ctx(ctx context.Context) *Logger {
+	return FromContext(ctx, l)
+}
+
+// Child returns a logger with the fields added, sharing the runtime level of the logger.
+func (l *Logger) Child(update func(zerolog.Context) zerolog.Context) *Logger {
+	return &Logger{
+		Logger:     update(l.With()).Logger(),
+		level:      l.level,
+		components: l.components,
+	}
+}
@@ -0,0 +1,115 @@
+package logger
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/rs/zerolog"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newBufferLogger creates a logger writing JSON events at or above the level to the buffer.
+func newBufferLogger(buffer *bytes.Buffer, level zerolog.Level) *Logger {
+	writer := &levelWriter{Writer: buffer}
+	writer.level.Store(int32(level))
+
+	return &Logger{
+		Logger: zerolog.New(writer),
+		level:  writer,
+	}
+}
+
+func TestContext(t *testing.T) {
+	t.Parallel()
+
+	t.Run("return fallback if context carries no logger", func(t *testing.T) {
+		t.Parallel()
+
+		var buffer bytes.Buffer
+
+		fallback := newBufferLogger(&buffer, zerolog.DebugLevel)
+
+		assert.Same(t, fallback, FromContext(context.Background(), fallback))
+		assert.Same(t, fallback, fallback.Ctx(context.Background()))
+		assert.Nil(t, FromContext(context.Background(), nil))
+	})
+
+	t.Run("return logger carried by context", func(t *testing.T) {
+		t.Parallel()
+
+		var buffer bytes.Buffer
+
+		fallback := newBufferLogger(&buffer, zerolog.DebugLevel)
+		child := fallback.Child(func(c zerolog.Context) zerolog.Context {
+			return c.Str("request_id", "req-1")
+		})
+
+		ctx := NewContext(context.Background(), child)
+
+		assert.Same(t, child, FromContext(ctx, fallback))
+		assert.Same(t, child, fallback.Ctx(ctx))
+
+		fallback.Ctx(ctx).Info().Msg("handled")
+		assert.Contains(t, buffer.String(), `"request_id":"req-1"`)
+	})
+
+	t.Run("add fields to logger carried by context in place", func(t *testing.T) {
+		t.Parallel()
+
+		var buffer bytes.Buffer
+
+		fallback := newBufferLogger(&buffer, zerolog.DebugLevel)
+		ctx := NewContext(context.Background(), fallback.Child(func(c zerolog.Context) zerolog.Context {
+			return c.Str("request_id", "req-1")
+		}))
+
+		// retrieved before the fields are added
+		log := FromContext(ctx, fallback)
+
+		UpdateContext(ctx, func(c zerolog.Context) zerolog.Context {
+			return c.Str("user_id", "user-1")
+		})
+
+		log.Info().Msg("handled")
+		assert.Contains(t, buffer.String(), `"request_id":"req-1"`)
+		assert.Contains(t, buffer.String(), `"user_id":"user-1"`)
+
+		buffer.Reset()
+
+		fallback.Info().Msg("unscoped")
+		assert.NotContains(t, buffer.String(), "user_id")
+	})
+
+	t.Run("ignore update if context carries no logger", func(t *testing.T) {
+		t.Parallel()
+
+		require.NotPanics(t, func() {
+			UpdateContext(context.Background(), func(c zerolog.Context) zerolog.Context {
+				return c.Str("user_id", "user-1")
+			})
+		})
+	})
+
+	t.Run("share runtime level with child", func(t *testing.T) {
+		t.Parallel()
+
+		var buffer bytes.Buffer
+
+		parent := newBufferLogger(&buffer, zerolog.InfoLevel)
+		child := parent.Child(func(c zerolog.Context) zerolog.Context {
+			return c.Str("request_id", "req-1")
+		})
+
+		child.Debug().Msg("dropped")
+		assert.Empty(t, buffer.String())
+
+		require.NoError(t, parent.SetLevel("debug"))
+		assert.Equal(t, zerolog.DebugLevel, child.GetLevel())
+
+		child.Debug().Msg("written")
+		assert.Contains(t, buffer.String(), "written")
+	})
+}
@@ -2,28 +2,108 @@
 package logger
 
 import (
+	"errors"
 	"fmt"
+	"io"
 	"os"
+	"sync/atomic"
 	"time"
 
 	"github.com/rs/zerolog"
 	"go.uber.org/fx"
+	"gopkg.in/natefinch/lumberjack.v2"
 )
 
 // Logger represents logger.
 type Logger struct {
 	zerolog.Logger
+
+	// level is minimum level written, changed at runtime by SetLevel.
+	level *levelWriter
+
+	// file is the rotated log file, nil if logs are written to stdout or stderr.
+	file io.Closer
+
+	// components provides writers of component loggers, nil if the logger is not created by New.
+	components *components
+}
+
+// levelWriter writes events at or above a level that can be changed at runtime.
+type levelWriter struct {
+	io.Writer
+
+	// level is minimum level written.
+	level atomic.Int32
+
+	// parent is writer whose level is followed unless overridden, nil for the root writer.
+	parent *levelWriter
+
+	// overridden is whether level overrides the level of the parent.
+	overridden atomic.Bool
 }
 
 // Config represents configuration for logger.
 type Config struct {
 	// Level is level of logger.
 	Level *string `json:"level"`
+
+	// Format is output format of logger (console, json).
+	Format *string `json:"format"`
+
+	// Output is destination of logs (stdout, stderr, or a file path).
+	Output *string `json:"output"`
+
+	// Rotation is rotation of the log file, ignored for stdout and stderr.
+	Rotation *RotationConfig `json:"rotation"`
+
+	// Levels is minimum levels of component loggers created by Named, overriding Level (e.g. {"server": "debug"}).
+	Levels map[string]string `json:"levels"`
+
+	// Sampling is 1 of how many events are written by level (e.g. {"debug": 10}), levels not listed are not sampled.
+	Sampling map[string]uint32 `json:"sampling"`
+}
+
+// RotationConfig represents configuration for log file rotation.
+type RotationConfig struct {
+	// MaxSize is maximum size in megabytes of the log file before it is rotated.
+	MaxSize *int `json:"max_size"`
+
+	// MaxBackups is maximum number of rotated files kept, 0 to keep all.
+	MaxBackups *int `json:"max_backups"`
+
+	// MaxAge is maximum age in days of rotated files kept, 0 to keep regardless of age.
+	MaxAge *int `json:"max_age"`
+
+	// Compress is whether rotated files are compressed with gzip.
+	Compress *bool `json:"compress"`
 }
 
 const (
 	// defaultLevel is default level of logger.
 	defaultLevel = "info"
+
+	// FormatConsole is human readable output format.
+	FormatConsole = "console"
+
+	// FormatJSON is JSON output format, one event per line.
+	FormatJSON = "json"
+
+	// OutputStdout writes logs to standard output.
+	OutputStdout = "stdout"
+
+	// OutputStderr writes logs to standard error.
+	OutputStderr = "stderr"
+)
+
+var (
+	// ErrInvalidFormat returned when the output format is unknown.
+	ErrInvalidFormat = errors.New("invalid log format")
+
+	// ErrInvalidRotation returned when the log file rotation is invalid.
+	ErrInvalidRotation = errors.New("invalid log rotation")
+
+	// ErrInvalidSampling returned when the sampling of a level is invalid.
+	ErrInvalidSampling = errors.New("invalid log sampling")
 )
 
 // SetDefault sets default values.
@@ -32,6 +112,57 @@ func (c *Config) SetDefault() {
 		level := defaultLevel
 		c.Level = &level
 	}
+
+	if c.Format == nil {
+		c.Format = &[]string{FormatConsole}[0]
+	}
+
+	if c.Output == nil {
+		c.Output = &[]string{OutputStdout}[0]
+	}
+
+	if c.Rotation == nil {
+		c.Rotation = &RotationConfig{}
+	}
+
+	c.Rotation.SetDefault()
+
+	if c.Levels == nil {
+		c.Levels = map[string]string{}
+	}
+
+	if c.Sampling == nil {
+		c.Sampling = map[string]uint32{}
+	}
+}
+
+// SetDefault sets default values.
+func (c *RotationConfig) SetDefault() {
+	if c.MaxSize == nil {
+		c.MaxSize = &[]int{100}[0]
+	}
+
+	if c.MaxBackups == nil {
+		c.MaxBackups = &[]int{5}[0]
+	}
+
+	if c.MaxAge == nil {
+		c.MaxAge = &[]int{30}[0]
+	}
+
+	if c.Compress == nil {
+		c.Compress = &[]bool{false}[0]
+	}
+}
+
+// Validate validates the log file rotation.
+func (c *RotationConfig) Validate() error {
+	if *c.MaxSize <= 0 || *c.MaxBackups < 0 || *c.MaxAge < 0 {
+		return fmt.Errorf("%w: max size must be positive, max backups and max age must not be negative",
+			ErrInvalidRotation)
+	}
+
+	return nil
 }
 
 // NewModule provides module for logger.
@@ -56,13 +187,187 @@ func New(config *Config) (*Logger, error) {
 		return nil, fmt.Errorf("failed to parse log level: %w", err)
 	}
 
+	componentLevels, err := parseLevels(config.Levels)
+	if err != nil {
+		return nil, err
+	}
+
+	sampler, err := newSampler(config.Sampling)
+	if err != nil {
+		return nil, err
+	}
+
+	if *config.Format != FormatConsole && *config.Format != FormatJSON {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidFormat, *config.Format)
+	}
+
+	// set output
+	var (
+		output io.Writer
+		file   io.Closer
+	)
+
+	switch *config.Output {
+	case OutputStdout:
+		output = os.Stdout
+	case OutputStderr:
+		output = os.Stderr
+	default:
+		if err := config.Rotation.Validate(); err != nil {
+			return nil, err
+		}
+
+		// the file is opened on the first write and rotated once it exceeds the max size
+		rotated := &lumberjack.Logger{
+			Filename:   *config.Output,
+			MaxSize:    *config.Rotation.MaxSize,
+			MaxBackups: *config.Rotation.MaxBackups,
+			MaxAge:     *config.Rotation.MaxAge,
+			Compress:   *config.Rotation.Compress,
+		}
+
+		output, file = rotated, rotated
+	}
+
 	// set writer
-	writer := zerolog.ConsoleWriter{
-		Out:        os.Stdout,
-		TimeFormat: time.RFC3339Nano,
+	writer := &levelWriter{Writer: output}
+
+	if *config.Format == FormatConsole {
+		writer.Writer = zerolog.ConsoleWriter{
+			Out:        output,
+			TimeFormat: time.RFC3339Nano,
+			NoColor:    file != nil,
+		}
 	}
 
+	writer.level.Store(int32(level))
+
+	// levels are filtered by the writer so they can change at runtime
 	return &Logger{
-		Logger: zerolog.New(writer).Level(level).With().Timestamp().Logger(),
+		Logger:     zerolog.New(writer).Level(zerolog.TraceLevel).Sample(sampler).With().Timestamp().Logger(),
+		level:      writer,
+		file:       file,
+		components: newComponents(writer, componentLevels),
 	}, nil
 }
+
+// newSampler creates the sampler writing 1 of N events of the levels.
+func newSampler(sampling map[string]uint32) (zerolog.Sampler, error) {
+	sampler := zerolog.LevelSampler{}
+
+	for name, n := range sampling {
+		if n == 0 {
+			return nil, fmt.Errorf("%w: %s must sample 1 of at least 1 events", ErrInvalidSampling, name)
+		}
+
+		level, err := zerolog.ParseLevel(name)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrInvalidSampling, err)
+		}
+
+		basic := &zerolog.BasicSampler{N: n}
+
+		switch level {
+		case zerolog.TraceLevel:
+			sampler.TraceSampler = basic
+		case zerolog.DebugLevel:
+			sampler.DebugSampler = basic
+		case zerolog.InfoLevel:
+			sampler.InfoSampler = basic
+		case zerolog.WarnLevel:
+			sampler.WarnSampler = basic
+		case zerolog.ErrorLevel:
+			sampler.ErrorSampler = basic
+		default:
+			return nil, fmt.Errorf("%w: %s events are always written", ErrInvalidSampling, name)
+		}
+	}
+
+	return sampler, nil
+}
+
+// Close closes the log file, it does nothing if logs are written to stdout or stderr.
+func (l *Logger) Close() error {
+	if l.file == nil {
+		return nil
+	}
+
+	if err := l.file.Close(); err != nil {
+		return fmt.Errorf("failed to close log file: %w", err)
+	}
+
+	return nil
+}
+
+// SetLevel changes the minimum level of the logger at runtime.
+func (l *Logger) SetLevel(level string) error {
+	parsed, err := zerolog.ParseLevel(level)
+	if err != nil {
+		return fmt.Errorf("failed to parse log level: %w", err)
+	}
+
+	l.level.setLevel(parsed)
+
+	return nil
+}
+
+// GetLevel returns the minimum level of the logger.
+func (l *Logger) GetLevel() zerolog.Level {
+	return l.level.minLevel()
+}
+
+// Reload applies the level and the component levels of the reloaded configuration.
+func (l *Logger) Reload(config *Config) error {
+	if config == nil {
+		return nil
+	}
+
+	if config.Levels != nil && l.components != nil {
+		levels, err := parseLevels(config.Levels)
+		if err != nil {
+			return err
+		}
+
+		l.components.setLevels(levels)
+	}
+
+	if config.Level == nil {
+		return nil
+	}
+
+	return l.SetLevel(*config.Level)
+}
+
+// minLevel returns minimum level written, the level of the parent unless overridden.
+func (w *levelWriter) minLevel() zerolog.Level {
+	if w.parent != nil && !w.overridden.Load() {
+		return w.parent.minLevel()
+	}
+
+	return zerolog.Level(w.level.Load())
+}
+
+// setLevel sets minimum level written, overriding the level of the parent.
+func (w *levelWriter) setLevel(level zerolog.Level) {
+	w.level.Store(int32(level))
+	w.overridden.Store(true)
+}
+
+// resetLevel follows the level of the parent again.
+func (w *levelWriter) resetLevel() {
+	w.overridden.Store(false)
+}
+
+// WriteLevel writes the event if its level is at or above the minimum level.
+func (w *levelWriter) WriteLevel(level zerolog.Level, p []byte) (int, error) {
+	if level < w.minLevel() {
+		return len(p), nil
+	}
+
+	n, err := w.Write(p)
+	if err != nil {
+		return n, fmt.Errorf("failed to write log: %w", err)
+	}
+
+	return n, nil
+}
@@ -1,8 +1,14 @@
 package logger
 
 import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
 	"testing"
 
+	"github.com/rs/zerolog"
+
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -23,6 +29,15 @@ func TestConfig(t *testing.T) {
 
 		require.NotNil(t, config.Level)
 		assert.Equal(t, defaultLevel, *config.Level)
+		require.NotNil(t, config.Format)
+		assert.Equal(t, FormatConsole, *config.Format)
+		require.NotNil(t, config.Output)
+		assert.Equal(t, OutputStdout, *config.Output)
+		require.NotNil(t, config.Rotation)
+		assert.Equal(t, 100, *config.Rotation.MaxSize)
+		assert.Equal(t, 5, *config.Rotation.MaxBackups)
+		assert.Equal(t, 30, *config.Rotation.MaxAge)
+		assert.False(t, *config.Rotation.Compress)
 	})
 
 	t.Run("preserve existing values on logger config", func(t *testing.T) {
@@ -81,6 +96,84 @@ func TestNew(t *testing.T) {
 		assert.Nil(t, logger)
 		assert.Contains(t, err.Error(), "failed to parse log level")
 	})
+
+	t.Run("create logger with json format", func(t *testing.T) {
+		t.Parallel()
+
+		logger, err := New(&Config{Format: &[]string{FormatJSON}[0]})
+		require.NoError(t, err)
+		require.NotNil(t, logger)
+	})
+
+	t.Run("return error by using invalid log format", func(t *testing.T) {
+		t.Parallel()
+
+		logger, err := New(&Config{Format: &[]string{"xml"}[0]})
+		require.ErrorIs(t, err, ErrInvalidFormat)
+		assert.Nil(t, logger)
+	})
+
+	t.Run("create logger writing to stderr", func(t *testing.T) {
+		t.Parallel()
+
+		logger, err := New(&Config{Output: &[]string{OutputStderr}[0]})
+		require.NoError(t, err)
+		require.NoError(t, logger.Close())
+	})
+
+	t.Run("write json logs to file", func(t *testing.T) {
+		t.Parallel()
+
+		path := filepath.Join(t.TempDir(), "app.log")
+
+		logger, err := New(&Config{
+			Format: &[]string{FormatJSON}[0],
+			Output: &path,
+		})
+		require.NoError(t, err)
+
+		logger.Info().Str("user_id", "user-1").Msg("written to file")
+		require.NoError(t, logger.Close())
+
+		content, err := os.ReadFile(path)
+		require.NoError(t, err)
+
+		var event map[string]interface{}
+		require.NoError(t, json.Unmarshal(content, &event))
+		assert.Equal(t, "info", event["level"])
+		assert.Equal(t, "user-1", event["user_id"])
+		assert.Equal(t, "written to file", event["message"])
+	})
+
+	t.Run("write console logs to file without colors", func(t *testing.T) {
+		t.Parallel()
+
+		path := filepath.Join(t.TempDir(), "app.log")
+
+		logger, err := New(&Config{Output: &path})
+		require.NoError(t, err)
+
+		logger.Info().Msg("written to file")
+		require.NoError(t, logger.Close())
+
+		content, err := os.ReadFile(path)
+		require.NoError(t, err)
+		assert.Contains(t, string(content), "INF written to file")
+		assert.NotContains(t, string(content), "\x1b[")
+	})
+
+	t.Run("return error by using invalid rotation", func(t *testing.T) {
+		t.Parallel()
+
+		path := filepath.Join(t.TempDir(), "app.log")
+
+		logger, err := New(&Config{
+			Output:   &path,
+			Rotation: &RotationConfig{MaxSize: &[]int{0}[0]},
+		})
+		require.ErrorIs(t, err, ErrInvalidRotation)
+		assert.Nil(t, logger)
+	})
 }
 
 func TestNewWithLevels(t *testing.T) {
@@ -146,6 +239,61 @@ func TestNewWithInsensitiveLevels(t *testing.T) {
 	}
 }
 
+func TestSetLevel(t *testing.T) {
+	t.Parallel()
+
+	t.Run("change level at runtime", func(t *testing.T) {
+		t.Parallel()
+
+		logger, err := New(&Config{Level: &[]string{"info"}[0]})
+		require.NoError(t, err)
+		assert.Equal(t, zerolog.InfoLevel, logger.GetLevel())
+
+		require.NoError(t, logger.SetLevel("debug"))
+		assert.Equal(t, zerolog.DebugLevel, logger.GetLevel())
+
+		require.NoError(t, logger.Reload(&Config{Level: &[]string{"warn"}[0]}))
+		assert.Equal(t, zerolog.WarnLevel, logger.GetLevel())
+	})
+
+	t.Run("keep level for invalid level", func(t *testing.T) {
+		t.Parallel()
+
+		logger, err := New(&Config{Level: &[]string{"info"}[0]})
+		require.NoError(t, err)
+
+		err = logger.Reload(&Config{Level: &[]string{"invalid"}[0]})
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "failed to parse log level")
+		assert.Equal(t, zerolog.InfoLevel, logger.GetLevel())
+
+		require.NoError(t, logger.Reload(nil))
+		assert.Equal(t, zerolog.InfoLevel, logger.GetLevel())
+	})
+
+	t.Run("write events at or above level", func(t *testing.T) {
+		t.Parallel()
+
+		var buffer bytes.Buffer
+
+		writer := &levelWriter{Writer: &buffer}
+		writer.level.Store(int32(zerolog.WarnLevel))
+
+		log := zerolog.New(writer)
+
+		log.Info().Msg("dropped")
+		assert.Empty(t, buffer.String())
+
+		log.Warn().Msg("written")
+		assert.Contains(t, buffer.String(), "written")
+
+		writer.level.Store(int32(zerolog.DebugLevel))
+
+		log.Debug().Msg("debug written")
+		assert.Contains(t, buffer.String(), "debug written")
+	})
+}
+
 func TestNewModule(t *testing.T) {
 	t.Parallel()
 
@@ -0,0 +1,357 @@
+// Package metering provides billable event recording, events are batched in memory and written to a sink
+// which delivers each event once by its ID, so that retried batches are not billed twice.
+package metering
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"go.uber.org/fx"
+
+	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/database"
+	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/logger"
+	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/readonly"
+	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/redis"
+)
+
+const (
+	// EventAPICall is type of events of billable API calls.
+	EventAPICall = "api_call"
+
+	// EventStorageBytes is type of events of stored bytes.
+	EventStorageBytes = "storage_bytes"
+
+	// EventJobExecution is type of events of executed jobs.
+	EventJobExecution = "job_execution"
+
+	// SinkDatabase writes events to the metering_events table.
+	SinkDatabase = "database"
+
+	// SinkRedis publishes events to a redis stream.
+	SinkRedis = "redis"
+)
+
+const (
+	// defaultBatchSize is default maximum number of events written at once.
+	defaultBatchSize = 100
+
+	// defaultFlushInterval is default interval of writing recorded events.
+	defaultFlushInterval = 10 * time.Second
+
+	// defaultMaxPending is default maximum number of events waiting to be written.
+	defaultMaxPending = 10000
+
+	// idLength is length of generated event IDs in bytes.
+	idLength = 16
+)
+
+var (
+	// ErrInvalidEvent is returned when an event misses its type or tenant, or has a negative quantity.
+	ErrInvalidEvent = errors.New("invalid metering event")
+
+	// ErrBufferFull is returned when an event is recorded while max pending events wait to be written.
+	ErrBufferFull = errors.New("metering buffer full")
+
+	// ErrUnknownSink is returned when the configured sink is not supported.
+	ErrUnknownSink = errors.New("unknown metering sink")
+)
+
+// Config represents configuration for metering.
+type Config struct {
+	// Enabled is whether billable events are recorded.
+	Enabled *bool `json:"enabled"`
+
+	// Sink is where events are written, database or redis.
+	Sink *string `json:"sink"`
+
+	// BatchSize is maximum number of events written at once, a full batch is written without waiting.
+	BatchSize *int `json:"batch_size"`
+
+	// FlushInterval is interval of writing recorded events.
+	FlushInterval *time.Duration `json:"flush_interval"`
+
+	// MaxPending is maximum number of events waiting to be written, recording fails beyond it.
+	MaxPending *int `json:"max_pending"`
+
+	// Redis provides configuration of the redis sink.
+	Redis *RedisSinkConfig `json:"redis"`
+}
+
+// SetDefault sets default values.
+func (c *Config) SetDefault() {
+	if c.Enabled == nil {
+		c.Enabled = &[]bool{false}[0]
+	}
+
+	if c.Sink == nil {
+		c.Sink = &[]string{SinkDatabase}[0]
+	}
+
+	if c.BatchSize == nil {
+		c.BatchSize = &[]int{defaultBatchSize}[0]
+	}
+
+	if c.FlushInterval == nil {
+		c.FlushInterval = &[]time.Duration{defaultFlushInterval}[0]
+	}
+
+	if c.MaxPending == nil {
+		c.MaxPending = &[]int{defaultMaxPending}[0]
+	}
+
+	if c.Redis == nil {
+		c.Redis = &RedisSinkConfig{}
+	}
+
+	c.Redis.SetDefault()
+}
+
+// Event represents a billable event.
+type Event struct {
+	// ID is idempotency key of the event, generated if empty, events with the same ID are delivered once.
+	ID string `json:"id"`
+
+	// TenantID is ID of the billed tenant.
+	TenantID string `json:"tenant_id"`
+
+	// Type is type of the event, e.g. EventAPICall.
+	Type string `json:"type"`
+
+	// Quantity is billed quantity, e.g. 1 call or stored bytes.
+	Quantity int64 `json:"quantity"`
+
+	// Attributes is additional attributes of the event, e.g. the route or job name.
+	Attributes map[string]string `json:"attributes,omitempty"`
+
+	// OccurredAt is time of the event, set to the recording time if zero.
+	OccurredAt time.Time `json:"occurred_at"`
+}
+
+// Meter records billable events.
+type Meter struct {
+	// config provides metering configuration.
+	config *Config
+
+	// sink provides delivery of events.
+	sink Sink
+
+	// readOnly provides read-only mode, events are kept in memory while it is on.
+	readOnly *readonly.ReadOnly
+
+	// logger provides logger.
+	logger *logger.Logger
+
+	// mu guards pending.
+	mu sync.Mutex
+
+	// pending is events waiting to be written, in order of recording.
+	pending []*Event
+
+	// flushMu serializes flushes.
+	flushMu sync.Mutex
+
+	// full signals the flush loop that a batch is full.
+	full chan struct{}
+
+	// stop stops the flush loop.
+	stop chan struct{}
+
+	// done is closed when the flush loop exits.
+	done chan struct{}
+
+	// now returns the current time, replaced in tests.
+	now func() time.Time
+}
+
+// NewModule provides module for metering.
+func NewModule() fx.Option {
+	return fx.Module("metering",
+		fx.Provide(New),
+	)
+}
+
+// New creates a new meter writing events to the configured sink.
+func New(
+	config *Config,
+	dbConn *database.DB,
+	redisConn *redis.Redis,
+	readOnly *readonly.ReadOnly,
+	logger *logger.Logger,
+) (*Meter, error) {
+	if config == nil {
+		config = &Config{}
+	}
+
+	config.SetDefault()
+
+	var sink Sink
+
+	switch *config.Sink {
+	case SinkDatabase:
+		sink = NewDatabaseSink(dbConn.Queries)
+	case SinkRedis:
+		sink = NewRedisSink(config.Redis, redisConn)
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnknownSink, *config.Sink)
+	}
+
+	return NewWithSink(config, sink, readOnly, logger), nil
+}
+
+// NewWithSink creates a new meter writing events to the sink.
+func NewWithSink(config *Config, sink Sink, readOnly *readonly.ReadOnly, logger *logger.Logger) *Meter {
+	if config == nil {
+		config = &Config{}
+	}
+
+	config.SetDefault()
+
+	return &Meter{
+		config:   config,
+		sink:     sink,
+		readOnly: readOnly,
+		logger:   logger.Named("metering"),
+		full:     make(chan struct{}, 1),
+		now:      time.Now,
+	}
+}
+
+// Enabled returns whether billable events are recorded.
+func (m *Meter) Enabled() bool {
+	return m != nil && *m.config.Enabled
+}
+
+// Record records the event to be written with the next batch, it does nothing if the meter is disabled.
+// The event is copied, an empty ID and a zero OccurredAt are filled in.
+func (m *Meter) Record(event *Event) error {
+	if !m.Enabled() {
+		return nil
+	}
+
+	if event.Type == "" || event.TenantID == "" || event.Quantity < 0 {
+		return fmt.Errorf("%w: type %q of tenant %q with quantity %d",
+			ErrInvalidEvent, event.Type, event.TenantID, event.Quantity)
+	}
+
+	recorded := *event
+
+	if recorded.ID == "" {
+		id := make([]byte, idLength)
+		if _, err := rand.Read(id); err != nil {
+			return fmt.Errorf("failed to generate event id: %w", err)
+		}
+
+		recorded.ID = hex.EncodeToString(id)
+	}
+
+	if recorded.OccurredAt.IsZero() {
+		recorded.OccurredAt = m.now()
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if len(m.pending) >= *m.config.MaxPending {
+		return ErrBufferFull
+	}
+
+	m.pending = append(m.pending, &recorded)
+
+	// wake the flush loop without blocking, a signal is already pending otherwise
+	if len(m.pending) >= *m.config.BatchSize {
+		select {
+		case m.full <- struct{}{}:
+		default:
+		}
+	}
+
+	return nil
+}
+
+// Flush writes the pending events in batches, a batch failing to be written is kept for the next flush
+// and written again, the sink skips events of the batch it already delivered.
+// Events are kept in memory while read-only mode is on.
+func (m *Meter) Flush(ctx context.Context) error {
+	m.flushMu.Lock()
+	defer m.flushMu.Unlock()
+
+	if m.readOnly != nil && m.readOnly.Enabled(ctx) {
+		return nil
+	}
+
+	for {
+		m.mu.Lock()
+		batch := m.pending[:min(len(m.pending), *m.config.BatchSize)]
+		m.mu.Unlock()
+
+		if len(batch) == 0 {
+			return nil
+		}
+
+		if err := m.sink.Write(ctx, batch); err != nil {
+			return fmt.Errorf("failed to write metering events: %w", err)
+		}
+
+		// events are only appended while flushing, so the batch is still at the front
+		m.mu.Lock()
+		m.pending = m.pending[len(batch):]
+		m.mu.Unlock()
+	}
+}
+
+// Pending returns number of events waiting to be written.
+func (m *Meter) Pending() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return len(m.pending)
+}
+
+// Start starts writing events every flush interval and whenever a batch is full,
+// it does nothing if the meter is disabled.
+func (m *Meter) Start() {
+	if !m.Enabled() || m.stop != nil {
+		return
+	}
+
+	m.stop, m.done = make(chan struct{}), make(chan struct{})
+
+	go func() {
+		defer close(m.done)
+
+		ticker := time.NewTicker(*m.config.FlushInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-m.stop:
+				return
+			case <-ticker.C:
+			case <-m.full:
+			}
+
+			if err := m.Flush(context.Background()); err != nil {
+				m.logger.Error().Err(err).Int("pending", m.Pending()).Msg("failed to flush metering events")
+			}
+		}
+	}()
+}
+
+// Stop stops writing events and writes the pending events.
+func (m *Meter) Stop(ctx context.Context) error {
+	if m.stop == nil {
+		return nil
+	}
+
+	close(m.stop)
+	<-m.done
+
+	m.stop = nil
+
+	return m.Flush(ctx)
+}
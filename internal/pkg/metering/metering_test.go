@@ -0,0 +1,259 @@
+package metering
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/logger"
+	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/readonly"
+)
+
+var errWriteFailed = errors.New("write failed")
+
+// mockSink is a mock sink keeping delivered events by ID.
+type mockSink struct {
+	mu        sync.Mutex
+	delivered map[string]*Event
+	writes    int
+	err       error
+}
+
+func newMockSink() *mockSink {
+	return &mockSink{delivered: make(map[string]*Event)}
+}
+
+func (m *mockSink) Write(_ context.Context, events []*Event) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.writes++
+
+	if m.err != nil {
+		return m.err
+	}
+
+	for _, event := range events {
+		m.delivered[event.ID] = event
+	}
+
+	return nil
+}
+
+func (m *mockSink) setErr(err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.err = err
+}
+
+func (m *mockSink) count() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return len(m.delivered)
+}
+
+// setupTestMeter creates an enabled meter writing events to the sink.
+func setupTestMeter(t *testing.T, config *Config, sink Sink, readOnly *readonly.ReadOnly) *Meter {
+	t.Helper()
+
+	log, err := logger.New(&logger.Config{})
+	require.NoError(t, err)
+
+	if config == nil {
+		config = &Config{}
+	}
+
+	if config.Enabled == nil {
+		config.Enabled = &[]bool{true}[0]
+	}
+
+	meter := NewWithSink(config, sink, readOnly, log)
+	meter.now = func() time.Time { return time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC) }
+
+	return meter
+}
+
+func TestConfigSetDefault(t *testing.T) {
+	t.Parallel()
+
+	config := &Config{}
+	config.SetDefault()
+
+	assert.False(t, *config.Enabled)
+	assert.Equal(t, SinkDatabase, *config.Sink)
+	assert.Equal(t, defaultBatchSize, *config.BatchSize)
+	assert.Equal(t, defaultFlushInterval, *config.FlushInterval)
+	assert.Equal(t, defaultMaxPending, *config.MaxPending)
+	assert.Equal(t, defaultStream, *config.Redis.Stream)
+}
+
+func TestNew(t *testing.T) {
+	t.Parallel()
+
+	t.Run("return fx.Option", func(t *testing.T) {
+		t.Parallel()
+
+		require.NotNil(t, NewModule())
+	})
+
+	t.Run("return error for unknown sink", func(t *testing.T) {
+		t.Parallel()
+
+		log, err := logger.New(&logger.Config{})
+		require.NoError(t, err)
+
+		_, err = New(&Config{Sink: &[]string{"kafka"}[0]}, nil, nil, nil, log)
+		require.ErrorIs(t, err, ErrUnknownSink)
+	})
+
+	t.Run("create meter with redis sink", func(t *testing.T) {
+		t.Parallel()
+
+		log, err := logger.New(&logger.Config{})
+		require.NoError(t, err)
+
+		meter, err := New(&Config{Sink: &[]string{SinkRedis}[0]}, nil, setupTestRedis(t), nil, log)
+		require.NoError(t, err)
+		assert.IsType(t, &RedisSink{}, meter.sink)
+	})
+}
+
+func TestRecord(t *testing.T) {
+	t.Parallel()
+
+	t.Run("fill in id and time of recorded event", func(t *testing.T) {
+		t.Parallel()
+
+		sink := newMockSink()
+		meter := setupTestMeter(t, nil, sink, nil)
+
+		event := &Event{TenantID: "tenant-a", Type: EventAPICall, Quantity: 1}
+		require.NoError(t, meter.Record(event))
+		require.NoError(t, meter.Flush(context.Background()))
+
+		require.Equal(t, 1, sink.count())
+
+		for id, delivered := range sink.delivered {
+			assert.Len(t, id, idLength*2)
+			assert.Equal(t, time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC), delivered.OccurredAt)
+		}
+
+		assert.Empty(t, event.ID, "recorded event is copied")
+	})
+
+	t.Run("reject invalid event", func(t *testing.T) {
+		t.Parallel()
+
+		meter := setupTestMeter(t, nil, newMockSink(), nil)
+
+		require.ErrorIs(t, meter.Record(&Event{Type: EventAPICall, Quantity: 1}), ErrInvalidEvent)
+		require.ErrorIs(t, meter.Record(&Event{TenantID: "tenant-a", Quantity: 1}), ErrInvalidEvent)
+		require.ErrorIs(t, meter.Record(&Event{TenantID: "tenant-a", Type: EventAPICall, Quantity: -1}), ErrInvalidEvent)
+	})
+
+	t.Run("return error when buffer is full", func(t *testing.T) {
+		t.Parallel()
+
+		meter := setupTestMeter(t, &Config{MaxPending: &[]int{1}[0]}, newMockSink(), nil)
+
+		require.NoError(t, meter.Record(&Event{TenantID: "tenant-a", Type: EventAPICall, Quantity: 1}))
+		require.ErrorIs(t, meter.Record(&Event{TenantID: "tenant-a", Type: EventAPICall, Quantity: 1}), ErrBufferFull)
+	})
+
+	t.Run("record nothing while disabled", func(t *testing.T) {
+		t.Parallel()
+
+		meter := setupTestMeter(t, &Config{Enabled: &[]bool{false}[0]}, newMockSink(), nil)
+
+		require.NoError(t, meter.Record(&Event{}))
+		assert.Zero(t, meter.Pending())
+
+		var nilMeter *Meter
+		require.NoError(t, nilMeter.Record(&Event{}))
+		assert.False(t, nilMeter.Enabled())
+	})
+}
+
+func TestFlush(t *testing.T) {
+	t.Parallel()
+
+	t.Run("write events in batches", func(t *testing.T) {
+		t.Parallel()
+
+		sink := newMockSink()
+		meter := setupTestMeter(t, &Config{BatchSize: &[]int{2}[0]}, sink, nil)
+
+		for range 5 {
+			require.NoError(t, meter.Record(&Event{TenantID: "tenant-a", Type: EventJobExecution, Quantity: 1}))
+		}
+
+		require.NoError(t, meter.Flush(context.Background()))
+		assert.Equal(t, 5, sink.count())
+		assert.Equal(t, 3, sink.writes)
+		assert.Zero(t, meter.Pending())
+	})
+
+	t.Run("keep failed batch for next flush", func(t *testing.T) {
+		t.Parallel()
+
+		sink := newMockSink()
+		sink.setErr(errWriteFailed)
+		meter := setupTestMeter(t, nil, sink, nil)
+
+		require.NoError(t, meter.Record(&Event{ID: "event-1", TenantID: "tenant-a", Type: EventAPICall, Quantity: 1}))
+		require.ErrorIs(t, meter.Flush(context.Background()), errWriteFailed)
+		assert.Equal(t, 1, meter.Pending())
+
+		sink.setErr(nil)
+
+		require.NoError(t, meter.Flush(context.Background()))
+		assert.Contains(t, sink.delivered, "event-1")
+		assert.Zero(t, meter.Pending())
+	})
+
+	t.Run("keep events in memory while read-only", func(t *testing.T) {
+		t.Parallel()
+
+		readOnly := readonly.New(&readonly.Config{}, nil)
+		require.NoError(t, readOnly.Set(context.Background(), true))
+
+		sink := newMockSink()
+		meter := setupTestMeter(t, nil, sink, readOnly)
+
+		require.NoError(t, meter.Record(&Event{TenantID: "tenant-a", Type: EventStorageBytes, Quantity: 1024}))
+		require.NoError(t, meter.Flush(context.Background()))
+		assert.Zero(t, sink.count())
+
+		require.NoError(t, readOnly.Set(context.Background(), false))
+		require.NoError(t, meter.Flush(context.Background()))
+		assert.Equal(t, 1, sink.count())
+	})
+
+	t.Run("write full batches and on stop", func(t *testing.T) {
+		t.Parallel()
+
+		sink := newMockSink()
+		meter := setupTestMeter(t, &Config{BatchSize: &[]int{2}[0]}, sink, nil)
+
+		meter.Start()
+
+		require.NoError(t, meter.Record(&Event{TenantID: "tenant-a", Type: EventAPICall, Quantity: 1}))
+		require.NoError(t, meter.Record(&Event{TenantID: "tenant-a", Type: EventAPICall, Quantity: 1}))
+
+		// the full batch is written before the flush interval
+		require.Eventually(t, func() bool {
+			return sink.count() == 2
+		}, time.Second, 5*time.Millisecond)
+
+		require.NoError(t, meter.Record(&Event{TenantID: "tenant-a", Type: EventAPICall, Quantity: 1}))
+		require.NoError(t, meter.Stop(context.Background()))
+		assert.Equal(t, 3, sink.count())
+	})
+}
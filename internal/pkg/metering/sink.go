@@ -0,0 +1,169 @@
+package metering
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	goredis "github.com/redis/go-redis/v9"
+
+	"github.com/pocj8ur4in/boilerplate-go/internal/gen/db"
+	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/redis"
+)
+
+const (
+	// defaultStream is default redis stream events are published to.
+	defaultStream = "metering:events"
+
+	// defaultStreamMaxLen is default approximate maximum length of the redis stream.
+	defaultStreamMaxLen = 100000
+
+	// defaultDedupTTL is default duration delivered event IDs are remembered by the redis sink.
+	defaultDedupTTL = 24 * time.Hour
+
+	// dedupKeyPrefix is prefix of redis keys remembering delivered event IDs.
+	dedupKeyPrefix = "metering:delivered:"
+)
+
+// publishScript publishes an event to the stream unless its ID was delivered within the dedup TTL,
+// remembering the ID and adding the entry atomically.
+var publishScript = goredis.NewScript(`
+if redis.call("SET", KEYS[1], "1", "NX", "PX", ARGV[1]) then
+	return redis.call("XADD", KEYS[2], "MAXLEN", "~", ARGV[2], "*", unpack(ARGV, 3))
+end
+return false
+`)
+
+// Sink delivers events, writing an event already delivered must not deliver it again.
+type Sink interface {
+	// Write delivers the events.
+	Write(ctx context.Context, events []*Event) error
+}
+
+// DatabaseSink writes events to the metering_events table, skipping IDs already written.
+type DatabaseSink struct {
+	// queries provides database queries.
+	queries db.Querier
+}
+
+// NewDatabaseSink creates a new sink writing events using the querier.
+func NewDatabaseSink(queries db.Querier) *DatabaseSink {
+	return &DatabaseSink{queries: queries}
+}
+
+// Write writes the events.
+func (s *DatabaseSink) Write(ctx context.Context, events []*Event) error {
+	for _, event := range events {
+		attributes, err := encodeAttributes(event)
+		if err != nil {
+			return err
+		}
+
+		if _, err := s.queries.InsertMeteringEvent(ctx, &db.InsertMeteringEventParams{
+			ID:         event.ID,
+			TenantID:   event.TenantID,
+			Type:       event.Type,
+			Quantity:   event.Quantity,
+			Attributes: attributes,
+			OccurredAt: pgtype.Timestamptz{Time: event.OccurredAt, Valid: true},
+		}); err != nil {
+			return fmt.Errorf("failed to insert event %s: %w", event.ID, err)
+		}
+	}
+
+	return nil
+}
+
+// RedisSinkConfig represents configuration for the redis sink.
+type RedisSinkConfig struct {
+	// Stream is redis stream events are published to.
+	Stream *string `json:"stream"`
+
+	// MaxLen is approximate maximum length of the stream, older entries are trimmed.
+	MaxLen *int64 `json:"max_len"`
+
+	// DedupTTL is duration delivered event IDs are remembered, events retried later are published again.
+	DedupTTL *time.Duration `json:"dedup_ttl"`
+}
+
+// SetDefault sets default values.
+func (c *RedisSinkConfig) SetDefault() {
+	if c.Stream == nil {
+		c.Stream = &[]string{defaultStream}[0]
+	}
+
+	if c.MaxLen == nil {
+		c.MaxLen = &[]int64{defaultStreamMaxLen}[0]
+	}
+
+	if c.DedupTTL == nil {
+		c.DedupTTL = &[]time.Duration{defaultDedupTTL}[0]
+	}
+}
+
+// RedisSink publishes events to a redis stream for consumers such as billing exporters,
+// skipping IDs delivered within the dedup TTL.
+type RedisSink struct {
+	// config provides redis sink configuration.
+	config *RedisSinkConfig
+
+	// redis provides redis client.
+	redis *redis.Redis
+}
+
+// NewRedisSink creates a new sink publishing events to the redis stream.
+func NewRedisSink(config *RedisSinkConfig, redisConn *redis.Redis) *RedisSink {
+	if config == nil {
+		config = &RedisSinkConfig{}
+	}
+
+	config.SetDefault()
+
+	return &RedisSink{config: config, redis: redisConn}
+}
+
+// Write publishes the events.
+func (s *RedisSink) Write(ctx context.Context, events []*Event) error {
+	for _, event := range events {
+		attributes, err := encodeAttributes(event)
+		if err != nil {
+			return err
+		}
+
+		err = publishScript.Run(ctx, s.redis,
+			[]string{dedupKeyPrefix + event.ID, *s.config.Stream},
+			s.config.DedupTTL.Milliseconds(),
+			*s.config.MaxLen,
+			"id", event.ID,
+			"tenant_id", event.TenantID,
+			"type", event.Type,
+			"quantity", strconv.FormatInt(event.Quantity, 10),
+			"attributes", string(attributes),
+			"occurred_at", event.OccurredAt.UTC().Format(time.RFC3339Nano),
+		).Err()
+		if err != nil && !errors.Is(err, goredis.Nil) {
+			return fmt.Errorf("failed to publish event %s: %w", event.ID, err)
+		}
+	}
+
+	return nil
+}
+
+// encodeAttributes encodes attributes of the event as a JSON object.
+func encodeAttributes(event *Event) ([]byte, error) {
+	// a nil map is encoded as null, consumers get an object
+	if event.Attributes == nil {
+		return []byte("{}"), nil
+	}
+
+	attributes, err := json.Marshal(event.Attributes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode attributes of event %s: %w", event.ID, err)
+	}
+
+	return attributes, nil
+}
@@ -0,0 +1,146 @@
+package metering
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pocj8ur4in/boilerplate-go/internal/gen/db"
+	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/redis"
+)
+
+var errQueryFailed = errors.New("query failed")
+
+// mockEventQuerier is a mock querier inserting events in memory.
+type mockEventQuerier struct {
+	db.Querier
+
+	inserted map[string]*db.InsertMeteringEventParams
+	err      error
+}
+
+func (m *mockEventQuerier) InsertMeteringEvent(_ context.Context, arg *db.InsertMeteringEventParams) (int64, error) {
+	if m.err != nil {
+		return 0, m.err
+	}
+
+	if _, ok := m.inserted[arg.ID]; ok {
+		return 0, nil
+	}
+
+	m.inserted[arg.ID] = arg
+
+	return 1, nil
+}
+
+// setupTestRedis creates a redis client of the test redis server.
+func setupTestRedis(t *testing.T) *redis.Redis {
+	t.Helper()
+
+	password := ""
+	redisDB := 0
+
+	redisClient, err := redis.New(&redis.Config{
+		Addrs:    []string{"localhost:36379"},
+		Password: &password,
+		DB:       &redisDB,
+	})
+	require.NoError(t, err)
+
+	t.Cleanup(func() {
+		_ = redisClient.Close()
+	})
+
+	return redisClient
+}
+
+// testEvents returns events of the test tenant.
+func testEvents() []*Event {
+	occurredAt := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	return []*Event{
+		{ID: "event-1", TenantID: "tenant-a", Type: EventAPICall, Quantity: 1, OccurredAt: occurredAt},
+		{
+			ID: "event-2", TenantID: "tenant-a", Type: EventJobExecution, Quantity: 1, OccurredAt: occurredAt,
+			Attributes: map[string]string{"job": "export"},
+		},
+	}
+}
+
+func TestDatabaseSink(t *testing.T) {
+	t.Parallel()
+
+	t.Run("insert events once", func(t *testing.T) {
+		t.Parallel()
+
+		querier := &mockEventQuerier{inserted: make(map[string]*db.InsertMeteringEventParams)}
+		sink := NewDatabaseSink(querier)
+
+		require.NoError(t, sink.Write(context.Background(), testEvents()))
+		require.NoError(t, sink.Write(context.Background(), testEvents()))
+
+		require.Len(t, querier.inserted, 2)
+		assert.JSONEq(t, `{}`, string(querier.inserted["event-1"].Attributes))
+		assert.JSONEq(t, `{"job": "export"}`, string(querier.inserted["event-2"].Attributes))
+		assert.True(t, querier.inserted["event-1"].OccurredAt.Valid)
+	})
+
+	t.Run("return error if insert fails", func(t *testing.T) {
+		t.Parallel()
+
+		sink := NewDatabaseSink(&mockEventQuerier{err: errQueryFailed})
+
+		require.ErrorIs(t, sink.Write(context.Background(), testEvents()), errQueryFailed)
+	})
+}
+
+func TestRedisSink(t *testing.T) {
+	t.Parallel()
+
+	t.Run("publish events once", func(t *testing.T) {
+		t.Parallel()
+
+		redisClient := setupTestRedis(t)
+		stream := "metering:test:" + t.Name()
+		events := testEvents()
+
+		// event IDs are shared by tests, so they are scoped to this test
+		for _, event := range events {
+			event.ID = t.Name() + ":" + event.ID
+		}
+
+		t.Cleanup(func() {
+			keys := []string{stream}
+			for _, event := range events {
+				keys = append(keys, dedupKeyPrefix+event.ID)
+			}
+
+			_ = redisClient.Del(context.Background(), keys...).Err()
+		})
+
+		sink := NewRedisSink(&RedisSinkConfig{Stream: &stream}, redisClient)
+
+		require.NoError(t, sink.Write(context.Background(), events))
+		require.NoError(t, sink.Write(context.Background(), events))
+
+		entries, err := redisClient.XRange(context.Background(), stream, "-", "+").Result()
+		require.NoError(t, err)
+		require.Len(t, entries, 2)
+
+		assert.Equal(t, events[0].ID, entries[0].Values["id"])
+		assert.Equal(t, "tenant-a", entries[0].Values["tenant_id"])
+		assert.Equal(t, EventAPICall, entries[0].Values["type"])
+		assert.Equal(t, "1", entries[0].Values["quantity"])
+		assert.Equal(t, "{}", entries[0].Values["attributes"])
+		assert.Equal(t, "2024-01-02T03:04:05Z", entries[0].Values["occurred_at"])
+		assert.Equal(t, `{"job":"export"}`, entries[1].Values["attributes"])
+
+		ttl, err := redisClient.PTTL(context.Background(), dedupKeyPrefix+events[0].ID).Result()
+		require.NoError(t, err)
+		assert.Positive(t, ttl)
+	})
+}
@@ -0,0 +1,230 @@
+// Package migrations provides migrations of the database schema, goose formatted SQL files applied by goose in
+// order of their version and recorded on its goose_db_version table. Migrations run under an advisory lock,
+// so instances starting together apply them once.
+package migrations
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io/fs"
+	"math"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/stdlib"
+	"github.com/pressly/goose/v3"
+	"github.com/pressly/goose/v3/lock"
+	"go.uber.org/fx"
+
+	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/database"
+	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/logger"
+	"github.com/pocj8ur4in/boilerplate-go/sql/schema"
+)
+
+// ErrNoMigration returned when there is no applied migration to roll back.
+var ErrNoMigration = errors.New("no migration to roll back")
+
+const (
+	// lockID is key of the advisory lock held while migrating.
+	lockID int64 = 7_263_513_046_912
+
+	// lockRetryInterval is interval of retrying the advisory lock held by another migrator, the minimum of goose.
+	lockRetryInterval = time.Second
+)
+
+// Migration represents a migration of the database schema.
+type Migration struct {
+	// Version is version of the migration, the numeric prefix of its file name.
+	Version int64
+
+	// Name is name of the migration, its file name without version and extension.
+	Name string
+}
+
+// Status represents status of a migration.
+type Status struct {
+	// Migration is the migration.
+	Migration *Migration
+
+	// AppliedAt is time the migration was applied, nil if it is pending.
+	AppliedAt *time.Time
+}
+
+// Migrator applies migrations to database.
+type Migrator struct {
+	// provider applies the migrations.
+	provider *goose.Provider
+
+	// logger provides logger.
+	logger *logger.Logger
+}
+
+// NewModule provides module applying migrations on start if database.Config.AutoMigrate is set.
+func NewModule() fx.Option {
+	return fx.Module("migrations",
+		fx.Invoke(autoMigrate),
+	)
+}
+
+// autoMigrate applies pending migrations if database.Config.AutoMigrate is set.
+func autoMigrate(config *database.Config, logger *logger.Logger) error {
+	if config == nil || config.AutoMigrate == nil || !*config.AutoMigrate {
+		return nil
+	}
+
+	db, err := Open(config)
+	if err != nil {
+		return err
+	}
+
+	defer func() { _ = db.Close() }()
+
+	migrator, err := New(db, schema.FS, *config.Migrations.LockTimeout, logger)
+	if err != nil {
+		return err
+	}
+
+	if _, err := migrator.Up(context.Background()); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// Open opens the connection of database for running migrations, using database.Config.Migrations credentials.
+func Open(config *database.Config) (*sql.DB, error) {
+	connString, err := config.MigrationConnString()
+	if err != nil {
+		return nil, fmt.Errorf("failed to build migration connection string: %w", err)
+	}
+
+	connConfig, err := pgx.ParseConfig(connString)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse migration connection config: %w", err)
+	}
+
+	return stdlib.OpenDB(*connConfig), nil
+}
+
+// New creates a migrator applying the migrations of the file system, waiting at most the lock timeout, rounded
+// up to seconds, for other migrators to finish.
+func New(db *sql.DB, fsys fs.FS, lockTimeout time.Duration, logger *logger.Logger) (*Migrator, error) {
+	return newMigrator(db, fsys, lockTimeout, logger, goose.DialectPostgres)
+}
+
+// newMigrator creates a migrator of the dialect, tests replace the postgres version table with options.
+func newMigrator(
+	db *sql.DB,
+	fsys fs.FS,
+	lockTimeout time.Duration,
+	logger *logger.Logger,
+	dialect goose.Dialect,
+	options ...goose.ProviderOption,
+) (*Migrator, error) {
+	// goose retries the lock a number of times, waiting indefinitely is retrying without limit
+	retries := uint64(math.MaxUint64)
+	if lockTimeout > 0 {
+		retries = uint64(max((lockTimeout+lockRetryInterval-1)/lockRetryInterval, 1))
+	}
+
+	locker, err := lock.NewPostgresSessionLocker(
+		lock.WithLockID(lockID),
+		lock.WithLockTimeout(uint64(lockRetryInterval/time.Second), retries),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create migration lock: %w", err)
+	}
+
+	provider, err := goose.NewProvider(dialect, db, fsys, append([]goose.ProviderOption{
+		goose.WithSessionLocker(locker),
+		goose.WithDisableGlobalRegistry(true),
+	}, options...)...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load migrations: %w", err)
+	}
+
+	return &Migrator{
+		provider: provider,
+		logger:   logger.Named("migrations"),
+	}, nil
+}
+
+// Up applies pending migrations in order of their version and returns them, with the migrations applied before
+// a failed one.
+func (m *Migrator) Up(ctx context.Context) ([]*Migration, error) {
+	results, err := m.provider.Up(ctx)
+
+	var partial *goose.PartialError
+	if errors.As(err, &partial) {
+		results = partial.Applied
+	}
+
+	applied := make([]*Migration, 0, len(results))
+
+	for _, result := range results {
+		migration := fromSource(result.Source)
+
+		m.logger.Ctx(ctx).Info().Int64("version", migration.Version).Str("name", migration.Name).
+			Msg("migration applied")
+
+		applied = append(applied, migration)
+	}
+
+	if err != nil {
+		return applied, fmt.Errorf("failed to apply migrations: %w", err)
+	}
+
+	return applied, nil
+}
+
+// Down rolls the latest applied migration back and returns it.
+func (m *Migrator) Down(ctx context.Context) (*Migration, error) {
+	result, err := m.provider.Down(ctx)
+
+	switch {
+	case errors.Is(err, goose.ErrNoNextVersion):
+		return nil, ErrNoMigration
+	case err != nil:
+		return nil, fmt.Errorf("failed to roll migration back: %w", err)
+	}
+
+	migration := fromSource(result.Source)
+
+	m.logger.Ctx(ctx).Info().Int64("version", migration.Version).Str("name", migration.Name).
+		Msg("migration rolled back")
+
+	return migration, nil
+}
+
+// Status returns status of the migrations in order of their version.
+func (m *Migrator) Status(ctx context.Context) ([]*Status, error) {
+	results, err := m.provider.Status(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get migration status: %w", err)
+	}
+
+	statuses := make([]*Status, 0, len(results))
+
+	for _, result := range results {
+		status := &Status{Migration: fromSource(result.Source)}
+		if result.State == goose.StateApplied {
+			status.AppliedAt = &result.AppliedAt
+		}
+
+		statuses = append(statuses, status)
+	}
+
+	return statuses, nil
+}
+
+// fromSource returns the migration of the goose source.
+func fromSource(source *goose.Source) *Migration {
+	name := strings.TrimSuffix(path.Base(source.Path), path.Ext(source.Path))
+	_, name, _ = strings.Cut(name, "_")
+
+	return &Migration{Version: source.Version, Name: name}
+}
@@ -0,0 +1,455 @@
+package migrations
+
+import (
+	"cmp"
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"io/fs"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/pressly/goose/v3"
+	gooseDatabase "github.com/pressly/goose/v3/database"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/database"
+	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/logger"
+	"github.com/pocj8ur4in/boilerplate-go/sql/schema"
+)
+
+var (
+	errStatementFailed = errors.New("statement failed")
+	errNotSupported    = errors.New("not supported")
+)
+
+// fakeDatabase is an in-memory database serving statements of the migrator, with the version table kept by
+// fakeStore.
+type fakeDatabase struct {
+	mu       sync.Mutex
+	applied  map[int64]time.Time
+	executed []string
+	locks    int
+	unlocks  int
+	failOn   string
+
+	// heldFor is number of lock attempts failing since another migrator holds the lock, -1 for all.
+	heldFor int
+}
+
+// newFakeDB opens a connection pool of the fake database.
+func newFakeDB(t *testing.T, database *fakeDatabase) *sql.DB {
+	t.Helper()
+
+	if database.applied == nil {
+		database.applied = make(map[int64]time.Time)
+	}
+
+	db := sql.OpenDB(&fakeConnector{database: database})
+	t.Cleanup(func() { _ = db.Close() })
+
+	return db
+}
+
+// statements returns the migration statements run on the fake database.
+func (d *fakeDatabase) statements() []string {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	return append([]string(nil), d.executed...)
+}
+
+// fakeStore is the version table of the fake database.
+type fakeStore struct {
+	database *fakeDatabase
+}
+
+func (s *fakeStore) Tablename() string {
+	return "goose_db_version"
+}
+
+func (s *fakeStore) CreateVersionTable(context.Context, gooseDatabase.DBTxConn) error {
+	return nil
+}
+
+func (s *fakeStore) Insert(_ context.Context, _ gooseDatabase.DBTxConn, req gooseDatabase.InsertRequest) error {
+	s.database.mu.Lock()
+	defer s.database.mu.Unlock()
+
+	s.database.applied[req.Version] = time.Now()
+
+	return nil
+}
+
+func (s *fakeStore) Delete(_ context.Context, _ gooseDatabase.DBTxConn, version int64) error {
+	s.database.mu.Lock()
+	defer s.database.mu.Unlock()
+
+	delete(s.database.applied, version)
+
+	return nil
+}
+
+func (s *fakeStore) GetMigration(
+	_ context.Context,
+	_ gooseDatabase.DBTxConn,
+	version int64,
+) (*gooseDatabase.GetMigrationResult, error) {
+	s.database.mu.Lock()
+	defer s.database.mu.Unlock()
+
+	appliedAt, ok := s.database.applied[version]
+	if !ok {
+		return nil, gooseDatabase.ErrVersionNotFound
+	}
+
+	return &gooseDatabase.GetMigrationResult{Timestamp: appliedAt, IsApplied: true}, nil
+}
+
+func (s *fakeStore) GetLatestVersion(ctx context.Context, db gooseDatabase.DBTxConn) (int64, error) {
+	migrations, err := s.ListMigrations(ctx, db)
+	if err != nil || len(migrations) == 0 {
+		return -1, gooseDatabase.ErrVersionNotFound
+	}
+
+	return migrations[0].Version, nil
+}
+
+func (s *fakeStore) ListMigrations(
+	context.Context,
+	gooseDatabase.DBTxConn,
+) ([]*gooseDatabase.ListMigrationsResult, error) {
+	s.database.mu.Lock()
+	defer s.database.mu.Unlock()
+
+	migrations := make([]*gooseDatabase.ListMigrationsResult, 0, len(s.database.applied))
+	for version := range s.database.applied {
+		migrations = append(migrations, &gooseDatabase.ListMigrationsResult{Version: version, IsApplied: true})
+	}
+
+	// migrations are listed latest first
+	slices.SortFunc(migrations, func(a, b *gooseDatabase.ListMigrationsResult) int {
+		return cmp.Compare(b.Version, a.Version)
+	})
+
+	return migrations, nil
+}
+
+type fakeConnector struct {
+	database *fakeDatabase
+}
+
+func (c *fakeConnector) Connect(context.Context) (driver.Conn, error) {
+	return &fakeConn{database: c.database}, nil
+}
+
+func (c *fakeConnector) Driver() driver.Driver {
+	return nil
+}
+
+type fakeConn struct {
+	database *fakeDatabase
+}
+
+func (c *fakeConn) Prepare(string) (driver.Stmt, error) {
+	return nil, errNotSupported
+}
+
+func (c *fakeConn) Close() error {
+	return nil
+}
+
+func (c *fakeConn) Begin() (driver.Tx, error) {
+	return fakeTx{}, nil
+}
+
+func (c *fakeConn) ExecContext(_ context.Context, query string, _ []driver.NamedValue) (driver.Result, error) {
+	d := c.database
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.failOn != "" && strings.Contains(query, d.failOn) {
+		return nil, errStatementFailed
+	}
+
+	d.executed = append(d.executed, strings.TrimSpace(query))
+
+	return driver.RowsAffected(1), nil
+}
+
+func (c *fakeConn) QueryContext(_ context.Context, query string, _ []driver.NamedValue) (driver.Rows, error) {
+	d := c.database
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.failOn != "" && strings.Contains(query, d.failOn) {
+		return nil, errStatementFailed
+	}
+
+	switch {
+	case strings.HasPrefix(query, "SELECT pg_try_advisory_lock"):
+		acquired := d.heldFor == 0
+		if acquired {
+			d.locks++
+		} else if d.heldFor > 0 {
+			d.heldFor--
+		}
+
+		return &fakeRows{columns: []string{"pg_try_advisory_lock"}, values: [][]driver.Value{{acquired}}}, nil
+	case strings.HasPrefix(query, "SELECT pg_advisory_unlock"):
+		d.unlocks++
+
+		return &fakeRows{columns: []string{"pg_advisory_unlock"}, values: [][]driver.Value{{true}}}, nil
+	default:
+		return nil, errNotSupported
+	}
+}
+
+type fakeTx struct{}
+
+func (fakeTx) Commit() error {
+	return nil
+}
+
+func (fakeTx) Rollback() error {
+	return nil
+}
+
+type fakeRows struct {
+	columns []string
+	values  [][]driver.Value
+}
+
+func (r *fakeRows) Columns() []string {
+	return r.columns
+}
+
+func (r *fakeRows) Close() error {
+	return nil
+}
+
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if len(r.values) == 0 {
+		return io.EOF
+	}
+
+	copy(dest, r.values[0])
+	r.values = r.values[1:]
+
+	return nil
+}
+
+// testMigrations is the file system of the test migrations.
+var testMigrations = fstest.MapFS{
+	"00001_create_a.sql": {Data: []byte("-- +goose Up\nCREATE TABLE a (id INT);\n\n-- +goose Down\nDROP TABLE a;\n")},
+	"00002_create_b.sql": {Data: []byte(
+		"-- +goose Up\n-- +goose StatementBegin\nCREATE TABLE b (id INT);\n-- +goose StatementEnd\n" +
+			"-- +goose Down\nDROP TABLE b;\n",
+	)},
+	"00003_index_b.sql": {Data: []byte(
+		"-- +goose NO TRANSACTION\n-- +goose Up\nCREATE INDEX CONCURRENTLY b_id ON b (id);\n" +
+			"-- +goose Down\nDROP INDEX b_id;\n",
+	)},
+	"README.md": {Data: []byte("not a migration")},
+}
+
+// setupTestMigrator creates a migrator of the migrations on the fake database, waiting for the lock for the timeout.
+func setupTestMigrator(t *testing.T, database *fakeDatabase, fsys fs.FS, lockTimeout time.Duration) *Migrator {
+	t.Helper()
+
+	log, err := logger.New(&logger.Config{})
+	require.NoError(t, err)
+
+	migrator, err := newMigrator(newFakeDB(t, database), fsys, lockTimeout, log, goose.DialectCustom,
+		goose.WithStore(&fakeStore{database: database}))
+	require.NoError(t, err)
+
+	return migrator
+}
+
+func TestNew(t *testing.T) {
+	t.Parallel()
+
+	t.Run("load embedded schema", func(t *testing.T) {
+		t.Parallel()
+
+		statuses, err := setupTestMigrator(t, &fakeDatabase{}, schema.FS, time.Second).Status(context.Background())
+		require.NoError(t, err)
+		require.NotEmpty(t, statuses)
+		assert.Equal(t, int64(1), statuses[0].Migration.Version)
+		assert.Equal(t, "create_tenant_rate_limits", statuses[0].Migration.Name)
+	})
+
+	t.Run("reject duplicate version", func(t *testing.T) {
+		t.Parallel()
+
+		log, err := logger.New(&logger.Config{})
+		require.NoError(t, err)
+
+		_, err = New(newFakeDB(t, &fakeDatabase{}), fstest.MapFS{
+			"00001_create_a.sql": {Data: []byte("-- +goose Up\nSELECT 1;\n")},
+			"1_create_b.sql":     {Data: []byte("-- +goose Up\nSELECT 1;\n")},
+		}, time.Second, log)
+		require.Error(t, err)
+	})
+}
+
+func TestMigrator(t *testing.T) {
+	t.Parallel()
+
+	t.Run("apply pending migrations once", func(t *testing.T) {
+		t.Parallel()
+
+		database := &fakeDatabase{}
+		migrator := setupTestMigrator(t, database, testMigrations, time.Second)
+
+		applied, err := migrator.Up(context.Background())
+		require.NoError(t, err)
+		require.Len(t, applied, 3)
+		assert.Equal(t, &Migration{Version: 1, Name: "create_a"}, applied[0])
+		assert.Equal(t, []string{
+			"CREATE TABLE a (id INT);", "CREATE TABLE b (id INT);", "CREATE INDEX CONCURRENTLY b_id ON b (id);",
+		}, database.statements())
+
+		// applied migrations are not applied again
+		applied, err = migrator.Up(context.Background())
+		require.NoError(t, err)
+		assert.Empty(t, applied)
+		assert.Len(t, database.statements(), 3)
+
+		// the lock is released after the run
+		assert.Equal(t, 1, database.locks)
+		assert.Equal(t, 1, database.unlocks)
+	})
+
+	t.Run("roll latest migration back", func(t *testing.T) {
+		t.Parallel()
+
+		database := &fakeDatabase{applied: map[int64]time.Time{0: time.Now(), 1: time.Now(), 2: time.Now()}}
+		migrator := setupTestMigrator(t, database, testMigrations, time.Second)
+
+		migration, err := migrator.Down(context.Background())
+		require.NoError(t, err)
+		assert.Equal(t, int64(2), migration.Version)
+		assert.Equal(t, []string{"DROP TABLE b;"}, database.statements())
+		assert.NotContains(t, database.applied, int64(2))
+		assert.Contains(t, database.applied, int64(1))
+	})
+
+	t.Run("return error without applied migration", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := setupTestMigrator(t, &fakeDatabase{}, testMigrations, time.Second).Down(context.Background())
+		require.ErrorIs(t, err, ErrNoMigration)
+	})
+
+	t.Run("report status of migrations", func(t *testing.T) {
+		t.Parallel()
+
+		database := &fakeDatabase{applied: map[int64]time.Time{0: time.Now(), 1: time.Now()}}
+
+		statuses, err := setupTestMigrator(t, database, testMigrations, time.Second).Status(context.Background())
+		require.NoError(t, err)
+		require.Len(t, statuses, 3)
+		assert.NotNil(t, statuses[0].AppliedAt)
+		assert.Nil(t, statuses[1].AppliedAt)
+		assert.Nil(t, statuses[2].AppliedAt)
+	})
+
+	t.Run("stop at failed migration without recording it", func(t *testing.T) {
+		t.Parallel()
+
+		database := &fakeDatabase{failOn: "CREATE TABLE b"}
+
+		applied, err := setupTestMigrator(t, database, testMigrations, time.Second).Up(context.Background())
+		require.ErrorIs(t, err, errStatementFailed)
+		require.Len(t, applied, 1)
+		assert.Contains(t, database.applied, int64(1))
+		assert.NotContains(t, database.applied, int64(2))
+		assert.Equal(t, 1, database.unlocks)
+	})
+
+	t.Run("return error if lock fails", func(t *testing.T) {
+		t.Parallel()
+
+		database := &fakeDatabase{failOn: "pg_try_advisory_lock"}
+
+		_, err := setupTestMigrator(t, database, testMigrations, time.Second).Up(context.Background())
+		require.Error(t, err)
+		assert.Empty(t, database.statements())
+	})
+
+	t.Run("wait for lock held by another migrator", func(t *testing.T) {
+		t.Parallel()
+
+		database := &fakeDatabase{heldFor: 1}
+
+		applied, err := setupTestMigrator(t, database, testMigrations, 5*time.Second).Up(context.Background())
+		require.NoError(t, err)
+		assert.Len(t, applied, 3)
+		assert.Zero(t, database.heldFor)
+		assert.Equal(t, 1, database.locks)
+	})
+
+	t.Run("return error if lock is held longer than timeout", func(t *testing.T) {
+		t.Parallel()
+
+		database := &fakeDatabase{heldFor: -1}
+
+		applied, err := setupTestMigrator(t, database, testMigrations, time.Millisecond).Up(context.Background())
+		require.Error(t, err)
+		assert.Empty(t, applied)
+		assert.Empty(t, database.statements())
+		assert.Zero(t, database.unlocks)
+	})
+
+	t.Run("stop waiting for lock when context is canceled", func(t *testing.T) {
+		t.Parallel()
+
+		migrator := setupTestMigrator(t, &fakeDatabase{heldFor: -1}, testMigrations, 0)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+		defer cancel()
+
+		_, err := migrator.Up(ctx)
+		require.ErrorIs(t, err, context.DeadlineExceeded)
+	})
+}
+
+func TestNewModule(t *testing.T) {
+	t.Parallel()
+
+	t.Run("return fx.Option", func(t *testing.T) {
+		t.Parallel()
+
+		require.NotNil(t, NewModule())
+	})
+
+	t.Run("skip migrations without auto migrate", func(t *testing.T) {
+		t.Parallel()
+
+		log, err := logger.New(&logger.Config{})
+		require.NoError(t, err)
+
+		config := &database.Config{}
+		config.SetDefault()
+
+		require.NoError(t, autoMigrate(config, log))
+	})
+
+	t.Run("return error for invalid database url", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := Open(&database.Config{URL: &[]string{"mysql://localhost"}[0]})
+		require.ErrorIs(t, err, database.ErrInvalidURL)
+	})
+}
@@ -0,0 +1,61 @@
+// Package netutil provides network helpers for HTTP requests.
+package netutil
+
+import (
+	"net"
+	"net/http"
+	"strings"
+)
+
+const (
+	// HeaderXForwardedFor is header carrying the client and proxy chain.
+	HeaderXForwardedFor = "X-Forwarded-For"
+
+	// HeaderXRealIP is header carrying the client address set by a proxy.
+	HeaderXRealIP = "X-Real-IP"
+)
+
+// ClientIP returns the client IP of the request without port from RemoteAddr, which is the client resolved by
+// Resolver once the request passed the RealIP middleware. Forwarded headers are not read, since clients can set them.
+func ClientIP(request *http.Request) string {
+	if ip := ParseIP(request.RemoteAddr); ip != "" {
+		return ip
+	}
+
+	// keep RemoteAddr so keys stay distinct when it is not an IP (e.g. unix sockets)
+	return request.RemoteAddr
+}
+
+// FirstForwardedFor returns the first hop of an X-Forwarded-For header, empty if it is not a valid IP.
+func FirstForwardedFor(header string) string {
+	first, _, _ := strings.Cut(header, ",")
+
+	return ParseIP(first)
+}
+
+// ParseIP parses an IP with optional port or brackets (e.g. 203.0.113.1:80, [2001:db8::1]:443).
+// It returns the canonical IP, empty if the value is not a valid IP.
+func ParseIP(value string) string {
+	value = StripPort(strings.TrimSpace(value))
+
+	ip := net.ParseIP(value)
+	if ip == nil {
+		return ""
+	}
+
+	return ip.String()
+}
+
+// StripPort removes the port and IPv6 brackets from an address, it keeps unbracketed IPv6 addresses intact.
+func StripPort(addr string) string {
+	if host, _, err := net.SplitHostPort(addr); err == nil {
+		return host
+	}
+
+	// bracketed IPv6 without port
+	if strings.HasPrefix(addr, "[") && strings.HasSuffix(addr, "]") {
+		return addr[1 : len(addr)-1]
+	}
+
+	return addr
+}
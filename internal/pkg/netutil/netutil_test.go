@@ -0,0 +1,119 @@
+package netutil
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseIP(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		value    string
+		expected string
+	}{
+		{name: "parse IPv4", value: "203.0.113.1", expected: "203.0.113.1"},
+		{name: "strip IPv4 port", value: "203.0.113.1:8080", expected: "203.0.113.1"},
+		{name: "trim whitespace", value: " 203.0.113.1 ", expected: "203.0.113.1"},
+		{name: "parse IPv6", value: "2001:db8::1", expected: "2001:db8::1"},
+		{name: "strip IPv6 brackets", value: "[2001:db8::1]", expected: "2001:db8::1"},
+		{name: "strip IPv6 brackets and port", value: "[2001:db8::1]:443", expected: "2001:db8::1"},
+		{name: "canonicalize IPv6", value: "2001:DB8:0:0:0:0:0:1", expected: "2001:db8::1"},
+		{name: "reject hostname", value: "example.com", expected: ""},
+		{name: "reject empty value", value: "", expected: ""},
+		{name: "reject garbage", value: "unknown", expected: ""},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			assert.Equal(t, test.expected, ParseIP(test.value))
+		})
+	}
+}
+
+func TestFirstForwardedFor(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		header   string
+		expected string
+	}{
+		{name: "use single hop", header: "203.0.113.1", expected: "203.0.113.1"},
+		{name: "use first of several hops", header: "203.0.113.1, 10.0.0.1, 10.0.0.2", expected: "203.0.113.1"},
+		{name: "strip port of first hop", header: "[2001:db8::1]:443, 10.0.0.1", expected: "2001:db8::1"},
+		{name: "reject invalid first hop", header: "unknown, 10.0.0.1", expected: ""},
+		{name: "reject empty header", header: "", expected: ""},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			assert.Equal(t, test.expected, FirstForwardedFor(test.header))
+		})
+	}
+}
+
+func TestClientIP(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name       string
+		headers    map[string]string
+		remoteAddr string
+		expected   string
+	}{
+		{
+			name:       "ignore forwarded headers",
+			headers:    map[string]string{HeaderXForwardedFor: "203.0.113.1, 10.0.0.1", HeaderXRealIP: "203.0.113.2"},
+			remoteAddr: "10.0.0.1:1234",
+			expected:   "10.0.0.1",
+		},
+		{
+			name:       "strip port from RemoteAddr",
+			remoteAddr: "192.168.1.1:12345",
+			expected:   "192.168.1.1",
+		},
+		{
+			name:       "strip brackets and port from IPv6 RemoteAddr",
+			remoteAddr: "[::1]:12345",
+			expected:   "::1",
+		},
+		{
+			name:       "keep RemoteAddr that is not an IP",
+			remoteAddr: "@",
+			expected:   "@",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			request := httptest.NewRequest(http.MethodGet, "/", nil)
+			request.RemoteAddr = test.remoteAddr
+
+			for key, value := range test.headers {
+				request.Header.Set(key, value)
+			}
+
+			assert.Equal(t, test.expected, ClientIP(request))
+		})
+	}
+}
+
+func TestStripPort(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, "203.0.113.1", StripPort("203.0.113.1:80"))
+	assert.Equal(t, "2001:db8::1", StripPort("[2001:db8::1]"))
+	assert.Equal(t, "2001:db8::1", StripPort("2001:db8::1"))
+	assert.Equal(t, "localhost", StripPort("localhost:8080"))
+}
@@ -0,0 +1,127 @@
+package netutil
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/netip"
+	"strings"
+)
+
+// ErrInvalidTrustedProxy is returned when a trusted proxy network is not a CIDR.
+var ErrInvalidTrustedProxy = errors.New("invalid trusted proxy")
+
+// Resolver resolves client IPs of requests, honoring forwarded headers only from trusted proxies.
+type Resolver struct {
+	// proxies is networks of trusted proxies.
+	proxies []netip.Prefix
+}
+
+// NewResolver creates a resolver trusting forwarded headers from proxies in the networks (e.g. 10.0.0.0/8), it
+// trusts no proxy if there are none.
+func NewResolver(trustedProxies []string) (*Resolver, error) {
+	proxies, err := ParsePrefixes(trustedProxies)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Resolver{proxies: proxies}, nil
+}
+
+// ParsePrefixes parses networks of trusted proxies in CIDR notation.
+func ParsePrefixes(cidrs []string) ([]netip.Prefix, error) {
+	prefixes := make([]netip.Prefix, 0, len(cidrs))
+
+	for _, cidr := range cidrs {
+		prefix, err := netip.ParsePrefix(cidr)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %q: %w", ErrInvalidTrustedProxy, cidr, err)
+		}
+
+		prefixes = append(prefixes, prefix.Masked())
+	}
+
+	return prefixes, nil
+}
+
+// ClientIP returns the client IP of the request. If the peer is a trusted proxy, X-Forwarded-For hops are walked
+// from the right and the first untrusted hop is the client, since hops left of it may be set by the client; the
+// leftmost hop is used if all hops are trusted, and X-Real-IP if there is no X-Forwarded-For. Otherwise the peer
+// is the client and forwarded headers are ignored.
+func (r *Resolver) ClientIP(request *http.Request) string {
+	peer := ParseIP(request.RemoteAddr)
+	if peer == "" {
+		// keep RemoteAddr so keys stay distinct when it is not an IP (e.g. unix sockets)
+		return request.RemoteAddr
+	}
+
+	if !r.trusted(peer) {
+		return peer
+	}
+
+	hops := forwardedFor(request)
+	if len(hops) == 0 {
+		if ip := ParseIP(request.Header.Get(HeaderXRealIP)); ip != "" {
+			return ip
+		}
+
+		return peer
+	}
+
+	client := peer
+
+	for i := len(hops) - 1; i >= 0; i-- {
+		ip := ParseIP(hops[i])
+
+		// hops left of a malformed hop can not be attributed to a trusted proxy
+		if ip == "" {
+			break
+		}
+
+		client = ip
+
+		if !r.trusted(ip) {
+			break
+		}
+	}
+
+	return client
+}
+
+// TrustedPeer returns whether the peer of the request is a trusted proxy, so that headers it sets can be trusted.
+func (r *Resolver) TrustedPeer(request *http.Request) bool {
+	return r.trusted(ParseIP(request.RemoteAddr))
+}
+
+// trusted returns whether the IP is in the networks of trusted proxies.
+func (r *Resolver) trusted(ip string) bool {
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return false
+	}
+
+	addr = addr.Unmap()
+
+	for _, prefix := range r.proxies {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// forwardedFor returns the hops of all X-Forwarded-For headers of the request in order.
+func forwardedFor(request *http.Request) []string {
+	var hops []string
+
+	for _, header := range request.Header.Values(HeaderXForwardedFor) {
+		for hop := range strings.SplitSeq(header, ",") {
+			if hop = strings.TrimSpace(hop); hop != "" {
+				hops = append(hops, hop)
+			}
+		}
+	}
+
+	return hops
+}
@@ -0,0 +1,142 @@
+package netutil
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewResolver(t *testing.T) {
+	t.Parallel()
+
+	t.Run("create resolver trusting networks", func(t *testing.T) {
+		t.Parallel()
+
+		resolver, err := NewResolver([]string{"10.0.0.0/8", "2001:db8::/32"})
+		require.NoError(t, err)
+		assert.Len(t, resolver.proxies, 2)
+	})
+
+	t.Run("return error for network that is not a CIDR", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := NewResolver([]string{"10.0.0.1"})
+		require.ErrorIs(t, err, ErrInvalidTrustedProxy)
+	})
+}
+
+func TestResolverClientIP(t *testing.T) {
+	t.Parallel()
+
+	resolver, err := NewResolver([]string{"10.0.0.0/8", "172.16.0.0/12"})
+	require.NoError(t, err)
+
+	tests := []struct {
+		name         string
+		remoteAddr   string
+		forwardedFor []string
+		realIP       string
+		expected     string
+	}{
+		{
+			name:         "ignore forwarded headers of untrusted peers",
+			remoteAddr:   "198.51.100.1:1234",
+			forwardedFor: []string{"203.0.113.1"},
+			realIP:       "203.0.113.2",
+			expected:     "198.51.100.1",
+		},
+		{
+			name:         "use rightmost untrusted hop",
+			remoteAddr:   "10.0.0.1:1234",
+			forwardedFor: []string{"192.0.2.1, 203.0.113.1, 10.0.0.2"},
+			expected:     "203.0.113.1",
+		},
+		{
+			name:         "join hops of repeated headers",
+			remoteAddr:   "10.0.0.1:1234",
+			forwardedFor: []string{"203.0.113.1", "10.0.0.3, 10.0.0.2"},
+			expected:     "203.0.113.1",
+		},
+		{
+			name:         "use leftmost hop when all hops are trusted",
+			remoteAddr:   "10.0.0.1:1234",
+			forwardedFor: []string{"10.0.0.3, 10.0.0.2"},
+			expected:     "10.0.0.3",
+		},
+		{
+			name:         "stop at malformed hop",
+			remoteAddr:   "10.0.0.1:1234",
+			forwardedFor: []string{"203.0.113.1, unknown, 10.0.0.2"},
+			expected:     "10.0.0.2",
+		},
+		{
+			name:         "strip port and brackets of hops",
+			remoteAddr:   "10.0.0.1:1234",
+			forwardedFor: []string{"[2001:db8::1]:443"},
+			expected:     "2001:db8::1",
+		},
+		{
+			name:       "use X-Real-IP of trusted peers without X-Forwarded-For",
+			remoteAddr: "10.0.0.1:1234",
+			realIP:     "203.0.113.2",
+			expected:   "203.0.113.2",
+		},
+		{
+			name:       "use trusted peer without forwarded headers",
+			remoteAddr: "10.0.0.1:1234",
+			expected:   "10.0.0.1",
+		},
+		{
+			name:         "trust IPv4-mapped peers",
+			remoteAddr:   "[::ffff:172.16.0.1]:1234",
+			forwardedFor: []string{"203.0.113.1"},
+			expected:     "203.0.113.1",
+		},
+		{
+			name:       "keep RemoteAddr that is not an IP",
+			remoteAddr: "@",
+			expected:   "@",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			request := httptest.NewRequest(http.MethodGet, "/", nil)
+			request.RemoteAddr = test.remoteAddr
+
+			for _, value := range test.forwardedFor {
+				request.Header.Add(HeaderXForwardedFor, value)
+			}
+
+			if test.realIP != "" {
+				request.Header.Set(HeaderXRealIP, test.realIP)
+			}
+
+			assert.Equal(t, test.expected, resolver.ClientIP(request))
+		})
+	}
+}
+
+func TestResolverTrustedPeer(t *testing.T) {
+	t.Parallel()
+
+	resolver, err := NewResolver([]string{"10.0.0.0/8"})
+	require.NoError(t, err)
+
+	for remoteAddr, expected := range map[string]bool{
+		"10.0.0.1:1234":          true,
+		"[::ffff:10.0.0.1]:1234": true,
+		"192.0.2.1:1234":         false,
+		"@":                      false,
+	} {
+		request := httptest.NewRequest(http.MethodGet, "/", nil)
+		request.RemoteAddr = remoteAddr
+
+		assert.Equal(t, expected, resolver.TrustedPeer(request), remoteAddr)
+	}
+}
@@ -0,0 +1,403 @@
+// Package payments provides subscriptions of Stripe, customers and subscriptions are synced to database
+// from webhooks, and entitlements granted by subscribed prices are looked up through a redis cache.
+package payments
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"go.uber.org/fx"
+
+	"github.com/pocj8ur4in/boilerplate-go/internal/gen/db"
+	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/database"
+	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/httpclient"
+	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/logger"
+	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/querycache"
+	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/redis"
+)
+
+const (
+	// defaultAPIURL is default base URL of the Stripe API.
+	defaultAPIURL = "https://api.stripe.com"
+
+	// defaultWebhookTolerance is default maximum age of webhook signatures.
+	defaultWebhookTolerance = 5 * time.Minute
+
+	// defaultCacheTTL is default duration entitlements of users are cached.
+	defaultCacheTTL = 5 * time.Minute
+
+	// cacheKeyPrefix is prefix of redis keys caching entitlements of users.
+	cacheKeyPrefix = "payments:entitlements:"
+)
+
+var (
+	// ErrNoCustomer is returned when the user has no customer of Stripe.
+	ErrNoCustomer = errors.New("user has no customer")
+
+	// ErrMissingSecret is returned when payments are enabled without a webhook secret.
+	ErrMissingSecret = errors.New("missing payments webhook secret")
+)
+
+// Config represents configuration for payments.
+type Config struct {
+	// Enabled is whether payments are enabled.
+	Enabled *bool `json:"enabled"`
+
+	// SecretKey is secret API key of Stripe.
+	SecretKey *string `json:"secret_key"`
+
+	// WebhookSecret is signing secret of the webhook endpoint of Stripe.
+	WebhookSecret *string `json:"webhook_secret"`
+
+	// WebhookPath is path of the webhook endpoint Stripe sends events to.
+	WebhookPath *string `json:"webhook_path"`
+
+	// WebhookTolerance is maximum age of webhook signatures, older events are rejected as replays.
+	WebhookTolerance *time.Duration `json:"webhook_tolerance"`
+
+	// APIURL is base URL of the Stripe API, replaced in tests.
+	APIURL *string `json:"api_url"`
+
+	// APIVersion is version of the Stripe API requests are made with, the account default if empty.
+	APIVersion *string `json:"api_version"`
+
+	// CacheTTL is duration entitlements of users are cached, webhooks clear the cache of changed subscriptions.
+	CacheTTL *time.Duration `json:"cache_ttl"`
+
+	// ActiveStatuses is statuses of subscriptions granting entitlements.
+	ActiveStatuses []string `json:"active_statuses"`
+
+	// Plans is entitlements granted by each price ID.
+	Plans map[string][]string `json:"plans"`
+
+	// Gates is entitlements required by API paths.
+	Gates []*Gate `json:"gates"`
+}
+
+// Gate represents an entitlement required by API paths.
+type Gate struct {
+	// PathPrefix is path prefix of the gated endpoints.
+	PathPrefix string `json:"path_prefix"`
+
+	// Entitlement is entitlement required by the endpoints.
+	Entitlement string `json:"entitlement"`
+}
+
+// SetDefault sets default values.
+func (c *Config) SetDefault() {
+	if c.Enabled == nil {
+		c.Enabled = &[]bool{false}[0]
+	}
+
+	if c.SecretKey == nil {
+		c.SecretKey = &[]string{""}[0]
+	}
+
+	if c.WebhookSecret == nil {
+		c.WebhookSecret = &[]string{""}[0]
+	}
+
+	if c.WebhookPath == nil {
+		c.WebhookPath = &[]string{"/webhooks/stripe"}[0]
+	}
+
+	if c.WebhookTolerance == nil {
+		c.WebhookTolerance = &[]time.Duration{defaultWebhookTolerance}[0]
+	}
+
+	if c.APIURL == nil {
+		c.APIURL = &[]string{defaultAPIURL}[0]
+	}
+
+	if c.APIVersion == nil {
+		c.APIVersion = &[]string{""}[0]
+	}
+
+	if c.CacheTTL == nil {
+		c.CacheTTL = &[]time.Duration{defaultCacheTTL}[0]
+	}
+
+	if c.ActiveStatuses == nil {
+		c.ActiveStatuses = []string{"active", "trialing"}
+	}
+
+	if c.Plans == nil {
+		c.Plans = map[string][]string{}
+	}
+
+	if c.Gates == nil {
+		c.Gates = []*Gate{}
+	}
+}
+
+// Payments provides subscriptions of users.
+type Payments struct {
+	// config provides payments configuration.
+	config *Config
+
+	// client provides the Stripe API.
+	client *Client
+
+	// queries provides database queries.
+	queries db.Querier
+
+	// redis provides redis client caching entitlements.
+	redis *redis.Redis
+
+	// logger provides logger.
+	logger *logger.Logger
+
+	// now returns the current time, replaced in tests.
+	now func() time.Time
+}
+
+// NewModule provides module for payments.
+func NewModule() fx.Option {
+	return fx.Module("payments",
+		fx.Provide(New),
+	)
+}
+
+// New creates a new payments service, reading billing through the query cache.
+func New(
+	config *Config,
+	dbConn *database.DB,
+	redisConn *redis.Redis,
+	httpClient *httpclient.Client,
+	queryCache *querycache.QueryCache,
+	logger *logger.Logger,
+) (*Payments, error) {
+	queries := querycache.NewQuerier(dbConn.Queries, queryCache)
+
+	return NewWithQuerier(config, NewClient(config, httpClient.Client), queries, redisConn, logger)
+}
+
+// NewWithQuerier creates a new payments service using the client and the querier, webhooks of enabled payments
+// cannot be verified without a secret.
+func NewWithQuerier(
+	config *Config,
+	client *Client,
+	queries db.Querier,
+	redisConn *redis.Redis,
+	logger *logger.Logger,
+) (*Payments, error) {
+	if config == nil {
+		config = &Config{}
+	}
+
+	config.SetDefault()
+
+	if *config.Enabled && *config.WebhookSecret == "" {
+		return nil, ErrMissingSecret
+	}
+
+	return &Payments{
+		config:  config,
+		client:  client,
+		queries: queries,
+		redis:   redisConn,
+		logger:  logger.Named("payments"),
+		now:     time.Now,
+	}, nil
+}
+
+// Enabled returns whether payments are enabled.
+func (p *Payments) Enabled() bool {
+	return p != nil && *p.config.Enabled
+}
+
+// WebhookPath returns path of the webhook endpoint.
+func (p *Payments) WebhookPath() string {
+	return *p.config.WebhookPath
+}
+
+// Gate returns the entitlement required by the path, the longest matching prefix wins,
+// and false if the path is not gated.
+func (p *Payments) Gate(path string) (string, bool) {
+	var matched *Gate
+
+	for _, gate := range p.config.Gates {
+		if !strings.HasPrefix(path, gate.PathPrefix) {
+			continue
+		}
+
+		if matched == nil || len(gate.PathPrefix) > len(matched.PathPrefix) {
+			matched = gate
+		}
+	}
+
+	if matched == nil {
+		return "", false
+	}
+
+	return matched.Entitlement, true
+}
+
+// EnsureCustomer returns the customer ID of the user, creating the customer on Stripe if it has none.
+func (p *Payments) EnsureCustomer(ctx context.Context, userID, email string) (string, error) {
+	customer, err := p.queries.GetBillingCustomerByUserID(ctx, userID)
+	if err == nil {
+		return customer.CustomerID, nil
+	}
+
+	if !errors.Is(err, pgx.ErrNoRows) {
+		return "", fmt.Errorf("failed to get customer of user %s: %w", userID, err)
+	}
+
+	created, err := p.client.CreateCustomer(ctx, userID, email)
+	if err != nil {
+		return "", err
+	}
+
+	// a concurrent call stored the customer first, the stored customer is returned
+	customer, err = p.queries.CreateBillingCustomer(ctx, &db.CreateBillingCustomerParams{
+		UserID:     userID,
+		CustomerID: created.ID,
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to store customer of user %s: %w", userID, err)
+	}
+
+	return customer.CustomerID, nil
+}
+
+// SyncSubscription fetches the subscription from Stripe and stores it, so that events delivered
+// out of order or more than once store its latest state.
+func (p *Payments) SyncSubscription(ctx context.Context, id string) error {
+	subscription, err := p.client.GetSubscription(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	if err := p.store(ctx, subscription); err != nil {
+		return err
+	}
+
+	customer, err := p.queries.GetBillingCustomerByCustomerID(ctx, subscription.CustomerID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		// customers created outside the service are not linked to users
+		p.logger.Ctx(ctx).Warn().Str("customer_id", subscription.CustomerID).Str("subscription_id", id).
+			Msg("subscription of unknown customer")
+
+		return nil
+	}
+
+	if err != nil {
+		return fmt.Errorf("failed to get user of customer %s: %w", subscription.CustomerID, err)
+	}
+
+	return p.invalidate(ctx, customer.UserID)
+}
+
+// SyncCustomer fetches all subscriptions of the user from Stripe and stores them,
+// e.g. to backfill subscriptions created before webhooks were set up.
+func (p *Payments) SyncCustomer(ctx context.Context, userID string) error {
+	customer, err := p.queries.GetBillingCustomerByUserID(ctx, userID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return ErrNoCustomer
+	}
+
+	if err != nil {
+		return fmt.Errorf("failed to get customer of user %s: %w", userID, err)
+	}
+
+	subscriptions, err := p.client.ListSubscriptions(ctx, customer.CustomerID)
+	if err != nil {
+		return err
+	}
+
+	for _, subscription := range subscriptions {
+		if err := p.store(ctx, subscription); err != nil {
+			return err
+		}
+	}
+
+	return p.invalidate(ctx, userID)
+}
+
+// store stores the subscription.
+func (p *Payments) store(ctx context.Context, subscription *Subscription) error {
+	periodEnd := pgtype.Timestamptz{Time: subscription.CurrentPeriodEnd, Valid: !subscription.CurrentPeriodEnd.IsZero()}
+
+	if _, err := p.queries.UpsertBillingSubscription(ctx, &db.UpsertBillingSubscriptionParams{
+		ID:                subscription.ID,
+		CustomerID:        subscription.CustomerID,
+		Status:            subscription.Status,
+		PriceIds:          subscription.PriceIDs,
+		CurrentPeriodEnd:  periodEnd,
+		CancelAtPeriodEnd: subscription.CancelAtPeriodEnd,
+	}); err != nil {
+		return fmt.Errorf("failed to store subscription %s: %w", subscription.ID, err)
+	}
+
+	return nil
+}
+
+// Entitlements returns entitlements granted to the user by prices of its active subscriptions, sorted.
+func (p *Payments) Entitlements(ctx context.Context, userID string) ([]string, error) {
+	key := cacheKeyPrefix + userID
+
+	// cache failures fall back to database, so that redis outages do not lock users out
+	if cached, err := p.redis.Get(ctx, key).Bytes(); err == nil {
+		var entitlements []string
+		if err := json.Unmarshal(cached, &entitlements); err == nil {
+			return entitlements, nil
+		}
+	}
+
+	subscriptions, err := p.queries.ListBillingSubscriptionsByUserID(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list subscriptions of user %s: %w", userID, err)
+	}
+
+	entitlements := []string{}
+
+	for _, subscription := range subscriptions {
+		if !slices.Contains(p.config.ActiveStatuses, subscription.Status) {
+			continue
+		}
+
+		for _, priceID := range subscription.PriceIds {
+			entitlements = append(entitlements, p.config.Plans[priceID]...)
+		}
+	}
+
+	slices.Sort(entitlements)
+	entitlements = slices.Compact(entitlements)
+
+	if encoded, err := json.Marshal(entitlements); err == nil {
+		if err := p.redis.Set(ctx, key, encoded, *p.config.CacheTTL).Err(); err != nil {
+			p.logger.Ctx(ctx).Warn().Err(err).Str("user_id", userID).Msg("failed to cache entitlements")
+		}
+	}
+
+	return entitlements, nil
+}
+
+// HasEntitlement returns whether the user is granted the entitlement.
+func (p *Payments) HasEntitlement(ctx context.Context, userID, entitlement string) (bool, error) {
+	entitlements, err := p.Entitlements(ctx, userID)
+	if err != nil {
+		return false, err
+	}
+
+	_, found := slices.BinarySearch(entitlements, entitlement)
+
+	return found, nil
+}
+
+// invalidate clears cached entitlements of the user.
+func (p *Payments) invalidate(ctx context.Context, userID string) error {
+	if err := p.redis.Del(ctx, cacheKeyPrefix+userID).Err(); err != nil {
+		return fmt.Errorf("failed to clear entitlements of user %s: %w", userID, err)
+	}
+
+	return nil
+}
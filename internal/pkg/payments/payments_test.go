@@ -0,0 +1,476 @@
+package payments
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pocj8ur4in/boilerplate-go/internal/gen/db"
+	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/logger"
+	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/redis"
+)
+
+var errQueryFailed = errors.New("query failed")
+
+// mockBillingQuerier is a mock querier storing customers and subscriptions in memory.
+type mockBillingQuerier struct {
+	db.Querier
+
+	mu            sync.Mutex
+	customers     map[string]*db.BillingCustomer
+	subscriptions map[string]*db.BillingSubscription
+	err           error
+}
+
+// newMockBillingQuerier creates a mock querier with the customers of users.
+func newMockBillingQuerier(customers map[string]string) *mockBillingQuerier {
+	querier := &mockBillingQuerier{
+		customers:     make(map[string]*db.BillingCustomer),
+		subscriptions: make(map[string]*db.BillingSubscription),
+	}
+
+	for userID, customerID := range customers {
+		querier.customers[userID] = &db.BillingCustomer{UserID: userID, CustomerID: customerID}
+	}
+
+	return querier
+}
+
+func (m *mockBillingQuerier) CreateBillingCustomer(
+	_ context.Context,
+	arg *db.CreateBillingCustomerParams,
+) (*db.BillingCustomer, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if customer, ok := m.customers[arg.UserID]; ok {
+		return customer, nil
+	}
+
+	m.customers[arg.UserID] = &db.BillingCustomer{UserID: arg.UserID, CustomerID: arg.CustomerID}
+
+	return m.customers[arg.UserID], nil
+}
+
+func (m *mockBillingQuerier) GetBillingCustomerByCustomerID(
+	_ context.Context,
+	customerID string,
+) (*db.BillingCustomer, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, customer := range m.customers {
+		if customer.CustomerID == customerID {
+			return customer, nil
+		}
+	}
+
+	return nil, pgx.ErrNoRows
+}
+
+func (m *mockBillingQuerier) GetBillingCustomerByUserID(_ context.Context, userID string) (*db.BillingCustomer, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.err != nil {
+		return nil, m.err
+	}
+
+	customer, ok := m.customers[userID]
+	if !ok {
+		return nil, pgx.ErrNoRows
+	}
+
+	return customer, nil
+}
+
+func (m *mockBillingQuerier) ListBillingSubscriptionsByUserID(
+	_ context.Context,
+	userID string,
+) ([]*db.BillingSubscription, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.err != nil {
+		return nil, m.err
+	}
+
+	customer, ok := m.customers[userID]
+	if !ok {
+		return []*db.BillingSubscription{}, nil
+	}
+
+	subscriptions := []*db.BillingSubscription{}
+
+	for _, subscription := range m.subscriptions {
+		if subscription.CustomerID == customer.CustomerID {
+			subscriptions = append(subscriptions, subscription)
+		}
+	}
+
+	return subscriptions, nil
+}
+
+func (m *mockBillingQuerier) UpsertBillingSubscription(
+	_ context.Context,
+	arg *db.UpsertBillingSubscriptionParams,
+) (*db.BillingSubscription, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.subscriptions[arg.ID] = &db.BillingSubscription{
+		ID:                arg.ID,
+		CustomerID:        arg.CustomerID,
+		Status:            arg.Status,
+		PriceIds:          arg.PriceIds,
+		CurrentPeriodEnd:  arg.CurrentPeriodEnd,
+		CancelAtPeriodEnd: arg.CancelAtPeriodEnd,
+	}
+
+	return m.subscriptions[arg.ID], nil
+}
+
+// setupTestRedis creates a redis client of the test redis server.
+func setupTestRedis(t *testing.T) *redis.Redis {
+	t.Helper()
+
+	password := ""
+	redisDB := 0
+
+	redisClient, err := redis.New(&redis.Config{
+		Addrs:    []string{"localhost:36379"},
+		Password: &password,
+		DB:       &redisDB,
+	})
+	require.NoError(t, err)
+
+	t.Cleanup(func() {
+		_ = redisClient.Close()
+	})
+
+	return redisClient
+}
+
+// setupTestPayments creates enabled payments of a fake Stripe API served by the handler.
+func setupTestPayments(t *testing.T, querier db.Querier, handler http.HandlerFunc) *Payments {
+	t.Helper()
+
+	log, err := logger.New(&logger.Config{})
+	require.NoError(t, err)
+
+	if handler == nil {
+		handler = func(writer http.ResponseWriter, _ *http.Request) {
+			writer.WriteHeader(http.StatusInternalServerError)
+		}
+	}
+
+	client := setupTestClient(t, handler)
+	config := client.config
+	config.Enabled = &[]bool{true}[0]
+	config.WebhookSecret = &[]string{"whsec_test"}[0]
+	config.Plans = map[string][]string{
+		"price_pro":   {"reports", "exports"},
+		"price_seats": {"seats"},
+	}
+	config.Gates = []*Gate{
+		{PathPrefix: "/api/v1/reports", Entitlement: "reports"},
+		{PathPrefix: "/api/v1/reports/exports", Entitlement: "exports"},
+	}
+
+	payments, err := NewWithQuerier(config, client, querier, setupTestRedis(t), log)
+	require.NoError(t, err)
+
+	return payments
+}
+
+// testUserID returns a user ID unique to the test, so that cached entitlements are not shared.
+func testUserID(t *testing.T) string {
+	t.Helper()
+
+	return fmt.Sprintf("%s-%d", t.Name(), time.Now().UnixNano())
+}
+
+func TestConfigSetDefault(t *testing.T) {
+	t.Parallel()
+
+	config := &Config{}
+	config.SetDefault()
+
+	assert.False(t, *config.Enabled)
+	assert.Equal(t, "/webhooks/stripe", *config.WebhookPath)
+	assert.Equal(t, defaultWebhookTolerance, *config.WebhookTolerance)
+	assert.Equal(t, defaultAPIURL, *config.APIURL)
+	assert.Equal(t, defaultCacheTTL, *config.CacheTTL)
+	assert.Equal(t, []string{"active", "trialing"}, config.ActiveStatuses)
+	assert.Empty(t, config.Plans)
+	assert.Empty(t, config.Gates)
+}
+
+func TestNewModule(t *testing.T) {
+	t.Parallel()
+
+	t.Run("return fx.Option", func(t *testing.T) {
+		t.Parallel()
+
+		require.NotNil(t, NewModule())
+	})
+}
+
+func TestNewWithQuerier(t *testing.T) {
+	t.Parallel()
+
+	log, err := logger.New(&logger.Config{})
+	require.NoError(t, err)
+
+	t.Run("create disabled payments without secret", func(t *testing.T) {
+		t.Parallel()
+
+		payments, err := NewWithQuerier(nil, nil, nil, nil, log)
+		require.NoError(t, err)
+		assert.False(t, payments.Enabled())
+	})
+
+	t.Run("return error if enabled without webhook secret", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := NewWithQuerier(&Config{Enabled: &[]bool{true}[0]}, nil, nil, nil, log)
+		require.ErrorIs(t, err, ErrMissingSecret)
+	})
+}
+
+func TestEnabled(t *testing.T) {
+	t.Parallel()
+
+	var disabled *Payments
+
+	assert.False(t, disabled.Enabled())
+	assert.True(t, setupTestPayments(t, nil, nil).Enabled())
+}
+
+func TestGate(t *testing.T) {
+	t.Parallel()
+
+	payments := setupTestPayments(t, nil, nil)
+
+	tests := []struct {
+		path        string
+		entitlement string
+		gated       bool
+	}{
+		{path: "/api/v1/reports", entitlement: "reports", gated: true},
+		{path: "/api/v1/reports/daily", entitlement: "reports", gated: true},
+		{path: "/api/v1/reports/exports/1", entitlement: "exports", gated: true},
+		{path: "/api/v1/users", gated: false},
+	}
+
+	for _, test := range tests {
+		entitlement, gated := payments.Gate(test.path)
+		assert.Equal(t, test.gated, gated, test.path)
+		assert.Equal(t, test.entitlement, entitlement, test.path)
+	}
+}
+
+func TestEnsureCustomer(t *testing.T) {
+	t.Parallel()
+
+	t.Run("return stored customer", func(t *testing.T) {
+		t.Parallel()
+
+		querier := newMockBillingQuerier(map[string]string{"user-1": "cus_1"})
+		payments := setupTestPayments(t, querier, nil)
+
+		customerID, err := payments.EnsureCustomer(context.Background(), "user-1", "user@example.com")
+		require.NoError(t, err)
+		assert.Equal(t, "cus_1", customerID)
+	})
+
+	t.Run("create and store customer", func(t *testing.T) {
+		t.Parallel()
+
+		querier := newMockBillingQuerier(nil)
+		payments := setupTestPayments(t, querier, func(writer http.ResponseWriter, _ *http.Request) {
+			_, _ = writer.Write([]byte(`{"id": "cus_new"}`))
+		})
+
+		customerID, err := payments.EnsureCustomer(context.Background(), "user-1", "user@example.com")
+		require.NoError(t, err)
+		assert.Equal(t, "cus_new", customerID)
+		assert.Equal(t, "cus_new", querier.customers["user-1"].CustomerID)
+	})
+
+	t.Run("return error of database", func(t *testing.T) {
+		t.Parallel()
+
+		querier := newMockBillingQuerier(nil)
+		querier.err = errQueryFailed
+		payments := setupTestPayments(t, querier, nil)
+
+		_, err := payments.EnsureCustomer(context.Background(), "user-1", "user@example.com")
+		require.ErrorIs(t, err, errQueryFailed)
+	})
+}
+
+func TestSyncSubscription(t *testing.T) {
+	t.Parallel()
+
+	t.Run("store subscription and clear cached entitlements", func(t *testing.T) {
+		t.Parallel()
+
+		userID := testUserID(t)
+		var canceled atomic.Bool
+
+		querier := newMockBillingQuerier(map[string]string{userID: "cus_1"})
+		payments := setupTestPayments(t, querier, func(writer http.ResponseWriter, _ *http.Request) {
+			status := "active"
+			if canceled.Load() {
+				status = "canceled"
+			}
+
+			writeSubscription(writer, "sub_1", "cus_1", status, "price_pro")
+		})
+
+		require.NoError(t, payments.SyncSubscription(context.Background(), "sub_1"))
+
+		entitled, err := payments.HasEntitlement(context.Background(), userID, "reports")
+		require.NoError(t, err)
+		assert.True(t, entitled)
+
+		canceled.Store(true)
+
+		require.NoError(t, payments.SyncSubscription(context.Background(), "sub_1"))
+		assert.Equal(t, "canceled", querier.subscriptions["sub_1"].Status)
+
+		entitled, err = payments.HasEntitlement(context.Background(), userID, "reports")
+		require.NoError(t, err)
+		assert.False(t, entitled)
+	})
+
+	t.Run("ignore subscription of unknown customer", func(t *testing.T) {
+		t.Parallel()
+
+		querier := newMockBillingQuerier(nil)
+		payments := setupTestPayments(t, querier, func(writer http.ResponseWriter, _ *http.Request) {
+			writeSubscription(writer, "sub_1", "cus_unknown", "active", "price_pro")
+		})
+
+		require.NoError(t, payments.SyncSubscription(context.Background(), "sub_1"))
+		assert.Contains(t, querier.subscriptions, "sub_1")
+	})
+
+	t.Run("return error of stripe", func(t *testing.T) {
+		t.Parallel()
+
+		querier := newMockBillingQuerier(nil)
+		payments := setupTestPayments(t, querier, nil)
+
+		var stripeErr *StripeError
+		require.ErrorAs(t, payments.SyncSubscription(context.Background(), "sub_1"), &stripeErr)
+		assert.Empty(t, querier.subscriptions)
+	})
+}
+
+func TestSyncCustomer(t *testing.T) {
+	t.Parallel()
+
+	t.Run("store all subscriptions of customer", func(t *testing.T) {
+		t.Parallel()
+
+		userID := testUserID(t)
+		querier := newMockBillingQuerier(map[string]string{userID: "cus_1"})
+		payments := setupTestPayments(t, querier, func(writer http.ResponseWriter, _ *http.Request) {
+			_, _ = writer.Write([]byte(`{"has_more": false, "data": [
+				{"id": "sub_1", "customer": "cus_1", "status": "active"},
+				{"id": "sub_2", "customer": "cus_1", "status": "canceled"}
+			]}`))
+		})
+
+		require.NoError(t, payments.SyncCustomer(context.Background(), userID))
+		assert.Len(t, querier.subscriptions, 2)
+	})
+
+	t.Run("return error of user without customer", func(t *testing.T) {
+		t.Parallel()
+
+		payments := setupTestPayments(t, newMockBillingQuerier(nil), nil)
+
+		require.ErrorIs(t, payments.SyncCustomer(context.Background(), "user-1"), ErrNoCustomer)
+	})
+}
+
+func TestEntitlements(t *testing.T) {
+	t.Parallel()
+
+	t.Run("return entitlements of active subscriptions", func(t *testing.T) {
+		t.Parallel()
+
+		userID := testUserID(t)
+		querier := newMockBillingQuerier(map[string]string{userID: "cus_1"})
+		querier.subscriptions["sub_1"] = &db.BillingSubscription{
+			ID: "sub_1", CustomerID: "cus_1", Status: "trialing", PriceIds: []string{"price_pro", "price_unknown"},
+		}
+		querier.subscriptions["sub_2"] = &db.BillingSubscription{
+			ID: "sub_2", CustomerID: "cus_1", Status: "active", PriceIds: []string{"price_pro"},
+		}
+		querier.subscriptions["sub_3"] = &db.BillingSubscription{
+			ID: "sub_3", CustomerID: "cus_1", Status: "past_due", PriceIds: []string{"price_seats"},
+		}
+		payments := setupTestPayments(t, querier, nil)
+
+		entitlements, err := payments.Entitlements(context.Background(), userID)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"exports", "reports"}, entitlements)
+	})
+
+	t.Run("return cached entitlements", func(t *testing.T) {
+		t.Parallel()
+
+		userID := testUserID(t)
+		querier := newMockBillingQuerier(map[string]string{userID: "cus_1"})
+		querier.subscriptions["sub_1"] = &db.BillingSubscription{
+			ID: "sub_1", CustomerID: "cus_1", Status: "active", PriceIds: []string{"price_seats"},
+		}
+		payments := setupTestPayments(t, querier, nil)
+
+		_, err := payments.Entitlements(context.Background(), userID)
+		require.NoError(t, err)
+
+		// database failures are not reached while entitlements are cached
+		querier.mu.Lock()
+		querier.err = errQueryFailed
+		querier.mu.Unlock()
+
+		entitlements, err := payments.Entitlements(context.Background(), userID)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"seats"}, entitlements)
+	})
+
+	t.Run("return no entitlements of user without customer", func(t *testing.T) {
+		t.Parallel()
+
+		payments := setupTestPayments(t, newMockBillingQuerier(nil), nil)
+
+		entitlements, err := payments.Entitlements(context.Background(), testUserID(t))
+		require.NoError(t, err)
+		assert.Empty(t, entitlements)
+	})
+
+	t.Run("return error of database", func(t *testing.T) {
+		t.Parallel()
+
+		querier := newMockBillingQuerier(nil)
+		querier.err = errQueryFailed
+		payments := setupTestPayments(t, querier, nil)
+
+		_, err := payments.HasEntitlement(context.Background(), testUserID(t), "reports")
+		require.ErrorIs(t, err, errQueryFailed)
+	})
+}
@@ -0,0 +1,252 @@
+package payments
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	// maxResponseSize is maximum size of responses of Stripe in bytes.
+	maxResponseSize = 1 << 20
+
+	// listLimit is number of subscriptions requested per page.
+	listLimit = "100"
+)
+
+// StripeError represents an error response of Stripe.
+type StripeError struct {
+	// StatusCode is HTTP status code of the response.
+	StatusCode int `json:"-"`
+
+	// Type is type of the error, e.g. invalid_request_error.
+	Type string `json:"type"`
+
+	// Code is code of the error, e.g. resource_missing.
+	Code string `json:"code"`
+
+	// Message is human readable message of the error.
+	Message string `json:"message"`
+}
+
+// Error returns message of the error.
+func (e *StripeError) Error() string {
+	return fmt.Sprintf("stripe: %s (status %d, type %s, code %s)", e.Message, e.StatusCode, e.Type, e.Code)
+}
+
+// Customer represents a customer of Stripe.
+type Customer struct {
+	// ID is ID of the customer, e.g. cus_123.
+	ID string `json:"id"`
+
+	// Email is email of the customer.
+	Email string `json:"email"`
+}
+
+// Subscription represents a subscription of Stripe.
+type Subscription struct {
+	// ID is ID of the subscription, e.g. sub_123.
+	ID string `json:"id"`
+
+	// CustomerID is ID of the subscribed customer.
+	CustomerID string `json:"customer_id"`
+
+	// Status is status of the subscription, e.g. active or canceled.
+	Status string `json:"status"`
+
+	// PriceIDs is IDs of the subscribed prices.
+	PriceIDs []string `json:"price_ids"`
+
+	// CurrentPeriodEnd is end of the current billing period, zero if unknown.
+	CurrentPeriodEnd time.Time `json:"current_period_end"`
+
+	// CancelAtPeriodEnd is whether the subscription is canceled at the end of the current period.
+	CancelAtPeriodEnd bool `json:"cancel_at_period_end"`
+}
+
+// stripeSubscription represents a subscription object of the Stripe API.
+type stripeSubscription struct {
+	ID                string `json:"id"`
+	Customer          string `json:"customer"`
+	Status            string `json:"status"`
+	CancelAtPeriodEnd bool   `json:"cancel_at_period_end"`
+
+	// CurrentPeriodEnd is set by API versions before 2025-03-31, later versions set it on items.
+	CurrentPeriodEnd int64 `json:"current_period_end"`
+
+	Items struct {
+		Data []struct {
+			CurrentPeriodEnd int64 `json:"current_period_end"`
+			Price            struct {
+				ID string `json:"id"`
+			} `json:"price"`
+		} `json:"data"`
+	} `json:"items"`
+}
+
+// subscription converts the subscription object.
+func (s *stripeSubscription) subscription() *Subscription {
+	subscription := &Subscription{
+		ID:                s.ID,
+		CustomerID:        s.Customer,
+		Status:            s.Status,
+		PriceIDs:          make([]string, 0, len(s.Items.Data)),
+		CancelAtPeriodEnd: s.CancelAtPeriodEnd,
+	}
+
+	periodEnd := s.CurrentPeriodEnd
+
+	for _, item := range s.Items.Data {
+		subscription.PriceIDs = append(subscription.PriceIDs, item.Price.ID)
+		periodEnd = max(periodEnd, item.CurrentPeriodEnd)
+	}
+
+	if periodEnd > 0 {
+		subscription.CurrentPeriodEnd = time.Unix(periodEnd, 0).UTC()
+	}
+
+	return subscription
+}
+
+// Client is a client of the Stripe API.
+type Client struct {
+	// config provides payments configuration.
+	config *Config
+
+	// http provides HTTP client.
+	http *http.Client
+}
+
+// NewClient creates a new client of the Stripe API sending requests with the HTTP client.
+func NewClient(config *Config, httpClient *http.Client) *Client {
+	if config == nil {
+		config = &Config{}
+	}
+
+	config.SetDefault()
+
+	return &Client{config: config, http: httpClient}
+}
+
+// CreateCustomer creates a customer of the user, requests with the same user ID within 24 hours
+// return the same customer, so that concurrent calls do not create duplicates.
+func (c *Client) CreateCustomer(ctx context.Context, userID, email string) (*Customer, error) {
+	form := url.Values{}
+	form.Set("email", email)
+	form.Set("metadata[user_id]", userID)
+
+	var customer Customer
+	if err := c.do(ctx, http.MethodPost, "/v1/customers", form, "customer-"+userID, &customer); err != nil {
+		return nil, fmt.Errorf("failed to create customer: %w", err)
+	}
+
+	return &customer, nil
+}
+
+// GetSubscription returns the subscription.
+func (c *Client) GetSubscription(ctx context.Context, id string) (*Subscription, error) {
+	var subscription stripeSubscription
+	if err := c.do(ctx, http.MethodGet, "/v1/subscriptions/"+url.PathEscape(id), nil, "", &subscription); err != nil {
+		return nil, fmt.Errorf("failed to get subscription %s: %w", id, err)
+	}
+
+	return subscription.subscription(), nil
+}
+
+// ListSubscriptions returns all subscriptions of the customer, including canceled ones.
+func (c *Client) ListSubscriptions(ctx context.Context, customerID string) ([]*Subscription, error) {
+	var subscriptions []*Subscription
+
+	query := url.Values{}
+	query.Set("customer", customerID)
+	query.Set("status", "all")
+	query.Set("limit", listLimit)
+
+	for {
+		var page struct {
+			Data    []*stripeSubscription `json:"data"`
+			HasMore bool                  `json:"has_more"`
+		}
+
+		if err := c.do(ctx, http.MethodGet, "/v1/subscriptions", query, "", &page); err != nil {
+			return nil, fmt.Errorf("failed to list subscriptions of customer %s: %w", customerID, err)
+		}
+
+		for _, subscription := range page.Data {
+			subscriptions = append(subscriptions, subscription.subscription())
+		}
+
+		if !page.HasMore || len(page.Data) == 0 {
+			return subscriptions, nil
+		}
+
+		query.Set("starting_after", page.Data[len(page.Data)-1].ID)
+	}
+}
+
+// do sends the request to the Stripe API and decodes the response into out,
+// form is sent as the body of POST requests and as the query of other requests.
+func (c *Client) do(ctx context.Context, method, path string, form url.Values, idempotencyKey string, out any) error {
+	target := strings.TrimSuffix(*c.config.APIURL, "/") + path
+
+	var body io.Reader
+
+	if method == http.MethodPost {
+		body = strings.NewReader(form.Encode())
+	} else if len(form) > 0 {
+		target += "?" + form.Encode()
+	}
+
+	request, err := http.NewRequestWithContext(ctx, method, target, body)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+
+	request.Header.Set("Authorization", "Bearer "+*c.config.SecretKey)
+
+	if method == http.MethodPost {
+		request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	}
+
+	if idempotencyKey != "" {
+		request.Header.Set("Idempotency-Key", idempotencyKey)
+	}
+
+	if *c.config.APIVersion != "" {
+		request.Header.Set("Stripe-Version", *c.config.APIVersion)
+	}
+
+	response, err := c.http.Do(request)
+	if err != nil {
+		return fmt.Errorf("failed to send request: %w", err)
+	}
+	defer response.Body.Close()
+
+	content, err := io.ReadAll(io.LimitReader(response.Body, maxResponseSize))
+	if err != nil {
+		return fmt.Errorf("failed to read response: %w", err)
+	}
+
+	if response.StatusCode >= http.StatusBadRequest {
+		var envelope struct {
+			Error StripeError `json:"error"`
+		}
+
+		// a body which is not an error envelope still returns the status
+		_ = json.Unmarshal(content, &envelope)
+		envelope.Error.StatusCode = response.StatusCode
+
+		return &envelope.Error
+	}
+
+	if err := json.Unmarshal(content, out); err != nil {
+		return fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	return nil
+}
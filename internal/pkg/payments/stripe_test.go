@@ -0,0 +1,155 @@
+package payments
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// setupTestClient creates a client of a fake Stripe API served by the handler.
+func setupTestClient(t *testing.T, handler http.HandlerFunc) *Client {
+	t.Helper()
+
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	return NewClient(&Config{
+		SecretKey:  &[]string{"sk_test"}[0],
+		APIURL:     &server.URL,
+		APIVersion: &[]string{"2025-03-31.basil"}[0],
+	}, server.Client())
+}
+
+// writeSubscription writes a subscription object of the customer with the prices.
+func writeSubscription(writer http.ResponseWriter, id, customerID, status string, priceIDs ...string) {
+	items := ""
+
+	for i, priceID := range priceIDs {
+		if i > 0 {
+			items += ","
+		}
+
+		items += fmt.Sprintf(`{"current_period_end": 1735689600, "price": {"id": %q}}`, priceID)
+	}
+
+	_, _ = fmt.Fprintf(writer, `{"id": %q, "customer": %q, "status": %q, "cancel_at_period_end": true,
+		"items": {"data": [%s]}}`, id, customerID, status, items)
+}
+
+func TestCreateCustomer(t *testing.T) {
+	t.Parallel()
+
+	t.Run("create customer of user idempotently", func(t *testing.T) {
+		t.Parallel()
+
+		client := setupTestClient(t, func(writer http.ResponseWriter, request *http.Request) {
+			assert.Equal(t, http.MethodPost, request.Method)
+			assert.Equal(t, "/v1/customers", request.URL.Path)
+			assert.Equal(t, "Bearer sk_test", request.Header.Get("Authorization"))
+			assert.Equal(t, "2025-03-31.basil", request.Header.Get("Stripe-Version"))
+			assert.Equal(t, "customer-user-1", request.Header.Get("Idempotency-Key"))
+			assert.NoError(t, request.ParseForm())
+			assert.Equal(t, "user@example.com", request.PostForm.Get("email"))
+			assert.Equal(t, "user-1", request.PostForm.Get("metadata[user_id]"))
+
+			_, _ = writer.Write([]byte(`{"id": "cus_1", "email": "user@example.com"}`))
+		})
+
+		customer, err := client.CreateCustomer(context.Background(), "user-1", "user@example.com")
+		require.NoError(t, err)
+		assert.Equal(t, &Customer{ID: "cus_1", Email: "user@example.com"}, customer)
+	})
+
+	t.Run("return stripe error", func(t *testing.T) {
+		t.Parallel()
+
+		client := setupTestClient(t, func(writer http.ResponseWriter, _ *http.Request) {
+			writer.WriteHeader(http.StatusUnauthorized)
+			_, _ = writer.Write([]byte(`{"error": {"type": "invalid_request_error", "message": "Invalid API Key"}}`))
+		})
+
+		_, err := client.CreateCustomer(context.Background(), "user-1", "user@example.com")
+
+		var stripeErr *StripeError
+		require.ErrorAs(t, err, &stripeErr)
+		assert.Equal(t, http.StatusUnauthorized, stripeErr.StatusCode)
+		assert.Equal(t, "invalid_request_error", stripeErr.Type)
+		assert.Equal(t, "Invalid API Key", stripeErr.Message)
+	})
+}
+
+func TestGetSubscription(t *testing.T) {
+	t.Parallel()
+
+	t.Run("convert subscription object", func(t *testing.T) {
+		t.Parallel()
+
+		client := setupTestClient(t, func(writer http.ResponseWriter, request *http.Request) {
+			assert.Equal(t, http.MethodGet, request.Method)
+			assert.Equal(t, "/v1/subscriptions/sub_1", request.URL.Path)
+			assert.Empty(t, request.Header.Get("Idempotency-Key"))
+
+			writeSubscription(writer, "sub_1", "cus_1", "active", "price_pro", "price_seats")
+		})
+
+		subscription, err := client.GetSubscription(context.Background(), "sub_1")
+		require.NoError(t, err)
+		assert.Equal(t, &Subscription{
+			ID:                "sub_1",
+			CustomerID:        "cus_1",
+			Status:            "active",
+			PriceIDs:          []string{"price_pro", "price_seats"},
+			CurrentPeriodEnd:  time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC),
+			CancelAtPeriodEnd: true,
+		}, subscription)
+	})
+
+	t.Run("return error of missing subscription", func(t *testing.T) {
+		t.Parallel()
+
+		client := setupTestClient(t, func(writer http.ResponseWriter, _ *http.Request) {
+			writer.WriteHeader(http.StatusNotFound)
+			_, _ = writer.Write([]byte(`{"error": {"code": "resource_missing"}}`))
+		})
+
+		_, err := client.GetSubscription(context.Background(), "sub_missing")
+
+		var stripeErr *StripeError
+		require.ErrorAs(t, err, &stripeErr)
+		assert.Equal(t, "resource_missing", stripeErr.Code)
+	})
+}
+
+func TestListSubscriptions(t *testing.T) {
+	t.Parallel()
+
+	t.Run("list all pages", func(t *testing.T) {
+		t.Parallel()
+
+		client := setupTestClient(t, func(writer http.ResponseWriter, request *http.Request) {
+			assert.Equal(t, "cus_1", request.URL.Query().Get("customer"))
+			assert.Equal(t, "all", request.URL.Query().Get("status"))
+
+			if request.URL.Query().Get("starting_after") == "" {
+				_, _ = writer.Write([]byte(`{"has_more": true, "data": [{"id": "sub_1", "customer": "cus_1"}]}`))
+
+				return
+			}
+
+			assert.Equal(t, "sub_1", request.URL.Query().Get("starting_after"))
+			_, _ = writer.Write([]byte(`{"has_more": false, "data": [{"id": "sub_2", "customer": "cus_1"}]}`))
+		})
+
+		subscriptions, err := client.ListSubscriptions(context.Background(), "cus_1")
+		require.NoError(t, err)
+		require.Len(t, subscriptions, 2)
+		assert.Equal(t, "sub_1", subscriptions[0].ID)
+		assert.Equal(t, "sub_2", subscriptions[1].ID)
+	})
+}
@@ -0,0 +1,118 @@
+package payments
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrInvalidSignature is returned when a webhook event is not signed with the webhook secret or is too old.
+var ErrInvalidSignature = errors.New("invalid webhook signature")
+
+// Event represents a webhook event of Stripe.
+type Event struct {
+	// ID is ID of the event, e.g. evt_123.
+	ID string `json:"id"`
+
+	// Type is type of the event, e.g. customer.subscription.updated.
+	Type string `json:"type"`
+
+	// Data holds the object of the event.
+	Data struct {
+		// Object is the object the event is about.
+		Object json.RawMessage `json:"object"`
+	} `json:"data"`
+}
+
+// subscriptionEvents is types of events of changed subscriptions.
+var subscriptionEvents = []string{
+	"customer.subscription.created",
+	"customer.subscription.updated",
+	"customer.subscription.deleted",
+	"customer.subscription.paused",
+	"customer.subscription.resumed",
+}
+
+// VerifySignature verifies the Stripe-Signature header of the payload, signed at most tolerance before now.
+func VerifySignature(payload []byte, header, secret string, tolerance time.Duration, now time.Time) error {
+	var (
+		timestamp  string
+		signatures [][]byte
+	)
+
+	for _, part := range strings.Split(header, ",") {
+		key, value, _ := strings.Cut(strings.TrimSpace(part), "=")
+
+		switch key {
+		case "t":
+			timestamp = value
+		case "v1":
+			// signatures which are not hex never match
+			if signature, err := hex.DecodeString(value); err == nil {
+				signatures = append(signatures, signature)
+			}
+		}
+	}
+
+	signedAt, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil || len(signatures) == 0 {
+		return fmt.Errorf("%w: malformed header", ErrInvalidSignature)
+	}
+
+	if age := now.Sub(time.Unix(signedAt, 0)); age > tolerance || age < -tolerance {
+		return fmt.Errorf("%w: timestamp outside tolerance", ErrInvalidSignature)
+	}
+
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp + "."))
+	mac.Write(payload)
+	expected := mac.Sum(nil)
+
+	// the header has a signature per active secret while secrets are rolled
+	for _, signature := range signatures {
+		if hmac.Equal(signature, expected) {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("%w: no matching signature", ErrInvalidSignature)
+}
+
+// HandleWebhook verifies the webhook event and syncs the subscription it is about,
+// events of other types are ignored.
+func (p *Payments) HandleWebhook(ctx context.Context, payload []byte, signature string) error {
+	err := VerifySignature(payload, signature, *p.config.WebhookSecret, *p.config.WebhookTolerance, p.now())
+	if err != nil {
+		return err
+	}
+
+	var event Event
+	if err := json.Unmarshal(payload, &event); err != nil {
+		return fmt.Errorf("failed to decode webhook event: %w", err)
+	}
+
+	if !slices.Contains(subscriptionEvents, event.Type) {
+		return nil
+	}
+
+	var object struct {
+		ID string `json:"id"`
+	}
+
+	if err := json.Unmarshal(event.Data.Object, &object); err != nil {
+		return fmt.Errorf("failed to decode object of event %s: %w", event.ID, err)
+	}
+
+	p.logger.Ctx(ctx).Info().Str("event_id", event.ID).Str("type", event.Type).Str("subscription_id", object.ID).
+		Msg("syncing subscription")
+
+	return p.SyncSubscription(ctx, object.ID)
+}
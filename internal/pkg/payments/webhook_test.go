@@ -0,0 +1,128 @@
+package payments
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// sign returns the Stripe-Signature header of the payload signed with the secret at the time.
+func sign(payload []byte, secret string, signedAt time.Time) string {
+	timestamp := fmt.Sprintf("%d", signedAt.Unix())
+
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp + "."))
+	mac.Write(payload)
+
+	return "t=" + timestamp + ",v1=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func TestVerifySignature(t *testing.T) {
+	t.Parallel()
+
+	payload := []byte(`{"id": "evt_1"}`)
+	now := time.Unix(1700000000, 0)
+
+	t.Run("accept signature of secret", func(t *testing.T) {
+		t.Parallel()
+
+		header := sign(payload, "whsec_test", now.Add(-time.Minute))
+
+		require.NoError(t, VerifySignature(payload, header, "whsec_test", 5*time.Minute, now))
+	})
+
+	t.Run("accept any signature while secrets are rolled", func(t *testing.T) {
+		t.Parallel()
+
+		header := sign(payload, "whsec_old", now) + ",v1=" + sign(payload, "whsec_test", now)[len("t=1700000000,v1="):]
+
+		require.NoError(t, VerifySignature(payload, header, "whsec_test", 5*time.Minute, now))
+	})
+
+	t.Run("reject invalid signatures", func(t *testing.T) {
+		t.Parallel()
+
+		tests := map[string]string{
+			"wrong secret":     sign(payload, "whsec_other", now),
+			"too old":          sign(payload, "whsec_test", now.Add(-10*time.Minute)),
+			"in future":        sign(payload, "whsec_test", now.Add(10*time.Minute)),
+			"no signature":     "t=1700000000",
+			"no timestamp":     "v1=abcd",
+			"not hex":          "t=1700000000,v1=zz",
+			"empty header":     "",
+			"modified payload": sign([]byte(`{"id": "evt_2"}`), "whsec_test", now),
+		}
+
+		for name, header := range tests {
+			err := VerifySignature(payload, header, "whsec_test", 5*time.Minute, now)
+			require.ErrorIs(t, err, ErrInvalidSignature, name)
+		}
+	})
+}
+
+func TestHandleWebhook(t *testing.T) {
+	t.Parallel()
+
+	t.Run("sync subscription of event", func(t *testing.T) {
+		t.Parallel()
+
+		querier := newMockBillingQuerier(map[string]string{testUserID(t): "cus_1"})
+		payments := setupTestPayments(t, querier, func(writer http.ResponseWriter, request *http.Request) {
+			assert.Equal(t, "/v1/subscriptions/sub_1", request.URL.Path)
+
+			writeSubscription(writer, "sub_1", "cus_1", "active", "price_pro")
+		})
+
+		// the object of the event is stale, the subscription is fetched from Stripe
+		payload := []byte(`{"id": "evt_1", "type": "customer.subscription.updated",
+			"data": {"object": {"id": "sub_1", "status": "incomplete"}}}`)
+
+		err := payments.HandleWebhook(context.Background(), payload, sign(payload, "whsec_test", time.Now()))
+		require.NoError(t, err)
+		assert.Equal(t, "active", querier.subscriptions["sub_1"].Status)
+	})
+
+	t.Run("ignore events of other types", func(t *testing.T) {
+		t.Parallel()
+
+		querier := newMockBillingQuerier(nil)
+		payments := setupTestPayments(t, querier, nil)
+
+		payload := []byte(`{"id": "evt_1", "type": "invoice.paid", "data": {"object": {"id": "in_1"}}}`)
+
+		err := payments.HandleWebhook(context.Background(), payload, sign(payload, "whsec_test", time.Now()))
+		require.NoError(t, err)
+		assert.Empty(t, querier.subscriptions)
+	})
+
+	t.Run("reject event with invalid signature", func(t *testing.T) {
+		t.Parallel()
+
+		payments := setupTestPayments(t, newMockBillingQuerier(nil), nil)
+
+		payload := []byte(`{"id": "evt_1", "type": "customer.subscription.deleted"}`)
+
+		err := payments.HandleWebhook(context.Background(), payload, sign(payload, "whsec_other", time.Now()))
+		require.ErrorIs(t, err, ErrInvalidSignature)
+	})
+
+	t.Run("return error of malformed event", func(t *testing.T) {
+		t.Parallel()
+
+		payments := setupTestPayments(t, newMockBillingQuerier(nil), nil)
+
+		payload := []byte(`not json`)
+
+		err := payments.HandleWebhook(context.Background(), payload, sign(payload, "whsec_test", time.Now()))
+		require.Error(t, err)
+		require.NotErrorIs(t, err, ErrInvalidSignature)
+	})
+}
@@ -0,0 +1,226 @@
+package proxy
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// defaultHealthCheckInterval is default interval in seconds of health checks.
+	defaultHealthCheckInterval = 10
+
+	// defaultHealthCheckTimeout is default time in seconds upstreams have to respond to health checks.
+	defaultHealthCheckTimeout = 2
+
+	// defaultUnhealthyThreshold is default number of consecutive failures marking an upstream unhealthy.
+	defaultUnhealthyThreshold = 3
+
+	// defaultHealthyThreshold is default number of consecutive successes marking an upstream healthy again.
+	defaultHealthyThreshold = 2
+)
+
+// errUnhealthyStatus is returned when an upstream responds to a health check with an error status.
+var errUnhealthyStatus = errors.New("upstream responded to health check with an error status")
+
+// HealthCheckConfig represents configuration for health checking of upstreams.
+type HealthCheckConfig struct {
+	// Path is path of upstreams requested by health checks, health checking is disabled if empty.
+	Path *string `json:"path"`
+
+	// Interval is interval in seconds of health checks.
+	Interval *int `json:"interval"`
+
+	// Timeout is time in seconds upstreams have to respond to health checks.
+	Timeout *int `json:"timeout"`
+
+	// UnhealthyThreshold is number of consecutive failures of health checks or proxied requests
+	// marking an upstream unhealthy.
+	UnhealthyThreshold *int `json:"unhealthy_threshold"`
+
+	// HealthyThreshold is number of consecutive successful health checks marking an upstream healthy again.
+	HealthyThreshold *int `json:"healthy_threshold"`
+}
+
+// SetDefault sets default values.
+func (c *HealthCheckConfig) SetDefault() {
+	if c.Path == nil {
+		c.Path = &[]string{""}[0]
+	}
+
+	if c.Interval == nil {
+		c.Interval = &[]int{defaultHealthCheckInterval}[0]
+	}
+
+	if c.Timeout == nil {
+		c.Timeout = &[]int{defaultHealthCheckTimeout}[0]
+	}
+
+	if c.UnhealthyThreshold == nil {
+		c.UnhealthyThreshold = &[]int{defaultUnhealthyThreshold}[0]
+	}
+
+	if c.HealthyThreshold == nil {
+		c.HealthyThreshold = &[]int{defaultHealthyThreshold}[0]
+	}
+}
+
+// upstream represents an upstream of the proxy.
+type upstream struct {
+	// url is URL of the upstream.
+	url *url.URL
+
+	// healthy is whether requests are proxied to the upstream.
+	healthy atomic.Bool
+
+	// mu guards the counters below.
+	mu sync.Mutex
+
+	// failures is number of consecutive failures.
+	failures int
+
+	// successes is number of consecutive successes.
+	successes int
+}
+
+// newUpstream creates an upstream, healthy until checks fail.
+func newUpstream(upstreamURL *url.URL) *upstream {
+	upstream := &upstream{url: upstreamURL}
+	upstream.healthy.Store(true)
+
+	return upstream
+}
+
+// report records the result of a check, returns whether the health of the upstream changed.
+func (u *upstream) report(ok bool, config *HealthCheckConfig) bool {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	if ok {
+		u.failures = 0
+		u.successes++
+
+		return u.successes >= *config.HealthyThreshold && !u.healthy.Swap(true)
+	}
+
+	u.successes = 0
+	u.failures++
+
+	return u.failures >= *config.UnhealthyThreshold && u.healthy.Swap(false)
+}
+
+// HealthCheckEnabled returns whether upstreams are health checked.
+func (p *Proxy) HealthCheckEnabled() bool {
+	return *p.config.HealthCheck.Path != ""
+}
+
+// Start starts health checking upstreams every interval, it does nothing if health checking is disabled.
+func (p *Proxy) Start() {
+	if !p.HealthCheckEnabled() || p.cancel != nil {
+		return
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	p.cancel, p.done = cancel, make(chan struct{})
+
+	go func() {
+		defer close(p.done)
+
+		ticker := time.NewTicker(p.interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				p.checkAll(ctx)
+			}
+		}
+	}()
+}
+
+// Stop stops health checking upstreams.
+func (p *Proxy) Stop() {
+	if p.cancel == nil {
+		return
+	}
+
+	p.cancel()
+	<-p.done
+
+	p.cancel = nil
+}
+
+// checkAll health checks all upstreams concurrently.
+func (p *Proxy) checkAll(ctx context.Context) {
+	var wg sync.WaitGroup
+
+	for _, upstream := range p.upstreams {
+		wg.Go(func() {
+			err := p.check(ctx, upstream)
+			if ctx.Err() != nil {
+				return
+			}
+
+			p.report(upstream, err)
+		})
+	}
+
+	wg.Wait()
+}
+
+// check requests the health check path of the upstream, redirects are considered healthy.
+func (p *Proxy) check(ctx context.Context, upstream *upstream) error {
+	target := upstream.url.JoinPath(*p.config.HealthCheck.Path).String()
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, target, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create health check request: %w", err)
+	}
+
+	response, err := p.client.Do(request)
+	if err != nil {
+		return fmt.Errorf("failed to check health: %w", err)
+	}
+
+	defer func() {
+		_, _ = io.Copy(io.Discard, response.Body)
+		_ = response.Body.Close()
+	}()
+
+	if response.StatusCode >= http.StatusBadRequest {
+		return fmt.Errorf("%w: %d", errUnhealthyStatus, response.StatusCode)
+	}
+
+	return nil
+}
+
+// reportFailure records a failure of a proxied request to the upstream, it does nothing if health checking is
+// disabled, since unhealthy upstreams are only marked healthy again by health checks.
+func (p *Proxy) reportFailure(upstream *upstream, err error) {
+	if p.HealthCheckEnabled() {
+		p.report(upstream, err)
+	}
+}
+
+// report records the result of a check of the upstream, logging changes of its health.
+func (p *Proxy) report(upstream *upstream, err error) {
+	if !upstream.report(err == nil, p.config.HealthCheck) {
+		return
+	}
+
+	if err != nil {
+		p.logger.Warn().Err(err).Str("upstream", upstream.url.Host).Msg("upstream is unhealthy")
+
+		return
+	}
+
+	p.logger.Info().Str("upstream", upstream.url.Host).Msg("upstream is healthy")
+}
@@ -0,0 +1,138 @@
+package proxy
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHealthCheckConfigSetDefault(t *testing.T) {
+	t.Parallel()
+
+	config := &HealthCheckConfig{}
+	config.SetDefault()
+
+	assert.Empty(t, *config.Path)
+	assert.Equal(t, defaultHealthCheckInterval, *config.Interval)
+	assert.Equal(t, defaultHealthCheckTimeout, *config.Timeout)
+	assert.Equal(t, defaultUnhealthyThreshold, *config.UnhealthyThreshold)
+	assert.Equal(t, defaultHealthyThreshold, *config.HealthyThreshold)
+}
+
+func TestHealthCheck(t *testing.T) {
+	t.Parallel()
+
+	t.Run("mark upstreams unhealthy and healthy again by thresholds", func(t *testing.T) {
+		t.Parallel()
+
+		failing, healthy := newTestUpstream(t, "a"), newTestUpstream(t, "b")
+		failing.status.Store(http.StatusInternalServerError)
+
+		proxy := setupTestProxy(t, &Config{
+			Targets:     []string{failing.URL, healthy.URL},
+			HealthCheck: &HealthCheckConfig{Path: &[]string{"/healthz"}[0]},
+		})
+
+		for range defaultUnhealthyThreshold - 1 {
+			proxy.checkAll(context.Background())
+		}
+
+		assert.True(t, proxy.upstreams[0].healthy.Load())
+
+		proxy.checkAll(context.Background())
+		assert.False(t, proxy.upstreams[0].healthy.Load())
+		assert.True(t, proxy.upstreams[1].healthy.Load())
+
+		// unhealthy upstreams receive no requests
+		requests := failing.requests.Load()
+
+		for range 4 {
+			assert.Equal(t, "b", decode(t, serve(proxy, http.MethodGet, "/legacy/users")).Upstream)
+		}
+
+		assert.Equal(t, requests, failing.requests.Load())
+
+		failing.status.Store(0)
+
+		proxy.checkAll(context.Background())
+		assert.False(t, proxy.upstreams[0].healthy.Load())
+
+		proxy.checkAll(context.Background())
+		assert.True(t, proxy.upstreams[0].healthy.Load())
+	})
+
+	t.Run("mark upstreams unhealthy by failed requests", func(t *testing.T) {
+		t.Parallel()
+
+		down := newTestUpstream(t, "a")
+		down.Close()
+
+		proxy := setupTestProxy(t, &Config{
+			Target:      &down.URL,
+			Retries:     &[]int{0}[0],
+			HealthCheck: &HealthCheckConfig{Path: &[]string{"/healthz"}[0]},
+		})
+
+		for range defaultUnhealthyThreshold {
+			assert.Equal(t, http.StatusBadGateway, serve(proxy, http.MethodGet, "/legacy/users").Code)
+		}
+
+		recorder := serve(proxy, http.MethodGet, "/legacy/users")
+		assert.Equal(t, http.StatusServiceUnavailable, recorder.Code)
+		assert.JSONEq(t, `{"error": "Service Unavailable", "code": "service_unavailable"}`, recorder.Body.String())
+	})
+
+	t.Run("keep upstreams healthy without health checks", func(t *testing.T) {
+		t.Parallel()
+
+		down := newTestUpstream(t, "a")
+		down.Close()
+
+		proxy := setupTestProxy(t, &Config{Target: &down.URL, Retries: &[]int{0}[0]})
+
+		for range defaultUnhealthyThreshold + 1 {
+			assert.Equal(t, http.StatusBadGateway, serve(proxy, http.MethodGet, "/legacy/users").Code)
+		}
+	})
+
+	t.Run("check upstreams every interval until stopped", func(t *testing.T) {
+		t.Parallel()
+
+		upstream := newTestUpstream(t, "a")
+		proxy := setupTestProxy(t, &Config{
+			Target:      &upstream.URL,
+			HealthCheck: &HealthCheckConfig{Path: &[]string{"/healthz"}[0]},
+		})
+		proxy.interval = 10 * time.Millisecond
+
+		proxy.Start()
+		proxy.Start()
+
+		require.Eventually(t, func() bool { return upstream.requests.Load() >= 2 }, time.Second, 10*time.Millisecond)
+
+		proxy.Stop()
+		proxy.Stop()
+
+		// checks canceled by stopping may still reach the upstream
+		time.Sleep(20 * time.Millisecond)
+
+		requests := upstream.requests.Load()
+
+		time.Sleep(50 * time.Millisecond)
+		assert.Equal(t, requests, upstream.requests.Load())
+	})
+
+	t.Run("not start without health check path", func(t *testing.T) {
+		t.Parallel()
+
+		proxy := setupTestProxy(t, &Config{Target: &[]string{"http://localhost"}[0]})
+		assert.False(t, proxy.HealthCheckEnabled())
+
+		proxy.Start()
+		assert.Nil(t, proxy.cancel)
+	})
+}
@@ -0,0 +1,449 @@
+// Package proxy provides a reverse proxy balancing requests over a pool of upstreams, with active and passive
+// health checking, retries of idempotent requests, path rewriting and header allowlists.
+package proxy
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"regexp"
+	"slices"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/apierror"
+	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/logger"
+)
+
+const (
+	// defaultTimeout is default time in seconds upstreams have to respond with headers.
+	defaultTimeout = 30
+
+	// defaultRetries is default number of retries of idempotent requests on other upstreams.
+	defaultRetries = 1
+)
+
+// ErrInvalidConfig is returned when the proxy has no targets, an invalid target or an invalid rewrite rule.
+var ErrInvalidConfig = errors.New("invalid proxy config")
+
+// errRetryableStatus is returned for responses of upstreams retried on another upstream.
+var errRetryableStatus = errors.New("upstream responded with a retryable status")
+
+// idempotentMethods is methods of requests retried on another upstream.
+var idempotentMethods = []string{
+	http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace, http.MethodPut, http.MethodDelete,
+}
+
+// representationHeaders is response headers kept by allowlists, since responses cannot be read without them.
+var representationHeaders = []string{"Content-Type", "Content-Length", "Content-Encoding"}
+
+// Config represents configuration for a reverse proxy.
+type Config struct {
+	// Target is URL of the upstream requests are proxied to, its path is prepended to proxied paths.
+	Target *string `json:"target"`
+
+	// Targets is URLs of further upstreams requests are balanced over in round-robin order.
+	Targets []string `json:"targets"`
+
+	// StripPrefix is whether the prefix of the proxy is removed from proxied paths.
+	StripPrefix *bool `json:"strip_prefix"`
+
+	// PreserveHost is whether the Host header of requests is kept, the host of the upstream is sent if false.
+	PreserveHost *bool `json:"preserve_host"`
+
+	// Timeout is time in seconds upstreams have to respond with headers, 0 to wait until the request is done.
+	Timeout *int `json:"timeout"`
+
+	// Retries is number of retries of idempotent requests without a body on other upstreams,
+	// after connection failures and 502, 503 and 504 responses.
+	Retries *int `json:"retries"`
+
+	// Headers is headers set on proxied requests.
+	Headers map[string]string `json:"headers"`
+
+	// AllowedRequestHeaders is headers of requests forwarded to upstreams, all headers if empty.
+	AllowedRequestHeaders *[]string `json:"allowed_request_headers"`
+
+	// AllowedResponseHeaders is headers of responses returned to clients, all headers if empty,
+	// Content-Type, Content-Length and Content-Encoding are always returned.
+	AllowedResponseHeaders *[]string `json:"allowed_response_headers"`
+
+	// Rewrites is rewrite rules applied in order to proxied paths, after the prefix is stripped.
+	Rewrites []*RewriteConfig `json:"rewrites"`
+
+	// HealthCheck is health checking of upstreams.
+	HealthCheck *HealthCheckConfig `json:"health_check"`
+}
+
+// RewriteConfig represents configuration for a rewrite rule of proxied paths.
+type RewriteConfig struct {
+	// Pattern is regular expression matched against the path.
+	Pattern *string `json:"pattern"`
+
+	// Replacement is replacement of matches, with $1 style references to submatches.
+	Replacement *string `json:"replacement"`
+}
+
+// SetDefault sets default values.
+func (c *Config) SetDefault() {
+	if c.Target == nil {
+		c.Target = &[]string{""}[0]
+	}
+
+	if c.StripPrefix == nil {
+		c.StripPrefix = &[]bool{false}[0]
+	}
+
+	if c.PreserveHost == nil {
+		c.PreserveHost = &[]bool{false}[0]
+	}
+
+	if c.Timeout == nil {
+		c.Timeout = &[]int{defaultTimeout}[0]
+	}
+
+	if c.Retries == nil {
+		c.Retries = &[]int{defaultRetries}[0]
+	}
+
+	if c.AllowedRequestHeaders == nil {
+		c.AllowedRequestHeaders = &[]string{}
+	}
+
+	if c.AllowedResponseHeaders == nil {
+		c.AllowedResponseHeaders = &[]string{}
+	}
+
+	for _, rule := range c.Rewrites {
+		if rule.Pattern == nil {
+			rule.Pattern = &[]string{""}[0]
+		}
+
+		if rule.Replacement == nil {
+			rule.Replacement = &[]string{""}[0]
+		}
+	}
+
+	if c.HealthCheck == nil {
+		c.HealthCheck = &HealthCheckConfig{}
+	}
+
+	c.HealthCheck.SetDefault()
+}
+
+// rewrite represents a compiled rewrite rule.
+type rewrite struct {
+	// pattern is regular expression matched against the path.
+	pattern *regexp.Regexp
+
+	// replacement is replacement of matches.
+	replacement string
+}
+
+// attempt represents an attempt of proxying a request to an upstream.
+type attempt struct {
+	// upstream is upstream the request is proxied to.
+	upstream *upstream
+
+	// retry is whether the request is retried on another upstream if the attempt fails.
+	retry bool
+
+	// err is error of the attempt, set if it is retried.
+	err error
+}
+
+// attemptKey is context key of the attempt of a proxied request.
+type attemptKey struct{}
+
+// Proxy represents a reverse proxy balancing requests over upstreams.
+type Proxy struct {
+	// config provides proxy configuration.
+	config *Config
+
+	// prefix is path prefix stripped from proxied paths.
+	prefix string
+
+	// upstreams is upstreams requests are balanced over.
+	upstreams []*upstream
+
+	// next is counter of round-robin selection of upstreams.
+	next atomic.Uint64
+
+	// rewrites is rewrite rules of proxied paths.
+	rewrites []rewrite
+
+	// allowedRequestHeaders is canonical headers of requests forwarded to upstreams, all headers if empty.
+	allowedRequestHeaders []string
+
+	// allowedResponseHeaders is canonical headers of responses returned to clients, all headers if empty.
+	allowedResponseHeaders []string
+
+	// proxy proxies requests to the upstream of their attempt.
+	proxy *httputil.ReverseProxy
+
+	// client sends health checks.
+	client *http.Client
+
+	// interval is interval of health checks, replaced in tests.
+	interval time.Duration
+
+	// logger provides logger.
+	logger *logger.Logger
+
+	// cancel stops health checks, nil if they are not running.
+	cancel context.CancelFunc
+
+	// done is closed when health checks are stopped.
+	done chan struct{}
+}
+
+// New creates a new reverse proxy, prefix is path prefix of proxied requests stripped if StripPrefix is set.
+func New(config *Config, prefix string, logger *logger.Logger) (*Proxy, error) {
+	if config == nil {
+		config = &Config{}
+	}
+
+	config.SetDefault()
+
+	proxy := &Proxy{
+		config:                 config,
+		prefix:                 strings.TrimSuffix(prefix, "/"),
+		allowedRequestHeaders:  canonicalHeaders(*config.AllowedRequestHeaders),
+		allowedResponseHeaders: canonicalHeaders(*config.AllowedResponseHeaders),
+		logger:                 logger.Named("proxy"),
+	}
+
+	if len(proxy.allowedResponseHeaders) > 0 {
+		proxy.allowedResponseHeaders = append(proxy.allowedResponseHeaders, representationHeaders...)
+	}
+
+	targets := config.Targets
+	if *config.Target != "" {
+		targets = append([]string{*config.Target}, targets...)
+	}
+
+	if len(targets) == 0 {
+		return nil, fmt.Errorf("%w: no targets for %s", ErrInvalidConfig, prefix)
+	}
+
+	for _, target := range targets {
+		targetURL, err := url.Parse(target)
+		if err != nil || (targetURL.Scheme != "http" && targetURL.Scheme != "https") || targetURL.Host == "" {
+			return nil, fmt.Errorf("%w: target %q of %s", ErrInvalidConfig, target, prefix)
+		}
+
+		proxy.upstreams = append(proxy.upstreams, newUpstream(targetURL))
+	}
+
+	for _, rule := range config.Rewrites {
+		pattern, err := regexp.Compile(*rule.Pattern)
+		if err != nil || *rule.Pattern == "" {
+			return nil, fmt.Errorf("%w: rewrite pattern %q of %s", ErrInvalidConfig, *rule.Pattern, prefix)
+		}
+
+		proxy.rewrites = append(proxy.rewrites, rewrite{pattern: pattern, replacement: *rule.Replacement})
+	}
+
+	transport, _ := http.DefaultTransport.(*http.Transport)
+	transport = transport.Clone()
+	transport.ResponseHeaderTimeout = time.Duration(*config.Timeout) * time.Second
+
+	proxy.proxy = &httputil.ReverseProxy{
+		Rewrite:        proxy.rewrite,
+		Transport:      transport,
+		ModifyResponse: proxy.modifyResponse,
+		ErrorHandler:   proxy.handleError,
+	}
+
+	proxy.interval = time.Duration(*config.HealthCheck.Interval) * time.Second
+	proxy.client = &http.Client{
+		Transport: transport,
+		Timeout:   time.Duration(*config.HealthCheck.Timeout) * time.Second,
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+
+	return proxy, nil
+}
+
+// ServeHTTP proxies the request to a healthy upstream, retrying idempotent requests on other upstreams.
+func (p *Proxy) ServeHTTP(writer http.ResponseWriter, request *http.Request) {
+	retries := 0
+	if isRetryable(request) {
+		retries = *p.config.Retries
+	}
+
+	tried := make([]*upstream, 0, retries+1)
+
+	for try := 0; ; try++ {
+		upstream := p.pick(tried)
+		if upstream == nil {
+			p.logger.Ctx(request.Context()).Warn().Str("path", request.URL.Path).Msg("no healthy upstream")
+			writeError(writer, http.StatusServiceUnavailable)
+
+			return
+		}
+
+		tried = append(tried, upstream)
+
+		state := &attempt{upstream: upstream, retry: try < retries}
+		p.proxy.ServeHTTP(writer, request.WithContext(context.WithValue(request.Context(), attemptKey{}, state)))
+
+		if state.err == nil {
+			return
+		}
+
+		p.logger.Ctx(request.Context()).Debug().Err(state.err).
+			Str("upstream", upstream.url.Host).
+			Str("path", request.URL.Path).
+			Msg("retrying request on another upstream")
+	}
+}
+
+// pick returns the next healthy upstream in round-robin order, preferring upstreams not tried yet,
+// nil if no upstream is healthy.
+func (p *Proxy) pick(tried []*upstream) *upstream {
+	var fallback *upstream
+
+	start := p.next.Add(1)
+
+	for i := range uint64(len(p.upstreams)) {
+		upstream := p.upstreams[(start+i)%uint64(len(p.upstreams))]
+		if !upstream.healthy.Load() {
+			continue
+		}
+
+		if !slices.Contains(tried, upstream) {
+			return upstream
+		}
+
+		if fallback == nil {
+			fallback = upstream
+		}
+	}
+
+	return fallback
+}
+
+// rewrite rewrites the request to the upstream of its attempt.
+func (p *Proxy) rewrite(request *httputil.ProxyRequest) {
+	state, _ := request.In.Context().Value(attemptKey{}).(*attempt)
+
+	path := request.In.URL.Path
+	if *p.config.StripPrefix {
+		path = "/" + strings.TrimLeft(strings.TrimPrefix(path, p.prefix), "/")
+	}
+
+	for _, rule := range p.rewrites {
+		path = rule.pattern.ReplaceAllString(path, rule.replacement)
+	}
+
+	if len(p.allowedRequestHeaders) > 0 {
+		filterHeaders(request.Out.Header, p.allowedRequestHeaders)
+	}
+
+	request.Out.URL.Path = path
+	request.Out.URL.RawPath = ""
+	request.SetURL(state.upstream.url)
+	request.SetXForwarded()
+
+	if *p.config.PreserveHost {
+		request.Out.Host = request.In.Host
+	}
+
+	for name, value := range p.config.Headers {
+		request.Out.Header.Set(name, value)
+	}
+}
+
+// modifyResponse fails retried attempts with retryable statuses and filters headers of responses.
+func (p *Proxy) modifyResponse(response *http.Response) error {
+	state, _ := response.Request.Context().Value(attemptKey{}).(*attempt)
+
+	switch response.StatusCode {
+	case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		if state.retry {
+			return fmt.Errorf("%w: %d", errRetryableStatus, response.StatusCode)
+		}
+	}
+
+	if len(p.allowedResponseHeaders) > 0 {
+		filterHeaders(response.Header, p.allowedResponseHeaders)
+	}
+
+	return nil
+}
+
+// handleError records errors of retried attempts, and responds with the error envelope otherwise.
+func (p *Proxy) handleError(writer http.ResponseWriter, request *http.Request, err error) {
+	state, _ := request.Context().Value(attemptKey{}).(*attempt)
+
+	// clients going away are neither upstream failures nor retried
+	if errors.Is(err, context.Canceled) {
+		return
+	}
+
+	if !errors.Is(err, errRetryableStatus) {
+		p.reportFailure(state.upstream, err)
+	}
+
+	if state.retry {
+		state.err = err
+
+		return
+	}
+
+	p.logger.Ctx(request.Context()).Warn().Err(err).
+		Str("upstream", state.upstream.url.Host).
+		Str("path", request.URL.Path).
+		Msg("failed to proxy request")
+
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		writeError(writer, http.StatusGatewayTimeout)
+
+		return
+	}
+
+	writeError(writer, http.StatusBadGateway)
+}
+
+// isRetryable returns whether the request can be sent again, its method is idempotent and it has no body.
+func isRetryable(request *http.Request) bool {
+	if !slices.Contains(idempotentMethods, request.Method) {
+		return false
+	}
+
+	return request.Body == nil || request.Body == http.NoBody || request.ContentLength == 0
+}
+
+// canonicalHeaders returns canonical forms of the header names.
+func canonicalHeaders(names []string) []string {
+	canonical := make([]string, 0, len(names))
+	for _, name := range names {
+		canonical = append(canonical, http.CanonicalHeaderKey(name))
+	}
+
+	return canonical
+}
+
+// filterHeaders removes headers not in the canonical allowlist.
+func filterHeaders(header http.Header, allowed []string) {
+	for name := range header {
+		if !slices.Contains(allowed, name) {
+			header.Del(name)
+		}
+	}
+}
+
+// writeError responds with the error envelope of the status.
+func writeError(writer http.ResponseWriter, status int) {
+	// error is ignored since nothing else can be written to the client
+	_ = apierror.Write(writer, status, &apierror.Response{Error: http.StatusText(status)})
+}
@@ -0,0 +1,310 @@
+package proxy
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/logger"
+)
+
+// received represents a request received by a test upstream.
+type received struct {
+	Upstream string      `json:"upstream"`
+	Path     string      `json:"path"`
+	Host     string      `json:"host"`
+	Header   http.Header `json:"header"`
+}
+
+// testUpstream is an upstream responding with the request it received, or with the status if set.
+type testUpstream struct {
+	*httptest.Server
+
+	// status is status of responses, the request is echoed if 0.
+	status atomic.Int32
+
+	// requests is number of requests received.
+	requests atomic.Int32
+}
+
+// newTestUpstream creates a test upstream named by the name.
+func newTestUpstream(t *testing.T, name string) *testUpstream {
+	t.Helper()
+
+	upstream := &testUpstream{}
+	upstream.Server = httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		upstream.requests.Add(1)
+
+		if status := int(upstream.status.Load()); status != 0 {
+			writer.WriteHeader(status)
+
+			return
+		}
+
+		writer.Header().Set("Content-Type", "application/json")
+		writer.Header().Set("X-Upstream", name)
+		writer.Header().Set("Set-Cookie", "legacy_session=1")
+		_ = json.NewEncoder(writer).Encode(received{
+			Upstream: name,
+			Path:     request.URL.Path,
+			Host:     request.Host,
+			Header:   request.Header,
+		})
+	}))
+	t.Cleanup(upstream.Close)
+
+	return upstream
+}
+
+// setupTestProxy creates a proxy mounted on /legacy with the configuration.
+func setupTestProxy(t *testing.T, config *Config) *Proxy {
+	t.Helper()
+
+	log, err := logger.New(&logger.Config{})
+	require.NoError(t, err)
+
+	proxy, err := New(config, "/legacy/", log)
+	require.NoError(t, err)
+
+	return proxy
+}
+
+// serve sends the request to the proxy.
+func serve(proxy *Proxy, method, target string) *httptest.ResponseRecorder {
+	recorder := httptest.NewRecorder()
+	proxy.ServeHTTP(recorder, httptest.NewRequest(method, target, nil))
+
+	return recorder
+}
+
+// decode decodes the request received by the upstream from the response.
+func decode(t *testing.T, recorder *httptest.ResponseRecorder) received {
+	t.Helper()
+
+	require.Equal(t, http.StatusOK, recorder.Code, recorder.Body.String())
+
+	var request received
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &request))
+
+	return request
+}
+
+func TestConfigSetDefault(t *testing.T) {
+	t.Parallel()
+
+	config := &Config{Rewrites: []*RewriteConfig{{}}}
+	config.SetDefault()
+
+	assert.Empty(t, *config.Target)
+	assert.False(t, *config.StripPrefix)
+	assert.False(t, *config.PreserveHost)
+	assert.Equal(t, defaultTimeout, *config.Timeout)
+	assert.Equal(t, defaultRetries, *config.Retries)
+	assert.Empty(t, *config.AllowedRequestHeaders)
+	assert.Empty(t, *config.AllowedResponseHeaders)
+	assert.Empty(t, *config.Rewrites[0].Pattern)
+	assert.Empty(t, *config.Rewrites[0].Replacement)
+	assert.Empty(t, *config.HealthCheck.Path)
+}
+
+func TestNew(t *testing.T) {
+	t.Parallel()
+
+	log, err := logger.New(&logger.Config{})
+	require.NoError(t, err)
+
+	t.Run("return error for invalid configuration", func(t *testing.T) {
+		t.Parallel()
+
+		configs := []*Config{
+			{},
+			{Target: &[]string{"localhost:8080"}[0]},
+			{Targets: []string{"http://localhost", "ftp://localhost"}},
+			{
+				Target:   &[]string{"http://localhost"}[0],
+				Rewrites: []*RewriteConfig{{Pattern: &[]string{"(["}[0]}},
+			},
+			{
+				Target:   &[]string{"http://localhost"}[0],
+				Rewrites: []*RewriteConfig{{Replacement: &[]string{"/"}[0]}},
+			},
+		}
+
+		for _, config := range configs {
+			_, err := New(config, "/legacy", log)
+			require.ErrorIs(t, err, ErrInvalidConfig)
+		}
+	})
+
+	t.Run("create proxy of target and targets", func(t *testing.T) {
+		t.Parallel()
+
+		proxy, err := New(&Config{
+			Target:  &[]string{"http://first"}[0],
+			Targets: []string{"http://second", "https://third"},
+		}, "/legacy", log)
+		require.NoError(t, err)
+		require.Len(t, proxy.upstreams, 3)
+		assert.Equal(t, "first", proxy.upstreams[0].url.Host)
+	})
+}
+
+func TestServeHTTP(t *testing.T) {
+	t.Parallel()
+
+	t.Run("rewrite paths and set headers", func(t *testing.T) {
+		t.Parallel()
+
+		upstream := newTestUpstream(t, "a")
+		proxy := setupTestProxy(t, &Config{
+			Target:       &[]string{upstream.URL + "/app"}[0],
+			StripPrefix:  &[]bool{true}[0],
+			PreserveHost: &[]bool{true}[0],
+			Headers:      map[string]string{"X-Legacy-Client": "boilerplate"},
+			Rewrites: []*RewriteConfig{
+				{Pattern: &[]string{`^/users/(\d+)$`}[0], Replacement: &[]string{"/user.php/$1"}[0]},
+				{Pattern: &[]string{`\.php`}[0], Replacement: &[]string{".cgi"}[0]},
+			},
+		})
+
+		request := decode(t, serve(proxy, http.MethodGet, "http://example.com/legacy/users/42"))
+		assert.Equal(t, "/app/user.cgi/42", request.Path)
+		assert.Equal(t, "example.com", request.Host)
+		assert.Equal(t, "boilerplate", request.Header.Get("X-Legacy-Client"))
+		assert.Equal(t, "example.com", request.Header.Get("X-Forwarded-Host"))
+
+		assert.Equal(t, "/app/", decode(t, serve(proxy, http.MethodGet, "/legacy")).Path)
+	})
+
+	t.Run("balance requests over upstreams", func(t *testing.T) {
+		t.Parallel()
+
+		first, second := newTestUpstream(t, "a"), newTestUpstream(t, "b")
+		proxy := setupTestProxy(t, &Config{Targets: []string{first.URL, second.URL}})
+
+		for range 4 {
+			decode(t, serve(proxy, http.MethodGet, "/legacy/users"))
+		}
+
+		assert.Equal(t, int32(2), first.requests.Load())
+		assert.Equal(t, int32(2), second.requests.Load())
+	})
+
+	t.Run("retry idempotent requests on another upstream", func(t *testing.T) {
+		t.Parallel()
+
+		failing, healthy := newTestUpstream(t, "a"), newTestUpstream(t, "b")
+		failing.status.Store(http.StatusServiceUnavailable)
+
+		down := newTestUpstream(t, "c")
+		down.Close()
+
+		proxy := setupTestProxy(t, &Config{
+			Targets: []string{failing.URL, down.URL, healthy.URL},
+			Retries: &[]int{2}[0],
+		})
+
+		for range 3 {
+			assert.Equal(t, "b", decode(t, serve(proxy, http.MethodGet, "/legacy/users")).Upstream)
+		}
+	})
+
+	t.Run("not retry requests with a body or of other methods", func(t *testing.T) {
+		t.Parallel()
+
+		failing := newTestUpstream(t, "a")
+		failing.status.Store(http.StatusServiceUnavailable)
+
+		healthy := newTestUpstream(t, "b")
+		healthy.status.Store(http.StatusCreated)
+
+		proxy := setupTestProxy(t, &Config{Targets: []string{failing.URL, healthy.URL}})
+
+		// requests are balanced, so one of two requests is proxied to the failing upstream
+		codes := []int{}
+
+		for range 2 {
+			codes = append(codes, serve(proxy, http.MethodPost, "/legacy/users").Code)
+
+			recorder := httptest.NewRecorder()
+			proxy.ServeHTTP(recorder, httptest.NewRequest(http.MethodPut, "/legacy/users/1", strings.NewReader("{}")))
+			codes = append(codes, recorder.Code)
+		}
+
+		assert.ElementsMatch(t, []int{
+			http.StatusServiceUnavailable, http.StatusServiceUnavailable, http.StatusCreated, http.StatusCreated,
+		}, codes)
+	})
+
+	t.Run("respond with bad gateway after last attempt", func(t *testing.T) {
+		t.Parallel()
+
+		down := newTestUpstream(t, "a")
+		down.Close()
+
+		proxy := setupTestProxy(t, &Config{Target: &down.URL, Retries: &[]int{3}[0]})
+
+		recorder := serve(proxy, http.MethodGet, "/legacy/users")
+		assert.Equal(t, http.StatusBadGateway, recorder.Code)
+		assert.JSONEq(t, `{"error": "Bad Gateway", "code": "internal_error"}`, recorder.Body.String())
+	})
+
+	t.Run("respond with gateway timeout when upstream does not respond", func(t *testing.T) {
+		t.Parallel()
+
+		slow := httptest.NewServer(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {
+			time.Sleep(1500 * time.Millisecond)
+		}))
+		t.Cleanup(slow.Close)
+
+		proxy := setupTestProxy(t, &Config{
+			Target:  &slow.URL,
+			Timeout: &[]int{1}[0],
+			Retries: &[]int{0}[0],
+		})
+
+		assert.Equal(t, http.StatusGatewayTimeout, serve(proxy, http.MethodGet, "/legacy/users").Code)
+	})
+
+	t.Run("filter headers by allowlists", func(t *testing.T) {
+		t.Parallel()
+
+		upstream := newTestUpstream(t, "a")
+		proxy := setupTestProxy(t, &Config{
+			Target:                 &upstream.URL,
+			Headers:                map[string]string{"X-Legacy-Client": "boilerplate"},
+			AllowedRequestHeaders:  &[]string{"accept", "x-request-id"},
+			AllowedResponseHeaders: &[]string{"x-upstream"},
+		})
+
+		req := httptest.NewRequest(http.MethodGet, "/legacy/users", nil)
+		req.Header.Set("Accept", "application/json")
+		req.Header.Set("X-Request-Id", "request")
+		req.Header.Set("Cookie", "session=secret")
+		req.Header.Set("Authorization", "Bearer token")
+
+		recorder := httptest.NewRecorder()
+		proxy.ServeHTTP(recorder, req)
+
+		request := decode(t, recorder)
+		assert.Equal(t, "application/json", request.Header.Get("Accept"))
+		assert.Equal(t, "request", request.Header.Get("X-Request-Id"))
+		assert.Empty(t, request.Header.Get("Cookie"))
+		assert.Empty(t, request.Header.Get("Authorization"))
+
+		// configured and forwarded headers are set after filtering
+		assert.Equal(t, "boilerplate", request.Header.Get("X-Legacy-Client"))
+		assert.NotEmpty(t, request.Header.Get("X-Forwarded-For"))
+
+		assert.Equal(t, "a", recorder.Header().Get("X-Upstream"))
+		assert.Equal(t, "application/json", recorder.Header().Get("Content-Type"))
+		assert.Empty(t, recorder.Header().Get("Set-Cookie"))
+	})
+}
@@ -0,0 +1,69 @@
+package querycache
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	// layerLocal is layer label of results cached in memory.
+	layerLocal = "local"
+
+	// layerRedis is layer label of results cached on redis.
+	layerRedis = "redis"
+)
+
+// metrics holds prometheus collectors of the query cache.
+type metrics struct {
+	// hitsTotal is number of results read from a cache by layer.
+	hitsTotal *prometheus.CounterVec
+
+	// missesTotal is number of results loaded from database.
+	missesTotal prometheus.Counter
+
+	// invalidationsTotal is number of invalidated tags.
+	invalidationsTotal prometheus.Counter
+}
+
+// newMetrics creates collectors of the query cache.
+func newMetrics() *metrics {
+	return &metrics{
+		hitsTotal: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "query_cache_hits_total",
+				Help: "Total number of query results read from the query cache by layer",
+			},
+			[]string{"layer"},
+		),
+		missesTotal: prometheus.NewCounter(
+			prometheus.CounterOpts{
+				Name: "query_cache_misses_total",
+				Help: "Total number of query results loaded from database on a query cache miss",
+			},
+		),
+		invalidationsTotal: prometheus.NewCounter(
+			prometheus.CounterOpts{
+				Name: "query_cache_invalidations_total",
+				Help: "Total number of tags invalidated in the query cache",
+			},
+		),
+	}
+}
+
+// collectors returns all collectors of the metrics.
+func (m *metrics) collectors() []prometheus.Collector {
+	return []prometheus.Collector{m.hitsTotal, m.missesTotal, m.invalidationsTotal}
+}
+
+// Describe implements prometheus.Collector.
+func (c *QueryCache) Describe(ch chan<- *prometheus.Desc) {
+	for _, collector := range c.metrics.collectors() {
+		collector.Describe(ch)
+	}
+}
+
+// Collect implements prometheus.Collector.
+func (c *QueryCache) Collect(ch chan<- prometheus.Metric) {
+	for _, collector := range c.metrics.collectors() {
+		collector.Collect(ch)
+	}
+}
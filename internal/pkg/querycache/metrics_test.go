@@ -0,0 +1,45 @@
+package querycache
+
+import (
+	"context"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestQueryCacheMetrics(t *testing.T) {
+	t.Parallel()
+
+	t.Run("count hits by layer, misses and invalidations", func(t *testing.T) {
+		t.Parallel()
+
+		config := testConfig(t)
+		first, second := setupTestQueryCache(t, config), setupTestQueryCache(t, config)
+		query := Query{Key: "key", Tags: []string{"tag"}}
+		load, _ := countingLoader(&testResult{ID: "1"}, nil)
+
+		for _, queryCache := range []*QueryCache{first, first, second} {
+			_, err := Run(context.Background(), queryCache, query, load)
+			require.NoError(t, err)
+		}
+
+		require.NoError(t, first.Invalidate(context.Background(), "tag", "other"))
+
+		assert.InDelta(t, 1, testutil.ToFloat64(first.metrics.missesTotal), 0)
+		assert.InDelta(t, 1, testutil.ToFloat64(first.metrics.hitsTotal.WithLabelValues(layerLocal)), 0)
+		assert.InDelta(t, 1, testutil.ToFloat64(second.metrics.hitsTotal.WithLabelValues(layerRedis)), 0)
+		assert.InDelta(t, 2, testutil.ToFloat64(first.metrics.invalidationsTotal), 0)
+	})
+
+	t.Run("register query cache as collector", func(t *testing.T) {
+		t.Parallel()
+
+		queryCache := setupTestQueryCache(t, testConfig(t))
+
+		registry := prometheus.NewRegistry()
+		require.NoError(t, registry.Register(queryCache))
+	})
+}
@@ -0,0 +1,180 @@
+package querycache
+
+import (
+	"context"
+	"errors"
+
+	"github.com/jackc/pgx/v5"
+
+	"github.com/pocj8ur4in/boilerplate-go/internal/gen/db"
+)
+
+// Querier decorates a querier, caching results of read-heavy queries and invalidating them after writes of the
+// rows they read. Other queries go to the querier unchanged.
+type Querier struct {
+	// Querier provides database queries.
+	db.Querier
+
+	// cache provides the query cache.
+	cache *QueryCache
+}
+
+// NewQuerier creates a querier caching results of the querier in the query cache.
+func NewQuerier(querier db.Querier, cache *QueryCache) *Querier {
+	return &Querier{
+		Querier: querier,
+		cache:   cache,
+	}
+}
+
+// apiKeysTag returns the tag of API keys of the user.
+func apiKeysTag(userID string) string {
+	return "api_keys:user:" + userID
+}
+
+// billingTag returns the tag of the billing customer and subscriptions of the user.
+func billingTag(userID string) string {
+	return "billing:user:" + userID
+}
+
+// billingCustomerTag returns the tag of the billing customer of the customer ID.
+func billingCustomerTag(customerID string) string {
+	return "billing:customer:" + customerID
+}
+
+// ListAPIKeys returns API keys of the user, cached until a key of the user is written.
+func (q *Querier) ListAPIKeys(ctx context.Context, userID string) ([]*db.ApiKey, error) {
+	return Run(ctx, q.cache, Query{Key: "api_keys:list:" + userID, Tags: []string{apiKeysTag(userID)}},
+		func(ctx context.Context) ([]*db.ApiKey, error) {
+			return q.Querier.ListAPIKeys(ctx, userID)
+		},
+	)
+}
+
+// CreateAPIKey creates the API key and invalidates API keys of the user.
+func (q *Querier) CreateAPIKey(ctx context.Context, arg *db.CreateAPIKeyParams) (*db.ApiKey, error) {
+	row, err := q.Querier.CreateAPIKey(ctx, arg)
+	if err == nil {
+		q.invalidate(ctx, apiKeysTag(arg.UserID))
+	}
+
+	return row, err //nolint:wrapcheck // the decorator returns errors of the querier unchanged
+}
+
+// RevokeAPIKey revokes the API key and invalidates API keys of the user.
+func (q *Querier) RevokeAPIKey(ctx context.Context, arg *db.RevokeAPIKeyParams) (*db.ApiKey, error) {
+	row, err := q.Querier.RevokeAPIKey(ctx, arg)
+	if err == nil {
+		q.invalidate(ctx, apiKeysTag(arg.UserID))
+	}
+
+	return row, err //nolint:wrapcheck // the decorator returns errors of the querier unchanged
+}
+
+// SetAPIKeyRateLimit sets the rate limit of the API key and invalidates API keys of its user.
+func (q *Querier) SetAPIKeyRateLimit(ctx context.Context, arg *db.SetAPIKeyRateLimitParams) (*db.ApiKey, error) {
+	row, err := q.Querier.SetAPIKeyRateLimit(ctx, arg)
+	if err == nil {
+		q.invalidate(ctx, apiKeysTag(row.UserID))
+	}
+
+	return row, err //nolint:wrapcheck // the decorator returns errors of the querier unchanged
+}
+
+// GetBillingCustomerByUserID returns the billing customer of the user, cached until it is created.
+func (q *Querier) GetBillingCustomerByUserID(ctx context.Context, userID string) (*db.BillingCustomer, error) {
+	return runRow(ctx, q.cache, Query{Key: "billing:customer_by_user:" + userID, Tags: []string{billingTag(userID)}},
+		func(ctx context.Context) (*db.BillingCustomer, error) {
+			return q.Querier.GetBillingCustomerByUserID(ctx, userID)
+		},
+	)
+}
+
+// GetBillingCustomerByCustomerID returns the billing customer of the customer ID, cached until it is created.
+func (q *Querier) GetBillingCustomerByCustomerID(ctx context.Context, customerID string) (*db.BillingCustomer, error) {
+	query := Query{Key: "billing:customer:" + customerID, Tags: []string{billingCustomerTag(customerID)}}
+
+	return runRow(ctx, q.cache, query, func(ctx context.Context) (*db.BillingCustomer, error) {
+		return q.Querier.GetBillingCustomerByCustomerID(ctx, customerID)
+	})
+}
+
+// CreateBillingCustomer creates the billing customer and invalidates billing of the user and the customer ID.
+func (q *Querier) CreateBillingCustomer(
+	ctx context.Context,
+	arg *db.CreateBillingCustomerParams,
+) (*db.BillingCustomer, error) {
+	row, err := q.Querier.CreateBillingCustomer(ctx, arg)
+	if err == nil {
+		q.invalidate(ctx, billingTag(arg.UserID), billingCustomerTag(arg.CustomerID))
+	}
+
+	return row, err //nolint:wrapcheck // the decorator returns errors of the querier unchanged
+}
+
+// ListBillingSubscriptionsByUserID returns subscriptions of the user, cached until a subscription of the user is
+// written.
+func (q *Querier) ListBillingSubscriptionsByUserID(
+	ctx context.Context,
+	userID string,
+) ([]*db.BillingSubscription, error) {
+	return Run(ctx, q.cache, Query{Key: "billing:subscriptions:" + userID, Tags: []string{billingTag(userID)}},
+		func(ctx context.Context) ([]*db.BillingSubscription, error) {
+			return q.Querier.ListBillingSubscriptionsByUserID(ctx, userID)
+		},
+	)
+}
+
+// UpsertBillingSubscription writes the subscription and invalidates billing of the user of its customer.
+func (q *Querier) UpsertBillingSubscription(
+	ctx context.Context,
+	arg *db.UpsertBillingSubscriptionParams,
+) (*db.BillingSubscription, error) {
+	row, err := q.Querier.UpsertBillingSubscription(ctx, arg)
+	if err != nil {
+		return nil, err //nolint:wrapcheck // the decorator returns errors of the querier unchanged
+	}
+
+	// subscriptions are listed by user, the customer is cached as well
+	customer, err := q.GetBillingCustomerByCustomerID(ctx, arg.CustomerID)
+	if err != nil {
+		q.cache.logger.Ctx(ctx).Error().Err(err).Str("customer_id", arg.CustomerID).
+			Msg("failed to get billing customer to invalidate subscriptions")
+
+		return row, nil
+	}
+
+	q.invalidate(ctx, billingTag(customer.UserID))
+
+	return row, nil
+}
+
+// invalidate invalidates the tags, the write has succeeded so a failure is logged and results stay cached for
+// their TTL.
+func (q *Querier) invalidate(ctx context.Context, tags ...string) {
+	if err := q.cache.Invalidate(ctx, tags...); err != nil {
+		q.cache.logger.Ctx(ctx).Error().Err(err).Strs("tags", tags).Msg("failed to invalidate query cache")
+	}
+}
+
+// runRow runs the query of a single row, caching a missing row as not found.
+func runRow[T any](
+	ctx context.Context,
+	cache *QueryCache,
+	query Query,
+	load func(ctx context.Context) (*T, error),
+) (*T, error) {
+	row, err := Run(ctx, cache, query, func(ctx context.Context) (*T, error) {
+		row, err := load(ctx)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+
+		return row, err
+	})
+	if errors.Is(err, ErrNotFound) {
+		return nil, pgx.ErrNoRows
+	}
+
+	return row, err
+}
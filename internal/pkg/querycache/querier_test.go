@@ -0,0 +1,193 @@
+package querycache
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pocj8ur4in/boilerplate-go/internal/gen/db"
+)
+
+// mockBillingQuerier is a mock querier serving API keys and billing from memory, counting reads.
+type mockBillingQuerier struct {
+	db.Querier
+
+	keys          map[string][]*db.ApiKey
+	customers     map[string]*db.BillingCustomer
+	subscriptions map[string][]*db.BillingSubscription
+	reads         atomic.Int32
+}
+
+func (m *mockBillingQuerier) ListAPIKeys(_ context.Context, userID string) ([]*db.ApiKey, error) {
+	m.reads.Add(1)
+
+	return m.keys[userID], nil
+}
+
+func (m *mockBillingQuerier) CreateAPIKey(_ context.Context, arg *db.CreateAPIKeyParams) (*db.ApiKey, error) {
+	key := &db.ApiKey{ID: arg.ID, UserID: arg.UserID, Name: arg.Name}
+	m.keys[arg.UserID] = append(m.keys[arg.UserID], key)
+
+	return key, nil
+}
+
+func (m *mockBillingQuerier) GetBillingCustomerByCustomerID(
+	_ context.Context,
+	customerID string,
+) (*db.BillingCustomer, error) {
+	m.reads.Add(1)
+
+	for _, customer := range m.customers {
+		if customer.CustomerID == customerID {
+			return customer, nil
+		}
+	}
+
+	return nil, pgx.ErrNoRows
+}
+
+func (m *mockBillingQuerier) GetBillingCustomerByUserID(
+	_ context.Context,
+	userID string,
+) (*db.BillingCustomer, error) {
+	m.reads.Add(1)
+
+	customer, ok := m.customers[userID]
+	if !ok {
+		return nil, pgx.ErrNoRows
+	}
+
+	return customer, nil
+}
+
+func (m *mockBillingQuerier) CreateBillingCustomer(
+	_ context.Context,
+	arg *db.CreateBillingCustomerParams,
+) (*db.BillingCustomer, error) {
+	customer := &db.BillingCustomer{UserID: arg.UserID, CustomerID: arg.CustomerID}
+	m.customers[arg.UserID] = customer
+
+	return customer, nil
+}
+
+func (m *mockBillingQuerier) ListBillingSubscriptionsByUserID(
+	_ context.Context,
+	userID string,
+) ([]*db.BillingSubscription, error) {
+	m.reads.Add(1)
+
+	return m.subscriptions[userID], nil
+}
+
+func (m *mockBillingQuerier) UpsertBillingSubscription(
+	_ context.Context,
+	arg *db.UpsertBillingSubscriptionParams,
+) (*db.BillingSubscription, error) {
+	subscription := &db.BillingSubscription{ID: arg.ID, CustomerID: arg.CustomerID, Status: arg.Status}
+
+	for userID, customer := range m.customers {
+		if customer.CustomerID == arg.CustomerID {
+			m.subscriptions[userID] = []*db.BillingSubscription{subscription}
+		}
+	}
+
+	return subscription, nil
+}
+
+// setupTestQuerier creates a caching querier of a mock querier.
+func setupTestQuerier(t *testing.T) (*Querier, *mockBillingQuerier) {
+	t.Helper()
+
+	mock := &mockBillingQuerier{
+		keys:          make(map[string][]*db.ApiKey),
+		customers:     make(map[string]*db.BillingCustomer),
+		subscriptions: make(map[string][]*db.BillingSubscription),
+	}
+
+	return NewQuerier(mock, setupTestQueryCache(t, testConfig(t))), mock
+}
+
+func TestQuerier(t *testing.T) {
+	t.Parallel()
+
+	t.Run("cache api keys until a key of the user is created", func(t *testing.T) {
+		t.Parallel()
+
+		querier, mock := setupTestQuerier(t)
+		ctx := context.Background()
+
+		for range 2 {
+			keys, err := querier.ListAPIKeys(ctx, "user-1")
+			require.NoError(t, err)
+			assert.Empty(t, keys)
+		}
+
+		assert.Equal(t, int32(1), mock.reads.Load())
+
+		_, err := querier.CreateAPIKey(ctx, &db.CreateAPIKeyParams{ID: "key-1", UserID: "user-1", Name: "ci"})
+		require.NoError(t, err)
+
+		keys, err := querier.ListAPIKeys(ctx, "user-1")
+		require.NoError(t, err)
+		require.Len(t, keys, 1)
+		assert.Equal(t, "key-1", keys[0].ID)
+		assert.Equal(t, int32(2), mock.reads.Load())
+	})
+
+	t.Run("cache missing customers until created", func(t *testing.T) {
+		t.Parallel()
+
+		querier, mock := setupTestQuerier(t)
+		ctx := context.Background()
+
+		for range 2 {
+			_, err := querier.GetBillingCustomerByUserID(ctx, "user-1")
+			require.ErrorIs(t, err, pgx.ErrNoRows)
+		}
+
+		assert.Equal(t, int32(1), mock.reads.Load())
+
+		_, err := querier.CreateBillingCustomer(ctx, &db.CreateBillingCustomerParams{
+			UserID:     "user-1",
+			CustomerID: "cus_1",
+		})
+		require.NoError(t, err)
+
+		customer, err := querier.GetBillingCustomerByUserID(ctx, "user-1")
+		require.NoError(t, err)
+		assert.Equal(t, "cus_1", customer.CustomerID)
+	})
+
+	t.Run("invalidate subscriptions of the user of the customer", func(t *testing.T) {
+		t.Parallel()
+
+		querier, _ := setupTestQuerier(t)
+		ctx := context.Background()
+
+		_, err := querier.CreateBillingCustomer(ctx, &db.CreateBillingCustomerParams{
+			UserID:     "user-1",
+			CustomerID: "cus_1",
+		})
+		require.NoError(t, err)
+
+		subscriptions, err := querier.ListBillingSubscriptionsByUserID(ctx, "user-1")
+		require.NoError(t, err)
+		assert.Empty(t, subscriptions)
+
+		_, err = querier.UpsertBillingSubscription(ctx, &db.UpsertBillingSubscriptionParams{
+			ID:         "sub_1",
+			CustomerID: "cus_1",
+			Status:     "active",
+		})
+		require.NoError(t, err)
+
+		subscriptions, err = querier.ListBillingSubscriptionsByUserID(ctx, "user-1")
+		require.NoError(t, err)
+		require.Len(t, subscriptions, 1)
+		assert.Equal(t, "active", subscriptions[0].Status)
+	})
+}
@@ -0,0 +1,551 @@
+// Package querycache caches results of database queries on redis and in memory. Queries declare a key and tags
+// of the rows they read, writes invalidate the tags, and invalidations are published over redis pub/sub so that
+// every instance drops its in-memory results.
+package querycache
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	goredis "github.com/redis/go-redis/v9"
+	"go.uber.org/fx"
+
+	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/cache"
+	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/logger"
+	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/redis"
+)
+
+const (
+	// defaultPrefix is default prefix of result keys and tag versions.
+	defaultPrefix = "query:"
+
+	// defaultTTL is default TTL of results cached on redis.
+	defaultTTL = 5 * time.Minute
+
+	// defaultLocalTTL is default TTL of results cached in memory.
+	defaultLocalTTL = 30 * time.Second
+
+	// defaultLocalSize is default maximum number of results cached in memory.
+	defaultLocalSize = 10000
+
+	// defaultTagTTL is default TTL of tag versions.
+	defaultTagTTL = 24 * time.Hour
+
+	// defaultChannel is default invalidation channel.
+	defaultChannel = "query_cache:invalidate"
+)
+
+var (
+	// ErrNotFound is returned by loaders when the result does not exist, and for results cached as not found.
+	ErrNotFound = cache.ErrNotFound
+
+	// ErrInvalidTagTTL is returned when the tag TTL does not exceed the TTL of results.
+	ErrInvalidTagTTL = errors.New("tag ttl must exceed ttl")
+
+	// ErrInvalidWarmer is returned when a warmer has no name or function, or its name is registered twice.
+	ErrInvalidWarmer = errors.New("invalid warmer")
+
+	// ErrUnknownWarmer is returned when no warmer of the name is registered.
+	ErrUnknownWarmer = errors.New("unknown warmer")
+)
+
+// Config represents configuration for query cache.
+type Config struct {
+	// Enabled is whether results are cached, queries go to database if disabled.
+	Enabled *bool `json:"enabled"`
+
+	// Prefix is prefix of result keys and tag versions on redis.
+	Prefix *string `json:"prefix"`
+
+	// TTL is TTL of results cached on redis, for queries declaring no TTL.
+	TTL *time.Duration `json:"ttl"`
+
+	// LocalTTL is TTL of results cached in memory, it bounds staleness if an invalidation is missed. 0 disables
+	// the in-memory cache.
+	LocalTTL *time.Duration `json:"local_ttl"`
+
+	// LocalSize is maximum number of results cached in memory.
+	LocalSize *int `json:"local_size"`
+
+	// TagTTL is TTL of tag versions since their last invalidation. It must exceed TTLs of results, so that results
+	// of an expired version are gone before the version is reused.
+	TagTTL *time.Duration `json:"tag_ttl"`
+
+	// Channel is redis pub/sub channel of invalidations.
+	Channel *string `json:"channel"`
+}
+
+// SetDefault sets default values.
+func (c *Config) SetDefault() {
+	if c.Enabled == nil {
+		c.Enabled = &[]bool{false}[0]
+	}
+
+	if c.Prefix == nil {
+		c.Prefix = &[]string{defaultPrefix}[0]
+	}
+
+	if c.TTL == nil {
+		c.TTL = &[]time.Duration{defaultTTL}[0]
+	}
+
+	if c.LocalTTL == nil {
+		c.LocalTTL = &[]time.Duration{defaultLocalTTL}[0]
+	}
+
+	if c.LocalSize == nil {
+		c.LocalSize = &[]int{defaultLocalSize}[0]
+	}
+
+	if c.TagTTL == nil {
+		c.TagTTL = &[]time.Duration{defaultTagTTL}[0]
+	}
+
+	if c.Channel == nil {
+		c.Channel = &[]string{defaultChannel}[0]
+	}
+}
+
+// Query represents a cached query.
+type Query struct {
+	// Key identifies the query and its arguments, e.g. "billing:subscriptions:" + userID.
+	Key string
+
+	// Tags are tags of the rows read by the query, invalidating any of them invalidates the result.
+	Tags []string
+
+	// TTL is TTL of the result on redis, the configured TTL if 0.
+	TTL time.Duration
+}
+
+// localEntry is a result cached in memory.
+type localEntry struct {
+	// data is the result encoded as JSON, nil if it was not found.
+	data []byte
+
+	// tags are tags of the query.
+	tags []string
+
+	// expiresAt is time the entry expires.
+	expiresAt time.Time
+}
+
+// QueryCache caches results of queries, reads go through an in-memory cache, then redis, then the loader.
+// Results on redis are stored under the versions of their tags, so invalidating a tag increments its version
+// and results loaded before are never read again, even if their load finishes after the invalidation.
+type QueryCache struct {
+	// config provides query cache configuration.
+	config *Config
+
+	// cache provides the cache storing results on redis.
+	cache *cache.Cache
+
+	// redis provides redis client storing tag versions.
+	redis *redis.Redis
+
+	// logger provides logger.
+	logger *logger.Logger
+
+	// metrics provides prometheus collectors of the query cache.
+	metrics *metrics
+
+	// mu guards local, tagged and generation.
+	mu sync.Mutex
+
+	// local is results cached in memory by query key.
+	local map[string]*localEntry
+
+	// tagged is keys of results cached in memory by tag.
+	tagged map[string]map[string]struct{}
+
+	// generation is incremented by every invalidation of results in memory, loads started before are not stored.
+	generation uint64
+
+	// warmers is registered warmers by name.
+	warmers map[string]Warmer
+
+	// pubsub is subscription of the invalidation channel, nil if disabled.
+	pubsub *goredis.PubSub
+
+	// wg waits for the invalidation loop to exit.
+	wg sync.WaitGroup
+}
+
+// WarmersParams represents warmers provided by modules.
+type WarmersParams struct {
+	fx.In
+
+	QueryCache *QueryCache
+	Warmers    []Warmer `group:"query_cache_warmers"`
+}
+
+// NewModule provides module for query cache.
+func NewModule() fx.Option {
+	return fx.Module("querycache",
+		fx.Provide(New),
+		fx.Invoke(registerWarmers),
+	)
+}
+
+// registerWarmers registers warmers provided by modules, e.g. with
+// fx.Annotate(newSubscriptionsWarmer, fx.ResultTags(`group:"query_cache_warmers"`)).
+func registerWarmers(params WarmersParams) error {
+	for _, warmer := range params.Warmers {
+		if err := params.QueryCache.RegisterWarmer(warmer); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// New creates a query cache storing results in the cache and subscribes to invalidations if it is enabled.
+func New(config *Config, cache *cache.Cache, redis *redis.Redis, logger *logger.Logger) (*QueryCache, error) {
+	if config == nil {
+		config = &Config{}
+	}
+
+	config.SetDefault()
+
+	if *config.TagTTL <= *config.TTL {
+		return nil, ErrInvalidTagTTL
+	}
+
+	queryCache := &QueryCache{
+		config:  config,
+		cache:   cache,
+		redis:   redis,
+		logger:  logger.Named("querycache"),
+		metrics: newMetrics(),
+		local:   make(map[string]*localEntry),
+		tagged:  make(map[string]map[string]struct{}),
+		warmers: make(map[string]Warmer),
+	}
+
+	if !*config.Enabled {
+		return queryCache, nil
+	}
+
+	pubsub := redis.Subscribe(context.Background(), *config.Channel)
+
+	// wait for the subscription so invalidations published after New are received
+	if _, err := pubsub.Receive(context.Background()); err != nil {
+		_ = pubsub.Close()
+
+		return nil, fmt.Errorf("failed to subscribe to query cache invalidations: %w", err)
+	}
+
+	queryCache.pubsub = pubsub
+
+	queryCache.wg.Add(1)
+
+	go queryCache.receive()
+
+	return queryCache, nil
+}
+
+// Close unsubscribes from invalidations.
+func (c *QueryCache) Close() error {
+	if c.pubsub == nil {
+		return nil
+	}
+
+	err := c.pubsub.Close()
+	c.wg.Wait()
+
+	if err != nil {
+		return fmt.Errorf("failed to close query cache subscription: %w", err)
+	}
+
+	return nil
+}
+
+// receive drops results from the in-memory cache as invalidations are received until closed.
+func (c *QueryCache) receive() {
+	defer c.wg.Done()
+
+	for message := range c.pubsub.ChannelWithSubscriptions() {
+		switch message := message.(type) {
+		case *goredis.Message:
+			c.dropLocal(strings.Split(message.Payload, "\n"))
+		case *goredis.Subscription:
+			// invalidations may have been missed while reconnecting
+			c.clearLocal()
+		}
+	}
+}
+
+// Run returns the cached result of the query, loading and caching it on a miss. When the loader returns
+// ErrNotFound, the result is cached as not found and ErrNotFound is returned. The result is loaded without
+// caching if the cache is disabled, and loaded as well if redis fails, so that an unavailable cache only adds load.
+func Run[T any](
+	ctx context.Context,
+	c *QueryCache,
+	query Query,
+	load func(ctx context.Context) (T, error),
+) (T, error) {
+	if !*c.config.Enabled {
+		return load(ctx)
+	}
+
+	// check in-memory cache first
+	if value, found, ok := getLocal[T](c, query.Key); ok {
+		c.metrics.hitsTotal.WithLabelValues(layerLocal).Inc()
+
+		if !found {
+			return value, ErrNotFound
+		}
+
+		return value, nil
+	}
+
+	generation := c.currentGeneration()
+
+	versions, err := c.versions(ctx, query.Tags)
+	if err != nil {
+		c.logger.Ctx(ctx).Warn().Err(err).Str("key", query.Key).Msg("failed to get tag versions, loading result")
+		c.metrics.missesTotal.Inc()
+
+		return load(ctx)
+	}
+
+	ttl := query.TTL
+	if ttl <= 0 {
+		ttl = *c.config.TTL
+	}
+
+	// the loader only runs on a miss, concurrent misses of the key wait for the load of another caller
+	loaded := false
+
+	value, err := cache.GetOrLoad(ctx, c.cache, c.key(query.Key, versions), ttl,
+		func(ctx context.Context) (T, error) {
+			loaded = true
+
+			return load(ctx)
+		},
+	)
+
+	if loaded {
+		c.metrics.missesTotal.Inc()
+	} else {
+		c.metrics.hitsTotal.WithLabelValues(layerRedis).Inc()
+	}
+
+	if err != nil && !errors.Is(err, ErrNotFound) {
+		return value, err
+	}
+
+	c.storeLocal(query, value, err == nil, generation)
+
+	return value, err
+}
+
+// Invalidate invalidates results of queries tagged with any of the tags on all instances.
+func (c *QueryCache) Invalidate(ctx context.Context, tags ...string) error {
+	if !*c.config.Enabled || len(tags) == 0 {
+		return nil
+	}
+
+	c.dropLocal(tags)
+
+	pipe := c.redis.Pipeline()
+
+	for _, tag := range tags {
+		pipe.Incr(ctx, c.tagKey(tag))
+		pipe.Expire(ctx, c.tagKey(tag), *c.config.TagTTL)
+	}
+
+	pipe.Publish(ctx, *c.config.Channel, strings.Join(tags, "\n"))
+
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("failed to invalidate query cache tags: %w", err)
+	}
+
+	c.metrics.invalidationsTotal.Add(float64(len(tags)))
+
+	return nil
+}
+
+// Delete deletes the keys of the cache, such as values cached with cache.GetOrLoad, so that they are loaded again.
+// Results of queries are invalidated by their tags instead.
+func (c *QueryCache) Delete(ctx context.Context, keys ...string) error {
+	if len(keys) == 0 {
+		return nil
+	}
+
+	return c.cache.Delete(ctx, keys...) //nolint:wrapcheck // errors are wrapped by the cache
+}
+
+// key returns the cache key of the result of the query under the tag versions.
+func (c *QueryCache) key(key, versions string) string {
+	if versions == "" {
+		return *c.config.Prefix + key
+	}
+
+	return *c.config.Prefix + key + "@" + versions
+}
+
+// tagKey returns the redis key of the version of the tag.
+func (c *QueryCache) tagKey(tag string) string {
+	return *c.config.Prefix + "tag:" + tag
+}
+
+// versions returns the versions of the tags joined by dots, tags never invalidated are at version 0.
+func (c *QueryCache) versions(ctx context.Context, tags []string) (string, error) {
+	if len(tags) == 0 {
+		return "", nil
+	}
+
+	keys := make([]string, 0, len(tags))
+	for _, tag := range tags {
+		keys = append(keys, c.tagKey(tag))
+	}
+
+	values, err := c.redis.MGet(ctx, keys...).Result()
+	if err != nil {
+		return "", fmt.Errorf("failed to get tag versions: %w", err)
+	}
+
+	versions := make([]string, 0, len(values))
+
+	for _, value := range values {
+		version, ok := value.(string)
+		if !ok {
+			version = "0"
+		}
+
+		versions = append(versions, version)
+	}
+
+	return strings.Join(versions, "."), nil
+}
+
+// getLocal returns the result of the key cached in memory, whether it was found and whether it is cached.
+func getLocal[T any](c *QueryCache, key string) (T, bool, bool) {
+	var value T
+
+	c.mu.Lock()
+	entry, ok := c.local[key]
+	c.mu.Unlock()
+
+	if !ok || time.Now().After(entry.expiresAt) {
+		return value, false, false
+	}
+
+	if entry.data == nil {
+		return value, false, true
+	}
+
+	// results are decoded for every caller, so that callers do not share them
+	if err := json.Unmarshal(entry.data, &value); err != nil {
+		return value, false, false
+	}
+
+	return value, true, true
+}
+
+// currentGeneration returns the generation of results in memory.
+func (c *QueryCache) currentGeneration() uint64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.generation
+}
+
+// storeLocal caches the result of the query in memory, unless results were invalidated since the generation.
+func (c *QueryCache) storeLocal(query Query, value any, found bool, generation uint64) {
+	if *c.config.LocalTTL <= 0 {
+		return
+	}
+
+	var data []byte
+
+	if found {
+		encoded, err := json.Marshal(value)
+		if err != nil {
+			return
+		}
+
+		data = encoded
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.generation != generation {
+		return
+	}
+
+	if _, ok := c.local[query.Key]; !ok && len(c.local) >= *c.config.LocalSize {
+		// evict an arbitrary result, map iteration order is random
+		for key := range c.local {
+			c.removeLocal(key)
+
+			break
+		}
+	}
+
+	c.removeLocal(query.Key)
+
+	c.local[query.Key] = &localEntry{
+		data:      data,
+		tags:      query.Tags,
+		expiresAt: time.Now().Add(*c.config.LocalTTL),
+	}
+
+	for _, tag := range query.Tags {
+		if c.tagged[tag] == nil {
+			c.tagged[tag] = make(map[string]struct{})
+		}
+
+		c.tagged[tag][query.Key] = struct{}{}
+	}
+}
+
+// removeLocal removes the result of the key from memory, the caller holds mu.
+func (c *QueryCache) removeLocal(key string) {
+	entry, ok := c.local[key]
+	if !ok {
+		return
+	}
+
+	delete(c.local, key)
+
+	for _, tag := range entry.tags {
+		delete(c.tagged[tag], key)
+
+		if len(c.tagged[tag]) == 0 {
+			delete(c.tagged, tag)
+		}
+	}
+}
+
+// dropLocal removes results tagged with any of the tags from memory.
+func (c *QueryCache) dropLocal(tags []string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.generation++
+
+	for _, tag := range tags {
+		for key := range c.tagged[tag] {
+			c.removeLocal(key)
+		}
+	}
+}
+
+// clearLocal removes all results from memory.
+func (c *QueryCache) clearLocal() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.generation++
+
+	clear(c.local)
+	clear(c.tagged)
+}
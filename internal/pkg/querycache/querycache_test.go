@@ -0,0 +1,370 @@
+package querycache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/cache"
+	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/logger"
+	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/redis"
+)
+
+var errLoadFailed = errors.New("load failed")
+
+// testResult is a query result cached in tests.
+type testResult struct {
+	ID     string `json:"id"`
+	Status string `json:"status"`
+}
+
+// setupTestRedis creates a client of the test redis server.
+func setupTestRedis(t *testing.T) *redis.Redis {
+	t.Helper()
+
+	password := ""
+	redisDB := 0
+
+	redisClient, err := redis.New(&redis.Config{
+		Addrs:    []string{"localhost:36379"},
+		Password: &password,
+		DB:       &redisDB,
+	})
+	require.NoError(t, err)
+
+	t.Cleanup(func() {
+		_ = redisClient.Close()
+	})
+
+	return redisClient
+}
+
+// testConfig returns an enabled config with keys and channel unique to the test.
+func testConfig(t *testing.T) *Config {
+	t.Helper()
+
+	namespace := fmt.Sprintf("%s:%d", t.Name(), time.Now().UnixNano())
+
+	return &Config{
+		Enabled: &[]bool{true}[0],
+		Prefix:  &[]string{"query:" + namespace + ":"}[0],
+		Channel: &[]string{"query_cache:" + namespace}[0],
+	}
+}
+
+// setupTestQueryCache creates a query cache of the config on the test redis server.
+func setupTestQueryCache(t *testing.T, config *Config) *QueryCache {
+	t.Helper()
+
+	log, err := logger.New(&logger.Config{})
+	require.NoError(t, err)
+
+	redisClient := setupTestRedis(t)
+
+	queryCache, err := New(config, cache.New(nil, redisClient, log), redisClient, log)
+	require.NoError(t, err)
+
+	t.Cleanup(func() {
+		_ = queryCache.Close()
+	})
+
+	return queryCache
+}
+
+// countingLoader returns a loader of the result counting its loads.
+func countingLoader(result *testResult, err error) (func(context.Context) (*testResult, error), *atomic.Int32) {
+	var loads atomic.Int32
+
+	return func(context.Context) (*testResult, error) {
+		loads.Add(1)
+
+		return result, err
+	}, &loads
+}
+
+// isLocal returns whether the result of the key is cached in memory.
+func isLocal(queryCache *QueryCache, key string) bool {
+	queryCache.mu.Lock()
+	defer queryCache.mu.Unlock()
+
+	_, ok := queryCache.local[key]
+
+	return ok
+}
+
+func TestConfigSetDefault(t *testing.T) {
+	t.Parallel()
+
+	config := &Config{}
+	config.SetDefault()
+
+	assert.False(t, *config.Enabled)
+	assert.Equal(t, defaultPrefix, *config.Prefix)
+	assert.Equal(t, defaultTTL, *config.TTL)
+	assert.Equal(t, defaultLocalTTL, *config.LocalTTL)
+	assert.Equal(t, defaultLocalSize, *config.LocalSize)
+	assert.Equal(t, defaultTagTTL, *config.TagTTL)
+	assert.Equal(t, defaultChannel, *config.Channel)
+}
+
+func TestNewModule(t *testing.T) {
+	t.Parallel()
+
+	t.Run("return fx.Option", func(t *testing.T) {
+		t.Parallel()
+
+		require.NotNil(t, NewModule())
+	})
+}
+
+func TestNew(t *testing.T) {
+	t.Parallel()
+
+	t.Run("reject tag ttl not exceeding ttl", func(t *testing.T) {
+		t.Parallel()
+
+		log, err := logger.New(&logger.Config{})
+		require.NoError(t, err)
+
+		_, err = New(&Config{TTL: &[]time.Duration{time.Hour}[0], TagTTL: &[]time.Duration{time.Hour}[0]}, nil, nil, log)
+		require.ErrorIs(t, err, ErrInvalidTagTTL)
+	})
+
+	t.Run("not subscribe when disabled", func(t *testing.T) {
+		t.Parallel()
+
+		log, err := logger.New(&logger.Config{})
+		require.NoError(t, err)
+
+		queryCache, err := New(nil, nil, nil, log)
+		require.NoError(t, err)
+
+		assert.Nil(t, queryCache.pubsub)
+		require.NoError(t, queryCache.Close())
+	})
+}
+
+func TestRun(t *testing.T) {
+	t.Parallel()
+
+	query := Query{Key: "billing:subscriptions:user-1", Tags: []string{"billing:user:user-1"}}
+	result := &testResult{ID: "sub-1", Status: "active"}
+
+	t.Run("load once and read cached result", func(t *testing.T) {
+		t.Parallel()
+
+		queryCache := setupTestQueryCache(t, testConfig(t))
+		load, loads := countingLoader(result, nil)
+
+		for range 3 {
+			value, err := Run(context.Background(), queryCache, query, load)
+			require.NoError(t, err)
+			assert.Equal(t, result, value)
+		}
+
+		assert.Equal(t, int32(1), loads.Load())
+		assert.True(t, isLocal(queryCache, query.Key))
+	})
+
+	t.Run("return copies of results cached in memory", func(t *testing.T) {
+		t.Parallel()
+
+		queryCache := setupTestQueryCache(t, testConfig(t))
+		load, _ := countingLoader(&testResult{ID: "sub-1", Status: "active"}, nil)
+
+		_, err := Run(context.Background(), queryCache, query, load)
+		require.NoError(t, err)
+
+		value, err := Run(context.Background(), queryCache, query, load)
+		require.NoError(t, err)
+
+		value.Status = "canceled"
+
+		value, err = Run(context.Background(), queryCache, query, load)
+		require.NoError(t, err)
+		assert.Equal(t, "active", value.Status)
+	})
+
+	t.Run("read results of other instances from redis", func(t *testing.T) {
+		t.Parallel()
+
+		config := testConfig(t)
+		first, second := setupTestQueryCache(t, config), setupTestQueryCache(t, config)
+		load, loads := countingLoader(result, nil)
+
+		_, err := Run(context.Background(), first, query, load)
+		require.NoError(t, err)
+
+		value, err := Run(context.Background(), second, query, load)
+		require.NoError(t, err)
+		assert.Equal(t, result, value)
+		assert.Equal(t, int32(1), loads.Load())
+	})
+
+	t.Run("cache results as not found", func(t *testing.T) {
+		t.Parallel()
+
+		queryCache := setupTestQueryCache(t, testConfig(t))
+		load, loads := countingLoader(nil, ErrNotFound)
+
+		for range 2 {
+			_, err := Run(context.Background(), queryCache, query, load)
+			require.ErrorIs(t, err, ErrNotFound)
+		}
+
+		assert.Equal(t, int32(1), loads.Load())
+	})
+
+	t.Run("not cache errors of the loader", func(t *testing.T) {
+		t.Parallel()
+
+		queryCache := setupTestQueryCache(t, testConfig(t))
+		load, loads := countingLoader(nil, errLoadFailed)
+
+		for range 2 {
+			_, err := Run(context.Background(), queryCache, query, load)
+			require.ErrorIs(t, err, errLoadFailed)
+		}
+
+		assert.Equal(t, int32(2), loads.Load())
+		assert.False(t, isLocal(queryCache, query.Key))
+	})
+
+	t.Run("load every time when disabled", func(t *testing.T) {
+		t.Parallel()
+
+		config := testConfig(t)
+		config.Enabled = &[]bool{false}[0]
+
+		queryCache := setupTestQueryCache(t, config)
+		load, loads := countingLoader(result, nil)
+
+		for range 2 {
+			_, err := Run(context.Background(), queryCache, query, load)
+			require.NoError(t, err)
+		}
+
+		assert.Equal(t, int32(2), loads.Load())
+	})
+
+	t.Run("evict results beyond local size", func(t *testing.T) {
+		t.Parallel()
+
+		config := testConfig(t)
+		config.LocalSize = &[]int{2}[0]
+
+		queryCache := setupTestQueryCache(t, config)
+		load, _ := countingLoader(result, nil)
+
+		for i := range 3 {
+			_, err := Run(context.Background(), queryCache, Query{Key: fmt.Sprintf("key-%d", i), Tags: []string{"tag"}}, load)
+			require.NoError(t, err)
+		}
+
+		queryCache.mu.Lock()
+		defer queryCache.mu.Unlock()
+
+		assert.Len(t, queryCache.local, 2)
+		assert.Len(t, queryCache.tagged["tag"], 2)
+	})
+}
+
+func TestInvalidate(t *testing.T) {
+	t.Parallel()
+
+	query := Query{Key: "billing:subscriptions:user-1", Tags: []string{"billing:user:user-1"}}
+	other := Query{Key: "billing:subscriptions:user-2", Tags: []string{"billing:user:user-2"}}
+	result := &testResult{ID: "sub-1", Status: "active"}
+
+	t.Run("reload invalidated results", func(t *testing.T) {
+		t.Parallel()
+
+		queryCache := setupTestQueryCache(t, testConfig(t))
+		load, loads := countingLoader(result, nil)
+		loadOther, otherLoads := countingLoader(result, nil)
+
+		_, err := Run(context.Background(), queryCache, query, load)
+		require.NoError(t, err)
+
+		_, err = Run(context.Background(), queryCache, other, loadOther)
+		require.NoError(t, err)
+
+		require.NoError(t, queryCache.Invalidate(context.Background(), "billing:user:user-1"))
+		assert.False(t, isLocal(queryCache, query.Key))
+		assert.True(t, isLocal(queryCache, other.Key))
+
+		_, err = Run(context.Background(), queryCache, query, load)
+		require.NoError(t, err)
+
+		_, err = Run(context.Background(), queryCache, other, loadOther)
+		require.NoError(t, err)
+
+		assert.Equal(t, int32(2), loads.Load())
+		assert.Equal(t, int32(1), otherLoads.Load())
+	})
+
+	t.Run("drop results of other instances", func(t *testing.T) {
+		t.Parallel()
+
+		config := testConfig(t)
+		first, second := setupTestQueryCache(t, config), setupTestQueryCache(t, config)
+		load, loads := countingLoader(result, nil)
+
+		_, err := Run(context.Background(), second, query, load)
+		require.NoError(t, err)
+		require.True(t, isLocal(second, query.Key))
+
+		require.NoError(t, first.Invalidate(context.Background(), query.Tags...))
+
+		require.Eventually(t, func() bool {
+			return !isLocal(second, query.Key)
+		}, time.Second, 10*time.Millisecond)
+
+		_, err = Run(context.Background(), second, query, load)
+		require.NoError(t, err)
+		assert.Equal(t, int32(2), loads.Load())
+	})
+
+	t.Run("not cache results loaded during an invalidation", func(t *testing.T) {
+		t.Parallel()
+
+		queryCache := setupTestQueryCache(t, testConfig(t))
+
+		var loads atomic.Int32
+
+		load := func(ctx context.Context) (*testResult, error) {
+			// the row is written while it is being read
+			if loads.Add(1) == 1 {
+				require.NoError(t, queryCache.Invalidate(ctx, query.Tags...))
+			}
+
+			return result, nil
+		}
+
+		_, err := Run(context.Background(), queryCache, query, load)
+		require.NoError(t, err)
+		assert.False(t, isLocal(queryCache, query.Key))
+
+		_, err = Run(context.Background(), queryCache, query, load)
+		require.NoError(t, err)
+		assert.Equal(t, int32(2), loads.Load())
+	})
+
+	t.Run("do nothing when disabled", func(t *testing.T) {
+		t.Parallel()
+
+		log, err := logger.New(&logger.Config{})
+		require.NoError(t, err)
+
+		queryCache, err := New(nil, nil, nil, log)
+		require.NoError(t, err)
+
+		require.NoError(t, queryCache.Invalidate(context.Background(), "tag"))
+	})
+}
@@ -0,0 +1,104 @@
+package querycache
+
+import (
+	"context"
+	"fmt"
+	"slices"
+)
+
+// Warmer loads results of queries into the cache ahead of requests, such as results of the most active users.
+type Warmer struct {
+	// Name identifies the warmer on the warm endpoint.
+	Name string
+
+	// Warm runs the queries through the cache and returns the number of results it loaded.
+	Warm func(ctx context.Context) (int, error)
+}
+
+// WarmResult represents the result of a warmer.
+type WarmResult struct {
+	// Name is name of the warmer.
+	Name string `json:"name"`
+
+	// Results is number of results the warmer loaded.
+	Results int `json:"results"`
+
+	// Error is error of the warmer, empty if it succeeded.
+	Error string `json:"error,omitempty"`
+}
+
+// RegisterWarmer registers the warmer to be run by Warm.
+func (c *QueryCache) RegisterWarmer(warmer Warmer) error {
+	if warmer.Name == "" || warmer.Warm == nil {
+		return fmt.Errorf("%w: warmer requires a name and a function", ErrInvalidWarmer)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, ok := c.warmers[warmer.Name]; ok {
+		return fmt.Errorf("%w: warmer %s is registered twice", ErrInvalidWarmer, warmer.Name)
+	}
+
+	c.warmers[warmer.Name] = warmer
+
+	return nil
+}
+
+// Warmers returns names of registered warmers in order.
+func (c *QueryCache) Warmers() []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	names := make([]string, 0, len(c.warmers))
+	for name := range c.warmers {
+		names = append(names, name)
+	}
+
+	slices.Sort(names)
+
+	return names
+}
+
+// Warm runs the warmers of the names, or all warmers if none are named, one after another. Cached results are
+// kept, so results are reloaded only once their tags are invalidated. Failures of warmers are reported in their
+// results, ErrUnknownWarmer is returned before running any warmer if a name is not registered.
+func (c *QueryCache) Warm(ctx context.Context, names ...string) ([]WarmResult, error) {
+	if len(names) == 0 {
+		names = c.Warmers()
+	}
+
+	c.mu.Lock()
+
+	warmers := make([]Warmer, 0, len(names))
+
+	for _, name := range names {
+		warmer, ok := c.warmers[name]
+		if !ok {
+			c.mu.Unlock()
+
+			return nil, fmt.Errorf("%w: %s", ErrUnknownWarmer, name)
+		}
+
+		warmers = append(warmers, warmer)
+	}
+
+	c.mu.Unlock()
+
+	results := make([]WarmResult, 0, len(warmers))
+
+	for _, warmer := range warmers {
+		loaded, err := warmer.Warm(ctx)
+
+		result := WarmResult{Name: warmer.Name, Results: loaded}
+		if err != nil {
+			result.Error = err.Error()
+
+			c.logger.Ctx(ctx).Error().Err(err).Str("warmer", warmer.Name).Msg("failed to warm query cache")
+		}
+
+		results = append(results, result)
+	}
+
+	return results, nil
+}
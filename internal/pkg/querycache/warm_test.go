@@ -0,0 +1,142 @@
+package querycache
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/cache"
+)
+
+func TestRegisterWarmer(t *testing.T) {
+	t.Parallel()
+
+	warm := func(context.Context) (int, error) {
+		return 0, nil
+	}
+
+	t.Run("reject warmers without name or function", func(t *testing.T) {
+		t.Parallel()
+
+		queryCache := setupTestQueryCache(t, testConfig(t))
+
+		require.ErrorIs(t, queryCache.RegisterWarmer(Warmer{Warm: warm}), ErrInvalidWarmer)
+		require.ErrorIs(t, queryCache.RegisterWarmer(Warmer{Name: "users"}), ErrInvalidWarmer)
+	})
+
+	t.Run("reject names registered twice", func(t *testing.T) {
+		t.Parallel()
+
+		queryCache := setupTestQueryCache(t, testConfig(t))
+
+		require.NoError(t, queryCache.RegisterWarmer(Warmer{Name: "users", Warm: warm}))
+		require.ErrorIs(t, queryCache.RegisterWarmer(Warmer{Name: "users", Warm: warm}), ErrInvalidWarmer)
+	})
+
+	t.Run("list names in order", func(t *testing.T) {
+		t.Parallel()
+
+		queryCache := setupTestQueryCache(t, testConfig(t))
+
+		require.NoError(t, queryCache.RegisterWarmer(Warmer{Name: "users", Warm: warm}))
+		require.NoError(t, queryCache.RegisterWarmer(Warmer{Name: "billing", Warm: warm}))
+
+		assert.Equal(t, []string{"billing", "users"}, queryCache.Warmers())
+	})
+}
+
+func TestWarm(t *testing.T) {
+	t.Parallel()
+
+	query := Query{Key: "billing:subscriptions:user-1", Tags: []string{"billing:user:user-1"}}
+
+	// setup registers a warmer running the query and a failing warmer.
+	setup := func(t *testing.T) (*QueryCache, func() int32) {
+		t.Helper()
+
+		queryCache := setupTestQueryCache(t, testConfig(t))
+		load, loads := countingLoader(&testResult{ID: "sub-1"}, nil)
+
+		require.NoError(t, queryCache.RegisterWarmer(Warmer{
+			Name: "subscriptions",
+			Warm: func(ctx context.Context) (int, error) {
+				_, err := Run(ctx, queryCache, query, load)
+
+				return 1, err
+			},
+		}))
+		require.NoError(t, queryCache.RegisterWarmer(Warmer{
+			Name: "failing",
+			Warm: func(context.Context) (int, error) {
+				return 0, errLoadFailed
+			},
+		}))
+
+		return queryCache, loads.Load
+	}
+
+	t.Run("run all warmers and report failures", func(t *testing.T) {
+		t.Parallel()
+
+		queryCache, loads := setup(t)
+
+		results, err := queryCache.Warm(context.Background())
+		require.NoError(t, err)
+		assert.Equal(t, []WarmResult{
+			{Name: "failing", Error: errLoadFailed.Error()},
+			{Name: "subscriptions", Results: 1},
+		}, results)
+		assert.Equal(t, int32(1), loads())
+		assert.True(t, isLocal(queryCache, query.Key))
+	})
+
+	t.Run("run named warmers", func(t *testing.T) {
+		t.Parallel()
+
+		queryCache, loads := setup(t)
+
+		results, err := queryCache.Warm(context.Background(), "subscriptions")
+		require.NoError(t, err)
+		require.Len(t, results, 1)
+		assert.Equal(t, "subscriptions", results[0].Name)
+		assert.Equal(t, int32(1), loads())
+	})
+
+	t.Run("reject unknown names before running", func(t *testing.T) {
+		t.Parallel()
+
+		queryCache, loads := setup(t)
+
+		_, err := queryCache.Warm(context.Background(), "subscriptions", "missing")
+		require.ErrorIs(t, err, ErrUnknownWarmer)
+		assert.Equal(t, int32(0), loads())
+	})
+}
+
+func TestDelete(t *testing.T) {
+	t.Parallel()
+
+	t.Run("delete cached values", func(t *testing.T) {
+		t.Parallel()
+
+		queryCache := setupTestQueryCache(t, testConfig(t))
+		ctx := context.Background()
+		key := "delete:" + t.Name()
+
+		require.NoError(t, cache.Set(ctx, queryCache.cache, key, "value", 0))
+		require.NoError(t, queryCache.Delete(ctx, key))
+
+		_, err := cache.Get[string](ctx, queryCache.cache, key)
+		require.ErrorIs(t, err, cache.ErrMiss)
+	})
+
+	t.Run("do nothing without keys", func(t *testing.T) {
+		t.Parallel()
+
+		queryCache := setupTestQueryCache(t, testConfig(t))
+
+		require.NoError(t, queryCache.Delete(context.Background()))
+	})
+}
@@ -0,0 +1,193 @@
+// Package readonly provides the read-only mode of the service, used during primary database maintenance.
+// The mode is turned on by configuration or by a toggle stored on redis, shared by all instances.
+package readonly
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"go.uber.org/fx"
+
+	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/redis"
+)
+
+const (
+	// toggleKey is the redis key of the read-only toggle.
+	toggleKey = "read_only:enabled"
+
+	// defaultMessage is default message of rejected requests.
+	defaultMessage = "Service is in read-only mode for maintenance"
+
+	// defaultRefreshInterval is default interval of reading the toggle from redis.
+	defaultRefreshInterval = 5 * time.Second
+)
+
+// Config represents configuration for read-only mode.
+type Config struct {
+	// Enabled is whether read-only mode is forced on, the toggle can not turn it off.
+	Enabled *bool `json:"enabled"`
+
+	// Message is message of rejected requests.
+	Message *string `json:"message"`
+
+	// RetryAfter is seconds clients are asked to wait before retrying rejected requests, 0 to omit.
+	RetryAfter *int `json:"retry_after"`
+
+	// RefreshInterval is interval of reading the toggle from redis, it bounds how long instances disagree.
+	RefreshInterval *time.Duration `json:"refresh_interval"`
+}
+
+// SetDefault sets default values.
+func (c *Config) SetDefault() {
+	if c.Enabled == nil {
+		c.Enabled = &[]bool{false}[0]
+	}
+
+	if c.Message == nil {
+		c.Message = &[]string{defaultMessage}[0]
+	}
+
+	if c.RetryAfter == nil {
+		c.RetryAfter = &[]int{0}[0]
+	}
+
+	if c.RefreshInterval == nil {
+		refreshInterval := defaultRefreshInterval
+		c.RefreshInterval = &refreshInterval
+	}
+}
+
+// ReadOnly provides the read-only mode, checked by middleware before mutating requests
+// and by background work before writing.
+type ReadOnly struct {
+	// redis provides redis storing the toggle, nil to keep the toggle in memory.
+	redis *redis.Redis
+
+	// mu guards config, toggled and refreshedAt.
+	mu sync.Mutex
+
+	// config provides read-only configuration.
+	config *Config
+
+	// toggled is the last read value of the toggle.
+	toggled bool
+
+	// refreshedAt is when the toggle was last read from redis.
+	refreshedAt time.Time
+
+	// now returns the current time, replaced in tests.
+	now func() time.Time
+}
+
+// NewModule provides module for read-only mode.
+func NewModule() fx.Option {
+	return fx.Module("readonly",
+		fx.Provide(New),
+	)
+}
+
+// New creates a new read-only mode storing the toggle on redis.
+func New(config *Config, redis *redis.Redis) *ReadOnly {
+	if config == nil {
+		config = &Config{}
+	}
+
+	config.SetDefault()
+
+	return &ReadOnly{
+		redis:  redis,
+		config: config,
+		now:    time.Now,
+	}
+}
+
+// Enabled returns whether the service is in read-only mode,
+// the last read toggle is kept if reading it from redis fails.
+func (r *ReadOnly) Enabled(ctx context.Context) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if *r.config.Enabled {
+		return true
+	}
+
+	now := r.now()
+	if r.redis == nil || now.Sub(r.refreshedAt) < *r.config.RefreshInterval {
+		return r.toggled
+	}
+
+	r.refreshedAt = now
+
+	toggled, err := r.redis.Exists(ctx, toggleKey).Result()
+	if err == nil {
+		r.toggled = toggled > 0
+	}
+
+	return r.toggled
+}
+
+// Forced returns whether read-only mode is forced on by configuration.
+func (r *ReadOnly) Forced() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return *r.config.Enabled
+}
+
+// Set turns the toggle on or off for all instances, other instances apply it within the refresh interval.
+func (r *ReadOnly) Set(ctx context.Context, enabled bool) error {
+	if r.redis != nil {
+		var err error
+		if enabled {
+			err = r.redis.Set(ctx, toggleKey, "1", 0).Err()
+		} else {
+			err = r.redis.Del(ctx, toggleKey).Err()
+		}
+
+		if err != nil {
+			return fmt.Errorf("failed to set read-only toggle: %w", err)
+		}
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.toggled = enabled
+	r.refreshedAt = r.now()
+
+	return nil
+}
+
+// Message returns the message of rejected requests.
+func (r *ReadOnly) Message() string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return *r.config.Message
+}
+
+// RetryAfter returns seconds clients are asked to wait before retrying, 0 if omitted.
+func (r *ReadOnly) RetryAfter() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return *r.config.RetryAfter
+}
+
+// Reload applies the configuration, the toggle on redis is kept.
+func (r *ReadOnly) Reload(config *Config) error {
+	if config == nil {
+		return nil
+	}
+
+	config.SetDefault()
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.config = config
+
+	return nil
+}
@@ -0,0 +1,116 @@
+package readonly
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/redis"
+)
+
+// setupTestRedis creates a redis client on a flushed database.
+func setupTestRedis(t *testing.T) *redis.Redis {
+	t.Helper()
+
+	password := ""
+	redisDB := 0
+
+	redisClient, err := redis.New(&redis.Config{
+		Addrs:    []string{"localhost:36379"},
+		Password: &password,
+		DB:       &redisDB,
+	})
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	require.NoError(t, redisClient.FlushDB(ctx).Err())
+
+	t.Cleanup(func() {
+		_ = redisClient.Close()
+	})
+
+	return redisClient
+}
+
+func TestConfig(t *testing.T) {
+	t.Parallel()
+
+	t.Run("set default values on read-only config", func(t *testing.T) {
+		t.Parallel()
+
+		config := &Config{}
+		config.SetDefault()
+
+		require.NotNil(t, config.Enabled)
+		assert.False(t, *config.Enabled)
+		require.NotNil(t, config.Message)
+		assert.Equal(t, defaultMessage, *config.Message)
+		require.NotNil(t, config.RetryAfter)
+		assert.Equal(t, 0, *config.RetryAfter)
+		require.NotNil(t, config.RefreshInterval)
+		assert.Equal(t, defaultRefreshInterval, *config.RefreshInterval)
+	})
+}
+
+//nolint:paralleltest // sequential execution required to avoid redis key conflicts
+func TestReadOnly(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("share the toggle between instances", func(t *testing.T) {
+		redisClient := setupTestRedis(t)
+
+		now := time.Now()
+		first := New(nil, redisClient)
+		second := New(nil, redisClient)
+		second.now = func() time.Time { return now }
+
+		assert.False(t, second.Enabled(ctx))
+
+		require.NoError(t, first.Set(ctx, true))
+		assert.True(t, first.Enabled(ctx))
+
+		// other instances read the toggle after the refresh interval
+		assert.False(t, second.Enabled(ctx))
+
+		now = now.Add(defaultRefreshInterval)
+		assert.True(t, second.Enabled(ctx))
+
+		require.NoError(t, first.Set(ctx, false))
+		assert.False(t, first.Enabled(ctx))
+
+		now = now.Add(defaultRefreshInterval)
+		assert.False(t, second.Enabled(ctx))
+	})
+
+	t.Run("force read-only mode by config", func(t *testing.T) {
+		readOnly := New(&Config{Enabled: &[]bool{true}[0]}, setupTestRedis(t))
+
+		require.NoError(t, readOnly.Set(ctx, false))
+		assert.True(t, readOnly.Enabled(ctx))
+		assert.True(t, readOnly.Forced())
+
+		require.NoError(t, readOnly.Reload(&Config{Message: &[]string{"back soon"}[0]}))
+		assert.False(t, readOnly.Enabled(ctx))
+		assert.False(t, readOnly.Forced())
+		assert.Equal(t, "back soon", readOnly.Message())
+	})
+
+	t.Run("keep the toggle in memory without redis", func(t *testing.T) {
+		readOnly := New(nil, nil)
+
+		require.NoError(t, readOnly.Set(ctx, true))
+		assert.True(t, readOnly.Enabled(ctx))
+	})
+
+	t.Run("ignore nil config on reload", func(t *testing.T) {
+		readOnly := New(&Config{RetryAfter: &[]int{30}[0]}, nil)
+
+		require.NoError(t, readOnly.Reload(nil))
+		assert.Equal(t, 30, readOnly.RetryAfter())
+	})
+}
@@ -0,0 +1,323 @@
+package redis
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	// defaultLockTTL is default time locks expire after unless extended.
+	defaultLockTTL = 30 * time.Second
+
+	// defaultLockRetryInterval is default interval between attempts of Lock.
+	defaultLockRetryInterval = 100 * time.Millisecond
+
+	// lockKeyPrefix is prefix of lock keys.
+	lockKeyPrefix = "lock:"
+
+	// lockClockDrift is fraction of TTLs subtracted from validity of locks for clock drift between servers.
+	lockClockDrift = 0.01
+)
+
+var (
+	// ErrLockNotAcquired is returned when the lock is held by another owner.
+	ErrLockNotAcquired = errors.New("lock is held by another owner")
+
+	// ErrLockLost is returned when the lock expired or was acquired by another owner before it was released.
+	ErrLockLost = errors.New("lock was lost")
+)
+
+// acquireScript sets the lock key to the owner token unless it exists,
+// and increments the fencing token of the lock when it is acquired.
+var acquireScript = redis.NewScript(`
+if redis.call("SET", KEYS[1], ARGV[1], "NX", "PX", ARGV[2]) then
+	return redis.call("INCR", KEYS[2])
+end
+return 0
+`)
+
+// extendScript resets the TTL of the lock key if it is held by the owner token.
+var extendScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("PEXPIRE", KEYS[1], ARGV[2])
+end
+return 0
+`)
+
+// releaseScript deletes the lock key if it is held by the owner token.
+var releaseScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("DEL", KEYS[1])
+end
+return 0
+`)
+
+// LockOptions represents options of a lock, zero values are replaced by defaults.
+type LockOptions struct {
+	// TTL is time the lock expires after unless extended.
+	TTL time.Duration
+
+	// RetryInterval is interval between attempts of Lock while the lock is held by another owner.
+	RetryInterval time.Duration
+
+	// AutoExtend is whether a watchdog extends the lock every third of the TTL until it is released.
+	AutoExtend bool
+}
+
+// Lock represents a distributed lock held on redis, released with Release.
+type Lock struct {
+	// redis provides redis client holding the lock.
+	redis *Redis
+
+	// key is redis key of the lock.
+	key string
+
+	// token is random value identifying the owner of the lock.
+	token string
+
+	// fence is fencing token of the lock, increasing with every acquisition.
+	fence int64
+
+	// ttl is time the lock expires after unless extended.
+	ttl time.Duration
+
+	// lost is closed when the watchdog fails to extend the lock.
+	lost chan struct{}
+
+	// stop stops the watchdog, nil if the lock is not extended automatically.
+	stop context.CancelFunc
+
+	// done is closed when the watchdog returns.
+	done chan struct{}
+}
+
+// setDefault sets default values of the options.
+func (o *LockOptions) setDefault() {
+	if o.TTL <= 0 {
+		o.TTL = defaultLockTTL
+	}
+
+	if o.RetryInterval <= 0 {
+		o.RetryInterval = defaultLockRetryInterval
+	}
+}
+
+// TryLock acquires the lock of the name, ErrLockNotAcquired is returned if it is held by another owner.
+// Options may be nil to use defaults.
+func (r *Redis) TryLock(ctx context.Context, name string, options *LockOptions) (*Lock, error) {
+	opts := LockOptions{}
+	if options != nil {
+		opts = *options
+	}
+
+	opts.setDefault()
+
+	token, err := newLockToken()
+	if err != nil {
+		return nil, err
+	}
+
+	key := lockKeyPrefix + "{" + name + "}"
+	start := time.Now()
+
+	fence, err := acquireScript.Run(ctx, r, []string{key, key + ":fence"}, token, opts.TTL.Milliseconds()).Int64()
+	if err != nil {
+		return nil, fmt.Errorf("failed to acquire lock %s: %w", name, err)
+	}
+
+	if fence == 0 {
+		return nil, ErrLockNotAcquired
+	}
+
+	lock := &Lock{
+		redis: r,
+		key:   key,
+		token: token,
+		fence: fence,
+		ttl:   opts.TTL,
+		lost:  make(chan struct{}),
+	}
+
+	// the lock is not valid if it may have expired while it was acquired
+	if time.Since(start) >= opts.TTL-time.Duration(lockClockDrift*float64(opts.TTL)) {
+		_ = lock.Release(context.WithoutCancel(ctx))
+
+		return nil, ErrLockNotAcquired
+	}
+
+	if opts.AutoExtend {
+		lock.startWatchdog()
+	}
+
+	return lock, nil
+}
+
+// Lock acquires the lock of the name, waiting while it is held by another owner until the context is done.
+// Options may be nil to use defaults.
+func (r *Redis) Lock(ctx context.Context, name string, options *LockOptions) (*Lock, error) {
+	opts := LockOptions{}
+	if options != nil {
+		opts = *options
+	}
+
+	opts.setDefault()
+
+	for {
+		lock, err := r.TryLock(ctx, name, &opts)
+		if !errors.Is(err, ErrLockNotAcquired) {
+			return lock, err
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, fmt.Errorf("failed to acquire lock %s: %w", name, ctx.Err())
+		case <-time.After(opts.RetryInterval):
+		}
+	}
+}
+
+// WithLock runs the function while holding the lock of the name, waiting for the lock like Lock.
+// The lock is extended automatically, and the context of the function is canceled if the lock is lost,
+// in which case ErrLockLost is returned unless the function returns an error.
+func (r *Redis) WithLock(
+	ctx context.Context,
+	name string,
+	options *LockOptions,
+	fn func(ctx context.Context, lock *Lock) error,
+) error {
+	opts := LockOptions{}
+	if options != nil {
+		opts = *options
+	}
+
+	opts.AutoExtend = true
+
+	lock, err := r.Lock(ctx, name, &opts)
+	if err != nil {
+		return err
+	}
+
+	fnCtx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+
+	go func() {
+		select {
+		case <-lock.Lost():
+			cancel(ErrLockLost)
+		case <-fnCtx.Done():
+		}
+	}()
+
+	fnErr := fn(fnCtx, lock)
+
+	// the lock is released even if the context is canceled, so that others do not wait for it to expire
+	releaseErr := lock.Release(context.WithoutCancel(ctx))
+
+	if fnErr != nil {
+		return fnErr
+	}
+
+	return releaseErr
+}
+
+// Token returns the fencing token of the lock, which increases with every acquisition of the lock,
+// so that resources can reject writes of owners whose lock was taken over.
+func (l *Lock) Token() int64 {
+	return l.fence
+}
+
+// Lost returns a channel closed when the watchdog fails to extend the lock, never closed without AutoExtend.
+func (l *Lock) Lost() <-chan struct{} {
+	return l.lost
+}
+
+// Extend resets the TTL of the lock, ErrLockLost is returned if the lock is no longer held.
+func (l *Lock) Extend(ctx context.Context, ttl time.Duration) error {
+	extended, err := extendScript.Run(ctx, l.redis, []string{l.key}, l.token, ttl.Milliseconds()).Int64()
+	if err != nil {
+		return fmt.Errorf("failed to extend lock %s: %w", l.key, err)
+	}
+
+	if extended == 0 {
+		return ErrLockLost
+	}
+
+	return nil
+}
+
+// Release stops the watchdog and releases the lock, ErrLockLost is returned if the lock is no longer held.
+func (l *Lock) Release(ctx context.Context) error {
+	if l.stop != nil {
+		l.stop()
+		<-l.done
+	}
+
+	released, err := releaseScript.Run(ctx, l.redis, []string{l.key}, l.token).Int64()
+	if err != nil {
+		return fmt.Errorf("failed to release lock %s: %w", l.key, err)
+	}
+
+	if released == 0 {
+		return ErrLockLost
+	}
+
+	return nil
+}
+
+// startWatchdog extends the lock every third of the TTL until it is released,
+// the lock is lost when it is not held anymore or could not be extended within the TTL.
+func (l *Lock) startWatchdog() {
+	ctx, cancel := context.WithCancel(context.Background())
+	l.stop = cancel
+	l.done = make(chan struct{})
+
+	go func() {
+		defer close(l.done)
+
+		ticker := time.NewTicker(l.ttl / 3)
+		defer ticker.Stop()
+
+		extended := time.Now()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+
+			extendCtx, cancelExtend := context.WithTimeout(ctx, l.ttl/3)
+			err := l.Extend(extendCtx, l.ttl)
+
+			cancelExtend()
+
+			// transient failures are retried until the lock may have expired
+			switch {
+			case err == nil:
+				extended = time.Now()
+			case ctx.Err() != nil:
+				return
+			case errors.Is(err, ErrLockLost), time.Since(extended) >= l.ttl:
+				close(l.lost)
+
+				return
+			}
+		}
+	}()
+}
+
+// newLockToken returns a random token identifying the owner of a lock.
+func newLockToken() (string, error) {
+	token := make([]byte, 16)
+	if _, err := rand.Read(token); err != nil {
+		return "", fmt.Errorf("failed to generate lock token: %w", err)
+	}
+
+	return hex.EncodeToString(token), nil
+}
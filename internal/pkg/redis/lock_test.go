@@ -0,0 +1,252 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var errCriticalSection = errors.New("critical section failed")
+
+// setupTestLockRedis creates a redis client for lock tests.
+func setupTestLockRedis(t *testing.T) *Redis {
+	t.Helper()
+
+	password := testPassword
+	redisDB := testDB
+
+	redis, err := New(&Config{Addrs: []string{testAddr}, Password: &password, DB: &redisDB})
+	require.NoError(t, err)
+
+	t.Cleanup(func() {
+		_ = redis.Close()
+	})
+
+	return redis
+}
+
+// testLockName returns a lock name unique to the test.
+func testLockName(t *testing.T) string {
+	t.Helper()
+
+	return fmt.Sprintf("%s:%d", t.Name(), time.Now().UnixNano())
+}
+
+func TestTryLock(t *testing.T) {
+	t.Parallel()
+
+	t.Run("acquire lock held by no other owner", func(t *testing.T) {
+		t.Parallel()
+
+		redis := setupTestLockRedis(t)
+		ctx := context.Background()
+		name := testLockName(t)
+
+		lock, err := redis.TryLock(ctx, name, nil)
+		require.NoError(t, err)
+
+		_, err = redis.TryLock(ctx, name, nil)
+		require.ErrorIs(t, err, ErrLockNotAcquired)
+
+		ttl, err := redis.PTTL(ctx, lock.key).Result()
+		require.NoError(t, err)
+		assert.InDelta(t, float64(defaultLockTTL), float64(ttl), float64(time.Second))
+
+		require.NoError(t, lock.Release(ctx))
+		require.ErrorIs(t, lock.Release(ctx), ErrLockLost)
+
+		// the lock can be acquired again once released, with a greater fencing token
+		next, err := redis.TryLock(ctx, name, nil)
+		require.NoError(t, err)
+		assert.Greater(t, next.Token(), lock.Token())
+		require.NoError(t, next.Release(ctx))
+	})
+
+	t.Run("lose lock after ttl", func(t *testing.T) {
+		t.Parallel()
+
+		redis := setupTestLockRedis(t)
+		ctx := context.Background()
+		name := testLockName(t)
+
+		lock, err := redis.TryLock(ctx, name, &LockOptions{TTL: 200 * time.Millisecond})
+		require.NoError(t, err)
+
+		var other *Lock
+
+		require.Eventually(t, func() bool {
+			other, err = redis.TryLock(ctx, name, nil)
+
+			return err == nil
+		}, 2*time.Second, 50*time.Millisecond)
+
+		require.ErrorIs(t, lock.Extend(ctx, time.Second), ErrLockLost)
+		require.ErrorIs(t, lock.Release(ctx), ErrLockLost)
+		require.NoError(t, other.Release(ctx))
+	})
+}
+
+func TestLock(t *testing.T) {
+	t.Parallel()
+
+	t.Run("wait for lock held by another owner", func(t *testing.T) {
+		t.Parallel()
+
+		redis := setupTestLockRedis(t)
+		ctx := context.Background()
+		name := testLockName(t)
+
+		lock, err := redis.TryLock(ctx, name, nil)
+		require.NoError(t, err)
+
+		go func() {
+			time.Sleep(100 * time.Millisecond)
+			_ = lock.Release(ctx)
+		}()
+
+		next, err := redis.Lock(ctx, name, &LockOptions{RetryInterval: 10 * time.Millisecond})
+		require.NoError(t, err)
+		require.NoError(t, next.Release(ctx))
+	})
+
+	t.Run("return error when context is done", func(t *testing.T) {
+		t.Parallel()
+
+		redis := setupTestLockRedis(t)
+		name := testLockName(t)
+
+		lock, err := redis.TryLock(context.Background(), name, nil)
+		require.NoError(t, err)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+
+		_, err = redis.Lock(ctx, name, nil)
+		require.ErrorIs(t, err, context.DeadlineExceeded)
+		require.NoError(t, lock.Release(context.Background()))
+	})
+
+	t.Run("extend lock automatically", func(t *testing.T) {
+		t.Parallel()
+
+		redis := setupTestLockRedis(t)
+		ctx := context.Background()
+		name := testLockName(t)
+
+		lock, err := redis.TryLock(ctx, name, &LockOptions{TTL: 300 * time.Millisecond, AutoExtend: true})
+		require.NoError(t, err)
+
+		time.Sleep(time.Second)
+
+		_, err = redis.TryLock(ctx, name, nil)
+		require.ErrorIs(t, err, ErrLockNotAcquired)
+		require.NoError(t, lock.Release(ctx))
+	})
+
+	t.Run("signal lost lock", func(t *testing.T) {
+		t.Parallel()
+
+		redis := setupTestLockRedis(t)
+		ctx := context.Background()
+
+		lock, err := redis.TryLock(ctx, testLockName(t), &LockOptions{TTL: 300 * time.Millisecond, AutoExtend: true})
+		require.NoError(t, err)
+
+		require.NoError(t, redis.Del(ctx, lock.key).Err())
+
+		select {
+		case <-lock.Lost():
+		case <-time.After(time.Second):
+			t.Fatal("lost lock was not signaled")
+		}
+
+		require.ErrorIs(t, lock.Release(ctx), ErrLockLost)
+	})
+}
+
+func TestWithLock(t *testing.T) {
+	t.Parallel()
+
+	t.Run("run critical sections one at a time", func(t *testing.T) {
+		t.Parallel()
+
+		redis := setupTestLockRedis(t)
+		name := testLockName(t)
+
+		var (
+			wg      sync.WaitGroup
+			mu      sync.Mutex
+			running int
+			tokens  []int64
+		)
+
+		for range 3 {
+			wg.Go(func() {
+				err := redis.WithLock(context.Background(), name, &LockOptions{RetryInterval: 10 * time.Millisecond},
+					func(_ context.Context, lock *Lock) error {
+						mu.Lock()
+						running++
+						assert.Equal(t, 1, running)
+						tokens = append(tokens, lock.Token())
+						mu.Unlock()
+
+						time.Sleep(20 * time.Millisecond)
+
+						mu.Lock()
+						running--
+						mu.Unlock()
+
+						return nil
+					})
+				assert.NoError(t, err)
+			})
+		}
+
+		wg.Wait()
+
+		require.Len(t, tokens, 3)
+		assert.Less(t, tokens[0], tokens[1])
+		assert.Less(t, tokens[1], tokens[2])
+	})
+
+	t.Run("return error of critical section and release lock", func(t *testing.T) {
+		t.Parallel()
+
+		redis := setupTestLockRedis(t)
+		ctx := context.Background()
+		name := testLockName(t)
+
+		err := redis.WithLock(ctx, name, nil, func(context.Context, *Lock) error {
+			return errCriticalSection
+		})
+		require.ErrorIs(t, err, errCriticalSection)
+
+		lock, err := redis.TryLock(ctx, name, nil)
+		require.NoError(t, err)
+		require.NoError(t, lock.Release(ctx))
+	})
+
+	t.Run("cancel critical section when lock is lost", func(t *testing.T) {
+		t.Parallel()
+
+		redis := setupTestLockRedis(t)
+		ctx := context.Background()
+
+		err := redis.WithLock(ctx, testLockName(t), &LockOptions{TTL: 300 * time.Millisecond},
+			func(ctx context.Context, lock *Lock) error {
+				require.NoError(t, redis.Del(ctx, lock.key).Err())
+
+				<-ctx.Done()
+				assert.ErrorIs(t, context.Cause(ctx), ErrLockLost)
+
+				return nil
+			})
+		require.ErrorIs(t, err, ErrLockLost)
+	})
+}
@@ -4,9 +4,12 @@ package redis
 import (
 	"context"
 	"fmt"
+	"time"
 
 	"github.com/redis/go-redis/v9"
 	"go.uber.org/fx"
+
+	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/health"
 )
 
 // Redis represents redis.
@@ -20,6 +23,9 @@ type Config struct {
 	// Addrs is addresses of redis servers.
 	Addrs []string `json:"addrs"`
 
+	// Username is username of redis ACL, empty for the default user.
+	Username *string `json:"username"`
+
 	// Password is password of redis.
 	Password *string `json:"password"`
 
@@ -31,6 +37,43 @@ type Config struct {
 
 	// SentinelAddrs is sentinel addresses.
 	SentinelAddrs []string `json:"sentinel_addrs"`
+
+	// SentinelUsername is username of sentinels, which authenticate separately from redis servers.
+	SentinelUsername *string `json:"sentinel_username"`
+
+	// SentinelPassword is password of sentinels, empty if sentinels do not require authentication.
+	SentinelPassword *string `json:"sentinel_password"`
+
+	// ReadOnly is whether read-only commands are served by replicas,
+	// in sentinel mode all commands are sent to replicas.
+	ReadOnly *bool `json:"read_only"`
+
+	// RouteByLatency is whether read-only commands are routed to the node with the lowest latency.
+	RouteByLatency *bool `json:"route_by_latency"`
+
+	// RouteRandomly is whether read-only commands are routed to a random node.
+	RouteRandomly *bool `json:"route_randomly"`
+
+	// MaxRedirects is maximum number of MOVED and ASK redirects followed per command in cluster mode.
+	MaxRedirects *int `json:"max_redirects"`
+
+	// TLS provides TLS of connections to redis servers and sentinels.
+	TLS *TLSConfig `json:"tls"`
+
+	// PoolSize is maximum number of connections per node, 0 for 10 per CPU.
+	PoolSize *int `json:"pool_size"`
+
+	// MinIdleConns is minimum number of idle connections kept open per node.
+	MinIdleConns *int `json:"min_idle_conns"`
+
+	// DialTimeout is timeout of establishing connections.
+	DialTimeout *time.Duration `json:"dial_timeout"`
+
+	// ReadTimeout is timeout of reading replies of commands.
+	ReadTimeout *time.Duration `json:"read_timeout"`
+
+	// WriteTimeout is timeout of writing commands.
+	WriteTimeout *time.Duration `json:"write_timeout"`
 }
 
 const (
@@ -45,6 +88,18 @@ const (
 
 	// defaultMasterName is default master name of redis.
 	defaultMasterName = ""
+
+	// defaultMaxRedirects is default maximum number of redirects per command in cluster mode.
+	defaultMaxRedirects = 3
+
+	// defaultDialTimeout is default timeout of establishing connections.
+	defaultDialTimeout = 5 * time.Second
+
+	// defaultReadTimeout is default timeout of reading replies.
+	defaultReadTimeout = 3 * time.Second
+
+	// defaultWriteTimeout is default timeout of writing commands.
+	defaultWriteTimeout = 3 * time.Second
 )
 
 // SetDefault sets default values.
@@ -53,6 +108,10 @@ func (c *Config) SetDefault() {
 		c.Addrs = []string{defaultAddr}
 	}
 
+	if c.Username == nil {
+		c.Username = &[]string{""}[0]
+	}
+
 	if c.Password == nil {
 		password := defaultPassword
 		c.Password = &password
@@ -71,15 +130,79 @@ func (c *Config) SetDefault() {
 	if c.SentinelAddrs == nil {
 		c.SentinelAddrs = []string{}
 	}
+
+	if c.SentinelUsername == nil {
+		c.SentinelUsername = &[]string{""}[0]
+	}
+
+	if c.SentinelPassword == nil {
+		c.SentinelPassword = &[]string{""}[0]
+	}
+
+	if c.ReadOnly == nil {
+		c.ReadOnly = &[]bool{false}[0]
+	}
+
+	if c.RouteByLatency == nil {
+		c.RouteByLatency = &[]bool{false}[0]
+	}
+
+	if c.RouteRandomly == nil {
+		c.RouteRandomly = &[]bool{false}[0]
+	}
+
+	if c.MaxRedirects == nil {
+		c.MaxRedirects = &[]int{defaultMaxRedirects}[0]
+	}
+
+	if c.TLS == nil {
+		c.TLS = &TLSConfig{}
+	}
+
+	c.TLS.SetDefault()
+
+	if c.PoolSize == nil {
+		c.PoolSize = &[]int{0}[0]
+	}
+
+	if c.MinIdleConns == nil {
+		c.MinIdleConns = &[]int{0}[0]
+	}
+
+	if c.DialTimeout == nil {
+		c.DialTimeout = &[]time.Duration{defaultDialTimeout}[0]
+	}
+
+	if c.ReadTimeout == nil {
+		c.ReadTimeout = &[]time.Duration{defaultReadTimeout}[0]
+	}
+
+	if c.WriteTimeout == nil {
+		c.WriteTimeout = &[]time.Duration{defaultWriteTimeout}[0]
+	}
 }
 
 // NewModule provides module for redis.
 func NewModule() fx.Option {
 	return fx.Module("redis",
 		fx.Provide(New),
+		fx.Invoke(registerHealthCheck),
 	)
 }
 
+// registerHealthCheck registers the ping of redis as readiness check.
+func registerHealthCheck(registry *health.Registry, redis *Redis) error {
+	check := health.CheckerFunc(func(ctx context.Context) error {
+		return redis.Ping(ctx).Err()
+	})
+
+	if err := registry.Register("redis", 0, check); err != nil {
+		return fmt.Errorf("failed to register redis health check: %w", err)
+	}
+
+	return nil
+}
+
 // New creates new redis instance.
 func New(config *Config) (*Redis, error) {
 	ctx := context.Background()
@@ -90,24 +213,17 @@ func New(config *Config) (*Redis, error) {
 
 	config.SetDefault()
 
-	// create universal client options
-	options := &redis.UniversalOptions{
-		Addrs:    config.Addrs,
-		Password: *config.Password,
-		DB:       *config.DB,
-	}
-
-	if *config.MasterName != "" {
-		options.MasterName = *config.MasterName
-	}
-
-	if len(config.SentinelAddrs) > 0 {
-		options.Addrs = config.SentinelAddrs
+	options, err := newUniversalOptions(config)
+	if err != nil {
+		return nil, err
 	}
 
 	// create universal client
 	redisClient := redis.NewUniversalClient(options)
 
+	// record a span of each command in the trace of the request
+	redisClient.AddHook(tracingHook{})
+
 	// ping redis connection
 	if err := redisClient.Ping(ctx).Err(); err != nil {
 		return nil, fmt.Errorf("failed to ping redis: %w", err)
@@ -117,3 +233,41 @@ func New(config *Config) (*Redis, error) {
 		UniversalClient: redisClient,
 	}, nil
 }
+
+// newUniversalOptions creates universal client options from the config, it expects default values to be set.
+func newUniversalOptions(config *Config) (*redis.UniversalOptions, error) {
+	tlsConfig, err := newTLSConfig(config.TLS)
+	if err != nil {
+		return nil, err
+	}
+
+	options := &redis.UniversalOptions{
+		Addrs:            config.Addrs,
+		Username:         *config.Username,
+		Password:         *config.Password,
+		DB:               *config.DB,
+		SentinelUsername: *config.SentinelUsername,
+		SentinelPassword: *config.SentinelPassword,
+		ReadOnly:         *config.ReadOnly,
+		RouteByLatency:   *config.RouteByLatency,
+		RouteRandomly:    *config.RouteRandomly,
+		MaxRedirects:     *config.MaxRedirects,
+		TLSConfig:        tlsConfig,
+		PoolSize:         *config.PoolSize,
+		MinIdleConns:     *config.MinIdleConns,
+		DialTimeout:      *config.DialTimeout,
+		ReadTimeout:      *config.ReadTimeout,
+		WriteTimeout:     *config.WriteTimeout,
+	}
+
+	if *config.MasterName != "" {
+		options.MasterName = *config.MasterName
+	}
+
+	// the universal client connects to sentinels through addrs in sentinel mode
+	if len(config.SentinelAddrs) > 0 {
+		options.Addrs = config.SentinelAddrs
+	}
+
+	return options, nil
+}
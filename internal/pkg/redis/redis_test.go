@@ -7,6 +7,8 @@ import (
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+
+	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/health"
 )
 
 const (
@@ -42,6 +44,19 @@ func TestConfig(t *testing.T) {
 		assert.Equal(t, defaultMasterName, *config.MasterName)
 		require.NotNil(t, config.SentinelAddrs)
 		assert.Equal(t, []string{}, config.SentinelAddrs)
+		assert.Empty(t, *config.Username)
+		assert.Empty(t, *config.SentinelUsername)
+		assert.Empty(t, *config.SentinelPassword)
+		assert.False(t, *config.ReadOnly)
+		assert.False(t, *config.RouteByLatency)
+		assert.False(t, *config.RouteRandomly)
+		assert.Equal(t, defaultMaxRedirects, *config.MaxRedirects)
+		assert.False(t, *config.TLS.Enabled)
+		assert.Zero(t, *config.PoolSize)
+		assert.Zero(t, *config.MinIdleConns)
+		assert.Equal(t, defaultDialTimeout, *config.DialTimeout)
+		assert.Equal(t, defaultReadTimeout, *config.ReadTimeout)
+		assert.Equal(t, defaultWriteTimeout, *config.WriteTimeout)
 	})
 
 	t.Run("preserve existing values on redis config", func(t *testing.T) {
@@ -71,6 +86,102 @@ func TestConfig(t *testing.T) {
 	})
 }
 
+func TestNewUniversalOptions(t *testing.T) {
+	t.Parallel()
+
+	t.Run("pass standalone options", func(t *testing.T) {
+		t.Parallel()
+
+		config := &Config{Addrs: []string{testAddr}, Username: &[]string{"app"}[0]}
+		config.SetDefault()
+
+		options, err := newUniversalOptions(config)
+		require.NoError(t, err)
+
+		assert.Equal(t, []string{testAddr}, options.Addrs)
+		assert.Equal(t, "app", options.Username)
+		assert.Equal(t, defaultPassword, options.Password)
+		assert.Empty(t, options.MasterName)
+	})
+
+	t.Run("pass sentinel auth and routing options", func(t *testing.T) {
+		t.Parallel()
+
+		config := &Config{
+			MasterName:       &[]string{"mymaster"}[0],
+			SentinelAddrs:    []string{"sentinel-1:26379", "sentinel-2:26379"},
+			SentinelUsername: &[]string{"sentinel"}[0],
+			SentinelPassword: &[]string{"sentinel_password"}[0],
+			ReadOnly:         &[]bool{true}[0],
+			RouteByLatency:   &[]bool{true}[0],
+			RouteRandomly:    &[]bool{true}[0],
+		}
+		config.SetDefault()
+
+		options, err := newUniversalOptions(config)
+		require.NoError(t, err)
+
+		assert.Equal(t, "mymaster", options.MasterName)
+		assert.Equal(t, []string{"sentinel-1:26379", "sentinel-2:26379"}, options.Addrs)
+		assert.Equal(t, "sentinel", options.SentinelUsername)
+		assert.Equal(t, "sentinel_password", options.SentinelPassword)
+		assert.True(t, options.ReadOnly)
+		assert.True(t, options.RouteByLatency)
+		assert.True(t, options.RouteRandomly)
+
+		// sentinel credentials reach the failover client separately from the redis password
+		failover := options.Failover()
+		assert.Equal(t, "sentinel_password", failover.SentinelPassword)
+		assert.Equal(t, defaultPassword, failover.Password)
+		assert.True(t, failover.ReplicaOnly)
+	})
+
+	t.Run("pass pool, timeout and cluster options", func(t *testing.T) {
+		t.Parallel()
+
+		config := &Config{
+			Addrs:          []string{"node-1:6379", "node-2:6379"},
+			ReadOnly:       &[]bool{true}[0],
+			RouteByLatency: &[]bool{true}[0],
+			MaxRedirects:   &[]int{5}[0],
+			PoolSize:       &[]int{50}[0],
+			MinIdleConns:   &[]int{5}[0],
+			DialTimeout:    &[]time.Duration{time.Second}[0],
+			ReadTimeout:    &[]time.Duration{500 * time.Millisecond}[0],
+			WriteTimeout:   &[]time.Duration{250 * time.Millisecond}[0],
+		}
+		config.SetDefault()
+
+		options, err := newUniversalOptions(config)
+		require.NoError(t, err)
+		assert.Nil(t, options.TLSConfig)
+
+		cluster := options.Cluster()
+		assert.Equal(t, []string{"node-1:6379", "node-2:6379"}, cluster.Addrs)
+		assert.True(t, cluster.ReadOnly)
+		assert.True(t, cluster.RouteByLatency)
+		assert.Equal(t, 5, cluster.MaxRedirects)
+		assert.Equal(t, 50, cluster.PoolSize)
+		assert.Equal(t, 5, cluster.MinIdleConns)
+		assert.Equal(t, time.Second, cluster.DialTimeout)
+		assert.Equal(t, 500*time.Millisecond, cluster.ReadTimeout)
+		assert.Equal(t, 250*time.Millisecond, cluster.WriteTimeout)
+	})
+
+	t.Run("return error of invalid tls config", func(t *testing.T) {
+		t.Parallel()
+
+		config := &Config{TLS: &TLSConfig{Enabled: &[]bool{true}[0], CertFile: &[]string{"cert.pem"}[0]}}
+		config.SetDefault()
+
+		_, err := newUniversalOptions(config)
+		require.ErrorIs(t, err, ErrInvalidTLSConfig)
+
+		_, err = New(config)
+		require.ErrorIs(t, err, ErrInvalidTLSConfig)
+	})
+}
+
 func TestNew(t *testing.T) {
 	t.Parallel()
 
@@ -307,4 +418,25 @@ func TestNewModule(t *testing.T) {
 		module := NewModule()
 		require.NotNil(t, module)
 	})
+
+	t.Run("register readiness check", func(t *testing.T) {
+		t.Parallel()
+
+		redis, err := New(&Config{Addrs: []string{testAddr}})
+		require.NoError(t, err)
+
+		defer func() { _ = redis.Close() }()
+
+		registry := health.NewRegistry()
+		require.NoError(t, registerHealthCheck(registry, redis))
+
+		report := registry.Check(context.Background())
+		assert.Equal(t, health.StatusOK, report.Checks["redis"].Status)
+
+		// a closed client fails the check
+		require.NoError(t, redis.Close())
+
+		report = registry.Check(context.Background())
+		assert.Equal(t, health.StatusFail, report.Checks["redis"].Status)
+	})
 }
@@ -0,0 +1,108 @@
+package redis
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+)
+
+var (
+	// ErrInvalidTLSConfig is returned when only one of the client certificate and key files is set.
+	ErrInvalidTLSConfig = errors.New("redis tls requires both cert_file and key_file for client certificates")
+
+	// ErrInvalidTLSCA is returned when the CA file contains no PEM certificates.
+	ErrInvalidTLSCA = errors.New("redis tls ca file contains no certificates")
+)
+
+// TLSConfig represents configuration for TLS of redis connections.
+type TLSConfig struct {
+	// Enabled is whether connections to redis servers and sentinels use TLS.
+	Enabled *bool `json:"enabled"`
+
+	// CertFile is path to the client certificate file, for servers requiring client certificates.
+	CertFile *string `json:"cert_file"`
+
+	// KeyFile is path to the client private key file.
+	KeyFile *string `json:"key_file"`
+
+	// CAFile is path to the CA file verifying server certificates, system roots if empty.
+	CAFile *string `json:"ca_file"`
+
+	// ServerName is name verified against server certificates, the host of each address if empty.
+	ServerName *string `json:"server_name"`
+
+	// InsecureSkipVerify is whether server certificates are not verified, for development only.
+	InsecureSkipVerify *bool `json:"insecure_skip_verify"`
+}
+
+// SetDefault sets default values.
+func (c *TLSConfig) SetDefault() {
+	if c.Enabled == nil {
+		c.Enabled = &[]bool{false}[0]
+	}
+
+	if c.CertFile == nil {
+		c.CertFile = &[]string{""}[0]
+	}
+
+	if c.KeyFile == nil {
+		c.KeyFile = &[]string{""}[0]
+	}
+
+	if c.CAFile == nil {
+		c.CAFile = &[]string{""}[0]
+	}
+
+	if c.ServerName == nil {
+		c.ServerName = &[]string{""}[0]
+	}
+
+	if c.InsecureSkipVerify == nil {
+		c.InsecureSkipVerify = &[]bool{false}[0]
+	}
+}
+
+// newTLSConfig creates TLS configuration of redis connections, nil if TLS is disabled.
+func newTLSConfig(config *TLSConfig) (*tls.Config, error) {
+	if !*config.Enabled {
+		return nil, nil //nolint:nilnil // nil disables TLS on the universal client
+	}
+
+	if (*config.CertFile == "") != (*config.KeyFile == "") {
+		return nil, ErrInvalidTLSConfig
+	}
+
+	// #nosec G402 -- skipping verification is opt-in for development
+	tlsConfig := &tls.Config{
+		MinVersion:         tls.VersionTLS12,
+		ServerName:         *config.ServerName,
+		InsecureSkipVerify: *config.InsecureSkipVerify,
+	}
+
+	if *config.CertFile != "" {
+		cert, err := tls.LoadX509KeyPair(*config.CertFile, *config.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load redis tls certificate %s: %w", *config.CertFile, err)
+		}
+
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	if *config.CAFile != "" {
+		pem, err := os.ReadFile(*config.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read redis tls ca file: %w", err)
+		}
+
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, ErrInvalidTLSCA
+		}
+
+		tlsConfig.RootCAs = pool
+	}
+
+	return tlsConfig, nil
+}
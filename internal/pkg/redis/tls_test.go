@@ -0,0 +1,158 @@
+package redis
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writeTestCert writes a self-signed certificate and key and returns their paths.
+func writeTestCert(t *testing.T) (string, string) {
+	t.Helper()
+
+	dir := t.TempDir()
+	certFile := filepath.Join(dir, "cert.pem")
+	keyFile := filepath.Join(dir, "key.pem")
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "redis"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+
+	certDER, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+
+	require.NoError(t, os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: certDER}), 0o600))
+	require.NoError(t, os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0o600))
+
+	return certFile, keyFile
+}
+
+func TestTLSConfigSetDefault(t *testing.T) {
+	t.Parallel()
+
+	config := &TLSConfig{}
+	config.SetDefault()
+
+	assert.False(t, *config.Enabled)
+	assert.Empty(t, *config.CertFile)
+	assert.Empty(t, *config.KeyFile)
+	assert.Empty(t, *config.CAFile)
+	assert.Empty(t, *config.ServerName)
+	assert.False(t, *config.InsecureSkipVerify)
+}
+
+func TestNewTLSConfig(t *testing.T) {
+	t.Parallel()
+
+	t.Run("return nil when disabled", func(t *testing.T) {
+		t.Parallel()
+
+		config := &TLSConfig{}
+		config.SetDefault()
+
+		tlsConfig, err := newTLSConfig(config)
+		require.NoError(t, err)
+		assert.Nil(t, tlsConfig)
+	})
+
+	t.Run("load client certificate and ca", func(t *testing.T) {
+		t.Parallel()
+
+		certFile, keyFile := writeTestCert(t)
+
+		config := &TLSConfig{
+			Enabled:    &[]bool{true}[0],
+			CertFile:   &certFile,
+			KeyFile:    &keyFile,
+			CAFile:     &certFile,
+			ServerName: &[]string{"redis.internal"}[0],
+		}
+		config.SetDefault()
+
+		tlsConfig, err := newTLSConfig(config)
+		require.NoError(t, err)
+		assert.Equal(t, uint16(tls.VersionTLS12), tlsConfig.MinVersion)
+		assert.Equal(t, "redis.internal", tlsConfig.ServerName)
+		assert.Len(t, tlsConfig.Certificates, 1)
+		assert.NotNil(t, tlsConfig.RootCAs)
+		assert.False(t, tlsConfig.InsecureSkipVerify)
+	})
+
+	t.Run("skip verification when configured", func(t *testing.T) {
+		t.Parallel()
+
+		config := &TLSConfig{Enabled: &[]bool{true}[0], InsecureSkipVerify: &[]bool{true}[0]}
+		config.SetDefault()
+
+		tlsConfig, err := newTLSConfig(config)
+		require.NoError(t, err)
+		assert.True(t, tlsConfig.InsecureSkipVerify)
+		assert.Nil(t, tlsConfig.RootCAs)
+	})
+
+	t.Run("return errors of invalid files", func(t *testing.T) {
+		t.Parallel()
+
+		certFile, _ := writeTestCert(t)
+		missing := filepath.Join(t.TempDir(), "missing.pem")
+
+		tests := map[string]struct {
+			config *TLSConfig
+			err    error
+		}{
+			"cert without key": {
+				config: &TLSConfig{CertFile: &certFile},
+				err:    ErrInvalidTLSConfig,
+			},
+			"ca without certificates": {
+				config: &TLSConfig{CAFile: &[]string{filepath.Join(t.TempDir(), "empty.pem")}[0]},
+				err:    ErrInvalidTLSCA,
+			},
+			"missing ca": {
+				config: &TLSConfig{CAFile: &missing},
+			},
+			"missing key pair": {
+				config: &TLSConfig{CertFile: &missing, KeyFile: &missing},
+			},
+		}
+
+		require.NoError(t, os.WriteFile(*tests["ca without certificates"].config.CAFile, []byte("empty"), 0o600))
+
+		for name, test := range tests {
+			test.config.Enabled = &[]bool{true}[0]
+			test.config.SetDefault()
+
+			_, err := newTLSConfig(test.config)
+			require.Error(t, err, name)
+
+			if test.err != nil {
+				require.ErrorIs(t, err, test.err, name)
+			}
+		}
+	})
+}
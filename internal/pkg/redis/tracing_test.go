@@ -0,0 +1,71 @@
+package redis
+
+import (
+	"context"
+	"testing"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+//nolint:paralleltest // sequential execution required to replace the global tracer provider
+func TestTracingHook(t *testing.T) {
+	t.Run("record commands and pipelines as child spans", func(t *testing.T) {
+		spanRecorder := tracetest.NewSpanRecorder()
+		otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(spanRecorder)))
+		t.Cleanup(func() { otel.SetTracerProvider(noop.NewTracerProvider()) })
+
+		password := testPassword
+		db := testDB
+
+		redisClient, err := New(&Config{Addrs: []string{testAddr}, Password: &password, DB: &db})
+		require.NoError(t, err)
+
+		t.Cleanup(func() {
+			_ = redisClient.Close()
+		})
+
+		ctx, parent := otel.Tracer("test").Start(context.Background(), "request")
+
+		require.ErrorIs(t, redisClient.Get(ctx, "tracing:missing").Err(), redis.Nil)
+
+		_, err = redisClient.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+			pipe.Set(ctx, "tracing:key", "secret-value", 0)
+			pipe.Del(ctx, "tracing:key")
+
+			return nil
+		})
+		require.NoError(t, err)
+
+		parent.End()
+
+		spans := map[string]sdktrace.ReadOnlySpan{}
+		for _, span := range spanRecorder.Ended() {
+			if span.Parent().SpanID() == parent.SpanContext().SpanID() {
+				spans[span.Name()] = span
+			}
+		}
+
+		require.Contains(t, spans, "redis.get")
+		assert.Equal(t, codes.Unset, spans["redis.get"].Status().Code)
+		assert.Contains(t, spans["redis.get"].Attributes(), attribute.String("db.system.name", "redis"))
+		assert.Contains(t, spans["redis.get"].Attributes(), attribute.String("db.operation.name", "get"))
+
+		require.Contains(t, spans, "redis.pipeline")
+		assert.Contains(t, spans["redis.pipeline"].Attributes(), attribute.Int("db.operation.batch.size", 2))
+
+		// arguments of commands are not recorded
+		for _, span := range spans {
+			for _, attr := range span.Attributes() {
+				assert.NotContains(t, attr.Value.Emit(), "secret-value")
+			}
+		}
+	})
+}
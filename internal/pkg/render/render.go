@@ -0,0 +1,199 @@
+// Package render provides HTML template rendering.
+package render
+
+import (
+	"bytes"
+	"embed"
+	"errors"
+	"fmt"
+	"html/template"
+	"io/fs"
+	"net/http"
+	"os"
+	"path"
+	"strings"
+
+	"go.uber.org/fx"
+)
+
+var (
+	// ErrTemplateNotFound returned when the page template is not found.
+	ErrTemplateNotFound = errors.New("template not found")
+
+	// ErrLayoutNotFound returned when the layout template is not found.
+	ErrLayoutNotFound = errors.New("layout not found")
+)
+
+//go:embed templates
+var embeddedTemplates embed.FS
+
+const (
+	// defaultLayout is default layout of pages.
+	defaultLayout = "base"
+
+	// layoutsDir is the directory of layout templates.
+	layoutsDir = "layouts"
+
+	// pagesDir is the directory of page templates.
+	pagesDir = "pages"
+
+	// templateExt is the extension of template files.
+	templateExt = ".html"
+)
+
+// Render provides HTML template rendering.
+type Render struct {
+	// config provides render configuration.
+	config *Config
+
+	// pages provides parsed page templates by name.
+	pages map[string]*template.Template
+
+	// injectors provides per-request data injectors.
+	injectors []Injector
+}
+
+// Config represents configuration for render.
+type Config struct {
+	// Dir is directory of templates, embedded templates are used if empty.
+	Dir *string `json:"dir"`
+
+	// Layout is layout used to render pages.
+	Layout *string `json:"layout"`
+}
+
+// SetDefault sets default values.
+func (c *Config) SetDefault() {
+	if c.Dir == nil {
+		dir := ""
+		c.Dir = &dir
+	}
+
+	if c.Layout == nil {
+		layout := defaultLayout
+		c.Layout = &layout
+	}
+}
+
+// PageData represents data passed to templates.
+type PageData struct {
+	// Data is page specific data passed by the handler.
+	Data interface{}
+
+	// User is the authenticated user of the request, empty if anonymous.
+	User string
+
+	// CSRFToken is the CSRF token of the request.
+	CSRFToken string
+
+	// RequestID is the ID of the request.
+	RequestID string
+}
+
+// Injector injects per-request data into page data.
+type Injector func(request *http.Request, page *PageData)
+
+// NewModule provides module for render.
+func NewModule() fx.Option {
+	return fx.Module("render",
+		fx.Provide(New),
+	)
+}
+
+// New creates a new render instance.
+func New(config *Config) (*Render, error) {
+	if config == nil {
+		config = &Config{}
+	}
+
+	config.SetDefault()
+
+	templates, err := templateFS(*config.Dir)
+	if err != nil {
+		return nil, err
+	}
+
+	pages, err := parsePages(templates, *config.Layout)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Render{
+		config: config,
+		pages:  pages,
+	}, nil
+}
+
+// Use adds injectors applied to every rendered page.
+func (r *Render) Use(injectors ...Injector) {
+	r.injectors = append(r.injectors, injectors...)
+}
+
+// HTML renders the page with the layout and writes it with the status code.
+func (r *Render) HTML(writer http.ResponseWriter, request *http.Request, code int, name string, data interface{}) error {
+	page, ok := r.pages[name]
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrTemplateNotFound, name)
+	}
+
+	pageData := &PageData{Data: data}
+	for _, inject := range r.injectors {
+		inject(request, pageData)
+	}
+
+	// render into buffer so that template errors don't produce partial responses
+	var buf bytes.Buffer
+	if err := page.ExecuteTemplate(&buf, *r.config.Layout+templateExt, pageData); err != nil {
+		return fmt.Errorf("failed to render template %s: %w", name, err)
+	}
+
+	writer.Header().Set("Content-Type", "text/html; charset=utf-8")
+	writer.WriteHeader(code)
+
+	if _, err := buf.WriteTo(writer); err != nil {
+		return fmt.Errorf("failed to write template %s: %w", name, err)
+	}
+
+	return nil
+}
+
+// templateFS returns the filesystem of templates.
+func templateFS(dir string) (fs.FS, error) {
+	if dir != "" {
+		return os.DirFS(dir), nil
+	}
+
+	templates, err := fs.Sub(embeddedTemplates, "templates")
+	if err != nil {
+		return nil, fmt.Errorf("failed to open embedded templates: %w", err)
+	}
+
+	return templates, nil
+}
+
+// parsePages parses every page template together with the layouts.
+func parsePages(templates fs.FS, layout string) (map[string]*template.Template, error) {
+	if _, err := fs.Stat(templates, path.Join(layoutsDir, layout+templateExt)); err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrLayoutNotFound, layout)
+	}
+
+	files, err := fs.Glob(templates, path.Join(pagesDir, "*"+templateExt))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list page templates: %w", err)
+	}
+
+	pages := make(map[string]*template.Template, len(files))
+
+	for _, file := range files {
+		name := strings.TrimSuffix(path.Base(file), templateExt)
+
+		page, err := template.New(name).ParseFS(templates, path.Join(layoutsDir, "*"+templateExt), file)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse template %s: %w", name, err)
+		}
+
+		pages[name] = page
+	}
+
+	return pages, nil
+}
@@ -0,0 +1,118 @@
+package render
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConfig(t *testing.T) {
+	t.Parallel()
+
+	t.Run("set default values on render config", func(t *testing.T) {
+		t.Parallel()
+
+		config := &Config{}
+		config.SetDefault()
+
+		require.NotNil(t, config.Dir)
+		assert.Empty(t, *config.Dir)
+		require.NotNil(t, config.Layout)
+		assert.Equal(t, defaultLayout, *config.Layout)
+	})
+}
+
+func TestNew(t *testing.T) {
+	t.Parallel()
+
+	t.Run("parse embedded templates", func(t *testing.T) {
+		t.Parallel()
+
+		renderer, err := New(nil)
+		require.NoError(t, err)
+		assert.Contains(t, renderer.pages, "home")
+		assert.Contains(t, renderer.pages, "error")
+	})
+
+	t.Run("return error for unknown layout", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := New(&Config{Layout: &[]string{"unknown"}[0]})
+		require.ErrorIs(t, err, ErrLayoutNotFound)
+	})
+
+	t.Run("parse templates from directory", func(t *testing.T) {
+		t.Parallel()
+
+		dir := t.TempDir()
+		require.NoError(t, os.MkdirAll(filepath.Join(dir, layoutsDir), 0o750))
+		require.NoError(t, os.MkdirAll(filepath.Join(dir, pagesDir), 0o750))
+		require.NoError(t, os.WriteFile(
+			filepath.Join(dir, layoutsDir, "base.html"),
+			[]byte(`<body>{{ template "content" . }}</body>`),
+			0o600,
+		))
+		require.NoError(t, os.WriteFile(
+			filepath.Join(dir, pagesDir, "custom.html"),
+			[]byte(`{{ define "content" }}custom {{ .Data }}{{ end }}`),
+			0o600,
+		))
+
+		renderer, err := New(&Config{Dir: &dir})
+		require.NoError(t, err)
+
+		recorder := httptest.NewRecorder()
+		err = renderer.HTML(recorder, httptest.NewRequest(http.MethodGet, "/", nil), http.StatusOK, "custom", "page")
+		require.NoError(t, err)
+		assert.Equal(t, "<body>custom page</body>", recorder.Body.String())
+	})
+}
+
+func TestHTML(t *testing.T) {
+	t.Parallel()
+
+	t.Run("render page with layout and injected data", func(t *testing.T) {
+		t.Parallel()
+
+		renderer, err := New(nil)
+		require.NoError(t, err)
+
+		renderer.Use(func(_ *http.Request, page *PageData) {
+			page.User = "user@example.com"
+			page.CSRFToken = "csrf-token"
+		})
+
+		recorder := httptest.NewRecorder()
+		data := map[string]string{"Heading": "hello", "Message": "<script>alert(1)</script>"}
+
+		err = renderer.HTML(recorder, httptest.NewRequest(http.MethodGet, "/", nil), http.StatusOK, "home", data)
+		require.NoError(t, err)
+
+		body := recorder.Body.String()
+		assert.Equal(t, http.StatusOK, recorder.Code)
+		assert.Equal(t, "text/html; charset=utf-8", recorder.Header().Get("Content-Type"))
+		assert.Contains(t, body, "<h1>hello</h1>")
+		assert.Contains(t, body, "signed in as user@example.com")
+		assert.Contains(t, body, `<meta name="csrf-token" content="csrf-token">`)
+		assert.Contains(t, body, "&lt;script&gt;")
+		assert.NotContains(t, body, "<script>")
+	})
+
+	t.Run("return error for unknown template", func(t *testing.T) {
+		t.Parallel()
+
+		renderer, err := New(nil)
+		require.NoError(t, err)
+
+		recorder := httptest.NewRecorder()
+
+		err = renderer.HTML(recorder, httptest.NewRequest(http.MethodGet, "/", nil), http.StatusOK, "unknown", nil)
+		require.ErrorIs(t, err, ErrTemplateNotFound)
+		assert.Empty(t, recorder.Body.String())
+	})
+}
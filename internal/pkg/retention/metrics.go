@@ -0,0 +1,105 @@
+package retention
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	// resultSuccess is result label of successful runs.
+	resultSuccess = "success"
+
+	// resultFailure is result label of failed runs.
+	resultFailure = "failure"
+)
+
+// metrics holds prometheus collectors of retention rules.
+type metrics struct {
+	// runsTotal is number of runs by rule and result.
+	runsTotal *prometheus.CounterVec
+
+	// deletedTotal is number of deleted rows by rule.
+	deletedTotal *prometheus.CounterVec
+
+	// expiredRows is number of expired rows counted by the last dry run by rule.
+	expiredRows *prometheus.GaugeVec
+
+	// duration is duration of runs by rule.
+	duration *prometheus.HistogramVec
+}
+
+// newMetrics creates collectors of retention rules.
+func newMetrics() *metrics {
+	return &metrics{
+		runsTotal: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "retention_runs_total",
+				Help: "Total number of runs of retention rules",
+			},
+			[]string{"rule", "result"},
+		),
+		deletedTotal: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "retention_rows_deleted_total",
+				Help: "Total number of rows deleted by retention rules",
+			},
+			[]string{"rule"},
+		),
+		expiredRows: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "retention_rows_expired",
+				Help: "Number of expired rows counted by the last dry run of retention rules",
+			},
+			[]string{"rule"},
+		),
+		duration: prometheus.NewHistogramVec(
+			prometheus.HistogramOpts{
+				Name:    "retention_run_duration_seconds",
+				Help:    "Duration of runs of retention rules in seconds",
+				Buckets: prometheus.ExponentialBuckets(0.01, 4, 8),
+			},
+			[]string{"rule"},
+		),
+	}
+}
+
+// collectors returns all collectors of the metrics.
+func (m *metrics) collectors() []prometheus.Collector {
+	return []prometheus.Collector{m.runsTotal, m.deletedTotal, m.expiredRows, m.duration}
+}
+
+// observe records the run of a rule, rows deleted before a failure are counted as well.
+func (m *metrics) observe(result *Result, err error, duration time.Duration) {
+	status := resultSuccess
+	if err != nil {
+		status = resultFailure
+	}
+
+	m.runsTotal.WithLabelValues(result.Rule, status).Inc()
+	m.duration.WithLabelValues(result.Rule).Observe(duration.Seconds())
+
+	if result.DryRun {
+		if err == nil {
+			m.expiredRows.WithLabelValues(result.Rule).Set(float64(result.Rows))
+		}
+
+		return
+	}
+
+	m.deletedTotal.WithLabelValues(result.Rule).Add(float64(result.Rows))
+}
+
+// Describe implements prometheus.Collector.
+func (r *Retention) Describe(ch chan<- *prometheus.Desc) {
+	for _, collector := range r.metrics.collectors() {
+		collector.Describe(ch)
+	}
+}
+
+// Collect implements prometheus.Collector.
+func (r *Retention) Collect(ch chan<- prometheus.Metric) {
+	for _, collector := range r.metrics.collectors() {
+		collector.Collect(ch)
+	}
+}
@@ -0,0 +1,54 @@
+package retention
+
+import (
+	"context"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRetentionMetrics(t *testing.T) {
+	t.Parallel()
+
+	t.Run("count deleted rows and runs", func(t *testing.T) {
+		t.Parallel()
+
+		database := &fakeDatabase{expired: map[string]int64{`"public"."users"`: 3}, failOn: `"sessions"`}
+		retention := setupTestRetention(t, &Config{Rules: testRules()}, database, nil)
+
+		_, err := retention.Run(context.Background())
+		require.Error(t, err)
+
+		metrics := retention.metrics
+		assert.InDelta(t, 3, testutil.ToFloat64(metrics.deletedTotal.WithLabelValues("public.users")), 0)
+		assert.InDelta(t, 1, testutil.ToFloat64(metrics.runsTotal.WithLabelValues("public.users", resultSuccess)), 0)
+		assert.InDelta(t, 1,
+			testutil.ToFloat64(metrics.runsTotal.WithLabelValues("expired_sessions", resultFailure)), 0)
+	})
+
+	t.Run("report expired rows of dry runs", func(t *testing.T) {
+		t.Parallel()
+
+		database := &fakeDatabase{expired: map[string]int64{`"sessions"`: 25}}
+		retention := setupTestRetention(t, &Config{DryRun: &[]bool{true}[0], Rules: testRules()[:1]}, database, nil)
+
+		_, err := retention.Run(context.Background())
+		require.NoError(t, err)
+
+		metrics := retention.metrics
+		assert.InDelta(t, 25, testutil.ToFloat64(metrics.expiredRows.WithLabelValues("expired_sessions")), 0)
+		assert.Zero(t, testutil.CollectAndCount(metrics.deletedTotal))
+	})
+
+	t.Run("register retention as collector", func(t *testing.T) {
+		t.Parallel()
+
+		retention := setupTestRetention(t, nil, &fakeDatabase{}, nil)
+
+		registry := prometheus.NewRegistry()
+		require.NoError(t, registry.Register(retention))
+	})
+}
@@ -0,0 +1,372 @@
+// Package retention provides data retention, rows older than the TTL of declarative rules are deleted in batches
+// by a periodic job, so that expired sessions, old audit rows and soft-deleted records do not grow unbounded.
+package retention
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"go.uber.org/fx"
+
+	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/database"
+	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/logger"
+	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/readonly"
+)
+
+const (
+	// defaultInterval is default interval of running the rules.
+	defaultInterval = time.Hour
+
+	// defaultBatchSize is default number of rows deleted per statement.
+	defaultBatchSize = 1000
+
+	// defaultMaxBatches is default maximum number of batches deleted per rule and run.
+	defaultMaxBatches = 100
+)
+
+// ErrInvalidRule is returned when a rule has an invalid table, age column or TTL.
+var ErrInvalidRule = errors.New("invalid retention rule")
+
+// identifierPattern matches table and column names, optionally qualified by a schema.
+var identifierPattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*(\.[A-Za-z_][A-Za-z0-9_]*)?$`)
+
+// Config represents configuration for data retention.
+type Config struct {
+	// Enabled is whether rules are run periodically.
+	Enabled *bool `json:"enabled"`
+
+	// Interval is interval of running the rules.
+	Interval *time.Duration `json:"interval"`
+
+	// BatchSize is number of rows deleted per statement, it bounds locks held and WAL written at once.
+	BatchSize *int `json:"batch_size"`
+
+	// MaxBatches is maximum number of batches deleted per rule and run, remaining rows are deleted on the next run.
+	MaxBatches *int `json:"max_batches"`
+
+	// DryRun is whether expired rows are counted instead of deleted, to check rules before enabling them.
+	DryRun *bool `json:"dry_run"`
+
+	// Rules is rules of tables to delete expired rows from.
+	Rules []Rule `json:"rules"`
+}
+
+// SetDefault sets default values.
+func (c *Config) SetDefault() {
+	if c.Enabled == nil {
+		c.Enabled = &[]bool{false}[0]
+	}
+
+	if c.Interval == nil {
+		c.Interval = &[]time.Duration{defaultInterval}[0]
+	}
+
+	if c.BatchSize == nil {
+		c.BatchSize = &[]int{defaultBatchSize}[0]
+	}
+
+	if c.MaxBatches == nil {
+		c.MaxBatches = &[]int{defaultMaxBatches}[0]
+	}
+
+	if c.DryRun == nil {
+		c.DryRun = &[]bool{false}[0]
+	}
+}
+
+// Rule represents a retention rule, rows of the table whose age column is older than the TTL are deleted.
+// Rows with a null age column are kept, so a soft-delete column such as deleted_at only expires deleted rows.
+type Rule struct {
+	// Name is name of the rule in logs and metrics, the table if empty.
+	Name string `json:"name"`
+
+	// Table is the table, optionally qualified by a schema.
+	Table string `json:"table"`
+
+	// AgeColumn is the timestamp column rows expire by, e.g. created_at, expires_at or deleted_at.
+	AgeColumn string `json:"age_column"`
+
+	// TTL is duration rows are kept after their age column.
+	TTL time.Duration `json:"ttl"`
+}
+
+// validate returns an error if the rule has an invalid table, age column or TTL.
+func (r *Rule) validate() error {
+	if !identifierPattern.MatchString(r.Table) {
+		return fmt.Errorf("%w: table %q", ErrInvalidRule, r.Table)
+	}
+
+	if !identifierPattern.MatchString(r.AgeColumn) || strings.Contains(r.AgeColumn, ".") {
+		return fmt.Errorf("%w: age column %q of table %s", ErrInvalidRule, r.AgeColumn, r.Table)
+	}
+
+	if r.TTL <= 0 {
+		return fmt.Errorf("%w: ttl of table %s must be positive", ErrInvalidRule, r.Table)
+	}
+
+	return nil
+}
+
+// name returns name of the rule, the table if not set.
+func (r *Rule) name() string {
+	if r.Name != "" {
+		return r.Name
+	}
+
+	return r.Table
+}
+
+// table returns the quoted table.
+func (r *Rule) table() string {
+	return pgx.Identifier(strings.Split(r.Table, ".")).Sanitize()
+}
+
+// column returns the quoted age column.
+func (r *Rule) column() string {
+	return pgx.Identifier{r.AgeColumn}.Sanitize()
+}
+
+// Result represents the result of running a rule.
+type Result struct {
+	// Rule is name of the rule.
+	Rule string `json:"rule"`
+
+	// Cutoff is time rows older than were expired.
+	Cutoff time.Time `json:"cutoff"`
+
+	// Rows is number of deleted rows, or of expired rows in dry-run mode.
+	Rows int64 `json:"rows"`
+
+	// DryRun is whether rows were counted instead of deleted.
+	DryRun bool `json:"dry_run"`
+}
+
+// Retention runs retention rules.
+type Retention struct {
+	// config provides retention configuration.
+	config *Config
+
+	// db provides database connection.
+	db *sql.DB
+
+	// readOnly provides read-only mode, rules are not run while it is on.
+	readOnly *readonly.ReadOnly
+
+	// logger provides logger.
+	logger *logger.Logger
+
+	// metrics records runs of rules.
+	metrics *metrics
+
+	// runMu serializes runs.
+	runMu sync.Mutex
+
+	// cancel stops the run loop, canceling a running run.
+	cancel context.CancelFunc
+
+	// done is closed when the run loop exits.
+	done chan struct{}
+
+	// now returns the current time, replaced in tests.
+	now func() time.Time
+}
+
+// NewModule provides module for data retention.
+func NewModule() fx.Option {
+	return fx.Module("retention",
+		fx.Provide(New),
+	)
+}
+
+// New creates a new retention deleting rows from database.
+func New(
+	config *Config,
+	dbConn *database.DB,
+	readOnly *readonly.ReadOnly,
+	logger *logger.Logger,
+) (*Retention, error) {
+	return NewWithDB(config, dbConn.DB, readOnly, logger)
+}
+
+// NewWithDB creates a new retention deleting rows using the database connection.
+func NewWithDB(config *Config, db *sql.DB, readOnly *readonly.ReadOnly, logger *logger.Logger) (*Retention, error) {
+	if config == nil {
+		config = &Config{}
+	}
+
+	config.SetDefault()
+
+	for i := range config.Rules {
+		if err := config.Rules[i].validate(); err != nil {
+			return nil, err
+		}
+	}
+
+	return &Retention{
+		config:   config,
+		db:       db,
+		readOnly: readOnly,
+		logger:   logger.Named("retention"),
+		metrics:  newMetrics(),
+		now:      time.Now,
+	}, nil
+}
+
+// Enabled returns whether rules are run periodically.
+func (r *Retention) Enabled() bool {
+	return r != nil && *r.config.Enabled
+}
+
+// Run runs all rules once, a failing rule does not stop the others. Rules are not run while read-only mode is on.
+func (r *Retention) Run(ctx context.Context) ([]*Result, error) {
+	r.runMu.Lock()
+	defer r.runMu.Unlock()
+
+	if r.readOnly != nil && r.readOnly.Enabled(ctx) {
+		return nil, nil
+	}
+
+	results := make([]*Result, 0, len(r.config.Rules))
+
+	var runErr error
+
+	for i := range r.config.Rules {
+		result, err := r.runRule(ctx, &r.config.Rules[i])
+		if err != nil {
+			runErr = errors.Join(runErr, err)
+		}
+
+		if result != nil {
+			results = append(results, result)
+		}
+	}
+
+	return results, runErr
+}
+
+// runRule deletes rows of the rule older than its TTL in batches, or counts them in dry-run mode.
+// Rows deleted by batches before a failure are returned with the error.
+func (r *Retention) runRule(ctx context.Context, rule *Rule) (*Result, error) {
+	start := time.Now()
+	result := &Result{Rule: rule.name(), Cutoff: r.now().Add(-rule.TTL).UTC(), DryRun: *r.config.DryRun}
+
+	var err error
+	if result.DryRun {
+		result.Rows, err = r.count(ctx, rule, result.Cutoff)
+	} else {
+		result.Rows, err = r.delete(ctx, rule, result.Cutoff)
+	}
+
+	r.metrics.observe(result, err, time.Since(start))
+
+	event := r.logger.Ctx(ctx).Info()
+	if err != nil {
+		event = r.logger.Ctx(ctx).Error().Err(err)
+	}
+
+	event.Str("rule", result.Rule).Time("cutoff", result.Cutoff).Int64("rows", result.Rows).
+		Bool("dry_run", result.DryRun).Msg("retention rule run")
+
+	if err != nil {
+		return result, fmt.Errorf("failed to run retention rule %s: %w", result.Rule, err)
+	}
+
+	return result, nil
+}
+
+// count returns number of rows of the rule older than the cutoff.
+func (r *Retention) count(ctx context.Context, rule *Rule, cutoff time.Time) (int64, error) {
+	// #nosec G201 -- identifiers are validated and quoted
+	query := fmt.Sprintf("SELECT count(*) FROM %s WHERE %s < $1", rule.table(), rule.column())
+
+	var rows int64
+	if err := r.db.QueryRowContext(ctx, query, cutoff).Scan(&rows); err != nil {
+		return 0, fmt.Errorf("failed to count expired rows: %w", err)
+	}
+
+	return rows, nil
+}
+
+// delete deletes rows of the rule older than the cutoff in batches, until a batch is not full
+// or the maximum number of batches is reached.
+func (r *Retention) delete(ctx context.Context, rule *Rule, cutoff time.Time) (int64, error) {
+	// rows are selected by ctid, since tables are not required to have a primary key
+	// #nosec G201 -- identifiers are validated and quoted
+	query := fmt.Sprintf(
+		"DELETE FROM %[1]s WHERE ctid = ANY(ARRAY(SELECT ctid FROM %[1]s WHERE %[2]s < $1 LIMIT $2))",
+		rule.table(), rule.column(),
+	)
+
+	var deleted int64
+
+	for range *r.config.MaxBatches {
+		if err := ctx.Err(); err != nil {
+			return deleted, fmt.Errorf("failed to delete expired rows: %w", err)
+		}
+
+		result, err := r.db.ExecContext(ctx, query, cutoff, *r.config.BatchSize)
+		if err != nil {
+			return deleted, fmt.Errorf("failed to delete expired rows: %w", err)
+		}
+
+		rows, err := result.RowsAffected()
+		if err != nil {
+			return deleted, fmt.Errorf("failed to get deleted rows: %w", err)
+		}
+
+		deleted += rows
+
+		if rows < int64(*r.config.BatchSize) {
+			break
+		}
+	}
+
+	return deleted, nil
+}
+
+// Start starts running the rules every interval, it does nothing if retention is disabled.
+func (r *Retention) Start() {
+	if !r.Enabled() || r.cancel != nil {
+		return
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	r.cancel, r.done = cancel, make(chan struct{})
+
+	go func() {
+		defer close(r.done)
+
+		ticker := time.NewTicker(*r.config.Interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				// failures are logged per rule
+				_, _ = r.Run(ctx)
+			}
+		}
+	}()
+}
+
+// Stop stops running the rules, canceling a running run between batches, remaining rows are deleted
+// on the next start.
+func (r *Retention) Stop() {
+	if r.cancel == nil {
+		return
+	}
+
+	r.cancel()
+	<-r.done
+
+	r.cancel = nil
+}
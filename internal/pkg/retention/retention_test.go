@@ -0,0 +1,367 @@
+package retention
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/logger"
+	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/readonly"
+)
+
+var (
+	errNotSupported    = errors.New("not supported")
+	errStatementFailed = errors.New("statement failed")
+)
+
+// fakeDatabase is a fake database of expired rows by quoted table.
+type fakeDatabase struct {
+	// mu guards the fields below.
+	mu sync.Mutex
+
+	// expired is number of expired rows by quoted table.
+	expired map[string]int64
+
+	// failOn fails statements of the quoted table.
+	failOn string
+
+	// statements is statements run on the database.
+	statements []string
+
+	// cutoffs is cutoffs of the statements.
+	cutoffs []time.Time
+}
+
+// open opens the fake database.
+func (d *fakeDatabase) open(t *testing.T) *sql.DB {
+	t.Helper()
+
+	db := sql.OpenDB(&fakeConnector{database: d})
+	t.Cleanup(func() { _ = db.Close() })
+
+	return db
+}
+
+// run records the statement and returns the quoted table it runs on.
+func (d *fakeDatabase) run(query string, args []driver.NamedValue) (string, error) {
+	d.statements = append(d.statements, query)
+
+	cutoff, _ := args[0].Value.(time.Time)
+	d.cutoffs = append(d.cutoffs, cutoff)
+
+	table := strings.Fields(strings.SplitN(query, "FROM ", 2)[1])[0]
+	if table == d.failOn {
+		return "", errStatementFailed
+	}
+
+	return table, nil
+}
+
+type fakeConnector struct {
+	database *fakeDatabase
+}
+
+func (c *fakeConnector) Connect(context.Context) (driver.Conn, error) {
+	return &fakeConn{database: c.database}, nil
+}
+
+func (c *fakeConnector) Driver() driver.Driver {
+	return nil
+}
+
+type fakeConn struct {
+	database *fakeDatabase
+}
+
+func (c *fakeConn) Prepare(string) (driver.Stmt, error) {
+	return nil, errNotSupported
+}
+
+func (c *fakeConn) Close() error {
+	return nil
+}
+
+func (c *fakeConn) Begin() (driver.Tx, error) {
+	return nil, errNotSupported
+}
+
+func (c *fakeConn) ExecContext(_ context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	d := c.database
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	table, err := d.run(query, args)
+	if err != nil {
+		return nil, err
+	}
+
+	limit, _ := args[1].Value.(int64)
+	deleted := min(d.expired[table], limit)
+	d.expired[table] -= deleted
+
+	return driver.RowsAffected(deleted), nil
+}
+
+func (c *fakeConn) QueryContext(_ context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	d := c.database
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	table, err := d.run(query, args)
+	if err != nil {
+		return nil, err
+	}
+
+	return &fakeRows{values: []driver.Value{d.expired[table]}}, nil
+}
+
+type fakeRows struct {
+	values []driver.Value
+}
+
+func (r *fakeRows) Columns() []string {
+	return []string{"count"}
+}
+
+func (r *fakeRows) Close() error {
+	return nil
+}
+
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if r.values == nil {
+		return io.EOF
+	}
+
+	copy(dest, r.values)
+	r.values = nil
+
+	return nil
+}
+
+// setupTestRetention creates a retention running the rules on the fake database.
+func setupTestRetention(t *testing.T, config *Config, database *fakeDatabase, readOnly *readonly.ReadOnly) *Retention {
+	t.Helper()
+
+	log, err := logger.New(&logger.Config{})
+	require.NoError(t, err)
+
+	if config == nil {
+		config = &Config{}
+	}
+
+	retention, err := NewWithDB(config, database.open(t), readOnly, log)
+	require.NoError(t, err)
+
+	return retention
+}
+
+// testRules returns rules of sessions and soft-deleted users.
+func testRules() []Rule {
+	return []Rule{
+		{Name: "expired_sessions", Table: "sessions", AgeColumn: "expires_at", TTL: time.Hour},
+		{Table: "public.users", AgeColumn: "deleted_at", TTL: 30 * 24 * time.Hour},
+	}
+}
+
+func TestConfigSetDefault(t *testing.T) {
+	t.Parallel()
+
+	config := &Config{}
+	config.SetDefault()
+
+	assert.False(t, *config.Enabled)
+	assert.Equal(t, time.Hour, *config.Interval)
+	assert.Equal(t, defaultBatchSize, *config.BatchSize)
+	assert.Equal(t, defaultMaxBatches, *config.MaxBatches)
+	assert.False(t, *config.DryRun)
+	assert.Empty(t, config.Rules)
+}
+
+func TestNewModule(t *testing.T) {
+	t.Parallel()
+
+	t.Run("return fx.Option", func(t *testing.T) {
+		t.Parallel()
+
+		require.NotNil(t, NewModule())
+	})
+}
+
+func TestNewWithDB(t *testing.T) {
+	t.Parallel()
+
+	log, err := logger.New(&logger.Config{})
+	require.NoError(t, err)
+
+	t.Run("reject invalid rules", func(t *testing.T) {
+		t.Parallel()
+
+		rules := []Rule{
+			{Table: "sessions; DROP TABLE users", AgeColumn: "expires_at", TTL: time.Hour},
+			{Table: "sessions", AgeColumn: "sessions.expires_at", TTL: time.Hour},
+			{Table: "sessions", AgeColumn: "", TTL: time.Hour},
+			{Table: "sessions", AgeColumn: "expires_at"},
+		}
+
+		for _, rule := range rules {
+			_, err := NewWithDB(&Config{Rules: []Rule{rule}}, nil, nil, log)
+			require.ErrorIs(t, err, ErrInvalidRule, rule.Table)
+		}
+	})
+
+	t.Run("create disabled retention", func(t *testing.T) {
+		t.Parallel()
+
+		retention, err := NewWithDB(nil, nil, nil, log)
+		require.NoError(t, err)
+		assert.False(t, retention.Enabled())
+	})
+}
+
+func TestRun(t *testing.T) {
+	t.Parallel()
+
+	t.Run("delete expired rows in batches", func(t *testing.T) {
+		t.Parallel()
+
+		database := &fakeDatabase{expired: map[string]int64{`"sessions"`: 25, `"public"."users"`: 3}}
+		retention := setupTestRetention(t, &Config{
+			BatchSize: &[]int{10}[0],
+			Rules:     testRules(),
+		}, database, nil)
+
+		now := time.Date(2025, 1, 31, 12, 0, 0, 0, time.UTC)
+		retention.now = func() time.Time { return now }
+
+		results, err := retention.Run(context.Background())
+		require.NoError(t, err)
+		assert.Equal(t, []*Result{
+			{Rule: "expired_sessions", Cutoff: now.Add(-time.Hour), Rows: 25},
+			{Rule: "public.users", Cutoff: now.Add(-30 * 24 * time.Hour), Rows: 3},
+		}, results)
+
+		// the last batch of sessions is not full, so no further batch is run
+		require.Len(t, database.statements, 4)
+		assert.Equal(t,
+			`DELETE FROM "sessions" WHERE ctid = ANY(ARRAY(SELECT ctid FROM "sessions" WHERE "expires_at" < $1 LIMIT $2))`,
+			database.statements[0],
+		)
+		assert.Equal(t, now.Add(-time.Hour), database.cutoffs[0])
+		assert.Zero(t, database.expired[`"sessions"`])
+	})
+
+	t.Run("stop after maximum batches", func(t *testing.T) {
+		t.Parallel()
+
+		database := &fakeDatabase{expired: map[string]int64{`"sessions"`: 100}}
+		retention := setupTestRetention(t, &Config{
+			BatchSize:  &[]int{10}[0],
+			MaxBatches: &[]int{3}[0],
+			Rules:      testRules()[:1],
+		}, database, nil)
+
+		results, err := retention.Run(context.Background())
+		require.NoError(t, err)
+		require.Len(t, results, 1)
+		assert.Equal(t, int64(30), results[0].Rows)
+		assert.Equal(t, int64(70), database.expired[`"sessions"`])
+	})
+
+	t.Run("count expired rows in dry-run mode", func(t *testing.T) {
+		t.Parallel()
+
+		database := &fakeDatabase{expired: map[string]int64{`"sessions"`: 25}}
+		retention := setupTestRetention(t, &Config{
+			DryRun: &[]bool{true}[0],
+			Rules:  testRules()[:1],
+		}, database, nil)
+
+		results, err := retention.Run(context.Background())
+		require.NoError(t, err)
+		require.Len(t, results, 1)
+		assert.True(t, results[0].DryRun)
+		assert.Equal(t, int64(25), results[0].Rows)
+
+		assert.Equal(t, []string{`SELECT count(*) FROM "sessions" WHERE "expires_at" < $1`}, database.statements)
+		assert.Equal(t, int64(25), database.expired[`"sessions"`])
+	})
+
+	t.Run("run other rules after a failure", func(t *testing.T) {
+		t.Parallel()
+
+		database := &fakeDatabase{
+			expired: map[string]int64{`"public"."users"`: 3},
+			failOn:  `"sessions"`,
+		}
+		retention := setupTestRetention(t, &Config{Rules: testRules()}, database, nil)
+
+		results, err := retention.Run(context.Background())
+		require.ErrorIs(t, err, errStatementFailed)
+		require.Len(t, results, 2)
+		assert.Equal(t, int64(3), results[1].Rows)
+	})
+
+	t.Run("skip rules while read-only", func(t *testing.T) {
+		t.Parallel()
+
+		readOnly := readonly.New(&readonly.Config{}, nil)
+		require.NoError(t, readOnly.Set(context.Background(), true))
+
+		database := &fakeDatabase{expired: map[string]int64{`"sessions"`: 25}}
+		retention := setupTestRetention(t, &Config{Rules: testRules()}, database, readOnly)
+
+		results, err := retention.Run(context.Background())
+		require.NoError(t, err)
+		assert.Empty(t, results)
+		assert.Empty(t, database.statements)
+	})
+}
+
+func TestStartStop(t *testing.T) {
+	t.Parallel()
+
+	t.Run("run rules every interval", func(t *testing.T) {
+		t.Parallel()
+
+		database := &fakeDatabase{expired: map[string]int64{`"sessions"`: 5}}
+		retention := setupTestRetention(t, &Config{
+			Enabled:  &[]bool{true}[0],
+			Interval: &[]time.Duration{10 * time.Millisecond}[0],
+			Rules:    testRules()[:1],
+		}, database, nil)
+
+		retention.Start()
+
+		assert.Eventually(t, func() bool {
+			database.mu.Lock()
+			defer database.mu.Unlock()
+
+			return database.expired[`"sessions"`] == 0
+		}, time.Second, 10*time.Millisecond)
+
+		retention.Stop()
+		retention.Stop()
+	})
+
+	t.Run("not start when disabled", func(t *testing.T) {
+		t.Parallel()
+
+		retention := setupTestRetention(t, nil, &fakeDatabase{}, nil)
+
+		retention.Start()
+		assert.Nil(t, retention.cancel)
+
+		retention.Stop()
+	})
+}
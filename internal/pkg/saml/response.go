@@ -0,0 +1,370 @@
+package saml
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/beevik/etree"
+	goredis "github.com/redis/go-redis/v9"
+)
+
+const (
+	// statusSuccess is the status code of successful responses.
+	statusSuccess = "urn:oasis:names:tc:SAML:2.0:status:Success"
+
+	// confirmationBearer is the subject confirmation method of the web browser SSO profile.
+	confirmationBearer = "urn:oasis:names:tc:SAML:2.0:cm:bearer"
+
+	// maxResponseSize is maximum size of encoded responses in bytes.
+	maxResponseSize = 1 << 20
+)
+
+// assertion represents the validated assertion of a response.
+type assertion struct {
+	// nameID is name ID of the subject.
+	nameID string
+
+	// attributes is attribute values by name.
+	attributes map[string][]string
+}
+
+// first returns the first value of the attribute, empty if it is missing.
+func (a *assertion) first(name string) string {
+	if values := a.attributes[name]; len(values) > 0 {
+		return values[0]
+	}
+
+	return ""
+}
+
+// validateResponse validates the encoded response of the identity provider according to the web browser SSO
+// profile and returns its assertion. Responses must answer a pending request of the tenant unless unsolicited
+// responses are allowed, and each assertion is accepted once.
+func (s *ServiceProvider) validateResponse(
+	ctx context.Context,
+	tenant string,
+	provider *provider,
+	encodedResponse string,
+) (*assertion, error) {
+	if len(encodedResponse) > maxResponseSize {
+		return nil, fmt.Errorf("%w: response too large", ErrInvalidResponse)
+	}
+
+	decoded, err := base64.StdEncoding.DecodeString(stripSpaces(encodedResponse))
+	if err != nil {
+		return nil, fmt.Errorf("%w: malformed encoding: %w", ErrInvalidResponse, err)
+	}
+
+	response, err := parseXML(decoded)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidResponse, err)
+	}
+
+	response, signedAssertion, err := verifyResponseSignatures(provider, response)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidResponse, err)
+	}
+
+	expiresAt, err := s.checkResponse(tenant, provider, response, signedAssertion)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidResponse, err)
+	}
+
+	if err := s.consumeRequest(ctx, tenant, provider, attr(response, "InResponseTo")); err != nil {
+		return nil, err
+	}
+
+	// the assertion is remembered until it expires, after which it is rejected anyway
+	ttl := expiresAt.Sub(s.now()) + *s.config.ClockSkew
+
+	consumed, err := s.redis.SetNX(ctx, assertionKeyPrefix+tenant+":"+attr(signedAssertion, "ID"), "1", ttl).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to store consumed assertion: %w", err)
+	}
+
+	if !consumed {
+		return nil, fmt.Errorf("%w: assertion already consumed", ErrInvalidResponse)
+	}
+
+	nameID := child(child(signedAssertion, namespaceAssertion, "Subject"), namespaceAssertion, "NameID")
+
+	return &assertion{
+		nameID:     strings.TrimSpace(text(nameID)),
+		attributes: attributes(signedAssertion),
+	}, nil
+}
+
+// verifyResponseSignatures verifies signatures of the response and its assertion, of which at least one must be
+// signed, and returns them as signed, a signed response covers its assertion.
+func verifyResponseSignatures(provider *provider, response *etree.Element) (*etree.Element, *etree.Element, error) {
+	if !is(response, namespaceProtocol, "Response") {
+		return nil, nil, errors.New("not a saml 2.0 response")
+	}
+
+	signed := false
+
+	verified, err := verifySignature(response, provider.certificates)
+
+	switch {
+	case err == nil:
+		response, signed = verified, true
+	case !errors.Is(err, ErrMissingSignature):
+		return nil, nil, err
+	}
+
+	if child(response, namespaceAssertion, "EncryptedAssertion") != nil {
+		return nil, nil, errors.New("encrypted assertions are not supported")
+	}
+
+	assertions := children(response, namespaceAssertion, "Assertion")
+	if len(assertions) != 1 {
+		return nil, nil, fmt.Errorf("expected one assertion, got %d", len(assertions))
+	}
+
+	signedAssertion := assertions[0]
+
+	verified, err = verifySignature(signedAssertion, provider.certificates)
+
+	switch {
+	case err == nil:
+		signedAssertion, signed = verified, true
+	case !errors.Is(err, ErrMissingSignature):
+		return nil, nil, err
+	}
+
+	if !signed {
+		return nil, nil, ErrMissingSignature
+	}
+
+	return response, signedAssertion, nil
+}
+
+// checkResponse checks the response is successful, issued by the identity provider and addressed to the service
+// provider, and returns the time its assertion expires.
+func (s *ServiceProvider) checkResponse(
+	tenant string,
+	provider *provider,
+	response, signedAssertion *etree.Element,
+) (time.Time, error) {
+	if attr(response, "Version") != "2.0" {
+		return time.Time{}, errors.New("not a saml 2.0 response")
+	}
+
+	if destination := attr(response, "Destination"); destination != "" && destination != s.ACSURL(tenant) {
+		return time.Time{}, fmt.Errorf("unexpected destination %s", destination)
+	}
+
+	if issuer := child(response, namespaceAssertion, "Issuer"); issuer != nil &&
+		strings.TrimSpace(text(issuer)) != *provider.config.EntityID {
+		return time.Time{}, errors.New("unexpected response issuer")
+	}
+
+	statusCode := child(child(response, namespaceProtocol, "Status"), namespaceProtocol, "StatusCode")
+	if statusCode == nil || attr(statusCode, "Value") != statusSuccess {
+		return time.Time{}, errors.New("unsuccessful response")
+	}
+
+	if issuer := child(signedAssertion, namespaceAssertion, "Issuer"); issuer == nil ||
+		strings.TrimSpace(text(issuer)) != *provider.config.EntityID {
+		return time.Time{}, errors.New("unexpected assertion issuer")
+	}
+
+	expiresAt, err := s.checkSubject(tenant, signedAssertion, attr(response, "InResponseTo"))
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	if err := s.checkConditions(tenant, signedAssertion); err != nil {
+		return time.Time{}, err
+	}
+
+	return expiresAt, nil
+}
+
+// checkSubject checks the subject of the assertion has a name ID and a bearer confirmation for the assertion
+// consumer service of the tenant, and returns the time the confirmation expires.
+func (s *ServiceProvider) checkSubject(
+	tenant string,
+	signedAssertion *etree.Element,
+	inResponseTo string,
+) (time.Time, error) {
+	subject := child(signedAssertion, namespaceAssertion, "Subject")
+	if subject == nil || child(subject, namespaceAssertion, "NameID") == nil {
+		return time.Time{}, errors.New("missing subject name id")
+	}
+
+	now := s.now()
+
+	for _, confirmation := range children(subject, namespaceAssertion, "SubjectConfirmation") {
+		data := child(confirmation, namespaceAssertion, "SubjectConfirmationData")
+		if attr(confirmation, "Method") != confirmationBearer || data == nil {
+			continue
+		}
+
+		if attr(data, "Recipient") != s.ACSURL(tenant) || attr(data, "InResponseTo") != inResponseTo ||
+			attr(data, "NotBefore") != "" {
+			continue
+		}
+
+		notOnOrAfter, err := time.Parse(time.RFC3339, attr(data, "NotOnOrAfter"))
+		if err != nil || !now.Before(notOnOrAfter.Add(*s.config.ClockSkew)) {
+			continue
+		}
+
+		return notOnOrAfter, nil
+	}
+
+	return time.Time{}, errors.New("missing valid bearer subject confirmation")
+}
+
+// checkConditions checks the validity period of the assertion and that it is restricted to the service
+// provider of the tenant.
+func (s *ServiceProvider) checkConditions(tenant string, signedAssertion *etree.Element) error {
+	conditions := child(signedAssertion, namespaceAssertion, "Conditions")
+	if conditions == nil {
+		return errors.New("missing conditions")
+	}
+
+	now := s.now()
+	skew := *s.config.ClockSkew
+
+	if notBefore := attr(conditions, "NotBefore"); notBefore != "" {
+		parsed, err := time.Parse(time.RFC3339, notBefore)
+		if err != nil || now.Add(skew).Before(parsed) {
+			return errors.New("assertion not yet valid")
+		}
+	}
+
+	if notOnOrAfter := attr(conditions, "NotOnOrAfter"); notOnOrAfter != "" {
+		parsed, err := time.Parse(time.RFC3339, notOnOrAfter)
+		if err != nil || !now.Before(parsed.Add(skew)) {
+			return errors.New("assertion expired")
+		}
+	}
+
+	restrictions := children(conditions, namespaceAssertion, "AudienceRestriction")
+	if len(restrictions) == 0 {
+		return errors.New("missing audience restriction")
+	}
+
+	// all restrictions must be met
+	for _, restriction := range restrictions {
+		audiences := children(restriction, namespaceAssertion, "Audience")
+		if !slices.ContainsFunc(audiences, func(audience *etree.Element) bool {
+			return strings.TrimSpace(text(audience)) == s.EntityID(tenant)
+		}) {
+			return errors.New("service provider not in audience")
+		}
+	}
+
+	return nil
+}
+
+// consumeRequest removes the pending request the response is in response to, so that it is answered once.
+// Unsolicited responses are accepted only if the identity provider allows them.
+func (s *ServiceProvider) consumeRequest(ctx context.Context, tenant string, provider *provider, id string) error {
+	if id == "" {
+		if !*provider.config.AllowIdPInitiated {
+			return fmt.Errorf("%w: unsolicited response", ErrInvalidResponse)
+		}
+
+		return nil
+	}
+
+	err := s.redis.GetDel(ctx, requestKeyPrefix+tenant+":"+id).Err()
+
+	switch {
+	case errors.Is(err, goredis.Nil):
+		return fmt.Errorf("%w: unknown or expired request", ErrInvalidResponse)
+	case err != nil:
+		return fmt.Errorf("failed to consume authentication request: %w", err)
+	}
+
+	return nil
+}
+
+// attributes returns values of attributes of the attribute statements of the assertion by name.
+func attributes(signedAssertion *etree.Element) map[string][]string {
+	values := map[string][]string{}
+
+	for _, statement := range children(signedAssertion, namespaceAssertion, "AttributeStatement") {
+		for _, attribute := range children(statement, namespaceAssertion, "Attribute") {
+			name := attr(attribute, "Name")
+
+			for _, value := range children(attribute, namespaceAssertion, "AttributeValue") {
+				values[name] = append(values[name], strings.TrimSpace(text(value)))
+			}
+		}
+	}
+
+	return values
+}
+
+// is returns whether the element is the local name in the namespace.
+func is(e *etree.Element, namespace, local string) bool {
+	return e.Tag == local && e.NamespaceURI() == namespace
+}
+
+// child returns the first child element of the local name in the namespace, nil if there is none or the element
+// is nil, so that lookups can be chained.
+func child(e *etree.Element, namespace, local string) *etree.Element {
+	if found := children(e, namespace, local); len(found) > 0 {
+		return found[0]
+	}
+
+	return nil
+}
+
+// children returns child elements of the local name in the namespace, none if the element is nil.
+func children(e *etree.Element, namespace, local string) []*etree.Element {
+	if e == nil {
+		return nil
+	}
+
+	var found []*etree.Element
+
+	for _, element := range e.ChildElements() {
+		if is(element, namespace, local) {
+			found = append(found, element)
+		}
+	}
+
+	return found
+}
+
+// attr returns the value of the unqualified attribute, empty if it is missing.
+func attr(e *etree.Element, key string) string {
+	for _, attribute := range e.Attr {
+		if attribute.Space == "" && attribute.Key == key {
+			return attribute.Value
+		}
+	}
+
+	return ""
+}
+
+// text returns the text content of the element and its descendants, so that comments can not truncate it.
+func text(e *etree.Element) string {
+	var builder strings.Builder
+
+	for _, token := range e.Child {
+		switch token := token.(type) {
+		case *etree.CharData:
+			builder.WriteString(token.Data)
+		case *etree.Element:
+			builder.WriteString(text(token))
+		}
+	}
+
+	return builder.String()
+}
+
+// stripSpaces returns the text without whitespace, base64 values are often wrapped.
+func stripSpaces(text string) string {
+	return strings.Join(strings.Fields(text), "")
+}
@@ -0,0 +1,275 @@
+package saml
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testResponse represents a response of the test identity provider.
+type testResponse struct {
+	// document is the unsigned response.
+	document string
+
+	// responseID is ID of the response.
+	responseID string
+
+	// assertionID is ID of the assertion.
+	assertionID string
+}
+
+// newTestID returns a random ID of test responses.
+func newTestID(t *testing.T) string {
+	t.Helper()
+
+	random := make([]byte, idLength)
+	_, err := rand.Read(random)
+	require.NoError(t, err)
+
+	return "_" + hex.EncodeToString(random)
+}
+
+// newTestResponse creates a valid response of the test identity provider to the request of the ID, unsolicited
+// if empty, asserting the name ID with the attributes.
+func newTestResponse(
+	serviceProvider *ServiceProvider,
+	inResponseTo, nameID string,
+	attributeValues map[string][]string,
+) *testResponse {
+	random := make([]byte, idLength)
+	_, _ = rand.Read(random)
+	responseID := "_r" + hex.EncodeToString(random)
+	_, _ = rand.Read(random)
+	assertionID := "_a" + hex.EncodeToString(random)
+
+	now := time.Now().UTC()
+	notBefore := now.Add(-time.Minute).Format(time.RFC3339)
+	notOnOrAfter := now.Add(5 * time.Minute).Format(time.RFC3339)
+
+	inResponseToAttr := ""
+	if inResponseTo != "" {
+		inResponseToAttr = ` InResponseTo="` + inResponseTo + `"`
+	}
+
+	var statement strings.Builder
+
+	for name, values := range attributeValues {
+		statement.WriteString(`<saml:Attribute Name="` + name + `">`)
+
+		for _, value := range values {
+			statement.WriteString(`<saml:AttributeValue>` + value + `</saml:AttributeValue>`)
+		}
+
+		statement.WriteString(`</saml:Attribute>`)
+	}
+
+	document := `<samlp:Response xmlns:samlp="urn:oasis:names:tc:SAML:2.0:protocol" ` +
+		`xmlns:saml="urn:oasis:names:tc:SAML:2.0:assertion" ID="` + responseID + `" Version="2.0" ` +
+		`IssueInstant="` + now.Format(time.RFC3339) + `" Destination="` + serviceProvider.ACSURL(testTenant) + `"` +
+		inResponseToAttr + `>` +
+		`<saml:Issuer>` + testEntityID + `</saml:Issuer>` +
+		`<samlp:Status><samlp:StatusCode Value="urn:oasis:names:tc:SAML:2.0:status:Success"/></samlp:Status>` +
+		`<saml:Assertion ID="` + assertionID + `" Version="2.0" IssueInstant="` + now.Format(time.RFC3339) + `">` +
+		`<saml:Issuer>` + testEntityID + `</saml:Issuer>` +
+		`<saml:Subject><saml:NameID Format="` + nameIDFormatEmail + `">` + nameID + `</saml:NameID>` +
+		`<saml:SubjectConfirmation Method="urn:oasis:names:tc:SAML:2.0:cm:bearer">` +
+		`<saml:SubjectConfirmationData` + inResponseToAttr + ` NotOnOrAfter="` + notOnOrAfter + `" ` +
+		`Recipient="` + serviceProvider.ACSURL(testTenant) + `"/></saml:SubjectConfirmation></saml:Subject>` +
+		`<saml:Conditions NotBefore="` + notBefore + `" NotOnOrAfter="` + notOnOrAfter + `">` +
+		`<saml:AudienceRestriction><saml:Audience>` + serviceProvider.EntityID(testTenant) + `</saml:Audience>` +
+		`</saml:AudienceRestriction></saml:Conditions>` +
+		`<saml:AuthnStatement AuthnInstant="` + now.Format(time.RFC3339) + `"/>` +
+		`<saml:AttributeStatement>` + statement.String() + `</saml:AttributeStatement>` +
+		`</saml:Assertion></samlp:Response>`
+
+	return &testResponse{document: document, responseID: responseID, assertionID: assertionID}
+}
+
+// replace returns the response with the first occurrence of old replaced.
+func (r *testResponse) replace(old, replacement string) *testResponse {
+	return &testResponse{
+		document:    strings.Replace(r.document, old, replacement, 1),
+		responseID:  r.responseID,
+		assertionID: r.assertionID,
+	}
+}
+
+// signAssertion returns the encoded response with its assertion signed.
+func (r *testResponse) signAssertion(t *testing.T, signer *testSigner) string {
+	t.Helper()
+
+	return base64.StdEncoding.EncodeToString([]byte(signer.sign(t, r.document, r.assertionID)))
+}
+
+// signResponse returns the encoded response signed as a whole.
+func (r *testResponse) signResponse(t *testing.T, signer *testSigner) string {
+	t.Helper()
+
+	return base64.StdEncoding.EncodeToString([]byte(signer.sign(t, r.document, r.responseID)))
+}
+
+func TestValidateResponse(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	serviceProvider, signer, _ := setupTestServiceProvider(t, nil)
+	provider := serviceProvider.providers[testTenant]
+
+	// newSolicitedResponse creates a response to a pending request of the service provider
+	newSolicitedResponse := func(t *testing.T) *testResponse {
+		t.Helper()
+
+		loginURL, err := serviceProvider.LoginURL(ctx, testTenant, "")
+		require.NoError(t, err)
+
+		return newTestResponse(serviceProvider, requestID(t, loginURL), "alice@example.com",
+			map[string][]string{"groups": {"staff", "admins"}})
+	}
+
+	t.Run("accept signed assertion", func(t *testing.T) {
+		t.Parallel()
+
+		validated, err := serviceProvider.validateResponse(ctx, testTenant, provider,
+			newSolicitedResponse(t).signAssertion(t, signer))
+		require.NoError(t, err)
+		assert.Equal(t, "alice@example.com", validated.nameID)
+		assert.Equal(t, []string{"staff", "admins"}, validated.attributes["groups"])
+	})
+
+	t.Run("accept signed response", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := serviceProvider.validateResponse(ctx, testTenant, provider,
+			newSolicitedResponse(t).signResponse(t, signer))
+		require.NoError(t, err)
+	})
+
+	t.Run("reject replayed response", func(t *testing.T) {
+		t.Parallel()
+
+		encoded := newSolicitedResponse(t).signAssertion(t, signer)
+
+		_, err := serviceProvider.validateResponse(ctx, testTenant, provider, encoded)
+		require.NoError(t, err)
+
+		_, err = serviceProvider.validateResponse(ctx, testTenant, provider, encoded)
+		require.ErrorIs(t, err, ErrInvalidResponse)
+	})
+
+	t.Run("reject unsolicited and unknown responses", func(t *testing.T) {
+		t.Parallel()
+
+		for _, inResponseTo := range []string{"", newTestID(t)} {
+			response := newTestResponse(serviceProvider, inResponseTo, "alice@example.com", nil)
+
+			_, err := serviceProvider.validateResponse(ctx, testTenant, provider, response.signAssertion(t, signer))
+			require.ErrorIs(t, err, ErrInvalidResponse)
+		}
+	})
+
+	t.Run("reject unsigned and tampered responses", func(t *testing.T) {
+		t.Parallel()
+
+		response := newSolicitedResponse(t)
+
+		_, err := serviceProvider.validateResponse(ctx, testTenant, provider,
+			base64.StdEncoding.EncodeToString([]byte(response.document)))
+		require.ErrorIs(t, err, ErrMissingSignature)
+		require.ErrorIs(t, err, ErrInvalidResponse)
+
+		signed, err := base64.StdEncoding.DecodeString(response.signAssertion(t, signer))
+		require.NoError(t, err)
+
+		tampered := strings.Replace(string(signed), "alice@example.com", "admin@example.com", 1)
+
+		_, err = serviceProvider.validateResponse(ctx, testTenant, provider,
+			base64.StdEncoding.EncodeToString([]byte(tampered)))
+		require.ErrorIs(t, err, ErrInvalidSignature)
+
+		_, err = serviceProvider.validateResponse(ctx, testTenant, provider,
+			newSolicitedResponse(t).signAssertion(t, newTestSigner(t)))
+		require.ErrorIs(t, err, ErrInvalidSignature)
+	})
+
+	t.Run("reject wrapped assertion", func(t *testing.T) {
+		t.Parallel()
+
+		response := newSolicitedResponse(t)
+
+		signed, err := base64.StdEncoding.DecodeString(response.signAssertion(t, signer))
+		require.NoError(t, err)
+
+		// the signed assertion is hidden in an extension while an unsigned one takes its place
+		start := strings.Index(string(signed), "<saml:Assertion ")
+		end := strings.Index(string(signed), "</saml:Assertion>") + len("</saml:Assertion>")
+		signedAssertion := string(signed)[start:end]
+
+		forged := strings.Replace(response.document, "alice@example.com", "admin@example.com", 1)
+		forgedStart := strings.Index(forged, "<saml:Assertion ")
+		forged = forged[:forgedStart] + `<samlp:Extensions>` + signedAssertion + `</samlp:Extensions>` +
+			forged[forgedStart:]
+
+		_, err = serviceProvider.validateResponse(ctx, testTenant, provider,
+			base64.StdEncoding.EncodeToString([]byte(forged)))
+		require.ErrorIs(t, err, ErrInvalidResponse)
+	})
+
+	t.Run("reject responses not addressed to service provider", func(t *testing.T) {
+		t.Parallel()
+
+		for name, replace := range map[string][2]string{
+			"destination": {`Destination="https://api.example.com/saml/acme/acs"`,
+				`Destination="https://evil.example.com/acs"`},
+			"recipient": {`Recipient="https://api.example.com/saml/acme/acs"`,
+				`Recipient="https://api.example.com/saml/other/acs"`},
+			"audience": {`<saml:Audience>https://api.example.com/saml/acme/metadata`,
+				`<saml:Audience>https://api.example.com/saml/other/metadata`},
+			"issuer": {`<saml:Issuer>` + testEntityID + `</saml:Issuer><saml:Subject>`,
+				`<saml:Issuer>https://evil.example.com</saml:Issuer><saml:Subject>`},
+			"status":     {`status:Success`, `status:Requester`},
+			"bearer":     {`cm:bearer`, `cm:holder-of-key`},
+			"encryption": {`<saml:Assertion `, `<saml:EncryptedAssertion/><saml:Assertion `},
+		} {
+			response := newSolicitedResponse(t).replace(replace[0], replace[1])
+			_, err := serviceProvider.validateResponse(ctx, testTenant, provider, response.signAssertion(t, signer))
+			require.ErrorIs(t, err, ErrInvalidResponse, name)
+		}
+	})
+
+	t.Run("reject expired assertion", func(t *testing.T) {
+		t.Parallel()
+
+		expired, expiredSigner, _ := setupTestServiceProvider(t, nil)
+		expired.now = func() time.Time { return time.Now().Add(time.Hour) }
+
+		loginURL, err := expired.LoginURL(ctx, testTenant, "")
+		require.NoError(t, err)
+
+		response := newTestResponse(expired, requestID(t, loginURL), "alice@example.com", nil)
+
+		_, err = expired.validateResponse(ctx, testTenant, expired.providers[testTenant],
+			response.signAssertion(t, expiredSigner))
+		require.ErrorIs(t, err, ErrInvalidResponse)
+	})
+
+	t.Run("reject malformed responses", func(t *testing.T) {
+		t.Parallel()
+
+		for _, encoded := range []string{
+			"not base64!",
+			base64.StdEncoding.EncodeToString([]byte("<not xml")),
+			base64.StdEncoding.EncodeToString([]byte(`<Response/>`)),
+		} {
+			_, err := serviceProvider.validateResponse(ctx, testTenant, provider, encoded)
+			require.ErrorIs(t, err, ErrInvalidResponse)
+		}
+	})
+}
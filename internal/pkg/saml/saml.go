@@ -0,0 +1,584 @@
+// Package saml provides a SAML 2.0 service provider, logging in users of tenants whose identity providers
+// assert them with signed responses of the HTTP-POST binding.
+package saml
+
+import (
+	"bytes"
+	"compress/flate"
+	"context"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/pem"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"go.uber.org/fx"
+
+	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/logger"
+	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/redis"
+	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/user"
+)
+
+const (
+	// namespaceProtocol is namespace of SAML protocol messages.
+	namespaceProtocol = "urn:oasis:names:tc:SAML:2.0:protocol"
+
+	// namespaceAssertion is namespace of SAML assertions.
+	namespaceAssertion = "urn:oasis:names:tc:SAML:2.0:assertion"
+
+	// bindingHTTPPost is the HTTP-POST binding responses are received with.
+	bindingHTTPPost = "urn:oasis:names:tc:SAML:2.0:bindings:HTTP-POST"
+
+	// nameIDFormatEmail is the name ID format of email addresses.
+	nameIDFormatEmail = "urn:oasis:names:tc:SAML:1.1:nameid-format:emailAddress"
+
+	// requestKeyPrefix is prefix of redis keys of pending authentication requests.
+	requestKeyPrefix = "saml:request:"
+
+	// assertionKeyPrefix is prefix of redis keys of consumed assertions.
+	assertionKeyPrefix = "saml:assertion:"
+
+	// idLength is number of random bytes of request IDs.
+	idLength = 16
+
+	// maxRelayStateLength is maximum length of relay states in bytes, as limited by the bindings.
+	maxRelayStateLength = 80
+
+	// defaultRequestTTL is default lifetime of authentication requests.
+	defaultRequestTTL = 10 * time.Minute
+
+	// defaultClockSkew is default clock skew tolerated against identity providers.
+	defaultClockSkew = time.Minute
+)
+
+var (
+	// ErrMissingBaseURL is returned when SAML is enabled without an absolute base URL.
+	ErrMissingBaseURL = errors.New("missing saml base url")
+
+	// ErrInvalidProvider is returned when an identity provider is configured without an entity ID, SSO URL,
+	// certificates or email domains.
+	ErrInvalidProvider = errors.New("invalid saml identity provider")
+
+	// ErrUnknownProvider is returned when no identity provider is configured for the tenant.
+	ErrUnknownProvider = errors.New("unknown saml identity provider")
+
+	// ErrInvalidRelayState is returned when the relay state is longer than the bindings allow.
+	ErrInvalidRelayState = errors.New("invalid relay state")
+
+	// ErrInvalidResponse is returned when a response is malformed, unsigned, expired, replayed or not addressed
+	// to the service provider.
+	ErrInvalidResponse = errors.New("invalid saml response")
+
+	// ErrDomainNotAllowed is returned when the asserted email is not in the email domains of the identity provider.
+	ErrDomainNotAllowed = errors.New("email domain not allowed")
+
+	// ErrAccountConflict is returned when the user of the asserted email is linked to another subject of the
+	// identity provider or has a local password, so that it is not linked to the subject.
+	ErrAccountConflict = errors.New("account linked to another identity")
+)
+
+// Config represents configuration for SAML.
+type Config struct {
+	// Enabled is whether SAML endpoints are enabled.
+	Enabled *bool `json:"enabled"`
+
+	// Path is path prefix of SAML endpoints, followed by the tenant.
+	Path *string `json:"path"`
+
+	// BaseURL is scheme and host of the server as reached by browsers, e.g. https://api.example.com, entity IDs
+	// and assertion consumer URLs are built from it.
+	BaseURL *string `json:"base_url"`
+
+	// RequestTTL is lifetime of authentication requests, responses to older requests are rejected.
+	RequestTTL *time.Duration `json:"request_ttl"`
+
+	// ClockSkew is clock skew tolerated when checking validity periods of responses.
+	ClockSkew *time.Duration `json:"clock_skew"`
+
+	// Providers is identity providers by tenant.
+	Providers map[string]*ProviderConfig `json:"providers"`
+}
+
+// SetDefault sets default values.
+func (c *Config) SetDefault() {
+	if c.Enabled == nil {
+		c.Enabled = &[]bool{false}[0]
+	}
+
+	if c.Path == nil {
+		c.Path = &[]string{"/saml"}[0]
+	}
+
+	if c.BaseURL == nil {
+		c.BaseURL = &[]string{""}[0]
+	}
+
+	if c.RequestTTL == nil {
+		c.RequestTTL = &[]time.Duration{defaultRequestTTL}[0]
+	}
+
+	if c.ClockSkew == nil {
+		c.ClockSkew = &[]time.Duration{defaultClockSkew}[0]
+	}
+
+	if c.Providers == nil {
+		c.Providers = map[string]*ProviderConfig{}
+	}
+
+	for _, provider := range c.Providers {
+		if provider != nil {
+			provider.SetDefault()
+		}
+	}
+}
+
+// ProviderConfig represents configuration for the identity provider of a tenant.
+type ProviderConfig struct {
+	// EntityID is entity ID of the identity provider, the issuer of its responses.
+	EntityID *string `json:"entity_id"`
+
+	// SSOURL is URL of the single sign-on service of the identity provider, of the HTTP-Redirect binding.
+	SSOURL *string `json:"sso_url"`
+
+	// Certificates is PEM encoded certificates signing responses, several while keys roll over.
+	Certificates []string `json:"certificates"`
+
+	// Domains is email domains the identity provider may assert users of, so that it can not log in users of
+	// other tenants.
+	Domains []string `json:"domains"`
+
+	// EmailAttribute is name of the attribute of the email of users, the name ID if empty.
+	EmailAttribute *string `json:"email_attribute"`
+
+	// RoleAttribute is name of the attribute whose values are mapped to roles by Roles, roles are not synced if
+	// it or Roles is empty.
+	RoleAttribute *string `json:"role_attribute"`
+
+	// Roles is roles of users by value of the role attribute, the first value mapped wins and users of no mapped
+	// value get the default role.
+	Roles map[string]string `json:"roles"`
+
+	// AllowIdPInitiated is whether unsolicited responses started at the identity provider are accepted.
+	AllowIdPInitiated *bool `json:"allow_idp_initiated"`
+
+	// RedirectURL is URL browsers are redirected to with tokens in the fragment, tokens are returned as JSON if
+	// empty.
+	RedirectURL *string `json:"redirect_url"`
+}
+
+// SetDefault sets default values.
+func (c *ProviderConfig) SetDefault() {
+	if c.EntityID == nil {
+		c.EntityID = &[]string{""}[0]
+	}
+
+	if c.SSOURL == nil {
+		c.SSOURL = &[]string{""}[0]
+	}
+
+	if c.Certificates == nil {
+		c.Certificates = []string{}
+	}
+
+	if c.Domains == nil {
+		c.Domains = []string{}
+	}
+
+	if c.EmailAttribute == nil {
+		c.EmailAttribute = &[]string{""}[0]
+	}
+
+	if c.RoleAttribute == nil {
+		c.RoleAttribute = &[]string{""}[0]
+	}
+
+	if c.Roles == nil {
+		c.Roles = map[string]string{}
+	}
+
+	if c.AllowIdPInitiated == nil {
+		c.AllowIdPInitiated = &[]bool{false}[0]
+	}
+
+	if c.RedirectURL == nil {
+		c.RedirectURL = &[]string{""}[0]
+	}
+}
+
+// provider represents a validated identity provider.
+type provider struct {
+	// config provides identity provider configuration.
+	config *ProviderConfig
+
+	// certificates is certificates signing responses.
+	certificates []*x509.Certificate
+}
+
+// ServiceProvider logs in users asserted by identity providers of tenants.
+type ServiceProvider struct {
+	// config provides SAML configuration.
+	config *Config
+
+	// providers is identity providers by tenant.
+	providers map[string]*provider
+
+	// users provides users logged in.
+	users *user.Service
+
+	// redis stores pending requests and consumed assertions.
+	redis *redis.Redis
+
+	// logger provides logging.
+	logger *logger.Logger
+
+	// now returns the current time, replaced in tests.
+	now func() time.Time
+}
+
+// NewModule provides module for SAML.
+func NewModule() fx.Option {
+	return fx.Module("saml",
+		fx.Provide(New),
+	)
+}
+
+// New creates a new service provider, identity providers are validated if SAML is enabled.
+func New(config *Config, users *user.Service, redisConn *redis.Redis, logger *logger.Logger) (*ServiceProvider, error) {
+	if config == nil {
+		config = &Config{}
+	}
+
+	config.SetDefault()
+
+	serviceProvider := &ServiceProvider{
+		config:    config,
+		providers: make(map[string]*provider, len(config.Providers)),
+		users:     users,
+		redis:     redisConn,
+		logger:    logger.Named("saml"),
+		now:       time.Now,
+	}
+
+	if !*config.Enabled {
+		return serviceProvider, nil
+	}
+
+	baseURL, err := url.Parse(*config.BaseURL)
+	if err != nil || baseURL.Scheme == "" || baseURL.Host == "" {
+		return nil, ErrMissingBaseURL
+	}
+
+	for tenant, providerConfig := range config.Providers {
+		provider, err := newProvider(providerConfig)
+		if err != nil {
+			return nil, fmt.Errorf("%w of tenant %s: %w", ErrInvalidProvider, tenant, err)
+		}
+
+		serviceProvider.providers[tenant] = provider
+	}
+
+	return serviceProvider, nil
+}
+
+// newProvider validates the identity provider configuration and parses its certificates.
+func newProvider(config *ProviderConfig) (*provider, error) {
+	if config == nil || *config.EntityID == "" {
+		return nil, errors.New("missing entity id")
+	}
+
+	if ssoURL, err := url.Parse(*config.SSOURL); err != nil || ssoURL.Scheme == "" || ssoURL.Host == "" {
+		return nil, errors.New("missing sso url")
+	}
+
+	if len(config.Domains) == 0 {
+		return nil, errors.New("missing email domains")
+	}
+
+	certificates := make([]*x509.Certificate, 0, len(config.Certificates))
+
+	for _, encoded := range config.Certificates {
+		block, _ := pem.Decode([]byte(encoded))
+		if block == nil || block.Type != "CERTIFICATE" {
+			return nil, errors.New("malformed certificate")
+		}
+
+		certificate, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("malformed certificate: %w", err)
+		}
+
+		certificates = append(certificates, certificate)
+	}
+
+	if len(certificates) == 0 {
+		return nil, errors.New("missing certificates")
+	}
+
+	return &provider{config: config, certificates: certificates}, nil
+}
+
+// Enabled returns whether SAML endpoints are enabled.
+func (s *ServiceProvider) Enabled() bool {
+	return s != nil && *s.config.Enabled
+}
+
+// Path returns path prefix of SAML endpoints.
+func (s *ServiceProvider) Path() string {
+	return *s.config.Path
+}
+
+// RedirectURL returns URL browsers are redirected to after logging in with the identity provider of the tenant,
+// empty if tokens are returned as JSON.
+func (s *ServiceProvider) RedirectURL(tenant string) (string, error) {
+	provider, err := s.provider(tenant)
+	if err != nil {
+		return "", err
+	}
+
+	return *provider.config.RedirectURL, nil
+}
+
+// EntityID returns entity ID of the service provider for the tenant, the URL of its metadata.
+func (s *ServiceProvider) EntityID(tenant string) string {
+	return s.endpoint(tenant, "metadata")
+}
+
+// ACSURL returns URL of the assertion consumer service for the tenant.
+func (s *ServiceProvider) ACSURL(tenant string) string {
+	return s.endpoint(tenant, "acs")
+}
+
+// endpoint returns the absolute URL of the SAML endpoint of the tenant.
+func (s *ServiceProvider) endpoint(tenant, name string) string {
+	return strings.TrimSuffix(*s.config.BaseURL, "/") + *s.config.Path + "/" + url.PathEscape(tenant) + "/" + name
+}
+
+// provider returns the identity provider of the tenant, ErrUnknownProvider if there is none.
+func (s *ServiceProvider) provider(tenant string) (*provider, error) {
+	provider, ok := s.providers[tenant]
+	if !ok {
+		return nil, ErrUnknownProvider
+	}
+
+	return provider, nil
+}
+
+// entityDescriptor represents metadata of the service provider.
+type entityDescriptor struct {
+	XMLName         xml.Name        `xml:"urn:oasis:names:tc:SAML:2.0:metadata EntityDescriptor"`
+	EntityID        string          `xml:"entityID,attr"`
+	SPSSODescriptor spSSODescriptor `xml:"SPSSODescriptor"`
+}
+
+// spSSODescriptor represents the service provider role of metadata.
+type spSSODescriptor struct {
+	ProtocolSupportEnumeration string                   `xml:"protocolSupportEnumeration,attr"`
+	AuthnRequestsSigned        bool                     `xml:"AuthnRequestsSigned,attr"`
+	NameIDFormat               string                   `xml:"NameIDFormat"`
+	AssertionConsumerService   assertionConsumerService `xml:"AssertionConsumerService"`
+}
+
+// assertionConsumerService represents the assertion consumer service endpoint of metadata.
+type assertionConsumerService struct {
+	Binding   string `xml:"Binding,attr"`
+	Location  string `xml:"Location,attr"`
+	Index     int    `xml:"index,attr"`
+	IsDefault bool   `xml:"isDefault,attr"`
+}
+
+// Metadata returns the metadata of the service provider for the identity provider of the tenant.
+func (s *ServiceProvider) Metadata(tenant string) ([]byte, error) {
+	if _, err := s.provider(tenant); err != nil {
+		return nil, err
+	}
+
+	metadata, err := xml.MarshalIndent(entityDescriptor{
+		EntityID: s.EntityID(tenant),
+		SPSSODescriptor: spSSODescriptor{
+			ProtocolSupportEnumeration: namespaceProtocol,
+			NameIDFormat:               nameIDFormatEmail,
+			AssertionConsumerService: assertionConsumerService{
+				Binding:   bindingHTTPPost,
+				Location:  s.ACSURL(tenant),
+				IsDefault: true,
+			},
+		},
+	}, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal metadata: %w", err)
+	}
+
+	return append([]byte(xml.Header), metadata...), nil
+}
+
+// authnRequest represents an authentication request.
+type authnRequest struct {
+	XMLName                     xml.Name     `xml:"urn:oasis:names:tc:SAML:2.0:protocol AuthnRequest"`
+	ID                          string       `xml:"ID,attr"`
+	Version                     string       `xml:"Version,attr"`
+	IssueInstant                string       `xml:"IssueInstant,attr"`
+	Destination                 string       `xml:"Destination,attr"`
+	AssertionConsumerServiceURL string       `xml:"AssertionConsumerServiceURL,attr"`
+	ProtocolBinding             string       `xml:"ProtocolBinding,attr"`
+	Issuer                      string       `xml:"urn:oasis:names:tc:SAML:2.0:assertion Issuer"`
+	NameIDPolicy                nameIDPolicy `xml:"NameIDPolicy"`
+}
+
+// nameIDPolicy represents the name ID policy of an authentication request.
+type nameIDPolicy struct {
+	Format      string `xml:"Format,attr"`
+	AllowCreate bool   `xml:"AllowCreate,attr"`
+}
+
+// LoginURL returns URL of the identity provider of the tenant browsers are redirected to for logging in, with
+// an authentication request of the HTTP-Redirect binding that its response must answer within the request TTL.
+func (s *ServiceProvider) LoginURL(ctx context.Context, tenant, relayState string) (string, error) {
+	provider, err := s.provider(tenant)
+	if err != nil {
+		return "", err
+	}
+
+	if len(relayState) > maxRelayStateLength {
+		return "", ErrInvalidRelayState
+	}
+
+	random := make([]byte, idLength)
+	if _, err := rand.Read(random); err != nil {
+		return "", fmt.Errorf("failed to generate request id: %w", err)
+	}
+
+	// IDs must not start with a digit
+	id := "_" + hex.EncodeToString(random)
+
+	request, err := xml.Marshal(authnRequest{
+		ID:                          id,
+		Version:                     "2.0",
+		IssueInstant:                s.now().UTC().Format(time.RFC3339),
+		Destination:                 *provider.config.SSOURL,
+		AssertionConsumerServiceURL: s.ACSURL(tenant),
+		ProtocolBinding:             bindingHTTPPost,
+		Issuer:                      s.EntityID(tenant),
+		NameIDPolicy:                nameIDPolicy{Format: nameIDFormatEmail, AllowCreate: true},
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal authentication request: %w", err)
+	}
+
+	var deflated bytes.Buffer
+
+	writer, err := flate.NewWriter(&deflated, flate.DefaultCompression)
+	if err != nil {
+		return "", fmt.Errorf("failed to deflate authentication request: %w", err)
+	}
+
+	if _, err := writer.Write(request); err != nil {
+		return "", fmt.Errorf("failed to deflate authentication request: %w", err)
+	}
+
+	if err := writer.Close(); err != nil {
+		return "", fmt.Errorf("failed to deflate authentication request: %w", err)
+	}
+
+	if err := s.redis.Set(ctx, requestKeyPrefix+tenant+":"+id, "1", *s.config.RequestTTL).Err(); err != nil {
+		return "", fmt.Errorf("failed to store authentication request: %w", err)
+	}
+
+	loginURL, err := url.Parse(*provider.config.SSOURL)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse sso url: %w", err)
+	}
+
+	query := loginURL.Query()
+	query.Set("SAMLRequest", base64.StdEncoding.EncodeToString(deflated.Bytes()))
+
+	if relayState != "" {
+		query.Set("RelayState", relayState)
+	}
+
+	loginURL.RawQuery = query.Encode()
+
+	return loginURL.String(), nil
+}
+
+// Login validates the base64 encoded response of the identity provider of the tenant and returns the user linked
+// to the asserted name ID, created and linked by its email on first login, so that the user keeps its account
+// whatever its email. The role of the user is synced from the role attribute.
+func (s *ServiceProvider) Login(ctx context.Context, tenant, encodedResponse string) (*user.User, error) {
+	provider, err := s.provider(tenant)
+	if err != nil {
+		return nil, err
+	}
+
+	assertion, err := s.validateResponse(ctx, tenant, provider, encodedResponse)
+	if err != nil {
+		s.logger.Ctx(ctx).Warn().Err(err).Str("tenant", tenant).Msg("rejected saml response")
+
+		return nil, err
+	}
+
+	email := assertion.nameID
+	if *provider.config.EmailAttribute != "" {
+		email = assertion.first(*provider.config.EmailAttribute)
+	}
+
+	email = strings.ToLower(strings.TrimSpace(email))
+
+	_, domain, found := strings.Cut(email, "@")
+	if !found || !slices.ContainsFunc(provider.config.Domains, func(allowed string) bool {
+		return strings.EqualFold(allowed, domain)
+	}) {
+		return nil, ErrDomainNotAllowed
+	}
+
+	if assertion.nameID == "" {
+		return nil, fmt.Errorf("%w: empty name id", ErrInvalidResponse)
+	}
+
+	loggedIn, err := s.users.ProvisionIdentity(ctx, *provider.config.EntityID, assertion.nameID, email)
+
+	switch {
+	case errors.Is(err, user.ErrInvalidEmail):
+		return nil, fmt.Errorf("%w: invalid email", ErrInvalidResponse)
+	case errors.Is(err, user.ErrIdentityConflict), errors.Is(err, user.ErrLocalPassword):
+		s.logger.Ctx(ctx).Warn().Err(err).Str("tenant", tenant).Msg("rejected saml user of email of another account")
+
+		return nil, ErrAccountConflict
+	case err != nil:
+		return nil, fmt.Errorf("failed to provision user: %w", err)
+	}
+
+	role := s.mapRole(provider, assertion)
+	if role == "" || role == loggedIn.Role {
+		return loggedIn, nil
+	}
+
+	loggedIn, err = s.users.SetRole(ctx, loggedIn.ID, role)
+	if err != nil {
+		return nil, fmt.Errorf("failed to sync user role: %w", err)
+	}
+
+	return loggedIn, nil
+}
+
+// mapRole returns the role of the first value of the role attribute mapped to a role, the default role if there
+// is none, so that users losing the value lose their role, or empty if roles are not synced.
+func (s *ServiceProvider) mapRole(provider *provider, assertion *assertion) string {
+	if *provider.config.RoleAttribute == "" || len(provider.config.Roles) == 0 {
+		return ""
+	}
+
+	for _, value := range assertion.attributes[*provider.config.RoleAttribute] {
+		if role, ok := provider.config.Roles[value]; ok {
+			return role
+		}
+	}
+
+	return s.users.DefaultRole()
+}
@@ -0,0 +1,562 @@
+package saml
+
+import (
+	"bytes"
+	"compress/flate"
+	"context"
+	"encoding/base64"
+	"encoding/pem"
+	"encoding/xml"
+	"io"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+
+	"github.com/pocj8ur4in/boilerplate-go/internal/gen/db"
+	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/logger"
+	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/redis"
+	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/user"
+)
+
+const (
+	// testTenant is tenant of the test identity provider.
+	testTenant = "acme"
+
+	// testEntityID is entity ID of the test identity provider.
+	testEntityID = "https://idp.example.com/metadata"
+)
+
+// mockUserQuerier is a mock querier storing users in memory.
+type mockUserQuerier struct {
+	db.Querier
+
+	mu         sync.Mutex
+	users      []*db.User
+	identities []*db.UserIdentity
+}
+
+func (m *mockUserQuerier) CreateUser(_ context.Context, arg *db.CreateUserParams) (*db.User, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	row := &db.User{
+		ID:           arg.ID,
+		Email:        arg.Email,
+		PasswordHash: arg.PasswordHash,
+		Role:         arg.Role,
+		CreatedAt:    pgtype.Timestamptz{Time: time.Now(), Valid: true},
+	}
+	m.users = append(m.users, row)
+
+	return row, nil
+}
+
+func (m *mockUserQuerier) GetUserByEmail(_ context.Context, email string) (*db.User, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, row := range m.users {
+		if row.Email == email {
+			return row, nil
+		}
+	}
+
+	return nil, pgx.ErrNoRows
+}
+
+func (m *mockUserQuerier) GetUserByID(_ context.Context, id string) (*db.User, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, row := range m.users {
+		if row.ID == id {
+			return row, nil
+		}
+	}
+
+	return nil, pgx.ErrNoRows
+}
+
+func (m *mockUserQuerier) CreateUserIdentity(
+	_ context.Context,
+	arg *db.CreateUserIdentityParams,
+) (*db.UserIdentity, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, row := range m.identities {
+		if row.Provider == arg.Provider && (row.Subject == arg.Subject || row.UserID == arg.UserID) {
+			return nil, &pgconn.PgError{Code: "23505"}
+		}
+	}
+
+	row := &db.UserIdentity{Provider: arg.Provider, Subject: arg.Subject, UserID: arg.UserID}
+	m.identities = append(m.identities, row)
+
+	return row, nil
+}
+
+func (m *mockUserQuerier) GetUserIdentity(_ context.Context, arg *db.GetUserIdentityParams) (*db.UserIdentity, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, row := range m.identities {
+		if row.Provider == arg.Provider && row.Subject == arg.Subject {
+			return row, nil
+		}
+	}
+
+	return nil, pgx.ErrNoRows
+}
+
+func (m *mockUserQuerier) GetUserIdentityByUserID(
+	_ context.Context,
+	arg *db.GetUserIdentityByUserIDParams,
+) (*db.UserIdentity, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, row := range m.identities {
+		if row.Provider == arg.Provider && row.UserID == arg.UserID {
+			return row, nil
+		}
+	}
+
+	return nil, pgx.ErrNoRows
+}
+
+func (m *mockUserQuerier) UpdateUserRole(_ context.Context, arg *db.UpdateUserRoleParams) (*db.User, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, row := range m.users {
+		if row.ID == arg.ID {
+			row.Role = arg.Role
+
+			return row, nil
+		}
+	}
+
+	return nil, pgx.ErrNoRows
+}
+
+// setupTestRedis creates a redis client of the test redis server.
+func setupTestRedis(t *testing.T) *redis.Redis {
+	t.Helper()
+
+	password := ""
+	redisDB := 0
+
+	redisClient, err := redis.New(&redis.Config{
+		Addrs:    []string{"localhost:36379"},
+		Password: &password,
+		DB:       &redisDB,
+	})
+	require.NoError(t, err)
+
+	t.Cleanup(func() {
+		_ = redisClient.Close()
+	})
+
+	return redisClient
+}
+
+// setupTestServiceProvider creates an enabled service provider with the identity provider of the test tenant
+// signing with the returned signer.
+func setupTestServiceProvider(
+	t *testing.T,
+	configure func(config *ProviderConfig),
+) (*ServiceProvider, *testSigner, *mockUserQuerier) {
+	t.Helper()
+
+	log, err := logger.New(&logger.Config{})
+	require.NoError(t, err)
+
+	querier := &mockUserQuerier{}
+
+	users, err := user.NewWithQuerier(&user.Config{PasswordCost: &[]int{bcrypt.MinCost}[0]}, querier)
+	require.NoError(t, err)
+
+	signer := newTestSigner(t)
+
+	providerConfig := &ProviderConfig{
+		EntityID:     &[]string{testEntityID}[0],
+		SSOURL:       &[]string{"https://idp.example.com/sso?app=boilerplate"}[0],
+		Certificates: []string{testCertificatePEM(signer)},
+		Domains:      []string{"example.com"},
+	}
+
+	if configure != nil {
+		configure(providerConfig)
+	}
+
+	serviceProvider, err := New(&Config{
+		Enabled:   &[]bool{true}[0],
+		BaseURL:   &[]string{"https://api.example.com"}[0],
+		Providers: map[string]*ProviderConfig{testTenant: providerConfig},
+	}, users, setupTestRedis(t), log)
+	require.NoError(t, err)
+
+	return serviceProvider, signer, querier
+}
+
+// testCertificatePEM returns the PEM encoded certificate of the signer.
+func testCertificatePEM(signer *testSigner) string {
+	return string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: signer.certificate.Raw}))
+}
+
+// requestID returns ID of the authentication request of the login URL.
+func requestID(t *testing.T, loginURL string) string {
+	t.Helper()
+
+	parsed, err := url.Parse(loginURL)
+	require.NoError(t, err)
+
+	deflated, err := base64.StdEncoding.DecodeString(parsed.Query().Get("SAMLRequest"))
+	require.NoError(t, err)
+
+	inflated, err := io.ReadAll(flate.NewReader(bytes.NewReader(deflated)))
+	require.NoError(t, err)
+
+	var request authnRequest
+	require.NoError(t, xml.Unmarshal(inflated, &request))
+
+	return request.ID
+}
+
+func TestConfigSetDefault(t *testing.T) {
+	t.Parallel()
+
+	config := &Config{Providers: map[string]*ProviderConfig{testTenant: {}}}
+	config.SetDefault()
+
+	assert.False(t, *config.Enabled)
+	assert.Equal(t, "/saml", *config.Path)
+	assert.Empty(t, *config.BaseURL)
+	assert.Equal(t, defaultRequestTTL, *config.RequestTTL)
+	assert.Equal(t, defaultClockSkew, *config.ClockSkew)
+
+	provider := config.Providers[testTenant]
+	assert.Empty(t, *provider.EmailAttribute)
+	assert.False(t, *provider.AllowIdPInitiated)
+	assert.Empty(t, provider.Roles)
+}
+
+func TestNew(t *testing.T) {
+	t.Parallel()
+
+	log, err := logger.New(&logger.Config{})
+	require.NoError(t, err)
+
+	t.Run("return fx.Option", func(t *testing.T) {
+		t.Parallel()
+
+		require.NotNil(t, NewModule())
+	})
+
+	t.Run("skip validation while disabled", func(t *testing.T) {
+		t.Parallel()
+
+		serviceProvider, err := New(&Config{Providers: map[string]*ProviderConfig{testTenant: {}}}, nil, nil, log)
+		require.NoError(t, err)
+		assert.False(t, serviceProvider.Enabled())
+
+		var nilServiceProvider *ServiceProvider
+		assert.False(t, nilServiceProvider.Enabled())
+	})
+
+	t.Run("reject missing base url", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := New(&Config{Enabled: &[]bool{true}[0], BaseURL: &[]string{"/relative"}[0]}, nil, nil, log)
+		require.ErrorIs(t, err, ErrMissingBaseURL)
+	})
+
+	t.Run("reject invalid identity providers", func(t *testing.T) {
+		t.Parallel()
+
+		certificate := testCertificatePEM(newTestSigner(t))
+
+		for name, provider := range map[string]*ProviderConfig{
+			"missing entity id": {
+				SSOURL: &[]string{"https://idp.example.com/sso"}[0], Certificates: []string{certificate},
+				Domains: []string{"example.com"},
+			},
+			"missing sso url": {
+				EntityID: &[]string{testEntityID}[0], Certificates: []string{certificate},
+				Domains: []string{"example.com"},
+			},
+			"missing certificates": {
+				EntityID: &[]string{testEntityID}[0], SSOURL: &[]string{"https://idp.example.com/sso"}[0],
+				Domains: []string{"example.com"},
+			},
+			"malformed certificate": {
+				EntityID: &[]string{testEntityID}[0], SSOURL: &[]string{"https://idp.example.com/sso"}[0],
+				Certificates: []string{"not a certificate"}, Domains: []string{"example.com"},
+			},
+			"missing domains": {
+				EntityID: &[]string{testEntityID}[0], SSOURL: &[]string{"https://idp.example.com/sso"}[0],
+				Certificates: []string{certificate},
+			},
+		} {
+			_, err := New(&Config{
+				Enabled:   &[]bool{true}[0],
+				BaseURL:   &[]string{"https://api.example.com"}[0],
+				Providers: map[string]*ProviderConfig{testTenant: provider},
+			}, nil, nil, log)
+			require.ErrorIs(t, err, ErrInvalidProvider, name)
+		}
+	})
+}
+
+func TestMetadata(t *testing.T) {
+	t.Parallel()
+
+	serviceProvider, _, _ := setupTestServiceProvider(t, nil)
+
+	t.Run("describe assertion consumer service of tenant", func(t *testing.T) {
+		t.Parallel()
+
+		metadata, err := serviceProvider.Metadata(testTenant)
+		require.NoError(t, err)
+
+		root, err := parseXML(metadata)
+		require.NoError(t, err)
+
+		assert.True(t, is(root, "urn:oasis:names:tc:SAML:2.0:metadata", "EntityDescriptor"))
+		assert.Equal(t, "https://api.example.com/saml/acme/metadata", attr(root, "entityID"))
+
+		acs := child(child(root, "urn:oasis:names:tc:SAML:2.0:metadata", "SPSSODescriptor"),
+			"urn:oasis:names:tc:SAML:2.0:metadata", "AssertionConsumerService")
+		require.NotNil(t, acs)
+		assert.Equal(t, bindingHTTPPost, attr(acs, "Binding"))
+		assert.Equal(t, "https://api.example.com/saml/acme/acs", attr(acs, "Location"))
+	})
+
+	t.Run("reject unknown tenant", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := serviceProvider.Metadata("unknown")
+		require.ErrorIs(t, err, ErrUnknownProvider)
+	})
+}
+
+func TestLoginURL(t *testing.T) {
+	t.Parallel()
+
+	serviceProvider, _, _ := setupTestServiceProvider(t, nil)
+
+	t.Run("redirect to identity provider with pending request", func(t *testing.T) {
+		t.Parallel()
+
+		loginURL, err := serviceProvider.LoginURL(context.Background(), testTenant, "state")
+		require.NoError(t, err)
+
+		parsed, err := url.Parse(loginURL)
+		require.NoError(t, err)
+
+		assert.Equal(t, "idp.example.com", parsed.Host)
+		assert.Equal(t, "boilerplate", parsed.Query().Get("app"))
+		assert.Equal(t, "state", parsed.Query().Get("RelayState"))
+
+		id := requestID(t, loginURL)
+		assert.True(t, strings.HasPrefix(id, "_"))
+
+		exists, err := serviceProvider.redis.Exists(context.Background(), requestKeyPrefix+testTenant+":"+id).Result()
+		require.NoError(t, err)
+		assert.Equal(t, int64(1), exists)
+	})
+
+	t.Run("reject long relay state", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := serviceProvider.LoginURL(context.Background(), testTenant, strings.Repeat("a", 81))
+		require.ErrorIs(t, err, ErrInvalidRelayState)
+	})
+
+	t.Run("reject unknown tenant", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := serviceProvider.LoginURL(context.Background(), "unknown", "")
+		require.ErrorIs(t, err, ErrUnknownProvider)
+	})
+}
+
+func TestLogin(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	t.Run("provision asserted user with mapped role", func(t *testing.T) {
+		t.Parallel()
+
+		serviceProvider, signer, querier := setupTestServiceProvider(t, func(config *ProviderConfig) {
+			config.RoleAttribute = &[]string{"groups"}[0]
+			config.Roles = map[string]string{"admins": "admin"}
+		})
+
+		loginURL, err := serviceProvider.LoginURL(ctx, testTenant, "")
+		require.NoError(t, err)
+
+		response := newTestResponse(serviceProvider, requestID(t, loginURL), "Alice@Example.com",
+			map[string][]string{"groups": {"staff", "admins"}})
+
+		loggedIn, err := serviceProvider.Login(ctx, testTenant, response.signAssertion(t, signer))
+		require.NoError(t, err)
+		assert.Equal(t, "alice@example.com", loggedIn.Email)
+		assert.Equal(t, "admin", loggedIn.Role)
+		require.Len(t, querier.users, 1)
+		assert.Empty(t, querier.users[0].PasswordHash)
+		require.Len(t, querier.identities, 1)
+		assert.Equal(t, testEntityID, querier.identities[0].Provider)
+		assert.Equal(t, "Alice@Example.com", querier.identities[0].Subject)
+	})
+
+	t.Run("demote users without mapped value", func(t *testing.T) {
+		t.Parallel()
+
+		serviceProvider, signer, _ := setupTestServiceProvider(t, func(config *ProviderConfig) {
+			config.RoleAttribute = &[]string{"groups"}[0]
+			config.Roles = map[string]string{"admins": "admin"}
+			config.AllowIdPInitiated = &[]bool{true}[0]
+		})
+
+		response := newTestResponse(serviceProvider, "", "alice@example.com", map[string][]string{"groups": {"admins"}})
+
+		loggedIn, err := serviceProvider.Login(ctx, testTenant, response.signAssertion(t, signer))
+		require.NoError(t, err)
+		assert.Equal(t, "admin", loggedIn.Role)
+
+		response = newTestResponse(serviceProvider, "", "alice@example.com", map[string][]string{"groups": {"staff"}})
+
+		loggedIn, err = serviceProvider.Login(ctx, testTenant, response.signAssertion(t, signer))
+		require.NoError(t, err)
+		assert.Equal(t, "user", loggedIn.Role)
+	})
+
+	t.Run("keep role if roles are not synced", func(t *testing.T) {
+		t.Parallel()
+
+		serviceProvider, signer, querier := setupTestServiceProvider(t, func(config *ProviderConfig) {
+			config.AllowIdPInitiated = &[]bool{true}[0]
+		})
+
+		response := newTestResponse(serviceProvider, "", "alice@example.com", nil)
+
+		loggedIn, err := serviceProvider.Login(ctx, testTenant, response.signAssertion(t, signer))
+		require.NoError(t, err)
+
+		_, err = querier.UpdateUserRole(ctx, &db.UpdateUserRoleParams{ID: loggedIn.ID, Role: "admin"})
+		require.NoError(t, err)
+
+		response = newTestResponse(serviceProvider, "", "alice@example.com", nil)
+
+		loggedIn, err = serviceProvider.Login(ctx, testTenant, response.signAssertion(t, signer))
+		require.NoError(t, err)
+		assert.Equal(t, "admin", loggedIn.Role)
+	})
+
+	t.Run("keep user of name id whose email changes", func(t *testing.T) {
+		t.Parallel()
+
+		serviceProvider, signer, _ := setupTestServiceProvider(t, func(config *ProviderConfig) {
+			config.EmailAttribute = &[]string{"mail"}[0]
+			config.AllowIdPInitiated = &[]bool{true}[0]
+		})
+
+		response := newTestResponse(serviceProvider, "", "employee-42", map[string][]string{"mail": {"bob@example.com"}})
+
+		loggedIn, err := serviceProvider.Login(ctx, testTenant, response.signAssertion(t, signer))
+		require.NoError(t, err)
+
+		response = newTestResponse(serviceProvider, "", "employee-42", map[string][]string{"mail": {"rob@example.com"}})
+
+		again, err := serviceProvider.Login(ctx, testTenant, response.signAssertion(t, signer))
+		require.NoError(t, err)
+		assert.Equal(t, loggedIn.ID, again.ID)
+	})
+
+	t.Run("reject email of other accounts", func(t *testing.T) {
+		t.Parallel()
+
+		serviceProvider, signer, querier := setupTestServiceProvider(t, func(config *ProviderConfig) {
+			config.EmailAttribute = &[]string{"mail"}[0]
+			config.AllowIdPInitiated = &[]bool{true}[0]
+		})
+
+		// signed up with a password before the identity provider asserted the email
+		querier.users = append(querier.users, &db.User{
+			ID:           "signed-up",
+			Email:        "alice@example.com",
+			PasswordHash: "hash",
+			Role:         "user",
+		})
+
+		response := newTestResponse(serviceProvider, "", "alice", map[string][]string{"mail": {"alice@example.com"}})
+
+		_, err := serviceProvider.Login(ctx, testTenant, response.signAssertion(t, signer))
+		require.ErrorIs(t, err, ErrAccountConflict)
+
+		response = newTestResponse(serviceProvider, "", "bob", map[string][]string{"mail": {"bob@example.com"}})
+
+		_, err = serviceProvider.Login(ctx, testTenant, response.signAssertion(t, signer))
+		require.NoError(t, err)
+
+		// the email is linked to another name id
+		response = newTestResponse(serviceProvider, "", "mallory", map[string][]string{"mail": {"bob@example.com"}})
+
+		_, err = serviceProvider.Login(ctx, testTenant, response.signAssertion(t, signer))
+		require.ErrorIs(t, err, ErrAccountConflict)
+		assert.Len(t, querier.identities, 1)
+	})
+
+	t.Run("read email from attribute", func(t *testing.T) {
+		t.Parallel()
+
+		serviceProvider, signer, _ := setupTestServiceProvider(t, func(config *ProviderConfig) {
+			config.EmailAttribute = &[]string{"mail"}[0]
+			config.AllowIdPInitiated = &[]bool{true}[0]
+		})
+
+		response := newTestResponse(serviceProvider, "", "employee-42", map[string][]string{"mail": {"bob@example.com"}})
+
+		loggedIn, err := serviceProvider.Login(ctx, testTenant, response.signAssertion(t, signer))
+		require.NoError(t, err)
+		assert.Equal(t, "bob@example.com", loggedIn.Email)
+	})
+
+	t.Run("reject email of other domains", func(t *testing.T) {
+		t.Parallel()
+
+		serviceProvider, signer, querier := setupTestServiceProvider(t, func(config *ProviderConfig) {
+			config.AllowIdPInitiated = &[]bool{true}[0]
+		})
+
+		for _, email := range []string{"mallory@other.com", "mallory@sub.example.com", "example.com"} {
+			response := newTestResponse(serviceProvider, "", email, nil)
+
+			_, err := serviceProvider.Login(ctx, testTenant, response.signAssertion(t, signer))
+			require.ErrorIs(t, err, ErrDomainNotAllowed, email)
+		}
+
+		assert.Empty(t, querier.users)
+	})
+
+	t.Run("reject unknown tenant", func(t *testing.T) {
+		t.Parallel()
+
+		serviceProvider, _, _ := setupTestServiceProvider(t, nil)
+
+		_, err := serviceProvider.Login(ctx, "unknown", "")
+		require.ErrorIs(t, err, ErrUnknownProvider)
+	})
+}
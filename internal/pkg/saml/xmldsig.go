@@ -0,0 +1,96 @@
+package saml
+
+import (
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/beevik/etree"
+	dsig "github.com/russellhaering/goxmldsig"
+	"github.com/russellhaering/goxmldsig/etreeutils"
+)
+
+var (
+	// ErrMalformedXML is returned when a document is not well-formed or declares a DTD.
+	ErrMalformedXML = errors.New("malformed xml")
+
+	// ErrMissingSignature is returned when an element carries no signature.
+	ErrMissingSignature = errors.New("missing signature")
+
+	// ErrInvalidSignature is returned when a signature does not verify, references another element or uses an
+	// unsupported algorithm.
+	ErrInvalidSignature = errors.New("invalid signature")
+)
+
+// parseXML parses the document and returns its root element. Documents declaring a DTD are rejected, so that
+// entities can not be expanded.
+func parseXML(data []byte) (*etree.Element, error) {
+	document := etree.NewDocument()
+	if err := document.ReadFromBytes(data); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrMalformedXML, err)
+	}
+
+	roots := 0
+
+	for _, token := range document.Child {
+		switch token.(type) {
+		case *etree.Directive:
+			return nil, fmt.Errorf("%w: directives are not allowed", ErrMalformedXML)
+		case *etree.Element:
+			roots++
+		}
+	}
+
+	if roots != 1 {
+		return nil, fmt.Errorf("%w: expected one root element, got %d", ErrMalformedXML, roots)
+	}
+
+	return document.Root(), nil
+}
+
+// verifySignature verifies the enveloped signature of the element against the certificates, and returns the
+// element as signed, parsed from its canonical form, which is the only one to be read since other elements of
+// the document may have been moved or added around it.
+func verifySignature(e *etree.Element, certificates []*x509.Certificate) (*etree.Element, error) {
+	// the element is detached with the namespaces declared by its ancestors, so that it can be canonicalized
+	detached, err := detach(e)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidSignature, err)
+	}
+
+	// SHA-1 digests and signatures are accepted by the library, but collisions of SHA-1 are practical
+	for _, method := range detached.FindElements("./Signature/SignedInfo/*[@Algorithm]") {
+		if strings.HasSuffix(strings.ToLower(method.SelectAttrValue("Algorithm", "")), "sha1") {
+			return nil, fmt.Errorf("%w: unsupported algorithm", ErrInvalidSignature)
+		}
+	}
+
+	validation := dsig.NewDefaultValidationContext(&dsig.MemoryX509CertificateStore{Roots: certificates})
+
+	verified, err := validation.Validate(detached)
+
+	switch {
+	case errors.Is(err, dsig.ErrMissingSignature):
+		return nil, ErrMissingSignature
+	case err != nil:
+		return nil, fmt.Errorf("%w: %w", ErrInvalidSignature, err)
+	}
+
+	return verified, nil
+}
+
+// detach returns a copy of the element declaring the namespaces in scope of its ancestors.
+func detach(e *etree.Element) (*etree.Element, error) {
+	namespaces, err := etreeutils.NSBuildParentContext(e)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build namespace context: %w", err)
+	}
+
+	detached, err := etreeutils.NSDetatch(namespaces, e)
+	if err != nil {
+		return nil, fmt.Errorf("failed to detach element: %w", err)
+	}
+
+	return detached, nil
+}
@@ -0,0 +1,197 @@
+package saml
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/beevik/etree"
+	dsig "github.com/russellhaering/goxmldsig"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testSigner signs elements of test documents as an identity provider.
+type testSigner struct {
+	key         *rsa.PrivateKey
+	certificate *x509.Certificate
+}
+
+// newTestSigner creates a signer with a new key and self-signed certificate.
+func newTestSigner(t *testing.T) *testSigner {
+	t.Helper()
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "idp.example.com"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+
+	certificate, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+
+	return &testSigner{key: key, certificate: certificate}
+}
+
+// sign returns the document with an enveloped signature added to the element of the ID.
+func (s *testSigner) sign(t *testing.T, document, id string) string {
+	t.Helper()
+
+	parsed := etree.NewDocument()
+	require.NoError(t, parsed.ReadFromString(document))
+
+	element := parsed.FindElement("//[@ID='" + id + "']")
+	require.NotNil(t, element)
+
+	detached, err := detach(element)
+	require.NoError(t, err)
+
+	signing := dsig.NewDefaultSigningContext(dsig.TLSCertKeyStore(tls.Certificate{
+		Certificate: [][]byte{s.certificate.Raw},
+		PrivateKey:  s.key,
+	}))
+
+	signed, err := signing.SignEnveloped(detached)
+	require.NoError(t, err)
+
+	if parent := element.Parent(); parent != nil {
+		parent.InsertChildAt(element.Index(), signed)
+		parent.RemoveChild(element)
+	} else {
+		parsed.SetRoot(signed)
+	}
+
+	signedDocument, err := parsed.WriteToString()
+	require.NoError(t, err)
+
+	return signedDocument
+}
+
+func TestParseXML(t *testing.T) {
+	t.Parallel()
+
+	t.Run("parse elements, attributes and text", func(t *testing.T) {
+		t.Parallel()
+
+		root, err := parseXML([]byte(`<?xml version="1.0"?><a:root xmlns:a="urn:a" ID="x"><!-- comment -->` +
+			`<a:child>one &amp; <!-- comment -->two</a:child><child/></a:root>`))
+		require.NoError(t, err)
+
+		assert.True(t, is(root, "urn:a", "root"))
+		assert.Equal(t, "x", attr(root, "ID"))
+		assert.Equal(t, "one & two", text(child(root, "urn:a", "child")))
+		assert.NotNil(t, child(root, "", "child"))
+		assert.Nil(t, child(child(root, "urn:a", "missing"), "urn:a", "child"))
+	})
+
+	t.Run("reject malformed documents", func(t *testing.T) {
+		t.Parallel()
+
+		for _, document := range []string{
+			``,
+			`<a>`,
+			`<a></b>`,
+			`<a/><b/>`,
+			`<!DOCTYPE a [<!ENTITY e "e">]><a>&e;</a>`,
+		} {
+			_, err := parseXML([]byte(document))
+			require.ErrorIs(t, err, ErrMalformedXML, document)
+		}
+	})
+}
+
+func TestVerifySignature(t *testing.T) {
+	t.Parallel()
+
+	signer := newTestSigner(t)
+	certificates := []*x509.Certificate{signer.certificate}
+
+	document := `<p:Response xmlns:p="urn:p" xmlns:a="urn:a" ID="r1"><a:Assertion ID="a1">` +
+		`<a:Subject>alice</a:Subject></a:Assertion></p:Response>`
+
+	// assertionOf returns the assertion of the signed document
+	assertionOf := func(t *testing.T, signed string) *etree.Element {
+		t.Helper()
+
+		root, err := parseXML([]byte(signed))
+		require.NoError(t, err)
+
+		return child(root, "urn:a", "Assertion")
+	}
+
+	t.Run("verify signed element", func(t *testing.T) {
+		t.Parallel()
+
+		signed := signer.sign(t, document, "a1")
+
+		verified, err := verifySignature(assertionOf(t, signed), certificates)
+		require.NoError(t, err)
+		assert.True(t, is(verified, "urn:a", "Assertion"))
+		assert.Equal(t, "alice", text(child(verified, "urn:a", "Subject")))
+
+		root, err := parseXML([]byte(signed))
+		require.NoError(t, err)
+
+		_, err = verifySignature(root, certificates)
+		require.ErrorIs(t, err, ErrMissingSignature)
+	})
+
+	t.Run("read text as signed", func(t *testing.T) {
+		t.Parallel()
+
+		// comments are dropped by canonicalization, so they do not break the signature
+		signed := strings.Replace(signer.sign(t, document, "a1"), "alice", "ali<!---->ce", 1)
+
+		verified, err := verifySignature(assertionOf(t, signed), certificates)
+		require.NoError(t, err)
+		assert.Equal(t, "alice", text(child(verified, "urn:a", "Subject")))
+	})
+
+	t.Run("reject modified element", func(t *testing.T) {
+		t.Parallel()
+
+		signed := strings.Replace(signer.sign(t, document, "a1"), "alice", "mallory", 1)
+
+		_, err := verifySignature(assertionOf(t, signed), certificates)
+		require.ErrorIs(t, err, ErrInvalidSignature)
+	})
+
+	t.Run("reject signature of another key", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := verifySignature(assertionOf(t, newTestSigner(t).sign(t, document, "a1")), certificates)
+		require.ErrorIs(t, err, ErrInvalidSignature)
+	})
+
+	t.Run("reject signature referencing another element", func(t *testing.T) {
+		t.Parallel()
+
+		signed := strings.Replace(signer.sign(t, document, "a1"), `URI="#a1"`, `URI="#r1"`, 1)
+
+		_, err := verifySignature(assertionOf(t, signed), certificates)
+		require.Error(t, err)
+	})
+
+	t.Run("reject sha-1", func(t *testing.T) {
+		t.Parallel()
+
+		signed := strings.Replace(signer.sign(t, document, "a1"), dsig.RSASHA256SignatureMethod,
+			dsig.RSASHA1SignatureMethod, 1)
+
+		_, err := verifySignature(assertionOf(t, signed), certificates)
+		require.ErrorIs(t, err, ErrInvalidSignature)
+	})
+}
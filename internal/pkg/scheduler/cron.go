@@ -0,0 +1,256 @@
+package scheduler
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxSearchYears bounds the search for the next run, so that schedules never matching (e.g. February 30)
+// do not search forever.
+const maxSearchYears = 5
+
+// ErrInvalidSchedule is returned when a cron expression cannot be parsed.
+var ErrInvalidSchedule = errors.New("invalid cron expression")
+
+// macros is expressions of the supported macros.
+var macros = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+// field represents bounds and value names of a field of cron expressions.
+type field struct {
+	// name is name of the field in errors.
+	name string
+
+	// min is minimum value of the field.
+	min int
+
+	// max is maximum value of the field.
+	max int
+
+	// names is values by name, e.g. jan or mon.
+	names map[string]int
+}
+
+// fields of cron expressions in order.
+var (
+	minuteField = field{name: "minute", min: 0, max: 59}
+	hourField   = field{name: "hour", min: 0, max: 23}
+	dayField    = field{name: "day of month", min: 1, max: 31}
+	monthField  = field{name: "month", min: 1, max: 12, names: map[string]int{
+		"jan": 1, "feb": 2, "mar": 3, "apr": 4, "may": 5, "jun": 6,
+		"jul": 7, "aug": 8, "sep": 9, "oct": 10, "nov": 11, "dec": 12,
+	}}
+	weekdayField = field{name: "day of week", min: 0, max: 7, names: map[string]int{
+		"sun": 0, "mon": 1, "tue": 2, "wed": 3, "thu": 4, "fri": 5, "sat": 6,
+	}}
+)
+
+// Schedule returns times of runs of a recurring task.
+type Schedule interface {
+	// Next returns the first time of a run after the time, zero if there is none.
+	Next(after time.Time) time.Time
+}
+
+// cronSchedule is a schedule of a cron expression, fields are bit sets of matching values.
+type cronSchedule struct {
+	minute, hour, day, month, weekday uint64
+
+	// anyDay and anyWeekday are whether day of month and day of week are *, if both are restricted
+	// a day matching either runs, as in cron.
+	anyDay, anyWeekday bool
+
+	// location is time zone the expression is evaluated in.
+	location *time.Location
+}
+
+// everySchedule is a schedule of runs at a fixed interval, aligned to multiples of the interval
+// so that instances agree on times of runs.
+type everySchedule struct {
+	// interval is interval of runs.
+	interval time.Duration
+}
+
+// Parse parses the cron expression in the time zone: five fields (minute, hour, day of month, month and day of
+// week) of *, values, ranges (1-5), steps (*/15 or 0-30/10) and lists (1,15) with month and weekday names,
+// a macro (@yearly, @monthly, @weekly, @daily, @hourly) or @every with a duration (e.g. @every 30s).
+func Parse(expression string, location *time.Location) (Schedule, error) {
+	expression = strings.TrimSpace(expression)
+
+	if interval, ok := strings.CutPrefix(expression, "@every "); ok {
+		duration, err := time.ParseDuration(strings.TrimSpace(interval))
+		if err != nil || duration < time.Second {
+			return nil, fmt.Errorf("%w: %q, @every requires a duration of at least 1s", ErrInvalidSchedule, expression)
+		}
+
+		return &everySchedule{interval: duration}, nil
+	}
+
+	if macro, ok := macros[strings.ToLower(expression)]; ok {
+		expression = macro
+	}
+
+	parts := strings.Fields(expression)
+	if len(parts) != 5 {
+		return nil, fmt.Errorf("%w: %q, expected 5 fields", ErrInvalidSchedule, expression)
+	}
+
+	if location == nil {
+		location = time.UTC
+	}
+
+	schedule := &cronSchedule{
+		anyDay:     parts[2] == "*",
+		anyWeekday: parts[4] == "*",
+		location:   location,
+	}
+
+	targets := []*uint64{&schedule.minute, &schedule.hour, &schedule.day, &schedule.month, &schedule.weekday}
+
+	for i, field := range []field{minuteField, hourField, dayField, monthField, weekdayField} {
+		bits, err := field.parse(parts[i])
+		if err != nil {
+			return nil, fmt.Errorf("%w: %q, %w", ErrInvalidSchedule, expression, err)
+		}
+
+		*targets[i] = bits
+	}
+
+	// 7 is sunday as well
+	if schedule.weekday&(1<<7) != 0 {
+		schedule.weekday |= 1
+	}
+
+	return schedule, nil
+}
+
+// parse parses the list of the field into a bit set of matching values.
+func (f *field) parse(list string) (uint64, error) {
+	var bits uint64
+
+	for item := range strings.SplitSeq(list, ",") {
+		start, end, step, err := f.parseItem(item)
+		if err != nil {
+			return 0, err
+		}
+
+		for value := start; value <= end; value += step {
+			bits |= 1 << value
+		}
+	}
+
+	return bits, nil
+}
+
+// parseItem parses an item of a list of the field into a range and a step.
+func (f *field) parseItem(item string) (int, int, int, error) {
+	rangePart, stepPart, hasStep := strings.Cut(item, "/")
+
+	step := 1
+
+	if hasStep {
+		value, err := strconv.Atoi(stepPart)
+		if err != nil || value <= 0 {
+			return 0, 0, 0, fmt.Errorf("invalid step %q of %s", stepPart, f.name)
+		}
+
+		step = value
+	}
+
+	if rangePart == "*" {
+		return f.min, f.max, step, nil
+	}
+
+	startPart, endPart, isRange := strings.Cut(rangePart, "-")
+
+	start, err := f.parseValue(startPart)
+	if err != nil {
+		return 0, 0, 0, err
+	}
+
+	end := start
+
+	switch {
+	case isRange:
+		if end, err = f.parseValue(endPart); err != nil {
+			return 0, 0, 0, err
+		}
+	case hasStep:
+		// a value with a step (e.g. 5/15) runs from the value to the maximum
+		end = f.max
+	}
+
+	if start > end {
+		return 0, 0, 0, fmt.Errorf("invalid range %q of %s", rangePart, f.name)
+	}
+
+	return start, end, step, nil
+}
+
+// parseValue parses a value or a name of the field.
+func (f *field) parseValue(value string) (int, error) {
+	if named, ok := f.names[strings.ToLower(value)]; ok {
+		return named, nil
+	}
+
+	number, err := strconv.Atoi(value)
+	if err != nil || number < f.min || number > f.max {
+		return 0, fmt.Errorf("invalid value %q of %s, expected %d-%d", value, f.name, f.min, f.max)
+	}
+
+	return number, nil
+}
+
+// Next returns the first time matching the expression after the time, in the time zone of the schedule.
+func (s *cronSchedule) Next(after time.Time) time.Time {
+	// runs are at the start of minutes
+	next := after.In(s.location).Truncate(time.Minute).Add(time.Minute)
+	limit := next.AddDate(maxSearchYears, 0, 0)
+
+	for next.Before(limit) {
+		switch {
+		case !has(s.month, int(next.Month())):
+			next = time.Date(next.Year(), next.Month()+1, 1, 0, 0, 0, 0, s.location)
+		case !s.matchDay(next):
+			next = time.Date(next.Year(), next.Month(), next.Day()+1, 0, 0, 0, 0, s.location)
+		case !has(s.hour, next.Hour()):
+			next = time.Date(next.Year(), next.Month(), next.Day(), next.Hour()+1, 0, 0, 0, s.location)
+		case !has(s.minute, next.Minute()):
+			next = next.Add(time.Minute)
+		default:
+			return next
+		}
+	}
+
+	return time.Time{}
+}
+
+// matchDay returns whether the day of the time matches day of month and day of week.
+func (s *cronSchedule) matchDay(t time.Time) bool {
+	day, weekday := has(s.day, t.Day()), has(s.weekday, int(t.Weekday()))
+
+	if s.anyDay || s.anyWeekday {
+		return day && weekday
+	}
+
+	return day || weekday
+}
+
+// Next returns the first multiple of the interval (since the zero time) after the time.
+func (s *everySchedule) Next(after time.Time) time.Time {
+	return after.Truncate(s.interval).Add(s.interval)
+}
+
+// has returns whether the value is in the bit set.
+func has(bits uint64, value int) bool {
+	return bits&(1<<value) != 0
+}
@@ -0,0 +1,101 @@
+package scheduler
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParse(t *testing.T) {
+	t.Parallel()
+
+	t.Run("return error for invalid expressions", func(t *testing.T) {
+		t.Parallel()
+
+		expressions := []string{
+			"",
+			"* * * *",
+			"* * * * * *",
+			"60 * * * *",
+			"* 24 * * *",
+			"* * 0 * *",
+			"* * * 13 *",
+			"* * * * 8",
+			"*/0 * * * *",
+			"30-10 * * * *",
+			"a * * * *",
+			"@every 500ms",
+			"@every soon",
+			"@minutely",
+		}
+
+		for _, expression := range expressions {
+			_, err := Parse(expression, nil)
+			require.ErrorIs(t, err, ErrInvalidSchedule, expression)
+		}
+	})
+
+	t.Run("return next runs of expressions", func(t *testing.T) {
+		t.Parallel()
+
+		// a wednesday
+		after := time.Date(2026, time.January, 14, 10, 7, 30, 0, time.UTC)
+
+		tests := map[string]time.Time{
+			"* * * * *":          time.Date(2026, time.January, 14, 10, 8, 0, 0, time.UTC),
+			"*/15 * * * *":       time.Date(2026, time.January, 14, 10, 15, 0, 0, time.UTC),
+			"5/20 * * * *":       time.Date(2026, time.January, 14, 10, 25, 0, 0, time.UTC),
+			"0 9-17/4 * * *":     time.Date(2026, time.January, 14, 13, 0, 0, 0, time.UTC),
+			"30 2 * * *":         time.Date(2026, time.January, 15, 2, 30, 0, 0, time.UTC),
+			"0 0 1,15 * *":       time.Date(2026, time.January, 15, 0, 0, 0, 0, time.UTC),
+			"0 0 * feb *":        time.Date(2026, time.February, 1, 0, 0, 0, 0, time.UTC),
+			"0 8 * * mon-fri":    time.Date(2026, time.January, 15, 8, 0, 0, 0, time.UTC),
+			"0 0 * * 7":          time.Date(2026, time.January, 18, 0, 0, 0, 0, time.UTC),
+			"0 0 13 * fri":       time.Date(2026, time.January, 16, 0, 0, 0, 0, time.UTC),
+			"0 0 29 2 *":         time.Date(2028, time.February, 29, 0, 0, 0, 0, time.UTC),
+			"@hourly":            time.Date(2026, time.January, 14, 11, 0, 0, 0, time.UTC),
+			"@daily":             time.Date(2026, time.January, 15, 0, 0, 0, 0, time.UTC),
+			"@weekly":            time.Date(2026, time.January, 18, 0, 0, 0, 0, time.UTC),
+			"@monthly":           time.Date(2026, time.February, 1, 0, 0, 0, 0, time.UTC),
+			"@yearly":            time.Date(2027, time.January, 1, 0, 0, 0, 0, time.UTC),
+			"@every 10m":         time.Date(2026, time.January, 14, 10, 10, 0, 0, time.UTC),
+			"  0   12  *  *  * ": time.Date(2026, time.January, 14, 12, 0, 0, 0, time.UTC),
+		}
+
+		for expression, expected := range tests {
+			schedule, err := Parse(expression, nil)
+			require.NoError(t, err, expression)
+			assert.True(t, expected.Equal(schedule.Next(after)), "%s: %s", expression, schedule.Next(after))
+		}
+	})
+
+	t.Run("evaluate expressions in the time zone", func(t *testing.T) {
+		t.Parallel()
+
+		location, err := time.LoadLocation("Asia/Kolkata")
+		require.NoError(t, err)
+
+		schedule, err := Parse("0 9 * * *", location)
+		require.NoError(t, err)
+
+		next := schedule.Next(time.Date(2026, time.January, 14, 0, 0, 0, 0, time.UTC))
+		assert.True(t, time.Date(2026, time.January, 14, 3, 30, 0, 0, time.UTC).Equal(next), next.String())
+
+		// hours of time zones with offsets of half hours start on half hours
+		hourly, err := Parse("0 * * * *", location)
+		require.NoError(t, err)
+
+		next = hourly.Next(time.Date(2026, time.January, 14, 0, 10, 0, 0, time.UTC))
+		assert.True(t, time.Date(2026, time.January, 14, 0, 30, 0, 0, time.UTC).Equal(next), next.String())
+	})
+
+	t.Run("return zero time for expressions never matching", func(t *testing.T) {
+		t.Parallel()
+
+		schedule, err := Parse("0 0 30 2 *", nil)
+		require.NoError(t, err)
+		assert.True(t, schedule.Next(time.Now()).IsZero())
+	})
+}
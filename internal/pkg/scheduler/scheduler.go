@@ -0,0 +1,480 @@
+// Package scheduler provides recurring tasks run on cron schedules, each run of a task is run by a single instance
+// holding its redis lock, with a timeout and panic recovery, and its outcome is logged.
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	goredis "github.com/redis/go-redis/v9"
+	"github.com/rs/zerolog"
+	"go.uber.org/fx"
+
+	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/logger"
+	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/redis"
+)
+
+const (
+	// defaultPrefix is default prefix of names of locks and keys of tasks.
+	defaultPrefix = "scheduler:"
+
+	// defaultTimezone is default time zone cron expressions are evaluated in.
+	defaultTimezone = "UTC"
+
+	// defaultTimeout is default time a run of a task may take.
+	defaultTimeout = 5 * time.Minute
+
+	// defaultHistorySize is default number of runs kept in the history of a task.
+	defaultHistorySize = 20
+
+	// lastRunTTL is time the last run of a task is kept, so that keys of removed tasks expire.
+	lastRunTTL = 30 * 24 * time.Hour
+
+	// claimTimeout is time claiming a run may take.
+	claimTimeout = 5 * time.Second
+)
+
+var (
+	// ErrInvalidConfig is returned when the time zone is not known.
+	ErrInvalidConfig = errors.New("invalid scheduler config")
+
+	// ErrInvalidTask is returned when a task has no name or function, or is registered twice.
+	ErrInvalidTask = errors.New("invalid scheduled task")
+
+	// ErrUnknownTask is returned when no task of the name is registered.
+	ErrUnknownTask = errors.New("unknown scheduled task")
+
+	// ErrTaskRunning is returned when a task is triggered while it is running on any instance.
+	ErrTaskRunning = errors.New("scheduled task is running")
+
+	// errPanic is returned when a task panicked.
+	errPanic = errors.New("scheduled task panicked")
+)
+
+// Config represents configuration for the scheduler.
+type Config struct {
+	// Enabled is whether tasks are run on this instance.
+	Enabled *bool `json:"enabled"`
+
+	// Prefix is prefix of names of locks and keys of tasks on redis.
+	Prefix *string `json:"prefix"`
+
+	// Timezone is IANA time zone cron expressions are evaluated in, e.g. Asia/Seoul.
+	Timezone *string `json:"timezone"`
+
+	// DefaultTimeout is time a run of a task without a timeout may take.
+	DefaultTimeout *time.Duration `json:"default_timeout"`
+
+	// HistorySize is number of runs kept in the history of each task.
+	HistorySize *int `json:"history_size"`
+}
+
+// SetDefault sets default values.
+func (c *Config) SetDefault() {
+	if c.Enabled == nil {
+		c.Enabled = &[]bool{true}[0]
+	}
+
+	if c.Prefix == nil {
+		c.Prefix = &[]string{defaultPrefix}[0]
+	}
+
+	if c.Timezone == nil {
+		c.Timezone = &[]string{defaultTimezone}[0]
+	}
+
+	if c.DefaultTimeout == nil {
+		c.DefaultTimeout = &[]time.Duration{defaultTimeout}[0]
+	}
+
+	if c.HistorySize == nil {
+		c.HistorySize = &[]int{defaultHistorySize}[0]
+	}
+}
+
+// Task represents a recurring task, modules provide tasks in the scheduled_tasks group.
+type Task struct {
+	// Name is unique name of the task, naming its lock.
+	Name string
+
+	// Schedule is cron expression of the task, see Parse.
+	Schedule string
+
+	// Timeout is time a run may take, the default timeout if 0.
+	Timeout time.Duration
+
+	// Run runs the task, its context is canceled after the timeout, when the lock is lost or on shutdown.
+	Run func(ctx context.Context) error
+}
+
+// task represents a registered task.
+type task struct {
+	Task
+
+	// schedule returns times of runs of the task.
+	schedule Schedule
+}
+
+// Scheduler runs recurring tasks.
+type Scheduler struct {
+	// config provides scheduler configuration.
+	config *Config
+
+	// redis provides locks and last runs of tasks.
+	redis *redis.Redis
+
+	// logger provides logger.
+	logger *logger.Logger
+
+	// location is time zone cron expressions are evaluated in.
+	location *time.Location
+
+	// mu guards tasks, cancel, abort and done.
+	mu sync.Mutex
+
+	// tasks is registered tasks by name.
+	tasks map[string]*task
+
+	// cancel stops scheduling runs, nil if tasks are not running.
+	cancel context.CancelFunc
+
+	// abort cancels running runs, when the scheduler is stopped before they finish.
+	abort context.CancelFunc
+
+	// done is closed when runs return.
+	done chan struct{}
+
+	// triggered tracks runs triggered by Trigger.
+	triggered sync.WaitGroup
+
+	// triggerCtx is context of triggered runs, canceled by abortTriggered.
+	triggerCtx context.Context //nolint:containedctx // triggered runs outlive requests triggering them
+
+	// abortTriggered cancels triggered runs, when the scheduler is stopped before they finish.
+	abortTriggered context.CancelFunc
+
+	// now returns the current time, replaced in tests.
+	now func() time.Time
+}
+
+// TasksParams represents tasks provided by modules.
+type TasksParams struct {
+	fx.In
+
+	Scheduler *Scheduler
+	Tasks     []Task `group:"scheduled_tasks"`
+}
+
+// NewModule provides module for the scheduler.
+func NewModule() fx.Option {
+	return fx.Module("scheduler",
+		fx.Provide(New),
+		fx.Invoke(registerTasks),
+	)
+}
+
+// registerTasks registers tasks provided by modules, e.g. with
+// fx.Annotate(newCleanupTask, fx.ResultTags(`group:"scheduled_tasks"`)).
+func registerTasks(params TasksParams) error {
+	for _, task := range params.Tasks {
+		if err := params.Scheduler.Register(task); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// New creates a new scheduler.
+func New(config *Config, redisConn *redis.Redis, logger *logger.Logger) (*Scheduler, error) {
+	if config == nil {
+		config = &Config{}
+	}
+
+	config.SetDefault()
+
+	location, err := time.LoadLocation(*config.Timezone)
+	if err != nil {
+		return nil, fmt.Errorf("%w: timezone %q: %w", ErrInvalidConfig, *config.Timezone, err)
+	}
+
+	triggerCtx, abortTriggered := context.WithCancel(context.Background())
+
+	return &Scheduler{
+		config:         config,
+		redis:          redisConn,
+		logger:         logger.Named("scheduler"),
+		location:       location,
+		tasks:          map[string]*task{},
+		triggerCtx:     triggerCtx,
+		abortTriggered: abortTriggered,
+		now:            time.Now,
+	}, nil
+}
+
+// Enabled returns whether tasks are run on this instance.
+func (s *Scheduler) Enabled() bool {
+	return *s.config.Enabled
+}
+
+// Register registers the task, tasks registered after the scheduler started run from the next start.
+func (s *Scheduler) Register(registered Task) error {
+	if registered.Name == "" || registered.Run == nil {
+		return fmt.Errorf("%w: task requires a name and a function", ErrInvalidTask)
+	}
+
+	schedule, err := Parse(registered.Schedule, s.location)
+	if err != nil {
+		return fmt.Errorf("%w: task %s: %w", ErrInvalidTask, registered.Name, err)
+	}
+
+	if registered.Timeout <= 0 {
+		registered.Timeout = *s.config.DefaultTimeout
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.tasks[registered.Name]; ok {
+		return fmt.Errorf("%w: task %s is registered twice", ErrInvalidTask, registered.Name)
+	}
+
+	s.tasks[registered.Name] = &task{Task: registered, schedule: schedule}
+
+	return nil
+}
+
+// Start starts running tasks on their schedules, it does nothing if the scheduler is disabled.
+func (s *Scheduler) Start() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.Enabled() || s.cancel != nil {
+		return
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	runCtx, abort := context.WithCancel(context.Background())
+	s.cancel, s.abort, s.done = cancel, abort, make(chan struct{})
+
+	var loops sync.WaitGroup
+
+	for _, task := range s.tasks {
+		loops.Go(func() { s.loop(ctx, runCtx, task) })
+	}
+
+	done := s.done
+
+	go func() {
+		loops.Wait()
+		close(done)
+	}()
+}
+
+// Stop stops scheduling runs and waits for running and triggered runs until the context is done, then cancels
+// them.
+func (s *Scheduler) Stop(ctx context.Context) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	defer s.stopTriggered(ctx)
+
+	if s.cancel == nil {
+		return
+	}
+
+	s.cancel()
+
+	select {
+	case <-s.done:
+	case <-ctx.Done():
+		s.logger.Warn().Msg("canceling scheduled tasks not finished before shutdown")
+		s.abort()
+		<-s.done
+	}
+
+	s.abort()
+	s.cancel = nil
+}
+
+// stopTriggered waits for triggered runs until the context is done, then cancels them.
+func (s *Scheduler) stopTriggered(ctx context.Context) {
+	done := make(chan struct{})
+
+	go func() {
+		s.triggered.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-ctx.Done():
+		s.logger.Warn().Msg("canceling triggered tasks not finished before shutdown")
+		s.abortTriggered()
+		<-done
+	}
+}
+
+// loop runs the task at each time of its schedule until the context is done. Runs are not overlapped on an
+// instance, times passed while the task runs are skipped.
+func (s *Scheduler) loop(ctx, runCtx context.Context, task *task) {
+	for {
+		next := task.schedule.Next(s.now())
+		if next.IsZero() {
+			s.logger.Warn().Str("task", task.Name).Msg("scheduled task has no next run")
+
+			return
+		}
+
+		timer := time.NewTimer(next.Sub(s.now()))
+
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+
+			return
+		case <-timer.C:
+		}
+
+		s.run(runCtx, task, next)
+	}
+}
+
+// run runs the task for the scheduled time if it is not paused and no other instance holds its lock or ran it
+// for the time already.
+func (s *Scheduler) run(ctx context.Context, task *task, scheduledAt time.Time) {
+	log := s.logger.With().Str("task", task.Name).Time("scheduled_at", scheduledAt).Logger()
+
+	paused, err := s.paused(ctx, task.Name)
+	if err != nil {
+		log.Error().Err(err).Msg("failed to get pause of scheduled task")
+
+		return
+	}
+
+	if paused {
+		log.Debug().Msg("scheduled task is paused")
+
+		return
+	}
+
+	lock, err := s.redis.TryLock(ctx, *s.config.Prefix+task.Name, &redis.LockOptions{AutoExtend: true})
+	if errors.Is(err, redis.ErrLockNotAcquired) {
+		log.Debug().Msg("scheduled task is running on another instance")
+
+		return
+	}
+
+	if err != nil {
+		log.Error().Err(err).Msg("failed to lock scheduled task")
+
+		return
+	}
+
+	// the lock is released even if the run is canceled, so that other instances do not wait for it to expire
+	defer func() {
+		if err := lock.Release(context.WithoutCancel(ctx)); err != nil {
+			log.Warn().Err(err).Msg("failed to release lock of scheduled task")
+		}
+	}()
+
+	claimed, err := s.claim(ctx, task, scheduledAt)
+	if err != nil {
+		log.Error().Err(err).Msg("failed to claim scheduled task run")
+
+		return
+	}
+
+	if !claimed {
+		log.Debug().Msg("scheduled task already ran on another instance")
+
+		return
+	}
+
+	s.runLocked(ctx, task, lock, &Run{Trigger: TriggerSchedule, ScheduledAt: &scheduledAt}, &log)
+}
+
+// runLocked runs the task holding its lock, logs its outcome and records it in the history of the task.
+func (s *Scheduler) runLocked(ctx context.Context, task *task, lock *redis.Lock, run *Run, log *zerolog.Logger) {
+	start := time.Now()
+	err := s.execute(ctx, task, lock)
+	duration := time.Since(start)
+
+	run.StartedAt = start
+	run.DurationMs = duration.Milliseconds()
+	run.Fence = lock.Token()
+
+	if err != nil {
+		run.Error = err.Error()
+
+		log.Error().Err(err).Dur("duration", duration).Int64("fence", lock.Token()).Msg("scheduled task failed")
+	} else {
+		log.Info().Dur("duration", duration).Int64("fence", lock.Token()).Msg("scheduled task succeeded")
+	}
+
+	// the run is recorded even if it is canceled on shutdown
+	if err := s.record(context.WithoutCancel(ctx), task.Name, run); err != nil {
+		log.Warn().Err(err).Msg("failed to record scheduled task run")
+	}
+}
+
+// claim records the scheduled time as the last run of the task, returns false if the task already ran for the
+// time or a later one, since instances whose clocks lag may lock the task after the run of another instance.
+func (s *Scheduler) claim(ctx context.Context, task *task, scheduledAt time.Time) (bool, error) {
+	ctx, cancel := context.WithTimeout(ctx, claimTimeout)
+	defer cancel()
+
+	key := s.key(task.Name, "last_run")
+
+	last, err := s.redis.Get(ctx, key).Int64()
+	if err != nil && !errors.Is(err, goredis.Nil) {
+		return false, fmt.Errorf("failed to get last run: %w", err)
+	}
+
+	if last >= scheduledAt.UnixMilli() {
+		return false, nil
+	}
+
+	if err := s.redis.Set(ctx, key, strconv.FormatInt(scheduledAt.UnixMilli(), 10), lastRunTTL).Err(); err != nil {
+		return false, fmt.Errorf("failed to set last run: %w", err)
+	}
+
+	return true, nil
+}
+
+// execute runs the task with its timeout, canceling it if the lock is lost and recovering panics.
+func (s *Scheduler) execute(ctx context.Context, task *task, lock *redis.Lock) (err error) {
+	ctx, cancel := context.WithTimeout(ctx, task.Timeout)
+	defer cancel()
+
+	ctx, cancelCause := context.WithCancelCause(ctx)
+	defer cancelCause(nil)
+
+	go func() {
+		select {
+		case <-lock.Lost():
+			cancelCause(redis.ErrLockLost)
+		case <-ctx.Done():
+		}
+	}()
+
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			err = fmt.Errorf("%w: %v", errPanic, recovered)
+		}
+	}()
+
+	if err := task.Run(ctx); err != nil {
+		if cause := context.Cause(ctx); cause != nil && errors.Is(cause, redis.ErrLockLost) {
+			return fmt.Errorf("%w: %w", cause, err)
+		}
+
+		return err
+	}
+
+	return nil
+}
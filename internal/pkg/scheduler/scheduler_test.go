@@ -0,0 +1,291 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/logger"
+	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/redis"
+)
+
+var errTaskFailed = errors.New("task failed")
+
+// intervalSchedule is a schedule of runs every interval from now, shorter than @every allows.
+type intervalSchedule time.Duration
+
+// Next returns the time after the interval.
+func (s intervalSchedule) Next(after time.Time) time.Time {
+	return after.Add(time.Duration(s))
+}
+
+// setupTestScheduler creates a scheduler on the test redis server with keys prefixed by the test name.
+func setupTestScheduler(t *testing.T, prefix string) *Scheduler {
+	t.Helper()
+
+	log, err := logger.New(&logger.Config{})
+	require.NoError(t, err)
+
+	password := ""
+	redisDB := 0
+
+	redisClient, err := redis.New(&redis.Config{
+		Addrs:    []string{"localhost:36379"},
+		Password: &password,
+		DB:       &redisDB,
+	})
+	require.NoError(t, err)
+
+	t.Cleanup(func() {
+		_ = redisClient.Close()
+	})
+
+	scheduler, err := New(&Config{Prefix: &prefix}, redisClient, log)
+	require.NoError(t, err)
+
+	return scheduler
+}
+
+// testPrefix returns a prefix unique to the test.
+func testPrefix(t *testing.T) string {
+	t.Helper()
+
+	return fmt.Sprintf("scheduler:%s:%d:", t.Name(), time.Now().UnixNano())
+}
+
+// registerTestTask registers the task running the function every 20ms.
+func registerTestTask(t *testing.T, scheduler *Scheduler, timeout time.Duration, fn func(ctx context.Context) error) {
+	t.Helper()
+
+	require.NoError(t, scheduler.Register(Task{Name: "cleanup", Schedule: "@hourly", Timeout: timeout, Run: fn}))
+
+	scheduler.tasks["cleanup"].schedule = intervalSchedule(20 * time.Millisecond)
+}
+
+func TestConfigSetDefault(t *testing.T) {
+	t.Parallel()
+
+	config := &Config{}
+	config.SetDefault()
+
+	assert.True(t, *config.Enabled)
+	assert.Equal(t, defaultPrefix, *config.Prefix)
+	assert.Equal(t, defaultTimezone, *config.Timezone)
+	assert.Equal(t, defaultTimeout, *config.DefaultTimeout)
+	assert.Equal(t, defaultHistorySize, *config.HistorySize)
+}
+
+func TestNew(t *testing.T) {
+	t.Parallel()
+
+	log, err := logger.New(&logger.Config{})
+	require.NoError(t, err)
+
+	_, err = New(&Config{Timezone: &[]string{"Mars/Olympus"}[0]}, nil, log)
+	require.ErrorIs(t, err, ErrInvalidConfig)
+
+	scheduler, err := New(&Config{Timezone: &[]string{"Asia/Seoul"}[0]}, nil, log)
+	require.NoError(t, err)
+	assert.Equal(t, "Asia/Seoul", scheduler.location.String())
+}
+
+func TestRegister(t *testing.T) {
+	t.Parallel()
+
+	scheduler := setupTestScheduler(t, testPrefix(t))
+	run := func(context.Context) error { return nil }
+
+	require.ErrorIs(t, scheduler.Register(Task{Schedule: "@daily", Run: run}), ErrInvalidTask)
+	require.ErrorIs(t, scheduler.Register(Task{Name: "cleanup", Schedule: "@daily"}), ErrInvalidTask)
+	require.ErrorIs(t, scheduler.Register(Task{Name: "cleanup", Schedule: "daily", Run: run}), ErrInvalidSchedule)
+
+	require.NoError(t, scheduler.Register(Task{Name: "cleanup", Schedule: "@daily", Run: run}))
+	assert.Equal(t, defaultTimeout, scheduler.tasks["cleanup"].Timeout)
+
+	require.ErrorIs(t, scheduler.Register(Task{Name: "cleanup", Schedule: "@hourly", Run: run}), ErrInvalidTask)
+
+	require.NoError(t, registerTasks(TasksParams{
+		Scheduler: scheduler,
+		Tasks:     []Task{{Name: "report", Schedule: "0 9 * * mon", Run: run}},
+	}))
+	assert.Len(t, scheduler.tasks, 2)
+}
+
+func TestRun(t *testing.T) {
+	t.Parallel()
+
+	t.Run("run each scheduled time on a single instance", func(t *testing.T) {
+		t.Parallel()
+
+		prefix := testPrefix(t)
+		first, second := setupTestScheduler(t, prefix), setupTestScheduler(t, prefix)
+
+		var runs atomic.Int32
+
+		run := func(context.Context) error {
+			runs.Add(1)
+
+			return nil
+		}
+
+		for _, scheduler := range []*Scheduler{first, second} {
+			require.NoError(t, scheduler.Register(Task{Name: "cleanup", Schedule: "@hourly", Run: run}))
+		}
+
+		scheduledAt := time.Now().Truncate(time.Hour)
+
+		first.run(context.Background(), first.tasks["cleanup"], scheduledAt)
+		second.run(context.Background(), second.tasks["cleanup"], scheduledAt)
+		assert.Equal(t, int32(1), runs.Load())
+
+		second.run(context.Background(), second.tasks["cleanup"], scheduledAt.Add(time.Hour))
+		assert.Equal(t, int32(2), runs.Load())
+	})
+
+	t.Run("skip runs while another instance holds the lock", func(t *testing.T) {
+		t.Parallel()
+
+		prefix := testPrefix(t)
+		scheduler := setupTestScheduler(t, prefix)
+
+		var runs atomic.Int32
+
+		registerTestTask(t, scheduler, 0, func(context.Context) error {
+			runs.Add(1)
+
+			return nil
+		})
+
+		lock, err := scheduler.redis.TryLock(context.Background(), prefix+"cleanup", nil)
+		require.NoError(t, err)
+
+		scheduler.run(context.Background(), scheduler.tasks["cleanup"], time.Now())
+		assert.Zero(t, runs.Load())
+
+		require.NoError(t, lock.Release(context.Background()))
+
+		scheduler.run(context.Background(), scheduler.tasks["cleanup"], time.Now())
+		assert.Equal(t, int32(1), runs.Load())
+	})
+
+	t.Run("cancel runs after timeout", func(t *testing.T) {
+		t.Parallel()
+
+		scheduler := setupTestScheduler(t, testPrefix(t))
+		task := &task{Task: Task{Name: "cleanup", Timeout: 10 * time.Millisecond, Run: func(ctx context.Context) error {
+			<-ctx.Done()
+
+			return ctx.Err()
+		}}}
+
+		lock, err := scheduler.redis.TryLock(context.Background(), testPrefix(t), nil)
+		require.NoError(t, err)
+
+		require.ErrorIs(t, scheduler.execute(context.Background(), task, lock), context.DeadlineExceeded)
+	})
+
+	t.Run("recover panicking tasks", func(t *testing.T) {
+		t.Parallel()
+
+		scheduler := setupTestScheduler(t, testPrefix(t))
+		task := &task{Task: Task{Name: "cleanup", Timeout: time.Second, Run: func(context.Context) error {
+			panic("boom")
+		}}}
+
+		lock, err := scheduler.redis.TryLock(context.Background(), testPrefix(t), nil)
+		require.NoError(t, err)
+
+		err = scheduler.execute(context.Background(), task, lock)
+		require.ErrorIs(t, err, errPanic)
+		assert.Contains(t, err.Error(), "boom")
+	})
+
+	t.Run("return errors of tasks", func(t *testing.T) {
+		t.Parallel()
+
+		scheduler := setupTestScheduler(t, testPrefix(t))
+		task := &task{Task: Task{Name: "cleanup", Timeout: time.Second, Run: func(context.Context) error {
+			return errTaskFailed
+		}}}
+
+		lock, err := scheduler.redis.TryLock(context.Background(), testPrefix(t), nil)
+		require.NoError(t, err)
+
+		require.ErrorIs(t, scheduler.execute(context.Background(), task, lock), errTaskFailed)
+	})
+}
+
+func TestStartStop(t *testing.T) {
+	t.Parallel()
+
+	t.Run("run tasks on schedule until stopped", func(t *testing.T) {
+		t.Parallel()
+
+		scheduler := setupTestScheduler(t, testPrefix(t))
+
+		var runs atomic.Int32
+
+		registerTestTask(t, scheduler, 0, func(context.Context) error {
+			runs.Add(1)
+
+			return nil
+		})
+
+		scheduler.Start()
+		scheduler.Start()
+
+		require.Eventually(t, func() bool { return runs.Load() >= 2 }, 2*time.Second, 10*time.Millisecond)
+
+		scheduler.Stop(context.Background())
+		scheduler.Stop(context.Background())
+
+		stopped := runs.Load()
+
+		time.Sleep(60 * time.Millisecond)
+		assert.Equal(t, stopped, runs.Load())
+	})
+
+	t.Run("cancel runs not finished before stop deadline", func(t *testing.T) {
+		t.Parallel()
+
+		scheduler := setupTestScheduler(t, testPrefix(t))
+		started := make(chan struct{})
+
+		var canceled atomic.Bool
+
+		registerTestTask(t, scheduler, time.Minute, func(ctx context.Context) error {
+			close(started)
+			<-ctx.Done()
+			canceled.Store(true)
+
+			return ctx.Err()
+		})
+
+		scheduler.Start()
+		<-started
+
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+		defer cancel()
+
+		scheduler.Stop(ctx)
+		assert.True(t, canceled.Load())
+	})
+
+	t.Run("not start disabled scheduler", func(t *testing.T) {
+		t.Parallel()
+
+		scheduler := setupTestScheduler(t, testPrefix(t))
+		scheduler.config.Enabled = &[]bool{false}[0]
+
+		scheduler.Start()
+		assert.Nil(t, scheduler.cancel)
+
+		scheduler.Stop(context.Background())
+	})
+}
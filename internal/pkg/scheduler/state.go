@@ -0,0 +1,284 @@
+package scheduler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	goredis "github.com/redis/go-redis/v9"
+
+	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/redis"
+)
+
+const (
+	// TriggerSchedule is trigger of runs started by the schedule of the task.
+	TriggerSchedule = "schedule"
+
+	// TriggerManual is trigger of runs started by Trigger.
+	TriggerManual = "manual"
+)
+
+// Run represents a run of a task recorded in its history.
+type Run struct {
+	// Trigger is what started the run (schedule, manual).
+	Trigger string `json:"trigger"`
+
+	// ScheduledAt is the scheduled time of the run, nil if it was triggered.
+	ScheduledAt *time.Time `json:"scheduled_at,omitempty"`
+
+	// StartedAt is time the run started.
+	StartedAt time.Time `json:"started_at"`
+
+	// DurationMs is duration of the run in milliseconds.
+	DurationMs int64 `json:"duration_ms"`
+
+	// Fence is fencing token of the lock the run held.
+	Fence int64 `json:"fence"`
+
+	// Error is error of the run, empty if it succeeded.
+	Error string `json:"error,omitempty"`
+}
+
+// TaskState represents a registered task and its state shared by instances.
+type TaskState struct {
+	// Name is name of the task.
+	Name string `json:"name"`
+
+	// Schedule is cron expression of the task.
+	Schedule string `json:"schedule"`
+
+	// TimeoutMs is time a run may take in milliseconds.
+	TimeoutMs int64 `json:"timeout_ms"`
+
+	// Paused is whether scheduled runs of the task are skipped on all instances.
+	Paused bool `json:"paused"`
+
+	// NextRun is time of the next scheduled run, nil if the schedule has no next run.
+	NextRun *time.Time `json:"next_run,omitempty"`
+
+	// LastRun is the last recorded run, nil if the task has not run.
+	LastRun *Run `json:"last_run,omitempty"`
+}
+
+// Tasks returns states of registered tasks ordered by name.
+func (s *Scheduler) Tasks(ctx context.Context) ([]TaskState, error) {
+	s.mu.Lock()
+
+	tasks := make([]*task, 0, len(s.tasks))
+	for _, task := range s.tasks {
+		tasks = append(tasks, task)
+	}
+
+	s.mu.Unlock()
+
+	slices.SortFunc(tasks, func(a, b *task) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+
+	states := make([]TaskState, 0, len(tasks))
+
+	for _, task := range tasks {
+		state, err := s.state(ctx, task)
+		if err != nil {
+			return nil, err
+		}
+
+		states = append(states, state)
+	}
+
+	return states, nil
+}
+
+// Task returns state of the task of the name, ErrUnknownTask if it is not registered.
+func (s *Scheduler) Task(ctx context.Context, name string) (TaskState, error) {
+	task, err := s.task(name)
+	if err != nil {
+		return TaskState{}, err
+	}
+
+	return s.state(ctx, task)
+}
+
+// History returns recorded runs of the task of the name, the latest first.
+func (s *Scheduler) History(ctx context.Context, name string) ([]Run, error) {
+	if _, err := s.task(name); err != nil {
+		return nil, err
+	}
+
+	entries, err := s.redis.LRange(ctx, s.key(name, "history"), 0, -1).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get history of task %s: %w", name, err)
+	}
+
+	runs := make([]Run, 0, len(entries))
+
+	for _, entry := range entries {
+		var run Run
+		if err := json.Unmarshal([]byte(entry), &run); err != nil {
+			return nil, fmt.Errorf("failed to decode run of task %s: %w", name, err)
+		}
+
+		runs = append(runs, run)
+	}
+
+	return runs, nil
+}
+
+// Pause pauses the task of the name on all instances, its scheduled runs are skipped until it is resumed while
+// triggered runs still run.
+func (s *Scheduler) Pause(ctx context.Context, name string) error {
+	if _, err := s.task(name); err != nil {
+		return err
+	}
+
+	if err := s.redis.Set(ctx, s.key(name, "paused"), "1", 0).Err(); err != nil {
+		return fmt.Errorf("failed to pause task %s: %w", name, err)
+	}
+
+	s.logger.Info().Str("task", name).Msg("scheduled task paused")
+
+	return nil
+}
+
+// Resume resumes the paused task of the name on all instances from its next scheduled run.
+func (s *Scheduler) Resume(ctx context.Context, name string) error {
+	if _, err := s.task(name); err != nil {
+		return err
+	}
+
+	if err := s.redis.Del(ctx, s.key(name, "paused")).Err(); err != nil {
+		return fmt.Errorf("failed to resume task %s: %w", name, err)
+	}
+
+	s.logger.Info().Str("task", name).Msg("scheduled task resumed")
+
+	return nil
+}
+
+// Trigger runs the task of the name on this instance now, even if it is paused or the scheduler is disabled on
+// this instance. It returns once the lock of the task is held, ErrTaskRunning if another run holds it, while the
+// run continues in the background and is recorded in the history; the scheduled runs are unaffected.
+func (s *Scheduler) Trigger(ctx context.Context, name string) error {
+	task, err := s.task(name)
+	if err != nil {
+		return err
+	}
+
+	lock, err := s.redis.TryLock(ctx, *s.config.Prefix+task.Name, &redis.LockOptions{AutoExtend: true})
+	if errors.Is(err, redis.ErrLockNotAcquired) {
+		return fmt.Errorf("%w: %s", ErrTaskRunning, name)
+	}
+
+	if err != nil {
+		return fmt.Errorf("failed to lock task %s: %w", name, err)
+	}
+
+	log := s.logger.With().Str("task", task.Name).Str("trigger", TriggerManual).Logger()
+
+	// runs are added while holding the mutex, so that they are not added while Stop waits for them
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.triggered.Go(func() {
+		defer func() {
+			if err := lock.Release(context.WithoutCancel(s.triggerCtx)); err != nil {
+				log.Warn().Err(err).Msg("failed to release lock of scheduled task")
+			}
+		}()
+
+		s.runLocked(s.triggerCtx, task, lock, &Run{Trigger: TriggerManual}, &log)
+	})
+
+	return nil
+}
+
+// task returns the registered task of the name.
+func (s *Scheduler) task(name string) (*task, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	task, ok := s.tasks[name]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownTask, name)
+	}
+
+	return task, nil
+}
+
+// state returns state of the task.
+func (s *Scheduler) state(ctx context.Context, task *task) (TaskState, error) {
+	state := TaskState{
+		Name:      task.Name,
+		Schedule:  task.Schedule,
+		TimeoutMs: task.Timeout.Milliseconds(),
+	}
+
+	if next := task.schedule.Next(s.now()); !next.IsZero() {
+		state.NextRun = &next
+	}
+
+	paused, err := s.paused(ctx, task.Name)
+	if err != nil {
+		return TaskState{}, err
+	}
+
+	state.Paused = paused
+
+	entry, err := s.redis.LIndex(ctx, s.key(task.Name, "history"), 0).Result()
+	if errors.Is(err, goredis.Nil) {
+		return state, nil
+	}
+
+	if err != nil {
+		return TaskState{}, fmt.Errorf("failed to get last run of task %s: %w", task.Name, err)
+	}
+
+	var run Run
+	if err := json.Unmarshal([]byte(entry), &run); err != nil {
+		return TaskState{}, fmt.Errorf("failed to decode run of task %s: %w", task.Name, err)
+	}
+
+	state.LastRun = &run
+
+	return state, nil
+}
+
+// paused returns whether the task of the name is paused.
+func (s *Scheduler) paused(ctx context.Context, name string) (bool, error) {
+	count, err := s.redis.Exists(ctx, s.key(name, "paused")).Result()
+	if err != nil {
+		return false, fmt.Errorf("failed to get pause of task %s: %w", name, err)
+	}
+
+	return count > 0, nil
+}
+
+// record prepends the run to the history of the task, keeping the configured number of runs.
+func (s *Scheduler) record(ctx context.Context, name string, run *Run) error {
+	entry, err := json.Marshal(run)
+	if err != nil {
+		return fmt.Errorf("failed to encode run: %w", err)
+	}
+
+	key := s.key(name, "history")
+
+	pipe := s.redis.TxPipeline()
+	pipe.LPush(ctx, key, entry)
+	pipe.LTrim(ctx, key, 0, int64(*s.config.HistorySize)-1)
+	pipe.Expire(ctx, key, lastRunTTL)
+
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("failed to record run: %w", err)
+	}
+
+	return nil
+}
+
+// key returns the redis key of the state of the task of the name, keys of a task share a cluster slot.
+func (s *Scheduler) key(name, state string) string {
+	return *s.config.Prefix + "{" + name + "}:" + state
+}
@@ -0,0 +1,203 @@
+package scheduler
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPauseResume(t *testing.T) {
+	t.Parallel()
+
+	t.Run("skip scheduled runs of paused tasks on all instances", func(t *testing.T) {
+		t.Parallel()
+
+		prefix := testPrefix(t)
+		first, second := setupTestScheduler(t, prefix), setupTestScheduler(t, prefix)
+
+		var runs atomic.Int32
+
+		run := func(context.Context) error {
+			runs.Add(1)
+
+			return nil
+		}
+
+		for _, scheduler := range []*Scheduler{first, second} {
+			require.NoError(t, scheduler.Register(Task{Name: "cleanup", Schedule: "@hourly", Run: run}))
+		}
+
+		require.NoError(t, first.Pause(context.Background(), "cleanup"))
+
+		scheduledAt := time.Now().Truncate(time.Hour)
+
+		second.run(context.Background(), second.tasks["cleanup"], scheduledAt)
+		assert.Zero(t, runs.Load())
+
+		state, err := second.Task(context.Background(), "cleanup")
+		require.NoError(t, err)
+		assert.True(t, state.Paused)
+
+		require.NoError(t, first.Resume(context.Background(), "cleanup"))
+
+		second.run(context.Background(), second.tasks["cleanup"], scheduledAt)
+		assert.Equal(t, int32(1), runs.Load())
+	})
+
+	t.Run("reject unknown tasks", func(t *testing.T) {
+		t.Parallel()
+
+		scheduler := setupTestScheduler(t, testPrefix(t))
+
+		require.ErrorIs(t, scheduler.Pause(context.Background(), "missing"), ErrUnknownTask)
+		require.ErrorIs(t, scheduler.Resume(context.Background(), "missing"), ErrUnknownTask)
+		require.ErrorIs(t, scheduler.Trigger(context.Background(), "missing"), ErrUnknownTask)
+
+		_, err := scheduler.History(context.Background(), "missing")
+		require.ErrorIs(t, err, ErrUnknownTask)
+
+		_, err = scheduler.Task(context.Background(), "missing")
+		require.ErrorIs(t, err, ErrUnknownTask)
+	})
+}
+
+func TestTrigger(t *testing.T) {
+	t.Parallel()
+
+	t.Run("run paused tasks now and record them", func(t *testing.T) {
+		t.Parallel()
+
+		scheduler := setupTestScheduler(t, testPrefix(t))
+
+		var runs atomic.Int32
+
+		require.NoError(t, scheduler.Register(Task{Name: "cleanup", Schedule: "@daily", Run: func(context.Context) error {
+			runs.Add(1)
+
+			return errTaskFailed
+		}}))
+		require.NoError(t, scheduler.Pause(context.Background(), "cleanup"))
+
+		require.NoError(t, scheduler.Trigger(context.Background(), "cleanup"))
+		scheduler.Stop(context.Background())
+
+		assert.Equal(t, int32(1), runs.Load())
+
+		history, err := scheduler.History(context.Background(), "cleanup")
+		require.NoError(t, err)
+		require.Len(t, history, 1)
+		assert.Equal(t, TriggerManual, history[0].Trigger)
+		assert.Nil(t, history[0].ScheduledAt)
+		assert.Equal(t, errTaskFailed.Error(), history[0].Error)
+	})
+
+	t.Run("reject tasks running on any instance", func(t *testing.T) {
+		t.Parallel()
+
+		prefix := testPrefix(t)
+		scheduler := setupTestScheduler(t, prefix)
+		require.NoError(t, scheduler.Register(Task{Name: "cleanup", Schedule: "@daily", Run: func(context.Context) error {
+			return nil
+		}}))
+
+		lock, err := scheduler.redis.TryLock(context.Background(), prefix+"cleanup", nil)
+		require.NoError(t, err)
+
+		require.ErrorIs(t, scheduler.Trigger(context.Background(), "cleanup"), ErrTaskRunning)
+		require.NoError(t, lock.Release(context.Background()))
+	})
+
+	t.Run("cancel triggered runs not finished before stop deadline", func(t *testing.T) {
+		t.Parallel()
+
+		scheduler := setupTestScheduler(t, testPrefix(t))
+		started := make(chan struct{})
+
+		require.NoError(t, scheduler.Register(Task{
+			Name:     "cleanup",
+			Schedule: "@daily",
+			Timeout:  time.Minute,
+			Run: func(ctx context.Context) error {
+				close(started)
+				<-ctx.Done()
+
+				return ctx.Err()
+			},
+		}))
+
+		require.NoError(t, scheduler.Trigger(context.Background(), "cleanup"))
+		<-started
+
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+		defer cancel()
+
+		scheduler.Stop(ctx)
+
+		history, err := scheduler.History(context.Background(), "cleanup")
+		require.NoError(t, err)
+		require.Len(t, history, 1)
+		assert.Contains(t, history[0].Error, context.Canceled.Error())
+	})
+}
+
+func TestTasks(t *testing.T) {
+	t.Parallel()
+
+	t.Run("report next and last runs ordered by name", func(t *testing.T) {
+		t.Parallel()
+
+		scheduler := setupTestScheduler(t, testPrefix(t))
+		scheduler.now = func() time.Time { return time.Date(2025, 1, 1, 8, 30, 0, 0, time.UTC) }
+		run := func(context.Context) error { return nil }
+
+		require.NoError(t, scheduler.Register(Task{Name: "report", Schedule: "0 9 * * *", Run: run}))
+		require.NoError(t, scheduler.Register(Task{Name: "cleanup", Schedule: "@hourly", Timeout: time.Second, Run: run}))
+
+		scheduledAt := time.Now().Truncate(time.Hour)
+		scheduler.run(context.Background(), scheduler.tasks["cleanup"], scheduledAt)
+
+		states, err := scheduler.Tasks(context.Background())
+		require.NoError(t, err)
+		require.Len(t, states, 2)
+
+		assert.Equal(t, "cleanup", states[0].Name)
+		assert.Equal(t, "@hourly", states[0].Schedule)
+		assert.Equal(t, int64(1000), states[0].TimeoutMs)
+		assert.False(t, states[0].Paused)
+		require.NotNil(t, states[0].LastRun)
+		assert.Equal(t, TriggerSchedule, states[0].LastRun.Trigger)
+		assert.True(t, scheduledAt.Equal(*states[0].LastRun.ScheduledAt))
+		assert.Empty(t, states[0].LastRun.Error)
+
+		assert.Equal(t, "report", states[1].Name)
+		require.NotNil(t, states[1].NextRun)
+		assert.Equal(t, time.Date(2025, 1, 1, 9, 0, 0, 0, time.UTC), *states[1].NextRun)
+		assert.Nil(t, states[1].LastRun)
+	})
+
+	t.Run("keep history size runs latest first", func(t *testing.T) {
+		t.Parallel()
+
+		scheduler := setupTestScheduler(t, testPrefix(t))
+		scheduler.config.HistorySize = &[]int{2}[0]
+
+		require.NoError(t, scheduler.Register(Task{Name: "cleanup", Schedule: "@hourly", Run: func(context.Context) error {
+			return nil
+		}}))
+
+		scheduledAt := time.Now().Truncate(time.Hour)
+		for i := range 3 {
+			scheduler.run(context.Background(), scheduler.tasks["cleanup"], scheduledAt.Add(time.Duration(i)*time.Hour))
+		}
+
+		history, err := scheduler.History(context.Background(), "cleanup")
+		require.NoError(t, err)
+		require.Len(t, history, 2)
+		assert.True(t, scheduledAt.Add(2*time.Hour).Equal(*history[0].ScheduledAt))
+		assert.True(t, scheduledAt.Add(time.Hour).Equal(*history[1].ScheduledAt))
+	})
+}
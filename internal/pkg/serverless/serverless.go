@@ -0,0 +1,348 @@
+// Package serverless provides an adapter serving HTTP handlers from serverless events.
+package serverless
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-lambda-go/lambda"
+)
+
+var (
+	// ErrNilInit returned when the adapter is created without an init function.
+	ErrNilInit = errors.New("init function is nil")
+
+	// ErrNilHandler returned when the init function returns a nil handler.
+	ErrNilHandler = errors.New("init function returned nil handler")
+)
+
+const (
+	// apiGatewayV2Version is the payload version of API Gateway HTTP API events.
+	apiGatewayV2Version = "2.0"
+)
+
+// InitFunc builds the HTTP handler on the first invocation.
+type InitFunc func() (http.Handler, error)
+
+// Adapter represents an adapter translating API Gateway and ALB events into an HTTP handler.
+type Adapter struct {
+	init InitFunc
+
+	mu      sync.Mutex
+	handler http.Handler
+}
+
+// eventProbe represents fields used to detect the event type of a payload.
+type eventProbe struct {
+	Version        string `json:"version"`
+	RequestContext struct {
+		ELB *json.RawMessage `json:"elb"`
+	} `json:"requestContext"`
+}
+
+// New creates a new adapter, init is called on the first invocation and retried until it succeeds.
+func New(init InitFunc) (*Adapter, error) {
+	if init == nil {
+		return nil, ErrNilInit
+	}
+
+	return &Adapter{init: init}, nil
+}
+
+// Start starts the lambda runtime loop with the adapter, it never returns.
+func (a *Adapter) Start() {
+	lambda.Start(a.Handle)
+}
+
+// Handle handles a raw event by detecting whether it is an API Gateway REST, HTTP API or ALB event.
+func (a *Adapter) Handle(ctx context.Context, payload json.RawMessage) (interface{}, error) {
+	var probe eventProbe
+	if err := json.Unmarshal(payload, &probe); err != nil {
+		return nil, fmt.Errorf("failed to decode event: %w", err)
+	}
+
+	switch {
+	case probe.RequestContext.ELB != nil:
+		var event events.ALBTargetGroupRequest
+		if err := json.Unmarshal(payload, &event); err != nil {
+			return nil, fmt.Errorf("failed to decode alb event: %w", err)
+		}
+
+		return a.HandleALB(ctx, event)
+	case probe.Version == apiGatewayV2Version:
+		var event events.APIGatewayV2HTTPRequest
+		if err := json.Unmarshal(payload, &event); err != nil {
+			return nil, fmt.Errorf("failed to decode api gateway v2 event: %w", err)
+		}
+
+		return a.HandleAPIGatewayV2(ctx, event)
+	default:
+		var event events.APIGatewayProxyRequest
+		if err := json.Unmarshal(payload, &event); err != nil {
+			return nil, fmt.Errorf("failed to decode api gateway event: %w", err)
+		}
+
+		return a.HandleAPIGateway(ctx, event)
+	}
+}
+
+// HandleAPIGateway handles an API Gateway REST API event.
+func (a *Adapter) HandleAPIGateway(
+	ctx context.Context, event events.APIGatewayProxyRequest,
+) (events.APIGatewayProxyResponse, error) {
+	header := mergeHeaders(event.Headers, event.MultiValueHeaders)
+	query := mergeQuery(event.QueryStringParameters, event.MultiValueQueryStringParameters)
+
+	request, err := newRequest(
+		ctx, event.HTTPMethod, event.Path, query.Encode(), header,
+		event.Body, event.IsBase64Encoded, event.RequestContext.Identity.SourceIP,
+	)
+	if err != nil {
+		return events.APIGatewayProxyResponse{}, err
+	}
+
+	recorder, err := a.serve(request)
+	if err != nil {
+		return events.APIGatewayProxyResponse{}, err
+	}
+
+	body, isBase64 := encodeBody(recorder)
+
+	return events.APIGatewayProxyResponse{
+		StatusCode:        recorder.Code,
+		MultiValueHeaders: recorder.Header(),
+		Body:              body,
+		IsBase64Encoded:   isBase64,
+	}, nil
+}
+
+// HandleAPIGatewayV2 handles an API Gateway HTTP API (payload version 2.0) event.
+func (a *Adapter) HandleAPIGatewayV2(
+	ctx context.Context, event events.APIGatewayV2HTTPRequest,
+) (events.APIGatewayV2HTTPResponse, error) {
+	header := mergeHeaders(event.Headers, nil)
+	if len(event.Cookies) > 0 {
+		header.Set("Cookie", strings.Join(event.Cookies, "; "))
+	}
+
+	request, err := newRequest(
+		ctx, event.RequestContext.HTTP.Method, event.RawPath, event.RawQueryString, header,
+		event.Body, event.IsBase64Encoded, event.RequestContext.HTTP.SourceIP,
+	)
+	if err != nil {
+		return events.APIGatewayV2HTTPResponse{}, err
+	}
+
+	recorder, err := a.serve(request)
+	if err != nil {
+		return events.APIGatewayV2HTTPResponse{}, err
+	}
+
+	body, isBase64 := encodeBody(recorder)
+
+	// cookies are returned in a dedicated field on http api responses
+	responseHeader := recorder.Header().Clone()
+	cookies := responseHeader.Values("Set-Cookie")
+	responseHeader.Del("Set-Cookie")
+
+	return events.APIGatewayV2HTTPResponse{
+		StatusCode:        recorder.Code,
+		MultiValueHeaders: responseHeader,
+		Body:              body,
+		IsBase64Encoded:   isBase64,
+		Cookies:           cookies,
+	}, nil
+}
+
+// HandleALB handles an Application Load Balancer target group event.
+func (a *Adapter) HandleALB(
+	ctx context.Context, event events.ALBTargetGroupRequest,
+) (events.ALBTargetGroupResponse, error) {
+	header := mergeHeaders(event.Headers, event.MultiValueHeaders)
+	query := mergeQuery(event.QueryStringParameters, event.MultiValueQueryStringParameters)
+
+	request, err := newRequest(
+		ctx, event.HTTPMethod, event.Path, query.Encode(), header,
+		event.Body, event.IsBase64Encoded, "",
+	)
+	if err != nil {
+		return events.ALBTargetGroupResponse{}, err
+	}
+
+	recorder, err := a.serve(request)
+	if err != nil {
+		return events.ALBTargetGroupResponse{}, err
+	}
+
+	body, isBase64 := encodeBody(recorder)
+
+	response := events.ALBTargetGroupResponse{
+		StatusCode:        recorder.Code,
+		StatusDescription: fmt.Sprintf("%d %s", recorder.Code, http.StatusText(recorder.Code)),
+		Body:              body,
+		IsBase64Encoded:   isBase64,
+	}
+
+	// alb rejects multi value headers unless they are enabled on the target group
+	if len(event.MultiValueHeaders) > 0 {
+		response.MultiValueHeaders = recorder.Header()
+	} else {
+		response.Headers = flattenHeaders(recorder.Header())
+	}
+
+	return response, nil
+}
+
+// getHandler returns the HTTP handler, building it on the first call.
+func (a *Adapter) getHandler() (http.Handler, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.handler != nil {
+		return a.handler, nil
+	}
+
+	handler, err := a.init()
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize handler: %w", err)
+	}
+
+	if handler == nil {
+		return nil, ErrNilHandler
+	}
+
+	a.handler = handler
+
+	return handler, nil
+}
+
+// serve serves the request with the HTTP handler and records the response.
+func (a *Adapter) serve(request *http.Request) (*httptest.ResponseRecorder, error) {
+	handler, err := a.getHandler()
+	if err != nil {
+		return nil, err
+	}
+
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, request)
+
+	return recorder, nil
+}
+
+// newRequest creates an HTTP request from event fields.
+func newRequest(
+	ctx context.Context,
+	method, path, rawQuery string,
+	header http.Header,
+	body string,
+	isBase64 bool,
+	sourceIP string,
+) (*http.Request, error) {
+	payload := []byte(body)
+
+	if isBase64 {
+		decoded, err := base64.StdEncoding.DecodeString(body)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode body: %w", err)
+		}
+
+		payload = decoded
+	}
+
+	if path == "" {
+		path = "/"
+	}
+
+	target := &url.URL{Path: path, RawQuery: rawQuery}
+
+	request, err := http.NewRequestWithContext(ctx, method, target.RequestURI(), bytes.NewReader(payload))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	request.Header = header
+	request.RequestURI = target.RequestURI()
+	request.ContentLength = int64(len(payload))
+
+	if host := header.Get("Host"); host != "" {
+		request.Host = host
+	}
+
+	if sourceIP != "" {
+		request.RemoteAddr = net.JoinHostPort(sourceIP, "0")
+	}
+
+	return request, nil
+}
+
+// mergeHeaders merges single and multi value headers of an event.
+func mergeHeaders(single map[string]string, multi map[string][]string) http.Header {
+	header := http.Header{}
+
+	for key, values := range multi {
+		for _, value := range values {
+			header.Add(key, value)
+		}
+	}
+
+	for key, value := range single {
+		if _, ok := header[http.CanonicalHeaderKey(key)]; !ok {
+			header.Set(key, value)
+		}
+	}
+
+	return header
+}
+
+// mergeQuery merges single and multi value query parameters of an event.
+func mergeQuery(single map[string]string, multi map[string][]string) url.Values {
+	query := url.Values{}
+
+	for key, values := range multi {
+		for _, value := range values {
+			query.Add(key, value)
+		}
+	}
+
+	for key, value := range single {
+		if _, ok := query[key]; !ok {
+			query.Set(key, value)
+		}
+	}
+
+	return query
+}
+
+// flattenHeaders joins multi value headers into single values.
+func flattenHeaders(header http.Header) map[string]string {
+	flattened := make(map[string]string, len(header))
+
+	for key, values := range header {
+		flattened[key] = strings.Join(values, ",")
+	}
+
+	return flattened
+}
+
+// encodeBody returns the response body, base64 encoded when it is compressed or not valid UTF-8.
+func encodeBody(recorder *httptest.ResponseRecorder) (string, bool) {
+	body := recorder.Body.Bytes()
+
+	if recorder.Header().Get("Content-Encoding") != "" || !utf8.Valid(body) {
+		return base64.StdEncoding.EncodeToString(body), true
+	}
+
+	return string(body), false
+}
@@ -0,0 +1,237 @@
+package serverless
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var errInitFailed = errors.New("init failed")
+
+// echoHandler returns a handler writing the request method, path, query and body.
+func echoHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+
+		http.SetCookie(w, &http.Cookie{Name: "session", Value: "abc"})
+		w.Header().Set("X-Remote-Addr", r.RemoteAddr)
+		w.WriteHeader(http.StatusCreated)
+		_, _ = w.Write([]byte(r.Method + " " + r.URL.Path + "?" + r.URL.RawQuery + " " + r.Header.Get("X-Test") + " " + string(body)))
+	})
+}
+
+func TestNew(t *testing.T) {
+	t.Parallel()
+
+	t.Run("return error when init is nil", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := New(nil)
+		require.ErrorIs(t, err, ErrNilInit)
+	})
+}
+
+func TestLazyInit(t *testing.T) {
+	t.Parallel()
+
+	t.Run("initialize handler once on first invocation", func(t *testing.T) {
+		t.Parallel()
+
+		calls := 0
+
+		adapter, err := New(func() (http.Handler, error) {
+			calls++
+
+			return echoHandler(), nil
+		})
+		require.NoError(t, err)
+		assert.Equal(t, 0, calls)
+
+		for range 3 {
+			_, err := adapter.HandleAPIGateway(context.Background(), events.APIGatewayProxyRequest{HTTPMethod: http.MethodGet, Path: "/"})
+			require.NoError(t, err)
+		}
+
+		assert.Equal(t, 1, calls)
+	})
+
+	t.Run("retry init after failure", func(t *testing.T) {
+		t.Parallel()
+
+		calls := 0
+
+		adapter, err := New(func() (http.Handler, error) {
+			calls++
+			if calls == 1 {
+				return nil, errInitFailed
+			}
+
+			return echoHandler(), nil
+		})
+		require.NoError(t, err)
+
+		_, err = adapter.HandleAPIGateway(context.Background(), events.APIGatewayProxyRequest{HTTPMethod: http.MethodGet, Path: "/"})
+		require.ErrorIs(t, err, errInitFailed)
+
+		response, err := adapter.HandleAPIGateway(context.Background(), events.APIGatewayProxyRequest{HTTPMethod: http.MethodGet, Path: "/"})
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusCreated, response.StatusCode)
+	})
+
+	t.Run("return error when init returns nil handler", func(t *testing.T) {
+		t.Parallel()
+
+		adapter, err := New(func() (http.Handler, error) { return nil, nil })
+		require.NoError(t, err)
+
+		_, err = adapter.HandleAPIGateway(context.Background(), events.APIGatewayProxyRequest{HTTPMethod: http.MethodGet, Path: "/"})
+		require.ErrorIs(t, err, ErrNilHandler)
+	})
+}
+
+func TestHandleAPIGateway(t *testing.T) {
+	t.Parallel()
+
+	adapter, err := New(func() (http.Handler, error) { return echoHandler(), nil })
+	require.NoError(t, err)
+
+	t.Run("translate rest api event", func(t *testing.T) {
+		t.Parallel()
+
+		event := events.APIGatewayProxyRequest{
+			HTTPMethod:                      http.MethodPost,
+			Path:                            "/api/items",
+			Headers:                         map[string]string{"X-Test": "1"},
+			MultiValueQueryStringParameters: map[string][]string{"tag": {"a", "b"}},
+			Body:                            base64.StdEncoding.EncodeToString([]byte("payload")),
+			IsBase64Encoded:                 true,
+			RequestContext: events.APIGatewayProxyRequestContext{
+				Identity: events.APIGatewayRequestIdentity{SourceIP: "203.0.113.1"},
+			},
+		}
+
+		response, err := adapter.HandleAPIGateway(context.Background(), event)
+		require.NoError(t, err)
+
+		assert.Equal(t, http.StatusCreated, response.StatusCode)
+		assert.Equal(t, "POST /api/items?tag=a&tag=b 1 payload", response.Body)
+		assert.False(t, response.IsBase64Encoded)
+		assert.Equal(t, []string{"203.0.113.1:0"}, response.MultiValueHeaders["X-Remote-Addr"])
+	})
+
+	t.Run("return error for invalid base64 body", func(t *testing.T) {
+		t.Parallel()
+
+		event := events.APIGatewayProxyRequest{HTTPMethod: http.MethodPost, Path: "/", Body: "!", IsBase64Encoded: true}
+
+		_, err := adapter.HandleAPIGateway(context.Background(), event)
+		require.Error(t, err)
+	})
+}
+
+func TestHandleAPIGatewayV2(t *testing.T) {
+	t.Parallel()
+
+	adapter, err := New(func() (http.Handler, error) { return echoHandler(), nil })
+	require.NoError(t, err)
+
+	t.Run("translate http api event and move cookies", func(t *testing.T) {
+		t.Parallel()
+
+		event := events.APIGatewayV2HTTPRequest{
+			RawPath:        "/api/items",
+			RawQueryString: "page=2",
+			Headers:        map[string]string{"x-test": "2"},
+			Body:           "payload",
+			RequestContext: events.APIGatewayV2HTTPRequestContext{
+				HTTP: events.APIGatewayV2HTTPRequestContextHTTPDescription{Method: http.MethodPut},
+			},
+		}
+
+		response, err := adapter.HandleAPIGatewayV2(context.Background(), event)
+		require.NoError(t, err)
+
+		assert.Equal(t, "PUT /api/items?page=2 2 payload", response.Body)
+		assert.Equal(t, []string{"session=abc"}, response.Cookies)
+		assert.NotContains(t, response.MultiValueHeaders, "Set-Cookie")
+	})
+}
+
+func TestHandleALB(t *testing.T) {
+	t.Parallel()
+
+	adapter, err := New(func() (http.Handler, error) { return echoHandler(), nil })
+	require.NoError(t, err)
+
+	t.Run("translate alb event with single value headers", func(t *testing.T) {
+		t.Parallel()
+
+		event := events.ALBTargetGroupRequest{
+			HTTPMethod:            http.MethodGet,
+			Path:                  "/status",
+			QueryStringParameters: map[string]string{"q": "x"},
+		}
+
+		response, err := adapter.HandleALB(context.Background(), event)
+		require.NoError(t, err)
+
+		assert.Equal(t, "201 Created", response.StatusDescription)
+		assert.Equal(t, "GET /status?q=x  ", response.Body)
+		assert.Equal(t, "session=abc", response.Headers["Set-Cookie"])
+		assert.Nil(t, response.MultiValueHeaders)
+	})
+}
+
+func TestHandle(t *testing.T) {
+	t.Parallel()
+
+	adapter, err := New(func() (http.Handler, error) { return echoHandler(), nil })
+	require.NoError(t, err)
+
+	tests := []struct {
+		name     string
+		payload  string
+		expected interface{}
+	}{
+		{
+			name:     "detect rest api event",
+			payload:  `{"httpMethod":"GET","path":"/rest"}`,
+			expected: events.APIGatewayProxyResponse{},
+		},
+		{
+			name:     "detect http api event",
+			payload:  `{"version":"2.0","rawPath":"/http","requestContext":{"http":{"method":"GET"}}}`,
+			expected: events.APIGatewayV2HTTPResponse{},
+		},
+		{
+			name:     "detect alb event",
+			payload:  `{"httpMethod":"GET","path":"/alb","requestContext":{"elb":{"targetGroupArn":"arn"}}}`,
+			expected: events.ALBTargetGroupResponse{},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			response, err := adapter.Handle(context.Background(), json.RawMessage(test.payload))
+			require.NoError(t, err)
+			assert.IsType(t, test.expected, response)
+		})
+	}
+
+	t.Run("return error for invalid payload", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := adapter.Handle(context.Background(), json.RawMessage(`not json`))
+		require.Error(t, err)
+	})
+}
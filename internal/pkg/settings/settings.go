@@ -0,0 +1,473 @@
+// Package settings provides per-user and global application settings
+// stored on database and cached on redis and in memory.
+package settings
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	goredis "github.com/redis/go-redis/v9"
+	"go.uber.org/fx"
+
+	"github.com/pocj8ur4in/boilerplate-go/internal/gen/db"
+	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/database"
+	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/logger"
+	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/redis"
+)
+
+var (
+	// ErrNotFound returned when the setting does not exist.
+	ErrNotFound = errors.New("setting not found")
+
+	// ErrInvalidKey returned when the setting key is invalid.
+	ErrInvalidKey = errors.New("invalid setting key")
+
+	// ErrInvalidValue returned when the setting value is not valid JSON.
+	ErrInvalidValue = errors.New("invalid setting value")
+
+	// ErrTypeMismatch returned when the setting value does not decode into the requested type.
+	ErrTypeMismatch = errors.New("setting type mismatch")
+)
+
+const (
+	// GlobalScope is scope of settings shared by all users.
+	GlobalScope Scope = "global"
+
+	// userScopePrefix is prefix of user scopes.
+	userScopePrefix = "user:"
+
+	// cacheKeyPrefix is the redis key prefix of cached settings.
+	cacheKeyPrefix = "settings:value:"
+
+	// cachedMissing is the cached value of settings that do not exist, a JSON value is never empty.
+	cachedMissing = ""
+
+	// maxKeyLength is maximum length of setting keys.
+	maxKeyLength = 128
+)
+
+// keyPattern matches valid setting keys.
+var keyPattern = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9._-]*$`)
+
+// Scope is scope of settings, either GlobalScope or a user scope.
+type Scope string
+
+// UserScope returns scope of settings of the user.
+func UserScope(userID string) Scope {
+	return Scope(userScopePrefix + userID)
+}
+
+// Config represents configuration for settings.
+type Config struct {
+	// CacheTTL is TTL of settings cached on redis.
+	CacheTTL *time.Duration `json:"cache_ttl"`
+
+	// LocalCacheTTL is TTL of settings cached in memory, it bounds staleness if an invalidation is missed.
+	LocalCacheTTL *time.Duration `json:"local_cache_ttl"`
+
+	// Channel is redis pub/sub channel of cache invalidations.
+	Channel *string `json:"channel"`
+}
+
+const (
+	// defaultCacheTTL is default TTL of settings cached on redis.
+	defaultCacheTTL = 5 * time.Minute
+
+	// defaultLocalCacheTTL is default TTL of settings cached in memory.
+	defaultLocalCacheTTL = 30 * time.Second
+
+	// defaultChannel is default invalidation channel.
+	defaultChannel = "settings:invalidate"
+)
+
+// SetDefault sets default values.
+func (c *Config) SetDefault() {
+	if c.CacheTTL == nil {
+		cacheTTL := defaultCacheTTL
+		c.CacheTTL = &cacheTTL
+	}
+
+	if c.LocalCacheTTL == nil {
+		localCacheTTL := defaultLocalCacheTTL
+		c.LocalCacheTTL = &localCacheTTL
+	}
+
+	if c.Channel == nil {
+		channel := defaultChannel
+		c.Channel = &channel
+	}
+}
+
+// localEntry is a setting cached in memory.
+type localEntry struct {
+	// value is value of the setting, nil if it does not exist.
+	value json.RawMessage
+
+	// expiresAt is time the entry expires.
+	expiresAt time.Time
+}
+
+// Settings provides settings, reads go through an in-memory cache, then redis, then database.
+// Writes invalidate the redis cache and publish an invalidation so every instance drops its in-memory copy.
+type Settings struct {
+	// config provides settings configuration.
+	config *Config
+
+	// queries provides database queries.
+	queries db.Querier
+
+	// redis provides redis client.
+	redis *redis.Redis
+
+	// logger provides logger.
+	logger *logger.Logger
+
+	// mu guards local.
+	mu sync.RWMutex
+
+	// local is settings cached in memory by cache key.
+	local map[string]localEntry
+
+	// pubsub is subscription of the invalidation channel.
+	pubsub *goredis.PubSub
+
+	// wg waits for the invalidation loop to exit.
+	wg sync.WaitGroup
+}
+
+// NewModule provides module for settings.
+func NewModule() fx.Option {
+	return fx.Module("settings",
+		fx.Provide(New),
+	)
+}
+
+// New creates settings and subscribes to cache invalidations.
+func New(config *Config, dbConn *database.DB, redis *redis.Redis, logger *logger.Logger) (*Settings, error) {
+	return NewWithQuerier(config, dbConn.Queries, redis, logger)
+}
+
+// NewWithQuerier creates settings stored using the querier.
+func NewWithQuerier(config *Config, queries db.Querier, redis *redis.Redis, logger *logger.Logger) (*Settings, error) {
+	if config == nil {
+		config = &Config{}
+	}
+
+	config.SetDefault()
+
+	settings := &Settings{
+		config:  config,
+		queries: queries,
+		redis:   redis,
+		logger:  logger.Named("settings"),
+		local:   make(map[string]localEntry),
+	}
+
+	pubsub := redis.Subscribe(context.Background(), *config.Channel)
+
+	// wait for the subscription so invalidations published after New are received
+	if _, err := pubsub.Receive(context.Background()); err != nil {
+		_ = pubsub.Close()
+
+		return nil, fmt.Errorf("failed to subscribe to settings invalidations: %w", err)
+	}
+
+	settings.pubsub = pubsub
+
+	settings.wg.Add(1)
+
+	go settings.invalidate()
+
+	return settings, nil
+}
+
+// Close unsubscribes from cache invalidations.
+func (s *Settings) Close() error {
+	err := s.pubsub.Close()
+	s.wg.Wait()
+
+	if err != nil {
+		return fmt.Errorf("failed to close settings subscription: %w", err)
+	}
+
+	return nil
+}
+
+// invalidate drops settings from the in-memory cache as invalidations are received until closed.
+func (s *Settings) invalidate() {
+	defer s.wg.Done()
+
+	for message := range s.pubsub.ChannelWithSubscriptions() {
+		switch message := message.(type) {
+		case *goredis.Message:
+			s.mu.Lock()
+			delete(s.local, message.Payload)
+			s.mu.Unlock()
+		case *goredis.Subscription:
+			// invalidations may have been missed while reconnecting
+			s.mu.Lock()
+			clear(s.local)
+			s.mu.Unlock()
+		}
+	}
+}
+
+// Get returns the raw JSON value of the setting, ErrNotFound if it does not exist.
+func (s *Settings) Get(ctx context.Context, scope Scope, key string) (json.RawMessage, error) {
+	if err := ValidateKey(key); err != nil {
+		return nil, err
+	}
+
+	cacheKey := cacheKeyPrefix + string(scope) + ":" + key
+
+	value, err := s.load(ctx, scope, key, cacheKey)
+	if err != nil {
+		return nil, err
+	}
+
+	if value == nil {
+		return nil, ErrNotFound
+	}
+
+	return value, nil
+}
+
+// load returns the value of the setting from the first cache tier holding it, nil if it does not exist.
+func (s *Settings) load(ctx context.Context, scope Scope, key, cacheKey string) (json.RawMessage, error) {
+	// check in-memory cache first
+	s.mu.RLock()
+	entry, ok := s.local[cacheKey]
+	s.mu.RUnlock()
+
+	if ok && time.Now().Before(entry.expiresAt) {
+		return entry.value, nil
+	}
+
+	// check redis cache, an empty value means the setting does not exist
+	cached, err := s.redis.Get(ctx, cacheKey).Result()
+	if err == nil {
+		var value json.RawMessage
+		if cached != cachedMissing {
+			value = json.RawMessage(cached)
+		}
+
+		s.storeLocal(cacheKey, value)
+
+		return value, nil
+	}
+
+	if !errors.Is(err, goredis.Nil) {
+		// redis is a cache, fall back to database
+		s.logger.Warn().Err(err).Str("key", cacheKey).Msg("failed to get cached setting")
+	}
+
+	// load from database
+	var value json.RawMessage
+
+	row, err := s.queries.GetSetting(ctx, &db.GetSettingParams{Scope: string(scope), Key: key})
+
+	switch {
+	case err == nil:
+		value = json.RawMessage(row.Value)
+	case errors.Is(err, pgx.ErrNoRows):
+		value = nil
+	default:
+		return nil, fmt.Errorf("failed to get setting: %w", err)
+	}
+
+	cached = cachedMissing
+	if value != nil {
+		cached = string(value)
+	}
+
+	if err := s.redis.Set(ctx, cacheKey, cached, *s.config.CacheTTL).Err(); err != nil {
+		s.logger.Warn().Err(err).Str("key", cacheKey).Msg("failed to cache setting")
+	}
+
+	s.storeLocal(cacheKey, value)
+
+	return value, nil
+}
+
+// storeLocal caches the value of the setting in memory.
+func (s *Settings) storeLocal(cacheKey string, value json.RawMessage) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.local[cacheKey] = localEntry{
+		value:     value,
+		expiresAt: time.Now().Add(*s.config.LocalCacheTTL),
+	}
+}
+
+// Resolve returns the value of the setting of the user, or the global setting if the user has not set it.
+func (s *Settings) Resolve(ctx context.Context, userID, key string) (json.RawMessage, Scope, error) {
+	value, err := s.Get(ctx, UserScope(userID), key)
+	if err == nil {
+		return value, UserScope(userID), nil
+	}
+
+	if !errors.Is(err, ErrNotFound) {
+		return nil, "", err
+	}
+
+	value, err = s.Get(ctx, GlobalScope, key)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return value, GlobalScope, nil
+}
+
+// Decode decodes the value of the setting into target, ErrNotFound if it does not exist.
+func (s *Settings) Decode(ctx context.Context, scope Scope, key string, target any) error {
+	value, err := s.Get(ctx, scope, key)
+	if err != nil {
+		return err
+	}
+
+	if err := json.Unmarshal(value, target); err != nil {
+		return fmt.Errorf("%w: %s: %w", ErrTypeMismatch, key, err)
+	}
+
+	return nil
+}
+
+// GetString returns the string setting, or fallback if it does not exist.
+func (s *Settings) GetString(ctx context.Context, scope Scope, key, fallback string) (string, error) {
+	return getOrFallback(ctx, s, scope, key, fallback)
+}
+
+// GetInt returns the integer setting, or fallback if it does not exist.
+func (s *Settings) GetInt(ctx context.Context, scope Scope, key string, fallback int) (int, error) {
+	return getOrFallback(ctx, s, scope, key, fallback)
+}
+
+// GetBool returns the boolean setting, or fallback if it does not exist.
+func (s *Settings) GetBool(ctx context.Context, scope Scope, key string, fallback bool) (bool, error) {
+	return getOrFallback(ctx, s, scope, key, fallback)
+}
+
+// getOrFallback decodes the setting, or returns fallback if it does not exist.
+func getOrFallback[T any](ctx context.Context, s *Settings, scope Scope, key string, fallback T) (T, error) {
+	var value T
+
+	err := s.Decode(ctx, scope, key, &value)
+
+	switch {
+	case err == nil:
+		return value, nil
+	case errors.Is(err, ErrNotFound):
+		return fallback, nil
+	default:
+		return fallback, err
+	}
+}
+
+// List returns raw JSON values of all settings of the scope by key.
+func (s *Settings) List(ctx context.Context, scope Scope) (map[string]json.RawMessage, error) {
+	rows, err := s.queries.ListSettings(ctx, string(scope))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list settings: %w", err)
+	}
+
+	settings := make(map[string]json.RawMessage, len(rows))
+	for _, row := range rows {
+		settings[row.Key] = json.RawMessage(row.Value)
+	}
+
+	return settings, nil
+}
+
+// Set stores the value of the setting encoded as JSON.
+func (s *Settings) Set(ctx context.Context, scope Scope, key string, value any) error {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrInvalidValue, err)
+	}
+
+	return s.SetRaw(ctx, scope, key, data)
+}
+
+// SetRaw stores the raw JSON value of the setting.
+func (s *Settings) SetRaw(ctx context.Context, scope Scope, key string, value json.RawMessage) error {
+	if err := ValidateKey(key); err != nil {
+		return err
+	}
+
+	if !json.Valid(value) {
+		return ErrInvalidValue
+	}
+
+	if _, err := s.queries.UpsertSetting(ctx, &db.UpsertSettingParams{
+		Scope: string(scope),
+		Key:   key,
+		Value: value,
+	}); err != nil {
+		return fmt.Errorf("failed to set setting: %w", err)
+	}
+
+	return s.invalidateKey(ctx, scope, key)
+}
+
+// SetString stores the string setting.
+func (s *Settings) SetString(ctx context.Context, scope Scope, key, value string) error {
+	return s.Set(ctx, scope, key, value)
+}
+
+// SetInt stores the integer setting.
+func (s *Settings) SetInt(ctx context.Context, scope Scope, key string, value int) error {
+	return s.Set(ctx, scope, key, value)
+}
+
+// SetBool stores the boolean setting.
+func (s *Settings) SetBool(ctx context.Context, scope Scope, key string, value bool) error {
+	return s.Set(ctx, scope, key, value)
+}
+
+// Delete removes the setting, deleting a setting that does not exist is not an error.
+func (s *Settings) Delete(ctx context.Context, scope Scope, key string) error {
+	if err := ValidateKey(key); err != nil {
+		return err
+	}
+
+	if err := s.queries.DeleteSetting(ctx, &db.DeleteSettingParams{Scope: string(scope), Key: key}); err != nil {
+		return fmt.Errorf("failed to delete setting: %w", err)
+	}
+
+	return s.invalidateKey(ctx, scope, key)
+}
+
+// invalidateKey removes the setting from caches of all instances.
+func (s *Settings) invalidateKey(ctx context.Context, scope Scope, key string) error {
+	cacheKey := cacheKeyPrefix + string(scope) + ":" + key
+
+	s.mu.Lock()
+	delete(s.local, cacheKey)
+	s.mu.Unlock()
+
+	if err := s.redis.Del(ctx, cacheKey).Err(); err != nil {
+		return fmt.Errorf("failed to invalidate cached setting: %w", err)
+	}
+
+	if err := s.redis.Publish(ctx, *s.config.Channel, cacheKey).Err(); err != nil {
+		return fmt.Errorf("failed to publish setting invalidation: %w", err)
+	}
+
+	return nil
+}
+
+// ValidateKey returns ErrInvalidKey if the key is not a valid setting key.
+func ValidateKey(key string) error {
+	if len(key) > maxKeyLength || !keyPattern.MatchString(key) {
+		return fmt.Errorf("%w: %q", ErrInvalidKey, key)
+	}
+
+	return nil
+}
@@ -0,0 +1,338 @@
+package settings
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pocj8ur4in/boilerplate-go/internal/gen/db"
+	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/logger"
+	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/redis"
+)
+
+var errQueryFailed = errors.New("query failed")
+
+// mockSettingsQuerier is a mock querier serving settings from memory.
+type mockSettingsQuerier struct {
+	db.Querier
+
+	mu       sync.Mutex
+	settings map[string][]byte
+	gets     int
+	err      error
+}
+
+func newMockSettingsQuerier() *mockSettingsQuerier {
+	return &mockSettingsQuerier{settings: make(map[string][]byte)}
+}
+
+func (m *mockSettingsQuerier) GetSetting(_ context.Context, arg *db.GetSettingParams) (*db.Setting, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.gets++
+
+	if m.err != nil {
+		return nil, m.err
+	}
+
+	value, ok := m.settings[arg.Scope+"|"+arg.Key]
+	if !ok {
+		return nil, pgx.ErrNoRows
+	}
+
+	return &db.Setting{Scope: arg.Scope, Key: arg.Key, Value: value}, nil
+}
+
+func (m *mockSettingsQuerier) ListSettings(_ context.Context, scope string) ([]*db.Setting, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	rows := []*db.Setting{}
+
+	for id, value := range m.settings {
+		if len(id) > len(scope) && id[:len(scope)+1] == scope+"|" {
+			rows = append(rows, &db.Setting{Scope: scope, Key: id[len(scope)+1:], Value: value})
+		}
+	}
+
+	return rows, nil
+}
+
+func (m *mockSettingsQuerier) UpsertSetting(_ context.Context, arg *db.UpsertSettingParams) (*db.Setting, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.settings[arg.Scope+"|"+arg.Key] = arg.Value
+
+	return &db.Setting{Scope: arg.Scope, Key: arg.Key, Value: arg.Value}, nil
+}
+
+func (m *mockSettingsQuerier) DeleteSetting(_ context.Context, arg *db.DeleteSettingParams) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delete(m.settings, arg.Scope+"|"+arg.Key)
+
+	return nil
+}
+
+func (m *mockSettingsQuerier) getCalls() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.gets
+}
+
+func setupTestRedis(t *testing.T) *redis.Redis {
+	t.Helper()
+
+	password := ""
+	redisDB := 0
+
+	redisClient, err := redis.New(&redis.Config{
+		Addrs:    []string{"localhost:36379"},
+		Password: &password,
+		DB:       &redisDB,
+	})
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	require.NoError(t, redisClient.FlushDB(ctx).Err())
+
+	t.Cleanup(func() {
+		_ = redisClient.Close()
+	})
+
+	return redisClient
+}
+
+func setupTestSettings(t *testing.T, querier db.Querier, redisClient *redis.Redis) *Settings {
+	t.Helper()
+
+	log, err := logger.New(&logger.Config{})
+	require.NoError(t, err)
+
+	settings, err := NewWithQuerier(&Config{}, querier, redisClient, log)
+	require.NoError(t, err)
+
+	t.Cleanup(func() {
+		_ = settings.Close()
+	})
+
+	return settings
+}
+
+func TestConfigSetDefault(t *testing.T) {
+	t.Parallel()
+
+	config := &Config{}
+	config.SetDefault()
+
+	assert.Equal(t, defaultCacheTTL, *config.CacheTTL)
+	assert.Equal(t, defaultLocalCacheTTL, *config.LocalCacheTTL)
+	assert.Equal(t, defaultChannel, *config.Channel)
+}
+
+func TestValidateKey(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name  string
+		key   string
+		valid bool
+	}{
+		{name: "simple", key: "theme", valid: true},
+		{name: "dotted", key: "notifications.email_enabled", valid: true},
+		{name: "dashed", key: "ui-density", valid: true},
+		{name: "empty", key: "", valid: false},
+		{name: "leading dot", key: ".theme", valid: false},
+		{name: "colon", key: "user:theme", valid: false},
+		{name: "slash", key: "a/b", valid: false},
+		{name: "too long", key: string(make([]byte, maxKeyLength+1)), valid: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			err := ValidateKey(tt.key)
+			if tt.valid {
+				assert.NoError(t, err)
+			} else {
+				assert.ErrorIs(t, err, ErrInvalidKey)
+			}
+		})
+	}
+}
+
+func TestUserScope(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, Scope("user:42"), UserScope("42"))
+}
+
+//nolint:paralleltest // sequential execution required to avoid redis key conflicts
+func TestSettings(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("typed getters and setters", func(t *testing.T) {
+		settings := setupTestSettings(t, newMockSettingsQuerier(), setupTestRedis(t))
+
+		require.NoError(t, settings.SetString(ctx, GlobalScope, "theme", "dark"))
+		require.NoError(t, settings.SetInt(ctx, GlobalScope, "page_size", 50))
+		require.NoError(t, settings.SetBool(ctx, GlobalScope, "beta", true))
+
+		theme, err := settings.GetString(ctx, GlobalScope, "theme", "light")
+		require.NoError(t, err)
+		assert.Equal(t, "dark", theme)
+
+		pageSize, err := settings.GetInt(ctx, GlobalScope, "page_size", 20)
+		require.NoError(t, err)
+		assert.Equal(t, 50, pageSize)
+
+		beta, err := settings.GetBool(ctx, GlobalScope, "beta", false)
+		require.NoError(t, err)
+		assert.True(t, beta)
+
+		missing, err := settings.GetInt(ctx, GlobalScope, "missing", 7)
+		require.NoError(t, err)
+		assert.Equal(t, 7, missing)
+
+		_, err = settings.GetInt(ctx, GlobalScope, "theme", 0)
+		require.ErrorIs(t, err, ErrTypeMismatch)
+	})
+
+	t.Run("get missing setting", func(t *testing.T) {
+		settings := setupTestSettings(t, newMockSettingsQuerier(), setupTestRedis(t))
+
+		_, err := settings.Get(ctx, GlobalScope, "missing")
+		require.ErrorIs(t, err, ErrNotFound)
+	})
+
+	t.Run("reject invalid key and value", func(t *testing.T) {
+		settings := setupTestSettings(t, newMockSettingsQuerier(), setupTestRedis(t))
+
+		require.ErrorIs(t, settings.SetString(ctx, GlobalScope, "bad key", "x"), ErrInvalidKey)
+		require.ErrorIs(t, settings.SetRaw(ctx, GlobalScope, "theme", json.RawMessage(`{`)), ErrInvalidValue)
+	})
+
+	t.Run("cache reads in memory and on redis", func(t *testing.T) {
+		querier := newMockSettingsQuerier()
+		redisClient := setupTestRedis(t)
+		settings := setupTestSettings(t, querier, redisClient)
+
+		require.NoError(t, settings.SetString(ctx, GlobalScope, "theme", "dark"))
+
+		for range 3 {
+			value, err := settings.Get(ctx, GlobalScope, "theme")
+			require.NoError(t, err)
+			assert.JSONEq(t, `"dark"`, string(value))
+		}
+
+		assert.Equal(t, 1, querier.getCalls())
+
+		// another instance reads from redis instead of database
+		other := setupTestSettings(t, querier, redisClient)
+
+		value, err := other.Get(ctx, GlobalScope, "theme")
+		require.NoError(t, err)
+		assert.JSONEq(t, `"dark"`, string(value))
+		assert.Equal(t, 1, querier.getCalls())
+	})
+
+	t.Run("cache missing settings", func(t *testing.T) {
+		querier := newMockSettingsQuerier()
+		settings := setupTestSettings(t, querier, setupTestRedis(t))
+
+		for range 2 {
+			_, err := settings.Get(ctx, GlobalScope, "missing")
+			require.ErrorIs(t, err, ErrNotFound)
+		}
+
+		assert.Equal(t, 1, querier.getCalls())
+	})
+
+	t.Run("invalidate other instances on write", func(t *testing.T) {
+		querier := newMockSettingsQuerier()
+		redisClient := setupTestRedis(t)
+		first := setupTestSettings(t, querier, redisClient)
+		second := setupTestSettings(t, querier, redisClient)
+
+		require.NoError(t, first.SetString(ctx, GlobalScope, "theme", "dark"))
+
+		theme, err := second.GetString(ctx, GlobalScope, "theme", "")
+		require.NoError(t, err)
+		assert.Equal(t, "dark", theme)
+
+		require.NoError(t, first.SetString(ctx, GlobalScope, "theme", "light"))
+
+		assert.Eventually(t, func() bool {
+			theme, err := second.GetString(ctx, GlobalScope, "theme", "")
+
+			return err == nil && theme == "light"
+		}, 2*time.Second, 10*time.Millisecond)
+
+		require.NoError(t, first.Delete(ctx, GlobalScope, "theme"))
+
+		assert.Eventually(t, func() bool {
+			_, err := second.Get(ctx, GlobalScope, "theme")
+
+			return errors.Is(err, ErrNotFound)
+		}, 2*time.Second, 10*time.Millisecond)
+	})
+
+	t.Run("resolve user setting before global setting", func(t *testing.T) {
+		settings := setupTestSettings(t, newMockSettingsQuerier(), setupTestRedis(t))
+
+		require.NoError(t, settings.SetString(ctx, GlobalScope, "theme", "light"))
+
+		value, scope, err := settings.Resolve(ctx, "42", "theme")
+		require.NoError(t, err)
+		assert.Equal(t, GlobalScope, scope)
+		assert.JSONEq(t, `"light"`, string(value))
+
+		require.NoError(t, settings.SetString(ctx, UserScope("42"), "theme", "dark"))
+
+		value, scope, err = settings.Resolve(ctx, "42", "theme")
+		require.NoError(t, err)
+		assert.Equal(t, UserScope("42"), scope)
+		assert.JSONEq(t, `"dark"`, string(value))
+
+		_, _, err = settings.Resolve(ctx, "42", "missing")
+		require.ErrorIs(t, err, ErrNotFound)
+	})
+
+	t.Run("list settings of scope", func(t *testing.T) {
+		settings := setupTestSettings(t, newMockSettingsQuerier(), setupTestRedis(t))
+
+		require.NoError(t, settings.SetString(ctx, UserScope("42"), "theme", "dark"))
+		require.NoError(t, settings.SetInt(ctx, UserScope("42"), "page_size", 50))
+		require.NoError(t, settings.SetString(ctx, UserScope("43"), "theme", "light"))
+
+		list, err := settings.List(ctx, UserScope("42"))
+		require.NoError(t, err)
+		assert.Len(t, list, 2)
+		assert.JSONEq(t, `"dark"`, string(list["theme"]))
+		assert.JSONEq(t, `50`, string(list["page_size"]))
+	})
+
+	t.Run("return database errors", func(t *testing.T) {
+		querier := newMockSettingsQuerier()
+		querier.err = errQueryFailed
+		settings := setupTestSettings(t, querier, setupTestRedis(t))
+
+		_, err := settings.Get(ctx, GlobalScope, "theme")
+		require.Error(t, err)
+		assert.NotErrorIs(t, err, ErrNotFound)
+	})
+}
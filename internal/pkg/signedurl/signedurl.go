@@ -0,0 +1,252 @@
+// Package signedurl provides short-lived URLs of protected resources, signed with HMAC so that
+// requests are authenticated as the signing user without a token, e.g. for links embedded in emails.
+package signedurl
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.uber.org/fx"
+)
+
+const (
+	// ExpiresParam is query parameter of the expiry of signed URLs in unix seconds.
+	ExpiresParam = "expires"
+
+	// SignedByParam is query parameter of ID of the user who signed the URL.
+	SignedByParam = "signed_by"
+
+	// SignatureParam is query parameter of the signature of signed URLs.
+	SignatureParam = "signature"
+
+	// defaultTTL is default lifetime of signed URLs.
+	defaultTTL = time.Hour
+
+	// defaultMaxTTL is default maximum lifetime of signed URLs.
+	defaultMaxTTL = 7 * 24 * time.Hour
+)
+
+var (
+	// ErrMissingSecret is returned when signed URLs are enabled without a secret.
+	ErrMissingSecret = errors.New("missing signed url secret")
+
+	// ErrInvalidSignature is returned when the URL is not signed with the secret or was modified.
+	ErrInvalidSignature = errors.New("invalid url signature")
+
+	// ErrExpired is returned when the signed URL is expired.
+	ErrExpired = errors.New("signed url expired")
+
+	// ErrPathNotSignable is returned when the path is not under any signable path prefix.
+	ErrPathNotSignable = errors.New("path not signable")
+
+	// ErrInvalidTTL is returned when the lifetime is negative or exceeds the maximum lifetime.
+	ErrInvalidTTL = errors.New("invalid signed url lifetime")
+)
+
+// Config represents configuration for signed URLs.
+type Config struct {
+	// Enabled is whether signed URLs are enabled.
+	Enabled *bool `json:"enabled"`
+
+	// Secret is secret key signing URLs, changing it invalidates all signed URLs.
+	Secret *string `json:"secret"`
+
+	// Path is path of the endpoint signing URLs for the authenticated user.
+	Path *string `json:"path"`
+
+	// BaseURL is scheme and host prepended to signed URLs, e.g. https://api.example.com, relative if empty.
+	BaseURL *string `json:"base_url"`
+
+	// DefaultTTL is lifetime of signed URLs if not requested.
+	DefaultTTL *time.Duration `json:"default_ttl"`
+
+	// MaxTTL is maximum lifetime of signed URLs.
+	MaxTTL *time.Duration `json:"max_ttl"`
+
+	// Paths is path prefixes of resources URLs can be signed for, e.g. /downloads/.
+	Paths []string `json:"paths"`
+}
+
+// SetDefault sets default values.
+func (c *Config) SetDefault() {
+	if c.Enabled == nil {
+		c.Enabled = &[]bool{false}[0]
+	}
+
+	if c.Secret == nil {
+		c.Secret = &[]string{""}[0]
+	}
+
+	if c.Path == nil {
+		c.Path = &[]string{"/signed-urls"}[0]
+	}
+
+	if c.BaseURL == nil {
+		c.BaseURL = &[]string{""}[0]
+	}
+
+	if c.DefaultTTL == nil {
+		c.DefaultTTL = &[]time.Duration{defaultTTL}[0]
+	}
+
+	if c.MaxTTL == nil {
+		c.MaxTTL = &[]time.Duration{defaultMaxTTL}[0]
+	}
+
+	if c.Paths == nil {
+		c.Paths = []string{}
+	}
+}
+
+// SignedURL represents a signed URL.
+type SignedURL struct {
+	// URL is the signed URL.
+	URL string `json:"url"`
+
+	// ExpiresAt is time the URL expires at.
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// Grant represents access granted by a verified signed URL.
+type Grant struct {
+	// UserID is ID of the user who signed the URL.
+	UserID string
+
+	// ExpiresAt is time the URL expires at.
+	ExpiresAt time.Time
+}
+
+// Signer signs and verifies URLs.
+type Signer struct {
+	// config provides signed URL configuration.
+	config *Config
+
+	// now returns the current time, replaced in tests.
+	now func() time.Time
+}
+
+// NewModule provides module for signed URLs.
+func NewModule() fx.Option {
+	return fx.Module("signedurl",
+		fx.Provide(New),
+	)
+}
+
+// New creates a new signer.
+func New(config *Config) (*Signer, error) {
+	if config == nil {
+		config = &Config{}
+	}
+
+	config.SetDefault()
+
+	if *config.Enabled && *config.Secret == "" {
+		return nil, ErrMissingSecret
+	}
+
+	return &Signer{config: config, now: time.Now}, nil
+}
+
+// Enabled returns whether signed URLs are enabled.
+func (s *Signer) Enabled() bool {
+	return s != nil && *s.config.Enabled
+}
+
+// Path returns path of the endpoint signing URLs.
+func (s *Signer) Path() string {
+	return *s.config.Path
+}
+
+// Sign signs the target, a path with an optional query, for the user, valid for ttl or the default lifetime if zero.
+func (s *Signer) Sign(target, userID string, ttl time.Duration) (*SignedURL, error) {
+	if ttl == 0 {
+		ttl = *s.config.DefaultTTL
+	}
+
+	if ttl < 0 || ttl > *s.config.MaxTTL {
+		return nil, fmt.Errorf("%w: must be positive and at most %s", ErrInvalidTTL, *s.config.MaxTTL)
+	}
+
+	parsed, err := url.Parse(target)
+	if err != nil || parsed.IsAbs() || parsed.Host != "" {
+		return nil, fmt.Errorf("%w: %s", ErrPathNotSignable, target)
+	}
+
+	if !s.signable(parsed.Path) {
+		return nil, fmt.Errorf("%w: %s", ErrPathNotSignable, parsed.Path)
+	}
+
+	expiresAt := s.now().Add(ttl).Truncate(time.Second)
+
+	query := parsed.Query()
+	query.Del(SignatureParam)
+	query.Set(ExpiresParam, strconv.FormatInt(expiresAt.Unix(), 10))
+	query.Set(SignedByParam, userID)
+	query.Set(SignatureParam, s.signature(parsed.Path, query))
+
+	return &SignedURL{
+		URL:       strings.TrimSuffix(*s.config.BaseURL, "/") + parsed.Path + "?" + query.Encode(),
+		ExpiresAt: expiresAt.UTC(),
+	}, nil
+}
+
+// Signed returns whether the URL carries a signature.
+func Signed(target *url.URL) bool {
+	return target.Query().Has(SignatureParam)
+}
+
+// Verify verifies the signature and expiry of the URL, any change to its path or query invalidates the signature.
+func (s *Signer) Verify(target *url.URL) (*Grant, error) {
+	query := target.Query()
+
+	signature := query.Get(SignatureParam)
+	query.Del(SignatureParam)
+
+	if !hmac.Equal([]byte(signature), []byte(s.signature(target.Path, query))) || !s.signable(target.Path) {
+		return nil, ErrInvalidSignature
+	}
+
+	expires, err := strconv.ParseInt(query.Get(ExpiresParam), 10, 64)
+	if err != nil {
+		return nil, ErrInvalidSignature
+	}
+
+	expiresAt := time.Unix(expires, 0)
+	if !s.now().Before(expiresAt) {
+		return nil, ErrExpired
+	}
+
+	return &Grant{UserID: query.Get(SignedByParam), ExpiresAt: expiresAt.UTC()}, nil
+}
+
+// signable returns whether the path is under a signable path prefix, paths with dot segments are not signable
+// so that they cannot escape the prefix.
+func (s *Signer) signable(target string) bool {
+	if cleaned := path.Clean(target); cleaned != target && cleaned+"/" != target {
+		return false
+	}
+
+	for _, prefix := range s.config.Paths {
+		if strings.HasPrefix(target, prefix) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// signature returns the signature of the path and the query, encoded sorted by key.
+func (s *Signer) signature(target string, query url.Values) string {
+	mac := hmac.New(sha256.New, []byte(*s.config.Secret))
+	mac.Write([]byte(target + "?" + query.Encode()))
+
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
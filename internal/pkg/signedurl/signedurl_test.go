@@ -0,0 +1,224 @@
+package signedurl
+
+import (
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// setupTestSigner creates an enabled signer of /downloads/ at a fixed time.
+func setupTestSigner(t *testing.T, baseURL string) *Signer {
+	t.Helper()
+
+	signer, err := New(&Config{
+		Enabled: &[]bool{true}[0],
+		Secret:  &[]string{"test_secret"}[0],
+		BaseURL: &baseURL,
+		Paths:   []string{"/downloads/"},
+	})
+	require.NoError(t, err)
+
+	signer.now = func() time.Time { return time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC) }
+
+	return signer
+}
+
+// mustParse parses the URL.
+func mustParse(t *testing.T, raw string) *url.URL {
+	t.Helper()
+
+	parsed, err := url.Parse(raw)
+	require.NoError(t, err)
+
+	return parsed
+}
+
+func TestConfigSetDefault(t *testing.T) {
+	t.Parallel()
+
+	config := &Config{}
+	config.SetDefault()
+
+	assert.False(t, *config.Enabled)
+	assert.Empty(t, *config.Secret)
+	assert.Equal(t, "/signed-urls", *config.Path)
+	assert.Empty(t, *config.BaseURL)
+	assert.Equal(t, defaultTTL, *config.DefaultTTL)
+	assert.Equal(t, defaultMaxTTL, *config.MaxTTL)
+	assert.Empty(t, config.Paths)
+}
+
+func TestNewModule(t *testing.T) {
+	t.Parallel()
+
+	t.Run("return fx.Option", func(t *testing.T) {
+		t.Parallel()
+
+		require.NotNil(t, NewModule())
+	})
+}
+
+func TestNew(t *testing.T) {
+	t.Parallel()
+
+	t.Run("create disabled signer without secret", func(t *testing.T) {
+		t.Parallel()
+
+		signer, err := New(nil)
+		require.NoError(t, err)
+		assert.False(t, signer.Enabled())
+	})
+
+	t.Run("return error if enabled without secret", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := New(&Config{Enabled: &[]bool{true}[0]})
+		require.ErrorIs(t, err, ErrMissingSecret)
+	})
+
+	t.Run("nil signer is disabled", func(t *testing.T) {
+		t.Parallel()
+
+		var signer *Signer
+
+		assert.False(t, signer.Enabled())
+	})
+}
+
+func TestSign(t *testing.T) {
+	t.Parallel()
+
+	t.Run("sign path with default lifetime", func(t *testing.T) {
+		t.Parallel()
+
+		signer := setupTestSigner(t, "https://api.example.com/")
+
+		signed, err := signer.Sign("/downloads/report.pdf?format=a4", "user-1", 0)
+		require.NoError(t, err)
+
+		assert.True(t, strings.HasPrefix(signed.URL, "https://api.example.com/downloads/report.pdf?"))
+		assert.Equal(t, time.Date(2024, 1, 1, 1, 0, 0, 0, time.UTC), signed.ExpiresAt)
+
+		parsed := mustParse(t, signed.URL)
+		assert.Equal(t, "a4", parsed.Query().Get("format"))
+		assert.Equal(t, "user-1", parsed.Query().Get(SignedByParam))
+		assert.Equal(t, "1704070800", parsed.Query().Get(ExpiresParam))
+		assert.True(t, Signed(parsed))
+	})
+
+	t.Run("return error of path which is not signable", func(t *testing.T) {
+		t.Parallel()
+
+		signer := setupTestSigner(t, "")
+
+		for _, target := range []string{"/users/me", "/downloads/../users/me", "https://evil.example.com/downloads/x"} {
+			_, err := signer.Sign(target, "user-1", 0)
+			require.ErrorIs(t, err, ErrPathNotSignable, target)
+		}
+	})
+
+	t.Run("return error of invalid lifetime", func(t *testing.T) {
+		t.Parallel()
+
+		signer := setupTestSigner(t, "")
+
+		_, err := signer.Sign("/downloads/report.pdf", "user-1", -time.Minute)
+		require.ErrorIs(t, err, ErrInvalidTTL)
+
+		_, err = signer.Sign("/downloads/report.pdf", "user-1", 8*24*time.Hour)
+		require.ErrorIs(t, err, ErrInvalidTTL)
+	})
+}
+
+func TestVerify(t *testing.T) {
+	t.Parallel()
+
+	t.Run("grant signing user", func(t *testing.T) {
+		t.Parallel()
+
+		signer := setupTestSigner(t, "")
+
+		signed, err := signer.Sign("/downloads/report.pdf?format=a4", "user-1", time.Minute)
+		require.NoError(t, err)
+
+		grant, err := signer.Verify(mustParse(t, signed.URL))
+		require.NoError(t, err)
+		assert.Equal(t, &Grant{UserID: "user-1", ExpiresAt: signed.ExpiresAt}, grant)
+	})
+
+	t.Run("reject modified url", func(t *testing.T) {
+		t.Parallel()
+
+		signer := setupTestSigner(t, "")
+
+		signed, err := signer.Sign("/downloads/report.pdf?format=a4", "user-1", time.Minute)
+		require.NoError(t, err)
+
+		replacements := map[string]string{
+			"path":      "/downloads/other.pdf",
+			"query":     "format=letter",
+			"user":      SignedByParam + "=user-2",
+			"expiry":    ExpiresParam + "=1704070861",
+			"signature": SignatureParam + "=invalid",
+		}
+		originals := map[string]string{
+			"path":      "/downloads/report.pdf",
+			"query":     "format=a4",
+			"user":      SignedByParam + "=user-1",
+			"expiry":    ExpiresParam + "=1704067260",
+			"signature": SignatureParam + "=" + mustParse(t, signed.URL).Query().Get(SignatureParam),
+		}
+
+		for name, replacement := range replacements {
+			modified := strings.Replace(signed.URL, originals[name], replacement, 1)
+			require.NotEqual(t, signed.URL, modified, name)
+
+			_, err := signer.Verify(mustParse(t, modified))
+			require.ErrorIs(t, err, ErrInvalidSignature, name)
+		}
+	})
+
+	t.Run("reject url signed with other secret", func(t *testing.T) {
+		t.Parallel()
+
+		signer := setupTestSigner(t, "")
+		other := setupTestSigner(t, "")
+		other.config.Secret = &[]string{"other_secret"}[0]
+
+		signed, err := other.Sign("/downloads/report.pdf", "user-1", time.Minute)
+		require.NoError(t, err)
+
+		_, err = signer.Verify(mustParse(t, signed.URL))
+		require.ErrorIs(t, err, ErrInvalidSignature)
+	})
+
+	t.Run("reject expired url", func(t *testing.T) {
+		t.Parallel()
+
+		signer := setupTestSigner(t, "")
+
+		signed, err := signer.Sign("/downloads/report.pdf", "user-1", time.Minute)
+		require.NoError(t, err)
+
+		signer.now = func() time.Time { return signed.ExpiresAt }
+
+		_, err = signer.Verify(mustParse(t, signed.URL))
+		require.ErrorIs(t, err, ErrExpired)
+	})
+
+	t.Run("reject url without signature", func(t *testing.T) {
+		t.Parallel()
+
+		signer := setupTestSigner(t, "")
+		target := mustParse(t, "/downloads/report.pdf")
+
+		assert.False(t, Signed(target))
+
+		_, err := signer.Verify(target)
+		require.ErrorIs(t, err, ErrInvalidSignature)
+	})
+}
@@ -0,0 +1,408 @@
+// Package sse provides server-sent events: a broker streaming events to connected clients over text/event-stream
+// responses, fanned out to all instances over redis pub/sub and kept in a redis stream, so that reconnecting
+// clients receive the events they missed since their Last-Event-ID.
+package sse
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	goredis "github.com/redis/go-redis/v9"
+	"go.uber.org/fx"
+
+	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/logger"
+	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/redis"
+)
+
+const (
+	// defaultPath is default path of the events endpoint.
+	defaultPath = "/events"
+
+	// defaultChannel is default channel events are published to instances on.
+	defaultChannel = "sse:events"
+
+	// defaultHistoryKey is default key of the stream of recent events.
+	defaultHistoryKey = "sse:history"
+
+	// defaultHistorySize is default number of recent events kept for reconnecting clients.
+	defaultHistorySize = 1000
+
+	// defaultHeartbeat is default interval of heartbeat comments.
+	defaultHeartbeat = 15 * time.Second
+
+	// defaultRetry is default reconnection delay of clients.
+	defaultRetry = 3 * time.Second
+
+	// defaultBuffer is default number of events buffered per client.
+	defaultBuffer = 64
+)
+
+var (
+	// ErrInvalidConfig is returned when heartbeats, the reconnection delay or the client buffer are not positive.
+	ErrInvalidConfig = errors.New("sse heartbeat, retry and buffer must be positive")
+
+	// ErrInvalidEvent is returned when publishing an event whose type contains a line break.
+	ErrInvalidEvent = errors.New("sse event type must not contain line breaks")
+
+	// ErrBrokerClosed is returned when streaming to clients after the broker was shut down.
+	ErrBrokerClosed = errors.New("sse broker is closed")
+
+	// ErrStreamingUnsupported is returned when the response writer can not be flushed.
+	ErrStreamingUnsupported = errors.New("streaming is not supported")
+)
+
+// Config represents configuration for server-sent events.
+type Config struct {
+	// Enabled is whether the events endpoint is served and events are received from other instances, events
+	// are published regardless, so that instances without the endpoint (e.g. workers) can push events.
+	Enabled *bool `json:"enabled"`
+
+	// Path is path of the events endpoint.
+	Path *string `json:"path"`
+
+	// Channel is redis channel events are published to instances on.
+	Channel *string `json:"channel"`
+
+	// HistoryKey is key of the redis stream of recent events.
+	HistoryKey *string `json:"history_key"`
+
+	// HistorySize is approximate number of recent events kept for reconnecting clients, 0 disables IDs
+	// and replays.
+	HistorySize *int64 `json:"history_size"`
+
+	// Heartbeat is interval of heartbeat comments keeping idle streams open through proxies.
+	Heartbeat *time.Duration `json:"heartbeat"`
+
+	// Retry is reconnection delay sent to clients.
+	Retry *time.Duration `json:"retry"`
+
+	// Buffer is number of events buffered per client, clients reading slower are disconnected and replay the
+	// missed events when reconnecting.
+	Buffer *int `json:"buffer"`
+}
+
+// SetDefault sets default values.
+func (c *Config) SetDefault() {
+	if c.Enabled == nil {
+		c.Enabled = &[]bool{true}[0]
+	}
+
+	if c.Path == nil {
+		c.Path = &[]string{defaultPath}[0]
+	}
+
+	if c.Channel == nil {
+		c.Channel = &[]string{defaultChannel}[0]
+	}
+
+	if c.HistoryKey == nil {
+		c.HistoryKey = &[]string{defaultHistoryKey}[0]
+	}
+
+	if c.HistorySize == nil {
+		c.HistorySize = &[]int64{defaultHistorySize}[0]
+	}
+
+	if c.Heartbeat == nil {
+		c.Heartbeat = &[]time.Duration{defaultHeartbeat}[0]
+	}
+
+	if c.Retry == nil {
+		c.Retry = &[]time.Duration{defaultRetry}[0]
+	}
+
+	if c.Buffer == nil {
+		c.Buffer = &[]int{defaultBuffer}[0]
+	}
+}
+
+// Broker streams events to clients connected to this instance and publishes events to all instances.
+type Broker struct {
+	// config provides sse configuration.
+	config *Config
+
+	// redis provides redis client.
+	redis *redis.Redis
+
+	// logger provides logger.
+	logger *logger.Logger
+
+	// mu guards clients and closed.
+	mu sync.RWMutex
+
+	// clients is clients streaming on this instance.
+	clients map[*client]struct{}
+
+	// closed is whether the broker was shut down.
+	closed bool
+
+	// done is closed when the broker is shut down, ending the streams.
+	done chan struct{}
+
+	// shutdownOnce closes done once.
+	shutdownOnce sync.Once
+
+	// pubsub is subscription of the events channel, nil if the broker is disabled.
+	pubsub *goredis.PubSub
+
+	// wg waits for the receive loop to exit.
+	wg sync.WaitGroup
+
+	// streams is gauge of open streams.
+	streams prometheus.Gauge
+
+	// dropped is counter of clients disconnected for reading too slowly.
+	dropped prometheus.Counter
+}
+
+// NewModule provides module for sse.
+func NewModule() fx.Option {
+	return fx.Module("sse",
+		fx.Provide(New),
+	)
+}
+
+// New creates a broker of the configuration, subscribing to events of other instances if it is enabled.
+func New(config *Config, redis *redis.Redis, logger *logger.Logger) (*Broker, error) {
+	if config == nil {
+		config = &Config{}
+	}
+
+	config.SetDefault()
+
+	if *config.Heartbeat <= 0 || *config.Retry <= 0 || *config.Buffer <= 0 {
+		return nil, fmt.Errorf("%w: heartbeat %s, retry %s, buffer %d",
+			ErrInvalidConfig, *config.Heartbeat, *config.Retry, *config.Buffer)
+	}
+
+	broker := &Broker{
+		config:  config,
+		redis:   redis,
+		logger:  logger.Named("sse"),
+		clients: make(map[*client]struct{}),
+		done:    make(chan struct{}),
+		streams: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "sse_streams",
+			Help: "Number of open server-sent event streams",
+		}),
+		dropped: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "sse_streams_dropped_total",
+			Help: "Total number of server-sent event streams closed for reading events too slowly",
+		}),
+	}
+
+	if !*config.Enabled {
+		return broker, nil
+	}
+
+	pubsub := redis.Subscribe(context.Background(), *config.Channel)
+
+	// wait for the subscription so events published after New are received
+	if _, err := pubsub.Receive(context.Background()); err != nil {
+		_ = pubsub.Close()
+
+		return nil, fmt.Errorf("failed to subscribe to sse events: %w", err)
+	}
+
+	broker.pubsub = pubsub
+
+	broker.wg.Add(1)
+
+	go broker.receive()
+
+	return broker, nil
+}
+
+// Enabled returns whether the events endpoint is served, false if the broker is nil.
+func (b *Broker) Enabled() bool {
+	return b != nil && *b.config.Enabled
+}
+
+// Config returns the sse configuration.
+func (b *Broker) Config() *Config {
+	return b.config
+}
+
+// Broadcast publishes the event to the clients of all users on all instances and returns its ID.
+func (b *Broker) Broadcast(ctx context.Context, event Event) (string, error) {
+	return b.publish(ctx, "", event)
+}
+
+// Send publishes the event to the clients of the user on all instances and returns its ID.
+func (b *Broker) Send(ctx context.Context, userID string, event Event) (string, error) {
+	if userID == "" {
+		return "", nil
+	}
+
+	return b.publish(ctx, userID, event)
+}
+
+// publish adds the event to the history and publishes it to all instances.
+func (b *Broker) publish(ctx context.Context, userID string, event Event) (string, error) {
+	if strings.ContainsAny(event.Type, "\r\n") {
+		return "", fmt.Errorf("%w: %q", ErrInvalidEvent, event.Type)
+	}
+
+	event.ID = ""
+
+	if *b.config.HistorySize > 0 {
+		id, err := b.redis.XAdd(ctx, &goredis.XAddArgs{
+			Stream: *b.config.HistoryKey,
+			MaxLen: *b.config.HistorySize,
+			Approx: true,
+			Values: map[string]any{"user_id": userID, "type": event.Type, "data": event.Data},
+		}).Result()
+		if err != nil {
+			return "", fmt.Errorf("failed to add sse event to history: %w", err)
+		}
+
+		event.ID = id
+	}
+
+	payload, err := json.Marshal(envelope{UserID: userID, Event: event})
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal sse event: %w", err)
+	}
+
+	if err := b.redis.Publish(ctx, *b.config.Channel, payload).Err(); err != nil {
+		return "", fmt.Errorf("failed to publish sse event: %w", err)
+	}
+
+	return event.ID, nil
+}
+
+// receive delivers events published by instances to the clients of this instance until unsubscribed.
+func (b *Broker) receive() {
+	defer b.wg.Done()
+
+	for message := range b.pubsub.ChannelWithSubscriptions() {
+		switch message := message.(type) {
+		case *goredis.Message:
+			var published envelope
+			if err := json.Unmarshal([]byte(message.Payload), &published); err != nil {
+				b.logger.Warn().Err(err).Msg("failed to unmarshal sse event")
+
+				continue
+			}
+
+			b.deliver(published)
+		case *goredis.Subscription:
+			// events published while reconnecting are replayed by clients reconnecting with their last ID
+			b.logger.Debug().Str("kind", message.Kind).Msg("sse subscription changed")
+		}
+	}
+}
+
+// deliver buffers the event to the clients it is addressed to, dropping clients whose buffer is full.
+func (b *Broker) deliver(published envelope) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	for client := range b.clients {
+		if published.UserID != "" && client.userID != published.UserID {
+			continue
+		}
+
+		select {
+		case client.events <- published.Event:
+		default:
+			if client.drop() {
+				b.dropped.Inc()
+				b.logger.Warn().Str("user_id", client.userID).Msg("sse buffer full, closing stream")
+			}
+		}
+	}
+}
+
+// register adds the client to the streaming clients, false if the broker was shut down.
+func (b *Broker) register(client *client) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.closed {
+		return false
+	}
+
+	b.clients[client] = struct{}{}
+	b.streams.Inc()
+
+	return true
+}
+
+// unregister removes the client from the streaming clients.
+func (b *Broker) unregister(client *client) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if _, ok := b.clients[client]; !ok {
+		return
+	}
+
+	delete(b.clients, client)
+	b.streams.Dec()
+}
+
+// Streams returns the number of open streams of the user on this instance, or of all users if the user ID
+// is empty.
+func (b *Broker) Streams(userID string) int {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	streams := 0
+
+	for client := range b.clients {
+		if userID == "" || client.userID == userID {
+			streams++
+		}
+	}
+
+	return streams
+}
+
+// Shutdown ends all streams, so that requests streaming events complete while the server drains. Clients
+// reconnect to other instances and replay the events they missed.
+func (b *Broker) Shutdown() {
+	b.shutdownOnce.Do(func() {
+		b.mu.Lock()
+		b.closed = true
+		b.mu.Unlock()
+
+		close(b.done)
+	})
+}
+
+// Close ends all streams and unsubscribes from events of other instances.
+func (b *Broker) Close() error {
+	b.Shutdown()
+
+	if b.pubsub == nil {
+		return nil
+	}
+
+	err := b.pubsub.Close()
+	b.wg.Wait()
+
+	if err != nil {
+		return fmt.Errorf("failed to close sse subscription: %w", err)
+	}
+
+	return nil
+}
+
+// Describe sends descriptors of the sse metrics.
+func (b *Broker) Describe(descs chan<- *prometheus.Desc) {
+	b.streams.Describe(descs)
+	b.dropped.Describe(descs)
+}
+
+// Collect sends the sse metrics.
+func (b *Broker) Collect(metrics chan<- prometheus.Metric) {
+	b.streams.Collect(metrics)
+	b.dropped.Collect(metrics)
+}
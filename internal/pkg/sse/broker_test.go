@@ -0,0 +1,232 @@
+package sse
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/logger"
+	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/redis"
+)
+
+func setupTestRedis(t *testing.T) *redis.Redis {
+	t.Helper()
+
+	password := ""
+	redisDB := 0
+
+	redisClient, err := redis.New(&redis.Config{
+		Addrs:    []string{"localhost:36379"},
+		Password: &password,
+		DB:       &redisDB,
+	})
+	require.NoError(t, err)
+
+	t.Cleanup(func() {
+		_ = redisClient.Close()
+	})
+
+	return redisClient
+}
+
+// setupTestBroker creates a broker of the configuration on the channel and history of the test, so that
+// parallel tests do not receive events of each other.
+func setupTestBroker(t *testing.T, redisClient *redis.Redis, config *Config) *Broker {
+	t.Helper()
+
+	log, err := logger.New(&logger.Config{})
+	require.NoError(t, err)
+
+	if config == nil {
+		config = &Config{}
+	}
+
+	config.Channel = &[]string{"sse:events:" + t.Name()}[0]
+	config.HistoryKey = &[]string{"sse:history:" + t.Name()}[0]
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	require.NoError(t, redisClient.Del(ctx, *config.HistoryKey).Err())
+
+	broker, err := New(config, redisClient, log)
+	require.NoError(t, err)
+
+	t.Cleanup(func() {
+		_ = broker.Close()
+	})
+
+	return broker
+}
+
+func TestConfigSetDefault(t *testing.T) {
+	t.Parallel()
+
+	config := &Config{}
+	config.SetDefault()
+
+	assert.True(t, *config.Enabled)
+	assert.Equal(t, defaultPath, *config.Path)
+	assert.Equal(t, defaultChannel, *config.Channel)
+	assert.Equal(t, defaultHistoryKey, *config.HistoryKey)
+	assert.Equal(t, int64(defaultHistorySize), *config.HistorySize)
+	assert.Equal(t, defaultHeartbeat, *config.Heartbeat)
+	assert.Equal(t, defaultRetry, *config.Retry)
+	assert.Equal(t, defaultBuffer, *config.Buffer)
+}
+
+func TestNew(t *testing.T) {
+	t.Parallel()
+
+	log, err := logger.New(&logger.Config{})
+	require.NoError(t, err)
+
+	redisClient := setupTestRedis(t)
+
+	_, err = New(&Config{Heartbeat: &[]time.Duration{0}[0]}, redisClient, log)
+	require.ErrorIs(t, err, ErrInvalidConfig)
+
+	_, err = New(&Config{Buffer: &[]int{0}[0]}, redisClient, log)
+	require.ErrorIs(t, err, ErrInvalidConfig)
+
+	// disabled brokers do not subscribe to events
+	broker, err := New(&Config{Enabled: &[]bool{false}[0]}, redisClient, log)
+	require.NoError(t, err)
+	assert.False(t, broker.Enabled())
+	assert.Nil(t, broker.pubsub)
+	require.NoError(t, broker.Close())
+
+	var disabled *Broker
+
+	assert.False(t, disabled.Enabled())
+}
+
+func TestBrokerPublish(t *testing.T) {
+	t.Parallel()
+
+	redisClient := setupTestRedis(t)
+
+	t.Run("add events to the history and deliver them to the clients they are addressed to", func(t *testing.T) {
+		t.Parallel()
+
+		broker := setupTestBroker(t, redisClient, nil)
+
+		alice := &client{userID: "alice", events: make(chan Event, 4), dropped: make(chan struct{})}
+		bob := &client{userID: "bob", events: make(chan Event, 4), dropped: make(chan struct{})}
+
+		require.True(t, broker.register(alice))
+		require.True(t, broker.register(bob))
+
+		assert.Equal(t, 2, broker.Streams(""))
+		assert.Equal(t, 1, broker.Streams("alice"))
+
+		id, err := broker.Send(t.Context(), "alice", Event{Type: "notification", Data: "hello alice"})
+		require.NoError(t, err)
+		assert.NotEmpty(t, id)
+
+		broadcastID, err := broker.Broadcast(t.Context(), Event{Data: "hello all"})
+		require.NoError(t, err)
+		assert.True(t, after(broadcastID, id))
+
+		for _, expected := range []Event{
+			{ID: id, Type: "notification", Data: "hello alice"},
+			{ID: broadcastID, Data: "hello all"},
+		} {
+			select {
+			case event := <-alice.events:
+				assert.Equal(t, expected, event)
+			case <-time.After(2 * time.Second):
+				t.Fatal("event was not delivered")
+			}
+		}
+
+		select {
+		case event := <-bob.events:
+			assert.Equal(t, Event{ID: broadcastID, Data: "hello all"}, event)
+		case <-time.After(2 * time.Second):
+			t.Fatal("event was not delivered")
+		}
+
+		assert.Empty(t, bob.events)
+
+		length, err := redisClient.XLen(t.Context(), *broker.config.HistoryKey).Result()
+		require.NoError(t, err)
+		assert.Equal(t, int64(2), length)
+	})
+
+	t.Run("publish events without IDs if the history is disabled", func(t *testing.T) {
+		t.Parallel()
+
+		broker := setupTestBroker(t, redisClient, &Config{HistorySize: &[]int64{0}[0]})
+
+		id, err := broker.Broadcast(t.Context(), Event{Data: "hello"})
+		require.NoError(t, err)
+		assert.Empty(t, id)
+
+		length, err := redisClient.XLen(t.Context(), *broker.config.HistoryKey).Result()
+		require.NoError(t, err)
+		assert.Zero(t, length)
+	})
+
+	t.Run("reject event types with line breaks and events to no user", func(t *testing.T) {
+		t.Parallel()
+
+		broker := setupTestBroker(t, redisClient, nil)
+
+		_, err := broker.Broadcast(t.Context(), Event{Type: "a\nb"})
+		require.ErrorIs(t, err, ErrInvalidEvent)
+
+		id, err := broker.Send(t.Context(), "", Event{Data: "hello"})
+		require.NoError(t, err)
+		assert.Empty(t, id)
+	})
+}
+
+func TestBrokerDeliver(t *testing.T) {
+	t.Parallel()
+
+	broker := setupTestBroker(t, setupTestRedis(t), nil)
+
+	slow := &client{userID: "alice", events: make(chan Event, 1), dropped: make(chan struct{})}
+	require.True(t, broker.register(slow))
+
+	broker.deliver(envelope{Event: Event{Data: "first"}})
+	broker.deliver(envelope{Event: Event{Data: "second"}})
+	broker.deliver(envelope{Event: Event{Data: "third"}})
+
+	// the client is dropped once when its buffer overflows
+	select {
+	case <-slow.dropped:
+	default:
+		t.Fatal("slow client was not dropped")
+	}
+
+	assert.InDelta(t, 1, testutil.ToFloat64(broker.dropped), 0)
+	assert.InDelta(t, 1, testutil.ToFloat64(broker.streams), 0)
+
+	broker.unregister(slow)
+	broker.unregister(slow)
+	assert.Zero(t, testutil.ToFloat64(broker.streams))
+}
+
+func TestBrokerShutdown(t *testing.T) {
+	t.Parallel()
+
+	broker := setupTestBroker(t, setupTestRedis(t), nil)
+
+	broker.Shutdown()
+	broker.Shutdown()
+
+	select {
+	case <-broker.done:
+	default:
+		t.Fatal("streams were not ended")
+	}
+
+	assert.False(t, broker.register(&client{userID: "alice"}))
+	require.NoError(t, broker.Close())
+}
@@ -0,0 +1,98 @@
+package sse
+
+import (
+	"bufio"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Event represents an event pushed to clients.
+type Event struct {
+	// ID is ID of the event in the history, set on publish and empty if the history is disabled. Clients send
+	// it back as Last-Event-ID when reconnecting to receive the events they missed.
+	ID string `json:"id,omitempty"`
+
+	// Type is type of the event dispatched by EventSource, empty for message events.
+	Type string `json:"type,omitempty"`
+
+	// Data is data of the event, written as one data line per line.
+	Data string `json:"data"`
+}
+
+// envelope represents an event published to instances, addressed to the connections of a user or all
+// connections if the user ID is empty.
+type envelope struct {
+	// UserID is ID of the user the event is addressed to, empty for all users.
+	UserID string `json:"user_id,omitempty"`
+
+	// Event is the event.
+	Event Event `json:"event"`
+}
+
+// writeEvent writes the event in the text/event-stream format.
+func writeEvent(writer *bufio.Writer, event Event) error {
+	if event.ID != "" {
+		_, _ = writer.WriteString("id: " + event.ID + "\n")
+	}
+
+	if event.Type != "" {
+		_, _ = writer.WriteString("event: " + event.Type + "\n")
+	}
+
+	data := strings.ReplaceAll(event.Data, "\r\n", "\n")
+
+	for line := range strings.SplitSeq(data, "\n") {
+		_, _ = writer.WriteString("data: " + line + "\n")
+	}
+
+	_, _ = writer.WriteString("\n")
+
+	return writer.Flush() //nolint:wrapcheck // errors of the response writer are returned as is
+}
+
+// writeRetry writes the reconnection delay of clients.
+func writeRetry(writer *bufio.Writer, retry time.Duration) error {
+	_, _ = writer.WriteString("retry: " + strconv.FormatInt(retry.Milliseconds(), 10) + "\n\n")
+
+	return writer.Flush() //nolint:wrapcheck // errors of the response writer are returned as is
+}
+
+// writeHeartbeat writes a comment keeping the connection open through proxies closing idle connections.
+func writeHeartbeat(writer *bufio.Writer) error {
+	_, _ = writer.WriteString(": heartbeat\n\n")
+
+	return writer.Flush() //nolint:wrapcheck // errors of the response writer are returned as is
+}
+
+// parseID parses the milliseconds and sequence of an ID of the history stream.
+func parseID(id string) (uint64, uint64, bool) {
+	milliseconds, sequence, found := strings.Cut(id, "-")
+	if !found {
+		return 0, 0, false
+	}
+
+	ms, err := strconv.ParseUint(milliseconds, 10, 64)
+	if err != nil {
+		return 0, 0, false
+	}
+
+	seq, err := strconv.ParseUint(sequence, 10, 64)
+	if err != nil {
+		return 0, 0, false
+	}
+
+	return ms, seq, true
+}
+
+// after returns whether the ID is after the other ID of the history stream, true if either is not an ID.
+func after(id, other string) bool {
+	ms, seq, ok := parseID(id)
+	otherMS, otherSeq, otherOK := parseID(other)
+
+	if !ok || !otherOK {
+		return true
+	}
+
+	return ms > otherMS || (ms == otherMS && seq > otherSeq)
+}
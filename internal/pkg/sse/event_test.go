@@ -0,0 +1,87 @@
+package sse
+
+import (
+	"bufio"
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriteEvent(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		event    Event
+		expected string
+	}{
+		{
+			name:     "message event",
+			event:    Event{Data: "hello"},
+			expected: "data: hello\n\n",
+		},
+		{
+			name:     "event with id and type",
+			event:    Event{ID: "1-0", Type: "notification", Data: `{"id":1}`},
+			expected: "id: 1-0\nevent: notification\ndata: {\"id\":1}\n\n",
+		},
+		{
+			name:     "data of several lines",
+			event:    Event{Data: "first\r\nsecond\nthird"},
+			expected: "data: first\ndata: second\ndata: third\n\n",
+		},
+		{
+			name:     "empty data",
+			event:    Event{Type: "ping"},
+			expected: "event: ping\ndata: \n\n",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			var buffer bytes.Buffer
+
+			require.NoError(t, writeEvent(bufio.NewWriter(&buffer), test.event))
+			assert.Equal(t, test.expected, buffer.String())
+		})
+	}
+}
+
+func TestWriteRetryAndHeartbeat(t *testing.T) {
+	t.Parallel()
+
+	var buffer bytes.Buffer
+
+	writer := bufio.NewWriter(&buffer)
+
+	require.NoError(t, writeRetry(writer, 3*time.Second))
+	require.NoError(t, writeHeartbeat(writer))
+	assert.Equal(t, "retry: 3000\n\n: heartbeat\n\n", buffer.String())
+}
+
+func TestAfter(t *testing.T) {
+	t.Parallel()
+
+	assert.True(t, after("2-0", "1-5"))
+	assert.True(t, after("1-6", "1-5"))
+	assert.False(t, after("1-5", "1-5"))
+	assert.False(t, after("1-4", "1-5"))
+	assert.False(t, after("0-9", "1-0"))
+
+	// events without IDs are always written
+	assert.True(t, after("", "1-5"))
+	assert.True(t, after("1-5", "invalid"))
+
+	_, _, ok := parseID("1-x")
+	assert.False(t, ok)
+
+	ms, seq, ok := parseID("1700000000000-3")
+	require.True(t, ok)
+	assert.Equal(t, uint64(1700000000000), ms)
+	assert.Equal(t, uint64(3), seq)
+}
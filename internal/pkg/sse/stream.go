@@ -0,0 +1,224 @@
+package sse
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/apierror"
+)
+
+// client represents a stream of events to a user.
+type client struct {
+	// userID is ID of the user authenticated on the request.
+	userID string
+
+	// events buffers events delivered to the client.
+	events chan Event
+
+	// dropped is closed when the buffer overflowed, ending the stream.
+	dropped chan struct{}
+
+	// dropOnce closes dropped once.
+	dropOnce sync.Once
+}
+
+// drop ends the stream of the client, false if it was already dropped.
+func (c *client) drop() bool {
+	dropped := false
+
+	c.dropOnce.Do(func() {
+		close(c.dropped)
+
+		dropped = true
+	})
+
+	return dropped
+}
+
+// Handler returns the handler streaming events to the user of the request returned by the function,
+// e.g. the user authenticated by a middleware.
+func (b *Broker) Handler(userID func(request *http.Request) string) http.Handler {
+	return http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		if err := b.Serve(writer, request, userID(request)); err != nil {
+			b.logger.Ctx(request.Context()).Debug().Err(err).Msg("sse stream ended")
+		}
+	})
+}
+
+// Serve streams events of the user to the response until the client disconnects or the broker shuts down,
+// first replaying events after the Last-Event-ID header of reconnecting clients.
+func (b *Broker) Serve(writer http.ResponseWriter, request *http.Request, userID string) error {
+	b.mu.RLock()
+	closed := b.closed
+	b.mu.RUnlock()
+
+	if closed {
+		writeError(writer, http.StatusServiceUnavailable, ErrBrokerClosed.Error())
+
+		return ErrBrokerClosed
+	}
+
+	controller := http.NewResponseController(writer)
+
+	header := writer.Header()
+	header.Set("Content-Type", "text/event-stream")
+	header.Set("Cache-Control", "no-cache")
+	header.Set("X-Accel-Buffering", "no")
+
+	writer.WriteHeader(http.StatusOK)
+
+	if err := controller.Flush(); err != nil {
+		return fmt.Errorf("%w: %w", ErrStreamingUnsupported, err)
+	}
+
+	// streams outlive the write timeout of the server, the error is ignored if deadlines are not supported
+	_ = controller.SetWriteDeadline(time.Time{})
+
+	client := &client{
+		userID:  userID,
+		events:  make(chan Event, *b.config.Buffer),
+		dropped: make(chan struct{}),
+	}
+
+	// the client is registered before the replay, so that events published meanwhile are not missed
+	if !b.register(client) {
+		return ErrBrokerClosed
+	}
+	defer b.unregister(client)
+
+	stream := &stream{writer: bufio.NewWriter(writer), controller: controller}
+
+	if err := b.start(request.Context(), stream, client, request.Header.Get("Last-Event-ID")); err != nil {
+		return err
+	}
+
+	heartbeat := time.NewTicker(*b.config.Heartbeat)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-request.Context().Done():
+			return nil
+		case <-b.done:
+			return nil
+		case <-client.dropped:
+			return nil
+		case <-heartbeat.C:
+			if err := stream.heartbeat(); err != nil {
+				return err
+			}
+		case event := <-client.events:
+			if err := stream.send(event); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// start writes the reconnection delay and replays events after the last event ID of the client.
+func (b *Broker) start(ctx context.Context, stream *stream, client *client, lastEventID string) error {
+	if err := writeRetry(stream.writer, *b.config.Retry); err != nil {
+		return fmt.Errorf("failed to write sse retry: %w", err)
+	}
+
+	if _, _, ok := parseID(lastEventID); ok && *b.config.HistorySize > 0 {
+		stream.lastID = lastEventID
+
+		if err := b.replay(ctx, stream, client); err != nil {
+			return err
+		}
+	}
+
+	return stream.flush()
+}
+
+// replay writes events of the history after the last ID of the stream addressed to the client. Events older
+// than the history are not replayed.
+func (b *Broker) replay(ctx context.Context, stream *stream, client *client) error {
+	messages, err := b.redis.XRangeN(ctx, *b.config.HistoryKey, stream.lastID, "+", *b.config.HistorySize).Result()
+	if err != nil {
+		return fmt.Errorf("failed to read sse history: %w", err)
+	}
+
+	for _, message := range messages {
+		userID, _ := message.Values["user_id"].(string)
+		if userID != "" && userID != client.userID {
+			continue
+		}
+
+		eventType, _ := message.Values["type"].(string)
+		data, _ := message.Values["data"].(string)
+
+		if err := stream.write(Event{ID: message.ID, Type: eventType, Data: data}); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// stream writes events to a response.
+type stream struct {
+	// writer buffers writes to the response.
+	writer *bufio.Writer
+
+	// controller flushes the response.
+	controller *http.ResponseController
+
+	// lastID is ID of the last event written, events with IDs up to it were replayed.
+	lastID string
+}
+
+// write writes the event unless it was already written by the replay.
+func (s *stream) write(event Event) error {
+	if event.ID != "" && s.lastID != "" && !after(event.ID, s.lastID) {
+		return nil
+	}
+
+	if err := writeEvent(s.writer, event); err != nil {
+		return fmt.Errorf("failed to write sse event: %w", err)
+	}
+
+	if event.ID != "" {
+		s.lastID = event.ID
+	}
+
+	return nil
+}
+
+// send writes the event and flushes the response.
+func (s *stream) send(event Event) error {
+	if err := s.write(event); err != nil {
+		return err
+	}
+
+	return s.flush()
+}
+
+// heartbeat writes a heartbeat comment and flushes the response.
+func (s *stream) heartbeat() error {
+	if err := writeHeartbeat(s.writer); err != nil {
+		return fmt.Errorf("failed to write sse heartbeat: %w", err)
+	}
+
+	return s.flush()
+}
+
+// flush flushes the response to the client.
+func (s *stream) flush() error {
+	if err := s.controller.Flush(); err != nil {
+		return fmt.Errorf("failed to flush sse stream: %w", err)
+	}
+
+	return nil
+}
+
+// writeError writes the error response of requests that can not stream.
+func writeError(writer http.ResponseWriter, status int, message string) {
+	// error is ignored since nothing else can be written to the client
+	_ = apierror.Write(writer, status, &apierror.Response{Error: message})
+}
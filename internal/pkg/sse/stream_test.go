@@ -0,0 +1,192 @@
+package sse
+
+import (
+	"bufio"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// serveTestBroker serves the broker on a test server, authenticating users by the user query parameter.
+func serveTestBroker(t *testing.T, broker *Broker) string {
+	t.Helper()
+
+	server := httptest.NewServer(broker.Handler(func(request *http.Request) string {
+		return request.URL.Query().Get("user")
+	}))
+
+	t.Cleanup(func() {
+		broker.Shutdown()
+		server.Close()
+	})
+
+	return server.URL
+}
+
+// testStream is a stream of events read by a test client.
+type testStream struct {
+	// response is the streaming response.
+	response *http.Response
+
+	// reader reads blocks of the stream.
+	reader *bufio.Reader
+}
+
+// connectTestStream opens a stream of the user, reconnecting after the last event ID if it is set, and waits
+// until it is registered.
+func connectTestStream(t *testing.T, broker *Broker, serverURL, userID, lastEventID string) *testStream {
+	t.Helper()
+
+	streams := broker.Streams(userID)
+
+	request, err := http.NewRequestWithContext(t.Context(), http.MethodGet, serverURL+"?user="+userID, nil)
+	require.NoError(t, err)
+
+	if lastEventID != "" {
+		request.Header.Set("Last-Event-ID", lastEventID)
+	}
+
+	response, err := http.DefaultClient.Do(request)
+	require.NoError(t, err)
+
+	t.Cleanup(func() {
+		_ = response.Body.Close()
+	})
+
+	require.Equal(t, http.StatusOK, response.StatusCode)
+	assert.Equal(t, "text/event-stream", response.Header.Get("Content-Type"))
+	assert.Equal(t, "no-cache", response.Header.Get("Cache-Control"))
+
+	require.Eventually(t, func() bool { return broker.Streams(userID) == streams+1 }, time.Second, 5*time.Millisecond)
+
+	stream := &testStream{response: response, reader: bufio.NewReader(response.Body)}
+	assert.Equal(t, "retry: 3000\n", stream.readBlock(t))
+
+	return stream
+}
+
+// readBlock reads the lines of the stream up to the next blank line.
+func (s *testStream) readBlock(t *testing.T) string {
+	t.Helper()
+
+	var block strings.Builder
+
+	for {
+		line, err := s.reader.ReadString('\n')
+		require.NoError(t, err)
+
+		if line == "\n" {
+			return block.String()
+		}
+
+		block.WriteString(line)
+	}
+}
+
+func TestServe(t *testing.T) {
+	t.Parallel()
+
+	redisClient := setupTestRedis(t)
+
+	t.Run("stream events addressed to the user", func(t *testing.T) {
+		t.Parallel()
+
+		broker := setupTestBroker(t, redisClient, nil)
+		serverURL := serveTestBroker(t, broker)
+
+		alice := connectTestStream(t, broker, serverURL, "alice", "")
+		bob := connectTestStream(t, broker, serverURL, "bob", "")
+
+		id, err := broker.Send(t.Context(), "alice", Event{Type: "notification", Data: "hello alice"})
+		require.NoError(t, err)
+
+		broadcastID, err := broker.Broadcast(t.Context(), Event{Data: "hello\nall"})
+		require.NoError(t, err)
+
+		assert.Equal(t, "id: "+id+"\nevent: notification\ndata: hello alice\n", alice.readBlock(t))
+		assert.Equal(t, "id: "+broadcastID+"\ndata: hello\ndata: all\n", alice.readBlock(t))
+		assert.Equal(t, "id: "+broadcastID+"\ndata: hello\ndata: all\n", bob.readBlock(t))
+	})
+
+	t.Run("replay events missed after the last event ID", func(t *testing.T) {
+		t.Parallel()
+
+		broker := setupTestBroker(t, redisClient, nil)
+		serverURL := serveTestBroker(t, broker)
+
+		seen, err := broker.Send(t.Context(), "alice", Event{Data: "seen"})
+		require.NoError(t, err)
+
+		missed, err := broker.Send(t.Context(), "alice", Event{Data: "missed"})
+		require.NoError(t, err)
+
+		_, err = broker.Send(t.Context(), "bob", Event{Data: "not for alice"})
+		require.NoError(t, err)
+
+		missedBroadcast, err := broker.Broadcast(t.Context(), Event{Type: "news", Data: "missed by all"})
+		require.NoError(t, err)
+
+		alice := connectTestStream(t, broker, serverURL, "alice", seen)
+
+		assert.Equal(t, "id: "+missed+"\ndata: missed\n", alice.readBlock(t))
+		assert.Equal(t, "id: "+missedBroadcast+"\nevent: news\ndata: missed by all\n", alice.readBlock(t))
+
+		live, err := broker.Send(t.Context(), "alice", Event{Data: "live"})
+		require.NoError(t, err)
+
+		assert.Equal(t, "id: "+live+"\ndata: live\n", alice.readBlock(t))
+	})
+
+	t.Run("write heartbeats on idle streams", func(t *testing.T) {
+		t.Parallel()
+
+		broker := setupTestBroker(t, redisClient, &Config{Heartbeat: &[]time.Duration{20 * time.Millisecond}[0]})
+		serverURL := serveTestBroker(t, broker)
+
+		stream := connectTestStream(t, broker, serverURL, "alice", "")
+
+		assert.Equal(t, ": heartbeat\n", stream.readBlock(t))
+		assert.Equal(t, ": heartbeat\n", stream.readBlock(t))
+	})
+
+	t.Run("end streams on shutdown and reject new streams", func(t *testing.T) {
+		t.Parallel()
+
+		broker := setupTestBroker(t, redisClient, nil)
+		serverURL := serveTestBroker(t, broker)
+
+		stream := connectTestStream(t, broker, serverURL, "alice", "")
+
+		broker.Shutdown()
+
+		_, err := stream.reader.ReadString('\n')
+		require.Error(t, err)
+		require.Eventually(t, func() bool { return broker.Streams("") == 0 }, time.Second, 5*time.Millisecond)
+
+		request, err := http.NewRequestWithContext(context.Background(), http.MethodGet, serverURL, nil)
+		require.NoError(t, err)
+
+		response, err := http.DefaultClient.Do(request)
+		require.NoError(t, err)
+		require.NoError(t, response.Body.Close())
+		assert.Equal(t, http.StatusServiceUnavailable, response.StatusCode)
+	})
+
+	t.Run("unregister streams of clients disconnecting", func(t *testing.T) {
+		t.Parallel()
+
+		broker := setupTestBroker(t, redisClient, nil)
+		serverURL := serveTestBroker(t, broker)
+
+		stream := connectTestStream(t, broker, serverURL, "alice", "")
+		require.NoError(t, stream.response.Body.Close())
+
+		require.Eventually(t, func() bool { return broker.Streams("alice") == 0 }, time.Second, 5*time.Millisecond)
+	})
+}
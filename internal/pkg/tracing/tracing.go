@@ -0,0 +1,235 @@
+// Package tracing provides distributed tracing.
+package tracing
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.37.0"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
+	"go.uber.org/fx"
+
+	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/version"
+)
+
+var (
+	// ErrUnsupportedProtocol returned when the exporter protocol is not supported.
+	ErrUnsupportedProtocol = errors.New("unsupported otlp protocol")
+
+	// ErrInvalidSampleRatio returned when the sample ratio is out of range.
+	ErrInvalidSampleRatio = errors.New("invalid sample ratio")
+)
+
+// Protocol represents transport protocol of the OTLP exporter.
+type Protocol string
+
+const (
+	// ProtocolGRPC exports spans over OTLP/gRPC.
+	ProtocolGRPC Protocol = "grpc"
+
+	// ProtocolHTTP exports spans over OTLP/HTTP with protobuf encoding.
+	ProtocolHTTP Protocol = "http"
+)
+
+// Config represents configuration for tracing.
+type Config struct {
+	// Enabled is whether spans are exported, spans are not recorded if disabled.
+	Enabled *bool `json:"enabled"`
+
+	// ServiceName is service.name resource attribute of spans.
+	ServiceName *string `json:"service_name"`
+
+	// Protocol is transport protocol of the OTLP exporter (grpc, http).
+	Protocol *Protocol `json:"protocol"`
+
+	// Endpoint is host:port of the OTLP collector, the default port of the protocol is used if empty.
+	Endpoint *string `json:"endpoint"`
+
+	// Insecure is whether spans are exported without TLS.
+	Insecure *bool `json:"insecure"`
+
+	// Headers is headers sent with exports, such as authentication of a hosted collector.
+	Headers map[string]string `json:"headers"`
+
+	// SampleRatio is ratio of traces started by this service to sample (0-1),
+	// traces started upstream follow the sampling decision of the parent.
+	SampleRatio *float64 `json:"sample_ratio"`
+}
+
+// SetDefault sets default values.
+func (c *Config) SetDefault() {
+	if c.Enabled == nil {
+		c.Enabled = &[]bool{false}[0]
+	}
+
+	if c.ServiceName == nil {
+		c.ServiceName = &[]string{"boilerplate"}[0]
+	}
+
+	if c.Protocol == nil {
+		c.Protocol = &[]Protocol{ProtocolGRPC}[0]
+	}
+
+	if c.Endpoint == nil {
+		c.Endpoint = &[]string{""}[0]
+	}
+
+	if c.Insecure == nil {
+		c.Insecure = &[]bool{true}[0]
+	}
+
+	if c.Headers == nil {
+		c.Headers = map[string]string{}
+	}
+
+	if c.SampleRatio == nil {
+		c.SampleRatio = &[]float64{1}[0]
+	}
+}
+
+// Validate validates the tracing config.
+func (c *Config) Validate() error {
+	if *c.Protocol != ProtocolGRPC && *c.Protocol != ProtocolHTTP {
+		return fmt.Errorf("%w: %s", ErrUnsupportedProtocol, *c.Protocol)
+	}
+
+	if *c.SampleRatio < 0 || *c.SampleRatio > 1 {
+		return fmt.Errorf("%w: %v", ErrInvalidSampleRatio, *c.SampleRatio)
+	}
+
+	return nil
+}
+
+// Tracing represents tracing, registered as the global tracer provider.
+type Tracing struct {
+	// provider provides the SDK tracer provider, nil if tracing is disabled.
+	provider *sdktrace.TracerProvider
+}
+
+// NewModule provides module for tracing.
+func NewModule() fx.Option {
+	return fx.Module("tracing",
+		fx.Provide(New),
+	)
+}
+
+// New creates new tracing instance, registering its tracer provider and the W3C trace context propagator globally
+// so that spans of all packages started with otel.Tracer are exported.
+func New(config *Config) (*Tracing, error) {
+	if config == nil {
+		config = &Config{}
+	}
+
+	config.SetDefault()
+
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+
+	// propagate trace context even if disabled, so that traces of upstream services continue downstream
+	otel.SetTextMapPropagator(Propagator())
+
+	if !*config.Enabled {
+		return &Tracing{}, nil
+	}
+
+	exporter, err := newExporter(config)
+	if err != nil {
+		return nil, err
+	}
+
+	serviceResource, err := resource.Merge(resource.Default(), resource.NewWithAttributes(
+		semconv.SchemaURL,
+		semconv.ServiceName(*config.ServiceName),
+		semconv.ServiceVersion(version.Version),
+	))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create tracing resource: %w", err)
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(serviceResource),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(*config.SampleRatio))),
+	)
+
+	otel.SetTracerProvider(provider)
+
+	return &Tracing{provider: provider}, nil
+}
+
+// Propagator returns the propagator of W3C traceparent and baggage headers, used without the SDK as well.
+func Propagator() propagation.TextMapPropagator {
+	return propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{},
+		propagation.Baggage{},
+	)
+}
+
+// newExporter creates the OTLP exporter of the protocol, the exporter connects lazily on the first export.
+func newExporter(config *Config) (*otlptrace.Exporter, error) {
+	var client otlptrace.Client
+
+	switch *config.Protocol {
+	case ProtocolHTTP:
+		options := []otlptracehttp.Option{otlptracehttp.WithHeaders(config.Headers)}
+		if *config.Endpoint != "" {
+			options = append(options, otlptracehttp.WithEndpoint(*config.Endpoint))
+		}
+
+		if *config.Insecure {
+			options = append(options, otlptracehttp.WithInsecure())
+		}
+
+		client = otlptracehttp.NewClient(options...)
+	default:
+		options := []otlptracegrpc.Option{otlptracegrpc.WithHeaders(config.Headers)}
+		if *config.Endpoint != "" {
+			options = append(options, otlptracegrpc.WithEndpoint(*config.Endpoint))
+		}
+
+		if *config.Insecure {
+			options = append(options, otlptracegrpc.WithInsecure())
+		}
+
+		client = otlptracegrpc.NewClient(options...)
+	}
+
+	exporter, err := otlptrace.New(context.Background(), client)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create otlp exporter: %w", err)
+	}
+
+	return exporter, nil
+}
+
+// TracerProvider returns the tracer provider, a no-op provider if tracing is disabled.
+func (t *Tracing) TracerProvider() trace.TracerProvider {
+	if t.provider == nil {
+		return noop.NewTracerProvider()
+	}
+
+	return t.provider
+}
+
+// Shutdown exports the remaining spans and stops the exporter.
+func (t *Tracing) Shutdown(ctx context.Context) error {
+	if t.provider == nil {
+		return nil
+	}
+
+	if err := t.provider.Shutdown(ctx); err != nil {
+		return fmt.Errorf("failed to shutdown tracer provider: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,98 @@
+package tracing
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+func TestConfig(t *testing.T) {
+	t.Parallel()
+
+	t.Run("set default values on tracing config", func(t *testing.T) {
+		t.Parallel()
+
+		config := &Config{}
+		config.SetDefault()
+
+		require.NotNil(t, config.Enabled)
+		assert.False(t, *config.Enabled)
+		require.NotNil(t, config.ServiceName)
+		assert.Equal(t, "boilerplate", *config.ServiceName)
+		require.NotNil(t, config.Protocol)
+		assert.Equal(t, ProtocolGRPC, *config.Protocol)
+		require.NotNil(t, config.SampleRatio)
+		assert.InDelta(t, 1.0, *config.SampleRatio, 0)
+		require.NoError(t, config.Validate())
+	})
+
+	t.Run("reject unsupported protocol", func(t *testing.T) {
+		t.Parallel()
+
+		config := &Config{Protocol: &[]Protocol{"zipkin"}[0]}
+		config.SetDefault()
+
+		require.ErrorIs(t, config.Validate(), ErrUnsupportedProtocol)
+	})
+
+	t.Run("reject out of range sample ratio", func(t *testing.T) {
+		t.Parallel()
+
+		config := &Config{SampleRatio: &[]float64{1.5}[0]}
+		config.SetDefault()
+
+		require.ErrorIs(t, config.Validate(), ErrInvalidSampleRatio)
+	})
+}
+
+//nolint:paralleltest // sequential execution required to replace the global tracer provider
+func TestNew(t *testing.T) {
+	t.Cleanup(func() { otel.SetTracerProvider(noop.NewTracerProvider()) })
+
+	t.Run("create disabled tracing", func(t *testing.T) {
+		tracing, err := New(nil)
+		require.NoError(t, err)
+
+		_, span := tracing.TracerProvider().Tracer("test").Start(context.Background(), "span")
+		assert.False(t, span.IsRecording())
+
+		require.NoError(t, tracing.Shutdown(context.Background()))
+	})
+
+	for _, protocol := range []Protocol{ProtocolGRPC, ProtocolHTTP} {
+		t.Run("create tracing exporting over "+string(protocol), func(t *testing.T) {
+			tracing, err := New(&Config{
+				Enabled:  &[]bool{true}[0],
+				Protocol: &protocol,
+				Endpoint: &[]string{"localhost:4317"}[0],
+			})
+			require.NoError(t, err)
+
+			// spans of all packages are recorded by the global provider
+			_, span := otel.Tracer("test").Start(context.Background(), "span")
+			assert.True(t, span.IsRecording())
+			span.End()
+
+			ctx, cancel := context.WithCancel(context.Background())
+			cancel()
+
+			// the collector is not running, shutdown returns without waiting for the export
+			_ = tracing.Shutdown(ctx)
+		})
+	}
+
+	t.Run("return error for invalid config", func(t *testing.T) {
+		_, err := New(&Config{Protocol: &[]Protocol{"zipkin"}[0]})
+		require.ErrorIs(t, err, ErrUnsupportedProtocol)
+	})
+}
+
+func TestPropagator(t *testing.T) {
+	t.Parallel()
+
+	assert.ElementsMatch(t, []string{"traceparent", "tracestate", "baggage"}, Propagator().Fields())
+}
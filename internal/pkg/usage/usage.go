@@ -0,0 +1,312 @@
+// Package usage provides per-tenant usage reporting, request and byte counts are aggregated in memory
+// and added to daily totals on database, so that totals of all instances feed billing exports.
+package usage
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"go.uber.org/fx"
+
+	"github.com/pocj8ur4in/boilerplate-go/internal/gen/db"
+	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/database"
+	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/logger"
+	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/readonly"
+)
+
+const (
+	// defaultFlushInterval is default interval of adding aggregated usage to database.
+	defaultFlushInterval = time.Minute
+
+	// defaultMaxTenants is default maximum number of tenants aggregated between flushes.
+	defaultMaxTenants = 10000
+
+	// dayLayout is layout of days usage is aggregated by.
+	dayLayout = "2006-01-02"
+)
+
+// Config represents configuration for usage reporting.
+type Config struct {
+	// Enabled is whether usage of tenants is recorded.
+	Enabled *bool `json:"enabled"`
+
+	// FlushInterval is interval of adding aggregated usage to database, it bounds usage lost on a crash.
+	FlushInterval *time.Duration `json:"flush_interval"`
+
+	// MaxTenants is maximum number of tenants aggregated between flushes, since tenant IDs come from clients,
+	// usage of further tenants is dropped until the next flush.
+	MaxTenants *int `json:"max_tenants"`
+}
+
+// SetDefault sets default values.
+func (c *Config) SetDefault() {
+	if c.Enabled == nil {
+		c.Enabled = &[]bool{false}[0]
+	}
+
+	if c.FlushInterval == nil {
+		c.FlushInterval = &[]time.Duration{defaultFlushInterval}[0]
+	}
+
+	if c.MaxTenants == nil {
+		c.MaxTenants = &[]int{defaultMaxTenants}[0]
+	}
+}
+
+// Usage represents usage of a tenant on a day.
+type Usage struct {
+	// TenantID is ID of the tenant.
+	TenantID string `json:"tenant_id"`
+
+	// Day is the day in UTC.
+	Day time.Time `json:"day"`
+
+	// Requests is number of requests.
+	Requests int64 `json:"requests"`
+
+	// RequestBytes is bytes of request bodies.
+	RequestBytes int64 `json:"request_bytes"`
+
+	// ResponseBytes is bytes of response bodies.
+	ResponseBytes int64 `json:"response_bytes"`
+}
+
+// key is key of aggregated usage.
+type key struct {
+	// tenantID is ID of the tenant.
+	tenantID string
+
+	// day is the day in UTC.
+	day string
+}
+
+// Recorder records usage of tenants.
+type Recorder struct {
+	// config provides usage configuration.
+	config *Config
+
+	// queries provides database queries.
+	queries db.Querier
+
+	// readOnly provides read-only mode, usage is kept in memory while it is on.
+	readOnly *readonly.ReadOnly
+
+	// logger provides logger.
+	logger *logger.Logger
+
+	// mu guards pending and dropped.
+	mu sync.Mutex
+
+	// pending is usage aggregated since the last flush.
+	pending map[key]*Usage
+
+	// dropped is number of requests dropped since the last flush for exceeding max tenants.
+	dropped int64
+
+	// flushMu serializes flushes.
+	flushMu sync.Mutex
+
+	// stop stops the flush loop.
+	stop chan struct{}
+
+	// done is closed when the flush loop exits.
+	done chan struct{}
+
+	// now returns the current time, replaced in tests.
+	now func() time.Time
+}
+
+// NewModule provides module for usage reporting.
+func NewModule() fx.Option {
+	return fx.Module("usage",
+		fx.Provide(New),
+	)
+}
+
+// New creates a new recorder adding usage to database.
+func New(config *Config, dbConn *database.DB, readOnly *readonly.ReadOnly, logger *logger.Logger) *Recorder {
+	return NewWithQuerier(config, dbConn.Queries, readOnly, logger)
+}
+
+// NewWithQuerier creates a new recorder adding usage using the querier.
+func NewWithQuerier(
+	config *Config,
+	queries db.Querier,
+	readOnly *readonly.ReadOnly,
+	logger *logger.Logger,
+) *Recorder {
+	if config == nil {
+		config = &Config{}
+	}
+
+	config.SetDefault()
+
+	return &Recorder{
+		config:   config,
+		queries:  queries,
+		readOnly: readOnly,
+		logger:   logger.Named("usage"),
+		pending:  make(map[key]*Usage),
+		now:      time.Now,
+	}
+}
+
+// Enabled returns whether usage of tenants is recorded.
+func (r *Recorder) Enabled() bool {
+	return r != nil && *r.config.Enabled
+}
+
+// Record records a request of the tenant, it does nothing if the recorder is disabled.
+func (r *Recorder) Record(tenantID string, requestBytes, responseBytes int64) {
+	if !r.Enabled() || tenantID == "" {
+		return
+	}
+
+	now := r.now().UTC()
+	k := key{tenantID: tenantID, day: now.Format(dayLayout)}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	usage, ok := r.pending[k]
+	if !ok {
+		if len(r.pending) >= *r.config.MaxTenants {
+			r.dropped++
+
+			return
+		}
+
+		day, _ := time.Parse(dayLayout, k.day)
+		usage = &Usage{TenantID: tenantID, Day: day}
+		r.pending[k] = usage
+	}
+
+	usage.Requests++
+	usage.RequestBytes += requestBytes
+	usage.ResponseBytes += responseBytes
+}
+
+// Flush adds the aggregated usage to database, usage failing to be added is kept for the next flush.
+// Usage is kept in memory while read-only mode is on.
+func (r *Recorder) Flush(ctx context.Context) error {
+	r.flushMu.Lock()
+	defer r.flushMu.Unlock()
+
+	if r.readOnly != nil && r.readOnly.Enabled(ctx) {
+		return nil
+	}
+
+	r.mu.Lock()
+	pending, dropped := r.pending, r.dropped
+	r.pending, r.dropped = make(map[key]*Usage), 0
+	r.mu.Unlock()
+
+	if dropped > 0 {
+		r.logger.Ctx(ctx).Warn().Int64("dropped", dropped).Int("max_tenants", *r.config.MaxTenants).
+			Msg("usage of requests dropped for exceeding max tenants")
+	}
+
+	var flushErr error
+
+	for k, usage := range pending {
+		err := r.queries.AddTenantUsage(ctx, &db.AddTenantUsageParams{
+			TenantID:      usage.TenantID,
+			Day:           pgtype.Date{Time: usage.Day, Valid: true},
+			Requests:      usage.Requests,
+			RequestBytes:  usage.RequestBytes,
+			ResponseBytes: usage.ResponseBytes,
+		})
+		if err != nil {
+			r.restore(k, usage)
+
+			flushErr = fmt.Errorf("failed to add tenant usage: %w", err)
+		}
+	}
+
+	return flushErr
+}
+
+// restore merges usage failing to be added back into the pending usage.
+func (r *Recorder) restore(k key, usage *Usage) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if pending, ok := r.pending[k]; ok {
+		pending.Requests += usage.Requests
+		pending.RequestBytes += usage.RequestBytes
+		pending.ResponseBytes += usage.ResponseBytes
+
+		return
+	}
+
+	r.pending[k] = usage
+}
+
+// Start starts flushing aggregated usage every flush interval, it does nothing if the recorder is disabled.
+func (r *Recorder) Start() {
+	if !r.Enabled() || r.stop != nil {
+		return
+	}
+
+	r.stop, r.done = make(chan struct{}), make(chan struct{})
+
+	go func() {
+		defer close(r.done)
+
+		ticker := time.NewTicker(*r.config.FlushInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-r.stop:
+				return
+			case <-ticker.C:
+				if err := r.Flush(context.Background()); err != nil {
+					r.logger.Error().Err(err).Msg("failed to flush usage")
+				}
+			}
+		}
+	}()
+}
+
+// Stop stops flushing and flushes the remaining usage.
+func (r *Recorder) Stop(ctx context.Context) error {
+	if r.stop == nil {
+		return nil
+	}
+
+	close(r.stop)
+	<-r.done
+
+	r.stop = nil
+
+	return r.Flush(ctx)
+}
+
+// Report returns daily usage of tenants on days from the day of from until the day of to, excluded,
+// in order of day and tenant.
+func (r *Recorder) Report(ctx context.Context, from, to time.Time) ([]*Usage, error) {
+	rows, err := r.queries.ListTenantUsage(ctx, &db.ListTenantUsageParams{
+		FromDay: pgtype.Date{Time: from.UTC(), Valid: true},
+		ToDay:   pgtype.Date{Time: to.UTC(), Valid: true},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list tenant usage: %w", err)
+	}
+
+	usages := make([]*Usage, 0, len(rows))
+	for _, row := range rows {
+		usages = append(usages, &Usage{
+			TenantID:      row.TenantID,
+			Day:           row.Day.Time,
+			Requests:      row.Requests,
+			RequestBytes:  row.RequestBytes,
+			ResponseBytes: row.ResponseBytes,
+		})
+	}
+
+	return usages, nil
+}
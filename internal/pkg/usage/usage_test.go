@@ -0,0 +1,254 @@
+package usage
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pocj8ur4in/boilerplate-go/internal/gen/db"
+	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/logger"
+	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/readonly"
+)
+
+var errQueryFailed = errors.New("query failed")
+
+// mockUsageQuerier is a mock querier adding usage in memory.
+type mockUsageQuerier struct {
+	db.Querier
+
+	mu     sync.Mutex
+	totals map[string]*db.AddTenantUsageParams
+	err    error
+}
+
+func (m *mockUsageQuerier) AddTenantUsage(_ context.Context, arg *db.AddTenantUsageParams) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.err != nil {
+		return m.err
+	}
+
+	k := arg.TenantID + "/" + arg.Day.Time.Format(dayLayout)
+	if total, ok := m.totals[k]; ok {
+		total.Requests += arg.Requests
+		total.RequestBytes += arg.RequestBytes
+		total.ResponseBytes += arg.ResponseBytes
+
+		return nil
+	}
+
+	added := *arg
+	m.totals[k] = &added
+
+	return nil
+}
+
+func (m *mockUsageQuerier) ListTenantUsage(_ context.Context, arg *db.ListTenantUsageParams) ([]*db.TenantUsage, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+
+	return []*db.TenantUsage{{
+		TenantID: "tenant-a", Day: arg.FromDay, Requests: 3, RequestBytes: 10, ResponseBytes: 20,
+	}}, nil
+}
+
+// total returns usage added for the tenant on the day.
+func (m *mockUsageQuerier) total(tenantID, day string) *db.AddTenantUsageParams {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.totals[tenantID+"/"+day]
+}
+
+// setupTestRecorder creates an enabled recorder adding usage to the querier.
+func setupTestRecorder(t *testing.T, config *Config, querier db.Querier, readOnly *readonly.ReadOnly) *Recorder {
+	t.Helper()
+
+	log, err := logger.New(&logger.Config{})
+	require.NoError(t, err)
+
+	if config == nil {
+		config = &Config{}
+	}
+
+	if config.Enabled == nil {
+		config.Enabled = &[]bool{true}[0]
+	}
+
+	recorder := NewWithQuerier(config, querier, readOnly, log)
+	recorder.now = func() time.Time { return time.Date(2024, 1, 2, 23, 0, 0, 0, time.UTC) }
+
+	return recorder
+}
+
+func TestConfigSetDefault(t *testing.T) {
+	t.Parallel()
+
+	config := &Config{}
+	config.SetDefault()
+
+	assert.False(t, *config.Enabled)
+	assert.Equal(t, defaultFlushInterval, *config.FlushInterval)
+	assert.Equal(t, defaultMaxTenants, *config.MaxTenants)
+}
+
+func TestNewModule(t *testing.T) {
+	t.Parallel()
+
+	t.Run("return fx.Option", func(t *testing.T) {
+		t.Parallel()
+
+		require.NotNil(t, NewModule())
+	})
+}
+
+func TestRecorder(t *testing.T) {
+	t.Parallel()
+
+	t.Run("add aggregated usage by tenant and day", func(t *testing.T) {
+		t.Parallel()
+
+		querier := &mockUsageQuerier{totals: make(map[string]*db.AddTenantUsageParams)}
+		recorder := setupTestRecorder(t, nil, querier, nil)
+
+		recorder.Record("tenant-a", 10, 100)
+		recorder.Record("tenant-a", 5, 50)
+		recorder.Record("tenant-b", 0, 7)
+		recorder.Record("", 1, 1)
+
+		require.NoError(t, recorder.Flush(context.Background()))
+
+		assert.Equal(t, &db.AddTenantUsageParams{
+			TenantID:      "tenant-a",
+			Day:           pgtype.Date{Time: time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC), Valid: true},
+			Requests:      2,
+			RequestBytes:  15,
+			ResponseBytes: 150,
+		}, querier.total("tenant-a", "2024-01-02"))
+		assert.Equal(t, int64(1), querier.total("tenant-b", "2024-01-02").Requests)
+
+		// flushed usage is not added again
+		require.NoError(t, recorder.Flush(context.Background()))
+		assert.Equal(t, int64(2), querier.total("tenant-a", "2024-01-02").Requests)
+	})
+
+	t.Run("record nothing while disabled", func(t *testing.T) {
+		t.Parallel()
+
+		querier := &mockUsageQuerier{totals: make(map[string]*db.AddTenantUsageParams)}
+		recorder := setupTestRecorder(t, &Config{Enabled: &[]bool{false}[0]}, querier, nil)
+
+		recorder.Record("tenant-a", 10, 100)
+		require.NoError(t, recorder.Flush(context.Background()))
+		assert.Nil(t, querier.total("tenant-a", "2024-01-02"))
+
+		var nilRecorder *Recorder
+		nilRecorder.Record("tenant-a", 10, 100)
+		assert.False(t, nilRecorder.Enabled())
+	})
+
+	t.Run("drop usage of tenants exceeding max tenants", func(t *testing.T) {
+		t.Parallel()
+
+		querier := &mockUsageQuerier{totals: make(map[string]*db.AddTenantUsageParams)}
+		recorder := setupTestRecorder(t, &Config{MaxTenants: &[]int{1}[0]}, querier, nil)
+
+		recorder.Record("tenant-a", 0, 0)
+		recorder.Record("tenant-b", 0, 0)
+		recorder.Record("tenant-a", 0, 0)
+
+		require.NoError(t, recorder.Flush(context.Background()))
+		assert.Equal(t, int64(2), querier.total("tenant-a", "2024-01-02").Requests)
+		assert.Nil(t, querier.total("tenant-b", "2024-01-02"))
+	})
+
+	t.Run("keep usage failing to be added for next flush", func(t *testing.T) {
+		t.Parallel()
+
+		querier := &mockUsageQuerier{totals: make(map[string]*db.AddTenantUsageParams), err: errQueryFailed}
+		recorder := setupTestRecorder(t, nil, querier, nil)
+
+		recorder.Record("tenant-a", 1, 1)
+		require.ErrorIs(t, recorder.Flush(context.Background()), errQueryFailed)
+
+		recorder.Record("tenant-a", 1, 1)
+
+		querier.mu.Lock()
+		querier.err = nil
+		querier.mu.Unlock()
+
+		require.NoError(t, recorder.Flush(context.Background()))
+		assert.Equal(t, int64(2), querier.total("tenant-a", "2024-01-02").Requests)
+	})
+
+	t.Run("keep usage in memory while read-only", func(t *testing.T) {
+		t.Parallel()
+
+		readOnly := readonly.New(&readonly.Config{}, nil)
+		require.NoError(t, readOnly.Set(context.Background(), true))
+
+		querier := &mockUsageQuerier{totals: make(map[string]*db.AddTenantUsageParams)}
+		recorder := setupTestRecorder(t, nil, querier, readOnly)
+
+		recorder.Record("tenant-a", 1, 1)
+		require.NoError(t, recorder.Flush(context.Background()))
+		assert.Nil(t, querier.total("tenant-a", "2024-01-02"))
+
+		require.NoError(t, readOnly.Set(context.Background(), false))
+		require.NoError(t, recorder.Flush(context.Background()))
+		assert.Equal(t, int64(1), querier.total("tenant-a", "2024-01-02").Requests)
+	})
+
+	t.Run("flush periodically and on stop", func(t *testing.T) {
+		t.Parallel()
+
+		querier := &mockUsageQuerier{totals: make(map[string]*db.AddTenantUsageParams)}
+		recorder := setupTestRecorder(t, &Config{FlushInterval: &[]time.Duration{10 * time.Millisecond}[0]}, querier, nil)
+
+		recorder.Start()
+		recorder.Record("tenant-a", 1, 1)
+
+		require.Eventually(t, func() bool {
+			return querier.total("tenant-a", "2024-01-02") != nil
+		}, time.Second, 5*time.Millisecond)
+
+		recorder.Record("tenant-b", 1, 1)
+		require.NoError(t, recorder.Stop(context.Background()))
+		assert.NotNil(t, querier.total("tenant-b", "2024-01-02"))
+	})
+}
+
+func TestReport(t *testing.T) {
+	t.Parallel()
+
+	t.Run("return daily usage", func(t *testing.T) {
+		t.Parallel()
+
+		recorder := setupTestRecorder(t, nil, &mockUsageQuerier{}, nil)
+
+		from := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+		usages, err := recorder.Report(context.Background(), from, from.AddDate(0, 0, 1))
+		require.NoError(t, err)
+		assert.Equal(t, []*Usage{{
+			TenantID: "tenant-a", Day: from, Requests: 3, RequestBytes: 10, ResponseBytes: 20,
+		}}, usages)
+	})
+
+	t.Run("return error if listing fails", func(t *testing.T) {
+		t.Parallel()
+
+		recorder := setupTestRecorder(t, nil, &mockUsageQuerier{err: errQueryFailed}, nil)
+
+		_, err := recorder.Report(context.Background(), time.Now(), time.Now())
+		require.ErrorIs(t, err, errQueryFailed)
+	})
+}
@@ -0,0 +1,404 @@
+// Package user provides users stored on database with bcrypt hashed passwords.
+package user
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/mail"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"go.uber.org/fx"
+	"golang.org/x/crypto/bcrypt"
+
+	"github.com/pocj8ur4in/boilerplate-go/internal/gen/db"
+	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/database"
+)
+
+var (
+	// ErrInvalidEmail returned when the email is not a valid address.
+	ErrInvalidEmail = errors.New("invalid email")
+
+	// ErrInvalidPassword returned when the password is shorter than the minimum or longer than bcrypt accepts.
+	ErrInvalidPassword = errors.New("invalid password")
+
+	// ErrEmailTaken returned when a user with the email already exists.
+	ErrEmailTaken = errors.New("email already taken")
+
+	// ErrInvalidCredentials returned when the email or password does not match.
+	ErrInvalidCredentials = errors.New("invalid credentials")
+
+	// ErrNotFound returned when the user does not exist.
+	ErrNotFound = errors.New("user not found")
+
+	// ErrInvalidRole returned when the role is empty.
+	ErrInvalidRole = errors.New("invalid role")
+
+	// ErrIdentityConflict returned when the user of the email is linked to another identity of the provider.
+	ErrIdentityConflict = errors.New("user linked to another identity")
+
+	// ErrLocalPassword returned when the user of the email to link has a local password, since whoever signed up
+	// with the email would keep logging in as the linked user.
+	ErrLocalPassword = errors.New("user has a local password")
+)
+
+const (
+	// idLength is number of random bytes of user IDs.
+	idLength = 16
+
+	// maxPasswordLength is maximum length of passwords in bytes, bcrypt ignores the rest.
+	maxPasswordLength = 72
+
+	// uniqueViolation is the postgres error code of unique constraint violations.
+	uniqueViolation = "23505"
+)
+
+// Config represents configuration for users.
+type Config struct {
+	// PasswordCost is bcrypt cost of password hashes.
+	PasswordCost *int `json:"password_cost"`
+
+	// MinPasswordLength is minimum length of passwords.
+	MinPasswordLength *int `json:"min_password_length"`
+
+	// DefaultRole is role of signed up users.
+	DefaultRole *string `json:"default_role"`
+}
+
+// SetDefault sets default values.
+func (c *Config) SetDefault() {
+	if c.PasswordCost == nil {
+		c.PasswordCost = &[]int{bcrypt.DefaultCost}[0]
+	}
+
+	if c.MinPasswordLength == nil {
+		c.MinPasswordLength = &[]int{8}[0]
+	}
+
+	if c.DefaultRole == nil {
+		c.DefaultRole = &[]string{"user"}[0]
+	}
+}
+
+// User represents a user, without its password hash.
+type User struct {
+	// ID is identifier of the user.
+	ID string `json:"id"`
+
+	// Email is email of the user.
+	Email string `json:"email"`
+
+	// Role is role of the user.
+	Role string `json:"role"`
+
+	// CreatedAt is time the user signed up.
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// RoleChangeHook is called after the role of the user changed.
+type RoleChangeHook func(ctx context.Context, userID string)
+
+// Service provides users stored on database.
+type Service struct {
+	// queries provides database queries.
+	queries db.Querier
+
+	// config provides user configuration.
+	config *Config
+
+	// dummyHash is compared on login of unknown emails so response time does not reveal them.
+	dummyHash []byte
+
+	// mu guards roleChangeHooks.
+	mu sync.Mutex
+
+	// roleChangeHooks are called after the role of a user changed.
+	roleChangeHooks []RoleChangeHook
+}
+
+// NewModule provides module for users.
+func NewModule() fx.Option {
+	return fx.Module("user",
+		fx.Provide(New),
+	)
+}
+
+// New creates a new user service on database.
+func New(config *Config, dbConn *database.DB) (*Service, error) {
+	return NewWithQuerier(config, dbConn.Queries)
+}
+
+// NewWithQuerier creates a new user service using the querier.
+func NewWithQuerier(config *Config, queries db.Querier) (*Service, error) {
+	if config == nil {
+		config = &Config{}
+	}
+
+	config.SetDefault()
+
+	dummyHash, err := bcrypt.GenerateFromPassword([]byte("dummy password"), *config.PasswordCost)
+	if err != nil {
+		return nil, fmt.Errorf("failed to hash dummy password: %w", err)
+	}
+
+	return &Service{
+		queries:   queries,
+		config:    config,
+		dummyHash: dummyHash,
+	}, nil
+}
+
+// Signup creates a user of the email and password with the default role.
+func (s *Service) Signup(ctx context.Context, email, password string) (*User, error) {
+	email, err := normalizeEmail(email)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(password) < *s.config.MinPasswordLength || len(password) > maxPasswordLength {
+		return nil, ErrInvalidPassword
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), *s.config.PasswordCost)
+	if err != nil {
+		return nil, fmt.Errorf("failed to hash password: %w", err)
+	}
+
+	row, err := s.create(ctx, email, string(hash))
+	if err != nil {
+		return nil, err
+	}
+
+	return fromRow(row), nil
+}
+
+// Provision returns the user of the email, created with the default role and without a password if it does not
+// exist, for users authenticated by identity providers. Users without a password can not log in with one.
+func (s *Service) Provision(ctx context.Context, email string) (*User, error) {
+	row, err := s.provision(ctx, email)
+	if err != nil {
+		return nil, err
+	}
+
+	return fromRow(row), nil
+}
+
+// ProvisionIdentity returns the user linked to the subject of the provider, or else provisions the user of the
+// email and links it, so that later logins of the subject keep their user when its email changes.
+// ErrIdentityConflict if the user of the email is linked to another subject of the provider, ErrLocalPassword if
+// it has a local password, since the email of signed up users is not verified.
+func (s *Service) ProvisionIdentity(ctx context.Context, provider, subject, email string) (*User, error) {
+	identity, err := s.queries.GetUserIdentity(ctx, &db.GetUserIdentityParams{Provider: provider, Subject: subject})
+
+	switch {
+	case err == nil:
+		return s.Get(ctx, identity.UserID)
+	case !errors.Is(err, pgx.ErrNoRows):
+		return nil, fmt.Errorf("failed to get user identity: %w", err)
+	}
+
+	row, err := s.provision(ctx, email)
+	if err != nil {
+		return nil, err
+	}
+
+	if row.PasswordHash != "" {
+		return nil, ErrLocalPassword
+	}
+
+	provisioned := fromRow(row)
+
+	_, err = s.queries.CreateUserIdentity(ctx, &db.CreateUserIdentityParams{
+		Provider: provider,
+		Subject:  subject,
+		UserID:   provisioned.ID,
+	})
+	if err == nil {
+		return provisioned, nil
+	}
+
+	var pgErr *pgconn.PgError
+	if !errors.As(err, &pgErr) || pgErr.Code != uniqueViolation {
+		return nil, fmt.Errorf("failed to create user identity: %w", err)
+	}
+
+	// the user is linked to another subject, or the subject was linked by a concurrent provision
+	identity, err = s.queries.GetUserIdentityByUserID(ctx, &db.GetUserIdentityByUserIDParams{
+		Provider: provider,
+		UserID:   provisioned.ID,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user identity: %w", err)
+	}
+
+	if identity.Subject != subject {
+		return nil, ErrIdentityConflict
+	}
+
+	return provisioned, nil
+}
+
+// provision returns the row of the user of the email, created without a password if it does not exist.
+func (s *Service) provision(ctx context.Context, email string) (*db.User, error) {
+	email, err := normalizeEmail(email)
+	if err != nil {
+		return nil, err
+	}
+
+	row, err := s.queries.GetUserByEmail(ctx, email)
+
+	switch {
+	case err == nil:
+		return row, nil
+	case !errors.Is(err, pgx.ErrNoRows):
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+
+	row, err = s.create(ctx, email, "")
+	if !errors.Is(err, ErrEmailTaken) {
+		return row, err
+	}
+
+	// the user was created by a concurrent provision
+	row, err = s.queries.GetUserByEmail(ctx, email)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+
+	return row, nil
+}
+
+// create creates the row of a user of the email and password hash with the default role, ErrEmailTaken if the
+// email is taken.
+func (s *Service) create(ctx context.Context, email, passwordHash string) (*db.User, error) {
+	id := make([]byte, idLength)
+	if _, err := rand.Read(id); err != nil {
+		return nil, fmt.Errorf("failed to generate user id: %w", err)
+	}
+
+	row, err := s.queries.CreateUser(ctx, &db.CreateUserParams{
+		ID:           hex.EncodeToString(id),
+		Email:        email,
+		PasswordHash: passwordHash,
+		Role:         *s.config.DefaultRole,
+	})
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == uniqueViolation {
+			return nil, ErrEmailTaken
+		}
+
+		return nil, fmt.Errorf("failed to create user: %w", err)
+	}
+
+	return row, nil
+}
+
+// Login returns the user of the email if the password matches, ErrInvalidCredentials otherwise.
+func (s *Service) Login(ctx context.Context, email, password string) (*User, error) {
+	row, err := s.queries.GetUserByEmail(ctx, strings.ToLower(strings.TrimSpace(email)))
+
+	switch {
+	case errors.Is(err, pgx.ErrNoRows):
+		// compare anyway so unknown emails take as long as wrong passwords
+		_ = bcrypt.CompareHashAndPassword(s.dummyHash, []byte(password))
+
+		return nil, ErrInvalidCredentials
+	case err != nil:
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+
+	// provisioned users have no password, compared against the dummy hash so they take as long
+	if row.PasswordHash == "" {
+		_ = bcrypt.CompareHashAndPassword(s.dummyHash, []byte(password))
+
+		return nil, ErrInvalidCredentials
+	}
+
+	if err := bcrypt.CompareHashAndPassword([]byte(row.PasswordHash), []byte(password)); err != nil {
+		return nil, ErrInvalidCredentials
+	}
+
+	return fromRow(row), nil
+}
+
+// Get returns the user of the ID, ErrNotFound if it does not exist.
+func (s *Service) Get(ctx context.Context, id string) (*User, error) {
+	row, err := s.queries.GetUserByID(ctx, id)
+
+	switch {
+	case errors.Is(err, pgx.ErrNoRows):
+		return nil, ErrNotFound
+	case err != nil:
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+
+	return fromRow(row), nil
+}
+
+// DefaultRole returns the role of signed up and provisioned users.
+func (s *Service) DefaultRole() string {
+	return *s.config.DefaultRole
+}
+
+// SetRole changes the role of the user and calls role change hooks, ErrNotFound if it does not exist.
+func (s *Service) SetRole(ctx context.Context, id, role string) (*User, error) {
+	if strings.TrimSpace(role) == "" {
+		return nil, ErrInvalidRole
+	}
+
+	row, err := s.queries.UpdateUserRole(ctx, &db.UpdateUserRoleParams{ID: id, Role: role})
+
+	switch {
+	case errors.Is(err, pgx.ErrNoRows):
+		return nil, ErrNotFound
+	case err != nil:
+		return nil, fmt.Errorf("failed to update user role: %w", err)
+	}
+
+	s.mu.Lock()
+	hooks := s.roleChangeHooks
+	s.mu.Unlock()
+
+	for _, hook := range hooks {
+		hook(ctx, id)
+	}
+
+	return fromRow(row), nil
+}
+
+// OnRoleChange adds a hook called after the role of a user changed, such as to invalidate cached authorizations.
+func (s *Service) OnRoleChange(hook RoleChangeHook) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.roleChangeHooks = append(s.roleChangeHooks, hook)
+}
+
+// normalizeEmail returns the lowercased email, ErrInvalidEmail if it is not a bare address.
+func normalizeEmail(email string) (string, error) {
+	email = strings.ToLower(strings.TrimSpace(email))
+
+	address, err := mail.ParseAddress(email)
+	if err != nil || address.Address != email {
+		return "", ErrInvalidEmail
+	}
+
+	return email, nil
+}
+
+// fromRow converts the database row to a user.
+func fromRow(row *db.User) *User {
+	return &User{
+		ID:        row.ID,
+		Email:     row.Email,
+		Role:      row.Role,
+		CreatedAt: row.CreatedAt.Time,
+	}
+}
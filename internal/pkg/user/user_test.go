@@ -0,0 +1,510 @@
+package user
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+
+	"github.com/pocj8ur4in/boilerplate-go/internal/gen/db"
+)
+
+// errQueryFailed is the test error of a failed query.
+var errQueryFailed = errors.New("query failed")
+
+// mockQuerier is a mock querier storing users in memory.
+type mockQuerier struct {
+	db.Querier
+
+	mu         sync.Mutex
+	users      []*db.User
+	identities []*db.UserIdentity
+	err        error
+}
+
+func (m *mockQuerier) CreateUser(_ context.Context, arg *db.CreateUserParams) (*db.User, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.err != nil {
+		return nil, m.err
+	}
+
+	for _, user := range m.users {
+		if user.Email == arg.Email {
+			return nil, &pgconn.PgError{Code: uniqueViolation}
+		}
+	}
+
+	user := &db.User{
+		ID:           arg.ID,
+		Email:        arg.Email,
+		PasswordHash: arg.PasswordHash,
+		Role:         arg.Role,
+		CreatedAt:    pgtype.Timestamptz{Time: time.Now(), Valid: true},
+		UpdatedAt:    pgtype.Timestamptz{Time: time.Now(), Valid: true},
+	}
+	m.users = append(m.users, user)
+
+	return user, nil
+}
+
+func (m *mockQuerier) GetUserByEmail(_ context.Context, email string) (*db.User, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.err != nil {
+		return nil, m.err
+	}
+
+	for _, user := range m.users {
+		if user.Email == email {
+			return user, nil
+		}
+	}
+
+	return nil, pgx.ErrNoRows
+}
+
+func (m *mockQuerier) GetUserByID(_ context.Context, id string) (*db.User, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.err != nil {
+		return nil, m.err
+	}
+
+	for _, user := range m.users {
+		if user.ID == id {
+			return user, nil
+		}
+	}
+
+	return nil, pgx.ErrNoRows
+}
+
+func (m *mockQuerier) UpdateUserRole(_ context.Context, arg *db.UpdateUserRoleParams) (*db.User, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.err != nil {
+		return nil, m.err
+	}
+
+	for _, user := range m.users {
+		if user.ID == arg.ID {
+			user.Role = arg.Role
+
+			return user, nil
+		}
+	}
+
+	return nil, pgx.ErrNoRows
+}
+
+func (m *mockQuerier) CreateUserIdentity(
+	_ context.Context,
+	arg *db.CreateUserIdentityParams,
+) (*db.UserIdentity, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, identity := range m.identities {
+		if identity.Provider == arg.Provider && (identity.Subject == arg.Subject || identity.UserID == arg.UserID) {
+			return nil, &pgconn.PgError{Code: uniqueViolation}
+		}
+	}
+
+	identity := &db.UserIdentity{Provider: arg.Provider, Subject: arg.Subject, UserID: arg.UserID}
+	m.identities = append(m.identities, identity)
+
+	return identity, nil
+}
+
+func (m *mockQuerier) GetUserIdentity(_ context.Context, arg *db.GetUserIdentityParams) (*db.UserIdentity, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.err != nil {
+		return nil, m.err
+	}
+
+	for _, identity := range m.identities {
+		if identity.Provider == arg.Provider && identity.Subject == arg.Subject {
+			return identity, nil
+		}
+	}
+
+	return nil, pgx.ErrNoRows
+}
+
+func (m *mockQuerier) GetUserIdentityByUserID(
+	_ context.Context,
+	arg *db.GetUserIdentityByUserIDParams,
+) (*db.UserIdentity, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, identity := range m.identities {
+		if identity.Provider == arg.Provider && identity.UserID == arg.UserID {
+			return identity, nil
+		}
+	}
+
+	return nil, pgx.ErrNoRows
+}
+
+// newTestService creates a user service with the minimum bcrypt cost.
+func newTestService(t *testing.T, querier db.Querier) *Service {
+	t.Helper()
+
+	service, err := NewWithQuerier(&Config{PasswordCost: &[]int{bcrypt.MinCost}[0]}, querier)
+	require.NoError(t, err)
+
+	return service
+}
+
+func TestConfig(t *testing.T) {
+	t.Parallel()
+
+	t.Run("set default values", func(t *testing.T) {
+		t.Parallel()
+
+		config := &Config{}
+		config.SetDefault()
+
+		assert.Equal(t, bcrypt.DefaultCost, *config.PasswordCost)
+		assert.Equal(t, 8, *config.MinPasswordLength)
+		assert.Equal(t, "user", *config.DefaultRole)
+	})
+}
+
+func TestNewModule(t *testing.T) {
+	t.Parallel()
+
+	t.Run("return fx.Option", func(t *testing.T) {
+		t.Parallel()
+
+		require.NotNil(t, NewModule())
+	})
+}
+
+func TestNewWithQuerier(t *testing.T) {
+	t.Parallel()
+
+	t.Run("reject invalid password cost", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := NewWithQuerier(&Config{PasswordCost: &[]int{bcrypt.MaxCost + 1}[0]}, &mockQuerier{})
+		require.Error(t, err)
+	})
+}
+
+func TestSignup(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	t.Run("create user with hashed password and default role", func(t *testing.T) {
+		t.Parallel()
+
+		querier := &mockQuerier{}
+		service := newTestService(t, querier)
+
+		user, err := service.Signup(ctx, " Alice@Example.com ", "correct horse")
+		require.NoError(t, err)
+
+		assert.Len(t, user.ID, 2*idLength)
+		assert.Equal(t, "alice@example.com", user.Email)
+		assert.Equal(t, "user", user.Role)
+		assert.False(t, user.CreatedAt.IsZero())
+
+		require.Len(t, querier.users, 1)
+		assert.NotEqual(t, "correct horse", querier.users[0].PasswordHash)
+		require.NoError(t, bcrypt.CompareHashAndPassword([]byte(querier.users[0].PasswordHash), []byte("correct horse")))
+	})
+
+	t.Run("reject taken email", func(t *testing.T) {
+		t.Parallel()
+
+		service := newTestService(t, &mockQuerier{})
+
+		_, err := service.Signup(ctx, "alice@example.com", "correct horse")
+		require.NoError(t, err)
+
+		_, err = service.Signup(ctx, "ALICE@example.com", "another password")
+		require.ErrorIs(t, err, ErrEmailTaken)
+	})
+
+	t.Run("reject invalid email", func(t *testing.T) {
+		t.Parallel()
+
+		service := newTestService(t, &mockQuerier{})
+
+		for _, email := range []string{"", "alice", "Alice <alice@example.com>", "alice@"} {
+			_, err := service.Signup(ctx, email, "correct horse")
+			require.ErrorIs(t, err, ErrInvalidEmail, email)
+		}
+	})
+
+	t.Run("reject invalid password", func(t *testing.T) {
+		t.Parallel()
+
+		service := newTestService(t, &mockQuerier{})
+
+		for _, password := range []string{"", "short", strings.Repeat("a", maxPasswordLength+1)} {
+			_, err := service.Signup(ctx, "alice@example.com", password)
+			require.ErrorIs(t, err, ErrInvalidPassword)
+		}
+	})
+
+	t.Run("return database errors", func(t *testing.T) {
+		t.Parallel()
+
+		service := newTestService(t, &mockQuerier{err: errQueryFailed})
+
+		_, err := service.Signup(ctx, "alice@example.com", "correct horse")
+		require.ErrorIs(t, err, errQueryFailed)
+	})
+}
+
+func TestLogin(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	t.Run("return user of matching credentials", func(t *testing.T) {
+		t.Parallel()
+
+		service := newTestService(t, &mockQuerier{})
+
+		created, err := service.Signup(ctx, "alice@example.com", "correct horse")
+		require.NoError(t, err)
+
+		user, err := service.Login(ctx, " Alice@Example.com", "correct horse")
+		require.NoError(t, err)
+		assert.Equal(t, created, user)
+	})
+
+	t.Run("reject wrong password and unknown email", func(t *testing.T) {
+		t.Parallel()
+
+		service := newTestService(t, &mockQuerier{})
+
+		_, err := service.Signup(ctx, "alice@example.com", "correct horse")
+		require.NoError(t, err)
+
+		_, err = service.Login(ctx, "alice@example.com", "wrong horse")
+		require.ErrorIs(t, err, ErrInvalidCredentials)
+
+		_, err = service.Login(ctx, "bob@example.com", "correct horse")
+		require.ErrorIs(t, err, ErrInvalidCredentials)
+	})
+
+	t.Run("return database errors", func(t *testing.T) {
+		t.Parallel()
+
+		service := newTestService(t, &mockQuerier{err: errQueryFailed})
+
+		_, err := service.Login(ctx, "alice@example.com", "correct horse")
+		require.ErrorIs(t, err, errQueryFailed)
+	})
+}
+
+func TestProvision(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	t.Run("create user without password", func(t *testing.T) {
+		t.Parallel()
+
+		querier := &mockQuerier{}
+		service := newTestService(t, querier)
+
+		user, err := service.Provision(ctx, " Alice@Example.com")
+		require.NoError(t, err)
+		assert.Equal(t, "alice@example.com", user.Email)
+		assert.Equal(t, "user", user.Role)
+		assert.Empty(t, querier.users[0].PasswordHash)
+
+		_, err = service.Login(ctx, "alice@example.com", "")
+		require.ErrorIs(t, err, ErrInvalidCredentials)
+	})
+
+	t.Run("return existing user", func(t *testing.T) {
+		t.Parallel()
+
+		service := newTestService(t, &mockQuerier{})
+
+		created, err := service.Signup(ctx, "alice@example.com", "correct horse")
+		require.NoError(t, err)
+
+		user, err := service.Provision(ctx, "alice@example.com")
+		require.NoError(t, err)
+		assert.Equal(t, created, user)
+	})
+
+	t.Run("reject invalid email", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := newTestService(t, &mockQuerier{}).Provision(ctx, "Alice <alice@example.com>")
+		require.ErrorIs(t, err, ErrInvalidEmail)
+	})
+
+	t.Run("return database errors", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := newTestService(t, &mockQuerier{err: errQueryFailed}).Provision(ctx, "alice@example.com")
+		require.ErrorIs(t, err, errQueryFailed)
+	})
+}
+
+func TestProvisionIdentity(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	t.Run("link provisioned user to subject", func(t *testing.T) {
+		t.Parallel()
+
+		querier := &mockQuerier{}
+		service := newTestService(t, querier)
+
+		user, err := service.ProvisionIdentity(ctx, "ldap", "uid=alice", "alice@example.com")
+		require.NoError(t, err)
+		assert.Equal(t, "alice@example.com", user.Email)
+		require.Len(t, querier.identities, 1)
+		assert.Equal(t, user.ID, querier.identities[0].UserID)
+	})
+
+	t.Run("keep user of subject when its email changes", func(t *testing.T) {
+		t.Parallel()
+
+		service := newTestService(t, &mockQuerier{})
+
+		linked, err := service.ProvisionIdentity(ctx, "ldap", "uid=alice", "alice@example.com")
+		require.NoError(t, err)
+
+		user, err := service.ProvisionIdentity(ctx, "ldap", "uid=alice", "admin@example.com")
+		require.NoError(t, err)
+		assert.Equal(t, linked.ID, user.ID)
+	})
+
+	t.Run("reject user linked to another subject", func(t *testing.T) {
+		t.Parallel()
+
+		querier := &mockQuerier{}
+		service := newTestService(t, querier)
+
+		_, err := service.ProvisionIdentity(ctx, "ldap", "uid=admin", "admin@example.com")
+		require.NoError(t, err)
+
+		_, err = service.ProvisionIdentity(ctx, "ldap", "uid=mallory", "admin@example.com")
+		require.ErrorIs(t, err, ErrIdentityConflict)
+
+		// subjects of other providers are linked separately
+		_, err = service.ProvisionIdentity(ctx, "saml", "mallory", "admin@example.com")
+		require.NoError(t, err)
+		assert.Len(t, querier.identities, 2)
+	})
+
+	t.Run("reject user with local password", func(t *testing.T) {
+		t.Parallel()
+
+		querier := &mockQuerier{}
+		service := newTestService(t, querier)
+
+		_, err := service.Signup(ctx, "alice@example.com", "correct horse")
+		require.NoError(t, err)
+
+		_, err = service.ProvisionIdentity(ctx, "ldap", "uid=alice", "alice@example.com")
+		require.ErrorIs(t, err, ErrLocalPassword)
+		assert.Empty(t, querier.identities)
+	})
+
+	t.Run("return database errors", func(t *testing.T) {
+		t.Parallel()
+
+		service := newTestService(t, &mockQuerier{err: errQueryFailed})
+
+		_, err := service.ProvisionIdentity(ctx, "ldap", "uid=alice", "alice@example.com")
+		require.ErrorIs(t, err, errQueryFailed)
+	})
+}
+
+func TestGet(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	t.Run("return user of id", func(t *testing.T) {
+		t.Parallel()
+
+		service := newTestService(t, &mockQuerier{})
+
+		created, err := service.Signup(ctx, "alice@example.com", "correct horse")
+		require.NoError(t, err)
+
+		user, err := service.Get(ctx, created.ID)
+		require.NoError(t, err)
+		assert.Equal(t, created, user)
+
+		_, err = service.Get(ctx, "unknown")
+		require.ErrorIs(t, err, ErrNotFound)
+	})
+}
+
+func TestSetRole(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	t.Run("change role and call hooks", func(t *testing.T) {
+		t.Parallel()
+
+		service := newTestService(t, &mockQuerier{})
+
+		created, err := service.Signup(ctx, "alice@example.com", "correct horse")
+		require.NoError(t, err)
+
+		var changed []string
+
+		service.OnRoleChange(func(_ context.Context, userID string) {
+			changed = append(changed, userID)
+		})
+
+		user, err := service.SetRole(ctx, created.ID, "admin")
+		require.NoError(t, err)
+		assert.Equal(t, "admin", user.Role)
+		assert.Equal(t, []string{created.ID}, changed)
+	})
+
+	t.Run("return error without calling hooks", func(t *testing.T) {
+		t.Parallel()
+
+		service := newTestService(t, &mockQuerier{})
+
+		service.OnRoleChange(func(_ context.Context, userID string) {
+			t.Errorf("unexpected role change of %s", userID)
+		})
+
+		_, err := service.SetRole(ctx, "unknown", "admin")
+		require.ErrorIs(t, err, ErrNotFound)
+
+		_, err = service.SetRole(ctx, "unknown", " ")
+		require.ErrorIs(t, err, ErrInvalidRole)
+
+		_, err = newTestService(t, &mockQuerier{err: errQueryFailed}).SetRole(ctx, "unknown", "admin")
+		require.ErrorIs(t, err, errQueryFailed)
+	})
+}
@@ -0,0 +1,116 @@
+// Package version provides build information of the service, injected on build with -ldflags, and runtime
+// information of the process.
+//
+//	go build -ldflags "-X github.com/pocj8ur4in/boilerplate-go/internal/pkg/version.Version=v1.0.0"
+package version
+
+import (
+	"runtime"
+	"runtime/debug"
+	"time"
+)
+
+// unknown is value of build information that is neither injected nor recorded by the go toolchain.
+const unknown = "unknown"
+
+var (
+	// Name is name of the service.
+	Name = "boilerplate"
+
+	// Version is version of the build.
+	Version = "dev"
+
+	// Commit is git commit of the build, read from vcs information of the binary if not injected.
+	Commit = ""
+
+	// BuildTime is time of the build in RFC 3339, read from vcs information of the binary if not injected.
+	BuildTime = ""
+)
+
+// startedAt is time the process started.
+var startedAt = time.Now()
+
+// Info represents build information of the service.
+type Info struct {
+	// Name is name of the service.
+	Name string `json:"name"`
+
+	// Version is version of the build.
+	Version string `json:"version"`
+
+	// Commit is git commit of the build.
+	Commit string `json:"commit"`
+
+	// BuildTime is time of the build.
+	BuildTime string `json:"build_time"`
+
+	// GoVersion is go version the build used.
+	GoVersion string `json:"go_version"`
+}
+
+// Runtime represents runtime information of the process.
+type Runtime struct {
+	// Uptime is time since the process started.
+	Uptime time.Duration
+
+	// Goroutines is number of goroutines.
+	Goroutines int
+
+	// Memory is memory statistics of the process.
+	Memory runtime.MemStats
+}
+
+// Get returns build information of the service.
+func Get() Info {
+	info := Info{
+		Name:      Name,
+		Version:   Version,
+		Commit:    Commit,
+		BuildTime: BuildTime,
+		GoVersion: runtime.Version(),
+	}
+
+	// fall back to vcs information recorded by the go toolchain
+	if build, ok := debug.ReadBuildInfo(); ok {
+		for _, setting := range build.Settings {
+			switch {
+			case setting.Key == "vcs.revision" && info.Commit == "":
+				info.Commit = setting.Value
+			case setting.Key == "vcs.time" && info.BuildTime == "":
+				info.BuildTime = setting.Value
+			}
+		}
+	}
+
+	if info.Commit == "" {
+		info.Commit = unknown
+	}
+
+	if info.BuildTime == "" {
+		info.BuildTime = unknown
+	}
+
+	return info
+}
+
+// StartedAt returns time the process started.
+func StartedAt() time.Time {
+	return startedAt
+}
+
+// Uptime returns time since the process started.
+func Uptime() time.Duration {
+	return time.Since(startedAt)
+}
+
+// ReadRuntime returns runtime information of the process, reading memory statistics stops the world briefly.
+func ReadRuntime() Runtime {
+	info := Runtime{
+		Uptime:     Uptime(),
+		Goroutines: runtime.NumGoroutine(),
+	}
+
+	runtime.ReadMemStats(&info.Memory)
+
+	return info
+}
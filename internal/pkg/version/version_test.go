@@ -0,0 +1,56 @@
+package version
+
+import (
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+//nolint:paralleltest // sequential execution required to avoid conflicts on injected variables
+func TestGet(t *testing.T) {
+	t.Run("return injected build information", func(t *testing.T) {
+		setBuild(t, "v1.2.3", "abc1234", "2024-01-01T00:00:00Z")
+
+		info := Get()
+		assert.Equal(t, "boilerplate", info.Name)
+		assert.Equal(t, "v1.2.3", info.Version)
+		assert.Equal(t, "abc1234", info.Commit)
+		assert.Equal(t, "2024-01-01T00:00:00Z", info.BuildTime)
+		assert.Equal(t, runtime.Version(), info.GoVersion)
+	})
+
+	t.Run("fall back without injected build information", func(t *testing.T) {
+		setBuild(t, "dev", "", "")
+
+		// test binaries carry no vcs information
+		info := Get()
+		assert.Equal(t, "dev", info.Version)
+		assert.NotEmpty(t, info.Commit)
+		assert.NotEmpty(t, info.BuildTime)
+	})
+}
+
+func TestReadRuntime(t *testing.T) {
+	t.Parallel()
+
+	info := ReadRuntime()
+	assert.Positive(t, info.Goroutines)
+	assert.Positive(t, info.Memory.Sys)
+	assert.GreaterOrEqual(t, info.Uptime, time.Duration(0))
+	assert.False(t, StartedAt().After(time.Now()))
+}
+
+// setBuild sets build information for the test and restores it after.
+func setBuild(t *testing.T, version, commit, buildTime string) {
+	t.Helper()
+
+	previous := []string{Version, Commit, BuildTime}
+
+	t.Cleanup(func() {
+		Version, Commit, BuildTime = previous[0], previous[1], previous[2]
+	})
+
+	Version, Commit, BuildTime = version, commit, buildTime
+}
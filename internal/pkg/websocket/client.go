@@ -0,0 +1,169 @@
+package websocket
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Client represents a websocket connection of a user registered on the hub.
+type Client struct {
+	// UserID is ID of the user authenticated on upgrade.
+	UserID string
+
+	// hub provides the hub the client is registered on.
+	hub *Hub
+
+	// conn is the websocket connection.
+	conn *Conn
+
+	// send queues messages written by the write loop.
+	send chan message
+
+	// done is closed when the connection is closed.
+	done chan struct{}
+
+	// closing is whether the close frame was sent, so that the read deadline is not extended anymore.
+	closing atomic.Bool
+
+	// closeOnce closes the connection once.
+	closeOnce sync.Once
+}
+
+// Send queues the message to the connection, false if it is closed or its queue is full.
+func (c *Client) Send(messageType MessageType, data []byte) bool {
+	return c.enqueue(message{messageType: messageType, data: data})
+}
+
+// Close starts the closing handshake with the code and reason.
+func (c *Client) Close(code int, reason string) {
+	c.closeGracefully(code, reason)
+}
+
+// enqueue queues the message, closing the connection if its queue is full since the peer reads too slowly.
+func (c *Client) enqueue(msg message) bool {
+	select {
+	case <-c.done:
+		return false
+	default:
+	}
+
+	if c.closing.Load() {
+		return false
+	}
+
+	select {
+	case c.send <- msg:
+		return true
+	default:
+		c.hub.logger.Warn().Str("user_id", c.UserID).Msg("websocket send buffer full, closing connection")
+		c.closeGracefully(ClosePolicyViolation, "send buffer full")
+
+		return false
+	}
+}
+
+// readLoop reads messages until the connection closes, passing them to the handler of the hub. Pongs and
+// messages extend the read deadline, so that connections of unresponsive peers time out.
+func (c *Client) readLoop(ctx context.Context) {
+	defer c.hub.wg.Done()
+	defer c.close()
+
+	extend := func() {
+		if !c.closing.Load() {
+			_ = c.conn.SetReadDeadline(time.Now().Add(*c.hub.config.PongTimeout))
+		}
+	}
+
+	extend()
+	c.conn.SetPongHandler(extend)
+
+	for {
+		messageType, data, err := c.conn.ReadMessage()
+		if err != nil {
+			var closeErr *CloseError
+			if !errors.As(err, &closeErr) && !c.closing.Load() {
+				c.hub.logger.Debug().Err(err).Str("user_id", c.UserID).Msg("websocket connection failed")
+			}
+
+			return
+		}
+
+		extend()
+
+		c.hub.mu.RLock()
+		handler := c.hub.handler
+		c.hub.mu.RUnlock()
+
+		if handler != nil {
+			handler(ctx, c, messageType, data)
+		}
+	}
+}
+
+// writeLoop writes queued messages and pings until the connection closes.
+func (c *Client) writeLoop() {
+	defer c.hub.wg.Done()
+
+	ticker := time.NewTicker(*c.hub.config.PingInterval)
+	defer ticker.Stop()
+
+	for {
+		var err error
+
+		select {
+		case <-c.done:
+			return
+		case msg := <-c.send:
+			_ = c.conn.SetWriteDeadline(time.Now().Add(*c.hub.config.WriteTimeout))
+			err = c.conn.WriteMessage(msg.messageType, msg.data)
+		case <-ticker.C:
+			_ = c.conn.SetWriteDeadline(time.Now().Add(*c.hub.config.WriteTimeout))
+			err = c.conn.Ping()
+		}
+
+		// after the close frame the read loop waits for the answer of the peer
+		if errors.Is(err, ErrCloseSent) {
+			return
+		}
+
+		if err != nil {
+			c.hub.logger.Debug().Err(err).Str("user_id", c.UserID).Msg("failed to write websocket message")
+			c.close()
+
+			return
+		}
+	}
+}
+
+// closeGracefully sends a close frame and gives the peer the write timeout to answer before the read loop
+// closes the connection.
+func (c *Client) closeGracefully(code int, reason string) {
+	c.closing.Store(true)
+
+	deadline := time.Now().Add(*c.hub.config.WriteTimeout)
+
+	_ = c.conn.SetWriteDeadline(deadline)
+
+	if err := c.conn.WriteClose(code, reason); err != nil && !errors.Is(err, ErrCloseSent) {
+		c.close()
+
+		return
+	}
+
+	_ = c.conn.SetReadDeadline(deadline)
+}
+
+// close closes the connection and removes it from the hub.
+func (c *Client) close() {
+	c.closeOnce.Do(func() {
+		close(c.done)
+
+		_ = c.conn.Close()
+
+		c.hub.unregister(c)
+		c.hub.logger.Debug().Str("user_id", c.UserID).Msg("websocket disconnected")
+	})
+}
@@ -0,0 +1,93 @@
+package websocket
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClientKeepalive(t *testing.T) {
+	t.Parallel()
+
+	config := &Config{
+		PingInterval: &[]time.Duration{20 * time.Millisecond}[0],
+		PongTimeout:  &[]time.Duration{100 * time.Millisecond}[0],
+	}
+
+	t.Run("keep connections answering pings open", func(t *testing.T) {
+		t.Parallel()
+
+		hub, serverURL := setupTestHub(t, config)
+		client := connectTestUser(t, hub, serverURL, "alice")
+
+		for range 8 {
+			opcode, _ := client.readFrame(t)
+			require.Equal(t, byte(opPing), opcode)
+
+			client.writeFrame(t, true, opPong, nil)
+		}
+
+		assert.Equal(t, 1, hub.Connections("alice"))
+	})
+
+	t.Run("close connections not answering pings", func(t *testing.T) {
+		t.Parallel()
+
+		hub, serverURL := setupTestHub(t, config)
+		client := connectTestUser(t, hub, serverURL, "alice")
+
+		require.Eventually(t, func() bool { return hub.Connections("alice") == 0 }, time.Second, 5*time.Millisecond)
+
+		// pings were sent until the connection was closed
+		require.NoError(t, client.conn.SetReadDeadline(time.Now().Add(time.Second)))
+
+		_, err := io.ReadAll(client.reader)
+		require.NoError(t, err)
+	})
+}
+
+func TestClientSend(t *testing.T) {
+	t.Parallel()
+
+	hub, _ := setupTestHub(t, &Config{
+		SendBuffer:   &[]int{1}[0],
+		WriteTimeout: &[]time.Duration{10 * time.Millisecond}[0],
+	})
+
+	server, peer := net.Pipe()
+
+	t.Cleanup(func() {
+		_ = peer.Close()
+	})
+
+	client := &Client{
+		UserID: "alice",
+		hub:    hub,
+		conn:   newConn(server, bufio.NewReader(server), 0),
+		send:   make(chan message, 1),
+		done:   make(chan struct{}),
+	}
+	require.True(t, hub.register(client))
+
+	// the write loop is not running, so the second message overflows the queue of the slow peer
+	assert.True(t, client.Send(TextMessage, []byte("first")))
+	assert.False(t, client.Send(TextMessage, []byte("second")))
+
+	select {
+	case <-client.done:
+	case <-time.After(time.Second):
+		t.Fatal("slow connection was not closed")
+	}
+
+	assert.Zero(t, hub.Connections("alice"))
+	assert.False(t, client.Send(TextMessage, []byte("third")))
+
+	_, err := peer.Read(make([]byte, 1))
+	assert.True(t, errors.Is(err, io.EOF) || errors.Is(err, io.ErrClosedPipe))
+}
@@ -0,0 +1,484 @@
+package websocket
+
+import (
+	"bufio"
+	"crypto/sha1" //nolint:gosec // the handshake of RFC 6455 is defined on SHA-1
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/apierror"
+)
+
+// MessageType is type of a data message.
+type MessageType int
+
+const (
+	// TextMessage is message of UTF-8 text.
+	TextMessage MessageType = 1
+
+	// BinaryMessage is message of binary data.
+	BinaryMessage MessageType = 2
+)
+
+const (
+	// CloseNormal is close code of connections closed after fulfilling their purpose.
+	CloseNormal = 1000
+
+	// CloseGoingAway is close code of connections closed since the server shuts down.
+	CloseGoingAway = 1001
+
+	// CloseProtocolError is close code of connections closed for violating the protocol.
+	CloseProtocolError = 1002
+
+	// CloseNoStatus is close code reported for close frames without a code, it is never sent.
+	CloseNoStatus = 1005
+
+	// CloseInvalidPayload is close code of connections closed for text messages that are not UTF-8.
+	CloseInvalidPayload = 1007
+
+	// ClosePolicyViolation is close code of connections closed for violating a policy, such as reading too slowly.
+	ClosePolicyViolation = 1008
+
+	// CloseMessageTooBig is close code of connections closed for messages over the maximum size.
+	CloseMessageTooBig = 1009
+)
+
+const (
+	// acceptGUID is GUID appended to keys of handshakes to compute the accept header.
+	acceptGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+	// maxControlPayload is maximum payload size of control frames.
+	maxControlPayload = 125
+
+	// opcodes of frames.
+	opContinuation = 0x0
+	opText         = 0x1
+	opBinary       = 0x2
+	opClose        = 0x8
+	opPing         = 0x9
+	opPong         = 0xa
+)
+
+var (
+	// ErrBadHandshake is returned when a request is not a websocket handshake.
+	ErrBadHandshake = errors.New("request is not a websocket handshake")
+
+	// ErrOriginNotAllowed is returned when the origin of a handshake is not allowed.
+	ErrOriginNotAllowed = errors.New("websocket origin not allowed")
+
+	// ErrProtocol is returned when the peer violates the websocket protocol.
+	ErrProtocol = errors.New("websocket protocol violation")
+
+	// ErrMessageTooBig is returned when a message exceeds the maximum message size.
+	ErrMessageTooBig = errors.New("websocket message too big")
+
+	// ErrCloseSent is returned when writing to a connection after its close frame was sent.
+	ErrCloseSent = errors.New("websocket close frame already sent")
+)
+
+// CloseError is returned when the peer closes the connection.
+type CloseError struct {
+	// Code is close code sent by the peer, CloseNoStatus without a code.
+	Code int
+
+	// Reason is close reason sent by the peer.
+	Reason string
+}
+
+// Error returns the close code and reason.
+func (e *CloseError) Error() string {
+	return fmt.Sprintf("websocket closed: %d %s", e.Code, e.Reason)
+}
+
+// Upgrader upgrades HTTP requests to websocket connections.
+type Upgrader struct {
+	// AllowedOrigins is origins allowed to connect besides the host of the request, "*" allows any origin.
+	// Requests without an Origin header, which are not sent by browsers, are allowed.
+	AllowedOrigins []string
+
+	// MaxMessageSize is maximum size in bytes of messages read, 0 for unlimited.
+	MaxMessageSize int64
+}
+
+// Upgrade completes the handshake of the request and returns the connection, an error response is written
+// if the request is not a valid handshake.
+func (u *Upgrader) Upgrade(writer http.ResponseWriter, request *http.Request) (*Conn, error) {
+	if request.Method != http.MethodGet ||
+		!headerContains(request.Header, "Connection", "upgrade") ||
+		!headerContains(request.Header, "Upgrade", "websocket") {
+		writeError(writer, http.StatusBadRequest, ErrBadHandshake.Error())
+
+		return nil, ErrBadHandshake
+	}
+
+	if request.Header.Get("Sec-WebSocket-Version") != "13" {
+		writer.Header().Set("Sec-WebSocket-Version", "13")
+		writeError(writer, http.StatusUpgradeRequired, "unsupported websocket version")
+
+		return nil, fmt.Errorf("%w: unsupported version", ErrBadHandshake)
+	}
+
+	key := request.Header.Get("Sec-WebSocket-Key")
+	if decoded, err := base64.StdEncoding.DecodeString(key); err != nil || len(decoded) != 16 {
+		writeError(writer, http.StatusBadRequest, "invalid websocket key")
+
+		return nil, fmt.Errorf("%w: invalid key", ErrBadHandshake)
+	}
+
+	if !u.originAllowed(request) {
+		writeError(writer, http.StatusForbidden, ErrOriginNotAllowed.Error())
+
+		return nil, ErrOriginNotAllowed
+	}
+
+	netConn, buffered, err := http.NewResponseController(writer).Hijack()
+	if err != nil {
+		writeError(writer, http.StatusInternalServerError, "websocket upgrade not supported")
+
+		return nil, fmt.Errorf("failed to hijack connection: %w", err)
+	}
+
+	// deadlines of the http server do not apply to the upgraded connection
+	_ = netConn.SetDeadline(time.Time{})
+
+	response := "HTTP/1.1 101 Switching Protocols\r\n" +
+		"Upgrade: websocket\r\n" +
+		"Connection: Upgrade\r\n" +
+		"Sec-WebSocket-Accept: " + acceptKey(key) + "\r\n\r\n"
+
+	if _, err := netConn.Write([]byte(response)); err != nil {
+		_ = netConn.Close()
+
+		return nil, fmt.Errorf("failed to write handshake: %w", err)
+	}
+
+	return newConn(netConn, buffered.Reader, u.MaxMessageSize), nil
+}
+
+// originAllowed returns whether the origin of the request is its host or an allowed origin.
+func (u *Upgrader) originAllowed(request *http.Request) bool {
+	origin := request.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+
+	for _, allowed := range u.AllowedOrigins {
+		if allowed == "*" || strings.EqualFold(allowed, origin) {
+			return true
+		}
+	}
+
+	parsed, err := url.Parse(origin)
+
+	return err == nil && strings.EqualFold(parsed.Host, request.Host)
+}
+
+// Conn represents a websocket connection on the server side. Messages are read by one goroutine at a time,
+// writes are safe for concurrent use.
+type Conn struct {
+	// conn is the upgraded network connection.
+	conn net.Conn
+
+	// reader reads frames, including bytes buffered before the upgrade.
+	reader *bufio.Reader
+
+	// maxMessageSize is maximum size in bytes of messages read, 0 for unlimited.
+	maxMessageSize int64
+
+	// pongHandler is called on pong frames, nil to ignore them.
+	pongHandler func()
+
+	// writeMu serializes frames written.
+	writeMu sync.Mutex
+
+	// closeSent is whether the close frame was sent, guarded by writeMu.
+	closeSent bool
+}
+
+// newConn creates a connection of the upgraded network connection.
+func newConn(conn net.Conn, reader *bufio.Reader, maxMessageSize int64) *Conn {
+	return &Conn{conn: conn, reader: reader, maxMessageSize: maxMessageSize}
+}
+
+// RemoteAddr returns the remote network address.
+func (c *Conn) RemoteAddr() net.Addr {
+	return c.conn.RemoteAddr()
+}
+
+// SetReadDeadline sets the deadline of reading messages.
+func (c *Conn) SetReadDeadline(deadline time.Time) error {
+	return c.conn.SetReadDeadline(deadline) //nolint:wrapcheck // errors of the connection are returned as is
+}
+
+// SetWriteDeadline sets the deadline of writing messages.
+func (c *Conn) SetWriteDeadline(deadline time.Time) error {
+	return c.conn.SetWriteDeadline(deadline) //nolint:wrapcheck // errors of the connection are returned as is
+}
+
+// SetPongHandler sets the function called on pong frames read by ReadMessage.
+func (c *Conn) SetPongHandler(handler func()) {
+	c.pongHandler = handler
+}
+
+// ReadMessage reads the next data message, answering pings and close frames of the peer on the way.
+// A *CloseError is returned once the peer closed the connection.
+func (c *Conn) ReadMessage() (MessageType, []byte, error) {
+	var (
+		messageType MessageType
+		message     []byte
+	)
+
+	for {
+		fin, opcode, payload, err := c.readFrame()
+		if err != nil {
+			return 0, nil, err
+		}
+
+		switch opcode {
+		case opPing:
+			if err := c.writeFrame(opPong, payload); err != nil && !errors.Is(err, ErrCloseSent) {
+				return 0, nil, err
+			}
+
+			continue
+		case opPong:
+			if c.pongHandler != nil {
+				c.pongHandler()
+			}
+
+			continue
+		case opClose:
+			return 0, nil, c.handleClose(payload)
+		case opText, opBinary:
+			if messageType != 0 {
+				return 0, nil, c.fail(CloseProtocolError, fmt.Errorf("%w: new message before final fragment", ErrProtocol))
+			}
+
+			messageType = MessageType(opcode)
+		case opContinuation:
+			if messageType == 0 {
+				return 0, nil, c.fail(CloseProtocolError, fmt.Errorf("%w: continuation without message", ErrProtocol))
+			}
+		default:
+			return 0, nil, c.fail(CloseProtocolError, fmt.Errorf("%w: unknown opcode %d", ErrProtocol, opcode))
+		}
+
+		if c.maxMessageSize > 0 && int64(len(message)+len(payload)) > c.maxMessageSize {
+			return 0, nil, c.fail(CloseMessageTooBig, ErrMessageTooBig)
+		}
+
+		message = append(message, payload...)
+
+		if !fin {
+			continue
+		}
+
+		if messageType == TextMessage && !utf8.Valid(message) {
+			return 0, nil, c.fail(CloseInvalidPayload, fmt.Errorf("%w: text message is not utf-8", ErrProtocol))
+		}
+
+		return messageType, message, nil
+	}
+}
+
+// readFrame reads a frame and returns whether it is final, its opcode and its unmasked payload.
+func (c *Conn) readFrame() (bool, byte, []byte, error) {
+	var header [2]byte
+	if _, err := io.ReadFull(c.reader, header[:]); err != nil {
+		return false, 0, nil, fmt.Errorf("failed to read frame: %w", err)
+	}
+
+	fin := header[0]&0x80 != 0
+	opcode := header[0] & 0x0f
+	masked := header[1]&0x80 != 0
+	length := uint64(header[1] & 0x7f)
+
+	if header[0]&0x70 != 0 {
+		return false, 0, nil, c.fail(CloseProtocolError, fmt.Errorf("%w: reserved bits set", ErrProtocol))
+	}
+
+	// frames of clients are always masked
+	if !masked {
+		return false, 0, nil, c.fail(CloseProtocolError, fmt.Errorf("%w: unmasked frame", ErrProtocol))
+	}
+
+	if opcode >= opClose && (!fin || length > maxControlPayload) {
+		return false, 0, nil, c.fail(CloseProtocolError, fmt.Errorf("%w: invalid control frame", ErrProtocol))
+	}
+
+	switch length {
+	case 126:
+		var extended [2]byte
+		if _, err := io.ReadFull(c.reader, extended[:]); err != nil {
+			return false, 0, nil, fmt.Errorf("failed to read frame: %w", err)
+		}
+
+		length = uint64(binary.BigEndian.Uint16(extended[:]))
+	case 127:
+		var extended [8]byte
+		if _, err := io.ReadFull(c.reader, extended[:]); err != nil {
+			return false, 0, nil, fmt.Errorf("failed to read frame: %w", err)
+		}
+
+		length = binary.BigEndian.Uint64(extended[:])
+	}
+
+	// frames over the maximum size are rejected before allocating their payload
+	if c.maxMessageSize > 0 && length > uint64(c.maxMessageSize) {
+		return false, 0, nil, c.fail(CloseMessageTooBig, ErrMessageTooBig)
+	}
+
+	var mask [4]byte
+	if _, err := io.ReadFull(c.reader, mask[:]); err != nil {
+		return false, 0, nil, fmt.Errorf("failed to read frame: %w", err)
+	}
+
+	payload := make([]byte, length)
+	if _, err := io.ReadFull(c.reader, payload); err != nil {
+		return false, 0, nil, fmt.Errorf("failed to read frame: %w", err)
+	}
+
+	for i := range payload {
+		payload[i] ^= mask[i%4]
+	}
+
+	return fin, opcode, payload, nil
+}
+
+// handleClose answers the close frame of the peer and returns its close error.
+func (c *Conn) handleClose(payload []byte) error {
+	closeErr := &CloseError{Code: CloseNoStatus}
+
+	if len(payload) >= 2 {
+		closeErr.Code = int(binary.BigEndian.Uint16(payload))
+		closeErr.Reason = string(payload[2:])
+	}
+
+	// the close code of the peer is echoed, unless the server closed first
+	echo := closeErr.Code
+	if echo == CloseNoStatus {
+		echo = CloseNormal
+	}
+
+	_ = c.WriteClose(echo, "")
+
+	return closeErr
+}
+
+// fail sends a close frame of the code and returns the error.
+func (c *Conn) fail(code int, err error) error {
+	_ = c.WriteClose(code, "")
+
+	return err
+}
+
+// WriteMessage writes a data message.
+func (c *Conn) WriteMessage(messageType MessageType, data []byte) error {
+	return c.writeFrame(byte(messageType), data)
+}
+
+// Ping writes a ping frame, answered by a pong frame of the peer.
+func (c *Conn) Ping() error {
+	return c.writeFrame(opPing, nil)
+}
+
+// WriteClose writes a close frame of the code and reason, starting the closing handshake. Further writes fail,
+// the peer answers with a close frame returned as *CloseError by ReadMessage.
+func (c *Conn) WriteClose(code int, reason string) error {
+	if len(reason) > maxControlPayload-2 {
+		reason = reason[:maxControlPayload-2]
+	}
+
+	payload := binary.BigEndian.AppendUint16(nil, uint16(code)) //nolint:gosec // close codes fit in 16 bits
+	payload = append(payload, reason...)
+
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+
+	if c.closeSent {
+		return ErrCloseSent
+	}
+
+	c.closeSent = true
+
+	return c.writeFrameLocked(opClose, payload)
+}
+
+// Close closes the network connection without a closing handshake.
+func (c *Conn) Close() error {
+	return c.conn.Close() //nolint:wrapcheck // errors of the connection are returned as is
+}
+
+// writeFrame writes an unfragmented frame of the opcode.
+func (c *Conn) writeFrame(opcode byte, payload []byte) error {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+
+	if c.closeSent {
+		return ErrCloseSent
+	}
+
+	return c.writeFrameLocked(opcode, payload)
+}
+
+// writeFrameLocked writes an unfragmented frame of the opcode while holding writeMu, frames of servers are not
+// masked.
+func (c *Conn) writeFrameLocked(opcode byte, payload []byte) error {
+	frame := make([]byte, 0, len(payload)+10)
+	frame = append(frame, 0x80|opcode)
+
+	switch length := len(payload); {
+	case length <= 125:
+		frame = append(frame, byte(length))
+	case length <= 0xffff:
+		frame = append(frame, 126)
+		frame = binary.BigEndian.AppendUint16(frame, uint16(length))
+	default:
+		frame = append(frame, 127)
+		frame = binary.BigEndian.AppendUint64(frame, uint64(length))
+	}
+
+	frame = append(frame, payload...)
+
+	if _, err := c.conn.Write(frame); err != nil {
+		return fmt.Errorf("failed to write frame: %w", err)
+	}
+
+	return nil
+}
+
+// acceptKey returns the accept header of the key of a handshake.
+func acceptKey(key string) string {
+	hash := sha1.Sum([]byte(key + acceptGUID)) //nolint:gosec // the handshake of RFC 6455 is defined on SHA-1
+
+	return base64.StdEncoding.EncodeToString(hash[:])
+}
+
+// headerContains returns whether the comma separated header contains the token, case-insensitively.
+func headerContains(header http.Header, name, token string) bool {
+	for _, value := range header.Values(name) {
+		for part := range strings.SplitSeq(value, ",") {
+			if strings.EqualFold(strings.TrimSpace(part), token) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// writeError writes the error response of a failed handshake.
+func writeError(writer http.ResponseWriter, status int, message string) {
+	// error is ignored since nothing else can be written to the client
+	_ = apierror.Write(writer, status, &apierror.Response{Error: message})
+}
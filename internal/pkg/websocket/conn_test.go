@@ -0,0 +1,351 @@
+package websocket
+
+import (
+	"bufio"
+	"encoding/binary"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testKey is key of handshakes of test clients.
+const testKey = "dGhlIHNhbXBsZSBub25jZQ=="
+
+// testClient is a websocket client writing masked frames.
+type testClient struct {
+	// conn is the network connection.
+	conn net.Conn
+
+	// reader reads frames of the server.
+	reader *bufio.Reader
+}
+
+// dialTest sends a handshake with the headers to the server and returns the client and the response.
+func dialTest(t *testing.T, serverURL string, header http.Header) (*testClient, *http.Response) {
+	t.Helper()
+
+	target, err := http.NewRequest(http.MethodGet, serverURL, nil)
+	require.NoError(t, err)
+
+	conn, err := net.Dial("tcp", target.URL.Host)
+	require.NoError(t, err)
+
+	t.Cleanup(func() {
+		_ = conn.Close()
+	})
+
+	target.Header.Set("Connection", "Upgrade")
+	target.Header.Set("Upgrade", "websocket")
+	target.Header.Set("Sec-WebSocket-Version", "13")
+	target.Header.Set("Sec-WebSocket-Key", testKey)
+
+	for name, values := range header {
+		target.Header[name] = values
+	}
+
+	require.NoError(t, target.Write(conn))
+
+	reader := bufio.NewReader(conn)
+
+	response, err := http.ReadResponse(reader, target)
+	require.NoError(t, err)
+
+	if response.StatusCode != http.StatusSwitchingProtocols {
+		_ = response.Body.Close()
+	}
+
+	return &testClient{conn: conn, reader: reader}, response
+}
+
+// writeFrame writes a masked frame.
+func (c *testClient) writeFrame(t *testing.T, fin bool, opcode byte, payload []byte) {
+	t.Helper()
+
+	first := opcode
+	if fin {
+		first |= 0x80
+	}
+
+	frame := []byte{first}
+
+	switch {
+	case len(payload) <= 125:
+		frame = append(frame, 0x80|byte(len(payload)))
+	default:
+		frame = append(frame, 0x80|126)
+		frame = binary.BigEndian.AppendUint16(frame, uint16(len(payload)))
+	}
+
+	mask := []byte{1, 2, 3, 4}
+	frame = append(frame, mask...)
+
+	for i, b := range payload {
+		frame = append(frame, b^mask[i%4])
+	}
+
+	_, err := c.conn.Write(frame)
+	require.NoError(t, err)
+}
+
+// readFrame reads an unmasked frame of the server.
+func (c *testClient) readFrame(t *testing.T) (byte, []byte) {
+	t.Helper()
+
+	require.NoError(t, c.conn.SetReadDeadline(time.Now().Add(2*time.Second)))
+
+	var header [2]byte
+
+	_, err := io.ReadFull(c.reader, header[:])
+	require.NoError(t, err)
+
+	length := int(header[1] & 0x7f)
+
+	if length == 126 {
+		var extended [2]byte
+
+		_, err = io.ReadFull(c.reader, extended[:])
+		require.NoError(t, err)
+
+		length = int(binary.BigEndian.Uint16(extended[:]))
+	}
+
+	payload := make([]byte, length)
+
+	_, err = io.ReadFull(c.reader, payload)
+	require.NoError(t, err)
+
+	return header[0] & 0x0f, payload
+}
+
+// closeCode returns the close code of the payload of a close frame.
+func closeCode(payload []byte) int {
+	if len(payload) < 2 {
+		return CloseNoStatus
+	}
+
+	return int(binary.BigEndian.Uint16(payload))
+}
+
+// serveUpgrader serves connections upgraded by the upgrader to the function.
+func serveUpgrader(t *testing.T, upgrader *Upgrader, serve func(conn *Conn)) string {
+	t.Helper()
+
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		conn, err := upgrader.Upgrade(writer, request)
+		if err != nil {
+			return
+		}
+
+		go func() {
+			defer func() {
+				_ = conn.Close()
+			}()
+
+			serve(conn)
+		}()
+	}))
+	t.Cleanup(server.Close)
+
+	return server.URL
+}
+
+// echo echoes messages of the connection until it fails.
+func echo(conn *Conn) {
+	for {
+		messageType, data, err := conn.ReadMessage()
+		if err != nil {
+			return
+		}
+
+		if err := conn.WriteMessage(messageType, data); err != nil {
+			return
+		}
+	}
+}
+
+func TestUpgrade(t *testing.T) {
+	t.Parallel()
+
+	serverURL := serveUpgrader(t, &Upgrader{AllowedOrigins: []string{"https://app.example.com"}}, echo)
+
+	t.Run("complete handshake", func(t *testing.T) {
+		t.Parallel()
+
+		_, response := dialTest(t, serverURL, nil)
+		assert.Equal(t, http.StatusSwitchingProtocols, response.StatusCode)
+		assert.Equal(t, "s3pPLMBiTxaQ9kYGzzhZRbK+xOo=", response.Header.Get("Sec-WebSocket-Accept"))
+	})
+
+	t.Run("allow origins of the host and allowed origins", func(t *testing.T) {
+		t.Parallel()
+
+		host := strings.TrimPrefix(serverURL, "http://")
+
+		_, response := dialTest(t, serverURL, http.Header{"Origin": {"http://" + host}})
+		assert.Equal(t, http.StatusSwitchingProtocols, response.StatusCode)
+
+		_, response = dialTest(t, serverURL, http.Header{"Origin": {"https://app.example.com"}})
+		assert.Equal(t, http.StatusSwitchingProtocols, response.StatusCode)
+
+		_, response = dialTest(t, serverURL, http.Header{"Origin": {"https://evil.example.com"}})
+		assert.Equal(t, http.StatusForbidden, response.StatusCode)
+	})
+
+	t.Run("reject invalid handshakes", func(t *testing.T) {
+		t.Parallel()
+
+		_, response := dialTest(t, serverURL, http.Header{"Sec-Websocket-Version": {"8"}})
+		assert.Equal(t, http.StatusUpgradeRequired, response.StatusCode)
+		assert.Equal(t, "13", response.Header.Get("Sec-WebSocket-Version"))
+
+		_, response = dialTest(t, serverURL, http.Header{"Sec-Websocket-Key": {"short"}})
+		assert.Equal(t, http.StatusBadRequest, response.StatusCode)
+
+		response, err := http.Get(serverURL) //nolint:noctx // test request
+		require.NoError(t, err)
+		require.NoError(t, response.Body.Close())
+		assert.Equal(t, http.StatusBadRequest, response.StatusCode)
+	})
+}
+
+func TestConn(t *testing.T) {
+	t.Parallel()
+
+	t.Run("read fragmented messages and answer pings", func(t *testing.T) {
+		t.Parallel()
+
+		client, _ := dialTest(t, serveUpgrader(t, &Upgrader{}, echo), nil)
+
+		client.writeFrame(t, false, opText, []byte("hello "))
+		client.writeFrame(t, true, opPing, []byte("ping"))
+		client.writeFrame(t, true, opContinuation, []byte("world"))
+
+		opcode, payload := client.readFrame(t)
+		assert.Equal(t, byte(opPong), opcode)
+		assert.Equal(t, "ping", string(payload))
+
+		opcode, payload = client.readFrame(t)
+		assert.Equal(t, byte(opText), opcode)
+		assert.Equal(t, "hello world", string(payload))
+
+		large := []byte(strings.Repeat("x", 300))
+		client.writeFrame(t, true, opBinary, large)
+
+		opcode, payload = client.readFrame(t)
+		assert.Equal(t, byte(opBinary), opcode)
+		assert.Equal(t, large, payload)
+	})
+
+	t.Run("echo close frames of the peer", func(t *testing.T) {
+		t.Parallel()
+
+		closed := make(chan error, 1)
+		client, _ := dialTest(t, serveUpgrader(t, &Upgrader{}, func(conn *Conn) {
+			_, _, err := conn.ReadMessage()
+			closed <- err
+		}), nil)
+
+		client.writeFrame(t, true, opClose, append(binary.BigEndian.AppendUint16(nil, CloseNormal), "bye"...))
+
+		opcode, payload := client.readFrame(t)
+		assert.Equal(t, byte(opClose), opcode)
+		assert.Equal(t, CloseNormal, closeCode(payload))
+
+		var closeErr *CloseError
+
+		require.ErrorAs(t, <-closed, &closeErr)
+		assert.Equal(t, CloseNormal, closeErr.Code)
+		assert.Equal(t, "bye", closeErr.Reason)
+	})
+
+	t.Run("close connections violating the protocol", func(t *testing.T) {
+		t.Parallel()
+
+		tests := []struct {
+			name string
+			send func(client *testClient)
+			code int
+			err  error
+		}{
+			{
+				name: "message over maximum size",
+				send: func(client *testClient) { client.writeFrame(t, true, opText, []byte(strings.Repeat("x", 20))) },
+				code: CloseMessageTooBig,
+				err:  ErrMessageTooBig,
+			},
+			{
+				name: "text message not utf-8",
+				send: func(client *testClient) { client.writeFrame(t, true, opText, []byte{0xff, 0xfe}) },
+				code: CloseInvalidPayload,
+				err:  ErrProtocol,
+			},
+			{
+				name: "continuation without message",
+				send: func(client *testClient) { client.writeFrame(t, true, opContinuation, []byte("x")) },
+				code: CloseProtocolError,
+				err:  ErrProtocol,
+			},
+			{
+				name: "unmasked frame",
+				send: func(client *testClient) { _, _ = client.conn.Write([]byte{0x81, 0x01, 'x'}) },
+				code: CloseProtocolError,
+				err:  ErrProtocol,
+			},
+		}
+
+		for _, test := range tests {
+			failed := make(chan error, 1)
+			client, _ := dialTest(t, serveUpgrader(t, &Upgrader{MaxMessageSize: 16}, func(conn *Conn) {
+				_, _, err := conn.ReadMessage()
+				failed <- err
+			}), nil)
+
+			test.send(client)
+
+			opcode, payload := client.readFrame(t)
+			assert.Equal(t, byte(opClose), opcode, test.name)
+			assert.Equal(t, test.code, closeCode(payload), test.name)
+			assert.ErrorIs(t, <-failed, test.err, test.name)
+		}
+	})
+
+	t.Run("fail writes after close frame", func(t *testing.T) {
+		t.Parallel()
+
+		written := make(chan error, 1)
+		client, _ := dialTest(t, serveUpgrader(t, &Upgrader{}, func(conn *Conn) {
+			_ = conn.WriteClose(CloseGoingAway, "bye")
+			written <- conn.WriteMessage(TextMessage, []byte("late"))
+		}), nil)
+
+		opcode, payload := client.readFrame(t)
+		assert.Equal(t, byte(opClose), opcode)
+		assert.Equal(t, CloseGoingAway, closeCode(payload))
+		assert.Equal(t, "bye", string(payload[2:]))
+		require.ErrorIs(t, <-written, ErrCloseSent)
+	})
+}
+
+func TestAcceptKey(t *testing.T) {
+	t.Parallel()
+
+	// example of RFC 6455
+	assert.Equal(t, "s3pPLMBiTxaQ9kYGzzhZRbK+xOo=", acceptKey(testKey))
+}
+
+func TestHeaderContains(t *testing.T) {
+	t.Parallel()
+
+	header := http.Header{"Connection": {"keep-alive, Upgrade"}}
+
+	assert.True(t, headerContains(header, "Connection", "upgrade"))
+	assert.False(t, headerContains(header, "Connection", "close"))
+	assert.False(t, headerContains(header, "Upgrade", "websocket"))
+}
@@ -0,0 +1,391 @@
+// Package websocket provides websocket connections (RFC 6455) upgraded from HTTP requests, and a hub tracking
+// the connections of users with keepalive, broadcasts to all or some users and graceful close on shutdown.
+package websocket
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"go.uber.org/fx"
+
+	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/logger"
+)
+
+const (
+	// defaultPath is default path of the websocket endpoint.
+	defaultPath = "/ws"
+
+	// defaultPingInterval is default interval of pings sent to connections.
+	defaultPingInterval = 30 * time.Second
+
+	// defaultPongTimeout is default time connections are closed after without a pong or message.
+	defaultPongTimeout = 60 * time.Second
+
+	// defaultWriteTimeout is default time a message may take to be written.
+	defaultWriteTimeout = 10 * time.Second
+
+	// defaultMaxMessageSize is default maximum size of messages read from connections.
+	defaultMaxMessageSize = 65536 // 64KB
+
+	// defaultSendBuffer is default number of messages queued per connection.
+	defaultSendBuffer = 64
+)
+
+var (
+	// ErrInvalidConfig is returned when connections time out before a pong to their ping can arrive.
+	ErrInvalidConfig = errors.New("websocket pong_timeout must be greater than ping_interval")
+
+	// ErrHubClosed is returned when upgrading requests after the hub was shut down.
+	ErrHubClosed = errors.New("websocket hub is closed")
+)
+
+// Config represents configuration for websocket connections.
+type Config struct {
+	// Enabled is whether the websocket endpoint is served.
+	Enabled *bool `json:"enabled"`
+
+	// Path is path of the websocket endpoint.
+	Path *string `json:"path"`
+
+	// AllowedOrigins is origins of browsers allowed to connect besides the host of the server, "*" allows any.
+	AllowedOrigins *[]string `json:"allowed_origins"`
+
+	// PingInterval is interval of pings sent to connections.
+	PingInterval *time.Duration `json:"ping_interval"`
+
+	// PongTimeout is time connections are closed after without a pong or message, greater than the ping interval.
+	PongTimeout *time.Duration `json:"pong_timeout"`
+
+	// WriteTimeout is time a message may take to be written before the connection is closed.
+	WriteTimeout *time.Duration `json:"write_timeout"`
+
+	// MaxMessageSize is maximum size in bytes of messages read from connections.
+	MaxMessageSize *int64 `json:"max_message_size"`
+
+	// SendBuffer is number of messages queued per connection, connections reading slower are closed.
+	SendBuffer *int `json:"send_buffer"`
+}
+
+// SetDefault sets default values.
+func (c *Config) SetDefault() {
+	if c.Enabled == nil {
+		c.Enabled = &[]bool{true}[0]
+	}
+
+	if c.Path == nil {
+		c.Path = &[]string{defaultPath}[0]
+	}
+
+	if c.AllowedOrigins == nil {
+		c.AllowedOrigins = &[]string{}
+	}
+
+	if c.PingInterval == nil {
+		c.PingInterval = &[]time.Duration{defaultPingInterval}[0]
+	}
+
+	if c.PongTimeout == nil {
+		c.PongTimeout = &[]time.Duration{defaultPongTimeout}[0]
+	}
+
+	if c.WriteTimeout == nil {
+		c.WriteTimeout = &[]time.Duration{defaultWriteTimeout}[0]
+	}
+
+	if c.MaxMessageSize == nil {
+		c.MaxMessageSize = &[]int64{defaultMaxMessageSize}[0]
+	}
+
+	if c.SendBuffer == nil {
+		c.SendBuffer = &[]int{defaultSendBuffer}[0]
+	}
+}
+
+// MessageHandler handles data messages read from connections of users.
+type MessageHandler func(ctx context.Context, client *Client, messageType MessageType, data []byte)
+
+// message represents a data message queued to a connection.
+type message struct {
+	// messageType is type of the message.
+	messageType MessageType
+
+	// data is payload of the message.
+	data []byte
+}
+
+// Hub tracks websocket connections by user and delivers messages to them.
+type Hub struct {
+	// config provides websocket configuration.
+	config *Config
+
+	// logger provides logger.
+	logger *logger.Logger
+
+	// upgrader upgrades requests to connections.
+	upgrader *Upgrader
+
+	// mu guards users and closed.
+	mu sync.RWMutex
+
+	// users is connections by user ID.
+	users map[string]map[*Client]struct{}
+
+	// closed is whether the hub was shut down.
+	closed bool
+
+	// handler handles messages read from connections, nil to discard them.
+	handler MessageHandler
+
+	// wg tracks goroutines of connections.
+	wg sync.WaitGroup
+
+	// connections is gauge of open connections.
+	connections prometheus.Gauge
+}
+
+// NewModule provides module for websocket.
+func NewModule() fx.Option {
+	return fx.Module("websocket",
+		fx.Provide(New),
+	)
+}
+
+// New creates a hub of the configuration.
+func New(config *Config, logger *logger.Logger) (*Hub, error) {
+	if config == nil {
+		config = &Config{}
+	}
+
+	config.SetDefault()
+
+	if *config.PongTimeout <= *config.PingInterval {
+		return nil, fmt.Errorf("%w: %s <= %s", ErrInvalidConfig, *config.PongTimeout, *config.PingInterval)
+	}
+
+	return &Hub{
+		config: config,
+		logger: logger.Named("websocket"),
+		upgrader: &Upgrader{
+			AllowedOrigins: *config.AllowedOrigins,
+			MaxMessageSize: *config.MaxMessageSize,
+		},
+		users: make(map[string]map[*Client]struct{}),
+		connections: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "websocket_connections",
+			Help: "Number of open websocket connections",
+		}),
+	}, nil
+}
+
+// Enabled returns whether the websocket endpoint is served, false if the hub is nil.
+func (h *Hub) Enabled() bool {
+	return h != nil && *h.config.Enabled
+}
+
+// Config returns the websocket configuration.
+func (h *Hub) Config() *Config {
+	return h.config
+}
+
+// Handle sets the handler of messages read from connections, messages are discarded without one.
+func (h *Hub) Handle(handler MessageHandler) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.handler = handler
+}
+
+// Serve upgrades the request to a connection of the authenticated user and returns once it is registered,
+// the connection is served in the background until it closes or the hub shuts down.
+func (h *Hub) Serve(writer http.ResponseWriter, request *http.Request, userID string) error {
+	h.mu.RLock()
+	closed := h.closed
+	h.mu.RUnlock()
+
+	if closed {
+		writeError(writer, http.StatusServiceUnavailable, ErrHubClosed.Error())
+
+		return ErrHubClosed
+	}
+
+	conn, err := h.upgrader.Upgrade(writer, request)
+	if err != nil {
+		return err
+	}
+
+	client := &Client{
+		UserID: userID,
+		hub:    h,
+		conn:   conn,
+		send:   make(chan message, *h.config.SendBuffer),
+		done:   make(chan struct{}),
+	}
+
+	if !h.register(client) {
+		_ = conn.WriteClose(CloseGoingAway, "server shutting down")
+		_ = conn.Close()
+
+		return ErrHubClosed
+	}
+
+	h.logger.Debug().Str("user_id", userID).Str("remote_addr", conn.RemoteAddr().String()).Msg("websocket connected")
+
+	// connections outlive the request, so they are not canceled with it
+	ctx := context.WithoutCancel(request.Context())
+
+	h.wg.Add(2)
+
+	go client.readLoop(ctx)
+	go client.writeLoop()
+
+	return nil
+}
+
+// register adds the client to the connections of its user, false if the hub was shut down.
+func (h *Hub) register(client *Client) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.closed {
+		return false
+	}
+
+	clients, ok := h.users[client.UserID]
+	if !ok {
+		clients = make(map[*Client]struct{})
+		h.users[client.UserID] = clients
+	}
+
+	clients[client] = struct{}{}
+	h.connections.Inc()
+
+	return true
+}
+
+// unregister removes the client from the connections of its user.
+func (h *Hub) unregister(client *Client) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	clients, ok := h.users[client.UserID]
+	if !ok {
+		return
+	}
+
+	if _, ok := clients[client]; !ok {
+		return
+	}
+
+	delete(clients, client)
+	h.connections.Dec()
+
+	if len(clients) == 0 {
+		delete(h.users, client.UserID)
+	}
+}
+
+// Broadcast queues the message to all connections and returns the number of connections it was queued to.
+func (h *Hub) Broadcast(messageType MessageType, data []byte) int {
+	return h.deliver(h.clients(""), messageType, data)
+}
+
+// Send queues the message to the connections of the user and returns the number of connections it was queued to.
+func (h *Hub) Send(userID string, messageType MessageType, data []byte) int {
+	if userID == "" {
+		return 0
+	}
+
+	return h.deliver(h.clients(userID), messageType, data)
+}
+
+// Connections returns the number of open connections of the user, or of all users if the user ID is empty.
+func (h *Hub) Connections(userID string) int {
+	return len(h.clients(userID))
+}
+
+// clients returns the connections of the user, or of all users if the user ID is empty.
+func (h *Hub) clients(userID string) []*Client {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	var clients []*Client
+
+	for user, userClients := range h.users {
+		if userID != "" && user != userID {
+			continue
+		}
+
+		for client := range userClients {
+			clients = append(clients, client)
+		}
+	}
+
+	return clients
+}
+
+// deliver queues the message to the clients, closing clients whose queue is full.
+func (h *Hub) deliver(clients []*Client, messageType MessageType, data []byte) int {
+	delivered := 0
+
+	for _, client := range clients {
+		if client.enqueue(message{messageType: messageType, data: data}) {
+			delivered++
+		}
+	}
+
+	return delivered
+}
+
+// Shutdown closes all connections with a going away close frame and waits until the peers answered or the
+// context is done, closing the remaining connections. Requests are not upgraded afterwards.
+func (h *Hub) Shutdown(ctx context.Context) error {
+	h.mu.Lock()
+	h.closed = true
+	h.mu.Unlock()
+
+	clients := h.clients("")
+	if len(clients) > 0 {
+		h.logger.Info().Int("connections", len(clients)).Msg("closing websocket connections")
+	}
+
+	for _, client := range clients {
+		client.closeGracefully(CloseGoingAway, "server shutting down")
+	}
+
+	done := make(chan struct{})
+
+	go func() {
+		h.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+	}
+
+	// peers that did not answer the close frame in time are disconnected
+	for _, client := range h.clients("") {
+		client.close()
+	}
+
+	<-done
+
+	return fmt.Errorf("failed to close websocket connections gracefully: %w", ctx.Err())
+}
+
+// Describe sends descriptors of the websocket metrics.
+func (h *Hub) Describe(descs chan<- *prometheus.Desc) {
+	h.connections.Describe(descs)
+}
+
+// Collect sends the websocket metrics.
+func (h *Hub) Collect(metrics chan<- prometheus.Metric) {
+	h.connections.Collect(metrics)
+}
@@ -0,0 +1,195 @@
+package websocket
+
+import (
+	"context"
+	"encoding/binary"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pocj8ur4in/boilerplate-go/internal/pkg/logger"
+)
+
+// setupTestHub creates a hub of the configuration served on a test server, authenticating users by the user
+// query parameter, and returns the hub and the server URL.
+func setupTestHub(t *testing.T, config *Config) (*Hub, string) {
+	t.Helper()
+
+	log, err := logger.New(&logger.Config{})
+	require.NoError(t, err)
+
+	hub, err := New(config, log)
+	require.NoError(t, err)
+
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		_ = hub.Serve(writer, request, request.URL.Query().Get("user"))
+	}))
+
+	t.Cleanup(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+
+		_ = hub.Shutdown(ctx)
+
+		server.Close()
+	})
+
+	return hub, server.URL
+}
+
+// connectTestUser connects a test client of the user to the hub and waits until it is registered.
+func connectTestUser(t *testing.T, hub *Hub, serverURL, userID string) *testClient {
+	t.Helper()
+
+	connected := hub.Connections(userID)
+
+	client, response := dialTest(t, serverURL+"?user="+userID, nil)
+	require.Equal(t, http.StatusSwitchingProtocols, response.StatusCode)
+
+	require.Eventually(t, func() bool { return hub.Connections(userID) == connected+1 }, time.Second, 5*time.Millisecond)
+
+	return client
+}
+
+func TestConfigSetDefault(t *testing.T) {
+	t.Parallel()
+
+	config := &Config{}
+	config.SetDefault()
+
+	assert.True(t, *config.Enabled)
+	assert.Equal(t, defaultPath, *config.Path)
+	assert.Empty(t, *config.AllowedOrigins)
+	assert.Equal(t, defaultPingInterval, *config.PingInterval)
+	assert.Equal(t, defaultPongTimeout, *config.PongTimeout)
+	assert.Equal(t, defaultWriteTimeout, *config.WriteTimeout)
+	assert.Equal(t, int64(defaultMaxMessageSize), *config.MaxMessageSize)
+	assert.Equal(t, defaultSendBuffer, *config.SendBuffer)
+}
+
+func TestNew(t *testing.T) {
+	t.Parallel()
+
+	log, err := logger.New(&logger.Config{})
+	require.NoError(t, err)
+
+	_, err = New(&Config{PongTimeout: &[]time.Duration{time.Second}[0]}, log)
+	require.ErrorIs(t, err, ErrInvalidConfig)
+
+	hub, err := New(nil, log)
+	require.NoError(t, err)
+	assert.True(t, hub.Enabled())
+
+	var disabled *Hub
+
+	assert.False(t, disabled.Enabled())
+}
+
+func TestHubSend(t *testing.T) {
+	t.Parallel()
+
+	hub, serverURL := setupTestHub(t, nil)
+
+	alice := connectTestUser(t, hub, serverURL, "alice")
+	aliceOther := connectTestUser(t, hub, serverURL, "alice")
+	bob := connectTestUser(t, hub, serverURL, "bob")
+
+	assert.Equal(t, 3, hub.Connections(""))
+	assert.InDelta(t, 3, testutil.ToFloat64(hub.connections), 0)
+
+	t.Run("send to connections of the user", func(t *testing.T) {
+		assert.Equal(t, 2, hub.Send("alice", TextMessage, []byte("hello alice")))
+		assert.Zero(t, hub.Send("carol", TextMessage, []byte("hello carol")))
+		assert.Zero(t, hub.Send("", TextMessage, []byte("hello nobody")))
+
+		for _, client := range []*testClient{alice, aliceOther} {
+			opcode, payload := client.readFrame(t)
+			assert.Equal(t, byte(opText), opcode)
+			assert.Equal(t, "hello alice", string(payload))
+		}
+	})
+
+	t.Run("broadcast to all connections", func(t *testing.T) {
+		assert.Equal(t, 3, hub.Broadcast(BinaryMessage, []byte{1, 2, 3}))
+
+		for _, client := range []*testClient{alice, aliceOther, bob} {
+			opcode, payload := client.readFrame(t)
+			assert.Equal(t, byte(opBinary), opcode)
+			assert.Equal(t, []byte{1, 2, 3}, payload)
+		}
+	})
+
+	t.Run("unregister closed connections", func(t *testing.T) {
+		bob.writeFrame(t, true, opClose, binary.BigEndian.AppendUint16(nil, CloseNormal))
+
+		opcode, _ := bob.readFrame(t)
+		assert.Equal(t, byte(opClose), opcode)
+
+		require.Eventually(t, func() bool { return hub.Connections("bob") == 0 }, time.Second, 5*time.Millisecond)
+		assert.Equal(t, 2, hub.Connections(""))
+	})
+}
+
+func TestHubHandle(t *testing.T) {
+	t.Parallel()
+
+	hub, serverURL := setupTestHub(t, nil)
+
+	hub.Handle(func(_ context.Context, client *Client, messageType MessageType, data []byte) {
+		client.Send(messageType, append([]byte(client.UserID+": "), data...))
+	})
+
+	client := connectTestUser(t, hub, serverURL, "alice")
+	client.writeFrame(t, true, opText, []byte("hi"))
+
+	opcode, payload := client.readFrame(t)
+	assert.Equal(t, byte(opText), opcode)
+	assert.Equal(t, "alice: hi", string(payload))
+}
+
+func TestHubShutdown(t *testing.T) {
+	t.Parallel()
+
+	t.Run("close connections gracefully", func(t *testing.T) {
+		t.Parallel()
+
+		hub, serverURL := setupTestHub(t, nil)
+		client := connectTestUser(t, hub, serverURL, "alice")
+
+		shutdown := make(chan error, 1)
+
+		go func() {
+			shutdown <- hub.Shutdown(context.Background())
+		}()
+
+		opcode, payload := client.readFrame(t)
+		assert.Equal(t, byte(opClose), opcode)
+		assert.Equal(t, CloseGoingAway, closeCode(payload))
+
+		client.writeFrame(t, true, opClose, payload[:2])
+
+		require.NoError(t, <-shutdown)
+		assert.Zero(t, hub.Connections(""))
+
+		_, response := dialTest(t, serverURL+"?user=alice", nil)
+		assert.Equal(t, http.StatusServiceUnavailable, response.StatusCode)
+	})
+
+	t.Run("disconnect peers not answering before deadline", func(t *testing.T) {
+		t.Parallel()
+
+		hub, serverURL := setupTestHub(t, nil)
+		connectTestUser(t, hub, serverURL, "alice")
+
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+		defer cancel()
+
+		require.ErrorIs(t, hub.Shutdown(ctx), context.DeadlineExceeded)
+		assert.Zero(t, hub.Connections(""))
+	})
+}
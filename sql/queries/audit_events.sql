@@ -0,0 +1,4 @@
+-- name: InsertAuditEvent :execrows
+INSERT INTO audit_events (id, action, actor_id, ip, user_agent, request_id, resource, attributes, occurred_at)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+ON CONFLICT (id) DO NOTHING;
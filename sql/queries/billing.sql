@@ -0,0 +1,32 @@
+-- name: CreateBillingCustomer :one
+INSERT INTO billing_customers (user_id, customer_id)
+VALUES ($1, $2)
+ON CONFLICT (user_id) DO UPDATE
+SET customer_id = billing_customers.customer_id
+RETURNING *;
+
+-- name: GetBillingCustomerByCustomerID :one
+SELECT * FROM billing_customers
+WHERE customer_id = $1;
+
+-- name: GetBillingCustomerByUserID :one
+SELECT * FROM billing_customers
+WHERE user_id = $1;
+
+-- name: ListBillingSubscriptionsByUserID :many
+SELECT billing_subscriptions.* FROM billing_subscriptions
+JOIN billing_customers ON billing_customers.customer_id = billing_subscriptions.customer_id
+WHERE billing_customers.user_id = $1
+ORDER BY billing_subscriptions.id;
+
+-- name: UpsertBillingSubscription :one
+INSERT INTO billing_subscriptions (id, customer_id, status, price_ids, current_period_end, cancel_at_period_end)
+VALUES ($1, $2, $3, $4, $5, $6)
+ON CONFLICT (id) DO UPDATE
+SET customer_id = EXCLUDED.customer_id,
+    status = EXCLUDED.status,
+    price_ids = EXCLUDED.price_ids,
+    current_period_end = EXCLUDED.current_period_end,
+    cancel_at_period_end = EXCLUDED.cancel_at_period_end,
+    updated_at = NOW()
+RETURNING *;
@@ -0,0 +1,4 @@
+-- name: InsertMeteringEvent :execrows
+INSERT INTO metering_events (id, tenant_id, type, quantity, attributes, occurred_at)
+VALUES ($1, $2, $3, $4, $5, $6)
+ON CONFLICT (id) DO NOTHING;
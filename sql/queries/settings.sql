@@ -0,0 +1,20 @@
+-- name: GetSetting :one
+SELECT * FROM settings
+WHERE scope = $1 AND key = $2;
+
+-- name: ListSettings :many
+SELECT * FROM settings
+WHERE scope = $1
+ORDER BY key;
+
+-- name: UpsertSetting :one
+INSERT INTO settings (scope, key, value)
+VALUES ($1, $2, $3)
+ON CONFLICT (scope, key) DO UPDATE
+SET value = EXCLUDED.value,
+    updated_at = NOW()
+RETURNING *;
+
+-- name: DeleteSetting :exec
+DELETE FROM settings
+WHERE scope = $1 AND key = $2;
@@ -0,0 +1,16 @@
+-- name: GetTenantRateLimit :one
+SELECT * FROM tenant_rate_limits
+WHERE tenant_id = $1;
+
+-- name: UpsertTenantRateLimit :one
+INSERT INTO tenant_rate_limits (tenant_id, requests, window_seconds)
+VALUES ($1, $2, $3)
+ON CONFLICT (tenant_id) DO UPDATE
+SET requests = EXCLUDED.requests,
+    window_seconds = EXCLUDED.window_seconds,
+    updated_at = NOW()
+RETURNING *;
+
+-- name: DeleteTenantRateLimit :exec
+DELETE FROM tenant_rate_limits
+WHERE tenant_id = $1;
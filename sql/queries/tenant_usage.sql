@@ -0,0 +1,13 @@
+-- name: AddTenantUsage :exec
+INSERT INTO tenant_usage (tenant_id, day, requests, request_bytes, response_bytes)
+VALUES ($1, $2, $3, $4, $5)
+ON CONFLICT (tenant_id, day) DO UPDATE
+SET requests = tenant_usage.requests + EXCLUDED.requests,
+    request_bytes = tenant_usage.request_bytes + EXCLUDED.request_bytes,
+    response_bytes = tenant_usage.response_bytes + EXCLUDED.response_bytes,
+    updated_at = NOW();
+
+-- name: ListTenantUsage :many
+SELECT * FROM tenant_usage
+WHERE day >= sqlc.arg(from_day) AND day < sqlc.arg(to_day)
+ORDER BY day, tenant_id;
@@ -0,0 +1,18 @@
+-- name: CreateUser :one
+INSERT INTO users (id, email, password_hash, role)
+VALUES ($1, $2, $3, $4)
+RETURNING *;
+
+-- name: GetUserByEmail :one
+SELECT * FROM users
+WHERE email = $1;
+
+-- name: GetUserByID :one
+SELECT * FROM users
+WHERE id = $1;
+
+-- name: UpdateUserRole :one
+UPDATE users
+SET role = $2, updated_at = NOW()
+WHERE id = $1
+RETURNING *;
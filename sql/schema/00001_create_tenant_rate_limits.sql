@@ -0,0 +1,11 @@
+-- +goose Up
+CREATE TABLE tenant_rate_limits (
+    tenant_id TEXT PRIMARY KEY,
+    requests INTEGER NOT NULL CHECK (requests > 0),
+    window_seconds INTEGER NOT NULL CHECK (window_seconds > 0),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+-- +goose Down
+DROP TABLE tenant_rate_limits;
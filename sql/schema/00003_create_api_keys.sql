@@ -0,0 +1,20 @@
+-- +goose Up
+CREATE TABLE api_keys (
+    id TEXT PRIMARY KEY,
+    user_id TEXT NOT NULL,
+    name TEXT NOT NULL,
+    key_hash TEXT NOT NULL UNIQUE,
+    role TEXT NOT NULL,
+    scopes TEXT[] NOT NULL DEFAULT '{}',
+    rate_limit_requests INTEGER CHECK (rate_limit_requests > 0),
+    rate_limit_window_seconds INTEGER CHECK (rate_limit_window_seconds > 0),
+    expires_at TIMESTAMPTZ,
+    revoked_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    CHECK ((rate_limit_requests IS NULL) = (rate_limit_window_seconds IS NULL))
+);
+
+CREATE INDEX api_keys_user_id_idx ON api_keys (user_id);
+
+-- +goose Down
+DROP TABLE api_keys;
@@ -0,0 +1,15 @@
+-- +goose Up
+CREATE TABLE metering_events (
+    id TEXT PRIMARY KEY,
+    tenant_id TEXT NOT NULL,
+    type TEXT NOT NULL,
+    quantity BIGINT NOT NULL,
+    attributes JSONB NOT NULL DEFAULT '{}',
+    occurred_at TIMESTAMPTZ NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX metering_events_tenant_id_occurred_at_idx ON metering_events (tenant_id, occurred_at);
+
+-- +goose Down
+DROP TABLE metering_events;
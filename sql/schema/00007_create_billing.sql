@@ -0,0 +1,22 @@
+-- +goose Up
+CREATE TABLE billing_customers (
+    user_id TEXT PRIMARY KEY,
+    customer_id TEXT NOT NULL UNIQUE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE TABLE billing_subscriptions (
+    id TEXT PRIMARY KEY,
+    customer_id TEXT NOT NULL,
+    status TEXT NOT NULL,
+    price_ids TEXT[] NOT NULL DEFAULT '{}',
+    current_period_end TIMESTAMPTZ,
+    cancel_at_period_end BOOLEAN NOT NULL DEFAULT FALSE,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX billing_subscriptions_customer_id_idx ON billing_subscriptions (customer_id);
+
+-- +goose Down
+DROP TABLE billing_subscriptions;
+DROP TABLE billing_customers;
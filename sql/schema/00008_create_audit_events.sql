@@ -0,0 +1,20 @@
+-- +goose Up
+CREATE TABLE audit_events (
+    id TEXT PRIMARY KEY,
+    action TEXT NOT NULL,
+    actor_id TEXT NOT NULL DEFAULT '',
+    ip TEXT NOT NULL DEFAULT '',
+    user_agent TEXT NOT NULL DEFAULT '',
+    request_id TEXT NOT NULL DEFAULT '',
+    resource TEXT NOT NULL DEFAULT '',
+    attributes JSONB NOT NULL DEFAULT '{}',
+    occurred_at TIMESTAMPTZ NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX audit_events_actor_id_occurred_at_idx ON audit_events (actor_id, occurred_at);
+
+CREATE INDEX audit_events_action_occurred_at_idx ON audit_events (action, occurred_at);
+
+-- +goose Down
+DROP TABLE audit_events;
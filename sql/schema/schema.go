@@ -0,0 +1,9 @@
+// Package schema embeds the migrations of the database schema, goose formatted SQL files also read by sqlc.
+package schema
+
+import "embed"
+
+// FS is the file system of the migrations.
+//
+//go:embed *.sql
+var FS embed.FS